@@ -0,0 +1,367 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// cliCommand is a subcommand: its arguments and summary for help, and the
+// flags it takes besides the global ones.
+type cliCommand struct {
+	name    string
+	args    string
+	summary string
+	flags   []string
+	words   []string // arguments shell completion offers
+}
+
+// commands are the subcommands, in the order help lists them.
+var commands = []cliCommand{
+	{name: "deploy", args: "[manifest...]", summary: "Create or update a deployment; further manifests are deployed with it as one batch", flags: []string{"dry-run", "policy", "allow-version-skew"}},
+	{name: "plan", summary: "Show what deploy would create, update, or delete without changing anything", flags: []string{"policy"}},
+	{name: "stop", summary: "Stop the environment but keep the application and its versions", flags: []string{"allow-version-skew"}},
+	{name: "destroy", summary: "Remove the application, environment, and versions", flags: []string{"dry-run", "allow-version-skew"}},
+	{name: "status", summary: "Check the deployment's status", flags: []string{"deep", "since"}},
+	{name: "rollback", summary: "Return to the previous version, or to a recorded version", flags: []string{"to", "allow-version-skew"}},
+	{name: "roll-forward", summary: "Redeploy the newest recorded version a rollback did not replace", flags: []string{"allow-version-skew"}},
+	{name: "attach", summary: "Wait for an operation an interrupted cloud-deploy left in progress"},
+	{name: "history", summary: "List the recorded deployments of the environment"},
+	{name: "scale", summary: "Change the instance counts without redeploying", flags: []string{"min", "max", "allow-version-skew"}},
+	{name: "maintenance", args: "on|off", summary: "Answer every request with a maintenance response, or stop doing so", flags: []string{"allow-version-skew"}, words: []string{"on", "off"}},
+	{name: "image", args: "promote", summary: "Promote images from one tag to another by digest", flags: []string{"from", "to"}, words: []string{"promote"}},
+	{name: "logs", summary: "Print recent application logs", flags: []string{"since", "follow"}},
+	{name: "export", summary: "Write the deployment as another tool's configuration", flags: []string{"export-format"}},
+	{name: "check-cname", summary: "Check whether the environment's CNAME prefix is available"},
+	{name: "secrets-sync", summary: "Update the environment variables whose secrets changed, without redeploying"},
+	{name: "credentials-rotate", summary: "Replace the provider credentials stored in Secrets Manager"},
+	{name: "iam-policy", summary: "Print the permissions cloud-deploy needs for the manifest"},
+	{name: "posture", summary: "Check quotas, credential expiry, billing, and API enablement of the account"},
+	{name: "validate", summary: "Check the manifest against what the provider offers"},
+	{name: "manifest", args: "schema", summary: "Print the JSON Schema of manifests, for editors and CI", words: []string{"schema"}},
+	{name: "inventory", summary: "List every cloud resource of the application"},
+	{name: "cleanup", summary: "Delete old images and application versions, keeping the newest and the deployed ones", flags: []string{"keep", "older-than", "dry-run"}},
+	{name: "stats", summary: "Summarize the commands recorded in the telemetry log", flags: []string{"local"}},
+	{name: "replay", args: "<transcript>", summary: "Play back a transcript recorded with -record", flags: []string{"speed"}},
+	{name: "completion", args: "bash|zsh|fish", summary: "Print a shell completion script", words: []string{"bash", "zsh", "fish"}},
+	{name: "help", args: "[command]", summary: "Show the commands, or the flags of a command"},
+}
+
+// globalFlags are the flags every command takes, before or after the
+// command's name.
+var globalFlags = []string{
+	"manifest", "env", "var", "var-file", "no-strict", "verify-manifest", "manifest-signature", "provider", "service", "endpoint-url", "timeout",
+	"read-only", "refresh", "record", "output", "lang", "no-color", "high-contrast", "ascii",
+}
+
+// rootFlags are the flags only taken before a command, if any: -command is
+// how commands were given before there were subcommands.
+var rootFlags = []string{"version", "command"}
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errNoCommand      = errors.New("no command given")
+)
+
+// listFlag is a flag that may be given more than once, such as -var.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ", ") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// lookupCommand returns the command named name, or nil.
+func lookupCommand(name string) *cliCommand {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// commandNames returns the names of the commands that act on deployments,
+// for the list of valid commands.
+func commandNames() []string {
+	var names []string
+	for _, c := range commands {
+		if c.name != "completion" && c.name != "help" {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// commandLine is a parsed command line.
+type commandLine struct {
+	command   string
+	args      []string
+	set       map[string]bool // the flags given
+	legacy    bool            // the command was given with -command, or defaulted
+	defaulted bool            // flags were given without a command, which deploys
+}
+
+// arg returns the i'th argument of the command, or "" if there is none.
+func (cl *commandLine) arg(i int) string {
+	if i < len(cl.args) {
+		return cl.args[i]
+	}
+	return ""
+}
+
+// parseCommandLine parses args, the command line without the program name.
+// root defines every flag; each command accepts the global flags and its
+// own, anywhere after its name. Flags before the name must be global, except
+// on command lines that give the command with -command, where every flag is
+// accepted there as before. Flags without a command deploy, as they did
+// before. Errors are printed to root's output along with the usage, unless
+// the command is unknown.
+func parseCommandLine(root *flag.FlagSet, args []string) (*commandLine, error) {
+	root.Usage = func() { writeUsage(root.Output(), root) }
+	if err := root.Parse(args); err != nil {
+		return nil, err
+	}
+	cl := &commandLine{set: map[string]bool{}}
+	root.Visit(func(f *flag.Flag) { cl.set[f.Name] = true })
+
+	rest := root.Args()
+	switch {
+	case cl.set["command"]:
+		cl.command, cl.legacy = root.Lookup("command").Value.String(), true
+	case len(rest) == 0:
+		if cl.set["version"] {
+			return cl, nil
+		}
+		if len(cl.set) == 0 {
+			root.Usage()
+			return nil, errNoCommand
+		}
+		cl.command, cl.legacy, cl.defaulted = "deploy", true, true
+	default:
+		cl.command, rest = rest[0], rest[1:]
+		for name := range cl.set {
+			if !slices.Contains(globalFlags, name) && !slices.Contains(rootFlags, name) {
+				err := fmt.Errorf("flag -%s must follow the command: cloud-deploy %s -%s", name, cl.command, name)
+				fmt.Fprintln(root.Output(), err)
+				return nil, err
+			}
+		}
+	}
+
+	c := lookupCommand(cl.command)
+	if c == nil {
+		cl.args = rest
+		return cl, errUnknownCommand
+	}
+	fs := c.flagSet(root)
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return nil, err
+		}
+		fs.Visit(func(f *flag.Flag) { cl.set[f.Name] = true })
+		if fs.NArg() == 0 {
+			break
+		}
+		// Flags may follow arguments, except after --
+		if parsed := len(rest) - fs.NArg(); parsed > 0 && rest[parsed-1] == "--" {
+			cl.args = append(cl.args, fs.Args()...)
+			break
+		}
+		cl.args = append(cl.args, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+	return cl, nil
+}
+
+// flagSet returns the flag set of the command, with the global flags and
+// its own taken from root.
+func (c *cliCommand) flagSet(root *flag.FlagSet) *flag.FlagSet {
+	fs := flag.NewFlagSet("cloud-deploy "+c.name, root.ErrorHandling())
+	fs.SetOutput(root.Output())
+	addFlags(fs, root, slices.Concat(c.flags, globalFlags))
+	fs.Usage = func() { writeCommandUsage(fs.Output(), root, c) }
+	return fs
+}
+
+// addFlags adds the flags of root named names to fs, sharing their values.
+// Names root does not define are skipped.
+func addFlags(fs, root *flag.FlagSet, names []string) {
+	for _, name := range names {
+		f := root.Lookup(name)
+		if f == nil {
+			continue
+		}
+		fs.Var(f.Value, f.Name, f.Usage)
+		fs.Lookup(name).DefValue = f.DefValue
+	}
+}
+
+// printFlags writes the usage of the flags of root named names to w.
+func printFlags(w io.Writer, root *flag.FlagSet, names []string) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(w)
+	addFlags(fs, root, names)
+	fs.PrintDefaults()
+}
+
+// writeUsage writes the commands and the global flags to w.
+func writeUsage(w io.Writer, root *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: cloud-deploy <command> [flags] [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	printFlags(w, root, slices.Concat(globalFlags, []string{"version"}))
+	fmt.Fprintln(w, "\nRun 'cloud-deploy help <command>' for the flags of a command.")
+}
+
+// writeCommandUsage writes the usage and flags of command c to w.
+func writeCommandUsage(w io.Writer, root *flag.FlagSet, c *cliCommand) {
+	fmt.Fprintf(w, "Usage: cloud-deploy %s [flags]", c.name)
+	if c.args != "" {
+		fmt.Fprintf(w, " %s", c.args)
+	}
+	fmt.Fprintf(w, "\n\n%s.\n", c.summary)
+	if len(c.flags) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		printFlags(w, root, c.flags)
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	printFlags(w, root, globalFlags)
+}
+
+// printHelp writes the usage of the command named in args to w, or of
+// cloud-deploy without one.
+func printHelp(w io.Writer, root *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		writeUsage(w, root)
+		return nil
+	}
+	c := lookupCommand(args[0])
+	if c == nil {
+		return fmt.Errorf("%w: %s", errUnknownCommand, args[0])
+	}
+	writeCommandUsage(w, root, c)
+	return nil
+}
+
+// writeCompletion writes the completion script for the shell named in args
+// to w. The scripts complete commands, the flags of each command, and the
+// arguments of commands that take words such as on or off.
+func writeCompletion(w io.Writer, root *flag.FlagSet, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: cloud-deploy completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Fprintln(w, "# bash completion for cloud-deploy; load with: source <(cloud-deploy completion bash)")
+		writeBashCompletion(w, root)
+	case "zsh":
+		fmt.Fprintln(w, "# zsh completion for cloud-deploy; load with: source <(cloud-deploy completion zsh)")
+		fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+		writeBashCompletion(w, root)
+	case "fish":
+		writeFishCompletion(w, root)
+	default:
+		return fmt.Errorf("unsupported shell %q: use bash, zsh, or fish", args[0])
+	}
+	return nil
+}
+
+// writeBashCompletion writes a bash completion function for cloud-deploy.
+func writeBashCompletion(w io.Writer, root *flag.FlagSet) {
+	dashed := func(names []string) string {
+		var words []string
+		for _, name := range names {
+			if root.Lookup(name) != nil {
+				words = append(words, "-"+name)
+			}
+		}
+		return strings.Join(words, " ")
+	}
+	// Flags with values are skipped with their value when finding the command
+	var valued []string
+	root.VisitAll(func(f *flag.Flag) {
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			valued = append(valued, "-"+f.Name, "--"+f.Name)
+		}
+	})
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+
+	fmt.Fprintln(w, "_cloud_deploy() {")
+	fmt.Fprintln(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} command= flags= words= i")
+	fmt.Fprintln(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do")
+	fmt.Fprintln(w, "\t\tcase ${COMP_WORDS[i]} in")
+	fmt.Fprintf(w, "\t\t%s) ((i++)) ;;\n", strings.Join(valued, "|"))
+	fmt.Fprintln(w, "\t\t-*) ;;")
+	fmt.Fprintln(w, "\t\t*) command=${COMP_WORDS[i]}; break ;;")
+	fmt.Fprintln(w, "\t\tesac")
+	fmt.Fprintln(w, "\tdone")
+	fmt.Fprintln(w, "\tcase $command in")
+	fmt.Fprintf(w, "\t\"\") flags=%q words=%q ;;\n", dashed(slices.Concat(globalFlags, rootFlags)), strings.Join(names, " "))
+	for _, c := range commands {
+		words := strings.Join(c.words, " ")
+		if c.name == "help" {
+			words = strings.Join(names, " ")
+		}
+		fmt.Fprintf(w, "\t%s) flags=%q words=%q ;;\n", c.name, dashed(slices.Concat(c.flags, globalFlags)), words)
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "\tif [[ $cur == -* ]]; then")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))")
+	fmt.Fprintln(w, "\telif [[ -n $words ]]; then")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o default -F _cloud_deploy cloud-deploy")
+}
+
+// writeFishCompletion writes fish completions for cloud-deploy.
+func writeFishCompletion(w io.Writer, root *flag.FlagSet) {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	}
+	option := func(condition, name string) {
+		f := root.Lookup(name)
+		if f == nil {
+			return
+		}
+		fmt.Fprintf(w, "complete -c cloud-deploy%s -o %s", condition, name)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			fmt.Fprint(w, " -r")
+		}
+		fmt.Fprintf(w, " -d %s\n", quote(f.Usage))
+	}
+
+	fmt.Fprintln(w, "# fish completion for cloud-deploy; load with: cloud-deploy completion fish | source")
+	for _, c := range commands {
+		fmt.Fprintf(w, "complete -c cloud-deploy -f -n __fish_use_subcommand -a %s -d %s\n", c.name, quote(c.summary))
+	}
+	for _, name := range globalFlags {
+		option("", name)
+	}
+	option(" -n __fish_use_subcommand", "version")
+	for _, c := range commands {
+		condition := fmt.Sprintf(" -n '__fish_seen_subcommand_from %s'", c.name)
+		for _, name := range c.flags {
+			option(condition, name)
+		}
+		words := c.words
+		if c.name == "help" {
+			words = commandNames()
+		}
+		if len(words) > 0 {
+			fmt.Fprintf(w, "complete -c cloud-deploy -f%s -a %s\n", condition, quote(strings.Join(words, " ")))
+		}
+	}
+}
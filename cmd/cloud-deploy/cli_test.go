@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRoot returns a flag set with a few of the global and command flags,
+// and the command flag.
+func testRoot() *flag.FlagSet {
+	root := flag.NewFlagSet("cloud-deploy", flag.ContinueOnError)
+	root.SetOutput(io.Discard)
+	root.String("manifest", "deploy-manifest.yaml", "Path to deployment manifest file")
+	root.String("output", "text", "Output format")
+	root.Bool("version", false, "Show version information")
+	root.String("command", "deploy", "Deprecated: the command to execute")
+	root.Bool("dry-run", false, "Show what would change without changing anything")
+	root.String("to", "", "Version to roll back to")
+	root.String("from", "", "Tag or image to promote")
+	root.Duration("since", 10*time.Minute, "How far back to read logs")
+	root.Bool("follow", false, "Keep streaming new log entries")
+	return root
+}
+
+func TestParseCommandLine(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		command string
+		rest    []string
+		set     []string
+		legacy  bool
+	}{
+		{[]string{"deploy", "-dry-run", "staging.yaml"}, "deploy", []string{"staging.yaml"}, []string{"dry-run"}, false},
+		{[]string{"-manifest", "prod.yaml", "logs", "--follow", "-since", "1h"}, "logs", nil, []string{"follow", "manifest", "since"}, false},
+		{[]string{"deploy", "a.yaml", "-manifest", "m.yaml", "b.yaml"}, "deploy", []string{"a.yaml", "b.yaml"}, []string{"manifest"}, false},
+		{[]string{"deploy", "--", "-odd.yaml", "-dry-run"}, "deploy", []string{"-odd.yaml", "-dry-run"}, nil, false},
+		{[]string{"image", "promote", "-from", "staging", "-to", "prod"}, "image", []string{"promote"}, []string{"from", "to"}, false},
+		{[]string{"-manifest", "m.yaml", "-command", "rollback", "-to", "v3"}, "rollback", nil, []string{"command", "manifest", "to"}, true},
+		{[]string{"-to", "v3", "-command", "rollback"}, "rollback", nil, []string{"command", "to"}, true},
+		{[]string{"-command", "maintenance", "on"}, "maintenance", []string{"on"}, []string{"command"}, true},
+		{[]string{"-version"}, "", nil, []string{"version"}, false},
+		{[]string{"-manifest", "m.yaml"}, "deploy", nil, []string{"manifest"}, true},
+		{[]string{"-dry-run", "-manifest", "m.yaml"}, "deploy", nil, []string{"dry-run", "manifest"}, true},
+	} {
+		cl, err := parseCommandLine(testRoot(), tc.args)
+		if err != nil {
+			t.Errorf("parseCommandLine(%q) failed: %v", tc.args, err)
+			continue
+		}
+		var set []string
+		for name := range cl.set {
+			set = append(set, name)
+		}
+		slices.Sort(set)
+		if cl.defaulted != (tc.legacy && !cl.set["command"]) {
+			t.Errorf("parseCommandLine(%q) defaulted = %t, want %t", tc.args, cl.defaulted, !cl.defaulted)
+		}
+		if cl.command != tc.command || !slices.Equal(cl.args, tc.rest) || !slices.Equal(set, tc.set) || cl.legacy != tc.legacy {
+			t.Errorf("parseCommandLine(%q) = %q %q, flags %q, legacy %t; want %q %q, flags %q, legacy %t", tc.args, cl.command, cl.args, set, cl.legacy, tc.command, tc.rest, tc.set, tc.legacy)
+		}
+	}
+}
+
+func TestParseCommandLineErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"-to", "v3", "rollback"},
+		{"status", "-to", "v3"},
+		{"deploy", "-command", "plan"},
+	} {
+		if _, err := parseCommandLine(testRoot(), args); err == nil || errors.Is(err, errUnknownCommand) {
+			t.Errorf("parseCommandLine(%q) error = %v, want a usage error", args, err)
+		}
+	}
+
+	cl, err := parseCommandLine(testRoot(), []string{"deplyo", "-dry-run"})
+	if !errors.Is(err, errUnknownCommand) || cl.command != "deplyo" {
+		t.Errorf("Expected deplyo to be an unknown command, got %+v, %v", cl, err)
+	}
+	if _, err := parseCommandLine(testRoot(), []string{"-command", "deplyo"}); !errors.Is(err, errUnknownCommand) {
+		t.Errorf("Expected -command deplyo to be an unknown command, got %v", err)
+	}
+}
+
+func TestPrintHelp(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printHelp(&buf, testRoot(), nil); err != nil {
+		t.Fatalf("printHelp failed: %v", err)
+	}
+	for _, want := range []string{"Usage: cloud-deploy <command>", "roll-forward", "Global flags:", "-manifest string", "-version"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected help to contain %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "-dry-run") || strings.Contains(buf.String(), "-command") {
+		t.Errorf("Expected only the global flags in the help:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := printHelp(&buf, testRoot(), []string{"rollback"}); err != nil {
+		t.Fatalf("printHelp failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Usage: cloud-deploy rollback [flags]") || !strings.Contains(buf.String(), "Flags:\n  -to string") || strings.Contains(buf.String(), "-since") {
+		t.Errorf("Unexpected rollback help:\n%s", buf.String())
+	}
+
+	if err := printHelp(io.Discard, testRoot(), []string{"deplyo"}); !errors.Is(err, errUnknownCommand) {
+		t.Errorf("Expected an unknown command error, got %v", err)
+	}
+}
+
+func TestCommandNames(t *testing.T) {
+	names := commandNames()
+	if !slices.Contains(names, "deploy") || !slices.Contains(names, "replay") || slices.Contains(names, "help") {
+		t.Errorf("Unexpected command names: %v", names)
+	}
+	for _, c := range commands {
+		for _, name := range c.flags {
+			if slices.Contains(globalFlags, name) {
+				t.Errorf("%s lists the global flag -%s", c.name, name)
+			}
+		}
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for shell, want := range map[string][]string{
+		"bash": {"complete -o default -F _cloud_deploy cloud-deploy", `rollback) flags="-to -manifest -output"`, `maintenance) flags="-manifest -output" words="on off"`, "-manifest|--manifest|"},
+		"zsh":  {"bashcompinit", "_cloud_deploy()"},
+		"fish": {"complete -c cloud-deploy -f -n __fish_use_subcommand -a deploy", "complete -c cloud-deploy -n '__fish_seen_subcommand_from logs' -o follow -d", "-o manifest -r -d 'Path to deployment manifest file'"},
+	} {
+		var buf bytes.Buffer
+		if err := writeCompletion(&buf, testRoot(), []string{shell}); err != nil {
+			t.Fatalf("writeCompletion(%s) failed: %v", shell, err)
+		}
+		for _, w := range want {
+			if !strings.Contains(buf.String(), w) {
+				t.Errorf("Expected the %s script to contain %q:\n%s", shell, w, buf.String())
+			}
+		}
+		if shell == "bash" {
+			if _, err := exec.LookPath("bash"); err == nil {
+				cmd := exec.Command("bash", "-n")
+				cmd.Stdin = &buf
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("bash rejected the script: %v\n%s", err, out)
+				}
+			}
+		}
+	}
+
+	for _, args := range [][]string{nil, {"powershell"}} {
+		if err := writeCompletion(io.Discard, testRoot(), args); err == nil {
+			t.Errorf("Expected writeCompletion(%q) to fail", args)
+		}
+	}
+}
+
+func TestListFlag(t *testing.T) {
+	var vars listFlag
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&vars, "var", "Manifest variable")
+	if err := fs.Parse([]string{"-var", "team=search", "-var", "region=eu-west-1"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !slices.Equal(vars, listFlag{"team=search", "region=eu-west-1"}) || vars.String() != "team=search, region=eu-west-1" {
+		t.Errorf("Unexpected values: %q", vars)
+	}
+}
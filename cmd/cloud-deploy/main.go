@@ -2,15 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/jvreagan/cloud-deploy/pkg/build"
+	"github.com/jvreagan/cloud-deploy/pkg/cache"
+	"github.com/jvreagan/cloud-deploy/pkg/cloudflare"
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/dependencies"
+	"github.com/jvreagan/cloud-deploy/pkg/errhints"
+	"github.com/jvreagan/cloud-deploy/pkg/hooks"
+	"github.com/jvreagan/cloud-deploy/pkg/i18n"
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
 	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/notify"
+	"github.com/jvreagan/cloud-deploy/pkg/output"
+	"github.com/jvreagan/cloud-deploy/pkg/policy"
 	"github.com/jvreagan/cloud-deploy/pkg/provider"
+	"github.com/jvreagan/cloud-deploy/pkg/readonly"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/secrets"
+	"github.com/jvreagan/cloud-deploy/pkg/signature"
+	"github.com/jvreagan/cloud-deploy/pkg/state"
+	"github.com/jvreagan/cloud-deploy/pkg/style"
+	"github.com/jvreagan/cloud-deploy/pkg/telemetry"
+	"github.com/jvreagan/cloud-deploy/pkg/transcript"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+	"github.com/jvreagan/cloud-deploy/pkg/userconfig"
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+	"github.com/jvreagan/cloud-deploy/pkg/verification"
 )
 
 // Version information (set via ldflags during build)
@@ -23,25 +58,335 @@ var (
 func main() {
 	// Parse command line flags
 	var (
-		manifestFile = flag.String("manifest", "deploy-manifest.yaml", "Path to deployment manifest file")
-		command      = flag.String("command", "deploy", "Command to execute: deploy, stop, destroy, status, rollback")
+		manifestFile = flag.String("manifest", "deploy-manifest.yaml", "Path to deployment manifest file, - for stdin, or an https://, s3://, or gs:// URL")
+		_            = flag.String("command", "deploy", "Deprecated: the command to execute; run cloud-deploy <command> instead")
+		dryRun       = flag.Bool("dry-run", false, "Show what would change without changing anything (for deploy, same as plan)")
 		timeout      = flag.Duration("timeout", 30*time.Minute, "Maximum time for the operation to complete")
+		exportFormat = flag.String("export-format", "", "Format to export (overrides export.format in the manifest)")
+		envName      = flag.String("env", os.Getenv("CLOUD_DEPLOY_ENV"), "Environment of the manifest's environments to act on, e.g. staging (default: $CLOUD_DEPLOY_ENV)")
+		vars         listFlag
+		varFiles     listFlag
+		noStrict     = flag.Bool("no-strict", false, "Ignore manifest settings cloud-deploy does not know, such as misspelled ones, instead of refusing the manifest")
+		manifestKey  = flag.String("verify-manifest", os.Getenv("CLOUD_DEPLOY_MANIFEST_KEY"), "Public key (minisign or cosign) that the manifest must be signed with; unsigned or modified manifests are refused (default: $CLOUD_DEPLOY_MANIFEST_KEY)")
+		manifestSig  = flag.String("manifest-signature", "", "Signature of the manifest for -verify-manifest: a file or URL (default: the manifest location plus .minisig or .sig)")
+		policyFile   = flag.String("policy", os.Getenv("CLOUD_DEPLOY_POLICY"), "Path to a policy file that manifests must satisfy before deploying (default: $CLOUD_DEPLOY_POLICY)")
+		follow       = flag.Bool("follow", false, "Keep streaming new log entries")
+		toFlag       = flag.String("to", "", "Version from the deployment history to roll back to, e.g. v3 (rollback), or tag or image to promote to (image promote)")
+		fromFlag     = flag.String("from", "", "Tag or image to promote (image promote)")
+		minInstances = flag.Int("min", -1, "Minimum number of instances (default: the manifest's)")
+		maxInstances = flag.Int("max", -1, "Maximum number of instances (default: the manifest's)")
+		since        = flag.Duration("since", 10*time.Minute, "How far back to read logs, or remediation events with status -deep (default for status: 24h)")
+		keep         = flag.Int("keep", 10, "Number of the newest images and application versions cleanup keeps")
+		olderThan    = flag.Duration("older-than", 0, "Only delete images and application versions older than this with cleanup, e.g. 720h (default: any age)")
+		allowSkew    = flag.Bool("allow-version-skew", false, "Change an environment even if it was last deployed by an incompatible version of cloud-deploy")
+		deep         = flag.Bool("deep", false, "Also check the manifest's dependencies and report instances and containers the provider replaced or restarted on its own")
+		record       = flag.String("record", "", "Write a transcript of the command's steps, API requests, and timing to this file")
+		replaySpeed  = flag.Float64("speed", 1, "Playback speed (0 prints the transcript without pauses)")
+		localStats   = flag.Bool("local", false, "Show statistics from the local telemetry log")
+		lang         = flag.String("lang", os.Getenv("CLOUD_DEPLOY_LANG"), "Language for CLI messages: en, es, ja (default: $CLOUD_DEPLOY_LANG, then the locale)")
+		noColor      = flag.Bool("no-color", false, "Disable colored output (also disabled by NO_COLOR and when output is not a terminal)")
+		highContrast = flag.Bool("high-contrast", false, "Use bold, underline, and reverse video instead of colors")
+		asciiOnly    = flag.Bool("ascii", false, "Replace symbols such as ✓ with plain text")
+		endpointURL  = flag.String("endpoint-url", "", "Send the provider's API requests to an emulator at this URL, such as LocalStack (overrides provider.endpoint_url)")
+		providerName = flag.String("provider", "", "Provider for the iam-policy command, or which of a multi-provider manifest's providers other commands act on (default: the manifest's provider)")
+		serviceName  = flag.String("service", "", "Which of the manifest's services commands other than deploy act on")
+		refresh      = flag.Bool("refresh", false, "Run the setup checks that deploys skip for 24h once passed, such as whether a GCP project is ready, and cache their results anew")
+		readOnly     = flag.Bool("read-only", false, "Refuse every command and API request that would change anything, e.g. to view production with its credentials (default: read_only in the user configuration)")
+		outputFlag   = flag.String("output", "text", "Output format: text, or json or yaml to write the command's result and errors to stdout as one document, with log messages on stderr")
 		showVersion  = flag.Bool("version", false, "Show version information")
 	)
-	flag.Parse()
+	flag.Var(&vars, "var", "Value of a manifest variable, as name=value (repeatable; overrides -var-file)")
+	flag.Var(&varFiles, "var-file", "YAML file of manifest variable values (repeatable)")
+	cl, parseErr := parseCommandLine(flag.CommandLine, os.Args[1:])
+	if parseErr != nil && !errors.Is(parseErr, errUnknownCommand) {
+		os.Exit(2)
+	}
+	command := cl.command
+
+	style.Configure(style.Options{NoColor: *noColor, HighContrast: *highContrast, ASCII: *asciiOnly}, os.Stdout)
+
+	if err := i18n.SetLanguage(*lang); err != nil {
+		logging.Errorf("%v\n", err)
+		os.Exit(1)
+	}
+
+	if errors.Is(parseErr, errUnknownCommand) {
+		logging.Error(i18n.T("command.unknown", command))
+		logging.Error(i18n.T("command.valid", strings.Join(commandNames(), ", ")))
+		os.Exit(1)
+	}
+	switch {
+	case cl.defaulted:
+		logging.Warn(i18n.T("command.defaulted"))
+	case cl.legacy:
+		logging.Warn(i18n.T("command.deprecated", command))
+	}
+
+	// Help and completion scripts are written from the command table
+	switch command {
+	case "help":
+		if err := printHelp(os.Stdout, flag.CommandLine, cl.args); err != nil {
+			logging.Error(i18n.T("command.unknown", cl.args[0]))
+			logging.Error(i18n.T("command.valid", strings.Join(commandNames(), ", ")))
+			os.Exit(1)
+		}
+		return
+	case "completion":
+		if err := writeCompletion(os.Stdout, flag.CommandLine, cl.args); err != nil {
+			logging.Error(i18n.T("completion.failed", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// With -output json or yaml, stdout holds only the command's document, so
+	// log messages go to stderr and the errors logged are collected for it
+	outFormat, err := output.ParseFormat(*outputFlag)
+	if err != nil {
+		logging.Error(i18n.T("output.invalid", err))
+		os.Exit(1)
+	}
+	out := io.Writer(os.Stdout)
+	var logged *output.Errors
+	if outFormat.Structured() {
+		logging.SetOutput(os.Stderr)
+		logged = &output.Errors{}
+		logging.SetLogger(slog.New(logged.Handler(logging.GetLogger().Handler())))
+		out = io.Discard
+	}
+
+	// document writes the command's result, or the errors it failed with, to
+	// stdout for -output json and yaml; exitEarly does so and exits before
+	// there is a manifest
+	var docResult any
+	document := func(code int) {
+		if !outFormat.Structured() || !writesDocument(command) {
+			return
+		}
+		doc := output.Document{Command: command, Succeeded: code == 0, Result: docResult, Errors: logged.Messages()}
+		if err := output.Write(os.Stdout, outFormat, doc); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	exitEarly := func(code int) {
+		document(code)
+		os.Exit(code)
+	}
 
 	if *showVersion {
-		logging.Infof("cloud-deploy version %s", version)
-		logging.Infof("  commit: %s", commit)
-		logging.Infof("  built: %s", date)
+		logging.Info(i18n.T("version", version))
+		logging.Info(i18n.T("version.commit", commit))
+		logging.Info(i18n.T("version.built", date))
 		os.Exit(0)
 	}
 
-	// Load and parse manifest
-	m, err := manifest.Load(*manifestFile)
+	if *dryRun && command == "deploy" {
+		command = "plan"
+	}
+
+	// Read-only mode refuses commands that change anything up front, and the
+	// API requests of any that would anyway
+	readOnlyOn, err := readOnlyMode(*readOnly)
 	if err != nil {
-		logging.Errorf("Error loading manifest: %v\n", err)
-		os.Exit(1)
+		logging.Error(i18n.T("read_only.failed", err))
+		exitEarly(1)
+	}
+	if readOnlyOn {
+		if !readOnlyCommand(command, *dryRun) {
+			logging.Error(i18n.T("read_only.refused", command))
+			exitEarly(1)
+		}
+		readonly.Enable()
+	}
+
+	if *refresh {
+		cache.Refresh()
+	}
+
+	// Usage statistics come from the local telemetry log and need no manifest
+	if command == "stats" {
+		if err := showStats(*localStats); err != nil {
+			logging.Error(i18n.T("stats.failed", err))
+			exitEarly(1)
+		}
+		return
+	}
+
+	// Replaying a transcript needs no manifest
+	if command == "replay" {
+		if err := replayTranscript(cl.arg(0), *replaySpeed); err != nil {
+			logging.Error(i18n.T("replay.failed", err))
+			exitEarly(1)
+		}
+		return
+	}
+
+	// The manifest schema is generated from the manifest's types
+	if command == "manifest" {
+		if err := writeManifestSchema(os.Stdout, cl.args); err != nil {
+			logging.Error(i18n.T("manifest.schema_failed", err))
+			exitEarly(1)
+		}
+		return
+	}
+
+	// Maintenance mode is turned on or off by the command's argument
+	maintenanceOn := false
+	if command == "maintenance" {
+		on, err := maintenanceMode(cl.arg(0))
+		if err != nil {
+			logging.Error(i18n.T("maintenance.failed", err))
+			exitEarly(1)
+		}
+		maintenanceOn = on
+	}
+
+	// Images are promoted with image promote -from <tag> -to <tag>
+	if command == "image" {
+		if err := imagePromotion(cl.args, *fromFlag, *toFlag); err != nil {
+			logging.Error(i18n.T("image.promote_failed", err))
+			exitEarly(1)
+		}
+	}
+
+	start := time.Now()
+
+	// Load and parse manifest, verifying its signature first when required
+	var verify manifest.Verifier
+	if *manifestKey != "" {
+		v, err := manifestVerifier(*manifestKey, *manifestFile, *manifestSig)
+		if err != nil {
+			logging.Error(i18n.T("manifest.load_failed", err))
+			exitEarly(1)
+		}
+		verify = v
+	}
+	variables, err := manifestVariables(varFiles, vars)
+	if err != nil {
+		logging.Error(i18n.T("manifest.load_failed", err))
+		exitEarly(1)
+	}
+	loadOpts := manifest.Options{Verify: verify, Environment: *envName, Variables: variables, Lenient: *noStrict}
+	m, err := manifest.LoadWith(*manifestFile, loadOpts)
+	if err != nil {
+		logging.Error(i18n.T("manifest.load_failed", err))
+		exitEarly(1)
+	}
+
+	// Manifests given as arguments are deployed together with it as one batch
+	var batch []*manifest.Manifest
+	if command == "deploy" && len(cl.args) > 0 {
+		batch, err = loadBatch(m, cl.args, loadOpts, *manifestSig)
+		if err != nil {
+			logging.Error(i18n.T("manifest.load_failed", err))
+			exitEarly(1)
+		}
+	}
+
+	// exit closes the provider (if created), saves the transcript (if
+	// recording), records the command's outcome as telemetry, and exits with
+	// code
+	var recorder *transcript.Recorder
+	var opened provider.Provider
+	exit := func(code int) {
+		if opened != nil {
+			closeProvider(opened)
+		}
+		// Secrets read from vault stay valid for the deployment; only the
+		// command's login token is revoked
+		if err := vault.CloseShared(); err != nil {
+			logging.Warn(i18n.T("vault.close_failed", err))
+		}
+		name := m.Provider.Name
+		if m.IsMultiProvider() || m.IsMultiService() {
+			name = "multi"
+		}
+		if recorder != nil {
+			if err := recorder.Save(*record, code == 0); err != nil {
+				logging.Warn(i18n.T("record.failed", err))
+			} else {
+				logging.Info(i18n.T("record.saved", *record))
+			}
+		}
+		telemetry.Record(context.Background(), telemetry.NewEvent(command, name, version, time.Since(start), code == 0))
+		document(code)
+		os.Exit(code)
+	}
+
+	// Deploy and plan run against every provider of a multi-provider manifest
+	// (plan only when -provider is not given); other commands act on the one
+	// selected with -provider
+	if m.IsMultiProvider() && command != "deploy" && (command != "plan" || *providerName != "") {
+		selected, err := m.ForProvider(*providerName)
+		if err != nil {
+			logging.Error(i18n.T("manifest.load_failed", err))
+			exit(1)
+		}
+		m = selected
+	}
+	// The services of a manifest are selected the same way, once it has a
+	// single provider
+	if m.IsMultiService() && !m.IsMultiProvider() && command != "deploy" && (command != "plan" || *serviceName != "") {
+		selected, err := m.ForService(*serviceName)
+		if err != nil {
+			logging.Error(i18n.T("manifest.load_failed", err))
+			exit(1)
+		}
+		m = selected
+	}
+
+	if *endpointURL != "" {
+		if err := m.Emulate(*endpointURL); err != nil {
+			logging.Error(i18n.T("manifest.load_failed", err))
+			exit(1)
+		}
+	}
+
+	// Record every logged step and the requests of cloud-deploy's own API
+	// clients, which use the default HTTP client
+	if *record != "" {
+		name := m.Provider.Name
+		if m.IsMultiProvider() || m.IsMultiService() {
+			name = "multi"
+		}
+		recorder = transcript.NewRecorder(command, name, m.Application.Name, m.Environment.Name, version)
+		logging.SetLogger(slog.New(recorder.Handler(logging.GetLogger().Handler())))
+		http.DefaultClient.Transport = recorder.Transport(http.DefaultTransport)
+	}
+	if readonly.Enabled() {
+		http.DefaultClient.Transport = readonly.Transport(http.DefaultClient.Transport)
+	}
+
+	// Apply organization policy defaults and enforce its rules before deploying,
+	// and before planning so the plan shows what would actually be deployed
+	var pol *policy.Policy
+	if *policyFile != "" && (command == "deploy" || command == "plan") {
+		pol, err = policy.Load(*policyFile)
+		if err != nil {
+			logging.Error(i18n.T("policy.load_failed", err))
+			exit(1)
+		}
+		if !m.IsMultiProvider() && !m.IsMultiService() {
+			if err := enforcePolicy(pol, m); err != nil {
+				logging.Error(err.Error())
+				exit(1)
+			}
+		}
+	}
+
+	// Generating a policy needs no cloud access, so it runs before the provider is created
+	if command == "iam-policy" {
+		name := *providerName
+		if name == "" {
+			name = m.Provider.Name
+		}
+		doc, err := provider.IAMPolicy(name, m)
+		if err != nil {
+			logging.Error(i18n.T("iam_policy.failed", err))
+			exit(1)
+		}
+		os.Stdout.Write(append(doc, '\n'))
+		exit(0)
 	}
 
 	// Set up context with timeout and signal handling
@@ -52,74 +397,2149 @@ func main() {
 	defer sigCancel()
 	ctx = sigCtx
 
+	// History is read from the state backend and needs no provider
+	if command == "history" {
+		records, err := showHistory(ctx, m, out)
+		if err != nil {
+			logging.Error(i18n.T("history.failed", err))
+			exit(1)
+		}
+		docResult = records
+		exit(0)
+	}
+
+	// Promoting copies images between registries and needs no provider
+	if command == "image" {
+		if err := promoteImages(ctx, m, *fromFlag, *toFlag); err != nil {
+			logging.Error(i18n.T("image.promote_failed", err))
+			printHints(err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	// Refuse to change an environment last deployed by an incompatible version;
+	// the targets of multi-provider and batch deploys are checked as they deploy
+	if changesEnvironment(command) && !m.IsMultiProvider() && !m.IsMultiService() && len(batch) == 0 {
+		if err := checkVersionSkew(ctx, m, *allowSkew); err != nil {
+			logging.Error(i18n.T("version_skew.failed", err))
+			exit(1)
+		}
+	}
+
+	// Build the image from source once, before it is deployed to any provider;
+	// a plan only names the image it would build. Images promoted with image
+	// promote are pinned to the promoted digest. A deploy then resolves the
+	// tag naming.image_tag_strategy pushes the images with.
+	if command == "deploy" || command == "plan" {
+		for _, bm := range append([]*manifest.Manifest{m}, batch...) {
+			if bm.Deployment.Source.Build == nil {
+				if err := pinPromotedImages(ctx, bm); err != nil {
+					logging.Error(i18n.T("image.pin_failed", err))
+					exit(1)
+				}
+			} else if err := buildImage(ctx, bm, command == "plan"); err != nil {
+				logging.Error(i18n.T("build.failed", err))
+				printHints(err)
+				exit(1)
+			}
+			if command != "deploy" {
+				continue
+			}
+			if err := bm.ResolveImageTag(); err != nil {
+				logging.Error(i18n.T("deploy.failed", err))
+				exit(1)
+			}
+		}
+	}
+
+	if (m.IsMultiProvider() || m.IsMultiService()) && command == "plan" {
+		failed := false
+		var plans []*types.Plan
+		for _, target := range m.Targets() {
+			plan, err := planTarget(ctx, target, pol, out)
+			if err != nil {
+				logging.Error(i18n.T("plan.provider_failed", target.Provider.Name, err))
+				printHints(err)
+				failed = true
+				continue
+			}
+			plans = append(plans, plan)
+		}
+		docResult = plans
+		if failed {
+			exit(1)
+		}
+		exit(0)
+	}
+
+	// Posture is account-level, so it is checked for every provider of the manifest
+	if command == "posture" {
+		healthy := true
+		var postures []*types.Posture
+		for _, target := range m.Targets() {
+			posture, err := postureTarget(ctx, target, out)
+			if err != nil {
+				logging.Error(i18n.T("posture.provider_failed", target.Provider.Name, err))
+				printHints(err)
+				healthy = false
+				continue
+			}
+			postures = append(postures, posture)
+			healthy = healthy && postureHealthy(posture)
+		}
+		docResult = postures
+		if !healthy {
+			exit(1)
+		}
+		exit(0)
+	}
+
+	// Validation reports every problem found, for every provider of the manifest
+	if command == "validate" {
+		findings := imageFindings(m)
+		failed := false
+		for _, target := range m.Targets() {
+			targetFindings, err := validateTarget(ctx, target)
+			if err != nil {
+				logging.Error(i18n.T("validate.provider_failed", target.Provider.Name, err))
+				printHints(err)
+				failed = true
+			}
+			findings = append(findings, targetFindings...)
+		}
+		docResult = append([]types.ValidationFinding{}, findings...)
+		if err := writeFindings(out, findings); err != nil {
+			logging.Error(err.Error())
+			exit(1)
+		}
+		if failed || len(findings) > 0 {
+			exit(1)
+		}
+		exit(0)
+	}
+
+	// Inventory lists the application's resources on every provider of the manifest
+	if command == "inventory" {
+		failed := false
+		var inventories []*types.Inventory
+		for _, target := range m.Targets() {
+			inventory, err := inventoryTarget(ctx, target, out)
+			if err != nil {
+				logging.Error(i18n.T("inventory.provider_failed", target.Provider.Name, err))
+				printHints(err)
+				failed = true
+				continue
+			}
+			inventories = append(inventories, inventory)
+		}
+		docResult = inventories
+		if failed {
+			exit(1)
+		}
+		exit(0)
+	}
+
+	// Cleanup prunes old images and application versions on every provider of the manifest
+	if command == "cleanup" {
+		if *keep < 1 {
+			logging.Error(i18n.T("cleanup.keep_invalid", *keep))
+			exit(1)
+		}
+		policy := types.RetentionPolicy{KeepLast: *keep, OlderThan: *olderThan}
+		failed := false
+		var cleanups []*types.Cleanup
+		for _, target := range m.Targets() {
+			cleanup, err := cleanupTarget(ctx, target, policy, *dryRun, out)
+			if err != nil {
+				logging.Error(i18n.T("cleanup.provider_failed", target.Provider.Name, err))
+				printHints(err)
+				failed = true
+			}
+			if cleanup != nil {
+				cleanups = append(cleanups, cleanup)
+			}
+		}
+		docResult = cleanups
+		if failed {
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if m.IsMultiProvider() || m.IsMultiService() || len(batch) > 0 {
+		targets := m.Targets()
+		for _, bm := range batch {
+			targets = append(targets, bm.Targets()...)
+		}
+		schedule, err := deploySchedule()
+		if err != nil {
+			logging.Error(i18n.T("deploy.failed", err))
+			exit(1)
+		}
+		var pushes registryPushes
+		results := provider.DeployScheduled(ctx, targets, schedule, func(ctx context.Context, target *manifest.Manifest) (*types.DeploymentResult, error) {
+			return deployTarget(ctx, target, pol, *allowSkew, &pushes)
+		})
+		reportResults(results)
+		docResult = targetResults(results)
+
+		// Point each manifest's load balancer at the deployments of its
+		// targets, which come first in the results, followed by the batch's
+		failed := false
+		offset := 0
+		for _, bm := range append([]*manifest.Manifest{m}, batch...) {
+			n := len(bm.Targets())
+			if err := updateLoadBalancer(ctx, bm, results[offset:offset+n]); err != nil {
+				logging.Error(i18n.T("lb.failed", err))
+				printHints(err)
+				failed = true
+			}
+			offset += n
+		}
+		if err := provider.DeployAllError(results); err != nil {
+			logging.Error(i18n.T("deploy.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		if failed {
+			exit(1)
+		}
+		exit(0)
+	}
+
 	// Create provider
 	p, err := provider.Factory(ctx, m)
 	if err != nil {
-		logging.Errorf("Error creating provider: %v\n", err)
-		os.Exit(1)
+		logging.Error(i18n.T("provider.create_failed", err))
+		printHints(err)
+		exit(1)
+	}
+	opened = p
+
+	// Journal the provider's long-running operations so another invocation can
+	// attach to them, and refuse to start one while another is in progress
+	var journal *state.Operations
+	var inProgress *types.Operation
+	if changesEnvironment(command) || command == "attach" {
+		journal, inProgress, err = journalOperations(ctx, p, m, command)
+		if err != nil {
+			if command == "attach" {
+				logging.Error(i18n.T("attach.failed", err))
+				exit(1)
+			}
+			logging.Warn(i18n.T("operation.journal_failed", err))
+		}
+		if inProgress != nil && command != "attach" {
+			logging.Error(i18n.T("operation.in_progress", inProgress.String()))
+			exit(1)
+		}
 	}
 
 	// Execute command
-	switch *command {
+	switch command {
 	case "deploy":
+		// fail reports a failed deploy, runs the on_failure hooks, and
+		// notifies the failure
+		var deployedVersion string
+		fail := func(key string, err error) {
+			logging.Error(i18n.T(key, err))
+			printHints(err)
+			runFailureHooks(ctx, m, command, err)
+			notifyResult(ctx, m, command, start, nil, deployedVersion, err)
+			exit(1)
+		}
+		if err := runHooks(ctx, m, manifest.HookPreDeploy, command, nil, nil); err != nil {
+			fail("hooks.pre_deploy_failed", err)
+		}
+		if err := secrets.Apply(ctx, m); err != nil {
+			fail("deploy.secrets_failed", err)
+		}
+		if err := new(registryPushes).push(ctx, m); err != nil {
+			fail("deploy.failed", err)
+		}
 		result, err := p.Deploy(ctx, m)
 		if err != nil {
-			logging.Errorf("Deployment failed: %v\n", err)
-			os.Exit(1)
+			fail("deploy.failed", err)
+		}
+		deployedVersion = recordHistory(ctx, m, state.NewRecord(state.CommandDeploy, p.Name(), result))
+		if err := verifyDeployment(ctx, p, m, result.URL); err != nil {
+			fail("verify.failed", err)
+		}
+		docResult = result
+		logging.Info(i18n.T("deploy.success"))
+		logging.Info(i18n.T("summary.application", result.ApplicationName))
+		logging.Info(i18n.T("summary.environment", result.EnvironmentName))
+		logging.Info(i18n.T("summary.url", result.URL))
+		logging.Info(i18n.T("summary.status", result.Status))
+		if err := updateDNS(ctx, m, result.URL); err != nil {
+			fail("dns.failed", err)
+		}
+		if err := updateLoadBalancer(ctx, m, []provider.TargetResult{{Provider: m.Provider.Name, Result: result}}); err != nil {
+			fail("lb.failed", err)
+		}
+		if err := runHooks(ctx, m, manifest.HookPostDeploy, command, result, nil); err != nil {
+			logging.Warn(i18n.T("hooks.failed", err))
+		}
+
+		// Record created resources as infrastructure-as-code if configured
+		if m.Export != nil && m.Export.Path != "" {
+			if err := exportResources(ctx, p, m, m.Export.Format, m.Export.Path); err != nil {
+				logging.Warn(i18n.T("deploy.export_failed", err))
+			} else {
+				logging.Info(i18n.T("deploy.exported", m.Export.Path))
+			}
 		}
-		logging.Info("✓ Deployment successful!")
-		logging.Infof("  Application: %s", result.ApplicationName)
-		logging.Infof("  Environment: %s", result.EnvironmentName)
-		logging.Infof("  URL: %s", result.URL)
-		logging.Infof("  Status: %s", result.Status)
+		notifyResult(ctx, m, command, start, result, deployedVersion, nil)
+
+	case "plan":
+		plan, err := showPlan(ctx, p, m, out)
+		if err != nil {
+			logging.Error(i18n.T("plan.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		docResult = plan
 
 	case "stop":
-		logging.Info("Stopping deployment...")
+		logging.Info(i18n.T("stop.start"))
 		if err := p.Stop(ctx, m); err != nil {
-			logging.Errorf("Stop failed: %v\n", err)
-			os.Exit(1)
+			logging.Error(i18n.T("stop.failed", err))
+			printHints(err)
+			exit(1)
 		}
-		logging.Info("✓ Deployment stopped successfully")
+		logging.Info(i18n.T("stop.success"))
 
 	case "destroy":
-		logging.Info("Destroying deployment...")
+		if *dryRun {
+			plan, err := showDestroyPlan(ctx, p, m, out)
+			if err != nil {
+				logging.Error(i18n.T("destroy.plan_failed", err))
+				printHints(err)
+				exit(1)
+			}
+			docResult = plan
+			break
+		}
+		logging.Info(i18n.T("destroy.start"))
 		if err := p.Destroy(ctx, m); err != nil {
-			logging.Errorf("Destroy failed: %v\n", err)
-			os.Exit(1)
+			logging.Error(i18n.T("destroy.failed", err))
+			printHints(err)
+			notifyResult(ctx, m, command, start, nil, "", err)
+			exit(1)
 		}
-		logging.Info("✓ Deployment destroyed successfully")
+		logging.Info(i18n.T("destroy.success"))
+		if err := removeDNS(ctx, m); err != nil {
+			logging.Error(i18n.T("dns.remove_failed", err))
+			printHints(err)
+			notifyResult(ctx, m, command, start, nil, "", err)
+			exit(1)
+		}
+		notifyResult(ctx, m, command, start, nil, "", nil)
 
 	case "status":
 		status, err := p.Status(ctx, m)
 		if err != nil {
-			logging.Errorf("Failed to get status: %v\n", err)
-			os.Exit(1)
+			logging.Error(i18n.T("status.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		var deps []dependencies.Result
+		if *deep {
+			if deps, err = dependencyHealth(ctx, m); err != nil {
+				logging.Error(i18n.T("status.dependencies_failed", err))
+				printHints(err)
+				exit(1)
+			}
+		}
+		report := &statusOutput{DeploymentStatus: status, Dependencies: deps}
+		docResult = report
+		logging.Info(i18n.T("status.header"))
+		logging.Info(i18n.T("summary.application", status.ApplicationName))
+		logging.Info(i18n.T("summary.environment", status.EnvironmentName))
+		logging.Info(i18n.T("summary.status", status.Status))
+		logging.Info(i18n.T("summary.health", status.Health))
+		if unhealthy := dependencies.Unhealthy(deps); unhealthy > 0 {
+			logging.Warn(i18n.T("summary.dependencies_down", unhealthy, len(deps)))
+		} else if len(deps) > 0 {
+			logging.Info(i18n.T("summary.dependencies", len(deps)))
+		}
+		logging.Info(i18n.T("summary.url", status.URL))
+		logging.Info(i18n.T("summary.last_updated", status.LastUpdated))
+		if len(deps) > 0 {
+			logging.Info(i18n.T("status.dependencies_header"))
+			for _, d := range deps {
+				if d.Healthy {
+					logging.Info("  " + d.String())
+				} else {
+					logging.Warn("  " + d.String())
+				}
+			}
+		}
+		if *deep {
+			window := deepStatusWindow
+			if cl.set["since"] {
+				window = *since
+			}
+			events, err := remediationEvents(ctx, p, m, time.Now().Add(-window))
+			if err != nil {
+				logging.Error(i18n.T("status.deep_failed", err))
+				printHints(err)
+				exit(1)
+			}
+			report.RemediationEvents = events
+			logging.Info(i18n.T("status.remediation_header", window))
+			if len(events) == 0 {
+				logging.Info(i18n.T("status.remediation_none"))
+			}
+			for _, e := range events {
+				args := []any{"kind", e.Kind, "source", e.Source}
+				if e.ExitCode != nil {
+					args = append(args, "exit_code", *e.ExitCode)
+				}
+				logging.Warn("  "+e.String(), args...)
+			}
 		}
-		logging.Info("Deployment Status:")
-		logging.Infof("  Application: %s", status.ApplicationName)
-		logging.Infof("  Environment: %s", status.EnvironmentName)
-		logging.Infof("  Status: %s", status.Status)
-		logging.Infof("  Health: %s", status.Health)
-		logging.Infof("  URL: %s", status.URL)
-		logging.Infof("  Last Updated: %s", status.LastUpdated)
 
 	case "rollback":
-		logging.Info("Rolling back deployment...")
-		result, err := p.Rollback(ctx, m)
+		var result *types.DeploymentResult
+		if *toFlag != "" {
+			logging.Info(i18n.T("rollback.to_start", *toFlag))
+			result, err = rollbackToVersion(ctx, p, m, *toFlag)
+		} else {
+			logging.Info(i18n.T("rollback.start"))
+			result, err = p.Rollback(ctx, m)
+		}
 		if err != nil {
-			logging.Errorf("Rollback failed: %v\n", err)
-			os.Exit(1)
+			logging.Error(i18n.T("rollback.failed", err))
+			printHints(err)
+			runFailureHooks(ctx, m, command, err)
+			notifyResult(ctx, m, command, start, nil, "", err)
+			exit(1)
+		}
+		docResult = result
+		logging.Info(i18n.T("rollback.success"))
+		logging.Info(i18n.T("summary.application", result.ApplicationName))
+		logging.Info(i18n.T("summary.environment", result.EnvironmentName))
+		logging.Info(i18n.T("summary.url", result.URL))
+		logging.Info(i18n.T("summary.status", result.Status))
+		logging.Info(i18n.T("summary.message", result.Message))
+		rec := state.NewRecord(state.CommandRollback, p.Name(), result)
+		rec.RestoredVersion = *toFlag
+		recorded := recordHistory(ctx, m, rec)
+		if err := updateDNS(ctx, m, result.URL); err != nil {
+			logging.Error(i18n.T("dns.failed", err))
+			printHints(err)
+			runFailureHooks(ctx, m, command, err)
+			notifyResult(ctx, m, command, start, result, recorded, err)
+			exit(1)
+		}
+		if err := runHooks(ctx, m, manifest.HookPostRollback, command, result, nil); err != nil {
+			logging.Warn(i18n.T("hooks.failed", err))
+		}
+		notifyResult(ctx, m, command, start, result, recorded, nil)
+
+	case "roll-forward":
+		logging.Info(i18n.T("roll_forward.start"))
+		result, restored, err := rollForward(ctx, p, m)
+		if err != nil {
+			logging.Error(i18n.T("roll_forward.failed", err))
+			printHints(err)
+			runFailureHooks(ctx, m, command, err)
+			notifyResult(ctx, m, command, start, nil, "", err)
+			exit(1)
+		}
+		docResult = result
+		logging.Info(i18n.T("roll_forward.success", restored))
+		logging.Info(i18n.T("summary.application", result.ApplicationName))
+		logging.Info(i18n.T("summary.environment", result.EnvironmentName))
+		logging.Info(i18n.T("summary.url", result.URL))
+		logging.Info(i18n.T("summary.status", result.Status))
+		logging.Info(i18n.T("summary.message", result.Message))
+		rec := state.NewRecord(state.CommandRollForward, p.Name(), result)
+		rec.RestoredVersion = restored
+		recorded := recordHistory(ctx, m, rec)
+		if err := updateDNS(ctx, m, result.URL); err != nil {
+			logging.Error(i18n.T("dns.failed", err))
+			printHints(err)
+			runFailureHooks(ctx, m, command, err)
+			notifyResult(ctx, m, command, start, result, recorded, err)
+			exit(1)
+		}
+		if err := runHooks(ctx, m, manifest.HookPostRollback, command, result, nil); err != nil {
+			logging.Warn(i18n.T("hooks.failed", err))
+		}
+		notifyResult(ctx, m, command, start, result, recorded, nil)
+
+	case "attach":
+		if inProgress == nil {
+			if _, ok := p.(provider.OperationAttacher); !ok {
+				logging.Error(i18n.T("attach.unsupported", p.Name()))
+				exit(1)
+			}
+			logging.Info(i18n.T("attach.none", m.Environment.Name))
+			break
+		}
+		logging.Info(i18n.T("attach.start", inProgress.String()))
+		result, err := attachOperation(ctx, p, m, journal, *inProgress)
+		if err != nil {
+			logging.Error(i18n.T("attach.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		docResult = result
+		logging.Info(i18n.T("attach.success"))
+		logging.Info(i18n.T("summary.application", result.ApplicationName))
+		logging.Info(i18n.T("summary.environment", result.EnvironmentName))
+		logging.Info(i18n.T("summary.url", result.URL))
+		logging.Info(i18n.T("summary.status", result.Status))
+
+	case "scale":
+		logging.Info(i18n.T("scale.start", m.Environment.Name))
+		if err := scaleDeployment(ctx, p, m, *minInstances, *maxInstances); err != nil {
+			logging.Error(i18n.T("scale.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		logging.Info(i18n.T("scale.success"))
+
+	case "maintenance":
+		mode := "off"
+		if maintenanceOn {
+			mode = "on"
+		}
+		logging.Info(i18n.T("maintenance."+mode+"_start", m.Environment.Name))
+		if err := setMaintenance(ctx, p, m, maintenanceOn); err != nil {
+			logging.Error(i18n.T("maintenance.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		logging.Info(i18n.T("maintenance." + mode + "_success"))
+
+	case "logs":
+		opts := types.LogOptions{Since: *since, Follow: *follow, Output: os.Stdout}
+		if err := p.Logs(ctx, m, opts); err != nil {
+			logging.Error(i18n.T("logs.failed", err))
+			printHints(err)
+			exit(1)
+		}
+
+	case "export":
+		format := *exportFormat
+		path := ""
+		if m.Export != nil {
+			if format == "" {
+				format = m.Export.Format
+			}
+			path = m.Export.Path
+		}
+		if err := exportResources(ctx, p, m, format, path); err != nil {
+			logging.Error(i18n.T("export.failed", err))
+			printHints(err)
+			exit(1)
+		}
+
+	case "secrets-sync":
+		if err := syncSecrets(ctx, p, m); err != nil {
+			logging.Error(i18n.T("secrets_sync.failed", err))
+			printHints(err)
+			exit(1)
+		}
+
+	case "credentials-rotate":
+		if err := rotateCredentials(ctx, p, m); err != nil {
+			logging.Error(i18n.T("credentials_rotate.failed", err))
+			printHints(err)
+			exit(1)
+		}
+
+	case "check-cname":
+		checker, ok := p.(provider.CNAMEChecker)
+		if !ok {
+			logging.Error(i18n.T("cname.unsupported", p.Name()))
+			exit(1)
+		}
+		available, fqdn, err := checker.CheckCNAME(ctx, m)
+		if err != nil {
+			logging.Error(i18n.T("cname.failed", err))
+			printHints(err)
+			exit(1)
+		}
+		docResult = cnameOutput{CName: m.Environment.CName, Available: available, FQDN: fqdn}
+		if !available {
+			logging.Error(i18n.T("cname.taken", m.Environment.CName))
+			exit(1)
 		}
-		logging.Info("✓ Rollback successful!")
-		logging.Infof("  Application: %s", result.ApplicationName)
-		logging.Infof("  Environment: %s", result.EnvironmentName)
-		logging.Infof("  URL: %s", result.URL)
-		logging.Infof("  Status: %s", result.Status)
-		logging.Infof("  Message: %s", result.Message)
+		logging.Info(i18n.T("cname.available", m.Environment.CName, fqdn))
 
 	default:
-		logging.Errorf("Unknown command: %s\n", *command)
-		logging.Error("Valid commands: deploy, stop, destroy, status, rollback")
-		os.Exit(1)
+		logging.Error(i18n.T("command.unknown", command))
+		logging.Error(i18n.T("command.valid", strings.Join(commandNames(), ", ")))
+		exit(1)
+	}
+
+	exit(0)
+}
+
+// replayTranscript prints a recorded transcript, pausing between steps as
+// long as the recorded command did, divided by speed.
+func replayTranscript(path string, speed float64) error {
+	if path == "" {
+		return fmt.Errorf("no transcript given (usage: cloud-deploy replay transcript.json)")
+	}
+	t, err := transcript.Load(path)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Println(style.Header(i18n.T("replay.header", t.Command, t.Provider, t.Environment, t.StartedAt.Local().Format(time.DateTime))))
+	if err := transcript.Replay(ctx, os.Stdout, t, speed); err != nil {
+		return err
+	}
+	duration := (time.Duration(t.DurationMS) * time.Millisecond).Round(100 * time.Millisecond)
+	if t.Success {
+		fmt.Println(style.Success(i18n.T("replay.succeeded", duration)))
+	} else {
+		fmt.Println(style.Failure(i18n.T("replay.command_failed", duration)))
+	}
+	return nil
+}
+
+// manifestVerifier returns a verifier that refuses manifests without a valid
+// signature by the public key in keyFile. The signature of the manifest at
+// manifestLocation is read from signatureLocation if set; those of other
+// manifests, and fragments they include, are read from their location with
+// the key's signature suffix (.minisig for minisign keys, .sig for cosign
+// keys).
+func manifestVerifier(keyFile, manifestLocation, signatureLocation string) (manifest.Verifier, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest public key: %w", err)
+	}
+	key, err := signature.ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest public key %s: %w", keyFile, err)
+	}
+
+	return func(location string, data []byte) error {
+		sigLocation := ""
+		if location == manifestLocation {
+			sigLocation = signatureLocation
+		}
+		if sigLocation == "" {
+			if location == "-" {
+				return fmt.Errorf("a manifest read from stdin needs -manifest-signature to be verified")
+			}
+			sigLocation = signatureFor(location, key.Suffix())
+		}
+		sig, err := manifest.Read(sigLocation)
+		if err != nil {
+			return fmt.Errorf("manifest is not signed (no signature at %s): %w", sigLocation, err)
+		}
+		if err := key.Verify(data, sig); err != nil {
+			return fmt.Errorf("manifest signature verification failed: %w", err)
+		}
+		logging.Info(i18n.T("manifest.verified", sigLocation))
+		return nil
+	}, nil
+}
+
+// signatureFor returns the location of a manifest's signature: the manifest's
+// location with suffix added to its path (before any URL query string).
+func signatureFor(location, suffix string) string {
+	if strings.Contains(location, "://") {
+		if u, err := url.Parse(location); err == nil {
+			u.Path += suffix
+			return u.String()
+		}
+	}
+	return location + suffix
+}
+
+// enforcePolicy applies the policy's defaults to m and checks m against its rules.
+func enforcePolicy(pol *policy.Policy, m *manifest.Manifest) error {
+	pol.ApplyDefaults(m)
+	return pol.Check(m)
+}
+
+// deployTarget deploys one provider of a multi-provider manifest: it enforces
+// the policy (if any), runs the pre_deploy hooks, resolves secrets, pushes
+// the image to the registries with pushes, and deploys with a new provider,
+// then runs the post_deploy or on_failure hooks and notifies the result.
+func deployTarget(ctx context.Context, m *manifest.Manifest, pol *policy.Policy, allowSkew bool, pushes *registryPushes) (*types.DeploymentResult, error) {
+	start := time.Now()
+	result, version, err := deployTargetSteps(ctx, m, pol, allowSkew, pushes)
+	if err != nil {
+		runFailureHooks(ctx, m, state.CommandDeploy, err)
+		notifyResult(ctx, m, state.CommandDeploy, start, result, version, err)
+		return result, err
+	}
+	if err := runHooks(ctx, m, manifest.HookPostDeploy, state.CommandDeploy, result, nil); err != nil {
+		logging.Warn(i18n.T("hooks.failed", err))
+	}
+	notifyResult(ctx, m, state.CommandDeploy, start, result, version, nil)
+	return result, nil
+}
+
+// deployTargetSteps runs the steps of deployTarget, from the pre_deploy hooks
+// to the DNS update, and returns the version recorded in the history once
+// deployed.
+func deployTargetSteps(ctx context.Context, m *manifest.Manifest, pol *policy.Policy, allowSkew bool, pushes *registryPushes) (*types.DeploymentResult, string, error) {
+	if pol != nil {
+		if err := enforcePolicy(pol, m); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := checkVersionSkew(ctx, m, allowSkew); err != nil {
+		return nil, "", err
+	}
+	if err := runHooks(ctx, m, manifest.HookPreDeploy, state.CommandDeploy, nil, nil); err != nil {
+		return nil, "", err
+	}
+	if err := secrets.Apply(ctx, m); err != nil {
+		return nil, "", fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+	if err := pushes.push(ctx, m); err != nil {
+		return nil, "", err
+	}
+	p, err := provider.Factory(ctx, m)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create provider: %w", err)
+	}
+	defer closeProvider(p)
+	if _, inProgress, err := journalOperations(ctx, p, m, state.CommandDeploy); err != nil {
+		logging.Warn(i18n.T("operation.journal_failed", err))
+	} else if inProgress != nil {
+		return nil, "", fmt.Errorf("%s is still in progress; wait for it with cloud-deploy attach -provider %s", inProgress, m.Provider.Name)
+	}
+	result, err := p.Deploy(ctx, m)
+	if err != nil {
+		return nil, "", err
+	}
+	version := recordHistory(ctx, m, state.NewRecord(state.CommandDeploy, p.Name(), result))
+	if err := verifyDeployment(ctx, p, m, result.URL); err != nil {
+		return nil, version, fmt.Errorf("verification failed: %w", err)
+	}
+	if err := updateDNS(ctx, m, result.URL); err != nil {
+		return result, version, err
+	}
+	return result, version, nil
+}
+
+// registryPushes pushes the images of manifests to their registries, such as
+// Docker Hub or a self-hosted Harbor, before any provider deploys them. An
+// image is pushed once to the same references, however many targets deploy
+// it, and the targets share the result of that push. The zero value is ready
+// to use.
+type registryPushes struct {
+	mu     sync.Mutex
+	pushes map[string]*registryPush
+}
+
+// registryPush is one push of an image to its references.
+type registryPush struct {
+	once sync.Once
+	err  error
+}
+
+// push pushes m's image to m's registries, or waits for the push of the same
+// image to the same references to finish and returns its error.
+func (ps *registryPushes) push(ctx context.Context, m *manifest.Manifest) error {
+	if len(m.Registries) == 0 {
+		return nil
+	}
+	distributor := registry.NewDistributor(m.Image, m.Platforms...)
+	refs := []string{m.Image}
+	for _, c := range m.Registries {
+		r, err := newRegistry(c, m)
+		if err != nil {
+			return err
+		}
+		distributor.AddRegistry(r)
+		refs = append(refs, r.GetImageReference())
+	}
+	key := strings.Join(refs, " ")
+
+	ps.mu.Lock()
+	if ps.pushes == nil {
+		ps.pushes = make(map[string]*registryPush)
+	}
+	push, ok := ps.pushes[key]
+	if !ok {
+		push = &registryPush{}
+		ps.pushes[key] = push
+	}
+	ps.mu.Unlock()
+
+	push.once.Do(func() {
+		if _, err := distributor.Distribute(ctx); err != nil {
+			push.err = fmt.Errorf("failed to push image to registries: %w", err)
+		}
+	})
+	return push.err
+}
+
+// newRegistry returns the registry c describes, for the manifest's image.
+func newRegistry(c manifest.RegistryConfig, m *manifest.Manifest) (registry.Registry, error) {
+	repository := c.Repository
+	if repository == "" {
+		repository = m.RepositoryName()
+	}
+	tag := c.Tag
+	if tag == "" {
+		tag = m.ImageTag(registry.ImageTag(m.Image), "")
+	}
+	switch c.RegistryType() {
+	case manifest.RegistryDockerHub:
+		return registry.NewDockerHubRegistry(c.Namespace, repository, tag, c.Username, c.Password)
+	case manifest.RegistryGHCR:
+		return registry.NewGHCRRegistry(c.Namespace, repository, tag, c.Username, c.Password)
+	default:
+		return registry.NewGenericRegistry(c.URL, repository, tag, c.Username, c.Password, c.Insecure)
+	}
+}
+
+// verifyDeployment runs the manifest's verification checks against the
+// deployment at url. When any fails, the deployment is rolled back and the
+// failure returned.
+func verifyDeployment(ctx context.Context, p provider.Provider, m *manifest.Manifest, url string) error {
+	if m.Verification == nil {
+		return nil
+	}
+	logging.Info(i18n.T("verify.start", len(m.Verification.Checks)))
+	results := verification.Run(ctx, url, m.Verification.Checks)
+	for _, r := range results {
+		if r.Passed {
+			logging.Info("  " + r.String())
+		} else {
+			logging.Warn("  " + r.String())
+		}
+	}
+	failed := verification.Failed(results)
+	if failed == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%d of %d checks failed", failed, len(results))
+	logging.Warn(i18n.T("verify.rolling_back"))
+	rolledBack, rollbackErr := p.Rollback(ctx, m)
+	if rollbackErr != nil {
+		return fmt.Errorf("%w, and rolling back failed: %v", err, rollbackErr)
+	}
+	recordHistory(ctx, m, state.NewRecord(state.CommandRollback, p.Name(), rolledBack))
+	if hookErr := runHooks(ctx, m, manifest.HookPostRollback, state.CommandDeploy, rolledBack, nil); hookErr != nil {
+		logging.Warn(i18n.T("hooks.failed", hookErr))
+	}
+	return fmt.Errorf("%w; rolled back: %s", err, rolledBack.Message)
+}
+
+// runHooks runs the manifest's hooks of event for command, with the
+// deployment's metadata and, once deployed, its result or failure.
+func runHooks(ctx context.Context, m *manifest.Manifest, event, command string, result *types.DeploymentResult, cause error) error {
+	hs := m.Hooks.For(event)
+	if len(hs) == 0 {
+		return nil
+	}
+	md := hooks.Metadata{
+		Event:       event,
+		Command:     command,
+		Provider:    m.Provider.Name,
+		Region:      m.Provider.Region,
+		Application: m.Application.Name,
+		Environment: m.Environment.Name,
+		Image:       m.Image,
+	}
+	if result != nil {
+		md.URL = result.URL
+		md.Status = result.Status
+		if image := result.Images[m.Application.Name]; image != "" {
+			md.Image = image
+		}
+	}
+	if cause != nil {
+		md.Error = cause.Error()
+	}
+	return hooks.Run(ctx, hs, md)
+}
+
+// notifyResult posts the result of command, which started at start, to the
+// manifest's notification channels, with the version recorded in the history
+// (if any). Failing to post is only logged.
+func notifyResult(ctx context.Context, m *manifest.Manifest, command string, start time.Time, result *types.DeploymentResult, version string, cause error) {
+	if m.Notifications == nil {
+		return
+	}
+	r := notify.Result{
+		Command:     command,
+		Provider:    m.Provider.Name,
+		Region:      m.Provider.Region,
+		Application: m.Application.Name,
+		Environment: m.Environment.Name,
+		Version:     version,
+		Image:       m.Image,
+		Succeeded:   cause == nil,
+		Duration:    time.Since(start),
+	}
+	if result != nil {
+		r.URL = result.URL
+		r.Status = result.Status
+		if image := result.Images[m.Application.Name]; image != "" {
+			r.Image = image
+		}
+	}
+	if cause != nil {
+		r.Error = cause.Error()
+	}
+	// SES email is sent from the deployment's region on AWS
+	var sesRegion string
+	if m.Provider.Name == "aws" {
+		sesRegion = m.Provider.Region
+	}
+	if err := notify.Send(ctx, m.Notifications, sesRegion, r); err != nil {
+		logging.Warn(i18n.T("notify.failed", err))
+	}
+}
+
+// runFailureHooks runs the on_failure hooks after command failed with err. A
+// failing hook is only logged, so the command's own failure is reported.
+func runFailureHooks(ctx context.Context, m *manifest.Manifest, command string, err error) {
+	if hookErr := runHooks(ctx, m, manifest.HookOnFailure, command, nil, err); hookErr != nil {
+		logging.Warn(i18n.T("hooks.failed", hookErr))
+	}
+}
+
+// manifestVariables returns the values of manifest variables given with
+// -var-file and -var, in that order, so later values override earlier ones.
+func manifestVariables(files, vars []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, file := range files {
+		fileVars, err := manifest.ReadVariables(file)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(values, fileVars)
+	}
+	for _, v := range vars {
+		name, value, err := manifest.ParseVariable(v)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// loadBatch loads the manifests deployed together with m, with the options
+// m was loaded with. Each manifest is verified against the signature next to
+// it, and no two may deploy the same environment with the same provider.
+func loadBatch(m *manifest.Manifest, locations []string, opts manifest.Options, signatureLocation string) ([]*manifest.Manifest, error) {
+	if opts.Verify != nil && signatureLocation != "" {
+		return nil, fmt.Errorf("-manifest-signature names the signature of one manifest; a batch is verified against the signature next to each manifest")
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range m.Targets() {
+		seen[target.Provider.Name+"/"+target.Environment.Name] = true
+	}
+	batch := make([]*manifest.Manifest, 0, len(locations))
+	for _, location := range locations {
+		bm, err := manifest.LoadWith(location, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", location, err)
+		}
+		for _, target := range bm.Targets() {
+			key := target.Provider.Name + "/" + target.Environment.Name
+			if seen[key] {
+				return nil, fmt.Errorf("%s: environment %s on %s is already deployed by another manifest of the batch", location, target.Environment.Name, target.Provider.Name)
+			}
+			seen[key] = true
+		}
+		batch = append(batch, bm)
+	}
+	return batch, nil
+}
+
+// deploySchedule returns the concurrency limits and environment priorities
+// of the user configuration for deploying several targets.
+func deploySchedule() (provider.Schedule, error) {
+	cfg, err := userconfig.Load()
+	if err != nil {
+		return provider.Schedule{}, err
+	}
+	concurrency := cfg.Concurrency
+	return provider.Schedule{
+		MaxParallel:    concurrency.MaxParallel,
+		ProviderLimits: concurrency.Providers,
+		Priority: func(m *manifest.Manifest) int {
+			return concurrency.Priority(m.Environment.Class)
+		},
+	}, nil
+}
+
+// planTarget writes the plan for one provider of a multi-provider manifest to
+// w, after enforcing the policy (if any), and returns it.
+func planTarget(ctx context.Context, m *manifest.Manifest, pol *policy.Policy, w io.Writer) (*types.Plan, error) {
+	if pol != nil {
+		if err := enforcePolicy(pol, m); err != nil {
+			return nil, err
+		}
+	}
+	p, err := provider.Factory(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	defer closeProvider(p)
+	return showPlan(ctx, p, m, w)
+}
+
+// showPlan writes the changes deploying m with p would make to w, and returns
+// them.
+func showPlan(ctx context.Context, p provider.Provider, m *manifest.Manifest, w io.Writer) (*types.Plan, error) {
+	planner, ok := p.(provider.Planner)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support planning deployments", p.Name())
+	}
+	plan, err := planner.Plan(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return plan, writePlan(w, plan)
+}
+
+// planMarkers prefix each change of a plan, in the style of a diff.
+var planMarkers = map[types.PlanAction]string{
+	types.PlanCreate:   "+",
+	types.PlanUpdate:   "~",
+	types.PlanDelete:   "-",
+	types.PlanNoChange: "=",
+}
+
+// writePlan writes a plan as one line per change followed by a summary.
+func writePlan(w io.Writer, plan *types.Plan) error {
+	if _, err := fmt.Fprintln(w, style.Header(i18n.T("plan.header", plan.Provider))); err != nil {
+		return err
+	}
+	if err := writePlanChanges(w, plan); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w, i18n.T("plan.summary",
+		plan.Count(types.PlanCreate), plan.Count(types.PlanUpdate), plan.Count(types.PlanDelete), plan.Count(types.PlanNoChange)))
+	return err
+}
+
+// writePlanChanges writes the changes of a plan, one per line.
+func writePlanChanges(w io.Writer, plan *types.Plan) error {
+	for _, c := range plan.Changes {
+		line := fmt.Sprintf("  %s %-9s %s %s", planMarkers[c.Action], c.Action, c.Type, c.Name)
+		if c.Reason != "" {
+			line += " (" + c.Reason + ")"
+		}
+		switch c.Action {
+		case types.PlanCreate:
+			line = style.Success(line)
+		case types.PlanUpdate:
+			line = style.Warning(line)
+		case types.PlanDelete:
+			line = style.Failure(line)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showDestroyPlan writes what destroying m's deployment with p would delete
+// and keep, and what depends on the deleted resources, to w, and returns it.
+// Nothing is deleted.
+func showDestroyPlan(ctx context.Context, p provider.Provider, m *manifest.Manifest, w io.Writer) (*types.Plan, error) {
+	planner, ok := p.(provider.DestroyPlanner)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support destroy -dry-run", p.Name())
+	}
+	plan, err := planner.PlanDestroy(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if err := addDNSDeletions(ctx, m, plan); err != nil {
+		logging.Warn(i18n.T("inventory.dns_failed", err))
+	}
+	return plan, writeDestroyPlan(w, plan)
+}
+
+// addDNSDeletions adds the existing Cloudflare DNS records the manifest
+// manages, which destroy removes after deleting the environment.
+func addDNSDeletions(ctx context.Context, m *manifest.Manifest, plan *types.Plan) error {
+	inventory := &types.Inventory{}
+	if err := addDNSInventory(ctx, m, inventory); err != nil {
+		return err
+	}
+	for _, r := range inventory.Resources {
+		plan.Add(types.PlanDelete, r.Type, r.Name, "managed by cloudflare.dns_records")
+	}
+	return nil
+}
+
+// writeDestroyPlan writes a destroy plan as one line per resource, the
+// resources depending on those deleted, and a summary.
+func writeDestroyPlan(w io.Writer, plan *types.Plan) error {
+	if _, err := fmt.Fprintln(w, style.Header(i18n.T("destroy.plan_header", plan.Provider))); err != nil {
+		return err
+	}
+	if err := writePlanChanges(w, plan); err != nil {
+		return err
+	}
+	if len(plan.Dependents) > 0 {
+		if _, err := fmt.Fprintln(w, style.Header(i18n.T("destroy.plan_dependents"))); err != nil {
+			return err
+		}
+		for _, d := range plan.Dependents {
+			if _, err := fmt.Fprintln(w, style.Warning(i18n.T("destroy.plan_dependent", d.Type, d.Name, d.Resource))); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, i18n.T("destroy.plan_summary",
+		plan.Count(types.PlanDelete), plan.Count(types.PlanNoChange), len(plan.Dependents)))
+	return err
+}
+
+// postureTarget checks the account m deploys to, writing its posture to w.
+func postureTarget(ctx context.Context, m *manifest.Manifest, w io.Writer) (*types.Posture, error) {
+	p, err := provider.Factory(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	defer closeProvider(p)
+	return showPosture(ctx, p, m, w)
+}
+
+// showPosture writes the posture of the account p deploys m to to w, and
+// returns it.
+func showPosture(ctx context.Context, p provider.Provider, m *manifest.Manifest, w io.Writer) (*types.Posture, error) {
+	reporter, ok := p.(provider.PostureReporter)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support posture checks", p.Name())
+	}
+	posture, err := reporter.Posture(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return posture, writePosture(w, posture)
+}
+
+// postureHealthy reports whether no check of posture failed; warnings alone
+// are healthy.
+func postureHealthy(posture *types.Posture) bool {
+	return posture.Count(types.PostureFailing) == 0
+}
+
+// postureMarkers prefix each check of a posture.
+var postureMarkers = map[types.PostureStatus]string{
+	types.PostureOK:      "✓",
+	types.PostureWarning: "!",
+	types.PostureFailing: "✗",
+}
+
+// writePosture writes a posture as one line per check followed by a summary.
+func writePosture(w io.Writer, posture *types.Posture) error {
+	if _, err := fmt.Fprintln(w, style.Header(i18n.T("posture.header", posture.Provider))); err != nil {
+		return err
+	}
+	for _, c := range posture.Checks {
+		line := fmt.Sprintf("  %s %-7s %-11s %s", postureMarkers[c.Status], c.Status, c.Category, c.Name)
+		if c.Detail != "" {
+			line += " (" + c.Detail + ")"
+		}
+		switch c.Status {
+		case types.PostureOK:
+			line = style.Success(line)
+		case types.PostureWarning:
+			line = style.Warning(line)
+		case types.PostureFailing:
+			line = style.Failure(line)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, i18n.T("posture.summary",
+		posture.Count(types.PostureOK), posture.Count(types.PostureWarning), posture.Count(types.PostureFailing)))
+	return err
+}
+
+// imageFindings checks the syntax of the image references of m.
+func imageFindings(m *manifest.Manifest) []types.ValidationFinding {
+	var findings []types.ValidationFinding
+	check := func(field, image string) {
+		if image == "" {
+			return
+		}
+		if err := registry.ValidateReference(image); err != nil {
+			findings = append(findings, types.ValidationFinding{Field: field, Message: err.Error()})
+		}
+	}
+	check("image", m.Image)
+	for i, c := range m.Containers {
+		check(fmt.Sprintf("containers[%d].image", i), c.Image)
+	}
+	for i, s := range m.Services {
+		check(fmt.Sprintf("services[%d].image", i), s.Image)
+	}
+	return findings
+}
+
+// validateTarget checks m against the constraints of its provider. Providers
+// without checks of their own have no findings.
+func validateTarget(ctx context.Context, m *manifest.Manifest) ([]types.ValidationFinding, error) {
+	p, err := provider.Factory(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	defer closeProvider(p)
+	validator, ok := p.(provider.Validator)
+	if !ok {
+		logging.Info(i18n.T("validate.unsupported", p.Name()))
+		return nil, nil
+	}
+	return validator.Validate(ctx, m)
+}
+
+// writeFindings writes validation findings, one per line, followed by a
+// summary.
+func writeFindings(w io.Writer, findings []types.ValidationFinding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, style.Success(i18n.T("validate.ok")))
+		return err
+	}
+	for _, f := range findings {
+		field := f.Field
+		if f.Provider != "" {
+			field = f.Provider + ": " + field
+		}
+		if _, err := fmt.Fprintln(w, style.Failure(fmt.Sprintf("  ✗ %s: %s", field, f.Message))); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, i18n.T("validate.summary", len(findings)))
+	return err
+}
+
+// inventoryTarget lists the resources of m's application on its provider to w.
+func inventoryTarget(ctx context.Context, m *manifest.Manifest, w io.Writer) (*types.Inventory, error) {
+	p, err := provider.Factory(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	defer closeProvider(p)
+	return showInventory(ctx, p, m, w)
+}
+
+// showInventory writes the resources p created for m's application to w,
+// together with the manifest's Cloudflare DNS records and the images recorded
+// in the environment's deployment history, and returns them.
+func showInventory(ctx context.Context, p provider.Provider, m *manifest.Manifest, w io.Writer) (*types.Inventory, error) {
+	reporter, ok := p.(provider.InventoryReporter)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support inventory", p.Name())
+	}
+	inventory, err := reporter.Inventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if err := addDNSInventory(ctx, m, inventory); err != nil {
+		logging.Warn(i18n.T("inventory.dns_failed", err))
+	}
+	if err := addHistoryInventory(ctx, m, p.Name(), inventory); err != nil {
+		logging.Warn(i18n.T("inventory.history_failed", err))
+	}
+	return inventory, writeInventory(w, inventory)
+}
+
+// cleanupTarget prunes the images and application versions of m's application
+// on its provider that policy selects, and writes what was deleted to w.
+func cleanupTarget(ctx context.Context, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool, w io.Writer) (*types.Cleanup, error) {
+	p, err := provider.Factory(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	defer closeProvider(p)
+	return prune(ctx, p, m, policy, dryRun, w)
+}
+
+// prune deletes the images and application versions of m's application that
+// policy selects, or only lists them with dryRun, and writes them to w. What
+// was deleted before a failure is returned with the error.
+func prune(ctx context.Context, p provider.Provider, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool, w io.Writer) (*types.Cleanup, error) {
+	pruner, ok := p.(provider.Pruner)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support cleanup", p.Name())
+	}
+	cleanup, err := pruner.Prune(ctx, m, policy, dryRun)
+	if cleanup == nil {
+		return nil, err
+	}
+	if werr := writeCleanup(w, cleanup); err == nil {
+		err = werr
+	}
+	return cleanup, err
+}
+
+// writeCleanup writes the resources a cleanup deleted as a table, one
+// resource per row, followed by how many were deleted and kept.
+func writeCleanup(w io.Writer, cleanup *types.Cleanup) error {
+	if _, err := fmt.Fprintln(w, style.Header(i18n.T("cleanup.header", cleanup.Provider))); err != nil {
+		return err
+	}
+	if len(cleanup.Deleted) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, i18n.T("cleanup.columns"))
+		for _, r := range cleanup.Deleted {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Type, r.Name, r.Created.Local().Format(time.DateTime))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	summary := "cleanup.summary"
+	if cleanup.DryRun {
+		summary = "cleanup.dry_run_summary"
+	}
+	_, err := fmt.Fprintln(w, i18n.T(summary, len(cleanup.Deleted), cleanup.Kept))
+	return err
+}
+
+// addDNSInventory adds the existing Cloudflare DNS records the manifest manages.
+func addDNSInventory(ctx context.Context, m *manifest.Manifest, inventory *types.Inventory) error {
+	if m.Cloudflare == nil || len(m.Cloudflare.DNSRecords) == 0 {
+		return nil
+	}
+	creds, err := (&credentials.Manager{Source: "environment"}).GetCredentials(ctx, "cloudflare")
+	if err != nil {
+		return fmt.Errorf("%w: set CLOUDFLARE_API_TOKEN to list cloudflare.dns_records", err)
+	}
+	records, err := newCloudflareClient(creds.Cloudflare.APIToken).FindDNSRecords(ctx, m.Cloudflare)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		inventory.Add("Cloudflare "+rec.Type+" record", rec.Name, rec.ID, types.InventoryByName)
+	}
+	return nil
+}
+
+// addHistoryInventory adds the images deployed to m's environment on the
+// named provider, as recorded in the deployment history.
+func addHistoryInventory(ctx context.Context, m *manifest.Manifest, providerName string, inventory *types.Inventory) error {
+	store, err := state.Open(ctx, m)
+	if err != nil {
+		return err
+	}
+	records, err := store.History(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if rec.Provider != providerName {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(rec.Images)) {
+			inventory.Add("Container image", name, rec.Images[name], types.InventoryByState)
+		}
+	}
+	return nil
+}
+
+// writeInventory writes an inventory as a table, one resource per row.
+func writeInventory(w io.Writer, inventory *types.Inventory) error {
+	if _, err := fmt.Fprintln(w, style.Header(i18n.T("inventory.header", inventory.Provider))); err != nil {
+		return err
+	}
+	if len(inventory.Resources) == 0 {
+		_, err := fmt.Fprintln(w, i18n.T("inventory.empty"))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("inventory.columns"))
+	for _, r := range inventory.Resources {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Type, r.Name, r.ID, r.Source)
+	}
+	return tw.Flush()
+}
+
+// rollbackToVersion redeploys the images recorded for a version of the
+// deployment history.
+func rollbackToVersion(ctx context.Context, p provider.Provider, m *manifest.Manifest, version string) (*types.DeploymentResult, error) {
+	store, err := state.Open(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := store.Find(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	target, err := withImages(m, rec.Images)
+	if err != nil {
+		return nil, fmt.Errorf("cannot roll back to %s: %w", rec.Version, err)
+	}
+	return deployRecorded(ctx, p, target)
+}
+
+// rollForward redeploys the newest version recorded after the one the last
+// rollback restored, skipping versions that were rolled back. It returns the
+// version redeployed with the result.
+func rollForward(ctx context.Context, p provider.Provider, m *manifest.Manifest) (*types.DeploymentResult, string, error) {
+	store, err := state.Open(ctx, m)
+	if err != nil {
+		return nil, "", err
+	}
+	rec, err := store.RollForwardTarget(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	logging.Info(i18n.T("roll_forward.target", rec.Version))
+	target, err := withImages(m, rec.Images)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot roll forward to %s: %w", rec.Version, err)
+	}
+	result, err := deployRecorded(ctx, p, target)
+	return result, rec.Version, err
+}
+
+// deployRecorded resolves the secrets of target, a copy of the manifest with
+// recorded images, and deploys it.
+func deployRecorded(ctx context.Context, p provider.Provider, target *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := secrets.Apply(ctx, target); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+	return p.Deploy(ctx, target)
+}
+
+// withImages returns a copy of m that deploys images, keyed by container name.
+func withImages(m *manifest.Manifest, images map[string]string) (*manifest.Manifest, error) {
+	target := *m
+	if !m.IsMultiContainer() {
+		image, ok := images[m.Application.Name]
+		if !ok {
+			return nil, fmt.Errorf("no image recorded for %s", m.Application.Name)
+		}
+		target.Image = image
+		return &target, nil
+	}
+
+	target.Containers = slices.Clone(m.Containers)
+	for i, c := range target.Containers {
+		image, ok := images[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("no image recorded for container %s", c.Name)
+		}
+		target.Containers[i].Image = image
+	}
+	return &target, nil
+}
+
+// recordHistory adds a deployment to the environment's history and returns
+// its version label. The deployment has already happened, so failing to record
+// it only warns, and returns no version.
+func recordHistory(ctx context.Context, m *manifest.Manifest, rec state.Record) string {
+	rec.ToolVersion = version
+	store, err := state.Open(ctx, m)
+	if err == nil {
+		rec, err = store.Record(ctx, rec)
+	}
+	if err != nil {
+		logging.Warn(i18n.T("history.record_failed", err))
+		return ""
+	}
+	logging.Info(i18n.T("history.recorded", rec.Version))
+	return rec.Version
+}
+
+// changesEnvironment reports whether command changes the deployed environment.
+func changesEnvironment(command string) bool {
+	switch command {
+	case "deploy", "rollback", "roll-forward", "stop", "destroy", "scale", "maintenance":
+		return true
+	}
+	return false
+}
+
+// readOnlyMode reports whether read-only mode is on: with -read-only, or
+// read_only in the user configuration.
+func readOnlyMode(flagSet bool) (bool, error) {
+	if flagSet {
+		return true, nil
+	}
+	cfg, err := userconfig.Load()
+	if err != nil {
+		return false, err
+	}
+	return cfg.ReadOnly, nil
+}
+
+// readOnlyCommand reports whether command only reads, and may run in
+// read-only mode.
+func readOnlyCommand(command string, dryRun bool) bool {
+	switch command {
+	case "plan", "status", "logs", "history", "export", "check-cname", "iam-policy", "posture", "validate", "manifest", "inventory", "stats", "replay":
+		return true
+	case "destroy", "cleanup":
+		return dryRun
+	}
+	return false
+}
+
+// journalOperations makes p journal its long-running operations in the state
+// backend, if it can, and returns the journal with the operation another
+// invocation left in progress (nil if none). Providers that cannot journal
+// return a nil journal.
+func journalOperations(ctx context.Context, p provider.Provider, m *manifest.Manifest, command string) (*state.Operations, *types.Operation, error) {
+	attacher, ok := p.(provider.OperationAttacher)
+	if !ok {
+		return nil, nil, nil
+	}
+	journal, err := state.OpenOperations(ctx, m, command, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := journal.Current(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	attacher.SetOperationJournal(journal)
+	return journal, current, nil
+}
+
+// attachOperation waits for op, left in progress by another invocation, to
+// finish. Deploys and rollbacks it finishes are recorded in the history, as
+// the invocation that started them would have. An operation that cannot be
+// attached to is ended in the journal, so it does not block the environment.
+func attachOperation(ctx context.Context, p provider.Provider, m *manifest.Manifest, journal *state.Operations, op types.Operation) (*types.DeploymentResult, error) {
+	result, err := p.(provider.OperationAttacher).Attach(ctx, m, op)
+	if err != nil {
+		if ctx.Err() == nil {
+			if endErr := journal.End(ctx); endErr != nil {
+				logging.Warn(i18n.T("operation.journal_failed", endErr))
+			}
+		}
+		return nil, err
+	}
+
+	switch op.Command {
+	case state.CommandDeploy, state.CommandRollback, state.CommandRollForward:
+		recordHistory(ctx, m, state.NewRecord(op.Command, p.Name(), result))
+	}
+	return result, nil
+}
+
+// checkVersionSkew compares the version of cloud-deploy that last deployed m's
+// environment, from its history, with this one. A different major version may
+// manage the environment's resources differently, so it fails unless allowed;
+// smaller differences only warn.
+func checkVersionSkew(ctx context.Context, m *manifest.Manifest, allow bool) error {
+	store, err := state.Open(ctx, m)
+	recorded := ""
+	if err == nil {
+		recorded, err = store.LastToolVersion(ctx)
+	}
+	if err != nil {
+		// The history is not needed to change the environment
+		logging.Warn(i18n.T("version_skew.check_failed", err))
+		return nil
+	}
+
+	switch state.VersionSkew(recorded, version) {
+	case state.SkewMajor:
+		if !allow {
+			return fmt.Errorf("environment %s was last deployed with cloud-deploy %s, which may manage its resources incompatibly with %s; use cloud-deploy %s or pass -allow-version-skew",
+				m.Environment.Name, recorded, version, recorded)
+		}
+		logging.Warn(i18n.T("version_skew.warning", m.Environment.Name, recorded, version))
+	case state.SkewMinor:
+		logging.Warn(i18n.T("version_skew.warning", m.Environment.Name, recorded, version))
+	}
+	return nil
+}
+
+// newCloudflareClient creates the client DNS records are managed with.
+var newCloudflareClient = cloudflare.New
+
+// updateDNS points the manifest's Cloudflare DNS records at the deployment at
+// deploymentURL, authenticating with CLOUDFLARE_API_TOKEN.
+func updateDNS(ctx context.Context, m *manifest.Manifest, deploymentURL string) error {
+	if m.Cloudflare == nil || len(m.Cloudflare.DNSRecords) == 0 {
+		return nil
+	}
+	creds, err := (&credentials.Manager{Source: "environment"}).GetCredentials(ctx, "cloudflare")
+	if err != nil {
+		return fmt.Errorf("%w: set CLOUDFLARE_API_TOKEN to manage cloudflare.dns_records", err)
+	}
+
+	changes, err := newCloudflareClient(creds.Cloudflare.APIToken).SyncDNSRecords(ctx, m.Cloudflare, deploymentURL)
+	for _, change := range changes {
+		logging.Info(i18n.T("dns."+change.Action, change.Record.Type, change.Record.Name, change.Record.Content))
+	}
+	return err
+}
+
+// removeDNS deletes the manifest's Cloudflare DNS records, which would
+// otherwise point at a destroyed environment, authenticating with
+// CLOUDFLARE_API_TOKEN.
+func removeDNS(ctx context.Context, m *manifest.Manifest) error {
+	if m.Cloudflare == nil || len(m.Cloudflare.DNSRecords) == 0 {
+		return nil
+	}
+	creds, err := (&credentials.Manager{Source: "environment"}).GetCredentials(ctx, "cloudflare")
+	if err != nil {
+		return fmt.Errorf("%w: set CLOUDFLARE_API_TOKEN to remove cloudflare.dns_records", err)
+	}
+
+	removed, err := newCloudflareClient(creds.Cloudflare.APIToken).RemoveDNSRecords(ctx, m.Cloudflare)
+	for _, record := range removed {
+		logging.Info(i18n.T("dns.removed", record.Type, record.Name, record.Content))
+	}
+	return err
+}
+
+// updateLoadBalancer points the manifest's Cloudflare load balancer at the
+// deployments of results, the outcomes of deploying m's targets,
+// authenticating with CLOUDFLARE_API_TOKEN. Nothing is changed when no target
+// deployed.
+func updateLoadBalancer(ctx context.Context, m *manifest.Manifest, results []provider.TargetResult) error {
+	if m.Cloudflare == nil || m.Cloudflare.LoadBalancer == nil {
+		return nil
+	}
+	deployments := make(map[string]string)
+	for _, r := range results {
+		if r.Err == nil && r.Result != nil {
+			deployments[r.Provider] = r.Result.URL
+		}
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+	creds, err := (&credentials.Manager{Source: "environment"}).GetCredentials(ctx, "cloudflare")
+	if err != nil {
+		return fmt.Errorf("%w: set CLOUDFLARE_API_TOKEN to manage cloudflare.load_balancer", err)
+	}
+
+	var providers []string
+	for _, target := range m.Targets() {
+		providers = append(providers, target.Provider.Name)
+	}
+	changes, err := newCloudflareClient(creds.Cloudflare.APIToken).SyncLoadBalancer(ctx, m.Cloudflare, providers, deployments)
+	for _, change := range changes {
+		logging.Info(i18n.T("lb."+change.Kind+"_"+change.Action, change.Name))
+	}
+	return err
+}
+
+// showHistory writes the recorded deployments of m's environment to w, and
+// returns them.
+func showHistory(ctx context.Context, m *manifest.Manifest, w io.Writer) ([]state.Record, error) {
+	store, err := state.Open(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	records, err := store.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, i18n.T("history.empty", m.Environment.Name))
+		return []state.Record{}, err
+	}
+	return records, writeHistory(w, records)
+}
+
+// writeHistory writes deployment records as a table, oldest first.
+func writeHistory(w io.Writer, records []state.Record) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("history.header"))
+	for _, rec := range records {
+		command := rec.Command
+		switch {
+		case rec.Command == state.CommandRollForward:
+			command += " to " + rec.RestoredVersion
+		case rec.RestoredVersion != "":
+			command += " -to " + rec.RestoredVersion
+		}
+		images := make([]string, 0, len(rec.Images))
+		for _, name := range slices.Sorted(maps.Keys(rec.Images)) {
+			images = append(images, rec.Images[name])
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rec.Version, command, rec.Timestamp.Local().Format(time.DateTime),
+			rec.Status, strings.Join(images, ", "), rec.URL)
+	}
+	return tw.Flush()
+}
+
+// reportResults prints the outcome of a multi-provider or batch deployment for
+// each target.
+func reportResults(results []provider.TargetResult) {
+	for _, r := range results {
+		if r.Err != nil {
+			logging.Error(i18n.T("deploy.provider_failed", r.Target, r.Err))
+			printHints(r.Err)
+			continue
+		}
+		logging.Info(i18n.T("deploy.provider_success", r.Target))
+		logging.Info(i18n.T("summary.environment", r.Result.EnvironmentName))
+		logging.Info(i18n.T("summary.url", r.Result.URL))
+		logging.Info(i18n.T("summary.status", r.Result.Status))
+	}
+}
+
+// targetOutput is the result of deploying to one target of a multi-provider
+// or batch deploy in -output json and yaml.
+type targetOutput struct {
+	Provider string                  `json:"provider"`
+	Target   string                  `json:"target"`
+	Result   *types.DeploymentResult `json:"result,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// targetResults returns the results of a multi-provider or batch deploy for
+// -output json and yaml.
+func targetResults(results []provider.TargetResult) []targetOutput {
+	outputs := make([]targetOutput, len(results))
+	for i, r := range results {
+		outputs[i] = targetOutput{Provider: r.Provider, Target: r.Target, Result: r.Result}
+		if r.Err != nil {
+			outputs[i].Error = r.Err.Error()
+		}
+	}
+	return outputs
+}
+
+// statusOutput is the result of the status command in -output json and yaml.
+type statusOutput struct {
+	*types.DeploymentStatus
+
+	// With -deep only
+	Dependencies      []dependencies.Result    `json:"dependencies,omitempty"`
+	RemediationEvents []types.RemediationEvent `json:"remediation_events,omitempty"`
+}
+
+// cnameOutput is the result of the check-cname command in -output json and
+// yaml.
+type cnameOutput struct {
+	CName     string `json:"cname"`
+	Available bool   `json:"available"`
+	FQDN      string `json:"fqdn,omitempty"`
+}
+
+// writesDocument reports whether command writes a document for -output json
+// and yaml. The others write their own output: logs, exported templates, IAM
+// policies, statistics, and transcripts.
+func writesDocument(command string) bool {
+	switch command {
+	case "logs", "export", "iam-policy", "manifest", "stats", "replay":
+		return false
+	}
+	return true
+}
+
+// closeProvider releases the connections p holds. Failing to only warns.
+func closeProvider(p provider.Provider) {
+	if err := p.Close(); err != nil {
+		logging.Warn(i18n.T("provider.close_failed", err))
+	}
+}
+
+// printHints prints remediation steps for err beneath its error message.
+func printHints(err error) {
+	for _, hint := range errhints.For(err) {
+		logging.Info(i18n.T("hint", hint))
+	}
+}
+
+// exportResources renders the provider's resources for m in the given format and
+// writes them to path, or to stdout when path is empty.
+func exportResources(ctx context.Context, p provider.Provider, m *manifest.Manifest, format, path string) error {
+	exporter, ok := p.(provider.Exporter)
+	if !ok {
+		return fmt.Errorf("provider %s does not support exporting resources", p.Name())
+	}
+	if format == "" {
+		return fmt.Errorf("export format is required (set export.format in the manifest or use -export-format)")
+	}
+
+	data, err := exporter.Export(ctx, m, format)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// buildImage builds the manifest's source and deploys the result as its
+// image. With planOnly, the image is only named.
+func buildImage(ctx context.Context, m *manifest.Manifest, planOnly bool) error {
+	image := build.ImageName(m, time.Now())
+	if m.Deployment.Source.Build.RemoteBuild() {
+		// The provider builds the image while deploying
+		if !planOnly {
+			logging.Info(i18n.T("build.remote", image))
+		}
+	} else if !planOnly {
+		logging.Info(i18n.T("build.start", image))
+		if err := build.Build(ctx, m, image); err != nil {
+			return err
+		}
+		logging.Info(i18n.T("build.success"))
+	}
+	m.Image = image
+	return nil
+}
+
+// deepStatusWindow is how far back status -deep looks for remediation events
+// unless -since is given.
+const deepStatusWindow = 24 * time.Hour
+
+// dependencyHealth checks the manifest's dependencies. Their URLs are read
+// from the deployment's environment variables, and from its secrets when a
+// dependency names one, since database URLs are usually kept in secrets.
+func dependencyHealth(ctx context.Context, m *manifest.Manifest) ([]dependencies.Result, error) {
+	if len(m.Dependencies) == 0 {
+		return nil, nil
+	}
+	env := maps.Clone(m.GetPrimaryContainer().Environment)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	for _, d := range m.Dependencies {
+		if d.Env != "" && slices.ContainsFunc(m.Secrets, func(s manifest.SecretRef) bool { return s.Name == d.Env }) {
+			values, err := secrets.Resolve(ctx, m)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(env, values)
+			break
+		}
+	}
+	return dependencies.Check(ctx, m.Dependencies, env), nil
+}
+
+// remediationEvents returns the replacements and restarts the provider made
+// to keep the deployment running since the given time.
+func remediationEvents(ctx context.Context, p provider.Provider, m *manifest.Manifest, since time.Time) ([]types.RemediationEvent, error) {
+	reporter, ok := p.(provider.RemediationReporter)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support status -deep", p.Name())
+	}
+	return reporter.RemediationEvents(ctx, m, since)
+}
+
+// scaleDeployment changes the instance counts of the running deployment without
+// redeploying it. min and max override the manifest's counts unless negative.
+func scaleDeployment(ctx context.Context, p provider.Provider, m *manifest.Manifest, min, max int) error {
+	scaler, ok := p.(provider.Scaler)
+	if !ok {
+		return fmt.Errorf("provider %s does not support scaling", p.Name())
+	}
+	scaled, err := withScale(m, min, max)
+	if err != nil {
+		return err
+	}
+	if err := scaler.Scale(ctx, scaled); err != nil {
+		return err
+	}
+	if scaled.Instance != m.Instance || (scaled.CloudRun != nil && (m.CloudRun == nil || *scaled.CloudRun != *m.CloudRun)) {
+		logging.Warn(i18n.T("scale.manifest_hint"))
+	}
+	return nil
+}
+
+// maintenanceMode parses the argument of the maintenance command, which
+// turns maintenance mode on or off.
+func maintenanceMode(arg string) (bool, error) {
+	switch arg {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("usage: cloud-deploy maintenance on|off")
+}
+
+// imagePromotion checks the arguments of the image command: promote, with
+// the -from and -to tags.
+func imagePromotion(args []string, from, to string) error {
+	if len(args) != 1 || args[0] != "promote" || from == "" || to == "" {
+		return fmt.Errorf("usage: cloud-deploy image promote -from <tag|image> -to <tag|image>")
+	}
+	return nil
+}
+
+// writeManifestSchema writes the JSON Schema of manifests to w for manifest
+// schema.
+func writeManifestSchema(w io.Writer, args []string) error {
+	if len(args) != 1 || args[0] != "schema" {
+		return fmt.Errorf("usage: cloud-deploy manifest schema")
+	}
+	schema, err := manifest.Schema()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", schema)
+	return err
+}
+
+// promoteImage copies an image to another tag by digest; tests replace it.
+var promoteImage = registry.Promote
+
+// promoteImages promotes the image tagged from to the tag to, and records each
+// promotion so deploys of the new tag use the promoted digest. A tag as from
+// promotes every image of the manifest, in its repository; an image reference
+// promotes just that image. to is a tag in the source's repository, or an
+// image reference in any registry for a single image.
+func promoteImages(ctx context.Context, m *manifest.Manifest, from, to string) error {
+	sources := []string{from}
+	if !strings.ContainsAny(from, "/:@") {
+		images := []string{m.Image}
+		if m.IsMultiContainer() {
+			images = images[:0]
+			for _, c := range m.Containers {
+				images = append(images, c.Image)
+			}
+		}
+		sources = sources[:0]
+		for _, image := range images {
+			source, err := registry.PromotionTarget(image, from)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) > 1 && strings.ContainsAny(to, "/@") {
+		return fmt.Errorf("-to must be a tag when promoting the %d images of the manifest", len(sources))
+	}
+
+	promotions, err := state.OpenPromotions(ctx, m)
+	if err != nil {
+		return err
+	}
+	logging.Info(i18n.T("image.promote_start", to))
+	for _, source := range sources {
+		target, err := registry.PromotionTarget(source, to)
+		if err != nil {
+			return err
+		}
+		digest, err := promoteImage(ctx, source, target)
+		if err != nil {
+			return err
+		}
+		if _, err := promotions.Record(ctx, state.Promotion{Source: source, Target: target, Digest: digest, ToolVersion: version}); err != nil {
+			return err
+		}
+		logging.Info(i18n.T("image.promoted", source, target, digest))
+	}
+	logging.Info(i18n.T("image.promote_success"))
+	return nil
+}
+
+// pinPromotedImages replaces the images of m that image promote promoted to
+// with the promoted digest, so a deploy gets the promoted artifact even if
+// the tag has moved since.
+func pinPromotedImages(ctx context.Context, m *manifest.Manifest) error {
+	promotions, err := state.OpenPromotions(ctx, m)
+	if err != nil {
+		return err
+	}
+	pin := func(image *string) error {
+		if *image == "" {
+			return nil
+		}
+		promo, ok, err := promotions.Latest(ctx, *image)
+		if err != nil || !ok {
+			return err
+		}
+		*image = promo.Pinned()
+		logging.Info(i18n.T("image.pinned", promo.Target, promo.Digest))
+		return nil
+	}
+	if err := pin(&m.Image); err != nil {
+		return err
+	}
+	for i := range m.Containers {
+		if err := pin(&m.Containers[i].Image); err != nil {
+			return err
+		}
+	}
+	for i := range m.Services {
+		if err := pin(&m.Services[i].Image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMaintenance turns maintenance mode of m's deployment on or off. Requests
+// to proxied cloudflare.dns_records are answered by a Cloudflare Worker in
+// front of any provider, authenticating with CLOUDFLARE_API_TOKEN; otherwise
+// the provider switches its own traffic. The deployment keeps running either
+// way.
+func setMaintenance(ctx context.Context, p provider.Provider, m *manifest.Manifest, on bool) error {
+	if len(cloudflare.MaintenancePatterns(m.Cloudflare)) > 0 {
+		creds, err := (&credentials.Manager{Source: "environment"}).GetCredentials(ctx, "cloudflare")
+		if err != nil {
+			return fmt.Errorf("%w: set CLOUDFLARE_API_TOKEN to serve the maintenance response from a Cloudflare Worker", err)
+		}
+		client := newCloudflareClient(creds.Cloudflare.APIToken)
+		script := cloudflare.MaintenanceScriptName(m.Application.Name, m.Environment.Name)
+		if on {
+			added, err := client.EnableMaintenance(ctx, m.Cloudflare, script, m.Maintenance)
+			for _, pattern := range added {
+				logging.Info(i18n.T("maintenance.route_added", pattern, script))
+			}
+			return err
+		}
+		removed, err := client.DisableMaintenance(ctx, m.Cloudflare, script)
+		for _, pattern := range removed {
+			logging.Info(i18n.T("maintenance.route_removed", pattern))
+		}
+		return err
+	}
+
+	switcher, ok := p.(provider.MaintenanceSwitcher)
+	if !ok {
+		return fmt.Errorf("provider %s does not support maintenance mode; add proxied cloudflare.dns_records to serve it from a Cloudflare Worker", p.Name())
+	}
+	return switcher.SetMaintenance(ctx, m, on)
+}
+
+// withScale returns a copy of m with its provider's instance counts (cloud_run
+// for gcp, instance for the others) replaced by min and max unless negative.
+func withScale(m *manifest.Manifest, min, max int) (*manifest.Manifest, error) {
+	if min > math.MaxInt32 || max > math.MaxInt32 {
+		return nil, fmt.Errorf("instance counts must be at most %d", math.MaxInt32)
+	}
+	scaled := *m
+	counts := [2]*int32{&scaled.Instance.MinInstances, &scaled.Instance.MaxInstances}
+	if m.Provider.Name == "gcp" {
+		var cloudRun manifest.CloudRunConfig
+		if m.CloudRun != nil {
+			cloudRun = *m.CloudRun
+		}
+		scaled.CloudRun = &cloudRun
+		counts = [2]*int32{&cloudRun.MinInstances, &cloudRun.MaxInstances}
+	}
+	if min >= 0 {
+		*counts[0] = int32(min)
+	}
+	if max >= 0 {
+		*counts[1] = int32(max)
+	}
+	if err := scaled.Validate(); err != nil {
+		return nil, err
+	}
+	return &scaled, nil
+}
+
+// syncSecrets re-resolves the manifest's secrets and updates only the secret-derived
+// environment variables of the running deployment, without redeploying the image.
+func syncSecrets(ctx context.Context, p provider.Provider, m *manifest.Manifest) error {
+	syncer, ok := p.(provider.SecretSyncer)
+	if !ok {
+		return fmt.Errorf("provider %s does not support updating environment variables in place", p.Name())
+	}
+	if len(m.Secrets) == 0 {
+		return fmt.Errorf("manifest has no secrets to sync")
+	}
+
+	values, err := secrets.Resolve(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	changed, err := syncer.UpdateEnvironmentVariables(ctx, m, values)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		logging.Info(i18n.T("secrets_sync.up_to_date"))
+		return nil
+	}
+	logging.Info(i18n.T("secrets_sync.updated", len(changed), strings.Join(changed, ", ")))
+	return nil
+}
+
+// rotateCredentials replaces the provider credentials held in the manifest's secret
+// store: it issues new credentials, verifies them, stores them, and only then
+// deactivates the old ones. If verification or storing fails, the new credentials
+// are revoked and the old ones stay in place.
+func rotateCredentials(ctx context.Context, p provider.Provider, m *manifest.Manifest) error {
+	rotator, ok := p.(provider.CredentialRotator)
+	if !ok {
+		return fmt.Errorf("provider %s does not support credential rotation", p.Name())
+	}
+	if m.Provider.Credentials == nil || m.Provider.Credentials.Source != "secrets-manager" {
+		return fmt.Errorf("credential rotation requires provider.credentials.source: secrets-manager")
+	}
+
+	store := m.CredentialsManager()
+	current, err := store.GetCredentials(ctx, p.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read current credentials: %w", err)
+	}
+	if err := credentials.ValidateCredentials(current, p.Name()); err != nil {
+		return err
+	}
+
+	logging.Info(i18n.T("credentials_rotate.creating", p.Name()))
+	rotated, err := rotator.CreateCredentials(ctx, m, current)
+	if err != nil {
+		return err
+	}
+
+	// discard revokes the new credentials when they cannot be put into use
+	discard := func(cause error) error {
+		if err := rotator.RevokeCredentials(ctx, m, current, rotated); err != nil {
+			logging.Warn(i18n.T("credentials_rotate.discard", err))
+		}
+		return cause
+	}
+
+	logging.Info(i18n.T("credentials_rotate.verifying"))
+	if err := rotator.VerifyCredentials(ctx, m, rotated); err != nil {
+		return discard(fmt.Errorf("new credentials failed verification, old credentials remain active: %w", err))
+	}
+
+	if err := store.PutCredentials(ctx, p.Name(), rotated); err != nil {
+		return discard(fmt.Errorf("failed to store new credentials, old credentials remain active: %w", err))
+	}
+	logging.Info(i18n.T("credentials_rotate.stored", m.Provider.Credentials.SecretID))
+
+	if err := rotator.RevokeCredentials(ctx, m, rotated, current); err != nil {
+		return fmt.Errorf("new credentials are stored, but the old credentials are still active: %w", err)
+	}
+	logging.Info(i18n.T("credentials_rotate.success"))
+	return nil
+}
+
+// showStats prints a summary of the commands recorded in the local telemetry log.
+func showStats(local bool) error {
+	if !local {
+		return fmt.Errorf("only local statistics are available; use -local (aggregate statistics are kept by the telemetry endpoint)")
+	}
+
+	events, err := telemetry.LoadLocal()
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		path, _ := telemetry.LogPath()
+		logging.Info(i18n.T("stats.empty", path))
+		return nil
 	}
+	return telemetry.WriteSummary(os.Stdout, telemetry.Summarize(events))
 }
@@ -1,10 +1,43 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/jvreagan/cloud-deploy/pkg/cloudflare"
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/provider"
+	"github.com/jvreagan/cloud-deploy/pkg/secrets"
+	"github.com/jvreagan/cloud-deploy/pkg/state"
+	"github.com/jvreagan/cloud-deploy/pkg/telemetry"
+	"github.com/jvreagan/cloud-deploy/pkg/transcript"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+	"github.com/jvreagan/cloud-deploy/pkg/userconfig"
 )
 
 // TestVersion tests the -version flag by running the binary
@@ -77,7 +110,7 @@ health_check:
 	}
 
 	// Test invalid command
-	cmd = exec.Command("./cloud-deploy-test", "-manifest", manifestPath, "-command", "invalid-command")
+	cmd = exec.Command("./cloud-deploy-test", "invalid-command", "-manifest", manifestPath)
 	output, err := cmd.CombinedOutput()
 	if err == nil {
 		t.Error("Expected error for invalid command, but got none")
@@ -89,6 +122,55 @@ health_check:
 	}
 }
 
+// TestFlagsWithoutCommand tests that flags without a command deploy, with a
+// deprecation warning, as they did before there were subcommands
+func TestFlagsWithoutCommand(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	cmd := exec.Command("go", "build", "-o", "cloud-deploy-test", ".")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("Could not build binary for testing: %v", err)
+	}
+	defer os.Remove("cloud-deploy-test")
+
+	tmpDir := t.TempDir()
+	manifestPath := tmpDir + "/test-manifest.yaml"
+	manifestContent := `version: "1.0"
+image: "test-app:latest"
+provider:
+  name: mock
+application:
+  name: test-app
+environment:
+  name: test-env
+state:
+  path: ` + tmpDir + `/state
+`
+	if err := os.WriteFile(manifestPath, []byte(manifestContent), 0644); err != nil {
+		t.Fatalf("Failed to create test manifest: %v", err)
+	}
+
+	cmd = exec.Command("./cloud-deploy-test", "-manifest", manifestPath)
+	cmd.Env = append(os.Environ(), "HOME="+tmpDir, "CLOUD_DEPLOY_TELEMETRY=off")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Expected -manifest alone to deploy, got: %v\nOutput: %s", err, output)
+	}
+	for _, want := range []string{"Running without a command is deprecated", "Deployment successful"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, output)
+		}
+	}
+
+	// With no flags either, the usage is printed
+	cmd = exec.Command("./cloud-deploy-test")
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "Usage: cloud-deploy <command>") {
+		t.Errorf("Expected the usage and an error without arguments, got %v: %s", err, output)
+	}
+}
+
 // TestMissingManifest tests that missing manifest file returns an error
 func TestMissingManifest(t *testing.T) {
 	if os.Getenv("CI") != "" {
@@ -103,7 +185,7 @@ func TestMissingManifest(t *testing.T) {
 	defer os.Remove("cloud-deploy-test")
 
 	// Test with non-existent manifest
-	cmd = exec.Command("./cloud-deploy-test", "-manifest", "/nonexistent/manifest.yaml", "-command", "status")
+	cmd = exec.Command("./cloud-deploy-test", "status", "-manifest", "/nonexistent/manifest.yaml")
 	output, err := cmd.CombinedOutput()
 	if err == nil {
 		t.Error("Expected error for missing manifest, but got none")
@@ -115,6 +197,66 @@ func TestMissingManifest(t *testing.T) {
 	}
 }
 
+// TestStructuredOutput tests that -output json writes only the command's
+// document to stdout, for successes and failures
+func TestStructuredOutput(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	cmd := exec.Command("go", "build", "-o", "cloud-deploy-test", ".")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("Could not build binary for testing: %v", err)
+	}
+	defer os.Remove("cloud-deploy-test")
+
+	tmpDir := t.TempDir()
+	manifestPath := tmpDir + "/test-manifest.yaml"
+	manifestContent := `version: "1.0"
+image: "test-app:latest"
+provider:
+  name: mock
+application:
+  name: test-app
+environment:
+  name: test-env
+state:
+  path: ` + tmpDir + `/state
+`
+	if err := os.WriteFile(manifestPath, []byte(manifestContent), 0644); err != nil {
+		t.Fatalf("Failed to create test manifest: %v", err)
+	}
+
+	run := func(args ...string) (map[string]any, error) {
+		cmd := exec.Command("./cloud-deploy-test", args...)
+		cmd.Env = append(os.Environ(), "HOME="+tmpDir, "CLOUD_DEPLOY_TELEMETRY=off")
+		stdout, err := cmd.Output()
+		var doc map[string]any
+		if jsonErr := json.Unmarshal(stdout, &doc); jsonErr != nil {
+			t.Fatalf("Expected only a JSON document on stdout, got %q: %v", stdout, jsonErr)
+		}
+		return doc, err
+	}
+
+	doc, err := run("deploy", "-manifest", manifestPath, "-output", "json")
+	if err != nil {
+		t.Fatalf("deploy failed: %v", err)
+	}
+	result, _ := doc["result"].(map[string]any)
+	if doc["command"] != "deploy" || doc["succeeded"] != true || result["environment_name"] != "test-env" || result["status"] != "Ready" {
+		t.Errorf("Unexpected deploy document: %v", doc)
+	}
+
+	doc, err = run("-manifest", tmpDir+"/missing.yaml", "-output", "json", "status")
+	if err == nil {
+		t.Error("Expected status of a missing manifest to fail")
+	}
+	errs, _ := doc["errors"].([]any)
+	if doc["succeeded"] != false || len(errs) != 1 || !strings.Contains(errs[0].(string), "Error loading manifest") {
+		t.Errorf("Unexpected failure document: %v", doc)
+	}
+}
+
 // TestVersionVariable tests that version variables are set
 func TestVersionVariable(t *testing.T) {
 	// Test that version variable exists and has a default value
@@ -408,3 +550,1762 @@ func TestFlagCount(t *testing.T) {
 		seen[flag] = true
 	}
 }
+
+// fakeProvider is a minimal provider.Provider for exercising command helpers.
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string { return "fake" }
+func (fakeProvider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	return &types.DeploymentResult{}, nil
+}
+func (fakeProvider) Destroy(ctx context.Context, m *manifest.Manifest) error { return nil }
+func (fakeProvider) Stop(ctx context.Context, m *manifest.Manifest) error    { return nil }
+func (fakeProvider) Close() error                                            { return nil }
+func (fakeProvider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	return &types.DeploymentStatus{}, nil
+}
+func (fakeProvider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	return &types.DeploymentResult{}, nil
+}
+func (fakeProvider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	return nil
+}
+
+// fakeExporter is a fakeProvider that supports exporting.
+type fakeExporter struct{ fakeProvider }
+
+func (fakeExporter) Export(ctx context.Context, m *manifest.Manifest, format string) ([]byte, error) {
+	return []byte("exported:" + format), nil
+}
+
+// TestExportResources tests writing an export to a file
+func TestExportResources(t *testing.T) {
+	path := t.TempDir() + "/export.tf"
+
+	if err := exportResources(context.Background(), fakeExporter{}, &manifest.Manifest{}, "terraform", path); err != nil {
+		t.Fatalf("exportResources failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if string(data) != "exported:terraform" {
+		t.Errorf("Unexpected export content: %s", data)
+	}
+}
+
+// TestExportResourcesErrors tests unsupported providers and missing formats
+func TestExportResourcesErrors(t *testing.T) {
+	ctx := context.Background()
+
+	err := exportResources(ctx, fakeProvider{}, &manifest.Manifest{}, "terraform", "")
+	if err == nil || !strings.Contains(err.Error(), "does not support exporting") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+
+	err = exportResources(ctx, fakeExporter{}, &manifest.Manifest{}, "", "")
+	if err == nil || !strings.Contains(err.Error(), "export format is required") {
+		t.Errorf("Expected missing format error, got: %v", err)
+	}
+}
+
+// fakePlanner is a fakeProvider that returns a fixed plan.
+type fakePlanner struct{ fakeProvider }
+
+func (fakePlanner) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "fake"}
+	plan.Add(types.PlanCreate, "Service", m.Environment.Name, "service does not exist")
+	plan.Add(types.PlanUpdate, "Image", "app:latest", "")
+	plan.Add(types.PlanDelete, "Instance", "old", "")
+	plan.Add(types.PlanNoChange, "Bucket", "artifacts", "")
+	return plan, nil
+}
+
+// TestShowPlan tests the plan output and unsupported providers
+func TestShowPlan(t *testing.T) {
+	ctx := context.Background()
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "web-prod"}}
+
+	var out bytes.Buffer
+	if _, err := showPlan(ctx, fakePlanner{}, m, &out); err != nil {
+		t.Fatalf("showPlan failed: %v", err)
+	}
+	for _, want := range []string{
+		"Plan for fake:",
+		"  + create    Service web-prod (service does not exist)",
+		"  ~ update    Image app:latest",
+		"  - delete    Instance old",
+		"  = no-change Bucket artifacts",
+		"Plan: 1 to create, 1 to update, 1 to delete, 1 unchanged",
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("Plan output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	_, err := showPlan(ctx, fakeProvider{}, m, &out)
+	if err == nil || !strings.Contains(err.Error(), "does not support planning") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+}
+
+// fakePostureReporter is a fakeProvider that reports a fixed posture.
+type fakePostureReporter struct {
+	fakeProvider
+	status types.PostureStatus
+}
+
+func (f fakePostureReporter) Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error) {
+	posture := &types.Posture{Provider: "fake"}
+	posture.Add(types.PostureCredentials, "Access key", types.PostureOK, "")
+	posture.AddQuota("Environments", 19, 20)
+	posture.Add(types.PostureAPI, "run.googleapis.com", f.status, "disabled")
+	return posture, nil
+}
+
+// TestShowPosture tests the posture output, its result, and unsupported providers
+func TestShowPosture(t *testing.T) {
+	ctx := context.Background()
+	m := &manifest.Manifest{}
+
+	var out bytes.Buffer
+	posture, err := showPosture(ctx, fakePostureReporter{status: types.PostureFailing}, m, &out)
+	if err != nil {
+		t.Fatalf("showPosture failed: %v", err)
+	}
+	if len(posture.Checks) != 3 || postureHealthy(posture) {
+		t.Errorf("Expected a failing check to make the returned posture unhealthy, got %+v", posture)
+	}
+	for _, want := range []string{
+		"Posture of fake:",
+		"  ✓ ok      credentials Access key",
+		"  ! warning quota       Environments (19 of 20 used)",
+		"  ✗ failing api         run.googleapis.com (disabled)",
+		"Posture: 1 ok, 1 warning, 1 failing",
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("Posture output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	posture, err = showPosture(ctx, fakePostureReporter{status: types.PostureWarning}, m, io.Discard)
+	if err != nil || !postureHealthy(posture) {
+		t.Errorf("Expected warnings alone to be healthy, got %+v (%v)", posture, err)
+	}
+
+	_, err = showPosture(ctx, fakeProvider{}, m, &out)
+	if err == nil || !strings.Contains(err.Error(), "does not support posture checks") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+}
+
+func TestImageFindings(t *testing.T) {
+	m := &manifest.Manifest{Image: "ghcr.io/acme/my-app:v1.2.0"}
+	if findings := imageFindings(m); len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v", findings)
+	}
+
+	m = &manifest.Manifest{Containers: []manifest.Container{
+		{Name: "web", Image: "my-app:latest"},
+		{Name: "worker", Image: "My-Worker:latest"},
+	}}
+	findings := imageFindings(m)
+	if len(findings) != 1 || findings[0].Field != "containers[1].image" || !strings.Contains(findings[0].Message, `"My-Worker:latest" is not a valid image reference`) {
+		t.Errorf("Expected a finding for the second container, got %+v", findings)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	findings, err := validateTarget(context.Background(), historyManifest(t))
+	if err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings for a provider without checks, got %+v (%v)", findings, err)
+	}
+}
+
+func TestWriteFindings(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeFindings(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "✓ No problems found\n" {
+		t.Errorf("Unexpected output without findings: %q", out.String())
+	}
+
+	out.Reset()
+	findings := []types.ValidationFinding{
+		{Field: "image", Message: `"My-App" is not a valid image reference`},
+		{Provider: "aws", Field: "instance.type", Message: "t3.mcro is not offered in us-east-1"},
+	}
+	if err := writeFindings(&out, findings); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`  ✗ image: "My-App" is not a valid image reference`,
+		"  ✗ aws: instance.type: t3.mcro is not offered in us-east-1",
+		"✗ 2 problems found",
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("Findings output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// fakeInventoryReporter is a fakeProvider that reports a fixed inventory.
+type fakeInventoryReporter struct{ fakeProvider }
+
+func (fakeInventoryReporter) Inventory(ctx context.Context, m *manifest.Manifest) (*types.Inventory, error) {
+	inventory := &types.Inventory{Provider: "fake"}
+	inventory.Add("Environment", m.Environment.Name, "fake://my-app/my-app-prod", types.InventoryByName)
+	return inventory, nil
+}
+
+// TestShowInventory tests the inventory table, images from the deployment
+// history, and unsupported providers
+func TestShowInventory(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Provider: "fake", Images: map[string]string{"my-app": "my-app@sha256:a"}})
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Provider: "fake", Images: map[string]string{"my-app": "my-app@sha256:a"}})
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Provider: "other", Images: map[string]string{"my-app": "my-app@sha256:b"}})
+
+	var out bytes.Buffer
+	if _, err := showInventory(ctx, fakeInventoryReporter{}, m, &out); err != nil {
+		t.Fatalf("showInventory failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || lines[0] != "Resources of fake:" || !strings.HasPrefix(lines[1], "TYPE") {
+		t.Fatalf("Unexpected inventory output:\n%s", out.String())
+	}
+	for i, want := range [][]string{
+		{"Environment", "my-app-prod", "fake://my-app/my-app-prod", "name"},
+		{"Container image", "my-app", "my-app@sha256:a", "state"},
+	} {
+		if fields := strings.Fields(lines[i+2]); strings.Join(fields, " ") != strings.Join(want, " ") {
+			t.Errorf("Row %d = %q, want %q", i, fields, want)
+		}
+	}
+
+	if err := writeInventory(&out, &types.Inventory{Provider: "fake"}); err != nil || !strings.Contains(out.String(), "No resources found") {
+		t.Errorf("Expected an empty inventory message, got %q (%v)", out.String(), err)
+	}
+
+	_, err := showInventory(ctx, fakeProvider{}, m, &out)
+	if err == nil || !strings.Contains(err.Error(), "does not support inventory") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+}
+
+// fakePruner is a fakeProvider that prunes a fixed image, or fails after it.
+type fakePruner struct {
+	fakeProvider
+	err error
+}
+
+func (p fakePruner) Prune(ctx context.Context, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool) (*types.Cleanup, error) {
+	cleanup := &types.Cleanup{Provider: "fake", DryRun: dryRun, Kept: policy.KeepLast}
+	cleanup.Prune("Image", "deploy-1", time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local))
+	return cleanup, p.err
+}
+
+// TestPrune tests the cleanup table and summary, failures after deleting
+// some resources, and unsupported providers
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+	policy := types.RetentionPolicy{KeepLast: 3}
+
+	var out bytes.Buffer
+	cleanup, err := prune(ctx, fakePruner{}, m, policy, false, &out)
+	if err != nil || len(cleanup.Deleted) != 1 {
+		t.Fatalf("prune = %+v, %v", cleanup, err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || lines[0] != "Cleanup of fake:" || !strings.HasPrefix(lines[1], "TYPE") {
+		t.Fatalf("Unexpected cleanup output:\n%s", out.String())
+	}
+	if fields := strings.Join(strings.Fields(lines[2]), " "); fields != "Image deploy-1 2026-03-01 12:00:00" {
+		t.Errorf("Row = %q", fields)
+	}
+	if lines[3] != "✓ 1 deleted, 3 kept" {
+		t.Errorf("Summary = %q", lines[3])
+	}
+
+	out.Reset()
+	if _, err := prune(ctx, fakePruner{}, m, policy, true, &out); err != nil || !strings.Contains(out.String(), "1 would be deleted, 3 kept (dry run)") {
+		t.Errorf("Expected a dry-run summary, got %q (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	cleanup, err = prune(ctx, fakePruner{err: errors.New("throttled")}, m, policy, false, &out)
+	if err == nil || cleanup == nil || !strings.Contains(out.String(), "deploy-1") {
+		t.Errorf("Expected what was deleted before the failure, got %+v, %v:\n%s", cleanup, err, out.String())
+	}
+
+	if _, err := prune(ctx, fakeProvider{}, m, policy, false, &out); err == nil || !strings.Contains(err.Error(), "does not support cleanup") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+}
+
+// TestAddDNSInventory tests that the manifest's existing DNS records are listed
+func TestAddDNSInventory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") == "CNAME" {
+			w.Write([]byte(`{"success":true,"errors":[],"result":[{"id":"rec-1","type":"CNAME","name":"app.example.com","content":"my-app.mock.localhost"}]}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"errors":[],"result":[]}`))
+	}))
+	defer server.Close()
+
+	defer func(f func(string) *cloudflare.Client) { newCloudflareClient = f }(newCloudflareClient)
+	newCloudflareClient = func(token string) *cloudflare.Client {
+		c := cloudflare.New(token)
+		c.SetEndpoint(server.URL)
+		return c
+	}
+
+	m := &manifest.Manifest{Cloudflare: &manifest.CloudflareConfig{ZoneID: "zone-123", Domain: "example.com", DNSRecords: []manifest.DNSRecordConfig{{Name: "app"}}}}
+	inventory := &types.Inventory{}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := addDNSInventory(context.Background(), m, inventory); err == nil || !strings.Contains(err.Error(), "CLOUDFLARE_API_TOKEN") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+	if err := addDNSInventory(context.Background(), m, inventory); err != nil {
+		t.Fatalf("addDNSInventory failed: %v", err)
+	}
+	want := []types.InventoryResource{{Type: "Cloudflare CNAME record", Name: "app.example.com", ID: "rec-1", Source: types.InventoryByName}}
+	if !reflect.DeepEqual(inventory.Resources, want) {
+		t.Errorf("Resources = %+v, want %+v", inventory.Resources, want)
+	}
+
+	plan := &types.Plan{}
+	if err := addDNSDeletions(context.Background(), m, plan); err != nil {
+		t.Fatalf("addDNSDeletions failed: %v", err)
+	}
+	wantChanges := []types.ResourceChange{{Action: types.PlanDelete, Type: "Cloudflare CNAME record", Name: "app.example.com", Reason: "managed by cloudflare.dns_records"}}
+	if !reflect.DeepEqual(plan.Changes, wantChanges) {
+		t.Errorf("Changes = %+v, want %+v", plan.Changes, wantChanges)
+	}
+}
+
+// fakeDestroyPlanner is a fakeProvider that returns a fixed destroy plan.
+type fakeDestroyPlanner struct{ fakeProvider }
+
+func (fakeDestroyPlanner) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "fake"}
+	plan.Add(types.PlanDelete, "Environment", m.Environment.Name, "")
+	plan.Add(types.PlanNoChange, "Application", "web", "kept; shared with 1 other environment")
+	plan.AddDependent("Stack web", "Environment", "web-staging")
+	return plan, nil
+}
+
+// TestShowDestroyPlan tests the destroy plan output and unsupported providers
+func TestShowDestroyPlan(t *testing.T) {
+	ctx := context.Background()
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "web-prod"}}
+
+	var out bytes.Buffer
+	if _, err := showDestroyPlan(ctx, fakeDestroyPlanner{}, m, &out); err != nil {
+		t.Fatalf("showDestroyPlan failed: %v", err)
+	}
+	for _, want := range []string{
+		"Destroy plan for fake (nothing was deleted):",
+		"  - delete    Environment web-prod",
+		"  = no-change Application web (kept; shared with 1 other environment)",
+		"Depending on deleted resources:",
+		"  ! Environment web-staging depends on Stack web",
+		"Destroy: 1 to delete, 1 kept, 1 dependents",
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("Destroy plan output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := writeDestroyPlan(&out, &types.Plan{Provider: "fake"}); err != nil || strings.Contains(out.String(), "Depending on") {
+		t.Errorf("Expected no dependents section, got %q (%v)", out.String(), err)
+	}
+
+	_, err := showDestroyPlan(ctx, fakeProvider{}, m, &out)
+	if err == nil || !strings.Contains(err.Error(), "does not support destroy -dry-run") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+}
+
+// fakeSecretSyncer is a fakeProvider that records environment variable updates.
+type fakeSecretSyncer struct {
+	fakeProvider
+	updated map[string]string
+}
+
+func (f *fakeSecretSyncer) UpdateEnvironmentVariables(ctx context.Context, m *manifest.Manifest, vars map[string]string) ([]string, error) {
+	f.updated = vars
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// fakeResolver returns the secret ID as the secret value.
+type fakeResolver struct{}
+
+func (fakeResolver) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	return "value-of-" + ref.SecretID, nil
+}
+
+// TestSyncSecrets tests that resolved secrets are passed to the provider
+func TestSyncSecrets(t *testing.T) {
+	original := secrets.NewResolver
+	secrets.NewResolver = func(ctx context.Context, source string, m *manifest.Manifest) (secrets.Resolver, error) {
+		return fakeResolver{}, nil
+	}
+	defer func() { secrets.NewResolver = original }()
+
+	m := &manifest.Manifest{
+		Secrets: []manifest.SecretRef{{Name: "API_KEY", SecretID: "myapp/api"}},
+	}
+	p := &fakeSecretSyncer{}
+
+	if err := syncSecrets(context.Background(), p, m); err != nil {
+		t.Fatalf("syncSecrets failed: %v", err)
+	}
+	if p.updated["API_KEY"] != "value-of-myapp/api" {
+		t.Errorf("Expected API_KEY to be synced, got %v", p.updated)
+	}
+}
+
+// TestDependencyHealth tests that dependency URLs are read from environment
+// variables and secrets
+func TestDependencyHealth(t *testing.T) {
+	original := secrets.NewResolver
+	resolved := false
+	secrets.NewResolver = func(ctx context.Context, source string, m *manifest.Manifest) (secrets.Resolver, error) {
+		resolved = true
+		return fakeResolver{}, nil
+	}
+	defer func() { secrets.NewResolver = original }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	m := &manifest.Manifest{
+		EnvironmentVariables: map[string]string{"API_URL": server.URL},
+		Secrets:              []manifest.SecretRef{{Name: "DATABASE_URL", SecretID: "myapp/db"}},
+		Dependencies:         []manifest.Dependency{{Name: "api", Env: "API_URL"}},
+	}
+	results, err := dependencyHealth(context.Background(), m)
+	if err != nil {
+		t.Fatalf("dependencyHealth failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].Healthy || resolved {
+		t.Errorf("Expected a healthy API without resolving secrets, got %+v", results)
+	}
+
+	m.Dependencies = append(m.Dependencies, manifest.Dependency{Name: "database", Env: "DATABASE_URL"})
+	if results, err = dependencyHealth(context.Background(), m); err != nil {
+		t.Fatalf("dependencyHealth failed: %v", err)
+	}
+	// The fake secret value is not a database URL
+	if !resolved || results[1].Healthy || results[1].Detail != "URL has no host" {
+		t.Errorf("Expected the database URL to be read from its secret, got %+v", results[1])
+	}
+
+	if results, err := dependencyHealth(context.Background(), &manifest.Manifest{}); err != nil || results != nil {
+		t.Errorf("Expected no results without dependencies, got %v, %v", results, err)
+	}
+}
+
+// TestSyncSecretsErrors tests unsupported providers and manifests without secrets
+func TestSyncSecretsErrors(t *testing.T) {
+	ctx := context.Background()
+	m := &manifest.Manifest{Secrets: []manifest.SecretRef{{Name: "API_KEY", SecretID: "myapp/api"}}}
+
+	err := syncSecrets(ctx, fakeProvider{}, m)
+	if err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+
+	err = syncSecrets(ctx, &fakeSecretSyncer{}, &manifest.Manifest{})
+	if err == nil || !strings.Contains(err.Error(), "no secrets") {
+		t.Errorf("Expected no secrets error, got: %v", err)
+	}
+}
+
+// fakeRotator is a fakeProvider that supports credential rotation.
+type fakeRotator struct {
+	fakeProvider
+}
+
+func (fakeRotator) CreateCredentials(ctx context.Context, m *manifest.Manifest, current *credentials.ProviderCredentials) (*credentials.ProviderCredentials, error) {
+	return current, nil
+}
+
+func (fakeRotator) VerifyCredentials(ctx context.Context, m *manifest.Manifest, creds *credentials.ProviderCredentials) error {
+	return nil
+}
+
+func (fakeRotator) RevokeCredentials(ctx context.Context, m *manifest.Manifest, active, revoked *credentials.ProviderCredentials) error {
+	return nil
+}
+
+// TestRotateCredentialsErrors tests unsupported providers and credential sources
+func TestRotateCredentialsErrors(t *testing.T) {
+	ctx := context.Background()
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{
+		Name:        "aws",
+		Credentials: &manifest.CredentialsConfig{Source: "secrets-manager", SecretID: "cloud-deploy/aws"},
+	}}
+
+	err := rotateCredentials(ctx, fakeProvider{}, m)
+	if err == nil || !strings.Contains(err.Error(), "does not support credential rotation") {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+
+	for _, creds := range []*manifest.CredentialsConfig{nil, {Source: "environment"}} {
+		m.Provider.Credentials = creds
+		err = rotateCredentials(ctx, fakeRotator{}, m)
+		if err == nil || !strings.Contains(err.Error(), "source: secrets-manager") {
+			t.Errorf("Expected secrets-manager source error, got: %v", err)
+		}
+	}
+}
+
+// TestShowStats tests the local telemetry summary
+func TestShowStats(t *testing.T) {
+	t.Setenv(telemetry.EnvLogPath, filepath.Join(t.TempDir(), "telemetry.jsonl"))
+	t.Setenv(telemetry.EnvMode, "")
+
+	if err := showStats(false); err == nil || !strings.Contains(err.Error(), "-local") {
+		t.Errorf("Expected -local error, got: %v", err)
+	}
+	if err := showStats(true); err != nil {
+		t.Errorf("Expected empty log to succeed, got: %v", err)
+	}
+
+	telemetry.Record(context.Background(), telemetry.NewEvent("deploy", "aws", version, time.Second, true))
+	if err := showStats(true); err != nil {
+		t.Errorf("showStats failed: %v", err)
+	}
+}
+
+// TestDeployTargetErrors tests that a failing provider is reported as that target's error
+func TestDeployTargetErrors(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "oci"}}
+	_, err := deployTarget(context.Background(), m, nil, false, new(registryPushes))
+	if err == nil || !strings.Contains(err.Error(), "failed to create provider") {
+		t.Errorf("Expected provider creation error, got: %v", err)
+	}
+}
+
+func TestPushToRegistries(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceRef, _ := name.ParseReference(host + "/build/my-app:v1.2.0")
+	if err := remote.Write(sourceRef, img); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+
+	m := &manifest.Manifest{
+		Image:       sourceRef.String(),
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Registries:  []manifest.RegistryConfig{{URL: host + "/mirror", Username: "ci", Password: "token"}},
+	}
+	if err := new(registryPushes).push(context.Background(), m); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	pushed, _ := name.ParseReference(host + "/mirror/my-app:v1.2.0")
+	if _, err := remote.Head(pushed); err != nil {
+		t.Errorf("Expected the image to be pushed with its tag: %v", err)
+	}
+
+	r, err := newRegistry(manifest.RegistryConfig{Type: manifest.RegistryDockerHub, Namespace: "acme", Repository: "web", Tag: "stable", Username: "ci", Password: "token"}, m)
+	if err != nil || r.GetImageURI() != "docker.io/acme/web:stable" {
+		t.Errorf("Expected the Docker Hub repository, got %v (%v)", r, err)
+	}
+}
+
+// TestDeployPushesToRegistries tests that deploy pushes the image to the
+// manifest's registries once, for a single manifest and for a batch deploying
+// the same image to two environments, and never for a target the policy denies
+func TestDeployPushesToRegistries(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	cmd := exec.Command("go", "build", "-o", "cloud-deploy-test", ".")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("Could not build binary for testing: %v", err)
+	}
+	defer os.Remove("cloud-deploy-test")
+
+	var mu sync.Mutex
+	pushes, deniedPushes := 0, 0
+	reg := ggcrregistry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every push first checks whether the registry has the manifest
+		if r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/mirror/") && strings.Contains(r.URL.Path, "/manifests/") {
+			mu.Lock()
+			pushes++
+			mu.Unlock()
+		}
+		if strings.HasPrefix(r.URL.Path, "/v2/mirror/denied/") {
+			mu.Lock()
+			deniedPushes++
+			mu.Unlock()
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceRef, _ := name.ParseReference(host + "/build/my-app:v1.2.0")
+	if err := remote.Write(sourceRef, img); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	writeManifest := func(env string) string {
+		path := filepath.Join(tmpDir, env+".yaml")
+		content := fmt.Sprintf(`version: "1.0"
+image: %s
+provider:
+  name: mock
+application:
+  name: my-app
+environment:
+  name: %s
+registries:
+  - url: %s/mirror
+    insecure: true
+state:
+  path: %s/state-%s
+`, sourceRef, env, host, tmpDir, env)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	run := func(manifests ...string) ([]byte, error) {
+		args := append([]string{"deploy", "-manifest", manifests[0]}, manifests[1:]...)
+		cmd := exec.Command("./cloud-deploy-test", args...)
+		cmd.Env = append(os.Environ(), "HOME="+tmpDir, "CLOUD_DEPLOY_TELEMETRY=off", "CLOUD_DEPLOY_POLICY="+filepath.Join(tmpDir, "policy.yaml"))
+		return cmd.CombinedOutput()
+	}
+	deploy := func(manifests ...string) {
+		t.Helper()
+		if out, err := run(manifests...); err != nil {
+			t.Fatalf("deploy failed: %v\n%s", err, out)
+		}
+	}
+	policy := "security:\n  - environments: [prod]\n    require_run_as_non_root: true\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "policy.yaml"), []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deploy(writeManifest("my-app-prod"))
+	if pushes != 1 {
+		t.Errorf("Expected a single-provider deploy to push the image once, got %d pushes", pushes)
+	}
+	pushed, _ := name.ParseReference(host + "/mirror/my-app:v1.2.0")
+	if _, err := remote.Get(pushed); err != nil {
+		t.Errorf("Expected the image to be pushed to the registry: %v", err)
+	}
+
+	pushes = 0
+	deploy(writeManifest("my-app-blue"), writeManifest("my-app-green"))
+	if pushes != 1 {
+		t.Errorf("Expected a batch deploying the same image to push it once, got %d pushes", pushes)
+	}
+
+	// The policy denies the prod target of the batch, whose image goes to
+	// another repository, so that image is never pushed
+	denied := writeManifest("my-app-denied")
+	raw, _ := os.ReadFile(denied)
+	content := strings.Replace(string(raw), "name: my-app-denied\n", "name: my-app-denied\n  class: prod\n", 1)
+	content = strings.Replace(content, "insecure: true\n", "insecure: true\n    repository: denied/my-app\n", 1)
+	if err := os.WriteFile(denied, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := run(writeManifest("my-app-blue"), denied); err == nil {
+		t.Errorf("Expected the policy to deny the batch:\n%s", out)
+	} else if !strings.Contains(string(out), "run_as_non_root") {
+		t.Errorf("Expected the policy violation in the output:\n%s", out)
+	}
+	if deniedPushes != 0 {
+		t.Errorf("Expected no push of the image of a target the policy denies, got %d requests", deniedPushes)
+	}
+}
+
+func TestPlanTargetErrors(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "oci"}}
+	_, err := planTarget(context.Background(), m, nil, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "failed to create provider") {
+		t.Errorf("Expected provider creation error, got: %v", err)
+	}
+}
+
+// recordingProvider is a fakeProvider that keeps the manifest it deploys.
+type recordingProvider struct {
+	fakeProvider
+	deployed *manifest.Manifest
+}
+
+func (p *recordingProvider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	p.deployed = m
+	return &types.DeploymentResult{Images: map[string]string{m.Application.Name: m.Image}}, nil
+}
+
+// historyManifest returns a manifest whose deployment history is kept in a temporary directory.
+func historyManifest(t *testing.T) *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:       "my-app:2.0.0",
+		Provider:    manifest.ProviderConfig{Name: "mock"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		State:       &manifest.StateConfig{Path: t.TempDir()},
+	}
+}
+
+func TestRollbackToVersion(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Images: map[string]string{"my-app": "registry/my-app@sha256:aaa"}})
+	recordHistory(ctx, m, state.Record{Command: state.CommandRollback})
+
+	p := &recordingProvider{}
+	if _, err := rollbackToVersion(ctx, p, m, "v1"); err != nil {
+		t.Fatalf("rollbackToVersion failed: %v", err)
+	}
+	if p.deployed.Image != "registry/my-app@sha256:aaa" {
+		t.Errorf("deployed image = %q, want the recorded image", p.deployed.Image)
+	}
+	if m.Image != "my-app:2.0.0" {
+		t.Errorf("manifest image changed to %q", m.Image)
+	}
+
+	if _, err := rollbackToVersion(ctx, p, m, "v2"); err == nil || !strings.Contains(err.Error(), "cannot roll back to v2: no image recorded for my-app") {
+		t.Errorf("Expected an error for a version without images, got: %v", err)
+	}
+	if _, err := rollbackToVersion(ctx, p, m, "v9"); err == nil || !strings.Contains(err.Error(), "version v9 not found") {
+		t.Errorf("Expected an error for an unknown version, got: %v", err)
+	}
+}
+
+func TestRollForward(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+	p := &recordingProvider{}
+
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Images: map[string]string{"my-app": "registry/my-app@sha256:aaa"}})
+	if _, _, err := rollForward(ctx, p, m); err == nil || !strings.Contains(err.Error(), "not a rollback") {
+		t.Errorf("Expected an error without a rollback, got: %v", err)
+	}
+
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Images: map[string]string{"my-app": "registry/my-app@sha256:bbb"}})
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Images: map[string]string{"my-app": "registry/my-app@sha256:ccc"}})
+	recordHistory(ctx, m, state.Record{Command: state.CommandRollback, RestoredVersion: "v1", Images: map[string]string{"my-app": "registry/my-app@sha256:aaa"}})
+
+	_, restored, err := rollForward(ctx, p, m)
+	if err != nil {
+		t.Fatalf("rollForward failed: %v", err)
+	}
+	if restored != "v2" || p.deployed.Image != "registry/my-app@sha256:bbb" {
+		t.Errorf("rolled forward to %s with image %q, want v2 skipping the rolled back v3", restored, p.deployed.Image)
+	}
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+	defer func(v string) { version = v }(version)
+
+	// A new environment has no recorded version
+	version = "0.5.0"
+	if err := checkVersionSkew(ctx, m, false); err != nil {
+		t.Fatalf("checkVersionSkew failed for a new environment: %v", err)
+	}
+
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy})
+	version = "0.5.3"
+	if err := checkVersionSkew(ctx, m, false); err != nil {
+		t.Errorf("Expected a patch release to be compatible, got: %v", err)
+	}
+
+	version = "0.6.0"
+	if err := checkVersionSkew(ctx, m, false); err == nil || !strings.Contains(err.Error(), "last deployed with cloud-deploy 0.5.0") {
+		t.Errorf("Expected a pre-1.0 minor release to be refused, got: %v", err)
+	}
+	if err := checkVersionSkew(ctx, m, true); err != nil {
+		t.Errorf("Expected -allow-version-skew to allow the skew, got: %v", err)
+	}
+}
+
+func TestWithImagesMultiContainer(t *testing.T) {
+	m := historyManifest(t)
+	m.Image = ""
+	m.Containers = []manifest.Container{{Name: "web", Image: "web:2"}, {Name: "worker", Image: "worker:2"}}
+
+	target, err := withImages(m, map[string]string{"web": "web@sha256:a", "worker": "worker@sha256:b"})
+	if err != nil {
+		t.Fatalf("withImages failed: %v", err)
+	}
+	if target.Containers[0].Image != "web@sha256:a" || target.Containers[1].Image != "worker@sha256:b" {
+		t.Errorf("unexpected images: %+v", target.Containers)
+	}
+	if m.Containers[0].Image != "web:2" {
+		t.Error("withImages changed the original manifest")
+	}
+
+	if _, err := withImages(m, map[string]string{"web": "web@sha256:a"}); err == nil || !strings.Contains(err.Error(), "container worker") {
+		t.Errorf("Expected an error for a missing container, got: %v", err)
+	}
+}
+
+func TestShowHistory(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+
+	var out bytes.Buffer
+	if records, err := showHistory(ctx, m, &out); err != nil || records == nil {
+		t.Fatalf("showHistory failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "No deployments recorded for my-app-prod") {
+		t.Errorf("unexpected output for an empty history: %q", out.String())
+	}
+
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, Status: "Ready", URL: "http://my-app.example.com",
+		Images: map[string]string{"worker": "worker@sha256:b", "web": "web@sha256:a"}})
+	recordHistory(ctx, m, state.Record{Command: state.CommandDeploy, RestoredVersion: "v1"})
+
+	out.Reset()
+	records, err := showHistory(ctx, m, &out)
+	if err != nil {
+		t.Fatalf("showHistory failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("Expected the records to be returned, got %+v", records)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "VERSION") {
+		t.Fatalf("unexpected history table:\n%s", out.String())
+	}
+	for _, want := range []string{"v1", "deploy", "Ready", "web@sha256:a, worker@sha256:b", "http://my-app.example.com"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("first row missing %q: %s", want, lines[1])
+		}
+	}
+	if !strings.Contains(lines[2], "deploy -to v1") {
+		t.Errorf("second row missing the restored version: %s", lines[2])
+	}
+}
+
+func TestReplayTranscript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	r := transcript.NewRecorder("deploy", "mock", "my-app", "my-app-prod", version)
+	if err := r.Save(path, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayTranscript(path, 0); err != nil {
+		t.Errorf("replayTranscript failed: %v", err)
+	}
+
+	if err := replayTranscript("", 0); err == nil || !strings.Contains(err.Error(), "no transcript given") {
+		t.Errorf("Expected a usage error without a path, got: %v", err)
+	}
+	if err := replayTranscript(filepath.Join(t.TempDir(), "missing.json"), 0); err == nil {
+		t.Error("Expected an error for a missing transcript")
+	}
+}
+
+func TestManifestVerifier(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("provider:\n  name: mock\nimage: my-app:1.0.0\napplication:\n  name: my-app\nenvironment:\n  name: my-app-prod\n")
+	manifestFile := filepath.Join(dir, "deploy-manifest.yaml")
+	if err := os.WriteFile(manifestFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	verify, err := manifestVerifier(keyFile, manifestFile, "")
+	if err != nil {
+		t.Fatalf("manifestVerifier failed: %v", err)
+	}
+	if _, err := manifest.LoadVerified(manifestFile, verify); err == nil || !strings.Contains(err.Error(), "manifest is not signed") {
+		t.Errorf("Expected an unsigned manifest to be refused, got: %v", err)
+	}
+
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+	if err := os.WriteFile(manifestFile+".sig", []byte(sig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manifest.LoadVerified(manifestFile, verify); err != nil {
+		t.Errorf("Expected a signed manifest to load, got: %v", err)
+	}
+
+	if err := os.WriteFile(manifestFile, append(data, "  tampered: true\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manifest.LoadVerified(manifestFile, verify); err == nil || !strings.Contains(err.Error(), "verification failed") {
+		t.Errorf("Expected a modified manifest to be refused, got: %v", err)
+	}
+
+	if err := verify("-", data); err == nil || !strings.Contains(err.Error(), "-manifest-signature") {
+		t.Errorf("Expected stdin to need an explicit signature, got: %v", err)
+	}
+	verify, err = manifestVerifier(keyFile, "-", manifestFile+".sig")
+	if err != nil {
+		t.Fatalf("manifestVerifier failed: %v", err)
+	}
+	if err := verify("-", data); err != nil {
+		t.Errorf("Expected stdin with -manifest-signature to verify, got: %v", err)
+	}
+	if err := verify(filepath.Join(dir, "common.yaml"), data); err == nil || !strings.Contains(err.Error(), "common.yaml.sig") {
+		t.Errorf("Expected an included fragment to be verified against the signature next to it, got: %v", err)
+	}
+
+	if _, err := manifestVerifier(filepath.Join(dir, "missing.pub"), manifestFile, ""); err == nil {
+		t.Error("Expected a missing public key to fail")
+	}
+}
+
+func TestSignatureFor(t *testing.T) {
+	tests := map[string]string{
+		"deploy-manifest.yaml":                    "deploy-manifest.yaml.sig",
+		"s3://bucket/prod/deploy-manifest.yaml":   "s3://bucket/prod/deploy-manifest.yaml.sig",
+		"https://example.com/prod.yaml?token=abc": "https://example.com/prod.yaml.sig?token=abc",
+		"gs://bucket/deploy-manifest.yaml":        "gs://bucket/deploy-manifest.yaml.sig",
+	}
+	for location, want := range tests {
+		if got := signatureFor(location, ".sig"); got != want {
+			t.Errorf("signatureFor(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
+
+// fakeScaler is a fakeProvider that records the manifest it scales.
+type fakeScaler struct {
+	fakeProvider
+	scaled *manifest.Manifest
+}
+
+func (f *fakeScaler) Scale(ctx context.Context, m *manifest.Manifest) error {
+	f.scaled = m
+	return nil
+}
+
+func TestWithScale(t *testing.T) {
+	m := &manifest.Manifest{
+		Image:       "my-app:1.0.0",
+		Provider:    manifest.ProviderConfig{Name: "aws"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Instance:    manifest.InstanceConfig{MinInstances: 1, MaxInstances: 4},
+	}
+
+	scaled, err := withScale(m, -1, 10)
+	if err != nil {
+		t.Fatalf("withScale failed: %v", err)
+	}
+	if scaled.Instance.MinInstances != 1 || scaled.Instance.MaxInstances != 10 {
+		t.Errorf("Expected 1-10 instances, got %+v", scaled.Instance)
+	}
+	if m.Instance.MaxInstances != 4 {
+		t.Error("withScale modified the original manifest")
+	}
+
+	if _, err := withScale(m, 5, 2); err == nil || !strings.Contains(err.Error(), "must not exceed") {
+		t.Errorf("Expected min above max to fail, got: %v", err)
+	}
+
+	m.Provider = manifest.ProviderConfig{Name: "gcp", ProjectID: "my-project", BillingAccountID: "000000-000000-000000", Credentials: &manifest.CredentialsConfig{Source: "environment"}}
+	scaled, err = withScale(m, 0, 20)
+	if err != nil {
+		t.Fatalf("withScale failed: %v", err)
+	}
+	if scaled.CloudRun == nil || scaled.CloudRun.MinInstances != 0 || scaled.CloudRun.MaxInstances != 20 {
+		t.Errorf("Expected cloud_run counts 0-20, got %+v", scaled.CloudRun)
+	}
+	if m.CloudRun != nil {
+		t.Error("withScale modified the original manifest")
+	}
+}
+
+func TestScaleDeployment(t *testing.T) {
+	m := &manifest.Manifest{
+		Image:       "my-app:1.0.0",
+		Provider:    manifest.ProviderConfig{Name: "mock"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+	}
+
+	if err := scaleDeployment(context.Background(), fakeProvider{}, m, 2, 10); err == nil || !strings.Contains(err.Error(), "does not support scaling") {
+		t.Errorf("Expected an unsupported provider error, got: %v", err)
+	}
+
+	p := &fakeScaler{}
+	if err := scaleDeployment(context.Background(), p, m, 2, 10); err != nil {
+		t.Fatalf("scaleDeployment failed: %v", err)
+	}
+	if p.scaled.Instance.MinInstances != 2 || p.scaled.Instance.MaxInstances != 10 {
+		t.Errorf("Expected the provider to scale to 2-10 instances, got %+v", p.scaled.Instance)
+	}
+}
+
+func TestBuildImagePlanOnly(t *testing.T) {
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Deployment: manifest.DeploymentConfig{Source: manifest.SourceConfig{
+			Path:  filepath.Join(t.TempDir(), "missing"),
+			Build: &manifest.BuildConfig{},
+		}},
+	}
+	// A plan names the image without building the (missing) source
+	if err := buildImage(context.Background(), m, true); err != nil {
+		t.Fatalf("buildImage failed: %v", err)
+	}
+	if !strings.HasPrefix(m.Image, "my-app:") {
+		t.Errorf("Expected an image named after the application, got %q", m.Image)
+	}
+
+	m.Image = ""
+	if err := buildImage(context.Background(), m, false); err == nil || m.Image != "" {
+		t.Errorf("Expected a failed build to leave the image unset, got %q (err: %v)", m.Image, err)
+	}
+
+	// Remote builds only name the image the provider builds
+	m.Deployment.Source.Build.Strategy = manifest.BuildStrategyCodeBuild
+	if err := buildImage(context.Background(), m, false); err != nil || !strings.HasPrefix(m.Image, "my-app:") {
+		t.Errorf("Expected a remote build to only name the image, got %q (err: %v)", m.Image, err)
+	}
+}
+
+func TestManifestVariables(t *testing.T) {
+	dir := t.TempDir()
+	common, team := filepath.Join(dir, "common.yaml"), filepath.Join(dir, "team.yaml")
+	os.WriteFile(common, []byte("region: us-east-1\nteam: core\n"), 0644)
+	os.WriteFile(team, []byte("team: search\n"), 0644)
+
+	values, err := manifestVariables([]string{common, team}, []string{"region=eu-west-1"})
+	if err != nil {
+		t.Fatalf("manifestVariables failed: %v", err)
+	}
+	if len(values) != 2 || values["team"] != "search" || values["region"] != "eu-west-1" {
+		t.Errorf("Expected later files and -var to override earlier values, got %v", values)
+	}
+
+	if _, err := manifestVariables(nil, []string{"region"}); err == nil {
+		t.Error("Expected a -var without a value to fail")
+	}
+	if _, err := manifestVariables([]string{filepath.Join(dir, "missing.yaml")}, nil); err == nil {
+		t.Error("Expected a missing -var-file to fail")
+	}
+}
+
+func TestLoadBatch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, env, class string) string {
+		path := filepath.Join(dir, name)
+		content := fmt.Sprintf("image: my-app:1\nprovider:\n  name: mock\napplication:\n  name: my-app\nenvironment:\n  name: %s\n  class: %s\n", env, class)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	m, err := manifest.Load(write("prod.yaml", "my-app-prod", "prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := loadBatch(m, []string{write("staging.yaml", "my-app-staging", "staging"), write("pr-42.yaml", "my-app-pr-42", "preview")}, manifest.Options{}, "")
+	if err != nil {
+		t.Fatalf("loadBatch failed: %v", err)
+	}
+	if len(batch) != 2 || batch[1].Environment.Name != "my-app-pr-42" {
+		t.Errorf("Unexpected batch: %+v", batch)
+	}
+
+	if _, err := loadBatch(m, []string{write("prod-copy.yaml", "my-app-prod", "prod")}, manifest.Options{}, ""); err == nil || !strings.Contains(err.Error(), "already deployed by another manifest") {
+		t.Errorf("Expected a duplicate environment to fail, got: %v", err)
+	}
+
+	verify := func(location string, data []byte) error { return nil }
+	if _, err := loadBatch(m, []string{filepath.Join(dir, "staging.yaml")}, manifest.Options{Verify: verify}, "prod.yaml.sig"); err == nil || !strings.Contains(err.Error(), "-manifest-signature") {
+		t.Errorf("Expected -manifest-signature to be refused for a batch, got: %v", err)
+	}
+}
+
+func TestDeploySchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("concurrency:\n  max_parallel: 3\n  providers:\n    aws: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(userconfig.EnvPath, path)
+
+	schedule, err := deploySchedule()
+	if err != nil {
+		t.Fatalf("deploySchedule failed: %v", err)
+	}
+	if schedule.MaxParallel != 3 || schedule.ProviderLimits["aws"] != 1 {
+		t.Errorf("Unexpected limits: %+v", schedule)
+	}
+	prod := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Class: "prod"}}
+	preview := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Class: "preview"}}
+	if schedule.Priority(prod) <= schedule.Priority(preview) {
+		t.Error("Expected prod to be deployed before previews by default")
+	}
+}
+
+// fakeReporter is a fakeProvider that reports remediation events.
+type fakeReporter struct {
+	fakeProvider
+	since time.Time
+}
+
+func (f *fakeReporter) RemediationEvents(ctx context.Context, m *manifest.Manifest, since time.Time) ([]types.RemediationEvent, error) {
+	f.since = since
+	return []types.RemediationEvent{{Timestamp: since, Kind: types.RemediationContainerRestarted, Source: "web"}}, nil
+}
+
+func TestRemediationEvents(t *testing.T) {
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-app-prod"}}
+	since := time.Now().Add(-deepStatusWindow)
+
+	p := &fakeReporter{}
+	events, err := remediationEvents(context.Background(), p, m, since)
+	if err != nil {
+		t.Fatalf("remediationEvents failed: %v", err)
+	}
+	if len(events) != 1 || !p.since.Equal(since) {
+		t.Errorf("Unexpected events %+v since %v", events, p.since)
+	}
+
+	if _, err := remediationEvents(context.Background(), &fakeProvider{}, m, since); err == nil || !strings.Contains(err.Error(), "does not support status -deep") {
+		t.Errorf("Expected an unsupported provider error, got: %v", err)
+	}
+}
+
+func TestUpdateDNS(t *testing.T) {
+	var created []cloudflare.DNSRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"success":true,"errors":[],"result":[]}`))
+		case http.MethodPost:
+			var rec cloudflare.DNSRecord
+			json.NewDecoder(r.Body).Decode(&rec)
+			rec.ID = "rec-1"
+			created = append(created, rec)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": rec})
+		}
+	}))
+	defer server.Close()
+
+	defer func(f func(string) *cloudflare.Client) { newCloudflareClient = f }(newCloudflareClient)
+	newCloudflareClient = func(token string) *cloudflare.Client {
+		c := cloudflare.New(token)
+		c.SetEndpoint(server.URL)
+		return c
+	}
+
+	m := &manifest.Manifest{Cloudflare: &manifest.CloudflareConfig{ZoneID: "zone-123", Domain: "example.com", DNSRecords: []manifest.DNSRecordConfig{{Name: "app"}}}}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := updateDNS(context.Background(), m, "http://my-app.mock.localhost"); err == nil || !strings.Contains(err.Error(), "CLOUDFLARE_API_TOKEN") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+	if err := updateDNS(context.Background(), m, "http://my-app.mock.localhost"); err != nil {
+		t.Fatalf("updateDNS failed: %v", err)
+	}
+	if len(created) != 1 || created[0].Name != "app.example.com" || created[0].Content != "my-app.mock.localhost" || created[0].Type != "CNAME" {
+		t.Errorf("Unexpected records created: %+v", created)
+	}
+
+	// Manifests without records need no token
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := updateDNS(context.Background(), &manifest.Manifest{}, "http://my-app.mock.localhost"); err != nil {
+		t.Errorf("Expected no DNS update without records, got: %v", err)
+	}
+}
+
+func TestUpdateLoadBalancer(t *testing.T) {
+	var pools []cloudflare.Pool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/zones/zone-123":
+			w.Write([]byte(`{"success":true,"errors":[],"result":{"id":"zone-123","account":{"id":"acct-1"}}}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"success":true,"errors":[],"result":[]}`))
+		case r.Method == http.MethodPost:
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if strings.HasSuffix(r.URL.Path, "/pools") {
+				var pool cloudflare.Pool
+				data, _ := json.Marshal(body)
+				json.Unmarshal(data, &pool)
+				pools = append(pools, pool)
+			}
+			body["id"] = fmt.Sprintf("id-%d", len(pools))
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": body})
+		}
+	}))
+	defer server.Close()
+
+	defer func(f func(string) *cloudflare.Client) { newCloudflareClient = f }(newCloudflareClient)
+	newCloudflareClient = func(token string) *cloudflare.Client {
+		c := cloudflare.New(token)
+		c.SetEndpoint(server.URL)
+		return c
+	}
+
+	m := &manifest.Manifest{
+		Providers: []manifest.ProviderConfig{{Name: "aws"}, {Name: "gcp"}},
+		Cloudflare: &manifest.CloudflareConfig{
+			ZoneID:       "zone-123",
+			Domain:       "example.com",
+			LoadBalancer: &manifest.CloudflareLoadBalancerConfig{Name: "app"},
+		},
+	}
+	results := []provider.TargetResult{
+		{Provider: "aws", Err: fmt.Errorf("deploy failed")},
+		{Provider: "gcp", Result: &types.DeploymentResult{URL: "https://my-app-abc123-uc.a.run.app"}},
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := updateLoadBalancer(context.Background(), m, results); err == nil || !strings.Contains(err.Error(), "CLOUDFLARE_API_TOKEN") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	// Only the deployed provider gets a pool
+	t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+	if err := updateLoadBalancer(context.Background(), m, results); err != nil {
+		t.Fatalf("updateLoadBalancer failed: %v", err)
+	}
+	if len(pools) != 1 || pools[0].Name != "app-example-com-gcp" || pools[0].Origins[0].Address != "my-app-abc123-uc.a.run.app" {
+		t.Errorf("Unexpected pools created: %+v", pools)
+	}
+
+	// Nothing is changed when no provider deployed, so no token is needed
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := updateLoadBalancer(context.Background(), m, results[:1]); err != nil {
+		t.Errorf("Expected no load balancer update without deployments, got: %v", err)
+	}
+}
+
+func TestRemoveDNS(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("type") == "CNAME":
+			w.Write([]byte(`{"success":true,"errors":[],"result":[{"id":"rec-1","type":"CNAME","name":"app.example.com","content":"my-app.mock.localhost"}]}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"success":true,"errors":[],"result":[]}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.Write([]byte(`{"success":true,"errors":[],"result":{"id":"rec-1"}}`))
+		}
+	}))
+	defer server.Close()
+
+	defer func(f func(string) *cloudflare.Client) { newCloudflareClient = f }(newCloudflareClient)
+	newCloudflareClient = func(token string) *cloudflare.Client {
+		c := cloudflare.New(token)
+		c.SetEndpoint(server.URL)
+		return c
+	}
+
+	m := &manifest.Manifest{Cloudflare: &manifest.CloudflareConfig{ZoneID: "zone-123", Domain: "example.com", DNSRecords: []manifest.DNSRecordConfig{{Name: "app"}}}}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := removeDNS(context.Background(), m); err == nil || !strings.Contains(err.Error(), "CLOUDFLARE_API_TOKEN") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+	if err := removeDNS(context.Background(), m); err != nil {
+		t.Fatalf("removeDNS failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/zones/zone-123/dns_records/rec-1" {
+		t.Errorf("Unexpected deletions: %v", deleted)
+	}
+
+	// Manifests without records need no token
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := removeDNS(context.Background(), &manifest.Manifest{}); err != nil {
+		t.Errorf("Expected no DNS removal without records, got: %v", err)
+	}
+}
+
+type fakeSwitcher struct {
+	fakeProvider
+	on []bool
+}
+
+func (f *fakeSwitcher) SetMaintenance(ctx context.Context, m *manifest.Manifest, on bool) error {
+	f.on = append(f.on, on)
+	return nil
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	if on, err := maintenanceMode("on"); err != nil || !on {
+		t.Errorf("Expected on, got %v, %v", on, err)
+	}
+	if on, err := maintenanceMode("off"); err != nil || on {
+		t.Errorf("Expected off, got %v, %v", on, err)
+	}
+	if _, err := maintenanceMode(""); err == nil || !strings.Contains(err.Error(), "maintenance on|off") {
+		t.Errorf("Expected a usage error, got: %v", err)
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-app-prod"}}
+
+	if err := setMaintenance(context.Background(), fakeProvider{}, m, true); err == nil || !strings.Contains(err.Error(), "does not support maintenance mode") {
+		t.Errorf("Expected an unsupported provider error, got: %v", err)
+	}
+
+	p := &fakeSwitcher{}
+	if err := setMaintenance(context.Background(), p, m, true); err != nil {
+		t.Fatalf("setMaintenance failed: %v", err)
+	}
+	if err := setMaintenance(context.Background(), p, m, false); err != nil {
+		t.Fatalf("setMaintenance failed: %v", err)
+	}
+	if len(p.on) != 2 || !p.on[0] || p.on[1] {
+		t.Errorf("Expected maintenance mode to be turned on then off, got %v", p.on)
+	}
+}
+
+func TestSetMaintenanceCloudflare(t *testing.T) {
+	var routes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone-123":
+			w.Write([]byte(`{"success":true,"errors":[],"result":{"id":"zone-123","account":{"id":"acct-1"}}}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"success":true,"errors":[],"result":[]}`))
+		case r.Method == http.MethodPost:
+			var route cloudflare.WorkerRoute
+			json.NewDecoder(r.Body).Decode(&route)
+			routes = append(routes, route.Pattern+" "+route.Script)
+			w.Write([]byte(`{"success":true,"errors":[],"result":{}}`))
+		default:
+			w.Write([]byte(`{"success":true,"errors":[],"result":{}}`))
+		}
+	}))
+	defer server.Close()
+
+	defer func(f func(string) *cloudflare.Client) { newCloudflareClient = f }(newCloudflareClient)
+	newCloudflareClient = func(token string) *cloudflare.Client {
+		c := cloudflare.New(token)
+		c.SetEndpoint(server.URL)
+		return c
+	}
+
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "prod"},
+		Cloudflare:  &manifest.CloudflareConfig{ZoneID: "zone-123", Domain: "example.com", DNSRecords: []manifest.DNSRecordConfig{{Name: "app"}}},
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if err := setMaintenance(context.Background(), fakeProvider{}, m, true); err == nil || !strings.Contains(err.Error(), "CLOUDFLARE_API_TOKEN") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	// The Worker serves maintenance whether or not the provider can
+	t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+	if err := setMaintenance(context.Background(), fakeProvider{}, m, true); err != nil {
+		t.Fatalf("setMaintenance failed: %v", err)
+	}
+	if strings.Join(routes, ", ") != "app.example.com/* cloud-deploy-maintenance-my-app-prod" {
+		t.Errorf("Unexpected routes: %v", routes)
+	}
+}
+
+func TestImagePromotion(t *testing.T) {
+	if err := imagePromotion([]string{"promote"}, "staging", "prod"); err != nil {
+		t.Errorf("imagePromotion failed: %v", err)
+	}
+	for _, tc := range []struct {
+		args     []string
+		from, to string
+	}{
+		{nil, "staging", "prod"},
+		{[]string{"push"}, "staging", "prod"},
+		{[]string{"promote"}, "staging", ""},
+		{[]string{"promote"}, "", "prod"},
+		{[]string{"promote", "extra"}, "staging", "prod"},
+	} {
+		if err := imagePromotion(tc.args, tc.from, tc.to); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("imagePromotion(%v, %q, %q) error = %v, want usage", tc.args, tc.from, tc.to, err)
+		}
+	}
+}
+
+func TestWriteManifestSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeManifestSchema(&buf, []string{"schema"}); err != nil {
+		t.Fatalf("writeManifestSchema failed: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil || schema["$ref"] != "#/definitions/Manifest" {
+		t.Errorf("Expected the manifest schema, got %v:\n%s", err, buf.String())
+	}
+	for _, args := range [][]string{nil, {"validate"}, {"schema", "extra"}} {
+		if err := writeManifestSchema(io.Discard, args); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("writeManifestSchema(%q) error = %v, want usage", args, err)
+		}
+	}
+}
+
+func TestPromoteImages(t *testing.T) {
+	ctx := context.Background()
+	var promoted []string
+	defer func(orig func(context.Context, string, string) (string, error)) { promoteImage = orig }(promoteImage)
+	promoteImage = func(_ context.Context, source, target string) (string, error) {
+		promoted = append(promoted, source+" -> "+target)
+		return "sha256:" + strings.Repeat("a", 64), nil
+	}
+
+	m := historyManifest(t)
+	m.Image = ""
+	m.Containers = []manifest.Container{
+		{Name: "web", Image: "123.dkr.ecr.us-east-1.amazonaws.com/web:latest"},
+		{Name: "worker", Image: "123.dkr.ecr.us-east-1.amazonaws.com/worker:latest"},
+	}
+	if err := promoteImages(ctx, m, "staging", "prod"); err != nil {
+		t.Fatalf("promoteImages failed: %v", err)
+	}
+	want := []string{
+		"123.dkr.ecr.us-east-1.amazonaws.com/web:staging -> 123.dkr.ecr.us-east-1.amazonaws.com/web:prod",
+		"123.dkr.ecr.us-east-1.amazonaws.com/worker:staging -> 123.dkr.ecr.us-east-1.amazonaws.com/worker:prod",
+	}
+	if !reflect.DeepEqual(promoted, want) {
+		t.Errorf("promoted %v, want %v", promoted, want)
+	}
+	if err := promoteImages(ctx, m, "staging", "myregistry.azurecr.io/web:prod"); err == nil || !strings.Contains(err.Error(), "-to must be a tag") {
+		t.Errorf("Expected an error for one target of several images, got: %v", err)
+	}
+
+	// Deploys of a promoted tag are pinned to the promoted digest
+	deploy := historyManifest(t)
+	deploy.State = m.State
+	deploy.Image = ""
+	deploy.Containers = []manifest.Container{
+		{Name: "web", Image: "123.dkr.ecr.us-east-1.amazonaws.com/web:prod"},
+		{Name: "sidecar", Image: "envoyproxy/envoy:v1.30"},
+	}
+	if err := pinPromotedImages(ctx, deploy); err != nil {
+		t.Fatalf("pinPromotedImages failed: %v", err)
+	}
+	if got := deploy.Containers[0].Image; got != "123.dkr.ecr.us-east-1.amazonaws.com/web@sha256:"+strings.Repeat("a", 64) {
+		t.Errorf("promoted image = %q, want it pinned to the digest", got)
+	}
+	if got := deploy.Containers[1].Image; got != "envoyproxy/envoy:v1.30" {
+		t.Errorf("image that was not promoted changed to %q", got)
+	}
+
+	// A single image can be copied to another registry
+	promoted = nil
+	if err := promoteImages(ctx, m, "123.dkr.ecr.us-east-1.amazonaws.com/web:prod", "us-docker.pkg.dev/acme/apps/web:prod"); err != nil {
+		t.Fatalf("promoteImages failed: %v", err)
+	}
+	if len(promoted) != 1 || promoted[0] != "123.dkr.ecr.us-east-1.amazonaws.com/web:prod -> us-docker.pkg.dev/acme/apps/web:prod" {
+		t.Errorf("promoted %v, want a copy to Artifact Registry", promoted)
+	}
+}
+
+// fakeAttacher is a fakeProvider that journals its operations.
+type fakeAttacher struct {
+	fakeProvider
+	journal  types.OperationJournal
+	attached []types.Operation
+	err      error
+}
+
+func (p *fakeAttacher) SetOperationJournal(journal types.OperationJournal) { p.journal = journal }
+
+func (p *fakeAttacher) Attach(ctx context.Context, m *manifest.Manifest, op types.Operation) (*types.DeploymentResult, error) {
+	p.attached = append(p.attached, op)
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.journal.End(ctx)
+	return &types.DeploymentResult{ApplicationName: m.Application.Name, EnvironmentName: m.Environment.Name, Status: "Ready"}, nil
+}
+
+func TestJournalOperations(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+
+	if journal, op, err := journalOperations(ctx, fakeProvider{}, m, "deploy"); journal != nil || op != nil || err != nil {
+		t.Errorf("Expected providers without a journal not to journal, got %v, %v, %v", journal, op, err)
+	}
+
+	p := &fakeAttacher{}
+	journal, op, err := journalOperations(ctx, p, m, "deploy")
+	if err != nil || op != nil {
+		t.Fatalf("journalOperations = %v, %v; want no operation in progress", op, err)
+	}
+	if p.journal == nil {
+		t.Fatal("Expected the journal to be set on the provider")
+	}
+	p.journal.Begin(ctx, types.Operation{Provider: "fake", Kind: "service-update", ID: "op-1"})
+
+	// Another invocation finds the operation in progress and attaches to it
+	_, op, err = journalOperations(ctx, &fakeAttacher{}, m, "attach")
+	if err != nil || op == nil || op.ID != "op-1" || op.Command != "deploy" {
+		t.Fatalf("journalOperations = %+v, %v; want the deploy in progress", op, err)
+	}
+	result, err := attachOperation(ctx, p, m, journal, *op)
+	if err != nil || result.Status != "Ready" {
+		t.Fatalf("attachOperation = %+v, %v", result, err)
+	}
+	if _, op, _ := journalOperations(ctx, p, m, "deploy"); op != nil {
+		t.Errorf("Expected the attached operation to be ended, got %+v", op)
+	}
+	store, _ := state.Open(ctx, m)
+	if history, _ := store.History(ctx); len(history) != 1 || history[0].Command != "deploy" {
+		t.Errorf("Expected the attached deploy to be recorded, got %+v", history)
+	}
+}
+
+func TestAttachOperationFailure(t *testing.T) {
+	ctx := context.Background()
+	m := historyManifest(t)
+	p := &fakeAttacher{err: fmt.Errorf("resume token expired")}
+	journal, _, _ := journalOperations(ctx, p, m, "attach")
+	journal.Begin(ctx, types.Operation{Provider: "fake", Kind: "container-group-update", ID: "token"})
+
+	if _, err := attachOperation(ctx, p, m, journal, types.Operation{Kind: "container-group-update"}); err == nil || !strings.Contains(err.Error(), "resume token expired") {
+		t.Errorf("Expected the attach error, got: %v", err)
+	}
+	if op, _ := journal.Current(ctx); op != nil {
+		t.Errorf("Expected an operation that cannot be attached to be ended, got %+v", op)
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(userconfig.EnvPath, path)
+	if err := os.WriteFile(path, []byte("read_only: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if on, err := readOnlyMode(false); err != nil || !on {
+		t.Errorf("readOnlyMode(false) = %v, %v; want read_only from the user configuration", on, err)
+	}
+	if err := os.WriteFile(path, []byte("read_only: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if on, err := readOnlyMode(true); err != nil || !on {
+		t.Errorf("readOnlyMode(true) = %v, %v; want the flag to turn it on", on, err)
+	}
+	if on, err := readOnlyMode(false); err != nil || on {
+		t.Errorf("readOnlyMode(false) = %v, %v; want off", on, err)
+	}
+}
+
+func TestTargetResults(t *testing.T) {
+	outputs := targetResults([]provider.TargetResult{
+		{Provider: "aws", Target: "aws", Result: &types.DeploymentResult{EnvironmentName: "my-app-prod"}},
+		{Provider: "gcp", Target: "gcp", Err: errors.New("quota exceeded")},
+	})
+	if len(outputs) != 2 || outputs[0].Result.EnvironmentName != "my-app-prod" || outputs[0].Error != "" || outputs[1].Error != "quota exceeded" {
+		t.Errorf("Unexpected target results: %+v", outputs)
+	}
+}
+
+func TestStatusOutput(t *testing.T) {
+	report := &statusOutput{DeploymentStatus: &types.DeploymentStatus{EnvironmentName: "my-app-prod", Health: "Green"}}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, `"environment_name":"my-app-prod"`) || !strings.Contains(got, `"health":"Green"`) || strings.Contains(got, "dependencies") {
+		t.Errorf("Expected the status fields inline and no -deep fields, got %s", got)
+	}
+}
+
+func TestWritesDocument(t *testing.T) {
+	for _, command := range []string{"deploy", "status", "rollback", "plan", "posture", "validate", "history", "stop"} {
+		if !writesDocument(command) {
+			t.Errorf("Expected %s to write a document", command)
+		}
+	}
+	for _, command := range []string{"logs", "export", "iam-policy", "manifest", "stats", "replay"} {
+		if writesDocument(command) {
+			t.Errorf("Expected %s to write its own output", command)
+		}
+	}
+}
+
+func TestReadOnlyCommand(t *testing.T) {
+	for _, command := range []string{"plan", "status", "logs", "history", "export", "iam-policy", "posture", "validate", "manifest", "inventory"} {
+		if !readOnlyCommand(command, false) {
+			t.Errorf("Expected %s to run in read-only mode", command)
+		}
+	}
+	for _, command := range []string{"deploy", "rollback", "roll-forward", "stop", "destroy", "scale", "maintenance", "attach", "image", "secrets-sync", "credentials-rotate"} {
+		if readOnlyCommand(command, false) {
+			t.Errorf("Expected %s to be refused in read-only mode", command)
+		}
+	}
+	if !readOnlyCommand("destroy", true) {
+		t.Error("Expected destroy -dry-run to run in read-only mode")
+	}
+	if readOnlyCommand("cleanup", false) || !readOnlyCommand("cleanup", true) {
+		t.Error("Expected only cleanup -dry-run to run in read-only mode")
+	}
+}
+
+// rollbackCounter is a fakeProvider that counts rollbacks.
+type rollbackCounter struct {
+	fakeProvider
+	rollbacks int
+}
+
+func (p *rollbackCounter) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	p.rollbacks++
+	return &types.DeploymentResult{Message: "Rolled back to version v1"}, nil
+}
+
+func TestVerifyDeployment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	noRetries := 0
+
+	m := historyManifest(t)
+	p := &rollbackCounter{}
+	if err := verifyDeployment(ctx, p, m, server.URL); err != nil || p.rollbacks != 0 {
+		t.Errorf("Expected no verification without checks, got %v and %d rollbacks", err, p.rollbacks)
+	}
+
+	m.Verification = &manifest.VerificationConfig{Checks: []manifest.VerificationCheck{{Path: "/health", Retries: &noRetries}}}
+	if err := verifyDeployment(ctx, p, m, server.URL); err != nil || p.rollbacks != 0 {
+		t.Errorf("Expected passing checks to keep the deployment, got %v and %d rollbacks", err, p.rollbacks)
+	}
+
+	m.Verification.Checks = append(m.Verification.Checks, manifest.VerificationCheck{Path: "/ready", Retries: &noRetries})
+	err := verifyDeployment(ctx, p, m, server.URL)
+	if err == nil || err.Error() != "1 of 2 checks failed; rolled back: Rolled back to version v1" || p.rollbacks != 1 {
+		t.Errorf("Expected a failed check to roll back, got %v and %d rollbacks", err, p.rollbacks)
+	}
+	store, _ := state.Open(ctx, m)
+	if history, _ := store.History(ctx); len(history) != 1 || history[0].Command != state.CommandRollback {
+		t.Errorf("Expected the rollback to be recorded, got %+v", history)
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	m := historyManifest(t)
+	if err := runHooks(ctx, m, manifest.HookPreDeploy, "deploy", nil, nil); err != nil {
+		t.Errorf("Expected no hooks to run without hooks, got: %v", err)
+	}
+
+	m.Hooks = &manifest.HooksConfig{
+		PostDeploy: []manifest.Hook{{Command: `echo "$CLOUD_DEPLOY_EVENT $CLOUD_DEPLOY_IMAGE $CLOUD_DEPLOY_URL" >> ` + out}},
+		OnFailure:  []manifest.Hook{{Command: `echo "$CLOUD_DEPLOY_EVENT $CLOUD_DEPLOY_COMMAND $CLOUD_DEPLOY_ERROR" >> ` + out + `; exit 1`}},
+	}
+	result := &types.DeploymentResult{URL: "https://my-app.example.com", Images: map[string]string{"my-app": "registry/my-app@sha256:aaa"}}
+	if err := runHooks(ctx, m, manifest.HookPostDeploy, "deploy", result, nil); err != nil {
+		t.Fatalf("runHooks failed: %v", err)
+	}
+	// A failing on_failure hook is only logged
+	runFailureHooks(ctx, m, "rollback", fmt.Errorf("no previous version"))
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "post_deploy registry/my-app@sha256:aaa https://my-app.example.com\non_failure rollback no previous version\n"
+	if string(data) != want {
+		t.Errorf("Hooks wrote %q, want %q", data, want)
+	}
+}
+
+func TestNotifyResult(t *testing.T) {
+	var got []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	m := historyManifest(t)
+	m.Notifications = &manifest.NotificationsConfig{
+		Commands: []string{"deploy"},
+		Webhooks: []manifest.WebhookNotification{{URL: server.URL}},
+	}
+	start := time.Now().Add(-time.Minute)
+	result := &types.DeploymentResult{URL: "https://my-app.example.com", Status: "Ready", Images: map[string]string{"my-app": "registry/my-app@sha256:aaa"}}
+	notifyResult(ctx, m, "deploy", start, result, "v3", nil)
+	notifyResult(ctx, m, "deploy", start, nil, "", fmt.Errorf("2 of 3 checks failed"))
+	notifyResult(ctx, m, "destroy", start, nil, "", nil)
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d: %v", len(got), got)
+	}
+	if got[0]["succeeded"] != true || got[0]["version"] != "v3" || got[0]["image"] != "registry/my-app@sha256:aaa" || got[0]["duration_seconds"].(float64) < 60 {
+		t.Errorf("Unexpected success notification: %v", got[0])
+	}
+	if got[1]["succeeded"] != false || got[1]["error"] != "2 of 3 checks failed" {
+		t.Errorf("Unexpected failure notification: %v", got[1])
+	}
+}
+
+type closingProvider struct {
+	fakeProvider
+	closed int
+}
+
+func (p *closingProvider) Close() error {
+	p.closed++
+	return nil
+}
+
+func TestCloseProvider(t *testing.T) {
+	p := &closingProvider{}
+	closeProvider(p)
+	if p.closed != 1 {
+		t.Errorf("Expected the provider to be closed once, got %d", p.closed)
+	}
+}
@@ -0,0 +1,346 @@
+// Package awsapi provides a minimal SigV4-signed client for AWS service APIs
+// that cloud-deploy calls without pulling in a dedicated SDK module.
+//
+// It supports the wire protocols used by the services we need:
+//   - AWS Query (form-encoded request, XML response) — e.g. CloudFormation, IAM
+//   - EC2 Query (form-encoded request, XML response without a result wrapper) — EC2
+//   - AWS JSON 1.0 and 1.1 (X-Amz-Target header, JSON body) — e.g. App Runner, CloudWatch Logs, SSM
+//   - REST-JSON (HTTP method and path, JSON body) — e.g. Lambda, API Gateway
+package awsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Client calls a single AWS service in a single region.
+type Client struct {
+	config      aws.Config
+	service     string
+	endpoint    string
+	jsonVersion string
+	httpClient  *http.Client
+	signer      *v4.Signer
+}
+
+// New creates a client for the given service signing name (for example
+// "cloudformation" or "logs"). The endpoint defaults to the regional public
+// endpoint and honours cfg.BaseEndpoint when it is set.
+func New(cfg aws.Config, service string) *Client {
+	endpoint := fmt.Sprintf("https://%s.%s.amazonaws.com", service, cfg.Region)
+	if cfg.BaseEndpoint != nil && *cfg.BaseEndpoint != "" {
+		endpoint = *cfg.BaseEndpoint
+	}
+
+	httpClient := http.DefaultClient
+	if hc, ok := cfg.HTTPClient.(*http.Client); ok && hc != nil {
+		httpClient = hc
+	}
+
+	return &Client{
+		config:      cfg,
+		service:     service,
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		jsonVersion: "1.1",
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+	}
+}
+
+// Endpoint returns the URL requests are sent to.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// CloseIdleConnections closes the idle connections of the HTTP client
+// requests are sent with.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// SetEndpoint overrides the URL requests are sent to.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = strings.TrimRight(endpoint, "/")
+}
+
+// SetJSONVersion sets the AWS JSON protocol version JSON requests declare:
+// "1.1", the default, or "1.0" for services such as App Runner.
+func (c *Client) SetJSONVersion(version string) {
+	c.jsonVersion = version
+}
+
+// APIError is an error returned by an AWS service.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s (HTTP %d)", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s (HTTP %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// IsErrorCode reports whether err is an APIError with the given code.
+func IsErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Query invokes action using the AWS Query protocol and decodes the
+// <ActionResult> element of the response into out. out may be nil.
+func (c *Client) Query(ctx context.Context, action, version string, params url.Values, out interface{}) error {
+	respBody, err := c.postForm(ctx, action, version, params)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(respBody))
+	resultName := action + "Result"
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			// Actions with no result element (e.g. DeleteStack) leave out untouched
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", action, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == resultName {
+			if err := decoder.DecodeElement(out, &start); err != nil {
+				return fmt.Errorf("failed to decode %s response: %w", action, err)
+			}
+			return nil
+		}
+	}
+}
+
+// EC2 invokes action using the EC2 Query protocol, whose responses hold the
+// result directly in the <ActionResponse> element, and decodes that element
+// into out. out may be nil.
+func (c *Client) EC2(ctx context.Context, action, version string, params url.Values, out interface{}) error {
+	respBody, err := c.postForm(ctx, action, version, params)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", action, err)
+	}
+	return nil
+}
+
+// postForm sends a form-encoded Query request and returns the response body.
+func (c *Client) postForm(ctx context.Context, action, version string, params url.Values) ([]byte, error) {
+	form := url.Values{}
+	for k, v := range params {
+		form[k] = v
+	}
+	form.Set("Action", action)
+	form.Set("Version", version)
+
+	body := []byte(form.Encode())
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded; charset=utf-8",
+	}
+
+	respBody, err := c.do(ctx, http.MethodPost, "/", body, headers)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && len(respBody) > 0 {
+			decodeQueryError(respBody, apiErr)
+		}
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// JSON invokes target (for example "Logs_20140328.FilterLogEvents") using the
+// AWS JSON protocol, version 1.1 unless SetJSONVersion changed it. in is
+// marshalled as the request body and the response is unmarshalled into out,
+// which may be nil.
+func (c *Client) JSON(ctx context.Context, target string, in, out interface{}) error {
+	if in == nil {
+		in = struct{}{}
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", target, err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-amz-json-" + c.jsonVersion,
+		"X-Amz-Target": target,
+	}
+
+	respBody, err := c.do(ctx, http.MethodPost, "/", body, headers)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && len(respBody) > 0 {
+			decodeJSONError(respBody, apiErr)
+		}
+		return err
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", target, err)
+	}
+	return nil
+}
+
+// REST sends a method request to path (for example
+// "/2015-03-31/functions/my-app"), which may include a query string, using
+// the REST-JSON protocol. in, when not nil, is marshalled as the request
+// body and the response is unmarshalled into out, which may be nil.
+func (c *Client) REST(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	headers := map[string]string{}
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+		}
+		headers["Content-Type"] = "application/json"
+	}
+
+	respBody, err := c.do(ctx, method, path, body, headers)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && len(respBody) > 0 {
+			decodeJSONError(respBody, apiErr)
+		}
+		return err
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// do signs and sends a request. On a non-2xx response it returns the body
+// alongside an *APIError so the caller can fill in protocol details.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, headers map[string]string) ([]byte, error) {
+	if c.config.Credentials == nil {
+		return nil, fmt.Errorf("no AWS credentials configured")
+	}
+	creds, err := c.config.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	sum := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), c.service, c.config.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", c.service, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.service, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return respBody, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       resp.Header.Get("X-Amzn-ErrorType"),
+		}
+	}
+
+	return respBody, nil
+}
+
+// queryError is the <Error> element of a Query error response.
+type queryError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// decodeQueryError fills apiErr from an <ErrorResponse> document, or the
+// <Response><Errors> document of EC2.
+func decodeQueryError(body []byte, apiErr *APIError) {
+	var doc struct {
+		Error  queryError `xml:"Error"`
+		Errors queryError `xml:"Errors>Error"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return
+	}
+	e := doc.Error
+	if e.Code == "" {
+		e = doc.Errors
+	}
+	if e.Code != "" {
+		apiErr.Code = e.Code
+	}
+	apiErr.Message = e.Message
+}
+
+// decodeJSONError fills apiErr from a JSON 1.1 error document.
+func decodeJSONError(body []byte, apiErr *APIError) {
+	var doc struct {
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return
+	}
+	if doc.Type != "" {
+		apiErr.Code = doc.Type
+	}
+	// Types may be namespaced, e.g. "com.amazonaws.logs#ResourceNotFoundException"
+	if i := strings.LastIndex(apiErr.Code, "#"); i >= 0 {
+		apiErr.Code = apiErr.Code[i+1:]
+	}
+	// The header form may carry a ":<url>" suffix
+	if i := strings.Index(apiErr.Code, ":"); i >= 0 {
+		apiErr.Code = apiErr.Code[:i]
+	}
+	apiErr.Message = doc.Message
+	if apiErr.Message == "" {
+		apiErr.Message = doc.MessageUpper
+	}
+}
@@ -0,0 +1,301 @@
+package awsapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func testConfig() aws.Config {
+	return aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	}
+}
+
+func TestNewDefaultEndpoint(t *testing.T) {
+	c := New(testConfig(), "cloudformation")
+	if c.Endpoint() != "https://cloudformation.us-east-1.amazonaws.com" {
+		t.Errorf("unexpected endpoint: %s", c.Endpoint())
+	}
+}
+
+func TestNewBaseEndpoint(t *testing.T) {
+	cfg := testConfig()
+	cfg.BaseEndpoint = aws.String("http://localhost:4566/")
+	c := New(cfg, "logs")
+	if c.Endpoint() != "http://localhost:4566" {
+		t.Errorf("unexpected endpoint: %s", c.Endpoint())
+	}
+}
+
+func TestQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			t.Errorf("request was not signed")
+		}
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		if form.Get("Action") != "DescribeStacks" || form.Get("Version") != "2010-05-15" {
+			t.Errorf("unexpected form: %v", form)
+		}
+		if form.Get("StackName") != "my-stack" {
+			t.Errorf("expected StackName param, got %v", form)
+		}
+		w.Write([]byte(`<DescribeStacksResponse><DescribeStacksResult><Stacks><member><StackName>my-stack</StackName><StackStatus>CREATE_COMPLETE</StackStatus></member></Stacks></DescribeStacksResult></DescribeStacksResponse>`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "cloudformation")
+	c.SetEndpoint(server.URL)
+
+	var out struct {
+		Stacks []struct {
+			StackName   string `xml:"StackName"`
+			StackStatus string `xml:"StackStatus"`
+		} `xml:"Stacks>member"`
+	}
+	err := c.Query(context.Background(), "DescribeStacks", "2010-05-15", url.Values{"StackName": {"my-stack"}}, &out)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(out.Stacks) != 1 || out.Stacks[0].StackStatus != "CREATE_COMPLETE" {
+		t.Errorf("unexpected result: %+v", out)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>ValidationError</Code><Message>Stack with id x does not exist</Message></Error></ErrorResponse>`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "cloudformation")
+	c.SetEndpoint(server.URL)
+
+	err := c.Query(context.Background(), "DescribeStacks", "2010-05-15", nil, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !IsErrorCode(err, "ValidationError") {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected message in error, got %v", err)
+	}
+}
+
+func TestEC2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		if form.Get("Action") != "DescribeVpcs" || form.Get("Filter.1.Name") != "is-default" {
+			t.Errorf("unexpected form: %v", form)
+		}
+		w.Write([]byte(`<DescribeVpcsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>r-1</requestId><vpcSet><item><vpcId>vpc-1</vpcId></item></vpcSet></DescribeVpcsResponse>`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "ec2")
+	c.SetEndpoint(server.URL)
+
+	var out struct {
+		VPCs []struct {
+			VPCID string `xml:"vpcId"`
+		} `xml:"vpcSet>item"`
+	}
+	err := c.EC2(context.Background(), "DescribeVpcs", "2016-11-15", url.Values{"Filter.1.Name": {"is-default"}}, &out)
+	if err != nil {
+		t.Fatalf("EC2 failed: %v", err)
+	}
+	if len(out.VPCs) != 1 || out.VPCs[0].VPCID != "vpc-1" {
+		t.Errorf("unexpected result: %+v", out)
+	}
+}
+
+func TestEC2Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`<Response><Errors><Error><Code>InvalidGroup.Duplicate</Code><Message>The security group already exists</Message></Error></Errors><RequestID>r-1</RequestID></Response>`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "ec2")
+	c.SetEndpoint(server.URL)
+
+	err := c.EC2(context.Background(), "CreateSecurityGroup", "2016-11-15", nil, nil)
+	if !IsErrorCode(err, "InvalidGroup.Duplicate") {
+		t.Errorf("expected InvalidGroup.Duplicate, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected message in error, got %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "Logs_20140328.DescribeLogGroups" {
+			t.Errorf("unexpected target: %s", r.Header.Get("X-Amz-Target"))
+		}
+		if r.Header.Get("Content-Type") != "application/x-amz-json-1.1" {
+			t.Errorf("unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if in["logGroupNamePrefix"] != "/aws" {
+			t.Errorf("unexpected body: %v", in)
+		}
+		w.Write([]byte(`{"logGroups":[{"logGroupName":"/aws/one"}]}`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "logs")
+	c.SetEndpoint(server.URL)
+
+	var out struct {
+		LogGroups []struct {
+			LogGroupName string `json:"logGroupName"`
+		} `json:"logGroups"`
+	}
+	err := c.JSON(context.Background(), "Logs_20140328.DescribeLogGroups", map[string]string{"logGroupNamePrefix": "/aws"}, &out)
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	if len(out.LogGroups) != 1 || out.LogGroups[0].LogGroupName != "/aws/one" {
+		t.Errorf("unexpected result: %+v", out)
+	}
+}
+
+func TestJSONVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-amz-json-1.0" {
+			t.Errorf("unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "apprunner")
+	c.SetEndpoint(server.URL)
+	c.SetJSONVersion("1.0")
+	if err := c.JSON(context.Background(), "AppRunner.ListServices", nil, nil); err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazonaws.logs#ResourceNotFoundException","message":"The specified log group does not exist."}`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "logs")
+	c.SetEndpoint(server.URL)
+
+	err := c.JSON(context.Background(), "Logs_20140328.FilterLogEvents", nil, nil)
+	if !IsErrorCode(err, "ResourceNotFoundException") {
+		t.Errorf("expected ResourceNotFoundException, got %v", err)
+	}
+}
+
+func TestREST(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/2015-03-31/functions/my-app/aliases/live" || r.URL.Query().Get("Qualifier") != "1" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/lambda/aws4_request") {
+			t.Errorf("expected a request signed for lambda, got %q", r.Header.Get("Authorization"))
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if in["FunctionVersion"] != "3" {
+			t.Errorf("unexpected body: %v", in)
+		}
+		w.Write([]byte(`{"Name":"live","FunctionVersion":"3"}`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "lambda")
+	c.SetEndpoint(server.URL)
+
+	var out struct {
+		FunctionVersion string
+	}
+	err := c.REST(context.Background(), http.MethodPut, "/2015-03-31/functions/my-app/aliases/live?Qualifier=1", map[string]string{"FunctionVersion": "3"}, &out)
+	if err != nil {
+		t.Fatalf("REST failed: %v", err)
+	}
+	if out.FunctionVersion != "3" {
+		t.Errorf("unexpected result: %+v", out)
+	}
+}
+
+func TestRESTError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > 0 {
+			t.Errorf("expected no body without input, got %d bytes", r.ContentLength)
+		}
+		w.Header().Set("X-Amzn-ErrorType", "ResourceNotFoundException")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"Type":"User","Message":"Function not found: arn:aws:lambda:us-east-1:123456789012:function:my-app"}`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig(), "lambda")
+	c.SetEndpoint(server.URL)
+
+	err := c.REST(context.Background(), http.MethodGet, "/2015-03-31/functions/my-app", nil, nil)
+	if !IsErrorCode(err, "ResourceNotFoundException") {
+		t.Fatalf("expected ResourceNotFoundException, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Function not found") {
+		t.Errorf("expected the message in the error, got %v", err)
+	}
+}
+
+func TestNoCredentials(t *testing.T) {
+	c := New(aws.Config{Region: "us-east-1"}, "logs")
+	if err := c.JSON(context.Background(), "X", nil, nil); err == nil {
+		t.Error("expected error without credentials")
+	}
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.BaseEndpoint = aws.String(server.URL)
+	cfg.HTTPClient = &http.Client{Transport: &http.Transport{}}
+	c := New(cfg, "logs")
+	if err := c.JSON(context.Background(), "Logs_20140328.DescribeLogGroups", map[string]string{}, nil); err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	c.CloseIdleConnections()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the idle connection to be closed")
+	}
+}
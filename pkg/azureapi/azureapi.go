@@ -0,0 +1,292 @@
+// Package azureapi provides a minimal client for Azure Resource Manager APIs
+// that cloud-deploy calls without pulling in a dedicated SDK module, such as
+// Azure Container Apps and App Service.
+//
+// Requests are authenticated with an azcore.TokenCredential, so the same
+// credentials as the SDK clients are used. Long-running operations are
+// followed through their Azure-AsyncOperation or Location header until they
+// finish.
+package azureapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// DefaultEndpoint is the public Azure Resource Manager endpoint.
+const DefaultEndpoint = "https://management.azure.com"
+
+// scope is the token scope of Azure Resource Manager requests.
+const scope = "https://management.azure.com/.default"
+
+// Client calls Azure Resource Manager in a single subscription.
+type Client struct {
+	credential     azcore.TokenCredential
+	subscriptionID string
+	endpoint       string
+	httpClient     *http.Client
+	pollInterval   time.Duration
+	timeout        time.Duration
+}
+
+// New creates a client for the given subscription.
+func New(cred azcore.TokenCredential, subscriptionID string) *Client {
+	return &Client{
+		credential:     cred,
+		subscriptionID: subscriptionID,
+		endpoint:       DefaultEndpoint,
+		httpClient:     http.DefaultClient,
+		pollInterval:   5 * time.Second,
+		timeout:        20 * time.Minute,
+	}
+}
+
+// Endpoint returns the URL requests are sent to.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// CloseIdleConnections closes the idle connections of the HTTP client
+// requests are sent with.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// SetEndpoint overrides the URL requests are sent to.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = strings.TrimRight(endpoint, "/")
+}
+
+// SetPolling sets how often long-running operations are checked and how long
+// to wait for them to finish.
+func (c *Client) SetPolling(interval, timeout time.Duration) {
+	c.pollInterval = interval
+	c.timeout = timeout
+}
+
+// ResourceGroupPath returns the path of a resource group in the client's
+// subscription, to which provider paths such as
+// "/providers/Microsoft.App/containerApps/my-app" are appended.
+func (c *Client) ResourceGroupPath(resourceGroup string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", c.subscriptionID, resourceGroup)
+}
+
+// APIError is an error returned by Azure Resource Manager.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s (HTTP %d)", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s (HTTP %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// IsNotFound reports whether err is an APIError for a resource that does not
+// exist.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsErrorCode reports whether err is an APIError with the given code.
+func IsErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Do sends a method request for path (for example
+// "/subscriptions/<id>/resourceGroups/my-rg") at the given API version. in,
+// when not nil, is marshalled as the request body and the response is
+// unmarshalled into out, which may be nil. Do does not wait for long-running
+// operations; use Run for requests that start one.
+func (c *Client) Do(ctx context.Context, method, path, apiVersion string, in, out interface{}) error {
+	resp, body, err := c.send(ctx, method, c.url(path, apiVersion), in)
+	if err != nil {
+		return err
+	}
+	return decode(resp, body, method, path, out)
+}
+
+// Run sends a request that may start a long-running operation, such as
+// creating or deleting a resource, and waits for the operation to finish.
+// For PUT and PATCH requests the resource is then read into out, which may
+// be nil.
+func (c *Client) Run(ctx context.Context, method, path, apiVersion string, in, out interface{}) error {
+	resp, body, err := c.send(ctx, method, c.url(path, apiVersion), in)
+	if err != nil {
+		return err
+	}
+
+	if err := c.wait(ctx, resp, method, path); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	if method == http.MethodPut || method == http.MethodPatch {
+		return c.Do(ctx, http.MethodGet, path, apiVersion, nil, out)
+	}
+	return decode(resp, body, method, path, out)
+}
+
+// wait follows the operation a response started, if any, until it finishes.
+func (c *Client) wait(ctx context.Context, resp *http.Response, method, path string) error {
+	statusURL := resp.Header.Get("Azure-AsyncOperation")
+	location := resp.Header.Get("Location")
+	if statusURL == "" && (location == "" || resp.StatusCode != http.StatusAccepted) {
+		return nil
+	}
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(c.timeout)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for %s %s to finish", method, path)
+		case <-ticker.C:
+		}
+
+		if statusURL != "" {
+			var op struct {
+				Status string `json:"status"`
+				Error  struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			resp, body, err := c.send(ctx, http.MethodGet, statusURL, nil)
+			if err != nil {
+				return fmt.Errorf("failed to check %s %s: %w", method, path, err)
+			}
+			if err := decode(resp, body, method, path, &op); err != nil {
+				return err
+			}
+			switch strings.ToLower(op.Status) {
+			case "succeeded":
+				return nil
+			case "failed", "canceled", "cancelled":
+				return &APIError{StatusCode: resp.StatusCode, Code: op.Error.Code, Message: fmt.Sprintf("%s %s %s: %s", method, path, strings.ToLower(op.Status), op.Error.Message)}
+			}
+			continue
+		}
+
+		resp, _, err := c.send(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return fmt.Errorf("%s %s failed: %w", method, path, err)
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			return nil
+		}
+	}
+}
+
+// url returns the URL of path at the given API version. Paths that are
+// already URLs, such as the next page of a list, are used as they are.
+func (c *Client) url(path, apiVersion string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return c.endpoint + path + sep + "api-version=" + apiVersion
+}
+
+// Token returns a Resource Manager access token. Other Azure endpoints that
+// accept it, such as the Kudu API of App Service apps, can be called with it.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	if c.credential == nil {
+		return "", fmt.Errorf("no Azure credentials configured")
+	}
+	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get Azure Resource Manager token: %w", err)
+	}
+	return token.Token, nil
+}
+
+// send authenticates and sends a request. On a non-2xx response it returns
+// an *APIError decoded from the ARM error body.
+func (c *Client) send(ctx context.Context, method, url string, in interface{}) (*http.Response, []byte, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reqBody io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+		var errBody struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &errBody) == nil && errBody.Error.Code != "" {
+			apiErr.Code = errBody.Error.Code
+			apiErr.Message = errBody.Error.Message
+		}
+		return resp, body, apiErr
+	}
+	return resp, body, nil
+}
+
+// decode unmarshals a response body into out, which may be nil.
+func decode(resp *http.Response, body []byte, method, path string, out interface{}) error {
+	if out == nil || len(body) == 0 || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
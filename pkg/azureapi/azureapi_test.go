@@ -0,0 +1,179 @@
+package azureapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// fakeCredential returns a fixed token.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// testClient returns a client for server that polls without waiting.
+func testClient(server *httptest.Server) *Client {
+	c := New(fakeCredential{}, "sub-123")
+	c.SetEndpoint(server.URL)
+	c.SetPolling(time.Millisecond, time.Second)
+	return c
+}
+
+func TestNewDefaultEndpoint(t *testing.T) {
+	c := New(fakeCredential{}, "sub-123")
+	if c.Endpoint() != DefaultEndpoint {
+		t.Errorf("unexpected endpoint: %s", c.Endpoint())
+	}
+	if got := c.ResourceGroupPath("my-rg"); got != "/subscriptions/sub-123/resourceGroups/my-rg" {
+		t.Errorf("ResourceGroupPath = %s", got)
+	}
+}
+
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("request was not authenticated")
+		}
+		if r.URL.Query().Get("api-version") != "2024-03-01" || r.URL.Query().Get("$filter") != "x" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Method != http.MethodPost || in["name"] != "my-app" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %v", r.Method, in)
+		}
+		w.Write([]byte(`{"name":"my-app","location":"eastus"}`))
+	}))
+	defer server.Close()
+
+	var out struct {
+		Location string `json:"location"`
+	}
+	err := testClient(server).Do(context.Background(), http.MethodPost, "/subscriptions/sub-123/resourceGroups/my-rg?$filter=x", "2024-03-01", map[string]string{"name": "my-app"}, &out)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	if out.Location != "eastus" {
+		t.Errorf("unexpected result: %+v", out)
+	}
+}
+
+func TestDoError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"ResourceNotFound","message":"The Resource 'my-app' was not found."}}`))
+	}))
+	defer server.Close()
+
+	err := testClient(server).Do(context.Background(), http.MethodGet, "/x", "2024-03-01", nil, nil)
+	if !IsNotFound(err) || !IsErrorCode(err, "ResourceNotFound") {
+		t.Fatalf("expected a ResourceNotFound error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "was not found") {
+		t.Errorf("error is missing the message: %v", err)
+	}
+}
+
+func TestRunAsyncOperation(t *testing.T) {
+	var polls int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/operations/op-1":
+			if atomic.AddInt32(&polls, 1) < 2 {
+				w.Write([]byte(`{"status":"InProgress"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"Succeeded"}`))
+		case r.Method == http.MethodPut:
+			w.Header().Set("Azure-AsyncOperation", server.URL+"/operations/op-1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"properties":{"provisioningState":"InProgress"}}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"properties":{"provisioningState":"Succeeded"}}`))
+		}
+	}))
+	defer server.Close()
+
+	var out struct {
+		Properties struct {
+			ProvisioningState string `json:"provisioningState"`
+		} `json:"properties"`
+	}
+	if err := testClient(server).Run(context.Background(), http.MethodPut, "/apps/my-app", "2024-03-01", map[string]string{}, &out); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if polls != 2 || out.Properties.ProvisioningState != "Succeeded" {
+		t.Errorf("expected 2 polls and the final resource, got %d polls and %+v", polls, out)
+	}
+}
+
+func TestRunFailedOperation(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/operations/op-1" {
+			w.Write([]byte(`{"status":"Failed","error":{"code":"InvalidImage","message":"image not found"}}`))
+			return
+		}
+		w.Header().Set("Azure-AsyncOperation", server.URL+"/operations/op-1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	err := testClient(server).Run(context.Background(), http.MethodPut, "/apps/my-app", "2024-03-01", map[string]string{}, nil)
+	if !IsErrorCode(err, "InvalidImage") || !strings.Contains(err.Error(), "image not found") {
+		t.Errorf("expected the operation's error, got %v", err)
+	}
+}
+
+func TestRunLocation(t *testing.T) {
+	var polls int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/operations/op-1" {
+			if atomic.AddInt32(&polls, 1) < 2 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Location", server.URL+"/operations/op-1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if err := testClient(server).Run(context.Background(), http.MethodDelete, "/apps/my-app", "2024-03-01", nil, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if polls != 2 {
+		t.Errorf("expected 2 polls, got %d", polls)
+	}
+}
+
+func TestNoCredential(t *testing.T) {
+	c := New(nil, "sub-123")
+	if err := c.Do(context.Background(), http.MethodGet, "/x", "2024-03-01", nil, nil); err == nil || !strings.Contains(err.Error(), "no Azure credentials") {
+		t.Errorf("expected a missing credentials error, got %v", err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	token, err := New(fakeCredential{}, "sub-123").Token(context.Background())
+	if err != nil || token != "test-token" {
+		t.Errorf("Token() = %q, %v", token, err)
+	}
+	if _, err := New(nil, "sub-123").Token(context.Background()); err == nil {
+		t.Error("expected an error without credentials")
+	}
+}
@@ -0,0 +1,205 @@
+// Package build builds container images from local source with the Docker CLI,
+// or with Cloud Native Buildpacks through the pack CLI for source without a
+// Dockerfile, so a deployment can go from a source checkout to a running
+// service in one command. Built images are loaded into the local Docker
+// daemon, where the registry distributor pushes them from like any other
+// local image.
+package build
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+)
+
+// DefaultDockerfile is built when the manifest names no Dockerfile.
+const DefaultDockerfile = "Dockerfile"
+
+// DefaultBuildpacksBuilder provides the buildpacks when the manifest names no
+// builder. Its Paketo buildpacks detect Node.js, Python, Go, Java, Ruby, PHP,
+// and .NET source.
+const DefaultBuildpacksBuilder = "paketobuildpacks/builder-jammy-base"
+
+// DefaultPlatform is built for when the manifest names no platform, so images
+// built on ARM machines (such as Apple silicon) run on every provider.
+var DefaultPlatform = registry.LinuxAMD64.String()
+
+// run runs the named CLI with args in dir, writing its output to out.
+// Replaced in tests.
+var run = func(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// ImageName returns the reference the manifest's source is built as: the
+// manifest's image when set, otherwise <application name>:<build time>.
+func ImageName(m *manifest.Manifest, now time.Time) string {
+	if m.Image != "" {
+		return m.Image
+	}
+	return strings.ToLower(m.Application.Name) + ":" + now.UTC().Format("20060102-150405")
+}
+
+// Args returns the docker CLI arguments building the source in contextDir
+// as image.
+func Args(cfg *manifest.BuildConfig, contextDir, image string) []string {
+	var args []string
+	if cfg.Builder == manifest.BuilderBuildKit {
+		// --load puts the image in the daemon instead of the build cache
+		args = []string{"buildx", "build", "--load"}
+	} else {
+		args = []string{"build"}
+	}
+
+	dockerfile := cfg.Dockerfile
+	if dockerfile == "" {
+		dockerfile = DefaultDockerfile
+	}
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(contextDir, dockerfile)
+	}
+	platform := cfg.Platform
+	if platform == "" {
+		platform = DefaultPlatform
+	}
+	args = append(args, "--file", dockerfile, "--tag", image, "--platform", platform)
+	if cfg.Target != "" {
+		args = append(args, "--target", cfg.Target)
+	}
+
+	// Sorted so the same manifest always runs the same command
+	for _, name := range slices.Sorted(maps.Keys(cfg.Args)) {
+		args = append(args, "--build-arg", name+"="+cfg.Args[name])
+	}
+	return append(args, contextDir)
+}
+
+// PackArgs returns the pack CLI arguments building the source in contextDir
+// as image with Cloud Native Buildpacks. Unlike docker builds, the platform is
+// left to the builder unless the manifest names one.
+func PackArgs(cfg *manifest.BuildConfig, contextDir, image string) []string {
+	builder := DefaultBuildpacksBuilder
+	var buildpacks []string
+	if cfg.Buildpacks != nil {
+		if cfg.Buildpacks.Builder != "" {
+			builder = cfg.Buildpacks.Builder
+		}
+		buildpacks = cfg.Buildpacks.Buildpacks
+	}
+
+	args := []string{"build", image, "--path", contextDir, "--builder", builder}
+	for _, bp := range buildpacks {
+		args = append(args, "--buildpack", bp)
+	}
+	if cfg.Platform != "" {
+		args = append(args, "--platform", cfg.Platform)
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Args)) {
+		args = append(args, "--env", name+"="+cfg.Args[name])
+	}
+	return args
+}
+
+// Build builds the image of the manifest's source as image. The output of the
+// build is logged line by line. With more than one of the manifest's
+// platforms, the image is built for each as registry.PlatformImage names it.
+func Build(ctx context.Context, m *manifest.Manifest, image string) error {
+	source := m.Deployment.Source
+	if source.Build == nil {
+		return fmt.Errorf("the manifest has no deployment.source.build")
+	}
+	if info, err := os.Stat(source.Path); err != nil || !info.IsDir() {
+		return fmt.Errorf("source path %s is not a directory", source.Path)
+	}
+
+	if len(m.Platforms) == 1 {
+		cfg := *source.Build
+		cfg.Platform = m.Platforms[0]
+		return build(ctx, &cfg, source.Path, image)
+	}
+	if m.IsMultiArch() {
+		// The Docker daemon keeps one platform per tag, so each platform is
+		// built as its own image, which the registry distributor assembles
+		// into a multi-arch image index
+		for _, platform := range m.Platforms {
+			cfg := *source.Build
+			cfg.Platform = platform
+			if err := build(ctx, &cfg, source.Path, registry.PlatformImage(image, platform)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return build(ctx, source.Build, source.Path, image)
+}
+
+// build builds the source in contextDir as image, as cfg configures.
+func build(ctx context.Context, cfg *manifest.BuildConfig, contextDir, image string) error {
+	tool, cli, args := "docker", "Docker CLI", Args(cfg, contextDir, image)
+	if cfg.Strategy == manifest.BuildStrategyBuildpacks {
+		tool, cli, args = "pack", "pack CLI", PackArgs(cfg, contextDir, image)
+	}
+
+	logging.Info("Building image from source", "image", image, "source", contextDir, "tool", tool)
+	out := &lineLogger{}
+	err := run(ctx, contextDir, out, tool, args...)
+	out.flush()
+	if err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			return fmt.Errorf("failed to run %s (is the %s installed and on PATH?): %w", tool, cli, err)
+		}
+		return fmt.Errorf("failed to build image %s: %w", image, err)
+	}
+	logging.Info("Built image", "image", image)
+	return nil
+}
+
+// lineLogger logs what is written to it, one message per line.
+type lineLogger struct {
+	buf bytes.Buffer
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf.Write(p)
+	for {
+		line, err := l.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			l.buf.Reset()
+			l.buf.WriteString(line)
+			return len(p), nil
+		}
+		l.log(line)
+	}
+}
+
+// flush logs the last line when it did not end with a newline.
+func (l *lineLogger) flush() {
+	if l.buf.Len() > 0 {
+		l.log(l.buf.String())
+		l.buf.Reset()
+	}
+}
+
+func (l *lineLogger) log(line string) {
+	if line = strings.TrimRight(line, "\r\n"); line != "" {
+		logging.Info("  " + line)
+	}
+}
@@ -0,0 +1,172 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestImageName(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 45, 0, time.FixedZone("EST", -5*3600))
+	m := &manifest.Manifest{Application: manifest.ApplicationConfig{Name: "My-App"}}
+	if got := ImageName(m, now); got != "my-app:20240501-173045" {
+		t.Errorf("ImageName() = %q, want my-app:20240501-173045", got)
+	}
+
+	m.Image = "registry.example.com/my-app:1.2.3"
+	if got := ImageName(m, now); got != m.Image {
+		t.Errorf("ImageName() = %q, want the manifest's image", got)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	got := Args(&manifest.BuildConfig{}, "/src", "my-app:1")
+	want := []string{"build", "--file", filepath.Join("/src", "Dockerfile"), "--tag", "my-app:1", "--platform", "linux/amd64", "/src"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Args() = %v, want %v", got, want)
+	}
+
+	got = Args(&manifest.BuildConfig{
+		Builder:    manifest.BuilderBuildKit,
+		Dockerfile: "docker/Dockerfile.prod",
+		Target:     "runtime",
+		Platform:   "linux/arm64",
+		Args:       map[string]string{"VERSION": "1.2.3", "COMMIT": "abc"},
+	}, "/src", "my-app:1")
+	want = []string{"buildx", "build", "--load",
+		"--file", filepath.Join("/src", "docker/Dockerfile.prod"), "--tag", "my-app:1", "--platform", "linux/arm64",
+		"--target", "runtime", "--build-arg", "COMMIT=abc", "--build-arg", "VERSION=1.2.3", "/src"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Args() = %v, want %v", got, want)
+	}
+}
+
+func TestPackArgs(t *testing.T) {
+	got := PackArgs(&manifest.BuildConfig{Strategy: manifest.BuildStrategyBuildpacks}, "/src", "my-app:1")
+	want := []string{"build", "my-app:1", "--path", "/src", "--builder", DefaultBuildpacksBuilder}
+	if !slices.Equal(got, want) {
+		t.Errorf("PackArgs() = %v, want %v", got, want)
+	}
+
+	got = PackArgs(&manifest.BuildConfig{
+		Strategy: manifest.BuildStrategyBuildpacks,
+		Platform: "linux/arm64",
+		Args:     map[string]string{"BP_NODE_VERSION": "20", "BP_LOG_LEVEL": "DEBUG"},
+		Buildpacks: &manifest.BuildpacksConfig{
+			Builder:    "heroku/builder:24",
+			Buildpacks: []string{"heroku/nodejs"},
+		},
+	}, "/src", "my-app:1")
+	want = []string{"build", "my-app:1", "--path", "/src", "--builder", "heroku/builder:24",
+		"--buildpack", "heroku/nodejs", "--platform", "linux/arm64",
+		"--env", "BP_LOG_LEVEL=DEBUG", "--env", "BP_NODE_VERSION=20"}
+	if !slices.Equal(got, want) {
+		t.Errorf("PackArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	m := &manifest.Manifest{Deployment: manifest.DeploymentConfig{Source: manifest.SourceConfig{
+		Type:  "local",
+		Path:  dir,
+		Build: &manifest.BuildConfig{},
+	}}}
+
+	var gotDir, gotTool string
+	var gotArgs []string
+	defer func(f func(context.Context, string, io.Writer, string, ...string) error) { run = f }(run)
+	run = func(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+		gotDir, gotTool, gotArgs = dir, name, args
+		fmt.Fprint(out, "Step 1/2 : FROM alpine\nStep 2/2 : COPY . /app")
+		return nil
+	}
+	if err := Build(context.Background(), m, "my-app:1"); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if gotDir != dir || gotTool != "docker" || gotArgs[0] != "build" || gotArgs[len(gotArgs)-1] != dir {
+		t.Errorf("Unexpected %s invocation in %s: %v", gotTool, gotDir, gotArgs)
+	}
+
+	m.Deployment.Source.Build.Strategy = manifest.BuildStrategyBuildpacks
+	if err := Build(context.Background(), m, "my-app:1"); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if gotTool != "pack" || gotArgs[1] != "my-app:1" {
+		t.Errorf("Expected a pack build, got %s %v", gotTool, gotArgs)
+	}
+	run = func(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+		return &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	if err := Build(context.Background(), m, "my-app:1"); err == nil || !strings.Contains(err.Error(), "is the pack CLI") {
+		t.Errorf("Expected a missing pack error, got: %v", err)
+	}
+	m.Deployment.Source.Build.Strategy = ""
+
+	run = func(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+		return errors.New("exit status 1")
+	}
+	if err := Build(context.Background(), m, "my-app:1"); err == nil || !strings.Contains(err.Error(), "failed to build image my-app:1") {
+		t.Errorf("Expected a build failure, got: %v", err)
+	}
+
+	run = func(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+		return &exec.Error{Name: "docker", Err: exec.ErrNotFound}
+	}
+	if err := Build(context.Background(), m, "my-app:1"); err == nil || !strings.Contains(err.Error(), "is the Docker CLI installed") {
+		t.Errorf("Expected a missing docker error, got: %v", err)
+	}
+
+	var built []string
+	run = func(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+		built = append(built, strings.Join(args, " "))
+		return nil
+	}
+	m.Platforms = []string{"linux/amd64", "linux/arm64"}
+	if err := Build(context.Background(), m, "my-app:1"); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(built) != 2 || !strings.Contains(built[0], "--tag my-app:1-linux-amd64 --platform linux/amd64") || !strings.Contains(built[1], "--tag my-app:1-linux-arm64 --platform linux/arm64") {
+		t.Errorf("Expected a build per platform, got %v", built)
+	}
+	built = nil
+	m.Platforms = []string{"linux/arm64"}
+	if err := Build(context.Background(), m, "my-app:1"); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(built) != 1 || !strings.Contains(built[0], "--tag my-app:1 --platform linux/arm64") {
+		t.Errorf("Expected one linux/arm64 build, got %v", built)
+	}
+	m.Platforms = nil
+
+	m.Deployment.Source.Path = filepath.Join(dir, "missing")
+	if err := Build(context.Background(), m, "my-app:1"); err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Errorf("Expected a missing source error, got: %v", err)
+	}
+}
+
+func TestLineLogger(t *testing.T) {
+	l := &lineLogger{}
+	l.Write([]byte("first\nsec"))
+	if got := l.buf.String(); got != "sec" {
+		t.Errorf("Expected the incomplete line to be kept, got %q", got)
+	}
+	l.Write([]byte("ond\n"))
+	if l.buf.Len() != 0 {
+		t.Errorf("Expected complete lines to be logged, %q left", l.buf.String())
+	}
+	l.Write([]byte("last"))
+	l.flush()
+	if l.buf.Len() != 0 {
+		t.Errorf("Expected flush to log the last line, %q left", l.buf.String())
+	}
+}
@@ -0,0 +1,154 @@
+// Package cache remembers, across invocations, setup checks that passed and
+// rarely change, such as whether a GCP project is ready for deployments, so
+// repeat deploys can skip them. The cache is cache.json in the cloud-deploy
+// directory of the user's cache directory (e.g.,
+// ~/.cache/cloud-deploy/cache.json), or the file named by CLOUD_DEPLOY_CACHE.
+// With -refresh, the checks run again and the cache is updated.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EnvPath names the cache file to use instead of the default.
+const EnvPath = "CLOUD_DEPLOY_CACHE"
+
+// DefaultTTL is how long a passed check is trusted.
+const DefaultTTL = 24 * time.Hour
+
+// maxAge is how long entries are kept in the file at all.
+const maxAge = 30 * 24 * time.Hour
+
+var (
+	// refresh is set by Refresh
+	refresh atomic.Bool
+
+	// mu serializes changes to the file by concurrent deployments
+	mu sync.Mutex
+
+	// now returns the current time. It is a variable so tests can replace it.
+	now = time.Now
+)
+
+// Refresh makes Fresh report every check stale for the rest of the process,
+// so they all run again and are marked anew.
+func Refresh() {
+	refresh.Store(true)
+}
+
+// Path returns the location of the cache file.
+func Path() (string, error) {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cloud-deploy", "cache.json"), nil
+}
+
+// Fresh reports whether the check named key passed within ttl. A missing or
+// unreadable cache has no fresh checks.
+func Fresh(key string, ttl time.Duration) bool {
+	if refresh.Load() {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	entries, err := load()
+	if err != nil {
+		return false
+	}
+	passed, ok := entries[key]
+	return ok && now().Sub(passed) < ttl
+}
+
+// Mark records that the check named key passed now.
+func Mark(key string) error {
+	return update(func(entries map[string]time.Time) {
+		entries[key] = now()
+	})
+}
+
+// Forget removes the check named key, so it runs again.
+func Forget(key string) error {
+	return update(func(entries map[string]time.Time) {
+		delete(entries, key)
+	})
+}
+
+// update changes the entries of the cache file with change, dropping those
+// older than maxAge.
+func update(change func(map[string]time.Time)) error {
+	mu.Lock()
+	defer mu.Unlock()
+	entries, err := load()
+	if err != nil {
+		// An unreadable cache is replaced
+		entries = map[string]time.Time{}
+	}
+	change(entries)
+	for key, passed := range entries {
+		if now().Sub(passed) > maxAge {
+			delete(entries, key)
+		}
+	}
+	return save(entries)
+}
+
+// load reads the entries of the cache file, by key.
+func load() (map[string]time.Time, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]time.Time{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// save replaces the cache file with entries, through a temporary file so
+// other processes never read a partial one.
+func save(entries map[string]time.Time) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
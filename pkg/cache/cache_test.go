@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useCache points the cache at a file in a temporary directory and fixes the
+// time until the test ends.
+func useCache(t *testing.T, at time.Time) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cloud-deploy", "cache.json")
+	t.Setenv(EnvPath, path)
+	original := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() {
+		now = original
+		refresh.Store(false)
+	})
+	return path
+}
+
+func TestMarkAndFresh(t *testing.T) {
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	path := useCache(t, start)
+
+	if Fresh("gcp-project-ready:my-project", DefaultTTL) {
+		t.Error("Expected no fresh checks without a cache")
+	}
+	if err := Mark("gcp-project-ready:my-project"); err != nil {
+		t.Fatalf("Mark failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the cache file to be written: %v", err)
+	}
+	if !Fresh("gcp-project-ready:my-project", DefaultTTL) {
+		t.Error("Expected a marked check to be fresh")
+	}
+	if Fresh("gcp-project-ready:other-project", DefaultTTL) {
+		t.Error("Expected other checks not to be fresh")
+	}
+
+	now = func() time.Time { return start.Add(DefaultTTL + time.Second) }
+	if Fresh("gcp-project-ready:my-project", DefaultTTL) {
+		t.Error("Expected the check to be stale after its TTL")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	useCache(t, time.Now())
+	if err := Mark("key"); err != nil {
+		t.Fatal(err)
+	}
+	Refresh()
+	if Fresh("key", DefaultTTL) {
+		t.Error("Expected every check to be stale after Refresh")
+	}
+}
+
+func TestForgetAndPrune(t *testing.T) {
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	useCache(t, start)
+	if err := Mark("old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Mark("forgotten"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Forget("forgotten"); err != nil {
+		t.Fatalf("Forget failed: %v", err)
+	}
+	if Fresh("forgotten", DefaultTTL) {
+		t.Error("Expected a forgotten check not to be fresh")
+	}
+
+	now = func() time.Time { return start.Add(maxAge + time.Hour) }
+	if err := Mark("new"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entries["old"]; ok || len(entries) != 1 {
+		t.Errorf("Expected entries older than %s to be dropped, got %v", maxAge, entries)
+	}
+}
+
+func TestCorruptCache(t *testing.T) {
+	path := useCache(t, time.Now())
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if Fresh("key", DefaultTTL) {
+		t.Error("Expected a corrupt cache to have no fresh checks")
+	}
+	if err := Mark("key"); err != nil {
+		t.Fatalf("Expected a corrupt cache to be replaced, got: %v", err)
+	}
+	if !Fresh("key", DefaultTTL) {
+		t.Error("Expected the replaced cache to hold the check")
+	}
+}
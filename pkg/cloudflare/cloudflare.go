@@ -0,0 +1,193 @@
+// Package cloudflare manages the Cloudflare resources in front of a deployment
+// through the Cloudflare API v4, authenticating with an API token, so
+// cloud-deploy needs no Cloudflare SDK module.
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultEndpoint is the base URL of the Cloudflare API.
+const DefaultEndpoint = "https://api.cloudflare.com/client/v4"
+
+// Client calls the Cloudflare API.
+type Client struct {
+	token      string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// New creates a client that authenticates with an API token.
+func New(token string) *Client {
+	return &Client{
+		token:      token,
+		endpoint:   DefaultEndpoint,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// SetEndpoint overrides the URL requests are sent to.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = strings.TrimRight(endpoint, "/")
+}
+
+// APIError is an error returned by the Cloudflare API.
+type APIError struct {
+	StatusCode int
+	Messages   []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("Cloudflare API error (HTTP %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("Cloudflare API error (HTTP %d): %s", e.StatusCode, strings.Join(e.Messages, "; "))
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends a request for path (including any query string). in is marshalled
+// as the JSON body and the result of the response envelope is unmarshalled
+// into out; either may be nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	if in == nil {
+		return c.send(ctx, method, path, "", nil, out)
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+	}
+	return c.send(ctx, method, path, "application/json", bytes.NewReader(data), out)
+}
+
+// send sends a request for path with a body of the given content type, which
+// may be nil, and unmarshals the result of the response envelope into out.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to Cloudflare failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var envelope struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}
+	decodeErr := json.Unmarshal(respBody, &envelope)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || (decodeErr == nil && !envelope.Success) {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		for _, e := range envelope.Errors {
+			apiErr.Messages = append(apiErr.Messages, fmt.Sprintf("%s (code %d)", e.Message, e.Code))
+		}
+		return apiErr
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, decodeErr)
+	}
+	if out == nil || len(envelope.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// ZoneID returns the ID of the zone of domain (e.g., example.com).
+func (c *Client) ZoneID(ctx context.Context, domain string) (string, error) {
+	var zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/zones?"+url.Values{"name": {domain}}.Encode(), nil, &zones); err != nil {
+		return "", fmt.Errorf("failed to look up zone %s: %w", domain, err)
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone %s not found (check the domain and that the API token can read it)", domain)
+	}
+	return zones[0].ID, nil
+}
+
+// DNSRecord is a DNS record of a zone.
+type DNSRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	Proxied *bool  `json:"proxied,omitempty"`
+
+	// Time to live in seconds; 1 is automatic
+	TTL int `json:"ttl,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// ListDNSRecords returns the zone's records of the given name and type.
+func (c *Client) ListDNSRecords(ctx context.Context, zoneID, name, recordType string) ([]DNSRecord, error) {
+	query := url.Values{"name": {name}, "type": {recordType}, "per_page": {"100"}}
+	var records []DNSRecord
+	if err := c.do(ctx, http.MethodGet, "/zones/"+url.PathEscape(zoneID)+"/dns_records?"+query.Encode(), nil, &records); err != nil {
+		return nil, fmt.Errorf("failed to list %s records of %s: %w", recordType, name, err)
+	}
+	return records, nil
+}
+
+// CreateDNSRecord creates a record in the zone.
+func (c *Client) CreateDNSRecord(ctx context.Context, zoneID string, record DNSRecord) (*DNSRecord, error) {
+	record.ID = ""
+	var created DNSRecord
+	if err := c.do(ctx, http.MethodPost, "/zones/"+url.PathEscape(zoneID)+"/dns_records", record, &created); err != nil {
+		return nil, fmt.Errorf("failed to create %s record %s: %w", record.Type, record.Name, err)
+	}
+	return &created, nil
+}
+
+// UpdateDNSRecord replaces the record with the given ID.
+func (c *Client) UpdateDNSRecord(ctx context.Context, zoneID, id string, record DNSRecord) (*DNSRecord, error) {
+	record.ID = ""
+	var updated DNSRecord
+	if err := c.do(ctx, http.MethodPut, "/zones/"+url.PathEscape(zoneID)+"/dns_records/"+url.PathEscape(id), record, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update %s record %s: %w", record.Type, record.Name, err)
+	}
+	return &updated, nil
+}
+
+// DeleteDNSRecord deletes the record with the given ID. Records that no longer
+// exist are not an error.
+func (c *Client) DeleteDNSRecord(ctx context.Context, zoneID, id string) error {
+	err := c.do(ctx, http.MethodDelete, "/zones/"+url.PathEscape(zoneID)+"/dns_records/"+url.PathEscape(id), nil, nil)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to delete DNS record %s: %w", id, err)
+	}
+	return nil
+}
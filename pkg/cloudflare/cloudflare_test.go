@@ -0,0 +1,81 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestZoneID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"success":false,"errors":[{"code":9109,"message":"Invalid access token"}]}`))
+			return
+		}
+		if r.URL.Path != "/zones" {
+			http.NotFound(w, r)
+			return
+		}
+		result := "[]"
+		if r.URL.Query().Get("name") == "example.com" {
+			result = `[{"id":"zone-123","name":"example.com"}]`
+		}
+		w.Write([]byte(`{"success":true,"errors":[],"result":` + result + `}`))
+	}))
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL + "/")
+
+	id, err := c.ZoneID(context.Background(), "example.com")
+	if err != nil || id != "zone-123" {
+		t.Errorf("ZoneID = %q, %v; want zone-123", id, err)
+	}
+	if _, err := c.ZoneID(context.Background(), "example.org"); err == nil || !strings.Contains(err.Error(), "zone example.org not found") {
+		t.Errorf("Expected a missing zone error, got: %v", err)
+	}
+
+	c = New("wrong-token")
+	c.SetEndpoint(server.URL)
+	_, err = c.ZoneID(context.Background(), "example.com")
+	if err == nil || !strings.Contains(err.Error(), "HTTP 403): Invalid access token (code 9109)") {
+		t.Errorf("Expected the API error, got: %v", err)
+	}
+}
+
+func TestUnsuccessfulResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":false,"errors":[{"code":81053,"message":"An A, AAAA, or CNAME record with that host already exists."}]}`))
+	}))
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	_, err := c.CreateDNSRecord(context.Background(), "zone-123", DNSRecord{Type: "CNAME", Name: "app.example.com", Content: "target.example.net"})
+	if err == nil || !strings.Contains(err.Error(), "record with that host already exists") {
+		t.Errorf("Expected an unsuccessful response to fail, got: %v", err)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []interface{}{}})
+	}))
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	_, err := c.UpdateDNSRecord(context.Background(), "zone-123", "missing", DNSRecord{Type: "A", Name: "app.example.com", Content: "203.0.113.10"})
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got: %v", err)
+	}
+
+	if err := c.DeleteDNSRecord(context.Background(), "zone-123", "missing"); err != nil {
+		t.Errorf("Expected deleting a missing record to succeed, got: %v", err)
+	}
+}
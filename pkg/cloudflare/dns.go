@@ -0,0 +1,248 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Actions taken on DNS records.
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionUnchanged = "unchanged"
+)
+
+// Change is what SyncDNSRecords did to a record.
+type Change struct {
+	Action string
+	Record DNSRecord
+}
+
+// SyncDNSRecords creates or updates the records of cfg, pointing records
+// without content at the host of deploymentURL. CNAME, A, and AAAA records
+// replace the existing record of their name and type; TXT records are added
+// next to existing ones, as a name can have several.
+func (c *Client) SyncDNSRecords(ctx context.Context, cfg *manifest.CloudflareConfig, deploymentURL string) ([]Change, error) {
+	records, err := DesiredRecords(cfg, deploymentURL)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneID := cfg.ZoneID
+	if zoneID == "" {
+		if zoneID, err = c.ZoneID(ctx, cfg.Domain); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []Change
+	for _, record := range records {
+		change, err := c.syncRecord(ctx, zoneID, record)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// FindDNSRecords returns the existing records that cfg manages. Records without
+// a type may be CNAME, A, or AAAA records, and records with content only match
+// records with that content, so TXT records of the same name that were not
+// created for the manifest are left out.
+func (c *Client) FindDNSRecords(ctx context.Context, cfg *manifest.CloudflareConfig) ([]DNSRecord, error) {
+	zoneID := cfg.ZoneID
+	if zoneID == "" {
+		var err error
+		if zoneID, err = c.ZoneID(ctx, cfg.Domain); err != nil {
+			return nil, err
+		}
+	}
+	return c.findRecords(ctx, zoneID, cfg)
+}
+
+// RemoveDNSRecords deletes the existing records that cfg manages, as found by
+// FindDNSRecords, and returns the deleted records.
+func (c *Client) RemoveDNSRecords(ctx context.Context, cfg *manifest.CloudflareConfig) ([]DNSRecord, error) {
+	zoneID := cfg.ZoneID
+	if zoneID == "" {
+		var err error
+		if zoneID, err = c.ZoneID(ctx, cfg.Domain); err != nil {
+			return nil, err
+		}
+	}
+	records, err := c.findRecords(ctx, zoneID, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []DNSRecord
+	for _, record := range records {
+		if err := c.DeleteDNSRecord(ctx, zoneID, record.ID); err != nil {
+			return removed, fmt.Errorf("failed to remove %s record %s: %w", record.Type, record.Name, err)
+		}
+		removed = append(removed, record)
+	}
+	return removed, nil
+}
+
+// findRecords returns the existing records of the zone that cfg manages.
+func (c *Client) findRecords(ctx context.Context, zoneID string, cfg *manifest.CloudflareConfig) ([]DNSRecord, error) {
+	var found []DNSRecord
+	seen := make(map[string]bool)
+	for _, r := range cfg.DNSRecords {
+		recordTypes := []string{r.Type}
+		if r.Type == "" {
+			recordTypes = []string{"CNAME", "A", "AAAA"}
+		}
+		for _, recordType := range recordTypes {
+			records, err := c.ListDNSRecords(ctx, zoneID, recordName(r.Name, cfg.Domain), recordType)
+			if err != nil {
+				return found, err
+			}
+			for _, record := range records {
+				if seen[record.ID] || (r.Content != "" && record.Content != r.Content) {
+					continue
+				}
+				seen[record.ID] = true
+				found = append(found, record)
+			}
+		}
+	}
+	return found, nil
+}
+
+// syncRecord creates or updates one record.
+func (c *Client) syncRecord(ctx context.Context, zoneID string, record DNSRecord) (Change, error) {
+	existing, err := c.ListDNSRecords(ctx, zoneID, record.Name, record.Type)
+	if err != nil {
+		return Change{}, err
+	}
+
+	if record.Type == "TXT" {
+		for _, e := range existing {
+			if e.Content == record.Content {
+				return Change{Action: ActionUnchanged, Record: e}, nil
+			}
+		}
+		existing = nil
+	}
+
+	if len(existing) == 0 {
+		created, err := c.CreateDNSRecord(ctx, zoneID, record)
+		if err != nil {
+			return Change{}, err
+		}
+		return Change{Action: ActionCreated, Record: *created}, nil
+	}
+
+	current := existing[0]
+	if sameRecord(current, record) {
+		return Change{Action: ActionUnchanged, Record: current}, nil
+	}
+	updated, err := c.UpdateDNSRecord(ctx, zoneID, current.ID, record)
+	if err != nil {
+		return Change{}, err
+	}
+	return Change{Action: ActionUpdated, Record: *updated}, nil
+}
+
+// sameRecord reports whether an existing record already has the desired
+// content, proxying, and TTL.
+func sameRecord(current, desired DNSRecord) bool {
+	return strings.EqualFold(strings.TrimSuffix(current.Content, "."), strings.TrimSuffix(desired.Content, ".")) &&
+		isProxied(current) == isProxied(desired) &&
+		current.TTL == desired.TTL
+}
+
+// isProxied reports whether traffic to the record goes through Cloudflare.
+func isProxied(r DNSRecord) bool {
+	return r.Proxied != nil && *r.Proxied
+}
+
+// DesiredRecords returns the records of cfg with their defaults applied:
+// names are completed with the zone's domain, and records without content
+// point at the host of deploymentURL (an A or AAAA record for an IP address,
+// otherwise a CNAME), proxied through Cloudflare with an automatic TTL.
+func DesiredRecords(cfg *manifest.CloudflareConfig, deploymentURL string) ([]DNSRecord, error) {
+	var host string
+	records := make([]DNSRecord, 0, len(cfg.DNSRecords))
+	for _, r := range cfg.DNSRecords {
+		record := DNSRecord{
+			Type:    r.Type,
+			Name:    recordName(r.Name, cfg.Domain),
+			Content: r.Content,
+			TTL:     r.TTL,
+		}
+
+		if record.Content == "" {
+			if host == "" {
+				var err error
+				if host, err = urlHost(deploymentURL); err != nil {
+					return nil, fmt.Errorf("cannot point %s at the deployment: %w", record.Name, err)
+				}
+			}
+			record.Content = host
+		}
+		if record.Type == "" {
+			record.Type = addressType(record.Content)
+		}
+		if record.TTL == 0 {
+			record.TTL = 1
+		}
+
+		proxied := record.Type != "TXT"
+		if r.Proxied != nil {
+			proxied = *r.Proxied
+		}
+		record.Proxied = &proxied
+
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// recordName returns name in full: names that are not already within domain
+// are relative to it.
+func recordName(name, domain string) string {
+	name = strings.TrimSuffix(name, ".")
+	if domain == "" || name == domain || strings.HasSuffix(name, "."+domain) {
+		return name
+	}
+	return name + "." + domain
+}
+
+// urlHost returns the host name of a deployment URL, which providers report
+// with or without a scheme.
+func urlHost(deploymentURL string) (string, error) {
+	if deploymentURL == "" {
+		return "", fmt.Errorf("the deployment has no URL")
+	}
+	if !strings.Contains(deploymentURL, "://") {
+		deploymentURL = "http://" + deploymentURL
+	}
+	u, err := url.Parse(deploymentURL)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("no host in deployment URL %q", deploymentURL)
+	}
+	return u.Hostname(), nil
+}
+
+// addressType returns the record type pointing a name at content: A or AAAA
+// for IP addresses, otherwise CNAME.
+func addressType(content string) string {
+	ip := net.ParseIP(content)
+	switch {
+	case ip == nil:
+		return "CNAME"
+	case ip.To4() != nil:
+		return "A"
+	default:
+		return "AAAA"
+	}
+}
@@ -0,0 +1,236 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeZone serves the DNS record endpoints of one zone from memory.
+type fakeZone struct {
+	mu      sync.Mutex
+	records map[string]DNSRecord
+	writes  int
+}
+
+func (z *fakeZone) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	const prefix = "/zones/zone-123/dns_records"
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "errors": []interface{}{}, "result": result})
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == prefix:
+		matches := []DNSRecord{}
+		for _, rec := range z.records {
+			if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+				matches = append(matches, rec)
+			}
+		}
+		reply(matches)
+	case r.Method == http.MethodPost && r.URL.Path == prefix:
+		var rec DNSRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = fmt.Sprintf("rec-%d", len(z.records)+1)
+		z.records[rec.ID] = rec
+		z.writes++
+		reply(rec)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, prefix+"/"):
+		id := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		var rec DNSRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		rec.ID = id
+		z.records[id] = rec
+		z.writes++
+		reply(rec)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, prefix+"/"):
+		delete(z.records, strings.TrimPrefix(r.URL.Path, prefix+"/"))
+		z.writes++
+		reply(map[string]string{"id": strings.TrimPrefix(r.URL.Path, prefix+"/")})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSyncDNSRecords(t *testing.T) {
+	proxied := true
+	zone := &fakeZone{records: map[string]DNSRecord{
+		"rec-old": {ID: "rec-old", Type: "CNAME", Name: "app.example.com", Content: "old-env.us-east-1.elasticbeanstalk.com", Proxied: &proxied, TTL: 1},
+		"rec-txt": {ID: "rec-txt", Type: "TXT", Name: "_verify.example.com", Content: "other-verification"},
+	}}
+	server := httptest.NewServer(zone)
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	cfg := &manifest.CloudflareConfig{
+		ZoneID: "zone-123",
+		Domain: "example.com",
+		DNSRecords: []manifest.DNSRecordConfig{
+			{Name: "app"},
+			{Name: "_verify", Type: "TXT", Content: "google-site-verification=abc"},
+		},
+	}
+
+	changes, err := c.SyncDNSRecords(context.Background(), cfg, "http://my-app-prod.us-east-1.elasticbeanstalk.com")
+	if err != nil {
+		t.Fatalf("SyncDNSRecords failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Action != ActionUpdated || changes[1].Action != ActionCreated {
+		t.Fatalf("Expected the CNAME to be updated and the TXT record created, got %+v", changes)
+	}
+	if got := zone.records["rec-old"].Content; got != "my-app-prod.us-east-1.elasticbeanstalk.com" {
+		t.Errorf("CNAME content = %q, want the deployment's host", got)
+	}
+	if _, ok := zone.records["rec-txt"]; !ok {
+		t.Error("Existing TXT record of the name was replaced")
+	}
+
+	writes := zone.writes
+	changes, err = c.SyncDNSRecords(context.Background(), cfg, "my-app-prod.us-east-1.elasticbeanstalk.com")
+	if err != nil {
+		t.Fatalf("SyncDNSRecords failed: %v", err)
+	}
+	for _, change := range changes {
+		if change.Action != ActionUnchanged {
+			t.Errorf("Expected no changes on the second sync, got %+v", change)
+		}
+	}
+	if zone.writes != writes {
+		t.Errorf("Expected no writes on the second sync, got %d", zone.writes-writes)
+	}
+}
+
+func TestFindDNSRecords(t *testing.T) {
+	zone := &fakeZone{records: map[string]DNSRecord{
+		"rec-a":     {ID: "rec-a", Type: "A", Name: "app.example.com", Content: "203.0.113.10"},
+		"rec-txt":   {ID: "rec-txt", Type: "TXT", Name: "_verify.example.com", Content: "google-site-verification=abc"},
+		"rec-other": {ID: "rec-other", Type: "TXT", Name: "_verify.example.com", Content: "other-verification"},
+		"rec-www":   {ID: "rec-www", Type: "CNAME", Name: "www.example.com", Content: "app.example.com"},
+	}}
+	server := httptest.NewServer(zone)
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	cfg := &manifest.CloudflareConfig{
+		ZoneID: "zone-123",
+		Domain: "example.com",
+		DNSRecords: []manifest.DNSRecordConfig{
+			{Name: "app"},
+			{Name: "_verify", Type: "TXT", Content: "google-site-verification=abc"},
+			{Name: "api"},
+		},
+	}
+
+	records, err := c.FindDNSRecords(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("FindDNSRecords failed: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "rec-a" || records[1].ID != "rec-txt" {
+		t.Errorf("Expected the A record and the manifest's TXT record, got %+v", records)
+	}
+}
+
+func TestRemoveDNSRecords(t *testing.T) {
+	zone := &fakeZone{records: map[string]DNSRecord{
+		"rec-cname": {ID: "rec-cname", Type: "CNAME", Name: "app.example.com", Content: "my-app-prod.us-east-1.elasticbeanstalk.com"},
+		"rec-txt":   {ID: "rec-txt", Type: "TXT", Name: "_verify.example.com", Content: "google-site-verification=abc"},
+		"rec-other": {ID: "rec-other", Type: "TXT", Name: "_verify.example.com", Content: "other-verification"},
+	}}
+	server := httptest.NewServer(zone)
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	cfg := &manifest.CloudflareConfig{
+		ZoneID: "zone-123",
+		Domain: "example.com",
+		DNSRecords: []manifest.DNSRecordConfig{
+			{Name: "app"},
+			{Name: "_verify", Type: "TXT", Content: "google-site-verification=abc"},
+		},
+	}
+
+	removed, err := c.RemoveDNSRecords(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("RemoveDNSRecords failed: %v", err)
+	}
+	if len(removed) != 2 || removed[0].ID != "rec-cname" || removed[1].ID != "rec-txt" {
+		t.Errorf("Expected the CNAME and the manifest's TXT record to be removed, got %+v", removed)
+	}
+	if len(zone.records) != 1 || zone.records["rec-other"].ID == "" {
+		t.Errorf("Expected only the other TXT record to remain, got %+v", zone.records)
+	}
+
+	// Removing again finds nothing to remove
+	if removed, err := c.RemoveDNSRecords(context.Background(), cfg); err != nil || len(removed) != 0 {
+		t.Errorf("RemoveDNSRecords = %+v, %v; want nothing removed", removed, err)
+	}
+}
+
+func TestDesiredRecords(t *testing.T) {
+	notProxied := false
+	cfg := &manifest.CloudflareConfig{
+		Domain: "example.com",
+		DNSRecords: []manifest.DNSRecordConfig{
+			{Name: "app.example.com"},
+			{Name: "api", Proxied: &notProxied, TTL: 300},
+			{Name: "example.com", Type: "TXT", Content: "v=spf1 -all"},
+		},
+	}
+
+	records, err := DesiredRecords(cfg, "http://203.0.113.10:8080")
+	if err != nil {
+		t.Fatalf("DesiredRecords failed: %v", err)
+	}
+	want := []struct {
+		typ, name, content string
+		proxied            bool
+		ttl                int
+	}{
+		{"A", "app.example.com", "203.0.113.10", true, 1},
+		{"A", "api.example.com", "203.0.113.10", false, 300},
+		{"TXT", "example.com", "v=spf1 -all", false, 1},
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.Type != w.typ || r.Name != w.name || r.Content != w.content || isProxied(r) != w.proxied || r.TTL != w.ttl {
+			t.Errorf("records[%d] = %+v (proxied %v), want %+v", i, r, isProxied(r), w)
+		}
+	}
+
+	records, err = DesiredRecords(cfg, "https://my-service-abc123-uc.a.run.app")
+	if err != nil {
+		t.Fatalf("DesiredRecords failed: %v", err)
+	}
+	if records[0].Type != "CNAME" || records[0].Content != "my-service-abc123-uc.a.run.app" {
+		t.Errorf("Expected a CNAME to the service host, got %+v", records[0])
+	}
+
+	if _, err := DesiredRecords(cfg, ""); err == nil || !strings.Contains(err.Error(), "the deployment has no URL") {
+		t.Errorf("Expected a missing URL error, got: %v", err)
+	}
+}
+
+func TestAddressType(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.10":             "A",
+		"2001:db8::1":              "AAAA",
+		"my-app.azurecontainer.io": "CNAME",
+	}
+	for content, want := range tests {
+		if got := addressType(content); got != want {
+			t.Errorf("addressType(%q) = %s, want %s", content, got, want)
+		}
+	}
+}
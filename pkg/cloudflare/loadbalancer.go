@@ -0,0 +1,379 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// ActionSkipped is reported for pools that have no origins yet because their
+// provider has not deployed.
+const ActionSkipped = "skipped"
+
+// Kinds of load balancing resources.
+const (
+	KindMonitor      = "monitor"
+	KindPool         = "pool"
+	KindLoadBalancer = "load_balancer"
+)
+
+// ResourceChange is what SyncLoadBalancer did to a monitor, pool, or load
+// balancer.
+type ResourceChange struct {
+	Action string
+	Kind   string
+	Name   string
+}
+
+// Monitor is a health monitor of an account's pools. Cloudflare monitors have
+// no name, so cloud-deploy identifies them by description.
+type Monitor struct {
+	ID            string `json:"id,omitempty"`
+	Type          string `json:"type"`
+	Description   string `json:"description"`
+	Method        string `json:"method,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Port          int    `json:"port,omitempty"`
+	Interval      int    `json:"interval"`
+	Retries       int    `json:"retries"`
+	Timeout       int    `json:"timeout"`
+	ExpectedCodes string `json:"expected_codes,omitempty"`
+}
+
+// Origin is an endpoint of a pool.
+type Origin struct {
+	Name    string              `json:"name"`
+	Address string              `json:"address"`
+	Enabled bool                `json:"enabled"`
+	Weight  float64             `json:"weight"`
+	Header  map[string][]string `json:"header,omitempty"`
+}
+
+// Pool is a group of origins of an account.
+type Pool struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	Monitor     string   `json:"monitor,omitempty"`
+	Origins     []Origin `json:"origins"`
+}
+
+// LoadBalancer is a load balancer of a zone, answering for its hostname.
+type LoadBalancer struct {
+	ID             string              `json:"id,omitempty"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description,omitempty"`
+	DefaultPools   []string            `json:"default_pools"`
+	FallbackPool   string              `json:"fallback_pool"`
+	Proxied        bool                `json:"proxied"`
+	TTL            int                 `json:"ttl,omitempty"`
+	SteeringPolicy string              `json:"steering_policy"`
+	RegionPools    map[string][]string `json:"region_pools,omitempty"`
+}
+
+// SyncLoadBalancer creates or updates the monitors, pools, and load balancer
+// of cfg. providers are the providers of the manifest, which get a pool each
+// when cfg has none, and deployments maps the providers that deployed to the
+// URLs of their deployments, which replace the provider's origin in its
+// pools. Pools of providers that did not deploy keep their current origins,
+// so a failed deploy does not take its provider out of the load balancer.
+func (c *Client) SyncLoadBalancer(ctx context.Context, cfg *manifest.CloudflareConfig, providers []string, deployments map[string]string) ([]ResourceChange, error) {
+	zoneID, accountID, err := c.zoneAccount(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AccountID != "" {
+		accountID = cfg.AccountID
+	}
+	base := "/accounts/" + url.PathEscape(accountID) + "/load_balancers"
+	var changes []ResourceChange
+
+	var existingMonitors []Monitor
+	if err := c.do(ctx, http.MethodGet, base+"/monitors", nil, &existingMonitors); err != nil {
+		return nil, fmt.Errorf("failed to list load balancer monitors: %w", err)
+	}
+	monitorIDs := make(map[string]string, len(cfg.Monitors))
+	for _, mc := range cfg.Monitors {
+		desired := DesiredMonitor(mc)
+		var current Monitor
+		for _, m := range existingMonitors {
+			if m.Description == desired.Description {
+				current = m
+				break
+			}
+		}
+		id, action, err := c.syncResource(ctx, base+"/monitors", current.ID, current, desired)
+		if err != nil {
+			return changes, fmt.Errorf("failed to sync monitor %s: %w", mc.Name, err)
+		}
+		monitorIDs[mc.Name] = id
+		changes = append(changes, ResourceChange{Action: action, Kind: KindMonitor, Name: mc.Name})
+	}
+
+	var existingPools []Pool
+	if err := c.do(ctx, http.MethodGet, base+"/pools", nil, &existingPools); err != nil {
+		return changes, fmt.Errorf("failed to list load balancer pools: %w", err)
+	}
+	poolIDs := make(map[string]string)
+	var poolOrder []string
+	for _, pc := range PoolConfigs(cfg, providers) {
+		var current Pool
+		for _, p := range existingPools {
+			if p.Name == pc.Name {
+				current = p
+				break
+			}
+		}
+
+		deploymentURL, deployed := deployments[pc.Provider]
+		if pc.Provider != "" && !deployed && current.ID != "" {
+			poolIDs[pc.Name] = current.ID
+			poolOrder = append(poolOrder, pc.Name)
+			changes = append(changes, ResourceChange{Action: ActionUnchanged, Kind: KindPool, Name: pc.Name})
+			continue
+		}
+		desired, err := DesiredPool(pc, deploymentURL)
+		if err != nil {
+			return changes, err
+		}
+		if len(desired.Origins) == 0 {
+			changes = append(changes, ResourceChange{Action: ActionSkipped, Kind: KindPool, Name: pc.Name})
+			continue
+		}
+		monitor := pc.Monitor
+		if monitor == "" && len(cfg.Monitors) > 0 {
+			monitor = cfg.Monitors[0].Name
+		}
+		desired.Monitor = monitorIDs[monitor]
+
+		id, action, err := c.syncResource(ctx, base+"/pools", current.ID, current, desired)
+		if err != nil {
+			return changes, fmt.Errorf("failed to sync pool %s: %w", pc.Name, err)
+		}
+		poolIDs[pc.Name] = id
+		poolOrder = append(poolOrder, pc.Name)
+		changes = append(changes, ResourceChange{Action: action, Kind: KindPool, Name: pc.Name})
+	}
+	if len(poolOrder) == 0 {
+		return changes, fmt.Errorf("no pool of load balancer %s has origins; deploy at least one of its providers", cfg.LoadBalancer.Name)
+	}
+
+	desired := DesiredLoadBalancer(cfg, poolOrder, poolIDs)
+	zoneBase := "/zones/" + url.PathEscape(zoneID) + "/load_balancers"
+	var existing []LoadBalancer
+	if err := c.do(ctx, http.MethodGet, zoneBase, nil, &existing); err != nil {
+		return changes, fmt.Errorf("failed to list load balancers: %w", err)
+	}
+	var current LoadBalancer
+	for _, lb := range existing {
+		if strings.EqualFold(lb.Name, desired.Name) {
+			current = lb
+			break
+		}
+	}
+	_, action, err := c.syncResource(ctx, zoneBase, current.ID, current, desired)
+	if err != nil {
+		return changes, fmt.Errorf("failed to sync load balancer %s: %w", desired.Name, err)
+	}
+	changes = append(changes, ResourceChange{Action: action, Kind: KindLoadBalancer, Name: desired.Name})
+	return changes, nil
+}
+
+// syncResource creates desired under collection when currentID is empty,
+// replaces the current resource when it differs from desired, and returns the
+// resource's ID.
+func (c *Client) syncResource(ctx context.Context, collection, currentID string, current, desired interface{}) (string, string, error) {
+	if currentID == "" {
+		var created struct {
+			ID string `json:"id"`
+		}
+		if err := c.do(ctx, http.MethodPost, collection, desired, &created); err != nil {
+			return "", "", err
+		}
+		return created.ID, ActionCreated, nil
+	}
+
+	if sameResource(current, desired) {
+		return currentID, ActionUnchanged, nil
+	}
+	if err := c.do(ctx, http.MethodPut, collection+"/"+url.PathEscape(currentID), desired, nil); err != nil {
+		return "", "", err
+	}
+	return currentID, ActionUpdated, nil
+}
+
+// sameResource reports whether current has the fields of desired, ignoring
+// its ID.
+func sameResource(current, desired interface{}) bool {
+	fields := func(v interface{}) (string, bool) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return "", false
+		}
+		delete(m, "id")
+		data, err = json.Marshal(m)
+		return string(data), err == nil
+	}
+	a, okA := fields(current)
+	b, okB := fields(desired)
+	return okA && okB && a == b
+}
+
+// PoolConfigs returns the pools of cfg, or one pool per provider, named after
+// the load balancer's hostname and the provider, when cfg has none.
+func PoolConfigs(cfg *manifest.CloudflareConfig, providers []string) []manifest.CloudflarePoolConfig {
+	if len(cfg.Pools) > 0 {
+		return cfg.Pools
+	}
+	prefix := strings.ReplaceAll(recordName(cfg.LoadBalancer.Name, cfg.Domain), ".", "-")
+	pools := make([]manifest.CloudflarePoolConfig, 0, len(providers))
+	for _, name := range providers {
+		pools = append(pools, manifest.CloudflarePoolConfig{
+			Name:     prefix + "-" + name,
+			Provider: name,
+		})
+	}
+	return pools
+}
+
+// DesiredPool returns the pool of pc with its defaults applied: the origins
+// of pc, enabled with a weight of 1 unless set, followed by the host of
+// deploymentURL when it is not empty. Origins with a host name are sent their
+// address as the Host header, which the providers route requests by.
+func DesiredPool(pc manifest.CloudflarePoolConfig, deploymentURL string) (Pool, error) {
+	pool := Pool{
+		Name:        pc.Name,
+		Description: pc.Description,
+		Enabled:     true,
+		Origins:     make([]Origin, 0, len(pc.Origins)+1),
+	}
+	if pool.Description == "" {
+		pool.Description = "Managed by cloud-deploy"
+	}
+
+	for _, oc := range pc.Origins {
+		origin := Origin{Name: oc.Name, Address: oc.Address, Enabled: true, Weight: 1}
+		if oc.Enabled != nil {
+			origin.Enabled = *oc.Enabled
+		}
+		if oc.Weight != nil {
+			origin.Weight = *oc.Weight
+		}
+		for k, v := range oc.Header {
+			if origin.Header == nil {
+				origin.Header = make(map[string][]string)
+			}
+			origin.Header[k] = []string{v}
+		}
+		pool.Origins = append(pool.Origins, withHostHeader(origin))
+	}
+
+	if deploymentURL != "" {
+		host, err := urlHost(deploymentURL)
+		if err != nil {
+			return Pool{}, fmt.Errorf("cannot add the %s deployment to pool %s: %w", pc.Provider, pc.Name, err)
+		}
+		pool.Origins = append(pool.Origins, withHostHeader(Origin{Name: pc.Provider, Address: host, Enabled: true, Weight: 1}))
+	}
+	return pool, nil
+}
+
+// withHostHeader sets the Host header of an origin with a host name to its
+// address, unless the header is set.
+func withHostHeader(o Origin) Origin {
+	if addressType(o.Address) != "CNAME" {
+		return o
+	}
+	for k := range o.Header {
+		if strings.EqualFold(k, "Host") {
+			return o
+		}
+	}
+	if o.Header == nil {
+		o.Header = make(map[string][]string)
+	}
+	o.Header["Host"] = []string{o.Address}
+	return o
+}
+
+// DesiredMonitor returns the monitor of mc with its defaults applied.
+func DesiredMonitor(mc manifest.CloudflareMonitorConfig) Monitor {
+	monitor := Monitor{
+		Type:        mc.Type,
+		Description: mc.Name,
+		Port:        mc.Port,
+		Interval:    mc.Interval,
+		Retries:     mc.Retries,
+		Timeout:     mc.Timeout,
+	}
+	if monitor.Type == "" {
+		monitor.Type = "https"
+	}
+	if monitor.Interval == 0 {
+		monitor.Interval = 60
+	}
+	if monitor.Retries == 0 {
+		monitor.Retries = 2
+	}
+	if monitor.Timeout == 0 {
+		monitor.Timeout = 5
+	}
+	if monitor.Type == "http" || monitor.Type == "https" {
+		monitor.Method = http.MethodGet
+		monitor.Path = mc.Path
+		if monitor.Path == "" {
+			monitor.Path = "/"
+		}
+		monitor.ExpectedCodes = mc.ExpectedCodes
+		if monitor.ExpectedCodes == "" {
+			monitor.ExpectedCodes = "200"
+		}
+	}
+	return monitor
+}
+
+// DesiredLoadBalancer returns the load balancer of cfg over the named pools,
+// which are tried in order with the last as the fallback when every pool is
+// unhealthy. Region pools that were skipped are left out.
+func DesiredLoadBalancer(cfg *manifest.CloudflareConfig, pools []string, poolIDs map[string]string) LoadBalancer {
+	lbc := cfg.LoadBalancer
+	lb := LoadBalancer{
+		Name:           recordName(lbc.Name, cfg.Domain),
+		Description:    "Managed by cloud-deploy",
+		DefaultPools:   make([]string, 0, len(pools)),
+		FallbackPool:   poolIDs[pools[len(pools)-1]],
+		Proxied:        lbc.Proxied == nil || *lbc.Proxied,
+		TTL:            lbc.TTL,
+		SteeringPolicy: lbc.SteeringPolicy,
+	}
+	for _, name := range pools {
+		lb.DefaultPools = append(lb.DefaultPools, poolIDs[name])
+	}
+	if lb.TTL == 0 {
+		lb.TTL = 30
+	}
+	if lb.SteeringPolicy == "" {
+		lb.SteeringPolicy = "off"
+	}
+	for region, names := range lbc.RegionPools {
+		for _, name := range names {
+			if id, ok := poolIDs[name]; ok {
+				if lb.RegionPools == nil {
+					lb.RegionPools = make(map[string][]string)
+				}
+				lb.RegionPools[region] = append(lb.RegionPools[region], id)
+			}
+		}
+	}
+	return lb
+}
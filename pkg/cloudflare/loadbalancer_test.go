@@ -0,0 +1,239 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeLoadBalancing serves the zone, monitor, pool, and load balancer
+// endpoints of one account and zone from memory.
+type fakeLoadBalancing struct {
+	mu        sync.Mutex
+	resources map[string]map[string]json.RawMessage
+	writes    int
+}
+
+func newFakeLoadBalancing() *fakeLoadBalancing {
+	return &fakeLoadBalancing{resources: map[string]map[string]json.RawMessage{
+		"/accounts/acct-1/load_balancers/monitors": {},
+		"/accounts/acct-1/load_balancers/pools":    {},
+		"/zones/zone-123/load_balancers":           {},
+	}}
+}
+
+func (f *fakeLoadBalancing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "errors": []interface{}{}, "result": result})
+	}
+	if r.Method == http.MethodGet && r.URL.Path == "/zones/zone-123" {
+		reply(map[string]interface{}{"id": "zone-123", "account": map[string]string{"id": "acct-1"}})
+		return
+	}
+
+	collection, id := r.URL.Path, ""
+	if _, ok := f.resources[collection]; !ok {
+		i := strings.LastIndex(r.URL.Path, "/")
+		collection, id = r.URL.Path[:i], r.URL.Path[i+1:]
+	}
+	items, ok := f.resources[collection]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		list := []json.RawMessage{}
+		for _, item := range items {
+			list = append(list, item)
+		}
+		reply(list)
+	case r.Method == http.MethodPost && id == "", r.Method == http.MethodPut && id != "":
+		var item map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&item)
+		if id == "" {
+			id = fmt.Sprintf("%s-%d", collection[strings.LastIndex(collection, "/")+1:], len(items)+1)
+		}
+		item["id"] = id
+		data, _ := json.Marshal(item)
+		items[id] = data
+		f.writes++
+		reply(item)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// get decodes the resource with the given ID into out.
+func (f *fakeLoadBalancing) get(t *testing.T, collection, id string, out interface{}) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.resources[collection][id]
+	if !ok {
+		t.Fatalf("%s/%s not found", collection, id)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncLoadBalancer(t *testing.T) {
+	fake := newFakeLoadBalancing()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	cfg := &manifest.CloudflareConfig{
+		ZoneID:       "zone-123",
+		Domain:       "example.com",
+		LoadBalancer: &manifest.CloudflareLoadBalancerConfig{Name: "app", SteeringPolicy: "dynamic_latency"},
+		Monitors:     []manifest.CloudflareMonitorConfig{{Name: "health", Path: "/health"}},
+	}
+	providers := []string{"aws", "gcp"}
+
+	changes, err := c.SyncLoadBalancer(context.Background(), cfg, providers, map[string]string{
+		"aws": "http://my-app-prod.us-east-1.elasticbeanstalk.com",
+		"gcp": "https://my-app-abc123-uc.a.run.app",
+	})
+	if err != nil {
+		t.Fatalf("SyncLoadBalancer failed: %v", err)
+	}
+	want := []ResourceChange{
+		{ActionCreated, KindMonitor, "health"},
+		{ActionCreated, KindPool, "app-example-com-aws"},
+		{ActionCreated, KindPool, "app-example-com-gcp"},
+		{ActionCreated, KindLoadBalancer, "app.example.com"},
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+
+	var lb LoadBalancer
+	fake.get(t, "/zones/zone-123/load_balancers", "load_balancers-1", &lb)
+	if lb.SteeringPolicy != "dynamic_latency" || !lb.Proxied || fmt.Sprint(lb.DefaultPools) != "[pools-1 pools-2]" || lb.FallbackPool != "pools-2" {
+		t.Errorf("Unexpected load balancer: %+v", lb)
+	}
+	var pool Pool
+	fake.get(t, "/accounts/acct-1/load_balancers/pools", "pools-2", &pool)
+	if pool.Monitor != "monitors-1" || len(pool.Origins) != 1 || pool.Origins[0].Address != "my-app-abc123-uc.a.run.app" ||
+		fmt.Sprint(pool.Origins[0].Header["Host"]) != "[my-app-abc123-uc.a.run.app]" {
+		t.Errorf("Unexpected pool: %+v", pool)
+	}
+
+	// A provider that failed to deploy keeps its pool; the others are up to date
+	writes := fake.writes
+	changes, err = c.SyncLoadBalancer(context.Background(), cfg, providers, map[string]string{
+		"gcp": "https://my-app-abc123-uc.a.run.app",
+	})
+	if err != nil {
+		t.Fatalf("SyncLoadBalancer failed: %v", err)
+	}
+	for _, change := range changes {
+		if change.Action != ActionUnchanged {
+			t.Errorf("Expected no changes on the second sync, got %+v", change)
+		}
+	}
+	if fake.writes != writes {
+		t.Errorf("Expected no writes on the second sync, got %d", fake.writes-writes)
+	}
+
+	// A new origin URL updates the provider's pool
+	changes, err = c.SyncLoadBalancer(context.Background(), cfg, providers, map[string]string{
+		"gcp": "https://my-app-def456-uc.a.run.app",
+	})
+	if err != nil {
+		t.Fatalf("SyncLoadBalancer failed: %v", err)
+	}
+	if changes[2].Action != ActionUpdated {
+		t.Errorf("Expected the gcp pool to be updated, got %+v", changes[2])
+	}
+}
+
+func TestSyncLoadBalancerNoOrigins(t *testing.T) {
+	server := httptest.NewServer(newFakeLoadBalancing())
+	defer server.Close()
+
+	c := New("test-token")
+	c.SetEndpoint(server.URL)
+	cfg := &manifest.CloudflareConfig{
+		ZoneID:       "zone-123",
+		LoadBalancer: &manifest.CloudflareLoadBalancerConfig{Name: "app.example.com"},
+	}
+
+	changes, err := c.SyncLoadBalancer(context.Background(), cfg, []string{"aws"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no pool of load balancer app.example.com has origins") {
+		t.Errorf("Expected a no origins error, got: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Action != ActionSkipped {
+		t.Errorf("Expected the pool to be skipped, got %+v", changes)
+	}
+}
+
+func TestDesiredPool(t *testing.T) {
+	disabled := false
+	weight := 0.25
+	pool, err := DesiredPool(manifest.CloudflarePoolConfig{
+		Name:     "us",
+		Provider: "aws",
+		Origins: []manifest.CloudflareOriginConfig{
+			{Name: "onprem", Address: "203.0.113.10", Enabled: &disabled, Weight: &weight},
+			{Name: "edge", Address: "edge.example.com", Header: map[string]string{"host": "app.example.com"}},
+		},
+	}, "my-app-prod.us-east-1.elasticbeanstalk.com")
+	if err != nil {
+		t.Fatalf("DesiredPool failed: %v", err)
+	}
+	if len(pool.Origins) != 3 {
+		t.Fatalf("Expected 3 origins, got %+v", pool.Origins)
+	}
+	if o := pool.Origins[0]; o.Enabled || o.Weight != 0.25 || o.Header != nil {
+		t.Errorf("IP origin = %+v, want disabled with weight 0.25 and no Host header", o)
+	}
+	if o := pool.Origins[1]; fmt.Sprint(o.Header) != "map[host:[app.example.com]]" {
+		t.Errorf("Origin header = %v, want the configured Host", o.Header)
+	}
+	if o := pool.Origins[2]; o.Name != "aws" || o.Address != "my-app-prod.us-east-1.elasticbeanstalk.com" || !o.Enabled || o.Weight != 1 {
+		t.Errorf("Deployment origin = %+v", o)
+	}
+}
+
+func TestDesiredMonitor(t *testing.T) {
+	if m := DesiredMonitor(manifest.CloudflareMonitorConfig{Name: "health"}); m.Type != "https" || m.Method != "GET" || m.Path != "/" || m.ExpectedCodes != "200" || m.Interval != 60 {
+		t.Errorf("Unexpected defaults: %+v", m)
+	}
+	if m := DesiredMonitor(manifest.CloudflareMonitorConfig{Name: "tcp", Type: "tcp", Port: 443, Path: "/ignored"}); m.Path != "" || m.Method != "" || m.ExpectedCodes != "" || m.Port != 443 {
+		t.Errorf("Expected no HTTP fields on a tcp monitor: %+v", m)
+	}
+}
+
+func TestDesiredLoadBalancer(t *testing.T) {
+	proxied := false
+	cfg := &manifest.CloudflareConfig{
+		Domain: "example.com",
+		LoadBalancer: &manifest.CloudflareLoadBalancerConfig{
+			Name:        "app.example.com",
+			Proxied:     &proxied,
+			TTL:         60,
+			RegionPools: map[string][]string{"WNAM": {"us"}, "WEU": {"eu", "us"}},
+		},
+	}
+	lb := DesiredLoadBalancer(cfg, []string{"us"}, map[string]string{"us": "pool-us"})
+	if lb.Name != "app.example.com" || lb.Proxied || lb.TTL != 60 || lb.SteeringPolicy != "off" || lb.FallbackPool != "pool-us" {
+		t.Errorf("Unexpected load balancer: %+v", lb)
+	}
+	if fmt.Sprint(lb.RegionPools) != "map[WEU:[pool-us] WNAM:[pool-us]]" {
+		t.Errorf("Expected skipped pools to be left out of region pools, got %v", lb.RegionPools)
+	}
+}
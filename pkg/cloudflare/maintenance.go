@@ -0,0 +1,211 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// maintenanceScriptPrefix starts the names of the Workers serving maintenance
+// pages.
+const maintenanceScriptPrefix = "cloud-deploy-maintenance-"
+
+// invalidScriptChars matches the characters Worker names cannot contain.
+var invalidScriptChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// WorkerRoute runs a Worker for the requests matching its pattern.
+type WorkerRoute struct {
+	ID      string `json:"id,omitempty"`
+	Pattern string `json:"pattern"`
+	Script  string `json:"script,omitempty"`
+}
+
+// MaintenanceScriptName returns the name of the Worker serving the
+// maintenance page of an application's environment.
+func MaintenanceScriptName(application, environment string) string {
+	name := maintenanceScriptPrefix + invalidScriptChars.ReplaceAllString(strings.ToLower(application+"-"+environment), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// MaintenancePatterns returns the route patterns covering the names of cfg's
+// proxied records, whose requests a Worker can answer. It is safe to call with
+// a nil config.
+func MaintenancePatterns(cfg *manifest.CloudflareConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var patterns []string
+	for _, r := range cfg.DNSRecords {
+		proxied := r.Type != "TXT"
+		if r.Proxied != nil {
+			proxied = *r.Proxied
+		}
+		if proxied {
+			patterns = append(patterns, recordName(r.Name, cfg.Domain)+"/*")
+		}
+	}
+	return patterns
+}
+
+// EnableMaintenance uploads a Worker named script answering every request with
+// the maintenance response of mc, and routes the names of cfg's proxied
+// records to it. Requests stop reaching the deployment, which keeps running.
+// It returns the patterns routed to the Worker, and fails without changing a
+// route that runs another Worker.
+func (c *Client) EnableMaintenance(ctx context.Context, cfg *manifest.CloudflareConfig, script string, mc *manifest.MaintenanceConfig) ([]string, error) {
+	patterns := MaintenancePatterns(cfg)
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("cloudflare.dns_records has no proxied records to serve a maintenance page for")
+	}
+	zoneID, accountID, err := c.zoneAccount(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := c.ListWorkerRoutes(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]WorkerRoute, len(routes))
+	for _, r := range routes {
+		existing[r.Pattern] = r
+	}
+	for _, pattern := range patterns {
+		if r, ok := existing[pattern]; ok && r.Script != script {
+			return nil, fmt.Errorf("route %s already runs Worker %s", pattern, r.Script)
+		}
+	}
+
+	if err := c.uploadWorker(ctx, accountID, script, maintenanceWorker(mc)); err != nil {
+		return nil, err
+	}
+	var added []string
+	for _, pattern := range patterns {
+		if _, ok := existing[pattern]; ok {
+			continue
+		}
+		route := WorkerRoute{Pattern: pattern, Script: script}
+		if err := c.do(ctx, http.MethodPost, "/zones/"+url.PathEscape(zoneID)+"/workers/routes", route, nil); err != nil {
+			return added, fmt.Errorf("failed to route %s to Worker %s: %w", pattern, script, err)
+		}
+		added = append(added, pattern)
+	}
+	return added, nil
+}
+
+// DisableMaintenance removes the routes to the Worker named script from the
+// zone of cfg and deletes the Worker, so requests reach the deployment again.
+// It returns the patterns that were routed to the Worker.
+func (c *Client) DisableMaintenance(ctx context.Context, cfg *manifest.CloudflareConfig, script string) ([]string, error) {
+	zoneID, accountID, err := c.zoneAccount(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := c.ListWorkerRoutes(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, r := range routes {
+		if r.Script != script {
+			continue
+		}
+		if err := c.do(ctx, http.MethodDelete, "/zones/"+url.PathEscape(zoneID)+"/workers/routes/"+url.PathEscape(r.ID), nil, nil); err != nil {
+			return removed, fmt.Errorf("failed to remove route %s: %w", r.Pattern, err)
+		}
+		removed = append(removed, r.Pattern)
+	}
+
+	err = c.do(ctx, http.MethodDelete, "/accounts/"+url.PathEscape(accountID)+"/workers/scripts/"+url.PathEscape(script), nil, nil)
+	if err != nil && !IsNotFound(err) {
+		return removed, fmt.Errorf("failed to delete Worker %s: %w", script, err)
+	}
+	return removed, nil
+}
+
+// ListWorkerRoutes returns the Worker routes of the zone.
+func (c *Client) ListWorkerRoutes(ctx context.Context, zoneID string) ([]WorkerRoute, error) {
+	var routes []WorkerRoute
+	if err := c.do(ctx, http.MethodGet, "/zones/"+url.PathEscape(zoneID)+"/workers/routes", nil, &routes); err != nil {
+		return nil, fmt.Errorf("failed to list Worker routes: %w", err)
+	}
+	return routes, nil
+}
+
+// zoneAccount returns the ID of cfg's zone and of the account owning it,
+// which owns the zone's Workers.
+func (c *Client) zoneAccount(ctx context.Context, cfg *manifest.CloudflareConfig) (string, string, error) {
+	zoneID := cfg.ZoneID
+	if zoneID == "" {
+		var err error
+		if zoneID, err = c.ZoneID(ctx, cfg.Domain); err != nil {
+			return "", "", err
+		}
+	}
+	var zone struct {
+		Account struct {
+			ID string `json:"id"`
+		} `json:"account"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/zones/"+url.PathEscape(zoneID), nil, &zone); err != nil {
+		return "", "", fmt.Errorf("failed to get zone %s: %w", zoneID, err)
+	}
+	return zoneID, zone.Account.ID, nil
+}
+
+// uploadWorker creates or replaces the Worker named script with an ES module.
+func (c *Client) uploadWorker(ctx context.Context, accountID, script, module string) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	metadata, err := w.CreateFormField("metadata")
+	if err != nil {
+		return err
+	}
+	json.NewEncoder(metadata).Encode(map[string]string{"main_module": "worker.js"})
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="worker.js"; filename="worker.js"`)
+	header.Set("Content-Type", "application/javascript+module")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	part.Write([]byte(module))
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	path := "/accounts/" + url.PathEscape(accountID) + "/workers/scripts/" + url.PathEscape(script)
+	if err := c.send(ctx, http.MethodPut, path, w.FormDataContentType(), &body, nil); err != nil {
+		return fmt.Errorf("failed to upload Worker %s: %w", script, err)
+	}
+	return nil
+}
+
+// maintenanceWorker returns the source of a Worker module answering every
+// request with the maintenance response of mc, uncached.
+func maintenanceWorker(mc *manifest.MaintenanceConfig) string {
+	message, status := mc.Response()
+	// JSON strings are valid JavaScript string literals
+	body, _ := json.Marshal(message)
+	contentType, _ := json.Marshal(mc.ContentType())
+	return fmt.Sprintf(`export default {
+  async fetch() {
+    return new Response(%s, {
+      status: %d,
+      headers: { "content-type": %s, "cache-control": "no-store" },
+    });
+  },
+};
+`, body, status, contentType)
+}
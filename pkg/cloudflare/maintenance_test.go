@@ -0,0 +1,172 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeWorkers serves the zone, Worker script, and Worker route endpoints of
+// one zone from memory.
+type fakeWorkers struct {
+	mu      sync.Mutex
+	scripts map[string]string
+	routes  map[string]WorkerRoute
+}
+
+func newFakeWorkers(routes ...WorkerRoute) *fakeWorkers {
+	f := &fakeWorkers{scripts: make(map[string]string), routes: make(map[string]WorkerRoute)}
+	for _, r := range routes {
+		f.routes[r.ID] = r
+	}
+	return f
+}
+
+func (f *fakeWorkers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "errors": []interface{}{}, "result": result})
+	}
+	const scripts, routes = "/accounts/acct-1/workers/scripts/", "/zones/zone-123/workers/routes"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/zones/zone-123":
+		reply(map[string]interface{}{"id": "zone-123", "account": map[string]string{"id": "acct-1"}})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, scripts):
+		r.ParseMultipartForm(1 << 20)
+		if r.MultipartForm == nil || len(r.MultipartForm.File["worker.js"]) != 1 || !strings.Contains(r.FormValue("metadata"), `"main_module":"worker.js"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			reply(nil)
+			return
+		}
+		file, _ := r.MultipartForm.File["worker.js"][0].Open()
+		module, _ := io.ReadAll(file)
+		f.scripts[strings.TrimPrefix(r.URL.Path, scripts)] = string(module)
+		reply(map[string]string{})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, scripts):
+		name := strings.TrimPrefix(r.URL.Path, scripts)
+		if _, ok := f.scripts[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "errors": []interface{}{map[string]interface{}{"code": 10007, "message": "workers.api.error.script_not_found"}}})
+			return
+		}
+		delete(f.scripts, name)
+		reply(nil)
+	case r.Method == http.MethodGet && r.URL.Path == routes:
+		list := []WorkerRoute{}
+		for _, route := range f.routes {
+			list = append(list, route)
+		}
+		reply(list)
+	case r.Method == http.MethodPost && r.URL.Path == routes:
+		var route WorkerRoute
+		json.NewDecoder(r.Body).Decode(&route)
+		route.ID = fmt.Sprintf("route-%d", len(f.routes)+1)
+		f.routes[route.ID] = route
+		reply(route)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, routes+"/"):
+		delete(f.routes, strings.TrimPrefix(r.URL.Path, routes+"/"))
+		reply(nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func maintenanceConfig() *manifest.CloudflareConfig {
+	direct := false
+	return &manifest.CloudflareConfig{ZoneID: "zone-123", Domain: "example.com", DNSRecords: []manifest.DNSRecordConfig{
+		{Name: "app"},
+		{Name: "www.example.com"},
+		{Name: "origin", Proxied: &direct},
+		{Name: "_verify", Type: "TXT", Content: "x"},
+	}}
+}
+
+func TestMaintenancePatterns(t *testing.T) {
+	got := strings.Join(MaintenancePatterns(maintenanceConfig()), ", ")
+	if got != "app.example.com/*, www.example.com/*" {
+		t.Errorf("Expected the proxied records' patterns, got %s", got)
+	}
+	if MaintenancePatterns(nil) != nil {
+		t.Error("Expected no patterns without a config")
+	}
+}
+
+func TestMaintenanceScriptName(t *testing.T) {
+	if got := MaintenanceScriptName("my_app", "My.Env"); got != "cloud-deploy-maintenance-my_app-my-env" {
+		t.Errorf("Unexpected script name %q", got)
+	}
+	if got := MaintenanceScriptName("my-app", strings.Repeat("e", 80)); len(got) != 63 {
+		t.Errorf("Expected the name to be cut to 63 characters, got %d", len(got))
+	}
+}
+
+func TestEnableAndDisableMaintenance(t *testing.T) {
+	fake := newFakeWorkers(
+		WorkerRoute{ID: "route-9", Pattern: "www.example.com/*", Script: "cloud-deploy-maintenance-prod"},
+		WorkerRoute{ID: "route-8", Pattern: "api.example.com/*", Script: "api-worker"},
+	)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := New("test-token")
+	client.SetEndpoint(server.URL)
+	ctx := context.Background()
+
+	added, err := client.EnableMaintenance(ctx, maintenanceConfig(), "cloud-deploy-maintenance-prod", &manifest.MaintenanceConfig{Message: "Back at 10:00", StatusCode: 503})
+	if err != nil {
+		t.Fatalf("EnableMaintenance failed: %v", err)
+	}
+	if strings.Join(added, ", ") != "app.example.com/*" {
+		t.Errorf("Expected only the missing route to be added, got %v", added)
+	}
+	module := fake.scripts["cloud-deploy-maintenance-prod"]
+	if !strings.Contains(module, `new Response("Back at 10:00"`) || !strings.Contains(module, "status: 503") || !strings.Contains(module, `"text/plain; charset=utf-8"`) {
+		t.Errorf("Unexpected Worker:\n%s", module)
+	}
+
+	removed, err := client.DisableMaintenance(ctx, maintenanceConfig(), "cloud-deploy-maintenance-prod")
+	if err != nil {
+		t.Fatalf("DisableMaintenance failed: %v", err)
+	}
+	if len(removed) != 2 || len(fake.routes) != 1 || fake.routes["route-8"].Script != "api-worker" {
+		t.Errorf("Expected the maintenance routes to be removed and others kept, got %v, %v", removed, fake.routes)
+	}
+	if _, ok := fake.scripts["cloud-deploy-maintenance-prod"]; ok {
+		t.Error("Expected the Worker to be deleted")
+	}
+
+	// Disabling again finds nothing to remove
+	if removed, err := client.DisableMaintenance(ctx, maintenanceConfig(), "cloud-deploy-maintenance-prod"); err != nil || len(removed) != 0 {
+		t.Errorf("Expected nothing to disable, got %v, %v", removed, err)
+	}
+}
+
+func TestEnableMaintenanceRouteConflict(t *testing.T) {
+	fake := newFakeWorkers(WorkerRoute{ID: "route-1", Pattern: "app.example.com/*", Script: "edge-auth"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := New("test-token")
+	client.SetEndpoint(server.URL)
+
+	_, err := client.EnableMaintenance(context.Background(), maintenanceConfig(), "cloud-deploy-maintenance-prod", nil)
+	if err == nil || !strings.Contains(err.Error(), "route app.example.com/* already runs Worker edge-auth") {
+		t.Errorf("Expected a route conflict, got: %v", err)
+	}
+	if len(fake.scripts) != 0 {
+		t.Error("Expected no Worker to be uploaded")
+	}
+
+	_, err = client.EnableMaintenance(context.Background(), &manifest.CloudflareConfig{ZoneID: "zone-123"}, "cloud-deploy-maintenance-prod", nil)
+	if err == nil || !strings.Contains(err.Error(), "no proxied records") {
+		t.Errorf("Expected an error without proxied records, got: %v", err)
+	}
+}
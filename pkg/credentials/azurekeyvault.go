@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+)
+
+// SplitKeyVaultSecretURL splits the URL of an Azure Key Vault secret,
+// https://<vault>.vault.azure.net/secrets/<name>[/<version>], into the vault's
+// URL, the secret's name, and its version ("" for the latest version).
+func SplitKeyVaultSecretURL(secretURL string) (vaultURL, name, version string, err error) {
+	u, err := url.Parse(secretURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", "", "", fmt.Errorf("invalid Key Vault secret URL %q", secretURL)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "secrets" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid Key Vault secret URL %q: expected https://<vault>/secrets/<name>[/<version>]", secretURL)
+	}
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+	return "https://" + u.Host, parts[1], version, nil
+}
+
+// getFromAzureKeyVault retrieves credentials from Azure Key Vault with the
+// default Azure credential chain. The provider's secret is a secret URL,
+// https://<vault>.vault.azure.net/secrets/<name>[/<version>], holding the same
+// JSON document as a Secrets Manager secret.
+func (m *Manager) getFromAzureKeyVault(ctx context.Context, provider string) (*ProviderCredentials, error) {
+	secretURL, ok := m.Secrets[provider]
+	if !ok {
+		return nil, fmt.Errorf("no secret configured for provider: %s", provider)
+	}
+	vaultURL, name, version, err := SplitKeyVaultSecretURL(secretURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+	client, err := azsecrets.NewClient(vaultURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Key Vault client: %w", err)
+	}
+
+	result, err := client.GetSecret(ctx, name, version, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve secret %s: %w", secretURL, err)
+	}
+	if result.Value == nil {
+		return nil, fmt.Errorf("secret %s has no value", secretURL)
+	}
+
+	creds := &ProviderCredentials{}
+	if err := json.Unmarshal([]byte(*result.Value), creds); err != nil {
+		return nil, fmt.Errorf("failed to parse secret JSON: %w", err)
+	}
+	return creds, nil
+}
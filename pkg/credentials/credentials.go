@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
 )
 
 // Manager handles credential retrieval from various sources
 type Manager struct {
-	Source  string            // "environment", "secrets-manager"
-	Secrets map[string]string // Maps credential keys to their source identifiers
+	Source  string            // "environment", "secrets-manager", "parameter-store", "gcp-secret-manager", "azure-key-vault", "vault"
+	Secrets map[string]string // Maps credential keys to their source identifiers (secret IDs or parameter paths)
+	Vault   *vault.Config     // Vault server the "vault" source reads from
 }
 
 // ProviderCredentials represents the credentials needed for a cloud provider
@@ -40,6 +44,12 @@ type ProviderCredentials struct {
 		Email     string `json:"email,omitempty"`
 		AccountID string `json:"account_id"`
 	} `json:"cloudflare,omitempty"`
+	DigitalOcean struct {
+		Token string `json:"token"`
+	} `json:"digitalocean,omitempty"`
+	Fly struct {
+		Token string `json:"token"`
+	} `json:"fly,omitempty"`
 }
 
 // GetCredentials retrieves credentials based on the configured source
@@ -49,6 +59,14 @@ func (m *Manager) GetCredentials(ctx context.Context, provider string) (*Provide
 		return m.getFromEnvironment(provider)
 	case "secrets-manager":
 		return m.getFromSecretsManager(ctx, provider)
+	case "parameter-store":
+		return m.getFromParameterStore(ctx, provider)
+	case "gcp-secret-manager":
+		return m.getFromGCPSecretManager(ctx, provider)
+	case "azure-key-vault":
+		return m.getFromAzureKeyVault(ctx, provider)
+	case "vault":
+		return m.getFromVault(ctx, provider)
 	default:
 		return nil, fmt.Errorf("unknown credentials source: %s", m.Source)
 	}
@@ -94,6 +112,26 @@ func (m *Manager) getFromEnvironment(provider string) (*ProviderCredentials, err
 			return nil, fmt.Errorf("Cloudflare credentials not found in environment")
 		}
 
+	case "digitalocean":
+		creds.DigitalOcean.Token = os.Getenv("DIGITALOCEAN_TOKEN")
+		if creds.DigitalOcean.Token == "" {
+			creds.DigitalOcean.Token = os.Getenv("DIGITALOCEAN_ACCESS_TOKEN")
+		}
+
+		if creds.DigitalOcean.Token == "" {
+			return nil, fmt.Errorf("DigitalOcean credentials not found in environment")
+		}
+
+	case "fly":
+		creds.Fly.Token = os.Getenv("FLY_API_TOKEN")
+		if creds.Fly.Token == "" {
+			creds.Fly.Token = os.Getenv("FLY_ACCESS_TOKEN")
+		}
+
+		if creds.Fly.Token == "" {
+			return nil, fmt.Errorf("Fly.io credentials not found in environment")
+		}
+
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", provider)
 	}
@@ -136,6 +174,68 @@ func (m *Manager) getFromSecretsManager(ctx context.Context, provider string) (*
 	return creds, nil
 }
 
+// PutCredentials stores creds as the credentials for provider in the configured source.
+// Only "secrets-manager" can be written; the other providers' credentials held in
+// the same secret are preserved.
+func (m *Manager) PutCredentials(ctx context.Context, provider string, creds *ProviderCredentials) error {
+	if m.Source != "secrets-manager" {
+		return fmt.Errorf("credentials source %q cannot be updated", m.Source)
+	}
+
+	secretID, ok := m.Secrets[provider]
+	if !ok {
+		return fmt.Errorf("no secret configured for provider: %s", provider)
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := secretsmanager.NewFromConfig(cfg)
+	_, err = client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(secretID),
+		SecretString: aws.String(string(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update secret %s: %w", secretID, err)
+	}
+
+	return nil
+}
+
+// Newly issued cloud credentials take a few seconds to propagate before they can
+// authenticate, so verification is retried.
+var (
+	verifyAttempts = 10
+	verifyInterval = 3 * time.Second
+)
+
+// WaitUntilValid calls check until it succeeds, retrying while newly issued
+// credentials propagate. Returns the last error if they never become valid.
+func WaitUntilValid(ctx context.Context, check func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= verifyAttempts; attempt++ {
+		if err = check(ctx); err == nil {
+			return nil
+		}
+		if attempt == verifyAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(verifyInterval):
+		}
+	}
+	return fmt.Errorf("credentials are not valid after %d attempts: %w", verifyAttempts, err)
+}
+
 // ValidateCredentials checks if the credentials are valid for the given provider
 func ValidateCredentials(creds *ProviderCredentials, provider string) error {
 	switch provider {
@@ -155,6 +255,14 @@ func ValidateCredentials(creds *ProviderCredentials, provider string) error {
 		if creds.Cloudflare.APIToken == "" {
 			return fmt.Errorf("Cloudflare credentials are incomplete")
 		}
+	case "digitalocean":
+		if creds.DigitalOcean.Token == "" {
+			return fmt.Errorf("DigitalOcean credentials are incomplete")
+		}
+	case "fly":
+		if creds.Fly.Token == "" {
+			return fmt.Errorf("Fly.io credentials are incomplete")
+		}
 	default:
 		return fmt.Errorf("unknown provider: %s", provider)
 	}
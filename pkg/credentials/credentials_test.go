@@ -2,7 +2,10 @@ package credentials
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetCredentials_Environment(t *testing.T) {
@@ -137,9 +140,47 @@ func TestGetFromEnvironment_Cloudflare_Missing(t *testing.T) {
 	}
 }
 
+func TestGetFromEnvironment_DigitalOcean(t *testing.T) {
+	m := &Manager{Source: "environment"}
+	t.Setenv("DIGITALOCEAN_TOKEN", "")
+	t.Setenv("DIGITALOCEAN_ACCESS_TOKEN", "do-token-abc")
+
+	creds, err := m.GetCredentials(context.Background(), "digitalocean")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.DigitalOcean.Token != "do-token-abc" {
+		t.Errorf("got Token=%q, want do-token-abc", creds.DigitalOcean.Token)
+	}
+
+	t.Setenv("DIGITALOCEAN_ACCESS_TOKEN", "")
+	if _, err := m.GetCredentials(context.Background(), "digitalocean"); err == nil {
+		t.Fatal("expected error when DigitalOcean env vars are missing")
+	}
+}
+
+func TestGetFromEnvironment_Fly(t *testing.T) {
+	m := &Manager{Source: "environment"}
+	t.Setenv("FLY_API_TOKEN", "fly-token-abc")
+	t.Setenv("FLY_ACCESS_TOKEN", "")
+
+	creds, err := m.GetCredentials(context.Background(), "fly")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Fly.Token != "fly-token-abc" {
+		t.Errorf("got Token=%q, want fly-token-abc", creds.Fly.Token)
+	}
+
+	t.Setenv("FLY_API_TOKEN", "")
+	if _, err := m.GetCredentials(context.Background(), "fly"); err == nil {
+		t.Fatal("expected error when Fly.io env vars are missing")
+	}
+}
+
 func TestGetFromEnvironment_UnknownProvider(t *testing.T) {
 	m := &Manager{Source: "environment"}
-	_, err := m.GetCredentials(context.Background(), "digitalocean")
+	_, err := m.GetCredentials(context.Background(), "linode")
 	if err == nil {
 		t.Fatal("expected error for unknown provider")
 	}
@@ -224,9 +265,94 @@ func TestValidateCredentials_Cloudflare_Incomplete(t *testing.T) {
 	}
 }
 
+func TestValidateCredentials_DigitalOcean(t *testing.T) {
+	creds := &ProviderCredentials{}
+	if err := ValidateCredentials(creds, "digitalocean"); err == nil {
+		t.Fatal("expected error for incomplete DigitalOcean credentials")
+	}
+	creds.DigitalOcean.Token = "token"
+	if err := ValidateCredentials(creds, "digitalocean"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateCredentials_Fly(t *testing.T) {
+	creds := &ProviderCredentials{}
+	if err := ValidateCredentials(creds, "fly"); err == nil {
+		t.Fatal("expected error for incomplete Fly.io credentials")
+	}
+	creds.Fly.Token = "token"
+	if err := ValidateCredentials(creds, "fly"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestValidateCredentials_UnknownProvider(t *testing.T) {
 	creds := &ProviderCredentials{}
 	if err := ValidateCredentials(creds, "unknown"); err == nil {
 		t.Fatal("expected error for unknown provider")
 	}
 }
+
+func TestPutCredentials_UnsupportedSource(t *testing.T) {
+	m := &Manager{Source: "environment"}
+	err := m.PutCredentials(context.Background(), "aws", &ProviderCredentials{})
+	if err == nil {
+		t.Fatal("expected error when source cannot be updated")
+	}
+}
+
+func TestPutCredentials_NoSecret(t *testing.T) {
+	m := &Manager{Source: "secrets-manager", Secrets: map[string]string{}}
+	err := m.PutCredentials(context.Background(), "aws", &ProviderCredentials{})
+	if err == nil {
+		t.Fatal("expected error when no secret is configured")
+	}
+}
+
+func TestWaitUntilValid(t *testing.T) {
+	verifyInterval = 0
+	defer func() { verifyInterval = 3 * time.Second }()
+
+	calls := 0
+	err := WaitUntilValid(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("InvalidClientTokenId")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+
+	calls = 0
+	err = WaitUntilValid(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("InvalidClientTokenId")
+	})
+	if err == nil || !strings.Contains(err.Error(), "InvalidClientTokenId") {
+		t.Errorf("expected last error to be returned, got %v", err)
+	}
+	if calls != verifyAttempts {
+		t.Errorf("got %d calls, want %d", calls, verifyAttempts)
+	}
+}
+
+func TestSplitKeyVaultSecretURL(t *testing.T) {
+	vault, name, version, err := SplitKeyVaultSecretURL("https://my-vault.vault.azure.net/secrets/cloud-deploy/abc123")
+	if err != nil || vault != "https://my-vault.vault.azure.net" || name != "cloud-deploy" || version != "abc123" {
+		t.Errorf("Unexpected split: %q %q %q %v", vault, name, version, err)
+	}
+	if _, _, version, err := SplitKeyVaultSecretURL("https://my-vault.vault.azure.net/secrets/cloud-deploy"); err != nil || version != "" {
+		t.Errorf("Expected the latest version, got %q, %v", version, err)
+	}
+	for _, u := range []string{"cloud-deploy", "http://my-vault.vault.azure.net/secrets/x", "https://my-vault.vault.azure.net/keys/x", "https://my-vault.vault.azure.net/secrets/"} {
+		if _, _, _, err := SplitKeyVaultSecretURL(u); err == nil {
+			t.Errorf("Expected an error for %q", u)
+		}
+	}
+}
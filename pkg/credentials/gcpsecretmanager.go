@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+// getFromGCPSecretManager retrieves credentials from Google Secret Manager with
+// Application Default Credentials. The provider's secret is a resource name,
+// projects/<project>/secrets/<name>, optionally with /versions/<version>
+// (default: the latest version), holding the same JSON document as a Secrets
+// Manager secret.
+func (m *Manager) getFromGCPSecretManager(ctx context.Context, provider string) (*ProviderCredentials, error) {
+	name, ok := m.Secrets[provider]
+	if !ok {
+		return nil, fmt.Errorf("no secret configured for provider: %s", provider)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Secret Manager client: %w", err)
+	}
+	defer client.Close()
+
+	result, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to access secret %s: %w", name, err)
+	}
+
+	creds := &ProviderCredentials{}
+	if err := json.Unmarshal(result.GetPayload().GetData(), creds); err != nil {
+		return nil, fmt.Errorf("failed to parse secret JSON: %w", err)
+	}
+	return creds, nil
+}
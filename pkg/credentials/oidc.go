@@ -0,0 +1,77 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultOIDCTokenEnv is the environment variable CIIDToken reads the CI
+// job's OIDC token from, e.g. a GitLab CI id_tokens entry.
+const DefaultOIDCTokenEnv = "CLOUD_DEPLOY_ID_TOKEN"
+
+// CIIDToken returns an OIDC ID token identifying the CI job, for clouds to
+// exchange for short-lived credentials. The token is read from the
+// environment variable env (DefaultOIDCTokenEnv if empty), or requested for
+// audience from GitHub Actions, which needs the id-token: write permission.
+func CIIDToken(ctx context.Context, env, audience string) (string, error) {
+	if env == "" {
+		env = DefaultOIDCTokenEnv
+	}
+	if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+		return token, nil
+	}
+
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", fmt.Errorf("no CI OIDC token: set %s to the job's ID token (GitLab CI id_tokens), or grant the GitHub Actions workflow the id-token: write permission", env)
+	}
+	return githubIDToken(ctx, requestURL, requestToken, audience)
+}
+
+// githubIDToken requests an ID token for audience from the GitHub Actions
+// token service.
+func githubIDToken(ctx context.Context, requestURL, requestToken, audience string) (string, error) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request GitHub Actions ID token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read GitHub Actions ID token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub Actions ID token request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil || out.Value == "" {
+		return "", fmt.Errorf("GitHub Actions returned no ID token")
+	}
+	return out.Value, nil
+}
@@ -0,0 +1,57 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCIIDTokenFromEnvironment(t *testing.T) {
+	t.Setenv("GITLAB_ID_TOKEN", "gitlab-jwt\n")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+
+	token, err := CIIDToken(context.Background(), "GITLAB_ID_TOKEN", "sts.amazonaws.com")
+	if err != nil {
+		t.Fatalf("CIIDToken() error = %v", err)
+	}
+	if token != "gitlab-jwt" {
+		t.Errorf("Expected gitlab-jwt, got %q", token)
+	}
+}
+
+func TestCIIDTokenFromGitHubActions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			t.Errorf("Unexpected Authorization %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("api-version") != "2.0" || r.URL.Query().Get("audience") != "api://AzureADTokenExchange" {
+			t.Errorf("Unexpected query %q", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"count":1,"value":"github-jwt"}`))
+	}))
+	defer server.Close()
+	t.Setenv(DefaultOIDCTokenEnv, "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+
+	token, err := CIIDToken(context.Background(), "", "api://AzureADTokenExchange")
+	if err != nil {
+		t.Fatalf("CIIDToken() error = %v", err)
+	}
+	if token != "github-jwt" {
+		t.Errorf("Expected github-jwt, got %q", token)
+	}
+}
+
+func TestCIIDTokenMissing(t *testing.T) {
+	t.Setenv(DefaultOIDCTokenEnv, "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "")
+
+	_, err := CIIDToken(context.Background(), "", "sts.amazonaws.com")
+	if err == nil || !strings.Contains(err.Error(), "id-token: write") {
+		t.Errorf("Expected a missing token error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+)
+
+// DefaultParameterPath is the path under which the "parameter-store" source
+// reads each provider's credentials, e.g. /cloud-deploy/aws/access_key_id.
+const DefaultParameterPath = "/cloud-deploy"
+
+// ssmParameter is a parameter of a GetParametersByPath response.
+type ssmParameter struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// getFromParameterStore retrieves credentials from AWS Systems Manager Parameter
+// Store. Each credential is a parameter named after its field under the
+// provider's path, e.g. /cloud-deploy/gcp/service_account_key; SecureString
+// parameters are decrypted.
+func (m *Manager) getFromParameterStore(ctx context.Context, provider string) (*ProviderCredentials, error) {
+	root, ok := m.Secrets[provider]
+	if !ok || root == "" {
+		root = DefaultParameterPath
+	}
+	prefix := strings.TrimRight(root, "/") + "/" + provider
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	params, err := getParametersByPath(ctx, awsapi.New(cfg, "ssm"), prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return nil, fmt.Errorf("no parameters found under %s", prefix)
+	}
+
+	// The parameters' names are the JSON fields of the provider's credentials
+	fields := make(map[string]string, len(params))
+	for _, p := range params {
+		fields[strings.TrimPrefix(p.Name, prefix+"/")] = p.Value
+	}
+	data, err := json.Marshal(map[string]map[string]string{provider: fields})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters: %w", err)
+	}
+	creds := &ProviderCredentials{}
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters under %s: %w", prefix, err)
+	}
+	return creds, nil
+}
+
+// getParametersByPath returns every parameter under path, following pages.
+func getParametersByPath(ctx context.Context, client *awsapi.Client, path string) ([]ssmParameter, error) {
+	var params []ssmParameter
+	token := ""
+	for {
+		in := map[string]interface{}{
+			"Path":           path,
+			"Recursive":      true,
+			"WithDecryption": true,
+		}
+		if token != "" {
+			in["NextToken"] = token
+		}
+		var out struct {
+			Parameters []ssmParameter `json:"Parameters"`
+			NextToken  string         `json:"NextToken"`
+		}
+		if err := client.JSON(ctx, "AmazonSSM.GetParametersByPath", in, &out); err != nil {
+			return nil, fmt.Errorf("failed to read parameters under %s: %w", path, err)
+		}
+		params = append(params, out.Parameters...)
+		if out.NextToken == "" {
+			return params, nil
+		}
+		token = out.NextToken
+	}
+}
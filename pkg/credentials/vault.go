@@ -0,0 +1,49 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+)
+
+// vaultCredentialsPath returns the path of the Vault secret holding the
+// credentials of provider, in the KV engine mounted at mount, or secret/ if
+// mount is empty.
+func vaultCredentialsPath(mount, provider string) string {
+	mount = strings.Trim(mount, "/")
+	if mount == "" {
+		mount = vault.DefaultKVMount
+	}
+	return mount + "/cloud-deploy/" + provider + "/credentials"
+}
+
+// getFromVault retrieves credentials from HashiCorp Vault. The provider's
+// secret holds one field per credential, named as in a Secrets Manager secret
+// (e.g. access_key_id and secret_access_key for AWS).
+func (m *Manager) getFromVault(ctx context.Context, provider string) (*ProviderCredentials, error) {
+	if m.Vault == nil {
+		return nil, fmt.Errorf("vault configuration required when credentials.source is 'vault'")
+	}
+	client, err := vault.Shared(*m.Vault)
+	if err != nil {
+		return nil, err
+	}
+	path := vaultCredentialsPath(m.Vault.KVMount, provider)
+	fields, err := client.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(map[string]interface{}{provider: fields})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vault secret: %w", err)
+	}
+	creds := &ProviderCredentials{}
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("failed to parse vault secret %s: %w", path, err)
+	}
+	return creds, nil
+}
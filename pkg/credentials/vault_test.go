@@ -0,0 +1,42 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+)
+
+func TestGetFromVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/kv/") {
+			w.Write([]byte(`{"data":{"path":"kv/","type":"kv","options":{"version":"2"}}}`))
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/cloud-deploy/azure/credentials" || r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"tenant_id":"tenant","client_id":"client","client_secret":"secret","subscription_id":"sub"},"metadata":{"version":1}}}`))
+	}))
+	defer server.Close()
+
+	m := &Manager{Source: "vault", Vault: &vault.Config{Address: server.URL, KVMount: "kv", Auth: vault.AuthConfig{Token: "root"}}}
+	creds, err := m.GetCredentials(context.Background(), "azure")
+	if err != nil {
+		t.Fatalf("GetCredentials() error = %v", err)
+	}
+	if creds.Azure.TenantID != "tenant" || creds.Azure.ClientSecret != "secret" || creds.Azure.SubscriptionID != "sub" {
+		t.Errorf("Unexpected credentials %+v", creds.Azure)
+	}
+
+	if _, err := m.GetCredentials(context.Background(), "gcp"); err == nil {
+		t.Error("Expected an error for a provider without a secret")
+	}
+	if _, err := (&Manager{Source: "vault"}).GetCredentials(context.Background(), "aws"); err == nil {
+		t.Error("Expected an error without a Vault configuration")
+	}
+}
@@ -0,0 +1,151 @@
+// Package dependencies checks the health of the external services a
+// deployment depends on, such as its database and third-party APIs, so that a
+// healthy deployment whose dependencies are down is visible in its status.
+package dependencies
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Result is the outcome of checking a dependency.
+type Result struct {
+	// Name of the dependency
+	Name string `json:"name"`
+
+	// What was checked: a URL without credentials or query, or a host and port
+	Target string `json:"target,omitempty"`
+
+	// Healthy reports whether the dependency answered the check
+	Healthy bool `json:"healthy"`
+
+	// HTTP status of a healthy URL, or why the check failed
+	Detail string `json:"detail,omitempty"`
+
+	// How long the check took
+	Latency time.Duration `json:"latency"`
+}
+
+// String formats the result as a line of status output.
+func (r Result) String() string {
+	s := r.Name
+	if r.Target != "" {
+		s += " (" + r.Target + ")"
+	}
+	if r.Healthy {
+		return s + ": " + r.Detail + " in " + r.Latency.Round(time.Millisecond).String()
+	}
+	return s + ": unhealthy: " + r.Detail
+}
+
+// dial opens the TCP connections of checks. It is a variable so tests can
+// replace it.
+var dial = func(ctx context.Context, address string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", address)
+}
+
+// Check checks every dependency concurrently and returns the results in the
+// order of deps. The URL of a dependency with env set is read from env, the
+// deployment's environment variables.
+func Check(ctx context.Context, deps []manifest.Dependency, env map[string]string) []Result {
+	results := make([]Result, len(deps))
+	var wg sync.WaitGroup
+	for i, d := range deps {
+		wg.Add(1)
+		go func(i int, d manifest.Dependency) {
+			defer wg.Done()
+			results[i] = check(ctx, d, env)
+		}(i, d)
+	}
+	wg.Wait()
+	return results
+}
+
+// Unhealthy returns the number of results that are not healthy.
+func Unhealthy(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Healthy {
+			n++
+		}
+	}
+	return n
+}
+
+// check checks a single dependency.
+func check(ctx context.Context, d manifest.Dependency, env map[string]string) Result {
+	result := Result{Name: d.Name}
+
+	rawURL := d.URL
+	if d.Env != "" {
+		value, ok := env[d.Env]
+		if !ok || value == "" {
+			result.Detail = fmt.Sprintf("environment variable %s is not set", d.Env)
+			return result
+		}
+		rawURL = value
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The error quotes the URL, which may hold credentials
+		result.Detail = "invalid URL"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout())
+	defer cancel()
+	start := time.Now()
+
+	if u.Scheme == "http" || u.Scheme == "https" {
+		result.Target = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+		result.Detail, err = checkHTTP(ctx, u.String())
+	} else {
+		result.Target, err = d.Address(rawURL)
+		if err == nil {
+			var conn net.Conn
+			if conn, err = dial(ctx, result.Target); err == nil {
+				conn.Close()
+				result.Detail = "connected"
+			}
+		}
+	}
+	result.Latency = time.Since(start)
+
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	result.Healthy = true
+	return result
+}
+
+// checkHTTP sends a GET request to rawURL and returns its status, or an
+// error when the request fails or the status is not 2xx or 3xx.
+func checkHTTP(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Report the cause without the URL, which may hold credentials
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return "HTTP " + resp.Status, nil
+}
@@ -0,0 +1,114 @@
+// Package errhints maps common cloud provider errors to remediation steps, so
+// failures come with what to do about them and not just what went wrong.
+package errhints
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rule matches an error message and describes how to fix it. Hint may refer
+// to the first submatch of Pattern as %s. Fallback rules only apply when no
+// other rule matched.
+type rule struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Hint     string
+	Fallback bool
+}
+
+// rules is checked in order; the first matching rule of each name applies.
+// Specific permission rules come first so a missing permission is named.
+var rules = []rule{
+	// AWS: "User: arn:... is not authorized to perform: ecr:CreateRepository on resource: ..."
+	{
+		Name:    "permission",
+		Pattern: regexp.MustCompile(`not authorized to perform: ([a-zA-Z0-9-]+:[a-zA-Z0-9*]+)`),
+		Hint:    "Grant the deployer the %s permission. Run `cloud-deploy iam-policy` to print every permission cloud-deploy needs.",
+	},
+	// GCP: "Permission 'run.services.create' denied on resource ..." or
+	// "... does not have permission run.services.create ..."
+	{
+		Name:    "permission",
+		Pattern: regexp.MustCompile(`(?:[Pp]ermission '([a-z]+\.[a-zA-Z.]+)' denied|does not have (?:the )?permission '?([a-z]+\.[a-zA-Z.]+)'?)`),
+		Hint:    "Grant the deployer's service account a role that includes %s. Run `cloud-deploy iam-policy` to print the roles cloud-deploy needs.",
+	},
+	// Azure: "... does not have authorization to perform action 'Microsoft.ContainerInstance/containerGroups/write' over scope ..."
+	{
+		Name:    "permission",
+		Pattern: regexp.MustCompile(`does not have authorization to perform action '([^']+)'`),
+		Hint:    "Assign the deployer a role that allows %s on the resource group. Run `cloud-deploy iam-policy` to print a custom role with every action cloud-deploy needs.",
+	},
+	{
+		Name:    "billing",
+		Pattern: regexp.MustCompile(`(?i)(billing (account )?(for project \S+ )?is (disabled|not enabled)|BILLING_DISABLED|billing must be enabled|requires billing)`),
+		Hint:    "Enable billing for the project: link a billing account with `gcloud billing projects link PROJECT_ID --billing-account=ACCOUNT_ID`, or set provider.billing_account_id in the manifest so cloud-deploy links it.",
+	},
+	{
+		Name:    "api-disabled",
+		Pattern: regexp.MustCompile(`(?i)(SERVICE_DISABLED|API has not been used in project|it is disabled\. Enable it by visiting)`),
+		Hint:    "Enable the API named in the error with `gcloud services enable SERVICE --project PROJECT_ID`, then wait a few minutes for it to propagate.",
+	},
+	{
+		Name:    "quota",
+		Pattern: regexp.MustCompile(`(?i)(quota exceeded|exceeded .*quota|RESOURCE_EXHAUSTED|QuotaExceeded|LimitExceeded|TooManyApplications|TooManyEnvironments|TooManyApplicationVersions|limit exceeded)`),
+		Hint:    "A service quota or limit was reached. Remove unused resources (for example old application versions or stopped environments) or request a quota increase in the provider's console (AWS Service Quotas, GCP IAM & Admin > Quotas, Azure Usage + quotas).",
+	},
+	{
+		Name:    "name-taken",
+		Pattern: regexp.MustCompile(`(?i)(already exists|AlreadyExists|already in use|ResourceExistsException|DNS name .* is not available|409 Conflict)`),
+		Hint:    "The name is already taken, possibly by another account or a resource that was not cleaned up. Choose a different application or environment name (or environment.cname), or remove the existing resource.",
+	},
+	{
+		Name:    "credentials",
+		Pattern: regexp.MustCompile(`(?i)(no valid credential|NoCredentialProviders|failed to refresh cached credentials|could not find default credentials|ExpiredToken|InvalidClientTokenId|token (has )?expired|AADSTS\d+)`),
+		Hint:    "Credentials are missing or expired. Sign in again (`aws configure` / `aws sso login`, `gcloud auth application-default login`, or `az login`) or check provider.credentials in the manifest.",
+	},
+	// Billing and disabled APIs are also reported as 403s, so a bare access
+	// denied only gets the generic hint when nothing more specific matched
+	{
+		Name:     "permission",
+		Pattern:  regexp.MustCompile(`(?i)(AccessDenied|PERMISSION_DENIED|AuthorizationFailed|Forbidden|status code: 403|Error 403)`),
+		Hint:     "The deployer's credentials lack a required permission. Run `cloud-deploy iam-policy` to print every permission cloud-deploy needs, and check which identity is in use (`aws sts get-caller-identity`, `gcloud auth list`, or `az account show`).",
+		Fallback: true,
+	},
+}
+
+// For returns remediation hints for err, most specific first. It returns nil
+// when no known problem matches.
+func For(err error) []string {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+
+	var hints []string
+	matched := make(map[string]bool)
+	for _, r := range rules {
+		if matched[r.Name] || (r.Fallback && len(hints) > 0) {
+			continue
+		}
+		m := r.Pattern.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		matched[r.Name] = true
+		hint := r.Hint
+		if strings.Contains(hint, "%s") {
+			hint = fmt.Sprintf(hint, firstGroup(m))
+		}
+		hints = append(hints, hint)
+	}
+	return hints
+}
+
+// firstGroup returns the first non-empty submatch.
+func firstGroup(m []string) string {
+	for _, g := range m[1:] {
+		if g != "" {
+			return g
+		}
+	}
+	return m[0]
+}
@@ -0,0 +1,115 @@
+package errhints
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string
+		want string
+	}{
+		{
+			name: "AWS missing permission",
+			err:  "failed to create repository: operation error ECR: CreateRepository, https response error StatusCode: 400, api error AccessDeniedException: User: arn:aws:iam::123456789012:user/deployer is not authorized to perform: ecr:CreateRepository on resource: arn:aws:ecr:us-east-1:123456789012:repository/my-app",
+			want: "Grant the deployer the ecr:CreateRepository permission",
+		},
+		{
+			name: "GCP missing permission",
+			err:  "rpc error: code = PermissionDenied desc = Permission 'run.services.create' denied on resource 'namespaces/my-project/services/my-app' (or resource may not exist).",
+			want: "includes run.services.create",
+		},
+		{
+			name: "GCP caller without permission",
+			err:  "googleapi: Error 403: The caller does not have permission 'artifactregistry.repositories.create'",
+			want: "includes artifactregistry.repositories.create",
+		},
+		{
+			name: "Azure missing permission",
+			err:  "RESPONSE 403: 403 Forbidden\nERROR CODE: AuthorizationFailed\nThe client 'abc' with object id 'abc' does not have authorization to perform action 'Microsoft.ContainerInstance/containerGroups/write' over scope '/subscriptions/x/resourceGroups/rg'",
+			want: "allows Microsoft.ContainerInstance/containerGroups/write",
+		},
+		{
+			name: "generic permission denied",
+			err:  "googleapi: Error 403: PERMISSION_DENIED",
+			want: "lack a required permission",
+		},
+		{
+			name: "GCP billing disabled",
+			err:  "googleapi: Error 403: This API method requires billing to be enabled. Please enable billing on project my-project, BILLING_DISABLED",
+			want: "Enable billing for the project",
+		},
+		{
+			name: "GCP API disabled",
+			err:  "googleapi: Error 403: Cloud Run Admin API has not been used in project 123 before or it is disabled., SERVICE_DISABLED",
+			want: "gcloud services enable",
+		},
+		{
+			name: "GCP quota",
+			err:  "rpc error: code = ResourceExhausted desc = Quota exceeded for quota metric 'Write requests'",
+			want: "quota or limit was reached",
+		},
+		{
+			name: "AWS too many environments",
+			err:  "api error TooManyEnvironmentsException: You have reached the maximum number of environments",
+			want: "quota or limit was reached",
+		},
+		{
+			name: "Azure quota",
+			err:  "ERROR CODE: QuotaExceeded",
+			want: "quota or limit was reached",
+		},
+		{
+			name: "name already exists",
+			err:  "api error InvalidParameterValue: Application my-app already exists.",
+			want: "name is already taken",
+		},
+		{
+			name: "CNAME taken",
+			err:  "DNS name (my-app.us-east-1.elasticbeanstalk.com) is not available",
+			want: "name is already taken",
+		},
+		{
+			name: "expired credentials",
+			err:  "operation error STS: GetCallerIdentity, api error ExpiredToken: The security token included in the request is expired",
+			want: "Credentials are missing or expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := For(errors.New(tt.err))
+			if len(hints) == 0 || !strings.Contains(hints[0], tt.want) {
+				t.Errorf("For() = %q, want first hint containing %q", hints, tt.want)
+			}
+		})
+	}
+}
+
+func TestForSpecificPermissionOnly(t *testing.T) {
+	// A message matching both a specific and the generic permission rule gets one hint
+	err := errors.New("AccessDeniedException: User: arn:aws:iam::1:user/d is not authorized to perform: s3:PutObject on resource: x")
+	hints := For(err)
+	if len(hints) != 1 || !strings.Contains(hints[0], "s3:PutObject") {
+		t.Errorf("Expected a single hint naming s3:PutObject, got %q", hints)
+	}
+}
+
+func TestForWrapped(t *testing.T) {
+	err := fmt.Errorf("deployment failed: %w", errors.New("Quota exceeded for region"))
+	if hints := For(err); len(hints) != 1 {
+		t.Errorf("Expected hint for wrapped error, got %q", hints)
+	}
+}
+
+func TestForNoMatch(t *testing.T) {
+	if hints := For(errors.New("manifest has no secrets to sync")); hints != nil {
+		t.Errorf("Expected no hints, got %q", hints)
+	}
+	if hints := For(nil); hints != nil {
+		t.Errorf("Expected no hints for nil, got %q", hints)
+	}
+}
@@ -0,0 +1,170 @@
+// Package hooks runs the manifest's lifecycle hooks: shell commands and
+// webhooks run before and after deploys and rollbacks, and when they fail, so
+// pipelines can run migrations, warm caches, or page someone without wrapping
+// cloud-deploy in scripts.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Metadata describes the deployment a hook runs for. Commands get it as
+// CLOUD_DEPLOY_* environment variables, and webhooks as the JSON body.
+type Metadata struct {
+	// Hook event, e.g. pre_deploy
+	Event string `json:"event"`
+
+	// cloud-deploy command, e.g. deploy
+	Command string `json:"command"`
+
+	Provider    string `json:"provider"`
+	Region      string `json:"region,omitempty"`
+	Application string `json:"application"`
+	Environment string `json:"environment"`
+
+	// Image being deployed, pinned to its digest once deployed when the provider reports it
+	Image string `json:"image,omitempty"`
+
+	// URL and status of the deployment, once deployed
+	URL    string `json:"url,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// Error of the failure, for on_failure hooks
+	Error string `json:"error,omitempty"`
+}
+
+// Env returns the metadata as CLOUD_DEPLOY_* environment variables.
+func (md Metadata) Env() []string {
+	return []string{
+		"CLOUD_DEPLOY_EVENT=" + md.Event,
+		"CLOUD_DEPLOY_COMMAND=" + md.Command,
+		"CLOUD_DEPLOY_PROVIDER=" + md.Provider,
+		"CLOUD_DEPLOY_REGION=" + md.Region,
+		"CLOUD_DEPLOY_APPLICATION=" + md.Application,
+		"CLOUD_DEPLOY_ENVIRONMENT=" + md.Environment,
+		"CLOUD_DEPLOY_IMAGE=" + md.Image,
+		"CLOUD_DEPLOY_URL=" + md.URL,
+		"CLOUD_DEPLOY_STATUS=" + md.Status,
+		"CLOUD_DEPLOY_ERROR=" + md.Error,
+	}
+}
+
+// Run runs hooks in order and returns the error of the first that fails,
+// without running the rest.
+func Run(ctx context.Context, hooks []manifest.Hook, md Metadata) error {
+	for _, h := range hooks {
+		logging.Info("Running hook", "event", md.Event, "hook", h.HookName())
+		var err error
+		if h.Command != "" {
+			err = runCommand(ctx, h, md)
+		} else {
+			err = callWebhook(ctx, h, md)
+		}
+		if err != nil {
+			return fmt.Errorf("%s hook %s failed: %w", md.Event, h.HookName(), err)
+		}
+	}
+	return nil
+}
+
+// runCommand runs the hook's command with sh -c, logging its output line by
+// line.
+func runCommand(ctx context.Context, h manifest.Hook, md Metadata) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout())
+	defer cancel()
+
+	out := &lineLogger{}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Env = append(os.Environ(), md.Env()...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	killProcessGroup(cmd)
+	// Output pipes held open by children that left the process group must not
+	// outlive the timeout
+	cmd.WaitDelay = 5 * time.Second
+	err := cmd.Run()
+	out.Flush()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", h.Timeout())
+	}
+	return err
+}
+
+// callWebhook POSTs the metadata to the hook's webhook as JSON, failing
+// unless it responds with a 2xx status.
+func callWebhook(ctx context.Context, h manifest.Hook, md Metadata) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout())
+	defer cancel()
+
+	body, err := json.Marshal(md)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range h.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Report the cause without the URL, which may hold a token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned HTTP %s", resp.Status)
+	}
+	return nil
+}
+
+// lineLogger logs what is written to it line by line.
+type lineLogger struct {
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		logging.Info("  " + strings.TrimRight(string(l.partial[:i]), "\r"))
+		l.partial = l.partial[i+1:]
+	}
+}
+
+// Flush logs the last line if it did not end with a newline.
+func (l *lineLogger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.partial) > 0 {
+		logging.Info("  " + string(l.partial))
+		l.partial = nil
+	}
+}
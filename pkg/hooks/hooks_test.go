@@ -0,0 +1,114 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func testMetadata() Metadata {
+	return Metadata{
+		Event:       manifest.HookPostDeploy,
+		Command:     "deploy",
+		Provider:    "aws",
+		Region:      "us-east-1",
+		Application: "my-app",
+		Environment: "my-app-prod",
+		URL:         "http://my-app-prod.us-east-1.elasticbeanstalk.com",
+		Status:      "Ready",
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	hooks := []manifest.Hook{{Command: `echo "$CLOUD_DEPLOY_EVENT $CLOUD_DEPLOY_ENVIRONMENT $CLOUD_DEPLOY_URL" > ` + out}}
+	if err := Run(context.Background(), hooks, testMetadata()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "post_deploy my-app-prod http://my-app-prod.us-east-1.elasticbeanstalk.com" {
+		t.Errorf("Hook saw %q", got)
+	}
+}
+
+func TestRunStopsAtFailure(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	hooks := []manifest.Hook{
+		{Name: "migrate", Command: "echo migrating; exit 3"},
+		{Command: "touch " + marker},
+	}
+	err := Run(context.Background(), hooks, testMetadata())
+	if err == nil || err.Error() != "post_deploy hook migrate failed: exit status 3" {
+		t.Errorf("Expected the failing hook's error, got: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected the hooks after a failure not to run")
+	}
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	start := time.Now()
+	err := Run(context.Background(), []manifest.Hook{{Command: "sleep 30", TimeoutSeconds: 1}}, testMetadata())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Errorf("Expected a timeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the hook to be stopped at its timeout, took %s", elapsed)
+	}
+}
+
+func TestRunWebhook(t *testing.T) {
+	var got Metadata
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.Error != "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	hooks := []manifest.Hook{{Webhook: server.URL + "/hooks", Headers: map[string]string{"Authorization": "Bearer t0ken"}}}
+	if err := Run(context.Background(), hooks, testMetadata()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got != testMetadata() || auth != "Bearer t0ken" {
+		t.Errorf("Webhook got %+v with Authorization %q", got, auth)
+	}
+
+	md := testMetadata()
+	md.Event = manifest.HookOnFailure
+	md.Error = "deploy failed"
+	if err := Run(context.Background(), hooks, md); err == nil || !strings.Contains(err.Error(), "webhook returned HTTP 503") {
+		t.Errorf("Expected a webhook error, got: %v", err)
+	}
+}
+
+func TestLineLogger(t *testing.T) {
+	l := &lineLogger{}
+	l.Write([]byte("first\nsec"))
+	l.Write([]byte("ond\r\nthi"))
+	if string(l.partial) != "thi" {
+		t.Errorf("Expected the unfinished line to be kept, got %q", l.partial)
+	}
+	l.Flush()
+	if len(l.partial) != 0 {
+		t.Errorf("Expected Flush to log the unfinished line, got %q", l.partial)
+	}
+}
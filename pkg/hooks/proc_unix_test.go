@@ -0,0 +1,46 @@
+//go:build !windows
+
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestRunCommandTimeoutKillsChildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	hook := manifest.Hook{Command: "sleep 30 & echo $! > " + pidFile + "; wait", TimeoutSeconds: 1}
+
+	start := time.Now()
+	if err := Run(context.Background(), []manifest.Hook{hook}, testMetadata()); err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Errorf("Expected a timeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the hook to be stopped at its timeout, took %s", elapsed)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The killed child is reaped by init shortly after
+	deadline := time.Now().Add(2 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Child process %d outlived the hook", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
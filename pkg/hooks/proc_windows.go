@@ -0,0 +1,8 @@
+package hooks
+
+import "os/exec"
+
+// killProcessGroup leaves cmd to be killed alone when its context is done:
+// Windows has no process groups to signal, and WaitDelay bounds how long its
+// children can hold the output open.
+func killProcessGroup(cmd *exec.Cmd) {}
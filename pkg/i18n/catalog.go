@@ -0,0 +1,519 @@
+package i18n
+
+// catalogs maps language codes to their messages. Every key must have an
+// English message; other languages may omit keys, which fall back to English.
+var catalogs = map[string]map[string]string{
+	"en": en,
+	"es": es,
+	"ja": ja,
+}
+
+var en = map[string]string{
+	"version":                      "cloud-deploy version %s",
+	"version.commit":               "  commit: %s",
+	"version.built":                "  built: %s",
+	"manifest.load_failed":         "Error loading manifest: %v",
+	"manifest.verified":            "Manifest signature verified: %s",
+	"manifest.schema_failed":       "Error writing the manifest schema: %v",
+	"policy.load_failed":           "Error loading policy: %v",
+	"provider.create_failed":       "Error creating provider: %v",
+	"provider.close_failed":        "Failed to close provider: %v",
+	"command.unknown":              "Unknown command: %s",
+	"command.valid":                "Valid commands: %s",
+	"command.deprecated":           "-command is deprecated; run cloud-deploy %s instead",
+	"command.defaulted":            "Running without a command is deprecated and deploys; run cloud-deploy deploy instead",
+	"completion.failed":            "Failed to write the completion script: %v",
+	"hint":                         "  Hint: %s",
+	"summary.application":          "  Application: %s",
+	"summary.environment":          "  Environment: %s",
+	"summary.url":                  "  URL: %s",
+	"summary.status":               "  Status: %s",
+	"summary.health":               "  Health: %s",
+	"summary.last_updated":         "  Last Updated: %s",
+	"summary.message":              "  Message: %s",
+	"deploy.secrets_failed":        "Failed to resolve secrets: %v",
+	"deploy.failed":                "Deployment failed: %v",
+	"deploy.success":               "✓ Deployment successful!",
+	"deploy.provider_success":      "✓ %s: deployment successful",
+	"deploy.provider_failed":       "✗ %s: deployment failed: %v",
+	"deploy.export_failed":         "Failed to export resources: %v",
+	"verify.start":                 "Verifying the deployment with %d checks...",
+	"verify.rolling_back":          "Verification failed, rolling back the deployment...",
+	"verify.failed":                "Deployment verification failed: %v",
+	"hooks.pre_deploy_failed":      "Pre-deploy hook failed, not deploying: %v",
+	"hooks.failed":                 "Hook failed: %v",
+	"notify.failed":                "Failed to send notifications: %v",
+	"deploy.exported":              "  Exported resources to: %s",
+	"stop.start":                   "Stopping deployment...",
+	"stop.failed":                  "Stop failed: %v",
+	"stop.success":                 "✓ Deployment stopped successfully",
+	"destroy.start":                "Destroying deployment...",
+	"destroy.failed":               "Destroy failed: %v",
+	"destroy.success":              "✓ Deployment destroyed successfully",
+	"destroy.plan_header":          "Destroy plan for %s (nothing was deleted):",
+	"destroy.plan_dependents":      "Depending on deleted resources:",
+	"destroy.plan_dependent":       "  ! %s %s depends on %s",
+	"destroy.plan_summary":         "Destroy: %d to delete, %d kept, %d dependents",
+	"destroy.plan_failed":          "Destroy plan failed: %v",
+	"status.failed":                "Failed to get status: %v",
+	"status.header":                "Deployment Status:",
+	"status.deep_failed":           "Failed to get remediation events: %v",
+	"status.remediation_header":    "Remediation events (last %s):",
+	"status.remediation_none":      "  None: no instances were replaced and no containers restarted",
+	"status.dependencies_failed":   "Failed to check dependencies: %v",
+	"status.dependencies_header":   "Dependencies:",
+	"summary.dependencies":         "  Dependencies: all %d healthy",
+	"summary.dependencies_down":    "  Dependencies: %d of %d unhealthy",
+	"rollback.start":               "Rolling back deployment...",
+	"rollback.failed":              "Rollback failed: %v",
+	"rollback.success":             "✓ Rollback successful!",
+	"logs.failed":                  "Failed to read logs: %v",
+	"export.failed":                "Export failed: %v",
+	"scale.start":                  "Scaling environment: %s",
+	"scale.success":                "✓ Scaling complete",
+	"scale.failed":                 "Scaling failed: %v",
+	"scale.manifest_hint":          "  The instance counts differ from the manifest; update it, or the next deploy restores the manifest's counts",
+	"maintenance.on_start":         "Turning on maintenance mode: %s",
+	"maintenance.off_start":        "Turning off maintenance mode: %s",
+	"maintenance.on_success":       "✓ Maintenance mode on; the deployment keeps running",
+	"maintenance.off_success":      "✓ Maintenance mode off; traffic restored",
+	"maintenance.failed":           "Maintenance mode change failed: %v",
+	"maintenance.route_added":      "  Routed %s to Worker %s",
+	"maintenance.route_removed":    "  Removed route %s",
+	"attach.start":                 "Attaching to %s",
+	"attach.success":               "✓ Operation finished",
+	"attach.failed":                "Attach failed: %v",
+	"attach.none":                  "No operation in progress for %s",
+	"output.invalid":               "Invalid -output: %v",
+	"read_only.failed":             "Failed to determine read-only mode: %v",
+	"read_only.refused":            "Refusing the %s command in read-only mode; only status, logs, history, plan, and other commands that read can run",
+	"attach.unsupported":           "Provider %s does not journal its operations, so there is nothing to attach to",
+	"operation.in_progress":        "Operation %s is still in progress; wait for it with cloud-deploy attach",
+	"operation.journal_failed":     "Operation journal unavailable: %v",
+	"image.promote_start":          "Promoting images to %s",
+	"image.promoted":               "  %s → %s (%s)",
+	"image.promote_success":        "✓ Images promoted; deploys of the new tags use the promoted digests",
+	"image.promote_failed":         "Image promotion failed: %v",
+	"image.pinned":                 "Deploying promoted image %s (%s)",
+	"image.pin_failed":             "Failed to pin promoted images: %v",
+	"dns.created":                  "  Created DNS record: %s %s → %s",
+	"dns.updated":                  "  Updated DNS record: %s %s → %s",
+	"dns.unchanged":                "  DNS record is up to date: %s %s → %s",
+	"dns.failed":                   "Failed to update DNS records: %v",
+	"dns.removed":                  "  Removed DNS record: %s %s → %s",
+	"dns.remove_failed":            "Failed to remove DNS records: %v",
+	"lb.monitor_created":           "  Created health monitor %s",
+	"lb.monitor_updated":           "  Updated health monitor %s",
+	"lb.monitor_unchanged":         "  Health monitor %s is up to date",
+	"lb.pool_created":              "  Created origin pool %s",
+	"lb.pool_updated":              "  Updated origin pool %s",
+	"lb.pool_unchanged":            "  Origin pool %s is up to date",
+	"lb.pool_skipped":              "  Skipped origin pool %s: its provider has not deployed",
+	"lb.load_balancer_created":     "  Created load balancer %s",
+	"lb.load_balancer_updated":     "  Updated load balancer %s",
+	"lb.load_balancer_unchanged":   "  Load balancer %s is up to date",
+	"lb.failed":                    "Failed to update the load balancer: %v",
+	"build.start":                  "Building image from source: %s",
+	"build.success":                "✓ Image built",
+	"build.failed":                 "Build failed: %v",
+	"build.remote":                 "Image %s will be built by AWS CodeBuild",
+	"version_skew.failed":          "Version check failed: %v",
+	"version_skew.warning":         "Environment %s was last deployed with cloud-deploy %s (this is %s)",
+	"version_skew.check_failed":    "Could not check which cloud-deploy version last deployed the environment: %v",
+	"posture.header":               "Posture of %s:",
+	"posture.summary":              "Posture: %d ok, %d warning, %d failing",
+	"posture.provider_failed":      "✗ Posture check for %s failed: %v",
+	"validate.ok":                  "✓ No problems found",
+	"validate.summary":             "✗ %d problems found",
+	"validate.provider_failed":     "✗ Validation for %s failed: %v",
+	"validate.unsupported":         "Provider %s has no checks of its own; only the manifest was checked",
+	"roll_forward.start":           "Rolling forward...",
+	"roll_forward.target":          "Redeploying %s, the newest version that was not rolled back",
+	"roll_forward.failed":          "Roll-forward failed: %v",
+	"roll_forward.success":         "✓ Rolled forward to %s!",
+	"inventory.header":             "Resources of %s:",
+	"inventory.columns":            "TYPE\tNAME\tID\tSOURCE",
+	"inventory.empty":              "No resources found",
+	"inventory.dns_failed":         "Could not list DNS records: %v",
+	"inventory.history_failed":     "Could not read the deployment history: %v",
+	"inventory.provider_failed":    "✗ Inventory for %s failed: %v",
+	"cleanup.header":               "Cleanup of %s:",
+	"cleanup.columns":              "TYPE\tNAME\tCREATED",
+	"cleanup.summary":              "✓ %d deleted, %d kept",
+	"cleanup.dry_run_summary":      "%d would be deleted, %d kept (dry run)",
+	"cleanup.keep_invalid":         "-keep must be at least 1, got %d",
+	"cleanup.provider_failed":      "✗ Cleanup for %s failed: %v",
+	"secrets_sync.failed":          "Secrets sync failed: %v",
+	"secrets_sync.up_to_date":      "✓ Secrets are up to date",
+	"secrets_sync.updated":         "✓ Updated %d secret(s): %s",
+	"credentials_rotate.failed":    "Credential rotation failed: %v",
+	"credentials_rotate.creating":  "Creating new %s credentials...",
+	"credentials_rotate.verifying": "Verifying new credentials...",
+	"credentials_rotate.discard":   "Failed to revoke unused new credentials: %v",
+	"credentials_rotate.stored":    "✓ Stored new credentials in %s",
+	"credentials_rotate.success":   "✓ Credentials rotated; old credentials deactivated",
+	"cname.unsupported":            "Provider %s does not use CNAME prefixes",
+	"cname.failed":                 "CNAME check failed: %v",
+	"cname.taken":                  "✗ CNAME prefix %s is taken",
+	"cname.available":              "✓ CNAME prefix %s is available: %s",
+	"iam_policy.failed":            "Failed to generate IAM policy: %v",
+	"stats.failed":                 "Failed to show stats: %v",
+	"stats.empty":                  "No commands recorded in %s",
+	"plan.header":                  "Plan for %s:",
+	"plan.summary":                 "Plan: %d to create, %d to update, %d to delete, %d unchanged",
+	"plan.failed":                  "Plan failed: %v",
+	"plan.provider_failed":         "✗ Plan for %s failed: %v",
+	"history.header":               "VERSION\tCOMMAND\tTIME\tSTATUS\tIMAGES\tURL",
+	"history.empty":                "No deployments recorded for %s",
+	"history.failed":               "Failed to show deployment history: %v",
+	"history.recorded":             "  Recorded as version: %s",
+	"history.record_failed":        "Failed to record deployment history: %v",
+	"rollback.to_start":            "Rolling back to %s...",
+	"record.saved":                 "  Transcript written to: %s",
+	"record.failed":                "Failed to write transcript: %v",
+	"vault.close_failed":           "Failed to close the vault session: %v",
+	"replay.header":                "Replay of %s on %s (%s), recorded %s",
+	"replay.succeeded":             "✓ Command succeeded in %v",
+	"replay.command_failed":        "✗ Command failed after %v",
+	"replay.failed":                "Replay failed: %v",
+}
+
+var es = map[string]string{
+	"version":                      "cloud-deploy versión %s",
+	"version.commit":               "  commit: %s",
+	"version.built":                "  compilado: %s",
+	"manifest.load_failed":         "Error al cargar el manifiesto: %v",
+	"manifest.verified":            "Firma del manifiesto verificada: %s",
+	"manifest.schema_failed":       "Error al escribir el esquema del manifiesto: %v",
+	"policy.load_failed":           "Error al cargar la política: %v",
+	"provider.create_failed":       "Error al crear el proveedor: %v",
+	"provider.close_failed":        "No se pudo cerrar el proveedor: %v",
+	"command.unknown":              "Comando desconocido: %s",
+	"command.valid":                "Comandos válidos: %s",
+	"command.deprecated":           "-command está obsoleto; ejecute cloud-deploy %s en su lugar",
+	"command.defaulted":            "Ejecutar sin un comando está obsoleto y despliega; ejecute cloud-deploy deploy en su lugar",
+	"completion.failed":            "No se pudo escribir el script de autocompletado: %v",
+	"hint":                         "  Sugerencia: %s",
+	"summary.application":          "  Aplicación: %s",
+	"summary.environment":          "  Entorno: %s",
+	"summary.url":                  "  URL: %s",
+	"summary.status":               "  Estado: %s",
+	"summary.health":               "  Salud: %s",
+	"summary.last_updated":         "  Última actualización: %s",
+	"summary.message":              "  Mensaje: %s",
+	"deploy.secrets_failed":        "No se pudieron resolver los secretos: %v",
+	"deploy.failed":                "El despliegue falló: %v",
+	"deploy.success":               "✓ ¡Despliegue completado!",
+	"deploy.provider_success":      "✓ %s: despliegue completado",
+	"deploy.provider_failed":       "✗ %s: el despliegue falló: %v",
+	"deploy.export_failed":         "No se pudieron exportar los recursos: %v",
+	"verify.start":                 "Verificando el despliegue con %d comprobaciones...",
+	"verify.rolling_back":          "La verificación falló, revirtiendo el despliegue...",
+	"verify.failed":                "La verificación del despliegue falló: %v",
+	"hooks.pre_deploy_failed":      "Falló un hook previo al despliegue, no se despliega: %v",
+	"hooks.failed":                 "Falló un hook: %v",
+	"notify.failed":                "No se pudieron enviar las notificaciones: %v",
+	"deploy.exported":              "  Recursos exportados a: %s",
+	"stop.start":                   "Deteniendo el despliegue...",
+	"stop.failed":                  "La detención falló: %v",
+	"stop.success":                 "✓ Despliegue detenido correctamente",
+	"destroy.start":                "Eliminando el despliegue...",
+	"destroy.failed":               "La eliminación falló: %v",
+	"destroy.success":              "✓ Despliegue eliminado correctamente",
+	"destroy.plan_header":          "Plan de eliminación para %s (no se eliminó nada):",
+	"destroy.plan_dependents":      "Dependen de recursos eliminados:",
+	"destroy.plan_dependent":       "  ! %s %s depende de %s",
+	"destroy.plan_summary":         "Eliminación: %d para eliminar, %d conservados, %d dependientes",
+	"destroy.plan_failed":          "El plan de eliminación falló: %v",
+	"status.failed":                "No se pudo obtener el estado: %v",
+	"status.header":                "Estado del despliegue:",
+	"status.deep_failed":           "No se pudieron obtener los eventos de remediación: %v",
+	"status.remediation_header":    "Eventos de remediación (últimos %s):",
+	"status.remediation_none":      "  Ninguno: no se reemplazaron instancias ni se reiniciaron contenedores",
+	"status.dependencies_failed":   "No se pudieron comprobar las dependencias: %v",
+	"status.dependencies_header":   "Dependencias:",
+	"summary.dependencies":         "  Dependencias: las %d están sanas",
+	"summary.dependencies_down":    "  Dependencias: %d de %d no están sanas",
+	"rollback.start":               "Revirtiendo el despliegue...",
+	"rollback.failed":              "La reversión falló: %v",
+	"rollback.success":             "✓ ¡Reversión completada!",
+	"logs.failed":                  "No se pudieron leer los registros: %v",
+	"export.failed":                "La exportación falló: %v",
+	"scale.start":                  "Escalando el entorno: %s",
+	"scale.success":                "✓ Escalado completado",
+	"scale.failed":                 "El escalado falló: %v",
+	"scale.manifest_hint":          "  El número de instancias difiere del manifiesto; actualícelo o el próximo despliegue restaurará los valores del manifiesto",
+	"maintenance.on_start":         "Activando el modo de mantenimiento: %s",
+	"maintenance.off_start":        "Desactivando el modo de mantenimiento: %s",
+	"maintenance.on_success":       "✓ Modo de mantenimiento activado; el despliegue sigue en ejecución",
+	"maintenance.off_success":      "✓ Modo de mantenimiento desactivado; tráfico restaurado",
+	"maintenance.failed":           "El cambio del modo de mantenimiento falló: %v",
+	"maintenance.route_added":      "  Ruta %s dirigida al Worker %s",
+	"maintenance.route_removed":    "  Ruta %s eliminada",
+	"attach.start":                 "Conectando con %s",
+	"attach.success":               "✓ Operación terminada",
+	"attach.failed":                "La conexión falló: %v",
+	"attach.none":                  "No hay ninguna operación en curso para %s",
+	"output.invalid":               "-output no válido: %v",
+	"read_only.failed":             "No se pudo determinar el modo de solo lectura: %v",
+	"read_only.refused":            "Se rechaza el comando %s en modo de solo lectura; solo pueden ejecutarse status, logs, history, plan y otros comandos de lectura",
+	"attach.unsupported":           "El proveedor %s no registra sus operaciones, así que no hay nada con lo que conectar",
+	"operation.in_progress":        "La operación %s sigue en curso; espérela con cloud-deploy attach",
+	"operation.journal_failed":     "Registro de operaciones no disponible: %v",
+	"image.promote_start":          "Promoviendo imágenes a %s",
+	"image.promoted":               "  %s → %s (%s)",
+	"image.promote_success":        "✓ Imágenes promovidas; los despliegues de las nuevas etiquetas usan los digests promovidos",
+	"image.promote_failed":         "La promoción de imágenes falló: %v",
+	"image.pinned":                 "Desplegando la imagen promovida %s (%s)",
+	"image.pin_failed":             "No se pudieron fijar las imágenes promovidas: %v",
+	"dns.created":                  "  Registro DNS creado: %s %s → %s",
+	"dns.updated":                  "  Registro DNS actualizado: %s %s → %s",
+	"dns.unchanged":                "  El registro DNS está actualizado: %s %s → %s",
+	"dns.failed":                   "Error al actualizar los registros DNS: %v",
+	"dns.removed":                  "  Registro DNS eliminado: %s %s → %s",
+	"dns.remove_failed":            "Error al eliminar los registros DNS: %v",
+	"lb.monitor_created":           "  Monitor de salud %s creado",
+	"lb.monitor_updated":           "  Monitor de salud %s actualizado",
+	"lb.monitor_unchanged":         "  El monitor de salud %s está actualizado",
+	"lb.pool_created":              "  Grupo de orígenes %s creado",
+	"lb.pool_updated":              "  Grupo de orígenes %s actualizado",
+	"lb.pool_unchanged":            "  El grupo de orígenes %s está actualizado",
+	"lb.pool_skipped":              "  Grupo de orígenes %s omitido: su proveedor no ha desplegado",
+	"lb.load_balancer_created":     "  Balanceador de carga %s creado",
+	"lb.load_balancer_updated":     "  Balanceador de carga %s actualizado",
+	"lb.load_balancer_unchanged":   "  El balanceador de carga %s está actualizado",
+	"lb.failed":                    "Error al actualizar el balanceador de carga: %v",
+	"build.start":                  "Construyendo la imagen desde el código fuente: %s",
+	"build.success":                "✓ Imagen construida",
+	"build.failed":                 "La construcción falló: %v",
+	"build.remote":                 "La imagen %s se construirá con AWS CodeBuild",
+	"version_skew.failed":          "La comprobación de versión falló: %v",
+	"version_skew.warning":         "El entorno %s se desplegó por última vez con cloud-deploy %s (esta es %s)",
+	"version_skew.check_failed":    "No se pudo comprobar qué versión de cloud-deploy desplegó el entorno por última vez: %v",
+	"posture.header":               "Estado de la cuenta de %s:",
+	"posture.summary":              "Estado: %d correctos, %d advertencias, %d fallidos",
+	"posture.provider_failed":      "✗ La comprobación del estado de %s falló: %v",
+	"validate.ok":                  "✓ No se encontraron problemas",
+	"validate.summary":             "✗ Se encontraron %d problemas",
+	"validate.provider_failed":     "✗ La validación para %s falló: %v",
+	"validate.unsupported":         "El proveedor %s no tiene comprobaciones propias; solo se comprobó el manifiesto",
+	"roll_forward.start":           "Avanzando el despliegue...",
+	"roll_forward.target":          "Volviendo a desplegar %s, la versión más reciente que no fue revertida",
+	"roll_forward.failed":          "El avance falló: %v",
+	"roll_forward.success":         "✓ ¡Avanzado a %s!",
+	"inventory.header":             "Recursos en %s:",
+	"inventory.columns":            "TIPO\tNOMBRE\tID\tORIGEN",
+	"inventory.empty":              "No se encontraron recursos",
+	"inventory.dns_failed":         "No se pudieron listar los registros DNS: %v",
+	"inventory.history_failed":     "No se pudo leer el historial de despliegues: %v",
+	"inventory.provider_failed":    "✗ El inventario de %s falló: %v",
+	"cleanup.header":               "Limpieza de %s:",
+	"cleanup.columns":              "TIPO\tNOMBRE\tCREADO",
+	"cleanup.summary":              "✓ %d eliminados, %d conservados",
+	"cleanup.dry_run_summary":      "Se eliminarían %d, se conservan %d (simulación)",
+	"cleanup.keep_invalid":         "-keep debe ser al menos 1, se indicó %d",
+	"cleanup.provider_failed":      "✗ La limpieza de %s falló: %v",
+	"secrets_sync.failed":          "La sincronización de secretos falló: %v",
+	"secrets_sync.up_to_date":      "✓ Los secretos están actualizados",
+	"secrets_sync.updated":         "✓ %d secreto(s) actualizado(s): %s",
+	"credentials_rotate.failed":    "La rotación de credenciales falló: %v",
+	"credentials_rotate.creating":  "Creando nuevas credenciales de %s...",
+	"credentials_rotate.verifying": "Verificando las nuevas credenciales...",
+	"credentials_rotate.discard":   "No se pudieron revocar las nuevas credenciales sin usar: %v",
+	"credentials_rotate.stored":    "✓ Nuevas credenciales guardadas en %s",
+	"credentials_rotate.success":   "✓ Credenciales rotadas; las credenciales anteriores se desactivaron",
+	"cname.unsupported":            "El proveedor %s no usa prefijos CNAME",
+	"cname.failed":                 "La comprobación del CNAME falló: %v",
+	"cname.taken":                  "✗ El prefijo CNAME %s ya está en uso",
+	"cname.available":              "✓ El prefijo CNAME %s está disponible: %s",
+	"iam_policy.failed":            "No se pudo generar la política de IAM: %v",
+	"stats.failed":                 "No se pudieron mostrar las estadísticas: %v",
+	"stats.empty":                  "No hay comandos registrados en %s",
+	"plan.header":                  "Plan para %s:",
+	"plan.summary":                 "Plan: %d para crear, %d para actualizar, %d para eliminar, %d sin cambios",
+	"plan.failed":                  "El plan falló: %v",
+	"plan.provider_failed":         "✗ El plan para %s falló: %v",
+	"history.header":               "VERSIÓN\tCOMANDO\tFECHA\tESTADO\tIMÁGENES\tURL",
+	"history.empty":                "No hay despliegues registrados para %s",
+	"history.failed":               "No se pudo mostrar el historial de despliegues: %v",
+	"history.recorded":             "  Registrado como versión: %s",
+	"history.record_failed":        "No se pudo registrar el historial de despliegues: %v",
+	"rollback.to_start":            "Revirtiendo a %s...",
+	"record.saved":                 "  Transcripción guardada en: %s",
+	"record.failed":                "No se pudo guardar la transcripción: %v",
+	"vault.close_failed":           "No se pudo cerrar la sesión de vault: %v",
+	"replay.header":                "Reproducción de %s en %s (%s), grabada el %s",
+	"replay.succeeded":             "✓ El comando se completó en %v",
+	"replay.command_failed":        "✗ El comando falló tras %v",
+	"replay.failed":                "La reproducción falló: %v",
+}
+
+var ja = map[string]string{
+	"version":                      "cloud-deploy バージョン %s",
+	"version.commit":               "  コミット: %s",
+	"version.built":                "  ビルド日時: %s",
+	"manifest.load_failed":         "マニフェストの読み込みに失敗しました: %v",
+	"manifest.verified":            "マニフェストの署名を検証しました: %s",
+	"manifest.schema_failed":       "マニフェストのスキーマの書き出しに失敗しました: %v",
+	"policy.load_failed":           "ポリシーの読み込みに失敗しました: %v",
+	"provider.create_failed":       "プロバイダーの作成に失敗しました: %v",
+	"provider.close_failed":        "プロバイダーのクローズに失敗しました: %v",
+	"command.unknown":              "不明なコマンドです: %s",
+	"command.valid":                "有効なコマンド: %s",
+	"command.deprecated":           "-command は非推奨です。代わりに cloud-deploy %s を実行してください",
+	"command.defaulted":            "コマンドなしでの実行は非推奨で、デプロイを行います。代わりに cloud-deploy deploy を実行してください",
+	"completion.failed":            "補完スクリプトを書き込めませんでした: %v",
+	"hint":                         "  ヒント: %s",
+	"summary.application":          "  アプリケーション: %s",
+	"summary.environment":          "  環境: %s",
+	"summary.url":                  "  URL: %s",
+	"summary.status":               "  ステータス: %s",
+	"summary.health":               "  ヘルス: %s",
+	"summary.last_updated":         "  最終更新: %s",
+	"summary.message":              "  メッセージ: %s",
+	"deploy.secrets_failed":        "シークレットの解決に失敗しました: %v",
+	"deploy.failed":                "デプロイに失敗しました: %v",
+	"deploy.success":               "✓ デプロイが完了しました",
+	"deploy.provider_success":      "✓ %s: デプロイが完了しました",
+	"deploy.provider_failed":       "✗ %s: デプロイに失敗しました: %v",
+	"deploy.export_failed":         "リソースのエクスポートに失敗しました: %v",
+	"verify.start":                 "%d 件のチェックでデプロイを検証しています...",
+	"verify.rolling_back":          "検証に失敗したため、デプロイをロールバックしています...",
+	"verify.failed":                "デプロイの検証に失敗しました: %v",
+	"hooks.pre_deploy_failed":      "デプロイ前フックが失敗したため、デプロイしません: %v",
+	"hooks.failed":                 "フックが失敗しました: %v",
+	"notify.failed":                "通知の送信に失敗しました: %v",
+	"deploy.exported":              "  リソースのエクスポート先: %s",
+	"stop.start":                   "デプロイを停止しています...",
+	"stop.failed":                  "停止に失敗しました: %v",
+	"stop.success":                 "✓ デプロイを停止しました",
+	"destroy.start":                "デプロイを削除しています...",
+	"destroy.failed":               "削除に失敗しました: %v",
+	"destroy.success":              "✓ デプロイを削除しました",
+	"destroy.plan_header":          "%s の削除計画 (何も削除していません):",
+	"destroy.plan_dependents":      "削除されるリソースに依存しているもの:",
+	"destroy.plan_dependent":       "  ! %s %s は %s に依存しています",
+	"destroy.plan_summary":         "削除: 削除 %d 件、保持 %d 件、依存 %d 件",
+	"destroy.plan_failed":          "削除計画の作成に失敗しました: %v",
+	"status.failed":                "ステータスの取得に失敗しました: %v",
+	"status.header":                "デプロイのステータス:",
+	"status.deep_failed":           "修復イベントの取得に失敗しました: %v",
+	"status.remediation_header":    "修復イベント (直近 %s):",
+	"status.remediation_none":      "  なし: インスタンスの置き換えやコンテナの再起動はありません",
+	"status.dependencies_failed":   "依存サービスを確認できませんでした: %v",
+	"status.dependencies_header":   "依存サービス:",
+	"summary.dependencies":         "  依存サービス: %d 件すべて正常",
+	"summary.dependencies_down":    "  依存サービス: %d / %d 件が異常",
+	"rollback.start":               "デプロイをロールバックしています...",
+	"rollback.failed":              "ロールバックに失敗しました: %v",
+	"rollback.success":             "✓ ロールバックが完了しました",
+	"logs.failed":                  "ログの読み取りに失敗しました: %v",
+	"export.failed":                "エクスポートに失敗しました: %v",
+	"scale.start":                  "環境をスケーリングしています: %s",
+	"scale.success":                "✓ スケーリングが完了しました",
+	"scale.failed":                 "スケーリングに失敗しました: %v",
+	"scale.manifest_hint":          "  インスタンス数がマニフェストと異なります。マニフェストを更新しないと、次回のデプロイでマニフェストの値に戻ります",
+	"maintenance.on_start":         "メンテナンスモードを有効にしています: %s",
+	"maintenance.off_start":        "メンテナンスモードを無効にしています: %s",
+	"maintenance.on_success":       "✓ メンテナンスモードを有効にしました。デプロイは稼働を続けます",
+	"maintenance.off_success":      "✓ メンテナンスモードを無効にし、トラフィックを戻しました",
+	"maintenance.failed":           "メンテナンスモードの切り替えに失敗しました: %v",
+	"maintenance.route_added":      "  %s を Worker %s にルーティングしました",
+	"maintenance.route_removed":    "  ルート %s を削除しました",
+	"attach.start":                 "%s に接続しています",
+	"attach.success":               "✓ 操作が完了しました",
+	"attach.failed":                "接続に失敗しました: %v",
+	"attach.none":                  "%s で進行中の操作はありません",
+	"output.invalid":               "-output が無効です: %v",
+	"read_only.failed":             "読み取り専用モードを判定できませんでした: %v",
+	"read_only.refused":            "読み取り専用モードでは %s コマンドを拒否します。status、logs、history、plan などの読み取りコマンドのみ実行できます",
+	"attach.unsupported":           "プロバイダー %s は操作を記録しないため、接続できる操作はありません",
+	"operation.in_progress":        "操作 %s はまだ進行中です。cloud-deploy attach で完了を待ってください",
+	"operation.journal_failed":     "操作ジャーナルを利用できません: %v",
+	"image.promote_start":          "イメージを %s に昇格しています",
+	"image.promoted":               "  %s → %s (%s)",
+	"image.promote_success":        "✓ イメージを昇格しました。新しいタグのデプロイは昇格したダイジェストを使用します",
+	"image.promote_failed":         "イメージの昇格に失敗しました: %v",
+	"image.pinned":                 "昇格したイメージ %s (%s) をデプロイしています",
+	"image.pin_failed":             "昇格したイメージを固定できませんでした: %v",
+	"dns.created":                  "  DNS レコードを作成しました: %s %s → %s",
+	"dns.updated":                  "  DNS レコードを更新しました: %s %s → %s",
+	"dns.unchanged":                "  DNS レコードは最新です: %s %s → %s",
+	"dns.failed":                   "DNS レコードの更新に失敗しました: %v",
+	"dns.removed":                  "  DNS レコードを削除しました: %s %s → %s",
+	"dns.remove_failed":            "DNS レコードの削除に失敗しました: %v",
+	"lb.monitor_created":           "  ヘルスモニター %s を作成しました",
+	"lb.monitor_updated":           "  ヘルスモニター %s を更新しました",
+	"lb.monitor_unchanged":         "  ヘルスモニター %s は最新です",
+	"lb.pool_created":              "  オリジンプール %s を作成しました",
+	"lb.pool_updated":              "  オリジンプール %s を更新しました",
+	"lb.pool_unchanged":            "  オリジンプール %s は最新です",
+	"lb.pool_skipped":              "  オリジンプール %s をスキップしました: プロバイダーがまだデプロイしていません",
+	"lb.load_balancer_created":     "  ロードバランサー %s を作成しました",
+	"lb.load_balancer_updated":     "  ロードバランサー %s を更新しました",
+	"lb.load_balancer_unchanged":   "  ロードバランサー %s は最新です",
+	"lb.failed":                    "ロードバランサーの更新に失敗しました: %v",
+	"build.start":                  "ソースからイメージをビルドしています: %s",
+	"build.success":                "✓ イメージをビルドしました",
+	"build.failed":                 "ビルドに失敗しました: %v",
+	"build.remote":                 "イメージ %s は AWS CodeBuild でビルドされます",
+	"version_skew.failed":          "バージョンの確認に失敗しました: %v",
+	"version_skew.warning":         "環境 %s は前回 cloud-deploy %s でデプロイされました (このバージョンは %s)",
+	"version_skew.check_failed":    "環境を前回デプロイした cloud-deploy のバージョンを確認できませんでした: %v",
+	"posture.header":               "%s のアカウント状態:",
+	"posture.summary":              "アカウント状態: 正常 %d 件、警告 %d 件、失敗 %d 件",
+	"posture.provider_failed":      "✗ %s のアカウント状態の確認に失敗しました: %v",
+	"validate.ok":                  "✓ 問題は見つかりませんでした",
+	"validate.summary":             "✗ %d 件の問題が見つかりました",
+	"validate.provider_failed":     "✗ %s の検証に失敗しました: %v",
+	"validate.unsupported":         "プロバイダー %s には独自の検査がないため、マニフェストのみを検査しました",
+	"roll_forward.start":           "ロールフォワードしています...",
+	"roll_forward.target":          "ロールバックされていない最新のバージョン %s を再デプロイしています",
+	"roll_forward.failed":          "ロールフォワードに失敗しました: %v",
+	"roll_forward.success":         "✓ %s にロールフォワードしました",
+	"inventory.header":             "%s のリソース:",
+	"inventory.columns":            "種類\t名前\tID\t検出元",
+	"inventory.empty":              "リソースが見つかりません",
+	"inventory.dns_failed":         "DNS レコードを一覧表示できませんでした: %v",
+	"inventory.history_failed":     "デプロイ履歴を読み取れませんでした: %v",
+	"inventory.provider_failed":    "✗ %s のインベントリの取得に失敗しました: %v",
+	"cleanup.header":               "%s のクリーンアップ:",
+	"cleanup.columns":              "種類\t名前\t作成日時",
+	"cleanup.summary":              "✓ %d 件を削除、%d 件を保持しました",
+	"cleanup.dry_run_summary":      "%d 件が削除対象、%d 件を保持 (ドライラン)",
+	"cleanup.keep_invalid":         "-keep は 1 以上である必要があります (指定値: %d)",
+	"cleanup.provider_failed":      "✗ %s のクリーンアップに失敗しました: %v",
+	"secrets_sync.failed":          "シークレットの同期に失敗しました: %v",
+	"secrets_sync.up_to_date":      "✓ シークレットは最新です",
+	"secrets_sync.updated":         "✓ %d 件のシークレットを更新しました: %s",
+	"credentials_rotate.failed":    "認証情報のローテーションに失敗しました: %v",
+	"credentials_rotate.creating":  "新しい %s の認証情報を作成しています...",
+	"credentials_rotate.verifying": "新しい認証情報を検証しています...",
+	"credentials_rotate.discard":   "未使用の新しい認証情報の失効に失敗しました: %v",
+	"credentials_rotate.stored":    "✓ 新しい認証情報を %s に保存しました",
+	"credentials_rotate.success":   "✓ 認証情報をローテーションし、古い認証情報を無効化しました",
+	"cname.unsupported":            "プロバイダー %s は CNAME プレフィックスを使用しません",
+	"cname.failed":                 "CNAME の確認に失敗しました: %v",
+	"cname.taken":                  "✗ CNAME プレフィックス %s は使用中です",
+	"cname.available":              "✓ CNAME プレフィックス %s は利用可能です: %s",
+	"iam_policy.failed":            "IAM ポリシーの生成に失敗しました: %v",
+	"stats.failed":                 "統計の表示に失敗しました: %v",
+	"stats.empty":                  "%s に記録されたコマンドはありません",
+	"plan.header":                  "%s の実行計画:",
+	"plan.summary":                 "計画: 作成 %d 件、更新 %d 件、削除 %d 件、変更なし %d 件",
+	"plan.failed":                  "実行計画の作成に失敗しました: %v",
+	"plan.provider_failed":         "✗ %s の実行計画の作成に失敗しました: %v",
+	"history.header":               "バージョン\tコマンド\t日時\t状態\tイメージ\tURL",
+	"history.empty":                "%s のデプロイ履歴はありません",
+	"history.failed":               "デプロイ履歴の表示に失敗しました: %v",
+	"history.recorded":             "  記録したバージョン: %s",
+	"history.record_failed":        "デプロイ履歴の記録に失敗しました: %v",
+	"rollback.to_start":            "%s にロールバックしています...",
+	"record.saved":                 "  トランスクリプトの保存先: %s",
+	"record.failed":                "トランスクリプトの保存に失敗しました: %v",
+	"vault.close_failed":           "vault セッションを閉じられませんでした: %v",
+	"replay.header":                "%s (%s、%s) の再生、記録日時 %s",
+	"replay.succeeded":             "✓ コマンドは %v で完了しました",
+	"replay.command_failed":        "✗ コマンドは %v 後に失敗しました",
+	"replay.failed":                "再生に失敗しました: %v",
+}
@@ -0,0 +1,90 @@
+// Package i18n translates the CLI's user-facing messages. Messages are looked
+// up by key in the catalog of the selected language, falling back to English
+// for languages or keys that have no translation.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language every message has a translation for.
+const DefaultLanguage = "en"
+
+// current is the language messages are translated into.
+var current = DefaultLanguage
+
+// Languages returns the supported language codes.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// SetLanguage selects the language for T. lang may be a language code ("es")
+// or a locale ("es_ES.UTF-8"); an empty lang selects the language of the
+// user's locale. Unsupported languages are an error.
+func SetLanguage(lang string) error {
+	if lang == "" {
+		current = Detect()
+		return nil
+	}
+	code := normalize(lang)
+	if _, ok := catalogs[code]; !ok {
+		return fmt.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	current = code
+	return nil
+}
+
+// Language returns the selected language code.
+func Language() string {
+	return current
+}
+
+// Detect returns the supported language of the user's locale, read from the
+// LC_ALL, LC_MESSAGES, and LANG environment variables in that order.
+func Detect() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(env)
+		if locale == "" {
+			continue
+		}
+		// The first variable set decides, as in the C library
+		if code := normalize(locale); catalogs[code] != nil {
+			return code
+		}
+		return DefaultLanguage
+	}
+	return DefaultLanguage
+}
+
+// normalize reduces a locale such as "ja_JP.UTF-8" to its language code.
+func normalize(locale string) string {
+	code := strings.ToLower(locale)
+	if i := strings.IndexAny(code, "_-.@"); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// T returns the message for key in the selected language, formatted with args
+// as by fmt.Sprintf. Unknown keys are returned unchanged.
+func T(key string, args ...interface{}) string {
+	msg, ok := catalogs[current][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+// verbs matches fmt verbs, which translations must keep in order
+var verbs = regexp.MustCompile(`%[a-z]`)
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, msg := range catalog {
+			english, ok := en[key]
+			if !ok {
+				t.Errorf("%s: key %q has no English message", lang, key)
+				continue
+			}
+			if got, want := verbs.FindAllString(msg, -1), verbs.FindAllString(english, -1); !equal(got, want) {
+				t.Errorf("%s: %q has verbs %v, English has %v", lang, key, got, want)
+			}
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestT(t *testing.T) {
+	t.Cleanup(func() { current = DefaultLanguage })
+
+	if got := T("deploy.failed", "boom"); got != "Deployment failed: boom" {
+		t.Errorf("T() = %q", got)
+	}
+
+	if err := SetLanguage("es_ES.UTF-8"); err != nil {
+		t.Fatalf("SetLanguage failed: %v", err)
+	}
+	if got := T("deploy.failed", "boom"); got != "El despliegue falló: boom" {
+		t.Errorf("T() = %q", got)
+	}
+
+	// Keys without a translation fall back to English, then to the key itself
+	catalogs["es"] = map[string]string{}
+	t.Cleanup(func() { catalogs["es"] = es })
+	if got := T("stop.start"); got != "Stopping deployment..." {
+		t.Errorf("Expected English fallback, got %q", got)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("Expected key fallback, got %q", got)
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	t.Cleanup(func() { current = DefaultLanguage })
+
+	if err := SetLanguage("fr"); err == nil {
+		t.Error("Expected error for unsupported language")
+	}
+	if err := SetLanguage("JA"); err != nil || Language() != "ja" {
+		t.Errorf("Expected ja, got %q (err %v)", Language(), err)
+	}
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "es_MX.UTF-8")
+	if err := SetLanguage(""); err != nil || Language() != "es" {
+		t.Errorf("Expected es from LANG, got %q (err %v)", Language(), err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lcAll, lcMessages, lng string
+		want                   string
+	}{
+		{"unset", "", "", "", "en"},
+		{"LANG", "", "", "ja_JP.UTF-8", "ja"},
+		{"LC_ALL wins", "es_ES", "", "ja_JP.UTF-8", "es"},
+		{"LC_MESSAGES before LANG", "", "ja", "es_ES", "ja"},
+		{"unsupported", "", "", "fr_FR.UTF-8", "en"},
+		{"C locale", "C", "", "ja_JP.UTF-8", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.lng)
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
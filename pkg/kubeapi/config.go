@@ -0,0 +1,219 @@
+package kubeapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// In-cluster service account files, mounted into every pod.
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeconfig is the part of a kubeconfig file needed to reach a cluster.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  *ExecConfig `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// DefaultConfigFile returns the kubeconfig kubectl reads: the first file of
+// $KUBECONFIG, or ~/.kube/config.
+func DefaultConfigFile() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kube", "config")
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// LoadConfigFile reads a context of a kubeconfig file. An empty context
+// selects the file's current context. Relative file paths in the kubeconfig
+// are relative to the file.
+func LoadConfigFile(path, context string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	if context == "" {
+		return nil, fmt.Errorf("no context given and no current-context in %s", path)
+	}
+
+	cfg := &Config{Context: context}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == context {
+			clusterName, userName, cfg.Namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %s not found in %s", context, path)
+	}
+
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		cfg.Server = c.Cluster.Server
+		cfg.TLS = &tls.Config{
+			InsecureSkipVerify: c.Cluster.InsecureSkipTLSVerify,
+			ServerName:         c.Cluster.TLSServerName,
+		}
+		ca, err := fileOrData(resolve(c.Cluster.CertificateAuthority), c.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate authority of cluster %s: %w", clusterName, err)
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("certificate authority of cluster %s is not PEM-encoded", clusterName)
+			}
+			cfg.TLS.RootCAs = pool
+		}
+		break
+	}
+	if !found || cfg.Server == "" {
+		return nil, fmt.Errorf("cluster %s of context %s has no server in %s", clusterName, context, path)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		user := u.User
+		if user.AuthProvider != nil {
+			return nil, fmt.Errorf("user %s uses an auth-provider, which is not supported; use an exec credential plugin (e.g. gke-gcloud-auth-plugin or kubelogin)", userName)
+		}
+
+		cfg.Token = user.Token
+		if user.TokenFile != "" {
+			token, err := os.ReadFile(resolve(user.TokenFile))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read token of user %s: %w", userName, err)
+			}
+			cfg.Token = strings.TrimSpace(string(token))
+		}
+
+		cert, err := fileOrData(resolve(user.ClientCertificate), user.ClientCertificateData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate of user %s: %w", userName, err)
+		}
+		key, err := fileOrData(resolve(user.ClientKey), user.ClientKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key of user %s: %w", userName, err)
+		}
+		if cert != nil || key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate of user %s: %w", userName, err)
+			}
+			cfg.TLS.Certificates = []tls.Certificate{pair}
+		}
+
+		if user.Exec != nil {
+			exec := *user.Exec
+			if exec.Command != "" && !filepath.IsAbs(exec.Command) && strings.ContainsRune(exec.Command, filepath.Separator) {
+				exec.Command = resolve(exec.Command)
+			}
+			cfg.Exec = &exec
+		}
+		break
+	}
+	return cfg, nil
+}
+
+// InClusterConfig returns the configuration of the pod's service account, for
+// running inside the cluster deployed to. It fails outside a cluster.
+func InClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster")
+	}
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	namespace, _ := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+	return &Config{
+		Server:    "https://" + host + ":" + port,
+		Namespace: strings.TrimSpace(string(namespace)),
+		Token:     strings.TrimSpace(string(token)),
+		TLS:       &tls.Config{RootCAs: pool},
+	}, nil
+}
+
+// fileOrData returns base64-encoded data when set, otherwise the contents of
+// path, or nil when neither is set.
+func fileOrData(path, data string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
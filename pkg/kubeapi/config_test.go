@@ -0,0 +1,162 @@
+package kubeapi
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: team-a
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com:6443
+    insecure-skip-tls-verify: true
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+    certificate-authority-data: CA_DATA
+users:
+- name: dev-user
+  user:
+    tokenFile: token
+- name: prod-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: gke-gcloud-auth-plugin
+      args: [--verbose]
+`
+
+// Stands in for the cluster CA; InClusterConfig does not require it to parse
+const testCA = `-----BEGIN CERTIFICATE-----
+MIIBdzCCAR2gAwIBAgIUJ0v5m1rXkzvH0sF6t3QG1nq2R5EwCgYIKoZIzj0EAwIw
+ETEPMA0GA1UEAwwGdGVzdGNhMB4XDTI0MDEwMTAwMDAwMFoXDTM0MDEwMTAwMDAw
+MFowETEPMA0GA1UEAwwGdGVzdGNhMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE
+-----END CERTIFICATE-----
+`
+
+func writeKubeconfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFileCurrentContext(t *testing.T) {
+	path := writeKubeconfig(t, testKubeconfig)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "token"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigFile(path, "")
+	if err != nil {
+		t.Fatalf("LoadConfigFile() failed: %v", err)
+	}
+	if cfg.Context != "dev" || cfg.Server != "https://dev.example.com:6443" || cfg.Namespace != "team-a" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cfg.Token != "s3cret" {
+		t.Errorf("expected token from the relative token file, got %q", cfg.Token)
+	}
+	if !cfg.TLS.InsecureSkipVerify {
+		t.Error("expected insecure-skip-tls-verify to be honored")
+	}
+}
+
+func TestLoadConfigFileExec(t *testing.T) {
+	content := strings.Replace(testKubeconfig, "    certificate-authority-data: CA_DATA\n", "", 1)
+	cfg, err := LoadConfigFile(writeKubeconfig(t, content), "prod")
+	if err != nil {
+		t.Fatalf("LoadConfigFile() failed: %v", err)
+	}
+	if cfg.Exec == nil || cfg.Exec.Command != "gke-gcloud-auth-plugin" || len(cfg.Exec.Args) != 1 {
+		t.Errorf("unexpected exec config: %+v", cfg.Exec)
+	}
+	if cfg.Namespace != "" {
+		t.Errorf("expected no namespace, got %q", cfg.Namespace)
+	}
+}
+
+func TestLoadConfigFileCertificateAuthority(t *testing.T) {
+	content := strings.Replace(testKubeconfig, "CA_DATA", base64.StdEncoding.EncodeToString([]byte("not a certificate")), 1)
+	_, err := LoadConfigFile(writeKubeconfig(t, content), "prod")
+	if err == nil || !strings.Contains(err.Error(), "not PEM-encoded") {
+		t.Errorf("expected a PEM error, got %v", err)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	path := writeKubeconfig(t, testKubeconfig)
+	if _, err := LoadConfigFile(path, "staging"); err == nil || !strings.Contains(err.Error(), "context staging not found") {
+		t.Errorf("expected a missing context error, got %v", err)
+	}
+	if _, err := LoadConfigFile(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	noCurrent := strings.Replace(testKubeconfig, "current-context: dev\n", "", 1)
+	if _, err := LoadConfigFile(writeKubeconfig(t, noCurrent), ""); err == nil || !strings.Contains(err.Error(), "no current-context") {
+		t.Errorf("expected a current-context error, got %v", err)
+	}
+
+	authProvider := strings.Replace(testKubeconfig, "    tokenFile: token\n", "    auth-provider:\n      name: gcp\n", 1)
+	if _, err := LoadConfigFile(writeKubeconfig(t, authProvider), "dev"); err == nil || !strings.Contains(err.Error(), "auth-provider") {
+		t.Errorf("expected an auth-provider error, got %v", err)
+	}
+}
+
+func TestDefaultConfigFile(t *testing.T) {
+	t.Setenv("KUBECONFIG", "/tmp/a"+string(os.PathListSeparator)+"/tmp/b")
+	if got := DefaultConfigFile(); got != "/tmp/a" {
+		t.Errorf("expected the first KUBECONFIG file, got %s", got)
+	}
+	t.Setenv("KUBECONFIG", "")
+	if got := DefaultConfigFile(); !strings.HasSuffix(got, filepath.Join(".kube", "config")) {
+		t.Errorf("expected ~/.kube/config, got %s", got)
+	}
+}
+
+func TestInClusterConfig(t *testing.T) {
+	dir := t.TempDir()
+	old := serviceAccountDir
+	serviceAccountDir = dir
+	defer func() { serviceAccountDir = old }()
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := InClusterConfig(); err == nil {
+		t.Error("expected an error outside a cluster")
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "443")
+	for name, content := range map[string]string{"token": "sa-token\n", "ca.crt": testCA, "namespace": "apps"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := InClusterConfig()
+	if err != nil {
+		t.Fatalf("InClusterConfig() failed: %v", err)
+	}
+	if cfg.Server != "https://10.0.0.1:443" || cfg.Token != "sa-token" || cfg.Namespace != "apps" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
@@ -0,0 +1,319 @@
+// Package kubeapi provides a minimal client for the Kubernetes API,
+// authenticating the way kubectl does from a kubeconfig (tokens, client
+// certificates, or exec credential plugins), so cloud-deploy needs no
+// client-go module. Objects are created and updated with server-side apply.
+package kubeapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/readonly"
+)
+
+// FieldManager is the field manager of the objects cloud-deploy applies.
+const FieldManager = "cloud-deploy"
+
+// Patch types accepted by Patch.
+const (
+	JSONPatch  = "application/json-patch+json"
+	MergePatch = "application/merge-patch+json"
+	ApplyPatch = "application/apply-patch+yaml"
+)
+
+// Config holds the cluster endpoint and credentials requests are sent with.
+type Config struct {
+	// API server URL (e.g., https://203.0.113.10:6443)
+	Server string
+
+	// Context of the kubeconfig the configuration was read from
+	Context string
+
+	// Default namespace of the context
+	Namespace string
+
+	// TLS settings: the cluster's CA and any client certificate
+	TLS *tls.Config
+
+	// Bearer token
+	Token string
+
+	// Credential plugin run for a bearer token
+	Exec *ExecConfig
+
+	// Function called for a bearer token, such as a cloud provider's OAuth
+	// access token; it caches and refreshes the token itself
+	TokenSource func(ctx context.Context) (string, error)
+}
+
+// ExecConfig runs a client-go credential plugin, such as
+// gke-gcloud-auth-plugin, aws eks get-token, or kubelogin.
+type ExecConfig struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+// Client calls the API server of a cluster.
+type Client struct {
+	config     *Config
+	endpoint   string
+	httpClient *http.Client
+
+	// Token from the credential plugin and when it expires
+	mu          sync.Mutex
+	execToken   string
+	execExpires time.Time
+}
+
+// New creates a client for the cluster of cfg.
+func New(cfg *Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLS != nil {
+		transport.TLSClientConfig = cfg.TLS
+	}
+	return &Client{
+		config:     cfg,
+		endpoint:   strings.TrimRight(cfg.Server, "/"),
+		httpClient: &http.Client{Transport: readonly.Transport(transport)},
+	}
+}
+
+// Endpoint returns the URL requests are sent to.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// CloseIdleConnections closes the idle connections of the HTTP client
+// requests to the API server are sent with.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// SetEndpoint overrides the URL requests are sent to.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = strings.TrimRight(endpoint, "/")
+}
+
+// CertificateExpiry returns when the client certificate requests are sent
+// with expires, and false when there is none.
+func (c *Client) CertificateExpiry() (time.Time, bool) {
+	if c.config.TLS == nil || len(c.config.TLS.Certificates) == 0 {
+		return time.Time{}, false
+	}
+	cert := c.config.TLS.Certificates[0]
+	leaf := cert.Leaf
+	if leaf == nil && len(cert.Certificate) > 0 {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return time.Time{}, false
+		}
+	}
+	if leaf == nil {
+		return time.Time{}, false
+	}
+	return leaf.NotAfter, true
+}
+
+// APIError is an error returned by the API server: a Status object.
+type APIError struct {
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Kubernetes API error (HTTP %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// IsNotFound reports whether err is an APIError for a missing object.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Do sends a request for path (including any query string). in is marshalled
+// as the JSON body and the JSON response is unmarshalled into out; either may
+// be nil.
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	return c.send(ctx, method, path, "application/json", in, out)
+}
+
+// Apply creates or updates the object at path (e.g.,
+// /apis/apps/v1/namespaces/default/deployments/web) with server-side apply,
+// taking over fields other managers set, and returns the object in out.
+func (c *Client) Apply(ctx context.Context, path string, obj, out interface{}) error {
+	query := url.Values{"fieldManager": {FieldManager}, "force": {"true"}}
+	return c.send(ctx, http.MethodPatch, path+"?"+query.Encode(), ApplyPatch, obj, out)
+}
+
+// Patch patches the object at path with a patch of the given type.
+func (c *Client) Patch(ctx context.Context, path, patchType string, patch, out interface{}) error {
+	return c.send(ctx, http.MethodPatch, path, patchType, patch, out)
+}
+
+// DoRaw sends a GET request for path and returns the response body unparsed,
+// for endpoints that do not return JSON, such as pod logs.
+func (c *Client) DoRaw(ctx context.Context, path string) ([]byte, error) {
+	return c.request(ctx, http.MethodGet, path, "", nil)
+}
+
+// send marshals in as the body of a request of the given content type and
+// unmarshals the JSON response into out.
+func (c *Client) send(ctx context.Context, method, path, contentType string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+		}
+	}
+	respBody, err := c.request(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// request sends an authenticated request and returns the response body,
+// or an APIError for responses other than 2xx.
+func (c *Client) request(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json, */*")
+
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to Kubernetes API server failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var status struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &status) == nil {
+			apiErr.Reason, apiErr.Message = status.Reason, status.Message
+		}
+		return nil, apiErr
+	}
+	return respBody, nil
+}
+
+// token returns the bearer token to send: one from the token source, the
+// configured token, or one from the credential plugin, which is run again
+// when its token expires.
+func (c *Client) token(ctx context.Context) (string, error) {
+	if c.config.TokenSource != nil {
+		return c.config.TokenSource(ctx)
+	}
+	if c.config.Exec == nil {
+		return c.config.Token, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.execToken != "" && (c.execExpires.IsZero() || time.Now().Before(c.execExpires.Add(-time.Minute))) {
+		return c.execToken, nil
+	}
+
+	token, expires, err := runExecPlugin(ctx, c.config.Exec)
+	if err != nil {
+		return "", err
+	}
+	c.execToken, c.execExpires = token, expires
+	return token, nil
+}
+
+// runExecPlugin runs a credential plugin and returns the token of the
+// ExecCredential it prints, and when the token expires (zero if never).
+func runExecPlugin(ctx context.Context, cfg *ExecConfig) (string, time.Time, error) {
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = "client.authentication.k8s.io/v1"
+	}
+	info, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]interface{}{"interactive": false},
+	})
+
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(info))
+	for _, e := range cfg.Env {
+		cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("credential plugin %s failed: %w: %s", cfg.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var cred struct {
+		Status struct {
+			Token               string    `json:"token"`
+			ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+			ClientCertData      string    `json:"clientCertificateData"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &cred); err != nil {
+		return "", time.Time{}, fmt.Errorf("credential plugin %s printed an invalid ExecCredential: %w", cfg.Command, err)
+	}
+	if cred.Status.Token == "" {
+		if cred.Status.ClientCertData != "" {
+			return "", time.Time{}, fmt.Errorf("credential plugin %s returned a client certificate, which is not supported", cfg.Command)
+		}
+		return "", time.Time{}, fmt.Errorf("credential plugin %s returned no token", cfg.Command)
+	}
+	return cred.Status.Token, cred.Status.ExpirationTimestamp, nil
+}
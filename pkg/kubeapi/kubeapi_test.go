@@ -0,0 +1,209 @@
+package kubeapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEndpoint(t *testing.T) {
+	c := New(&Config{Server: "https://k8s.example.com/"})
+	if c.Endpoint() != "https://k8s.example.com" {
+		t.Errorf("unexpected endpoint: %s", c.Endpoint())
+	}
+	c.SetEndpoint("http://localhost:8080/")
+	if c.Endpoint() != "http://localhost:8080" {
+		t.Errorf("unexpected endpoint after SetEndpoint: %s", c.Endpoint())
+	}
+}
+
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected Authorization header: %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != "/api/v1/namespaces/default/services/web" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"metadata":{"name":"web"}}`))
+	}))
+	defer server.Close()
+
+	c := New(&Config{Server: server.URL, Token: "tok"})
+	var out struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := c.Do(context.Background(), http.MethodGet, "/api/v1/namespaces/default/services/web", nil, &out); err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	if out.Metadata.Name != "web" {
+		t.Errorf("unexpected response: %+v", out)
+	}
+}
+
+func TestApply(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		if r.Header.Get("Content-Type") != ApplyPatch {
+			t.Errorf("unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+		if r.URL.Query().Get("fieldManager") != FieldManager || r.URL.Query().Get("force") != "true" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"kind":"Service"`) {
+			t.Errorf("unexpected body: %s", body)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := New(&Config{Server: server.URL})
+	obj := map[string]interface{}{"apiVersion": "v1", "kind": "Service"}
+	if err := c.Apply(context.Background(), "/api/v1/namespaces/default/services/web", obj, nil); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","status":"Failure","message":"deployments.apps \"web\" not found","reason":"NotFound","code":404}`))
+	}))
+	defer server.Close()
+
+	c := New(&Config{Server: server.URL})
+	err := c.Do(context.Background(), http.MethodGet, "/apis/apps/v1/namespaces/default/deployments/web", nil, nil)
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if apiErr := err.(*APIError); apiErr.Reason != "NotFound" || !strings.Contains(apiErr.Error(), `"web" not found`) {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	if IsNotFound(nil) {
+		t.Error("nil is not a not found error")
+	}
+}
+
+func TestDoRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2026-01-01T00:00:00Z hello\n"))
+	}))
+	defer server.Close()
+
+	body, err := New(&Config{Server: server.URL}).DoRaw(context.Background(), "/api/v1/namespaces/default/pods/web-1/log")
+	if err != nil {
+		t.Fatalf("DoRaw() failed: %v", err)
+	}
+	if !strings.HasSuffix(string(body), "hello\n") {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
+func TestExecPluginToken(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	plugin := filepath.Join(dir, "plugin.sh")
+	script := "#!/bin/sh\necho run >> " + counter + "\n" +
+		`echo '{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"exec-token","expirationTimestamp":"2999-01-01T00:00:00Z"}}'` + "\n"
+	if err := os.WriteFile(plugin, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer exec-token" {
+			t.Errorf("unexpected Authorization header: %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	c := New(&Config{Server: server.URL, Exec: &ExecConfig{Command: plugin}})
+	for i := 0; i < 2; i++ {
+		if err := c.Do(context.Background(), http.MethodGet, "/version", nil, nil); err != nil {
+			t.Fatalf("Do() failed: %v", err)
+		}
+	}
+	runs, _ := os.ReadFile(counter)
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("expected the plugin token to be cached, plugin ran %d times", n)
+	}
+}
+
+func TestExecPluginFailure(t *testing.T) {
+	c := New(&Config{Server: "http://127.0.0.1:0", Exec: &ExecConfig{Command: "cloud-deploy-missing-plugin"}})
+	err := c.Do(context.Background(), http.MethodGet, "/version", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "credential plugin") {
+		t.Errorf("expected a credential plugin error, got %v", err)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer source-token" {
+			t.Errorf("unexpected Authorization header: %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	source := func(ctx context.Context) (string, error) { return "source-token", nil }
+	c := New(&Config{Server: server.URL, Token: "static", TokenSource: source})
+	if err := c.Do(context.Background(), http.MethodGet, "/version", nil, nil); err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+
+	failing := func(ctx context.Context) (string, error) { return "", errors.New("token expired") }
+	c = New(&Config{Server: server.URL, TokenSource: failing})
+	if err := c.Do(context.Background(), http.MethodGet, "/version", nil, nil); err == nil || !strings.Contains(err.Error(), "token expired") {
+		t.Errorf("expected the token source error, got %v", err)
+	}
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "deployer"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(&Config{Server: "https://cluster", TLS: &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}}}}})
+	expires, ok := c.CertificateExpiry()
+	if !ok || !expires.Equal(notAfter) {
+		t.Errorf("Expected %s, got %s (%v)", notAfter, expires, ok)
+	}
+
+	for _, cfg := range []*Config{{Server: "https://cluster"}, {Server: "https://cluster", TLS: &tls.Config{}}} {
+		if _, ok := New(cfg).CertificateExpiry(); ok {
+			t.Error("Expected no expiry without a client certificate")
+		}
+	}
+}
@@ -2,10 +2,13 @@ package logging
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"regexp"
 	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/style"
 )
 
 var (
@@ -24,6 +27,12 @@ var (
 
 func init() {
 	// Initialize with JSON handler by default
+	SetOutput(os.Stdout)
+}
+
+// SetOutput replaces the default logger with one writing JSON to w, e.g.
+// os.Stderr so stdout holds only a command's output.
+func SetOutput(w io.Writer) {
 	opts := &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}
@@ -33,7 +42,7 @@ func init() {
 		opts.Level = slog.LevelDebug
 	}
 
-	logger = slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	logger = slog.New(slog.NewJSONHandler(w, opts))
 }
 
 // SetLogger allows overriding the default logger
@@ -99,42 +108,42 @@ func SanitizeMap(m map[string]interface{}) map[string]interface{} {
 
 // Info logs an informational message with structured key-value pairs.
 func Info(msg string, args ...any) {
-	logger.Info(msg, args...)
+	logger.Info(style.Text(msg), args...)
 }
 
 // Infof logs an informational message using printf-style formatting.
 func Infof(format string, args ...any) {
-	logger.Info(fmt.Sprintf(strings.TrimRight(format, "\n"), args...))
+	logger.Info(style.Text(fmt.Sprintf(strings.TrimRight(format, "\n"), args...)))
 }
 
 // Debug logs a debug message with structured key-value pairs.
 func Debug(msg string, args ...any) {
-	logger.Debug(msg, args...)
+	logger.Debug(style.Text(msg), args...)
 }
 
 // Debugf logs a debug message using printf-style formatting.
 func Debugf(format string, args ...any) {
-	logger.Debug(fmt.Sprintf(strings.TrimRight(format, "\n"), args...))
+	logger.Debug(style.Text(fmt.Sprintf(strings.TrimRight(format, "\n"), args...)))
 }
 
 // Warn logs a warning message with structured key-value pairs.
 func Warn(msg string, args ...any) {
-	logger.Warn(msg, args...)
+	logger.Warn(style.Text(msg), args...)
 }
 
 // Warnf logs a warning message using printf-style formatting.
 func Warnf(format string, args ...any) {
-	logger.Warn(fmt.Sprintf(strings.TrimRight(format, "\n"), args...))
+	logger.Warn(style.Text(fmt.Sprintf(strings.TrimRight(format, "\n"), args...)))
 }
 
 // Error logs an error message with structured key-value pairs.
 func Error(msg string, args ...any) {
-	logger.Error(msg, args...)
+	logger.Error(style.Text(msg), args...)
 }
 
 // Errorf logs an error message using printf-style formatting.
 func Errorf(format string, args ...any) {
-	logger.Error(fmt.Sprintf(strings.TrimRight(format, "\n"), args...))
+	logger.Error(style.Text(fmt.Sprintf(strings.TrimRight(format, "\n"), args...)))
 }
 
 // InfoContext logs with additional context fields
@@ -145,7 +154,7 @@ func InfoContext(msg string, contextFields map[string]interface{}, args ...any)
 	for k, v := range sanitized {
 		allArgs = append(allArgs, k, v)
 	}
-	logger.Info(msg, allArgs...)
+	logger.Info(style.Text(msg), allArgs...)
 }
 
 // ErrorContext logs an error with additional context fields
@@ -156,5 +165,5 @@ func ErrorContext(msg string, contextFields map[string]interface{}, args ...any)
 	for k, v := range sanitized {
 		allArgs = append(allArgs, k, v)
 	}
-	logger.Error(msg, allArgs...)
+	logger.Error(style.Text(msg), allArgs...)
 }
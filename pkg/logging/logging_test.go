@@ -1,8 +1,12 @@
 package logging
 
 import (
+	"bytes"
+	"log/slog"
 	"strings"
 	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/style"
 )
 
 func TestSanitizeString(t *testing.T) {
@@ -156,3 +160,31 @@ func TestSanitizeMapNestedStructures(t *testing.T) {
 		t.Errorf("app_name was modified: %v", result["app_name"])
 	}
 }
+
+func TestASCIIMessages(t *testing.T) {
+	var buf bytes.Buffer
+	previous := GetLogger()
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	style.Configure(style.Options{ASCII: true}, nil)
+	t.Cleanup(func() {
+		SetLogger(previous)
+		style.Configure(style.Options{}, nil)
+	})
+
+	Infof("✓ Deployed %s", "my-app")
+	if out := buf.String(); !strings.Contains(out, `"msg":"[OK] Deployed my-app"`) {
+		t.Errorf("Expected symbols replaced in ASCII mode, got %s", out)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	var buf bytes.Buffer
+	previous := GetLogger()
+	SetOutput(&buf)
+	t.Cleanup(func() { SetLogger(previous) })
+
+	Info("Deploying", "provider", "aws")
+	if out := buf.String(); !strings.Contains(out, `"msg":"Deploying","provider":"aws"`) {
+		t.Errorf("Expected a JSON record in the output, got %s", out)
+	}
+}
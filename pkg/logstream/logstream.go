@@ -0,0 +1,66 @@
+// Package logstream prints application logs fetched from a provider, polling
+// for new entries when following.
+package logstream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// PollInterval is how often new entries are fetched when following.
+var PollInterval = 5 * time.Second
+
+// FetchFunc returns the log entries written at or after since.
+type FetchFunc func(ctx context.Context, since time.Time) ([]types.LogEntry, error)
+
+// Stream writes the entries returned by fetch to opts.Output in time order.
+// When opts.Follow is set it keeps polling for new entries until ctx is done,
+// which ends the stream without an error.
+func Stream(ctx context.Context, opts types.LogOptions, fetch FetchFunc) error {
+	since := time.Now().Add(-opts.Since)
+	// IDs of the entries already printed at the since timestamp, which the next
+	// fetch returns again
+	seen := make(map[string]bool)
+
+	for {
+		entries, err := fetch(ctx, since)
+		if err != nil {
+			if opts.Follow && ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		})
+		for _, e := range entries {
+			if e.Timestamp.Before(since) || seen[e.ID] {
+				continue
+			}
+			if e.Timestamp.After(since) {
+				since = e.Timestamp
+				seen = make(map[string]bool)
+			}
+			if e.ID != "" {
+				seen[e.ID] = true
+			}
+			if _, err := fmt.Fprintln(opts.Output, e.String()); err != nil {
+				return err
+			}
+		}
+
+		if !opts.Follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(PollInterval):
+		}
+	}
+}
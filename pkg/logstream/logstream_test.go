@@ -0,0 +1,83 @@
+package logstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestStream(t *testing.T) {
+	base := time.Now().Add(-time.Minute).Truncate(time.Second)
+	var requested time.Time
+	fetch := func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		requested = since
+		return []types.LogEntry{
+			{ID: "2", Timestamp: base.Add(2 * time.Second), Message: "second"},
+			{ID: "1", Timestamp: base.Add(time.Second), Message: "first"},
+			{ID: "0", Timestamp: base.Add(-time.Hour), Message: "too old"},
+		}, nil
+	}
+
+	var out bytes.Buffer
+	if err := Stream(context.Background(), types.LogOptions{Since: 5 * time.Minute, Output: &out}, fetch); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "first") || !strings.HasSuffix(lines[1], "second") {
+		t.Errorf("Expected first and second in order, got:\n%s", out.String())
+	}
+	if age := time.Since(requested); age < 5*time.Minute || age > 6*time.Minute {
+		t.Errorf("Expected fetch from 5m ago, got %s ago", age)
+	}
+}
+
+func TestStreamFollow(t *testing.T) {
+	PollInterval = time.Millisecond
+	defer func() { PollInterval = 5 * time.Second }()
+
+	base := time.Now().Truncate(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Each poll returns the previous entries again plus a new one
+	var polls int
+	var all []types.LogEntry
+	fetch := func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		polls++
+		all = append(all, types.LogEntry{ID: string(rune('a' + polls)), Timestamp: base.Add(time.Duration(polls/2) * time.Second), Message: "poll"})
+		if polls == 4 {
+			cancel()
+		}
+		var entries []types.LogEntry
+		for _, e := range all {
+			if !e.Timestamp.Before(since) {
+				entries = append(entries, e)
+			}
+		}
+		return entries, nil
+	}
+
+	var out bytes.Buffer
+	if err := Stream(ctx, types.LogOptions{Since: time.Minute, Follow: true, Output: &out}, fetch); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if n := strings.Count(out.String(), "poll"); n != 4 {
+		t.Errorf("Expected each entry printed once (4), got %d:\n%s", n, out.String())
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	fetch := func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		return nil, errors.New("access denied")
+	}
+	err := Stream(context.Background(), types.LogOptions{Output: &bytes.Buffer{}}, fetch)
+	if err == nil || err.Error() != "access denied" {
+		t.Errorf("Expected fetch error, got %v", err)
+	}
+}
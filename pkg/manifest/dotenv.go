@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadEnvironmentFiles merges the dotenv files listed in EnvironmentVariablesFrom into
+// EnvironmentVariables. Files are applied in order, so later files override earlier ones,
+// and variables set directly in environment_variables override them all.
+// Relative paths are resolved against baseDir (the manifest's directory).
+func (m *Manifest) loadEnvironmentFiles(baseDir string) error {
+	if len(m.EnvironmentVariablesFrom) == 0 {
+		return nil
+	}
+
+	vars := make(map[string]string)
+	for _, file := range m.EnvironmentVariablesFrom {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if err := parseDotenvFile(path, vars); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range m.EnvironmentVariables {
+		vars[key] = value
+	}
+	m.EnvironmentVariables = vars
+	return nil
+}
+
+// parseDotenvFile reads a dotenv file into vars. Values may reference variables defined
+// earlier (in this or a previous file) or in the process environment as ${VAR} or $VAR.
+func parseDotenvFile(path string, vars map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read environment file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		key, value, ok, err := parseDotenvLine(scanner.Text(), vars)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		if ok {
+			vars[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read environment file %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseDotenvLine parses one dotenv line. ok is false for blank lines and comments.
+//
+// Supported forms:
+//
+//	KEY=value            # unquoted; trailing " #" comments are stripped
+//	export KEY=value     # "export" prefix is ignored
+//	KEY='literal $value' # single quotes: no escapes or interpolation
+//	KEY="line\nbreak"    # double quotes: \n, \t, \", \\ escapes and interpolation
+func parseDotenvLine(line string, vars map[string]string) (key, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	line = strings.TrimPrefix(line, "export ")
+
+	key, raw, found := strings.Cut(line, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false, fmt.Errorf("invalid line %q (expected KEY=VALUE)", line)
+	}
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", "", false, fmt.Errorf("unterminated single quote in %s", key)
+		}
+		return key, raw[1 : end+1], true, nil
+
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == '"' {
+				return key, interpolate(b.String(), vars), true, nil
+			}
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(raw[i])
+				}
+				continue
+			}
+			b.WriteByte(c)
+		}
+		return "", "", false, fmt.Errorf("unterminated double quote in %s", key)
+
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = strings.TrimSpace(raw[:i])
+		}
+		return key, interpolate(raw, vars), true, nil
+	}
+}
+
+// interpolate expands ${VAR} and $VAR from vars, falling back to the process environment.
+func interpolate(s string, vars map[string]string) string {
+	return os.Expand(s, func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return os.Getenv(name)
+	})
+}
@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDotenvLine(t *testing.T) {
+	t.Setenv("DOTENV_TEST_HOST", "db.internal")
+	vars := map[string]string{"PORT": "5432"}
+
+	tests := []struct {
+		line  string
+		key   string
+		value string
+		ok    bool
+	}{
+		{"", "", "", false},
+		{"# comment", "", "", false},
+		{"KEY=value", "KEY", "value", true},
+		{"export KEY=value", "KEY", "value", true},
+		{"KEY = spaced ", "KEY", "spaced", true},
+		{"KEY=value # trailing comment", "KEY", "value", true},
+		{"KEY=a#b", "KEY", "a#b", true},
+		{"KEY=", "KEY", "", true},
+		{"KEY='literal $PORT # not a comment'", "KEY", "literal $PORT # not a comment", true},
+		{`KEY="line\nbreak \"quoted\""`, "KEY", "line\nbreak \"quoted\"", true},
+		{"URL=postgres://${DOTENV_TEST_HOST}:$PORT/app", "URL", "postgres://db.internal:5432/app", true},
+		{`URL="${DOTENV_TEST_HOST}:${PORT}"`, "URL", "db.internal:5432", true},
+	}
+
+	for _, tt := range tests {
+		key, value, ok, err := parseDotenvLine(tt.line, vars)
+		if err != nil {
+			t.Errorf("parseDotenvLine(%q) unexpected error: %v", tt.line, err)
+			continue
+		}
+		if ok != tt.ok || key != tt.key || value != tt.value {
+			t.Errorf("parseDotenvLine(%q) = %q, %q, %v; want %q, %q, %v", tt.line, key, value, ok, tt.key, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestParseDotenvLineErrors(t *testing.T) {
+	for _, line := range []string{"NOEQUALS", "=value", "BAD KEY=value", "KEY='open", `KEY="open`} {
+		if _, _, _, err := parseDotenvLine(line, map[string]string{}); err == nil {
+			t.Errorf("parseDotenvLine(%q) expected error", line)
+		}
+	}
+}
+
+func TestLoadEnvironmentVariablesFrom(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".env":            "LOG_LEVEL=info\nDB_HOST=localhost\nDB_URL=postgres://${DB_HOST}/app\n",
+		".env.production": "DB_HOST=db.prod\nDB_URL=postgres://${DB_HOST}/app\nFEATURE=on\n",
+		"manifest.yaml": `version: "1.0"
+provider:
+  name: aws
+  region: us-east-1
+application:
+  name: my-app
+environment:
+  name: my-env
+image: my-app:latest
+environment_variables_from:
+  - .env
+  - .env.production
+environment_variables:
+  FEATURE: off
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	m, err := Load(filepath.Join(dir, "manifest.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"LOG_LEVEL": "info",
+		"DB_HOST":   "db.prod",
+		"DB_URL":    "postgres://db.prod/app",
+		"FEATURE":   "off",
+	}
+	for key, value := range expected {
+		if m.EnvironmentVariables[key] != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, m.EnvironmentVariables[key])
+		}
+	}
+}
+
+func TestLoadEnvironmentVariablesFromMissingFile(t *testing.T) {
+	m := &Manifest{EnvironmentVariablesFrom: []string{"missing.env"}}
+	if err := m.loadEnvironmentFiles(t.TempDir()); err == nil {
+		t.Error("Expected error for missing environment file")
+	}
+}
@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyEnvironment overlays the entry of the document's environments section
+// named name on the rest of the document, and removes the section. It reports
+// whether the entry names the environment itself, with environment.name or
+// environment.name_template.
+func applyEnvironment(doc *yaml.Node, name string) (named bool, err error) {
+	root := documentMapping(doc)
+	var environments *yaml.Node
+	if root != nil {
+		environments = removeKey(root, "environments")
+	}
+	if environments == nil {
+		if name != "" {
+			return false, fmt.Errorf("environment %s was selected, but the manifest has no environments", name)
+		}
+		return false, nil
+	}
+	if environments.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("environments must map environment names to the settings they override")
+	}
+
+	var names []string
+	for i := 0; i < len(environments.Content); i += 2 {
+		names = append(names, environments.Content[i].Value)
+	}
+	if name == "" {
+		return false, fmt.Errorf("the manifest has environments (%s); select one with -env", strings.Join(names, ", "))
+	}
+	overlay := lookupKey(environments, name)
+	if overlay == nil {
+		return false, fmt.Errorf("environment %q is not one of the manifest's environments (%s)", name, strings.Join(names, ", "))
+	}
+	if isNull(overlay) {
+		return false, nil
+	}
+	if overlay.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("environments.%s must be a mapping of the settings it overrides", name)
+	}
+
+	if env := lookupKey(overlay, "environment"); env != nil && env.Kind == yaml.MappingNode {
+		named = lookupKey(env, "name") != nil || lookupKey(env, "name_template") != nil
+	}
+	mergeNodes(root, overlay)
+	return named, nil
+}
+
+// documentMapping returns the top-level mapping of a YAML document, or nil
+// if it is not a mapping.
+func documentMapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// mergeNodes deep-merges overlay into base, both mappings: values that are
+// mappings on both sides are merged in turn, a null value in overlay removes
+// the key from base, and every other value of overlay replaces base's,
+// lists included. Keys base lacks are added after its own.
+func mergeNodes(base, overlay *yaml.Node) {
+	for i := 0; i < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		j := keyIndex(base, key.Value)
+		var existing *yaml.Node
+		if j >= 0 {
+			existing = base.Content[j+1]
+		}
+		switch {
+		case isNull(value):
+			removeKey(base, key.Value)
+		case existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value)
+		case existing != nil:
+			// The overlay's own nodes replace base's, so they keep their positions
+			base.Content[j], base.Content[j+1] = key, value
+		default:
+			base.Content = append(base.Content, key, value)
+		}
+	}
+}
+
+// lookupKey returns the value of key in mapping, or nil.
+func lookupKey(mapping *yaml.Node, key string) *yaml.Node {
+	if i := keyIndex(mapping, key); i >= 0 {
+		return mapping.Content[i+1]
+	}
+	return nil
+}
+
+// keyIndex returns the index of key's node in mapping's content, or -1.
+func keyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeKey removes key from mapping, returning its value, or nil if
+// mapping has no such key.
+func removeKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// isNull reports whether node is an explicit null, such as ~ or null.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const environmentsManifest = `version: "1.0"
+provider:
+  name: aws
+  region: us-east-1
+application:
+  name: my-app
+environment:
+  name: my-app
+image: my-app:latest
+instance:
+  type: t3.micro
+  environment_type: SingleInstance
+environment_variables:
+  LOG_LEVEL: debug
+  FEATURE: "on"
+cloudflare:
+  zone_id: abc123
+  dns_records:
+    - name: app.example.com
+environments:
+  dev:
+    cloudflare: ~
+  staging:
+    instance:
+      type: t3.small
+    environment_variables:
+      LOG_LEVEL: info
+  prod:
+    provider:
+      region: eu-west-1
+    environment:
+      name: my-app-production
+      class: production
+    instance:
+      type: t3.large
+      environment_type: LoadBalanced
+      min_instances: 2
+      max_instances: 10
+`
+
+func writeEnvironmentsManifest(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(environmentsManifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func TestLoadEnvironment(t *testing.T) {
+	path := writeEnvironmentsManifest(t)
+
+	m, err := LoadWith(path, Options{Environment: "staging"})
+	if err != nil {
+		t.Fatalf("LoadWith failed: %v", err)
+	}
+	if m.Environment.Name != "my-app-staging" || m.Environment.Class != "staging" {
+		t.Errorf("Expected environment my-app-staging of class staging, got %+v", m.Environment)
+	}
+	if m.Instance.Type != "t3.small" || m.Instance.EnvironmentType != "SingleInstance" || m.Provider.Region != "us-east-1" {
+		t.Errorf("Expected only the instance type overridden, got %+v in %s", m.Instance, m.Provider.Region)
+	}
+	if m.EnvironmentVariables["LOG_LEVEL"] != "info" || m.EnvironmentVariables["FEATURE"] != "on" {
+		t.Errorf("Expected environment variables merged, got %v", m.EnvironmentVariables)
+	}
+	if m.Cloudflare == nil {
+		t.Error("Expected the base's Cloudflare configuration")
+	}
+
+	m, err = LoadWith(path, Options{Environment: "prod"})
+	if err != nil {
+		t.Fatalf("LoadWith failed: %v", err)
+	}
+	if m.Environment.Name != "my-app-production" || m.Environment.Class != "production" || m.Provider.Region != "eu-west-1" || m.Instance.MaxInstances != 10 {
+		t.Errorf("Unexpected prod manifest: %+v, %+v, %s", m.Environment, m.Instance, m.Provider.Region)
+	}
+
+	m, err = LoadWith(path, Options{Environment: "dev"})
+	if err != nil {
+		t.Fatalf("LoadWith failed: %v", err)
+	}
+	if m.Cloudflare != nil || m.Environment.Name != "my-app-dev" {
+		t.Errorf("Expected a null to remove cloudflare, got %+v in %s", m.Cloudflare, m.Environment.Name)
+	}
+}
+
+func TestLoadEnvironmentErrors(t *testing.T) {
+	path := writeEnvironmentsManifest(t)
+	for _, tc := range []struct {
+		environment, want string
+	}{
+		{"", "the manifest has environments (dev, staging, prod); select one with -env"},
+		{"qa", `environment "qa" is not one of the manifest's environments (dev, staging, prod)`},
+	} {
+		if _, err := LoadWith(path, Options{Environment: tc.environment}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("LoadWith(%q) error = %v, want %q", tc.environment, err, tc.want)
+		}
+	}
+
+	plain := filepath.Join(t.TempDir(), "plain.yaml")
+	if err := os.WriteFile(plain, []byte(strings.Split(environmentsManifest, "environments:")[0]), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, err := LoadWith(plain, Options{Environment: "staging"}); err == nil || !strings.Contains(err.Error(), "has no environments") {
+		t.Errorf("Expected an error selecting an environment of a manifest without any, got %v", err)
+	}
+	if _, err := LoadWith(plain, Options{}); err != nil {
+		t.Errorf("LoadWith failed: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// maxManifestSize limits how much of a remote manifest is read.
+const maxManifestSize = 10 << 20
+
+// Fetcher reads a manifest from a location that is not a local file.
+type Fetcher func(ctx context.Context, location *url.URL) ([]byte, error)
+
+// Fetchers read manifests by the URL scheme of their location. Plain http is
+// not supported, since a manifest controls what is deployed.
+var Fetchers = map[string]Fetcher{
+	"https": fetchHTTPS,
+	"s3":    fetchS3,
+	"gs":    fetchGCS,
+}
+
+// Stdin is read for the manifest location "-".
+var Stdin io.Reader = os.Stdin
+
+// httpClient fetches https manifests.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Read returns the contents of a file path, "-" for stdin, or a URL handled by
+// Fetchers, such as a manifest's signature.
+func Read(location string) ([]byte, error) {
+	data, _, err := read(location)
+	return data, err
+}
+
+// read returns the manifest at location (a file path, "-" for stdin, or a URL
+// handled by Fetchers) and the directory its relative paths are resolved
+// against: the manifest's directory for files, otherwise the working directory.
+func read(location string) ([]byte, string, error) {
+	if location == "-" {
+		data, err := io.ReadAll(Stdin)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read manifest from stdin: %w", err)
+		}
+		return data, ".", nil
+	}
+
+	scheme, _, isURL := strings.Cut(location, "://")
+	if !isURL {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read manifest file: %w", err)
+		}
+		return data, filepath.Dir(location), nil
+	}
+
+	fetch, ok := Fetchers[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported manifest location %s (use a file, -, https://, s3://, or gs://)", location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid manifest location: %w", err)
+	}
+	data, err := fetch(context.Background(), u)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch manifest %s: %w", location, err)
+	}
+	return data, ".", nil
+}
+
+// fetchHTTPS downloads a manifest over https.
+func fetchHTTPS(ctx context.Context, location *url.URL) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+}
+
+// s3GetObjectAPI is the subset of the S3 client used to fetch manifests.
+type s3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// newS3Client creates the client for s3:// manifests from the default AWS
+// configuration (AWS_REGION must match the bucket's region).
+var newS3Client = func(ctx context.Context) (s3GetObjectAPI, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+// fetchS3 reads a manifest from s3://bucket/key.
+func fetchS3(ctx context.Context, location *url.URL) ([]byte, error) {
+	client, err := newS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(location.Host),
+		Key:    aws.String(strings.TrimPrefix(location.Path, "/")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(io.LimitReader(out.Body, maxManifestSize))
+}
+
+// fetchGCS reads a manifest from gs://bucket/object with Application Default Credentials.
+func fetchGCS(ctx context.Context, location *url.URL) ([]byte, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Storage client: %w", err)
+	}
+	defer client.Close()
+
+	r, err := client.Bucket(location.Host).Object(strings.TrimPrefix(location.Path, "/")).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxManifestSize))
+}
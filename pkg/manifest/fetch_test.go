@@ -0,0 +1,139 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const fetchedManifest = `
+provider:
+  name: mock
+image: my-app:1.0.0
+application:
+  name: my-app
+environment:
+  name: my-app-prod
+`
+
+func TestLoadFromStdin(t *testing.T) {
+	defer func(r io.Reader) { Stdin = r }(Stdin)
+	Stdin = strings.NewReader(fetchedManifest)
+
+	m, err := Load("-")
+	if err != nil {
+		t.Fatalf("Load(-) failed: %v", err)
+	}
+	if m.Application.Name != "my-app" {
+		t.Errorf("Application.Name = %q, want my-app", m.Application.Name)
+	}
+}
+
+func TestLoadFromHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manifests/prod.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(fetchedManifest))
+	}))
+	defer server.Close()
+	defer func(c *http.Client) { httpClient = c }(httpClient)
+	httpClient = server.Client()
+
+	m, err := Load(server.URL + "/manifests/prod.yaml")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Environment.Name != "my-app-prod" {
+		t.Errorf("Environment.Name = %q, want my-app-prod", m.Environment.Name)
+	}
+
+	if _, err := Load(server.URL + "/missing.yaml"); err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("Expected a 404 error, got: %v", err)
+	}
+}
+
+// fakeS3 serves one manifest object.
+type fakeS3 struct {
+	bucket, key string
+}
+
+func (f fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if *in.Bucket != f.bucket || *in.Key != f.key {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(fetchedManifest)))}, nil
+}
+
+func TestLoadFromS3(t *testing.T) {
+	defer func(f func(context.Context) (s3GetObjectAPI, error)) { newS3Client = f }(newS3Client)
+	newS3Client = func(context.Context) (s3GetObjectAPI, error) {
+		return fakeS3{bucket: "deploy-manifests", key: "my-app/prod.yaml"}, nil
+	}
+
+	if _, err := Load("s3://deploy-manifests/my-app/prod.yaml"); err != nil {
+		t.Errorf("Load failed: %v", err)
+	}
+	if _, err := Load("s3://deploy-manifests/other.yaml"); err == nil || !strings.Contains(err.Error(), "failed to fetch manifest s3://deploy-manifests/other.yaml") {
+		t.Errorf("Expected a fetch error, got: %v", err)
+	}
+}
+
+func TestLoadUnsupportedScheme(t *testing.T) {
+	for _, location := range []string{"http://example.com/manifest.yaml", "ftp://example.com/manifest.yaml"} {
+		if _, err := Load(location); err == nil || !strings.Contains(err.Error(), "unsupported manifest location") {
+			t.Errorf("Load(%q): expected an unsupported location error, got: %v", location, err)
+		}
+	}
+}
+
+func TestReadBaseDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy-manifest.yaml")
+	if err := os.WriteFile(path, []byte(fetchedManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, dir, err := read(path); err != nil || dir != filepath.Dir(path) {
+		t.Errorf("read(file) dir = %q, %v; want the manifest's directory", dir, err)
+	}
+
+	defer func(r io.Reader) { Stdin = r }(Stdin)
+	Stdin = strings.NewReader(fetchedManifest)
+	if _, dir, err := read("-"); err != nil || dir != "." {
+		t.Errorf("read(-) dir = %q, %v; want the working directory", dir, err)
+	}
+}
+
+func TestLoadVerified(t *testing.T) {
+	defer func(r io.Reader) { Stdin = r }(Stdin)
+
+	var verified []byte
+	Stdin = strings.NewReader(fetchedManifest)
+	if _, err := LoadVerified("-", func(location string, data []byte) error {
+		if location != "-" {
+			t.Errorf("location = %q, want -", location)
+		}
+		verified = data
+		return nil
+	}); err != nil {
+		t.Fatalf("LoadVerified failed: %v", err)
+	}
+	if string(verified) != fetchedManifest {
+		t.Errorf("Verifier got %q, want the manifest as read", verified)
+	}
+
+	Stdin = strings.NewReader(fetchedManifest)
+	refused := errors.New("unsigned")
+	if _, err := LoadVerified("-", func(string, []byte) error { return refused }); !errors.Is(err, refused) {
+		t.Errorf("Expected the verifier's error, got: %v", err)
+	}
+}
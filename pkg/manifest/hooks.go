@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// HooksConfig holds the shell commands and webhooks run at points of a
+// deployment's lifecycle.
+type HooksConfig struct {
+	// Run before deploy changes anything; a failing hook stops the deploy - optional
+	PreDeploy []Hook `yaml:"pre_deploy,omitempty" json:"pre_deploy,omitempty"`
+
+	// Run after a successful deploy, once its verification checks pass - optional
+	PostDeploy []Hook `yaml:"post_deploy,omitempty" json:"post_deploy,omitempty"`
+
+	// Run after a successful rollback or roll-forward, including the rollback of a failed verification - optional
+	PostRollback []Hook `yaml:"post_rollback,omitempty" json:"post_rollback,omitempty"`
+
+	// Run when deploy, rollback, or roll-forward fails - optional
+	OnFailure []Hook `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+}
+
+// Hook events, the lifecycle points hooks run at.
+const (
+	HookPreDeploy    = "pre_deploy"
+	HookPostDeploy   = "post_deploy"
+	HookPostRollback = "post_rollback"
+	HookOnFailure    = "on_failure"
+)
+
+// Hook is a shell command or a webhook. Commands run with sh -c in the
+// current directory, with the deployment's metadata in CLOUD_DEPLOY_*
+// environment variables; webhooks receive it as a JSON POST.
+type Hook struct {
+	// Name of the hook in the output - default: the command or webhook host
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Shell command to run - required unless webhook is set
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+
+	// URL to POST the deployment's metadata to - required unless command is set
+	Webhook string `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+
+	// Headers of the webhook request, e.g. Authorization - optional
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// Seconds the hook may run (1 to 3600) - default: 300
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+}
+
+// DefaultHookTimeout is how long a hook may run without timeout_seconds.
+const DefaultHookTimeout = 5 * time.Minute
+
+// For returns the hooks of event.
+func (c *HooksConfig) For(event string) []Hook {
+	if c == nil {
+		return nil
+	}
+	switch event {
+	case HookPreDeploy:
+		return c.PreDeploy
+	case HookPostDeploy:
+		return c.PostDeploy
+	case HookPostRollback:
+		return c.PostRollback
+	case HookOnFailure:
+		return c.OnFailure
+	}
+	return nil
+}
+
+// HookName returns the name of the hook, or when it has none its command or
+// the host of its webhook, whose URL may hold a token.
+func (h Hook) HookName() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.Command != "":
+		return h.Command
+	}
+	if u, err := url.Parse(h.Webhook); err == nil {
+		return "webhook " + u.Host
+	}
+	return "webhook"
+}
+
+// Timeout returns how long the hook may run.
+func (h Hook) Timeout() time.Duration {
+	if h.TimeoutSeconds == 0 {
+		return DefaultHookTimeout
+	}
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+// validate checks the hooks of every event.
+func (c *HooksConfig) validate() error {
+	for _, event := range []string{HookPreDeploy, HookPostDeploy, HookPostRollback, HookOnFailure} {
+		for i, h := range c.For(event) {
+			field := fmt.Sprintf("hooks.%s[%d]", event, i)
+			if (h.Command == "") == (h.Webhook == "") {
+				return fmt.Errorf("%s must set exactly one of command and webhook", field)
+			}
+			if h.Webhook != "" {
+				u, err := url.Parse(h.Webhook)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("%s.webhook must be an http or https URL", field)
+				}
+			} else if len(h.Headers) > 0 {
+				return fmt.Errorf("%s.headers apply only to webhooks", field)
+			}
+			if h.TimeoutSeconds < 0 || h.TimeoutSeconds > 3600 {
+				return fmt.Errorf("%s.timeout_seconds must be from 1 to 3600, got %d", field, h.TimeoutSeconds)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package manifest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     HooksConfig
+		wantErr string
+	}{
+		{name: "valid", cfg: HooksConfig{
+			PreDeploy: []Hook{{Command: "./migrate.sh", TimeoutSeconds: 600}},
+			OnFailure: []Hook{{Webhook: "https://hooks.example.com/deploy", Headers: map[string]string{"Authorization": "Bearer x"}}},
+		}},
+		{name: "neither", cfg: HooksConfig{PostDeploy: []Hook{{Name: "empty"}}}, wantErr: "hooks.post_deploy[0] must set exactly one of command and webhook"},
+		{name: "both", cfg: HooksConfig{PostRollback: []Hook{{Command: "true", Webhook: "https://example.com"}}}, wantErr: "hooks.post_rollback[0] must set exactly one"},
+		{name: "bad webhook", cfg: HooksConfig{OnFailure: []Hook{{Webhook: "ftp://example.com"}}}, wantErr: "hooks.on_failure[0].webhook must be an http or https URL"},
+		{name: "command headers", cfg: HooksConfig{PreDeploy: []Hook{{Command: "true", Headers: map[string]string{"A": "b"}}}}, wantErr: "headers apply only to webhooks"},
+		{name: "bad timeout", cfg: HooksConfig{PreDeploy: []Hook{{Command: "true", TimeoutSeconds: 3601}}}, wantErr: "timeout_seconds must be from 1 to 3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHooksFor(t *testing.T) {
+	var none *HooksConfig
+	if hooks := none.For(HookPreDeploy); hooks != nil {
+		t.Errorf("Expected no hooks without hooks, got %v", hooks)
+	}
+	cfg := &HooksConfig{PostDeploy: []Hook{{Command: "./warm-cache.sh"}}}
+	if hooks := cfg.For(HookPostDeploy); len(hooks) != 1 || hooks[0].HookName() != "./warm-cache.sh" || hooks[0].Timeout() != 5*time.Minute {
+		t.Errorf("Unexpected post_deploy hooks: %+v", hooks)
+	}
+	if hooks := cfg.For("pre_destroy"); hooks != nil {
+		t.Errorf("Expected no hooks of an unknown event, got %v", hooks)
+	}
+	if name := (Hook{Webhook: "https://example.com/hook/t0ken"}).HookName(); name != "webhook example.com" {
+		t.Errorf("HookName() = %q", name)
+	}
+}
@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyIncludes replaces the document's includes with the manifest fragments
+// they name, read from location's point of view. The fragments are
+// deep-merged in order, later ones over earlier ones, and the document over
+// them all (see mergeNodes). Fragments may include others, but not
+// themselves; chain holds the locations including the document. When verify
+// is set, each fragment is verified like the manifest. The location of each
+// fragment's nodes is recorded in positions.
+func applyIncludes(doc *yaml.Node, location string, verify Verifier, chain []string, positions *positions) error {
+	root := documentMapping(doc)
+	if root == nil {
+		return nil
+	}
+	includes := removeKey(root, "includes")
+	if includes == nil || isNull(includes) {
+		return nil
+	}
+	if includes.Kind != yaml.SequenceNode {
+		return fmt.Errorf("includes must be a list of manifest fragments")
+	}
+
+	chain = append(chain, location)
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: root.Line, Column: root.Column}
+	for _, item := range includes.Content {
+		if item.Kind != yaml.ScalarNode || item.Value == "" || item.Value == "-" {
+			return fmt.Errorf("includes must be a list of manifest fragment files or URLs")
+		}
+		include := resolveLocation(location, item.Value)
+		if slices.Contains(chain, include) {
+			return fmt.Errorf("includes form a cycle: %s -> %s", strings.Join(chain, " -> "), include)
+		}
+		fragment, err := readFragment(include, verify)
+		if err != nil {
+			return err
+		}
+		positions.record(fragment, include)
+		if err := applyIncludes(fragment, include, verify, chain, positions); err != nil {
+			return err
+		}
+		if fragment.Kind == 0 {
+			continue
+		}
+		mapping := documentMapping(fragment)
+		if mapping == nil {
+			return fmt.Errorf("included fragment %s must be a mapping of manifest settings", include)
+		}
+		mergeNodes(merged, mapping)
+	}
+	mergeNodes(merged, root)
+	*root = *merged
+	return nil
+}
+
+// readFragment reads and parses the manifest fragment at location.
+func readFragment(location string, verify Verifier) (*yaml.Node, error) {
+	data, _, err := read(location)
+	if err != nil {
+		return nil, fmt.Errorf("included fragment %s: %w", location, err)
+	}
+	if verify != nil {
+		if err := verify(location, data); err != nil {
+			return nil, fmt.Errorf("included fragment %s: %w", location, err)
+		}
+	}
+	var fragment yaml.Node
+	if err := yaml.Unmarshal([]byte(expandEnv(string(data))), &fragment); err != nil {
+		return nil, fmt.Errorf("failed to parse included fragment %s: %w", location, err)
+	}
+	return &fragment, nil
+}
+
+// resolveLocation resolves the location ref relative to the manifest at
+// base: against base's URL when base is a URL, and against its directory
+// when it is a file. URLs and absolute paths are taken as they are.
+func resolveLocation(base, ref string) string {
+	if strings.Contains(ref, "://") || filepath.IsAbs(ref) {
+		return ref
+	}
+	if strings.Contains(base, "://") {
+		if u, err := url.Parse(base); err == nil {
+			if r, err := url.Parse(ref); err == nil {
+				return u.ResolveReference(r).String()
+			}
+		}
+		return ref
+	}
+	if base == "-" {
+		return filepath.Clean(ref)
+	}
+	return filepath.Join(filepath.Dir(base), ref)
+}
@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, named relative to dir, creating their directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"shared/base.yaml": `includes:
+  - tags.yaml
+version: "1.0"
+provider:
+  name: aws
+  region: us-east-1
+instance:
+  type: t3.micro
+  environment_type: SingleInstance
+environment_variables:
+  LOG_LEVEL: info
+  REGION: us-east-1
+`,
+		"shared/tags.yaml": `tags:
+  team: payments
+  cost-center: "1234"
+`,
+		"shared/monitoring.yaml": `monitoring:
+  enhanced_health: true
+environment_variables:
+  LOG_LEVEL: warn
+`,
+		"app/manifest.yaml": `includes:
+  - ../shared/base.yaml
+  - ../shared/monitoring.yaml
+application:
+  name: my-app
+environment:
+  name: my-env
+image: my-app:latest
+instance:
+  type: t3.small
+environment_variables:
+  REGION: ~
+tags:
+  team: search
+`,
+	})
+
+	m, err := Load(filepath.Join(dir, "app", "manifest.yaml"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if m.Provider.Region != "us-east-1" || m.Instance.Type != "t3.small" || m.Instance.EnvironmentType != "SingleInstance" {
+		t.Errorf("Expected the manifest merged over its fragments, got %s, %+v", m.Provider.Region, m.Instance)
+	}
+	if !m.Monitoring.EnhancedHealth {
+		t.Errorf("Expected monitoring from the second fragment, got %+v", m.Monitoring)
+	}
+	if len(m.EnvironmentVariables) != 1 || m.EnvironmentVariables["LOG_LEVEL"] != "warn" {
+		t.Errorf("Expected later fragments to override earlier ones and nulls to remove variables, got %v", m.EnvironmentVariables)
+	}
+	if m.Tags["team"] != "search" || m.Tags["cost-center"] != "1234" {
+		t.Errorf("Expected nested includes merged, got %v", m.Tags)
+	}
+}
+
+func TestLoadIncludesErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml":        "includes: [b.yaml]\n",
+		"b.yaml":        "includes: [a.yaml]\n",
+		"list.yaml":     "includes: [fragment.yaml]\n",
+		"fragment.yaml": "- not\n- a mapping\n",
+		"missing.yaml":  "includes: [nowhere.yaml]\n",
+		"scalar.yaml":   "includes: common.yaml\n",
+	})
+	for name, want := range map[string]string{
+		"a.yaml":       "includes form a cycle",
+		"list.yaml":    "must be a mapping of manifest settings",
+		"missing.yaml": "nowhere.yaml",
+		"scalar.yaml":  "includes must be a list",
+	} {
+		if _, err := Load(filepath.Join(dir, name)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%s) error = %v, want %q", name, err, want)
+		}
+	}
+}
+
+func TestLoadIncludesVerified(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"manifest.yaml": "includes: [common.yaml]\n",
+		"common.yaml":   "version: \"1.0\"\n",
+	})
+	var verified []string
+	verify := func(location string, data []byte) error {
+		verified = append(verified, filepath.Base(location))
+		if filepath.Base(location) == "common.yaml" {
+			return errors.New("not signed")
+		}
+		return nil
+	}
+	_, err := LoadVerified(filepath.Join(dir, "manifest.yaml"), verify)
+	if err == nil || !strings.Contains(err.Error(), "included fragment") || strings.Join(verified, ",") != "manifest.yaml,common.yaml" {
+		t.Errorf("Expected the fragment to be verified and refused, got %v after verifying %v", err, verified)
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	for _, tc := range []struct {
+		base, ref, want string
+	}{
+		{"app/manifest.yaml", "../shared/base.yaml", "shared/base.yaml"},
+		{"manifest.yaml", "/etc/cloud-deploy/common.yaml", "/etc/cloud-deploy/common.yaml"},
+		{"-", "./common.yaml", "common.yaml"},
+		{"s3://manifests/app/prod.yaml", "../shared/tags.yaml", "s3://manifests/shared/tags.yaml"},
+		{"https://config.example.com/app/prod.yaml", "common.yaml", "https://config.example.com/app/common.yaml"},
+		{"app/manifest.yaml", "gs://manifests/common.yaml", "gs://manifests/common.yaml"},
+	} {
+		if got := resolveLocation(tc.base, tc.ref); got != tc.want {
+			t.Errorf("resolveLocation(%q, %q) = %q, want %q", tc.base, tc.ref, got, tc.want)
+		}
+	}
+}
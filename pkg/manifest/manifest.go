@@ -6,7 +6,16 @@ package manifest
 import (
 	"context"
 	"fmt"
-	"os"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jvreagan/cloud-deploy/pkg/credentials"
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
@@ -40,9 +49,30 @@ type Manifest struct {
 	// Each container runs as a separate process in the deployment
 	Containers []Container `yaml:"containers,omitempty" json:"containers,omitempty"`
 
+	// Platforms the images are built and pushed for, e.g. [linux/amd64, linux/arm64]; more than one pushes a
+	// multi-arch image index - default: the platform of the image, or of deployment.source.build.platform
+	Platforms []string `yaml:"platforms,omitempty" json:"platforms,omitempty"`
+
+	// Container startup overrides (command, args, working directory) for single-container deployments - optional
+	Container *ContainerStartup `yaml:"container,omitempty" json:"container,omitempty"`
+
+	// Sidecar containers, such as log shippers and proxies, deployed next to the application's (AWS and Azure) - optional
+	Sidecars []Container `yaml:"sidecars,omitempty" json:"sidecars,omitempty"`
+
+	// Services to deploy together, each with its own image, environment variables, and scaling - optional
+	// Use instead of Image and Containers; other commands select one with -service
+	Services []Service `yaml:"services,omitempty" json:"services,omitempty"`
+
+	// What the images package: container (default) or wasm for WebAssembly OCI artifacts
+	ArtifactType string `yaml:"artifact_type,omitempty" json:"artifact_type,omitempty"`
+
 	// Provider configuration (cloud provider, region, credentials)
 	Provider ProviderConfig `yaml:"provider" json:"provider"`
 
+	// Providers to deploy the same image to in one run (multi-cloud) - optional
+	// Use instead of Provider; other commands select one with -provider
+	Providers []ProviderConfig `yaml:"providers,omitempty" json:"providers,omitempty"`
+
 	// Application configuration (name, description)
 	Application ApplicationConfig `yaml:"application" json:"application"`
 
@@ -61,9 +91,57 @@ type Manifest struct {
 	// Azure configuration (Azure-specific) - optional
 	Azure *AzureConfig `yaml:"azure,omitempty" json:"azure,omitempty"`
 
+	// AWS configuration (AWS-specific) - optional
+	AWS *AWSConfig `yaml:"aws,omitempty" json:"aws,omitempty"`
+
+	// OCI configuration (OCI-specific) - required for the oci provider
+	OCI *OCIConfig `yaml:"oci,omitempty" json:"oci,omitempty"`
+
+	// Kubernetes configuration (cluster, namespace, workload) - optional
+	Kubernetes *KubernetesConfig `yaml:"kubernetes,omitempty" json:"kubernetes,omitempty"`
+
+	// AWS ECS configuration (cluster, Fargate task size, networking) - optional
+	ECS *ECSConfig `yaml:"ecs,omitempty" json:"ecs,omitempty"`
+
+	// AWS Lambda configuration (memory, timeout, HTTP endpoint) - optional
+	Lambda *LambdaConfig `yaml:"lambda,omitempty" json:"lambda,omitempty"`
+
+	// AWS App Runner configuration (instance size, autoscaling, health check) - optional
+	AppRunner *AppRunnerConfig `yaml:"app_runner,omitempty" json:"app_runner,omitempty"`
+
+	// Azure Container Apps configuration (replicas, scale rules, ingress) - optional
+	ContainerApps *ContainerAppsConfig `yaml:"container_apps,omitempty" json:"container_apps,omitempty"`
+
+	// Azure App Service configuration (plan, deployment slot, custom domains) - optional
+	AppService *AppServiceConfig `yaml:"app_service,omitempty" json:"app_service,omitempty"`
+
+	// GKE configuration (cluster) - optional
+	GKE *GKEConfig `yaml:"gke,omitempty" json:"gke,omitempty"`
+
+	// DigitalOcean App Platform configuration (container registry) - optional
+	DigitalOcean *DigitalOceanConfig `yaml:"digitalocean,omitempty" json:"digitalocean,omitempty"`
+
+	// Fly.io configuration (organization, regions) - optional
+	Fly *FlyConfig `yaml:"fly,omitempty" json:"fly,omitempty"`
+
+	// Mock provider configuration (latencies and failure injection) - optional
+	Mock *MockConfig `yaml:"mock,omitempty" json:"mock,omitempty"`
+
 	// Health check configuration
 	HealthCheck HealthCheckConfig `yaml:"health_check" json:"health_check"`
 
+	// External services the application depends on, checked by status -deep - optional
+	Dependencies []Dependency `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+
+	// HTTP checks run against the deployment after deploy, which roll it back when they fail - optional
+	Verification *VerificationConfig `yaml:"verification,omitempty" json:"verification,omitempty"`
+
+	// Shell commands and webhooks run before and after deploys and rollbacks, and on failures - optional
+	Hooks *HooksConfig `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+
+	// Slack, webhook, and email notifications of deploy, rollback, and destroy results - optional
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty" json:"notifications,omitempty"`
+
 	// Monitoring configuration (CloudWatch, metrics) - optional
 	Monitoring MonitoringConfig `yaml:"monitoring,omitempty" json:"monitoring,omitempty"`
 
@@ -73,6 +151,23 @@ type Manifest struct {
 	// Environment variables to set in the deployment - optional
 	EnvironmentVariables map[string]string `yaml:"environment_variables,omitempty" json:"environment_variables,omitempty"`
 
+	// Dotenv files to load environment variables from, relative to the manifest - optional
+	// Later files override earlier ones; environment_variables overrides them all
+	EnvironmentVariablesFrom []string `yaml:"environment_variables_from,omitempty" json:"environment_variables_from,omitempty"`
+
+	// Secrets to resolve from a secret store into environment variables at deploy time - optional
+	Secrets []SecretRef `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+
+	// HashiCorp Vault server secrets with vault_path and credentials with source vault are read from - optional
+	Vault *VaultConfig `yaml:"vault,omitempty" json:"vault,omitempty"`
+
+	// Registries the image is pushed to before it is deployed, in addition to the provider's - optional
+	Registries []RegistryConfig `yaml:"registries,omitempty" json:"registries,omitempty"`
+
+	// How secrets reach the deployment: "environment" sets them as plain environment variables, "native" stores
+	// them as the provider's secrets and references them from the service - default: "environment"
+	SecretDelivery string `yaml:"secret_delivery,omitempty" json:"secret_delivery,omitempty"`
+
 	// Tags to apply to cloud resources - optional
 	Tags map[string]string `yaml:"tags,omitempty" json:"tags,omitempty"`
 
@@ -81,6 +176,31 @@ type Manifest struct {
 
 	// SSL/TLS configuration (certificates, termination) - optional
 	SSL *SSLConfig `yaml:"ssl,omitempty" json:"ssl,omitempty"`
+
+	// Infrastructure-as-code export of created resources - optional
+	Export *ExportConfig `yaml:"export,omitempty" json:"export,omitempty"`
+
+	// Container security hardening (read-only root filesystem, non-root user, capabilities) - optional
+	Security *SecurityConfig `yaml:"security,omitempty" json:"security,omitempty"`
+
+	// Where deployment history is kept for the history command and rollback -to - optional
+	State *StateConfig `yaml:"state,omitempty" json:"state,omitempty"`
+
+	// Templates for derived names (version labels, DNS labels, registry repositories) - optional
+	Naming *NamingConfig `yaml:"naming,omitempty" json:"naming,omitempty"`
+
+	// Cloudflare DNS records pointing at the deployment - optional
+	Cloudflare *CloudflareConfig `yaml:"cloudflare,omitempty" json:"cloudflare,omitempty"`
+
+	// Response served by the maintenance command while traffic is paused - optional
+	Maintenance *MaintenanceConfig `yaml:"maintenance,omitempty" json:"maintenance,omitempty"`
+
+	// When the manifest was loaded, the .timestamp of its naming templates
+	loadedAt time.Time
+
+	// Version label of naming.image_tag_strategy git-sha or semver, once
+	// resolved by ResolveImageTag
+	strategyTag string
 }
 
 // Container defines a single container in a multi-container deployment.
@@ -97,8 +217,39 @@ type Container struct {
 	// Environment variables for this container - optional
 	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
 
-	// Command to override the container's default command - optional
+	// Command to override the image's ENTRYPOINT - optional
+	Command []string `yaml:"command,omitempty" json:"command,omitempty"`
+
+	// Args to override the image's CMD - optional
+	Args []string `yaml:"args,omitempty" json:"args,omitempty"`
+
+	// Workdir to override the image's working directory - optional
+	Workdir string `yaml:"workdir,omitempty" json:"workdir,omitempty"`
+
+	// Resources reserved for this container - optional (default: an even share of the deployment's)
+	Resources *ContainerResources `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+// ContainerResources are the CPU and memory of one container of a deployment.
+type ContainerResources struct {
+	// CPU cores (e.g., 0.25) - optional
+	CPU float64 `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+
+	// Memory in GB (e.g., 0.5) - optional
+	MemoryGB float64 `yaml:"memory_gb,omitempty" json:"memory_gb,omitempty"`
+}
+
+// ContainerStartup overrides how the container starts in single-container deployments.
+// Multi-container deployments set the same fields on each entry in Containers.
+type ContainerStartup struct {
+	// Command to override the image's ENTRYPOINT - optional
 	Command []string `yaml:"command,omitempty" json:"command,omitempty"`
+
+	// Args to override the image's CMD - optional
+	Args []string `yaml:"args,omitempty" json:"args,omitempty"`
+
+	// Workdir to override the image's working directory - optional
+	Workdir string `yaml:"workdir,omitempty" json:"workdir,omitempty"`
 }
 
 // PortMapping defines a container port mapping.
@@ -112,7 +263,7 @@ type PortMapping struct {
 
 // ProviderConfig specifies which cloud provider to use and how to authenticate.
 type ProviderConfig struct {
-	// Name of the cloud provider (aws, gcp, azure, oci)
+	// Name of the cloud provider (aws, aws-ecs, aws-lambda, aws-apprunner, gcp, gcp-gke, azure, azure-container-apps, azure-app-service, oci, kubernetes, digitalocean, fly, mock)
 	Name string `yaml:"name" json:"name"`
 
 	// Region to deploy to (e.g., us-east-2, us-west-1)
@@ -142,31 +293,139 @@ type ProviderConfig struct {
 	// Azure-specific: Resource Group name (required for Azure provider)
 	// Will be created if it doesn't exist
 	ResourceGroup string `yaml:"resource_group,omitempty" json:"resource_group,omitempty"`
+
+	// OCI-specific: Compartment OCID to create resources in (required for OCI provider)
+	CompartmentID string `yaml:"compartment_id,omitempty" json:"compartment_id,omitempty"`
+
+	// OCI-specific: Tenancy OCID (default: from the OCI CLI config file)
+	TenancyID string `yaml:"tenancy_id,omitempty" json:"tenancy_id,omitempty"`
+
+	// Base URL of an emulator that receives the provider's API requests instead
+	// of the cloud (e.g., http://localhost:4566 for LocalStack) - optional
+	EndpointURL string `yaml:"endpoint_url,omitempty" json:"endpoint_url,omitempty"`
+
+	// Registry images are pushed to instead of the provider's, without
+	// authenticating (e.g., localhost:5000) - optional
+	RegistryURL string `yaml:"registry_url,omitempty" json:"registry_url,omitempty"`
+}
+
+// Emulated reports whether the provider's API requests go to an emulator.
+func (p ProviderConfig) Emulated() bool {
+	return p.EndpointURL != ""
+}
+
+// Cloud returns the cloud whose credentials, secret stores, and regions the
+// provider uses: "aws" for the aws-* providers, "azure" for the azure-*
+// providers, "gcp" for the gcp-* providers, and the provider name otherwise.
+func (p ProviderConfig) Cloud() string {
+	switch {
+	case strings.HasPrefix(p.Name, "aws-"):
+		return "aws"
+	case strings.HasPrefix(p.Name, "azure-"):
+		return "azure"
+	case strings.HasPrefix(p.Name, "gcp-"):
+		return "gcp"
+	}
+	return p.Name
 }
 
 // CredentialsConfig contains cloud provider credentials.
 // Credentials can be provided directly or via environment variables.
 type CredentialsConfig struct {
-	// Source of credentials: "manifest", "environment", "cli" (default: "cli")
+	// Source of credentials: "manifest", "environment", "secrets-manager", "parameter-store",
+	// "gcp-secret-manager", "azure-key-vault", "vault", "oidc", "cli" (default: "cli")
 	// - "manifest": Use credentials specified directly in this manifest
 	// - "environment": Use environment variables (AWS_ACCESS_KEY_ID, etc.)
+	// - "secrets-manager": Use credentials stored in the AWS Secrets Manager secret SecretID
+	// - "parameter-store": Use credentials stored as AWS SSM parameters under ParameterPath
+	// - "gcp-secret-manager": Use credentials stored in the Google Secret Manager secret SecretID
+	// - "azure-key-vault": Use credentials stored in the Azure Key Vault secret SecretID
+	// - "vault": Use credentials stored in HashiCorp Vault
+	// - "oidc": Exchange the CI job's OIDC token (GitHub Actions, GitLab CI) for short-lived credentials
 	// - "cli": Use cloud provider CLI credentials (default)
 	Source string `yaml:"source,omitempty" json:"source,omitempty"`
 
+	// Secret holding the credentials (used when Source is "secrets-manager", "gcp-secret-manager",
+	// or "azure-key-vault"): a name or ARN for AWS Secrets Manager, a name in provider.project_id or
+	// a resource name, projects/<project>/secrets/<name>[/versions/<version>], for Google Secret
+	// Manager, or a name in KeyVaultURI or a URL, https://<vault>.vault.azure.net/secrets/<name>[/<version>],
+	// for Azure Key Vault
+	SecretID string `yaml:"secret_id,omitempty" json:"secret_id,omitempty"`
+
+	// URI of the Azure Key Vault holding SecretID, e.g. https://my-vault.vault.azure.net
+	// (used when Source is "azure-key-vault" and SecretID is a name)
+	KeyVaultURI string `yaml:"key_vault_uri,omitempty" json:"key_vault_uri,omitempty"`
+
+	// Path of the AWS SSM parameters holding the credentials, one per field under <path>/<cloud>/ (used when
+	// Source is "parameter-store") - default: "/cloud-deploy"
+	ParameterPath string `yaml:"parameter_path,omitempty" json:"parameter_path,omitempty"`
+
+	// Audience of the CI job's OIDC token (used when Source is "oidc") - default: "sts.amazonaws.com" for AWS,
+	// the workload identity provider for GCP, and "api://AzureADTokenExchange" for Azure
+	OIDCAudience string `yaml:"oidc_audience,omitempty" json:"oidc_audience,omitempty"`
+
+	// Environment variable holding the CI job's OIDC token, e.g. a GitLab CI id_tokens entry (used when
+	// Source is "oidc"); without it, the token is requested from GitHub Actions - default: "CLOUD_DEPLOY_ID_TOKEN"
+	OIDCTokenEnv string `yaml:"oidc_token_env,omitempty" json:"oidc_token_env,omitempty"`
+
 	// AWS: Access key ID (used when Source is "manifest")
 	AccessKeyID string `yaml:"access_key_id,omitempty" json:"access_key_id,omitempty"`
 
 	// AWS: Secret access key (used when Source is "manifest")
 	SecretAccessKey string `yaml:"secret_access_key,omitempty" json:"secret_access_key,omitempty"`
 
+	// AWS: Named profile of the shared config and credentials files (~/.aws/config), including
+	// IAM Identity Center (SSO) profiles signed in with `aws sso login` - default: $AWS_PROFILE
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+
+	// AWS: ARN of an IAM role to assume with the loaded credentials before deploying, e.g. a deployer
+	// role in another account - optional
+	RoleARN string `yaml:"role_arn,omitempty" json:"role_arn,omitempty"`
+
+	// AWS: External ID the role's trust policy requires (used with RoleARN) - optional
+	ExternalID string `yaml:"external_id,omitempty" json:"external_id,omitempty"`
+
+	// AWS: Session name of the assumed role, shown in CloudTrail (used with RoleARN) - default: "cloud-deploy"
+	RoleSessionName string `yaml:"role_session_name,omitempty" json:"role_session_name,omitempty"`
+
+	// AWS: Serial number or ARN of the MFA device the role requires; the code is prompted for on the
+	// terminal (used with RoleARN) - optional
+	MFASerial string `yaml:"mfa_serial,omitempty" json:"mfa_serial,omitempty"`
+
 	// GCP: Path to service account JSON key file (used when Source is "manifest")
 	ServiceAccountKeyPath string `yaml:"service_account_key_path,omitempty" json:"service_account_key_path,omitempty"`
 
 	// GCP: Or provide service account JSON content directly (used when Source is "manifest")
 	ServiceAccountKeyJSON string `yaml:"service_account_key_json,omitempty" json:"service_account_key_json,omitempty"`
 
+	// GCP: Path to a Workload Identity Federation credential configuration file (type external_account),
+	// created with `gcloud iam workload-identity-pools create-cred-config`, so CI systems and other
+	// clouds deploy without service account keys
+	WorkloadIdentityConfigPath string `yaml:"workload_identity_config_path,omitempty" json:"workload_identity_config_path,omitempty"`
+
+	// GCP: Email of a service account to impersonate with the loaded credentials, or Application Default
+	// Credentials without any; they need roles/iam.serviceAccountTokenCreator on it - optional
+	ImpersonateServiceAccount string `yaml:"impersonate_service_account,omitempty" json:"impersonate_service_account,omitempty"`
+
+	// GCP: Service accounts in the delegation chain to ImpersonateServiceAccount, each able to
+	// impersonate the next - optional
+	ImpersonationDelegates []string `yaml:"impersonation_delegates,omitempty" json:"impersonation_delegates,omitempty"`
+
+	// GCP: Resource name of the Workload Identity Federation provider that trusts the CI job's OIDC token,
+	// projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider> (used when Source is "oidc")
+	WorkloadIdentityProvider string `yaml:"workload_identity_provider,omitempty" json:"workload_identity_provider,omitempty"`
+
 	// Azure: Service Principal credentials (used when Source is "manifest")
 	Azure *AzureCredentialsConfig `yaml:"azure,omitempty" json:"azure,omitempty"`
+
+	// OCI: API signing key and registry credentials
+	OCI *OCICredentialsConfig `yaml:"oci,omitempty" json:"oci,omitempty"`
+
+	// DigitalOcean: API token (used when Source is "manifest")
+	DigitalOcean *DigitalOceanCredentialsConfig `yaml:"digitalocean,omitempty" json:"digitalocean,omitempty"`
+
+	// Fly.io: API token (used when Source is "manifest")
+	Fly *FlyCredentialsConfig `yaml:"fly,omitempty" json:"fly,omitempty"`
 }
 
 // AzureCredentialsConfig contains Azure Service Principal credentials.
@@ -181,6 +440,44 @@ type AzureCredentialsConfig struct {
 	TenantID string `yaml:"tenant_id,omitempty" json:"tenant_id,omitempty"`
 }
 
+// OCICredentialsConfig contains Oracle Cloud Infrastructure credentials.
+// The API signing key is read from the OCI CLI config file unless Source is
+// "manifest"; the registry auth token is needed either way.
+type OCICredentialsConfig struct {
+	// Path to the OCI CLI config file (default: $OCI_CLI_CONFIG_FILE or ~/.oci/config)
+	ConfigFile string `yaml:"config_file,omitempty" json:"config_file,omitempty"`
+
+	// Profile in the config file (default: $OCI_CLI_PROFILE or DEFAULT)
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+
+	// User OCID (used when Source is "manifest")
+	UserID string `yaml:"user_id,omitempty" json:"user_id,omitempty"`
+
+	// Fingerprint of the API signing key (used when Source is "manifest")
+	Fingerprint string `yaml:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+
+	// Path to the PEM-encoded API signing key (used when Source is "manifest")
+	PrivateKeyPath string `yaml:"private_key_path,omitempty" json:"private_key_path,omitempty"`
+
+	// Username for OCI Registry, without the namespace (default: the API user's name)
+	RegistryUsername string `yaml:"registry_username,omitempty" json:"registry_username,omitempty"`
+
+	// Auth token for OCI Registry (default: $OCI_AUTH_TOKEN)
+	AuthToken string `yaml:"auth_token,omitempty" json:"auth_token,omitempty"`
+}
+
+// DigitalOceanCredentialsConfig contains a DigitalOcean API token.
+type DigitalOceanCredentialsConfig struct {
+	// Personal access token with read and write scopes for apps and the container registry
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
+// FlyCredentialsConfig contains a Fly.io API token.
+type FlyCredentialsConfig struct {
+	// Deploy or organization token from 'fly tokens create', or a personal access token
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
 // ApplicationConfig defines the application being deployed.
 type ApplicationConfig struct {
 	// Name of the application (must be unique within the cloud account)
@@ -196,8 +493,19 @@ type EnvironmentConfig struct {
 	// Name of the environment (must be unique within the application)
 	Name string `yaml:"name" json:"name"`
 
+	// Template the name is derived from (e.g., "{{.app}}-{{.env}}-{{.region}}", where .env is name as written),
+	// evaluated when the manifest is loaded and per provider of multi-provider manifests - optional
+	NameTemplate string `yaml:"name_template,omitempty" json:"name_template,omitempty"`
+
 	// CName/subdomain for the environment (creates: <cname>.<region>.<provider>.com)
 	CName string `yaml:"cname" json:"cname,omitempty"`
+
+	// CNameConflict controls what happens when the CNAME prefix is taken (AWS only):
+	// "fail" (default), "suffix" (append -2, -3, ...), or "prompt" (ask on the terminal) - optional
+	CNameConflict string `yaml:"cname_conflict,omitempty" json:"cname_conflict,omitempty"`
+
+	// Class of the environment (e.g., dev, staging, prod), used to select policy rules - optional
+	Class string `yaml:"class,omitempty" json:"class,omitempty"`
 }
 
 // DeploymentConfig specifies how the application should be deployed.
@@ -210,6 +518,128 @@ type DeploymentConfig struct {
 
 	// Source code location
 	Source SourceConfig `yaml:"source" json:"source"`
+
+	// How a new version replaces the running one: in-place, blue-green, or canary - default: in-place
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// Options of blue-green deployments - optional
+	BlueGreen *BlueGreenConfig `yaml:"blue_green,omitempty" json:"blue_green,omitempty"`
+
+	// Options of canary deployments - optional
+	Canary *CanaryConfig `yaml:"canary,omitempty" json:"canary,omitempty"`
+}
+
+// Deployment strategies.
+const (
+	// Update the running environment to the new version
+	StrategyInPlace = "in-place"
+
+	// Start the new version in a parallel environment and swap traffic to it once it is healthy
+	StrategyBlueGreen = "blue-green"
+
+	// Shift traffic to the new version in steps while it stays healthy, and back if it fails
+	StrategyCanary = "canary"
+)
+
+// BlueGreenConfig configures blue-green deployments.
+type BlueGreenConfig struct {
+	// Keep the old environment running after the swap, so a swap back is immediate - default: false (terminated)
+	KeepOld bool `yaml:"keep_old,omitempty" json:"keep_old,omitempty"`
+}
+
+// CanaryConfig configures canary deployments.
+type CanaryConfig struct {
+	// Percentages of traffic the new version receives in turn, increasing and ending at 100 - default: [10, 50, 100]
+	Steps []int32 `yaml:"steps,omitempty" json:"steps,omitempty"`
+
+	// Seconds the new version must stay healthy at each step before the next - default: 300
+	IntervalSeconds int `yaml:"interval_seconds,omitempty" json:"interval_seconds,omitempty"`
+}
+
+// DefaultCanarySteps are the traffic percentages of canary deployments
+// without deployment.canary.steps.
+var DefaultCanarySteps = []int32{10, 50, 100}
+
+// DefaultCanaryInterval is how long the new version of a canary deployment
+// must stay healthy at each step without deployment.canary.interval_seconds.
+const DefaultCanaryInterval = 5 * time.Minute
+
+// IsBlueGreen reports whether new versions are deployed blue-green.
+func (c DeploymentConfig) IsBlueGreen() bool {
+	return c.Strategy == StrategyBlueGreen
+}
+
+// KeepOld reports whether blue-green deployments keep the old environment.
+func (c DeploymentConfig) KeepOld() bool {
+	return c.BlueGreen != nil && c.BlueGreen.KeepOld
+}
+
+// IsCanary reports whether new versions are deployed as canaries.
+func (c DeploymentConfig) IsCanary() bool {
+	return c.Strategy == StrategyCanary
+}
+
+// CanarySteps returns the traffic percentages the new version of a canary
+// deployment receives in turn.
+func (c DeploymentConfig) CanarySteps() []int32 {
+	if c.Canary == nil || len(c.Canary.Steps) == 0 {
+		return DefaultCanarySteps
+	}
+	return c.Canary.Steps
+}
+
+// CanaryInterval returns how long the new version of a canary deployment must
+// stay healthy at each step.
+func (c DeploymentConfig) CanaryInterval() time.Duration {
+	if c.Canary == nil || c.Canary.IntervalSeconds == 0 {
+		return DefaultCanaryInterval
+	}
+	return time.Duration(c.Canary.IntervalSeconds) * time.Second
+}
+
+// BlueGreenSuffix is appended to the environment name to name the second
+// environment of blue-green deployments, which alternate between the two.
+const BlueGreenSuffix = "-green"
+
+// MaxBlueGreenEnvironmentName is the longest environment name of blue-green
+// deployments, leaving room for BlueGreenSuffix in Elastic Beanstalk's limit
+// of 40 characters.
+const MaxBlueGreenEnvironmentName = 40 - len(BlueGreenSuffix)
+
+// blueGreenProviders are the providers that can deploy blue-green.
+var blueGreenProviders = map[string]bool{"aws": true}
+
+// canaryProviders are the providers that can deploy canaries.
+var canaryProviders = map[string]bool{"gcp": true}
+
+// validate checks the deployment strategy.
+func (c DeploymentConfig) validate() error {
+	switch c.Strategy {
+	case "", StrategyInPlace, StrategyBlueGreen, StrategyCanary:
+	default:
+		return fmt.Errorf("deployment.strategy must be %s, %s, or %s, got %q", StrategyInPlace, StrategyBlueGreen, StrategyCanary, c.Strategy)
+	}
+	if c.BlueGreen != nil && !c.IsBlueGreen() {
+		return fmt.Errorf("deployment.blue_green applies only to deployment.strategy %s", StrategyBlueGreen)
+	}
+	if c.Canary == nil {
+		return nil
+	}
+	if !c.IsCanary() {
+		return fmt.Errorf("deployment.canary applies only to deployment.strategy %s", StrategyCanary)
+	}
+	if c.Canary.IntervalSeconds < 0 {
+		return fmt.Errorf("deployment.canary.interval_seconds must not be negative, got %d", c.Canary.IntervalSeconds)
+	}
+	for i, step := range c.Canary.Steps {
+		if step < 1 || step > 100 || (i > 0 && step <= c.Canary.Steps[i-1]) {
+			return fmt.Errorf("deployment.canary.steps must be increasing percentages from 1 to 100, got %v", c.Canary.Steps)
+		}
+	}
+	if n := len(c.Canary.Steps); n > 0 && c.Canary.Steps[n-1] != 100 {
+		return fmt.Errorf("deployment.canary.steps must end at 100, got %v", c.Canary.Steps)
+	}
+	return nil
 }
 
 // SourceConfig specifies where the application source code is located.
@@ -219,6 +649,133 @@ type SourceConfig struct {
 
 	// Path to source code (file path, S3 URL, or git repository)
 	Path string `yaml:"path" json:"path,omitempty"`
+
+	// Build the image from the source at Path before deploying - optional
+	Build *BuildConfig `yaml:"build,omitempty" json:"build,omitempty"`
+}
+
+// Artifact types of the images a manifest deploys.
+const (
+	// Container images run by a container runtime
+	ArtifactContainer = "container"
+
+	// WebAssembly modules or applications (e.g., Spin) packaged as OCI artifacts
+	ArtifactWasm = "wasm"
+)
+
+// wasmProviders are the providers that can run WebAssembly workloads.
+var wasmProviders = map[string]bool{"kubernetes": true, "mock": true}
+
+// Image builders.
+const (
+	// docker build
+	BuilderDocker = "docker"
+
+	// docker buildx build, which uses BuildKit with its cache and multi-platform support
+	BuilderBuildKit = "buildkit"
+)
+
+// BuildConfig builds the deployed image from local source. The image is tagged
+// with the manifest's image, or <application name>:<build time> when it has none.
+type BuildConfig struct {
+	// Dockerfile, relative to the source path - default: Dockerfile
+	Dockerfile string `yaml:"dockerfile,omitempty" json:"dockerfile,omitempty"`
+
+	// Build arguments (--build-arg) - optional
+	Args map[string]string `yaml:"args,omitempty" json:"args,omitempty"`
+
+	// Stage of a multi-stage Dockerfile to build - optional
+	Target string `yaml:"target,omitempty" json:"target,omitempty"`
+
+	// Platform to build for - default: linux/amd64, which every provider runs
+	Platform string `yaml:"platform,omitempty" json:"platform,omitempty"`
+
+	// Builder: docker or buildkit - default: docker
+	Builder string `yaml:"builder,omitempty" json:"builder,omitempty"`
+
+	// Where and how the image is built: local (the Docker CLI on this machine),
+	// buildpacks (Cloud Native Buildpacks with the pack CLI on this machine, no
+	// Dockerfile needed), or codebuild (AWS CodeBuild, pushing to ECR) - default: local
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// Cloud Native Buildpacks settings (strategy buildpacks)
+	Buildpacks *BuildpacksConfig `yaml:"buildpacks,omitempty" json:"buildpacks,omitempty"`
+
+	// AWS CodeBuild settings (strategy codebuild)
+	CodeBuild *CodeBuildConfig `yaml:"codebuild,omitempty" json:"codebuild,omitempty"`
+}
+
+// Build strategies.
+const (
+	BuildStrategyLocal      = "local"
+	BuildStrategyBuildpacks = "buildpacks"
+	BuildStrategyCodeBuild  = "codebuild"
+)
+
+// BuildpacksConfig configures a Cloud Native Buildpacks build. Build
+// arguments (args) are passed to the buildpacks as environment variables.
+type BuildpacksConfig struct {
+	// Builder image providing the buildpacks - default: paketobuildpacks/builder-jammy-base
+	Builder string `yaml:"builder,omitempty" json:"builder,omitempty"`
+
+	// Buildpacks to use instead of those the builder detects (e.g., paketo-buildpacks/nodejs) - optional
+	Buildpacks []string `yaml:"buildpacks,omitempty" json:"buildpacks,omitempty"`
+}
+
+// CodeBuildConfig configures the AWS CodeBuild project that builds the image.
+type CodeBuildConfig struct {
+	// Name or ARN of the IAM role CodeBuild runs as; it needs to read the
+	// source from the application's S3 bucket, push to its ECR repository,
+	// and write CloudWatch logs (required)
+	ServiceRole string `yaml:"service_role" json:"service_role"`
+
+	// Compute type of the build (e.g., BUILD_GENERAL1_MEDIUM) - default: BUILD_GENERAL1_SMALL
+	ComputeType string `yaml:"compute_type,omitempty" json:"compute_type,omitempty"`
+
+	// Build timeout in minutes (5-2160) - default: 30
+	TimeoutMinutes int `yaml:"timeout_minutes,omitempty" json:"timeout_minutes,omitempty"`
+}
+
+// RemoteBuild reports whether the image is built by the cloud provider
+// rather than on this machine.
+func (c *BuildConfig) RemoteBuild() bool {
+	return c.Strategy == BuildStrategyCodeBuild
+}
+
+// validate checks that the source can be built.
+func (c *SourceConfig) validate() error {
+	if c.Build == nil {
+		return nil
+	}
+	if c.Type != "" && c.Type != "local" {
+		return fmt.Errorf("deployment.source.build requires a local source, not %q", c.Type)
+	}
+	if c.Path == "" {
+		return fmt.Errorf("deployment.source.path is required to build the image")
+	}
+	switch c.Build.Builder {
+	case "", BuilderDocker, BuilderBuildKit:
+	default:
+		return fmt.Errorf("deployment.source.build.builder must be %s or %s, got %q", BuilderDocker, BuilderBuildKit, c.Build.Builder)
+	}
+	switch c.Build.Strategy {
+	case "", BuildStrategyLocal:
+	case BuildStrategyBuildpacks:
+		if c.Build.Dockerfile != "" || c.Build.Target != "" || c.Build.Builder != "" {
+			return fmt.Errorf("deployment.source.build.dockerfile, target, and builder do not apply to strategy %s; set buildpacks.builder to choose the builder image", BuildStrategyBuildpacks)
+		}
+	case BuildStrategyCodeBuild:
+		cb := c.Build.CodeBuild
+		if cb == nil || cb.ServiceRole == "" {
+			return fmt.Errorf("deployment.source.build.codebuild.service_role is required for strategy %s", BuildStrategyCodeBuild)
+		}
+		if cb.TimeoutMinutes != 0 && (cb.TimeoutMinutes < 5 || cb.TimeoutMinutes > 2160) {
+			return fmt.Errorf("deployment.source.build.codebuild.timeout_minutes must be between 5 and 2160")
+		}
+	default:
+		return fmt.Errorf("deployment.source.build.strategy must be %s, %s, or %s, got %q", BuildStrategyLocal, BuildStrategyBuildpacks, BuildStrategyCodeBuild, c.Build.Strategy)
+	}
+	return nil
 }
 
 // InstanceConfig specifies the compute resources for the deployment.
@@ -228,6 +785,17 @@ type InstanceConfig struct {
 
 	// Environment type: SingleInstance or LoadBalanced
 	EnvironmentType string `yaml:"environment_type" json:"environment_type,omitempty"`
+
+	// Minimum number of instances of a LoadBalanced environment - optional
+	MinInstances int32 `yaml:"min_instances,omitempty" json:"min_instances,omitempty"`
+
+	// Maximum number of instances of a LoadBalanced environment - optional
+	MaxInstances int32 `yaml:"max_instances,omitempty" json:"max_instances,omitempty"`
+}
+
+// validate checks the instance counts.
+func (c *InstanceConfig) validate() error {
+	return validateInstanceCounts("instance", c.MinInstances, c.MaxInstances)
 }
 
 // CloudRunConfig specifies GCP Cloud Run-specific configuration.
@@ -251,6 +819,23 @@ type CloudRunConfig struct {
 	TimeoutSeconds int32 `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
 }
 
+// validate checks the instance counts.
+func (c *CloudRunConfig) validate() error {
+	return validateInstanceCounts("cloud_run", c.MinInstances, c.MaxInstances)
+}
+
+// validateInstanceCounts checks that the min_instances and max_instances of a
+// section are not negative and that the minimum does not exceed a set maximum.
+func validateInstanceCounts(section string, min, max int32) error {
+	if min < 0 || max < 0 {
+		return fmt.Errorf("%s.min_instances and %s.max_instances must not be negative", section, section)
+	}
+	if max > 0 && min > max {
+		return fmt.Errorf("%s.min_instances (%d) must not exceed %s.max_instances (%d)", section, min, section, max)
+	}
+	return nil
+}
+
 // AzureConfig specifies Azure Container Instances-specific configuration.
 type AzureConfig struct {
 	// CPU allocation in cores (e.g., 1.0, 2.0) - default: 1.0
@@ -260,163 +845,2250 @@ type AzureConfig struct {
 	MemoryGB float64 `yaml:"memory_gb,omitempty" json:"memory_gb,omitempty"`
 }
 
-// HealthCheckConfig defines how the cloud provider should check application health.
-type HealthCheckConfig struct {
-	// Type of health check (basic or enhanced)
-	Type string `yaml:"type" json:"type,omitempty"`
+// OCIConfig specifies OCI Container Instances-specific configuration.
+type OCIConfig struct {
+	// Subnet OCID the container instance is attached to (required)
+	SubnetID string `yaml:"subnet_id" json:"subnet_id"`
 
-	// Path to health check endpoint (e.g., /health, /api/status)
-	Path string `yaml:"path" json:"path,omitempty"`
-}
+	// Availability domain to run in (default: the first in the region)
+	AvailabilityDomain string `yaml:"availability_domain,omitempty" json:"availability_domain,omitempty"`
 
-// MonitoringConfig defines monitoring and metrics collection settings.
-type MonitoringConfig struct {
-	// Enable enhanced health reporting (default: false)
-	// Enhanced health provides detailed metrics like ApplicationRequests2xx, latency, etc.
-	EnhancedHealth bool `yaml:"enhanced_health,omitempty" json:"enhanced_health,omitempty"`
+	// Container instance shape - default: "CI.Standard.E4.Flex"
+	Shape string `yaml:"shape,omitempty" json:"shape,omitempty"`
 
-	// Enable CloudWatch custom metrics collection (default: false)
-	// This enables application-level metrics beyond basic EC2 metrics
-	CloudWatchMetrics bool `yaml:"cloudwatch_metrics,omitempty" json:"cloudwatch_metrics,omitempty"`
+	// OCPUs for flexible shapes (e.g., 1, 2) - default: 1
+	OCPUs float64 `yaml:"ocpus,omitempty" json:"ocpus,omitempty"`
 
-	// CloudWatch Logs configuration (optional)
-	CloudWatchLogs *CloudWatchLogsConfig `yaml:"cloudwatch_logs,omitempty" json:"cloudwatch_logs,omitempty"`
+	// Memory in GB for flexible shapes (e.g., 4, 8) - default: 4
+	MemoryGB float64 `yaml:"memory_gb,omitempty" json:"memory_gb,omitempty"`
+
+	// Assign a public IP address to the container instance (default: true)
+	PublicIP *bool `yaml:"public_ip,omitempty" json:"public_ip,omitempty"`
 }
 
-// CloudWatchLogsConfig defines CloudWatch Logs streaming settings.
-type CloudWatchLogsConfig struct {
-	// Enable streaming logs to CloudWatch (default: false)
-	Enabled bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+// Kubernetes service types.
+const (
+	KubernetesServiceClusterIP    = "ClusterIP"
+	KubernetesServiceNodePort     = "NodePort"
+	KubernetesServiceLoadBalancer = "LoadBalancer"
+)
 
-	// Log retention in days (1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 3653)
-	RetentionDays int `yaml:"retention_days,omitempty" json:"retention_days,omitempty"`
+// KubernetesConfig specifies the cluster deployed to by the kubernetes provider
+// and the Deployment, Service, and Ingress created in it.
+type KubernetesConfig struct {
+	// Path to the kubeconfig file - default: $KUBECONFIG or ~/.kube/config,
+	// or the pod's service account when run inside the cluster
+	Kubeconfig string `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty"`
 
-	// Stream application logs (default: true if enabled)
-	StreamLogs bool `yaml:"stream_logs,omitempty" json:"stream_logs,omitempty"`
-}
+	// Kubeconfig context to use - default: the current context
+	Context string `yaml:"context,omitempty" json:"context,omitempty"`
 
-// IAMConfig specifies IAM roles and profiles to use.
-// This allows the application to access other cloud resources securely.
-type IAMConfig struct {
-	// Instance profile for EC2/compute instances - optional
-	InstanceProfile string `yaml:"instance_profile,omitempty" json:"instance_profile,omitempty"`
+	// Namespace to deploy to - default: the context's namespace, or "default"
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
 
-	// Service role for the cloud service - optional
-	ServiceRole string `yaml:"service_role,omitempty" json:"service_role,omitempty"`
+	// Number of pods to run - default: 1
+	Replicas *int32 `yaml:"replicas,omitempty" json:"replicas,omitempty"`
+
+	// Service type: ClusterIP, NodePort, or LoadBalancer
+	// default: LoadBalancer, or ClusterIP with an ingress
+	ServiceType string `yaml:"service_type,omitempty" json:"service_type,omitempty"`
+
+	// CPU and memory requests and limits of each container - optional
+	Resources *KubernetesResources `yaml:"resources,omitempty" json:"resources,omitempty"`
+
+	// Secret with the registry credentials to pull the image with - optional
+	ImagePullSecret string `yaml:"image_pull_secret,omitempty" json:"image_pull_secret,omitempty"`
+
+	// Ingress routing a host name to the service - optional
+	Ingress *KubernetesIngress `yaml:"ingress,omitempty" json:"ingress,omitempty"`
+
+	// RuntimeClass of the pods - default: the cluster's default runtime, or
+	// wasmtime-spin-v2 (SpinKube) for WebAssembly workloads
+	RuntimeClassName string `yaml:"runtime_class_name,omitempty" json:"runtime_class_name,omitempty"`
 }
 
-// SSLConfig defines SSL/TLS certificate configuration.
-type SSLConfig struct {
-	// CertificateArn is the AWS ACM certificate ARN for HTTPS (AWS only)
-	CertificateArn string `yaml:"certificate_arn,omitempty" json:"certificate_arn,omitempty"`
+// KubernetesResources are Kubernetes resource quantities (e.g., cpu: "250m",
+// memory: "512Mi").
+type KubernetesResources struct {
+	// CPU reserved for the container - optional
+	CPURequest string `yaml:"cpu_request,omitempty" json:"cpu_request,omitempty"`
+
+	// Most CPU the container can use - optional
+	CPULimit string `yaml:"cpu_limit,omitempty" json:"cpu_limit,omitempty"`
+
+	// Memory reserved for the container - optional
+	MemoryRequest string `yaml:"memory_request,omitempty" json:"memory_request,omitempty"`
+
+	// Most memory the container can use before it is killed - optional
+	MemoryLimit string `yaml:"memory_limit,omitempty" json:"memory_limit,omitempty"`
 }
 
-// Load reads a manifest file from disk, parses it, and validates it.
-// Returns an error if the file cannot be read, is invalid YAML, or fails validation.
-//
-// Example:
-//
-//	manifest, err := manifest.Load("deploy-manifest.yaml")
-//	if err != nil {
-//	  log.Fatal(err)
-//	}
-func Load(filename string) (*Manifest, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read manifest file: %w", err)
-	}
+// KubernetesIngress configures the Ingress of a Kubernetes deployment.
+type KubernetesIngress struct {
+	// Host name routed to the service (required)
+	Host string `yaml:"host" json:"host"`
 
-	// Expand environment variables in the YAML content
-	expanded := os.ExpandEnv(string(data))
+	// Path prefix routed to the service - default: "/"
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
 
-	var manifest Manifest
-	if err := yaml.Unmarshal([]byte(expanded), &manifest); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
-	}
+	// Ingress class (e.g., nginx, traefik) - default: the cluster's default class
+	ClassName string `yaml:"class_name,omitempty" json:"class_name,omitempty"`
 
-	if err := manifest.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid manifest: %w", err)
-	}
+	// Secret with the TLS certificate for the host; serves HTTPS when set - optional
+	TLSSecret string `yaml:"tls_secret,omitempty" json:"tls_secret,omitempty"`
 
-	return &manifest, nil
+	// Annotations for the ingress controller (e.g., cert-manager.io/cluster-issuer) - optional
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 }
 
-// Validate checks if the manifest has all required fields and valid values.
-// Returns an error describing what is invalid.
-func (m *Manifest) Validate() error {
-	// Validate container configuration (single or multi-container)
-	if m.Image == "" && len(m.Containers) == 0 {
-		return fmt.Errorf("either 'image' (single-container) or 'containers' (multi-container) is required")
+// dnsLabel matches names Kubernetes accepts for Deployments and Services.
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// validate checks the replica count, service type, and ingress.
+func (c *KubernetesConfig) validate() error {
+	if c.Replicas != nil && *c.Replicas < 0 {
+		return fmt.Errorf("kubernetes.replicas must not be negative")
 	}
-	if m.Image != "" && len(m.Containers) > 0 {
-		return fmt.Errorf("cannot specify both 'image' and 'containers' - use one or the other")
+	switch c.ServiceType {
+	case "", KubernetesServiceClusterIP, KubernetesServiceNodePort, KubernetesServiceLoadBalancer:
+	default:
+		return fmt.Errorf("kubernetes.service_type must be ClusterIP, NodePort, or LoadBalancer, got %q", c.ServiceType)
 	}
-
+	if c.Ingress != nil {
+		if c.Ingress.Host == "" {
+			return fmt.Errorf("kubernetes.ingress.host is required")
+		}
+		if c.Ingress.Path != "" && !strings.HasPrefix(c.Ingress.Path, "/") {
+			return fmt.Errorf("kubernetes.ingress.path must start with /")
+		}
+	}
+	return nil
+}
+
+// ECS CPU architectures of Fargate tasks.
+const (
+	ECSArchitectureX86 = "X86_64"
+	ECSArchitectureARM = "ARM64"
+)
+
+// ECSConfig specifies the cluster, Fargate task, and networking of the aws-ecs
+// provider.
+type ECSConfig struct {
+	// Cluster to run the service in; created if it doesn't exist - default: the application name
+	Cluster string `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+
+	// CPU units of the task (256, 512, 1024, 2048, 4096, 8192, or 16384) - default: 256
+	CPU int `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+
+	// Memory of the task in MiB, valid for the CPU (e.g., 512-2048 for 256 CPU) - default: 512
+	Memory int `yaml:"memory,omitempty" json:"memory,omitempty"`
+
+	// Number of tasks to run - default: 1
+	DesiredCount *int `yaml:"desired_count,omitempty" json:"desired_count,omitempty"`
+
+	// CPU architecture of the task: X86_64 or ARM64 - default: X86_64
+	Architecture string `yaml:"architecture,omitempty" json:"architecture,omitempty"`
+
+	// Subnets of the tasks and load balancer - default: the default VPC's default subnets
+	Subnets []string `yaml:"subnets,omitempty" json:"subnets,omitempty"`
+
+	// Security groups of the tasks - default: a group allowing the load balancer in
+	SecurityGroups []string `yaml:"security_groups,omitempty" json:"security_groups,omitempty"`
+
+	// Give tasks a public IP, needed to pull images from public subnets without
+	// a NAT gateway - default: true
+	AssignPublicIP *bool `yaml:"assign_public_ip,omitempty" json:"assign_public_ip,omitempty"`
+
+	// Role ECS pulls images and writes logs with - default: ecsTaskExecutionRole, created if needed
+	ExecutionRoleARN string `yaml:"execution_role_arn,omitempty" json:"execution_role_arn,omitempty"`
+
+	// Role the application's containers run as - optional
+	TaskRoleARN string `yaml:"task_role_arn,omitempty" json:"task_role_arn,omitempty"`
+
+	// Days to keep the CloudWatch logs of the tasks (1, 3, 5, 7, 14, 30, 60, 90, ...) - default: never expire
+	LogRetentionDays int `yaml:"log_retention_days,omitempty" json:"log_retention_days,omitempty"`
+}
+
+// logRetentionDays are the retention periods CloudWatch Logs accepts.
+var logRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// fargateMemory lists the memory sizes in MiB Fargate accepts for each CPU size.
+var fargateMemory = map[int][]int{
+	256:   {512, 1024, 2048},
+	512:   fargateMemoryRange(1024, 4096, 1024),
+	1024:  fargateMemoryRange(2048, 8192, 1024),
+	2048:  fargateMemoryRange(4096, 16384, 1024),
+	4096:  fargateMemoryRange(8192, 30720, 1024),
+	8192:  fargateMemoryRange(16384, 61440, 4096),
+	16384: fargateMemoryRange(32768, 122880, 8192),
+}
+
+// fargateMemoryRange returns the memory sizes from min to max in steps of step.
+func fargateMemoryRange(min, max, step int) []int {
+	var sizes []int
+	for size := min; size <= max; size += step {
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// TaskSize returns the CPU units and memory in MiB of the task. The CPU
+// defaults to 256 and the memory to the least Fargate allows for the CPU.
+func (c *ECSConfig) TaskSize() (int, int) {
+	cpu, memory := 256, 0
+	if c != nil {
+		if c.CPU != 0 {
+			cpu = c.CPU
+		}
+		memory = c.Memory
+	}
+	if sizes := fargateMemory[cpu]; memory == 0 && len(sizes) > 0 {
+		memory = sizes[0]
+	}
+	return cpu, memory
+}
+
+// validate checks the task size, desired count, and architecture.
+func (c *ECSConfig) validate() error {
+	cpu, memory := c.TaskSize()
+	sizes, ok := fargateMemory[cpu]
+	if !ok {
+		return fmt.Errorf("ecs.cpu must be 256, 512, 1024, 2048, 4096, 8192, or 16384, got %d", c.CPU)
+	}
+	if !slices.Contains(sizes, memory) {
+		return fmt.Errorf("ecs.memory %d is not a Fargate memory size for %d CPU (%d to %d MiB)", memory, cpu, sizes[0], sizes[len(sizes)-1])
+	}
+	if c.DesiredCount != nil && *c.DesiredCount < 0 {
+		return fmt.Errorf("ecs.desired_count must not be negative")
+	}
+	switch c.Architecture {
+	case "", ECSArchitectureX86, ECSArchitectureARM:
+	default:
+		return fmt.Errorf("ecs.architecture must be X86_64 or ARM64, got %q", c.Architecture)
+	}
+	if c.LogRetentionDays != 0 && !slices.Contains(logRetentionDays, c.LogRetentionDays) {
+		return fmt.Errorf("ecs.log_retention_days must be a CloudWatch Logs retention period (1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, ...), got %d", c.LogRetentionDays)
+	}
+	return nil
+}
+
+// lambdaFunctionName matches names Lambda accepts for functions.
+var lambdaFunctionName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Architectures of Lambda functions.
+const (
+	LambdaArchitectureX86 = "x86_64"
+	LambdaArchitectureARM = "arm64"
+)
+
+// HTTP endpoints of Lambda functions.
+const (
+	// A function URL on the function's alias
+	LambdaEndpointFunctionURL = "function_url"
+
+	// An API Gateway HTTP API routing every request to the function's alias
+	LambdaEndpointAPIGateway = "api_gateway"
+
+	// No HTTP endpoint; the function is invoked by other AWS services or the API
+	LambdaEndpointNone = "none"
+)
+
+// LambdaConfig specifies the function the aws-lambda provider runs the image
+// as and how it is exposed over HTTP.
+type LambdaConfig struct {
+	// Memory of the function in MB (128 to 10240), which also scales its CPU - default: 512
+	MemorySize int `yaml:"memory_size,omitempty" json:"memory_size,omitempty"`
+
+	// Maximum run time of an invocation in seconds (1 to 900) - default: 30
+	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Instruction set of the function: x86_64 or arm64 - default: x86_64
+	Architecture string `yaml:"architecture,omitempty" json:"architecture,omitempty"`
+
+	// Role the function runs as - default: cloud-deploy-lambda, created with
+	// permission to write logs if it doesn't exist
+	RoleARN string `yaml:"role_arn,omitempty" json:"role_arn,omitempty"`
+
+	// HTTP endpoint: function_url, api_gateway, or none - default: function_url
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Require SigV4-signed requests to the function URL - default: false (public)
+	IAMAuth bool `yaml:"iam_auth,omitempty" json:"iam_auth,omitempty"`
+
+	// Concurrent executions reserved for the function - default: unreserved
+	ReservedConcurrency *int `yaml:"reserved_concurrency,omitempty" json:"reserved_concurrency,omitempty"`
+
+	// Days to keep the function's CloudWatch logs (1, 3, 5, 7, 14, 30, 60, 90, ...) - default: never expire
+	LogRetentionDays int `yaml:"log_retention_days,omitempty" json:"log_retention_days,omitempty"`
+}
+
+// Defaults of Lambda functions.
+const (
+	DefaultLambdaMemorySize = 512
+	DefaultLambdaTimeout    = 30
+)
+
+// FunctionSize returns the memory in MB and timeout in seconds of the
+// function, with defaults applied.
+func (c *LambdaConfig) FunctionSize() (int, int) {
+	memory, timeout := DefaultLambdaMemorySize, DefaultLambdaTimeout
+	if c != nil {
+		if c.MemorySize != 0 {
+			memory = c.MemorySize
+		}
+		if c.Timeout != 0 {
+			timeout = c.Timeout
+		}
+	}
+	return memory, timeout
+}
+
+// HTTPEndpoint returns how the function is exposed over HTTP.
+func (c *LambdaConfig) HTTPEndpoint() string {
+	if c == nil || c.Endpoint == "" {
+		return LambdaEndpointFunctionURL
+	}
+	return c.Endpoint
+}
+
+// validate checks the function's size, architecture, endpoint, and log retention.
+func (c *LambdaConfig) validate() error {
+	memory, timeout := c.FunctionSize()
+	if memory < 128 || memory > 10240 {
+		return fmt.Errorf("lambda.memory_size must be from 128 to 10240 MB, got %d", memory)
+	}
+	if timeout < 1 || timeout > 900 {
+		return fmt.Errorf("lambda.timeout must be from 1 to 900 seconds, got %d", timeout)
+	}
+	switch c.Architecture {
+	case "", LambdaArchitectureX86, LambdaArchitectureARM:
+	default:
+		return fmt.Errorf("lambda.architecture must be x86_64 or arm64, got %q", c.Architecture)
+	}
+	switch c.Endpoint {
+	case "", LambdaEndpointFunctionURL, LambdaEndpointAPIGateway, LambdaEndpointNone:
+	default:
+		return fmt.Errorf("lambda.endpoint must be function_url, api_gateway, or none, got %q", c.Endpoint)
+	}
+	if c.IAMAuth && c.HTTPEndpoint() != LambdaEndpointFunctionURL {
+		return fmt.Errorf("lambda.iam_auth only applies to the function_url endpoint")
+	}
+	if c.ReservedConcurrency != nil && *c.ReservedConcurrency < 0 {
+		return fmt.Errorf("lambda.reserved_concurrency must not be negative")
+	}
+	if c.LogRetentionDays != 0 && !slices.Contains(logRetentionDays, c.LogRetentionDays) {
+		return fmt.Errorf("lambda.log_retention_days must be a CloudWatch Logs retention period (1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, ...), got %d", c.LogRetentionDays)
+	}
+	return nil
+}
+
+// appRunnerServiceName matches names App Runner accepts for services.
+var appRunnerServiceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{3,39}$`)
+
+// appRunnerMemory lists the memory sizes in MiB App Runner accepts for each CPU size.
+var appRunnerMemory = map[int][]int{
+	256:  {512, 1024},
+	512:  {1024},
+	1024: {2048, 3072, 4096},
+	2048: {4096, 6144},
+	4096: {8192, 10240, 12288},
+}
+
+// AppRunnerConfig specifies the instances, autoscaling, and health check of
+// the aws-apprunner provider's service.
+type AppRunnerConfig struct {
+	// CPU units of each instance (256, 512, 1024, 2048, or 4096) - default: 1024
+	CPU int `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+
+	// Memory of each instance in MiB, valid for the CPU (e.g., 2048-4096 for 1024 CPU) - default: the least for the CPU
+	Memory int `yaml:"memory,omitempty" json:"memory,omitempty"`
+
+	// Concurrent requests an instance serves before App Runner scales out (1 to 200) - default: 100
+	MaxConcurrency int `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`
+
+	// Instances kept provisioned - default: 1
+	MinInstances int32 `yaml:"min_instances,omitempty" json:"min_instances,omitempty"`
+
+	// Instances to scale out to - default: 25
+	MaxInstances int32 `yaml:"max_instances,omitempty" json:"max_instances,omitempty"`
+
+	// Seconds between health checks (1 to 20) - default: 5
+	HealthCheckInterval int `yaml:"health_check_interval,omitempty" json:"health_check_interval,omitempty"`
+
+	// Seconds to wait for a health check response (1 to 20) - default: 2
+	HealthCheckTimeout int `yaml:"health_check_timeout,omitempty" json:"health_check_timeout,omitempty"`
+
+	// Consecutive successful checks before an instance is healthy (1 to 20) - default: 1
+	HealthyThreshold int `yaml:"healthy_threshold,omitempty" json:"healthy_threshold,omitempty"`
+
+	// Consecutive failed checks before an instance is unhealthy (1 to 20) - default: 5
+	UnhealthyThreshold int `yaml:"unhealthy_threshold,omitempty" json:"unhealthy_threshold,omitempty"`
+
+	// Role App Runner pulls the image from ECR with - default: cloud-deploy-apprunner-ecr,
+	// created if it doesn't exist
+	AccessRoleARN string `yaml:"access_role_arn,omitempty" json:"access_role_arn,omitempty"`
+
+	// Role the application runs as - optional
+	InstanceRoleARN string `yaml:"instance_role_arn,omitempty" json:"instance_role_arn,omitempty"`
+}
+
+// Defaults of App Runner services.
+const (
+	DefaultAppRunnerCPU            = 1024
+	DefaultAppRunnerMaxConcurrency = 100
+	DefaultAppRunnerMinInstances   = 1
+	DefaultAppRunnerMaxInstances   = 25
+)
+
+// InstanceSize returns the CPU units and memory in MiB of each instance. The
+// memory defaults to the least App Runner allows for the CPU.
+func (c *AppRunnerConfig) InstanceSize() (int, int) {
+	cpu, memory := DefaultAppRunnerCPU, 0
+	if c != nil {
+		if c.CPU != 0 {
+			cpu = c.CPU
+		}
+		memory = c.Memory
+	}
+	if sizes := appRunnerMemory[cpu]; memory == 0 && len(sizes) > 0 {
+		memory = sizes[0]
+	}
+	return cpu, memory
+}
+
+// Scaling returns the minimum and maximum instances and the concurrent
+// requests per instance of the service, with defaults applied.
+func (c *AppRunnerConfig) Scaling() (int, int, int) {
+	min, max, concurrency := DefaultAppRunnerMinInstances, DefaultAppRunnerMaxInstances, DefaultAppRunnerMaxConcurrency
+	if c != nil {
+		if c.MinInstances != 0 {
+			min = int(c.MinInstances)
+		}
+		if c.MaxInstances != 0 {
+			max = int(c.MaxInstances)
+		}
+		if c.MaxConcurrency != 0 {
+			concurrency = c.MaxConcurrency
+		}
+	}
+	return min, max, concurrency
+}
+
+// validate checks the instance size, autoscaling, and health check settings.
+func (c *AppRunnerConfig) validate() error {
+	cpu, memory := c.InstanceSize()
+	sizes, ok := appRunnerMemory[cpu]
+	if !ok {
+		return fmt.Errorf("app_runner.cpu must be 256, 512, 1024, 2048, or 4096, got %d", c.CPU)
+	}
+	if !slices.Contains(sizes, memory) {
+		return fmt.Errorf("app_runner.memory %d is not an App Runner memory size for %d CPU (%v MiB)", memory, cpu, sizes)
+	}
+	if err := validateInstanceCounts("app_runner", c.MinInstances, c.MaxInstances); err != nil {
+		return err
+	}
+	if min, max, _ := c.Scaling(); min > max {
+		return fmt.Errorf("app_runner.min_instances (%d) must not exceed the default max_instances (%d)", min, max)
+	}
+	if c.MaxConcurrency < 0 || c.MaxConcurrency > 200 {
+		return fmt.Errorf("app_runner.max_concurrency must be from 1 to 200, got %d", c.MaxConcurrency)
+	}
+	for name, v := range map[string]int{
+		"health_check_interval": c.HealthCheckInterval,
+		"health_check_timeout":  c.HealthCheckTimeout,
+		"healthy_threshold":     c.HealthyThreshold,
+		"unhealthy_threshold":   c.UnhealthyThreshold,
+	} {
+		if v < 0 || v > 20 {
+			return fmt.Errorf("app_runner.%s must be from 1 to 20, got %d", name, v)
+		}
+	}
+	return nil
+}
+
+// containerAppName matches names Azure Container Apps accepts for apps.
+var containerAppName = regexp.MustCompile(`^[a-z][a-z0-9-]{0,30}[a-z0-9]$`)
+
+// ContainerAppsConfig specifies the replicas, scale rules, and ingress of the
+// azure-container-apps provider's container app.
+type ContainerAppsConfig struct {
+	// Container Apps environment the app runs in, created if it doesn't exist -
+	// default: "<environment.name>-env"
+	Environment string `yaml:"environment,omitempty" json:"environment,omitempty"`
+
+	// CPU cores of each container of a replica (0.25 to 4, in steps of 0.25); each core comes with 2 GiB of memory - default: 0.5
+	CPU float64 `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+
+	// Replicas kept running; 0 scales the app to zero when it is idle - default: 0
+	MinReplicas int32 `yaml:"min_replicas,omitempty" json:"min_replicas,omitempty"`
+
+	// Replicas to scale out to (up to 1000) - default: 10
+	MaxReplicas int32 `yaml:"max_replicas,omitempty" json:"max_replicas,omitempty"`
+
+	// Rules replicas are scaled by - default: Container Apps' HTTP rule (10 concurrent requests per replica)
+	ScaleRules []ContainerAppsScaleRule `yaml:"scale_rules,omitempty" json:"scale_rules,omitempty"`
+
+	// Accept HTTPS traffic from the internet, rather than only from inside the environment - default: true
+	External *bool `yaml:"external,omitempty" json:"external,omitempty"`
+}
+
+// ContainerAppsScaleRule is a rule Container Apps scales the replicas of an
+// app by. It is an HTTP rule when concurrent_requests is set, and a custom
+// KEDA scaler otherwise.
+type ContainerAppsScaleRule struct {
+	// Name of the rule
+	Name string `yaml:"name" json:"name"`
+
+	// Concurrent HTTP requests per replica before Container Apps scales out - optional
+	ConcurrentRequests int `yaml:"concurrent_requests,omitempty" json:"concurrent_requests,omitempty"`
+
+	// KEDA scaler type of a custom rule (e.g., "cpu", "memory", "azure-servicebus") - optional
+	Custom string `yaml:"custom,omitempty" json:"custom,omitempty"`
+
+	// Metadata of the KEDA scaler (e.g., type: Utilization, value: "70") - optional
+	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// Defaults of Container Apps replicas.
+const (
+	DefaultContainerAppsCPU         = 0.5
+	DefaultContainerAppsMaxReplicas = 10
+)
+
+// ManagedEnvironment returns the name of the Container Apps environment the
+// app of the named deployment environment runs in.
+func (c *ContainerAppsConfig) ManagedEnvironment(environment string) string {
+	if c != nil && c.Environment != "" {
+		return c.Environment
+	}
+	return environment + "-env"
+}
+
+// Resources returns the CPU cores and memory (e.g., "1Gi") of each container.
+// Container Apps pairs each core with 2 GiB of memory.
+func (c *ContainerAppsConfig) Resources() (float64, string) {
+	cpu := DefaultContainerAppsCPU
+	if c != nil && c.CPU != 0 {
+		cpu = c.CPU
+	}
+	return cpu, strconv.FormatFloat(cpu*2, 'f', -1, 64) + "Gi"
+}
+
+// Replicas returns the minimum and maximum replicas of the app, with
+// defaults applied.
+func (c *ContainerAppsConfig) Replicas() (int, int) {
+	min, max := 0, DefaultContainerAppsMaxReplicas
+	if c != nil {
+		min = int(c.MinReplicas)
+		if c.MaxReplicas != 0 {
+			max = int(c.MaxReplicas)
+		}
+	}
+	return min, max
+}
+
+// IsExternal reports whether the app accepts traffic from the internet.
+func (c *ContainerAppsConfig) IsExternal() bool {
+	return c == nil || c.External == nil || *c.External
+}
+
+// validate checks the replica size, replica counts, and scale rules.
+func (c *ContainerAppsConfig) validate() error {
+	cpu, _ := c.Resources()
+	if cpu < 0.25 || cpu > 4 || math.Mod(cpu, 0.25) != 0 {
+		return fmt.Errorf("container_apps.cpu must be from 0.25 to 4 in steps of 0.25, got %g", c.CPU)
+	}
+	if c.MinReplicas < 0 || c.MaxReplicas < 0 {
+		return fmt.Errorf("container_apps.min_replicas and container_apps.max_replicas must not be negative")
+	}
+	if min, max := c.Replicas(); min > max || max > 1000 {
+		return fmt.Errorf("container_apps.min_replicas (%d) must not exceed container_apps.max_replicas (%d), which must be at most 1000", min, max)
+	}
+	names := make(map[string]bool)
+	for i, rule := range c.ScaleRules {
+		if rule.Name == "" {
+			return fmt.Errorf("container_apps.scale_rules[%d].name is required", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("container_apps.scale_rules[%d].name %q is used by another rule", i, rule.Name)
+		}
+		names[rule.Name] = true
+		if (rule.ConcurrentRequests > 0) == (rule.Custom != "") {
+			return fmt.Errorf("container_apps.scale_rules[%d] must set exactly one of concurrent_requests and custom", i)
+		}
+		if rule.ConcurrentRequests < 0 {
+			return fmt.Errorf("container_apps.scale_rules[%d].concurrent_requests must be positive", i)
+		}
+	}
+	return nil
+}
+
+// AppServiceConfig specifies Azure App Service (Web App for Containers)
+// configuration. The pricing tier of the App Service plan is instance.type
+// and its number of workers instance.min_instances.
+type AppServiceConfig struct {
+	// App Service plan the app runs on, created if it doesn't exist - default: "<environment.name>-plan"
+	Plan string `yaml:"plan,omitempty" json:"plan,omitempty"`
+
+	// Deployment slot new versions are deployed to and swapped into production
+	// from once they are warmed up; rollback swaps them back - optional
+	Slot string `yaml:"slot,omitempty" json:"slot,omitempty"`
+
+	// Custom domains served by the app over HTTPS, each with a free App Service
+	// managed certificate - optional
+	CustomDomains []string `yaml:"custom_domains,omitempty" json:"custom_domains,omitempty"`
+}
+
+// DefaultAppServiceSKU is the pricing tier of App Service plans when
+// instance.type is not set.
+const DefaultAppServiceSKU = "B1"
+
+var (
+	// appServiceName matches names Azure App Service accepts for apps.
+	appServiceName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]{0,58}[a-zA-Z0-9]$`)
+
+	// appServiceSKU matches the pricing tiers of Linux App Service plans.
+	appServiceSKU = regexp.MustCompile(`^(F1|B[1-3]|S[1-3]|P[1-3]v2|P[0-3]v3|P[1-5]mv3|I[1-6]v2)$`)
+
+	// appServiceSlot matches names of deployment slots.
+	appServiceSlot = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+	// domainName matches fully qualified domain names.
+	domainName = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]$`)
+)
+
+// PlanName returns the name of the App Service plan the app runs on.
+func (c *AppServiceConfig) PlanName(environment string) string {
+	if c == nil || c.Plan == "" {
+		return environment + "-plan"
+	}
+	return c.Plan
+}
+
+// SlotName returns the deployment slot deployments go through, or "" when
+// they update production directly.
+func (c *AppServiceConfig) SlotName() string {
+	if c == nil {
+		return ""
+	}
+	return c.Slot
+}
+
+// Domains returns the custom domains of the app.
+func (c *AppServiceConfig) Domains() []string {
+	if c == nil {
+		return nil
+	}
+	return c.CustomDomains
+}
+
+// AppServiceSKU returns the pricing tier and number of workers of the App
+// Service plan for the instance configuration.
+func AppServiceSKU(instance InstanceConfig) (string, int32) {
+	sku, workers := instance.Type, instance.MinInstances
+	if sku == "" {
+		sku = DefaultAppServiceSKU
+	}
+	if workers == 0 {
+		workers = 1
+	}
+	return sku, workers
+}
+
+// validate checks the plan's pricing tier, the slot, and the custom domains.
+// Slots need the Standard tier or higher and custom domains the Basic tier.
+func (c *AppServiceConfig) validate(instance InstanceConfig) error {
+	sku, _ := AppServiceSKU(instance)
+	if !appServiceSKU.MatchString(sku) {
+		return fmt.Errorf("instance.type %q is not an App Service pricing tier (e.g., B1, S1, P1v3)", sku)
+	}
+	if slot := c.SlotName(); slot != "" {
+		if !appServiceSlot.MatchString(slot) || slot == "production" {
+			return fmt.Errorf("app_service.slot %q must be up to 40 lowercase letters, digits, and '-', and not production", slot)
+		}
+		if sku == "F1" || sku[0] == 'B' {
+			return fmt.Errorf("app_service.slot requires a Standard, Premium, or Isolated pricing tier (instance.type), not %s", sku)
+		}
+	}
+	seen := make(map[string]bool)
+	for i, domain := range c.Domains() {
+		if !domainName.MatchString(domain) {
+			return fmt.Errorf("app_service.custom_domains[%d] %q is not a lowercase domain name", i, domain)
+		}
+		if seen[domain] {
+			return fmt.Errorf("app_service.custom_domains[%d] %q is listed twice", i, domain)
+		}
+		seen[domain] = true
+		if sku == "F1" {
+			return fmt.Errorf("app_service.custom_domains require a Basic pricing tier (instance.type) or higher, not F1")
+		}
+	}
+	return nil
+}
+
+// GKEConfig specifies the GKE cluster the gcp-gke provider deploys to. The
+// Deployment, Service, and Ingress in it are configured by the kubernetes
+// section, as for the kubernetes provider.
+type GKEConfig struct {
+	// Cluster to deploy to in the provider's region; an Autopilot cluster is
+	// created if it doesn't exist - default: the application name
+	Cluster string `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+}
+
+// gkeClusterName matches names GKE accepts for clusters.
+var gkeClusterName = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,38}[a-z0-9])?$`)
+
+// ClusterName returns the name of the GKE cluster to deploy to.
+func (c *GKEConfig) ClusterName(application string) string {
+	if c == nil || c.Cluster == "" {
+		return application
+	}
+	return c.Cluster
+}
+
+// validateGKE checks the cluster name of the gcp-gke provider and that the
+// kubernetes section does not select a kubeconfig, which it does not read.
+func (m *Manifest) validateGKE() error {
+	if name := m.GKE.ClusterName(m.Application.Name); !gkeClusterName.MatchString(name) {
+		return fmt.Errorf("GKE cluster name %q must be up to 40 lowercase letters, digits, and '-', starting with a letter; set gke.cluster", name)
+	}
+	if k := m.Kubernetes; k != nil && (k.Kubeconfig != "" || k.Context != "") {
+		return fmt.Errorf("kubernetes.kubeconfig and kubernetes.context are not used by gcp-gke, which connects to the cluster in gke.cluster")
+	}
+	return nil
+}
+
+// DigitalOceanConfig specifies the DigitalOcean resources the digitalocean
+// provider uses besides the App Platform app, which is named after the
+// environment and sized by instance.type and instance.min_instances.
+type DigitalOceanConfig struct {
+	// Container registry images are pushed to - default: the account's
+	// registry, or one named after the application if the account has none
+	Registry string `yaml:"registry,omitempty" json:"registry,omitempty"`
+}
+
+// DefaultDigitalOceanInstanceSize is the instance size of App Platform
+// services when instance.type is not set.
+const DefaultDigitalOceanInstanceSize = "apps-s-1vcpu-1gb"
+
+// digitalOceanInstanceSizes maps EC2 instance types, which manifests written
+// for AWS use, to the App Platform instance size with the same CPUs and memory.
+var digitalOceanInstanceSizes = map[string]string{
+	"t3.nano":    "apps-s-1vcpu-0.5gb",
+	"t3.micro":   "apps-s-1vcpu-1gb",
+	"t3.small":   "apps-s-1vcpu-2gb",
+	"t3.medium":  "apps-s-2vcpu-4gb",
+	"t3.large":   "apps-d-2vcpu-8gb",
+	"t3.xlarge":  "apps-d-4vcpu-16gb",
+	"t3.2xlarge": "apps-d-8vcpu-32gb",
+}
+
+// digitalOceanRegions are the regions App Platform runs apps in.
+var digitalOceanRegions = map[string]bool{
+	"ams": true, "atl": true, "blr": true, "fra": true, "lon": true,
+	"nyc": true, "sfo": true, "sgp": true, "syd": true, "tor": true,
+}
+
+var (
+	// digitalOceanInstanceSize matches App Platform instance size slugs.
+	digitalOceanInstanceSize = regexp.MustCompile(`^apps-[sd]-[0-9]+vcpu-[0-9.]+gb(-fixed)?$`)
+
+	// digitalOceanRegistryName matches names of DigitalOcean container registries.
+	digitalOceanRegistryName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+// RegistryName returns the configured container registry, or "" to use the
+// account's registry.
+func (c *DigitalOceanConfig) RegistryName() string {
+	if c == nil {
+		return ""
+	}
+	return c.Registry
+}
+
+// DigitalOceanInstanceSize returns the App Platform instance size and number of
+// instances for the instance configuration. EC2 instance types are mapped to the
+// size with the same CPUs and memory.
+func DigitalOceanInstanceSize(instance InstanceConfig) (string, int32) {
+	size, count := instance.Type, instance.MinInstances
+	if size == "" {
+		size = DefaultDigitalOceanInstanceSize
+	}
+	if mapped, ok := digitalOceanInstanceSizes[size]; ok {
+		size = mapped
+	}
+	if count == 0 {
+		count = 1
+	}
+	return size, count
+}
+
+// DigitalOceanRegion returns the App Platform region of a provider region,
+// which may also name a datacenter (nyc3 runs apps in nyc).
+func DigitalOceanRegion(region string) string {
+	return strings.TrimRight(region, "0123456789")
+}
+
+// validateDigitalOcean checks the app name, instance size, region, and registry
+// of the digitalocean provider.
+func (m *Manifest) validateDigitalOcean(region string) error {
+	if m.IsMultiContainer() {
+		return fmt.Errorf("digitalocean runs a single image; use image instead of containers")
+	}
+	if m.Environment.Name != "" && !containerAppName.MatchString(m.Environment.Name) {
+		return fmt.Errorf("environment name %q must be 2 to 32 lowercase letters, digits, and '-', starting with a letter and ending with a letter or digit, to name an App Platform app", m.Environment.Name)
+	}
+	if size, _ := DigitalOceanInstanceSize(m.Instance); !digitalOceanInstanceSize.MatchString(size) {
+		return fmt.Errorf("instance.type %q is not an App Platform instance size (e.g., apps-s-1vcpu-1gb) or an EC2 type from t3.nano to t3.2xlarge", size)
+	}
+	if region != "" && !digitalOceanRegions[DigitalOceanRegion(region)] {
+		return fmt.Errorf("region %q is not an App Platform region (ams, atl, blr, fra, lon, nyc, sfo, sgp, syd, tor)", region)
+	}
+	if name := m.DigitalOcean.RegistryName(); name != "" && !digitalOceanRegistryName.MatchString(name) {
+		return fmt.Errorf("digitalocean.registry %q must be up to 63 lowercase letters, digits, and '-'", name)
+	}
+	return nil
+}
+
+// FlyConfig specifies how the fly provider runs the application on Fly.io,
+// as an app named after the environment with instance.min_instances Machines
+// (default 1) in each region, sized by instance.type.
+type FlyConfig struct {
+	// Organization the app is created in - default: personal
+	Org string `yaml:"org,omitempty" json:"org,omitempty"`
+
+	// Regions to run Machines in (e.g., iad, lhr, syd) - default: provider.region, or iad
+	Regions []string `yaml:"regions,omitempty" json:"regions,omitempty"`
+}
+
+// DefaultFlyOrg is the organization Fly.io apps are created in when fly.org
+// is not set.
+const DefaultFlyOrg = "personal"
+
+// DefaultFlyRegion is the region Fly Machines run in when neither fly.regions
+// nor provider.region is set.
+const DefaultFlyRegion = "iad"
+
+// DefaultFlyMachineSize is the size of Fly Machines when instance.type is not
+// set.
+const DefaultFlyMachineSize = "shared-cpu-1x"
+
+// FlyGuest is the CPU kind, number of CPUs, and memory of a Fly Machine.
+type FlyGuest struct {
+	CPUKind  string `json:"cpu_kind"`
+	CPUs     int    `json:"cpus"`
+	MemoryMB int    `json:"memory_mb"`
+}
+
+// flyGuests maps EC2 instance types, which manifests written for AWS use, to
+// a Fly Machine with the same CPUs and memory.
+var flyGuests = map[string]FlyGuest{
+	"t3.nano":    {"shared", 1, 512},
+	"t3.micro":   {"shared", 1, 1024},
+	"t3.small":   {"shared", 2, 2048},
+	"t3.medium":  {"shared", 2, 4096},
+	"t3.large":   {"performance", 2, 8192},
+	"t3.xlarge":  {"performance", 4, 16384},
+	"t3.2xlarge": {"performance", 8, 32768},
+}
+
+// flyPreset matches the Fly Machine size presets, such as shared-cpu-2x and
+// performance-4x.
+var flyPreset = regexp.MustCompile(`^(shared-cpu|performance)-(1|2|4|8|16)x$`)
+
+// flyRegion matches Fly.io region codes.
+var flyRegion = regexp.MustCompile(`^[a-z]{3}$`)
+
+// OrgSlug returns the organization apps are created in.
+func (c *FlyConfig) OrgSlug() string {
+	if c == nil || c.Org == "" {
+		return DefaultFlyOrg
+	}
+	return c.Org
+}
+
+// FlyRegions returns the regions Fly Machines run in: fly.regions, or else
+// the provider region, or else DefaultFlyRegion. It is safe to call with a
+// nil config.
+func FlyRegions(c *FlyConfig, region string) []string {
+	switch {
+	case c != nil && len(c.Regions) > 0:
+		return c.Regions
+	case region != "":
+		return []string{region}
+	default:
+		return []string{DefaultFlyRegion}
+	}
+}
+
+// FlyMachineGuest returns the size of the Fly Machines for the instance
+// configuration, and the number to run in each region. instance.type is a
+// size preset (shared-cpu-1x to shared-cpu-8x, performance-1x to
+// performance-16x), whose memory is the preset's minimum, or an EC2 instance
+// type mapped to the same CPUs and memory. ok is false for other types.
+func FlyMachineGuest(instance InstanceConfig) (guest FlyGuest, count int, ok bool) {
+	size := instance.Type
+	if size == "" {
+		size = DefaultFlyMachineSize
+	}
+	count = int(instance.MinInstances)
+	if count == 0 {
+		count = 1
+	}
+	if guest, ok := flyGuests[size]; ok {
+		return guest, count, true
+	}
+	match := flyPreset.FindStringSubmatch(size)
+	if match == nil || (match[1] == "shared-cpu" && match[2] == "16") {
+		return FlyGuest{}, count, false
+	}
+	cpus, _ := strconv.Atoi(match[2])
+	if match[1] == "shared-cpu" {
+		return FlyGuest{CPUKind: "shared", CPUs: cpus, MemoryMB: 256 * cpus}, count, true
+	}
+	return FlyGuest{CPUKind: "performance", CPUs: cpus, MemoryMB: 2048 * cpus}, count, true
+}
+
+// validateFly checks the app name, Machine size, and regions of the fly
+// provider.
+func (m *Manifest) validateFly(region string) error {
+	if m.IsMultiContainer() {
+		return fmt.Errorf("fly runs a single image; use image instead of containers")
+	}
+	if m.Environment.Name != "" && (len(m.Environment.Name) > 63 || !dnsLabel.MatchString(m.Environment.Name)) {
+		return fmt.Errorf("environment name %q must be a lowercase DNS label (letters, digits, and '-') to name a Fly.io app", m.Environment.Name)
+	}
+	if _, _, ok := FlyMachineGuest(m.Instance); !ok {
+		return fmt.Errorf("instance.type %q is not a Fly Machine size (shared-cpu-1x to shared-cpu-8x, performance-1x to performance-16x) or an EC2 type from t3.nano to t3.2xlarge", m.Instance.Type)
+	}
+	for _, r := range FlyRegions(m.Fly, region) {
+		if !flyRegion.MatchString(r) {
+			return fmt.Errorf("region %q is not a Fly.io region code (e.g., iad, lhr, syd)", r)
+		}
+	}
+	return nil
+}
+
+// MockOperations are the operations of the mock provider that latencies and
+// failures can be configured for.
+var MockOperations = []string{"deploy", "stop", "destroy", "status", "rollback", "logs", "plan", "scale", "posture", "inventory", "maintenance"}
+
+// MockConfig configures the mock provider, which simulates deployments without
+// a cloud account so pipelines, hooks, and notifications can be tested.
+type MockConfig struct {
+	// How long every operation takes, in milliseconds - default: 0
+	LatencyMS int `yaml:"latency_ms,omitempty" json:"latency_ms,omitempty"`
+
+	// How long specific operations take, in milliseconds, overriding latency_ms
+	// (e.g., deploy: 5000) - optional
+	OperationLatencyMS map[string]int `yaml:"operation_latency_ms,omitempty" json:"operation_latency_ms,omitempty"`
+
+	// Operations that always fail (e.g., ["rollback"]) - optional
+	FailOn []string `yaml:"fail_on,omitempty" json:"fail_on,omitempty"`
+
+	// Probability from 0 to 1 that any operation fails - default: 0
+	FailureRate float64 `yaml:"failure_rate,omitempty" json:"failure_rate,omitempty"`
+
+	// Error message of injected failures - default: "injected failure"
+	FailureMessage string `yaml:"failure_message,omitempty" json:"failure_message,omitempty"`
+
+	// File that keeps simulated deployments between runs, so status and
+	// rollback see earlier deploys (default: deployments only last for one run)
+	StateFile string `yaml:"state_file,omitempty" json:"state_file,omitempty"`
+}
+
+// validate checks that latencies, failure rates, and operation names are valid.
+func (c *MockConfig) validate() error {
+	if c.LatencyMS < 0 {
+		return fmt.Errorf("mock.latency_ms must not be negative")
+	}
+	for op, ms := range c.OperationLatencyMS {
+		if !slices.Contains(MockOperations, op) {
+			return fmt.Errorf("mock.operation_latency_ms: unknown operation %q", op)
+		}
+		if ms < 0 {
+			return fmt.Errorf("mock.operation_latency_ms.%s must not be negative", op)
+		}
+	}
+	for _, op := range c.FailOn {
+		if !slices.Contains(MockOperations, op) {
+			return fmt.Errorf("mock.fail_on: unknown operation %q", op)
+		}
+	}
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		return fmt.Errorf("mock.failure_rate must be between 0 and 1, got %v", c.FailureRate)
+	}
+	return nil
+}
+
+// StateBackends are the supported deployment history backends.
+var StateBackends = []string{"local", "s3", "gcs"}
+
+// StateConfig configures where the history of deployments is recorded.
+type StateConfig struct {
+	// Backend storing the history: "local", "s3", or "gcs" - default: "local"
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+
+	// Directory of the local backend - default: the user's config directory
+	// (e.g., ~/.config/cloud-deploy/state)
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Bucket of the s3 and gcs backends - required for s3 and gcs
+	Bucket string `yaml:"bucket,omitempty" json:"bucket,omitempty"`
+
+	// Key prefix of history objects in the bucket - default: "cloud-deploy/state"
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// Region of the S3 bucket - default: the AWS SDK's configured region
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+
+	// URL of an S3 or Cloud Storage emulator serving the bucket - default:
+	// provider.endpoint_url when the backend is on the provider's cloud
+	EndpointURL string `yaml:"endpoint_url,omitempty" json:"endpoint_url,omitempty"`
+}
+
+// validate checks the backend and that bucket backends have a bucket.
+func (c *StateConfig) validate() error {
+	if c.Backend != "" && !slices.Contains(StateBackends, c.Backend) {
+		return fmt.Errorf("state.backend must be one of %s, got %q", strings.Join(StateBackends, ", "), c.Backend)
+	}
+	if (c.Backend == "s3" || c.Backend == "gcs") && c.Bucket == "" {
+		return fmt.Errorf("state.bucket is required for the %s backend", c.Backend)
+	}
+	if c.EndpointURL != "" {
+		if c.Backend != "s3" && c.Backend != "gcs" {
+			return fmt.Errorf("state.endpoint_url is only supported by the s3 and gcs backends")
+		}
+		if !isEndpointURL(c.EndpointURL) {
+			return fmt.Errorf("state.endpoint_url must be an http:// or https:// URL, got %q", c.EndpointURL)
+		}
+	}
+	return nil
+}
+
+// DNSRecordTypes are the DNS record types cloudflare.dns_records can manage.
+var DNSRecordTypes = []string{"CNAME", "A", "AAAA", "TXT"}
+
+// CloudflareConfig specifies the Cloudflare zone in front of the deployment.
+// The API token is read from the CLOUDFLARE_API_TOKEN environment variable.
+type CloudflareConfig struct {
+	// ID of the zone - required unless domain is set
+	ZoneID string `yaml:"zone_id,omitempty" json:"zone_id,omitempty"`
+
+	// Domain of the zone (e.g., example.com), used to look up its ID - required unless zone_id is set
+	Domain string `yaml:"domain,omitempty" json:"domain,omitempty"`
+
+	// ID of the account owning the load balancer's pools and monitors - default: the zone's account
+	AccountID string `yaml:"account_id,omitempty" json:"account_id,omitempty"`
+
+	// DNS records created or updated after each deploy and rollback - optional
+	DNSRecords []DNSRecordConfig `yaml:"dns_records,omitempty" json:"dns_records,omitempty"`
+
+	// Load balancer steering traffic between the providers' deployments, created or updated after each deploy - optional
+	LoadBalancer *CloudflareLoadBalancerConfig `yaml:"load_balancer,omitempty" json:"load_balancer,omitempty"`
+
+	// Origin pools of the load balancer - default: one pool per provider
+	Pools []CloudflarePoolConfig `yaml:"pools,omitempty" json:"pools,omitempty"`
+
+	// Health monitors of the pools - optional
+	Monitors []CloudflareMonitorConfig `yaml:"monitors,omitempty" json:"monitors,omitempty"`
+}
+
+// SteeringPolicies are the ways a Cloudflare load balancer can choose a pool.
+var SteeringPolicies = []string{"off", "geo", "random", "dynamic_latency", "proximity", "least_outstanding_requests", "least_connections"}
+
+// MonitorTypes are the protocols a Cloudflare health monitor can check with.
+var MonitorTypes = []string{"http", "https", "tcp", "udp_icmp", "icmp_ping", "smtp"}
+
+// CloudflareLoadBalancerConfig is a Cloudflare load balancer in front of the
+// deployments of every provider.
+type CloudflareLoadBalancerConfig struct {
+	// Hostname of the load balancer (e.g., app.example.com); a name without the domain is relative to it
+	Name string `yaml:"name" json:"name"`
+
+	// How a pool is chosen: off (failover in pool order), geo, random, dynamic_latency, proximity,
+	// least_outstanding_requests, or least_connections - default: off
+	SteeringPolicy string `yaml:"steering_policy,omitempty" json:"steering_policy,omitempty"`
+
+	// Time to live in seconds of unproxied answers - default: 30
+	TTL int `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+
+	// Proxy traffic through Cloudflare - default: true
+	Proxied *bool `yaml:"proxied,omitempty" json:"proxied,omitempty"`
+
+	// Pools serving each Cloudflare region (e.g., WNAM, WEU) with geo steering - optional
+	RegionPools map[string][]string `yaml:"region_pools,omitempty" json:"region_pools,omitempty"`
+}
+
+// CloudflarePoolConfig is an origin pool of the load balancer.
+type CloudflarePoolConfig struct {
+	// Name of the pool, unique within the account (letters, digits, hyphens, and underscores)
+	Name string `yaml:"name" json:"name"`
+
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Provider whose deployment URL is added to the pool's origins after it deploys - optional
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Name of the monitor checking the pool's origins - default: the first monitor
+	Monitor string `yaml:"monitor,omitempty" json:"monitor,omitempty"`
+
+	// Origins besides the provider's deployment - optional
+	Origins []CloudflareOriginConfig `yaml:"origins,omitempty" json:"origins,omitempty"`
+}
+
+// CloudflareOriginConfig is an origin of a pool.
+type CloudflareOriginConfig struct {
+	Name string `yaml:"name" json:"name"`
+
+	// Host name or IP address of the origin
+	Address string `yaml:"address" json:"address"`
+
+	// Send traffic to the origin - default: true
+	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+
+	// Share of the pool's traffic, from 0 to 1 - default: 1
+	Weight *float64 `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// Request headers sent to the origin - default: Host set to the address of host name origins
+	Header map[string]string `yaml:"header,omitempty" json:"header,omitempty"`
+}
+
+// CloudflareMonitorConfig is a health monitor of the load balancer's pools.
+type CloudflareMonitorConfig struct {
+	// Name of the monitor, which pools refer to it by
+	Name string `yaml:"name" json:"name"`
+
+	// Protocol: http, https, tcp, udp_icmp, icmp_ping, or smtp - default: https
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// Path requested by http and https monitors - default: /
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// Port checked - default: the protocol's port
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+
+	// Seconds between checks - default: 60
+	Interval int `yaml:"interval,omitempty" json:"interval,omitempty"`
+
+	// Retries before an origin is marked unhealthy - default: 2
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty"`
+
+	// Seconds to wait for a response - default: 5
+	Timeout int `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Status codes of healthy responses to http and https monitors (e.g., 200 or 2xx) - default: 200
+	ExpectedCodes string `yaml:"expected_codes,omitempty" json:"expected_codes,omitempty"`
+}
+
+// DNSRecordConfig is a DNS record managed in the Cloudflare zone.
+type DNSRecordConfig struct {
+	// Name of the record (e.g., app.example.com); a name without the domain is relative to it
+	Name string `yaml:"name" json:"name"`
+
+	// Record type: CNAME, A, AAAA, or TXT - default: CNAME, or A when the deployment URL's host is an IP address
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// Record content - default: the deployment URL's host (required for TXT)
+	Content string `yaml:"content,omitempty" json:"content,omitempty"`
+
+	// Proxy traffic through Cloudflare - default: true, except for TXT records
+	Proxied *bool `yaml:"proxied,omitempty" json:"proxied,omitempty"`
+
+	// Time to live in seconds, from 60 to 86400; 1 is automatic - default: 1
+	TTL int `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+// validate checks the zone and the records.
+func (c *CloudflareConfig) validate() error {
+	if c.ZoneID == "" && c.Domain == "" {
+		return fmt.Errorf("cloudflare.zone_id or cloudflare.domain is required")
+	}
+	for i, r := range c.DNSRecords {
+		if r.Name == "" {
+			return fmt.Errorf("cloudflare.dns_records[%d]: name is required", i)
+		}
+		if r.Type != "" && !slices.Contains(DNSRecordTypes, r.Type) {
+			return fmt.Errorf("cloudflare.dns_records[%d] (%s): type must be one of %s, got %q", i, r.Name, strings.Join(DNSRecordTypes, ", "), r.Type)
+		}
+		if r.Type == "TXT" {
+			if r.Content == "" {
+				return fmt.Errorf("cloudflare.dns_records[%d] (%s): content is required for TXT records", i, r.Name)
+			}
+			if r.Proxied != nil && *r.Proxied {
+				return fmt.Errorf("cloudflare.dns_records[%d] (%s): TXT records cannot be proxied", i, r.Name)
+			}
+		}
+		if r.TTL != 0 && r.TTL != 1 && (r.TTL < 60 || r.TTL > 86400) {
+			return fmt.Errorf("cloudflare.dns_records[%d] (%s): ttl must be 1 (automatic) or from 60 to 86400, got %d", i, r.Name, r.TTL)
+		}
+	}
+	return c.validateLoadBalancer()
+}
+
+// validateLoadBalancer checks the load balancer, its pools, and its monitors.
+func (c *CloudflareConfig) validateLoadBalancer() error {
+	if c.LoadBalancer == nil {
+		if len(c.Pools) > 0 || len(c.Monitors) > 0 {
+			return fmt.Errorf("cloudflare.pools and cloudflare.monitors require cloudflare.load_balancer")
+		}
+		return nil
+	}
+
+	lb := c.LoadBalancer
+	if lb.Name == "" {
+		return fmt.Errorf("cloudflare.load_balancer.name is required")
+	}
+	if lb.SteeringPolicy != "" && !slices.Contains(SteeringPolicies, lb.SteeringPolicy) {
+		return fmt.Errorf("cloudflare.load_balancer.steering_policy must be one of %s, got %q", strings.Join(SteeringPolicies, ", "), lb.SteeringPolicy)
+	}
+	if lb.TTL < 0 {
+		return fmt.Errorf("cloudflare.load_balancer.ttl must not be negative, got %d", lb.TTL)
+	}
+
+	monitors := make(map[string]bool, len(c.Monitors))
+	for i, mon := range c.Monitors {
+		if mon.Name == "" {
+			return fmt.Errorf("cloudflare.monitors[%d]: name is required", i)
+		}
+		if monitors[mon.Name] {
+			return fmt.Errorf("cloudflare.monitors[%d]: duplicate name %q", i, mon.Name)
+		}
+		monitors[mon.Name] = true
+		if mon.Type != "" && !slices.Contains(MonitorTypes, mon.Type) {
+			return fmt.Errorf("cloudflare.monitors[%d] (%s): type must be one of %s, got %q", i, mon.Name, strings.Join(MonitorTypes, ", "), mon.Type)
+		}
+		if mon.Port < 0 || mon.Port > 65535 {
+			return fmt.Errorf("cloudflare.monitors[%d] (%s): port must be from 1 to 65535, got %d", i, mon.Name, mon.Port)
+		}
+		if mon.Interval < 0 || mon.Retries < 0 || mon.Timeout < 0 {
+			return fmt.Errorf("cloudflare.monitors[%d] (%s): interval, retries, and timeout must not be negative", i, mon.Name)
+		}
+	}
+
+	pools := make(map[string]bool, len(c.Pools))
+	for i, pool := range c.Pools {
+		if pool.Name == "" {
+			return fmt.Errorf("cloudflare.pools[%d]: name is required", i)
+		}
+		if !poolNamePattern.MatchString(pool.Name) {
+			return fmt.Errorf("cloudflare.pools[%d]: name %q may only contain letters, digits, hyphens, and underscores", i, pool.Name)
+		}
+		if pools[pool.Name] {
+			return fmt.Errorf("cloudflare.pools[%d]: duplicate name %q", i, pool.Name)
+		}
+		pools[pool.Name] = true
+		if pool.Provider == "" && len(pool.Origins) == 0 {
+			return fmt.Errorf("cloudflare.pools[%d] (%s): provider or origins is required", i, pool.Name)
+		}
+		if pool.Monitor != "" && !monitors[pool.Monitor] {
+			return fmt.Errorf("cloudflare.pools[%d] (%s): monitor %q is not in cloudflare.monitors", i, pool.Name, pool.Monitor)
+		}
+		for j, origin := range pool.Origins {
+			if origin.Name == "" || origin.Address == "" {
+				return fmt.Errorf("cloudflare.pools[%d].origins[%d]: name and address are required", i, j)
+			}
+			if origin.Weight != nil && (*origin.Weight < 0 || *origin.Weight > 1) {
+				return fmt.Errorf("cloudflare.pools[%d].origins[%d] (%s): weight must be from 0 to 1, got %g", i, j, origin.Name, *origin.Weight)
+			}
+		}
+	}
+	for region, names := range lb.RegionPools {
+		for _, name := range names {
+			if len(c.Pools) > 0 && !pools[name] {
+				return fmt.Errorf("cloudflare.load_balancer.region_pools[%s]: pool %q is not in cloudflare.pools", region, name)
+			}
+		}
+	}
+	return nil
+}
+
+// poolNamePattern matches the names Cloudflare accepts for pools.
+var poolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DefaultMaintenanceMessage is the page served in maintenance mode when
+// maintenance.message is not set.
+const DefaultMaintenanceMessage = "<!DOCTYPE html><html><head><title>Down for maintenance</title></head>" +
+	"<body><h1>Down for maintenance</h1><p>We'll be back shortly.</p></body></html>"
+
+// maxMaintenanceMessage is the largest body a load balancer's fixed response
+// can hold.
+const maxMaintenanceMessage = 1024
+
+// MaintenanceConfig specifies the response the maintenance command serves in
+// place of the application while its traffic is paused.
+type MaintenanceConfig struct {
+	// Body of the response, HTML or plain text, up to 1024 bytes - default: a short "Down for maintenance" page
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+
+	// HTTP status code of the response (2xx, 4xx, or 5xx) - default: 503
+	StatusCode int `yaml:"status_code,omitempty" json:"status_code,omitempty"`
+
+	// Image serving the maintenance page on Cloud Run, listening on $PORT; it
+	// gets the message and status code as MAINTENANCE_MESSAGE and
+	// MAINTENANCE_STATUS_CODE - required for gcp
+	Image string `yaml:"image,omitempty" json:"image,omitempty"`
+}
+
+// Response returns the body and status code served in maintenance mode, with
+// defaults for unset fields. A nil config returns the defaults.
+func (c *MaintenanceConfig) Response() (string, int) {
+	message, status := DefaultMaintenanceMessage, 503
+	if c != nil && c.Message != "" {
+		message = c.Message
+	}
+	if c != nil && c.StatusCode != 0 {
+		status = c.StatusCode
+	}
+	return message, status
+}
+
+// ContentType returns the media type of the maintenance response: HTML when
+// the message starts with markup, as the default page does, otherwise plain
+// text.
+func (c *MaintenanceConfig) ContentType() string {
+	message, _ := c.Response()
+	if strings.HasPrefix(strings.TrimSpace(message), "<") {
+		return "text/html; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// CloudRunImage returns the image serving the maintenance page on Cloud
+// Run, or "" if none is set. It is safe to call on a nil config.
+func (c *MaintenanceConfig) CloudRunImage() string {
+	if c == nil {
+		return ""
+	}
+	return c.Image
+}
+
+// validate checks the size of the message and the status code.
+func (c *MaintenanceConfig) validate() error {
+	if len(c.Message) > maxMaintenanceMessage {
+		return fmt.Errorf("maintenance.message must be at most %d bytes, got %d", maxMaintenanceMessage, len(c.Message))
+	}
+	if c.StatusCode != 0 && (c.StatusCode < 200 || c.StatusCode > 599 || c.StatusCode/100 == 3) {
+		return fmt.Errorf("maintenance.status_code must be a 2xx, 4xx, or 5xx code, got %d", c.StatusCode)
+	}
+	return nil
+}
+
+// AWSConfig specifies AWS Elastic Beanstalk-specific configuration.
+type AWSConfig struct {
+	// Manage ancillary resources (S3 bucket, ECR repository, instance role) through a
+	// per-application CloudFormation stack so they are visible, drift-detectable,
+	// and deleted together on destroy (default: false)
+	CloudFormation bool `yaml:"cloudformation,omitempty" json:"cloudformation,omitempty"`
+}
+
+// HealthCheckConfig defines how the cloud provider should check application health.
+type HealthCheckConfig struct {
+	// Type of health check (basic or enhanced)
+	Type string `yaml:"type" json:"type,omitempty"`
+
+	// Path to health check endpoint (e.g., /health, /api/status)
+	Path string `yaml:"path" json:"path,omitempty"`
+}
+
+// Dependency is an external service the application depends on, such as its
+// database or a third-party API. An http or https URL is healthy when a GET
+// request returns a 2xx or 3xx status; for any other scheme (e.g., postgres,
+// redis, tcp) a TCP connection is opened to its host and port.
+type Dependency struct {
+	// Name of the dependency (e.g., "database")
+	Name string `yaml:"name" json:"name"`
+
+	// URL to check - required unless env is set
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Environment variable or secret holding the URL to check, such as DATABASE_URL - optional
+	Env string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Seconds to wait for the check (1 to 60) - default: 5
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+}
+
+// DefaultDependencyTimeout is the default timeout of a dependency check, in seconds.
+const DefaultDependencyTimeout = 5
+
+// defaultPorts are the ports of URL schemes whose URLs often leave the port out.
+var defaultPorts = map[string]string{
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"mysql":      "3306",
+	"redis":      "6379",
+	"rediss":     "6380",
+	"mongodb":    "27017",
+	"amqp":       "5672",
+	"amqps":      "5671",
+}
+
+// Timeout returns how long to wait for the dependency's check.
+func (d Dependency) Timeout() time.Duration {
+	if d.TimeoutSeconds == 0 {
+		return DefaultDependencyTimeout * time.Second
+	}
+	return time.Duration(d.TimeoutSeconds) * time.Second
+}
+
+// Address returns the host and port to connect to for a URL of a scheme other
+// than http and https.
+func (d Dependency) Address(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("URL has no host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[u.Scheme]
+	}
+	if port == "" {
+		return "", fmt.Errorf("URL has no port, which %s URLs need", u.Scheme)
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// validate checks a dependency, reporting problems under field.
+func (d Dependency) validate(field string) error {
+	if d.Name == "" {
+		return fmt.Errorf("%s.name is required", field)
+	}
+	if (d.URL == "") == (d.Env == "") {
+		return fmt.Errorf("%s (%s) must set exactly one of url and env", field, d.Name)
+	}
+	if d.TimeoutSeconds < 0 || d.TimeoutSeconds > 60 {
+		return fmt.Errorf("%s (%s): timeout_seconds must be from 1 to 60", field, d.Name)
+	}
+	if d.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return fmt.Errorf("%s (%s): invalid url: %w", field, d.Name, err)
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		if u.Host == "" {
+			return fmt.Errorf("%s (%s): url has no host", field, d.Name)
+		}
+		return nil
+	}
+	if _, err := d.Address(d.URL); err != nil {
+		return fmt.Errorf("%s (%s): %w", field, d.Name, err)
+	}
+	return nil
+}
+
+// MonitoringConfig defines monitoring and metrics collection settings.
+type MonitoringConfig struct {
+	// Enable enhanced health reporting (default: false)
+	// Enhanced health provides detailed metrics like ApplicationRequests2xx, latency, etc.
+	EnhancedHealth bool `yaml:"enhanced_health,omitempty" json:"enhanced_health,omitempty"`
+
+	// Enable CloudWatch custom metrics collection (default: false)
+	// This enables application-level metrics beyond basic EC2 metrics
+	CloudWatchMetrics bool `yaml:"cloudwatch_metrics,omitempty" json:"cloudwatch_metrics,omitempty"`
+
+	// CloudWatch Logs configuration (optional)
+	CloudWatchLogs *CloudWatchLogsConfig `yaml:"cloudwatch_logs,omitempty" json:"cloudwatch_logs,omitempty"`
+}
+
+// CloudWatchLogsConfig defines CloudWatch Logs streaming settings.
+type CloudWatchLogsConfig struct {
+	// Enable streaming logs to CloudWatch (default: false)
+	Enabled bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+
+	// Log retention in days (1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 3653)
+	RetentionDays int `yaml:"retention_days,omitempty" json:"retention_days,omitempty"`
+
+	// Stream application logs (default: true if enabled)
+	StreamLogs bool `yaml:"stream_logs,omitempty" json:"stream_logs,omitempty"`
+}
+
+// IAMConfig specifies IAM roles and profiles to use.
+// This allows the application to access other cloud resources securely.
+type IAMConfig struct {
+	// Instance profile for EC2/compute instances - optional
+	InstanceProfile string `yaml:"instance_profile,omitempty" json:"instance_profile,omitempty"`
+
+	// Service role for the cloud service - optional
+	ServiceRole string `yaml:"service_role,omitempty" json:"service_role,omitempty"`
+}
+
+// SSLConfig defines SSL/TLS certificate configuration.
+type SSLConfig struct {
+	// CertificateArn is the AWS ACM certificate ARN for HTTPS (AWS only)
+	CertificateArn string `yaml:"certificate_arn,omitempty" json:"certificate_arn,omitempty"`
+}
+
+// ExportConfig records the resources cloud-deploy creates as infrastructure-as-code
+// so platform teams can adopt them into their own pipelines.
+type ExportConfig struct {
+	// Format of the export:
+	// - "config-connector": Config Connector YAML (GCP)
+	// - "terraform": Terraform import blocks (GCP)
+	// - "bicep": Bicep template (Azure)
+	Format string `yaml:"format" json:"format"`
+
+	// Path to write the export to after each successful deployment - optional
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+}
+
+// SecretRef maps an environment variable to a value held in a secret store.
+type SecretRef struct {
+	// Name of the environment variable to set
+	Name string `yaml:"name" json:"name"`
+
+	// Source of the secret - default: "vault" with VaultPath, otherwise "secrets-manager"
+	// - "secrets-manager": AWS Secrets Manager
+	// - "gcp-secret-manager": Google Secret Manager
+	// - "azure-key-vault": Azure Key Vault
+	// - "vault": HashiCorp Vault
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+
+	// SecretID is the secret's name or ARN (Secrets Manager), its name or
+	// resource name, projects/<project>/secrets/<name> (Secret Manager), or its
+	// name or URL, https://<vault>.vault.azure.net/secrets/<name> (Key Vault)
+	SecretID string `yaml:"secret_id" json:"secret_id"`
+
+	// Key selects a field of a JSON secret - optional (default: the whole secret value)
+	Key string `yaml:"key,omitempty" json:"key,omitempty"`
+
+	// Region of the secret store - optional (default: provider region)
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+
+	// GCP-specific: Project of the secret - optional (default: provider.project_id)
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
+
+	// Version of the secret in Secret Manager or Key Vault - optional (default: the latest version)
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+
+	// Azure-specific: URI of the Key Vault holding the secret, e.g. https://my-vault.vault.azure.net
+	// (required for azure-key-vault secrets unless secret_id is a URL)
+	KeyVaultURI string `yaml:"key_vault_uri,omitempty" json:"key_vault_uri,omitempty"`
+
+	// Vault-specific: API path of the secret, e.g. secret/data/myapp/db (required for vault secrets)
+	VaultPath string `yaml:"vault_path,omitempty" json:"vault_path,omitempty"`
+
+	// Vault-specific: Field of the secret to read - optional (default: all fields, as a JSON object)
+	VaultKey string `yaml:"vault_key,omitempty" json:"vault_key,omitempty"`
+}
+
+// Secret sources.
+const (
+	SecretSourceSecretsManager   = "secrets-manager"
+	SecretSourceGCPSecretManager = "gcp-secret-manager"
+	SecretSourceAzureKeyVault    = "azure-key-vault"
+	SecretSourceVault            = "vault"
+)
+
+// SecretSource returns the source of the secret: source if it is set,
+// otherwise vault for secrets with vault_path and secrets-manager for others.
+func (r SecretRef) SecretSource() string {
+	switch {
+	case r.Source != "":
+		return r.Source
+	case r.VaultPath != "":
+		return SecretSourceVault
+	default:
+		return SecretSourceSecretsManager
+	}
+}
+
+// GCPSecretName returns the resource name of a gcp-secret-manager secret:
+// secret_id if it is a resource name, otherwise the secret in project, or in
+// defaultProject if project is not set. It returns "" without a project.
+func (r SecretRef) GCPSecretName(defaultProject string) string {
+	project := r.Project
+	if project == "" {
+		project = defaultProject
+	}
+	return gcpSecretName(project, r.SecretID)
+}
+
+// gcpSecretName returns the resource name of the Secret Manager secret
+// secretID, a resource name or a name in project, or "" without a project.
+func gcpSecretName(project, secretID string) string {
+	if strings.HasPrefix(secretID, "projects/") {
+		return secretID
+	}
+	if project == "" {
+		return ""
+	}
+	return "projects/" + project + "/secrets/" + secretID
+}
+
+// KeyVaultSecretURL returns the URL of an azure-key-vault secret: secret_id if
+// it is a URL, otherwise the secret in key_vault_uri. It returns "" without a
+// vault.
+func (r SecretRef) KeyVaultSecretURL() string {
+	return keyVaultSecretURL(r.KeyVaultURI, r.SecretID)
+}
+
+// keyVaultSecretURL returns the URL of the Key Vault secret secretID, a URL or
+// a name in the vault vaultURI, or "" without a vault.
+func keyVaultSecretURL(vaultURI, secretID string) string {
+	if strings.HasPrefix(secretID, "https://") {
+		return secretID
+	}
+	if vaultURI == "" {
+		return ""
+	}
+	return strings.TrimRight(vaultURI, "/") + "/secrets/" + secretID
+}
+
+// Secret deliveries.
+const (
+	SecretDeliveryEnvironment = "environment"
+	SecretDeliveryNative      = "native"
+)
+
+// nativeSecretProviders reference secrets from the service with
+// secret_delivery native: Elastic Beanstalk environment secrets, Cloud Run
+// Secret Manager variables, and ACI secure values. Container Apps,
+// App Platform, and Fly.io always store secrets as their own.
+var nativeSecretProviders = map[string]bool{
+	"aws":                  true,
+	"gcp":                  true,
+	"azure":                true,
+	"azure-container-apps": true,
+	"digitalocean":         true,
+	"fly":                  true,
+}
+
+// validateSecretDelivery checks secret_delivery against the manifest's providers.
+func (m *Manifest) validateSecretDelivery(errs *ValidationErrors) {
+	switch m.SecretDelivery {
+	case "", SecretDeliveryEnvironment:
+		return
+	case SecretDeliveryNative:
+	default:
+		errs.addf("secret_delivery", "secret_delivery must be %s or %s, got %q", SecretDeliveryEnvironment, SecretDeliveryNative, m.SecretDelivery)
+		return
+	}
+	for _, target := range m.Targets() {
+		name := target.Provider.Name
+		if !nativeSecretProviders[name] {
+			errs.addf("secret_delivery", "secret_delivery %s is not supported by the %s provider", SecretDeliveryNative, name)
+			continue
+		}
+		if name != "aws" {
+			continue
+		}
+		// Elastic Beanstalk sets the whole secret, so it cannot select a field
+		for i, secret := range m.Secrets {
+			if secret.Key != "" {
+				errs.addf(fmt.Sprintf("secrets[%d].key", i), "secrets[%d] (%s): key cannot be used with secret_delivery %s on aws, which sets the whole secret; store the value in its own secret", i, secret.Name, SecretDeliveryNative)
+			}
+			if source := secret.SecretSource(); source != SecretSourceSecretsManager {
+				errs.addf(fmt.Sprintf("secrets[%d].source", i), "secrets[%d] (%s): secret_delivery %s on aws reads secrets from %s, not %s", i, secret.Name, SecretDeliveryNative, SecretSourceSecretsManager, source)
+			}
+		}
+	}
+}
+
+// NativeSecrets returns true if the manifest's secrets are stored as the
+// provider's secrets rather than set as plain environment variables.
+func (m *Manifest) NativeSecrets() bool {
+	return m.SecretDelivery == SecretDeliveryNative && len(m.Secrets) > 0
+}
+
+// IsSecret returns true if the environment variable name is set from secrets.
+func (m *Manifest) IsSecret(name string) bool {
+	return slices.ContainsFunc(m.Secrets, func(s SecretRef) bool { return s.Name == name })
+}
+
+// SecurityConfig specifies container hardening options. Options a provider cannot
+// enforce are reported as warnings during deployment.
+type SecurityConfig struct {
+	// ReadOnlyRootFilesystem mounts the container's root filesystem read-only - optional
+	ReadOnlyRootFilesystem bool `yaml:"read_only_root_filesystem,omitempty" json:"read_only_root_filesystem,omitempty"`
+
+	// RunAsNonRoot fails the deployment if a container would run as root - optional
+	RunAsNonRoot bool `yaml:"run_as_non_root,omitempty" json:"run_as_non_root,omitempty"`
+
+	// User to run container processes as (e.g., "1000" or "1000:1000") - optional
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+
+	// DropCapabilities lists Linux capabilities to drop (e.g., ["ALL"] or ["NET_RAW"]) - optional
+	DropCapabilities []string `yaml:"drop_capabilities,omitempty" json:"drop_capabilities,omitempty"`
+}
+
+// IsRootUser reports whether a container user specification ("user", "uid" or "user:group")
+// refers to root.
+func IsRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "root" || name == "0"
+}
+
+// Load reads a manifest, parses it, and validates it. The manifest is read from
+// a file, from stdin when filename is "-", or from an https://, s3://, or gs://
+// URL (see Fetchers).
+// Returns an error if the manifest cannot be read, is invalid YAML, or fails validation.
+//
+// Example:
+//
+//	manifest, err := manifest.Load("deploy-manifest.yaml")
+//	if err != nil {
+//	  log.Fatal(err)
+//	}
+func Load(filename string) (*Manifest, error) {
+	return LoadVerified(filename, nil)
+}
+
+// Verifier checks the contents of a manifest read from location before it is
+// parsed, returning an error to refuse the manifest.
+type Verifier func(location string, data []byte) error
+
+// Options select how a manifest is loaded.
+type Options struct {
+	// Verify checks the manifest's contents before they are parsed; nil accepts every manifest
+	Verify Verifier
+
+	// Environment selects the entry of the manifest's environments to overlay on the rest of it;
+	// required when the manifest has environments
+	Environment string
+
+	// Variables are values of the manifest's variables, overriding their defaults in variables
+	Variables map[string]string
+
+	// Lenient ignores settings no field of the manifest decodes, instead of refusing the manifest
+	Lenient bool
+}
+
+// LoadVerified is Load, but refuses the manifest unless verify accepts its
+// contents. The contents verified are the contents parsed, so a remote manifest
+// cannot change between the two. A nil verify accepts every manifest.
+func LoadVerified(filename string, verify Verifier) (*Manifest, error) {
+	return LoadWith(filename, Options{Verify: verify})
+}
+
+// LoadWith is Load with the options in opts: the verification of LoadVerified,
+// the environment to select from the manifest's environments, and values of the
+// manifest's variables. The fragments the manifest includes are merged first,
+// then the selected entry of environments is deep-merged over the result, and
+// variables are substituted last, so fragments and entries can set them too.
+// Unless it names the environment, the environment is named after the base's
+// name and the entry's (e.g., my-app-staging), and its class defaults to the
+// entry's name. Unknown settings are refused unless opts.Lenient is set.
+func LoadWith(filename string, opts Options) (*Manifest, error) {
+	data, baseDir, err := read(filename)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Verify != nil {
+		if err := opts.Verify(filename, data); err != nil {
+			return nil, err
+		}
+	}
+
+	// Expand environment variables in the YAML content, leaving references to
+	// manifest variables until the environment is selected
+	expanded := expandEnv(string(data))
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	positions := newPositions(&doc, filename)
+	if err := applyIncludes(&doc, filename, opts.Verify, nil, positions); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if !opts.Lenient {
+		if err := unknownFields(&doc, positions); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+	}
+	named, err := applyEnvironment(&doc, opts.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := applyVariables(&doc, opts.Variables); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	var manifest Manifest
+	if doc.Kind != 0 {
+		if err := doc.Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+	}
+	if opts.Environment != "" {
+		if !named {
+			manifest.Environment.Name = strings.Trim(manifest.Environment.Name+"-"+opts.Environment, "-")
+		}
+		if manifest.Environment.Class == "" {
+			manifest.Environment.Class = opts.Environment
+		}
+	}
+
+	if err := manifest.loadEnvironmentFiles(baseDir); err != nil {
+		return nil, err
+	}
+
+	// Sources to build are relative to the manifest, like environment files
+	if source := &manifest.Deployment.Source; source.Build != nil && source.Path != "" && !filepath.IsAbs(source.Path) {
+		source.Path = filepath.Join(baseDir, source.Path)
+	}
+
+	if err := manifest.renderNames(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		positions.locate(err)
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// Validate checks if the manifest has all required fields and valid values.
+// Returns ValidationErrors describing everything that is invalid.
+func (m *Manifest) Validate() error {
+	var errs ValidationErrors
+
+	// Validate container configuration (single or multi-container). Images
+	// built from source are named when they are built.
+	errs.add("deployment.source", m.Deployment.Source.validate())
+	errs.add("deployment", m.Deployment.validate())
+	building := m.Deployment.Source.Build != nil
+	if building && len(m.Containers) > 0 {
+		errs.addf("deployment.source.build", "deployment.source.build only builds single-container deployments - build the images of 'containers' before deploying")
+	}
+	if m.Image == "" && len(m.Containers) == 0 && !building && !m.IsMultiService() {
+		errs.addf("image", "either 'image' (single-container) or 'containers' (multi-container) is required")
+	}
+	if m.Image != "" && len(m.Containers) > 0 {
+		errs.addf("containers", "cannot specify both 'image' and 'containers' - use one or the other")
+	}
+
+	m.validateServices(&errs)
+	m.validateSidecars(&errs)
+
 	// Validate multi-container configuration
 	if len(m.Containers) > 0 {
 		containerNames := make(map[string]bool)
 		for i, container := range m.Containers {
+			field := fmt.Sprintf("containers[%d]", i)
 			if container.Name == "" {
-				return fmt.Errorf("container[%d]: name is required", i)
+				errs.addf(field+".name", "container[%d]: name is required", i)
+				continue
 			}
 			if container.Image == "" {
-				return fmt.Errorf("container[%d] (%s): image is required", i, container.Name)
+				errs.addf(field+".image", "container[%d] (%s): image is required", i, container.Name)
 			}
 			// Check for duplicate names
 			if containerNames[container.Name] {
-				return fmt.Errorf("duplicate container name: %s", container.Name)
+				errs.addf(field+".name", "duplicate container name: %s", container.Name)
 			}
 			containerNames[container.Name] = true
 		}
 	}
 
-	if m.Provider.Name == "" {
-		return fmt.Errorf("provider name is required")
+	switch m.ArtifactType {
+	case "", ArtifactContainer, ArtifactWasm:
+	default:
+		errs.addf("artifact_type", "artifact_type must be %s or %s, got %q", ArtifactContainer, ArtifactWasm, m.ArtifactType)
+	}
+
+	m.validatePlatforms(&errs)
+
+	if len(m.Providers) > 0 {
+		if m.Provider.Name != "" {
+			errs.addf("provider", "cannot specify both 'provider' and 'providers' - use one or the other")
+		}
+		names := make(map[string]bool)
+		for i := range m.Providers {
+			p := &m.Providers[i]
+			field := fmt.Sprintf("providers[%d]", i)
+			if p.Name == "" {
+				errs.addf(field+".name", "providers[%d]: name is required", i)
+				continue
+			}
+			if names[p.Name] {
+				errs.addf(field+".name", "duplicate provider: %s", p.Name)
+			}
+			names[p.Name] = true
+			errs.add(field, validateProvider(p, field))
+		}
+	} else {
+		if m.Provider.Name == "" {
+			errs.addf("provider.name", "provider name is required")
+		} else {
+			errs.add("provider", validateProvider(&m.Provider, "provider"))
+		}
+	}
+
+	for _, target := range m.Targets() {
+		if target.Provider.Name == "oci" && (m.OCI == nil || m.OCI.SubnetID == "") {
+			errs.addf("oci.subnet_id", "oci.subnet_id is required for OCI deployments")
+		}
+		if (target.Provider.Name == "kubernetes" || target.Provider.Name == "gcp-gke") && target.Environment.Name != "" && (len(target.Environment.Name) > 63 || !dnsLabel.MatchString(target.Environment.Name)) {
+			errs.addf("environment.name", "environment name %q must be a lowercase DNS label (letters, digits, and '-') to name Kubernetes resources", target.Environment.Name)
+		}
+		if target.Provider.Name == "gcp-gke" {
+			errs.add("gke", m.validateGKE())
+		}
+		if target.Provider.Name == "digitalocean" {
+			errs.add("digitalocean", m.validateDigitalOcean(target.Provider.Region))
+		}
+		if target.Provider.Name == "fly" {
+			errs.add("fly", m.validateFly(target.Provider.Region))
+		}
+		if target.Provider.Name == "aws-lambda" {
+			if m.IsMultiContainer() {
+				errs.addf("containers", "aws-lambda runs a single image; use image instead of containers")
+			}
+			if m.Environment.Name != "" && !lambdaFunctionName.MatchString(m.Environment.Name) {
+				errs.addf("environment.name", "environment name %q must be at most 64 letters, digits, '-', and '_' to name a Lambda function", m.Environment.Name)
+			}
+		}
+		if target.Provider.Name == "aws-apprunner" {
+			if m.IsMultiContainer() {
+				errs.addf("containers", "aws-apprunner runs a single image; use image instead of containers")
+			}
+			if m.Environment.Name != "" && !appRunnerServiceName.MatchString(m.Environment.Name) {
+				errs.addf("environment.name", "environment name %q must be 4 to 40 letters, digits, '-', and '_', starting with a letter or digit, to name an App Runner service", m.Environment.Name)
+			}
+		}
+		if target.Provider.Name == "azure-container-apps" && m.Environment.Name != "" && !containerAppName.MatchString(m.Environment.Name) {
+			errs.addf("environment.name", "environment name %q must be 2 to 32 lowercase letters, digits, and '-', starting with a letter and ending with a letter or digit, to name a container app", m.Environment.Name)
+		}
+		if target.Provider.Name == "azure-app-service" {
+			if m.IsMultiContainer() {
+				errs.addf("containers", "azure-app-service runs a single image; use image instead of containers")
+			}
+			if m.Environment.Name != "" && !appServiceName.MatchString(m.Environment.Name) {
+				errs.addf("environment.name", "environment name %q must be 2 to 60 letters, digits, and '-', starting and ending with a letter or digit, to name an App Service app", m.Environment.Name)
+			}
+			errs.add("app_service", m.AppService.validate(m.Instance))
+		}
+		if m.Wasm() && !wasmProviders[target.Provider.Name] {
+			errs.addf("artifact_type", "artifact_type %s is not supported by the %s provider, which runs only container images; WebAssembly workloads can be deployed with kubernetes", ArtifactWasm, target.Provider.Name)
+		}
+		if m.Deployment.IsBlueGreen() && !blueGreenProviders[target.Provider.Name] {
+			errs.addf("deployment.strategy", "deployment.strategy %s is not supported by the %s provider", StrategyBlueGreen, target.Provider.Name)
+		}
+		if m.Deployment.IsCanary() && !canaryProviders[target.Provider.Name] {
+			errs.addf("deployment.strategy", "deployment.strategy %s is not supported by the %s provider", StrategyCanary, target.Provider.Name)
+		}
+		if building && m.Deployment.Source.Build.RemoteBuild() && target.Provider.Name != "aws" {
+			errs.addf("deployment.source.build.strategy", "deployment.source.build.strategy %s only builds for aws, not %s", BuildStrategyCodeBuild, target.Provider.Name)
+		}
+	}
+
+	errs.add("instance", m.Instance.validate())
+	if m.CloudRun != nil {
+		errs.add("cloud_run", m.CloudRun.validate())
+	}
+
+	if m.Kubernetes != nil {
+		errs.add("kubernetes", m.Kubernetes.validate())
+	}
+
+	if m.ECS != nil {
+		errs.add("ecs", m.ECS.validate())
+	}
+	if m.Lambda != nil {
+		errs.add("lambda", m.Lambda.validate())
+	}
+	if m.AppRunner != nil {
+		errs.add("app_runner", m.AppRunner.validate())
+	}
+	if m.ContainerApps != nil {
+		errs.add("container_apps", m.ContainerApps.validate())
+	}
+
+	if m.Mock != nil {
+		errs.add("mock", m.Mock.validate())
+	}
+
+	if m.State != nil {
+		errs.add("state", m.State.validate())
+	}
+
+	if m.Cloudflare != nil {
+		errs.add("cloudflare", m.Cloudflare.validate())
+		if m.IsMultiProvider() && len(m.Cloudflare.DNSRecords) > 0 {
+			errs.addf("cloudflare.dns_records", "cloudflare.dns_records point at a single deployment and cannot be used with multiple providers; use cloudflare.load_balancer")
+		}
+		for i, pool := range m.Cloudflare.Pools {
+			if m.IsMultiProvider() && pool.Provider != "" && !m.deploysTo(pool.Provider) {
+				errs.addf(fmt.Sprintf("cloudflare.pools[%d].provider", i), "cloudflare.pools[%d] (%s): the manifest does not deploy to provider %s", i, pool.Name, pool.Provider)
+			}
+		}
+	}
+
+	if m.Maintenance != nil {
+		errs.add("maintenance", m.Maintenance.validate())
 	}
+
 	if m.Application.Name == "" {
-		return fmt.Errorf("application name is required")
+		errs.addf("application.name", "application name is required")
 	}
-	if m.Environment.Name == "" {
-		return fmt.Errorf("environment name is required")
+	if m.Environment.Name == "" && m.Environment.NameTemplate == "" {
+		errs.addf("environment.name", "environment name is required")
+	}
+	if m.Deployment.IsBlueGreen() {
+		if m.Environment.CName == "" {
+			errs.addf("environment.cname", "deployment.strategy %s requires environment.cname, the address swapped to the new environment", StrategyBlueGreen)
+		}
+		if len(m.Environment.Name) > MaxBlueGreenEnvironmentName {
+			errs.addf("environment.name", "environment name %q must be at most %d characters with deployment.strategy %s, which names the second environment %s", m.Environment.Name, MaxBlueGreenEnvironmentName, StrategyBlueGreen, m.Environment.Name+BlueGreenSuffix)
+		}
+	}
+
+	dependencies := make(map[string]bool, len(m.Dependencies))
+	for i, d := range m.Dependencies {
+		field := fmt.Sprintf("dependencies[%d]", i)
+		errs.add(field, d.validate(field))
+		if dependencies[d.Name] {
+			errs.addf(field+".name", "dependencies[%d]: name %q is used by another dependency", i, d.Name)
+		}
+		dependencies[d.Name] = true
+	}
+
+	if m.Verification != nil {
+		errs.add("verification", m.Verification.validate())
+	}
+
+	if m.Hooks != nil {
+		errs.add("hooks", m.Hooks.validate())
+	}
+
+	if m.Notifications != nil {
+		errs.add("notifications", m.Notifications.validate())
+	}
+
+	for i, secret := range m.Secrets {
+		field := fmt.Sprintf("secrets[%d]", i)
+		if secret.Name == "" {
+			errs.addf(field+".name", "secrets[%d]: name is required", i)
+			continue
+		}
+		if secret.SecretSource() == SecretSourceVault {
+			if secret.VaultPath == "" {
+				errs.addf(field+".vault_path", "secrets[%d] (%s): vault_path is required for vault secrets", i, secret.Name)
+			}
+			if secret.SecretID != "" {
+				errs.addf(field+".secret_id", "secrets[%d] (%s): vault secrets are read from vault_path, not secret_id", i, secret.Name)
+			}
+			continue
+		}
+		if secret.VaultPath != "" || secret.VaultKey != "" {
+			errs.addf(field, "secrets[%d] (%s): vault_path and vault_key require source %s", i, secret.Name, SecretSourceVault)
+		}
+		if secret.SecretID == "" {
+			errs.addf(field+".secret_id", "secrets[%d] (%s): secret_id is required", i, secret.Name)
+		}
+		if secret.Project != "" && secret.Source != SecretSourceGCPSecretManager {
+			errs.addf(field+".project", "secrets[%d] (%s): project requires source %s", i, secret.Name, SecretSourceGCPSecretManager)
+		}
+		if secret.KeyVaultURI != "" && secret.Source != SecretSourceAzureKeyVault {
+			errs.addf(field+".key_vault_uri", "secrets[%d] (%s): key_vault_uri requires source %s", i, secret.Name, SecretSourceAzureKeyVault)
+		}
+		switch secret.SecretSource() {
+		case SecretSourceSecretsManager:
+			if secret.Version != "" {
+				errs.addf(field+".version", "secrets[%d] (%s): version requires source %s or %s", i, secret.Name, SecretSourceGCPSecretManager, SecretSourceAzureKeyVault)
+			}
+		case SecretSourceGCPSecretManager:
+			if secret.SecretID != "" && secret.GCPSecretName(m.Provider.ProjectID) == "" {
+				errs.addf(field+".project", "secrets[%d] (%s): project is required for %s secrets unless secret_id is projects/<project>/secrets/<name>", i, secret.Name, SecretSourceGCPSecretManager)
+			}
+		case SecretSourceAzureKeyVault:
+			if secret.SecretID != "" && secret.KeyVaultSecretURL() == "" {
+				errs.addf(field+".key_vault_uri", "secrets[%d] (%s): key_vault_uri is required for %s secrets unless secret_id is a secret URL", i, secret.Name, SecretSourceAzureKeyVault)
+			}
+		default:
+			errs.addf(field+".source", "secrets[%d] (%s): source must be %s, %s, %s, or %s, got %q", i, secret.Name, SecretSourceSecretsManager, SecretSourceGCPSecretManager, SecretSourceAzureKeyVault, SecretSourceVault, secret.Source)
+		}
+	}
+	m.validateVault(&errs)
+	m.validateRegistries(&errs)
+	m.validateSecretDelivery(&errs)
+
+	switch m.Environment.CNameConflict {
+	case "", "fail", "suffix", "prompt":
+	default:
+		errs.addf("environment.cname_conflict", "environment.cname_conflict must be fail, suffix or prompt, got %q", m.Environment.CNameConflict)
+	}
+
+	if m.Security != nil && m.Security.RunAsNonRoot && m.Security.User != "" && IsRootUser(m.Security.User) {
+		errs.addf("security.user", "security.user %q is root but security.run_as_non_root is set", m.Security.User)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateProvider checks the settings of a provider, reporting problems under field.
+func validateProvider(p *ProviderConfig, field string) error {
+	if p.Credentials != nil && (p.Credentials.Source == "secrets-manager" || p.Credentials.Source == "gcp-secret-manager" || p.Credentials.Source == "azure-key-vault") && p.Credentials.SecretID == "" {
+		return fieldError(field+".credentials.secret_id", "%s.credentials.secret_id is required when source is %s", field, p.Credentials.Source)
+	}
+	if p.Credentials != nil && p.Credentials.Source == "gcp-secret-manager" && gcpSecretName(p.ProjectID, p.Credentials.SecretID) == "" {
+		return fieldError(field+".credentials.secret_id", "%s.credentials.secret_id must be projects/<project>/secrets/<name> without %s.project_id", field, field)
+	}
+	if p.Credentials != nil && p.Credentials.Source == "azure-key-vault" && keyVaultSecretURL(p.Credentials.KeyVaultURI, p.Credentials.SecretID) == "" {
+		return fieldError(field+".credentials.key_vault_uri", "%s.credentials.key_vault_uri is required when source is azure-key-vault unless secret_id is a secret URL", field)
+	}
+	if p.Credentials != nil && p.Credentials.ParameterPath != "" && !strings.HasPrefix(p.Credentials.ParameterPath, "/") {
+		return fieldError(field+".credentials.parameter_path", "%s.credentials.parameter_path must start with /, got %q", field, p.Credentials.ParameterPath)
+	}
+	if err := validateAssumeRole(p, field); err != nil {
+		return err
+	}
+	if c := p.Credentials; c != nil && p.Cloud() != "gcp" {
+		if c.WorkloadIdentityConfigPath != "" || c.ImpersonateServiceAccount != "" || len(c.ImpersonationDelegates) > 0 || c.WorkloadIdentityProvider != "" {
+			return fieldError(field+".credentials", "%s.credentials.workload_identity_config_path, workload_identity_provider, impersonate_service_account, and impersonation_delegates are only supported for GCP providers", field)
+		}
+	}
+	if err := validateOIDC(p, field); err != nil {
+		return err
+	}
+	if c := p.Credentials; c != nil && len(c.ImpersonationDelegates) > 0 && c.ImpersonateServiceAccount == "" {
+		return fieldError(field+".credentials.impersonation_delegates", "%s.credentials.impersonation_delegates requires impersonate_service_account", field)
+	}
+	if c := p.Credentials; c != nil && c.Profile != "" {
+		if p.Cloud() != "aws" {
+			return fieldError(field+".credentials.profile", "%s.credentials.profile is only supported for AWS providers", field)
+		}
+		if c.Source == "environment" || c.FromSecretStore() || c.AccessKeyID != "" {
+			return fieldError(field+".credentials.profile", "%s.credentials.profile cannot be combined with access keys or a credentials source", field)
+		}
 	}
 
 	// GCP-specific validation
-	if m.Provider.Name == "gcp" {
-		if m.Provider.ProjectID == "" {
-			return fmt.Errorf("provider.project_id is required for GCP deployments")
+	if p.Cloud() == "gcp" {
+		if p.ProjectID == "" {
+			return fieldError(field+".project_id", "%s.project_id is required for GCP deployments", field)
 		}
 		// Check credentials
-		if m.Provider.Credentials == nil ||
-			(m.Provider.Credentials.Source != "environment" &&
-				m.Provider.Credentials.ServiceAccountKeyPath == "" &&
-				m.Provider.Credentials.ServiceAccountKeyJSON == "") {
-			return fmt.Errorf("provider.credentials.service_account_key_path, service_account_key_json, or source: environment is required for GCP deployments")
+		if p.Credentials == nil ||
+			(p.Credentials.Source != "environment" &&
+				p.Credentials.Source != "oidc" &&
+				!p.Credentials.FromSecretStore() &&
+				p.Credentials.ServiceAccountKeyPath == "" &&
+				p.Credentials.ServiceAccountKeyJSON == "" &&
+				p.Credentials.WorkloadIdentityConfigPath == "" &&
+				p.Credentials.ImpersonateServiceAccount == "") {
+			return fieldError(field+".credentials", "%s.credentials.service_account_key_path, service_account_key_json, workload_identity_config_path, impersonate_service_account, or source: environment is required for GCP deployments", field)
 		}
-		if m.Provider.BillingAccountID == "" {
-			return fmt.Errorf("provider.billing_account_id is required for GCP deployments")
+		// The gcp-gke provider deploys to an existing project
+		if p.Name == "gcp" && p.BillingAccountID == "" {
+			return fieldError(field+".billing_account_id", "%s.billing_account_id is required for GCP deployments", field)
 		}
 	}
 
 	// Azure-specific validation
-	if m.Provider.Name == "azure" {
-		if m.Provider.SubscriptionID == "" {
-			return fmt.Errorf("provider.subscription_id is required for Azure deployments")
+	if p.Cloud() == "azure" {
+		if p.SubscriptionID == "" {
+			return fieldError(field+".subscription_id", "%s.subscription_id is required for Azure deployments", field)
+		}
+		if p.ResourceGroup == "" {
+			return fieldError(field+".resource_group", "%s.resource_group is required for Azure deployments", field)
+		}
+	}
+
+	// OCI-specific validation
+	if p.Name == "oci" && p.CompartmentID == "" {
+		return fieldError(field+".compartment_id", "%s.compartment_id is required for OCI deployments", field)
+	}
+
+	return validateEmulator(p, field)
+}
+
+// validateAssumeRole checks the role AWS credentials assume.
+func validateAssumeRole(p *ProviderConfig, field string) error {
+	c := p.Credentials
+	if c == nil {
+		return nil
+	}
+	if c.RoleARN == "" {
+		if c.ExternalID != "" || c.RoleSessionName != "" || c.MFASerial != "" {
+			return fieldError(field+".credentials.role_arn", "%s.credentials.external_id, role_session_name, and mfa_serial require role_arn", field)
+		}
+		return nil
+	}
+	if p.Cloud() != "aws" {
+		return fieldError(field+".credentials.role_arn", "%s.credentials.role_arn is only supported for AWS providers", field)
+	}
+	if !strings.HasPrefix(c.RoleARN, "arn:") || !strings.Contains(c.RoleARN, ":role/") {
+		return fieldError(field+".credentials.role_arn", "%s.credentials.role_arn must be an IAM role ARN such as arn:aws:iam::123456789012:role/deployer, got %q", field, c.RoleARN)
+	}
+	return nil
+}
+
+// validateOIDC checks what CI OIDC credentials are exchanged for: an AWS
+// role, a GCP workload identity provider, or an Azure app registration.
+func validateOIDC(p *ProviderConfig, field string) error {
+	c := p.Credentials
+	if c == nil || c.Source != "oidc" {
+		if c != nil && (c.OIDCAudience != "" || c.OIDCTokenEnv != "" || c.WorkloadIdentityProvider != "") {
+			return fieldError(field+".credentials.source", "%s.credentials.oidc_audience, oidc_token_env, and workload_identity_provider require source: oidc", field)
 		}
-		if m.Provider.ResourceGroup == "" {
-			return fmt.Errorf("provider.resource_group is required for Azure deployments")
+		return nil
+	}
+	switch p.Cloud() {
+	case "aws":
+		if c.RoleARN == "" {
+			return fieldError(field+".credentials.role_arn", "%s.credentials.role_arn is required when source is oidc", field)
+		}
+		if c.MFASerial != "" || c.ExternalID != "" {
+			return fieldError(field+".credentials.role_arn", "%s.credentials.external_id and mfa_serial cannot be used when source is oidc", field)
+		}
+	case "gcp":
+		if !strings.HasPrefix(c.WorkloadIdentityProvider, "projects/") || !strings.Contains(c.WorkloadIdentityProvider, "/workloadIdentityPools/") || !strings.Contains(c.WorkloadIdentityProvider, "/providers/") {
+			return fieldError(field+".credentials.workload_identity_provider", "%s.credentials.workload_identity_provider must be projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider> when source is oidc, got %q", field, c.WorkloadIdentityProvider)
+		}
+	case "azure":
+		if c.Azure == nil || c.Azure.TenantID == "" || c.Azure.ClientID == "" {
+			return fieldError(field+".credentials.azure", "%s.credentials.azure.tenant_id and client_id are required when source is oidc", field)
 		}
+	default:
+		return fieldError(field+".credentials.source", "%s.credentials.source oidc is only supported for AWS, GCP, and Azure providers", field)
 	}
+	return nil
+}
 
+// validateEmulator checks the emulator endpoint and registry of a provider.
+func validateEmulator(p *ProviderConfig, field string) error {
+	if p.EndpointURL != "" {
+		switch p.Name {
+		case "kubernetes":
+			return fieldError(field+".endpoint_url", "%s.endpoint_url is not supported by the kubernetes provider; point the kubeconfig context at a local cluster instead", field)
+		case "mock":
+			return fieldError(field+".endpoint_url", "%s.endpoint_url is not supported by the mock provider, which makes no API requests", field)
+		}
+		if !isEndpointURL(p.EndpointURL) {
+			return fieldError(field+".endpoint_url", "%s.endpoint_url must be an http:// or https:// URL, got %q", field, p.EndpointURL)
+		}
+	}
+	if p.RegistryURL != "" && (strings.Contains(p.RegistryURL, "://") || strings.ContainsAny(p.RegistryURL, " \t") || strings.HasPrefix(p.RegistryURL, "/")) {
+		return fieldError(field+".registry_url", "%s.registry_url must be a registry host such as localhost:5000, without a scheme, got %q", field, p.RegistryURL)
+	}
 	return nil
 }
 
+// isEndpointURL reports whether s is an http:// or https:// URL with a host.
+func isEndpointURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // GetCloudCredentials retrieves cloud provider credentials based on the configured source.
-// Supports: CLI credentials (default), environment variables, or manifest.
+// Supports: CLI credentials (default), environment variables, AWS Secrets Manager, or manifest.
 //
 // Returns credentials for the specified provider using the configured source.
 func (m *Manifest) GetCloudCredentials(ctx context.Context) (*credentials.ProviderCredentials, error) {
 	// Create credentials manager
-	credMgr := &credentials.Manager{}
+	credMgr := m.CredentialsManager()
 
 	// Determine credential source
 	source := "cli" // default
@@ -429,7 +3101,37 @@ func (m *Manifest) GetCloudCredentials(ctx context.Context) (*credentials.Provid
 		// Use environment variables
 		credMgr.Source = "environment"
 		logging.Infof("📦 Loading %s credentials from environment variables...", m.Provider.Name)
-		return credMgr.GetCredentials(ctx, m.Provider.Name)
+		return credMgr.GetCredentials(ctx, m.Provider.Cloud())
+
+	case "secrets-manager":
+		// Use credentials stored in AWS Secrets Manager
+		logging.Infof("📦 Loading %s credentials from AWS Secrets Manager...", m.Provider.Name)
+		return credMgr.GetCredentials(ctx, m.Provider.Cloud())
+
+	case "parameter-store":
+		// Use credentials stored in AWS Systems Manager Parameter Store
+		logging.Infof("📦 Loading %s credentials from AWS Systems Manager Parameter Store...", m.Provider.Name)
+		return credMgr.GetCredentials(ctx, m.Provider.Cloud())
+
+	case "gcp-secret-manager":
+		// Use credentials stored in Google Secret Manager
+		logging.Infof("📦 Loading %s credentials from Google Secret Manager...", m.Provider.Name)
+		return credMgr.GetCredentials(ctx, m.Provider.Cloud())
+
+	case "azure-key-vault":
+		// Use credentials stored in Azure Key Vault
+		logging.Infof("📦 Loading %s credentials from Azure Key Vault...", m.Provider.Name)
+		return credMgr.GetCredentials(ctx, m.Provider.Cloud())
+
+	case "vault":
+		// Use credentials stored in HashiCorp Vault
+		logging.Infof("📦 Loading %s credentials from Vault...", m.Provider.Name)
+		return credMgr.GetCredentials(ctx, m.Provider.Cloud())
+
+	case "oidc":
+		// The CI job's OIDC token is exchanged for credentials by the provider
+		logging.Infof("📦 Using %s credentials from the CI OIDC token...", m.Provider.Name)
+		return nil, nil
 
 	case "manifest":
 		// Credentials are directly in the manifest (return nil to use default behavior)
@@ -445,11 +3147,123 @@ func (m *Manifest) GetCloudCredentials(ctx context.Context) (*credentials.Provid
 	}
 }
 
+// Wasm returns true if the manifest deploys WebAssembly OCI artifacts instead
+// of container images.
+func (m *Manifest) Wasm() bool {
+	return m.ArtifactType == ArtifactWasm
+}
+
 // IsMultiContainer returns true if this manifest defines a multi-container deployment.
 func (m *Manifest) IsMultiContainer() bool {
 	return len(m.Containers) > 0
 }
 
+// IsMultiProvider returns true if this manifest deploys to several providers.
+func (m *Manifest) IsMultiProvider() bool {
+	return len(m.Providers) > 0
+}
+
+// deploysTo reports whether a multi-provider manifest deploys to the named
+// provider.
+func (m *Manifest) deploysTo(name string) bool {
+	for _, p := range m.Providers {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets returns a single-provider manifest for each provider the manifest
+// deploys to, and for each of its services on providers that deploy them
+// separately. Each copy has its own environment variables, containers, and
+// tags, so targets can be prepared and deployed independently.
+func (m *Manifest) Targets() []*Manifest {
+	if !m.IsMultiProvider() {
+		return m.withServices()
+	}
+	targets := make([]*Manifest, 0, len(m.Providers))
+	for _, p := range m.Providers {
+		targets = append(targets, m.withProvider(p).withServices()...)
+	}
+	return targets
+}
+
+// ForProvider returns the single-provider manifest for the named provider of a
+// multi-provider manifest. Single-provider manifests are returned unchanged
+// when name is empty or matches.
+func (m *Manifest) ForProvider(name string) (*Manifest, error) {
+	if !m.IsMultiProvider() {
+		if name != "" && name != m.Provider.Name {
+			return nil, fmt.Errorf("manifest deploys to %s, not %s", m.Provider.Name, name)
+		}
+		return m, nil
+	}
+
+	names := make([]string, 0, len(m.Providers))
+	for _, p := range m.Providers {
+		if p.Name == name {
+			return m.withProvider(p), nil
+		}
+		names = append(names, p.Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("manifest deploys to multiple providers (%s); choose one with -provider", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("manifest does not deploy to %s (providers: %s)", name, strings.Join(names, ", "))
+}
+
+// Emulate sends the provider's API requests to the emulator at endpoint, as
+// provider.endpoint_url does. A multi-provider manifest must first be
+// narrowed to one provider with ForProvider.
+func (m *Manifest) Emulate(endpoint string) error {
+	if m.IsMultiProvider() {
+		return fmt.Errorf("an emulator endpoint applies to one provider; choose it with -provider")
+	}
+	p := m.Provider
+	p.EndpointURL = endpoint
+	if err := validateEmulator(&p, "provider"); err != nil {
+		return err
+	}
+	m.Provider = p
+	return nil
+}
+
+// withProvider returns a copy of the manifest deploying only to p.
+func (m *Manifest) withProvider(p ProviderConfig) *Manifest {
+	c := *m
+	c.Provider = p
+	c.Providers = nil
+	c.EnvironmentVariables = maps.Clone(m.EnvironmentVariables)
+	c.Tags = maps.Clone(m.Tags)
+	c.Containers = slices.Clone(m.Containers)
+	for i := range c.Containers {
+		c.Containers[i].Environment = maps.Clone(c.Containers[i].Environment)
+	}
+	// The templates were checked for every provider when the manifest was loaded
+	_ = c.renderNames()
+	return &c
+}
+
+// Images returns every container image referenced by the manifest.
+func (m *Manifest) Images() []string {
+	if m.IsMultiService() {
+		images := make([]string, 0, len(m.Services))
+		for _, s := range m.Services {
+			images = append(images, s.Image)
+		}
+		return images
+	}
+	if !m.IsMultiContainer() {
+		return []string{m.Image}
+	}
+	images := make([]string, 0, len(m.Containers))
+	for _, c := range m.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
 // GetPrimaryContainer returns the primary/first container.
 // For single-container: returns a Container created from the Image field
 // For multi-container: returns the first container in the Containers array
@@ -458,10 +3272,71 @@ func (m *Manifest) GetPrimaryContainer() Container {
 		return m.Containers[0]
 	}
 	// Convert single-container config to Container struct
-	return Container{
+	c := Container{
 		Name:        m.Application.Name,
 		Image:       m.Image,
 		Ports:       m.Ports,
 		Environment: m.EnvironmentVariables,
 	}
+	if m.Container != nil {
+		c.Command = m.Container.Command
+		c.Args = m.Container.Args
+		c.Workdir = m.Container.Workdir
+	}
+	return c
+}
+
+// CredentialsManager returns a credentials manager for the manifest's credentials source.
+// For "secrets-manager", the provider's credentials are read from and written to
+// provider.credentials.secret_id; for "parameter-store", they are read from the
+// parameters under provider.credentials.parameter_path; for "gcp-secret-manager"
+// and "azure-key-vault", they are read from the resource name or URL of
+// provider.credentials.secret_id; for "vault", they are read from the manifest's
+// Vault server.
+func (m *Manifest) CredentialsManager() *credentials.Manager {
+	mgr := &credentials.Manager{Source: "cli"}
+	if c := m.Provider.Credentials; c != nil && c.Source != "" {
+		mgr.Source = c.Source
+		if c.SecretID != "" {
+			mgr.Secrets = map[string]string{m.Provider.Cloud(): c.SecretID}
+		}
+		if c.Source == "parameter-store" && c.ParameterPath != "" {
+			mgr.Secrets = map[string]string{m.Provider.Cloud(): c.ParameterPath}
+		}
+		if c.Source == "gcp-secret-manager" && c.SecretID != "" {
+			mgr.Secrets = map[string]string{m.Provider.Cloud(): gcpSecretName(m.Provider.ProjectID, c.SecretID)}
+		}
+		if c.Source == "azure-key-vault" && c.SecretID != "" {
+			mgr.Secrets = map[string]string{m.Provider.Cloud(): keyVaultSecretURL(c.KeyVaultURI, c.SecretID)}
+		}
+		if c.Source == "vault" && m.Vault != nil {
+			mgr.Vault = m.Vault.ClientConfig()
+		}
+	}
+	return mgr
+}
+
+// FromSecretStore reports whether credentials are loaded from a secret store
+// (Vault, AWS Secrets Manager, AWS SSM Parameter Store, Google Secret Manager, or
+// Azure Key Vault) rather than the manifest, environment, or CLI.
+func (c *CredentialsConfig) FromSecretStore() bool {
+	if c == nil {
+		return false
+	}
+	switch c.Source {
+	case "vault", "secrets-manager", "parameter-store", "gcp-secret-manager", "azure-key-vault":
+		return true
+	}
+	return false
+}
+
+// OIDCToken returns the CI job's OIDC token for OIDCAudience, or for
+// defaultAudience if it is empty, to exchange for cloud credentials when
+// Source is "oidc".
+func (c *CredentialsConfig) OIDCToken(ctx context.Context, defaultAudience string) (string, error) {
+	audience := c.OIDCAudience
+	if audience == "" {
+		audience = defaultAudience
+	}
+	return credentials.CIIDToken(ctx, c.OIDCTokenEnv, audience)
 }
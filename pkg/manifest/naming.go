@@ -0,0 +1,282 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Image tag strategies of naming.image_tag_strategy.
+const (
+	TagStrategyGitSHA    = "git-sha"
+	TagStrategyTimestamp = "timestamp"
+	TagStrategySemver    = "semver"
+	TagStrategyManual    = "manual"
+)
+
+// NamingConfig holds templates for the names cloud-deploy derives from the
+// manifest. Templates are Go templates evaluated when the manifest is loaded,
+// with the fields .app (application name), .env (environment name), .region,
+// .provider, and .timestamp (UTC, e.g. 20260304T120000).
+type NamingConfig struct {
+	// Label of each deployed version: the image tag pushed by providers that push a new tag per deploy,
+	// and the Elastic Beanstalk application version - default: deploy-{{.timestamp}}, or latest on Elastic Beanstalk
+	VersionLabel string `yaml:"version_label,omitempty" json:"version_label,omitempty"`
+
+	// DNS name label of Azure Container Instances, which names the deployment's FQDN - default: the environment name
+	// lowercased, with characters other than letters, digits, and hyphens replaced by hyphens
+	DNSLabel string `yaml:"dns_label,omitempty" json:"dns_label,omitempty"`
+
+	// Registry repository the application's images are pushed to - default: the application name
+	Repository string `yaml:"repository,omitempty" json:"repository,omitempty"`
+
+	// How every provider tags the images of each deploy, in place of the fixed latest tag of ECR and
+	// Artifact Registry: "git-sha" (the deployed commit), "timestamp" (deploy-{{.timestamp}}), "semver"
+	// (the semantic version the image is tagged with), or "manual" (version_label) - optional
+	ImageTagStrategy string `yaml:"image_tag_strategy,omitempty" json:"image_tag_strategy,omitempty"`
+}
+
+// versionLabelPattern matches labels usable as image tags.
+var versionLabelPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// semverPattern matches semantic versions usable as image tags, which cannot
+// carry build metadata.
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?$`)
+
+// gitCommit returns the commit being deployed: the one CI checked out, or HEAD
+// of the repository in the working directory. Replaced in tests.
+var gitCommit = func() (string, error) {
+	for _, env := range []string{"GITHUB_SHA", "CI_COMMIT_SHA", "BUILD_SOURCEVERSION"} {
+		if sha := os.Getenv(env); sha != "" {
+			return sha, nil
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// repositoryPattern matches repository names every supported registry accepts.
+var repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*$`)
+
+// renderNames evaluates environment.name_template and the naming templates
+// in place. A multi-provider manifest keeps its templates, which each target
+// evaluates with its own provider; they are only checked here.
+func (m *Manifest) renderNames() error {
+	if m.Environment.NameTemplate == "" && m.Naming == nil {
+		return nil
+	}
+	if m.loadedAt.IsZero() {
+		m.loadedAt = time.Now()
+	}
+	if m.IsMultiProvider() {
+		for _, p := range m.Providers {
+			c := *m
+			c.Provider = p
+			c.Providers = nil
+			if err := c.renderNames(); err != nil {
+				return fmt.Errorf("provider %s: %w", p.Name, err)
+			}
+		}
+		return nil
+	}
+
+	data := map[string]string{
+		"app":       m.Application.Name,
+		"env":       m.Environment.Name,
+		"region":    m.Provider.Region,
+		"provider":  m.Provider.Name,
+		"timestamp": m.loadedAt.UTC().Format("20060102T150405"),
+	}
+	if m.Environment.NameTemplate != "" {
+		name, err := renderName("environment.name_template", m.Environment.NameTemplate, data)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return fmt.Errorf("environment.name_template %q renders an empty name", m.Environment.NameTemplate)
+		}
+		m.Environment.Name = name
+		m.Environment.NameTemplate = ""
+		data["env"] = name
+	}
+
+	if m.Naming == nil {
+		return nil
+	}
+	naming := *m.Naming
+	label, err := m.strategyLabel(naming)
+	if err != nil {
+		return err
+	}
+	if label != "" {
+		naming.VersionLabel = label
+	}
+	for _, field := range []struct {
+		name    string
+		value   *string
+		pattern *regexp.Regexp
+		maxLen  int
+		rule    string
+	}{
+		{"naming.version_label", &naming.VersionLabel, versionLabelPattern, 128, "an image tag (letters, digits, '_', '.', and '-', up to 128 characters)"},
+		{"naming.dns_label", &naming.DNSLabel, dnsLabel, 63, "a lowercase DNS label (letters, digits, and '-', up to 63 characters)"},
+		{"naming.repository", &naming.Repository, repositoryPattern, 256, "a lowercase repository name (letters, digits, '.', '_', '-', and '/', up to 256 characters)"},
+	} {
+		if *field.value == "" {
+			continue
+		}
+		value, err := renderName(field.name, *field.value, data)
+		if err != nil {
+			return err
+		}
+		if len(value) > field.maxLen || !field.pattern.MatchString(value) {
+			return fmt.Errorf("%s renders %q, which is not %s", field.name, value, field.rule)
+		}
+		*field.value = value
+	}
+	m.Naming = &naming
+	return nil
+}
+
+// strategyLabel returns the version label naming.image_tag_strategy tags
+// images with, or "" for the manual strategy and none. The labels of the
+// git-sha and semver strategies are resolved by ResolveImageTag, as only
+// commands pushing images need them.
+func (m *Manifest) strategyLabel(naming NamingConfig) (string, error) {
+	if naming.ImageTagStrategy == "" || naming.ImageTagStrategy == TagStrategyManual {
+		if naming.ImageTagStrategy == TagStrategyManual && naming.VersionLabel == "" {
+			return "", fmt.Errorf("naming.version_label is required with image_tag_strategy %s", TagStrategyManual)
+		}
+		return "", nil
+	}
+	if naming.VersionLabel != "" {
+		return "", fmt.Errorf("naming.version_label is only used with image_tag_strategy %s, got %s", TagStrategyManual, naming.ImageTagStrategy)
+	}
+	switch naming.ImageTagStrategy {
+	case TagStrategyGitSHA, TagStrategySemver:
+		return "", nil
+	case TagStrategyTimestamp:
+		return "deploy-{{.timestamp}}", nil
+	default:
+		return "", fmt.Errorf("naming.image_tag_strategy must be %s, %s, %s, or %s, got %q",
+			TagStrategyGitSHA, TagStrategyTimestamp, TagStrategySemver, TagStrategyManual, naming.ImageTagStrategy)
+	}
+}
+
+// ResolveImageTag resolves the version label of naming.image_tag_strategy
+// git-sha, from the commit being deployed, or semver, from the tag of the
+// manifest's images. Commands pushing images resolve it before deploying; the
+// targets of a multi-provider manifest share it.
+func (m *Manifest) ResolveImageTag() error {
+	if m.Naming == nil || m.strategyTag != "" {
+		return nil
+	}
+	switch m.Naming.ImageTagStrategy {
+	case TagStrategyGitSHA:
+		sha, err := gitCommit()
+		if err != nil {
+			return fmt.Errorf("naming.image_tag_strategy %s requires a git commit: %w", TagStrategyGitSHA, err)
+		}
+		// Short commit hashes, as git abbreviates them in large repositories
+		m.strategyTag = sha[:min(len(sha), 12)]
+	case TagStrategySemver:
+		version := ""
+		for _, image := range m.Images() {
+			tag := imageTag(image)
+			if !semverPattern.MatchString(tag) || (version != "" && tag != version) {
+				return fmt.Errorf("naming.image_tag_strategy %s requires images tagged with the same semantic version (e.g., my-app:1.4.2), got %q", TagStrategySemver, image)
+			}
+			version = tag
+		}
+		if version == "" {
+			return fmt.Errorf("naming.image_tag_strategy %s requires an image tagged with a semantic version", TagStrategySemver)
+		}
+		m.strategyTag = version
+	}
+	return nil
+}
+
+// imageTag returns the tag of image, or "" if it has none.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return ""
+}
+
+// renderName evaluates the template of the named field with data.
+func renderName(field, text string, data map[string]string) (string, error) {
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", field, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return b.String(), nil
+}
+
+// VersionLabel returns the label of the version being deployed:
+// naming.version_label, the resolved label of naming.image_tag_strategy, or
+// def when neither is set.
+func (m *Manifest) VersionLabel(def string) string {
+	if m.Naming != nil && m.Naming.VersionLabel != "" {
+		return m.Naming.VersionLabel
+	}
+	if m.strategyTag != "" {
+		return m.strategyTag
+	}
+	return def
+}
+
+// ImageTag returns the tag a provider pushing the same tag every deploy, def,
+// pushes images with. With naming.image_tag_strategy, each deploy pushes the
+// version label instead, after the container's name for the containers of
+// multi-container deployments (e.g., web-3f9c2a1b4d5e). def is returned
+// while the label of the strategy is not resolved.
+func (m *Manifest) ImageTag(def, container string) string {
+	if m.Naming == nil || m.Naming.ImageTagStrategy == "" {
+		return def
+	}
+	label := m.VersionLabel("")
+	if label == "" {
+		return def
+	}
+	if container != "" {
+		return container + "-" + label
+	}
+	return label
+}
+
+// DNSLabel returns the DNS name label of the deployment: naming.dns_label, or
+// the environment name lowercased, with characters other than letters,
+// digits, and hyphens replaced by hyphens.
+func (m *Manifest) DNSLabel() string {
+	if m.Naming != nil && m.Naming.DNSLabel != "" {
+		return m.Naming.DNSLabel
+	}
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(m.Environment.Name))
+}
+
+// RepositoryName returns the registry repository of the application's
+// images: naming.repository, or the application name.
+func (m *Manifest) RepositoryName() string {
+	if m.Naming != nil && m.Naming.Repository != "" {
+		return m.Naming.Repository
+	}
+	return m.Application.Name
+}
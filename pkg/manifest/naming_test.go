@@ -0,0 +1,228 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadNaming(t *testing.T) {
+	content := `version: "1.0"
+image: "test-app:latest"
+provider:
+  name: aws
+  region: us-east-1
+application:
+  name: test-app
+environment:
+  name_template: "{{.app}}-prod-{{.region}}"
+naming:
+  version_label: "v-{{.timestamp}}"
+  dns_label: "{{.env}}"
+  repository: "team/{{.app}}"
+`
+	tmpFile := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	m, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.Environment.Name != "test-app-prod-us-east-1" || m.Environment.NameTemplate != "" {
+		t.Errorf("Environment = %+v, want the rendered name", m.Environment)
+	}
+	if m.DNSLabel() != "test-app-prod-us-east-1" {
+		t.Errorf("DNSLabel() = %q", m.DNSLabel())
+	}
+	if m.RepositoryName() != "team/test-app" {
+		t.Errorf("RepositoryName() = %q", m.RepositoryName())
+	}
+	if label := m.VersionLabel("latest"); label != "v-"+m.loadedAt.UTC().Format("20060102T150405") {
+		t.Errorf("VersionLabel() = %q", label)
+	}
+}
+
+func TestRenderNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		naming  *NamingConfig
+		wantErr string
+	}{
+		{name: "unknown field", env: "{{.stage}}", wantErr: "invalid environment.name_template"},
+		{name: "parse error", env: "{{.app", wantErr: "invalid environment.name_template"},
+		{name: "empty name", env: "{{if false}}x{{end}}", wantErr: "renders an empty name"},
+		{name: "invalid DNS label", naming: &NamingConfig{DNSLabel: "{{.app}}_{{.env}}"}, wantErr: "naming.dns_label renders \"app_prod\""},
+		{name: "invalid version label", naming: &NamingConfig{VersionLabel: "-{{.env}}"}, wantErr: "naming.version_label"},
+		{name: "invalid repository", naming: &NamingConfig{Repository: "Team/{{.app}}"}, wantErr: "naming.repository"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manifest{
+				Provider:    ProviderConfig{Name: "aws", Region: "us-east-1"},
+				Application: ApplicationConfig{Name: "app"},
+				Environment: EnvironmentConfig{Name: "prod", NameTemplate: tt.env},
+				Naming:      tt.naming,
+			}
+			err := m.renderNames()
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRenderNamesMultiProvider(t *testing.T) {
+	m := &Manifest{
+		Providers:   []ProviderConfig{{Name: "aws", Region: "us-east-1"}, {Name: "gcp", Region: "us-central1"}},
+		Application: ApplicationConfig{Name: "app"},
+		Environment: EnvironmentConfig{NameTemplate: "{{.app}}-{{.provider}}-{{.region}}"},
+		Naming:      &NamingConfig{VersionLabel: "{{.provider}}-{{.timestamp}}"},
+	}
+	if err := m.renderNames(); err != nil {
+		t.Fatalf("renderNames failed: %v", err)
+	}
+	if m.Environment.NameTemplate == "" || m.Naming.VersionLabel != "{{.provider}}-{{.timestamp}}" {
+		t.Fatal("Expected a multi-provider manifest to keep its templates")
+	}
+
+	targets := m.Targets()
+	if targets[0].Environment.Name != "app-aws-us-east-1" || targets[1].Environment.Name != "app-gcp-us-central1" {
+		t.Errorf("Expected each target to render its own name, got %q and %q", targets[0].Environment.Name, targets[1].Environment.Name)
+	}
+	stamp := m.loadedAt.UTC().Format("20060102T150405")
+	if targets[0].VersionLabel("") != "aws-"+stamp || targets[1].VersionLabel("") != "gcp-"+stamp {
+		t.Errorf("Expected version labels sharing the load timestamp, got %q and %q", targets[0].VersionLabel(""), targets[1].VersionLabel(""))
+	}
+
+	m.Environment.NameTemplate = "{{.app}}-{{.nope}}"
+	m.loadedAt = time.Time{}
+	if err := m.renderNames(); err == nil || !contains(err.Error(), "provider aws") {
+		t.Errorf("Expected the failing provider in the error, got: %v", err)
+	}
+}
+
+func TestNamingDefaults(t *testing.T) {
+	m := &Manifest{
+		Application: ApplicationConfig{Name: "my-app"},
+		Environment: EnvironmentConfig{Name: "My_App.Prod"},
+	}
+	if got := m.DNSLabel(); got != "my-app-prod" {
+		t.Errorf("DNSLabel() = %q, want my-app-prod", got)
+	}
+	if got := m.VersionLabel("latest"); got != "latest" {
+		t.Errorf("VersionLabel() = %q, want latest", got)
+	}
+	if got := m.RepositoryName(); got != "my-app" {
+		t.Errorf("RepositoryName() = %q, want my-app", got)
+	}
+}
+
+func TestImageTagStrategy(t *testing.T) {
+	defer func(f func() (string, error)) { gitCommit = f }(gitCommit)
+	gitCommit = func() (string, error) { return "3f9c2a1b4d5e6f708192a3b4c5d6e7f801234567", nil }
+
+	tests := []struct {
+		name      string
+		image     string
+		naming    NamingConfig
+		wantLabel string
+		wantErr   string
+	}{
+		{name: "git-sha", image: "app:latest", naming: NamingConfig{ImageTagStrategy: TagStrategyGitSHA}, wantLabel: "3f9c2a1b4d5e"},
+		{name: "timestamp", image: "app:latest", naming: NamingConfig{ImageTagStrategy: TagStrategyTimestamp}, wantLabel: "deploy-20260304T120000"},
+		{name: "semver", image: "ghcr.io/acme/app:v1.4.2-rc.1", naming: NamingConfig{ImageTagStrategy: TagStrategySemver}, wantLabel: "v1.4.2-rc.1"},
+		{name: "manual", image: "app:latest", naming: NamingConfig{ImageTagStrategy: TagStrategyManual, VersionLabel: "{{.env}}-7"}, wantLabel: "prod-7"},
+		{name: "semver without version", image: "localhost:5000/app:latest", naming: NamingConfig{ImageTagStrategy: TagStrategySemver}, wantErr: "requires images tagged with the same semantic version"},
+		{name: "manual without label", image: "app:latest", naming: NamingConfig{ImageTagStrategy: TagStrategyManual}, wantErr: "naming.version_label is required"},
+		{name: "label with strategy", image: "app:latest", naming: NamingConfig{ImageTagStrategy: TagStrategyGitSHA, VersionLabel: "v1"}, wantErr: "only used with image_tag_strategy manual"},
+		{name: "unknown strategy", image: "app:latest", naming: NamingConfig{ImageTagStrategy: "sha"}, wantErr: "naming.image_tag_strategy must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			naming := tt.naming
+			m := &Manifest{
+				Image:       tt.image,
+				Provider:    ProviderConfig{Name: "aws", Region: "us-east-1"},
+				Application: ApplicationConfig{Name: "app"},
+				Environment: EnvironmentConfig{Name: "prod"},
+				Naming:      &naming,
+				loadedAt:    time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC),
+			}
+			err := m.renderNames()
+			if err == nil {
+				err = m.ResolveImageTag()
+			}
+			if tt.wantErr != "" {
+				if err == nil || !contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("naming failed: %v", err)
+			}
+			if got := m.VersionLabel("latest"); got != tt.wantLabel {
+				t.Errorf("VersionLabel() = %q, want %q", got, tt.wantLabel)
+			}
+			if got := m.ImageTag("latest", ""); got != tt.wantLabel {
+				t.Errorf("ImageTag() = %q, want %q", got, tt.wantLabel)
+			}
+			if got := m.ImageTag("web", "web"); got != "web-"+tt.wantLabel {
+				t.Errorf("ImageTag() of container web = %q, want web-%s", got, tt.wantLabel)
+			}
+		})
+	}
+
+	// Without a strategy, providers keep their fixed tags
+	m := &Manifest{Naming: &NamingConfig{VersionLabel: "v1"}}
+	if got := m.ImageTag("latest", ""); got != "latest" {
+		t.Errorf("ImageTag() without a strategy = %q, want latest", got)
+	}
+}
+
+func TestImageTagStrategyResolvedLazily(t *testing.T) {
+	defer func(f func() (string, error)) { gitCommit = f }(gitCommit)
+	gitCommit = func() (string, error) { return "", fmt.Errorf("not a git repository") }
+
+	content := `version: "1.0"
+image: "test-app:latest"
+provider:
+  name: aws
+  region: us-east-1
+application:
+  name: test-app
+environment:
+  name: prod
+naming:
+  image_tag_strategy: git-sha
+`
+	tmpFile := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	// Commands that push no images load the manifest outside a git repository
+	m, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() outside a git repository error = %v", err)
+	}
+	if got := m.ImageTag("latest", ""); got != "latest" {
+		t.Errorf("ImageTag() before ResolveImageTag = %q, want latest", got)
+	}
+	if err := m.ResolveImageTag(); err == nil || !contains(err.Error(), "requires a git commit") {
+		t.Errorf("ResolveImageTag() error = %v, want one requiring a git commit", err)
+	}
+
+	gitCommit = func() (string, error) { return "3f9c2a1b4d5e6f708192a3b4c5d6e7f801234567", nil }
+	if err := m.ResolveImageTag(); err != nil {
+		t.Fatalf("ResolveImageTag() error = %v", err)
+	}
+	if got := m.ImageTag("web", "web"); got != "web-3f9c2a1b4d5e" {
+		t.Errorf("ImageTag() of container web = %q, want web-3f9c2a1b4d5e", got)
+	}
+}
@@ -0,0 +1,145 @@
+package manifest
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+)
+
+// NotificationsConfig holds where the results of deploys, rollbacks, and
+// destroys are posted.
+type NotificationsConfig struct {
+	// Commands to notify about: deploy, rollback, roll-forward, destroy - default: all
+	Commands []string `yaml:"commands,omitempty" json:"commands,omitempty"`
+
+	// Notify only about failed commands - default: false
+	OnlyFailures bool `yaml:"only_failures,omitempty" json:"only_failures,omitempty"`
+
+	// Slack incoming webhooks - optional
+	Slack []SlackNotification `yaml:"slack,omitempty" json:"slack,omitempty"`
+
+	// HTTP endpoints the result is POSTed to as JSON - optional
+	Webhooks []WebhookNotification `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+
+	// Email sent through SES or SendGrid - optional
+	Email []EmailNotification `yaml:"email,omitempty" json:"email,omitempty"`
+}
+
+// SlackNotification is a Slack incoming webhook.
+type SlackNotification struct {
+	// Incoming webhook URL, e.g. https://hooks.slack.com/services/... - required
+	WebhookURL string `yaml:"webhook_url" json:"webhook_url"`
+}
+
+// WebhookNotification is an HTTP endpoint the result is POSTed to as JSON.
+type WebhookNotification struct {
+	// URL to POST to - required
+	URL string `yaml:"url" json:"url"`
+
+	// Headers of the request, e.g. Authorization - optional
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+}
+
+// Email services notifications can be sent through.
+const (
+	EmailSES      = "ses"
+	EmailSendGrid = "sendgrid"
+)
+
+// EmailNotification is an email sent through SES or SendGrid.
+type EmailNotification struct {
+	// Service sending the email: ses or sendgrid - required
+	Service string `yaml:"service" json:"service"`
+
+	// Sender address, verified with the service - required
+	From string `yaml:"from" json:"from"`
+
+	// Recipient addresses - required
+	To []string `yaml:"to" json:"to"`
+
+	// SES region - default: the aws provider's region, else us-east-1
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+
+	// SendGrid API key, e.g. ${SENDGRID_API_KEY} - required for sendgrid
+	APIKey string `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+}
+
+// notificationCommands are the commands notifications can be sent about.
+var notificationCommands = []string{"deploy", "rollback", "roll-forward", "destroy"}
+
+// Notifies reports whether a command's result is posted: its command is
+// notified about, and it failed or not only failures are.
+func (c *NotificationsConfig) Notifies(command string, failed bool) bool {
+	if c == nil || (c.OnlyFailures && !failed) {
+		return false
+	}
+	if len(c.Commands) == 0 {
+		return slices.Contains(notificationCommands, command)
+	}
+	return slices.Contains(c.Commands, command)
+}
+
+// validate checks the commands and channels.
+func (c *NotificationsConfig) validate() error {
+	for i, command := range c.Commands {
+		if !slices.Contains(notificationCommands, command) {
+			return fmt.Errorf("notifications.commands[%d]: unknown command %q (expected deploy, rollback, roll-forward, or destroy)", i, command)
+		}
+	}
+	if len(c.Slack)+len(c.Webhooks)+len(c.Email) == 0 {
+		return fmt.Errorf("notifications must configure at least one of slack, webhooks, and email")
+	}
+	for i, s := range c.Slack {
+		if err := validateNotificationURL(s.WebhookURL); err != nil {
+			return fmt.Errorf("notifications.slack[%d].webhook_url %w", i, err)
+		}
+	}
+	for i, w := range c.Webhooks {
+		if err := validateNotificationURL(w.URL); err != nil {
+			return fmt.Errorf("notifications.webhooks[%d].url %w", i, err)
+		}
+	}
+	for i, e := range c.Email {
+		field := fmt.Sprintf("notifications.email[%d]", i)
+		switch e.Service {
+		case EmailSES:
+			if e.APIKey != "" {
+				return fmt.Errorf("%s.api_key applies only to sendgrid", field)
+			}
+		case EmailSendGrid:
+			if e.APIKey == "" {
+				return fmt.Errorf("%s.api_key is required for sendgrid", field)
+			}
+			if e.Region != "" {
+				return fmt.Errorf("%s.region applies only to ses", field)
+			}
+		default:
+			return fmt.Errorf("%s.service must be ses or sendgrid, got %q", field, e.Service)
+		}
+		if _, err := mail.ParseAddress(e.From); err != nil {
+			return fmt.Errorf("%s.from %q is not an email address", field, e.From)
+		}
+		if len(e.To) == 0 {
+			return fmt.Errorf("%s.to is required", field)
+		}
+		for _, to := range e.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("%s.to %q is not an email address", field, to)
+			}
+		}
+	}
+	return nil
+}
+
+// validateNotificationURL checks that rawURL is an http or https URL.
+func validateNotificationURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	return nil
+}
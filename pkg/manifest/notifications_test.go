@@ -0,0 +1,57 @@
+package manifest
+
+import "testing"
+
+func TestValidateNotifications(t *testing.T) {
+	slack := []SlackNotification{{WebhookURL: "https://hooks.slack.com/services/T0/B0/x"}}
+	tests := []struct {
+		name    string
+		cfg     NotificationsConfig
+		wantErr string
+	}{
+		{name: "valid", cfg: NotificationsConfig{
+			Commands: []string{"deploy", "rollback"},
+			Slack:    slack,
+			Webhooks: []WebhookNotification{{URL: "https://events.example.com/deploys", Headers: map[string]string{"Authorization": "Bearer x"}}},
+			Email: []EmailNotification{
+				{Service: EmailSES, From: "deploys@example.com", To: []string{"team@example.com"}, Region: "eu-west-1"},
+				{Service: EmailSendGrid, From: "Deploys <deploys@example.com>", To: []string{"oncall@example.com"}, APIKey: "SG.key"},
+			},
+		}},
+		{name: "no channels", cfg: NotificationsConfig{OnlyFailures: true}, wantErr: "at least one of slack, webhooks, and email"},
+		{name: "unknown command", cfg: NotificationsConfig{Commands: []string{"stop"}, Slack: slack}, wantErr: `notifications.commands[0]: unknown command "stop"`},
+		{name: "missing slack url", cfg: NotificationsConfig{Slack: []SlackNotification{{}}}, wantErr: "notifications.slack[0].webhook_url is required"},
+		{name: "bad webhook url", cfg: NotificationsConfig{Webhooks: []WebhookNotification{{URL: "events.example.com"}}}, wantErr: "notifications.webhooks[0].url must be an http or https URL"},
+		{name: "unknown service", cfg: NotificationsConfig{Email: []EmailNotification{{Service: "smtp", From: "a@example.com", To: []string{"b@example.com"}}}}, wantErr: `service must be ses or sendgrid, got "smtp"`},
+		{name: "sendgrid without key", cfg: NotificationsConfig{Email: []EmailNotification{{Service: EmailSendGrid, From: "a@example.com", To: []string{"b@example.com"}}}}, wantErr: "api_key is required for sendgrid"},
+		{name: "ses with key", cfg: NotificationsConfig{Email: []EmailNotification{{Service: EmailSES, From: "a@example.com", To: []string{"b@example.com"}, APIKey: "x"}}}, wantErr: "api_key applies only to sendgrid"},
+		{name: "bad from", cfg: NotificationsConfig{Email: []EmailNotification{{Service: EmailSES, From: "deploys", To: []string{"b@example.com"}}}}, wantErr: `notifications.email[0].from "deploys" is not an email address`},
+		{name: "no recipients", cfg: NotificationsConfig{Email: []EmailNotification{{Service: EmailSES, From: "a@example.com"}}}, wantErr: "notifications.email[0].to is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNotifies(t *testing.T) {
+	var none *NotificationsConfig
+	if none.Notifies("deploy", true) {
+		t.Error("Expected no notifications without notifications")
+	}
+	all := &NotificationsConfig{}
+	if !all.Notifies("deploy", false) || !all.Notifies("roll-forward", true) || all.Notifies("stop", true) {
+		t.Error("Expected every notified command by default, and no others")
+	}
+	failures := &NotificationsConfig{Commands: []string{"deploy"}, OnlyFailures: true}
+	if failures.Notifies("deploy", false) || !failures.Notifies("deploy", true) || failures.Notifies("destroy", true) {
+		t.Error("Expected only failed deploys to be notified")
+	}
+}
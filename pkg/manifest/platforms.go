@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// platformPattern matches platforms in os/arch[/variant] form, e.g. linux/arm64.
+var platformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// IsMultiArch reports whether the manifest's images are pushed as multi-arch
+// image indexes, for more than one platform.
+func (m *Manifest) IsMultiArch() bool {
+	return len(m.Platforms) > 1
+}
+
+// validatePlatforms checks the platforms images are built and pushed for.
+func (m *Manifest) validatePlatforms(errs *ValidationErrors) {
+	if len(m.Platforms) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	for i, p := range m.Platforms {
+		field := fmt.Sprintf("platforms[%d]", i)
+		if !platformPattern.MatchString(p) {
+			errs.addf(field, "%s must be a platform such as linux/amd64 or linux/arm64, got %q", field, p)
+		} else if seen[p] {
+			errs.addf(field, "duplicate platform: %s", p)
+		}
+		seen[p] = true
+	}
+	if m.ArtifactType == ArtifactWasm {
+		errs.addf("platforms", "platforms do not apply to artifact_type %s", ArtifactWasm)
+	}
+	if m.IsMultiArch() {
+		// Lambda and App Runner run single-platform images, not image indexes
+		reported := make(map[string]bool)
+		for _, target := range m.Targets() {
+			name := target.Provider.Name
+			if (name == "aws-lambda" || name == "aws-apprunner") && !reported[name] {
+				reported[name] = true
+				errs.addf("platforms", "%s runs single-platform images; list one platform, not an image index of %d", name, len(m.Platforms))
+			}
+		}
+	}
+	if build := m.Deployment.Source.Build; build != nil {
+		if build.Platform != "" {
+			errs.addf("platforms", "cannot specify both 'platforms' and deployment.source.build.platform - use one or the other")
+		}
+		if m.IsMultiArch() && build.Strategy != "" && build.Strategy != BuildStrategyLocal {
+			errs.addf("platforms", "deployment.source.build.strategy %s builds for one platform; multi-arch images are built with strategy %s", build.Strategy, BuildStrategyLocal)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package manifest
+
+import "testing"
+
+func TestValidatePlatforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		platforms []string
+		build     *BuildConfig
+		artifact  string
+		provider  string
+		wantErr   string
+	}{
+		{name: "multi-arch", platforms: []string{"linux/amd64", "linux/arm64"}},
+		{name: "variant", platforms: []string{"linux/arm/v7"}},
+		{name: "built multi-arch", platforms: []string{"linux/amd64", "linux/arm64"}, build: &BuildConfig{Builder: BuilderBuildKit}},
+		{name: "buildpacks for one platform", platforms: []string{"linux/arm64"}, build: &BuildConfig{Strategy: BuildStrategyBuildpacks}},
+		{name: "invalid", platforms: []string{"arm64"}, wantErr: `platforms[0] must be a platform such as linux/amd64 or linux/arm64, got "arm64"`},
+		{name: "duplicate", platforms: []string{"linux/amd64", "linux/amd64"}, wantErr: "duplicate platform: linux/amd64"},
+		{name: "with build platform", platforms: []string{"linux/arm64"}, build: &BuildConfig{Platform: "linux/arm64"}, wantErr: "cannot specify both 'platforms' and deployment.source.build.platform"},
+		{name: "buildpacks multi-arch", platforms: []string{"linux/amd64", "linux/arm64"}, build: &BuildConfig{Strategy: BuildStrategyBuildpacks}, wantErr: "builds for one platform"},
+		{name: "lambda for one platform", platforms: []string{"linux/arm64"}, provider: "aws-lambda"},
+		{name: "lambda multi-arch", platforms: []string{"linux/amd64", "linux/arm64"}, provider: "aws-lambda", wantErr: "aws-lambda runs single-platform images"},
+		{name: "apprunner multi-arch", platforms: []string{"linux/amd64", "linux/arm64"}, provider: "aws-apprunner", wantErr: "aws-apprunner runs single-platform images"},
+		{name: "wasm", platforms: []string{"linux/amd64"}, artifact: ArtifactWasm, wantErr: "platforms do not apply to artifact_type wasm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manifest{Platforms: tt.platforms, ArtifactType: tt.artifact, Provider: ProviderConfig{Name: tt.provider}}
+			if tt.build != nil {
+				m.Deployment.Source = SourceConfig{Type: "local", Path: ".", Build: tt.build}
+			}
+			var errs ValidationErrors
+			m.validatePlatforms(&errs)
+			if tt.wantErr == "" && len(errs) > 0 {
+				t.Errorf("Unexpected error: %v", errs)
+			}
+			if tt.wantErr != "" && (len(errs) == 0 || !contains(errs.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem with one setting of a manifest.
+type FieldError struct {
+	// Field is the setting's path, e.g. provider.name or containers[0].image
+	Field string
+
+	// Position is where the setting is written, as file:line:column; empty
+	// when the manifest was not loaded from a file
+	Position string
+
+	// Err describes the problem
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Position == "" {
+		return e.Err.Error()
+	}
+	return e.Position + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors are all the problems Validate found with a manifest, in
+// the order it checks them.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	problems := make([]string, len(e))
+	for i, fe := range e {
+		problems[i] = fe.Error()
+	}
+	return fmt.Sprintf("%d problems:\n  %s", len(e), strings.Join(problems, "\n  "))
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, fe := range e {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// add adds err, a problem with the setting field, unless it is nil. A
+// FieldError in err keeps its own, more precise, field.
+func (e *ValidationErrors) add(field string, err error) {
+	if err == nil {
+		return
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		fe = &FieldError{Field: field, Err: err}
+	}
+	*e = append(*e, fe)
+}
+
+// addf adds a problem with the setting field, formatted as fmt.Errorf does.
+func (e *ValidationErrors) addf(field, format string, args ...any) {
+	e.add(field, fmt.Errorf(format, args...))
+}
+
+// fieldError returns the problem with the setting field, formatted as
+// fmt.Errorf does.
+func fieldError(field, format string, args ...any) error {
+	return &FieldError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// positions locates the settings of a manifest document in the files they
+// were read from.
+type positions struct {
+	doc      *yaml.Node
+	location string
+
+	// sources holds the locations of the nodes of included fragments; the
+	// other nodes are the manifest's own
+	sources map[*yaml.Node]string
+}
+
+// newPositions returns the positions of doc, the manifest read from location.
+func newPositions(doc *yaml.Node, location string) *positions {
+	return &positions{doc: doc, location: location, sources: make(map[*yaml.Node]string)}
+}
+
+// record notes that n, and the nodes within it, were read from location.
+func (p *positions) record(n *yaml.Node, location string) {
+	p.sources[n] = location
+	for _, child := range n.Content {
+		p.record(child, location)
+	}
+}
+
+// of returns the position of n as file:line:column.
+func (p *positions) of(n *yaml.Node) string {
+	location, ok := p.sources[n]
+	if !ok {
+		location = p.location
+	}
+	if location == "-" {
+		location = "stdin"
+	}
+	return fmt.Sprintf("%s:%d:%d", location, n.Line, n.Column)
+}
+
+// lookup returns the position of the setting at path, e.g. containers[0].image,
+// or of the closest setting containing it when the manifest does not set it.
+func (p *positions) lookup(path string) string {
+	n := documentMapping(p.doc)
+	if n == nil {
+		if p.doc.Kind == 0 {
+			return ""
+		}
+		return p.of(p.doc)
+	}
+	at := n
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes, _ := strings.Cut(segment, "[")
+		if name != "" {
+			if n.Kind != yaml.MappingNode {
+				break
+			}
+			i := keyIndex(n, name)
+			if i < 0 {
+				break
+			}
+			at, n = n.Content[i], n.Content[i+1]
+		}
+		for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			if index == "" {
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			if n.Kind != yaml.SequenceNode || err != nil || i < 0 || i >= len(n.Content) {
+				return p.of(at)
+			}
+			at, n = n.Content[i], n.Content[i]
+		}
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+	}
+	return p.of(at)
+}
+
+// locate sets the position of each setting err reports a problem with.
+func (p *positions) locate(err error) {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		for _, fe := range errs {
+			if fe.Position == "" {
+				fe.Position = p.lookup(fe.Field)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadReportsEveryProblemWithItsPosition(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"shared.yaml": `provider:
+  name: gcp
+  region: us-central1
+  project_id: my-project
+`,
+		"manifest.yaml": `includes:
+  - shared.yaml
+version: "1.0"
+application:
+  name: my-app
+containers:
+  - name: web
+  - name: worker
+    image: worker:latest
+environment:
+  name: my-env
+  cname_conflict: retry
+environments:
+  prod:
+    secrets:
+      - name: DB_PASSWORD
+`,
+	})
+	manifest := filepath.Join(dir, "manifest.yaml")
+	shared := filepath.Join(dir, "shared.yaml")
+
+	_, err := LoadWith(manifest, Options{Environment: "prod"})
+	if err == nil {
+		t.Fatal("Expected the manifest to be refused")
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+	want := []struct{ field, position, message string }{
+		{"containers[0].image", manifest + ":7:5", "container[0] (web): image is required"},
+		{"provider.credentials", shared + ":1:1", "provider.credentials.service_account_key_path"},
+		{"secrets[0].secret_id", manifest + ":16:9", "secrets[0] (DB_PASSWORD): secret_id is required"},
+		{"environment.cname_conflict", manifest + ":12:3", "environment.cname_conflict must be"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d problems, got %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Field != w.field || errs[i].Position != w.position || !strings.Contains(errs[i].Err.Error(), w.message) {
+			t.Errorf("Problem %d = %s at %s: %v, want %s at %s: %s", i, errs[i].Field, errs[i].Position, errs[i].Err, w.field, w.position, w.message)
+		}
+	}
+	if !strings.Contains(err.Error(), "4 problems:\n  "+manifest+":7:5: container[0] (web): image is required") {
+		t.Errorf("Unexpected error message:\n%v", err)
+	}
+}
+
+func TestValidateSingleProblem(t *testing.T) {
+	m := &Manifest{
+		Provider:    ProviderConfig{Name: "aws", Region: "us-east-1"},
+		Application: ApplicationConfig{Name: "my-app"},
+		Environment: EnvironmentConfig{Name: "my-env"},
+	}
+	err := m.Validate()
+	if err == nil || err.Error() != "either 'image' (single-container) or 'containers' (multi-container) is required" {
+		t.Errorf("Expected the problem alone, got %v", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "image" {
+		t.Errorf("Expected a FieldError for image, got %#v", err)
+	}
+}
+
+func TestPositionsLookup(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(`provider:
+  name: aws
+containers:
+  - name: web
+    ports:
+      - container_port: 8080
+`), &doc); err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	positions := newPositions(&doc, "-")
+	for _, tc := range []struct{ path, want string }{
+		{"provider.name", "stdin:2:3"},
+		{"provider.region", "stdin:1:1"},
+		{"containers[0].ports[0].container_port", "stdin:6:9"},
+		{"containers[3].image", "stdin:3:1"},
+		{"application.name", "stdin:1:1"},
+	} {
+		if got := positions.lookup(tc.path); got != tc.want {
+			t.Errorf("lookup(%s) = %s, want %s", tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Registry types of the registries section.
+const (
+	RegistryGeneric   = "generic"
+	RegistryDockerHub = "dockerhub"
+	RegistryGHCR      = "ghcr"
+)
+
+// RegistryConfig is a registry the image is pushed to before it is deployed,
+// in addition to the provider's own.
+type RegistryConfig struct {
+	// Type of registry: "generic" (Harbor, Quay, Artifactory, or any other OCI registry), "dockerhub",
+	// or "ghcr" - default: "generic"
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// Registry host and optional path prefix, e.g. harbor.example.com/platform (generic)
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Docker Hub user or organization (dockerhub) - default: Username; or GitHub user or organization (ghcr)
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+
+	// Repository the image is pushed to - default: the manifest's repository name
+	Repository string `yaml:"repository,omitempty" json:"repository,omitempty"`
+
+	// Tag the image is pushed with - default: the tag of naming.image_tag_strategy, the image's tag, or "latest"
+	Tag string `yaml:"tag,omitempty" json:"tag,omitempty"`
+
+	// User name to log in with - default: the Docker credentials of the host (generic), $GITHUB_ACTOR (ghcr)
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Password or access token, e.g. ${HARBOR_TOKEN} - default: $GITHUB_TOKEN (ghcr)
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+
+	// Reach the registry over plain HTTP (generic) - default: false
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+}
+
+// RegistryType returns the type of the registry, "generic" if it is not set.
+func (r *RegistryConfig) RegistryType() string {
+	if r.Type == "" {
+		return RegistryGeneric
+	}
+	return r.Type
+}
+
+// validateRegistries checks the registries section.
+func (m *Manifest) validateRegistries(errs *ValidationErrors) {
+	if len(m.Registries) > 0 && m.IsMultiContainer() {
+		errs.addf("registries", "registries require image; multi-container manifests are not supported")
+	}
+	for i, r := range m.Registries {
+		field := fmt.Sprintf("registries[%d]", i)
+		switch r.RegistryType() {
+		case RegistryGeneric:
+			if r.URL == "" {
+				errs.addf(field+".url", "%s.url is required for generic registries", field)
+			} else if strings.Contains(r.URL, "://") || strings.ContainsAny(r.URL, " \t") || strings.HasPrefix(r.URL, "/") {
+				errs.addf(field+".url", "%s.url must be a registry host and optional path such as harbor.example.com/platform, without a scheme, got %q", field, r.URL)
+			}
+			if r.Username != "" && r.Password == "" {
+				errs.addf(field+".password", "%s.password is required with username", field)
+			}
+		case RegistryDockerHub:
+			if r.Username == "" || r.Password == "" {
+				errs.addf(field+".username", "%s.username and password are required for Docker Hub", field)
+			}
+		case RegistryGHCR:
+			if r.Namespace == "" {
+				errs.addf(field+".namespace", "%s.namespace, the GitHub user or organization, is required for GHCR", field)
+			}
+		default:
+			errs.addf(field+".type", "%s.type must be %s, %s, or %s, got %q", field, RegistryGeneric, RegistryDockerHub, RegistryGHCR, r.Type)
+			continue
+		}
+		if r.RegistryType() != RegistryGeneric && (r.URL != "" || r.Insecure) {
+			errs.addf(field+".url", "%s.url and insecure are only supported for generic registries", field)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package manifest
+
+import "testing"
+
+func TestValidateRegistries(t *testing.T) {
+	base := func() *Manifest {
+		return &Manifest{
+			Image:       "my-app:latest",
+			Provider:    ProviderConfig{Name: "aws", Region: "us-east-1"},
+			Application: ApplicationConfig{Name: "my-app"},
+			Environment: EnvironmentConfig{Name: "my-env"},
+		}
+	}
+	tests := []struct {
+		name       string
+		registries []RegistryConfig
+		wantErr    string
+	}{
+		{name: "generic", registries: []RegistryConfig{{URL: "harbor.example.com/platform", Username: "robot$ci", Password: "token"}}},
+		{name: "generic with docker credentials", registries: []RegistryConfig{{Type: "generic", URL: "quay.io/acme"}}},
+		{name: "dockerhub", registries: []RegistryConfig{{Type: "dockerhub", Namespace: "acme", Username: "ci", Password: "token"}}},
+		{name: "ghcr", registries: []RegistryConfig{{Type: "ghcr", Namespace: "acme"}}},
+		{name: "generic without url", registries: []RegistryConfig{{}}, wantErr: "registries[0].url is required"},
+		{name: "url with scheme", registries: []RegistryConfig{{URL: "https://harbor.example.com"}}, wantErr: "without a scheme"},
+		{name: "username without password", registries: []RegistryConfig{{URL: "harbor.example.com", Username: "ci"}}, wantErr: "password is required with username"},
+		{name: "dockerhub without token", registries: []RegistryConfig{{Type: "dockerhub", Username: "ci"}}, wantErr: "username and password are required for Docker Hub"},
+		{name: "ghcr without namespace", registries: []RegistryConfig{{Type: "ghcr"}}, wantErr: "namespace, the GitHub user or organization, is required"},
+		{name: "url on ghcr", registries: []RegistryConfig{{Type: "ghcr", Namespace: "acme", URL: "ghcr.io"}}, wantErr: "only supported for generic registries"},
+		{name: "unknown type", registries: []RegistryConfig{{Type: "ecr"}}, wantErr: `registries[0].type must be generic, dockerhub, or ghcr, got "ecr"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base()
+			m.Registries = tt.registries
+			err := m.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// variableValue matches a value that is only a reference to a manifest
+// variable, which may stand for a number or boolean until it is replaced.
+const variableValue = `^\$\{var\.[^}]+\}$`
+
+// Schema returns the JSON Schema (draft-07) of manifests, generated from the
+// Manifest type, for editors (yaml-language-server) and for validating
+// manifests in CI. Any setting may be null, as overlays and includes use null
+// to remove settings, and numbers and booleans may be variable references.
+func Schema() ([]byte, error) {
+	s := schemaBuilder{definitions: make(map[string]any)}
+	s.typeSchema(reflect.TypeOf(Manifest{}))
+
+	// The settings the loader consumes before the manifest is decoded
+	root := s.definitions["Manifest"].(map[string]any)["properties"].(map[string]any)
+	root["includes"] = map[string]any{
+		"type":  []string{"array", "null"},
+		"items": map[string]any{"type": "string"},
+	}
+	root["environments"] = map[string]any{
+		"type":                 []string{"object", "null"},
+		"additionalProperties": map[string]any{"$ref": "#/definitions/Manifest"},
+	}
+	root["variables"] = map[string]any{
+		"type":                 []string{"object", "null"},
+		"additionalProperties": map[string]any{"type": []string{"string", "number", "boolean", "null"}},
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "cloud-deploy manifest",
+		"$ref":        "#/definitions/Manifest",
+		"definitions": s.definitions,
+	}, "", "  ")
+}
+
+// schemaBuilder generates the schemas of Go types, collecting a definition
+// for each struct type so that types used more than once, or recursively,
+// are defined once.
+type schemaBuilder struct {
+	definitions map[string]any
+}
+
+// typeSchema returns the schema of values of type t.
+func (s schemaBuilder) typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.typeSchema(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, ok := s.definitions[t.Name()]; !ok {
+			s.definitions[t.Name()] = nil
+			s.definitions[t.Name()] = s.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/definitions/" + t.Name()}
+	case reflect.String:
+		// Numbers and booleans decode into strings as they are written
+		return map[string]any{"type": []string{"string", "number", "boolean", "null"}}
+	case reflect.Bool:
+		return map[string]any{"type": []string{"boolean", "string", "null"}, "pattern": variableValue}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": []string{"integer", "string", "null"}, "pattern": variableValue}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": []string{"integer", "string", "null"}, "minimum": 0, "pattern": variableValue}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": []string{"number", "string", "null"}, "pattern": variableValue}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": []string{"array", "null"}, "items": s.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": s.typeSchema(t.Elem())}
+	}
+	return map[string]any{}
+}
+
+// structSchema returns the schema of a struct type: an object with a
+// property for each field YAML decodes, and no others.
+func (s schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for name, field := range yamlFields(t) {
+		properties[name] = s.typeSchema(field.Type)
+	}
+	return map[string]any{
+		"type":                 []string{"object", "null"},
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
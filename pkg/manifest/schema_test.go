@@ -0,0 +1,149 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaErrors returns where value breaks the parts of schema that Schema
+// generates: unknown properties, the types of values, and variable
+// references.
+func schemaErrors(definitions map[string]any, schema map[string]any, value any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return schemaErrors(definitions, definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]any), value, path)
+	}
+	var types []string
+	for _, t := range schema["type"].([]any) {
+		types = append(types, t.(string))
+	}
+	kind := "null"
+	switch v := value.(type) {
+	case map[string]any:
+		kind = "object"
+	case []any:
+		kind = "array"
+	case string:
+		kind = "string"
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			return []string{fmt.Sprintf("%s: %q does not match %s", path, v, pattern)}
+		}
+	case bool:
+		kind = "boolean"
+	case int:
+		kind = "integer"
+	case float64:
+		kind = "number"
+	}
+	if !slices.Contains(types, kind) && !(kind == "integer" && slices.Contains(types, "number")) {
+		return []string{fmt.Sprintf("%s: %s is not one of %v", path, kind, types)}
+	}
+
+	var errs []string
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for key, item := range v {
+			if property, ok := properties[key]; ok {
+				errs = append(errs, schemaErrors(definitions, property.(map[string]any), item, path+"."+key)...)
+			} else if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+				errs = append(errs, schemaErrors(definitions, additional, item, path+"."+key)...)
+			} else {
+				errs = append(errs, fmt.Sprintf("%s: unknown property %s", path, key))
+			}
+		}
+	case []any:
+		for i, item := range v {
+			errs = append(errs, schemaErrors(definitions, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+func loadSchema(t *testing.T) (map[string]any, map[string]any) {
+	t.Helper()
+	data, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema failed: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not JSON: %v", err)
+	}
+	definitions := schema["definitions"].(map[string]any)
+	return definitions, definitions["Manifest"].(map[string]any)
+}
+
+func TestSchema(t *testing.T) {
+	definitions, root := loadSchema(t)
+	properties := root["properties"].(map[string]any)
+	for _, name := range []string{"version", "provider", "containers", "environment_variables", "includes", "environments", "variables"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Expected the manifest schema to have %s", name)
+		}
+	}
+	if root["additionalProperties"] != false {
+		t.Error("Expected the manifest schema to refuse unknown settings")
+	}
+
+	var doc any
+	manifest := `version: "1.0"
+provider:
+  name: aws
+  region: us-east-1
+instance:
+  max_instances: ${var.max}
+  min_instances: ~
+environments:
+  prod:
+    instance:
+      max_instances: 10
+  dev:
+variables:
+  max: 4
+`
+	if err := yaml.Unmarshal([]byte(manifest), &doc); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if errs := schemaErrors(definitions, root, doc, "manifest"); len(errs) > 0 {
+		t.Errorf("Expected the manifest to match the schema: %v", errs)
+	}
+
+	for _, bad := range []string{"instance:\n  type: t3.micro\n  max_instance: 4\n", "instance:\n  max_instances: four\n", "containers:\n  name: web\n"} {
+		if err := yaml.Unmarshal([]byte(bad), &doc); err != nil {
+			t.Fatalf("Failed to parse manifest: %v", err)
+		}
+		if errs := schemaErrors(definitions, root, doc, "manifest"); len(errs) == 0 {
+			t.Errorf("Expected the schema to refuse %q", bad)
+		}
+	}
+}
+
+func TestSchemaExamples(t *testing.T) {
+	definitions, root := loadSchema(t)
+	examples, err := filepath.Glob("../../examples/*.yaml")
+	if err != nil || len(examples) == 0 {
+		t.Fatalf("Failed to find the examples: %v", err)
+	}
+	more, _ := filepath.Glob("../../examples/*/manifests/*.yaml")
+	for _, example := range append(examples, more...) {
+		data, err := os.ReadFile(example)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", example, err)
+		}
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("Failed to parse %s: %v", example, err)
+		}
+		if errs := schemaErrors(definitions, root, doc, "manifest"); len(errs) > 0 {
+			t.Errorf("%s does not match the schema:\n%s", example, strings.Join(errs, "\n"))
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Service is one of several services a manifest deploys together, such as
+// an API, a worker, and a frontend. Each has its own image, environment
+// variables, and scaling; the rest of the manifest is shared.
+type Service struct {
+	// Name of the service, a lowercase DNS label; it names the service's environment (e.g., my-env-api)
+	Name string `yaml:"name" json:"name"`
+
+	// Image is the Docker image of the service
+	Image string `yaml:"image" json:"image"`
+
+	// Ports to expose from the service's container - default: the manifest's ports, in a container group only
+	// for the first service
+	Ports []PortMapping `yaml:"ports,omitempty" json:"ports,omitempty"`
+
+	// Environment variables of the service, set over the manifest's environment_variables - optional
+	EnvironmentVariables map[string]string `yaml:"environment_variables,omitempty" json:"environment_variables,omitempty"`
+
+	// Container startup overrides (command, args, working directory) - default: the manifest's container
+	Container *ContainerStartup `yaml:"container,omitempty" json:"container,omitempty"`
+
+	// Minimum number of instances, overriding instance.min_instances and cloud_run.min_instances - optional
+	MinInstances int32 `yaml:"min_instances,omitempty" json:"min_instances,omitempty"`
+
+	// Maximum number of instances, overriding instance.max_instances and cloud_run.max_instances - optional
+	MaxInstances int32 `yaml:"max_instances,omitempty" json:"max_instances,omitempty"`
+}
+
+// groupedServiceProviders run the services of a manifest as the containers of
+// one container group, instead of deploying each as its own environment.
+var groupedServiceProviders = map[string]bool{
+	"azure": true,
+}
+
+// IsMultiService returns true if this manifest deploys several services.
+func (m *Manifest) IsMultiService() bool {
+	return len(m.Services) > 0
+}
+
+// validateServices checks the services of the manifest.
+func (m *Manifest) validateServices(errs *ValidationErrors) {
+	if !m.IsMultiService() {
+		return
+	}
+	if m.Image != "" || len(m.Containers) > 0 {
+		errs.addf("services", "cannot specify 'services' with 'image' or 'containers' - give each service its image")
+	}
+	if m.Deployment.Source.Build != nil {
+		errs.addf("deployment.source.build", "deployment.source.build builds a single image; build the images of 'services' before deploying")
+	}
+	names := make(map[string]bool, len(m.Services))
+	for i, s := range m.Services {
+		field := fmt.Sprintf("services[%d]", i)
+		if s.Name == "" {
+			errs.addf(field+".name", "services[%d]: name is required", i)
+			continue
+		}
+		if len(s.Name) > 63 || !dnsLabel.MatchString(s.Name) {
+			errs.addf(field+".name", "services[%d]: name %q must be a lowercase DNS label (letters, digits, and '-')", i, s.Name)
+		}
+		if names[s.Name] {
+			errs.addf(field+".name", "services[%d]: name %q is used by another service", i, s.Name)
+		}
+		names[s.Name] = true
+		if s.Image == "" {
+			errs.addf(field+".image", "services[%d] (%s): image is required", i, s.Name)
+		}
+		errs.add(field, validateInstanceCounts(field, s.MinInstances, s.MaxInstances))
+	}
+}
+
+// withServices returns the manifests deploying the services of m, which has a
+// single provider: one per service, or one running them all as a container
+// group on providers that group them. Manifests without services are
+// returned as they are.
+func (m *Manifest) withServices() []*Manifest {
+	if !m.IsMultiService() {
+		return []*Manifest{m}
+	}
+	if groupedServiceProviders[m.Provider.Name] {
+		return []*Manifest{m.asContainerGroup()}
+	}
+	targets := make([]*Manifest, 0, len(m.Services))
+	for _, s := range m.Services {
+		targets = append(targets, m.withService(s))
+	}
+	return targets
+}
+
+// ForService returns the manifest deploying the named service of a
+// single-provider manifest. Manifests without services are returned unchanged
+// when name is empty, and so are services grouped in one container group,
+// which are deployed together, when name is one of them.
+func (m *Manifest) ForService(name string) (*Manifest, error) {
+	if !m.IsMultiService() {
+		if name != "" {
+			return nil, fmt.Errorf("manifest has no services, so it has no service %s", name)
+		}
+		return m, nil
+	}
+
+	names := make([]string, 0, len(m.Services))
+	for _, s := range m.Services {
+		if s.Name == name {
+			if groupedServiceProviders[m.Provider.Name] {
+				return m.asContainerGroup(), nil
+			}
+			return m.withService(s), nil
+		}
+		names = append(names, s.Name)
+	}
+	if name == "" {
+		if groupedServiceProviders[m.Provider.Name] {
+			return m.asContainerGroup(), nil
+		}
+		return nil, fmt.Errorf("manifest deploys multiple services (%s); choose one with -service", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("manifest has no service %s (services: %s)", name, strings.Join(names, ", "))
+}
+
+// withService returns a copy of the manifest deploying only s, in an
+// environment named after the manifest's and the service's (e.g., my-env-api).
+func (m *Manifest) withService(s Service) *Manifest {
+	c := *m
+	c.Services = nil
+	c.Image = s.Image
+	c.Environment.Name = m.Environment.Name + "-" + s.Name
+	if m.Environment.CName != "" {
+		c.Environment.CName = m.Environment.CName + "-" + s.Name
+	}
+	if len(s.Ports) > 0 {
+		c.Ports = s.Ports
+	}
+	c.EnvironmentVariables = maps.Clone(m.EnvironmentVariables)
+	if c.EnvironmentVariables == nil && len(s.EnvironmentVariables) > 0 {
+		c.EnvironmentVariables = make(map[string]string, len(s.EnvironmentVariables))
+	}
+	maps.Copy(c.EnvironmentVariables, s.EnvironmentVariables)
+	c.Tags = maps.Clone(m.Tags)
+	if s.Container != nil {
+		c.Container = s.Container
+	}
+
+	if s.MinInstances != 0 {
+		c.Instance.MinInstances = s.MinInstances
+	}
+	if s.MaxInstances != 0 {
+		c.Instance.MaxInstances = s.MaxInstances
+	}
+	if s.MinInstances != 0 || s.MaxInstances != 0 {
+		cloudRun := CloudRunConfig{}
+		if m.CloudRun != nil {
+			cloudRun = *m.CloudRun
+		}
+		if s.MinInstances != 0 {
+			cloudRun.MinInstances = s.MinInstances
+		}
+		if s.MaxInstances != 0 {
+			cloudRun.MaxInstances = s.MaxInstances
+		}
+		c.CloudRun = &cloudRun
+	}
+	return &c
+}
+
+// asContainerGroup returns a copy of the manifest running its services as
+// the containers of one container group, each with the manifest's
+// environment variables and its own.
+func (m *Manifest) asContainerGroup() *Manifest {
+	c := *m
+	c.Services = nil
+	c.Tags = maps.Clone(m.Tags)
+	c.Containers = make([]Container, 0, len(m.Services))
+	for i, s := range m.Services {
+		env := maps.Clone(m.EnvironmentVariables)
+		if env == nil {
+			env = make(map[string]string, len(s.EnvironmentVariables))
+		}
+		maps.Copy(env, s.EnvironmentVariables)
+		container := Container{
+			Name:        s.Name,
+			Image:       s.Image,
+			Ports:       slices.Clone(s.Ports),
+			Environment: env,
+		}
+		if len(container.Ports) == 0 && i == 0 {
+			container.Ports = slices.Clone(m.Ports)
+		}
+		if s.Container != nil {
+			container.Command = s.Container.Command
+			container.Args = s.Container.Args
+			container.Workdir = s.Container.Workdir
+		}
+		c.Containers = append(c.Containers, container)
+	}
+	return &c
+}
@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const servicesManifest = `version: "1.0"
+provider:
+  name: %s
+  region: us-east-1
+  subscription_id: sub
+  resource_group: rg
+application:
+  name: shop
+environment:
+  name: shop-prod
+instance:
+  min_instances: 1
+  max_instances: 2
+ports:
+  - container: 8080
+environment_variables:
+  LOG_LEVEL: info
+  REGION: us-east-1
+services:
+  - name: api
+    image: shop/api:1.0
+    environment_variables:
+      LOG_LEVEL: debug
+    max_instances: 10
+  - name: worker
+    image: shop/worker:1.0
+    container:
+      command: ["worker"]
+`
+
+func loadServices(t *testing.T, provider string) *Manifest {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"manifest.yaml": strings.Replace(servicesManifest, "%s", provider, 1)})
+	m, err := Load(filepath.Join(dir, "manifest.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	return m
+}
+
+func TestServicesTargets(t *testing.T) {
+	m := loadServices(t, "aws")
+	if !m.IsMultiService() {
+		t.Fatal("Expected a multi-service manifest")
+	}
+	targets := m.Targets()
+	if len(targets) != 2 {
+		t.Fatalf("Expected a target per service, got %d", len(targets))
+	}
+
+	api, worker := targets[0], targets[1]
+	if api.Environment.Name != "shop-prod-api" || api.Image != "shop/api:1.0" || api.IsMultiService() {
+		t.Errorf("Unexpected api target: %s, %s, %d services", api.Environment.Name, api.Image, len(api.Services))
+	}
+	if api.EnvironmentVariables["LOG_LEVEL"] != "debug" || api.EnvironmentVariables["REGION"] != "us-east-1" {
+		t.Errorf("Expected the service's variables over the manifest's, got %v", api.EnvironmentVariables)
+	}
+	if m.EnvironmentVariables["LOG_LEVEL"] != "info" {
+		t.Errorf("Expected the manifest's variables to be unchanged, got %v", m.EnvironmentVariables)
+	}
+	if api.Instance.MinInstances != 1 || api.Instance.MaxInstances != 10 || api.CloudRun == nil || api.CloudRun.MaxInstances != 10 {
+		t.Errorf("Expected the service's scaling over the manifest's, got %+v, %+v", api.Instance, api.CloudRun)
+	}
+	if len(api.Ports) != 1 || api.Ports[0].ContainerPort != 8080 {
+		t.Errorf("Expected the manifest's ports, got %v", api.Ports)
+	}
+
+	if worker.Environment.Name != "shop-prod-worker" || worker.Container == nil || worker.Container.Command[0] != "worker" {
+		t.Errorf("Unexpected worker target: %s, %+v", worker.Environment.Name, worker.Container)
+	}
+	if worker.Instance.MaxInstances != 2 || worker.CloudRun != nil {
+		t.Errorf("Expected the manifest's scaling, got %+v, %+v", worker.Instance, worker.CloudRun)
+	}
+
+	selected, err := m.ForService("worker")
+	if err != nil || selected.Environment.Name != "shop-prod-worker" {
+		t.Errorf("ForService(worker) = %v, %v", selected, err)
+	}
+	if _, err := m.ForService(""); err == nil || !strings.Contains(err.Error(), "-service") {
+		t.Errorf("Expected a service to be required, got %v", err)
+	}
+	if _, err := m.ForService("frontend"); err == nil || !strings.Contains(err.Error(), "api, worker") {
+		t.Errorf("Expected an unknown service to be refused, got %v", err)
+	}
+}
+
+func TestServicesContainerGroup(t *testing.T) {
+	m := loadServices(t, "azure")
+	targets := m.Targets()
+	if len(targets) != 1 {
+		t.Fatalf("Expected the services in one container group, got %d targets", len(targets))
+	}
+	group := targets[0]
+	if group.Environment.Name != "shop-prod" || !group.IsMultiContainer() || len(group.Containers) != 2 {
+		t.Fatalf("Unexpected container group: %s, %d containers", group.Environment.Name, len(group.Containers))
+	}
+	api, worker := group.Containers[0], group.Containers[1]
+	if api.Name != "api" || api.Environment["LOG_LEVEL"] != "debug" || len(api.Ports) != 1 {
+		t.Errorf("Unexpected api container: %+v", api)
+	}
+	if worker.Image != "shop/worker:1.0" || worker.Command[0] != "worker" || len(worker.Ports) != 0 || worker.Environment["LOG_LEVEL"] != "info" {
+		t.Errorf("Unexpected worker container: %+v", worker)
+	}
+	if selected, err := m.ForService(""); err != nil || len(selected.Containers) != 2 {
+		t.Errorf("Expected the container group, got %v", err)
+	}
+}
+
+func TestServicesValidate(t *testing.T) {
+	base := func(services ...Service) *Manifest {
+		return &Manifest{
+			Provider:    ProviderConfig{Name: "aws", Region: "us-east-1"},
+			Application: ApplicationConfig{Name: "shop"},
+			Environment: EnvironmentConfig{Name: "shop-prod"},
+			Services:    services,
+		}
+	}
+	if err := base(Service{Name: "api", Image: "api:1"}, Service{Name: "web", Image: "web:1"}).Validate(); err != nil {
+		t.Errorf("Expected valid services, got %v", err)
+	}
+
+	withImage := base(Service{Name: "api", Image: "api:1"})
+	withImage.Image = "app:1"
+	for _, tt := range []struct {
+		name string
+		m    *Manifest
+		want string
+	}{
+		{"image", withImage, "cannot specify 'services' with 'image'"},
+		{"no name", base(Service{Image: "api:1"}), "services[0]: name is required"},
+		{"bad name", base(Service{Name: "API", Image: "api:1"}), "lowercase DNS label"},
+		{"duplicate", base(Service{Name: "api", Image: "api:1"}, Service{Name: "api", Image: "api:2"}), "used by another service"},
+		{"no image", base(Service{Name: "api"}), "services[0] (api): image is required"},
+		{"scaling", base(Service{Name: "api", Image: "api:1", MinInstances: 3, MaxInstances: 2}), "services[0].min_instances (3) must not exceed"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.m.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
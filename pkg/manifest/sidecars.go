@@ -0,0 +1,86 @@
+package manifest
+
+import (
+	"fmt"
+	"slices"
+)
+
+// sidecarProviders deploy sidecars, as the containers of a multi-container
+// deployment: Elastic Beanstalk's Docker Compose bundle and Azure Container
+// Instances' container group.
+var sidecarProviders = map[string]bool{
+	"aws":   true,
+	"azure": true,
+}
+
+// validateSidecars checks the sidecars of the manifest, and the resources of
+// its containers.
+func (m *Manifest) validateSidecars(errs *ValidationErrors) {
+	for i, c := range m.Containers {
+		errs.add(fmt.Sprintf("containers[%d].resources", i), validateResources(fmt.Sprintf("containers[%d]", i), c.Resources))
+	}
+	if len(m.Sidecars) == 0 {
+		return
+	}
+
+	// The application's containers are named after it, or listed in containers
+	names := map[string]bool{m.Application.Name: len(m.Containers) == 0}
+	for _, c := range m.Containers {
+		names[c.Name] = true
+	}
+	for _, s := range m.Services {
+		names[s.Name] = true
+	}
+	for i, s := range m.Sidecars {
+		field := fmt.Sprintf("sidecars[%d]", i)
+		if s.Name == "" {
+			errs.addf(field+".name", "sidecars[%d]: name is required", i)
+			continue
+		}
+		if names[s.Name] {
+			errs.addf(field+".name", "sidecars[%d]: name %q is used by another container", i, s.Name)
+		}
+		names[s.Name] = true
+		if s.Image == "" {
+			errs.addf(field+".image", "sidecars[%d] (%s): image is required", i, s.Name)
+		}
+		errs.add(field+".resources", validateResources(field, s.Resources))
+	}
+
+	for _, target := range m.Targets() {
+		if !sidecarProviders[target.Provider.Name] {
+			errs.addf("sidecars", "sidecars are not supported by the %s provider; they are deployed by aws (Elastic Beanstalk) and azure (Container Instances)", target.Provider.Name)
+		}
+	}
+	if build := m.Deployment.Source.Build; build != nil && build.RemoteBuild() {
+		errs.addf("sidecars", "sidecars cannot be deployed with deployment.source.build.strategy %s; build the image locally", BuildStrategyCodeBuild)
+	}
+}
+
+// validateResources checks the resources of the container at field.
+func validateResources(field string, r *ContainerResources) error {
+	if r != nil && (r.CPU < 0 || r.MemoryGB < 0) {
+		return fmt.Errorf("%s.resources.cpu and %s.resources.memory_gb must not be negative", field, field)
+	}
+	return nil
+}
+
+// WithSidecars returns a copy of the manifest deploying its sidecars as
+// containers after the application's, so it is deployed as a multi-container
+// deployment. Manifests without sidecars are returned as they are.
+func (m *Manifest) WithSidecars() *Manifest {
+	if len(m.Sidecars) == 0 {
+		return m
+	}
+	c := *m
+	if m.IsMultiContainer() {
+		c.Containers = slices.Clone(m.Containers)
+	} else {
+		c.Containers = []Container{m.GetPrimaryContainer()}
+		c.Image = ""
+		c.Container = nil
+	}
+	c.Containers = append(c.Containers, m.Sidecars...)
+	c.Sidecars = nil
+	return &c
+}
@@ -0,0 +1,76 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithSidecars(t *testing.T) {
+	m := &Manifest{
+		Provider:             ProviderConfig{Name: "aws", Region: "us-east-1"},
+		Application:          ApplicationConfig{Name: "shop"},
+		Environment:          EnvironmentConfig{Name: "shop-prod"},
+		Image:                "shop:1.0",
+		Ports:                []PortMapping{{ContainerPort: 8080}},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info"},
+		Sidecars: []Container{
+			{Name: "fluent-bit", Image: "fluent/fluent-bit:3", Resources: &ContainerResources{CPU: 0.25, MemoryGB: 0.25}},
+		},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Expected valid sidecars, got %v", err)
+	}
+
+	c := m.WithSidecars()
+	if !c.IsMultiContainer() || len(c.Containers) != 2 || c.Image != "" || c.Sidecars != nil {
+		t.Fatalf("Expected the app and its sidecar as containers, got %+v", c)
+	}
+	app, sidecar := c.Containers[0], c.Containers[1]
+	if app.Name != "shop" || app.Image != "shop:1.0" || len(app.Ports) != 1 || app.Environment["LOG_LEVEL"] != "info" {
+		t.Errorf("Unexpected app container: %+v", app)
+	}
+	if sidecar.Name != "fluent-bit" || sidecar.Resources.CPU != 0.25 {
+		t.Errorf("Unexpected sidecar container: %+v", sidecar)
+	}
+	if m.Image != "shop:1.0" || len(m.Sidecars) != 1 {
+		t.Error("Expected the manifest to be unchanged")
+	}
+
+	multi := &Manifest{Containers: []Container{{Name: "web", Image: "web:1"}}, Sidecars: m.Sidecars}
+	if c := multi.WithSidecars(); len(c.Containers) != 2 || len(multi.Containers) != 1 {
+		t.Errorf("Expected the sidecar after the containers, got %d containers", len(c.Containers))
+	}
+	plain := &Manifest{Image: "shop:1.0"}
+	if plain.WithSidecars() != plain {
+		t.Error("Expected a manifest without sidecars to be returned as it is")
+	}
+}
+
+func TestSidecarsValidate(t *testing.T) {
+	base := func(provider string, sidecars ...Container) *Manifest {
+		return &Manifest{
+			Provider:    ProviderConfig{Name: provider, Region: "us-east-1", ProjectID: "p"},
+			Application: ApplicationConfig{Name: "shop"},
+			Environment: EnvironmentConfig{Name: "shop-prod"},
+			Image:       "shop:1.0",
+			Sidecars:    sidecars,
+		}
+	}
+	for _, tt := range []struct {
+		name string
+		m    *Manifest
+		want string
+	}{
+		{"no name", base("aws", Container{Image: "envoy:1"}), "sidecars[0]: name is required"},
+		{"app name", base("aws", Container{Name: "shop", Image: "envoy:1"}), "used by another container"},
+		{"no image", base("aws", Container{Name: "proxy"}), "sidecars[0] (proxy): image is required"},
+		{"resources", base("aws", Container{Name: "proxy", Image: "envoy:1", Resources: &ContainerResources{CPU: -1}}), "must not be negative"},
+		{"provider", base("gcp", Container{Name: "proxy", Image: "envoy:1"}), "not supported by the gcp provider"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.m.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
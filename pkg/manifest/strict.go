@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestDocument is a manifest as it is written: the settings of Manifest,
+// and those the loader consumes before the manifest is decoded.
+type manifestDocument struct {
+	Manifest     `yaml:",inline"`
+	Includes     []string                    `yaml:"includes,omitempty"`
+	Environments map[string]environmentEntry `yaml:"environments,omitempty"`
+	Variables    map[string]any              `yaml:"variables,omitempty"`
+}
+
+// environmentEntry is an entry of a manifest's environments.
+type environmentEntry struct {
+	Manifest  `yaml:",inline"`
+	Variables map[string]any `yaml:"variables,omitempty"`
+}
+
+// unknownFields returns an error listing every setting of the document that
+// no field of the manifest decodes, such as a misspelled enviroment:, with
+// its position; yaml.Unmarshal would ignore them. Entries of environments
+// are checked whether or not they are selected.
+func unknownFields(doc *yaml.Node, positions *positions) error {
+	root := documentMapping(doc)
+	if root == nil {
+		return nil
+	}
+	unknown := checkFields(root, reflect.TypeOf(manifestDocument{}), "", positions)
+	if len(unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unknown settings (correct them, or ignore them with -no-strict):\n  %s", strings.Join(unknown, "\n  "))
+}
+
+// checkFields returns the keys of n, and of the nodes within it, that have
+// no field in the type t that n decodes into. path is n's place in the
+// manifest, e.g. instance or containers[0].
+func checkFields(n *yaml.Node, t reflect.Type, path string, positions *positions) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Tag == "!!merge" {
+				merged := []*yaml.Node{value}
+				if value.Kind == yaml.SequenceNode {
+					merged = value.Content
+				}
+				for _, m := range merged {
+					unknown = append(unknown, checkFields(m, t, path, positions)...)
+				}
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				unknown = append(unknown, unknownField(key, path, fields, positions))
+				continue
+			}
+			unknown = append(unknown, checkFields(value, field.Type, settingPath(path, key.Value), positions)...)
+		}
+	case reflect.Slice, reflect.Array:
+		if n.Kind == yaml.SequenceNode {
+			for i, item := range n.Content {
+				unknown = append(unknown, checkFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), positions)...)
+			}
+		}
+	case reflect.Map:
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				unknown = append(unknown, checkFields(n.Content[i+1], t.Elem(), settingPath(path, n.Content[i].Value), positions)...)
+			}
+		}
+	}
+	return unknown
+}
+
+// unknownField describes the unknown setting key, suggesting the field it
+// was most likely meant to be.
+func unknownField(key *yaml.Node, path string, fields map[string]reflect.StructField, positions *positions) string {
+	message := positions.of(key) + ": " + settingPath(path, key.Value)
+	best, bestDistance := "", max(2, len(key.Value)/3)+1
+	for name := range fields {
+		if d := editDistance(key.Value, name); d < bestDistance || (d == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if best != "" {
+		message += fmt.Sprintf(" (did you mean %s?)", best)
+	}
+	return message
+}
+
+// settingPath returns the path of the setting key within the setting at path.
+func settingPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// yamlFields returns the fields of the struct type t by the keys YAML
+// decodes them from, including the fields of structs inlined with ",inline".
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(options, "inline") {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			for name, f := range yamlFields(ft) {
+				fields[name] = f
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
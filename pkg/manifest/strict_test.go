@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const misspelledManifest = `version: "1.0"
+provider:
+  name: aws
+  region: us-east-1
+application:
+  name: my-app
+enviroment:
+  name: my-env
+cloudrun:
+  cpu: "1"
+containers:
+  - name: web
+    image: web:latest
+    workdr: /app
+environment_variables:
+  ANY_NAME: is fine
+variables:
+  any_name: is fine too
+environments:
+  prod:
+    instance:
+      typ: t3.large
+    variables:
+      region: eu-west-1
+`
+
+func TestLoadUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(misspelledManifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	_, err := LoadWith(path, Options{Environment: "prod"})
+	if err == nil {
+		t.Fatal("Expected the misspelled settings to be refused")
+	}
+	for _, want := range []string{
+		path + ":7:1: enviroment (did you mean environment?)",
+		path + ":9:1: cloudrun (did you mean cloud_run?)",
+		path + ":14:5: containers[0].workdr (did you mean workdir?)",
+		path + ":22:7: environments.prod.instance.typ (did you mean type?)",
+		"-no-strict",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to contain %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "ANY_NAME") || strings.Contains(err.Error(), "any_name") || strings.Contains(err.Error(), "region") {
+		t.Errorf("Expected the names of variables not to be checked:\n%v", err)
+	}
+
+	m, err := LoadWith(path, Options{Environment: "prod", Lenient: true})
+	if err != nil {
+		t.Fatalf("Expected a lenient load to ignore unknown settings, got %v", err)
+	}
+	if m.Application.Name != "my-app" || m.Environment.Name != "prod" {
+		t.Errorf("Unexpected manifest: %s, %s", m.Application.Name, m.Environment.Name)
+	}
+}
+
+func TestUnknownFieldSuggestion(t *testing.T) {
+	fields := yamlFields(reflect.TypeOf(Manifest{}))
+	positions := newPositions(&yaml.Node{}, "manifest.yaml")
+	for _, tc := range []struct{ key, want string }{
+		{"enviroment", "environment"},
+		{"helth_check", "health_check"},
+		{"kubernetes_config", ""},
+		{"zzz", ""},
+	} {
+		got := unknownField(&yaml.Node{Kind: yaml.ScalarNode, Value: tc.key, Line: 1, Column: 1}, "", fields, positions)
+		if tc.want == "" && strings.Contains(got, "did you mean") || tc.want != "" && !strings.HasSuffix(got, "(did you mean "+tc.want+"?)") {
+			t.Errorf("unknownField(%s) = %q, want a suggestion of %q", tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"cloudrun", "cloud_run", 1},
+		{"kitten", "sitting", 3},
+		{"same", "same", 0},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
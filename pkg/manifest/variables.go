@@ -0,0 +1,131 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// variableReference matches a reference to a manifest variable, ${var.name}.
+var variableReference = regexp.MustCompile(`\$\{var\.([^}]*)\}`)
+
+// variableName matches the names variables may have.
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// expandEnv expands ${VAR} and $VAR in s from the process environment, like
+// os.ExpandEnv, but leaves variable references (${var.name}) for
+// applyVariables.
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if strings.HasPrefix(name, "var.") {
+			return "${" + name + "}"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// applyVariables replaces each ${var.name} in the document's values with the
+// value of the variable, and removes the document's variables section. Values
+// in vars override the defaults the section gives; a variable the section
+// declares with a null value has no default and must be in vars.
+func applyVariables(doc *yaml.Node, vars map[string]string) error {
+	values := make(map[string]string)
+	required := make(map[string]bool)
+	if root := documentMapping(doc); root != nil {
+		if section := removeKey(root, "variables"); section != nil && !isNull(section) {
+			if section.Kind != yaml.MappingNode {
+				return fmt.Errorf("variables must map variable names to their default values")
+			}
+			for i := 0; i < len(section.Content); i += 2 {
+				name, value := section.Content[i].Value, section.Content[i+1]
+				if !variableName.MatchString(name) {
+					return fmt.Errorf("variables.%s: names may only contain letters, digits, underscores, and hyphens", name)
+				}
+				switch {
+				case isNull(value):
+					required[name] = true
+				case value.Kind == yaml.ScalarNode:
+					values[name] = value.Value
+				default:
+					return fmt.Errorf("variables.%s must be a string, number, or boolean", name)
+				}
+			}
+		}
+	}
+	for name, value := range vars {
+		values[name] = value
+		delete(required, name)
+	}
+	if len(required) > 0 {
+		names := make([]string, 0, len(required))
+		for name := range required {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("variables without a value: %s (set them with -var name=value or in a -var-file)", strings.Join(names, ", "))
+	}
+
+	var undefined []string
+	var substitute func(n *yaml.Node)
+	substitute = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "${var.") {
+			n.Value = variableReference.ReplaceAllStringFunc(n.Value, func(ref string) string {
+				name := variableReference.FindStringSubmatch(ref)[1]
+				value, ok := values[name]
+				if !ok {
+					undefined = append(undefined, name)
+				}
+				return value
+			})
+			// A plain scalar is typed by its new value, so ${var.count} can be a number
+			if n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				n.Tag = ""
+			}
+		}
+		for _, child := range n.Content {
+			substitute(child)
+		}
+	}
+	substitute(doc)
+	if len(undefined) > 0 {
+		return fmt.Errorf("undefined variable %s: declare it under variables or set it with -var %s=value", undefined[0], undefined[0])
+	}
+	return nil
+}
+
+// ReadVariables reads the variables of a variables file (-var-file): a YAML
+// mapping of variable names to values.
+func ReadVariables(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variables file: %w", err)
+	}
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse variables file %s: %w", path, err)
+	}
+	vars := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("%s: %s is not a variable name", path, name)
+		}
+		if value.Kind != yaml.ScalarNode || isNull(&value) {
+			return nil, fmt.Errorf("%s: %s must be a string, number, or boolean", path, name)
+		}
+		vars[name] = value.Value
+	}
+	return vars, nil
+}
+
+// ParseVariable parses a variable given as name=value (-var).
+func ParseVariable(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || !variableName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid variable %q: use name=value", s)
+	}
+	return name, value, nil
+}
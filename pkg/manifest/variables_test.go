@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const variablesManifest = `version: "1.0"
+variables:
+  team: payments
+  region: us-east-1
+  max: 4
+  owner: ~
+provider:
+  name: aws
+  region: ${var.region}
+application:
+  name: ${var.team}-api
+environment:
+  name: ${var.team}-${ENV_SUFFIX}
+image: "registry.example.com/${var.team}/api:latest"
+instance:
+  type: t3.micro
+  environment_type: LoadBalanced
+  min_instances: 1
+  max_instances: ${var.max}
+tags:
+  owner: ${var.owner}
+  quoted: "${var.max}"
+environments:
+  prod:
+    variables:
+      region: eu-west-1
+`
+
+func writeVariablesManifest(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(variablesManifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func TestLoadVariables(t *testing.T) {
+	t.Setenv("ENV_SUFFIX", "prod")
+	path := writeVariablesManifest(t)
+
+	m, err := LoadWith(path, Options{Environment: "prod", Variables: map[string]string{"owner": "alice", "max": "8"}})
+	if err != nil {
+		t.Fatalf("LoadWith failed: %v", err)
+	}
+	if m.Application.Name != "payments-api" || m.Environment.Name != "payments-prod-prod" || m.Image != "registry.example.com/payments/api:latest" {
+		t.Errorf("Expected variables substituted, got %s, %s, %s", m.Application.Name, m.Environment.Name, m.Image)
+	}
+	if m.Provider.Region != "eu-west-1" {
+		t.Errorf("Expected the environment's variables to override the base's, got region %s", m.Provider.Region)
+	}
+	if m.Instance.MaxInstances != 8 {
+		t.Errorf("Expected -var to override the default and make a number, got %d", m.Instance.MaxInstances)
+	}
+	if m.Tags["owner"] != "alice" || m.Tags["quoted"] != "8" {
+		t.Errorf("Unexpected tags: %v", m.Tags)
+	}
+}
+
+func TestLoadVariablesErrors(t *testing.T) {
+	path := writeVariablesManifest(t)
+	if _, err := LoadWith(path, Options{Environment: "prod"}); err == nil || !strings.Contains(err.Error(), "variables without a value: owner") {
+		t.Errorf("Expected an error for a variable without a value, got %v", err)
+	}
+
+	undefined := filepath.Join(t.TempDir(), "undefined.yaml")
+	content := strings.Replace(variablesManifest, "${var.team}-api", "${var.tema}-api", 1)
+	if err := os.WriteFile(undefined, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	_, err := LoadWith(undefined, Options{Environment: "prod", Variables: map[string]string{"owner": "alice"}})
+	if err == nil || !strings.Contains(err.Error(), "undefined variable tema") {
+		t.Errorf("Expected an undefined variable error, got %v", err)
+	}
+}
+
+func TestReadVariables(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vars.yaml")
+	if err := os.WriteFile(path, []byte("team: search\nmax: 10\nenabled: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write variables: %v", err)
+	}
+	vars, err := ReadVariables(path)
+	if err != nil {
+		t.Fatalf("ReadVariables failed: %v", err)
+	}
+	if len(vars) != 3 || vars["team"] != "search" || vars["max"] != "10" || vars["enabled"] != "true" {
+		t.Errorf("Unexpected variables: %v", vars)
+	}
+
+	for _, content := range []string{"team: [a, b]\n", "team: ~\n", "bad name: x\n", "- a\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write variables: %v", err)
+		}
+		if _, err := ReadVariables(path); err == nil {
+			t.Errorf("Expected ReadVariables to reject %q", content)
+		}
+	}
+}
+
+func TestParseVariable(t *testing.T) {
+	name, value, err := ParseVariable("region=us-west-2=x")
+	if err != nil || name != "region" || value != "us-west-2=x" {
+		t.Errorf("ParseVariable = %q, %q, %v", name, value, err)
+	}
+	for _, s := range []string{"region", "=us-west-2", "bad name=x"} {
+		if _, _, err := ParseVariable(s); err == nil {
+			t.Errorf("Expected ParseVariable(%q) to fail", s)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("EXPAND_TEST", "value")
+	if got := expandEnv("$EXPAND_TEST ${EXPAND_TEST} ${var.name}"); got != "value value ${var.name}" {
+		t.Errorf("expandEnv = %q", got)
+	}
+}
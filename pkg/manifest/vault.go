@@ -0,0 +1,120 @@
+package manifest
+
+import (
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+)
+
+// VaultConfig is the HashiCorp Vault server secrets with vault_path and
+// credentials with source vault are read from.
+type VaultConfig struct {
+	// Address of the server, e.g. https://vault.example.com:8200 - default: $VAULT_ADDR
+	Address string `yaml:"address,omitempty" json:"address,omitempty"`
+
+	// Vault Enterprise namespace, e.g. admin/team-a - default: $VAULT_NAMESPACE
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+
+	// Mount of the KV secrets engine holding provider credentials - default: "secret"
+	KVMount string `yaml:"kv_mount,omitempty" json:"kv_mount,omitempty"`
+
+	// How to log in to the server
+	Auth VaultAuthConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
+
+	// Skip verification of the server's TLS certificate (only for dev/testing) - default: false
+	TLSSkipVerify bool `yaml:"tls_skip_verify,omitempty" json:"tls_skip_verify,omitempty"`
+}
+
+// VaultAuthConfig is how cloud-deploy logs in to Vault.
+type VaultAuthConfig struct {
+	// Auth method: "token", "approle", "aws-iam", or "kubernetes" - default: "token"
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+
+	// Path the auth method is mounted at, e.g. ci-approle - default: "approle", "aws", or "kubernetes"
+	MountPath string `yaml:"mount_path,omitempty" json:"mount_path,omitempty"`
+
+	// Token, e.g. ${VAULT_TOKEN} (token) - default: $VAULT_TOKEN
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+
+	// AppRole role ID, e.g. ${VAULT_ROLE_ID} (approle)
+	RoleID string `yaml:"role_id,omitempty" json:"role_id,omitempty"`
+
+	// AppRole secret ID, e.g. ${VAULT_SECRET_ID} (approle)
+	SecretID string `yaml:"secret_id,omitempty" json:"secret_id,omitempty"`
+
+	// Vault role to log in as (aws-iam, kubernetes)
+	Role string `yaml:"role,omitempty" json:"role,omitempty"`
+
+	// Region of the STS endpoint the login request is signed for (aws-iam) - default: the global endpoint
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+
+	// Value of the X-Vault-AWS-IAM-Server-ID header the auth method requires, if any (aws-iam)
+	ServerID string `yaml:"server_id,omitempty" json:"server_id,omitempty"`
+
+	// File holding the pod's service account token (kubernetes) - default: /var/run/secrets/kubernetes.io/serviceaccount/token
+	ServiceAccountTokenPath string `yaml:"service_account_token_path,omitempty" json:"service_account_token_path,omitempty"`
+}
+
+// validate checks the auth method and the settings it needs.
+func (c *VaultConfig) validate() error {
+	a := c.Auth
+	switch a.Method {
+	case "", vault.AuthToken:
+	case vault.AuthAppRole:
+		if a.RoleID == "" || a.SecretID == "" {
+			return fieldError("vault.auth", "vault.auth.role_id and vault.auth.secret_id are required for approle authentication")
+		}
+	case vault.AuthAWSIAM, vault.AuthKubernetes:
+		if a.Role == "" {
+			return fieldError("vault.auth.role", "vault.auth.role is required for %s authentication", a.Method)
+		}
+	default:
+		return fieldError("vault.auth.method", "vault.auth.method must be token, approle, aws-iam, or kubernetes, got %q", a.Method)
+	}
+	return nil
+}
+
+// ClientConfig returns the configuration of a client for the Vault server.
+func (c *VaultConfig) ClientConfig() *vault.Config {
+	return &vault.Config{
+		Address:       c.Address,
+		TLSSkipVerify: c.TLSSkipVerify,
+		Namespace:     c.Namespace,
+		KVMount:       c.KVMount,
+		Auth: vault.AuthConfig{
+			Method:                  c.Auth.Method,
+			MountPath:               c.Auth.MountPath,
+			Token:                   c.Auth.Token,
+			RoleID:                  c.Auth.RoleID,
+			SecretID:                c.Auth.SecretID,
+			Role:                    c.Auth.Role,
+			Region:                  c.Auth.Region,
+			ServerID:                c.Auth.ServerID,
+			ServiceAccountTokenPath: c.Auth.ServiceAccountTokenPath,
+		},
+	}
+}
+
+// usesVault returns true if secrets or the credentials of a target are read from Vault.
+func (m *Manifest) usesVault() bool {
+	for _, s := range m.Secrets {
+		if s.SecretSource() == SecretSourceVault {
+			return true
+		}
+	}
+	for _, target := range m.Targets() {
+		if c := target.Provider.Credentials; c != nil && c.Source == "vault" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVault checks the vault section, which Vault secrets and credentials need.
+func (m *Manifest) validateVault(errs *ValidationErrors) {
+	if m.Vault != nil {
+		errs.add("vault", m.Vault.validate())
+		return
+	}
+	if m.usesVault() {
+		errs.addf("vault", "vault configuration is required when secrets have vault_path or credentials.source is vault")
+	}
+}
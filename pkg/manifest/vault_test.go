@@ -0,0 +1,76 @@
+package manifest
+
+import "testing"
+
+func TestValidateVault(t *testing.T) {
+	base := func() *Manifest {
+		return &Manifest{
+			Image:       "my-app:latest",
+			Provider:    ProviderConfig{Name: "aws", Region: "us-east-1"},
+			Application: ApplicationConfig{Name: "my-app"},
+			Environment: EnvironmentConfig{Name: "my-env"},
+			Secrets:     []SecretRef{{Name: "DATABASE_URL", VaultPath: "secret/data/myapp/db", VaultKey: "url"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(m *Manifest)
+		wantErr string
+	}{
+		{name: "token", modify: func(m *Manifest) { m.Vault = &VaultConfig{Address: "http://127.0.0.1:8200"} }},
+		{name: "approle", modify: func(m *Manifest) {
+			m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "approle", RoleID: "role", SecretID: "secret"}}
+		}},
+		{name: "aws-iam", modify: func(m *Manifest) { m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "aws-iam", Role: "deployer"}} }},
+		{name: "kubernetes", modify: func(m *Manifest) {
+			m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "kubernetes", Role: "deployer", ServiceAccountTokenPath: "/tmp/token"}}
+		}},
+		{name: "missing vault", modify: func(m *Manifest) {}, wantErr: "vault configuration is required"},
+		{name: "missing vault for credentials", modify: func(m *Manifest) {
+			m.Secrets = nil
+			m.Provider.Credentials = &CredentialsConfig{Source: "vault"}
+		}, wantErr: "vault configuration is required"},
+		{name: "unknown method", modify: func(m *Manifest) { m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "ldap"}} }, wantErr: `vault.auth.method must be token, approle, aws-iam, or kubernetes, got "ldap"`},
+		{name: "approle without secret", modify: func(m *Manifest) { m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "approle", RoleID: "role"}} }, wantErr: "role_id and vault.auth.secret_id are required"},
+		{name: "aws-iam without role", modify: func(m *Manifest) { m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "aws-iam"}} }, wantErr: "vault.auth.role is required"},
+		{name: "kubernetes without role", modify: func(m *Manifest) { m.Vault = &VaultConfig{Auth: VaultAuthConfig{Method: "kubernetes"}} }, wantErr: "vault.auth.role is required for kubernetes"},
+		{name: "vault without path", modify: func(m *Manifest) {
+			m.Vault = &VaultConfig{}
+			m.Secrets = []SecretRef{{Name: "X", Source: "vault", SecretID: "x"}}
+		}, wantErr: "vault_path is required"},
+		{name: "vault key on secrets manager", modify: func(m *Manifest) {
+			m.Secrets = []SecretRef{{Name: "X", Source: "secrets-manager", SecretID: "x", VaultKey: "url"}}
+		}, wantErr: "vault_path and vault_key require source vault"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base()
+			tt.modify(m)
+			err := m.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSecretSource(t *testing.T) {
+	tests := map[string]SecretRef{
+		SecretSourceSecretsManager:   {SecretID: "myapp/db"},
+		SecretSourceVault:            {VaultPath: "secret/data/myapp/db"},
+		SecretSourceGCPSecretManager: {Source: "gcp-secret-manager", SecretID: "db"},
+	}
+	for want, ref := range tests {
+		if got := ref.SecretSource(); got != want {
+			t.Errorf("SecretSource() of %+v = %q, want %q", ref, got, want)
+		}
+	}
+
+	m := &Manifest{Provider: ProviderConfig{Name: "aws", Credentials: &CredentialsConfig{Source: "vault"}}, Vault: &VaultConfig{Address: "https://vault.example.com", Namespace: "team-a", KVMount: "kv", Auth: VaultAuthConfig{Method: "approle", MountPath: "ci"}}}
+	if mgr := m.CredentialsManager(); mgr.Vault == nil || mgr.Vault.Address != "https://vault.example.com" || mgr.Vault.Namespace != "team-a" || mgr.Vault.KVMount != "kv" || mgr.Vault.Auth.MountPath != "ci" {
+		t.Errorf("Expected the manager to read from the manifest's Vault server, got %+v", mgr)
+	}
+}
@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VerificationConfig holds the HTTP checks run against the deployment's URL
+// after deploy. When any check fails, the deployment is rolled back and deploy
+// fails.
+type VerificationConfig struct {
+	// Checks to run - required
+	Checks []VerificationCheck `yaml:"checks" json:"checks"`
+}
+
+// VerificationCheck is an HTTP request to the deployment and the response it
+// must get.
+type VerificationCheck struct {
+	// Name of the check (e.g., "health") - default: the path
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Path requested with GET, relative to the deployment's URL (e.g., /health) - required
+	Path string `yaml:"path" json:"path"`
+
+	// HTTP status the response must have - default: any 2xx or 3xx status
+	ExpectedStatus int `yaml:"expected_status,omitempty" json:"expected_status,omitempty"`
+
+	// Seconds to wait for each response (1 to 60) - default: 5
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+
+	// Attempts after the first that fails before the check fails (0 to 20) - default: 2
+	Retries *int `yaml:"retries,omitempty" json:"retries,omitempty"`
+
+	// Seconds between attempts (0 to 300) - default: 5
+	IntervalSeconds *int `yaml:"interval_seconds,omitempty" json:"interval_seconds,omitempty"`
+}
+
+// Defaults of verification checks.
+const (
+	DefaultVerificationTimeout  = 5
+	DefaultVerificationRetries  = 2
+	DefaultVerificationInterval = 5
+)
+
+// CheckName returns the name of the check, or its path when it has none.
+func (c VerificationCheck) CheckName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Path
+}
+
+// Timeout returns how long to wait for each response of the check.
+func (c VerificationCheck) Timeout() time.Duration {
+	if c.TimeoutSeconds == 0 {
+		return DefaultVerificationTimeout * time.Second
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Attempts returns how many times the check is tried before it fails.
+func (c VerificationCheck) Attempts() int {
+	if c.Retries == nil {
+		return DefaultVerificationRetries + 1
+	}
+	return *c.Retries + 1
+}
+
+// Interval returns how long to wait between attempts of the check.
+func (c VerificationCheck) Interval() time.Duration {
+	if c.IntervalSeconds == nil {
+		return DefaultVerificationInterval * time.Second
+	}
+	return time.Duration(*c.IntervalSeconds) * time.Second
+}
+
+// validate checks the verification checks.
+func (c *VerificationConfig) validate() error {
+	if len(c.Checks) == 0 {
+		return fmt.Errorf("verification.checks must have at least one check")
+	}
+	for i, check := range c.Checks {
+		field := fmt.Sprintf("verification.checks[%d]", i)
+		if !strings.HasPrefix(check.Path, "/") {
+			return fmt.Errorf("%s.path must start with /, got %q", field, check.Path)
+		}
+		if check.ExpectedStatus != 0 && (check.ExpectedStatus < 100 || check.ExpectedStatus > 599) {
+			return fmt.Errorf("%s.expected_status must be an HTTP status from 100 to 599, got %d", field, check.ExpectedStatus)
+		}
+		if check.TimeoutSeconds < 0 || check.TimeoutSeconds > 60 {
+			return fmt.Errorf("%s.timeout_seconds must be from 1 to 60, got %d", field, check.TimeoutSeconds)
+		}
+		if check.Retries != nil && (*check.Retries < 0 || *check.Retries > 20) {
+			return fmt.Errorf("%s.retries must be from 0 to 20, got %d", field, *check.Retries)
+		}
+		if check.IntervalSeconds != nil && (*check.IntervalSeconds < 0 || *check.IntervalSeconds > 300) {
+			return fmt.Errorf("%s.interval_seconds must be from 0 to 300, got %d", field, *check.IntervalSeconds)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package manifest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateVerification(t *testing.T) {
+	negative, tooMany := -1, 21
+	tests := []struct {
+		name    string
+		cfg     VerificationConfig
+		wantErr string
+	}{
+		{name: "valid", cfg: VerificationConfig{Checks: []VerificationCheck{{Path: "/health", ExpectedStatus: 204}}}},
+		{name: "no checks", cfg: VerificationConfig{}, wantErr: "at least one check"},
+		{name: "relative path", cfg: VerificationConfig{Checks: []VerificationCheck{{Path: "health"}}}, wantErr: "verification.checks[0].path must start with /"},
+		{name: "bad status", cfg: VerificationConfig{Checks: []VerificationCheck{{Path: "/", ExpectedStatus: 42}}}, wantErr: "expected_status must be an HTTP status"},
+		{name: "bad timeout", cfg: VerificationConfig{Checks: []VerificationCheck{{Path: "/", TimeoutSeconds: 61}}}, wantErr: "timeout_seconds must be from 1 to 60"},
+		{name: "bad retries", cfg: VerificationConfig{Checks: []VerificationCheck{{Path: "/", Retries: &tooMany}}}, wantErr: "retries must be from 0 to 20"},
+		{name: "bad interval", cfg: VerificationConfig{Checks: []VerificationCheck{{Path: "/", IntervalSeconds: &negative}}}, wantErr: "interval_seconds must be from 0 to 300"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerificationCheckDefaults(t *testing.T) {
+	c := VerificationCheck{Path: "/health"}
+	if c.CheckName() != "/health" || c.Timeout() != 5*time.Second || c.Attempts() != 3 || c.Interval() != 5*time.Second {
+		t.Errorf("Unexpected defaults: %s %v %d %v", c.CheckName(), c.Timeout(), c.Attempts(), c.Interval())
+	}
+	zero := 0
+	c = VerificationCheck{Name: "health", Path: "/health", TimeoutSeconds: 2, Retries: &zero, IntervalSeconds: &zero}
+	if c.CheckName() != "health" || c.Timeout() != 2*time.Second || c.Attempts() != 1 || c.Interval() != 0 {
+		t.Errorf("Unexpected settings: %s %v %d %v", c.CheckName(), c.Timeout(), c.Attempts(), c.Interval())
+	}
+}
@@ -0,0 +1,246 @@
+// Package notify posts the results of deploys, rollbacks, and destroys to
+// the manifest's notification channels: Slack incoming webhooks, HTTP
+// webhooks, and email sent through SES or SendGrid.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// timeout is how long a notification may take to send.
+const timeout = 30 * time.Second
+
+// Result is the result of a command, as notified.
+type Result struct {
+	// cloud-deploy command, e.g. deploy
+	Command string `json:"command"`
+
+	Provider    string `json:"provider"`
+	Region      string `json:"region,omitempty"`
+	Application string `json:"application"`
+	Environment string `json:"environment"`
+
+	// Version label recorded in the deployment history
+	Version string `json:"version,omitempty"`
+
+	// Image deployed, pinned to its digest when the provider reports it
+	Image string `json:"image,omitempty"`
+
+	URL    string `json:"url,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// Succeeded reports whether the command succeeded; Error holds why not
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
+
+	// How long the command ran
+	Duration time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes the result with its duration in seconds.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		DurationSeconds float64 `json:"duration_seconds"`
+	}{result(r), r.Duration.Round(time.Second).Seconds()})
+}
+
+// Subject returns a one-line summary of the result, e.g. "deploy of
+// my-app-prod succeeded".
+func (r Result) Subject() string {
+	outcome := "succeeded"
+	if !r.Succeeded {
+		outcome = "failed"
+	}
+	return fmt.Sprintf("%s of %s %s", r.Command, r.Environment, outcome)
+}
+
+// Text returns the result as plain text: the summary followed by its
+// details, one per line.
+func (r Result) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s in %s\n", r.Subject(), r.Duration.Round(time.Second))
+	line := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", label, value)
+		}
+	}
+	line("Application", r.Application)
+	provider := r.Provider
+	if r.Region != "" {
+		provider += " (" + r.Region + ")"
+	}
+	line("Provider", provider)
+	line("Version", r.Version)
+	line("Image", r.Image)
+	line("URL", r.URL)
+	line("Status", r.Status)
+	line("Error", r.Error)
+	return b.String()
+}
+
+// Send posts r to every channel of cfg when cfg notifies about its command,
+// and returns the errors of the channels it failed to post to. defaultRegion
+// is the SES region of email without one.
+func Send(ctx context.Context, cfg *manifest.NotificationsConfig, defaultRegion string, r Result) error {
+	if !cfg.Notifies(r.Command, !r.Succeeded) {
+		return nil
+	}
+	var errs []error
+	for _, s := range cfg.Slack {
+		if err := postJSON(ctx, s.WebhookURL, nil, map[string]string{"text": r.Text()}); err != nil {
+			errs = append(errs, fmt.Errorf("slack notification failed: %w", err))
+		}
+	}
+	for _, w := range cfg.Webhooks {
+		if err := postJSON(ctx, w.URL, w.Headers, r); err != nil {
+			errs = append(errs, fmt.Errorf("webhook notification to %s failed: %w", host(w.URL), err))
+		}
+	}
+	for _, e := range cfg.Email {
+		var err error
+		if e.Service == manifest.EmailSES {
+			err = sendSES(ctx, e, defaultRegion, r)
+		} else {
+			err = sendSendGrid(ctx, e, r)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s email to %s failed: %w", e.Service, strings.Join(e.To, ", "), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postJSON POSTs body to rawURL as JSON, failing unless it responds with a
+// 2xx status.
+func postJSON(ctx context.Context, rawURL string, headers map[string]string, body any) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Report the cause without the URL, which may hold a token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return nil
+}
+
+// host returns the host of rawURL, which is safe to log.
+func host(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return "webhook"
+}
+
+// loadAWSConfig loads the AWS configuration SES email is sent with. It is a
+// variable so tests can replace it.
+var loadAWSConfig = func(ctx context.Context, region string) (aws.Config, error) {
+	return config.LoadDefaultConfig(ctx, config.WithRegion(region))
+}
+
+// sesEndpoint returns the SES API endpoint of region. It is a variable so
+// tests can replace it.
+var sesEndpoint = func(region string) string {
+	return "https://email." + region + ".amazonaws.com"
+}
+
+// sendSES sends r as email through the SES v2 API, in the email's region,
+// else defaultRegion, else us-east-1.
+func sendSES(ctx context.Context, e manifest.EmailNotification, defaultRegion string, r Result) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	region := e.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	cfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := awsapi.New(cfg, "ses")
+	client.SetEndpoint(sesEndpoint(region))
+
+	content := func(data string) map[string]string { return map[string]string{"Data": data} }
+	return client.REST(ctx, http.MethodPost, "/v2/email/outbound-emails", map[string]any{
+		"FromEmailAddress": e.From,
+		"Destination":      map[string]any{"ToAddresses": e.To},
+		"Content": map[string]any{
+			"Simple": map[string]any{
+				"Subject": content("cloud-deploy: " + r.Subject()),
+				"Body":    map[string]any{"Text": content(r.Text())},
+			},
+		},
+	}, nil)
+}
+
+// sendGridURL is the SendGrid mail send endpoint. It is a variable so tests
+// can replace it.
+var sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// sendGridAddress is an address of a SendGrid mail send request.
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// sendSendGrid sends r as email through the SendGrid API.
+func sendSendGrid(ctx context.Context, e manifest.EmailNotification, r Result) error {
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to := make([]sendGridAddress, len(e.To))
+	for i, addr := range e.To {
+		to[i] = sendGridAddress{Email: addr}
+		if parsed, err := mail.ParseAddress(addr); err == nil {
+			to[i] = sendGridAddress{Email: parsed.Address, Name: parsed.Name}
+		}
+	}
+	return postJSON(ctx, sendGridURL, map[string]string{"Authorization": "Bearer " + e.APIKey}, map[string]any{
+		"personalizations": []map[string]any{{"to": to}},
+		"from":             sendGridAddress{Email: from.Address, Name: from.Name},
+		"subject":          "cloud-deploy: " + r.Subject(),
+		"content":          []map[string]string{{"type": "text/plain", "value": r.Text()}},
+	})
+}
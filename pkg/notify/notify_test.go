@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func testResult() Result {
+	return Result{
+		Command:     "deploy",
+		Provider:    "aws",
+		Region:      "us-east-1",
+		Application: "my-app",
+		Environment: "my-app-prod",
+		Version:     "v12",
+		URL:         "http://my-app-prod.us-east-1.elasticbeanstalk.com",
+		Status:      "Ready",
+		Succeeded:   true,
+		Duration:    192 * time.Second,
+	}
+}
+
+// requestRecorder is a test server that records the requests it gets.
+type requestRecorder struct {
+	*httptest.Server
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+func newRequestRecorder(t *testing.T, status int) *requestRecorder {
+	rec := &requestRecorder{}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		rec.requests = append(rec.requests, recordedRequest{path: r.URL.Path, header: r.Header, body: body})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func TestResultText(t *testing.T) {
+	r := testResult()
+	if r.Subject() != "deploy of my-app-prod succeeded" {
+		t.Errorf("Subject() = %q", r.Subject())
+	}
+	text := r.Text()
+	for _, want := range []string{"deploy of my-app-prod succeeded in 3m12s\n", "Provider: aws (us-east-1)\n", "Version: v12\n", "URL: http://my-app-prod"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, want it to contain %q", text, want)
+		}
+	}
+	if strings.Contains(text, "Error:") {
+		t.Errorf("Expected no error line for a success, got %q", text)
+	}
+
+	r.Succeeded = false
+	r.Error = "2 of 3 checks failed"
+	if !strings.Contains(r.Text(), "deploy of my-app-prod failed in 3m12s\n") || !strings.Contains(r.Text(), "Error: 2 of 3 checks failed") {
+		t.Errorf("Text() = %q", r.Text())
+	}
+}
+
+func TestSendSlackAndWebhooks(t *testing.T) {
+	slack := newRequestRecorder(t, http.StatusOK)
+	webhook := newRequestRecorder(t, http.StatusOK)
+	cfg := &manifest.NotificationsConfig{
+		Slack:    []manifest.SlackNotification{{WebhookURL: slack.URL + "/services/T0/B0/x"}},
+		Webhooks: []manifest.WebhookNotification{{URL: webhook.URL + "/deploys", Headers: map[string]string{"Authorization": "Bearer t0ken"}}},
+	}
+	if err := Send(context.Background(), cfg, "", testResult()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(slack.requests) != 1 || !strings.HasPrefix(slack.requests[0].body["text"].(string), "deploy of my-app-prod succeeded") {
+		t.Errorf("Slack got %+v", slack.requests)
+	}
+	if len(webhook.requests) != 1 {
+		t.Fatalf("Webhook got %d requests", len(webhook.requests))
+	}
+	got := webhook.requests[0]
+	if got.header.Get("Authorization") != "Bearer t0ken" || got.body["version"] != "v12" || got.body["succeeded"] != true || got.body["duration_seconds"] != 192.0 {
+		t.Errorf("Webhook got %+v", got)
+	}
+}
+
+func TestSendFiltered(t *testing.T) {
+	slack := newRequestRecorder(t, http.StatusOK)
+	cfg := &manifest.NotificationsConfig{
+		OnlyFailures: true,
+		Slack:        []manifest.SlackNotification{{WebhookURL: slack.URL}},
+	}
+	if err := Send(context.Background(), cfg, "", testResult()); err != nil || len(slack.requests) != 0 {
+		t.Errorf("Expected a success not to be notified, got %v with %d requests", err, len(slack.requests))
+	}
+	if err := Send(context.Background(), nil, "", testResult()); err != nil {
+		t.Errorf("Expected no notifications without notifications, got %v", err)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	failing := newRequestRecorder(t, http.StatusInternalServerError)
+	ok := newRequestRecorder(t, http.StatusOK)
+	cfg := &manifest.NotificationsConfig{
+		Webhooks: []manifest.WebhookNotification{{URL: failing.URL + "/t0ken"}, {URL: ok.URL}},
+	}
+	err := Send(context.Background(), cfg, "", testResult())
+	if err == nil || !strings.Contains(err.Error(), "webhook notification to "+strings.TrimPrefix(failing.URL, "http://")+" failed: HTTP 500") {
+		t.Errorf("Expected the failing webhook's error, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "t0ken") {
+		t.Errorf("Expected the error not to hold the URL's path, got: %v", err)
+	}
+	if len(ok.requests) != 1 {
+		t.Error("Expected the other channels to be notified after a failure")
+	}
+}
+
+func TestSendSES(t *testing.T) {
+	ses := newRequestRecorder(t, http.StatusOK)
+	var region string
+	originalConfig, originalEndpoint := loadAWSConfig, sesEndpoint
+	loadAWSConfig = func(ctx context.Context, r string) (aws.Config, error) {
+		region = r
+		return aws.Config{Region: r, Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", "")}, nil
+	}
+	sesEndpoint = func(string) string { return ses.URL }
+	t.Cleanup(func() { loadAWSConfig, sesEndpoint = originalConfig, originalEndpoint })
+
+	cfg := &manifest.NotificationsConfig{
+		Email: []manifest.EmailNotification{{Service: manifest.EmailSES, From: "deploys@example.com", To: []string{"team@example.com"}}},
+	}
+	if err := Send(context.Background(), cfg, "eu-west-1", testResult()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if region != "eu-west-1" {
+		t.Errorf("Expected the default region, got %q", region)
+	}
+	if len(ses.requests) != 1 {
+		t.Fatalf("SES got %d requests", len(ses.requests))
+	}
+	got := ses.requests[0]
+	if got.path != "/v2/email/outbound-emails" || !strings.Contains(got.header.Get("Authorization"), "/eu-west-1/ses/aws4_request") {
+		t.Errorf("SES got %s with Authorization %q", got.path, got.header.Get("Authorization"))
+	}
+	subject := got.body["Content"].(map[string]any)["Simple"].(map[string]any)["Subject"].(map[string]any)["Data"]
+	if got.body["FromEmailAddress"] != "deploys@example.com" || subject != "cloud-deploy: deploy of my-app-prod succeeded" {
+		t.Errorf("SES got %+v", got.body)
+	}
+}
+
+func TestSendSendGrid(t *testing.T) {
+	sendGrid := newRequestRecorder(t, http.StatusAccepted)
+	original := sendGridURL
+	sendGridURL = sendGrid.URL + "/v3/mail/send"
+	t.Cleanup(func() { sendGridURL = original })
+
+	cfg := &manifest.NotificationsConfig{
+		Email: []manifest.EmailNotification{{Service: manifest.EmailSendGrid, From: "Deploys <deploys@example.com>", To: []string{"oncall@example.com"}, APIKey: "SG.key"}},
+	}
+	if err := Send(context.Background(), cfg, "", testResult()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(sendGrid.requests) != 1 {
+		t.Fatalf("SendGrid got %d requests", len(sendGrid.requests))
+	}
+	got := sendGrid.requests[0]
+	from := got.body["from"].(map[string]any)
+	if got.header.Get("Authorization") != "Bearer SG.key" || from["email"] != "deploys@example.com" || from["name"] != "Deploys" {
+		t.Errorf("SendGrid got %+v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package ociapi
+
+import (
+	"bufio"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultConfigFile returns the OCI CLI config file path: $OCI_CLI_CONFIG_FILE
+// or ~/.oci/config.
+func DefaultConfigFile() string {
+	if path := os.Getenv("OCI_CLI_CONFIG_FILE"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".oci", "config")
+	}
+	return filepath.Join(home, ".oci", "config")
+}
+
+// LoadConfigFile reads a profile of an OCI CLI config file. An empty profile
+// selects $OCI_CLI_PROFILE or DEFAULT. Values missing from the profile are
+// taken from DEFAULT, as the OCI CLI does.
+func LoadConfigFile(path, profile string) (*Config, error) {
+	if profile == "" {
+		profile = os.Getenv("OCI_CLI_PROFILE")
+	}
+	if profile == "" {
+		profile = "DEFAULT"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI config file: %w", err)
+	}
+	defer f.Close()
+
+	sections := make(map[string]map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		sections[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read OCI config file: %w", err)
+	}
+
+	values, ok := sections[profile]
+	if !ok && profile != "DEFAULT" {
+		return nil, fmt.Errorf("profile %s not found in %s", profile, path)
+	}
+	get := func(key string) string {
+		if v := values[key]; v != "" {
+			return v
+		}
+		return sections["DEFAULT"][key]
+	}
+
+	if get("pass_phrase") != "" {
+		return nil, fmt.Errorf("encrypted API signing keys (pass_phrase) are not supported")
+	}
+	keyFile := get("key_file")
+	if keyFile == "" {
+		return nil, fmt.Errorf("key_file is missing from profile %s in %s", profile, path)
+	}
+	key, err := LoadPrivateKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		Region:      get("region"),
+		TenancyID:   get("tenancy"),
+		UserID:      get("user"),
+		Fingerprint: get("fingerprint"),
+		PrivateKey:  key,
+	}, nil
+}
+
+// LoadPrivateKey reads a PEM-encoded RSA API signing key. A leading ~ in path
+// is expanded to the home directory.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API signing key: %w", err)
+	}
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey parses a PEM-encoded PKCS #1 or PKCS #8 RSA private key.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("API signing key is not PEM-encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("API signing key is not an RSA key")
+	}
+	return key, nil
+}
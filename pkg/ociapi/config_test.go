@@ -0,0 +1,106 @@
+package ociapi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKey(t *testing.T, dir string, pkcs8 bool) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		der, _ := x509.MarshalPKCS8PrivateKey(key)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	path := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writeKey(t, dir, true)
+	config := "[DEFAULT]\nuser=ocid1.user.oc1..default\nfingerprint=aa:bb\nkey_file=" + keyPath +
+		"\ntenancy=ocid1.tenancy.oc1..t\nregion=us-ashburn-1\n\n# comment\n[PROD]\nuser=ocid1.user.oc1..prod\nregion=eu-frankfurt-1\n"
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigFile(path, "")
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if cfg.UserID != "ocid1.user.oc1..default" || cfg.Region != "us-ashburn-1" || cfg.PrivateKey == nil {
+		t.Errorf("unexpected DEFAULT config: %+v", cfg)
+	}
+
+	cfg, err = LoadConfigFile(path, "PROD")
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if cfg.UserID != "ocid1.user.oc1..prod" || cfg.Region != "eu-frankfurt-1" {
+		t.Errorf("unexpected PROD config: %+v", cfg)
+	}
+	if cfg.TenancyID != "ocid1.tenancy.oc1..t" || cfg.Fingerprint != "aa:bb" {
+		t.Errorf("expected values to fall back to DEFAULT, got %+v", cfg)
+	}
+
+	if _, err := LoadConfigFile(path, "MISSING"); err == nil {
+		t.Error("expected error for missing profile")
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadConfigFile(filepath.Join(dir, "missing"), ""); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	path := filepath.Join(dir, "config")
+	os.WriteFile(path, []byte("[DEFAULT]\nuser=u\n"), 0600)
+	if _, err := LoadConfigFile(path, ""); err == nil || !strings.Contains(err.Error(), "key_file") {
+		t.Errorf("expected key_file error, got %v", err)
+	}
+
+	os.WriteFile(path, []byte("[DEFAULT]\nkey_file=k\npass_phrase=secret\n"), 0600)
+	if _, err := LoadConfigFile(path, ""); err == nil || !strings.Contains(err.Error(), "pass_phrase") {
+		t.Errorf("expected pass_phrase error, got %v", err)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	dir := t.TempDir()
+	for _, pkcs8 := range []bool{false, true} {
+		data, _ := os.ReadFile(writeKey(t, dir, pkcs8))
+		if _, err := ParsePrivateKey(data); err != nil {
+			t.Errorf("ParsePrivateKey(pkcs8=%v) failed: %v", pkcs8, err)
+		}
+	}
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Error("expected error for non-PEM data")
+	}
+}
+
+func TestDefaultConfigFile(t *testing.T) {
+	t.Setenv("OCI_CLI_CONFIG_FILE", "/etc/oci/config")
+	if got := DefaultConfigFile(); got != "/etc/oci/config" {
+		t.Errorf("unexpected config file: %s", got)
+	}
+	t.Setenv("OCI_CLI_CONFIG_FILE", "")
+	if got := DefaultConfigFile(); !strings.HasSuffix(got, filepath.Join(".oci", "config")) {
+		t.Errorf("unexpected default config file: %s", got)
+	}
+}
@@ -0,0 +1,216 @@
+// Package ociapi provides a minimal client for Oracle Cloud Infrastructure
+// REST APIs, signing requests with an API signing key the same way the OCI
+// CLI and SDKs do, so cloud-deploy needs no OCI SDK module.
+package ociapi
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the identity and API signing key requests are signed with.
+type Config struct {
+	Region      string
+	TenancyID   string
+	UserID      string
+	Fingerprint string
+	PrivateKey  *rsa.PrivateKey
+}
+
+// keyID returns the key identifier of the signing key.
+func (c *Config) keyID() string {
+	return c.TenancyID + "/" + c.UserID + "/" + c.Fingerprint
+}
+
+// Client calls a single OCI service endpoint.
+type Client struct {
+	config     *Config
+	endpoint   string
+	httpClient *http.Client
+}
+
+// New creates a client for the service at endpoint, for example
+// https://iaas.us-ashburn-1.oraclecloud.com for the Core Services API.
+func New(cfg *Config, endpoint string) *Client {
+	return &Client{
+		config:     cfg,
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Endpoint returns the URL requests are sent to.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// CloseIdleConnections closes the idle connections of the HTTP client
+// requests are sent with.
+func (c *Client) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// SetEndpoint overrides the URL requests are sent to.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = strings.TrimRight(endpoint, "/")
+}
+
+// APIError is an error returned by an OCI service.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s (HTTP %d, opc-request-id %s)", e.Code, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("%s: %s (HTTP %d, opc-request-id %s)", e.Code, e.Message, e.StatusCode, e.RequestID)
+}
+
+// IsErrorCode reports whether err is an APIError with the given code.
+func IsErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource.
+// OCI reports resources the caller cannot see the same way, as a 404.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Do sends a signed request for path (including any query string). in is
+// marshalled as the JSON body and the JSON response is unmarshalled into out;
+// either may be nil.
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	body, err := c.DoRaw(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// DoRaw sends a signed request like Do and returns the response body unparsed.
+func (c *Client) DoRaw(ctx context.Context, method, path string, in interface{}) ([]byte, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := c.sign(req, body); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", c.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("opc-request-id")}
+		var doc struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &doc) == nil {
+			apiErr.Code = doc.Code
+			apiErr.Message = doc.Message
+		}
+		return nil, apiErr
+	}
+	return respBody, nil
+}
+
+// sign adds the Authorization header of OCI request signing version 1, an
+// HTTP Signature (draft-cavage) over the date, request target, and host and,
+// for requests with a body, its length, type, and SHA-256 digest.
+func (c *Client) sign(req *http.Request, body []byte) error {
+	if c.config == nil || c.config.PrivateKey == nil {
+		return fmt.Errorf("no OCI API signing key configured")
+	}
+
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"date", "(request-target)", "host"}
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		req.Header.Set("Content-Type", "application/json")
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	signature, err := signRequest(c.config.PrivateKey, req, headers)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		c.config.keyID(), strings.Join(headers, " "), signature))
+	return nil
+}
+
+// signingString returns the string signed for the given headers of req.
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.URL.Host
+		default:
+			value = req.Header.Get(h)
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// signRequest signs the signing string of req with key, returning it base64-encoded.
+func signRequest(key *rsa.PrivateKey, req *http.Request, headers []string) (string, error) {
+	digest := sha256.Sum256([]byte(signingString(req, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign request: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
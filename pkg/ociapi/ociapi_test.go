@@ -0,0 +1,139 @@
+package ociapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return &Config{
+		Region:      "us-ashburn-1",
+		TenancyID:   "ocid1.tenancy.oc1..aaa",
+		UserID:      "ocid1.user.oc1..bbb",
+		Fingerprint: "20:3b:97:13",
+		PrivateKey:  key,
+	}
+}
+
+var authPattern = regexp.MustCompile(`^Signature version="1",keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"$`)
+
+// verifySignature checks the Authorization header of r against the public key of cfg.
+func verifySignature(t *testing.T, cfg *Config, r *http.Request) []string {
+	t.Helper()
+	match := authPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		t.Fatalf("unexpected Authorization header: %q", r.Header.Get("Authorization"))
+	}
+	if match[1] != cfg.keyID() {
+		t.Errorf("expected keyId %s, got %s", cfg.keyID(), match[1])
+	}
+	headers := strings.Split(match[2], " ")
+
+	// The server sees the Host header rather than URL.Host
+	r.URL.Host = r.Host
+	digest := sha256.Sum256([]byte(signingString(r, headers)))
+	sig, _ := base64.StdEncoding.DecodeString(match[3])
+	if err := rsa.VerifyPKCS1v15(&cfg.PrivateKey.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	return headers
+}
+
+func TestNewEndpoint(t *testing.T) {
+	c := New(testConfig(t), "http://localhost:8080/")
+	if c.Endpoint() != "http://localhost:8080" {
+		t.Errorf("unexpected endpoint: %s", c.Endpoint())
+	}
+}
+
+func TestDoGetSigned(t *testing.T) {
+	cfg := testConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := verifySignature(t, cfg, r)
+		if strings.Join(headers, " ") != "date (request-target) host" {
+			t.Errorf("unexpected signed headers: %v", headers)
+		}
+		if r.URL.RequestURI() != "/20160918/vnics/ocid1.vnic?x=1" {
+			t.Errorf("unexpected request URI: %s", r.URL.RequestURI())
+		}
+		w.Write([]byte(`{"publicIp":"203.0.113.10"}`))
+	}))
+	defer server.Close()
+
+	var out struct {
+		PublicIP string `json:"publicIp"`
+	}
+	c := New(cfg, server.URL)
+	if err := c.Do(context.Background(), http.MethodGet, "/20160918/vnics/ocid1.vnic?x=1", nil, &out); err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	if out.PublicIP != "203.0.113.10" {
+		t.Errorf("unexpected response: %+v", out)
+	}
+}
+
+func TestDoPostSignsBody(t *testing.T) {
+	cfg := testConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := verifySignature(t, cfg, r)
+		if len(headers) != 6 {
+			t.Errorf("expected body headers to be signed, got %v", headers)
+		}
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Content-Sha256") != base64.StdEncoding.EncodeToString(sum[:]) {
+			t.Errorf("x-content-sha256 does not match the body")
+		}
+		if string(body) != `{"name":"demo"}` {
+			t.Errorf("unexpected body: %s", body)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := New(cfg, server.URL)
+	if err := c.Do(context.Background(), http.MethodPost, "/items", map[string]string{"name": "demo"}, nil); err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+}
+
+func TestDoAPIError(t *testing.T) {
+	cfg := testConfig(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("opc-request-id", "req-1")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"NotAuthorizedOrNotFound","message":"Authorization failed or requested resource not found."}`))
+	}))
+	defer server.Close()
+
+	c := New(cfg, server.URL)
+	err := c.Do(context.Background(), http.MethodGet, "/missing", nil, nil)
+	if !IsNotFound(err) || !IsErrorCode(err, "NotAuthorizedOrNotFound") {
+		t.Fatalf("expected not found API error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "req-1") {
+		t.Errorf("expected request ID in error, got %v", err)
+	}
+}
+
+func TestDoWithoutKey(t *testing.T) {
+	c := New(&Config{}, "http://localhost")
+	if err := c.Do(context.Background(), http.MethodGet, "/", nil, nil); err == nil {
+		t.Error("expected error without a signing key")
+	}
+}
@@ -0,0 +1,144 @@
+// Package output writes the result of a command as one JSON or YAML document
+// on stdout, for CI pipelines and scripts to parse. Text meant for people
+// remains the default.
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how a command writes its result.
+type Format string
+
+// Output formats.
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ParseFormat returns the format named s; an empty s is Text.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case "":
+		return Text, nil
+	case Text, JSON, YAML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (valid: text, json, yaml)", s)
+	}
+}
+
+// Structured reports whether f is written as a document rather than text.
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// Document is what a command writes in a structured format.
+type Document struct {
+	// cloud-deploy command, e.g. deploy
+	Command string `json:"command"`
+
+	// Whether the command succeeded
+	Succeeded bool `json:"succeeded"`
+
+	// What the command produced, such as the types.DeploymentResult of a
+	// deploy or the types.DeploymentStatus of status - optional
+	Result any `json:"result,omitempty"`
+
+	// Error messages logged while the command ran, in order
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Write writes v to w in format f. Both JSON and YAML use the names of v's
+// json tags, so the two have the same fields.
+func Write(w io.Writer, f Format, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if f == YAML {
+		// JSON is YAML, so decoding it keeps the fields' names and order
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		blockStyle(&node)
+		if data, err = yaml.Marshal(&node); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// blockStyle clears the flow style and quoting decoding JSON gives n and its
+// children, so they are written as YAML usually is.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+// Errors collects the error messages logged while a command runs, for its
+// document.
+type Errors struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+// Handler returns a log handler that collects the messages of error records
+// and passes every record on to next.
+func (e *Errors) Handler(next slog.Handler) slog.Handler {
+	return &handler{errors: e, next: next}
+}
+
+// Messages returns the error messages collected so far.
+func (e *Errors) Messages() []string {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.messages...)
+}
+
+// handler collects error messages.
+type handler struct {
+	errors *Errors
+	next   slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level >= slog.LevelError {
+		h.errors.mu.Lock()
+		h.errors.messages = append(h.errors.messages, rec.Message)
+		h.errors.mu.Unlock()
+	}
+	if !h.next.Enabled(ctx, rec.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{errors: h.errors, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{errors: h.errors, next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type result struct {
+	Name    string            `json:"application_name"`
+	Version string            `json:"version"`
+	Images  map[string]string `json:"images,omitempty"`
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"": Text, "text": Text, "json": JSON, "yaml": YAML} {
+		if got, err := ParseFormat(s); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil || !strings.Contains(err.Error(), `unknown output format "xml"`) {
+		t.Errorf("Expected an unknown format error, got: %v", err)
+	}
+	if Text.Structured() || !JSON.Structured() || !YAML.Structured() {
+		t.Error("Expected only JSON and YAML to be structured")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	doc := Document{Command: "deploy", Succeeded: true, Result: result{Name: "my-app", Version: "1.10"}}
+	if err := Write(&out, JSON, doc); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Expected valid JSON, got %q: %v", out.String(), err)
+	}
+	if got["command"] != "deploy" || got["succeeded"] != true || got["result"].(map[string]any)["application_name"] != "my-app" {
+		t.Errorf("Unexpected document: %v", got)
+	}
+	if _, ok := got["errors"]; ok {
+		t.Error("Expected no errors field for a success")
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	var out bytes.Buffer
+	doc := Document{
+		Command: "deploy",
+		Result:  result{Name: "my-app", Version: "1.10", Images: map[string]string{"web": "my-app@sha256:abc"}},
+		Errors:  []string{"✗ Deployment failed: timed out"},
+	}
+	if err := Write(&out, YAML, doc); err != nil {
+		t.Fatal(err)
+	}
+	want := `command: deploy
+succeeded: false
+result:
+    application_name: my-app
+    version: "1.10"
+    images:
+        web: my-app@sha256:abc
+errors:
+    - '✗ Deployment failed: timed out'
+`
+	if out.String() != want {
+		t.Errorf("Write(YAML) =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var logged bytes.Buffer
+	errs := &Errors{}
+	logger := slog.New(errs.Handler(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	logger.Info("Deploying")
+	logger.Error("✗ Deployment failed")
+	logger.With("provider", "aws").Error("✗ Rollback failed")
+
+	if got := errs.Messages(); len(got) != 2 || got[0] != "✗ Deployment failed" || got[1] != "✗ Rollback failed" {
+		t.Errorf("Messages() = %q", got)
+	}
+	if strings.Contains(logged.String(), "Deploying") || !strings.Contains(logged.String(), "Rollback failed") {
+		t.Errorf("Expected records to be passed on at the next handler's level, got %q", logged.String())
+	}
+
+	var none *Errors
+	if none.Messages() != nil {
+		t.Error("Expected no messages without a collector")
+	}
+}
@@ -0,0 +1,144 @@
+// Package policy evaluates organization-wide rules against cloud-deploy manifests
+// before anything is deployed, so that platform teams can require settings such as
+// container hardening in production environments and cap the resources each
+// environment class may request.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Policy is a set of rules that manifests must satisfy.
+//
+// Example policy file:
+//
+//	default_class: dev
+//	security:
+//	  - environments: [prod]
+//	    require_read_only_root_filesystem: true
+//	    require_run_as_non_root: true
+//	    require_dropped_capabilities: [ALL]
+//	resources:
+//	  dev:
+//	    default: {cpu: 1, memory_gb: 0.5, instance_type: t3.micro}
+//	    max: {cpu: 2, memory_gb: 2, instance_type: t3.medium}
+type Policy struct {
+	// DefaultClass is the environment class assumed for manifests without environment.class
+	DefaultClass string `yaml:"default_class,omitempty" json:"default_class,omitempty"`
+
+	// Security rules for container hardening
+	Security []SecurityRule `yaml:"security,omitempty" json:"security,omitempty"`
+
+	// Resources maps environment classes to resource defaults and maximums
+	Resources map[string]ResourceRule `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+// SecurityRule requires security options for manifests in matching environments.
+type SecurityRule struct {
+	// Environments (environment classes) the rule applies to; empty applies to all
+	Environments []string `yaml:"environments,omitempty" json:"environments,omitempty"`
+
+	// RequireReadOnlyRootFilesystem requires security.read_only_root_filesystem
+	RequireReadOnlyRootFilesystem bool `yaml:"require_read_only_root_filesystem,omitempty" json:"require_read_only_root_filesystem,omitempty"`
+
+	// RequireRunAsNonRoot requires security.run_as_non_root
+	RequireRunAsNonRoot bool `yaml:"require_run_as_non_root,omitempty" json:"require_run_as_non_root,omitempty"`
+
+	// RequireDroppedCapabilities lists capabilities that security.drop_capabilities must
+	// include; dropping "ALL" satisfies any capability
+	RequireDroppedCapabilities []string `yaml:"require_dropped_capabilities,omitempty" json:"require_dropped_capabilities,omitempty"`
+}
+
+// Load reads and parses a policy file.
+func Load(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return &p, nil
+}
+
+// Check evaluates the policy against a manifest and returns an error listing
+// every violated rule, or nil if the manifest complies. Call ApplyDefaults first
+// so that defaulted resources are checked too.
+func (p *Policy) Check(m *manifest.Manifest) error {
+	class := p.class(m)
+
+	var violations []error
+	for _, rule := range p.Security {
+		if !rule.appliesTo(class) {
+			continue
+		}
+		violations = append(violations, rule.check(m, class)...)
+	}
+	violations = append(violations, p.checkResources(m)...)
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("manifest violates policy: %w", errors.Join(violations...))
+}
+
+// appliesTo reports whether the rule applies to an environment class.
+func (r SecurityRule) appliesTo(class string) bool {
+	if len(r.Environments) == 0 {
+		return true
+	}
+	for _, env := range r.Environments {
+		if strings.EqualFold(env, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns a violation for each requirement the manifest does not meet.
+func (r SecurityRule) check(m *manifest.Manifest, class string) []error {
+	sec := m.Security
+	if sec == nil {
+		sec = &manifest.SecurityConfig{}
+	}
+
+	scope := "all environments"
+	if len(r.Environments) > 0 {
+		scope = fmt.Sprintf("environment class %q", class)
+	}
+
+	var violations []error
+	if r.RequireReadOnlyRootFilesystem && !sec.ReadOnlyRootFilesystem {
+		violations = append(violations, fmt.Errorf("security.read_only_root_filesystem must be true for %s", scope))
+	}
+	if r.RequireRunAsNonRoot && !sec.RunAsNonRoot {
+		violations = append(violations, fmt.Errorf("security.run_as_non_root must be true for %s", scope))
+	}
+	for _, capability := range r.RequireDroppedCapabilities {
+		if !drops(sec.DropCapabilities, capability) {
+			violations = append(violations, fmt.Errorf("security.drop_capabilities must include %s for %s", capability, scope))
+		}
+	}
+	return violations
+}
+
+// drops reports whether dropped covers capability.
+func drops(dropped []string, capability string) bool {
+	capability = strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+	for _, d := range dropped {
+		d = strings.TrimPrefix(strings.ToUpper(d), "CAP_")
+		if d == "ALL" || d == capability {
+			return true
+		}
+	}
+	return false
+}
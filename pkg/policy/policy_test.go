@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func prodRule() SecurityRule {
+	return SecurityRule{
+		Environments:                  []string{"prod"},
+		RequireReadOnlyRootFilesystem: true,
+		RequireRunAsNonRoot:           true,
+		RequireDroppedCapabilities:    []string{"NET_RAW"},
+	}
+}
+
+func TestCheckCompliant(t *testing.T) {
+	p := &Policy{Security: []SecurityRule{prodRule()}}
+	m := &manifest.Manifest{
+		Environment: manifest.EnvironmentConfig{Name: "app-prod", Class: "prod"},
+		Security: &manifest.SecurityConfig{
+			ReadOnlyRootFilesystem: true,
+			RunAsNonRoot:           true,
+			DropCapabilities:       []string{"ALL"},
+		},
+	}
+
+	if err := p.Check(m); err != nil {
+		t.Errorf("Expected compliant manifest, got: %v", err)
+	}
+}
+
+func TestCheckViolations(t *testing.T) {
+	p := &Policy{Security: []SecurityRule{prodRule()}}
+	m := &manifest.Manifest{
+		Environment: manifest.EnvironmentConfig{Name: "app-prod", Class: "PROD"},
+	}
+
+	err := p.Check(m)
+	if err == nil {
+		t.Fatal("Expected policy violations")
+	}
+	for _, s := range []string{"read_only_root_filesystem", "run_as_non_root", "must include NET_RAW", `environment class "PROD"`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Expected error to contain %q, got: %v", s, err)
+		}
+	}
+}
+
+func TestCheckOtherEnvironment(t *testing.T) {
+	p := &Policy{Security: []SecurityRule{prodRule()}}
+	m := &manifest.Manifest{
+		Environment: manifest.EnvironmentConfig{Name: "app-dev", Class: "dev"},
+	}
+
+	if err := p.Check(m); err != nil {
+		t.Errorf("Expected prod rule not to apply to dev, got: %v", err)
+	}
+}
+
+func TestCheckRuleWithoutEnvironments(t *testing.T) {
+	p := &Policy{Security: []SecurityRule{{RequireRunAsNonRoot: true}}}
+	m := &manifest.Manifest{}
+
+	err := p.Check(m)
+	if err == nil || !strings.Contains(err.Error(), "all environments") {
+		t.Errorf("Expected rule to apply to all environments, got: %v", err)
+	}
+}
+
+func TestDrops(t *testing.T) {
+	tests := []struct {
+		dropped    []string
+		capability string
+		expected   bool
+	}{
+		{[]string{"ALL"}, "NET_RAW", true},
+		{[]string{"NET_RAW"}, "NET_RAW", true},
+		{[]string{"cap_net_raw"}, "NET_RAW", true},
+		{[]string{"NET_RAW"}, "CAP_NET_RAW", true},
+		{[]string{"SYS_ADMIN"}, "NET_RAW", false},
+		{nil, "NET_RAW", false},
+	}
+	for _, tt := range tests {
+		if got := drops(tt.dropped, tt.capability); got != tt.expected {
+			t.Errorf("drops(%v, %q) = %v, want %v", tt.dropped, tt.capability, got, tt.expected)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	content := `security:
+  - environments: [prod, staging]
+    require_read_only_root_filesystem: true
+    require_dropped_capabilities: [ALL]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(p.Security) != 1 {
+		t.Fatalf("Expected 1 security rule, got %d", len(p.Security))
+	}
+	rule := p.Security[0]
+	if len(rule.Environments) != 2 || !rule.RequireReadOnlyRootFilesystem || rule.RequireDroppedCapabilities[0] != "ALL" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing policy file")
+	}
+}
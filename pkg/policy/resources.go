@@ -0,0 +1,214 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// ResourceRule sets default and maximum compute resources for an environment class.
+type ResourceRule struct {
+	// Default resources applied when the manifest does not set them
+	Default ResourceLimits `yaml:"default,omitempty" json:"default,omitempty"`
+
+	// Max resources a manifest may request
+	Max ResourceLimits `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// ResourceLimits describes compute resources across providers. CPU and memory apply to
+// GCP (cloud_run) and Azure (azure); instance types apply to AWS.
+type ResourceLimits struct {
+	// CPU cores (e.g., 0.5, 1, 2)
+	CPU float64 `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+
+	// Memory in GB (e.g., 0.5, 1.5, 4)
+	MemoryGB float64 `yaml:"memory_gb,omitempty" json:"memory_gb,omitempty"`
+
+	// InstanceType for AWS (e.g., t3.small). As a maximum, only the size
+	// (nano, micro, ..., large, xlarge, 2xlarge, ...) is compared.
+	InstanceType string `yaml:"instance_type,omitempty" json:"instance_type,omitempty"`
+}
+
+// class returns the environment class the policy evaluates a manifest as.
+func (p *Policy) class(m *manifest.Manifest) string {
+	if m.Environment.Class != "" {
+		return m.Environment.Class
+	}
+	return p.DefaultClass
+}
+
+// resourceRule returns the resource rule for the manifest's environment class, if any.
+func (p *Policy) resourceRule(m *manifest.Manifest) (ResourceRule, bool) {
+	class := p.class(m)
+	for name, rule := range p.Resources {
+		if strings.EqualFold(name, class) {
+			return rule, true
+		}
+	}
+	return ResourceRule{}, false
+}
+
+// ApplyDefaults fills in resources the manifest leaves unset from the defaults
+// for its environment class.
+func (p *Policy) ApplyDefaults(m *manifest.Manifest) {
+	rule, ok := p.resourceRule(m)
+	if !ok {
+		return
+	}
+	d := rule.Default
+
+	switch m.Provider.Name {
+	case "aws":
+		if m.Instance.Type == "" && d.InstanceType != "" {
+			m.Instance.Type = d.InstanceType
+		}
+	case "gcp":
+		if d.CPU == 0 && d.MemoryGB == 0 {
+			return
+		}
+		if m.CloudRun == nil {
+			m.CloudRun = &manifest.CloudRunConfig{}
+		}
+		if m.CloudRun.CPU == "" && d.CPU > 0 {
+			m.CloudRun.CPU = strconv.FormatFloat(d.CPU, 'f', -1, 64)
+		}
+		if m.CloudRun.Memory == "" && d.MemoryGB > 0 {
+			m.CloudRun.Memory = fmt.Sprintf("%dMi", int(d.MemoryGB*1024))
+		}
+	case "azure":
+		if d.CPU == 0 && d.MemoryGB == 0 {
+			return
+		}
+		if m.Azure == nil {
+			m.Azure = &manifest.AzureConfig{}
+		}
+		if m.Azure.CPU == 0 {
+			m.Azure.CPU = d.CPU
+		}
+		if m.Azure.MemoryGB == 0 {
+			m.Azure.MemoryGB = d.MemoryGB
+		}
+	}
+}
+
+// checkResources returns a violation for each resource above the maximum for the
+// manifest's environment class.
+func (p *Policy) checkResources(m *manifest.Manifest) []error {
+	rule, ok := p.resourceRule(m)
+	if !ok {
+		return nil
+	}
+	max := rule.Max
+	class := p.class(m)
+
+	var violations []error
+	switch m.Provider.Name {
+	case "aws":
+		if max.InstanceType != "" && m.Instance.Type != "" {
+			maxRank, err := instanceSizeRank(max.InstanceType)
+			if err != nil {
+				return []error{fmt.Errorf("invalid maximum instance type for environment class %q: %w", class, err)}
+			}
+			rank, err := instanceSizeRank(m.Instance.Type)
+			if err != nil {
+				return []error{err}
+			}
+			if rank > maxRank {
+				violations = append(violations, fmt.Errorf("instance.type %s exceeds the maximum size %s for environment class %q", m.Instance.Type, max.InstanceType, class))
+			}
+		}
+	case "gcp":
+		if m.CloudRun == nil {
+			return nil
+		}
+		if max.CPU > 0 && m.CloudRun.CPU != "" {
+			cpu, err := parseCPU(m.CloudRun.CPU)
+			if err != nil {
+				violations = append(violations, fmt.Errorf("cloud_run.cpu: %w", err))
+			} else if cpu > max.CPU {
+				violations = append(violations, fmt.Errorf("cloud_run.cpu %s exceeds the maximum of %g for environment class %q", m.CloudRun.CPU, max.CPU, class))
+			}
+		}
+		if max.MemoryGB > 0 && m.CloudRun.Memory != "" {
+			mem, err := parseMemoryGB(m.CloudRun.Memory)
+			if err != nil {
+				violations = append(violations, fmt.Errorf("cloud_run.memory: %w", err))
+			} else if mem > max.MemoryGB {
+				violations = append(violations, fmt.Errorf("cloud_run.memory %s exceeds the maximum of %gGB for environment class %q", m.CloudRun.Memory, max.MemoryGB, class))
+			}
+		}
+	case "azure":
+		if m.Azure == nil {
+			return nil
+		}
+		if max.CPU > 0 && m.Azure.CPU > max.CPU {
+			violations = append(violations, fmt.Errorf("azure.cpu %g exceeds the maximum of %g for environment class %q", m.Azure.CPU, max.CPU, class))
+		}
+		if max.MemoryGB > 0 && m.Azure.MemoryGB > max.MemoryGB {
+			violations = append(violations, fmt.Errorf("azure.memory_gb %g exceeds the maximum of %g for environment class %q", m.Azure.MemoryGB, max.MemoryGB, class))
+		}
+	}
+	return violations
+}
+
+// instanceSizeRank orders EC2 instance sizes (t3.micro < t3.large < m5.2xlarge < m5.metal).
+func instanceSizeRank(instanceType string) (int, error) {
+	_, size, ok := strings.Cut(instanceType, ".")
+	if !ok {
+		return 0, fmt.Errorf("invalid instance type %q", instanceType)
+	}
+
+	sizes := []string{"nano", "micro", "small", "medium", "large", "xlarge"}
+	for i, s := range sizes {
+		if size == s {
+			return i, nil
+		}
+	}
+	if strings.HasPrefix(size, "metal") {
+		return 1000, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(size, "xlarge")); err == nil && strings.HasSuffix(size, "xlarge") {
+		return len(sizes) - 1 + n, nil
+	}
+	return 0, fmt.Errorf("unknown size in instance type %q", instanceType)
+}
+
+// parseCPU parses a Cloud Run CPU value ("1", "0.5", "1000m") as cores.
+func parseCPU(s string) (float64, error) {
+	if milli, ok := strings.CutSuffix(s, "m"); ok {
+		v, err := strconv.ParseFloat(milli, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU value %q", s)
+		}
+		return v / 1000, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU value %q", s)
+	}
+	return v, nil
+}
+
+// parseMemoryGB parses a Cloud Run memory value ("512Mi", "2Gi", "1G") as GB.
+// Binary and decimal suffixes are treated alike, which is precise enough for caps.
+func parseMemoryGB(s string) (float64, error) {
+	units := []struct {
+		suffix string
+		gb     float64
+	}{
+		{"Gi", 1}, {"Mi", 1.0 / 1024}, {"Ki", 1.0 / (1024 * 1024)},
+		{"G", 1}, {"M", 1.0 / 1024}, {"K", 1.0 / (1024 * 1024)},
+	}
+	for _, u := range units {
+		if value, ok := strings.CutSuffix(s, u.suffix); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid memory value %q", s)
+			}
+			return v * u.gb, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid memory value %q (expected a Mi or Gi suffix)", s)
+}
@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func resourcePolicy() *Policy {
+	return &Policy{
+		DefaultClass: "dev",
+		Resources: map[string]ResourceRule{
+			"dev": {
+				Default: ResourceLimits{CPU: 1, MemoryGB: 0.5, InstanceType: "t3.micro"},
+				Max:     ResourceLimits{CPU: 2, MemoryGB: 2, InstanceType: "t3.medium"},
+			},
+			"prod": {
+				Max: ResourceLimits{CPU: 8, MemoryGB: 32, InstanceType: "m5.4xlarge"},
+			},
+		},
+	}
+}
+
+func TestApplyDefaultsAWS(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "aws"}}
+	resourcePolicy().ApplyDefaults(m)
+	if m.Instance.Type != "t3.micro" {
+		t.Errorf("Expected default instance type t3.micro, got %q", m.Instance.Type)
+	}
+
+	m = &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "aws"}, Instance: manifest.InstanceConfig{Type: "t3.small"}}
+	resourcePolicy().ApplyDefaults(m)
+	if m.Instance.Type != "t3.small" {
+		t.Errorf("Expected explicit instance type to be kept, got %q", m.Instance.Type)
+	}
+}
+
+func TestApplyDefaultsGCP(t *testing.T) {
+	m := &manifest.Manifest{
+		Provider: manifest.ProviderConfig{Name: "gcp"},
+		CloudRun: &manifest.CloudRunConfig{CPU: "2"},
+	}
+	resourcePolicy().ApplyDefaults(m)
+	if m.CloudRun.CPU != "2" {
+		t.Errorf("Expected explicit CPU to be kept, got %q", m.CloudRun.CPU)
+	}
+	if m.CloudRun.Memory != "512Mi" {
+		t.Errorf("Expected default memory 512Mi, got %q", m.CloudRun.Memory)
+	}
+}
+
+func TestApplyDefaultsAzure(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "azure"}}
+	resourcePolicy().ApplyDefaults(m)
+	if m.Azure == nil || m.Azure.CPU != 1 || m.Azure.MemoryGB != 0.5 {
+		t.Errorf("Expected Azure defaults cpu=1 memory_gb=0.5, got %+v", m.Azure)
+	}
+}
+
+func TestApplyDefaultsUnknownClass(t *testing.T) {
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "aws"},
+		Environment: manifest.EnvironmentConfig{Class: "sandbox"},
+	}
+	resourcePolicy().ApplyDefaults(m)
+	if m.Instance.Type != "" {
+		t.Errorf("Expected no defaults for unknown class, got %q", m.Instance.Type)
+	}
+}
+
+func TestCheckResourceCaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *manifest.Manifest
+		wantErr string
+	}{
+		{
+			name:    "aws instance too large for dev",
+			m:       &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "aws"}, Instance: manifest.InstanceConfig{Type: "c5.8xlarge"}},
+			wantErr: "exceeds the maximum size t3.medium",
+		},
+		{
+			name: "aws instance allowed in prod",
+			m: &manifest.Manifest{
+				Provider:    manifest.ProviderConfig{Name: "aws"},
+				Environment: manifest.EnvironmentConfig{Class: "prod"},
+				Instance:    manifest.InstanceConfig{Type: "m5.2xlarge"},
+			},
+		},
+		{
+			name:    "gcp cpu too high",
+			m:       &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "gcp"}, CloudRun: &manifest.CloudRunConfig{CPU: "32"}},
+			wantErr: "cloud_run.cpu 32 exceeds the maximum of 2",
+		},
+		{
+			name:    "gcp memory too high",
+			m:       &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "gcp"}, CloudRun: &manifest.CloudRunConfig{Memory: "4Gi"}},
+			wantErr: "cloud_run.memory 4Gi exceeds",
+		},
+		{
+			name: "gcp within limits",
+			m:    &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "gcp"}, CloudRun: &manifest.CloudRunConfig{CPU: "1000m", Memory: "1Gi"}},
+		},
+		{
+			name:    "azure cpu too high",
+			m:       &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "azure"}, Azure: &manifest.AzureConfig{CPU: 4}},
+			wantErr: "azure.cpu 4 exceeds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resourcePolicy().Check(tt.m)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInstanceSizeRank(t *testing.T) {
+	order := []string{"t3.nano", "t3.micro", "t3.small", "t3.medium", "m5.large", "m5.xlarge", "m5.2xlarge", "m5.24xlarge", "m5.metal"}
+	prev := -1
+	for _, instanceType := range order {
+		rank, err := instanceSizeRank(instanceType)
+		if err != nil {
+			t.Fatalf("instanceSizeRank(%q) failed: %v", instanceType, err)
+		}
+		if rank <= prev {
+			t.Errorf("Expected %s to rank above the previous size", instanceType)
+		}
+		prev = rank
+	}
+
+	for _, invalid := range []string{"t3", "t3.huge"} {
+		if _, err := instanceSizeRank(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	tests := map[string]float64{"1": 1, "0.5": 0.5, "2000m": 2, "500m": 0.5}
+	for in, expected := range tests {
+		got, err := parseCPU(in)
+		if err != nil || got != expected {
+			t.Errorf("parseCPU(%q) = %v, %v; want %v", in, got, err, expected)
+		}
+	}
+	if _, err := parseCPU("lots"); err == nil {
+		t.Error("Expected error for invalid CPU")
+	}
+}
+
+func TestParseMemoryGB(t *testing.T) {
+	tests := map[string]float64{"512Mi": 0.5, "2Gi": 2, "1G": 1, "1024M": 1}
+	for in, expected := range tests {
+		got, err := parseMemoryGB(in)
+		if err != nil || got != expected {
+			t.Errorf("parseMemoryGB(%q) = %v, %v; want %v", in, got, err, expected)
+		}
+	}
+	for _, invalid := range []string{"512", "xGi"} {
+		if _, err := parseMemoryGB(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// DeployFunc deploys a single-provider manifest.
+type DeployFunc func(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error)
+
+// TargetResult is the outcome of deploying to one provider of a multi-provider
+// manifest, or to one environment of a batch.
+type TargetResult struct {
+	Provider string
+
+	// The provider, qualified with the environment (aws/my-app-prod) when the
+	// targets deploy more than one environment
+	Target string
+
+	Result *types.DeploymentResult
+	Err    error
+}
+
+// label names the target of the result.
+func (r TargetResult) label() string {
+	if r.Target != "" {
+		return r.Target
+	}
+	return r.Provider
+}
+
+// Schedule limits how many targets DeployScheduled deploys at once and
+// decides which waiting target starts next.
+type Schedule struct {
+	// Most targets deploying at once; 0 is unlimited
+	MaxParallel int
+
+	// Most targets deploying at once per provider name; providers without a
+	// limit are only limited by MaxParallel
+	ProviderLimits map[string]int
+
+	// Priority of a target; when a slot frees up, the waiting target with the
+	// highest priority that fits starts, in target order among equals. Nil
+	// gives every target the same priority.
+	Priority func(m *manifest.Manifest) int
+}
+
+// DeployAll deploys each target concurrently with deploy and returns the results
+// in target order. A failure on one provider does not stop the others.
+func DeployAll(ctx context.Context, targets []*manifest.Manifest, deploy DeployFunc) []TargetResult {
+	return DeployScheduled(ctx, targets, Schedule{}, deploy)
+}
+
+// DeployScheduled deploys the targets with deploy within the limits of
+// schedule, highest priority first, and returns the results in target order.
+// A failure on one target does not stop the others.
+func DeployScheduled(ctx context.Context, targets []*manifest.Manifest, schedule Schedule, deploy DeployFunc) []TargetResult {
+	results := make([]TargetResult, len(targets))
+	labels := targetLabels(targets)
+
+	pending := make([]int, len(targets))
+	for i := range pending {
+		pending[i] = i
+	}
+	if schedule.Priority != nil {
+		slices.SortStableFunc(pending, func(a, b int) int {
+			return cmp.Compare(schedule.Priority(targets[b]), schedule.Priority(targets[a]))
+		})
+	}
+
+	done := make(chan int)
+	running := make(map[string]int)
+	total := 0
+	for len(pending) > 0 || total > 0 {
+		// Start every waiting target that fits, highest priority first
+		for i := 0; i < len(pending); {
+			idx := pending[i]
+			name := targets[idx].Provider.Name
+			if !schedule.fits(total, name, running[name]) {
+				i++
+				continue
+			}
+			pending = slices.Delete(pending, i, i+1)
+			running[name]++
+			total++
+			go func() {
+				result, err := deploy(ctx, targets[idx])
+				results[idx] = TargetResult{Provider: name, Target: labels[idx], Result: result, Err: err}
+				done <- idx
+			}()
+		}
+
+		idx := <-done
+		running[targets[idx].Provider.Name]--
+		total--
+	}
+	return results
+}
+
+// fits reports whether another target of the named provider can start while
+// total targets, running of them on that provider, are deploying.
+func (s Schedule) fits(total int, providerName string, running int) bool {
+	if s.MaxParallel > 0 && total >= s.MaxParallel {
+		return false
+	}
+	limit, ok := s.ProviderLimits[providerName]
+	return !ok || limit <= 0 || running < limit
+}
+
+// targetLabels names each target by its provider, qualified with its
+// environment when the targets deploy more than one environment.
+func targetLabels(targets []*manifest.Manifest) []string {
+	qualify := false
+	for _, t := range targets {
+		if t.Environment.Name != targets[0].Environment.Name {
+			qualify = true
+		}
+	}
+	labels := make([]string, len(targets))
+	for i, t := range targets {
+		labels[i] = t.Provider.Name
+		if qualify {
+			labels[i] += "/" + t.Environment.Name
+		}
+	}
+	return labels
+}
+
+// DeployAllError summarizes the failures in results, or returns nil when every
+// provider succeeded.
+func DeployAllError(results []TargetResult) error {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.label())
+		}
+	}
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(failed) == len(results):
+		return fmt.Errorf("deployment failed on all providers (%s)", strings.Join(failed, ", "))
+	default:
+		return fmt.Errorf("partial deployment: %d of %d providers succeeded; failed: %s",
+			len(results)-len(failed), len(results), strings.Join(failed, ", "))
+	}
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestDeployAll(t *testing.T) {
+	m := &manifest.Manifest{
+		Image:       "my-app:latest",
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-env"},
+		Providers: []manifest.ProviderConfig{
+			{Name: "aws", Region: "us-east-1"},
+			{Name: "gcp", Region: "us-central1"},
+			{Name: "azure", Region: "eastus"},
+		},
+	}
+
+	results := DeployAll(context.Background(), m.Targets(), func(ctx context.Context, target *manifest.Manifest) (*types.DeploymentResult, error) {
+		if target.Provider.Name == "gcp" {
+			return nil, errors.New("quota exceeded")
+		}
+		return &types.DeploymentResult{URL: "https://" + target.Provider.Name + ".example.com"}, nil
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, name := range []string{"aws", "gcp", "azure"} {
+		if results[i].Provider != name {
+			t.Errorf("results[%d].Provider = %s, want %s", i, results[i].Provider, name)
+		}
+	}
+	if results[0].Err != nil || results[0].Result.URL != "https://aws.example.com" {
+		t.Errorf("Unexpected aws result: %+v", results[0])
+	}
+	if results[1].Err == nil {
+		t.Error("Expected gcp to fail")
+	}
+	if results[2].Err != nil {
+		t.Errorf("Expected azure to succeed despite the gcp failure, got: %v", results[2].Err)
+	}
+
+	err := DeployAllError(results)
+	if err == nil || !strings.Contains(err.Error(), "2 of 3 providers succeeded; failed: gcp") {
+		t.Errorf("Expected partial deployment error, got: %v", err)
+	}
+}
+
+func TestDeployAllError(t *testing.T) {
+	if err := DeployAllError([]TargetResult{{Provider: "aws"}, {Provider: "gcp"}}); err != nil {
+		t.Errorf("Expected no error when all providers succeed, got: %v", err)
+	}
+
+	err := DeployAllError([]TargetResult{{Provider: "aws", Err: errors.New("x")}, {Provider: "gcp", Err: errors.New("y")}})
+	if err == nil || !strings.Contains(err.Error(), "failed on all providers (aws, gcp)") {
+		t.Errorf("Expected total failure error, got: %v", err)
+	}
+}
+
+func TestDeployScheduled(t *testing.T) {
+	target := func(providerName, envName, class string) *manifest.Manifest {
+		return &manifest.Manifest{
+			Provider:    manifest.ProviderConfig{Name: providerName},
+			Environment: manifest.EnvironmentConfig{Name: envName, Class: class},
+		}
+	}
+	targets := []*manifest.Manifest{
+		target("aws", "preview-1", "preview"),
+		target("aws", "staging", "staging"),
+		target("gcp", "preview-2", "preview"),
+		target("aws", "prod", "prod"),
+	}
+	priorities := map[string]int{"prod": 100, "staging": 50, "preview": -100}
+	schedule := Schedule{
+		MaxParallel:    1,
+		ProviderLimits: map[string]int{"aws": 1},
+		Priority:       func(m *manifest.Manifest) int { return priorities[m.Environment.Class] },
+	}
+
+	var mu sync.Mutex
+	var order []string
+	running, maxRunning := 0, 0
+	results := DeployScheduled(context.Background(), targets, schedule, func(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+		mu.Lock()
+		order = append(order, m.Environment.Name)
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return &types.DeploymentResult{EnvironmentName: m.Environment.Name}, nil
+	})
+
+	if want := []string{"prod", "staging", "preview-1", "preview-2"}; !slices.Equal(order, want) {
+		t.Errorf("Deployed in order %v, want %v", order, want)
+	}
+	if maxRunning != 1 {
+		t.Errorf("Expected at most 1 deployment at once, got %d", maxRunning)
+	}
+	for i, r := range results {
+		if r.Result.EnvironmentName != targets[i].Environment.Name {
+			t.Errorf("results[%d] is for %s, want %s", i, r.Result.EnvironmentName, targets[i].Environment.Name)
+		}
+	}
+	if results[3].Target != "aws/prod" {
+		t.Errorf("Expected targets of several environments to be qualified, got %q", results[3].Target)
+	}
+}
+
+func TestDeployScheduledProviderLimits(t *testing.T) {
+	var targets []*manifest.Manifest
+	for _, name := range []string{"aws", "aws", "aws", "gcp", "gcp", "gcp"} {
+		targets = append(targets, &manifest.Manifest{Provider: manifest.ProviderConfig{Name: name}})
+	}
+	schedule := Schedule{ProviderLimits: map[string]int{"aws": 2}}
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	DeployScheduled(context.Background(), targets, schedule, func(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+		name := m.Provider.Name
+		mu.Lock()
+		running[name]++
+		maxRunning[name] = max(maxRunning[name], running[name])
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running[name]--
+		mu.Unlock()
+		return &types.DeploymentResult{}, nil
+	})
+
+	if maxRunning["aws"] > 2 {
+		t.Errorf("Expected at most 2 aws deployments at once, got %d", maxRunning["aws"])
+	}
+	if maxRunning["gcp"] != 3 {
+		t.Errorf("Expected the unlimited gcp deployments to run together, got %d at most", maxRunning["gcp"])
+	}
+}
@@ -1,21 +1,34 @@
 // Package provider defines the interface that all cloud providers must implement.
 // This abstraction allows cloud-deploy to support multiple cloud providers
-// (AWS, GCP, Azure, OCI) with a consistent interface.
+// (AWS, AWS ECS, AWS Lambda, AWS App Runner, GCP, GCP GKE Autopilot, Azure, Azure Container Apps, Azure App Service, OCI, Kubernetes, DigitalOcean App Platform, Fly.io) with a consistent interface.
 package provider
 
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
 	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/apprunner"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/appservice"
 	"github.com/jvreagan/cloud-deploy/pkg/providers/aws"
 	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azurecontainerapps"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/digitalocean"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/ecs"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/fly"
 	"github.com/jvreagan/cloud-deploy/pkg/providers/gcp"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/gke"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/kubernetes"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/lambda"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/mock"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/oci"
 	"github.com/jvreagan/cloud-deploy/pkg/types"
 )
 
 // Provider defines the interface that all cloud providers must implement.
-// Each provider (AWS, GCP, Azure, OCI) implements these methods to handle
+// Each provider (AWS, GCP, Azure, OCI, Kubernetes) implements these methods to handle
 // provider-specific deployment logic.
 //
 // Example implementation:
@@ -25,7 +38,7 @@ import (
 //	func (p *AWSProvider) Name() string { return "aws" }
 //	func (p *AWSProvider) Deploy(ctx, manifest) (*DeploymentResult, error) { ... }
 type Provider interface {
-	// Name returns the provider name (e.g., "aws", "gcp", "azure", "oci")
+	// Name returns the provider name (e.g., "aws", "aws-ecs", "aws-lambda", "aws-apprunner", "gcp", "gcp-gke", "azure", "azure-container-apps", "azure-app-service", "oci", "kubernetes", "digitalocean", "fly")
 	Name() string
 
 	// Deploy deploys an application according to the manifest.
@@ -77,13 +90,213 @@ type Provider interface {
 	//
 	// Returns deployment information for the rolled-back version.
 	Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error)
+
+	// Logs writes recent application logs to opts.Output, oldest first.
+	// When opts.Follow is set it keeps streaming new entries until ctx is done.
+	//
+	// Provider-specific behavior:
+	// - AWS: Reads the container log groups in CloudWatch Logs (requires monitoring.cloudwatch_logs)
+	// - AWS ECS: Reads the tasks' output from the environment's CloudWatch log group
+	// - GCP: Reads the Cloud Run service's stdout and stderr from Cloud Logging
+	// - Azure: Reads the logs of each container in the container group
+	// - OCI: Reads the recent output of each container in the container instance (no follow)
+	// - Kubernetes: Reads the output of each container in the deployment's pods
+	// - Mock: Prints the simulated deployment's events (no follow)
+	Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error
+
+	// Close releases the connections the provider's clients hold. The provider
+	// must not be used afterwards; closing it again does nothing.
+	//
+	// Provider-specific behavior:
+	// - GCP: Closes the gRPC connections of the Cloud Build, Cloud Run, Cloud Logging, and Cloud Storage clients
+	// - Kubernetes: Closes the idle connections to the API server
+	// - Others: Close the idle connections of their HTTP clients, where they hold their own pools
+	Close() error
+}
+
+// Exporter is implemented by providers that can describe the resources they
+// create as infrastructure-as-code, so platform teams can adopt them into
+// their own tooling (Terraform, Config Connector, ...).
+type Exporter interface {
+	// Export renders the resources created for the manifest in the given format.
+	// Returns an error if the format is not supported by the provider.
+	Export(ctx context.Context, m *manifest.Manifest, format string) ([]byte, error)
+}
+
+// Planner is implemented by providers that can preview a deployment, so the
+// resources it would create, update, or delete can be reviewed first.
+type Planner interface {
+	// Plan returns the changes Deploy would make for the manifest. It only
+	// reads from the cloud provider and never changes anything.
+	Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error)
+}
+
+// DestroyPlanner is implemented by providers that can preview a destroy, so
+// its blast radius can be reviewed before anything is deleted (destroy
+// -dry-run).
+type DestroyPlanner interface {
+	// PlanDestroy returns the resources Destroy would delete for the manifest
+	// (PlanDelete), the shared resources it would keep (PlanNoChange), and the
+	// resources of other environments that depend on a deleted one. It only
+	// reads from the cloud provider and never changes anything:
+	// - AWS: the environment, and the application's ancillary stack when aws.cloudformation is set
+	// - GCP: the Cloud Run service; the project and Artifact Registry repository are kept
+	// - Azure: the container group; the resource group and container registry are kept
+	// - Azure Container Apps: the app, and its environment when no other app runs in it
+	// - Azure App Service: the app, its slot and certificates, and its plan when no other app runs on it
+	// - Mock: the simulated environment
+	PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error)
+}
+
+// SecretSyncer is implemented by providers that can update the environment
+// variables of a running deployment in place, so rotated secrets can be rolled
+// out without redeploying the image.
+type SecretSyncer interface {
+	// UpdateEnvironmentVariables sets vars on the running deployment (the primary
+	// container, for multi-container deployments), restarting it if needed.
+	// Returns the names of the variables whose values changed; when none changed,
+	// nothing is updated.
+	UpdateEnvironmentVariables(ctx context.Context, m *manifest.Manifest, vars map[string]string) ([]string, error)
+}
+
+// Scaler is implemented by providers whose deployments autoscale, so their
+// instance counts can be changed without redeploying.
+type Scaler interface {
+	// Scale applies the manifest's minimum and maximum instance counts to the
+	// running deployment:
+	// - AWS: the Auto Scaling group of the environment (instance.min_instances, instance.max_instances)
+	// - GCP: the Cloud Run service (cloud_run.min_instances, cloud_run.max_instances)
+	// - Azure: not possible; container groups run a fixed set of containers
+	Scale(ctx context.Context, m *manifest.Manifest) error
+}
+
+// MaintenanceSwitcher is implemented by providers that can pause a
+// deployment's traffic behind a static response while leaving the deployment
+// running, so planned work such as database migrations can happen without
+// visitors reaching the application (maintenance on|off).
+type MaintenanceSwitcher interface {
+	// SetMaintenance serves the response of the manifest's maintenance section
+	// in place of the application when on is true, and restores its traffic
+	// when on is false. Switching to the mode already in effect changes nothing:
+	// - AWS: a fixed-response rule on each listener of the environment's Application Load Balancer
+	// - GCP: the Cloud Run service's traffic sent to a revision of maintenance.image
+	// - Mock: the simulated environment's status
+	SetMaintenance(ctx context.Context, m *manifest.Manifest, on bool) error
+}
+
+// RemediationReporter is implemented by providers that can report the
+// actions the platform took on its own to keep a deployment running, so
+// instability hidden behind a Ready status can be seen (status -deep).
+type RemediationReporter interface {
+	// RemediationEvents returns the remediation events of the deployment since
+	// the given time, oldest first:
+	// - AWS: instances Elastic Beanstalk replaced or removed, from the environment's events
+	// - GCP: container exits in the Cloud Run service's revisions, from Cloud Logging
+	// - Azure: container restarts in the container group, with their exit codes
+	RemediationEvents(ctx context.Context, m *manifest.Manifest, since time.Time) ([]types.RemediationEvent, error)
+}
+
+// PostureReporter is implemented by providers that can summarize the health
+// of the account they deploy to, so quotas running out, expiring credentials,
+// or disabled APIs can be spotted before a deployment fails on them.
+type PostureReporter interface {
+	// Posture checks the account the manifest deploys to. It only reads from
+	// the cloud provider and never changes anything:
+	// - AWS: Elastic Beanstalk quotas and the expiry or age of the credentials
+	// - GCP: the service account key's expiry, project billing, and the APIs deployments need
+	// - Azure: authentication, resource provider registration, and Container Instances quotas in the location
+	// - Kubernetes: ResourceQuotas of the namespace and the client certificate's expiry
+	// - Mock: the simulated environments
+	Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error)
+}
+
+// Validator is implemented by providers that can check a manifest against
+// what they accept, so a manifest that would fail partway through a
+// deployment is caught before it starts.
+type Validator interface {
+	// Validate returns every problem found with the manifest, rather than
+	// stopping at the first. It only reads from the cloud provider and never
+	// changes anything:
+	// - AWS: the region, the instance type's availability there, and the solution stack's
+	// - GCP: the region name and the Cloud Run CPU and memory combination
+	// - Azure: Container Instances' availability in the location and its CPU and memory limits
+	Validate(ctx context.Context, m *manifest.Manifest) ([]types.ValidationFinding, error)
+}
+
+// InventoryReporter is implemented by providers that can list the resources
+// they created for an application, so they can be audited or cleaned up by
+// hand.
+type InventoryReporter interface {
+	// Inventory lists the resources of the manifest's application. It only
+	// reads from the cloud provider and never changes anything:
+	// - AWS: the Elastic Beanstalk application and its environments, the ancillary stack, the ECR repository, and the S3 bucket
+	// - GCP: the project, the Artifact Registry repository, and the Cloud Run service
+	// - Azure: the resource group, the container registry, and the container groups tagged with the application
+	// - Mock: the simulated environments of the application
+	Inventory(ctx context.Context, m *manifest.Manifest) (*types.Inventory, error)
+}
+
+// Pruner is implemented by providers that keep an image or application version
+// per deployment, so the old ones can be deleted by a retention policy.
+type Pruner interface {
+	// Prune deletes the application's images and versions the policy selects,
+	// or only lists them with dryRun. What is deployed is always kept:
+	// - AWS: Elastic Beanstalk application versions with their S3 source bundles, unreferenced source bundles, and ECR images
+	// - GCP: versions of the Artifact Registry package
+	// - Azure: tags of the ACR repository
+	Prune(ctx context.Context, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool) (*types.Cleanup, error)
+}
+
+// CNAMEChecker is implemented by providers whose environments claim a globally
+// unique CNAME prefix (AWS Elastic Beanstalk), so availability can be checked
+// before a deployment.
+type CNAMEChecker interface {
+	// CheckCNAME reports whether the manifest's CNAME prefix is available and the
+	// fully qualified domain name it resolves to.
+	CheckCNAME(ctx context.Context, m *manifest.Manifest) (bool, string, error)
+}
+
+// CredentialRotator is implemented by providers that can issue and revoke the
+// credentials cloud-deploy authenticates with (AWS access keys, GCP service
+// account keys, Azure client secrets), so they can be rotated without a console.
+type CredentialRotator interface {
+	// CreateCredentials issues a new credential for the identity in current,
+	// authenticating as current. The returned credentials are a copy of current
+	// with the provider's fields replaced.
+	CreateCredentials(ctx context.Context, m *manifest.Manifest, current *credentials.ProviderCredentials) (*credentials.ProviderCredentials, error)
+
+	// VerifyCredentials checks that creds can authenticate, waiting for newly
+	// issued credentials to propagate.
+	VerifyCredentials(ctx context.Context, m *manifest.Manifest, creds *credentials.ProviderCredentials) error
+
+	// RevokeCredentials deactivates revoked, authenticating as active.
+	RevokeCredentials(ctx context.Context, m *manifest.Manifest, active, revoked *credentials.ProviderCredentials) error
+}
+
+// OperationAttacher is implemented by providers that journal the long-running
+// operations they wait for, so that if cloud-deploy dies while one is in
+// progress, another invocation (possibly by another operator) can wait for it
+// instead of starting a conflicting one (attach).
+type OperationAttacher interface {
+	// SetOperationJournal makes the provider record each long-running
+	// operation it starts waiting for in journal, and end it there once it
+	// finishes.
+	SetOperationJournal(journal types.OperationJournal)
+
+	// Attach waits for op, started by another invocation, to finish, ends it
+	// in the journal, and returns the environment's deployment:
+	// - AWS: the Elastic Beanstalk environment creation or update, until the environment is Ready
+	// - GCP: the Cloud Run operation, resumed by its name
+	// - Azure: the container group operation, resumed from its poller's resume token
+	Attach(ctx context.Context, m *manifest.Manifest, op types.Operation) (*types.DeploymentResult, error)
 }
 
 // Factory creates a provider based on the manifest configuration.
 // It requires a context and manifest to properly initialize the provider
 // with the correct region and settings.
 //
-// Supported providers: aws, gcp, azure, oci
+// Supported providers: aws, aws-ecs, aws-lambda, aws-apprunner, gcp, gcp-gke, azure, azure-container-apps, azure-app-service, oci, kubernetes, digitalocean, fly, and mock (simulated
+// deployments for testing pipelines without a cloud account)
 //
 // Example:
 //
@@ -92,22 +305,80 @@ type Provider interface {
 //	  log.Fatal(err)
 //	}
 //
-// Returns an error if the provider is not supported or not yet implemented.
+// Returns an error if the provider is not supported.
 func Factory(ctx context.Context, m *manifest.Manifest) (Provider, error) {
 	switch m.Provider.Name {
 	case "aws":
 		return aws.New(ctx, m.Provider.Region, m.Provider.Credentials, m)
+	case "aws-ecs":
+		return ecs.New(ctx, &m.Provider, m)
+	case "aws-lambda":
+		return lambda.New(ctx, &m.Provider, m)
+	case "aws-apprunner":
+		return apprunner.New(ctx, &m.Provider, m)
 	case "gcp":
 		return gcp.New(ctx, &m.Provider, m)
+	case "gcp-gke":
+		return gke.New(ctx, &m.Provider, m)
 	case "azure":
 		var azureCreds *manifest.AzureCredentialsConfig
 		if m.Provider.Credentials != nil && m.Provider.Credentials.Azure != nil {
 			azureCreds = m.Provider.Credentials.Azure
 		}
 		return azure.New(ctx, m.Provider.SubscriptionID, m.Provider.Region, m.Provider.ResourceGroup, azureCreds, m.Provider.Credentials, m)
+	case "azure-container-apps":
+		return azurecontainerapps.New(ctx, &m.Provider, m)
+	case "azure-app-service":
+		return appservice.New(ctx, &m.Provider, m)
 	case "oci":
-		return nil, fmt.Errorf("OCI provider not yet implemented")
+		return oci.New(ctx, &m.Provider, m)
+	case "kubernetes":
+		return kubernetes.New(ctx, &m.Provider, m)
+	case "digitalocean":
+		return digitalocean.New(ctx, &m.Provider, m)
+	case "fly":
+		return fly.New(ctx, &m.Provider, m)
+	case "mock":
+		return mock.New(m), nil
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", m.Provider.Name)
 	}
 }
+
+// IAMPolicy returns the least-privilege permissions the deployer needs on the
+// named provider for the features used in the manifest:
+//   - aws, aws-ecs, aws-lambda, aws-apprunner: an IAM policy document
+//   - gcp, gcp-gke: the predefined roles to grant, with the resource for each
+//   - azure, azure-container-apps, azure-app-service: a custom role definition
+//   - oci: the policy statements to grant the deployer's group
+//   - kubernetes: an RBAC Role for the deployment's namespace
+//
+// No cloud APIs are called, so it works before any access has been granted.
+func IAMPolicy(name string, m *manifest.Manifest) ([]byte, error) {
+	switch name {
+	case "aws":
+		return aws.IAMPolicy(m)
+	case "aws-ecs":
+		return ecs.IAMPolicy(m)
+	case "aws-lambda":
+		return lambda.IAMPolicy(m)
+	case "aws-apprunner":
+		return apprunner.IAMPolicy(m)
+	case "gcp":
+		return gcp.IAMPolicy(m)
+	case "gcp-gke":
+		return gke.IAMPolicy(m)
+	case "azure":
+		return azure.IAMPolicy(m)
+	case "azure-container-apps":
+		return azurecontainerapps.IAMPolicy(m)
+	case "azure-app-service":
+		return appservice.IAMPolicy(m)
+	case "oci":
+		return oci.IAMPolicy(m)
+	case "kubernetes":
+		return kubernetes.IAMPolicy(m)
+	default:
+		return nil, fmt.Errorf("IAM policy generation is not supported for provider: %s", name)
+	}
+}
@@ -44,6 +44,39 @@ func TestFactory(t *testing.T) {
 			expectError:  false,
 			providerName: "aws",
 		},
+		{
+			name: "AWS ECS provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:   "aws-ecs",
+					Region: "us-east-1",
+				},
+			},
+			expectError:  false,
+			providerName: "aws-ecs",
+		},
+		{
+			name: "AWS Lambda provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:   "aws-lambda",
+					Region: "us-east-1",
+				},
+			},
+			expectError:  false,
+			providerName: "aws-lambda",
+		},
+		{
+			name: "AWS App Runner provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:   "aws-apprunner",
+					Region: "us-east-1",
+				},
+			},
+			expectError:  false,
+			providerName: "aws-apprunner",
+		},
 		{
 			name: "GCP provider - requires valid credentials",
 			manifest: &manifest.Manifest{
@@ -58,7 +91,18 @@ func TestFactory(t *testing.T) {
 				},
 			},
 			expectError:  true,
-			errorMessage: "failed to create Cloud Resource Manager client",
+			errorMessage: "could not parse key", // from whichever client fails first
+		},
+		{
+			name: "GCP GKE provider - missing project ID",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:   "gcp-gke",
+					Region: "us-central1",
+				},
+			},
+			expectError:  true,
+			errorMessage: "provider.project_id is required",
 		},
 		{
 			name: "Azure provider",
@@ -74,7 +118,33 @@ func TestFactory(t *testing.T) {
 			providerName: "azure",
 		},
 		{
-			name: "OCI provider - not implemented",
+			name: "Azure Container Apps provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:           "azure-container-apps",
+					Region:         "eastus",
+					SubscriptionID: "test-subscription-id",
+					ResourceGroup:  "test-rg",
+				},
+			},
+			expectError:  false,
+			providerName: "azure-container-apps",
+		},
+		{
+			name: "Azure App Service provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:           "azure-app-service",
+					Region:         "eastus",
+					SubscriptionID: "test-subscription-id",
+					ResourceGroup:  "test-rg",
+				},
+			},
+			expectError:  false,
+			providerName: "azure-app-service",
+		},
+		{
+			name: "OCI provider - missing compartment",
 			manifest: &manifest.Manifest{
 				Provider: manifest.ProviderConfig{
 					Name:   "oci",
@@ -82,7 +152,53 @@ func TestFactory(t *testing.T) {
 				},
 			},
 			expectError:  true,
-			errorMessage: "OCI provider not yet implemented",
+			errorMessage: "compartment ID is required",
+		},
+		{
+			name: "Kubernetes provider - missing kubeconfig",
+			manifest: &manifest.Manifest{
+				Provider:   manifest.ProviderConfig{Name: "kubernetes"},
+				Kubernetes: &manifest.KubernetesConfig{Kubeconfig: "/nonexistent/kubeconfig"},
+			},
+			expectError:  true,
+			errorMessage: "failed to read kubeconfig",
+		},
+		{
+			name: "DigitalOcean provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name:   "digitalocean",
+					Region: "nyc3",
+					Credentials: &manifest.CredentialsConfig{
+						Source:       "manifest",
+						DigitalOcean: &manifest.DigitalOceanCredentialsConfig{Token: "test-token"},
+					},
+				},
+			},
+			expectError:  false,
+			providerName: "digitalocean",
+		},
+		{
+			name: "Fly.io provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{
+					Name: "fly",
+					Credentials: &manifest.CredentialsConfig{
+						Source: "manifest",
+						Fly:    &manifest.FlyCredentialsConfig{Token: "test-token"},
+					},
+				},
+			},
+			expectError:  false,
+			providerName: "fly",
+		},
+		{
+			name: "mock provider",
+			manifest: &manifest.Manifest{
+				Provider: manifest.ProviderConfig{Name: "mock"},
+			},
+			expectError:  false,
+			providerName: "mock",
 		},
 		{
 			name: "unknown provider",
@@ -122,6 +238,12 @@ func TestFactory(t *testing.T) {
 				if provider.Name() != tt.providerName {
 					t.Errorf("Expected provider name '%s', got '%s'", tt.providerName, provider.Name())
 				}
+				// Closing releases the provider's connections, and again does nothing
+				for i := 0; i < 2; i++ {
+					if err := provider.Close(); err != nil {
+						t.Errorf("Close failed: %v", err)
+					}
+				}
 			}
 		})
 	}
@@ -258,3 +380,18 @@ func TestFactoryEmptyManifest(t *testing.T) {
 		t.Error("Expected provider to be nil for empty manifest")
 	}
 }
+
+func TestIAMPolicy(t *testing.T) {
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "aws", Region: "us-east-1", ProjectID: "my-project", SubscriptionID: "sub", ResourceGroup: "rg", CompartmentID: "ocid1.compartment"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+	}
+	for _, name := range []string{"aws", "aws-ecs", "aws-lambda", "aws-apprunner", "gcp", "gcp-gke", "azure", "azure-container-apps", "azure-app-service", "oci", "kubernetes"} {
+		if doc, err := IAMPolicy(name, m); err != nil || len(doc) == 0 {
+			t.Errorf("IAMPolicy(%s) failed: %v", name, err)
+		}
+	}
+	if _, err := IAMPolicy("digitalocean", m); err == nil {
+		t.Error("Expected error for unsupported provider")
+	}
+}
@@ -0,0 +1,694 @@
+// Package apprunner provides an AWS App Runner provider that runs the
+// manifest's image as an App Runner service: a managed HTTPS endpoint in
+// front of instances App Runner scales with traffic, much like Cloud Run.
+// Images are pushed to ECR and deployed by digest.
+package apprunner
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	awsprovider "github.com/jvreagan/cloud-deploy/pkg/providers/aws"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// API versions and JSON target prefixes of the services the provider calls.
+const (
+	appRunnerTargetPrefix = "AppRunner."
+	logsTargetPrefix      = "Logs_20140328."
+	iamAPIVersion         = "2010-05-08"
+)
+
+// iamEndpoint is the global IAM endpoint; IAM requests are signed for us-east-1.
+const iamEndpoint = "https://iam.amazonaws.com"
+
+// The role App Runner pulls images from ECR with when app_runner.access_role_arn
+// is not set.
+const (
+	accessRoleName   = "cloud-deploy-apprunner-ecr"
+	accessRolePolicy = "arn:aws:iam::aws:policy/service-role/AWSAppRunnerServicePolicyForECRAccess"
+)
+
+// previousImageTag is the service tag that records the image the service ran
+// before the last deployment, which Rollback redeploys.
+const previousImageTag = "cloud-deploy:previous-image"
+
+// pollInterval is how often an operation is checked while waiting for it.
+var pollInterval = 5 * time.Second
+
+// operationTimeout is how long to wait for App Runner to create, update,
+// pause, resume, or delete the service.
+var operationTimeout = 20 * time.Minute
+
+// roleTimeout is how long Deploy retries creating the service while a new
+// access role propagates through IAM.
+var roleTimeout = 2 * time.Minute
+
+// Provider implements the provider.Provider interface for AWS App Runner.
+type Provider struct {
+	region          string
+	config          aws.Config
+	appRunnerClient *awsapi.Client
+	logsClient      *awsapi.Client
+	iamClient       *awsapi.Client
+}
+
+// New creates a new AWS App Runner provider instance. Credentials are loaded
+// as they are for the aws provider.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	cfg, err := awsprovider.LoadConfig(ctx, config.Region, config.Credentials, m)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(cfg), nil
+}
+
+// newProvider creates the service clients for cfg.
+func newProvider(cfg aws.Config) *Provider {
+	appRunnerClient := awsapi.New(cfg, "apprunner")
+	appRunnerClient.SetJSONVersion("1.0")
+
+	iamConfig := cfg.Copy()
+	iamConfig.Region = "us-east-1"
+	iamClient := awsapi.New(iamConfig, "iam")
+	if cfg.BaseEndpoint == nil {
+		// Emulators serve IAM at the same endpoint as every other service
+		iamClient.SetEndpoint(iamEndpoint)
+	}
+
+	return &Provider{
+		region:          cfg.Region,
+		config:          cfg,
+		appRunnerClient: appRunnerClient,
+		logsClient:      awsapi.New(cfg, "logs"),
+		iamClient:       iamClient,
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "aws-apprunner"
+}
+
+// Close closes the idle connections of the provider's clients.
+func (p *Provider) Close() error {
+	for _, c := range []*awsapi.Client{p.appRunnerClient, p.logsClient, p.iamClient} {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// service is an App Runner service, as returned by DescribeService.
+type service struct {
+	ServiceName         string
+	ServiceId           string
+	ServiceArn          string
+	ServiceUrl          string
+	Status              string
+	UpdatedAt           float64
+	SourceConfiguration struct {
+		ImageRepository struct {
+			ImageIdentifier string
+		}
+	}
+	AutoScalingConfigurationSummary struct {
+		AutoScalingConfigurationArn string
+	}
+}
+
+// url returns the service's HTTPS URL.
+func (s *service) url() string {
+	if s.ServiceUrl == "" {
+		return ""
+	}
+	return "https://" + s.ServiceUrl
+}
+
+// image returns the image the service runs.
+func (s *service) image() string {
+	return s.SourceConfiguration.ImageRepository.ImageIdentifier
+}
+
+// Deploy pushes the manifest's image to ECR and runs it as an App Runner
+// service, creating the service, its auto scaling configuration, and the ECR
+// access role the first time.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := registry.ValidatePlatform(ctx, m.Image, registry.LinuxAMD64, "App Runner", ""); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Starting AWS App Runner deployment")
+
+	// Step 1: Push the image to ECR
+	image, err := p.pushImage(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return p.deployImage(ctx, m, image)
+}
+
+// deployImage runs image, an ECR image, as the environment's service.
+func (p *Provider) deployImage(ctx context.Context, m *manifest.Manifest, image string) (*types.DeploymentResult, error) {
+	name := m.Environment.Name
+	if c := m.GetPrimaryContainer(); c.Workdir != "" {
+		logging.Warnf("App Runner cannot change the working directory; ignoring container.workdir %s", c.Workdir)
+	}
+
+	// Step 2: Create the access role and the auto scaling configuration
+	accessRole, err := p.ensureAccessRole(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	autoScaling, err := p.ensureAutoScaling(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Create or update the service
+	svc, err := p.findService(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	input := map[string]interface{}{
+		"SourceConfiguration":         sourceConfiguration(m, image, accessRole),
+		"InstanceConfiguration":       instanceConfiguration(m.AppRunner),
+		"HealthCheckConfiguration":    healthCheckConfiguration(m),
+		"AutoScalingConfigurationArn": autoScaling,
+	}
+	previous := ""
+	if svc == nil {
+		logging.Infof("Creating App Runner service: %s", name)
+		input["ServiceName"] = name
+		if tags := appRunnerTags(m.Tags); len(tags) > 0 {
+			input["Tags"] = tags
+		}
+		if svc, err = p.createService(ctx, input); err != nil {
+			return nil, err
+		}
+	} else {
+		if svc.Status == "PAUSED" {
+			logging.Infof("Resuming paused service: %s", name)
+			if err := p.runOperation(ctx, svc, "ResumeService", map[string]string{"ServiceArn": svc.ServiceArn}); err != nil {
+				return nil, err
+			}
+		}
+		logging.Infof("Updating App Runner service: %s", name)
+		previous = svc.image()
+		oldAutoScaling := svc.AutoScalingConfigurationSummary.AutoScalingConfigurationArn
+		input["ServiceArn"] = svc.ServiceArn
+		if err := p.runOperation(ctx, svc, "UpdateService", input); err != nil {
+			return nil, err
+		}
+		if oldAutoScaling != "" && oldAutoScaling != autoScaling {
+			p.deleteAutoScalingRevision(ctx, oldAutoScaling)
+		}
+	}
+
+	// Step 4: Remember the image the service ran before, for Rollback
+	if previous != "" && previous != image {
+		tags := []map[string]string{{"Key": previousImageTag, "Value": previous}}
+		if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"TagResource", map[string]interface{}{"ResourceArn": svc.ServiceArn, "Tags": tags}, nil); err != nil {
+			logging.Warnf("Failed to record the previous image of service %s; rollback will not find it: %v", name, err)
+		}
+	}
+
+	svc, err = p.describeService(ctx, svc.ServiceArn)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             svc.url(),
+		Status:          "Running",
+		Message:         "Deployment successful",
+		Images:          map[string]string{m.GetPrimaryContainer().Name: image},
+	}, nil
+}
+
+// Destroy deletes the service and its auto scaling configuration. The shared
+// ECR access role is kept.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	svc, err := p.findService(ctx, name)
+	if err != nil {
+		return err
+	}
+	if svc != nil {
+		logging.Infof("Deleting App Runner service: %s", name)
+		if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"DeleteService", map[string]string{"ServiceArn": svc.ServiceArn}, nil); err != nil {
+			return fmt.Errorf("failed to delete service %s: %w", name, err)
+		}
+		if err := p.waitForServiceDeleted(ctx, svc.ServiceArn); err != nil {
+			return err
+		}
+	}
+
+	if err := p.deleteAutoScaling(ctx, m); err != nil {
+		return err
+	}
+	logging.Info("App Runner resources deleted successfully")
+	return nil
+}
+
+// Stop pauses the service, which stops its instances and rejects requests.
+// The service and its configuration are preserved; running Deploy again
+// resumes it.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	svc, err := p.requireService(ctx, m)
+	if err != nil {
+		return err
+	}
+	if svc.Status == "PAUSED" {
+		logging.Infof("Service %s is already paused", svc.ServiceName)
+		return nil
+	}
+	logging.Infof("Pausing App Runner service: %s", svc.ServiceName)
+	if err := p.runOperation(ctx, svc, "PauseService", map[string]string{"ServiceArn": svc.ServiceArn}); err != nil {
+		return err
+	}
+	logging.Info("Service paused successfully (resume with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the service.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	svc, err := p.requireService(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	status, health := serviceStatus(svc.Status)
+	lastUpdated := ""
+	if svc.UpdatedAt > 0 {
+		lastUpdated = time.Unix(int64(svc.UpdatedAt), 0).UTC().Format(time.RFC3339)
+	}
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             svc.url(),
+		LastUpdated:     lastUpdated,
+	}, nil
+}
+
+// serviceStatus returns the status and health of a service in an App Runner
+// service status.
+func serviceStatus(status string) (string, string) {
+	switch status {
+	case "RUNNING":
+		return "Running", "Green"
+	case "PAUSED":
+		return "Stopped", "Grey"
+	case "OPERATION_IN_PROGRESS":
+		return "Updating", "Yellow"
+	case "CREATE_FAILED", "DELETE_FAILED":
+		return "Failed", "Red"
+	default:
+		return status, "Unknown"
+	}
+}
+
+// Rollback redeploys the image the service ran before the last deployment,
+// which Deploy records in a tag of the service. Rolling back twice returns to
+// the image rolled back from.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting AWS App Runner rollback...")
+	svc, err := p.requireService(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Tags []struct {
+			Key   string
+			Value string
+		}
+	}
+	if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"ListTagsForResource", map[string]string{"ResourceArn": svc.ServiceArn}, &out); err != nil {
+		return nil, fmt.Errorf("failed to read tags of service %s: %w", svc.ServiceName, err)
+	}
+	previous := ""
+	for _, tag := range out.Tags {
+		if tag.Key == previousImageTag {
+			previous = tag.Value
+		}
+	}
+	if previous == "" {
+		return nil, fmt.Errorf("no previous image recorded for service %s; it has been deployed only once", svc.ServiceName)
+	}
+
+	logging.Infof("Rolling back %s to image %s", svc.ServiceName, previous)
+	result, err := p.deployImage(ctx, m, previous)
+	if err != nil {
+		return nil, err
+	}
+	result.Message = "Rolled back to " + previous
+	return result, nil
+}
+
+// findService returns the named service, or nil when it does not exist.
+func (p *Provider) findService(ctx context.Context, name string) (*service, error) {
+	input := map[string]interface{}{}
+	for {
+		var out struct {
+			ServiceSummaryList []struct {
+				ServiceName string
+				ServiceArn  string
+				Status      string
+			}
+			NextToken string
+		}
+		if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"ListServices", input, &out); err != nil {
+			return nil, fmt.Errorf("failed to list App Runner services: %w", err)
+		}
+		for _, s := range out.ServiceSummaryList {
+			if s.ServiceName == name && s.Status != "DELETED" {
+				return p.describeService(ctx, s.ServiceArn)
+			}
+		}
+		if out.NextToken == "" {
+			return nil, nil
+		}
+		input["NextToken"] = out.NextToken
+	}
+}
+
+// requireService returns the environment's service, failing when it does not
+// exist.
+func (p *Provider) requireService(ctx context.Context, m *manifest.Manifest) (*service, error) {
+	svc, err := p.findService(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("no App Runner service found for environment %s", m.Environment.Name)
+	}
+	return svc, nil
+}
+
+// describeService returns the service with the given ARN.
+func (p *Provider) describeService(ctx context.Context, arn string) (*service, error) {
+	var out struct {
+		Service service
+	}
+	if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"DescribeService", map[string]string{"ServiceArn": arn}, &out); err != nil {
+		return nil, fmt.Errorf("failed to describe service %s: %w", arn, err)
+	}
+	return &out.Service, nil
+}
+
+// createService creates the service and waits until it runs. An access role
+// created moments ago may not be assumable by App Runner yet, so creation is
+// retried until IAM has propagated it.
+func (p *Provider) createService(ctx context.Context, input map[string]interface{}) (*service, error) {
+	deadline := time.Now().Add(roleTimeout)
+	for {
+		var out struct {
+			Service     service
+			OperationId string
+		}
+		err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"CreateService", input, &out)
+		if err == nil {
+			svc := &out.Service
+			return svc, p.waitForOperation(ctx, svc, out.OperationId)
+		}
+		if !awsapi.IsErrorCode(err, "InvalidRequestException") || !strings.Contains(err.Error(), "access role") || time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to create service: %w", err)
+		}
+		logging.Info("Waiting for the ECR access role to propagate...")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// runOperation calls action, an App Runner action that starts an operation on
+// svc, and waits for the operation to finish.
+func (p *Provider) runOperation(ctx context.Context, svc *service, action string, input interface{}) error {
+	var out struct {
+		OperationId string
+	}
+	if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+action, input, &out); err != nil {
+		return fmt.Errorf("failed to %s %s: %w", strings.ToLower(strings.TrimSuffix(action, "Service")), svc.ServiceName, err)
+	}
+	return p.waitForOperation(ctx, svc, out.OperationId)
+}
+
+// waitForOperation polls the service's operations until the given one
+// finishes. App Runner rolls back a failed update itself, so an operation
+// that rolled back is reported as a failure.
+func (p *Provider) waitForOperation(ctx context.Context, svc *service, id string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(operationTimeout)
+
+	for {
+		var out struct {
+			OperationSummaryList []struct {
+				Id     string
+				Type   string
+				Status string
+			}
+		}
+		if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"ListOperations", map[string]string{"ServiceArn": svc.ServiceArn}, &out); err != nil {
+			return fmt.Errorf("failed to list operations of service %s: %w", svc.ServiceName, err)
+		}
+		for _, op := range out.OperationSummaryList {
+			if op.Id != id {
+				continue
+			}
+			switch op.Status {
+			case "SUCCEEDED":
+				return nil
+			case "FAILED", "ROLLBACK_SUCCEEDED", "ROLLBACK_FAILED":
+				return fmt.Errorf("%s of service %s failed (%s); see the service's event log in the App Runner console", op.Type, svc.ServiceName, op.Status)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for operation %s of service %s", id, svc.ServiceName)
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForServiceDeleted polls the service until App Runner has deleted it.
+func (p *Provider) waitForServiceDeleted(ctx context.Context, arn string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(operationTimeout)
+
+	for {
+		var out struct {
+			Service service
+		}
+		err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"DescribeService", map[string]string{"ServiceArn": arn}, &out)
+		switch {
+		case awsapi.IsErrorCode(err, "ResourceNotFoundException"):
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to describe service %s: %w", arn, err)
+		case out.Service.Status == "DELETED":
+			return nil
+		case out.Service.Status == "DELETE_FAILED":
+			return fmt.Errorf("App Runner failed to delete service %s", out.Service.ServiceName)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for service %s to be deleted", arn)
+		case <-ticker.C:
+		}
+	}
+}
+
+// validateSecurity applies security.run_as_non_root. App Runner runs the image
+// as the user it declares, so security.user cannot be applied and the image
+// itself must not run as root.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	if m.Security == nil {
+		return nil
+	}
+	if m.Security.User != "" {
+		logging.Warnf("App Runner runs the image as the user it declares; ignoring security.user %s", m.Security.User)
+	}
+	if !m.Security.RunAsNonRoot {
+		return nil
+	}
+	return registry.ValidateNonRoot(ctx, m.Image)
+}
+
+// pushImage pushes the manifest's image to the application's ECR repository,
+// returning the pushed image pinned by digest.
+func (p *Provider) pushImage(ctx context.Context, m *manifest.Manifest) (string, error) {
+	logging.Info("Pushing image to ECR", "image", m.Image)
+	ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.RepositoryName(), m.ImageTag("latest", ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to create ECR registry: %w", err)
+	}
+	distributor := registry.NewDistributor(m.Image, m.Platforms...)
+	dest := registry.Local(ecrRegistry, m.Provider.RegistryURL, m.RepositoryName(), m.ImageTag("latest", ""))
+	distributor.AddRegistry(dest)
+
+	imageURIs, err := distributor.Distribute(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to distribute image: %w", err)
+	}
+	image := registry.PinDigest(imageURIs[dest.GetRegistryURL()], distributor.Digest())
+	logging.Info("Image pushed to ECR", "image_uri", image)
+	return image, nil
+}
+
+// ensureAccessRole returns the ARN of the role App Runner pulls the image
+// with, creating cloud-deploy-apprunner-ecr when no role is configured and it
+// does not exist yet.
+func (p *Provider) ensureAccessRole(ctx context.Context, m *manifest.Manifest) (string, error) {
+	if m.AppRunner != nil && m.AppRunner.AccessRoleARN != "" {
+		return m.AppRunner.AccessRoleARN, nil
+	}
+
+	var role struct {
+		ARN string `xml:"Role>Arn"`
+	}
+	err := p.iamClient.Query(ctx, "GetRole", iamAPIVersion, url.Values{"RoleName": {accessRoleName}}, &role)
+	if err == nil {
+		return role.ARN, nil
+	}
+	if !awsapi.IsErrorCode(err, "NoSuchEntity") {
+		return "", fmt.Errorf("failed to get role %s: %w", accessRoleName, err)
+	}
+
+	logging.Infof("Creating ECR access role: %s", accessRoleName)
+	trust := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"build.apprunner.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+	params := url.Values{"RoleName": {accessRoleName}, "AssumeRolePolicyDocument": {trust}}
+	if err := p.iamClient.Query(ctx, "CreateRole", iamAPIVersion, params, &role); err != nil {
+		return "", fmt.Errorf("failed to create role %s: %w", accessRoleName, err)
+	}
+	params = url.Values{"RoleName": {accessRoleName}, "PolicyArn": {accessRolePolicy}}
+	if err := p.iamClient.Query(ctx, "AttachRolePolicy", iamAPIVersion, params, nil); err != nil {
+		return "", fmt.Errorf("failed to attach policy to role %s: %w", accessRoleName, err)
+	}
+	return role.ARN, nil
+}
+
+// sourceConfiguration returns the image the service runs, with its port,
+// environment variables, and start command.
+func sourceConfiguration(m *manifest.Manifest, image, accessRole string) map[string]interface{} {
+	c := m.GetPrimaryContainer()
+	env := make(map[string]string, len(m.EnvironmentVariables))
+	for k, v := range m.EnvironmentVariables {
+		env[k] = v
+	}
+	imageConfig := map[string]interface{}{
+		"Port":                        strconv.Itoa(containerPort(c)),
+		"RuntimeEnvironmentVariables": env,
+	}
+	if start := startCommand(c); start != "" {
+		imageConfig["StartCommand"] = start
+	}
+	return map[string]interface{}{
+		"ImageRepository": map[string]interface{}{
+			"ImageIdentifier":     image,
+			"ImageRepositoryType": "ECR",
+			"ImageConfiguration":  imageConfig,
+		},
+		"AutoDeploymentsEnabled":      false,
+		"AuthenticationConfiguration": map[string]string{"AccessRoleArn": accessRole},
+	}
+}
+
+// startCommand returns the command App Runner starts the image with. App
+// Runner takes a single command line, which replaces both the image's
+// ENTRYPOINT and CMD.
+func startCommand(c manifest.Container) string {
+	return strings.Join(append(append([]string{}, c.Command...), c.Args...), " ")
+}
+
+// containerPort returns the port the application listens on, defaulting to
+// App Runner's 8080.
+func containerPort(c manifest.Container) int {
+	if len(c.Ports) > 0 && c.Ports[0].ContainerPort > 0 {
+		return c.Ports[0].ContainerPort
+	}
+	return 8080
+}
+
+// instanceConfiguration returns the size and role of the service's instances.
+func instanceConfiguration(c *manifest.AppRunnerConfig) map[string]string {
+	cpu, memory := c.InstanceSize()
+	config := map[string]string{"Cpu": strconv.Itoa(cpu), "Memory": strconv.Itoa(memory)}
+	if c != nil && c.InstanceRoleARN != "" {
+		config["InstanceRoleArn"] = c.InstanceRoleARN
+	}
+	return config
+}
+
+// healthCheckConfiguration returns the service's health check: an HTTP check
+// of health_check.path when it is set, otherwise a TCP check of the port.
+func healthCheckConfiguration(m *manifest.Manifest) map[string]interface{} {
+	interval, timeout, healthy, unhealthy := 5, 2, 1, 5
+	if c := m.AppRunner; c != nil {
+		interval = orDefault(c.HealthCheckInterval, interval)
+		timeout = orDefault(c.HealthCheckTimeout, timeout)
+		healthy = orDefault(c.HealthyThreshold, healthy)
+		unhealthy = orDefault(c.UnhealthyThreshold, unhealthy)
+	}
+	config := map[string]interface{}{
+		"Protocol":           "TCP",
+		"Interval":           interval,
+		"Timeout":            timeout,
+		"HealthyThreshold":   healthy,
+		"UnhealthyThreshold": unhealthy,
+	}
+	if m.HealthCheck.Path != "" {
+		config["Protocol"] = "HTTP"
+		config["Path"] = m.HealthCheck.Path
+	}
+	return config
+}
+
+// orDefault returns v, or def when v is not set.
+func orDefault(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// appRunnerTags converts tags to App Runner tags, sorted by key.
+func appRunnerTags(tags map[string]string) []map[string]string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, map[string]string{"Key": k, "Value": tags[k]})
+	}
+	return out
+}
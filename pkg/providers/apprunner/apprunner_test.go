@@ -0,0 +1,496 @@
+package apprunner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const serviceARN = "arn:aws:apprunner:us-east-1:123456789012:service/my-env/svc123"
+
+// fakeAWS serves the subset of the App Runner, IAM, and CloudWatch Logs APIs
+// the provider uses. Operations are in progress the first time they are
+// listed and finish the next time.
+type fakeAWS struct {
+	mu          sync.Mutex
+	calls       []string
+	requests    map[string]interface{} // operation -> last JSON body or form
+	service     map[string]interface{}
+	tags        map[string]string
+	operations  []map[string]interface{}
+	opResult    string // status operations finish with, SUCCEEDED by default
+	autoScaling []map[string]interface{}
+	role        bool
+	roleDelays  int // CreateService calls to reject while the access role propagates
+	logGroup    bool
+	events      []logEvent
+}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{requests: make(map[string]interface{}), tags: make(map[string]string), opResult: "SUCCEEDED"}
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		action := target[strings.Index(target, ".")+1:]
+		var in map[string]interface{}
+		json.Unmarshal(body, &in)
+		f.calls = append(f.calls, action)
+		f.requests[action] = in
+		var out interface{}
+		var code, message string
+		if strings.HasPrefix(target, appRunnerTargetPrefix) {
+			out, code, message = f.handleAppRunner(action, in)
+		} else {
+			out, code = f.handleLogs(action)
+		}
+		if code != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+			return
+		}
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+
+	form, _ := url.ParseQuery(string(body))
+	action := form.Get("Action")
+	f.calls = append(f.calls, action)
+	f.requests[action] = form
+	out, code := f.handleIAM(action)
+	if code != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "<ErrorResponse><Error><Code>%s</Code></Error></ErrorResponse>", code)
+		return
+	}
+	fmt.Fprintf(w, "<%sResponse><%sResult>%s</%sResult></%sResponse>", action, action, out, action, action)
+}
+
+// startOperation records an operation on the service and returns its ID.
+func (f *fakeAWS) startOperation(kind string) map[string]interface{} {
+	id := fmt.Sprintf("op%d", len(f.operations)+1)
+	f.operations = append(f.operations, map[string]interface{}{"Id": id, "Type": kind, "Status": "PENDING"})
+	return map[string]interface{}{"OperationId": id, "Service": f.service}
+}
+
+func (f *fakeAWS) handleAppRunner(action string, in map[string]interface{}) (interface{}, string, string) {
+	switch action {
+	case "ListServices":
+		summaries := []map[string]interface{}{{"ServiceName": "other", "ServiceArn": "arn:other", "Status": "RUNNING"}}
+		if f.service != nil {
+			summaries = append(summaries, map[string]interface{}{"ServiceName": "my-env", "ServiceArn": serviceARN, "Status": f.service["Status"]})
+		}
+		return map[string]interface{}{"ServiceSummaryList": summaries}, "", ""
+	case "DescribeService":
+		if f.service == nil {
+			return nil, "ResourceNotFoundException", "service not found"
+		}
+		return map[string]interface{}{"Service": f.service}, "", ""
+	case "CreateService":
+		if f.roleDelays > 0 {
+			f.roleDelays--
+			return nil, "InvalidRequestException", "Error in assuming access role arn:aws:iam::123456789012:role/cloud-deploy-apprunner-ecr"
+		}
+		f.service = map[string]interface{}{
+			"ServiceName": "my-env", "ServiceId": "svc123", "ServiceArn": serviceARN,
+			"ServiceUrl": "abc.us-east-1.awsapprunner.com", "Status": "RUNNING", "UpdatedAt": 1704164645.0,
+		}
+		f.applyService(in)
+		return f.startOperation("CREATE_SERVICE"), "", ""
+	case "UpdateService":
+		f.applyService(in)
+		return f.startOperation("UPDATE_SERVICE"), "", ""
+	case "PauseService":
+		f.service["Status"] = "PAUSED"
+		return f.startOperation("PAUSE_SERVICE"), "", ""
+	case "ResumeService":
+		f.service["Status"] = "RUNNING"
+		return f.startOperation("RESUME_SERVICE"), "", ""
+	case "DeleteService":
+		f.service = nil
+		return map[string]interface{}{"OperationId": "delete"}, "", ""
+	case "ListOperations":
+		summaries := make([]map[string]interface{}, len(f.operations))
+		for i, op := range f.operations {
+			summaries[i] = map[string]interface{}{"Id": op["Id"], "Type": op["Type"], "Status": op["Status"]}
+			if op["Status"] == "PENDING" {
+				summaries[i]["Status"] = "IN_PROGRESS"
+				op["Status"] = f.opResult
+			}
+		}
+		return map[string]interface{}{"OperationSummaryList": summaries}, "", ""
+	case "TagResource":
+		for _, tag := range in["Tags"].([]interface{}) {
+			tag := tag.(map[string]interface{})
+			f.tags[tag["Key"].(string)] = tag["Value"].(string)
+		}
+		return map[string]interface{}{}, "", ""
+	case "ListTagsForResource":
+		tags := []map[string]string{}
+		for k, v := range f.tags {
+			tags = append(tags, map[string]string{"Key": k, "Value": v})
+		}
+		return map[string]interface{}{"Tags": tags}, "", ""
+	case "ListAutoScalingConfigurations":
+		summaries := []map[string]interface{}{}
+		if n := len(f.autoScaling); n > 0 {
+			summaries = append(summaries, map[string]interface{}{"AutoScalingConfigurationArn": f.autoScaling[n-1]["AutoScalingConfigurationArn"]})
+		}
+		return map[string]interface{}{"AutoScalingConfigurationSummaryList": summaries}, "", ""
+	case "DescribeAutoScalingConfiguration":
+		for _, a := range f.autoScaling {
+			if a["AutoScalingConfigurationArn"] == in["AutoScalingConfigurationArn"] {
+				return map[string]interface{}{"AutoScalingConfiguration": a}, "", ""
+			}
+		}
+		return nil, "ResourceNotFoundException", "configuration not found"
+	case "CreateAutoScalingConfiguration":
+		revision := len(f.autoScaling) + 1
+		a := map[string]interface{}{
+			"AutoScalingConfigurationArn":      fmt.Sprintf("arn:aws:apprunner:us-east-1:123456789012:autoscalingconfiguration/my-env/%d/abc", revision),
+			"AutoScalingConfigurationRevision": revision,
+			"MinSize":                          in["MinSize"],
+			"MaxSize":                          in["MaxSize"],
+			"MaxConcurrency":                   in["MaxConcurrency"],
+		}
+		f.autoScaling = append(f.autoScaling, a)
+		return map[string]interface{}{"AutoScalingConfiguration": a}, "", ""
+	case "DeleteAutoScalingConfiguration":
+		if in["DeleteAllRevisions"] == true {
+			f.autoScaling = nil
+			return map[string]interface{}{}, "", ""
+		}
+		for i, a := range f.autoScaling {
+			if a["AutoScalingConfigurationArn"] == in["AutoScalingConfigurationArn"] {
+				f.autoScaling = append(f.autoScaling[:i:i], f.autoScaling[i+1:]...)
+				return map[string]interface{}{}, "", ""
+			}
+		}
+		return nil, "ResourceNotFoundException", "configuration not found"
+	}
+	return nil, "UnknownOperationException", ""
+}
+
+// applyService copies the image and auto scaling configuration of a create
+// or update request to the service.
+func (f *fakeAWS) applyService(in map[string]interface{}) {
+	source := in["SourceConfiguration"].(map[string]interface{})
+	f.service["SourceConfiguration"] = map[string]interface{}{"ImageRepository": source["ImageRepository"]}
+	f.service["AutoScalingConfigurationSummary"] = map[string]interface{}{"AutoScalingConfigurationArn": in["AutoScalingConfigurationArn"]}
+}
+
+func (f *fakeAWS) handleLogs(action string) (interface{}, string) {
+	if action == "FilterLogEvents" {
+		if !f.logGroup {
+			return nil, "ResourceNotFoundException"
+		}
+		return map[string]interface{}{"events": f.events}, ""
+	}
+	return nil, "UnknownOperationException"
+}
+
+func (f *fakeAWS) handleIAM(action string) (string, string) {
+	switch action {
+	case "GetRole":
+		if !f.role {
+			return "", "NoSuchEntity"
+		}
+		return "<Role><Arn>arn:aws:iam::123456789012:role/cloud-deploy-apprunner-ecr</Arn></Role>", ""
+	case "CreateRole":
+		f.role = true
+		return "<Role><Arn>arn:aws:iam::123456789012:role/cloud-deploy-apprunner-ecr</Arn></Role>", ""
+	case "AttachRolePolicy":
+		return "", ""
+	}
+	return "", "InvalidAction"
+}
+
+// called reports whether action was called.
+func (f *fakeAWS) called(action string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == action {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestProvider returns a provider sending every request to fake.
+func newTestProvider(t *testing.T, fake *fakeAWS) *Provider {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	oldPoll := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldPoll })
+
+	p := newProvider(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	})
+	for _, c := range []interface{ SetEndpoint(string) }{p.appRunnerClient, p.logsClient, p.iamClient} {
+		c.SetEndpoint(server.URL)
+	}
+	return p
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:                "my-app:latest",
+		Provider:             manifest.ProviderConfig{Name: "aws-apprunner", Region: "us-east-1"},
+		Application:          manifest.ApplicationConfig{Name: "my-app"},
+		Environment:          manifest.EnvironmentConfig{Name: "my-env"},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info"},
+		HealthCheck:          manifest.HealthCheckConfig{Path: "/healthz"},
+		AppRunner:            &manifest.AppRunnerConfig{MinInstances: 2, MaxInstances: 10},
+	}
+}
+
+const testImage = "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app@sha256:abc"
+
+func TestDeployImage(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	result, err := p.deployImage(context.Background(), m, testImage)
+	if err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if result.URL != "https://abc.us-east-1.awsapprunner.com" || result.Images["my-app"] != testImage {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	for _, action := range []string{"CreateRole", "AttachRolePolicy", "CreateAutoScalingConfiguration", "CreateService", "ListOperations"} {
+		if !fake.called(action) {
+			t.Errorf("expected %s to be called; calls: %v", action, fake.calls)
+		}
+	}
+
+	create := fake.requests["CreateService"].(map[string]interface{})
+	source := create["SourceConfiguration"].(map[string]interface{})
+	repository := source["ImageRepository"].(map[string]interface{})
+	imageConfig := repository["ImageConfiguration"].(map[string]interface{})
+	if repository["ImageIdentifier"] != testImage || repository["ImageRepositoryType"] != "ECR" || imageConfig["Port"] != "8080" {
+		t.Errorf("unexpected image repository: %v", repository)
+	}
+	if env := imageConfig["RuntimeEnvironmentVariables"].(map[string]interface{}); env["LOG_LEVEL"] != "info" {
+		t.Errorf("unexpected environment: %v", env)
+	}
+	if auth := source["AuthenticationConfiguration"].(map[string]interface{}); auth["AccessRoleArn"] != "arn:aws:iam::123456789012:role/cloud-deploy-apprunner-ecr" {
+		t.Errorf("unexpected access role: %v", auth)
+	}
+	if instance := create["InstanceConfiguration"].(map[string]interface{}); instance["Cpu"] != "1024" || instance["Memory"] != "2048" {
+		t.Errorf("unexpected instance configuration: %v", instance)
+	}
+	if hc := create["HealthCheckConfiguration"].(map[string]interface{}); hc["Protocol"] != "HTTP" || hc["Path"] != "/healthz" || hc["UnhealthyThreshold"] != 5.0 {
+		t.Errorf("unexpected health check: %v", hc)
+	}
+	scaling := fake.requests["CreateAutoScalingConfiguration"].(map[string]interface{})
+	if scaling["AutoScalingConfigurationName"] != "my-env" || scaling["MinSize"] != 2.0 || scaling["MaxSize"] != 10.0 || scaling["MaxConcurrency"] != 100.0 {
+		t.Errorf("unexpected auto scaling configuration: %v", scaling)
+	}
+
+	// A second deployment with new scaling settings updates the service to a
+	// new revision and deletes the old one
+	fake.calls = nil
+	m.AppRunner.MaxInstances = 20
+	image := strings.Replace(testImage, "abc", "def", 1)
+	if _, err := p.deployImage(context.Background(), m, image); err != nil {
+		t.Fatalf("second deployImage() failed: %v", err)
+	}
+	for _, action := range []string{"CreateRole", "CreateService"} {
+		if fake.called(action) {
+			t.Errorf("expected %s not to be called again", action)
+		}
+	}
+	for _, action := range []string{"UpdateService", "CreateAutoScalingConfiguration", "DeleteAutoScalingConfiguration", "TagResource"} {
+		if !fake.called(action) {
+			t.Errorf("expected %s to be called; calls: %v", action, fake.calls)
+		}
+	}
+	if len(fake.autoScaling) != 1 || fake.autoScaling[0]["AutoScalingConfigurationRevision"] != 2 {
+		t.Errorf("expected only revision 2 to be kept, got %v", fake.autoScaling)
+	}
+	if fake.tags[previousImageTag] != testImage {
+		t.Errorf("expected the previous image to be recorded, got %v", fake.tags)
+	}
+}
+
+func TestDeployImageWaitsForAccessRole(t *testing.T) {
+	fake := newFakeAWS()
+	fake.roleDelays = 2
+	p := newTestProvider(t, fake)
+
+	if _, err := p.deployImage(context.Background(), testManifest(), testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if fake.service == nil {
+		t.Error("expected the service to be created once the access role propagated")
+	}
+}
+
+func TestDeployImageOperationFailed(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	if _, err := p.deployImage(context.Background(), testManifest(), testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+
+	fake.opResult = "ROLLBACK_SUCCEEDED"
+	_, err := p.deployImage(context.Background(), testManifest(), testImage)
+	if err == nil || !strings.Contains(err.Error(), "UPDATE_SERVICE of service my-env failed (ROLLBACK_SUCCEEDED)") {
+		t.Errorf("expected update failure, got %v", err)
+	}
+}
+
+func TestDeployImageConfigured(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	m.HealthCheck.Path = ""
+	m.AppRunner.CPU, m.AppRunner.Memory = 2048, 6144
+	m.AppRunner.AccessRoleARN = "arn:aws:iam::123456789012:role/ecr"
+	m.AppRunner.InstanceRoleARN = "arn:aws:iam::123456789012:role/app"
+	m.Container = &manifest.ContainerStartup{Command: []string{"/bin/app"}, Args: []string{"serve", "--port", "3000"}}
+	m.Ports = []manifest.PortMapping{{ContainerPort: 3000}}
+
+	if _, err := p.deployImage(context.Background(), m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if fake.called("GetRole") || fake.called("CreateRole") {
+		t.Error("expected the configured access role to be used")
+	}
+	create := fake.requests["CreateService"].(map[string]interface{})
+	repository := create["SourceConfiguration"].(map[string]interface{})["ImageRepository"].(map[string]interface{})
+	imageConfig := repository["ImageConfiguration"].(map[string]interface{})
+	if imageConfig["Port"] != "3000" || imageConfig["StartCommand"] != "/bin/app serve --port 3000" {
+		t.Errorf("unexpected image configuration: %v", imageConfig)
+	}
+	if instance := create["InstanceConfiguration"].(map[string]interface{}); instance["Cpu"] != "2048" || instance["Memory"] != "6144" || instance["InstanceRoleArn"] != m.AppRunner.InstanceRoleARN {
+		t.Errorf("unexpected instance configuration: %v", instance)
+	}
+	if hc := create["HealthCheckConfiguration"].(map[string]interface{}); hc["Protocol"] != "TCP" || hc["Path"] != nil {
+		t.Errorf("expected a TCP health check, got %v", hc)
+	}
+}
+
+func TestStopStatusAndDestroy(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.Status(ctx, m); err == nil {
+		t.Error("expected Status() to fail before the first deployment")
+	}
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+
+	status, err := p.Status(ctx, m)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.URL != "https://abc.us-east-1.awsapprunner.com" || status.LastUpdated != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	if err := p.Stop(ctx, m); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if status, _ := p.Status(ctx, m); status.Status != "Stopped" {
+		t.Errorf("expected the service to be paused, got %+v", status)
+	}
+
+	// Deploying a paused service resumes it first
+	fake.calls = nil
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if !fake.called("ResumeService") || fake.service["Status"] != "RUNNING" {
+		t.Errorf("expected the service to be resumed; calls: %v", fake.calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Destroy(ctx, m); err != nil {
+			t.Fatalf("Destroy() failed on call %d: %v", i+1, err)
+		}
+	}
+	if fake.service != nil || len(fake.autoScaling) != 0 {
+		t.Errorf("expected the service and auto scaling configuration to be deleted")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil || !strings.Contains(err.Error(), "deployed only once") {
+		t.Errorf("expected rollback to fail after one deployment, got %v", err)
+	}
+
+	image := strings.Replace(testImage, "abc", "def", 1)
+	if _, err := p.deployImage(ctx, m, image); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	result, err := p.Rollback(ctx, m)
+	if err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if result.Images["my-app"] != testImage || result.Message != "Rolled back to "+testImage {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if fake.tags[previousImageTag] != image {
+		t.Errorf("expected the rolled-back image to be recorded, got %v", fake.tags)
+	}
+}
+
+func TestServiceStatus(t *testing.T) {
+	tests := []struct {
+		status, want, health string
+	}{
+		{"RUNNING", "Running", "Green"},
+		{"PAUSED", "Stopped", "Grey"},
+		{"OPERATION_IN_PROGRESS", "Updating", "Yellow"},
+		{"CREATE_FAILED", "Failed", "Red"},
+		{"DELETED", "DELETED", "Unknown"},
+	}
+	for _, tt := range tests {
+		status, health := serviceStatus(tt.status)
+		if status != tt.want || health != tt.health {
+			t.Errorf("serviceStatus(%q) = %q, %q; want %q, %q", tt.status, status, health, tt.want, tt.health)
+		}
+	}
+}
+
+func TestAppRunnerTags(t *testing.T) {
+	tags := appRunnerTags(map[string]string{"team": "web", "env": "prod"})
+	if fmt.Sprint(tags) != "[map[Key:env Value:prod] map[Key:team Value:web]]" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+}
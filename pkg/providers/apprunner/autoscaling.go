@@ -0,0 +1,121 @@
+package apprunner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// autoScaling is a revision of an App Runner auto scaling configuration.
+type autoScaling struct {
+	AutoScalingConfigurationArn      string
+	AutoScalingConfigurationRevision int
+	MaxConcurrency                   int
+	MinSize                          int
+	MaxSize                          int
+}
+
+// autoScalingName returns the name of the environment's auto scaling
+// configuration. App Runner allows 32 characters; environment names allow 40.
+func autoScalingName(m *manifest.Manifest) string {
+	name := m.Environment.Name
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// matches reports whether the revision has the manifest's scaling settings.
+func (a *autoScaling) matches(c *manifest.AppRunnerConfig) bool {
+	min, max, concurrency := c.Scaling()
+	return a.MinSize == min && a.MaxSize == max && a.MaxConcurrency == concurrency
+}
+
+// latestAutoScaling returns the latest revision of the named auto scaling
+// configuration, or nil when it does not exist.
+func (p *Provider) latestAutoScaling(ctx context.Context, name string) (*autoScaling, error) {
+	var list struct {
+		AutoScalingConfigurationSummaryList []struct {
+			AutoScalingConfigurationArn string
+		}
+	}
+	input := map[string]interface{}{"AutoScalingConfigurationName": name, "LatestOnly": true}
+	if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"ListAutoScalingConfigurations", input, &list); err != nil {
+		return nil, fmt.Errorf("failed to list auto scaling configurations: %w", err)
+	}
+	if len(list.AutoScalingConfigurationSummaryList) == 0 {
+		return nil, nil
+	}
+
+	var out struct {
+		AutoScalingConfiguration autoScaling
+	}
+	arn := list.AutoScalingConfigurationSummaryList[0].AutoScalingConfigurationArn
+	if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"DescribeAutoScalingConfiguration", map[string]string{"AutoScalingConfigurationArn": arn}, &out); err != nil {
+		return nil, fmt.Errorf("failed to describe auto scaling configuration %s: %w", name, err)
+	}
+	return &out.AutoScalingConfiguration, nil
+}
+
+// ensureAutoScaling returns the ARN of the auto scaling configuration with
+// the manifest's settings. Revisions cannot be changed, so a new revision is
+// created when the settings differ from the latest one.
+func (p *Provider) ensureAutoScaling(ctx context.Context, m *manifest.Manifest) (string, error) {
+	name := autoScalingName(m)
+	latest, err := p.latestAutoScaling(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if latest != nil && latest.matches(m.AppRunner) {
+		return latest.AutoScalingConfigurationArn, nil
+	}
+
+	min, max, concurrency := m.AppRunner.Scaling()
+	logging.Infof("Creating auto scaling configuration %s: %d-%d instances, %d concurrent requests each", name, min, max, concurrency)
+	input := map[string]interface{}{
+		"AutoScalingConfigurationName": name,
+		"MinSize":                      min,
+		"MaxSize":                      max,
+		"MaxConcurrency":               concurrency,
+	}
+	if tags := appRunnerTags(m.Tags); len(tags) > 0 {
+		input["Tags"] = tags
+	}
+	var out struct {
+		AutoScalingConfiguration autoScaling
+	}
+	if err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"CreateAutoScalingConfiguration", input, &out); err != nil {
+		return "", fmt.Errorf("failed to create auto scaling configuration %s: %w", name, err)
+	}
+	return out.AutoScalingConfiguration.AutoScalingConfigurationArn, nil
+}
+
+// deleteAutoScalingRevision deletes a revision the service no longer uses.
+// App Runner limits the revisions of a configuration, so old ones are cleaned
+// up; failing to is only a warning.
+func (p *Provider) deleteAutoScalingRevision(ctx context.Context, arn string) {
+	err := p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"DeleteAutoScalingConfiguration", map[string]string{"AutoScalingConfigurationArn": arn}, nil)
+	if err != nil && !awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+		logging.Warnf("Failed to delete unused auto scaling configuration %s: %v", arn, err)
+	}
+}
+
+// deleteAutoScaling deletes every revision of the environment's auto scaling
+// configuration if it exists.
+func (p *Provider) deleteAutoScaling(ctx context.Context, m *manifest.Manifest) error {
+	name := autoScalingName(m)
+	latest, err := p.latestAutoScaling(ctx, name)
+	if err != nil || latest == nil {
+		return err
+	}
+	input := map[string]interface{}{"AutoScalingConfigurationArn": latest.AutoScalingConfigurationArn, "DeleteAllRevisions": true}
+	err = p.appRunnerClient.JSON(ctx, appRunnerTargetPrefix+"DeleteAutoScalingConfiguration", input, nil)
+	if err != nil && !awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+		return fmt.Errorf("failed to delete auto scaling configuration %s: %w", name, err)
+	}
+	logging.Infof("Deleted auto scaling configuration: %s", name)
+	return nil
+}
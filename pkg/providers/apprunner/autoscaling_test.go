@@ -0,0 +1,47 @@
+package apprunner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestAutoScalingName(t *testing.T) {
+	m := testManifest()
+	if name := autoScalingName(m); name != "my-env" {
+		t.Errorf("autoScalingName() = %q", name)
+	}
+	m.Environment.Name = "a-very-long-environment-name-for-production"
+	if name := autoScalingName(m); name != "a-very-long-environment-name-for" {
+		t.Errorf("expected the name to be truncated to 32 characters, got %q", name)
+	}
+}
+
+func TestEnsureAutoScalingReusesRevision(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	first, err := p.ensureAutoScaling(ctx, m)
+	if err != nil {
+		t.Fatalf("ensureAutoScaling() failed: %v", err)
+	}
+	second, err := p.ensureAutoScaling(ctx, m)
+	if err != nil {
+		t.Fatalf("ensureAutoScaling() failed: %v", err)
+	}
+	if first != second || len(fake.autoScaling) != 1 {
+		t.Errorf("expected the revision to be reused, got %s and %s", first, second)
+	}
+
+	m.AppRunner = &manifest.AppRunnerConfig{MaxConcurrency: 50}
+	third, err := p.ensureAutoScaling(ctx, m)
+	if err != nil {
+		t.Fatalf("ensureAutoScaling() failed: %v", err)
+	}
+	if third == second || fake.autoScaling[1]["MaxConcurrency"] != 50.0 || fake.autoScaling[1]["MinSize"] != 1.0 {
+		t.Errorf("expected a new revision with the defaults, got %v", fake.autoScaling)
+	}
+}
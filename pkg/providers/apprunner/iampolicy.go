@@ -0,0 +1,151 @@
+package apprunner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	awsprovider "github.com/jvreagan/cloud-deploy/pkg/providers/aws"
+)
+
+// iamStatement is a statement of an IAM policy document.
+type iamStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// IAMPolicy returns the IAM policy the deployer needs for the features used in m,
+// scoped to the manifest's service, environment, and region. App Runner
+// lists services and auto scaling configurations across the account, so list
+// actions are granted on all resources.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	region := m.Provider.Region
+	if region == "" {
+		region = "*"
+	}
+	app := m.Application.Name
+	if app == "" {
+		return nil, fmt.Errorf("application.name is required to scope the policy")
+	}
+	serviceARN := fmt.Sprintf("arn:aws:apprunner:%s:*:service/%s/*", region, m.Environment.Name)
+	operation := fmt.Sprintf("arn:aws:apprunner:%s:*:operation/%s/*", region, m.Environment.Name)
+	autoScaling := fmt.Sprintf("arn:aws:apprunner:%s:*:autoscalingconfiguration/%s/*", region, autoScalingName(m))
+
+	accessRole := "arn:aws:iam::*:role/" + accessRoleName
+	if m.AppRunner != nil && m.AppRunner.AccessRoleARN != "" {
+		accessRole = m.AppRunner.AccessRoleARN
+	}
+	passRoles := []string{accessRole}
+	if m.AppRunner != nil && m.AppRunner.InstanceRoleARN != "" {
+		passRoles = append(passRoles, m.AppRunner.InstanceRoleARN)
+	}
+
+	statements := []iamStatement{
+		{
+			Sid:    "Service",
+			Effect: "Allow",
+			Action: []string{
+				"apprunner:CreateService",
+				"apprunner:DescribeService",
+				"apprunner:UpdateService",
+				"apprunner:DeleteService",
+				"apprunner:PauseService",
+				"apprunner:ResumeService",
+				"apprunner:ListOperations",
+				"apprunner:TagResource",
+				"apprunner:ListTagsForResource",
+			},
+			Resource: []string{serviceARN, operation},
+		},
+		{
+			Sid:    "AutoScaling",
+			Effect: "Allow",
+			Action: []string{
+				"apprunner:CreateAutoScalingConfiguration",
+				"apprunner:DescribeAutoScalingConfiguration",
+				"apprunner:DeleteAutoScalingConfiguration",
+				"apprunner:TagResource",
+			},
+			Resource: []string{autoScaling},
+		},
+		{
+			Sid:    "List",
+			Effect: "Allow",
+			Action: []string{
+				"apprunner:ListServices",
+				"apprunner:ListAutoScalingConfigurations",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "ImagePush",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:CreateRepository",
+				"ecr:DescribeRepositories",
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:BatchGetImage",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:InitiateLayerUpload",
+				"ecr:UploadLayerPart",
+				"ecr:CompleteLayerUpload",
+				"ecr:PutImage",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:ecr:%s:*:repository/%s", region, m.RepositoryName())},
+		},
+		{
+			Sid:    "RegistryLogin",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:GetAuthorizationToken",
+				"sts:GetCallerIdentity",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:      "Logs",
+			Effect:   "Allow",
+			Action:   []string{"logs:FilterLogEvents"},
+			Resource: []string{fmt.Sprintf("arn:aws:logs:%s:*:log-group:/aws/apprunner/%s/*", region, m.Environment.Name)},
+		},
+		{
+			Sid:      "PassServiceRoles",
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: passRoles,
+		},
+		{
+			// App Runner creates its service-linked role with the first service
+			Sid:      "ServiceLinkedRole",
+			Effect:   "Allow",
+			Action:   []string{"iam:CreateServiceLinkedRole"},
+			Resource: []string{"arn:aws:iam::*:role/aws-service-role/apprunner.amazonaws.com/AWSServiceRoleForAppRunner"},
+		},
+	}
+
+	if m.AppRunner == nil || m.AppRunner.AccessRoleARN == "" {
+		statements = append(statements, iamStatement{
+			Sid:    "AccessRole",
+			Effect: "Allow",
+			Action: []string{
+				"iam:GetRole",
+				"iam:CreateRole",
+				"iam:AttachRolePolicy",
+			},
+			Resource: []string{accessRole},
+		})
+	}
+
+	if resources := awsprovider.SecretResources(m, region); len(resources) > 0 {
+		statements = append(statements, iamStatement{
+			Sid:      "ReadSecrets",
+			Effect:   "Allow",
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Resource: resources,
+		})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{"Version": "2012-10-17", "Statement": statements}, "", "  ")
+}
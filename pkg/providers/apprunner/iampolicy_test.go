@@ -0,0 +1,88 @@
+package apprunner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func policyStatements(t *testing.T, m *manifest.Manifest) map[string]iamStatement {
+	t.Helper()
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc struct {
+		Version   string
+		Statement []iamStatement
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	if doc.Version != "2012-10-17" {
+		t.Errorf("Unexpected policy version %q", doc.Version)
+	}
+	statements := make(map[string]iamStatement)
+	for _, s := range doc.Statement {
+		statements[s.Sid] = s
+	}
+	return statements
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIAMPolicy(t *testing.T) {
+	m := testManifest()
+	statements := policyStatements(t, m)
+
+	for _, sid := range []string{"Service", "AutoScaling", "List", "ImagePush", "RegistryLogin", "Logs", "PassServiceRoles", "ServiceLinkedRole", "AccessRole"} {
+		if _, ok := statements[sid]; !ok {
+			t.Errorf("Expected statement %s", sid)
+		}
+	}
+	if _, ok := statements["ReadSecrets"]; ok {
+		t.Error("Unexpected ReadSecrets statement without secrets")
+	}
+
+	if !containsString(statements["Service"].Resource, "arn:aws:apprunner:us-east-1:*:service/my-env/*") {
+		t.Errorf("Expected the service, got %v", statements["Service"].Resource)
+	}
+	if !containsString(statements["AutoScaling"].Resource, "arn:aws:apprunner:us-east-1:*:autoscalingconfiguration/my-env/*") {
+		t.Errorf("Expected the auto scaling configuration, got %v", statements["AutoScaling"].Resource)
+	}
+	if !containsString(statements["Logs"].Resource, "arn:aws:logs:us-east-1:*:log-group:/aws/apprunner/my-env/*") {
+		t.Errorf("Expected logs scoped to the service's log groups, got %v", statements["Logs"].Resource)
+	}
+	if roles := statements["PassServiceRoles"].Resource; len(roles) != 1 || roles[0] != "arn:aws:iam::*:role/cloud-deploy-apprunner-ecr" {
+		t.Errorf("Expected the default access role to be passable, got %v", roles)
+	}
+}
+
+func TestIAMPolicyConfiguredRoles(t *testing.T) {
+	m := testManifest()
+	m.AppRunner.AccessRoleARN = "arn:aws:iam::123456789012:role/ecr"
+	m.AppRunner.InstanceRoleARN = "arn:aws:iam::123456789012:role/app"
+	statements := policyStatements(t, m)
+
+	if _, ok := statements["AccessRole"]; ok {
+		t.Error("Unexpected AccessRole statement with a configured access role")
+	}
+	roles := statements["PassServiceRoles"].Resource
+	if len(roles) != 2 || !containsString(roles, m.AppRunner.AccessRoleARN) || !containsString(roles, m.AppRunner.InstanceRoleARN) {
+		t.Errorf("Expected the configured roles to be passable, got %v", roles)
+	}
+}
+
+func TestIAMPolicyRequiresApplication(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected an error without application.name")
+	}
+}
@@ -0,0 +1,68 @@
+package apprunner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// logEvent is an event returned by CloudWatch Logs FilterLogEvents.
+type logEvent struct {
+	EventID       string `json:"eventId"`
+	LogStreamName string `json:"logStreamName"`
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+}
+
+// Logs prints the output of the service from the application log group App
+// Runner creates for it. Streams are named instance/<instance ID>.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	svc, err := p.requireService(ctx, m)
+	if err != nil {
+		return err
+	}
+	group := logGroupName(svc)
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var entries []types.LogEntry
+		input := map[string]interface{}{
+			"logGroupName": group,
+			"startTime":    since.UnixMilli(),
+		}
+		for {
+			var out struct {
+				Events    []logEvent `json:"events"`
+				NextToken string     `json:"nextToken"`
+			}
+			if err := p.logsClient.JSON(ctx, logsTargetPrefix+"FilterLogEvents", input, &out); err != nil {
+				if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+					return nil, fmt.Errorf("log group %s does not exist; the service has not logged anything yet", group)
+				}
+				return nil, fmt.Errorf("failed to read log group %s: %w", group, err)
+			}
+			for _, e := range out.Events {
+				entries = append(entries, types.LogEntry{
+					ID:        e.EventID,
+					Timestamp: time.UnixMilli(e.Timestamp),
+					Source:    e.LogStreamName,
+					Message:   strings.TrimRight(e.Message, "\n"),
+				})
+			}
+			if out.NextToken == "" {
+				return entries, nil
+			}
+			input["nextToken"] = out.NextToken
+		}
+	})
+}
+
+// logGroupName returns the log group App Runner sends the service's
+// application output to.
+func logGroupName(svc *service) string {
+	return fmt.Sprintf("/aws/apprunner/%s/%s/application", svc.ServiceName, svc.ServiceId)
+}
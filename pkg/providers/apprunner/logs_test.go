@@ -0,0 +1,47 @@
+package apprunner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out}); err == nil {
+		t.Error("expected Logs() to fail before the first deployment")
+	}
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "has not logged anything yet") {
+		t.Errorf("expected missing log group error, got %v", err)
+	}
+
+	now := time.Now().UnixMilli()
+	fake.logGroup = true
+	fake.events = []logEvent{
+		{EventID: "1", LogStreamName: "instance/abc", Timestamp: now - 2000, Message: "listening on :8080\n"},
+		{EventID: "2", LogStreamName: "instance/abc", Timestamp: now - 1000, Message: "handled request"},
+	}
+	if err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs() failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[instance/abc] listening on :8080") || !strings.HasSuffix(lines[1], "[instance/abc] handled request") {
+		t.Errorf("unexpected logs:\n%s", out.String())
+	}
+	if group := fake.requests["FilterLogEvents"].(map[string]interface{})["logGroupName"]; group != "/aws/apprunner/my-env/svc123/application" {
+		t.Errorf("unexpected log group %v", group)
+	}
+}
@@ -0,0 +1,49 @@
+package apprunner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing anything in the account. Every deployment updates the service to
+// run the newly pushed image.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "aws-apprunner"}
+	name := m.Environment.Name
+	cpu, memory := m.AppRunner.InstanceSize()
+	min, max, concurrency := m.AppRunner.Scaling()
+
+	scalingName := autoScalingName(m)
+	scaling := fmt.Sprintf("%d-%d instances, %d concurrent requests each", min, max, concurrency)
+	latest, err := p.latestAutoScaling(ctx, scalingName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case latest == nil:
+		plan.Add(types.PlanCreate, "AutoScalingConfiguration", scalingName, scaling)
+	case !latest.matches(m.AppRunner):
+		plan.Add(types.PlanUpdate, "AutoScalingConfiguration", scalingName, fmt.Sprintf("new revision: %s (was %d-%d instances, %d concurrent requests each)", scaling, latest.MinSize, latest.MaxSize, latest.MaxConcurrency))
+	default:
+		plan.Add(types.PlanNoChange, "AutoScalingConfiguration", scalingName, scaling)
+	}
+
+	svc, err := p.findService(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	size := fmt.Sprintf("%d CPU, %d MiB, running %s", cpu, memory, m.Image)
+	switch {
+	case svc == nil:
+		plan.Add(types.PlanCreate, "Service", name, size)
+	case svc.Status == "PAUSED":
+		plan.Add(types.PlanUpdate, "Service", name, "resume and run "+m.Image)
+	default:
+		plan.Add(types.PlanUpdate, "Service", name, size)
+	}
+	return plan, nil
+}
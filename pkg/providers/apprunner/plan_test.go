@@ -0,0 +1,47 @@
+package apprunner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	plan, err := p.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[0].Action != types.PlanCreate || plan.Changes[1].Action != types.PlanCreate || plan.Changes[1].Type != "Service" {
+		t.Errorf("expected the auto scaling configuration and service to be created, got %+v", plan.Changes)
+	}
+
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	plan, err = p.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if plan.Changes[0].Action != types.PlanNoChange || plan.Changes[1].Action != types.PlanUpdate {
+		t.Errorf("expected only the service to be updated, got %+v", plan.Changes)
+	}
+
+	m.AppRunner.MaxInstances = 20
+	fake.service["Status"] = "PAUSED"
+	plan, err = p.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if plan.Changes[0].Action != types.PlanUpdate || plan.Changes[1].Reason != "resume and run my-app:latest" {
+		t.Errorf("expected a new revision and the service resumed, got %+v", plan.Changes)
+	}
+	if len(fake.autoScaling) != 1 {
+		t.Error("expected Plan() not to create a revision")
+	}
+}
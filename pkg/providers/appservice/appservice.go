@@ -0,0 +1,667 @@
+// Package appservice provides an Azure App Service provider that runs the
+// manifest's image as a Web App for Containers on an App Service plan, whose
+// pricing tier comes from instance.type. Deployments can go through a
+// deployment slot that is swapped into production once it is warmed up, so
+// rolling back swaps the previous version back. Custom domains are served
+// over HTTPS with free App Service managed certificates. Images are pushed to
+// the application's ACR registry, the same registry the azure provider uses.
+package appservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	"github.com/jvreagan/cloud-deploy/pkg/azureapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// API versions of the resource providers the provider calls.
+const (
+	webAPIVersion           = "2023-12-01"
+	resourceGroupAPIVersion = "2021-04-01"
+	registryAPIVersion      = "2023-07-01"
+)
+
+// previousImageTag is the app tag recording the image the app ran before the
+// last deployment, which Rollback returns to when no slot is configured.
+const previousImageTag = "cloud-deploy-previous-image"
+
+// dockerPrefix prefixes the image in an app's linuxFxVersion.
+const dockerPrefix = "DOCKER|"
+
+// Provider implements the provider.Provider interface for Azure App Service.
+type Provider struct {
+	subscriptionID string
+	location       string
+	resourceGroup  string
+	credential     azcore.TokenCredential
+	client         *azureapi.Client
+	httpClient     *http.Client
+}
+
+// New creates a new Azure App Service provider instance. Credentials are
+// loaded as they are for the azure provider.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	if config.SubscriptionID == "" {
+		return nil, fmt.Errorf("subscription ID is required")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("location is required")
+	}
+	if config.ResourceGroup == "" {
+		return nil, fmt.Errorf("resource group is required")
+	}
+
+	var azureCreds *manifest.AzureCredentialsConfig
+	if config.Credentials != nil {
+		azureCreds = config.Credentials.Azure
+	}
+	cred, err := azure.NewCredential(ctx, azureCreds, config.Credentials, m)
+	if err != nil {
+		return nil, err
+	}
+	p := newProvider(cred, config.SubscriptionID, config.Region, config.ResourceGroup)
+	if config.Emulated() {
+		p.client.SetEndpoint(config.EndpointURL)
+	}
+	return p, nil
+}
+
+// newProvider creates the Resource Manager client for cred.
+func newProvider(cred azcore.TokenCredential, subscriptionID, location, resourceGroup string) *Provider {
+	return &Provider{
+		subscriptionID: subscriptionID,
+		location:       location,
+		resourceGroup:  resourceGroup,
+		credential:     cred,
+		client:         azureapi.New(cred, subscriptionID),
+		httpClient:     http.DefaultClient,
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "azure-app-service"
+}
+
+// Close closes the idle connections of the provider's HTTP clients.
+func (p *Provider) Close() error {
+	p.client.CloseIdleConnections()
+	p.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// site is an App Service app or deployment slot, as returned by Resource
+// Manager.
+type site struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		State                      string `json:"state"`
+		AvailabilityState          string `json:"availabilityState"`
+		DefaultHostName            string `json:"defaultHostName"`
+		LastModifiedTimeUTC        string `json:"lastModifiedTimeUtc"`
+		ServerFarmID               string `json:"serverFarmId"`
+		CustomDomainVerificationID string `json:"customDomainVerificationId"`
+		HostNameSSLStates          []struct {
+			Name     string `json:"name"`
+			HostType string `json:"hostType"`
+		} `json:"hostNameSslStates"`
+	} `json:"properties"`
+}
+
+// url returns the app's HTTPS URL.
+func (s *site) url() string {
+	if s.Properties.DefaultHostName == "" {
+		return ""
+	}
+	return "https://" + s.Properties.DefaultHostName
+}
+
+// scmHost returns the host name of the app's Kudu (SCM) site.
+func (s *site) scmHost() string {
+	for _, state := range s.Properties.HostNameSSLStates {
+		if state.HostType == "Repository" {
+			return state.Name
+		}
+	}
+	return ""
+}
+
+// plan is an App Service plan.
+type plan struct {
+	ID  string `json:"id"`
+	SKU struct {
+		Name     string `json:"name"`
+		Capacity int32  `json:"capacity"`
+	} `json:"sku"`
+}
+
+// Deploy pushes the manifest's image to ACR and runs it on App Service,
+// creating the resource group, the registry, and the App Service plan the
+// first time. When app_service.slot is set, an existing app is updated by
+// deploying to the slot and swapping it into production.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := validateImagePlatforms(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Starting Azure App Service deployment...")
+
+	// Step 1: Ensure the resource group exists
+	if err := p.ensureResourceGroup(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure resource group: %w", err)
+	}
+
+	// Step 2: Push the image to ACR
+	server, image, err := p.pushImage(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Ensure the App Service plan exists with the manifest's tier
+	planID, err := p.ensurePlan(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: Deploy the image to the app, through the slot if configured
+	app, err := p.deployApp(ctx, m, planID, server, image)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Serve the custom domains over HTTPS
+	if err := p.ensureDomains(ctx, m, app, planID); err != nil {
+		return nil, err
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             app.url(),
+		Status:          "Running",
+		Message:         "Deployment successful",
+		Images:          map[string]string{m.Application.Name: image},
+	}, nil
+}
+
+// deployApp runs image on the app. A new app, or any app without a slot
+// configured, is updated in place; otherwise the slot is updated and swapped
+// into production, where App Service warms it up first.
+func (p *Provider) deployApp(ctx context.Context, m *manifest.Manifest, planID, server, image string) (*site, error) {
+	name := m.Environment.Name
+	existing, err := p.getSite(ctx, p.sitePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	username, password, err := p.registryCredentials(ctx, azure.RegistryName(m.Application.Name))
+	if err != nil {
+		return nil, err
+	}
+	body := buildSite(m, p.location, planID, image, server, username, password)
+
+	slot := m.AppService.SlotName()
+	if existing != nil && slot != "" {
+		logging.Infof("Deploying to slot %s of App Service app %s", slot, name)
+		var staged site
+		if err := p.client.Run(ctx, http.MethodPut, p.slotPath(name, slot), webAPIVersion, body, &staged); err != nil {
+			return nil, fmt.Errorf("failed to deploy slot %s: %w", slot, err)
+		}
+		if staged.Properties.State == "Stopped" {
+			if err := p.client.Do(ctx, http.MethodPost, p.slotPath(name, slot)+"/start", webAPIVersion, nil, nil); err != nil {
+				return nil, fmt.Errorf("failed to start slot %s: %w", slot, err)
+			}
+		}
+		if err := p.swap(ctx, name, slot); err != nil {
+			return nil, err
+		}
+	} else {
+		if existing == nil {
+			logging.Infof("Creating App Service app: %s", name)
+		} else {
+			logging.Infof("Updating App Service app: %s", name)
+			current, err := p.currentImage(ctx, p.sitePath(name))
+			if err != nil {
+				return nil, err
+			}
+			if current != "" && current != image {
+				body["tags"].(map[string]string)[previousImageTag] = current
+			} else if previous := existing.Tags[previousImageTag]; previous != "" {
+				body["tags"].(map[string]string)[previousImageTag] = previous
+			}
+		}
+		if err := p.client.Run(ctx, http.MethodPut, p.sitePath(name), webAPIVersion, body, nil); err != nil {
+			return nil, fmt.Errorf("failed to deploy App Service app %s: %w", name, err)
+		}
+	}
+
+	app, err := p.getSite(ctx, p.sitePath(name))
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, fmt.Errorf("App Service app %s was not created", name)
+	}
+	if app.Properties.State == "Stopped" {
+		logging.Infof("Starting stopped App Service app: %s", name)
+		if err := p.client.Do(ctx, http.MethodPost, p.sitePath(name)+"/start", webAPIVersion, nil, nil); err != nil {
+			return nil, fmt.Errorf("failed to start App Service app %s: %w", name, err)
+		}
+	}
+	return app, nil
+}
+
+// swap swaps the named slot into production. App Service warms the slot up
+// before swapping, so production keeps serving until the new version is ready.
+func (p *Provider) swap(ctx context.Context, name, slot string) error {
+	logging.Infof("Swapping slot %s into production", slot)
+	body := map[string]interface{}{"targetSlot": "production", "preserveVnet": true}
+	if err := p.client.Run(ctx, http.MethodPost, p.slotPath(name, slot)+"/slotsswap", webAPIVersion, body, nil); err != nil {
+		return fmt.Errorf("failed to swap slot %s into production: %w", slot, err)
+	}
+	return nil
+}
+
+// Destroy deletes the app with its deployment slot and managed certificates.
+// The App Service plan is deleted too when cloud-deploy named it for the
+// deployment environment and no other app runs on it; a configured
+// app_service.plan and the ACR registry, which other environments share, are
+// kept.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	if slot := m.AppService.SlotName(); slot != "" {
+		logging.Infof("Deleting slot %s of App Service app %s", slot, name)
+		if err := p.client.Run(ctx, http.MethodDelete, p.slotPath(name, slot), webAPIVersion, nil, nil); err != nil && !azureapi.IsNotFound(err) {
+			return fmt.Errorf("failed to delete slot %s: %w", slot, err)
+		}
+	}
+
+	logging.Infof("Deleting App Service app: %s", name)
+	path := p.sitePath(name)
+	if m.AppService == nil || m.AppService.Plan == "" {
+		path += "?deleteEmptyServerFarm=true"
+	}
+	if err := p.client.Run(ctx, http.MethodDelete, path, webAPIVersion, nil, nil); err != nil && !azureapi.IsNotFound(err) {
+		return fmt.Errorf("failed to delete App Service app %s: %w", name, err)
+	}
+
+	for _, domain := range m.AppService.Domains() {
+		logging.Infof("Deleting managed certificate: %s", domain)
+		if err := p.client.Do(ctx, http.MethodDelete, p.certificatePath(domain), webAPIVersion, nil, nil); err != nil && !azureapi.IsNotFound(err) {
+			return fmt.Errorf("failed to delete managed certificate %s: %w", domain, err)
+		}
+	}
+	logging.Info("App Service resources deleted successfully")
+	return nil
+}
+
+// Stop stops the app, which stops serving requests. The app and its
+// configuration are preserved; running Deploy again starts it.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	app, err := p.requireSite(ctx, m)
+	if err != nil {
+		return err
+	}
+	if app.Properties.State == "Stopped" {
+		logging.Infof("App Service app %s is already stopped", app.Name)
+		return nil
+	}
+	logging.Infof("Stopping App Service app: %s", app.Name)
+	if err := p.client.Do(ctx, http.MethodPost, p.sitePath(app.Name)+"/stop", webAPIVersion, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop App Service app %s: %w", app.Name, err)
+	}
+	logging.Info("App Service app stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the app.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	app, err := p.requireSite(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	status, health := siteStatus(app.Properties.State, app.Properties.AvailabilityState)
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             app.url(),
+		LastUpdated:     app.Properties.LastModifiedTimeUTC,
+	}, nil
+}
+
+// siteStatus returns the status and health of an app in the given state and
+// availability state.
+func siteStatus(state, availability string) (string, string) {
+	switch {
+	case state == "Stopped":
+		return "Stopped", "Grey"
+	case state == "Running" && availability == "Normal":
+		return "Running", "Green"
+	case state == "Running":
+		return "Running", "Yellow"
+	default:
+		return state, "Unknown"
+	}
+}
+
+// Rollback returns the app to the version it ran before the last deployment.
+// With a slot configured, the slot holds that version after a swap, so it is
+// swapped back into production; otherwise the previous image recorded on the
+// app is restored. Rolling back twice returns to the version rolled back from.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting Azure App Service rollback...")
+	app, err := p.requireSite(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var image string
+	if slot := m.AppService.SlotName(); slot != "" {
+		if image, err = p.currentImage(ctx, p.slotPath(app.Name, slot)); err != nil {
+			if azureapi.IsNotFound(err) {
+				return nil, fmt.Errorf("slot %s of App Service app %s does not exist; the app has been deployed only once", slot, app.Name)
+			}
+			return nil, err
+		}
+		if err := p.swap(ctx, app.Name, slot); err != nil {
+			return nil, err
+		}
+	} else {
+		image = app.Tags[previousImageTag]
+		if image == "" {
+			return nil, fmt.Errorf("no previous image recorded for App Service app %s; it has been deployed only once", app.Name)
+		}
+		current, err := p.currentImage(ctx, p.sitePath(app.Name))
+		if err != nil {
+			return nil, err
+		}
+
+		logging.Infof("Rolling back %s to image %s", app.Name, image)
+		config := map[string]interface{}{"properties": map[string]string{"linuxFxVersion": dockerPrefix + image}}
+		if err := p.client.Do(ctx, http.MethodPatch, p.sitePath(app.Name)+"/config/web", webAPIVersion, config, nil); err != nil {
+			return nil, fmt.Errorf("failed to roll back App Service app %s: %w", app.Name, err)
+		}
+		tags := map[string]string{previousImageTag: current}
+		for k, v := range app.Tags {
+			if k != previousImageTag {
+				tags[k] = v
+			}
+		}
+		if err := p.client.Do(ctx, http.MethodPatch, p.sitePath(app.Name), webAPIVersion, map[string]interface{}{"tags": tags}, nil); err != nil {
+			return nil, fmt.Errorf("failed to record the previous image of App Service app %s: %w", app.Name, err)
+		}
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             app.url(),
+		Status:          "Running",
+		Message:         "Rolled back to " + image,
+		Images:          map[string]string{m.Application.Name: image},
+	}, nil
+}
+
+// sitePath returns the Resource Manager path of the named app.
+func (p *Provider) sitePath(name string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.Web/sites/" + name
+}
+
+// slotPath returns the Resource Manager path of a deployment slot of the
+// named app.
+func (p *Provider) slotPath(name, slot string) string {
+	return p.sitePath(name) + "/slots/" + slot
+}
+
+// planPath returns the Resource Manager path of the named App Service plan.
+func (p *Provider) planPath(name string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.Web/serverfarms/" + name
+}
+
+// certificatePath returns the Resource Manager path of the managed
+// certificate of a custom domain.
+func (p *Provider) certificatePath(domain string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.Web/certificates/" + domain
+}
+
+// registryPath returns the Resource Manager path of the named ACR registry.
+func (p *Provider) registryPath(name string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.ContainerRegistry/registries/" + name
+}
+
+// getSite returns the app or slot at path, or nil when it does not exist.
+func (p *Provider) getSite(ctx context.Context, path string) (*site, error) {
+	var s site
+	err := p.client.Do(ctx, http.MethodGet, path, webAPIVersion, nil, &s)
+	if azureapi.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get App Service app: %w", err)
+	}
+	return &s, nil
+}
+
+// requireSite returns the environment's app, failing when it does not exist.
+func (p *Provider) requireSite(ctx context.Context, m *manifest.Manifest) (*site, error) {
+	app, err := p.getSite(ctx, p.sitePath(m.Environment.Name))
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, fmt.Errorf("no App Service app found for environment %s", m.Environment.Name)
+	}
+	return app, nil
+}
+
+// currentImage returns the image the app or slot at path runs.
+func (p *Provider) currentImage(ctx context.Context, path string) (string, error) {
+	var config struct {
+		Properties struct {
+			LinuxFxVersion string `json:"linuxFxVersion"`
+		} `json:"properties"`
+	}
+	if err := p.client.Do(ctx, http.MethodGet, path+"/config/web", webAPIVersion, nil, &config); err != nil {
+		return "", fmt.Errorf("failed to get site configuration: %w", err)
+	}
+	return strings.TrimPrefix(config.Properties.LinuxFxVersion, dockerPrefix), nil
+}
+
+// ensureResourceGroup creates the resource group, or updates it if it exists.
+func (p *Provider) ensureResourceGroup(ctx context.Context) error {
+	logging.Infof("Ensuring resource group exists: %s", p.resourceGroup)
+	body := map[string]interface{}{
+		"location": p.location,
+		"tags":     map[string]string{"ManagedBy": "cloud-deploy"},
+	}
+	return p.client.Do(ctx, http.MethodPut, p.client.ResourceGroupPath(p.resourceGroup), resourceGroupAPIVersion, body, nil)
+}
+
+// ensurePlan returns the ID of the app's App Service plan. The plan is
+// created, or updated to the manifest's pricing tier and worker count, unless
+// it is a configured app_service.plan that exists, which other apps may share.
+func (p *Provider) ensurePlan(ctx context.Context, m *manifest.Manifest) (string, error) {
+	name := m.AppService.PlanName(m.Environment.Name)
+	var existing plan
+	err := p.client.Do(ctx, http.MethodGet, p.planPath(name), webAPIVersion, nil, &existing)
+	if err != nil && !azureapi.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get App Service plan %s: %w", name, err)
+	}
+	sku, workers := manifest.AppServiceSKU(m.Instance)
+	if err == nil {
+		if m.AppService != nil && m.AppService.Plan != "" {
+			return existing.ID, nil
+		}
+		if existing.SKU.Name == sku && existing.SKU.Capacity == workers {
+			return existing.ID, nil
+		}
+		logging.Infof("Updating App Service plan %s to %s with %d workers", name, sku, workers)
+	} else {
+		logging.Infof("Creating App Service plan %s (%s, %d workers)", name, sku, workers)
+	}
+
+	body := map[string]interface{}{
+		"location":   p.location,
+		"kind":       "linux",
+		"tags":       map[string]string{"ManagedBy": "cloud-deploy"},
+		"sku":        map[string]interface{}{"name": sku, "capacity": workers},
+		"properties": map[string]interface{}{"reserved": true},
+	}
+	var created plan
+	if err := p.client.Run(ctx, http.MethodPut, p.planPath(name), webAPIVersion, body, &created); err != nil {
+		return "", fmt.Errorf("failed to create App Service plan %s: %w", name, err)
+	}
+	return created.ID, nil
+}
+
+// pushImage pushes the manifest's image to the application's ACR registry,
+// returning the registry's login server and the pushed image pinned by digest.
+// Every deployment pushes a new timestamped tag.
+func (p *Provider) pushImage(ctx context.Context, m *manifest.Manifest) (string, string, error) {
+	logging.Info("=== Distributing image to ACR ===")
+	tag := m.VersionLabel(fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405")))
+	acrRegistry, err := registry.NewACRRegistry(p.credential, p.subscriptionID, p.resourceGroup, azure.RegistryName(m.Application.Name), p.location, tag)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create ACR registry handler: %w", err)
+	}
+	distributor := registry.NewDistributor(m.Image, m.Platforms...)
+	dest := registry.Local(acrRegistry, m.Provider.RegistryURL, m.RepositoryName(), tag)
+	distributor.AddRegistry(dest)
+	imageURIs, err := distributor.Distribute(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to distribute image: %w", err)
+	}
+	server := dest.GetRegistryURL()
+	image := registry.PinDigest(imageURIs[server], distributor.Digest())
+	logging.Infof("Image pushed to ACR: %s", image)
+	return server, image, nil
+}
+
+// registryCredentials returns the admin user name and password of the named
+// ACR registry.
+func (p *Provider) registryCredentials(ctx context.Context, name string) (string, string, error) {
+	var out struct {
+		Username  string `json:"username"`
+		Passwords []struct {
+			Value string `json:"value"`
+		} `json:"passwords"`
+	}
+	if err := p.client.Do(ctx, http.MethodPost, p.registryPath(name)+"/listCredentials", registryAPIVersion, nil, &out); err != nil {
+		return "", "", fmt.Errorf("failed to get registry credentials: %w", err)
+	}
+	if len(out.Passwords) == 0 {
+		return "", "", fmt.Errorf("no passwords found for registry %s", name)
+	}
+	return out.Username, out.Passwords[0].Value, nil
+}
+
+// buildSite returns the request body that creates or updates an app or slot
+// running image, pulled from the ACR registry at server.
+func buildSite(m *manifest.Manifest, location, planID, image, server, username, password string) map[string]interface{} {
+	sku, _ := manifest.AppServiceSKU(m.Instance)
+	siteConfig := map[string]interface{}{
+		"linuxFxVersion": dockerPrefix + image,
+		"appSettings":    appSettings(m, server, username, password),
+		"alwaysOn":       sku != "F1",
+		"http20Enabled":  true,
+		"minTlsVersion":  "1.2",
+		"ftpsState":      "Disabled",
+	}
+	if m.HealthCheck.Path != "" {
+		siteConfig["healthCheckPath"] = m.HealthCheck.Path
+	}
+	c := m.GetPrimaryContainer()
+	if command := strings.Join(append(append([]string{}, c.Command...), c.Args...), " "); command != "" {
+		siteConfig["appCommandLine"] = command
+	}
+	if c.Workdir != "" {
+		logging.Warn("container.workdir is not supported by Azure App Service and will be ignored")
+	}
+
+	tags := map[string]string{"Application": m.Application.Name, "ManagedBy": "cloud-deploy"}
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+	return map[string]interface{}{
+		"location": location,
+		"kind":     "app,linux,container",
+		"tags":     tags,
+		"properties": map[string]interface{}{
+			"serverFarmId": planID,
+			"httpsOnly":    true,
+			"siteConfig":   siteConfig,
+		},
+	}
+}
+
+// appSettings returns the app settings of an app: the manifest's environment
+// variables, the ACR credentials App Service pulls the image with, and the
+// port the container listens on. With a slot configured, the health check path
+// is also the path App Service warms the slot up with before a swap.
+func appSettings(m *manifest.Manifest, server, username, password string) []map[string]string {
+	env := m.GetPrimaryContainer().Environment
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	settings := make([]map[string]string, 0, len(names)+6)
+	for _, name := range names {
+		settings = append(settings, map[string]string{"name": name, "value": env[name]})
+	}
+	settings = append(settings,
+		map[string]string{"name": "DOCKER_REGISTRY_SERVER_URL", "value": "https://" + server},
+		map[string]string{"name": "DOCKER_REGISTRY_SERVER_USERNAME", "value": username},
+		map[string]string{"name": "DOCKER_REGISTRY_SERVER_PASSWORD", "value": password},
+		map[string]string{"name": "WEBSITES_ENABLE_APP_SERVICE_STORAGE", "value": "false"},
+	)
+	if ports := m.GetPrimaryContainer().Ports; len(ports) > 0 {
+		settings = append(settings, map[string]string{"name": "WEBSITES_PORT", "value": strconv.Itoa(ports[0].ContainerPort)})
+	}
+	if m.AppService.SlotName() != "" && m.HealthCheck.Path != "" {
+		settings = append(settings, map[string]string{"name": "WEBSITE_SWAP_WARMUP_PING_PATH", "value": m.HealthCheck.Path})
+	}
+	return settings
+}
+
+// validateImagePlatforms fails unless the image can run on App Service,
+// which runs linux/amd64 containers.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	return registry.ValidatePlatform(ctx, m.Image, registry.LinuxAMD64, "Azure App Service", "")
+}
+
+// validateSecurity warns about security options App Service cannot enforce,
+// and fails if the image runs as root when run_as_non_root is set.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	sec := m.Security
+	if sec == nil {
+		return nil
+	}
+	if sec.ReadOnlyRootFilesystem || sec.User != "" || len(sec.DropCapabilities) > 0 {
+		logging.Warn("security.read_only_root_filesystem, user and drop_capabilities are not supported by Azure App Service and will be ignored")
+	}
+	if !sec.RunAsNonRoot {
+		return nil
+	}
+	return registry.ValidateNonRoot(ctx, m.Image)
+}
@@ -0,0 +1,556 @@
+package appservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const (
+	rgPath   = "/subscriptions/sub-123/resourceGroups/my-rg"
+	sitePath = rgPath + "/providers/Microsoft.Web/sites/my-app-prod"
+	slotPath = sitePath + "/slots/staging"
+	planPath = rgPath + "/providers/Microsoft.Web/serverfarms/my-app-prod-plan"
+	certPath = rgPath + "/providers/Microsoft.Web/certificates/"
+)
+
+// fakeCredential returns a fixed token.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeApp is an app or slot of the fake, with the image it runs.
+type fakeApp struct {
+	site  site
+	image string
+}
+
+// fakeARM serves the subset of the Resource Manager APIs the provider uses.
+type fakeARM struct {
+	mu       sync.Mutex
+	calls    []string
+	bodies   map[string]map[string]interface{} // "METHOD path" -> last JSON body
+	app      *fakeApp
+	slot     *fakeApp
+	plan     *plan
+	bindings map[string]string // custom domain -> SSL state
+	certs    map[string]bool
+	scmHost  string
+	deleted  string // query of the last app deletion
+	unbound  bool   // reject new custom domains, as for domains without DNS records
+	others   []site // other apps on the plan
+}
+
+func newFakeARM() *fakeARM {
+	return &fakeARM{
+		bodies:   make(map[string]map[string]interface{}),
+		bindings: make(map[string]string),
+		certs:    make(map[string]bool),
+	}
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := r.Method + " " + r.URL.Path
+	f.calls = append(f.calls, call)
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.bodies[call] = body
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"ResourceNotFound","message":"not found"}}`))
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/listCredentials"):
+		w.Write([]byte(`{"username":"myapp","passwords":[{"value":"registry-secret"}]}`))
+	case r.URL.Path == planPath+"/sites":
+		apps := append([]site{}, f.others...)
+		if f.app != nil {
+			apps = append(apps, f.app.site)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": apps})
+	case r.URL.Path == planPath:
+		switch r.Method {
+		case http.MethodGet:
+			if f.plan == nil {
+				notFound()
+				return
+			}
+		case http.MethodPut:
+			f.plan = &plan{ID: planPath}
+			sku := body["sku"].(map[string]interface{})
+			f.plan.SKU.Name = sku["name"].(string)
+			f.plan.SKU.Capacity = int32(sku["capacity"].(float64))
+		}
+		json.NewEncoder(w).Encode(f.plan)
+	case r.URL.Path == sitePath+"/slots/staging/slotsswap":
+		f.app.image, f.slot.image = f.slot.image, f.app.image
+	case strings.HasPrefix(r.URL.Path, slotPath):
+		f.slot = f.serveApp(w, r, f.slot, "my-app-prod/staging", strings.TrimPrefix(r.URL.Path, slotPath), body, notFound)
+	case strings.HasPrefix(r.URL.Path, sitePath+"/hostNameBindings/"):
+		domain := strings.TrimPrefix(r.URL.Path, sitePath+"/hostNameBindings/")
+		if r.Method == http.MethodPut && f.unbound {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":"BadRequest","message":"A TXT record pointing from asuid.www.example.com was not found."}}`))
+			return
+		}
+		if r.Method == http.MethodPut {
+			props := body["properties"].(map[string]interface{})
+			f.bindings[domain], _ = props["sslState"].(string)
+		}
+		state, ok := f.bindings[domain]
+		if !ok {
+			notFound()
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": domain, "properties": map[string]string{"sslState": state}})
+	case strings.HasPrefix(r.URL.Path, sitePath):
+		if r.Method == http.MethodDelete {
+			f.deleted = r.URL.RawQuery
+		}
+		f.app = f.serveApp(w, r, f.app, "my-app-prod", strings.TrimPrefix(r.URL.Path, sitePath), body, notFound)
+	case strings.HasPrefix(r.URL.Path, certPath):
+		domain := strings.TrimPrefix(r.URL.Path, certPath)
+		switch r.Method {
+		case http.MethodGet:
+			if !f.certs[domain] {
+				notFound()
+				return
+			}
+		case http.MethodPut:
+			f.certs[domain] = true
+		case http.MethodDelete:
+			if !f.certs[domain] {
+				notFound()
+				return
+			}
+			delete(f.certs, domain)
+			return
+		}
+		w.Write([]byte(`{"properties":{"thumbprint":"ABC123"}}`))
+	default:
+		w.Write([]byte(`{}`))
+	}
+}
+
+// serveApp handles requests for an app or slot, returning it as it is after
+// the request.
+func (f *fakeARM) serveApp(w http.ResponseWriter, r *http.Request, app *fakeApp, name, sub string, body map[string]interface{}, notFound func()) *fakeApp {
+	if app == nil && !(sub == "" && r.Method == http.MethodPut) {
+		notFound()
+		return nil
+	}
+	switch {
+	case sub == "/start":
+		app.site.Properties.State = "Running"
+	case sub == "/stop":
+		app.site.Properties.State = "Stopped"
+	case sub == "/config/web" && r.Method == http.MethodPatch:
+		props := body["properties"].(map[string]interface{})
+		app.image = strings.TrimPrefix(props["linuxFxVersion"].(string), dockerPrefix)
+	case sub == "/config/web":
+		json.NewEncoder(w).Encode(map[string]interface{}{"properties": map[string]string{"linuxFxVersion": dockerPrefix + app.image}})
+	case r.Method == http.MethodDelete:
+		return nil
+	case r.Method == http.MethodPut:
+		if app == nil {
+			app = &fakeApp{}
+			app.site.Name = name
+			app.site.Properties.State = "Running"
+			app.site.Properties.AvailabilityState = "Normal"
+			app.site.Properties.DefaultHostName = strings.Replace(name, "/", "-", 1) + ".azurewebsites.net"
+			app.site.Properties.CustomDomainVerificationID = "verification-id"
+			app.site.Properties.HostNameSSLStates = append(app.site.Properties.HostNameSSLStates, struct {
+				Name     string `json:"name"`
+				HostType string `json:"hostType"`
+			}{Name: f.scmHost, HostType: "Repository"})
+		}
+		app.site.Tags = make(map[string]string)
+		for k, v := range body["tags"].(map[string]interface{}) {
+			app.site.Tags[k] = v.(string)
+		}
+		props := body["properties"].(map[string]interface{})
+		config := props["siteConfig"].(map[string]interface{})
+		app.image = strings.TrimPrefix(config["linuxFxVersion"].(string), dockerPrefix)
+		json.NewEncoder(w).Encode(app.site)
+	case r.Method == http.MethodPatch:
+		app.site.Tags = make(map[string]string)
+		for k, v := range body["tags"].(map[string]interface{}) {
+			app.site.Tags[k] = v.(string)
+		}
+		json.NewEncoder(w).Encode(app.site)
+	default:
+		json.NewEncoder(w).Encode(app.site)
+	}
+	return app
+}
+
+// testProvider returns a provider that calls f.
+func testProvider(t *testing.T, f *fakeARM) *Provider {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	p := newProvider(fakeCredential{}, "sub-123", "eastus", "my-rg")
+	p.client.SetEndpoint(server.URL)
+	p.client.SetPolling(time.Millisecond, time.Second)
+	p.httpClient = server.Client()
+	return p
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:                "my-app:1.0",
+		Provider:             manifest.ProviderConfig{Name: "azure-app-service", Region: "eastus", SubscriptionID: "sub-123", ResourceGroup: "my-rg"},
+		Application:          manifest.ApplicationConfig{Name: "my-app"},
+		Environment:          manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Instance:             manifest.InstanceConfig{Type: "P1v3", MinInstances: 2},
+		Ports:                []manifest.PortMapping{{ContainerPort: 8080}},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info"},
+		HealthCheck:          manifest.HealthCheckConfig{Path: "/health"},
+		Tags:                 map[string]string{"team": "web"},
+	}
+}
+
+func TestNewRequiresSettings(t *testing.T) {
+	for _, config := range []manifest.ProviderConfig{
+		{Region: "eastus", ResourceGroup: "my-rg"},
+		{SubscriptionID: "sub-123", ResourceGroup: "my-rg"},
+		{SubscriptionID: "sub-123", Region: "eastus"},
+	} {
+		if _, err := New(context.Background(), &config, &manifest.Manifest{}); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestDeployApp(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	ctx := context.Background()
+
+	app, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "myapp.azurecr.io/myapp@sha256:a")
+	if err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if app.url() != "https://my-app-prod.azurewebsites.net" || f.app.image != "myapp.azurecr.io/myapp@sha256:a" {
+		t.Errorf("unexpected app: %s running %s", app.url(), f.app.image)
+	}
+	if _, ok := f.app.site.Tags[previousImageTag]; ok {
+		t.Errorf("Expected no previous image on a new app, got %v", f.app.site.Tags)
+	}
+
+	// Updating the app records the image it ran
+	f.app.site.Properties.State = "Stopped"
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "myapp.azurecr.io/myapp@sha256:b"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if f.app.image != "myapp.azurecr.io/myapp@sha256:b" || f.app.site.Tags[previousImageTag] != "myapp.azurecr.io/myapp@sha256:a" {
+		t.Errorf("unexpected app: running %s with tags %v", f.app.image, f.app.site.Tags)
+	}
+	if f.app.site.Properties.State != "Running" {
+		t.Errorf("Expected the stopped app to be started, got %s", f.app.site.Properties.State)
+	}
+
+	// Redeploying the same image keeps the recorded image
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "myapp.azurecr.io/myapp@sha256:b"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if f.app.site.Tags[previousImageTag] != "myapp.azurecr.io/myapp@sha256:a" {
+		t.Errorf("Expected the previous image to be kept, got %v", f.app.site.Tags)
+	}
+}
+
+func TestDeployAppSlot(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging"}
+	ctx := context.Background()
+
+	// The first deployment creates the app itself
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-a"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if f.slot != nil || f.app.image != "image-a" {
+		t.Errorf("Expected the app to be created without the slot")
+	}
+
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-b"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if f.app.image != "image-b" || f.slot.image != "image-a" {
+		t.Errorf("Expected image-b swapped into production, got production %s and slot %s", f.app.image, f.slot.image)
+	}
+	if _, ok := f.bodies["POST "+slotPath+"/slotsswap"]; !ok {
+		t.Errorf("Expected the slot to be swapped, got %v", f.calls)
+	}
+}
+
+func TestBuildSite(t *testing.T) {
+	m := testManifest()
+	m.Container = &manifest.ContainerStartup{Command: []string{"./server"}, Args: []string{"--port", "8080"}}
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging"}
+	body := buildSite(m, "eastus", planPath, "myapp.azurecr.io/myapp@sha256:a", "myapp.azurecr.io", "myapp", "registry-secret")
+
+	props := body["properties"].(map[string]interface{})
+	if props["serverFarmId"] != planPath || props["httpsOnly"] != true || body["kind"] != "app,linux,container" {
+		t.Errorf("unexpected site: %v", body)
+	}
+	config := props["siteConfig"].(map[string]interface{})
+	if config["linuxFxVersion"] != "DOCKER|myapp.azurecr.io/myapp@sha256:a" || config["alwaysOn"] != true || config["healthCheckPath"] != "/health" {
+		t.Errorf("unexpected site config: %v", config)
+	}
+	if config["appCommandLine"] != "./server --port 8080" {
+		t.Errorf("unexpected command line: %v", config["appCommandLine"])
+	}
+	settings, _ := json.Marshal(config["appSettings"])
+	want := `[{"name":"LOG_LEVEL","value":"info"},` +
+		`{"name":"DOCKER_REGISTRY_SERVER_URL","value":"https://myapp.azurecr.io"},` +
+		`{"name":"DOCKER_REGISTRY_SERVER_USERNAME","value":"myapp"},` +
+		`{"name":"DOCKER_REGISTRY_SERVER_PASSWORD","value":"registry-secret"},` +
+		`{"name":"WEBSITES_ENABLE_APP_SERVICE_STORAGE","value":"false"},` +
+		`{"name":"WEBSITES_PORT","value":"8080"},` +
+		`{"name":"WEBSITE_SWAP_WARMUP_PING_PATH","value":"/health"}]`
+	if string(settings) != want {
+		t.Errorf("app settings = %s, want %s", settings, want)
+	}
+	if tags := body["tags"].(map[string]string); tags["Application"] != "my-app" || tags["team"] != "web" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	// Free plans cannot keep the app always on
+	m.Instance.Type = "F1"
+	config = buildSite(m, "eastus", planPath, "image", "myapp.azurecr.io", "myapp", "secret")["properties"].(map[string]interface{})["siteConfig"].(map[string]interface{})
+	if config["alwaysOn"] != false {
+		t.Errorf("Expected alwaysOn to be off on F1, got %v", config["alwaysOn"])
+	}
+}
+
+func TestEnsurePlan(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	ctx := context.Background()
+
+	id, err := p.ensurePlan(ctx, m)
+	if err != nil {
+		t.Fatalf("ensurePlan failed: %v", err)
+	}
+	if id != planPath || f.plan.SKU.Name != "P1v3" || f.plan.SKU.Capacity != 2 {
+		t.Errorf("unexpected plan %s: %+v", id, f.plan)
+	}
+	if props := f.bodies["PUT "+planPath]["properties"].(map[string]interface{}); props["reserved"] != true {
+		t.Errorf("Expected a Linux plan, got %v", props)
+	}
+
+	// An unchanged plan is not written
+	delete(f.bodies, "PUT "+planPath)
+	if _, err := p.ensurePlan(ctx, m); err != nil {
+		t.Fatalf("ensurePlan failed: %v", err)
+	}
+	if _, ok := f.bodies["PUT "+planPath]; ok {
+		t.Error("Expected the unchanged plan not to be written")
+	}
+
+	m.Instance.MinInstances = 3
+	if _, err := p.ensurePlan(ctx, m); err != nil {
+		t.Fatalf("ensurePlan failed: %v", err)
+	}
+	if f.plan.SKU.Capacity != 3 {
+		t.Errorf("Expected the plan to be scaled to 3 workers, got %d", f.plan.SKU.Capacity)
+	}
+
+	// A configured plan that exists is never changed
+	m.Instance.MinInstances = 4
+	m.AppService = &manifest.AppServiceConfig{Plan: "my-app-prod-plan"}
+	if _, err := p.ensurePlan(ctx, m); err != nil {
+		t.Fatalf("ensurePlan failed: %v", err)
+	}
+	if f.plan.SKU.Capacity != 3 {
+		t.Errorf("Expected the configured plan to be kept, got %d workers", f.plan.SKU.Capacity)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+
+	if _, err := p.Status(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no App Service app found") {
+		t.Errorf("Expected a missing app error, got: %v", err)
+	}
+
+	if _, err := p.deployApp(context.Background(), m, planPath, "myapp.azurecr.io", "image-a"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	f.app.site.Properties.LastModifiedTimeUTC = "2026-03-01T10:00:00Z"
+	status, err := p.Status(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.LastUpdated != "2026-03-01T10:00:00Z" || status.URL != "https://my-app-prod.azurewebsites.net" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestSiteStatus(t *testing.T) {
+	tests := []struct {
+		state, availability, status, health string
+	}{
+		{"Running", "Normal", "Running", "Green"},
+		{"Running", "Limited", "Running", "Yellow"},
+		{"Stopped", "Normal", "Stopped", "Grey"},
+		{"Starting", "", "Starting", "Unknown"},
+	}
+	for _, tt := range tests {
+		if status, health := siteStatus(tt.state, tt.availability); status != tt.status || health != tt.health {
+			t.Errorf("siteStatus(%s, %s) = %s, %s, want %s, %s", tt.state, tt.availability, status, health, tt.status, tt.health)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	if _, err := p.deployApp(context.Background(), m, planPath, "myapp.azurecr.io", "image-a"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+
+	if err := p.Stop(context.Background(), m); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if f.app.site.Properties.State != "Stopped" {
+		t.Errorf("Expected the app to be stopped, got %s", f.app.site.Properties.State)
+	}
+
+	// Stopping again does nothing
+	calls := len(f.calls)
+	if err := p.Stop(context.Background(), m); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if len(f.calls) != calls+1 {
+		t.Errorf("Expected only the app to be read, got %v", f.calls[calls:])
+	}
+}
+
+func TestRollback(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-a"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil || !strings.Contains(err.Error(), "deployed only once") {
+		t.Errorf("Expected an error without a previous image, got: %v", err)
+	}
+
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-b"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	result, err := p.Rollback(ctx, m)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.Message != "Rolled back to image-a" || f.app.image != "image-a" {
+		t.Errorf("unexpected rollback: %+v, running %s", result, f.app.image)
+	}
+	if f.app.site.Tags[previousImageTag] != "image-b" || f.app.site.Tags["team"] != "web" {
+		t.Errorf("Expected image-b recorded with the other tags kept, got %v", f.app.site.Tags)
+	}
+
+	// Rolling back again returns to the image rolled back from
+	if _, err := p.Rollback(ctx, m); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if f.app.image != "image-b" {
+		t.Errorf("Expected a second rollback to image-b, got %s", f.app.image)
+	}
+}
+
+func TestRollbackSlot(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging"}
+	ctx := context.Background()
+
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-a"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil || !strings.Contains(err.Error(), "slot staging of App Service app my-app-prod does not exist") {
+		t.Errorf("Expected an error without the slot, got: %v", err)
+	}
+
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-b"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	result, err := p.Rollback(ctx, m)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.Images["my-app"] != "image-a" || f.app.image != "image-a" || f.slot.image != "image-b" {
+		t.Errorf("Expected image-a swapped back into production, got production %s and slot %s", f.app.image, f.slot.image)
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging", CustomDomains: []string{"www.example.com", "api.example.com"}}
+	ctx := context.Background()
+
+	for _, image := range []string{"image-a", "image-b"} {
+		if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", image); err != nil {
+			t.Fatalf("deployApp failed: %v", err)
+		}
+	}
+	f.certs["www.example.com"] = true
+
+	if err := p.Destroy(ctx, m); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if f.app != nil || f.slot != nil || len(f.certs) != 0 {
+		t.Errorf("Expected the app, slot and certificates to be deleted")
+	}
+	if f.deleted != "api-version=2023-12-01&deleteEmptyServerFarm=true" && f.deleted != "deleteEmptyServerFarm=true&api-version=2023-12-01" {
+		t.Errorf("Expected the default plan to be deleted with the app, got query %q", f.deleted)
+	}
+
+	// A configured plan is kept, and destroying again succeeds
+	m.AppService.Plan = "shared-plan"
+	if err := p.Destroy(ctx, m); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if strings.Contains(f.deleted, "deleteEmptyServerFarm") {
+		t.Errorf("Expected the configured plan to be kept, got query %q", f.deleted)
+	}
+}
+
+func TestName(t *testing.T) {
+	p := newProvider(fakeCredential{}, "sub-123", "eastus", "my-rg")
+	if p.Name() != "azure-app-service" {
+		t.Errorf("unexpected name: %s", p.Name())
+	}
+}
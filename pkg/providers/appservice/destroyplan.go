@@ -0,0 +1,129 @@
+package appservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// destroyState records which of a deployment's resources exist, and the
+// other apps running on its App Service plan.
+type destroyState struct {
+	resourceGroup bool
+	registry      bool
+	plan          bool
+
+	// app is nil when the app does not exist
+	app *site
+
+	slot bool
+
+	// certificates are the custom domains with a managed certificate
+	certificates []string
+
+	// otherApps are the names of the other apps on the plan
+	otherApps []string
+}
+
+// PlanDestroy returns what Destroy would do: delete the app with its slot
+// and managed certificates, and the App Service plan cloud-deploy created for
+// it unless other apps run on it. A configured plan, the container registry,
+// and the resource group are kept.
+func (p *Provider) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	var state destroyState
+	var err error
+
+	if state.resourceGroup, err = p.exists(ctx, p.client.ResourceGroupPath(p.resourceGroup), resourceGroupAPIVersion, nil); err != nil {
+		return nil, fmt.Errorf("failed to get resource group: %w", err)
+	}
+	if !state.resourceGroup {
+		return buildDestroyPlan(m, p.resourceGroup, state), nil
+	}
+
+	if state.registry, err = p.exists(ctx, p.registryPath(azure.RegistryName(m.Application.Name)), registryAPIVersion, nil); err != nil {
+		return nil, fmt.Errorf("failed to get container registry: %w", err)
+	}
+	if state.app, err = p.getSite(ctx, p.sitePath(m.Environment.Name)); err != nil {
+		return nil, err
+	}
+	if slot := m.AppService.SlotName(); slot != "" && state.app != nil {
+		if state.slot, err = p.exists(ctx, p.slotPath(m.Environment.Name, slot), webAPIVersion, nil); err != nil {
+			return nil, fmt.Errorf("failed to get slot %s: %w", slot, err)
+		}
+	}
+	for _, domain := range m.AppService.Domains() {
+		found, err := p.exists(ctx, p.certificatePath(domain), webAPIVersion, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get managed certificate %s: %w", domain, err)
+		}
+		if found {
+			state.certificates = append(state.certificates, domain)
+		}
+	}
+
+	planPath := p.planPath(m.AppService.PlanName(m.Environment.Name))
+	if state.plan, err = p.exists(ctx, planPath, webAPIVersion, nil); err != nil {
+		return nil, fmt.Errorf("failed to get App Service plan: %w", err)
+	}
+	if state.plan {
+		var apps struct {
+			Value []site `json:"value"`
+		}
+		if err := p.client.Do(ctx, http.MethodGet, planPath+"/sites", webAPIVersion, nil, &apps); err != nil {
+			return nil, fmt.Errorf("failed to list the apps of the App Service plan: %w", err)
+		}
+		for _, app := range apps.Value {
+			if app.Name != m.Environment.Name {
+				state.otherApps = append(state.otherApps, app.Name)
+			}
+		}
+	}
+
+	return buildDestroyPlan(m, p.resourceGroup, state), nil
+}
+
+// buildDestroyPlan returns what a destroy of m's environment does given the
+// existing resources in state.
+func buildDestroyPlan(m *manifest.Manifest, resourceGroup string, state destroyState) *types.Plan {
+	plan := &types.Plan{Provider: "azure-app-service"}
+	name := m.Environment.Name
+
+	if state.slot {
+		plan.Add(types.PlanDelete, "Deployment slot", name+"/"+m.AppService.SlotName(), "holds the version before the last swap")
+	}
+	if state.app != nil {
+		reason := "with its custom domain bindings"
+		if host := state.app.Properties.DefaultHostName; host != "" {
+			reason += "; releases " + host
+		}
+		plan.Add(types.PlanDelete, "App Service app", name, reason)
+	}
+	for _, domain := range state.certificates {
+		plan.Add(types.PlanDelete, "Managed certificate", domain, "")
+	}
+
+	if state.plan {
+		planName := m.AppService.PlanName(name)
+		switch {
+		case m.AppService != nil && m.AppService.Plan != "":
+			plan.Add(types.PlanNoChange, "App Service plan", planName, "kept; configured app_service.plan")
+		case len(state.otherApps) > 0:
+			plan.Add(types.PlanNoChange, "App Service plan", planName, "kept; apps "+strings.Join(state.otherApps, ", ")+" run on it")
+		default:
+			plan.Add(types.PlanDelete, "App Service plan", planName, "no other app runs on it")
+		}
+	}
+
+	if state.registry {
+		plan.Add(types.PlanNoChange, "Container registry", azure.RegistryName(m.Application.Name), "kept with the images of every environment")
+	}
+	if state.resourceGroup {
+		plan.Add(types.PlanNoChange, "Resource group", resourceGroup, "kept")
+	}
+	return plan
+}
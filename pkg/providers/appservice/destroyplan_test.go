@@ -0,0 +1,71 @@
+package appservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlanDestroy(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging", CustomDomains: []string{"www.example.com", "api.example.com"}}
+	ctx := context.Background()
+
+	if _, err := p.ensurePlan(ctx, m); err != nil {
+		t.Fatalf("ensurePlan failed: %v", err)
+	}
+	for _, image := range []string{"image-a", "image-b"} {
+		if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", image); err != nil {
+			t.Fatalf("deployApp failed: %v", err)
+		}
+	}
+	f.certs["www.example.com"] = true
+	f.others = []site{{Name: "other-app"}}
+
+	calls := len(f.calls)
+	plan, err := p.PlanDestroy(ctx, m)
+	if err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	want := []types.ResourceChange{
+		{Action: types.PlanDelete, Type: "Deployment slot", Name: "my-app-prod/staging", Reason: "holds the version before the last swap"},
+		{Action: types.PlanDelete, Type: "App Service app", Name: "my-app-prod", Reason: "with its custom domain bindings; releases my-app-prod.azurewebsites.net"},
+		{Action: types.PlanDelete, Type: "Managed certificate", Name: "www.example.com"},
+		{Action: types.PlanNoChange, Type: "App Service plan", Name: "my-app-prod-plan", Reason: "kept; apps other-app run on it"},
+		{Action: types.PlanNoChange, Type: "Container registry", Name: "myapp", Reason: "kept with the images of every environment"},
+		{Action: types.PlanNoChange, Type: "Resource group", Name: "my-rg", Reason: "kept"},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("unexpected plan: %+v", plan.Changes)
+	}
+	for i, c := range want {
+		if plan.Changes[i] != c {
+			t.Errorf("change %d = %+v, want %+v", i, plan.Changes[i], c)
+		}
+	}
+	for _, call := range f.calls[calls:] {
+		if call[:3] != "GET" {
+			t.Errorf("Expected PlanDestroy to only read, got %s", call)
+		}
+	}
+
+	// The default plan goes with the last app on it, unless it is configured
+	f.others = nil
+	if plan, err = p.PlanDestroy(ctx, m); err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	if c := plan.Changes[3]; c.Action != types.PlanDelete || c.Reason != "no other app runs on it" {
+		t.Errorf("Expected the plan to be deleted, got %+v", c)
+	}
+	m.AppService.Plan = "my-app-prod-plan"
+	if plan, err = p.PlanDestroy(ctx, m); err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	if c := plan.Changes[3]; c.Action != types.PlanNoChange || c.Reason != "kept; configured app_service.plan" {
+		t.Errorf("Expected the configured plan to be kept, got %+v", c)
+	}
+}
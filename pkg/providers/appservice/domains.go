@@ -0,0 +1,80 @@
+package appservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jvreagan/cloud-deploy/pkg/azureapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// hostNameBinding is a custom domain bound to an app.
+type hostNameBinding struct {
+	Name       string `json:"name"`
+	Properties struct {
+		SSLState   string `json:"sslState"`
+		Thumbprint string `json:"thumbprint"`
+	} `json:"properties"`
+}
+
+// certificate is an App Service certificate.
+type certificate struct {
+	Properties struct {
+		Thumbprint string `json:"thumbprint"`
+	} `json:"properties"`
+}
+
+// ensureDomains binds each custom domain of the manifest to the app and
+// serves it over HTTPS with a free App Service managed certificate. Domains
+// already served over HTTPS are left alone. App Service only binds a domain
+// that points at the app and proves ownership with an asuid TXT record.
+func (p *Provider) ensureDomains(ctx context.Context, m *manifest.Manifest, app *site, planID string) error {
+	for _, domain := range m.AppService.Domains() {
+		path := p.sitePath(app.Name) + "/hostNameBindings/" + domain
+		var binding hostNameBinding
+		err := p.client.Do(ctx, http.MethodGet, path, webAPIVersion, nil, &binding)
+		if err != nil && !azureapi.IsNotFound(err) {
+			return fmt.Errorf("failed to get custom domain %s: %w", domain, err)
+		}
+		if err == nil && binding.Properties.SSLState == "SniEnabled" {
+			continue
+		}
+
+		properties := map[string]interface{}{
+			"siteName":                    app.Name,
+			"hostNameType":                "Verified",
+			"customHostNameDnsRecordType": "CName",
+		}
+		if err != nil {
+			logging.Infof("Adding custom domain: %s", domain)
+			if err := p.client.Do(ctx, http.MethodPut, path, webAPIVersion, map[string]interface{}{"properties": properties}, nil); err != nil {
+				return fmt.Errorf("failed to add custom domain %s: %w (point a CNAME record for it at %s and add a TXT record asuid.%s with the value %s)",
+					domain, err, app.Properties.DefaultHostName, domain, app.Properties.CustomDomainVerificationID)
+			}
+		}
+
+		logging.Infof("Creating managed certificate: %s", domain)
+		body := map[string]interface{}{
+			"location": p.location,
+			"tags":     map[string]string{"ManagedBy": "cloud-deploy"},
+			"properties": map[string]interface{}{
+				"canonicalName": domain,
+				"serverFarmId":  planID,
+			},
+		}
+		var cert certificate
+		if err := p.client.Run(ctx, http.MethodPut, p.certificatePath(domain), webAPIVersion, body, &cert); err != nil {
+			return fmt.Errorf("failed to create managed certificate for %s: %w", domain, err)
+		}
+
+		properties["sslState"] = "SniEnabled"
+		properties["thumbprint"] = cert.Properties.Thumbprint
+		if err := p.client.Do(ctx, http.MethodPut, path, webAPIVersion, map[string]interface{}{"properties": properties}, nil); err != nil {
+			return fmt.Errorf("failed to enable HTTPS for custom domain %s: %w", domain, err)
+		}
+		logging.Infof("Custom domain ready: https://%s", domain)
+	}
+	return nil
+}
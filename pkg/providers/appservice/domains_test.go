@@ -0,0 +1,67 @@
+package appservice
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestEnsureDomains(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	m.AppService = &manifest.AppServiceConfig{CustomDomains: []string{"www.example.com"}}
+	ctx := context.Background()
+
+	app, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-a")
+	if err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if err := p.ensureDomains(ctx, m, app, planPath); err != nil {
+		t.Fatalf("ensureDomains failed: %v", err)
+	}
+	if f.bindings["www.example.com"] != "SniEnabled" || !f.certs["www.example.com"] {
+		t.Errorf("Expected the domain to be served over HTTPS, got bindings %v and certificates %v", f.bindings, f.certs)
+	}
+	cert := f.bodies["PUT "+certPath+"www.example.com"]["properties"].(map[string]interface{})
+	if cert["canonicalName"] != "www.example.com" || cert["serverFarmId"] != planPath {
+		t.Errorf("unexpected certificate: %v", cert)
+	}
+	binding := f.bodies["PUT "+sitePath+"/hostNameBindings/www.example.com"]["properties"].(map[string]interface{})
+	if binding["thumbprint"] != "ABC123" || binding["hostNameType"] != "Verified" {
+		t.Errorf("unexpected binding: %v", binding)
+	}
+
+	// Domains already served over HTTPS are left alone
+	calls := len(f.calls)
+	if err := p.ensureDomains(ctx, m, app, planPath); err != nil {
+		t.Fatalf("ensureDomains failed: %v", err)
+	}
+	if len(f.calls) != calls+1 {
+		t.Errorf("Expected only the binding to be read, got %v", f.calls[calls:])
+	}
+}
+
+func TestEnsureDomainsUnverified(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	m.AppService = &manifest.AppServiceConfig{CustomDomains: []string{"www.example.com"}}
+	ctx := context.Background()
+
+	app, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-a")
+	if err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	// App Service rejects domains that do not point at the app
+	f.unbound = true
+	err = p.ensureDomains(ctx, m, app, planPath)
+	if err == nil || !strings.Contains(err.Error(), "CNAME record for it at my-app-prod.azurewebsites.net") || !strings.Contains(err.Error(), "asuid.www.example.com with the value verification-id") {
+		t.Errorf("Expected the DNS records to add, got: %v", err)
+	}
+	if len(f.certs) != 0 {
+		t.Errorf("Expected no certificate for an unbound domain, got %v", f.certs)
+	}
+}
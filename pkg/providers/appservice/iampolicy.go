@@ -0,0 +1,80 @@
+package appservice
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// roleDefinition is an Azure custom role definition, in the format accepted by
+// `az role definition create --role-definition`.
+type roleDefinition struct {
+	Name             string   `json:"Name"`
+	IsCustom         bool     `json:"IsCustom"`
+	Description      string   `json:"Description"`
+	Actions          []string `json:"Actions"`
+	NotActions       []string `json:"NotActions"`
+	AssignableScopes []string `json:"AssignableScopes"`
+}
+
+// IAMPolicy returns a custom role definition with the actions the deployer
+// needs, assignable on the manifest's resource group. Slot and custom domain
+// actions are only included when the manifest uses them.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	if m.Provider.SubscriptionID == "" || m.Provider.ResourceGroup == "" {
+		return nil, fmt.Errorf("provider.subscription_id and provider.resource_group are required to scope the role")
+	}
+
+	actions := []string{
+		"Microsoft.Resources/subscriptions/resourceGroups/read",
+		"Microsoft.Resources/subscriptions/resourceGroups/write",
+		"Microsoft.ContainerRegistry/registries/read",
+		"Microsoft.ContainerRegistry/registries/write",
+		"Microsoft.ContainerRegistry/registries/listCredentials/action",
+		"Microsoft.Web/serverfarms/read",
+		"Microsoft.Web/serverfarms/write",
+		"Microsoft.Web/serverfarms/delete",
+		"Microsoft.Web/sites/read",
+		"Microsoft.Web/sites/write",
+		"Microsoft.Web/sites/delete",
+		"Microsoft.Web/sites/config/read",
+		"Microsoft.Web/sites/config/write",
+		"Microsoft.Web/sites/start/action",
+		"Microsoft.Web/sites/stop/action",
+		// Reading container logs through the app's Kudu API
+		"Microsoft.Web/sites/publish/action",
+	}
+	if m.AppService.SlotName() != "" {
+		actions = append(actions,
+			"Microsoft.Web/sites/slots/read",
+			"Microsoft.Web/sites/slots/write",
+			"Microsoft.Web/sites/slots/delete",
+			"Microsoft.Web/sites/slots/config/read",
+			"Microsoft.Web/sites/slots/start/action",
+			"Microsoft.Web/sites/slots/slotsswap/action",
+		)
+	}
+	if len(m.AppService.Domains()) > 0 {
+		actions = append(actions,
+			"Microsoft.Web/sites/hostNameBindings/read",
+			"Microsoft.Web/sites/hostNameBindings/write",
+			"Microsoft.Web/certificates/read",
+			"Microsoft.Web/certificates/write",
+			"Microsoft.Web/certificates/delete",
+		)
+	}
+
+	role := roleDefinition{
+		Name:        fmt.Sprintf("cloud-deploy deployer (%s)", m.Application.Name),
+		IsCustom:    true,
+		Description: fmt.Sprintf("Deploys %s to Azure App Service with cloud-deploy.", m.Application.Name),
+		Actions:     actions,
+		NotActions:  []string{},
+		AssignableScopes: []string{
+			fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", m.Provider.SubscriptionID, m.Provider.ResourceGroup),
+		},
+	}
+
+	return json.MarshalIndent(role, "", "  ")
+}
@@ -0,0 +1,58 @@
+package appservice
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	m := testManifest()
+	actions := func() map[string]bool {
+		t.Helper()
+		data, err := IAMPolicy(m)
+		if err != nil {
+			t.Fatalf("IAMPolicy failed: %v", err)
+		}
+		var role roleDefinition
+		if err := json.Unmarshal(data, &role); err != nil {
+			t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+		}
+		if !role.IsCustom || role.Name != "cloud-deploy deployer (my-app)" {
+			t.Errorf("unexpected role: %+v", role)
+		}
+		if len(role.AssignableScopes) != 1 || role.AssignableScopes[0] != "/subscriptions/sub-123/resourceGroups/my-rg" {
+			t.Errorf("Expected the role to be scoped to the resource group, got %v", role.AssignableScopes)
+		}
+		actions := make(map[string]bool)
+		for _, a := range role.Actions {
+			actions[a] = true
+		}
+		return actions
+	}
+
+	got := actions()
+	for _, a := range []string{"Microsoft.Web/sites/write", "Microsoft.Web/serverfarms/write", "Microsoft.ContainerRegistry/registries/listCredentials/action"} {
+		if !got[a] {
+			t.Errorf("Expected action %s", a)
+		}
+	}
+	if got["Microsoft.Web/sites/slots/slotsswap/action"] || got["Microsoft.Web/certificates/write"] {
+		t.Error("Expected no slot or certificate actions without a slot or custom domains")
+	}
+
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging", CustomDomains: []string{"www.example.com"}}
+	got = actions()
+	for _, a := range []string{"Microsoft.Web/sites/slots/slotsswap/action", "Microsoft.Web/sites/hostNameBindings/write", "Microsoft.Web/certificates/write"} {
+		if !got[a] {
+			t.Errorf("Expected action %s", a)
+		}
+	}
+}
+
+func TestIAMPolicyRequiresScope(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{Provider: manifest.ProviderConfig{SubscriptionID: "sub-123"}}); err == nil {
+		t.Error("Expected an error without provider.resource_group")
+	}
+}
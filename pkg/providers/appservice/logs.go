@@ -0,0 +1,127 @@
+package appservice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// logFile is a container log file listed by the Kudu API.
+type logFile struct {
+	MachineName string    `json:"machineName"`
+	LastUpdated time.Time `json:"lastUpdated"`
+	Href        string    `json:"href"`
+	Path        string    `json:"path"`
+}
+
+// containerLogSuffix ends the names of the log files holding the container's
+// output; the other docker log files hold App Service's own messages.
+const containerLogSuffix = "_default_docker.log"
+
+// Logs prints the container's output, read from the docker log files of the
+// app's instances through its Kudu API, which accepts the same Azure AD
+// tokens as Resource Manager.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	app, err := p.requireSite(ctx, m)
+	if err != nil {
+		return err
+	}
+	scmHost := app.scmHost()
+	if scmHost == "" {
+		return fmt.Errorf("App Service app %s has no Kudu site", app.Name)
+	}
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		token, err := p.client.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var files []logFile
+		body, err := p.kudu(ctx, "https://"+scmHost+"/api/logs/docker", token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list log files: %w", err)
+		}
+		if err := json.Unmarshal([]byte(body), &files); err != nil {
+			return nil, fmt.Errorf("failed to decode log files: %w", err)
+		}
+
+		var entries []types.LogEntry
+		for _, f := range files {
+			// Files not written to since are skipped; instances start a new file when they restart
+			if !strings.HasSuffix(f.Path, containerLogSuffix) || f.LastUpdated.Before(since) {
+				continue
+			}
+			content, err := p.kudu(ctx, f.Href, token)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read log file %s: %w", f.Path, err)
+			}
+			for _, e := range parseDockerLog(f.MachineName, content) {
+				if !e.Timestamp.Before(since) {
+					entries = append(entries, e)
+				}
+			}
+		}
+		return entries, nil
+	})
+}
+
+// kudu returns the body of a Kudu API request.
+func (p *Provider) kudu(ctx context.Context, url, token string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// parseDockerLog converts a docker log file, whose lines start with an
+// RFC 3339 timestamp, into entries. Lines without a timestamp are skipped.
+func parseDockerLog(source, content string) []types.LogEntry {
+	var entries []types.LogEntry
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		timestamp, message, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, types.LogEntry{
+			// The files have no entry IDs; the timestamped line identifies the entry
+			ID:        source + " " + line,
+			Timestamp: ts,
+			Source:    source,
+			Message:   strings.TrimSpace(message),
+		})
+	}
+	return entries
+}
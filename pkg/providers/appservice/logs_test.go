@@ -0,0 +1,79 @@
+package appservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	now := time.Now().UTC()
+	var kudu *httptest.Server
+	kudu = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/logs/docker":
+			json.NewEncoder(w).Encode([]logFile{
+				{MachineName: "lw0sdlwk000001", LastUpdated: now, Href: kudu.URL + "/api/vfs/current_default_docker.log", Path: "/home/LogFiles/current_default_docker.log"},
+				{MachineName: "lw0sdlwk000001", LastUpdated: now, Href: kudu.URL + "/api/vfs/current_docker.log", Path: "/home/LogFiles/current_docker.log"},
+				{MachineName: "lw0sdlwk000002", LastUpdated: now.Add(-2 * time.Hour), Href: kudu.URL + "/api/vfs/old_default_docker.log", Path: "/home/LogFiles/old_default_docker.log"},
+			})
+		case "/api/vfs/current_default_docker.log":
+			w.Write([]byte(now.Add(-2*time.Hour).Format(time.RFC3339Nano) + " too old\n" +
+				now.Add(-2*time.Second).Format(time.RFC3339Nano) + " listening on :8080\n" +
+				now.Add(-time.Second).Format(time.RFC3339Nano) + " handled request\n"))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer kudu.Close()
+
+	f := newFakeARM()
+	f.scmHost = strings.TrimPrefix(kudu.URL, "https://")
+	p := testProvider(t, f)
+	p.httpClient = kudu.Client()
+	m := testManifest()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out}); err == nil {
+		t.Error("Expected Logs to fail before the first deployment")
+	}
+
+	if _, err := p.deployApp(ctx, m, planPath, "myapp.azurecr.io", "image-a"); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[lw0sdlwk000001] listening on :8080") || !strings.HasSuffix(lines[1], "handled request") {
+		t.Errorf("unexpected logs:\n%s", out.String())
+	}
+}
+
+func TestParseDockerLog(t *testing.T) {
+	content := "2026-03-01T10:00:00.5Z started\n" +
+		"Container my-app-prod didn't respond to HTTP pings\n" +
+		"2026-03-01T10:00:01Z ready\n"
+	entries := parseDockerLog("instance", content)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Message != "started" || entries[0].Source != "instance" || !entries[0].Timestamp.Equal(time.Date(2026, 3, 1, 10, 0, 0, 5e8, time.UTC)) {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[0].ID == entries[1].ID {
+		t.Errorf("Expected distinct IDs, got %q", entries[0].ID)
+	}
+}
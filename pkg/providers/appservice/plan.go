@@ -0,0 +1,138 @@
+package appservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jvreagan/cloud-deploy/pkg/azureapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// planState records which of a deployment's resources already exist.
+type planState struct {
+	resourceGroup bool
+	registry      bool
+
+	// plan is nil when the App Service plan does not exist
+	plan *plan
+
+	// app is nil when the app does not exist
+	app *site
+
+	// domains holds the custom domains already served over HTTPS
+	domains map[string]bool
+}
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing any Azure resources.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	state := planState{domains: make(map[string]bool)}
+	var err error
+
+	if state.resourceGroup, err = p.exists(ctx, p.client.ResourceGroupPath(p.resourceGroup), resourceGroupAPIVersion, nil); err != nil {
+		return nil, fmt.Errorf("failed to get resource group: %w", err)
+	}
+
+	// Nothing can exist in a resource group that does not
+	if state.resourceGroup {
+		if state.registry, err = p.exists(ctx, p.registryPath(azure.RegistryName(m.Application.Name)), registryAPIVersion, nil); err != nil {
+			return nil, fmt.Errorf("failed to get container registry: %w", err)
+		}
+		var existing plan
+		found, err := p.exists(ctx, p.planPath(m.AppService.PlanName(m.Environment.Name)), webAPIVersion, &existing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get App Service plan: %w", err)
+		}
+		if found {
+			state.plan = &existing
+		}
+		if state.app, err = p.getSite(ctx, p.sitePath(m.Environment.Name)); err != nil {
+			return nil, err
+		}
+		if state.app != nil {
+			for _, domain := range m.AppService.Domains() {
+				var binding hostNameBinding
+				found, err := p.exists(ctx, p.sitePath(state.app.Name)+"/hostNameBindings/"+domain, webAPIVersion, &binding)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get custom domain %s: %w", domain, err)
+				}
+				state.domains[domain] = found && binding.Properties.SSLState == "SniEnabled"
+			}
+		}
+	}
+
+	return buildPlan(m, p.resourceGroup, state), nil
+}
+
+// exists reports whether a Resource Manager Get call finds its resource,
+// which is read into out unless it is nil.
+func (p *Provider) exists(ctx context.Context, path, apiVersion string, out interface{}) (bool, error) {
+	err := p.client.Do(ctx, http.MethodGet, path, apiVersion, nil, out)
+	switch {
+	case err == nil:
+		return true, nil
+	case azureapi.IsNotFound(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// buildPlan returns the changes a deployment of m makes given the existing resources.
+func buildPlan(m *manifest.Manifest, resourceGroup string, state planState) *types.Plan {
+	plan := &types.Plan{Provider: "azure-app-service"}
+
+	if state.resourceGroup {
+		plan.Add(types.PlanNoChange, "Resource group", resourceGroup, "")
+	} else {
+		plan.Add(types.PlanCreate, "Resource group", resourceGroup, "resource group does not exist")
+	}
+
+	registryName := azure.RegistryName(m.Application.Name)
+	if state.registry {
+		plan.Add(types.PlanNoChange, "Container registry", registryName, "")
+	} else {
+		plan.Add(types.PlanCreate, "Container registry", registryName, "registry does not exist")
+	}
+	plan.Add(types.PlanCreate, "Container image", fmt.Sprintf("%s.azurecr.io/%s:deploy-<timestamp>", registryName, registryName), "push "+m.Image)
+
+	planName := m.AppService.PlanName(m.Environment.Name)
+	sku, workers := manifest.AppServiceSKU(m.Instance)
+	tier := fmt.Sprintf("%s, %d workers", sku, workers)
+	switch {
+	case state.plan == nil:
+		plan.Add(types.PlanCreate, "App Service plan", planName, "plan does not exist; "+tier)
+	case m.AppService != nil && m.AppService.Plan != "":
+		plan.Add(types.PlanNoChange, "App Service plan", planName, "configured plan is used as it is")
+	case state.plan.SKU.Name != sku || state.plan.SKU.Capacity != workers:
+		plan.Add(types.PlanUpdate, "App Service plan", planName, fmt.Sprintf("%s, %d workers -> %s", state.plan.SKU.Name, state.plan.SKU.Capacity, tier))
+	default:
+		plan.Add(types.PlanNoChange, "App Service plan", planName, "")
+	}
+
+	name := m.Environment.Name
+	slot := m.AppService.SlotName()
+	switch {
+	case state.app == nil:
+		plan.Add(types.PlanCreate, "App Service app", name, "app does not exist")
+	case slot != "":
+		plan.Add(types.PlanUpdate, "Deployment slot", name+"/"+slot, "deploy the new image")
+		plan.Add(types.PlanUpdate, "App Service app", name, "swap slot "+slot+" into production")
+	case state.app.Properties.State == "Stopped":
+		plan.Add(types.PlanUpdate, "App Service app", name, "start and run the new image")
+	default:
+		plan.Add(types.PlanUpdate, "App Service app", name, "run the new image")
+	}
+
+	for _, domain := range m.AppService.Domains() {
+		if state.domains[domain] {
+			plan.Add(types.PlanNoChange, "Custom domain", domain, "")
+		} else {
+			plan.Add(types.PlanCreate, "Custom domain", domain, "bind with a managed certificate")
+		}
+	}
+	return plan
+}
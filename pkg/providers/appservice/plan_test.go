@@ -0,0 +1,83 @@
+package appservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+
+	plan, err := p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	app := plan.Changes[len(plan.Changes)-1]
+	if app.Type != "App Service app" || app.Action != types.PlanCreate {
+		t.Errorf("Expected the app to be created, got %+v", app)
+	}
+	for _, call := range f.calls {
+		if call[:3] != "GET" {
+			t.Errorf("Expected Plan to only read, got %s", call)
+		}
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	m := testManifest()
+
+	got := buildPlan(m, "my-rg", planState{})
+	want := []types.ResourceChange{
+		{Action: types.PlanCreate, Type: "Resource group", Name: "my-rg", Reason: "resource group does not exist"},
+		{Action: types.PlanCreate, Type: "Container registry", Name: "myapp", Reason: "registry does not exist"},
+		{Action: types.PlanCreate, Type: "Container image", Name: "myapp.azurecr.io/myapp:deploy-<timestamp>", Reason: "push my-app:1.0"},
+		{Action: types.PlanCreate, Type: "App Service plan", Name: "my-app-prod-plan", Reason: "plan does not exist; P1v3, 2 workers"},
+		{Action: types.PlanCreate, Type: "App Service app", Name: "my-app-prod", Reason: "app does not exist"},
+	}
+	if got.Provider != "azure-app-service" || len(got.Changes) != len(want) {
+		t.Fatalf("unexpected plan: %+v", got)
+	}
+	for i, c := range want {
+		if got.Changes[i] != c {
+			t.Errorf("change %d = %+v, want %+v", i, got.Changes[i], c)
+		}
+	}
+
+	existing := &plan{}
+	existing.SKU.Name = "B1"
+	existing.SKU.Capacity = 1
+	app := &site{}
+	app.Properties.State = "Stopped"
+	got = buildPlan(m, "my-rg", planState{resourceGroup: true, registry: true, plan: existing, app: app})
+	for _, c := range got.Changes[:2] {
+		if c.Action != types.PlanNoChange {
+			t.Errorf("Expected no change, got %+v", c)
+		}
+	}
+	if c := got.Changes[3]; c.Action != types.PlanUpdate || c.Reason != "B1, 1 workers -> P1v3, 2 workers" {
+		t.Errorf("Expected the plan to be scaled, got %+v", c)
+	}
+	if c := got.Changes[4]; c.Action != types.PlanUpdate || c.Reason != "start and run the new image" {
+		t.Errorf("Expected the stopped app to be started, got %+v", c)
+	}
+
+	// Slot deployments update the slot and swap it; new custom domains are bound
+	m.AppService = &manifest.AppServiceConfig{Slot: "staging", CustomDomains: []string{"www.example.com", "api.example.com"}}
+	got = buildPlan(m, "my-rg", planState{resourceGroup: true, registry: true, plan: existing, app: app, domains: map[string]bool{"www.example.com": true}})
+	want = []types.ResourceChange{
+		{Action: types.PlanUpdate, Type: "Deployment slot", Name: "my-app-prod/staging", Reason: "deploy the new image"},
+		{Action: types.PlanUpdate, Type: "App Service app", Name: "my-app-prod", Reason: "swap slot staging into production"},
+		{Action: types.PlanNoChange, Type: "Custom domain", Name: "www.example.com"},
+		{Action: types.PlanCreate, Type: "Custom domain", Name: "api.example.com", Reason: "bind with a managed certificate"},
+	}
+	for i, c := range want {
+		if got.Changes[4+i] != c {
+			t.Errorf("change %d = %+v, want %+v", 4+i, got.Changes[4+i], c)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// operationEnvironmentUpdate is the kind of journaled environment creations
+// and updates. Elastic Beanstalk has no operation IDs, so they are
+// identified by "<application>/<environment>".
+const operationEnvironmentUpdate = "environment-update"
+
+// SetOperationJournal makes the provider journal the environment creations
+// and updates it waits for.
+func (p *Provider) SetOperationJournal(journal types.OperationJournal) {
+	p.journal = journal
+}
+
+// Attach waits for an environment creation or update started by another
+// invocation until the environment is Ready, and ends it in the journal.
+func (p *Provider) Attach(ctx context.Context, m *manifest.Manifest, op types.Operation) (*types.DeploymentResult, error) {
+	if op.Kind != operationEnvironmentUpdate {
+		return nil, fmt.Errorf("cannot attach to %s operations", op.Kind)
+	}
+	// Blue-green deployments journal the environment beside the live one
+	envName := strings.TrimPrefix(op.ID, m.Application.Name+"/")
+	logging.Info("Attaching to environment update", "environment", envName, "operator", op.Operator)
+	url, err := p.waitForDeployment(ctx, m.Application.Name, envName)
+	if err != nil {
+		return nil, fmt.Errorf("environment deployment failed: %w", err)
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: envName,
+		URL:             url,
+		Status:          "Ready",
+		Message:         "Attached to the environment update",
+	}, nil
+}
+
+// waitForDeployment journals the environment creation or update in progress
+// and waits for the environment to become ready. The operation stays in the
+// journal if ctx ends first, since Elastic Beanstalk carries on without us.
+func (p *Provider) waitForDeployment(ctx context.Context, appName, envName string) (string, error) {
+	if p.journal != nil {
+		op := types.Operation{Provider: "aws", Kind: operationEnvironmentUpdate, ID: appName + "/" + envName}
+		if err := p.journal.Begin(ctx, op); err != nil {
+			logging.Warn("Failed to journal the environment update", "error", err)
+		}
+	}
+	url, err := p.waitForEnvironment(ctx, appName, envName)
+	if p.journal != nil && ctx.Err() == nil {
+		if err := p.journal.End(ctx); err != nil {
+			logging.Warn("Failed to end the environment update in the journal", "error", err)
+		}
+	}
+	return url, err
+}
@@ -0,0 +1,17 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestAttachUnknownKind(t *testing.T) {
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-app-prod"}}
+	if _, err := (&Provider{}).Attach(context.Background(), m, types.Operation{Kind: "service-update"}); err == nil || !strings.Contains(err.Error(), "cannot attach to service-update operations") {
+		t.Errorf("Expected an error for another provider's operation, got: %v", err)
+	}
+}
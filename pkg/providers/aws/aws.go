@@ -6,54 +6,94 @@ import (
 	"archive/zip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
 	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"gopkg.in/yaml.v3"
 
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
 	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/readonly"
 	"github.com/jvreagan/cloud-deploy/pkg/registry"
 	"github.com/jvreagan/cloud-deploy/pkg/types"
 )
 
 // Provider implements the provider.Provider interface for AWS Elastic Beanstalk.
 type Provider struct {
-	ebClient *elasticbeanstalk.Client
-	s3Client *s3.Client
-	region   string
-	config   aws.Config
+	ebClient        *elasticbeanstalk.Client
+	s3Client        *s3.Client
+	cfnClient       *awsapi.Client
+	logsClient      *awsapi.Client
+	codebuildClient *awsapi.Client
+	elbClient       *awsapi.Client
+	secretsClient   *awsapi.Client
+	region          string
+	config          aws.Config
+	journal         types.OperationJournal
 }
 
 // New creates a new AWS provider instance with the specified region, credentials config, and manifest.
+// Credentials are loaded as described for LoadConfig.
+func New(ctx context.Context, region string, creds *manifest.CredentialsConfig, m *manifest.Manifest) (*Provider, error) {
+	cfg, err := LoadConfig(ctx, region, creds, m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		ebClient:        elasticbeanstalk.NewFromConfig(cfg),
+		s3Client:        s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = cfg.BaseEndpoint != nil }),
+		cfnClient:       awsapi.New(cfg, "cloudformation"),
+		logsClient:      awsapi.New(cfg, "logs"),
+		codebuildClient: awsapi.New(cfg, "codebuild"),
+		elbClient:       awsapi.New(cfg, "elasticloadbalancing"),
+		secretsClient:   awsapi.New(cfg, "secretsmanager"),
+		region:          region,
+		config:          cfg,
+	}, nil
+}
+
+// LoadConfig loads the AWS configuration for the region with the manifest's credentials,
+// so every provider deploying to AWS authenticates the same way.
 // Credentials can be loaded from:
-// 1. Vault (if credentials.source == "vault")
+// 1. A secret store (if credentials.source is "vault", "secrets-manager", "parameter-store", or "gcp-secret-manager")
 // 2. Environment variables (if credentials.source == "environment")
 // 3. Manifest (if credentials contain access_key_id and secret_access_key)
-// 4. AWS SDK default credential chain (default)
-func New(ctx context.Context, region string, creds *manifest.CredentialsConfig, m *manifest.Manifest) (*Provider, error) {
+// 4. A named profile of the shared config files (if credentials.profile is set)
+// 5. AWS SDK default credential chain (default)
+//
+// With credentials.role_arn set, the role is assumed with those credentials.
+// With provider.endpoint_url set, every service is called at that URL.
+func LoadConfig(ctx context.Context, region string, creds *manifest.CredentialsConfig, m *manifest.Manifest) (aws.Config, error) {
 	var cfg aws.Config
 	var err error
 
-	// Check if credentials should be loaded from Vault
-	if creds != nil && creds.Source == "vault" {
-		logging.Info("Loading AWS credentials from Vault")
+	// Check if credentials should be loaded from a secret store
+	if creds.FromSecretStore() {
+		logging.Info("Loading AWS credentials from secret store", "source", creds.Source)
 
-		// Get credentials from Vault using manifest helper
+		// Get credentials from the secret store using manifest helper
 		vaultCreds, err := m.GetCloudCredentials(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load AWS credentials from Vault: %w", err)
+			return aws.Config{}, fmt.Errorf("failed to load AWS credentials from %s: %w", creds.Source, err)
 		}
 
 		if vaultCreds != nil {
@@ -77,6 +117,13 @@ func New(ctx context.Context, region string, creds *manifest.CredentialsConfig,
 				"", // session token (optional)
 			)),
 		)
+	} else if creds != nil && creds.Profile != "" {
+		// Named profile of the shared config files, e.g. an SSO profile
+		logging.Info("Using AWS profile", "profile", creds.Profile)
+		cfg, err = config.LoadDefaultConfig(ctx, config.WithRegion(region), config.WithSharedConfigProfile(creds.Profile))
+		if err == nil {
+			err = checkProfile(ctx, cfg, creds.Profile)
+		}
 	} else {
 		// Fall back to default credential chain
 		logging.Info("Using AWS default credential chain")
@@ -84,15 +131,94 @@ func New(ctx context.Context, region string, creds *manifest.CredentialsConfig,
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	return &Provider{
-		ebClient: elasticbeanstalk.NewFromConfig(cfg),
-		s3Client: s3.NewFromConfig(cfg),
-		region:   region,
-		config:   cfg,
-	}, nil
+	// Emulators such as LocalStack serve every service at one endpoint
+	if m != nil && m.Provider.Emulated() {
+		logging.Info("Sending AWS requests to emulator", "endpoint", m.Provider.EndpointURL)
+		cfg.BaseEndpoint = aws.String(m.Provider.EndpointURL)
+	}
+
+	// Deploy as the role, e.g. in another account. STS is called before the
+	// read-only option is added, since assuming a role changes nothing.
+	switch {
+	case creds != nil && creds.Source == "oidc":
+		logging.Info("Assuming AWS role with the CI OIDC token", "role", creds.RoleARN)
+		cfg.Credentials = webIdentityCredentials(ctx, cfg, creds)
+	case creds != nil && creds.RoleARN != "":
+		logging.Info("Assuming AWS role", "role", creds.RoleARN)
+		cfg.Credentials = assumeRoleCredentials(cfg, creds)
+	}
+
+	// Refuse operations that are not reads in read-only mode
+	cfg.APIOptions = append(cfg.APIOptions, readonly.AWSAPIOption)
+	return cfg, nil
+}
+
+// checkProfile retrieves the credentials of profile, so an expired SSO
+// session is reported with how to sign in again before anything is deployed.
+// The credentials are cached for the clients.
+func checkProfile(ctx context.Context, cfg aws.Config, profile string) error {
+	_, err := cfg.Credentials.Retrieve(ctx)
+	var invalidToken *ssocreds.InvalidTokenError
+	switch {
+	case errors.As(err, &invalidToken):
+		return fmt.Errorf("the SSO session of AWS profile %s has expired or was never started; run `aws sso login --profile %s` and try again", profile, profile)
+	case err != nil:
+		return fmt.Errorf("failed to get the credentials of AWS profile %s: %w", profile, err)
+	}
+	return nil
+}
+
+// assumeRoleCredentials returns credentials of the role creds.RoleARN,
+// assumed with the credentials of cfg for an hour, the longest session every
+// role allows, so long deployments prompt for an MFA code at most hourly.
+func assumeRoleCredentials(cfg aws.Config, creds *manifest.CredentialsConfig) aws.CredentialsProvider {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), creds.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "cloud-deploy"
+		o.Duration = time.Hour
+		if creds.RoleSessionName != "" {
+			o.RoleSessionName = creds.RoleSessionName
+		}
+		if creds.ExternalID != "" {
+			o.ExternalID = aws.String(creds.ExternalID)
+		}
+		if creds.MFASerial != "" {
+			o.SerialNumber = aws.String(creds.MFASerial)
+			o.TokenProvider = stscreds.StdinTokenProvider
+		}
+	})
+	return aws.NewCredentialsCache(provider)
+}
+
+// webIdentityCredentials returns credentials of the role creds.RoleARN,
+// assumed with the CI job's OIDC token, so CI deploys without stored keys.
+func webIdentityCredentials(ctx context.Context, cfg aws.Config, creds *manifest.CredentialsConfig) aws.CredentialsProvider {
+	provider := stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(cfg), creds.RoleARN, ciIDToken{ctx: ctx, creds: creds}, func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = "cloud-deploy"
+		o.Duration = time.Hour
+		if creds.RoleSessionName != "" {
+			o.RoleSessionName = creds.RoleSessionName
+		}
+	})
+	return aws.NewCredentialsCache(provider)
+}
+
+// ciIDToken retrieves the CI job's OIDC token for STS each time the role's
+// credentials are refreshed, since the tokens expire within minutes.
+type ciIDToken struct {
+	ctx   context.Context
+	creds *manifest.CredentialsConfig
+}
+
+// GetIdentityToken implements stscreds.IdentityTokenRetriever.
+func (t ciIDToken) GetIdentityToken() ([]byte, error) {
+	token, err := t.creds.OIDCToken(t.ctx, "sts.amazonaws.com")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(token), nil
 }
 
 // Name returns the provider name.
@@ -100,8 +226,32 @@ func (p *Provider) Name() string {
 	return "aws"
 }
 
-// Deploy deploys an application to AWS Elastic Beanstalk.
+// Close closes the idle connections of the CloudFormation, CloudWatch Logs,
+// CodeBuild, and Elastic Load Balancing clients. The SDK clients' idle
+// connections time out on their own.
+func (p *Provider) Close() error {
+	for _, c := range []*awsapi.Client{p.cfnClient, p.logsClient, p.codebuildClient, p.elbClient} {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Deploy deploys an application to AWS Elastic Beanstalk. Sidecars are
+// deployed as services of a Docker Compose bundle next to the application's.
 func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	m = m.WithSidecars()
+
+	// Images built by CodeBuild are built for the instances' platform, and do not exist yet
+	remoteBuild := m.Deployment.Source.Build != nil && m.Deployment.Source.Build.RemoteBuild()
+	if !remoteBuild {
+		if err := validateImagePlatforms(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
 	if m.IsMultiContainer() {
 		logging.Info("Starting AWS Elastic Beanstalk multi-container deployment")
 		return p.deployMultiContainer(ctx, m)
@@ -119,37 +269,57 @@ func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.Dep
 		return nil, fmt.Errorf("failed to ensure application: %w", err)
 	}
 
-	// Step 2: Push image to ECR
-	logging.Info("Distributing image to ECR")
-	ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.Application.Name, "latest")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ECR registry: %w", err)
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, m.Application.Name)
+	if usesCloudFormation(m) {
+		if err := p.ensureAncillaryStack(ctx, m, bucketName); err != nil {
+			return nil, fmt.Errorf("failed to apply ancillary resources stack: %w", err)
+		}
 	}
 
-	// Use Distributor to push image to registry
-	distributor := registry.NewDistributor(m.Image)
-	distributor.AddRegistry(ecrRegistry)
+	// Step 2: Push image to ECR, or build it there with CodeBuild
+	var imageURI, digest string
+	if remoteBuild {
+		var err error
+		imageURI, digest, err = p.buildWithCodeBuild(ctx, m, bucketName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build image with CodeBuild: %w", err)
+		}
+	} else {
+		logging.Info("Distributing image to ECR")
+		ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.RepositoryName(), m.ImageTag("latest", ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ECR registry: %w", err)
+		}
+
+		// Use Distributor to push image to registry
+		distributor := registry.NewDistributor(m.Image, m.Platforms...)
+		dest := registry.Local(ecrRegistry, m.Provider.RegistryURL, m.RepositoryName(), m.ImageTag("latest", ""))
+		distributor.AddRegistry(dest)
 
-	imageURIs, err := distributor.Distribute(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to distribute image to ECR: %w", err)
-	}
+		imageURIs, err := distributor.Distribute(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to distribute image to ECR: %w", err)
+		}
 
-	imageURI := imageURIs[ecrRegistry.GetRegistryURL()]
-	logging.Info("Image pushed to ECR", "image_uri", imageURI)
+		imageURI = imageURIs[dest.GetRegistryURL()]
+		digest = distributor.Digest()
+		logging.Info("Image pushed to ECR", "image_uri", imageURI)
+	}
 
 	// Step 3: Create S3 bucket for application versions
-	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, m.Application.Name)
 	if err := p.ensureBucket(ctx, bucketName); err != nil {
 		return nil, fmt.Errorf("failed to ensure S3 bucket: %w", err)
 	}
 
 	// Step 4: Create and upload Dockerrun.aws.json
 	// Use a fixed version label so deployments replace the existing version instead of creating new ones
-	versionLabel := "latest"
+	versionLabel := m.VersionLabel("latest")
 	s3Key := fmt.Sprintf("%s/%s.zip", m.Application.Name, versionLabel)
 
-	if err := p.uploadDockerrun(ctx, m, imageURI, bucketName, s3Key); err != nil {
+	// Instances pull the pushed image by digest, so a tag moving later never
+	// changes the version's image
+	image := registry.PinDigest(imageURI, digest)
+	if err := p.uploadDockerrun(ctx, m, image, bucketName, s3Key); err != nil {
 		return nil, fmt.Errorf("failed to upload Dockerrun.aws.json: %w", err)
 	}
 
@@ -158,37 +328,19 @@ func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.Dep
 		return nil, fmt.Errorf("failed to create application version: %w", err)
 	}
 
-	// Step 5: Create or update environment
-	envExists, err := p.environmentExists(ctx, m.Application.Name, m.Environment.Name)
+	// Step 5: Create or update the environment, or deploy blue-green, and wait for it to be ready
+	envName, url, err := p.deployEnvironment(ctx, m, versionLabel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check environment: %w", err)
-	}
-
-	if envExists {
-		logging.Info("Updating existing environment", "environment", m.Environment.Name)
-		if err := p.updateEnvironment(ctx, m, versionLabel); err != nil {
-			return nil, fmt.Errorf("failed to update environment: %w", err)
-		}
-	} else {
-		logging.Info("Creating new environment", "environment", m.Environment.Name)
-		if err := p.createEnvironment(ctx, m, versionLabel); err != nil {
-			return nil, fmt.Errorf("failed to create environment: %w", err)
-		}
-	}
-
-	// Step 6: Wait for environment to be ready
-	logging.Info("Waiting for environment to be ready")
-	url, err := p.waitForEnvironment(ctx, m.Application.Name, m.Environment.Name)
-	if err != nil {
-		return nil, fmt.Errorf("environment deployment failed: %w", err)
+		return nil, err
 	}
 
 	return &types.DeploymentResult{
 		ApplicationName: m.Application.Name,
-		EnvironmentName: m.Environment.Name,
+		EnvironmentName: envName,
 		URL:             url,
 		Status:          "Ready",
 		Message:         "Deployment successful",
+		Images:          map[string]string{m.Application.Name: image},
 	}, nil
 }
 
@@ -204,42 +356,51 @@ func (p *Provider) deployMultiContainer(ctx context.Context, m *manifest.Manifes
 		return nil, fmt.Errorf("failed to ensure application: %w", err)
 	}
 
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, m.Application.Name)
+	if usesCloudFormation(m) {
+		if err := p.ensureAncillaryStack(ctx, m, bucketName); err != nil {
+			return nil, fmt.Errorf("failed to apply ancillary resources stack: %w", err)
+		}
+	}
+
 	// Step 2: Push ALL container images to ECR
 	logging.Infof("Distributing %d container images to ECR", len(m.Containers))
-	containerImageURIs := make(map[string]string) // container name -> ECR URI
+	pinnedImages := make(map[string]string) // container name -> URI pinned by digest
 
 	for _, container := range m.Containers {
 		logging.Info("Pushing container image", "container", container.Name, "image", container.Image)
 
-		ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.Application.Name, container.Name)
+		tag := m.ImageTag(container.Name, container.Name)
+		ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.RepositoryName(), tag)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create ECR registry for container %s: %w", container.Name, err)
 		}
 
-		distributor := registry.NewDistributor(container.Image)
-		distributor.AddRegistry(ecrRegistry)
+		distributor := registry.NewDistributor(container.Image, m.Platforms...)
+		dest := registry.Local(ecrRegistry, m.Provider.RegistryURL, m.RepositoryName(), tag)
+		distributor.AddRegistry(dest)
 
 		imageURIs, err := distributor.Distribute(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to distribute image for container %s: %w", container.Name, err)
 		}
 
-		imageURI := imageURIs[ecrRegistry.GetRegistryURL()]
-		containerImageURIs[container.Name] = imageURI
+		imageURI := imageURIs[dest.GetRegistryURL()]
+		pinnedImages[container.Name] = registry.PinDigest(imageURI, distributor.Digest())
 		logging.Info("Image pushed to ECR", "container", container.Name, "image_uri", imageURI)
 	}
 
 	// Step 3: Create S3 bucket for application versions
-	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, m.Application.Name)
 	if err := p.ensureBucket(ctx, bucketName); err != nil {
 		return nil, fmt.Errorf("failed to ensure S3 bucket: %w", err)
 	}
 
 	// Step 4: Create and upload docker-compose.yml
-	versionLabel := "latest"
+	versionLabel := m.VersionLabel("latest")
 	s3Key := fmt.Sprintf("%s/%s.zip", m.Application.Name, versionLabel)
 
-	if err := p.uploadDockerCompose(ctx, m, containerImageURIs, bucketName, s3Key); err != nil {
+	// Instances pull the pushed images by digest, like single-container versions
+	if err := p.uploadDockerCompose(ctx, m, pinnedImages, bucketName, s3Key); err != nil {
 		return nil, fmt.Errorf("failed to upload docker-compose.yml: %w", err)
 	}
 
@@ -248,57 +409,36 @@ func (p *Provider) deployMultiContainer(ctx context.Context, m *manifest.Manifes
 		return nil, fmt.Errorf("failed to create application version: %w", err)
 	}
 
-	// Step 6: Create or update environment
-	envExists, err := p.environmentExists(ctx, m.Application.Name, m.Environment.Name)
+	// Step 6: Create or update the environment, or deploy blue-green, and wait for it to be ready
+	envName, url, err := p.deployEnvironment(ctx, m, versionLabel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check environment: %w", err)
-	}
-
-	if envExists {
-		logging.Info("Updating existing environment", "environment", m.Environment.Name)
-		if err := p.updateEnvironment(ctx, m, versionLabel); err != nil {
-			return nil, fmt.Errorf("failed to update environment: %w", err)
-		}
-	} else {
-		logging.Info("Creating new environment", "environment", m.Environment.Name)
-		if err := p.createEnvironment(ctx, m, versionLabel); err != nil {
-			return nil, fmt.Errorf("failed to create environment: %w", err)
-		}
-	}
-
-	// Step 7: Wait for environment to be ready
-	logging.Info("Waiting for environment to be ready")
-	url, err := p.waitForEnvironment(ctx, m.Application.Name, m.Environment.Name)
-	if err != nil {
-		return nil, fmt.Errorf("environment deployment failed: %w", err)
+		return nil, err
 	}
 
 	return &types.DeploymentResult{
 		ApplicationName: m.Application.Name,
-		EnvironmentName: m.Environment.Name,
+		EnvironmentName: envName,
 		URL:             url,
 		Status:          "Ready",
 		Message:         fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers)),
+		Images:          pinnedImages,
 	}, nil
 }
 
 // Destroy terminates an AWS Elastic Beanstalk environment and optionally the application.
+// When ancillary resources are managed by CloudFormation, their stack is deleted as well.
 func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
-	logging.Info("Terminating environment", "environment", m.Environment.Name)
-
-	_, err := p.ebClient.TerminateEnvironment(ctx, &elasticbeanstalk.TerminateEnvironmentInput{
-		EnvironmentName: aws.String(m.Environment.Name),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to terminate environment: %w", err)
+	if err := p.terminateEnvironments(ctx, m); err != nil {
+		return err
 	}
 
-	logging.Info("Waiting for environment termination")
-	if err := p.waitForEnvironmentTermination(ctx, m.Application.Name, m.Environment.Name); err != nil {
-		return fmt.Errorf("failed to wait for termination: %w", err)
+	if usesCloudFormation(m) {
+		bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, m.Application.Name)
+		if err := p.destroyAncillaryStack(ctx, m, bucketName); err != nil {
+			return fmt.Errorf("failed to delete ancillary resources stack: %w", err)
+		}
 	}
 
-	logging.Info("Environment terminated successfully")
 	return nil
 }
 
@@ -309,26 +449,63 @@ func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
 	logging.Info("Stopping environment", "environment", m.Environment.Name)
 	logging.Info("This will terminate all resources but preserve the application for fast restart")
 
-	_, err := p.ebClient.TerminateEnvironment(ctx, &elasticbeanstalk.TerminateEnvironmentInput{
-		EnvironmentName: aws.String(m.Environment.Name),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to terminate environment: %w", err)
-	}
-
-	logging.Info("Waiting for environment termination")
-	if err := p.waitForEnvironmentTermination(ctx, m.Application.Name, m.Environment.Name); err != nil {
-		return fmt.Errorf("failed to wait for termination: %w", err)
+	if err := p.terminateEnvironments(ctx, m); err != nil {
+		return err
 	}
 
 	logging.Info("Environment stopped successfully")
 	logging.Info("Application and versions are preserved in S3", "application", m.Application.Name)
-	logging.Info("Run 'cloud-deploy -command deploy' to restart")
+	logging.Info("Run 'cloud-deploy deploy' to restart")
+	return nil
+}
+
+// terminateEnvironments terminates the manifest's environment, or both
+// environments of a blue-green deployment, and waits for the termination.
+func (p *Provider) terminateEnvironments(ctx context.Context, m *manifest.Manifest) error {
+	names := []string{m.Environment.Name}
+	if m.Deployment.IsBlueGreen() {
+		live, idle, err := p.blueGreenEnvironments(ctx, m)
+		if err != nil {
+			return err
+		}
+		names = nil
+		for _, env := range []*ebtypes.EnvironmentDescription{live, idle} {
+			if env != nil {
+				names = append(names, aws.ToString(env.EnvironmentName))
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("environment not found: %s", m.Environment.Name)
+		}
+	}
+
+	for _, name := range names {
+		logging.Info("Terminating environment", "environment", name)
+		_, err := p.ebClient.TerminateEnvironment(ctx, &elasticbeanstalk.TerminateEnvironmentInput{
+			EnvironmentName: aws.String(name),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to terminate environment: %w", err)
+		}
+	}
+
+	for _, name := range names {
+		logging.Info("Waiting for environment termination", "environment", name)
+		if err := p.waitForEnvironmentTermination(ctx, m.Application.Name, name); err != nil {
+			return fmt.Errorf("failed to wait for termination: %w", err)
+		}
+	}
+
+	logging.Info("Environment terminated successfully")
 	return nil
 }
 
 // Status retrieves the current status of an AWS Elastic Beanstalk deployment.
 func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	m, err := p.liveManifest(ctx, m)
+	if err != nil {
+		return nil, err
+	}
 	result, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
 		ApplicationName:  aws.String(m.Application.Name),
 		EnvironmentNames: []string{m.Environment.Name},
@@ -451,10 +628,8 @@ func (p *Provider) ensureBucket(ctx context.Context, bucketName string) error {
 	return err
 }
 
-// uploadDockerrun creates a Dockerrun.aws.json file for the ECR image and uploads it to S3.
-func (p *Provider) uploadDockerrun(ctx context.Context, m *manifest.Manifest, imageURI, bucketName, s3Key string) error {
-	logging.Info("Creating Dockerrun.aws.json")
-
+// buildDockerrun creates the Dockerrun.aws.json (v1) structure for a single-container deployment.
+func buildDockerrun(m *manifest.Manifest, imageURI string) map[string]interface{} {
 	// Build port mappings from manifest
 	var ports []map[string]interface{}
 	if len(m.Ports) > 0 {
@@ -480,9 +655,13 @@ func (p *Provider) uploadDockerrun(ctx context.Context, m *manifest.Manifest, im
 		logging.Debug("No ports specified in manifest, using default port 80")
 	}
 
-	// Build environment variables array for Docker
+	// Build environment variables array for Docker; Elastic Beanstalk passes
+	// secrets delivered natively to the container itself
 	var envVars []map[string]string
 	for key, value := range m.EnvironmentVariables {
+		if m.NativeSecrets() && m.IsSecret(key) {
+			continue
+		}
 		envVars = append(envVars, map[string]string{
 			"Name":  key,
 			"Value": value,
@@ -504,6 +683,27 @@ func (p *Provider) uploadDockerrun(ctx context.Context, m *manifest.Manifest, im
 		dockerrun["Environment"] = envVars
 	}
 
+	// Apply startup overrides (Dockerrun v1 takes them as strings)
+	c := m.GetPrimaryContainer()
+	if len(c.Command) > 0 {
+		dockerrun["Entrypoint"] = strings.Join(c.Command, " ")
+	}
+	if len(c.Args) > 0 {
+		dockerrun["Command"] = strings.Join(c.Args, " ")
+	}
+	if c.Workdir != "" {
+		logging.Warn("container.workdir is not supported by single-container Elastic Beanstalk and will be ignored")
+	}
+
+	return dockerrun
+}
+
+// uploadDockerrun creates a Dockerrun.aws.json file for the ECR image and uploads it to S3.
+func (p *Provider) uploadDockerrun(ctx context.Context, m *manifest.Manifest, imageURI, bucketName, s3Key string) error {
+	logging.Info("Creating Dockerrun.aws.json")
+
+	dockerrun := buildDockerrun(m, imageURI)
+
 	// Marshal to JSON
 	dockerrunJSON, err := json.MarshalIndent(dockerrun, "", "  ")
 	if err != nil {
@@ -553,8 +753,8 @@ func (p *Provider) uploadDockerrun(ctx context.Context, m *manifest.Manifest, im
 	return err
 }
 
-// uploadDockerCompose creates a docker-compose.yml file for multi-container deployment and uploads it to S3.
-func (p *Provider) uploadDockerCompose(ctx context.Context, m *manifest.Manifest, containerImageURIs map[string]string, bucketName, s3Key string) error {
+// buildComposeFile creates the docker-compose.yml structure for a multi-container deployment.
+func buildComposeFile(m *manifest.Manifest, containerImageURIs map[string]string) map[string]interface{} {
 	// Build docker-compose.yml structure
 	composeFile := map[string]interface{}{
 		"version":  "3.8",
@@ -585,18 +785,49 @@ func (p *Provider) uploadDockerCompose(ctx context.Context, m *manifest.Manifest
 			service["ports"] = ports
 		}
 
-		// Add environment variables if specified
+		// Add environment variables if specified. Secrets delivered natively
+		// are interpolated from the environment secrets Elastic Beanstalk
+		// writes to the bundle's .env file, so the compose file holds no values.
 		if len(container.Environment) > 0 {
 			envVars := make(map[string]string)
 			for key, value := range container.Environment {
+				if m.NativeSecrets() && m.IsSecret(key) {
+					value = "${" + key + "}"
+				}
 				envVars[key] = value
 			}
 			service["environment"] = envVars
 		}
 
-		// Add command if specified
+		// Add startup overrides if specified
 		if len(container.Command) > 0 {
-			service["command"] = container.Command
+			service["entrypoint"] = container.Command
+		}
+		if len(container.Args) > 0 {
+			service["command"] = container.Args
+		}
+		if container.Workdir != "" {
+			service["working_dir"] = container.Workdir
+		}
+
+		// Limit the container to its resources if specified
+		if limits := composeLimits(container.Resources); limits != nil {
+			service["deploy"] = map[string]interface{}{
+				"resources": map[string]interface{}{"limits": limits},
+			}
+		}
+
+		// Apply security hardening if specified
+		if sec := m.Security; sec != nil {
+			if sec.ReadOnlyRootFilesystem {
+				service["read_only"] = true
+			}
+			if sec.User != "" {
+				service["user"] = sec.User
+			}
+			if len(sec.DropCapabilities) > 0 {
+				service["cap_drop"] = sec.DropCapabilities
+			}
 		}
 
 		// Special handling for Datadog agent container
@@ -618,6 +849,29 @@ func (p *Provider) uploadDockerCompose(ctx context.Context, m *manifest.Manifest
 		services[container.Name] = service
 	}
 
+	return composeFile
+}
+
+// composeLimits returns the Docker Compose resource limits of r, or nil if r
+// sets none.
+func composeLimits(r *manifest.ContainerResources) map[string]interface{} {
+	if r == nil || (r.CPU == 0 && r.MemoryGB == 0) {
+		return nil
+	}
+	limits := make(map[string]interface{})
+	if r.CPU > 0 {
+		limits["cpus"] = strconv.FormatFloat(r.CPU, 'f', -1, 64)
+	}
+	if r.MemoryGB > 0 {
+		limits["memory"] = fmt.Sprintf("%dM", int(math.Round(r.MemoryGB*1024)))
+	}
+	return limits
+}
+
+// uploadDockerCompose creates a docker-compose.yml file for multi-container deployment and uploads it to S3.
+func (p *Provider) uploadDockerCompose(ctx context.Context, m *manifest.Manifest, containerImageURIs map[string]string, bucketName, s3Key string) error {
+	composeFile := buildComposeFile(m, containerImageURIs)
+
 	// Marshal to YAML
 	composeYAML, err := yaml.Marshal(composeFile)
 	if err != nil {
@@ -725,17 +979,39 @@ func (p *Provider) environmentExists(ctx context.Context, appName, envName strin
 }
 
 // createEnvironment creates a new Elastic Beanstalk environment.
+// A CNAME prefix that is taken is resolved according to environment.cname_conflict, including
+// when another account claims it between the availability check and the create call.
 func (p *Provider) createEnvironment(ctx context.Context, m *manifest.Manifest, versionLabel string) error {
 	optionSettings := p.buildOptionSettings(m)
+	secretSettings, err := p.secretSettings(ctx, m)
+	if err != nil {
+		return err
+	}
+	optionSettings = append(optionSettings, secretSettings...)
 
-	_, err := p.ebClient.CreateEnvironment(ctx, &elasticbeanstalk.CreateEnvironmentInput{
-		ApplicationName:   aws.String(m.Application.Name),
-		EnvironmentName:   aws.String(m.Environment.Name),
-		VersionLabel:      aws.String(versionLabel),
-		SolutionStackName: aws.String(m.Deployment.SolutionStack),
-		CNAMEPrefix:       aws.String(m.Environment.CName),
-		OptionSettings:    optionSettings,
-	})
+	create := func() error {
+		_, err := p.ebClient.CreateEnvironment(ctx, &elasticbeanstalk.CreateEnvironmentInput{
+			ApplicationName:   aws.String(m.Application.Name),
+			EnvironmentName:   aws.String(m.Environment.Name),
+			VersionLabel:      aws.String(versionLabel),
+			SolutionStackName: aws.String(m.Deployment.SolutionStack),
+			CNAMEPrefix:       aws.String(m.Environment.CName),
+			OptionSettings:    optionSettings,
+		})
+		return err
+	}
+
+	if err := p.ensureCNAMEAvailable(ctx, m); err != nil {
+		return err
+	}
+	err = create()
+	if m.Environment.CName != "" && isCNAMEConflict(err) {
+		logging.Warn("CNAME prefix was claimed during environment creation, retrying", "cname", m.Environment.CName)
+		if err := p.ensureCNAMEAvailable(ctx, m); err != nil {
+			return err
+		}
+		err = create()
+	}
 	return err
 }
 
@@ -743,11 +1019,17 @@ func (p *Provider) createEnvironment(ctx context.Context, m *manifest.Manifest,
 func (p *Provider) updateEnvironment(ctx context.Context, m *manifest.Manifest, versionLabel string) error {
 	// Build option settings from manifest to apply configuration changes
 	optionSettings := p.buildOptionSettings(m)
+	secretSettings, err := p.secretSettings(ctx, m)
+	if err != nil {
+		return err
+	}
+	optionSettings = append(optionSettings, secretSettings...)
 
-	_, err := p.ebClient.UpdateEnvironment(ctx, &elasticbeanstalk.UpdateEnvironmentInput{
+	_, err = p.ebClient.UpdateEnvironment(ctx, &elasticbeanstalk.UpdateEnvironmentInput{
 		EnvironmentName: aws.String(m.Environment.Name),
 		VersionLabel:    aws.String(versionLabel),
 		OptionSettings:  optionSettings,
+		OptionsToRemove: plainSecretOptions(m),
 	})
 	return err
 }
@@ -767,6 +1049,11 @@ func (p *Provider) buildOptionSettings(m *manifest.Manifest) []ebtypes.Configura
 		},
 	}
 
+	// Add instance counts of load-balanced environments
+	if m.Instance.EnvironmentType != "SingleInstance" {
+		settings = append(settings, scalingSettings(m)...)
+	}
+
 	// Add IAM instance profile if specified
 	if m.IAM.InstanceProfile != "" {
 		settings = append(settings, ebtypes.ConfigurationOptionSetting{
@@ -914,10 +1201,13 @@ func (p *Provider) buildOptionSettings(m *manifest.Manifest) []ebtypes.Configura
 		}
 	}
 
-	// Add environment variables
+	// Add environment variables; secrets delivered natively are environment secrets instead
 	for key, value := range m.EnvironmentVariables {
+		if m.NativeSecrets() && m.IsSecret(key) {
+			continue
+		}
 		settings = append(settings, ebtypes.ConfigurationOptionSetting{
-			Namespace:  aws.String("aws:elasticbeanstalk:application:environment"),
+			Namespace:  aws.String(environmentNamespace),
 			OptionName: aws.String(key),
 			Value:      aws.String(value),
 		})
@@ -1050,6 +1340,10 @@ func zipDirectory(sourceDir string, zipFile *os.File) error {
 // Rollback rolls back the AWS Elastic Beanstalk environment to the previous application version.
 func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
 	logging.Info("Starting AWS Elastic Beanstalk rollback")
+	m, err := p.liveManifest(ctx, m)
+	if err != nil {
+		return nil, err
+	}
 
 	// Step 1: Get current environment to find the deployed version
 	envResult, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
@@ -1147,7 +1441,7 @@ func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.D
 
 	// Step 5: Wait for environment to be ready
 	logging.Info("Waiting for rollback to complete")
-	url, err := p.waitForEnvironment(ctx, m.Application.Name, m.Environment.Name)
+	url, err := p.waitForDeployment(ctx, m.Application.Name, m.Environment.Name)
 	if err != nil {
 		return nil, fmt.Errorf("rollback failed: %w", err)
 	}
@@ -1160,3 +1454,67 @@ func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.D
 		Message:         fmt.Sprintf("Rolled back to version %s", *previousVersion),
 	}, nil
 }
+
+// validateImagePlatforms fails early when an image cannot run on the configured instance type.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	target := instancePlatform(m.Instance.Type)
+	hint := "or choose a Graviton instance type (e.g. t4g.small) for arm64 images"
+	if target.Architecture == "arm64" {
+		hint = "or choose an x86 instance type (e.g. t3.small) for amd64 images"
+	}
+
+	targetName := "Elastic Beanstalk"
+	if m.Instance.Type != "" {
+		targetName = fmt.Sprintf("instance type %s", m.Instance.Type)
+	}
+
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, target, targetName, hint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instancePlatform returns the container platform an EC2 instance type runs.
+// Graviton families (a1, and any family with a "g" after its generation number,
+// such as t4g, m6gd or c7gn) are arm64; everything else is amd64.
+func instancePlatform(instanceType string) registry.Platform {
+	family, _, _ := strings.Cut(instanceType, ".")
+	if family == "a1" {
+		return registry.Platform{OS: "linux", Architecture: "arm64"}
+	}
+
+	i := strings.IndexAny(family, "0123456789")
+	if i > 0 {
+		rest := strings.TrimLeft(family[i:], "0123456789")
+		if strings.HasPrefix(rest, "g") {
+			return registry.Platform{OS: "linux", Architecture: "arm64"}
+		}
+	}
+	return registry.LinuxAMD64
+}
+
+// validateSecurity warns about security options single-container Elastic Beanstalk cannot
+// enforce, and fails if an image runs as root when run_as_non_root is set.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	sec := m.Security
+	if sec == nil {
+		return nil
+	}
+
+	if !m.IsMultiContainer() && (sec.ReadOnlyRootFilesystem || sec.User != "" || len(sec.DropCapabilities) > 0) {
+		logging.Warn("security.read_only_root_filesystem, user and drop_capabilities are only enforced for multi-container (Docker Compose) deployments on Elastic Beanstalk")
+	}
+
+	// Docker Compose applies security.user to every container, overriding the image's user
+	if !sec.RunAsNonRoot || (m.IsMultiContainer() && sec.User != "") {
+		return nil
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidateNonRoot(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
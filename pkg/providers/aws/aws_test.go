@@ -2,6 +2,9 @@ package aws
 
 import (
 	"archive/zip"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +23,117 @@ func TestProviderName(t *testing.T) {
 	}
 }
 
+func TestLoadConfigEmulator(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "aws", Region: "us-east-1", EndpointURL: "http://localhost:4566"}}
+	cfg, err := LoadConfig(context.Background(), "us-east-1", nil, m)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.BaseEndpoint == nil || *cfg.BaseEndpoint != "http://localhost:4566" {
+		t.Errorf("BaseEndpoint = %v, want the emulator", cfg.BaseEndpoint)
+	}
+
+	m.Provider.EndpointURL = ""
+	if cfg, _ := LoadConfig(context.Background(), "us-east-1", nil, m); cfg.BaseEndpoint != nil {
+		t.Errorf("Expected no base endpoint without an emulator, got %s", *cfg.BaseEndpoint)
+	}
+}
+
+func TestLoadConfigAssumeRole(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDBASE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "base")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRole" || r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/deployer" ||
+			r.Form.Get("ExternalId") != "ext" || r.Form.Get("RoleSessionName") != "ci" || r.Form.Get("DurationSeconds") != "3600" {
+			t.Errorf("Unexpected STS request %v", r.Form)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKIDBASE/") {
+			t.Errorf("Expected the request to be signed with the base credentials, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials>` +
+			`<AccessKeyId>ASIAROLE</AccessKeyId><SecretAccessKey>role</SecretAccessKey><SessionToken>token</SessionToken>` +
+			`<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer server.Close()
+
+	creds := &manifest.CredentialsConfig{RoleARN: "arn:aws:iam::123456789012:role/deployer", ExternalID: "ext", RoleSessionName: "ci"}
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "aws", Region: "us-east-1", EndpointURL: server.URL, Credentials: creds}}
+	cfg, err := LoadConfig(context.Background(), "us-east-1", creds, m)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	got, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if got.AccessKeyID != "ASIAROLE" || got.SessionToken != "token" {
+		t.Errorf("Expected the role's credentials, got %+v", got)
+	}
+}
+
+func TestLoadConfigOIDC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "ci-jwt" || r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/ci" {
+			t.Errorf("Unexpected STS request %v", r.Form)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleWithWebIdentityResult><Credentials>` +
+			`<AccessKeyId>ASIACI</AccessKeyId><SecretAccessKey>ci</SecretAccessKey><SessionToken>token</SessionToken>` +
+			`<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+	}))
+	defer server.Close()
+	t.Setenv("CI_JOB_JWT", "ci-jwt")
+
+	creds := &manifest.CredentialsConfig{Source: "oidc", RoleARN: "arn:aws:iam::123456789012:role/ci", OIDCTokenEnv: "CI_JOB_JWT"}
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "aws", Region: "us-east-1", EndpointURL: server.URL, Credentials: creds}}
+	cfg, err := LoadConfig(context.Background(), "us-east-1", creds, m)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	got, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if got.AccessKeyID != "ASIACI" {
+		t.Errorf("Expected the role's credentials, got %+v", got)
+	}
+}
+
+func TestLoadConfigProfile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	os.WriteFile(filepath.Join(dir, "config"), []byte(`[profile sso]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 123456789012
+sso_role_name = Deployer
+`), 0600)
+	os.WriteFile(filepath.Join(dir, "credentials"), []byte("[deployer]\naws_access_key_id = AKIDPROFILE\naws_secret_access_key = secret\n"), 0600)
+
+	creds := &manifest.CredentialsConfig{Profile: "deployer"}
+	cfg, err := LoadConfig(context.Background(), "us-east-1", creds, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got, _ := cfg.Credentials.Retrieve(context.Background()); got.AccessKeyID != "AKIDPROFILE" {
+		t.Errorf("Expected the profile's credentials, got %+v", got)
+	}
+
+	creds.Profile = "sso"
+	if _, err := LoadConfig(context.Background(), "us-east-1", creds, nil); err == nil || !strings.Contains(err.Error(), "aws sso login --profile sso") {
+		t.Errorf("Expected an SSO sign-in error, got %v", err)
+	}
+}
+
 func TestZipDirectory(t *testing.T) {
 	// Create a temporary directory with test files
 	tmpDir := t.TempDir()
@@ -937,3 +1051,140 @@ func TestProviderRegion(t *testing.T) {
 		})
 	}
 }
+
+func TestInstancePlatform(t *testing.T) {
+	tests := map[string]string{
+		"":            "amd64",
+		"t3.micro":    "amd64",
+		"m5.large":    "amd64",
+		"g4dn.xlarge": "amd64",
+		"c5n.large":   "amd64",
+		"a1.medium":   "arm64",
+		"t4g.small":   "arm64",
+		"m6gd.large":  "arm64",
+		"c7gn.large":  "arm64",
+		"g5g.xlarge":  "arm64",
+	}
+	for instanceType, expected := range tests {
+		p := instancePlatform(instanceType)
+		if p.OS != "linux" || p.Architecture != expected {
+			t.Errorf("instancePlatform(%q) = %s, want linux/%s", instanceType, p, expected)
+		}
+	}
+}
+
+func TestBuildDockerrunStartupOverrides(t *testing.T) {
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Image:       "my-app:latest",
+		Container: &manifest.ContainerStartup{
+			Command: []string{"/bin/server"},
+			Args:    []string{"--port", "80"},
+		},
+	}
+
+	dockerrun := buildDockerrun(m, "123.dkr.ecr.us-east-1.amazonaws.com/my-app:latest")
+
+	if dockerrun["Entrypoint"] != "/bin/server" {
+		t.Errorf("Expected Entrypoint '/bin/server', got %v", dockerrun["Entrypoint"])
+	}
+	if dockerrun["Command"] != "--port 80" {
+		t.Errorf("Expected Command '--port 80', got %v", dockerrun["Command"])
+	}
+}
+
+func TestBuildDockerrunWithoutOverrides(t *testing.T) {
+	m := &manifest.Manifest{Image: "my-app:latest"}
+
+	dockerrun := buildDockerrun(m, "my-app:latest")
+
+	if _, ok := dockerrun["Entrypoint"]; ok {
+		t.Error("Expected no Entrypoint")
+	}
+	if _, ok := dockerrun["Command"]; ok {
+		t.Error("Expected no Command")
+	}
+	ports := dockerrun["Ports"].([]map[string]interface{})
+	if len(ports) != 1 || ports[0]["ContainerPort"] != 80 {
+		t.Errorf("Expected default port 80, got %v", ports)
+	}
+}
+
+func TestBuildComposeFileStartupOverrides(t *testing.T) {
+	m := &manifest.Manifest{
+		Containers: []manifest.Container{
+			{Name: "web", Image: "web:1", Command: []string{"/bin/web"}, Args: []string{"serve"}, Workdir: "/app"},
+			{Name: "worker", Image: "worker:1"},
+		},
+	}
+	uris := map[string]string{"web": "ecr/web:1", "worker": "ecr/worker:1"}
+
+	compose := buildComposeFile(m, uris)
+	services := compose["services"].(map[string]interface{})
+
+	web := services["web"].(map[string]interface{})
+	if web["image"] != "ecr/web:1" {
+		t.Errorf("Expected image 'ecr/web:1', got %v", web["image"])
+	}
+	if entrypoint := web["entrypoint"].([]string); len(entrypoint) != 1 || entrypoint[0] != "/bin/web" {
+		t.Errorf("Expected entrypoint [/bin/web], got %v", web["entrypoint"])
+	}
+	if command := web["command"].([]string); len(command) != 1 || command[0] != "serve" {
+		t.Errorf("Expected command [serve], got %v", web["command"])
+	}
+	if web["working_dir"] != "/app" {
+		t.Errorf("Expected working_dir '/app', got %v", web["working_dir"])
+	}
+
+	worker := services["worker"].(map[string]interface{})
+	for _, key := range []string{"entrypoint", "command", "working_dir"} {
+		if _, ok := worker[key]; ok {
+			t.Errorf("Expected no %s for worker", key)
+		}
+	}
+}
+
+func TestBuildComposeFileSecurity(t *testing.T) {
+	m := &manifest.Manifest{
+		Containers: []manifest.Container{{Name: "web", Image: "web:1"}},
+		Security: &manifest.SecurityConfig{
+			ReadOnlyRootFilesystem: true,
+			User:                   "1000:1000",
+			DropCapabilities:       []string{"ALL"},
+		},
+	}
+
+	compose := buildComposeFile(m, map[string]string{"web": "ecr/web:1"})
+	web := compose["services"].(map[string]interface{})["web"].(map[string]interface{})
+
+	if web["read_only"] != true {
+		t.Errorf("Expected read_only true, got %v", web["read_only"])
+	}
+	if web["user"] != "1000:1000" {
+		t.Errorf("Expected user '1000:1000', got %v", web["user"])
+	}
+	if capDrop := web["cap_drop"].([]string); len(capDrop) != 1 || capDrop[0] != "ALL" {
+		t.Errorf("Expected cap_drop [ALL], got %v", web["cap_drop"])
+	}
+}
+
+func TestBuildComposeFileResources(t *testing.T) {
+	m := &manifest.Manifest{
+		Containers: []manifest.Container{
+			{Name: "web", Image: "web:1"},
+			{Name: "proxy", Image: "envoy:1", Resources: &manifest.ContainerResources{CPU: 0.25, MemoryGB: 0.5}},
+		},
+	}
+
+	compose := buildComposeFile(m, map[string]string{"web": "ecr/web:1", "proxy": "envoy:1"})
+	services := compose["services"].(map[string]interface{})
+
+	if _, ok := services["web"].(map[string]interface{})["deploy"]; ok {
+		t.Error("Expected no resource limits for web")
+	}
+	deploy := services["proxy"].(map[string]interface{})["deploy"].(map[string]interface{})
+	limits := deploy["resources"].(map[string]interface{})["limits"].(map[string]interface{})
+	if limits["cpus"] != "0.25" || limits["memory"] != "512M" {
+		t.Errorf("Expected limits of 0.25 CPUs and 512M, got %v", limits)
+	}
+}
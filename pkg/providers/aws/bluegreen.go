@@ -0,0 +1,286 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// healthTimeout bounds how long a new blue-green environment may take to
+// report Green health once it is Ready.
+const healthTimeout = 10 * time.Minute
+
+// blueGreenNames returns the names of the two environments a blue-green
+// deployment alternates between.
+func blueGreenNames(m *manifest.Manifest) []string {
+	return []string{m.Environment.Name, m.Environment.Name + manifest.BlueGreenSuffix}
+}
+
+// blueGreenTarget returns the environment a blue-green deployment deploys to
+// while live serves traffic.
+func blueGreenTarget(m *manifest.Manifest, live string) string {
+	names := blueGreenNames(m)
+	if live == names[0] {
+		return names[1]
+	}
+	return names[0]
+}
+
+// splitBlueGreen returns the environment of envs serving the manifest's
+// CNAME prefix, and the other one, if any. Terminated environments are
+// ignored.
+func splitBlueGreen(envs []ebtypes.EnvironmentDescription, cname string) (live, idle *ebtypes.EnvironmentDescription) {
+	for i := range envs {
+		env := &envs[i]
+		if env.Status == ebtypes.EnvironmentStatusTerminated || env.Status == ebtypes.EnvironmentStatusTerminating {
+			continue
+		}
+		if live == nil && strings.HasPrefix(aws.ToString(env.CNAME), cname+".") {
+			live = env
+		} else {
+			idle = env
+		}
+	}
+	return live, idle
+}
+
+// blueGreenEnvironments returns the environment of a blue-green deployment
+// serving traffic and the other one, if any.
+func (p *Provider) blueGreenEnvironments(ctx context.Context, m *manifest.Manifest) (live, idle *ebtypes.EnvironmentDescription, err error) {
+	result, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
+		ApplicationName:  aws.String(m.Application.Name),
+		EnvironmentNames: blueGreenNames(m),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe environments: %w", err)
+	}
+	live, idle = splitBlueGreen(result.Environments, m.Environment.CName)
+	return live, idle, nil
+}
+
+// liveManifest returns m, or for blue-green deployments a copy of m naming
+// the environment that serves traffic, so commands act on that environment.
+func (p *Provider) liveManifest(ctx context.Context, m *manifest.Manifest) (*manifest.Manifest, error) {
+	if !m.Deployment.IsBlueGreen() {
+		return m, nil
+	}
+	live, _, err := p.blueGreenEnvironments(ctx, m)
+	if err != nil || live == nil {
+		return m, err
+	}
+	c := *m
+	c.Environment.Name = aws.ToString(live.EnvironmentName)
+	return &c, nil
+}
+
+// deployEnvironment deploys versionLabel to the manifest's environment,
+// updating it in place or, for blue-green deployments, swapping to a
+// parallel environment, and returns the environment's name and URL.
+func (p *Provider) deployEnvironment(ctx context.Context, m *manifest.Manifest, versionLabel string) (string, string, error) {
+	if m.Deployment.IsBlueGreen() {
+		live, idle, err := p.blueGreenEnvironments(ctx, m)
+		if err != nil {
+			return "", "", err
+		}
+		if live != nil {
+			return p.deployBlueGreen(ctx, m, versionLabel, live, idle)
+		}
+		logging.Info("No environment serves the CNAME yet, deploying in place", "cname", m.Environment.CName)
+		if idle != nil {
+			c := *m
+			c.Environment.Name = aws.ToString(idle.EnvironmentName)
+			m = &c
+		}
+	}
+
+	envExists, err := p.environmentExists(ctx, m.Application.Name, m.Environment.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to check environment: %w", err)
+	}
+
+	if envExists {
+		logging.Info("Updating existing environment", "environment", m.Environment.Name)
+		if err := p.updateEnvironment(ctx, m, versionLabel); err != nil {
+			return "", "", fmt.Errorf("failed to update environment: %w", err)
+		}
+	} else {
+		logging.Info("Creating new environment", "environment", m.Environment.Name)
+		if err := p.createEnvironment(ctx, m, versionLabel); err != nil {
+			return "", "", fmt.Errorf("failed to create environment: %w", err)
+		}
+	}
+
+	logging.Info("Waiting for environment to be ready")
+	url, err := p.waitForDeployment(ctx, m.Application.Name, m.Environment.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("environment deployment failed: %w", err)
+	}
+	return m.Environment.Name, url, nil
+}
+
+// deployBlueGreen deploys versionLabel to the environment beside live,
+// creating it or updating the one left by the previous deployment, and swaps
+// CNAMEs with live once the new environment's health is Green. A new
+// environment that fails is terminated, leaving live serving traffic; one
+// that turns unhealthy after the swap is swapped back. The old environment
+// is then terminated, unless deployment.blue_green.keep_old is set.
+func (p *Provider) deployBlueGreen(ctx context.Context, m *manifest.Manifest, versionLabel string, live, idle *ebtypes.EnvironmentDescription) (string, string, error) {
+	liveName := aws.ToString(live.EnvironmentName)
+	target := *m
+	target.Environment.Name = blueGreenTarget(m, liveName)
+	// The new environment gets a generated CNAME until the swap
+	target.Environment.CName = ""
+	name := target.Environment.Name
+
+	if idle != nil {
+		logging.Info("Updating idle environment", "environment", name, "live", liveName)
+		if err := p.updateEnvironment(ctx, &target, versionLabel); err != nil {
+			return "", "", fmt.Errorf("failed to update environment %s: %w", name, err)
+		}
+	} else {
+		logging.Info("Creating parallel environment", "environment", name, "live", liveName)
+		if err := p.createEnvironment(ctx, &target, versionLabel); err != nil {
+			return "", "", fmt.Errorf("failed to create environment %s: %w", name, err)
+		}
+	}
+
+	logging.Info("Waiting for the new environment to be healthy", "environment", name)
+	if _, err := p.waitForDeployment(ctx, m.Application.Name, name); err != nil {
+		return "", "", p.abandonEnvironment(ctx, name, liveName, err)
+	}
+	if err := p.waitForHealth(ctx, m.Application.Name, name); err != nil {
+		return "", "", p.abandonEnvironment(ctx, name, liveName, err)
+	}
+
+	logging.Info("Swapping environment CNAMEs", "from", liveName, "to", name)
+	if err := p.swapCNAMEs(ctx, m.Application.Name, liveName, name); err != nil {
+		return "", "", err
+	}
+	url, err := p.waitForEnvironment(ctx, m.Application.Name, name)
+	if err == nil {
+		err = p.waitForHealth(ctx, m.Application.Name, name)
+	}
+	if err != nil {
+		logging.Warn("New environment is unhealthy after the swap, swapping back", "environment", name, "error", err)
+		if swapErr := p.swapCNAMEs(ctx, m.Application.Name, name, liveName); swapErr != nil {
+			return "", "", fmt.Errorf("environment %s is unhealthy after the swap (%v), and swapping back to %s failed: %w", name, err, liveName, swapErr)
+		}
+		return "", "", fmt.Errorf("environment %s is unhealthy after the swap, swapped back to %s: %w", name, liveName, err)
+	}
+
+	if m.Deployment.KeepOld() {
+		logging.Info("Keeping old environment for a swap back", "environment", liveName)
+		return name, url, nil
+	}
+	logging.Info("Terminating old environment", "environment", liveName)
+	if _, err := p.ebClient.TerminateEnvironment(ctx, &elasticbeanstalk.TerminateEnvironmentInput{
+		EnvironmentName: aws.String(liveName),
+	}); err != nil {
+		logging.Warn("Failed to terminate old environment", "environment", liveName, "error", err)
+	}
+	return name, url, nil
+}
+
+// abandonEnvironment terminates the new environment of a blue-green
+// deployment that failed before the swap, and returns the failure.
+func (p *Provider) abandonEnvironment(ctx context.Context, name, liveName string, cause error) error {
+	logging.Warn("New environment failed, terminating it", "environment", name, "error", cause)
+	if _, err := p.ebClient.TerminateEnvironment(ctx, &elasticbeanstalk.TerminateEnvironmentInput{
+		EnvironmentName: aws.String(name),
+	}); err != nil {
+		logging.Warn("Failed to terminate new environment", "environment", name, "error", err)
+	}
+	return fmt.Errorf("environment %s failed, %s still serves traffic: %w", name, liveName, cause)
+}
+
+// swapCNAMEs swaps the CNAMEs of two environments and waits until both are
+// Ready again.
+func (p *Provider) swapCNAMEs(ctx context.Context, appName, source, destination string) error {
+	if _, err := p.ebClient.SwapEnvironmentCNAMEs(ctx, &elasticbeanstalk.SwapEnvironmentCNAMEsInput{
+		SourceEnvironmentName:      aws.String(source),
+		DestinationEnvironmentName: aws.String(destination),
+	}); err != nil {
+		return fmt.Errorf("failed to swap environment CNAMEs: %w", err)
+	}
+	if _, err := p.waitForEnvironment(ctx, appName, source); err != nil {
+		return fmt.Errorf("environment %s did not recover from the swap: %w", source, err)
+	}
+	return nil
+}
+
+// waitForHealth waits until the environment's health is Green, failing when
+// it turns Red or stays otherwise for healthTimeout.
+func (p *Provider) waitForHealth(ctx context.Context, appName, envName string) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	timeout := time.After(healthTimeout)
+
+	for {
+		err := p.checkHealth(ctx, appName, envName)
+		if err == nil {
+			return nil
+		}
+		if !isPendingHealth(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for environment health: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingHealthError reports health that may still turn Green.
+type pendingHealthError struct {
+	health ebtypes.EnvironmentHealth
+}
+
+func (e *pendingHealthError) Error() string {
+	return fmt.Sprintf("environment health is %s", e.health)
+}
+
+// isPendingHealth reports whether err is health that may still turn Green.
+func isPendingHealth(err error) bool {
+	_, ok := err.(*pendingHealthError)
+	return ok
+}
+
+// checkHealth returns nil when the environment's health is Green, a
+// pendingHealthError while it is Grey or Yellow, and an error when it is Red.
+func (p *Provider) checkHealth(ctx context.Context, appName, envName string) error {
+	result, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
+		ApplicationName:  aws.String(appName),
+		EnvironmentNames: []string{envName},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe environment: %w", err)
+	}
+	if len(result.Environments) == 0 {
+		return fmt.Errorf("environment disappeared")
+	}
+	return healthError(result.Environments[0].Health)
+}
+
+// healthError classifies an environment's health for checkHealth.
+func healthError(health ebtypes.EnvironmentHealth) error {
+	switch health {
+	case ebtypes.EnvironmentHealthGreen:
+		return nil
+	case ebtypes.EnvironmentHealthRed:
+		return fmt.Errorf("environment health checks failed: health is %s", health)
+	default:
+		return &pendingHealthError{health: health}
+	}
+}
@@ -0,0 +1,78 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func blueGreenManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod", CName: "my-app"},
+		Deployment:  manifest.DeploymentConfig{Strategy: manifest.StrategyBlueGreen},
+	}
+}
+
+func TestBlueGreenTarget(t *testing.T) {
+	m := blueGreenManifest()
+	if got := blueGreenTarget(m, "my-app-prod"); got != "my-app-prod-green" {
+		t.Errorf("blueGreenTarget(my-app-prod) = %q, want my-app-prod-green", got)
+	}
+	if got := blueGreenTarget(m, "my-app-prod-green"); got != "my-app-prod" {
+		t.Errorf("blueGreenTarget(my-app-prod-green) = %q, want my-app-prod", got)
+	}
+}
+
+func TestSplitBlueGreen(t *testing.T) {
+	envs := []ebtypes.EnvironmentDescription{
+		{EnvironmentName: aws.String("my-app-prod"), CNAME: aws.String("my-app-prod.a1b2c3.us-east-1.elasticbeanstalk.com"), Status: ebtypes.EnvironmentStatusReady},
+		{EnvironmentName: aws.String("my-app-prod-green"), CNAME: aws.String("my-app.us-east-1.elasticbeanstalk.com"), Status: ebtypes.EnvironmentStatusReady},
+	}
+	live, idle := splitBlueGreen(envs, "my-app")
+	if live == nil || aws.ToString(live.EnvironmentName) != "my-app-prod-green" {
+		t.Errorf("live = %+v, want my-app-prod-green", live)
+	}
+	if idle == nil || aws.ToString(idle.EnvironmentName) != "my-app-prod" {
+		t.Errorf("idle = %+v, want my-app-prod", idle)
+	}
+
+	// A CNAME prefix must match a whole label
+	envs[1].CNAME = aws.String("my-app-2.us-east-1.elasticbeanstalk.com")
+	if live, _ := splitBlueGreen(envs, "my-app"); live != nil {
+		t.Errorf("Expected no live environment, got %s", aws.ToString(live.EnvironmentName))
+	}
+
+	envs[0].Status = ebtypes.EnvironmentStatusTerminated
+	envs[1].CNAME = aws.String("my-app.us-east-1.elasticbeanstalk.com")
+	if live, idle := splitBlueGreen(envs, "my-app"); live == nil || idle != nil {
+		t.Errorf("Expected terminated environments to be ignored, got live %+v, idle %+v", live, idle)
+	}
+}
+
+func TestHealthError(t *testing.T) {
+	if err := healthError(ebtypes.EnvironmentHealthGreen); err != nil {
+		t.Errorf("Green: %v", err)
+	}
+	for _, health := range []ebtypes.EnvironmentHealth{ebtypes.EnvironmentHealthGrey, ebtypes.EnvironmentHealthYellow} {
+		if err := healthError(health); !isPendingHealth(err) {
+			t.Errorf("%s: expected pending health, got %v", health, err)
+		}
+	}
+	if err := healthError(ebtypes.EnvironmentHealthRed); err == nil || isPendingHealth(err) {
+		t.Errorf("Red: expected a failure, got %v", err)
+	}
+}
+
+func TestLiveManifestInPlace(t *testing.T) {
+	m := blueGreenManifest()
+	m.Deployment.Strategy = ""
+	got, err := (&Provider{}).liveManifest(context.Background(), m)
+	if err != nil || got != m {
+		t.Errorf("Expected in-place manifests to be returned as they are, got %+v (err %v)", got, err)
+	}
+}
@@ -0,0 +1,324 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// ECR deletes and gets at most 100 images, and S3 deletes 1000 objects, per
+// request.
+const (
+	ecrDeleteBatch = 100
+	ecrGetBatch    = 100
+	s3DeleteBatch  = 1000
+)
+
+// cleanupState records the application's versions, source bundles, and images.
+type cleanupState struct {
+	versions []ebtypes.ApplicationVersionDescription
+	deployed map[string]bool // labels of the versions environments run
+	bundles  []s3types.Object
+	images   []ecrtypes.ImageDetail
+	children map[string][]string // digests of the images of each multi-arch image index
+}
+
+// cleanupSelection is what a cleanup deletes.
+type cleanupSelection struct {
+	versions []string
+	bundles  []s3types.ObjectIdentifier
+	images   []ecrtypes.ImageIdentifier
+}
+
+// Prune deletes the application versions, with their source bundles, the
+// source bundles no version references, and the ECR images the policy
+// selects. The versions environments of the application run, and the images
+// they may pull, are kept.
+func (p *Provider) Prune(ctx context.Context, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool) (*types.Cleanup, error) {
+	state, err := p.readCleanup(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	cleanup := &types.Cleanup{Provider: "aws", DryRun: dryRun}
+	selection := selectCleanup(m, state, policy, time.Now(), cleanup)
+	if dryRun {
+		return cleanup, nil
+	}
+	return cleanup, p.deleteCleanup(ctx, m, selection)
+}
+
+// readCleanup reads the application's versions, the versions its environments
+// run, the source bundles in its bucket, and the images in its ECR repository.
+func (p *Provider) readCleanup(ctx context.Context, m *manifest.Manifest) (cleanupState, error) {
+	state := cleanupState{deployed: make(map[string]bool), children: make(map[string][]string)}
+	app := m.Application.Name
+
+	input := &elasticbeanstalk.DescribeApplicationVersionsInput{ApplicationName: aws.String(app)}
+	for {
+		out, err := p.ebClient.DescribeApplicationVersions(ctx, input)
+		if err != nil {
+			return state, fmt.Errorf("failed to describe application versions: %w", err)
+		}
+		state.versions = append(state.versions, out.ApplicationVersions...)
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	envs, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
+		ApplicationName: aws.String(app),
+		IncludeDeleted:  aws.Bool(false),
+	})
+	if err != nil {
+		return state, fmt.Errorf("failed to describe environments: %w", err)
+	}
+	for _, env := range envs.Environments {
+		if env.Status != ebtypes.EnvironmentStatusTerminated {
+			state.deployed[aws.ToString(env.VersionLabel)] = true
+		}
+	}
+
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, app)
+	if _, err := p.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)}); err == nil {
+		paginator := s3.NewListObjectsV2Paginator(p.s3Client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(bucketName),
+			Prefix: aws.String(app + "/"),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return state, fmt.Errorf("failed to list source bundles in %s: %w", bucketName, err)
+			}
+			state.bundles = append(state.bundles, page.Contents...)
+		}
+	}
+
+	ecrClient := ecr.NewFromConfig(p.config)
+	paginator := ecr.NewDescribeImagesPaginator(ecrClient, &ecr.DescribeImagesInput{
+		RepositoryName: aws.String(m.RepositoryName()),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		var notFound *ecrtypes.RepositoryNotFoundException
+		if errors.As(err, &notFound) {
+			break
+		}
+		if err != nil {
+			return state, fmt.Errorf("failed to describe images in ECR repository %s: %w", m.RepositoryName(), err)
+		}
+		state.images = append(state.images, page.ImageDetails...)
+	}
+
+	// The images of multi-arch image indexes are untagged, and kept with their index
+	var indexes []ecrtypes.ImageIdentifier
+	for _, image := range state.images {
+		if ggcrtypes.MediaType(aws.ToString(image.ImageManifestMediaType)).IsIndex() {
+			indexes = append(indexes, ecrtypes.ImageIdentifier{ImageDigest: image.ImageDigest})
+		}
+	}
+	for batch := range slices.Chunk(indexes, ecrGetBatch) {
+		out, err := ecrClient.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+			RepositoryName:     aws.String(m.RepositoryName()),
+			ImageIds:           batch,
+			AcceptedMediaTypes: []string{string(ggcrtypes.OCIImageIndex), string(ggcrtypes.DockerManifestList)},
+		})
+		if err != nil {
+			return state, fmt.Errorf("failed to get image indexes in ECR repository %s: %w", m.RepositoryName(), err)
+		}
+		for _, image := range out.Images {
+			index, err := v1.ParseIndexManifest(strings.NewReader(aws.ToString(image.ImageManifest)))
+			if err != nil {
+				return state, fmt.Errorf("failed to parse image index %s: %w", aws.ToString(image.ImageId.ImageDigest), err)
+			}
+			digest := aws.ToString(image.ImageId.ImageDigest)
+			for _, desc := range index.Manifests {
+				state.children[digest] = append(state.children[digest], desc.Digest.String())
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// selectCleanup selects what the policy deletes from state at now, and
+// records it in cleanup. Application versions and images are ranked among
+// their kind; source bundles that no version references among themselves.
+// The images of a multi-arch image index are kept while it is.
+func selectCleanup(m *manifest.Manifest, state cleanupState, policy types.RetentionPolicy, now time.Time, cleanup *types.Cleanup) cleanupSelection {
+	var selection cleanupSelection
+
+	// Source bundles of versions are deleted with them, or kept with them
+	referenced := make(map[string]bool)
+	created := make([]time.Time, len(state.versions))
+	for i, v := range state.versions {
+		created[i] = aws.ToTime(v.DateCreated)
+		if v.SourceBundle != nil {
+			referenced[aws.ToString(v.SourceBundle.S3Key)] = true
+		}
+	}
+	for i, expired := range policy.Expired(created, now) {
+		label := aws.ToString(state.versions[i].VersionLabel)
+		if !expired || state.deployed[label] {
+			cleanup.Kept++
+			continue
+		}
+		selection.versions = append(selection.versions, label)
+		cleanup.Prune("Elastic Beanstalk application version", label, created[i])
+	}
+
+	var bundles []s3types.Object
+	for _, obj := range state.bundles {
+		if !referenced[aws.ToString(obj.Key)] {
+			bundles = append(bundles, obj)
+		}
+	}
+	created = make([]time.Time, len(bundles))
+	for i, obj := range bundles {
+		created[i] = aws.ToTime(obj.LastModified)
+	}
+	for i, expired := range policy.Expired(created, now) {
+		if !expired {
+			cleanup.Kept++
+			continue
+		}
+		selection.bundles = append(selection.bundles, s3types.ObjectIdentifier{Key: bundles[i].Key})
+		cleanup.Prune("S3 source bundle", aws.ToString(bundles[i].Key), created[i])
+	}
+
+	// The images of multi-arch image indexes are not ranked: they are kept
+	// with any index referencing them that is kept, and otherwise deleted
+	// after their indexes, which ECR requires
+	protected := deployedTags(m, state.deployed)
+	indexed := make(map[string]bool)
+	for _, children := range state.children {
+		for _, child := range children {
+			indexed[child] = true
+		}
+	}
+	var ranked, unranked []ecrtypes.ImageDetail
+	for _, image := range state.images {
+		if indexed[aws.ToString(image.ImageDigest)] {
+			unranked = append(unranked, image)
+		} else {
+			ranked = append(ranked, image)
+		}
+	}
+	created = make([]time.Time, len(ranked))
+	for i, image := range ranked {
+		created[i] = aws.ToTime(image.ImagePushedAt)
+	}
+	kept := make(map[string]bool)
+	for i, expired := range policy.Expired(created, now) {
+		if !expired || containsAny(ranked[i].ImageTags, protected) {
+			digest := aws.ToString(ranked[i].ImageDigest)
+			kept[digest] = true
+			for _, child := range state.children[digest] {
+				kept[child] = true
+			}
+		}
+	}
+	for _, image := range append(ranked, unranked...) {
+		if kept[aws.ToString(image.ImageDigest)] {
+			cleanup.Kept++
+			continue
+		}
+		name := strings.Join(image.ImageTags, ",")
+		if name == "" {
+			name = aws.ToString(image.ImageDigest)
+		}
+		selection.images = append(selection.images, ecrtypes.ImageIdentifier{ImageDigest: image.ImageDigest})
+		cleanup.Prune("ECR image", name, aws.ToTime(image.ImagePushedAt))
+	}
+
+	return selection
+}
+
+// deployedTags returns the image tags environments may pull: the tags pushed
+// with every deploy, and the tags of the deployed versions' labels with
+// naming.image_tag_strategy.
+func deployedTags(m *manifest.Manifest, deployed map[string]bool) map[string]bool {
+	tags := map[string]bool{"latest": true, m.ImageTag("latest", ""): true}
+	for _, c := range m.Containers {
+		tags[c.Name] = true
+		tags[m.ImageTag(c.Name, c.Name)] = true
+	}
+	for label := range deployed {
+		tags[label] = true
+		for _, c := range m.Containers {
+			tags[c.Name+"-"+label] = true
+		}
+	}
+	return tags
+}
+
+// containsAny reports whether any of tags is in set.
+func containsAny(tags []string, set map[string]bool) bool {
+	for _, tag := range tags {
+		if set[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteCleanup deletes the selected application versions with their source
+// bundles, the unreferenced source bundles, and the images.
+func (p *Provider) deleteCleanup(ctx context.Context, m *manifest.Manifest, selection cleanupSelection) error {
+	app := m.Application.Name
+	for _, label := range selection.versions {
+		logging.Info("Deleting application version", "version", label)
+		if _, err := p.ebClient.DeleteApplicationVersion(ctx, &elasticbeanstalk.DeleteApplicationVersionInput{
+			ApplicationName:    aws.String(app),
+			VersionLabel:       aws.String(label),
+			DeleteSourceBundle: aws.Bool(true),
+		}); err != nil {
+			return fmt.Errorf("failed to delete application version %s: %w", label, err)
+		}
+	}
+
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, app)
+	for batch := range slices.Chunk(selection.bundles, s3DeleteBatch) {
+		logging.Info("Deleting source bundles", "bucket", bucketName, "count", len(batch))
+		if _, err := p.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &s3types.Delete{Objects: batch, Quiet: aws.Bool(true)},
+		}); err != nil {
+			return fmt.Errorf("failed to delete source bundles in %s: %w", bucketName, err)
+		}
+	}
+
+	ecrClient := ecr.NewFromConfig(p.config)
+	for batch := range slices.Chunk(selection.images, ecrDeleteBatch) {
+		logging.Info("Deleting ECR images", "repository", m.RepositoryName(), "count", len(batch))
+		out, err := ecrClient.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+			RepositoryName: aws.String(m.RepositoryName()),
+			ImageIds:       batch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete images in ECR repository %s: %w", m.RepositoryName(), err)
+		}
+		if len(out.Failures) > 0 {
+			f := out.Failures[0]
+			return fmt.Errorf("failed to delete image %s in ECR repository %s: %s", aws.ToString(f.ImageId.ImageDigest), m.RepositoryName(), aws.ToString(f.FailureReason))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestSelectCleanup(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+	version := func(label string, days int) ebtypes.ApplicationVersionDescription {
+		return ebtypes.ApplicationVersionDescription{
+			VersionLabel: aws.String(label),
+			DateCreated:  daysAgo(days),
+			SourceBundle: &ebtypes.S3Location{S3Bucket: aws.String("elasticbeanstalk-us-east-1-my-app"), S3Key: aws.String("my-app/" + label + ".zip")},
+		}
+	}
+	state := cleanupState{
+		versions: []ebtypes.ApplicationVersionDescription{
+			version("deploy-1", 30),
+			version("deploy-2", 20),
+			version("deploy-3", 10),
+			version("deploy-4", 1),
+		},
+		deployed: map[string]bool{"deploy-1": true},
+		bundles: []s3types.Object{
+			{Key: aws.String("my-app/deploy-2.zip"), LastModified: daysAgo(20)},
+			{Key: aws.String("my-app/orphan-1.zip"), LastModified: daysAgo(40)},
+			{Key: aws.String("my-app/orphan-2.zip"), LastModified: daysAgo(2)},
+		},
+		images: []ecrtypes.ImageDetail{
+			{ImageDigest: aws.String("sha256:a"), ImageTags: []string{"latest"}, ImagePushedAt: daysAgo(30)},
+			{ImageDigest: aws.String("sha256:b"), ImagePushedAt: daysAgo(20)},
+			{ImageDigest: aws.String("sha256:c"), ImageTags: []string{"v1", "v1.0"}, ImagePushedAt: daysAgo(10)},
+			{ImageDigest: aws.String("sha256:d"), ImageTags: []string{"v2"}, ImagePushedAt: daysAgo(1)},
+		},
+	}
+
+	cleanup := &types.Cleanup{Provider: "aws"}
+	selection := selectCleanup(planManifest(), state, types.RetentionPolicy{KeepLast: 1}, now, cleanup)
+
+	if got := selection.versions; len(got) != 2 || got[0] != "deploy-2" || got[1] != "deploy-3" {
+		t.Errorf("versions = %v, want the expired versions that are not deployed", got)
+	}
+	if got := selection.bundles; len(got) != 1 || aws.ToString(got[0].Key) != "my-app/orphan-1.zip" {
+		t.Errorf("bundles = %+v, want the old bundle no version references", got)
+	}
+	if got := selection.images; len(got) != 2 || aws.ToString(got[0].ImageDigest) != "sha256:b" || aws.ToString(got[1].ImageDigest) != "sha256:c" {
+		t.Errorf("images = %+v, want the expired images not tagged latest", got)
+	}
+
+	want := []types.PrunedResource{
+		{Type: "Elastic Beanstalk application version", Name: "deploy-2", Created: *daysAgo(20)},
+		{Type: "Elastic Beanstalk application version", Name: "deploy-3", Created: *daysAgo(10)},
+		{Type: "S3 source bundle", Name: "my-app/orphan-1.zip", Created: *daysAgo(40)},
+		{Type: "ECR image", Name: "sha256:b", Created: *daysAgo(20)},
+		{Type: "ECR image", Name: "v1,v1.0", Created: *daysAgo(10)},
+	}
+	if len(cleanup.Deleted) != len(want) {
+		t.Fatalf("got %d deleted, want %d: %+v", len(cleanup.Deleted), len(want), cleanup.Deleted)
+	}
+	for i, r := range cleanup.Deleted {
+		if r != want[i] {
+			t.Errorf("deleted %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+	if cleanup.Kept != 5 {
+		t.Errorf("Kept = %d, want 5", cleanup.Kept)
+	}
+}
+
+func TestSelectCleanupImageIndex(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+	image := func(digest string, days int, tags ...string) ecrtypes.ImageDetail {
+		return ecrtypes.ImageDetail{ImageDigest: aws.String(digest), ImageTags: tags, ImagePushedAt: daysAgo(days)}
+	}
+	// The indexes v1 and v2 were pushed with their untagged images
+	state := cleanupState{
+		images: []ecrtypes.ImageDetail{
+			image("sha256:v1-amd64", 20),
+			image("sha256:v1-arm64", 20),
+			image("sha256:v1", 20, "v1"),
+			image("sha256:v2-amd64", 10),
+			image("sha256:v2-arm64", 10),
+			image("sha256:v2", 10, "v2"),
+		},
+		children: map[string][]string{
+			"sha256:v1": {"sha256:v1-amd64", "sha256:v1-arm64"},
+			"sha256:v2": {"sha256:v2-amd64", "sha256:v2-arm64"},
+		},
+	}
+
+	cleanup := &types.Cleanup{Provider: "aws"}
+	selection := selectCleanup(planManifest(), state, types.RetentionPolicy{KeepLast: 1}, now, cleanup)
+
+	var got []string
+	for _, id := range selection.images {
+		got = append(got, aws.ToString(id.ImageDigest))
+	}
+	if strings.Join(got, " ") != "sha256:v1 sha256:v1-amd64 sha256:v1-arm64" {
+		t.Errorf("images = %v, want the expired index v1 with its images", got)
+	}
+	if cleanup.Kept != 3 {
+		t.Errorf("Kept = %d, want the index v2 with its images", cleanup.Kept)
+	}
+}
+
+func TestSelectCleanupOlderThan(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	old, recent := now.AddDate(0, 0, -30), now.AddDate(0, 0, -2)
+	state := cleanupState{
+		deployed: map[string]bool{},
+		images: []ecrtypes.ImageDetail{
+			{ImageDigest: aws.String("sha256:a"), ImagePushedAt: &old},
+			{ImageDigest: aws.String("sha256:b"), ImagePushedAt: &recent},
+			{ImageDigest: aws.String("sha256:c"), ImagePushedAt: &now},
+		},
+	}
+
+	cleanup := &types.Cleanup{Provider: "aws"}
+	selection := selectCleanup(planManifest(), state, types.RetentionPolicy{KeepLast: 1, OlderThan: 7 * 24 * time.Hour}, now, cleanup)
+	if len(selection.images) != 1 || aws.ToString(selection.images[0].ImageDigest) != "sha256:a" {
+		t.Errorf("images = %+v, want only the image older than 7 days", selection.images)
+	}
+}
+
+func TestDeployedTags(t *testing.T) {
+	m := planManifest()
+	tags := deployedTags(m, map[string]bool{"deploy-7": true})
+	for _, tag := range []string{"latest", "deploy-7"} {
+		if !tags[tag] {
+			t.Errorf("expected %s to be protected, got %v", tag, tags)
+		}
+	}
+}
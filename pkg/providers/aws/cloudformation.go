@@ -0,0 +1,569 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const cfnAPIVersion = "2010-05-15"
+
+// Logical IDs of the resources in the ancillary stack.
+const (
+	cfnBucketID          = "ApplicationVersionsBucket"
+	cfnRepositoryID      = "ImageRepository"
+	cfnInstanceRoleID    = "InstanceRole"
+	cfnInstanceProfileID = "InstanceProfile"
+)
+
+// cfnStack is the subset of a DescribeStacks result we use.
+type cfnStack struct {
+	StackId           string `xml:"StackId"`
+	StackName         string `xml:"StackName"`
+	StackStatus       string `xml:"StackStatus"`
+	StackStatusReason string `xml:"StackStatusReason"`
+	Outputs           []struct {
+		OutputKey   string `xml:"OutputKey"`
+		OutputValue string `xml:"OutputValue"`
+	} `xml:"Outputs>member"`
+}
+
+// output returns the value of the named stack output, or "" if absent.
+func (s *cfnStack) output(key string) string {
+	for _, o := range s.Outputs {
+		if o.OutputKey == key {
+			return o.OutputValue
+		}
+	}
+	return ""
+}
+
+// cfnImport identifies an existing resource to adopt into the stack.
+type cfnImport struct {
+	ResourceType      string
+	LogicalResourceID string
+	IdentifierKey     string
+	IdentifierValue   string
+}
+
+// usesCloudFormation reports whether ancillary resources are managed by a stack.
+func usesCloudFormation(m *manifest.Manifest) bool {
+	return m.AWS != nil && m.AWS.CloudFormation
+}
+
+// ancillaryStackName returns the CloudFormation stack name for an application.
+// Stack names may only contain alphanumerics and hyphens.
+func ancillaryStackName(appName string) string {
+	var b strings.Builder
+	b.WriteString("cloud-deploy-")
+	for _, r := range appName {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	name := b.String()
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
+
+// buildAncillaryTemplate generates the CloudFormation template for the resources
+// cloud-deploy creates alongside an Elastic Beanstalk application. When only is
+// non-empty, the template is restricted to those logical IDs (used for imports,
+// which may not create new resources in the same operation).
+func buildAncillaryTemplate(m *manifest.Manifest, bucketName string, only ...string) (string, error) {
+	resources := map[string]interface{}{
+		cfnBucketID: map[string]interface{}{
+			"Type":           "AWS::S3::Bucket",
+			"DeletionPolicy": "Delete",
+			"Properties": map[string]interface{}{
+				"BucketName": bucketName,
+			},
+		},
+		cfnRepositoryID: map[string]interface{}{
+			"Type":           "AWS::ECR::Repository",
+			"DeletionPolicy": "Delete",
+			"Properties": map[string]interface{}{
+				"RepositoryName": m.RepositoryName(),
+				"EmptyOnDelete":  true,
+			},
+		},
+	}
+	outputs := map[string]interface{}{
+		"BucketName": map[string]interface{}{
+			"Value": map[string]interface{}{"Ref": cfnBucketID},
+		},
+		"RepositoryUri": map[string]interface{}{
+			"Value": map[string]interface{}{"Fn::GetAtt": []string{cfnRepositoryID, "RepositoryUri"}},
+		},
+	}
+
+	// Only create an instance role when the manifest doesn't bring its own
+	if m.IAM.InstanceProfile == "" {
+		role := map[string]interface{}{
+			"AssumeRolePolicyDocument": map[string]interface{}{
+				"Version": "2012-10-17",
+				"Statement": []interface{}{
+					map[string]interface{}{
+						"Effect":    "Allow",
+						"Principal": map[string]interface{}{"Service": "ec2.amazonaws.com"},
+						"Action":    "sts:AssumeRole",
+					},
+				},
+			},
+			"ManagedPolicyArns": []string{
+				"arn:aws:iam::aws:policy/AWSElasticBeanstalkWebTier",
+				"arn:aws:iam::aws:policy/AWSElasticBeanstalkMulticontainerDocker",
+				"arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
+			},
+		}
+		// Instances read the environment secrets of secrets delivered natively
+		if m.NativeSecrets() {
+			role["Policies"] = []interface{}{
+				map[string]interface{}{
+					"PolicyName": "ReadSecrets",
+					"PolicyDocument": map[string]interface{}{
+						"Version": "2012-10-17",
+						"Statement": []interface{}{
+							map[string]interface{}{
+								"Effect":   "Allow",
+								"Action":   "secretsmanager:GetSecretValue",
+								"Resource": SecretResources(m, m.Provider.Region),
+							},
+						},
+					},
+				},
+			}
+		}
+		resources[cfnInstanceRoleID] = map[string]interface{}{
+			"Type":           "AWS::IAM::Role",
+			"DeletionPolicy": "Delete",
+			"Properties":     role,
+		}
+		resources[cfnInstanceProfileID] = map[string]interface{}{
+			"Type":           "AWS::IAM::InstanceProfile",
+			"DeletionPolicy": "Delete",
+			"Properties": map[string]interface{}{
+				"Roles": []interface{}{map[string]interface{}{"Ref": cfnInstanceRoleID}},
+			},
+		}
+		outputs["InstanceProfileName"] = map[string]interface{}{
+			"Value": map[string]interface{}{"Ref": cfnInstanceProfileID},
+		}
+	}
+
+	if len(only) > 0 {
+		keep := make(map[string]bool, len(only))
+		for _, id := range only {
+			keep[id] = true
+		}
+		for id := range resources {
+			if !keep[id] {
+				delete(resources, id)
+			}
+		}
+		// Outputs can't be changed during an import
+		outputs = nil
+	}
+
+	template := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              fmt.Sprintf("cloud-deploy ancillary resources for %s", m.Application.Name),
+		"Resources":                resources,
+	}
+	if len(outputs) > 0 {
+		template["Outputs"] = outputs
+	}
+
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal template: %w", err)
+	}
+	return string(data), nil
+}
+
+// changeSetParams builds the Query parameters for CreateChangeSet.
+func changeSetParams(stackName, changeSetName, changeSetType, template string, tags map[string]string, imports []cfnImport) url.Values {
+	params := url.Values{}
+	params.Set("StackName", stackName)
+	params.Set("ChangeSetName", changeSetName)
+	params.Set("ChangeSetType", changeSetType)
+	params.Set("TemplateBody", template)
+	params.Set("Capabilities.member.1", "CAPABILITY_IAM")
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		params.Set(fmt.Sprintf("Tags.member.%d.Key", i+1), k)
+		params.Set(fmt.Sprintf("Tags.member.%d.Value", i+1), tags[k])
+	}
+
+	for i, imp := range imports {
+		prefix := fmt.Sprintf("ResourcesToImport.member.%d.", i+1)
+		params.Set(prefix+"ResourceType", imp.ResourceType)
+		params.Set(prefix+"LogicalResourceId", imp.LogicalResourceID)
+		params.Set(prefix+"ResourceIdentifier.entry.1.key", imp.IdentifierKey)
+		params.Set(prefix+"ResourceIdentifier.entry.1.value", imp.IdentifierValue)
+	}
+
+	return params
+}
+
+// stackTags returns the tags applied to the ancillary stack (and propagated to its resources).
+func stackTags(m *manifest.Manifest) map[string]string {
+	tags := make(map[string]string, len(m.Tags)+1)
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+	tags["cloud-deploy:application"] = m.Application.Name
+	return tags
+}
+
+// ensureAncillaryStack creates or updates the per-application CloudFormation stack
+// that owns the S3 bucket, ECR repository and (optionally) instance profile.
+// Resources that already exist outside the stack are imported first.
+func (p *Provider) ensureAncillaryStack(ctx context.Context, m *manifest.Manifest, bucketName string) error {
+	stackName := ancillaryStackName(m.Application.Name)
+	logging.Info("Applying ancillary resources stack", "stack", stackName)
+
+	stack, err := p.describeStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+
+	// A stack that failed its first creation can only be deleted
+	if stack != nil && stack.StackStatus == "ROLLBACK_COMPLETE" {
+		logging.Info("Deleting stack left over from a failed creation", "stack", stackName)
+		if err := p.deleteStack(ctx, stackName); err != nil {
+			return err
+		}
+		stack = nil
+	}
+
+	// REVIEW_IN_PROGRESS stacks have never been executed and behave as new stacks
+	if stack == nil || stack.StackStatus == "REVIEW_IN_PROGRESS" {
+		imports, err := p.findExistingAncillaryResources(ctx, m, bucketName)
+		if err != nil {
+			return err
+		}
+
+		if len(imports) > 0 {
+			ids := make([]string, 0, len(imports))
+			for _, imp := range imports {
+				ids = append(ids, imp.LogicalResourceID)
+				logging.Info("Importing existing resource into stack", "resource", imp.IdentifierValue)
+			}
+			template, err := buildAncillaryTemplate(m, bucketName, ids...)
+			if err != nil {
+				return err
+			}
+			if _, err := p.applyChangeSet(ctx, stackName, "IMPORT", template, stackTags(m), imports); err != nil {
+				return fmt.Errorf("failed to import existing resources: %w", err)
+			}
+			stack = &cfnStack{StackName: stackName}
+		}
+	}
+
+	changeSetType := "UPDATE"
+	if stack == nil || stack.StackStatus == "REVIEW_IN_PROGRESS" {
+		changeSetType = "CREATE"
+	}
+
+	template, err := buildAncillaryTemplate(m, bucketName)
+	if err != nil {
+		return err
+	}
+
+	applied, err := p.applyChangeSet(ctx, stackName, changeSetType, template, stackTags(m), nil)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		logging.Info("Ancillary resources stack is up to date", "stack", stackName)
+	}
+
+	// Use the stack-managed instance profile unless the manifest names one
+	if m.IAM.InstanceProfile == "" {
+		stack, err = p.describeStack(ctx, stackName)
+		if err != nil {
+			return err
+		}
+		if stack != nil {
+			if profile := stack.output("InstanceProfileName"); profile != "" {
+				m.IAM.InstanceProfile = profile
+			}
+		}
+	}
+
+	return nil
+}
+
+// findExistingAncillaryResources returns import descriptors for ancillary resources
+// that already exist (for example from deployments made before the stack was enabled).
+func (p *Provider) findExistingAncillaryResources(ctx context.Context, m *manifest.Manifest, bucketName string) ([]cfnImport, error) {
+	var imports []cfnImport
+
+	if _, err := p.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)}); err == nil {
+		imports = append(imports, cfnImport{
+			ResourceType:      "AWS::S3::Bucket",
+			LogicalResourceID: cfnBucketID,
+			IdentifierKey:     "BucketName",
+			IdentifierValue:   bucketName,
+		})
+	}
+
+	ecrClient := ecr.NewFromConfig(p.config)
+	if _, err := ecrClient.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{m.RepositoryName()},
+	}); err == nil {
+		imports = append(imports, cfnImport{
+			ResourceType:      "AWS::ECR::Repository",
+			LogicalResourceID: cfnRepositoryID,
+			IdentifierKey:     "RepositoryName",
+			IdentifierValue:   m.RepositoryName(),
+		})
+	}
+
+	return imports, nil
+}
+
+// applyChangeSet creates a change set, waits for it to be computed and executes it.
+// Returns false without error when the change set contains no changes.
+func (p *Provider) applyChangeSet(ctx context.Context, stackName, changeSetType, template string, tags map[string]string, imports []cfnImport) (bool, error) {
+	changeSetName := fmt.Sprintf("cloud-deploy-%s", time.Now().UTC().Format("20060102T150405"))
+	params := changeSetParams(stackName, changeSetName, changeSetType, template, tags, imports)
+
+	if err := p.cfnClient.Query(ctx, "CreateChangeSet", cfnAPIVersion, params, nil); err != nil {
+		return false, fmt.Errorf("failed to create change set: %w", err)
+	}
+
+	logging.Info("Waiting for change set", "change_set", changeSetName, "type", changeSetType)
+	ready, err := p.waitForChangeSet(ctx, stackName, changeSetName)
+	if err != nil {
+		return false, err
+	}
+	if !ready {
+		p.cfnClient.Query(ctx, "DeleteChangeSet", cfnAPIVersion, url.Values{
+			"StackName":     {stackName},
+			"ChangeSetName": {changeSetName},
+		}, nil)
+		return false, nil
+	}
+
+	if err := p.cfnClient.Query(ctx, "ExecuteChangeSet", cfnAPIVersion, url.Values{
+		"StackName":     {stackName},
+		"ChangeSetName": {changeSetName},
+	}, nil); err != nil {
+		return false, fmt.Errorf("failed to execute change set: %w", err)
+	}
+
+	logging.Info("Waiting for stack operation to complete", "stack", stackName)
+	if err := p.waitForStack(ctx, stackName); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// waitForChangeSet waits for a change set to finish computing. It returns false
+// if the change set failed only because it contained no changes.
+func (p *Provider) waitForChangeSet(ctx context.Context, stackName, changeSetName string) (bool, error) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	timeout := time.After(5 * time.Minute)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timeout:
+			return false, fmt.Errorf("timeout waiting for change set %s", changeSetName)
+		case <-ticker.C:
+			var result struct {
+				Status       string `xml:"Status"`
+				StatusReason string `xml:"StatusReason"`
+			}
+			if err := p.cfnClient.Query(ctx, "DescribeChangeSet", cfnAPIVersion, url.Values{
+				"StackName":     {stackName},
+				"ChangeSetName": {changeSetName},
+			}, &result); err != nil {
+				return false, fmt.Errorf("failed to describe change set: %w", err)
+			}
+
+			switch result.Status {
+			case "CREATE_COMPLETE":
+				return true, nil
+			case "FAILED":
+				if strings.Contains(result.StatusReason, "didn't contain changes") ||
+					strings.Contains(result.StatusReason, "No updates are to be performed") {
+					return false, nil
+				}
+				return false, fmt.Errorf("change set failed: %s", result.StatusReason)
+			}
+		}
+	}
+}
+
+// waitForStack waits for an in-progress stack operation to reach a terminal state.
+func (p *Provider) waitForStack(ctx context.Context, stackName string) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	timeout := time.After(15 * time.Minute)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for stack %s", stackName)
+		case <-ticker.C:
+			stack, err := p.describeStack(ctx, stackName)
+			if err != nil {
+				return err
+			}
+			if stack == nil {
+				return fmt.Errorf("stack %s disappeared", stackName)
+			}
+
+			status := stack.StackStatus
+			logging.Info("Stack status update", "stack", stackName, "status", status)
+
+			switch {
+			case strings.HasSuffix(status, "ROLLBACK_COMPLETE"), strings.HasSuffix(status, "_FAILED"):
+				return fmt.Errorf("stack operation failed: status=%s reason=%s", status, stack.StackStatusReason)
+			case strings.HasSuffix(status, "_COMPLETE"):
+				return nil
+			}
+		}
+	}
+}
+
+// describeStack returns the named stack, or nil if it does not exist.
+func (p *Provider) describeStack(ctx context.Context, stackName string) (*cfnStack, error) {
+	var result struct {
+		Stacks []cfnStack `xml:"Stacks>member"`
+	}
+	err := p.cfnClient.Query(ctx, "DescribeStacks", cfnAPIVersion, url.Values{"StackName": {stackName}}, &result)
+	if err != nil {
+		if awsapi.IsErrorCode(err, "ValidationError") && strings.Contains(err.Error(), "does not exist") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to describe stack: %w", err)
+	}
+	if len(result.Stacks) == 0 {
+		return nil, nil
+	}
+	return &result.Stacks[0], nil
+}
+
+// deleteStack deletes a stack and waits until it is gone.
+func (p *Provider) deleteStack(ctx context.Context, stackName string) error {
+	if err := p.cfnClient.Query(ctx, "DeleteStack", cfnAPIVersion, url.Values{"StackName": {stackName}}, nil); err != nil {
+		return fmt.Errorf("failed to delete stack: %w", err)
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	timeout := time.After(15 * time.Minute)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for stack deletion")
+		case <-ticker.C:
+			stack, err := p.describeStack(ctx, stackName)
+			if err != nil {
+				return err
+			}
+			if stack == nil || stack.StackStatus == "DELETE_COMPLETE" {
+				return nil
+			}
+			if stack.StackStatus == "DELETE_FAILED" {
+				return fmt.Errorf("stack deletion failed: %s", stack.StackStatusReason)
+			}
+			logging.Info("Stack deletion status", "status", stack.StackStatus)
+		}
+	}
+}
+
+// destroyAncillaryStack empties the application versions bucket and deletes the stack.
+func (p *Provider) destroyAncillaryStack(ctx context.Context, m *manifest.Manifest, bucketName string) error {
+	stackName := ancillaryStackName(m.Application.Name)
+
+	stack, err := p.describeStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+	if stack == nil {
+		logging.Info("Ancillary resources stack not found", "stack", stackName)
+		return nil
+	}
+
+	// CloudFormation can't delete a non-empty bucket
+	if err := p.emptyBucket(ctx, bucketName); err != nil {
+		return fmt.Errorf("failed to empty S3 bucket: %w", err)
+	}
+
+	logging.Info("Deleting ancillary resources stack", "stack", stackName)
+	if err := p.deleteStack(ctx, stackName); err != nil {
+		return err
+	}
+
+	logging.Info("Ancillary resources deleted", "stack", stackName)
+	return nil
+}
+
+// emptyBucket deletes every object in a bucket. A missing bucket is not an error.
+func (p *Provider) emptyBucket(ctx context.Context, bucketName string) error {
+	if _, err := p.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)}); err != nil {
+		return nil
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(p.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		objects := make([]s3types.ObjectIdentifier, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			objects = append(objects, s3types.ObjectIdentifier{Key: obj.Key})
+		}
+		if _, err := p.s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
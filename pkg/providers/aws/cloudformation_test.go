@@ -0,0 +1,210 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestAncillaryStackName(t *testing.T) {
+	tests := []struct {
+		appName  string
+		expected string
+	}{
+		{"my-app", "cloud-deploy-my-app"},
+		{"my_app", "cloud-deploy-my-app"},
+		{"app.v2", "cloud-deploy-app-v2"},
+		{strings.Repeat("a", 200), "cloud-deploy-" + strings.Repeat("a", 115)},
+	}
+
+	for _, tt := range tests {
+		if got := ancillaryStackName(tt.appName); got != tt.expected {
+			t.Errorf("ancillaryStackName(%q) = %q, want %q", tt.appName, got, tt.expected)
+		}
+	}
+}
+
+func TestUsesCloudFormation(t *testing.T) {
+	if usesCloudFormation(&manifest.Manifest{}) {
+		t.Error("Expected CloudFormation to be disabled without aws config")
+	}
+	if !usesCloudFormation(&manifest.Manifest{AWS: &manifest.AWSConfig{CloudFormation: true}}) {
+		t.Error("Expected CloudFormation to be enabled")
+	}
+}
+
+func TestBuildAncillaryTemplate(t *testing.T) {
+	m := &manifest.Manifest{Application: manifest.ApplicationConfig{Name: "my-app"}}
+
+	body, err := buildAncillaryTemplate(m, "elasticbeanstalk-us-east-1-my-app")
+	if err != nil {
+		t.Fatalf("buildAncillaryTemplate failed: %v", err)
+	}
+
+	var template struct {
+		Resources map[string]struct {
+			Type           string                 `json:"Type"`
+			DeletionPolicy string                 `json:"DeletionPolicy"`
+			Properties     map[string]interface{} `json:"Properties"`
+		} `json:"Resources"`
+		Outputs map[string]interface{} `json:"Outputs"`
+	}
+	if err := json.Unmarshal([]byte(body), &template); err != nil {
+		t.Fatalf("Template is not valid JSON: %v", err)
+	}
+
+	expectedTypes := map[string]string{
+		cfnBucketID:          "AWS::S3::Bucket",
+		cfnRepositoryID:      "AWS::ECR::Repository",
+		cfnInstanceRoleID:    "AWS::IAM::Role",
+		cfnInstanceProfileID: "AWS::IAM::InstanceProfile",
+	}
+	for id, typ := range expectedTypes {
+		res, ok := template.Resources[id]
+		if !ok {
+			t.Errorf("Expected resource %s", id)
+			continue
+		}
+		if res.Type != typ {
+			t.Errorf("Resource %s: expected type %s, got %s", id, typ, res.Type)
+		}
+		if res.DeletionPolicy != "Delete" {
+			t.Errorf("Resource %s: expected DeletionPolicy Delete, got %q", id, res.DeletionPolicy)
+		}
+	}
+
+	if got := template.Resources[cfnBucketID].Properties["BucketName"]; got != "elasticbeanstalk-us-east-1-my-app" {
+		t.Errorf("Unexpected bucket name: %v", got)
+	}
+	if got := template.Resources[cfnRepositoryID].Properties["RepositoryName"]; got != "my-app" {
+		t.Errorf("Unexpected repository name: %v", got)
+	}
+	if _, ok := template.Outputs["InstanceProfileName"]; !ok {
+		t.Error("Expected InstanceProfileName output")
+	}
+}
+
+func TestBuildAncillaryTemplate_ExistingInstanceProfile(t *testing.T) {
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		IAM:         manifest.IAMConfig{InstanceProfile: "custom-profile"},
+	}
+
+	body, err := buildAncillaryTemplate(m, "bucket")
+	if err != nil {
+		t.Fatalf("buildAncillaryTemplate failed: %v", err)
+	}
+
+	if strings.Contains(body, "AWS::IAM::Role") || strings.Contains(body, "InstanceProfileName") {
+		t.Error("Expected no instance role when manifest specifies an instance profile")
+	}
+}
+
+func TestBuildAncillaryTemplate_ImportSubset(t *testing.T) {
+	m := &manifest.Manifest{Application: manifest.ApplicationConfig{Name: "my-app"}}
+
+	body, err := buildAncillaryTemplate(m, "bucket", cfnBucketID)
+	if err != nil {
+		t.Fatalf("buildAncillaryTemplate failed: %v", err)
+	}
+
+	var template struct {
+		Resources map[string]interface{} `json:"Resources"`
+		Outputs   map[string]interface{} `json:"Outputs"`
+	}
+	if err := json.Unmarshal([]byte(body), &template); err != nil {
+		t.Fatalf("Template is not valid JSON: %v", err)
+	}
+
+	if len(template.Resources) != 1 {
+		t.Errorf("Expected only the imported resource, got %d resources", len(template.Resources))
+	}
+	if _, ok := template.Resources[cfnBucketID]; !ok {
+		t.Error("Expected bucket resource in import template")
+	}
+	if template.Outputs != nil {
+		t.Error("Expected no outputs in import template")
+	}
+}
+
+func TestChangeSetParams(t *testing.T) {
+	params := changeSetParams("stack", "cs", "IMPORT", "{}",
+		map[string]string{"team": "web", "env": "prod"},
+		[]cfnImport{{
+			ResourceType:      "AWS::S3::Bucket",
+			LogicalResourceID: cfnBucketID,
+			IdentifierKey:     "BucketName",
+			IdentifierValue:   "bucket",
+		}})
+
+	expected := map[string]string{
+		"StackName":                                    "stack",
+		"ChangeSetName":                                "cs",
+		"ChangeSetType":                                "IMPORT",
+		"Capabilities.member.1":                        "CAPABILITY_IAM",
+		"Tags.member.1.Key":                            "env",
+		"Tags.member.1.Value":                          "prod",
+		"Tags.member.2.Key":                            "team",
+		"ResourcesToImport.member.1.ResourceType":      "AWS::S3::Bucket",
+		"ResourcesToImport.member.1.LogicalResourceId": cfnBucketID,
+		"ResourcesToImport.member.1.ResourceIdentifier.entry.1.key":   "BucketName",
+		"ResourcesToImport.member.1.ResourceIdentifier.entry.1.value": "bucket",
+	}
+	for k, v := range expected {
+		if got := params.Get(k); got != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got)
+		}
+	}
+}
+
+func TestDescribeStack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("StackName") == "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>ValidationError</Code><Message>Stack with id missing does not exist</Message></Error></ErrorResponse>`))
+			return
+		}
+		w.Write([]byte(`<DescribeStacksResponse><DescribeStacksResult><Stacks><member>
+<StackName>present</StackName><StackStatus>UPDATE_COMPLETE</StackStatus>
+<Outputs><member><OutputKey>InstanceProfileName</OutputKey><OutputValue>profile-123</OutputValue></member></Outputs>
+</member></Stacks></DescribeStacksResult></DescribeStacksResponse>`))
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+	}
+	client := awsapi.New(cfg, "cloudformation")
+	client.SetEndpoint(server.URL)
+	provider := &Provider{cfnClient: client, region: "us-east-1", config: cfg}
+
+	stack, err := provider.describeStack(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("Expected no error for missing stack, got %v", err)
+	}
+	if stack != nil {
+		t.Errorf("Expected nil stack, got %+v", stack)
+	}
+
+	stack, err = provider.describeStack(context.Background(), "present")
+	if err != nil {
+		t.Fatalf("describeStack failed: %v", err)
+	}
+	if stack == nil || stack.StackStatus != "UPDATE_COMPLETE" {
+		t.Fatalf("Unexpected stack: %+v", stack)
+	}
+	if got := stack.output("InstanceProfileName"); got != "profile-123" {
+		t.Errorf("Expected output profile-123, got %q", got)
+	}
+}
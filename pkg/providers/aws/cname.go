@@ -0,0 +1,162 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// CNAME conflict strategies (environment.cname_conflict)
+const (
+	cnameConflictFail   = "fail"
+	cnameConflictSuffix = "suffix"
+	cnameConflictPrompt = "prompt"
+)
+
+// maxCNAMESuffix bounds how many suffixed prefixes are tried before giving up.
+const maxCNAMESuffix = 20
+
+// CheckCNAME reports whether the manifest's CNAME prefix is available and the
+// fully qualified domain name it resolves to. A prefix already held by the
+// manifest's own environment counts as available.
+func (p *Provider) CheckCNAME(ctx context.Context, m *manifest.Manifest) (bool, string, error) {
+	if m.Environment.CName == "" {
+		return false, "", fmt.Errorf("environment.cname is not set")
+	}
+
+	available, fqdn, err := p.cnameAvailable(ctx, m.Environment.CName)
+	if err != nil || available {
+		return available, fqdn, err
+	}
+
+	result, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
+		ApplicationName:  aws.String(m.Application.Name),
+		EnvironmentNames: []string{m.Environment.Name},
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to describe environment: %w", err)
+	}
+	for _, env := range result.Environments {
+		cname := aws.ToString(env.CNAME)
+		if env.Status != ebtypes.EnvironmentStatusTerminated && strings.HasPrefix(cname, m.Environment.CName+".") {
+			return true, cname, nil
+		}
+	}
+	return false, fqdn, nil
+}
+
+// cnameAvailable checks a CNAME prefix with Elastic Beanstalk.
+func (p *Provider) cnameAvailable(ctx context.Context, prefix string) (bool, string, error) {
+	result, err := p.ebClient.CheckDNSAvailability(ctx, &elasticbeanstalk.CheckDNSAvailabilityInput{
+		CNAMEPrefix: aws.String(prefix),
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check CNAME availability: %w", err)
+	}
+	return aws.ToBool(result.Available), aws.ToString(result.FullyQualifiedCNAME), nil
+}
+
+// ensureCNAMEAvailable resolves a taken CNAME prefix before an environment is created,
+// according to environment.cname_conflict. The chosen prefix is written back to the manifest.
+func (p *Provider) ensureCNAMEAvailable(ctx context.Context, m *manifest.Manifest) error {
+	if m.Environment.CName == "" {
+		return nil
+	}
+
+	available := func(prefix string) (bool, error) {
+		ok, _, err := p.cnameAvailable(ctx, prefix)
+		return ok, err
+	}
+	prefix, err := resolveCNAME(m.Environment.CName, m.Environment.CNameConflict, available, os.Stdin)
+	if err != nil {
+		return err
+	}
+	if prefix != m.Environment.CName {
+		logging.Warn("CNAME prefix is taken, using alternative", "requested", m.Environment.CName, "cname", prefix)
+		m.Environment.CName = prefix
+	}
+	return nil
+}
+
+// resolveCNAME returns prefix if it is available, otherwise an alternative chosen by strategy:
+// "fail" (default) returns an error, "suffix" appends the first free numeric suffix, and
+// "prompt" asks on in (which must be a terminal when it is os.Stdin).
+func resolveCNAME(prefix, strategy string, available func(string) (bool, error), in io.Reader) (string, error) {
+	ok, err := available(prefix)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return prefix, nil
+	}
+
+	switch strategy {
+	case "", cnameConflictFail:
+		return "", fmt.Errorf("CNAME prefix %q is already taken; choose another environment.cname or set environment.cname_conflict to %q", prefix, cnameConflictSuffix)
+
+	case cnameConflictSuffix:
+		for i := 2; i <= maxCNAMESuffix; i++ {
+			candidate := cnameWithSuffix(prefix, i)
+			ok, err := available(candidate)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("CNAME prefix %q and its suffixed alternatives are all taken", prefix)
+
+	case cnameConflictPrompt:
+		if f, isFile := in.(*os.File); isFile {
+			if info, err := f.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+				return "", fmt.Errorf("CNAME prefix %q is already taken and no terminal is available to prompt for another", prefix)
+			}
+		}
+		reader := bufio.NewReader(in)
+		for {
+			fmt.Fprintf(os.Stderr, "CNAME prefix %q is taken. Enter another prefix: ", prefix)
+			line, readErr := reader.ReadString('\n')
+			if candidate := strings.TrimSpace(line); candidate != "" {
+				ok, err := available(candidate)
+				if err != nil {
+					return "", err
+				}
+				if ok {
+					return candidate, nil
+				}
+				prefix = candidate
+			}
+			if readErr != nil {
+				return "", fmt.Errorf("CNAME prefix %q is already taken and no available alternative was entered", prefix)
+			}
+		}
+
+	default:
+		return "", fmt.Errorf("invalid environment.cname_conflict %q (must be %s, %s or %s)", strategy, cnameConflictFail, cnameConflictSuffix, cnameConflictPrompt)
+	}
+}
+
+// cnameWithSuffix appends "-n" to prefix, trimming it to stay within the 63 character DNS label limit.
+func cnameWithSuffix(prefix string, n int) string {
+	suffix := fmt.Sprintf("-%d", n)
+	if len(prefix)+len(suffix) > 63 {
+		prefix = strings.TrimRight(prefix[:63-len(suffix)], "-")
+	}
+	return prefix + suffix
+}
+
+// isCNAMEConflict reports whether a CreateEnvironment error was caused by a taken CNAME prefix.
+func isCNAMEConflict(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "is not available")
+}
@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func takenExcept(free ...string) func(string) (bool, error) {
+	return func(prefix string) (bool, error) {
+		for _, f := range free {
+			if prefix == f {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func TestResolveCNAMEAvailable(t *testing.T) {
+	prefix, err := resolveCNAME("my-app", "", takenExcept("my-app"), strings.NewReader(""))
+	if err != nil || prefix != "my-app" {
+		t.Errorf("Expected my-app, got %q (%v)", prefix, err)
+	}
+}
+
+func TestResolveCNAMEFail(t *testing.T) {
+	for _, strategy := range []string{"", "fail"} {
+		_, err := resolveCNAME("my-app", strategy, takenExcept(), strings.NewReader(""))
+		if err == nil || !strings.Contains(err.Error(), "already taken") {
+			t.Errorf("strategy %q: expected taken error, got %v", strategy, err)
+		}
+	}
+}
+
+func TestResolveCNAMESuffix(t *testing.T) {
+	prefix, err := resolveCNAME("my-app", "suffix", takenExcept("my-app-3"), strings.NewReader(""))
+	if err != nil || prefix != "my-app-3" {
+		t.Errorf("Expected my-app-3, got %q (%v)", prefix, err)
+	}
+
+	_, err = resolveCNAME("my-app", "suffix", takenExcept(), strings.NewReader(""))
+	if err == nil || !strings.Contains(err.Error(), "suffixed alternatives") {
+		t.Errorf("Expected exhausted error, got %v", err)
+	}
+}
+
+func TestResolveCNAMEPrompt(t *testing.T) {
+	in := strings.NewReader("\ntaken-too\nmy-other-app\n")
+	prefix, err := resolveCNAME("my-app", "prompt", takenExcept("my-other-app"), in)
+	if err != nil || prefix != "my-other-app" {
+		t.Errorf("Expected my-other-app, got %q (%v)", prefix, err)
+	}
+
+	_, err = resolveCNAME("my-app", "prompt", takenExcept(), strings.NewReader("taken-too\n"))
+	if err == nil || !strings.Contains(err.Error(), `"taken-too" is already taken`) {
+		t.Errorf("Expected error after input runs out, got %v", err)
+	}
+}
+
+func TestResolveCNAMEInvalidStrategy(t *testing.T) {
+	_, err := resolveCNAME("my-app", "random", takenExcept(), strings.NewReader(""))
+	if err == nil || !strings.Contains(err.Error(), "invalid environment.cname_conflict") {
+		t.Errorf("Expected invalid strategy error, got %v", err)
+	}
+}
+
+func TestResolveCNAMECheckError(t *testing.T) {
+	failing := func(string) (bool, error) { return false, errors.New("throttled") }
+	if _, err := resolveCNAME("my-app", "suffix", failing, strings.NewReader("")); err == nil {
+		t.Error("Expected availability check error to be returned")
+	}
+}
+
+func TestCNAMEWithSuffix(t *testing.T) {
+	if got := cnameWithSuffix("my-app", 2); got != "my-app-2" {
+		t.Errorf("Expected my-app-2, got %s", got)
+	}
+
+	long := strings.Repeat("a", 62) + "-b"
+	got := cnameWithSuffix(long, 12)
+	if len(got) > 63 || !strings.HasSuffix(got, "-12") {
+		t.Errorf("Expected at most 63 characters ending in -12, got %q (%d)", got, len(got))
+	}
+}
+
+func TestIsCNAMEConflict(t *testing.T) {
+	if !isCNAMEConflict(errors.New("InvalidParameterValue: DNS name (my-app.us-east-1.elasticbeanstalk.com) is not available.")) {
+		t.Error("Expected DNS name error to be a CNAME conflict")
+	}
+	if isCNAMEConflict(errors.New("InsufficientPrivilegesException")) || isCNAMEConflict(nil) {
+		t.Error("Expected other errors not to be CNAME conflicts")
+	}
+}
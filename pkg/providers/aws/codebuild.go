@@ -0,0 +1,320 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/build"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+)
+
+const (
+	// Curated CodeBuild images with Docker, for x86 and Graviton builds
+	codeBuildImageAMD64 = "aws/codebuild/amazonlinux2-x86_64-standard:5.0"
+	codeBuildImageARM64 = "aws/codebuild/amazonlinux2-aarch64-standard:3.0"
+
+	defaultCodeBuildComputeType = "BUILD_GENERAL1_SMALL"
+	defaultCodeBuildTimeout     = 30
+
+	// codeBuildDigestVariable is exported by the buildspec with the digest of
+	// the pushed image.
+	codeBuildDigestVariable = "IMAGE_DIGEST"
+)
+
+// codeBuildPollInterval is how often a running build is checked. Replaced in tests.
+var codeBuildPollInterval = 10 * time.Second
+
+// safeShellWord matches arguments that need no quoting in a shell command.
+var safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// codeBuildProject is the CreateProject and UpdateProject request of CodeBuild.
+type codeBuildProject struct {
+	Name             string               `json:"name"`
+	Source           codeBuildSource      `json:"source"`
+	Artifacts        codeBuildArtifacts   `json:"artifacts"`
+	Environment      codeBuildEnvironment `json:"environment"`
+	ServiceRole      string               `json:"serviceRole"`
+	TimeoutInMinutes int                  `json:"timeoutInMinutes"`
+}
+
+type codeBuildSource struct {
+	Type      string `json:"type"`
+	Location  string `json:"location"`
+	Buildspec string `json:"buildspec"`
+}
+
+type codeBuildArtifacts struct {
+	Type string `json:"type"`
+}
+
+type codeBuildEnvironment struct {
+	Type           string `json:"type"`
+	Image          string `json:"image"`
+	ComputeType    string `json:"computeType"`
+	PrivilegedMode bool   `json:"privilegedMode"`
+}
+
+// codeBuildBuild is a build returned by StartBuild and BatchGetBuilds.
+type codeBuildBuild struct {
+	ID           string `json:"id"`
+	BuildStatus  string `json:"buildStatus"`
+	CurrentPhase string `json:"currentPhase"`
+	Logs         struct {
+		DeepLink string `json:"deepLink"`
+	} `json:"logs"`
+	ExportedEnvironmentVariables []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"exportedEnvironmentVariables"`
+}
+
+// buildWithCodeBuild builds the manifest's source with AWS CodeBuild and
+// pushes it to the application's ECR repository. The zipped source is
+// uploaded to the application's bucket, and the application's CodeBuild
+// project is created or updated to build it. It returns the URI and digest of
+// the pushed image.
+func (p *Provider) buildWithCodeBuild(ctx context.Context, m *manifest.Manifest, bucketName string) (string, string, error) {
+	tag, err := name.NewTag(m.Image)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image name %s: %w", m.Image, err)
+	}
+
+	// The authenticator creates the repository and resolves the image URI
+	ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.RepositoryName(), tag.TagStr())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create ECR registry: %w", err)
+	}
+	if _, err := ecrRegistry.GetAuthenticator(ctx); err != nil {
+		return "", "", err
+	}
+	imageURI := ecrRegistry.GetImageURI()
+
+	if err := p.ensureBucket(ctx, bucketName); err != nil {
+		return "", "", fmt.Errorf("failed to ensure S3 bucket: %w", err)
+	}
+	sourceKey := fmt.Sprintf("%s/source/%s.zip", m.Application.Name, tag.TagStr())
+	if err := p.uploadSource(ctx, m.Deployment.Source.Path, bucketName, sourceKey); err != nil {
+		return "", "", fmt.Errorf("failed to upload source: %w", err)
+	}
+
+	project, err := buildProject(m, ecrRegistry.GetRegistryURL(), imageURI, bucketName+"/"+sourceKey)
+	if err != nil {
+		return "", "", err
+	}
+	if err := p.ensureCodeBuildProject(ctx, project); err != nil {
+		return "", "", err
+	}
+
+	logging.Info("Starting CodeBuild build", "project", project.Name, "image", imageURI)
+	var started struct {
+		Build codeBuildBuild `json:"build"`
+	}
+	if err := p.codebuildClient.JSON(ctx, "CodeBuild_20161006.StartBuild", map[string]interface{}{
+		"projectName":            project.Name,
+		"sourceLocationOverride": project.Source.Location,
+	}, &started); err != nil {
+		return "", "", fmt.Errorf("failed to start CodeBuild build: %w", err)
+	}
+
+	finished, err := p.waitForCodeBuild(ctx, started.Build.ID)
+	if err != nil {
+		return "", "", err
+	}
+	digest := ""
+	for _, v := range finished.ExportedEnvironmentVariables {
+		if v.Name == codeBuildDigestVariable {
+			digest = v.Value
+		}
+	}
+	logging.Info("Image built by CodeBuild", "image_uri", imageURI, "digest", digest)
+	return imageURI, digest, nil
+}
+
+// uploadSource zips the source directory and uploads it to the bucket.
+func (p *Provider) uploadSource(ctx context.Context, sourceDir, bucketName, key string) error {
+	if info, err := os.Stat(sourceDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("source path %s is not a directory", sourceDir)
+	}
+
+	zipFile, err := os.CreateTemp("", "cloud-deploy-source-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	if err := zipDirectory(sourceDir, zipFile); err != nil {
+		return fmt.Errorf("failed to zip %s: %w", sourceDir, err)
+	}
+	if _, err := zipFile.Seek(0, 0); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+
+	logging.Info("Uploading source to S3", "bucket", bucketName, "key", key)
+	_, err = p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   zipFile,
+	})
+	return err
+}
+
+// ensureCodeBuildProject creates the project, or updates it when it exists.
+func (p *Provider) ensureCodeBuildProject(ctx context.Context, project codeBuildProject) error {
+	err := p.codebuildClient.JSON(ctx, "CodeBuild_20161006.CreateProject", project, nil)
+	if awsapi.IsErrorCode(err, "ResourceAlreadyExistsException") {
+		logging.Info("Updating CodeBuild project", "project", project.Name)
+		err = p.codebuildClient.JSON(ctx, "CodeBuild_20161006.UpdateProject", project, nil)
+	} else if err == nil {
+		logging.Info("Created CodeBuild project", "project", project.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply CodeBuild project %s: %w", project.Name, err)
+	}
+	return nil
+}
+
+// waitForCodeBuild polls a build until it completes, failing unless it succeeded.
+func (p *Provider) waitForCodeBuild(ctx context.Context, id string) (*codeBuildBuild, error) {
+	phase := ""
+	for {
+		var out struct {
+			Builds []codeBuildBuild `json:"builds"`
+		}
+		if err := p.codebuildClient.JSON(ctx, "CodeBuild_20161006.BatchGetBuilds", map[string]interface{}{"ids": []string{id}}, &out); err != nil {
+			return nil, fmt.Errorf("failed to get CodeBuild build %s: %w", id, err)
+		}
+		if len(out.Builds) == 0 {
+			return nil, fmt.Errorf("CodeBuild build %s not found", id)
+		}
+
+		b := out.Builds[0]
+		if b.CurrentPhase != phase {
+			phase = b.CurrentPhase
+			logging.Info("CodeBuild build phase", "build", id, "phase", phase)
+		}
+		switch b.BuildStatus {
+		case "SUCCEEDED":
+			return &b, nil
+		case "IN_PROGRESS", "":
+		default:
+			return nil, fmt.Errorf("CodeBuild build %s %s in phase %s; see the build logs at %s", id, strings.ToLower(strings.ReplaceAll(b.BuildStatus, "_", " ")), b.CurrentPhase, b.Logs.DeepLink)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(codeBuildPollInterval):
+		}
+	}
+}
+
+// buildProject returns the CodeBuild project building the manifest's source
+// from sourceLocation (bucket/key) and pushing it as imageURI. Images are
+// built for the instances' architecture unless the manifest names a platform.
+func buildProject(m *manifest.Manifest, registryURL, imageURI, sourceLocation string) (codeBuildProject, error) {
+	cfg := *m.Deployment.Source.Build
+	if cfg.Platform == "" {
+		cfg.Platform = instancePlatform(m.Instance.Type).String()
+	}
+	buildspec, err := codeBuildSpec(&cfg, registryURL, imageURI)
+	if err != nil {
+		return codeBuildProject{}, err
+	}
+
+	env := codeBuildEnvironment{
+		Type:           "LINUX_CONTAINER",
+		Image:          codeBuildImageAMD64,
+		ComputeType:    defaultCodeBuildComputeType,
+		PrivilegedMode: true, // required to run Docker
+	}
+	if strings.HasSuffix(cfg.Platform, "/arm64") {
+		env.Type = "ARM_CONTAINER"
+		env.Image = codeBuildImageARM64
+	}
+	timeout := defaultCodeBuildTimeout
+	serviceRole := ""
+	if cb := cfg.CodeBuild; cb != nil {
+		if cb.ComputeType != "" {
+			env.ComputeType = cb.ComputeType
+		}
+		if cb.TimeoutMinutes != 0 {
+			timeout = cb.TimeoutMinutes
+		}
+		serviceRole = cb.ServiceRole
+	}
+	if !strings.HasPrefix(serviceRole, "arn:") {
+		// The registry host starts with the account ID
+		accountID, _, _ := strings.Cut(registryURL, ".")
+		serviceRole = fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, serviceRole)
+	}
+
+	return codeBuildProject{
+		Name: m.Application.Name + "-build",
+		Source: codeBuildSource{
+			Type:      "S3",
+			Location:  sourceLocation,
+			Buildspec: buildspec,
+		},
+		Artifacts:        codeBuildArtifacts{Type: "NO_ARTIFACTS"},
+		Environment:      env,
+		ServiceRole:      serviceRole,
+		TimeoutInMinutes: timeout,
+	}, nil
+}
+
+// codeBuildSpec returns the buildspec logging in to ECR, building the source
+// the way a local build would, pushing the image, and exporting its digest.
+func codeBuildSpec(cfg *manifest.BuildConfig, registryURL, imageURI string) (string, error) {
+	buildCommand := "docker"
+	for _, arg := range build.Args(cfg, ".", imageURI) {
+		buildCommand += " " + shellQuote(arg)
+	}
+	quotedImage := shellQuote(imageURI)
+
+	spec := map[string]interface{}{
+		"version": "0.2",
+		"env": map[string]interface{}{
+			"exported-variables": []string{codeBuildDigestVariable},
+		},
+		"phases": map[string]interface{}{
+			"pre_build": map[string]interface{}{
+				"commands": []string{
+					"aws ecr get-login-password --region $AWS_REGION | docker login --username AWS --password-stdin " + shellQuote(registryURL),
+				},
+			},
+			"build": map[string]interface{}{
+				"commands": []string{
+					buildCommand,
+					"docker push " + quotedImage,
+					codeBuildDigestVariable + "=$(docker inspect --format '{{index .RepoDigests 0}}' " + quotedImage + " | cut -d@ -f2)",
+				},
+			},
+		},
+	}
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode buildspec: %w", err)
+	}
+	return string(data), nil
+}
+
+// shellQuote quotes s as a single word of a shell command.
+func shellQuote(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
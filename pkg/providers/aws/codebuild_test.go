@@ -0,0 +1,182 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func codeBuildManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Instance:    manifest.InstanceConfig{Type: "t4g.small"},
+		Deployment: manifest.DeploymentConfig{Source: manifest.SourceConfig{Path: "./app", Build: &manifest.BuildConfig{
+			Args:      map[string]string{"GREETING": "hello world"},
+			Strategy:  manifest.BuildStrategyCodeBuild,
+			CodeBuild: &manifest.CodeBuildConfig{ServiceRole: "codebuild-role", TimeoutMinutes: 60},
+		}}},
+	}
+}
+
+func TestBuildProject(t *testing.T) {
+	m := codeBuildManifest()
+	project, err := buildProject(m, "123456789012.dkr.ecr.us-east-1.amazonaws.com", "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1", "bucket/my-app/source/v1.zip")
+	if err != nil {
+		t.Fatalf("buildProject failed: %v", err)
+	}
+
+	if project.Name != "my-app-build" {
+		t.Errorf("Unexpected project name %s", project.Name)
+	}
+	if project.ServiceRole != "arn:aws:iam::123456789012:role/codebuild-role" {
+		t.Errorf("Expected the role name to be expanded to an ARN, got %s", project.ServiceRole)
+	}
+	if project.TimeoutInMinutes != 60 || project.Environment.ComputeType != defaultCodeBuildComputeType {
+		t.Errorf("Unexpected timeout %d or compute type %s", project.TimeoutInMinutes, project.Environment.ComputeType)
+	}
+	// Graviton instances get images built on ARM
+	if project.Environment.Type != "ARM_CONTAINER" || project.Environment.Image != codeBuildImageARM64 || !project.Environment.PrivilegedMode {
+		t.Errorf("Unexpected environment %+v", project.Environment)
+	}
+	if project.Source.Type != "S3" || project.Source.Location != "bucket/my-app/source/v1.zip" {
+		t.Errorf("Unexpected source %+v", project.Source)
+	}
+
+	var spec struct {
+		Env struct {
+			ExportedVariables []string `yaml:"exported-variables"`
+		} `yaml:"env"`
+		Phases map[string]struct {
+			Commands []string `yaml:"commands"`
+		} `yaml:"phases"`
+	}
+	if err := yaml.Unmarshal([]byte(project.Source.Buildspec), &spec); err != nil {
+		t.Fatalf("Invalid buildspec: %v\n%s", err, project.Source.Buildspec)
+	}
+	if len(spec.Env.ExportedVariables) != 1 || spec.Env.ExportedVariables[0] != codeBuildDigestVariable {
+		t.Errorf("Expected the digest to be exported, got %v", spec.Env.ExportedVariables)
+	}
+	commands := spec.Phases["build"].Commands
+	want := "docker build --file Dockerfile --tag 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1 --platform linux/arm64 --build-arg 'GREETING=hello world' ."
+	if len(commands) != 3 || commands[0] != want || commands[1] != "docker push 123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1" {
+		t.Errorf("Unexpected build commands %q", commands)
+	}
+
+	m.Instance.Type = "t3.small"
+	m.Deployment.Source.Build.CodeBuild.ServiceRole = "arn:aws:iam::999999999999:role/shared"
+	project, err = buildProject(m, "123456789012.dkr.ecr.us-east-1.amazonaws.com", "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1", "bucket/key.zip")
+	if err != nil {
+		t.Fatalf("buildProject failed: %v", err)
+	}
+	if project.Environment.Type != "LINUX_CONTAINER" || project.Environment.Image != codeBuildImageAMD64 {
+		t.Errorf("Expected an x86 build for x86 instances, got %+v", project.Environment)
+	}
+	if project.ServiceRole != "arn:aws:iam::999999999999:role/shared" {
+		t.Errorf("Expected role ARNs to be kept, got %s", project.ServiceRole)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"linux/amd64":  "linux/amd64",
+		"A=b c":        "'A=b c'",
+		"it's":         `'it'\''s'`,
+		"$(rm -rf /)":  "'$(rm -rf /)'",
+		"--build-arg":  "--build-arg",
+		"repo:tag@x_y": "repo:tag@x_y",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func testCodeBuildProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+	}
+	client := awsapi.New(cfg, "codebuild")
+	client.SetEndpoint(server.URL)
+
+	interval := codeBuildPollInterval
+	codeBuildPollInterval = 0
+	t.Cleanup(func() { codeBuildPollInterval = interval })
+	return &Provider{codebuildClient: client, region: "us-east-1", config: cfg}
+}
+
+func TestEnsureCodeBuildProjectUpdates(t *testing.T) {
+	var targets []string
+	p := testCodeBuildProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		targets = append(targets, target)
+		if target == "CodeBuild_20161006.CreateProject" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceAlreadyExistsException","message":"Project already exists: my-app-build"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+
+	if err := p.ensureCodeBuildProject(context.Background(), codeBuildProject{Name: "my-app-build"}); err != nil {
+		t.Fatalf("ensureCodeBuildProject failed: %v", err)
+	}
+	if len(targets) != 2 || targets[1] != "CodeBuild_20161006.UpdateProject" {
+		t.Errorf("Expected the existing project to be updated, got %v", targets)
+	}
+}
+
+func TestWaitForCodeBuild(t *testing.T) {
+	polls := 0
+	p := testCodeBuildProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.IDs) != 1 || body.IDs[0] != "my-app-build:1" {
+			t.Errorf("Unexpected build IDs %v", body.IDs)
+		}
+		polls++
+		if polls == 1 {
+			w.Write([]byte(`{"builds":[{"id":"my-app-build:1","buildStatus":"IN_PROGRESS","currentPhase":"BUILD"}]}`))
+			return
+		}
+		w.Write([]byte(`{"builds":[{"id":"my-app-build:1","buildStatus":"SUCCEEDED","currentPhase":"COMPLETED",
+"exportedEnvironmentVariables":[{"name":"IMAGE_DIGEST","value":"sha256:abc"}]}]}`))
+	})
+
+	b, err := p.waitForCodeBuild(context.Background(), "my-app-build:1")
+	if err != nil {
+		t.Fatalf("waitForCodeBuild failed: %v", err)
+	}
+	if polls != 2 || len(b.ExportedEnvironmentVariables) != 1 || b.ExportedEnvironmentVariables[0].Value != "sha256:abc" {
+		t.Errorf("Unexpected build %+v after %d polls", b, polls)
+	}
+}
+
+func TestWaitForCodeBuildFailed(t *testing.T) {
+	p := testCodeBuildProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"builds":[{"id":"my-app-build:2","buildStatus":"TIMED_OUT","currentPhase":"BUILD",
+"logs":{"deepLink":"https://console.aws.amazon.com/cloudwatch/logs"}}]}`))
+	})
+
+	_, err := p.waitForCodeBuild(context.Background(), "my-app-build:2")
+	if err == nil || !strings.Contains(err.Error(), "timed out in phase BUILD") || !strings.Contains(err.Error(), "https://console.aws.amazon.com/cloudwatch/logs") {
+		t.Errorf("Expected a failure naming the phase and logs, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// PlanDestroy returns what Destroy would do: terminate the environment and,
+// with aws.cloudformation set, delete the ancillary stack with the S3 bucket
+// and ECR repository in it. The stack belongs to the application, so the
+// application's other environments depend on it. The Elastic Beanstalk
+// application and its versions are kept.
+func (p *Provider) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	state, err := p.readInventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildDestroyPlan(m, p.region, state), nil
+}
+
+// buildDestroyPlan returns what a destroy of m's environment does given the
+// existing resources in state.
+func buildDestroyPlan(m *manifest.Manifest, region string, state inventoryState) *types.Plan {
+	plan := &types.Plan{Provider: "aws"}
+	app := m.Application.Name
+
+	var others []string
+	for _, env := range state.environments {
+		name := aws.ToString(env.EnvironmentName)
+		if name != m.Environment.Name {
+			others = append(others, name)
+			continue
+		}
+		reason := "terminate its instances and load balancer"
+		if cname := aws.ToString(env.CNAME); cname != "" {
+			reason += "; releases " + cname
+		}
+		plan.Add(types.PlanDelete, "Elastic Beanstalk environment", name, reason)
+	}
+
+	if state.applicationARN != "" {
+		reason := "kept with its application versions"
+		if len(others) > 0 {
+			reason = fmt.Sprintf("kept; shared with %d other environments", len(others))
+		}
+		plan.Add(types.PlanNoChange, "Elastic Beanstalk application", app, reason)
+	}
+
+	inStack := make(map[string]bool)
+	if state.stack != nil {
+		if !usesCloudFormation(m) {
+			plan.Add(types.PlanNoChange, "CloudFormation stack", state.stack.StackName, "kept; aws.cloudformation is not set")
+		} else {
+			plan.Add(types.PlanDelete, "CloudFormation stack", state.stack.StackName, "ancillary resources of application "+app)
+			for _, r := range state.stackResources {
+				inStack[r.LogicalID] = true
+				switch r.LogicalID {
+				case cfnBucketID:
+					plan.Add(types.PlanDelete, "S3 bucket", r.PhysicalID, "emptied first; holds every application version")
+				case cfnRepositoryID:
+					plan.Add(types.PlanDelete, "ECR repository", r.PhysicalID, "with every image of the application")
+				default:
+					plan.Add(types.PlanDelete, r.Type, r.LogicalID, "")
+				}
+			}
+			resource := "CloudFormation stack " + state.stack.StackName
+			for _, name := range others {
+				plan.AddDependent(resource, "Elastic Beanstalk environment", name)
+			}
+		}
+	}
+
+	// A bucket or repository outside the stack is never deleted
+	if state.repositoryARN != "" && !(usesCloudFormation(m) && inStack[cfnRepositoryID]) {
+		plan.Add(types.PlanNoChange, "ECR repository", app, "kept")
+	}
+	if state.bucket && !(usesCloudFormation(m) && inStack[cfnBucketID]) {
+		plan.Add(types.PlanNoChange, "S3 bucket", fmt.Sprintf("elasticbeanstalk-%s-%s", region, app), "kept")
+	}
+	return plan
+}
@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func destroyState() inventoryState {
+	return inventoryState{
+		applicationARN: "arn:aws:elasticbeanstalk:us-east-1:123456789012:application/my-app",
+		environments: []ebtypes.EnvironmentDescription{
+			{EnvironmentName: aws.String("my-app-prod"), CNAME: aws.String("my-app-prod.us-east-1.elasticbeanstalk.com")},
+			{EnvironmentName: aws.String("my-app-staging")},
+		},
+		stack: &cfnStack{StackName: "cloud-deploy-my-app"},
+		stackResources: []stackResource{
+			{LogicalID: cfnBucketID, PhysicalID: "elasticbeanstalk-us-east-1-my-app", Type: "AWS::S3::Bucket"},
+			{LogicalID: cfnRepositoryID, PhysicalID: "my-app", Type: "AWS::ECR::Repository"},
+			{LogicalID: cfnInstanceRoleID, PhysicalID: "cloud-deploy-my-app-InstanceRole-XYZ", Type: "AWS::IAM::Role"},
+		},
+		repositoryARN: "arn:aws:ecr:us-east-1:123456789012:repository/my-app",
+		bucket:        true,
+	}
+}
+
+func TestBuildDestroyPlan(t *testing.T) {
+	plan := buildDestroyPlan(planManifest(), "us-east-1", destroyState())
+
+	want := []types.ResourceChange{
+		{Action: types.PlanDelete, Type: "Elastic Beanstalk environment", Name: "my-app-prod", Reason: "terminate its instances and load balancer; releases my-app-prod.us-east-1.elasticbeanstalk.com"},
+		{Action: types.PlanNoChange, Type: "Elastic Beanstalk application", Name: "my-app", Reason: "kept; shared with 1 other environments"},
+		{Action: types.PlanNoChange, Type: "CloudFormation stack", Name: "cloud-deploy-my-app", Reason: "kept; aws.cloudformation is not set"},
+		{Action: types.PlanNoChange, Type: "ECR repository", Name: "my-app", Reason: "kept"},
+		{Action: types.PlanNoChange, Type: "S3 bucket", Name: "elasticbeanstalk-us-east-1-my-app", Reason: "kept"},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(plan.Changes), len(want), plan.Changes)
+	}
+	for i, c := range plan.Changes {
+		if c != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+	if len(plan.Dependents) != 0 {
+		t.Errorf("expected no dependents when the stack is kept, got %+v", plan.Dependents)
+	}
+}
+
+func TestBuildDestroyPlanCloudFormation(t *testing.T) {
+	m := planManifest()
+	m.AWS = &manifest.AWSConfig{CloudFormation: true}
+	plan := buildDestroyPlan(m, "us-east-1", destroyState())
+
+	actions := changeActions(plan)
+	for _, name := range []string{"CloudFormation stack cloud-deploy-my-app", "S3 bucket elasticbeanstalk-us-east-1-my-app", "ECR repository my-app", "AWS::IAM::Role " + cfnInstanceRoleID} {
+		if actions[name] != types.PlanDelete {
+			t.Errorf("expected %s to be deleted, got %q", name, actions[name])
+		}
+	}
+	if plan.Count(types.PlanNoChange) != 1 {
+		t.Errorf("expected only the application to be kept, got %+v", plan.Changes)
+	}
+	want := types.Dependent{Resource: "CloudFormation stack cloud-deploy-my-app", Type: "Elastic Beanstalk environment", Name: "my-app-staging"}
+	if len(plan.Dependents) != 1 || plan.Dependents[0] != want {
+		t.Errorf("expected the staging environment to depend on the stack, got %+v", plan.Dependents)
+	}
+}
+
+func TestBuildDestroyPlanEmpty(t *testing.T) {
+	plan := buildDestroyPlan(planManifest(), "us-east-1", inventoryState{})
+	if plan.Provider != "aws" || len(plan.Changes) != 0 {
+		t.Errorf("expected nothing to destroy, got %+v", plan)
+	}
+}
@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/secrets"
+)
+
+// environmentNamespace is the option namespace of Elastic Beanstalk environment properties.
+const environmentNamespace = "aws:elasticbeanstalk:application:environment"
+
+// UpdateEnvironmentVariables sets vars as environment properties of the running environment.
+// Only changed properties are sent; Elastic Beanstalk restarts the application with the
+// new values without deploying a new application version. Secrets delivered natively are
+// environment secrets, which cannot be updated in place.
+func (p *Provider) UpdateEnvironmentVariables(ctx context.Context, m *manifest.Manifest, vars map[string]string) ([]string, error) {
+	if m.NativeSecrets() {
+		return nil, fmt.Errorf("secrets with secret_delivery %s are read from Secrets Manager when instances are deployed; run deploy to pick up new values", manifest.SecretDeliveryNative)
+	}
+	m, err := p.liveManifest(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.ebClient.DescribeConfigurationSettings(ctx, &elasticbeanstalk.DescribeConfigurationSettingsInput{
+		ApplicationName: aws.String(m.Application.Name),
+		EnvironmentName: aws.String(m.Environment.Name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe environment configuration: %w", err)
+	}
+
+	current := environmentProperties(result.ConfigurationSettings)
+	changed := secrets.Changed(current, vars)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	settings := make([]ebtypes.ConfigurationOptionSetting, 0, len(changed))
+	for _, name := range changed {
+		settings = append(settings, ebtypes.ConfigurationOptionSetting{
+			Namespace:  aws.String(environmentNamespace),
+			OptionName: aws.String(name),
+			Value:      aws.String(vars[name]),
+		})
+	}
+
+	logging.Info("Updating environment properties", "environment", m.Environment.Name, "count", len(changed))
+	_, err = p.ebClient.UpdateEnvironment(ctx, &elasticbeanstalk.UpdateEnvironmentInput{
+		ApplicationName: aws.String(m.Application.Name),
+		EnvironmentName: aws.String(m.Environment.Name),
+		OptionSettings:  settings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update environment properties: %w", err)
+	}
+
+	if _, err := p.waitForEnvironment(ctx, m.Application.Name, m.Environment.Name); err != nil {
+		return nil, fmt.Errorf("environment update failed: %w", err)
+	}
+	return changed, nil
+}
+
+// environmentProperties extracts environment properties from configuration settings.
+func environmentProperties(configs []ebtypes.ConfigurationSettingsDescription) map[string]string {
+	props := make(map[string]string)
+	for _, cfg := range configs {
+		for _, opt := range cfg.OptionSettings {
+			if aws.ToString(opt.Namespace) == environmentNamespace {
+				props[aws.ToString(opt.OptionName)] = aws.ToString(opt.Value)
+			}
+		}
+	}
+	return props
+}
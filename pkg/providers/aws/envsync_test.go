@@ -0,0 +1,27 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+)
+
+func TestEnvironmentProperties(t *testing.T) {
+	configs := []ebtypes.ConfigurationSettingsDescription{{
+		OptionSettings: []ebtypes.ConfigurationOptionSetting{
+			{Namespace: aws.String(environmentNamespace), OptionName: aws.String("API_KEY"), Value: aws.String("old")},
+			{Namespace: aws.String(environmentNamespace), OptionName: aws.String("LOG_LEVEL"), Value: aws.String("info")},
+			{Namespace: aws.String("aws:autoscaling:launchconfiguration"), OptionName: aws.String("InstanceType"), Value: aws.String("t3.micro")},
+		},
+	}}
+
+	props := environmentProperties(configs)
+
+	if len(props) != 2 {
+		t.Fatalf("Expected 2 environment properties, got %d: %v", len(props), props)
+	}
+	if props["API_KEY"] != "old" || props["LOG_LEVEL"] != "info" {
+		t.Errorf("Unexpected properties: %v", props)
+	}
+}
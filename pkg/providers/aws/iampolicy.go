@@ -0,0 +1,336 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/secrets"
+)
+
+// iamStatement is a statement of an IAM policy document.
+type iamStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// iamPolicyDocument is an IAM identity-based policy document.
+type iamPolicyDocument struct {
+	Version   string         `json:"Version"`
+	Statement []iamStatement `json:"Statement"`
+}
+
+// IAMPolicy returns the IAM policy the deployer needs for the features used in m,
+// scoped to the manifest's application and region where AWS allows it.
+//
+// Elastic Beanstalk creates the environment's EC2, Auto Scaling, load balancing,
+// and CloudFormation resources with the caller's permissions, so those services
+// are granted but limited to the resources Elastic Beanstalk names.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	region := m.Provider.Region
+	if region == "" {
+		region = "*"
+	}
+	app := m.Application.Name
+	if app == "" {
+		return nil, fmt.Errorf("application.name is required to scope the policy")
+	}
+
+	eb := func(resource string) string {
+		return fmt.Sprintf("arn:aws:elasticbeanstalk:%s:*:%s", region, resource)
+	}
+
+	statements := []iamStatement{
+		{
+			Sid:    "ElasticBeanstalkRead",
+			Effect: "Allow",
+			Action: []string{
+				"elasticbeanstalk:CheckDNSAvailability",
+				"elasticbeanstalk:DescribeAccountAttributes",
+				"elasticbeanstalk:DescribeApplications",
+				"elasticbeanstalk:DescribeApplicationVersions",
+				"elasticbeanstalk:DescribeConfigurationSettings",
+				"elasticbeanstalk:DescribeEnvironmentResources",
+				"elasticbeanstalk:DescribeEnvironments",
+				"elasticbeanstalk:DescribeEvents",
+				"elasticbeanstalk:ListAvailableSolutionStacks",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "ElasticBeanstalkApplication",
+			Effect: "Allow",
+			Action: []string{
+				"elasticbeanstalk:CreateApplication",
+				"elasticbeanstalk:CreateApplicationVersion",
+				"elasticbeanstalk:DeleteApplicationVersion",
+				"elasticbeanstalk:CreateEnvironment",
+				"elasticbeanstalk:UpdateEnvironment",
+				"elasticbeanstalk:TerminateEnvironment",
+				"elasticbeanstalk:AddTags",
+				"elasticbeanstalk:ListTagsForResource",
+			},
+			Resource: []string{
+				eb("application/" + app),
+				eb("applicationversion/" + app + "/*"),
+				eb("environment/" + app + "/*"),
+				eb("configurationtemplate/" + app + "/*"),
+				fmt.Sprintf("arn:aws:elasticbeanstalk:%s::platform/*", region),
+				fmt.Sprintf("arn:aws:elasticbeanstalk:%s::solutionstack/*", region),
+			},
+		},
+		{
+			Sid:    "ElasticBeanstalkManagedResources",
+			Effect: "Allow",
+			Action: []string{
+				"autoscaling:*",
+				"cloudwatch:*",
+				"ec2:*",
+				"elasticloadbalancing:*",
+				"sns:*",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "ElasticBeanstalkStacks",
+			Effect: "Allow",
+			Action: []string{"cloudformation:*"},
+			Resource: []string{
+				fmt.Sprintf("arn:aws:cloudformation:%s:*:stack/awseb-*", region),
+			},
+		},
+		{
+			Sid:    "ApplicationVersionsBucket",
+			Effect: "Allow",
+			Action: []string{
+				"s3:CreateBucket",
+				"s3:ListBucket",
+				"s3:GetObject",
+				"s3:PutObject",
+				"s3:DeleteObject",
+				"s3:GetBucketLocation",
+				"s3:GetBucketPolicy",
+				"s3:PutBucketPolicy",
+				"s3:PutBucketOwnershipControls",
+			},
+			Resource: []string{
+				"arn:aws:s3:::elasticbeanstalk-*",
+				"arn:aws:s3:::elasticbeanstalk-*/*",
+			},
+		},
+		{
+			Sid:    "ImagePush",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:CreateRepository",
+				"ecr:DescribeRepositories",
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:BatchGetImage",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:InitiateLayerUpload",
+				"ecr:UploadLayerPart",
+				"ecr:CompleteLayerUpload",
+				"ecr:PutImage",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:ecr:%s:*:repository/%s", region, m.RepositoryName())},
+		},
+		{
+			Sid:    "RegistryLogin",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:GetAuthorizationToken",
+				"sts:GetCallerIdentity",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:      "PassInstanceRole",
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: passRoleResources(m),
+		},
+	}
+
+	if m.Monitoring.CloudWatchLogs != nil && m.Monitoring.CloudWatchLogs.Enabled {
+		statements = append(statements, iamStatement{
+			Sid:    "ReadLogs",
+			Effect: "Allow",
+			Action: []string{
+				"logs:DescribeLogGroups",
+				"logs:FilterLogEvents",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:logs:%s:*:log-group:/aws/elasticbeanstalk/*", region)},
+		})
+	}
+
+	if usesCloudFormation(m) {
+		stack := ancillaryStackName(app)
+		statements = append(statements, iamStatement{
+			Sid:    "AncillaryStack",
+			Effect: "Allow",
+			Action: []string{
+				"cloudformation:CreateChangeSet",
+				"cloudformation:DescribeChangeSet",
+				"cloudformation:ExecuteChangeSet",
+				"cloudformation:DeleteChangeSet",
+				"cloudformation:DescribeStacks",
+				"cloudformation:DeleteStack",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:cloudformation:%s:*:stack/%s/*", region, stack)},
+		})
+		statements = append(statements, iamStatement{
+			Sid:    "AncillaryStackResources",
+			Effect: "Allow",
+			Action: []string{
+				"s3:DeleteBucket",
+				"s3:PutBucketTagging",
+				"ecr:DeleteRepository",
+				"ecr:TagResource",
+				"ecr:ListImages",
+				"ecr:BatchDeleteImage",
+			},
+			Resource: []string{
+				"arn:aws:s3:::elasticbeanstalk-*",
+				fmt.Sprintf("arn:aws:ecr:%s:*:repository/%s", region, m.RepositoryName()),
+			},
+		})
+		if m.IAM.InstanceProfile == "" {
+			statements = append(statements, iamStatement{
+				Sid:    "AncillaryInstanceRole",
+				Effect: "Allow",
+				Action: []string{
+					"iam:CreateRole",
+					"iam:DeleteRole",
+					"iam:GetRole",
+					"iam:TagRole",
+					"iam:AttachRolePolicy",
+					"iam:DetachRolePolicy",
+					"iam:CreateInstanceProfile",
+					"iam:DeleteInstanceProfile",
+					"iam:GetInstanceProfile",
+					"iam:AddRoleToInstanceProfile",
+					"iam:RemoveRoleFromInstanceProfile",
+				},
+				Resource: []string{
+					"arn:aws:iam::*:role/" + stack + "-*",
+					"arn:aws:iam::*:instance-profile/" + stack + "-*",
+				},
+			})
+		}
+	}
+
+	if build := m.Deployment.Source.Build; build != nil && build.RemoteBuild() && build.CodeBuild != nil {
+		statements = append(statements, iamStatement{
+			Sid:    "CodeBuildProject",
+			Effect: "Allow",
+			Action: []string{
+				"codebuild:CreateProject",
+				"codebuild:UpdateProject",
+				"codebuild:StartBuild",
+				"codebuild:BatchGetBuilds",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:codebuild:%s:*:project/%s-build", region, app)},
+		})
+		role := build.CodeBuild.ServiceRole
+		if !strings.HasPrefix(role, "arn:") {
+			role = "arn:aws:iam::*:role/" + role
+		}
+		statements = append(statements, iamStatement{
+			Sid:      "PassCodeBuildRole",
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: []string{role},
+		})
+	}
+
+	if resources := SecretResources(m, region); len(resources) > 0 {
+		actions := []string{"secretsmanager:GetSecretValue"}
+		if m.NativeSecrets() {
+			// Environment secrets reference the secrets by their full ARNs
+			actions = append(actions, "secretsmanager:DescribeSecret")
+		}
+		statements = append(statements, iamStatement{
+			Sid:      "ReadSecrets",
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: resources,
+		})
+	}
+
+	if c := m.Provider.Credentials; c != nil && c.Source == "secrets-manager" && c.SecretID != "" {
+		statements = append(statements, iamStatement{
+			Sid:    "RotateCredentials",
+			Effect: "Allow",
+			Action: []string{
+				"iam:CreateAccessKey",
+				"iam:UpdateAccessKey",
+			},
+			Resource: []string{"arn:aws:iam::*:user/${aws:username}"},
+		})
+		statements = append(statements, iamStatement{
+			Sid:    "CredentialsSecret",
+			Effect: "Allow",
+			Action: []string{
+				"secretsmanager:GetSecretValue",
+				"secretsmanager:PutSecretValue",
+			},
+			Resource: []string{secretARN(c.SecretID, region)},
+		})
+	}
+
+	return json.MarshalIndent(iamPolicyDocument{Version: "2012-10-17", Statement: statements}, "", "  ")
+}
+
+// passRoleResources returns the roles Elastic Beanstalk environments are created with.
+func passRoleResources(m *manifest.Manifest) []string {
+	var roles []string
+	switch {
+	case m.IAM.InstanceProfile != "":
+		// Instance profiles usually share their role's name
+		roles = append(roles, "arn:aws:iam::*:role/"+m.IAM.InstanceProfile)
+	case usesCloudFormation(m):
+		roles = append(roles, "arn:aws:iam::*:role/"+ancillaryStackName(m.Application.Name)+"-*")
+	default:
+		roles = append(roles, "arn:aws:iam::*:role/aws-elasticbeanstalk-ec2-role")
+	}
+
+	serviceRole := m.IAM.ServiceRole
+	if serviceRole == "" {
+		serviceRole = "aws-elasticbeanstalk-service-role"
+	}
+	return append(roles, "arn:aws:iam::*:role/"+serviceRole)
+}
+
+// SecretResources returns the ARNs of the Secrets Manager secrets referenced by m,
+// which deployers to AWS need to read.
+func SecretResources(m *manifest.Manifest, defaultRegion string) []string {
+	seen := make(map[string]bool)
+	var resources []string
+	for _, ref := range m.Secrets {
+		if ref.SecretSource() != secrets.SourceSecretsManager {
+			continue
+		}
+		region := ref.Region
+		if region == "" {
+			region = defaultRegion
+		}
+		arn := secretARN(ref.SecretID, region)
+		if !seen[arn] {
+			seen[arn] = true
+			resources = append(resources, arn)
+		}
+	}
+	return resources
+}
+
+// secretARN returns the ARN pattern of a Secrets Manager secret given its name or ARN.
+// Secret ARNs end in a random six-character suffix, which is matched by a wildcard.
+func secretARN(secretID, region string) string {
+	if strings.HasPrefix(secretID, "arn:") {
+		return secretID
+	}
+	return fmt.Sprintf("arn:aws:secretsmanager:%s:*:secret:%s-??????", region, secretID)
+}
@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func policyStatements(t *testing.T, m *manifest.Manifest) map[string]iamStatement {
+	t.Helper()
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc iamPolicyDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	if doc.Version != "2012-10-17" {
+		t.Errorf("Unexpected policy version %q", doc.Version)
+	}
+	statements := make(map[string]iamStatement)
+	for _, s := range doc.Statement {
+		statements[s.Sid] = s
+	}
+	return statements
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIAMPolicy(t *testing.T) {
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "aws", Region: "us-west-2"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+	}
+	statements := policyStatements(t, m)
+
+	for _, sid := range []string{"ElasticBeanstalkRead", "ElasticBeanstalkApplication", "ApplicationVersionsBucket", "ImagePush", "RegistryLogin", "PassInstanceRole"} {
+		if _, ok := statements[sid]; !ok {
+			t.Errorf("Expected statement %s", sid)
+		}
+	}
+	for _, sid := range []string{"AncillaryStack", "AncillaryInstanceRole", "ReadSecrets", "RotateCredentials", "ReadLogs", "CodeBuildProject"} {
+		if _, ok := statements[sid]; ok {
+			t.Errorf("Unexpected statement %s for features not in use", sid)
+		}
+	}
+
+	if !containsString(statements["ImagePush"].Resource, "arn:aws:ecr:us-west-2:*:repository/my-app") {
+		t.Errorf("Expected ECR access scoped to the application repository, got %v", statements["ImagePush"].Resource)
+	}
+	if !containsString(statements["ElasticBeanstalkApplication"].Resource, "arn:aws:elasticbeanstalk:us-west-2:*:environment/my-app/*") {
+		t.Errorf("Expected environment access scoped to the application, got %v", statements["ElasticBeanstalkApplication"].Resource)
+	}
+	if !containsString(statements["PassInstanceRole"].Resource, "arn:aws:iam::*:role/aws-elasticbeanstalk-ec2-role") {
+		t.Errorf("Expected default instance role, got %v", statements["PassInstanceRole"].Resource)
+	}
+}
+
+func TestIAMPolicyFeatures(t *testing.T) {
+	m := &manifest.Manifest{
+		Provider: manifest.ProviderConfig{
+			Name:        "aws",
+			Region:      "us-west-2",
+			Credentials: &manifest.CredentialsConfig{Source: "secrets-manager", SecretID: "cloud-deploy/aws"},
+		},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		AWS:         &manifest.AWSConfig{CloudFormation: true},
+		Deployment: manifest.DeploymentConfig{Source: manifest.SourceConfig{Path: "./app", Build: &manifest.BuildConfig{
+			Strategy:  manifest.BuildStrategyCodeBuild,
+			CodeBuild: &manifest.CodeBuildConfig{ServiceRole: "codebuild-role"},
+		}}},
+		Monitoring: manifest.MonitoringConfig{CloudWatchLogs: &manifest.CloudWatchLogsConfig{Enabled: true}},
+		Secrets: []manifest.SecretRef{
+			{Name: "DB_PASSWORD", SecretID: "myapp/db", Key: "password"},
+			{Name: "DB_USER", SecretID: "myapp/db", Key: "user"},
+			{Name: "API_KEY", SecretID: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:api-AbCdEf"},
+		},
+	}
+	statements := policyStatements(t, m)
+
+	if got := statements["AncillaryStack"].Resource; !containsString(got, "arn:aws:cloudformation:us-west-2:*:stack/cloud-deploy-my-app/*") {
+		t.Errorf("Expected ancillary stack access, got %v", got)
+	}
+	if _, ok := statements["AncillaryInstanceRole"]; !ok {
+		t.Error("Expected instance role management when the stack creates the role")
+	}
+	if got := statements["ReadSecrets"].Resource; len(got) != 2 ||
+		!containsString(got, "arn:aws:secretsmanager:us-west-2:*:secret:myapp/db-??????") ||
+		!containsString(got, "arn:aws:secretsmanager:eu-west-1:123456789012:secret:api-AbCdEf") {
+		t.Errorf("Unexpected secret resources: %v", got)
+	}
+	if _, ok := statements["RotateCredentials"]; !ok {
+		t.Error("Expected credential rotation permissions for secrets-manager credentials")
+	}
+	if got := statements["ReadLogs"].Resource; !containsString(got, "arn:aws:logs:us-west-2:*:log-group:/aws/elasticbeanstalk/*") {
+		t.Errorf("Expected log access when CloudWatch Logs is enabled, got %v", got)
+	}
+
+	if got := statements["CodeBuildProject"].Resource; !containsString(got, "arn:aws:codebuild:us-west-2:*:project/my-app-build") {
+		t.Errorf("Expected CodeBuild access scoped to the application's project, got %v", got)
+	}
+	if got := statements["PassCodeBuildRole"].Resource; !containsString(got, "arn:aws:iam::*:role/codebuild-role") {
+		t.Errorf("Expected pass role on the CodeBuild service role, got %v", got)
+	}
+
+	m.IAM.InstanceProfile = "my-profile"
+	statements = policyStatements(t, m)
+	if _, ok := statements["AncillaryInstanceRole"]; ok {
+		t.Error("Expected no instance role management when the manifest brings its own profile")
+	}
+	if !containsString(statements["PassInstanceRole"].Resource, "arn:aws:iam::*:role/my-profile") {
+		t.Errorf("Expected pass role on the manifest's profile, got %v", statements["PassInstanceRole"].Resource)
+	}
+}
+
+func TestIAMPolicyRequiresApplication(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected error without an application name")
+	}
+}
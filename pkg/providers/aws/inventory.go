@@ -0,0 +1,139 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// stackResource is a resource of the ancillary stack.
+type stackResource struct {
+	LogicalID  string `xml:"LogicalResourceId"`
+	PhysicalID string `xml:"PhysicalResourceId"`
+	Type       string `xml:"ResourceType"`
+}
+
+// inventoryState records the application's existing resources.
+type inventoryState struct {
+	applicationARN string
+	environments   []ebtypes.EnvironmentDescription
+	stack          *cfnStack
+	stackResources []stackResource
+	repositoryARN  string
+	bucket         bool
+}
+
+// Inventory lists the application's resources: the Elastic Beanstalk
+// application and all of its environments, the ancillary stack and the
+// resources in it, the ECR repository, and the S3 bucket of application
+// versions.
+func (p *Provider) Inventory(ctx context.Context, m *manifest.Manifest) (*types.Inventory, error) {
+	state, err := p.readInventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildInventory(m, p.region, state), nil
+}
+
+// readInventory reads which of the application's resources exist.
+func (p *Provider) readInventory(ctx context.Context, m *manifest.Manifest) (inventoryState, error) {
+	var state inventoryState
+	app := m.Application.Name
+
+	apps, err := p.ebClient.DescribeApplications(ctx, &elasticbeanstalk.DescribeApplicationsInput{
+		ApplicationNames: []string{app},
+	})
+	if err != nil {
+		return state, fmt.Errorf("failed to describe application: %w", err)
+	}
+	if len(apps.Applications) > 0 {
+		state.applicationARN = aws.ToString(apps.Applications[0].ApplicationArn)
+		envs, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
+			ApplicationName: aws.String(app),
+			IncludeDeleted:  aws.Bool(false),
+		})
+		if err != nil {
+			return state, fmt.Errorf("failed to describe environments: %w", err)
+		}
+		for _, env := range envs.Environments {
+			if env.Status != ebtypes.EnvironmentStatusTerminated {
+				state.environments = append(state.environments, env)
+			}
+		}
+	}
+
+	if state.stack, err = p.describeStack(ctx, ancillaryStackName(app)); err != nil {
+		return state, fmt.Errorf("failed to describe ancillary resources stack: %w", err)
+	}
+	if state.stack != nil {
+		var result struct {
+			Resources []stackResource `xml:"StackResourceSummaries>member"`
+		}
+		if err := p.cfnClient.Query(ctx, "ListStackResources", cfnAPIVersion, url.Values{"StackName": {state.stack.StackName}}, &result); err != nil {
+			return state, fmt.Errorf("failed to list resources of stack %s: %w", state.stack.StackName, err)
+		}
+		state.stackResources = result.Resources
+	}
+
+	ecrClient := ecr.NewFromConfig(p.config)
+	repos, err := ecrClient.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{RepositoryNames: []string{m.RepositoryName()}})
+	var notFound *ecrtypes.RepositoryNotFoundException
+	switch {
+	case err == nil && len(repos.Repositories) > 0:
+		state.repositoryARN = aws.ToString(repos.Repositories[0].RepositoryArn)
+	case err != nil && !errors.As(err, &notFound):
+		return state, fmt.Errorf("failed to describe ECR repository %s: %w", m.RepositoryName(), err)
+	}
+
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, app)
+	if _, err := p.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)}); err == nil {
+		state.bucket = true
+	}
+
+	return state, nil
+}
+
+// buildInventory returns the inventory of the existing resources in state.
+func buildInventory(m *manifest.Manifest, region string, state inventoryState) *types.Inventory {
+	inventory := &types.Inventory{Provider: "aws"}
+	app := m.Application.Name
+
+	if state.applicationARN != "" {
+		inventory.Add("Elastic Beanstalk application", app, state.applicationARN, types.InventoryByName)
+	}
+	for _, env := range state.environments {
+		inventory.Add("Elastic Beanstalk environment", aws.ToString(env.EnvironmentName), aws.ToString(env.EnvironmentArn), types.InventoryByName)
+		if cname := aws.ToString(env.CNAME); cname != "" {
+			inventory.Add("Elastic Beanstalk CNAME", cname, cname, types.InventoryByName)
+		}
+	}
+	if state.stack != nil {
+		inventory.Add("CloudFormation stack", state.stack.StackName, state.stack.StackId, types.InventoryByName)
+		for _, r := range state.stackResources {
+			// The bucket and repository are listed below under their names
+			if r.LogicalID == cfnBucketID || r.LogicalID == cfnRepositoryID {
+				continue
+			}
+			inventory.Add(r.Type, r.LogicalID, r.PhysicalID, types.InventoryByName)
+		}
+	}
+	if state.repositoryARN != "" {
+		inventory.Add("ECR repository", m.RepositoryName(), state.repositoryARN, types.InventoryByName)
+	}
+	if state.bucket {
+		bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", region, app)
+		inventory.Add("S3 bucket", bucketName, "arn:aws:s3:::"+bucketName, types.InventoryByName)
+	}
+	return inventory
+}
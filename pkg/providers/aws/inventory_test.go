@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestBuildInventoryEmpty(t *testing.T) {
+	inventory := buildInventory(planManifest(), "us-east-1", inventoryState{})
+	if inventory.Provider != "aws" || len(inventory.Resources) != 0 {
+		t.Errorf("expected no resources, got %+v", inventory)
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	state := inventoryState{
+		applicationARN: "arn:aws:elasticbeanstalk:us-east-1:123456789012:application/my-app",
+		environments: []ebtypes.EnvironmentDescription{
+			{EnvironmentName: aws.String("my-app-prod"), EnvironmentArn: aws.String("arn:aws:elasticbeanstalk:us-east-1:123456789012:environment/my-app/my-app-prod"), CNAME: aws.String("my-app-prod.us-east-1.elasticbeanstalk.com")},
+			{EnvironmentName: aws.String("my-app-staging"), EnvironmentArn: aws.String("arn:aws:elasticbeanstalk:us-east-1:123456789012:environment/my-app/my-app-staging")},
+		},
+		stack: &cfnStack{StackName: "cloud-deploy-my-app", StackId: "arn:aws:cloudformation:us-east-1:123456789012:stack/cloud-deploy-my-app/abc"},
+		stackResources: []stackResource{
+			{LogicalID: cfnBucketID, PhysicalID: "elasticbeanstalk-us-east-1-my-app", Type: "AWS::S3::Bucket"},
+			{LogicalID: cfnInstanceRoleID, PhysicalID: "cloud-deploy-my-app-InstanceRole-XYZ", Type: "AWS::IAM::Role"},
+		},
+		repositoryARN: "arn:aws:ecr:us-east-1:123456789012:repository/my-app",
+		bucket:        true,
+	}
+	inventory := buildInventory(planManifest(), "us-east-1", state)
+
+	want := []types.InventoryResource{
+		{Type: "Elastic Beanstalk application", Name: "my-app", ID: state.applicationARN, Source: types.InventoryByName},
+		{Type: "Elastic Beanstalk environment", Name: "my-app-prod", ID: "arn:aws:elasticbeanstalk:us-east-1:123456789012:environment/my-app/my-app-prod", Source: types.InventoryByName},
+		{Type: "Elastic Beanstalk CNAME", Name: "my-app-prod.us-east-1.elasticbeanstalk.com", ID: "my-app-prod.us-east-1.elasticbeanstalk.com", Source: types.InventoryByName},
+		{Type: "Elastic Beanstalk environment", Name: "my-app-staging", ID: "arn:aws:elasticbeanstalk:us-east-1:123456789012:environment/my-app/my-app-staging", Source: types.InventoryByName},
+		{Type: "CloudFormation stack", Name: "cloud-deploy-my-app", ID: state.stack.StackId, Source: types.InventoryByName},
+		{Type: "AWS::IAM::Role", Name: cfnInstanceRoleID, ID: "cloud-deploy-my-app-InstanceRole-XYZ", Source: types.InventoryByName},
+		{Type: "ECR repository", Name: "my-app", ID: state.repositoryARN, Source: types.InventoryByName},
+		{Type: "S3 bucket", Name: "elasticbeanstalk-us-east-1-my-app", ID: "arn:aws:s3:::elasticbeanstalk-us-east-1-my-app", Source: types.InventoryByName},
+	}
+	if len(inventory.Resources) != len(want) {
+		t.Fatalf("got %d resources, want %d: %+v", len(inventory.Resources), len(want), inventory.Resources)
+	}
+	for i, r := range inventory.Resources {
+		if r != want[i] {
+			t.Errorf("resource %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// cwLogEvent is an event returned by CloudWatch Logs FilterLogEvents.
+type cwLogEvent struct {
+	EventID       string `json:"eventId"`
+	LogStreamName string `json:"logStreamName"`
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+}
+
+// Logs prints the application's container output from CloudWatch Logs.
+// Elastic Beanstalk only streams logs to CloudWatch when
+// monitoring.cloudwatch_logs.enabled is set in the manifest.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	m, err := p.liveManifest(ctx, m)
+	if err != nil {
+		return err
+	}
+	groups, err := p.applicationLogGroups(ctx, m.Environment.Name)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("no CloudWatch log groups found for environment %s; set monitoring.cloudwatch_logs.enabled in the manifest and redeploy", m.Environment.Name)
+	}
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var entries []types.LogEntry
+		for _, group := range groups {
+			events, err := p.filterLogEvents(ctx, group, since)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range events {
+				entries = append(entries, types.LogEntry{
+					ID:        e.EventID,
+					Timestamp: time.UnixMilli(e.Timestamp),
+					Source:    e.LogStreamName,
+					Message:   strings.TrimRight(e.Message, "\n"),
+				})
+			}
+		}
+		return entries, nil
+	})
+}
+
+// applicationLogGroups returns the log groups holding the container output of an
+// Elastic Beanstalk environment.
+func (p *Provider) applicationLogGroups(ctx context.Context, envName string) ([]string, error) {
+	var groups []string
+	input := map[string]interface{}{"logGroupNamePrefix": "/aws/elasticbeanstalk/" + envName + "/"}
+	for {
+		var out struct {
+			LogGroups []struct {
+				LogGroupName string `json:"logGroupName"`
+			} `json:"logGroups"`
+			NextToken string `json:"nextToken"`
+		}
+		if err := p.logsClient.JSON(ctx, "Logs_20140328.DescribeLogGroups", input, &out); err != nil {
+			return nil, fmt.Errorf("failed to list CloudWatch log groups: %w", err)
+		}
+		for _, g := range out.LogGroups {
+			if strings.Contains(g.LogGroupName, "/eb-docker/containers/") {
+				groups = append(groups, g.LogGroupName)
+			}
+		}
+		if out.NextToken == "" {
+			return groups, nil
+		}
+		input["nextToken"] = out.NextToken
+	}
+}
+
+// filterLogEvents returns the events in a log group written at or after since.
+func (p *Provider) filterLogEvents(ctx context.Context, group string, since time.Time) ([]cwLogEvent, error) {
+	var events []cwLogEvent
+	input := map[string]interface{}{
+		"logGroupName": group,
+		"startTime":    since.UnixMilli(),
+	}
+	for {
+		var out struct {
+			Events    []cwLogEvent `json:"events"`
+			NextToken string       `json:"nextToken"`
+		}
+		if err := p.logsClient.JSON(ctx, "Logs_20140328.FilterLogEvents", input, &out); err != nil {
+			return nil, fmt.Errorf("failed to read log group %s: %w", group, err)
+		}
+		events = append(events, out.Events...)
+		if out.NextToken == "" {
+			return events, nil
+		}
+		input["nextToken"] = out.NextToken
+	}
+}
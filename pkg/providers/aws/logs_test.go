@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func testLogsProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+	}
+	client := awsapi.New(cfg, "logs")
+	client.SetEndpoint(server.URL)
+	return &Provider{logsClient: client, region: "us-east-1", config: cfg}
+}
+
+func TestLogs(t *testing.T) {
+	now := time.Now().UnixMilli()
+	p := testLogsProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "Logs_20140328.DescribeLogGroups":
+			if body["logGroupNamePrefix"] != "/aws/elasticbeanstalk/my-env/" {
+				t.Errorf("Unexpected prefix %v", body["logGroupNamePrefix"])
+			}
+			w.Write([]byte(`{"logGroups":[
+{"logGroupName":"/aws/elasticbeanstalk/my-env/var/log/eb-engine.log"},
+{"logGroupName":"/aws/elasticbeanstalk/my-env/var/log/eb-docker/containers/eb-current-app/stdouterr.log"}]}`))
+		case "Logs_20140328.FilterLogEvents":
+			if body["logGroupName"] != "/aws/elasticbeanstalk/my-env/var/log/eb-docker/containers/eb-current-app/stdouterr.log" {
+				t.Errorf("Unexpected log group %v", body["logGroupName"])
+			}
+			if _, ok := body["nextToken"]; !ok {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"events":    []cwLogEvent{{EventID: "1", LogStreamName: "i-123", Timestamp: now - 2000, Message: "starting\n"}},
+					"nextToken": "page2",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"events": []cwLogEvent{{EventID: "2", LogStreamName: "i-123", Timestamp: now - 1000, Message: "listening"}},
+			})
+		default:
+			t.Errorf("Unexpected target %s", r.Header.Get("X-Amz-Target"))
+		}
+	})
+
+	var out bytes.Buffer
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-env"}}
+	if err := p.Logs(context.Background(), m, types.LogOptions{Since: time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[i-123] starting") || !strings.HasSuffix(lines[1], "[i-123] listening") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestLogsNoLogGroups(t *testing.T) {
+	p := testLogsProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"logGroups":[]}`))
+	})
+
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-env"}}
+	err := p.Logs(context.Background(), m, types.LogOptions{Output: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "cloudwatch_logs") {
+		t.Errorf("Expected hint to enable CloudWatch logs, got: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// elbAPIVersion is the version of the Elastic Load Balancing (v2) API.
+const elbAPIVersion = "2015-12-01"
+
+// maintenanceTag marks the listener rules created by the maintenance command.
+const maintenanceTag = "cloud-deploy:maintenance"
+
+// elbListener is a listener of a load balancer.
+type elbListener struct {
+	ARN  string `xml:"ListenerArn"`
+	Port int    `xml:"Port"`
+}
+
+// elbRule is a rule of a listener. The default rule has priority "default".
+type elbRule struct {
+	ARN      string `xml:"RuleArn"`
+	Priority string `xml:"Priority"`
+}
+
+// SetMaintenance serves the manifest's maintenance response from the
+// environment's Application Load Balancer, with a fixed-response rule ahead
+// of the rules forwarding to the instances on each listener, and deletes the
+// rules when on is false. The instances keep running, and deploys leave the
+// rules in place.
+func (p *Provider) SetMaintenance(ctx context.Context, m *manifest.Manifest, on bool) error {
+	lbARN, err := p.environmentLoadBalancer(ctx, m)
+	if err != nil {
+		return err
+	}
+	return p.setLoadBalancerMaintenance(ctx, lbARN, on, m.Maintenance)
+}
+
+// environmentLoadBalancer returns the ARN of the environment's Application
+// Load Balancer. Shared load balancers are refused, as a rule on them would
+// pause every environment using them.
+func (p *Provider) environmentLoadBalancer(ctx context.Context, m *manifest.Manifest) (string, error) {
+	m, err := p.liveManifest(ctx, m)
+	if err != nil {
+		return "", err
+	}
+	result, err := p.ebClient.DescribeEnvironmentResources(ctx, &elasticbeanstalk.DescribeEnvironmentResourcesInput{
+		EnvironmentName: aws.String(m.Environment.Name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe environment resources: %w", err)
+	}
+	if result.EnvironmentResources == nil || len(result.EnvironmentResources.LoadBalancers) == 0 {
+		return "", fmt.Errorf("environment %s has no load balancer; maintenance mode needs a LoadBalanced environment", m.Environment.Name)
+	}
+	lbARN := aws.ToString(result.EnvironmentResources.LoadBalancers[0].Name)
+	if !strings.Contains(lbARN, ":loadbalancer/app/") {
+		return "", fmt.Errorf("environment %s uses a Classic or Network Load Balancer; maintenance mode needs an Application Load Balancer", m.Environment.Name)
+	}
+
+	settings, err := p.ebClient.DescribeConfigurationSettings(ctx, &elasticbeanstalk.DescribeConfigurationSettingsInput{
+		ApplicationName: aws.String(m.Application.Name),
+		EnvironmentName: aws.String(m.Environment.Name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe environment configuration: %w", err)
+	}
+	for _, cs := range settings.ConfigurationSettings {
+		for _, o := range cs.OptionSettings {
+			if aws.ToString(o.Namespace) == "aws:elasticbeanstalk:environment" && aws.ToString(o.OptionName) == "LoadBalancerIsShared" && aws.ToString(o.Value) == "true" {
+				return "", fmt.Errorf("environment %s uses a shared load balancer; maintenance mode would pause every environment on it", m.Environment.Name)
+			}
+		}
+	}
+	return lbARN, nil
+}
+
+// setLoadBalancerMaintenance adds or removes the maintenance rule of each
+// listener of the load balancer. Listeners that already have the rule, or
+// have none to remove, are left as they are. Every listener is checked before
+// any rule is created, so a listener whose rules cannot be preceded leaves the
+// others unchanged.
+func (p *Provider) setLoadBalancerMaintenance(ctx context.Context, lbARN string, on bool, mc *manifest.MaintenanceConfig) error {
+	var listeners struct {
+		Listeners []elbListener `xml:"Listeners>member"`
+	}
+	if err := p.elbClient.Query(ctx, "DescribeListeners", elbAPIVersion, url.Values{"LoadBalancerArn": {lbARN}}, &listeners); err != nil {
+		return fmt.Errorf("failed to list listeners: %w", err)
+	}
+	if len(listeners.Listeners) == 0 {
+		return fmt.Errorf("load balancer %s has no listeners", lbARN)
+	}
+
+	ruleARNs := make([]string, len(listeners.Listeners))
+	for i, l := range listeners.Listeners {
+		rules, ruleARN, err := p.listenerRules(ctx, l.ARN)
+		if err != nil {
+			return err
+		}
+		if on && ruleARN == "" {
+			if err := checkMaintenancePriority(rules); err != nil {
+				return fmt.Errorf("listener on port %d: %w", l.Port, err)
+			}
+		}
+		ruleARNs[i] = ruleARN
+	}
+
+	for i, l := range listeners.Listeners {
+		switch {
+		case on && ruleARNs[i] == "":
+			logging.Info("Serving maintenance response", "port", l.Port)
+			if err := p.elbClient.Query(ctx, "CreateRule", elbAPIVersion, maintenanceRuleParams(l.ARN, mc), nil); err != nil {
+				return fmt.Errorf("failed to create maintenance rule on port %d: %w", l.Port, err)
+			}
+		case !on && ruleARNs[i] != "":
+			logging.Info("Removing maintenance response", "port", l.Port)
+			if err := p.elbClient.Query(ctx, "DeleteRule", elbAPIVersion, url.Values{"RuleArn": {ruleARNs[i]}}, nil); err != nil {
+				return fmt.Errorf("failed to delete maintenance rule on port %d: %w", l.Port, err)
+			}
+		}
+	}
+	return nil
+}
+
+// listenerRules returns the rules of a listener and the ARN of its
+// maintenance rule, or "" if it has none.
+func (p *Provider) listenerRules(ctx context.Context, listenerARN string) ([]elbRule, string, error) {
+	var result struct {
+		Rules []elbRule `xml:"Rules>member"`
+	}
+	if err := p.elbClient.Query(ctx, "DescribeRules", elbAPIVersion, url.Values{"ListenerArn": {listenerARN}}, &result); err != nil {
+		return nil, "", fmt.Errorf("failed to list listener rules: %w", err)
+	}
+
+	params := url.Values{}
+	for _, r := range result.Rules {
+		if r.Priority != "default" {
+			params.Add(fmt.Sprintf("ResourceArns.member.%d", len(params)+1), r.ARN)
+		}
+	}
+	if len(params) == 0 {
+		return result.Rules, "", nil
+	}
+	var tags struct {
+		Descriptions []struct {
+			ResourceARN string `xml:"ResourceArn"`
+			Tags        []struct {
+				Key string `xml:"Key"`
+			} `xml:"Tags>member"`
+		} `xml:"TagDescriptions>member"`
+	}
+	if err := p.elbClient.Query(ctx, "DescribeTags", elbAPIVersion, params, &tags); err != nil {
+		return nil, "", fmt.Errorf("failed to read listener rule tags: %w", err)
+	}
+	for _, d := range tags.Descriptions {
+		for _, t := range d.Tags {
+			if t.Key == maintenanceTag {
+				return result.Rules, d.ResourceARN, nil
+			}
+		}
+	}
+	return result.Rules, "", nil
+}
+
+// checkMaintenancePriority checks that a maintenance rule, which takes
+// priority 1 to be evaluated before every other rule of the listener, can be
+// added next to the listener's rules.
+func checkMaintenancePriority(rules []elbRule) error {
+	for _, r := range rules {
+		if r.Priority == "1" {
+			return fmt.Errorf("rule %s has priority 1, so a maintenance rule cannot take precedence over it", r.ARN)
+		}
+	}
+	return nil
+}
+
+// maintenanceRuleParams returns the CreateRule parameters of a rule answering
+// every request with the maintenance response.
+func maintenanceRuleParams(listenerARN string, mc *manifest.MaintenanceConfig) url.Values {
+	message, status := mc.Response()
+	// Fixed responses take the media type without parameters
+	contentType, _, _ := strings.Cut(mc.ContentType(), ";")
+	return url.Values{
+		"ListenerArn":               {listenerARN},
+		"Priority":                  {"1"},
+		"Conditions.member.1.Field": {"path-pattern"},
+		"Conditions.member.1.PathPatternConfig.Values.member.1": {"/*"},
+		"Actions.member.1.Type":                                 {"fixed-response"},
+		"Actions.member.1.FixedResponseConfig.StatusCode":       {strconv.Itoa(status)},
+		"Actions.member.1.FixedResponseConfig.ContentType":      {contentType},
+		"Actions.member.1.FixedResponseConfig.MessageBody":      {message},
+		"Tags.member.1.Key":                                     {maintenanceTag},
+		"Tags.member.1.Value":                                   {"true"},
+	}
+}
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeELB serves the listener and rule actions of one load balancer with two
+// listeners. tagged is the listener 443 rule carrying the maintenance tag.
+type fakeELB struct {
+	tagged  bool
+	created []string
+	deleted []string
+}
+
+func (f *fakeELB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	switch r.Form.Get("Action") {
+	case "DescribeListeners":
+		fmt.Fprint(w, `<DescribeListenersResponse><DescribeListenersResult><Listeners>
+<member><ListenerArn>listener-80</ListenerArn><Port>80</Port></member>
+<member><ListenerArn>listener-443</ListenerArn><Port>443</Port></member>
+</Listeners></DescribeListenersResult></DescribeListenersResponse>`)
+	case "DescribeRules":
+		rules := `<member><RuleArn>` + r.Form.Get("ListenerArn") + `-default</RuleArn><Priority>default</Priority></member>`
+		if r.Form.Get("ListenerArn") == "listener-443" {
+			rules += `<member><RuleArn>rule-443</RuleArn><Priority>1</Priority></member>`
+		}
+		fmt.Fprint(w, `<DescribeRulesResponse><DescribeRulesResult><Rules>`+rules+`</Rules></DescribeRulesResult></DescribeRulesResponse>`)
+	case "DescribeTags":
+		tags := ""
+		if f.tagged {
+			tags = `<member><Key>cloud-deploy:maintenance</Key><Value>true</Value></member>`
+		}
+		fmt.Fprint(w, `<DescribeTagsResponse><DescribeTagsResult><TagDescriptions><member><ResourceArn>`+
+			r.Form.Get("ResourceArns.member.1")+`</ResourceArn><Tags>`+tags+`</Tags></member></TagDescriptions></DescribeTagsResult></DescribeTagsResponse>`)
+	case "CreateRule":
+		f.created = append(f.created, r.Form.Get("ListenerArn")+" "+r.Form.Get("Priority")+" "+r.Form.Get("Actions.member.1.FixedResponseConfig.StatusCode"))
+		fmt.Fprint(w, `<CreateRuleResponse><CreateRuleResult/></CreateRuleResponse>`)
+	case "DeleteRule":
+		f.deleted = append(f.deleted, r.Form.Get("RuleArn"))
+		fmt.Fprint(w, `<DeleteRuleResponse><DeleteRuleResult/></DeleteRuleResponse>`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Code>InvalidAction</Code><Message>%s</Message></Error></ErrorResponse>`, r.Form.Get("Action"))
+	}
+}
+
+func testELBProvider(t *testing.T, handler http.Handler) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+	}
+	client := awsapi.New(cfg, "elasticloadbalancing")
+	client.SetEndpoint(server.URL)
+	return &Provider{elbClient: client, region: "us-east-1", config: cfg}
+}
+
+func TestSetLoadBalancerMaintenance(t *testing.T) {
+	elb := &fakeELB{tagged: true}
+	p := testELBProvider(t, elb)
+
+	// Listener 443 already has the maintenance rule, so only 80 gets one
+	if err := p.setLoadBalancerMaintenance(context.Background(), "lb", true, nil); err != nil {
+		t.Fatalf("setLoadBalancerMaintenance failed: %v", err)
+	}
+	if strings.Join(elb.created, ", ") != "listener-80 1 503" {
+		t.Errorf("Unexpected rules created: %v", elb.created)
+	}
+
+	if err := p.setLoadBalancerMaintenance(context.Background(), "lb", false, nil); err != nil {
+		t.Fatalf("setLoadBalancerMaintenance failed: %v", err)
+	}
+	if strings.Join(elb.deleted, ", ") != "rule-443" {
+		t.Errorf("Unexpected rules deleted: %v", elb.deleted)
+	}
+}
+
+func TestSetLoadBalancerMaintenanceTakenPriority(t *testing.T) {
+	elb := &fakeELB{}
+	p := testELBProvider(t, elb)
+	err := p.setLoadBalancerMaintenance(context.Background(), "lb", true, nil)
+	if err == nil || !strings.Contains(err.Error(), "listener on port 443: rule rule-443 has priority 1") {
+		t.Errorf("Expected the rule with priority 1 to be reported, got: %v", err)
+	}
+	if len(elb.created) != 0 {
+		t.Errorf("Expected no listener to be changed, got %v", elb.created)
+	}
+}
+
+func TestMaintenanceRuleParams(t *testing.T) {
+	params := maintenanceRuleParams("listener-80", &manifest.MaintenanceConfig{Message: "  <h1>Down</h1>"})
+	for k, want := range map[string]string{
+		"Priority":                  "1",
+		"Conditions.member.1.Field": "path-pattern",
+		"Actions.member.1.Type":     "fixed-response",
+		"Actions.member.1.FixedResponseConfig.ContentType": "text/html",
+		"Actions.member.1.FixedResponseConfig.StatusCode":  "503",
+		"Tags.member.1.Key": "cloud-deploy:maintenance",
+	} {
+		if got := params.Get(k); got != want {
+			t.Errorf("%s: expected %q, got %q", k, want, got)
+		}
+	}
+
+	if got := maintenanceRuleParams("listener-80", &manifest.MaintenanceConfig{Message: "Down", StatusCode: 200}).Get("Actions.member.1.FixedResponseConfig.ContentType"); got != "text/plain" {
+		t.Errorf("Expected plain text for a message without markup, got %q", got)
+	}
+}
@@ -0,0 +1,163 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// planState records which of a deployment's resources already exist.
+type planState struct {
+	application bool
+	stack       bool
+	repository  bool
+	bucket      bool
+	version     bool
+	environment bool
+
+	// Environments of a blue-green deployment serving traffic and beside it
+	liveEnvironment string
+	idleEnvironment string
+}
+
+// Plan returns the changes Deploy would make for the manifest without changing
+// any AWS resources.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	m = m.WithSidecars()
+	var state planState
+	app := m.Application.Name
+
+	apps, err := p.ebClient.DescribeApplications(ctx, &elasticbeanstalk.DescribeApplicationsInput{
+		ApplicationNames: []string{app},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe application: %w", err)
+	}
+	state.application = len(apps.Applications) > 0
+
+	if usesCloudFormation(m) {
+		stack, err := p.describeStack(ctx, ancillaryStackName(app))
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe ancillary resources stack: %w", err)
+		}
+		state.stack = stack != nil
+	}
+
+	ecrClient := ecr.NewFromConfig(p.config)
+	if _, err := ecrClient.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{m.RepositoryName()},
+	}); err == nil {
+		state.repository = true
+	}
+
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", p.region, app)
+	if _, err := p.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)}); err == nil {
+		state.bucket = true
+	}
+
+	if state.application {
+		versions, err := p.ebClient.DescribeApplicationVersions(ctx, &elasticbeanstalk.DescribeApplicationVersionsInput{
+			ApplicationName: aws.String(app),
+			VersionLabels:   []string{"latest"},
+		})
+		if err == nil && len(versions.ApplicationVersions) > 0 {
+			state.version = true
+		}
+
+		state.environment, err = p.environmentExists(ctx, app, m.Environment.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check environment: %w", err)
+		}
+		if m.Deployment.IsBlueGreen() {
+			live, idle, err := p.blueGreenEnvironments(ctx, m)
+			if err != nil {
+				return nil, err
+			}
+			if live != nil {
+				state.liveEnvironment = aws.ToString(live.EnvironmentName)
+			}
+			if idle != nil {
+				state.idleEnvironment = aws.ToString(idle.EnvironmentName)
+			}
+		}
+	}
+
+	return buildPlan(m, p.region, state), nil
+}
+
+// buildPlan returns the changes a deployment of m makes given the existing resources.
+func buildPlan(m *manifest.Manifest, region string, state planState) *types.Plan {
+	plan := &types.Plan{Provider: "aws"}
+	app := m.Application.Name
+	createOrKeep := func(exists bool, resourceType, name, missing string) {
+		if exists {
+			plan.Add(types.PlanNoChange, resourceType, name, "")
+		} else {
+			plan.Add(types.PlanCreate, resourceType, name, missing)
+		}
+	}
+
+	createOrKeep(state.application, "Elastic Beanstalk application", app, "application does not exist")
+
+	if usesCloudFormation(m) {
+		stack := ancillaryStackName(app)
+		if state.stack {
+			plan.Add(types.PlanUpdate, "CloudFormation stack", stack, "apply a change set for the ancillary resources")
+		} else {
+			plan.Add(types.PlanCreate, "CloudFormation stack", stack, "stack does not exist")
+		}
+	}
+
+	repository := m.RepositoryName()
+	createOrKeep(state.repository, "ECR repository", repository, "repository does not exist")
+	imageAction := types.PlanUpdate
+	if !state.repository {
+		imageAction = types.PlanCreate
+	}
+	if m.IsMultiContainer() {
+		for _, c := range m.Containers {
+			plan.Add(imageAction, "Container image", repository+":"+c.Name, "push "+c.Image)
+		}
+	} else {
+		plan.Add(imageAction, "Container image", repository+":"+m.VersionLabel("latest"), "push "+m.Image)
+	}
+
+	bucketName := fmt.Sprintf("elasticbeanstalk-%s-%s", region, app)
+	createOrKeep(state.bucket, "S3 bucket", bucketName, "bucket does not exist")
+
+	bundle := "upload Dockerrun.aws.json"
+	if m.IsMultiContainer() {
+		bundle = "upload docker-compose.yml"
+	}
+	if state.version {
+		plan.Add(types.PlanUpdate, "Application version", "latest", "replace the version with a new source bundle: "+bundle)
+	} else {
+		plan.Add(types.PlanCreate, "Application version", "latest", bundle)
+	}
+
+	if state.liveEnvironment != "" {
+		target := blueGreenTarget(m, state.liveEnvironment)
+		if state.idleEnvironment != "" {
+			plan.Add(types.PlanUpdate, "Elastic Beanstalk environment", target, "deploy application version latest, then swap CNAMEs with "+state.liveEnvironment)
+		} else {
+			plan.Add(types.PlanCreate, "Elastic Beanstalk environment", target, "parallel environment, then swap CNAMEs with "+state.liveEnvironment)
+		}
+		if m.Deployment.KeepOld() {
+			plan.Add(types.PlanNoChange, "Elastic Beanstalk environment", state.liveEnvironment, "kept after the swap")
+		} else {
+			plan.Add(types.PlanDelete, "Elastic Beanstalk environment", state.liveEnvironment, "terminated after the swap")
+		}
+	} else if state.environment {
+		plan.Add(types.PlanUpdate, "Elastic Beanstalk environment", m.Environment.Name, "deploy application version latest")
+	} else {
+		plan.Add(types.PlanCreate, "Elastic Beanstalk environment", m.Environment.Name, "environment does not exist")
+	}
+	return plan
+}
@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func planManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Image:       "my-app:1.2.0",
+	}
+}
+
+func changeActions(plan *types.Plan) map[string]types.PlanAction {
+	actions := make(map[string]types.PlanAction)
+	for _, c := range plan.Changes {
+		actions[c.Type+" "+c.Name] = c.Action
+	}
+	return actions
+}
+
+func TestBuildPlanFirstDeploy(t *testing.T) {
+	plan := buildPlan(planManifest(), "us-east-1", planState{})
+
+	if plan.Provider != "aws" {
+		t.Errorf("Provider = %q, want aws", plan.Provider)
+	}
+	if n := plan.Count(types.PlanCreate); n != len(plan.Changes) {
+		t.Errorf("got %d creates out of %d changes on first deploy: %+v", n, len(plan.Changes), plan.Changes)
+	}
+	actions := changeActions(plan)
+	for _, key := range []string{
+		"Elastic Beanstalk application my-app",
+		"ECR repository my-app",
+		"Container image my-app:latest",
+		"S3 bucket elasticbeanstalk-us-east-1-my-app",
+		"Application version latest",
+		"Elastic Beanstalk environment my-app-prod",
+	} {
+		if _, ok := actions[key]; !ok {
+			t.Errorf("plan is missing %q: %+v", key, plan.Changes)
+		}
+	}
+	if _, ok := actions["CloudFormation stack cloud-deploy-my-app"]; ok {
+		t.Error("plan includes a stack without aws.cloudformation")
+	}
+}
+
+func TestBuildPlanRedeploy(t *testing.T) {
+	state := planState{application: true, repository: true, bucket: true, version: true, environment: true}
+	plan := buildPlan(planManifest(), "us-east-1", state)
+
+	actions := changeActions(plan)
+	for key, want := range map[string]types.PlanAction{
+		"Elastic Beanstalk application my-app":        types.PlanNoChange,
+		"ECR repository my-app":                       types.PlanNoChange,
+		"Container image my-app:latest":               types.PlanUpdate,
+		"S3 bucket elasticbeanstalk-us-east-1-my-app": types.PlanNoChange,
+		"Application version latest":                  types.PlanUpdate,
+		"Elastic Beanstalk environment my-app-prod":   types.PlanUpdate,
+	} {
+		if actions[key] != want {
+			t.Errorf("%s: got %q, want %q", key, actions[key], want)
+		}
+	}
+}
+
+func TestBuildPlanCloudFormationAndMultiContainer(t *testing.T) {
+	m := planManifest()
+	m.AWS = &manifest.AWSConfig{CloudFormation: true}
+	m.Image = ""
+	m.Containers = []manifest.Container{
+		{Name: "web", Image: "web:1"},
+		{Name: "worker", Image: "worker:1"},
+	}
+	plan := buildPlan(m, "us-east-1", planState{stack: true})
+
+	actions := changeActions(plan)
+	if actions["CloudFormation stack cloud-deploy-my-app"] != types.PlanUpdate {
+		t.Errorf("stack action = %q, want update", actions["CloudFormation stack cloud-deploy-my-app"])
+	}
+	for _, key := range []string{"Container image my-app:web", "Container image my-app:worker"} {
+		if actions[key] != types.PlanCreate {
+			t.Errorf("%s: got %q, want create", key, actions[key])
+		}
+	}
+}
+
+func TestBuildPlanBlueGreen(t *testing.T) {
+	m := planManifest()
+	m.Deployment.Strategy = manifest.StrategyBlueGreen
+	state := planState{application: true, repository: true, bucket: true, version: true, environment: true, liveEnvironment: "my-app-prod"}
+
+	plan := buildPlan(m, "us-east-1", state)
+	var envChanges []types.ResourceChange
+	for _, c := range plan.Changes {
+		if c.Type == "Elastic Beanstalk environment" {
+			envChanges = append(envChanges, c)
+		}
+	}
+	if len(envChanges) != 2 {
+		t.Fatalf("Expected two environment changes, got %+v", envChanges)
+	}
+	if c := envChanges[0]; c.Action != types.PlanCreate || c.Name != "my-app-prod-green" {
+		t.Errorf("Expected the parallel environment to be created, got %+v", c)
+	}
+	if c := envChanges[1]; c.Action != types.PlanDelete || c.Name != "my-app-prod" {
+		t.Errorf("Expected the live environment to be terminated, got %+v", c)
+	}
+
+	m.Deployment.BlueGreen = &manifest.BlueGreenConfig{KeepOld: true}
+	state.liveEnvironment, state.idleEnvironment = "my-app-prod-green", "my-app-prod"
+	actions := changeActions(buildPlan(m, "us-east-1", state))
+	if actions["Elastic Beanstalk environment my-app-prod"] != types.PlanUpdate || actions["Elastic Beanstalk environment my-app-prod-green"] != types.PlanNoChange {
+		t.Errorf("Expected the idle environment to be updated and the live one kept, got %v", actions)
+	}
+}
@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// accessKeyMaxAge is the age after which an access key should be rotated.
+const accessKeyMaxAge = 90 * 24 * time.Hour
+
+// Posture checks the credentials the provider authenticates with and the
+// account's Elastic Beanstalk quotas in the region. AWS has no API for the
+// billing status of an account, so it is not checked.
+func (p *Provider) Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error) {
+	posture := &types.Posture{Provider: "aws"}
+	now := time.Now()
+
+	// Nothing else can be checked without credentials
+	creds, err := p.config.Credentials.Retrieve(ctx)
+	if err != nil {
+		posture.Add(types.PostureCredentials, "AWS credentials", types.PostureFailing, err.Error())
+		return posture, nil
+	}
+	if creds.CanExpire {
+		posture.AddExpiry("Session credentials", creds.Expires, now)
+	} else {
+		created, err := p.accessKeyCreated(ctx, creds.AccessKeyID)
+		if err != nil {
+			posture.Add(types.PostureCredentials, "Access key "+creds.AccessKeyID, types.PostureWarning, "age unknown: "+err.Error())
+		} else {
+			addAccessKeyAge(posture, creds.AccessKeyID, created, now)
+		}
+	}
+
+	attrs, err := p.ebClient.DescribeAccountAttributes(ctx, &elasticbeanstalk.DescribeAccountAttributesInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe Elastic Beanstalk account attributes: %w", err)
+	}
+	apps, err := p.ebClient.DescribeApplications(ctx, &elasticbeanstalk.DescribeApplicationsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe applications: %w", err)
+	}
+	versions, err := p.ebClient.DescribeApplicationVersions(ctx, &elasticbeanstalk.DescribeApplicationVersionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe application versions: %w", err)
+	}
+	envs, err := p.ebClient.DescribeEnvironments(ctx, &elasticbeanstalk.DescribeEnvironmentsInput{
+		IncludeDeleted: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe environments: %w", err)
+	}
+	addQuotas(posture, p.region, attrs.ResourceQuotas, len(apps.Applications), len(versions.ApplicationVersions), len(envs.Environments))
+	return posture, nil
+}
+
+// addQuotas adds the Elastic Beanstalk quotas a deployment uses, skipping
+// those the account does not report.
+func addQuotas(posture *types.Posture, region string, quotas *ebtypes.ResourceQuotas, apps, versions, envs int) {
+	if quotas == nil {
+		return
+	}
+	for _, q := range []struct {
+		name  string
+		quota *ebtypes.ResourceQuota
+		used  int
+	}{
+		{"Elastic Beanstalk applications", quotas.ApplicationQuota, apps},
+		{"Elastic Beanstalk application versions", quotas.ApplicationVersionQuota, versions},
+		{"Elastic Beanstalk environments", quotas.EnvironmentQuota, envs},
+	} {
+		if q.quota == nil || q.quota.Maximum == nil {
+			continue
+		}
+		posture.AddQuota(fmt.Sprintf("%s (%s)", q.name, region), int64(q.used), int64(*q.quota.Maximum))
+	}
+}
+
+// addAccessKeyAge adds a check warning when a long-lived access key is older
+// than accessKeyMaxAge.
+func addAccessKeyAge(posture *types.Posture, accessKeyID string, created, now time.Time) {
+	days := int(now.Sub(created).Hours() / 24)
+	detail := fmt.Sprintf("created %s (%d days ago)", created.UTC().Format(time.DateOnly), days)
+	status := types.PostureOK
+	if now.Sub(created) > accessKeyMaxAge {
+		status = types.PostureWarning
+		detail += "; rotate it with cloud-deploy credentials-rotate"
+	}
+	posture.Add(types.PostureCredentials, "Access key "+accessKeyID, status, detail)
+}
+
+// accessKeyCreated returns when the caller's access key was created. Only IAM
+// users have access keys listed this way; roles fail with an error.
+func (p *Provider) accessKeyCreated(ctx context.Context, accessKeyID string) (time.Time, error) {
+	cfg := p.config.Copy()
+	cfg.Region = "us-east-1"
+	client := awsapi.New(cfg, "iam")
+	if cfg.BaseEndpoint == nil {
+		client.SetEndpoint(iamEndpoint)
+	}
+
+	var result struct {
+		Keys []struct {
+			AccessKeyID string    `xml:"AccessKeyId"`
+			CreateDate  time.Time `xml:"CreateDate"`
+		} `xml:"AccessKeyMetadata>member"`
+	}
+	if err := client.Query(ctx, "ListAccessKeys", iamAPIVersion, url.Values{}, &result); err != nil {
+		return time.Time{}, fmt.Errorf("failed to list access keys: %w", err)
+	}
+	for _, k := range result.Keys {
+		if k.AccessKeyID == accessKeyID {
+			return k.CreateDate, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("access key %s is not listed for the caller", accessKeyID)
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscreds "github.com/aws/aws-sdk-go-v2/credentials"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestAddQuotas(t *testing.T) {
+	posture := &types.Posture{}
+	quotas := &ebtypes.ResourceQuotas{
+		ApplicationQuota:        &ebtypes.ResourceQuota{Maximum: aws.Int32(75)},
+		ApplicationVersionQuota: &ebtypes.ResourceQuota{Maximum: aws.Int32(1000)},
+		EnvironmentQuota:        &ebtypes.ResourceQuota{},
+	}
+	addQuotas(posture, "us-east-1", quotas, 3, 950, 4)
+
+	if len(posture.Checks) != 2 {
+		t.Fatalf("Expected quotas without a maximum to be skipped, got %+v", posture.Checks)
+	}
+	if c := posture.Checks[0]; c.Name != "Elastic Beanstalk applications (us-east-1)" || c.Status != types.PostureOK || c.Detail != "3 of 75 used" {
+		t.Errorf("Unexpected applications check: %+v", c)
+	}
+	if c := posture.Checks[1]; c.Status != types.PostureWarning || c.Detail != "950 of 1000 used" {
+		t.Errorf("Unexpected application versions check: %+v", c)
+	}
+
+	addQuotas(posture, "us-east-1", nil, 0, 0, 0)
+	if len(posture.Checks) != 2 {
+		t.Error("Expected no checks without quotas")
+	}
+}
+
+func TestAddAccessKeyAge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	posture := &types.Posture{}
+	addAccessKeyAge(posture, "AKIAOLD", now.Add(-100*24*time.Hour), now)
+	addAccessKeyAge(posture, "AKIANEW", now.Add(-10*24*time.Hour), now)
+
+	old, recent := posture.Checks[0], posture.Checks[1]
+	if old.Status != types.PostureWarning || !strings.Contains(old.Detail, "100 days ago") || !strings.Contains(old.Detail, "credentials-rotate") {
+		t.Errorf("Unexpected check for old key: %+v", old)
+	}
+	if recent.Status != types.PostureOK || recent.Detail != "created 2025-05-22 (10 days ago)" {
+		t.Errorf("Unexpected check for recent key: %+v", recent)
+	}
+}
+
+func TestAccessKeyCreated(t *testing.T) {
+	testIAMServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "ListAccessKeys" {
+			t.Errorf("Unexpected action %q", r.Form.Get("Action"))
+		}
+		w.Write([]byte(`<ListAccessKeysResponse><ListAccessKeysResult><AccessKeyMetadata>
+<member><UserName>deployer</UserName><AccessKeyId>AKIAOTHER</AccessKeyId><CreateDate>2024-01-01T00:00:00Z</CreateDate></member>
+<member><UserName>deployer</UserName><AccessKeyId>AKIAOLD</AccessKeyId><CreateDate>2025-02-03T04:05:06Z</CreateDate></member>
+</AccessKeyMetadata></ListAccessKeysResult></ListAccessKeysResponse>`))
+	})
+
+	p := &Provider{region: "us-west-2", config: aws.Config{
+		Region:      "us-west-2",
+		Credentials: awscreds.NewStaticCredentialsProvider("AKIAOLD", "secret", ""),
+	}}
+
+	created, err := p.accessKeyCreated(context.Background(), "AKIAOLD")
+	if err != nil {
+		t.Fatalf("accessKeyCreated failed: %v", err)
+	}
+	if want := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC); !created.Equal(want) {
+		t.Errorf("Expected %s, got %s", want, created)
+	}
+
+	if _, err := p.accessKeyCreated(context.Background(), "AKIAMISSING"); err == nil {
+		t.Error("Expected an error for a key that is not listed")
+	}
+}
@@ -0,0 +1,74 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+var (
+	// instanceIDPattern matches an EC2 instance ID in an event message.
+	instanceIDPattern = regexp.MustCompile(`\bi-[0-9a-f]{8,17}\b`)
+
+	// replacementPattern matches event messages about instances taken out of
+	// service by Elastic Beanstalk or the Auto Scaling group. Instances added
+	// or removed by scaling alone are not remediation.
+	replacementPattern = regexp.MustCompile(`(?i)unhealthy|health check|terminat|replac`)
+)
+
+// RemediationEvents returns the instances Elastic Beanstalk or the
+// environment's Auto Scaling group replaced since the given time, from the
+// environment's events.
+func (p *Provider) RemediationEvents(ctx context.Context, m *manifest.Manifest, since time.Time) ([]types.RemediationEvent, error) {
+	envName := m.Environment.Name
+	var events []types.RemediationEvent
+	input := &elasticbeanstalk.DescribeEventsInput{
+		ApplicationName: aws.String(m.Application.Name),
+		EnvironmentName: aws.String(envName),
+		StartTime:       aws.Time(since),
+	}
+	for {
+		out, err := p.ebClient.DescribeEvents(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe events of environment %s: %w", envName, err)
+		}
+		for _, e := range out.Events {
+			if event, ok := remediationEvent(e); ok {
+				events = append(events, event)
+			}
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	// Elastic Beanstalk returns the newest events first
+	slices.Reverse(events)
+	return events, nil
+}
+
+// remediationEvent converts an environment event about an instance being
+// replaced. Other events are skipped.
+func remediationEvent(e ebtypes.EventDescription) (types.RemediationEvent, bool) {
+	message := aws.ToString(e.Message)
+	instanceID := instanceIDPattern.FindString(message)
+	if instanceID == "" || !replacementPattern.MatchString(message) {
+		return types.RemediationEvent{}, false
+	}
+	return types.RemediationEvent{
+		Timestamp: aws.ToTime(e.EventDate),
+		Kind:      types.RemediationInstanceReplaced,
+		Source:    instanceID,
+		Message:   message,
+	}, true
+}
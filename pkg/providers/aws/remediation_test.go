@@ -0,0 +1,40 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestRemediationEvent(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		message    string
+		wantOK     bool
+		wantSource string
+	}{
+		{"Instance i-0123456789abcdef0 failed health checks and is being replaced.", true, "i-0123456789abcdef0"},
+		{"EC2 instance(s) [i-0abc1234] terminated by the Auto Scaling group.", true, "i-0abc1234"},
+		{"Added instance [i-0abc1234] to your environment.", false, ""},
+		{"Removed instance [i-0abc1234] from your environment.", false, ""},
+		{"Environment health has transitioned from Ok to Severe.", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			event, ok := remediationEvent(ebtypes.EventDescription{EventDate: aws.Time(ts), Message: aws.String(tt.message)})
+			if ok != tt.wantOK {
+				t.Fatalf("remediationEvent ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if event.Source != tt.wantSource || event.Kind != types.RemediationInstanceReplaced || !event.Timestamp.Equal(ts) || event.Message != tt.message {
+				t.Errorf("Unexpected event: %+v", event)
+			}
+		})
+	}
+}
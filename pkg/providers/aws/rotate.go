@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscreds "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const iamAPIVersion = "2010-05-08"
+
+// iamEndpoint is the global IAM endpoint; IAM requests are signed for us-east-1.
+var iamEndpoint = "https://iam.amazonaws.com"
+
+// iamAccessKey is the AccessKey element of a CreateAccessKey response.
+type iamAccessKey struct {
+	UserName        string `xml:"UserName"`
+	AccessKeyID     string `xml:"AccessKeyId"`
+	SecretAccessKey string `xml:"SecretAccessKey"`
+	Status          string `xml:"Status"`
+}
+
+// CreateCredentials creates a new access key for the IAM user that owns the
+// current access key. IAM allows two access keys per user, so the user must not
+// already have a second key.
+func (p *Provider) CreateCredentials(ctx context.Context, m *manifest.Manifest, current *credentials.ProviderCredentials) (*credentials.ProviderCredentials, error) {
+	if current.AWS.SessionToken != "" {
+		return nil, fmt.Errorf("temporary (session token) credentials cannot be rotated; store an IAM user access key instead")
+	}
+
+	var result struct {
+		AccessKey iamAccessKey `xml:"AccessKey"`
+	}
+	if err := p.iamClient(current).Query(ctx, "CreateAccessKey", iamAPIVersion, url.Values{}, &result); err != nil {
+		if awsapi.IsErrorCode(err, "LimitExceeded") {
+			return nil, fmt.Errorf("IAM user already has two access keys; delete the unused one before rotating: %w", err)
+		}
+		return nil, fmt.Errorf("failed to create access key: %w", err)
+	}
+	logging.Info("Created access key", "user", result.AccessKey.UserName, "access_key_id", result.AccessKey.AccessKeyID)
+
+	rotated := *current
+	rotated.AWS.AccessKeyID = result.AccessKey.AccessKeyID
+	rotated.AWS.SecretAccessKey = result.AccessKey.SecretAccessKey
+	return &rotated, nil
+}
+
+// VerifyCredentials checks that creds can call STS GetCallerIdentity.
+func (p *Provider) VerifyCredentials(ctx context.Context, m *manifest.Manifest, creds *credentials.ProviderCredentials) error {
+	client := sts.NewFromConfig(p.configFor(creds, p.region))
+	return credentials.WaitUntilValid(ctx, func(ctx context.Context) error {
+		_, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		return err
+	})
+}
+
+// RevokeCredentials deactivates the revoked access key. The key is made
+// inactive rather than deleted, so it can be re-enabled if something still
+// depends on it.
+func (p *Provider) RevokeCredentials(ctx context.Context, m *manifest.Manifest, active, revoked *credentials.ProviderCredentials) error {
+	params := url.Values{}
+	params.Set("AccessKeyId", revoked.AWS.AccessKeyID)
+	params.Set("Status", "Inactive")
+
+	if err := p.iamClient(active).Query(ctx, "UpdateAccessKey", iamAPIVersion, params, nil); err != nil {
+		return fmt.Errorf("failed to deactivate access key %s: %w", revoked.AWS.AccessKeyID, err)
+	}
+	logging.Info("Deactivated access key", "access_key_id", revoked.AWS.AccessKeyID)
+	return nil
+}
+
+// iamClient returns an IAM client that authenticates as creds.
+func (p *Provider) iamClient(creds *credentials.ProviderCredentials) *awsapi.Client {
+	client := awsapi.New(p.configFor(creds, "us-east-1"), "iam")
+	if p.config.BaseEndpoint == nil {
+		// Emulators serve IAM at the same endpoint as every other service
+		client.SetEndpoint(iamEndpoint)
+	}
+	return client
+}
+
+// configFor returns a copy of the provider's AWS config that authenticates as creds.
+func (p *Provider) configFor(creds *credentials.ProviderCredentials, region string) aws.Config {
+	cfg := p.config.Copy()
+	cfg.Region = region
+	cfg.Credentials = aws.NewCredentialsCache(awscreds.NewStaticCredentialsProvider(
+		creds.AWS.AccessKeyID,
+		creds.AWS.SecretAccessKey,
+		creds.AWS.SessionToken,
+	))
+	return cfg
+}
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func testIAMServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	original := iamEndpoint
+	iamEndpoint = server.URL
+	t.Cleanup(func() {
+		iamEndpoint = original
+		server.Close()
+	})
+}
+
+func currentAWSCredentials() *credentials.ProviderCredentials {
+	creds := &credentials.ProviderCredentials{}
+	creds.AWS.AccessKeyID = "AKIAOLD"
+	creds.AWS.SecretAccessKey = "old-secret"
+	creds.Azure.TenantID = "untouched"
+	return creds
+}
+
+func TestCreateCredentials(t *testing.T) {
+	testIAMServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "CreateAccessKey" {
+			t.Errorf("Unexpected action %q", r.Form.Get("Action"))
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKIAOLD/") {
+			t.Errorf("Expected request signed with current key, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`<CreateAccessKeyResponse><CreateAccessKeyResult><AccessKey>
+<UserName>deployer</UserName><AccessKeyId>AKIANEW</AccessKeyId><Status>Active</Status><SecretAccessKey>new-secret</SecretAccessKey>
+</AccessKey></CreateAccessKeyResult></CreateAccessKeyResponse>`))
+	})
+
+	p := &Provider{region: "us-west-2", config: aws.Config{Region: "us-west-2"}}
+	current := currentAWSCredentials()
+
+	rotated, err := p.CreateCredentials(context.Background(), &manifest.Manifest{}, current)
+	if err != nil {
+		t.Fatalf("CreateCredentials failed: %v", err)
+	}
+	if rotated.AWS.AccessKeyID != "AKIANEW" || rotated.AWS.SecretAccessKey != "new-secret" {
+		t.Errorf("Unexpected rotated credentials: %+v", rotated.AWS)
+	}
+	if rotated.Azure.TenantID != "untouched" {
+		t.Error("Expected other providers' credentials to be preserved")
+	}
+	if current.AWS.AccessKeyID != "AKIAOLD" {
+		t.Error("Expected current credentials to be left unchanged")
+	}
+}
+
+func TestCreateCredentialsLimitExceeded(t *testing.T) {
+	testIAMServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`<ErrorResponse><Error><Code>LimitExceeded</Code><Message>Cannot exceed quota for AccessKeysPerUser: 2</Message></Error></ErrorResponse>`))
+	})
+
+	p := &Provider{region: "us-west-2", config: aws.Config{Region: "us-west-2"}}
+	_, err := p.CreateCredentials(context.Background(), &manifest.Manifest{}, currentAWSCredentials())
+	if err == nil || !strings.Contains(err.Error(), "two access keys") {
+		t.Errorf("Expected access key limit error, got: %v", err)
+	}
+}
+
+func TestCreateCredentialsSessionToken(t *testing.T) {
+	p := &Provider{region: "us-west-2"}
+	current := currentAWSCredentials()
+	current.AWS.SessionToken = "token"
+
+	_, err := p.CreateCredentials(context.Background(), &manifest.Manifest{}, current)
+	if err == nil || !strings.Contains(err.Error(), "temporary") {
+		t.Errorf("Expected temporary credentials error, got: %v", err)
+	}
+}
+
+func TestRevokeCredentials(t *testing.T) {
+	testIAMServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "UpdateAccessKey" || r.Form.Get("AccessKeyId") != "AKIAOLD" || r.Form.Get("Status") != "Inactive" {
+			t.Errorf("Unexpected request: %v", r.Form)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKIANEW/") {
+			t.Errorf("Expected request signed with active key, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`<UpdateAccessKeyResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></UpdateAccessKeyResponse>`))
+	})
+
+	p := &Provider{region: "us-west-2", config: aws.Config{Region: "us-west-2"}}
+	active := currentAWSCredentials()
+	active.AWS.AccessKeyID = "AKIANEW"
+
+	if err := p.RevokeCredentials(context.Background(), &manifest.Manifest{}, active, currentAWSCredentials()); err != nil {
+		t.Fatalf("RevokeCredentials failed: %v", err)
+	}
+}
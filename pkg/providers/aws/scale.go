@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// asgNamespace is the option namespace of an environment's Auto Scaling group.
+const asgNamespace = "aws:autoscaling:asg"
+
+// Scale sets the minimum and maximum size of the environment's Auto Scaling group
+// from instance.min_instances and instance.max_instances. Only the group is
+// updated; no new application version is deployed.
+func (p *Provider) Scale(ctx context.Context, m *manifest.Manifest) error {
+	if m.Instance.EnvironmentType == "SingleInstance" {
+		return fmt.Errorf("environment %s is a SingleInstance environment, which always runs one instance (use environment_type LoadBalanced to scale)", m.Environment.Name)
+	}
+	settings := scalingSettings(m)
+	if len(settings) == 0 {
+		return fmt.Errorf("no instance counts to scale to: set -min and -max, or instance.min_instances and instance.max_instances")
+	}
+	m, err := p.liveManifest(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Scaling environment", "environment", m.Environment.Name, "min", m.Instance.MinInstances, "max", m.Instance.MaxInstances)
+	_, err = p.ebClient.UpdateEnvironment(ctx, &elasticbeanstalk.UpdateEnvironmentInput{
+		ApplicationName: aws.String(m.Application.Name),
+		EnvironmentName: aws.String(m.Environment.Name),
+		OptionSettings:  settings,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update environment: %w", err)
+	}
+
+	if _, err := p.waitForEnvironment(ctx, m.Application.Name, m.Environment.Name); err != nil {
+		return fmt.Errorf("environment update failed: %w", err)
+	}
+	return nil
+}
+
+// scalingSettings returns the Auto Scaling group sizes set in the manifest.
+// Elastic Beanstalk's defaults apply to the ones that are not set.
+func scalingSettings(m *manifest.Manifest) []ebtypes.ConfigurationOptionSetting {
+	var settings []ebtypes.ConfigurationOptionSetting
+	if m.Instance.MinInstances > 0 {
+		settings = append(settings, ebtypes.ConfigurationOptionSetting{
+			Namespace:  aws.String(asgNamespace),
+			OptionName: aws.String("MinSize"),
+			Value:      aws.String(fmt.Sprintf("%d", m.Instance.MinInstances)),
+		})
+	}
+	if m.Instance.MaxInstances > 0 {
+		settings = append(settings, ebtypes.ConfigurationOptionSetting{
+			Namespace:  aws.String(asgNamespace),
+			OptionName: aws.String("MaxSize"),
+			Value:      aws.String(fmt.Sprintf("%d", m.Instance.MaxInstances)),
+		})
+	}
+	return settings
+}
@@ -0,0 +1,72 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestScalingSettings(t *testing.T) {
+	m := &manifest.Manifest{Instance: manifest.InstanceConfig{MinInstances: 2, MaxInstances: 10}}
+	settings := scalingSettings(m)
+	if len(settings) != 2 {
+		t.Fatalf("Expected MinSize and MaxSize, got %d settings", len(settings))
+	}
+	got := map[string]string{}
+	for _, s := range settings {
+		if aws.ToString(s.Namespace) != asgNamespace {
+			t.Errorf("Unexpected namespace %s", aws.ToString(s.Namespace))
+		}
+		got[aws.ToString(s.OptionName)] = aws.ToString(s.Value)
+	}
+	if got["MinSize"] != "2" || got["MaxSize"] != "10" {
+		t.Errorf("Unexpected settings: %v", got)
+	}
+
+	m.Instance = manifest.InstanceConfig{MaxInstances: 3}
+	if settings := scalingSettings(m); len(settings) != 1 || aws.ToString(settings[0].OptionName) != "MaxSize" {
+		t.Errorf("Expected only MaxSize, got %+v", settings)
+	}
+}
+
+func TestBuildOptionSettingsInstanceCounts(t *testing.T) {
+	provider := &Provider{}
+	m := &manifest.Manifest{Instance: manifest.InstanceConfig{Type: "t3.small", EnvironmentType: "LoadBalanced", MinInstances: 2, MaxInstances: 6}}
+
+	hasASG := func(m *manifest.Manifest) bool {
+		for _, s := range provider.buildOptionSettings(m) {
+			if aws.ToString(s.Namespace) == asgNamespace {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasASG(m) {
+		t.Error("Expected Auto Scaling group sizes for a LoadBalanced environment")
+	}
+	m.Instance.EnvironmentType = "SingleInstance"
+	if hasASG(m) {
+		t.Error("Expected no Auto Scaling group sizes for a SingleInstance environment")
+	}
+}
+
+func TestScaleErrors(t *testing.T) {
+	provider := &Provider{}
+
+	m := &manifest.Manifest{
+		Environment: manifest.EnvironmentConfig{Name: "my-env"},
+		Instance:    manifest.InstanceConfig{EnvironmentType: "SingleInstance", MaxInstances: 4},
+	}
+	if err := provider.Scale(context.Background(), m); err == nil || !strings.Contains(err.Error(), "SingleInstance") {
+		t.Errorf("Expected SingleInstance environments to refuse scaling, got: %v", err)
+	}
+
+	m.Instance = manifest.InstanceConfig{EnvironmentType: "LoadBalanced"}
+	if err := provider.Scale(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no instance counts") {
+		t.Errorf("Expected an error without instance counts, got: %v", err)
+	}
+}
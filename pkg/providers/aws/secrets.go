@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// environmentSecretsNamespace is the option namespace of Elastic Beanstalk
+// environment secrets: environment variables the instances read from Secrets
+// Manager, so their values never appear in the environment's configuration.
+const environmentSecretsNamespace = "aws:elasticbeanstalk:application:environmentsecrets"
+
+// secretSettings returns the environment secrets referencing the Secrets
+// Manager secrets of a manifest with secret_delivery native, or nil for other
+// manifests. Environment secrets take the secrets' full ARNs.
+func (p *Provider) secretSettings(ctx context.Context, m *manifest.Manifest) ([]ebtypes.ConfigurationOptionSetting, error) {
+	if !m.NativeSecrets() {
+		return nil, nil
+	}
+	arns := make(map[string]string)
+	settings := make([]ebtypes.ConfigurationOptionSetting, 0, len(m.Secrets))
+	for _, ref := range m.Secrets {
+		region := ref.Region
+		if region == "" {
+			region = p.region
+		}
+		key := region + "|" + ref.SecretID
+		arn, ok := arns[key]
+		if !ok {
+			var err error
+			if arn, err = p.secretARN(ctx, ref.SecretID, region); err != nil {
+				return nil, fmt.Errorf("failed to reference secret %s: %w", ref.Name, err)
+			}
+			arns[key] = arn
+		}
+		settings = append(settings, ebtypes.ConfigurationOptionSetting{
+			Namespace:  aws.String(environmentSecretsNamespace),
+			OptionName: aws.String(ref.Name),
+			Value:      aws.String(arn),
+		})
+	}
+	return settings, nil
+}
+
+// secretARN returns the full ARN of the Secrets Manager secret secretID, a
+// name or an ARN, in region.
+func (p *Provider) secretARN(ctx context.Context, secretID, region string) (string, error) {
+	client := p.secretsClient
+	if client == nil || region != p.region {
+		cfg := p.config.Copy()
+		cfg.Region = region
+		client = awsapi.New(cfg, "secretsmanager")
+	}
+	var out struct {
+		ARN string `json:"ARN"`
+	}
+	if err := client.JSON(ctx, "secretsmanager.DescribeSecret", map[string]string{"SecretId": secretID}, &out); err != nil {
+		return "", fmt.Errorf("failed to describe secret %s: %w", secretID, err)
+	}
+	return out.ARN, nil
+}
+
+// plainSecretOptions returns the environment properties to remove when a
+// manifest with secret_delivery native is deployed, so secrets a previous
+// deployment set as plain properties do not stay in the configuration.
+func plainSecretOptions(m *manifest.Manifest) []ebtypes.OptionSpecification {
+	if !m.NativeSecrets() {
+		return nil
+	}
+	options := make([]ebtypes.OptionSpecification, 0, len(m.Secrets))
+	for _, ref := range m.Secrets {
+		options = append(options, ebtypes.OptionSpecification{
+			Namespace:  aws.String(environmentNamespace),
+			OptionName: aws.String(ref.Name),
+		})
+	}
+	return options
+}
@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func nativeSecretsManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Instance:             manifest.InstanceConfig{Type: "t3.micro", EnvironmentType: "SingleInstance"},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info", "DB_PASSWORD": "secret", "API_KEY": "key"},
+		Secrets: []manifest.SecretRef{
+			{Name: "DB_PASSWORD", SecretID: "prod/db"},
+			{Name: "API_KEY", SecretID: "prod/db"},
+		},
+		SecretDelivery: manifest.SecretDeliveryNative,
+	}
+}
+
+func TestSecretSettings(t *testing.T) {
+	describes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.DescribeSecret" || in["SecretId"] != "prod/db" {
+			t.Errorf("Unexpected request %s %v", r.Header.Get("X-Amz-Target"), in)
+		}
+		describes++
+		w.Write([]byte(`{"ARN":"arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db-AbCdEf"}`))
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+	}
+	client := awsapi.New(cfg, "secretsmanager")
+	client.SetEndpoint(server.URL)
+	p := &Provider{secretsClient: client, region: "us-east-1", config: cfg}
+
+	m := nativeSecretsManifest()
+	settings, err := p.secretSettings(context.Background(), m)
+	if err != nil {
+		t.Fatalf("secretSettings() error = %v", err)
+	}
+	if describes != 1 {
+		t.Errorf("Expected the shared secret to be described once, got %d", describes)
+	}
+	if len(settings) != 2 {
+		t.Fatalf("Expected 2 environment secrets, got %d", len(settings))
+	}
+	for _, s := range settings {
+		if *s.Namespace != environmentSecretsNamespace || *s.Value != "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db-AbCdEf" {
+			t.Errorf("Unexpected setting %s:%s=%s", *s.Namespace, *s.OptionName, *s.Value)
+		}
+	}
+
+	for _, s := range p.buildOptionSettings(m) {
+		if *s.Namespace == environmentNamespace && *s.OptionName != "LOG_LEVEL" {
+			t.Errorf("Expected secrets not to be environment properties, got %s", *s.OptionName)
+		}
+	}
+	if removed := plainSecretOptions(m); len(removed) != 2 {
+		t.Errorf("Expected the plain secret properties to be removed, got %d", len(removed))
+	}
+
+	m.SecretDelivery = ""
+	if settings, err := p.secretSettings(context.Background(), m); err != nil || settings != nil {
+		t.Errorf("Expected no environment secrets, got %v, %v", settings, err)
+	}
+}
+
+func TestBuildComposeFileNativeSecrets(t *testing.T) {
+	m := nativeSecretsManifest()
+	m.Containers = []manifest.Container{{Name: "web", Image: "web:1", Environment: map[string]string{"DB_PASSWORD": "secret", "LOG_LEVEL": "info"}}}
+
+	compose := buildComposeFile(m, map[string]string{"web": "ecr/web:1"})
+	env := compose["services"].(map[string]interface{})["web"].(map[string]interface{})["environment"].(map[string]string)
+
+	if env["DB_PASSWORD"] != "${DB_PASSWORD}" || env["LOG_LEVEL"] != "info" {
+		t.Errorf("Expected DB_PASSWORD from the environment secrets, got %v", env)
+	}
+}
@@ -0,0 +1,139 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+const ec2APIVersion = "2016-11-15"
+
+// Validate checks that the region exists and is enabled for the account, that
+// the instance type is offered in it, and that the solution stack is
+// available there. Nothing else can be checked in a region that is not
+// enabled, so its findings end there.
+func (p *Provider) Validate(ctx context.Context, m *manifest.Manifest) ([]types.ValidationFinding, error) {
+	var findings []types.ValidationFinding
+	add := func(field, format string, args ...any) {
+		findings = append(findings, types.ValidationFinding{Provider: "aws", Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	optIn, err := p.regionOptIn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch optIn {
+	case "":
+		add("provider.region", "%q is not an AWS region", p.region)
+		return findings, nil
+	case "not-opted-in":
+		add("provider.region", "%s is not enabled for the account; enable it in the AWS console's account settings", p.region)
+		return findings, nil
+	}
+
+	if m.Instance.Type != "" {
+		offered, err := p.instanceTypeOffered(ctx, m.Instance.Type)
+		if err != nil {
+			return nil, err
+		}
+		if !offered {
+			add("instance.type", "%s is not offered in %s", m.Instance.Type, p.region)
+		}
+	}
+
+	if m.Deployment.SolutionStack != "" {
+		result, err := p.ebClient.ListAvailableSolutionStacks(ctx, &elasticbeanstalk.ListAvailableSolutionStacksInput{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list solution stacks: %w", err)
+		}
+		if !slices.Contains(result.SolutionStacks, m.Deployment.SolutionStack) {
+			add("deployment.solution_stack", "%q is not available in %s%s", m.Deployment.SolutionStack, p.region, latestStackHint(result.SolutionStacks, m.Deployment.Platform))
+		}
+	}
+	return findings, nil
+}
+
+// latestStackHint suggests the latest Amazon Linux 2023 stack for platform
+// among stacks, which AWS lists newest first.
+func latestStackHint(stacks []string, platform string) string {
+	platform = strings.ToLower(platform)
+	for _, stack := range stacks {
+		lower := strings.ToLower(stack)
+		if platform != "" && strings.Contains(lower, platform) && strings.Contains(lower, "amazon linux 2023") {
+			return fmt.Sprintf("; the latest is %q", stack)
+		}
+	}
+	return ""
+}
+
+// ec2Client returns an EC2 API client for region.
+func (p *Provider) ec2Client(region string) *awsapi.Client {
+	cfg := p.config.Copy()
+	cfg.Region = region
+	return awsapi.New(cfg, "ec2")
+}
+
+// regionOptIn returns the opt-in status of the provider's region for the
+// account (opt-in-not-required, opted-in, or not-opted-in), or "" when there
+// is no such region. Regions are listed from the first region of the
+// region's partition, since an unknown region has no endpoint to ask.
+func (p *Provider) regionOptIn(ctx context.Context) (string, error) {
+	client := p.ec2Client(partitionRegion(p.region))
+	defer client.CloseIdleConnections()
+
+	var out struct {
+		Regions []struct {
+			Name  string `xml:"regionName"`
+			OptIn string `xml:"optInStatus"`
+		} `xml:"regionInfo>item"`
+	}
+	if err := client.EC2(ctx, "DescribeRegions", ec2APIVersion, url.Values{"AllRegions": {"true"}}, &out); err != nil {
+		return "", fmt.Errorf("failed to describe regions: %w", err)
+	}
+	for _, r := range out.Regions {
+		if r.Name == p.region {
+			return r.OptIn, nil
+		}
+	}
+	return "", nil
+}
+
+// partitionRegion returns a region of the partition region names belong to.
+func partitionRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "cn-north-1"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "us-gov-west-1"
+	default:
+		return "us-east-1"
+	}
+}
+
+// instanceTypeOffered reports whether instanceType is offered in the
+// provider's region.
+func (p *Provider) instanceTypeOffered(ctx context.Context, instanceType string) (bool, error) {
+	client := p.ec2Client(p.region)
+	defer client.CloseIdleConnections()
+
+	params := url.Values{
+		"LocationType":     {"region"},
+		"Filter.1.Name":    {"instance-type"},
+		"Filter.1.Value.1": {instanceType},
+	}
+	var out struct {
+		Types []string `xml:"instanceTypeOfferingSet>item>instanceType"`
+	}
+	if err := client.EC2(ctx, "DescribeInstanceTypeOfferings", ec2APIVersion, params, &out); err != nil {
+		return false, fmt.Errorf("failed to describe instance type offerings: %w", err)
+	}
+	return slices.Contains(out.Types, instanceType), nil
+}
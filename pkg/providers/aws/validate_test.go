@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscreds "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/elasticbeanstalk"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// testValidationProvider returns a provider in region whose EC2 and Elastic
+// Beanstalk requests go to a test server offering t3.micro and one solution
+// stack, with ap-east-1 not enabled.
+func testValidationProvider(t *testing.T, region string) *Provider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("Action") {
+		case "DescribeRegions":
+			if r.Form.Get("AllRegions") != "true" {
+				t.Error("Expected every region to be listed")
+			}
+			w.Write([]byte(`<DescribeRegionsResponse><regionInfo>
+<item><regionName>us-east-1</regionName><optInStatus>opt-in-not-required</optInStatus></item>
+<item><regionName>ap-east-1</regionName><optInStatus>not-opted-in</optInStatus></item>
+</regionInfo></DescribeRegionsResponse>`))
+		case "DescribeInstanceTypeOfferings":
+			if r.Form.Get("Filter.1.Value.1") == "t3.micro" {
+				w.Write([]byte(`<DescribeInstanceTypeOfferingsResponse><instanceTypeOfferingSet><item><instanceType>t3.micro</instanceType><locationType>region</locationType></item></instanceTypeOfferingSet></DescribeInstanceTypeOfferingsResponse>`))
+				return
+			}
+			w.Write([]byte(`<DescribeInstanceTypeOfferingsResponse><instanceTypeOfferingSet/></DescribeInstanceTypeOfferingsResponse>`))
+		case "ListAvailableSolutionStacks":
+			w.Header().Set("Content-Type", "text/xml")
+			w.Write([]byte(`<ListAvailableSolutionStacksResponse><ListAvailableSolutionStacksResult><SolutionStacks>
+<member>64bit Amazon Linux 2023 v4.7.2 running Docker</member>
+</SolutionStacks></ListAvailableSolutionStacksResult></ListAvailableSolutionStacksResponse>`))
+		default:
+			t.Errorf("Unexpected action %q", r.Form.Get("Action"))
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region:       region,
+		Credentials:  awscreds.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	return &Provider{region: region, config: cfg, ebClient: elasticbeanstalk.NewFromConfig(cfg)}
+}
+
+func TestValidate(t *testing.T) {
+	m := &manifest.Manifest{}
+	m.Instance.Type = "t3.micro"
+	m.Deployment.Platform = "docker"
+	m.Deployment.SolutionStack = "64bit Amazon Linux 2023 v4.7.2 running Docker"
+
+	p := testValidationProvider(t, "us-east-1")
+	findings, err := p.Validate(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v", findings)
+	}
+
+	m.Instance.Type = "t3.mcro"
+	m.Deployment.SolutionStack = "64bit Amazon Linux 2 v3.4.0 running Docker"
+	findings, err = p.Validate(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected every finding to be reported, got %+v", findings)
+	}
+	if f := findings[0]; f.Provider != "aws" || f.Field != "instance.type" || f.Message != "t3.mcro is not offered in us-east-1" {
+		t.Errorf("Unexpected instance type finding: %+v", f)
+	}
+	if f := findings[1]; f.Field != "deployment.solution_stack" || !strings.Contains(f.Message, `the latest is "64bit Amazon Linux 2023 v4.7.2 running Docker"`) {
+		t.Errorf("Unexpected solution stack finding: %+v", f)
+	}
+}
+
+func TestValidateRegion(t *testing.T) {
+	m := &manifest.Manifest{}
+	m.Instance.Type = "t3.micro"
+
+	for region, want := range map[string]string{
+		"us-east-9": `"us-east-9" is not an AWS region`,
+		"ap-east-1": "ap-east-1 is not enabled for the account",
+	} {
+		findings, err := testValidationProvider(t, region).Validate(context.Background(), m)
+		if err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+		if len(findings) != 1 || findings[0].Field != "provider.region" || !strings.Contains(findings[0].Message, want) {
+			t.Errorf("Expected only a region finding containing %q, got %+v", want, findings)
+		}
+	}
+}
+
+func TestPartitionRegion(t *testing.T) {
+	for region, want := range map[string]string{
+		"eu-west-1":      "us-east-1",
+		"cn-northwest-1": "cn-north-1",
+		"us-gov-east-1":  "us-gov-west-1",
+	} {
+		if got := partitionRegion(region); got != want {
+			t.Errorf("partitionRegion(%q) = %q, want %q", region, got, want)
+		}
+	}
+}
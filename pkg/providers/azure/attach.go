@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Kinds of journaled container group operations, which are identified by
+// their pollers' resume tokens.
+const (
+	operationContainerGroupUpdate = "container-group-update"
+	operationContainerGroupDelete = "container-group-delete"
+)
+
+// SetOperationJournal makes the provider journal the container group
+// operations it polls.
+func (p *Provider) SetOperationJournal(journal types.OperationJournal) {
+	p.journal = journal
+}
+
+// Attach resumes the poller of a container group operation started by
+// another invocation from its resume token, waits for the operation to
+// finish, and ends it in the journal.
+func (p *Provider) Attach(ctx context.Context, m *manifest.Manifest, op types.Operation) (*types.DeploymentResult, error) {
+	result := &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+	}
+	logging.Infof("Attaching to container group operation of %s", m.Environment.Name)
+
+	switch op.Kind {
+	case operationContainerGroupUpdate:
+		poller, err := p.containerClient.BeginCreateOrUpdate(ctx, p.resourceGroup, m.Environment.Name, armcontainerinstance.ContainerGroup{},
+			&armcontainerinstance.ContainerGroupsClientBeginCreateOrUpdateOptions{ResumeToken: op.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resume container group update: %w", err)
+		}
+		resp, err := pollOperation(ctx, p, op.Kind, poller)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create or update container group: %w", err)
+		}
+		if err := p.waitForContainerGroup(ctx, m.Environment.Name); err != nil {
+			return nil, fmt.Errorf("container group deployment failed: %w", err)
+		}
+		if resp.Properties != nil && resp.Properties.IPAddress != nil && resp.Properties.IPAddress.Fqdn != nil {
+			result.URL = fmt.Sprintf("http://%s", *resp.Properties.IPAddress.Fqdn)
+		}
+		result.Status = "Running"
+		result.Message = "Attached to the container group update"
+
+	case operationContainerGroupDelete:
+		poller, err := p.containerClient.BeginDelete(ctx, p.resourceGroup, m.Environment.Name,
+			&armcontainerinstance.ContainerGroupsClientBeginDeleteOptions{ResumeToken: op.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resume container group deletion: %w", err)
+		}
+		if _, err := pollOperation(ctx, p, op.Kind, poller); err != nil {
+			return nil, fmt.Errorf("failed to delete container group: %w", err)
+		}
+		result.Status = "Deleted"
+		result.Message = "Attached to the container group deletion"
+
+	default:
+		return nil, fmt.Errorf("cannot attach to %s operations", op.Kind)
+	}
+	return result, nil
+}
+
+// pollOperation journals the operation of poller as the operation in
+// progress and polls it until it finishes. The operation stays in the
+// journal if ctx ends first, since Azure carries on without us.
+func pollOperation[T any](ctx context.Context, p *Provider, kind string, poller *runtime.Poller[T]) (T, error) {
+	if p.journal != nil && !poller.Done() {
+		token, err := poller.ResumeToken()
+		if err == nil {
+			err = p.journal.Begin(ctx, types.Operation{Provider: "azure", Kind: kind, ID: token})
+		}
+		if err != nil {
+			logging.Warnf("Failed to journal the %s operation: %v", kind, err)
+		}
+	}
+	result, err := poller.PollUntilDone(ctx, nil)
+	if p.journal != nil && ctx.Err() == nil {
+		if err := p.journal.End(ctx); err != nil {
+			logging.Warnf("Failed to end the %s operation in the journal: %v", kind, err)
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,84 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestAttachUnknownKind(t *testing.T) {
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-app-prod"}}
+	if _, err := (&Provider{}).Attach(context.Background(), m, types.Operation{Kind: "service-update"}); err == nil || !strings.Contains(err.Error(), "cannot attach to service-update operations") {
+		t.Errorf("Expected an error for another provider's operation, got: %v", err)
+	}
+}
+
+// fakeJournal records the operations begun and ended.
+type fakeJournal struct {
+	begun []types.Operation
+	ended int
+}
+
+func (j *fakeJournal) Begin(ctx context.Context, op types.Operation) error {
+	j.begun = append(j.begun, op)
+	return nil
+}
+
+func (j *fakeJournal) End(ctx context.Context) error {
+	j.ended++
+	return nil
+}
+
+func TestPollOperation(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			w.Header().Set("Location", server.URL+"/operations/op-1")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/operations/op-1":
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	config := &manifest.ProviderConfig{Name: "azure", EndpointURL: server.URL}
+	client, err := armcontainerinstance.NewContainerGroupsClient("sub", emulatorCredential{}, ClientOptions(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal := &fakeJournal{}
+	p := &Provider{resourceGroup: "rg", containerClient: client}
+	p.SetOperationJournal(journal)
+
+	ctx := context.Background()
+	poller, err := client.BeginDelete(ctx, "rg", "my-app-prod", nil)
+	if err != nil {
+		t.Fatalf("BeginDelete failed: %v", err)
+	}
+	if _, err := pollOperation(ctx, p, operationContainerGroupDelete, poller); err != nil {
+		t.Fatalf("pollOperation failed: %v", err)
+	}
+	if len(journal.begun) != 1 || journal.begun[0].Kind != operationContainerGroupDelete || journal.begun[0].ID == "" || journal.ended != 1 {
+		t.Fatalf("Expected the operation to be journaled with its resume token, got %+v, %d", journal.begun, journal.ended)
+	}
+
+	// The resume token lets another invocation attach to the operation
+	m := &manifest.Manifest{Application: manifest.ApplicationConfig{Name: "my-app"}, Environment: manifest.EnvironmentConfig{Name: "my-app-prod"}}
+	result, err := p.Attach(ctx, m, journal.begun[0])
+	if err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	if result.Status != "Deleted" || journal.ended != 2 {
+		t.Errorf("Attach = %+v, ended %d times; want the deletion finished and ended", result, journal.ended)
+	}
+}
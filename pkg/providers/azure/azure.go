@@ -19,6 +19,7 @@ import (
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
@@ -36,10 +37,13 @@ type Provider struct {
 	location            string
 	resourceGroup       string
 	credential          azcore.TokenCredential
+	clientOptions       *arm.ClientOptions
 	containerClient     *armcontainerinstance.ContainerGroupsClient
+	containersClient    *armcontainerinstance.ContainersClient
 	registryClient      *armcontainerregistry.RegistriesClient
 	resourceGroupClient *armresources.ResourceGroupsClient
 	blobServiceClient   *azblob.Client
+	journal             types.OperationJournal
 }
 
 // New creates a new Azure provider instance.
@@ -54,9 +58,12 @@ type Provider struct {
 //   - m: Full manifest (for Vault credential loading)
 //
 // Authentication methods:
-//  1. Vault: Load from HashiCorp Vault (if credentials.source == "vault")
+//  1. Secret store: Load from HashiCorp Vault or AWS Secrets Manager (if credentials.source == "vault" or "secrets-manager")
 //  2. Service Principal: Provide client_id, client_secret, tenant_id
 //  3. Default Azure credentials: Leave credentials nil to use Azure CLI/Managed Identity
+//
+// When provider.endpoint_url is set, clients reach that emulator instead of
+// Azure Resource Manager, with a static token.
 func New(ctx context.Context, subscriptionID, location, resourceGroup string, credentials *manifest.AzureCredentialsConfig, credConfig *manifest.CredentialsConfig, m *manifest.Manifest) (*Provider, error) {
 	if subscriptionID == "" {
 		return nil, fmt.Errorf("subscription ID is required")
@@ -68,21 +75,83 @@ func New(ctx context.Context, subscriptionID, location, resourceGroup string, cr
 		return nil, fmt.Errorf("resource group is required")
 	}
 
+	cred, err := NewCredential(ctx, credentials, credConfig, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Azure clients
+	var providerConfig *manifest.ProviderConfig
+	if m != nil {
+		providerConfig = &m.Provider
+	}
+	clientOptions := ClientOptions(providerConfig)
+	containerClient, err := armcontainerinstance.NewContainerGroupsClient(subscriptionID, cred, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container groups client: %w", err)
+	}
+
+	containersClient, err := armcontainerinstance.NewContainersClient(subscriptionID, cred, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create containers client: %w", err)
+	}
+
+	registryClient, err := armcontainerregistry.NewRegistriesClient(subscriptionID, cred, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client: %w", err)
+	}
+
+	resourceGroupClient, err := armresources.NewResourceGroupsClient(subscriptionID, cred, clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource groups client: %w", err)
+	}
+
+	return &Provider{
+		subscriptionID:      subscriptionID,
+		location:            location,
+		resourceGroup:       resourceGroup,
+		credential:          cred,
+		clientOptions:       clientOptions,
+		containerClient:     containerClient,
+		containersClient:    containersClient,
+		registryClient:      registryClient,
+		resourceGroupClient: resourceGroupClient,
+	}, nil
+}
+
+// NewCredential returns the credential Azure requests are authenticated with,
+// using the same methods as New: the CI job's OIDC token, a secret store, the
+// manifest's Service Principal, or the default Azure credentials. Emulators get a static token.
+func NewCredential(ctx context.Context, credentials *manifest.AzureCredentialsConfig, credConfig *manifest.CredentialsConfig, m *manifest.Manifest) (azcore.TokenCredential, error) {
 	var cred azcore.TokenCredential
 	var err error
 
-	// Check if credentials should be loaded from Vault
-	if credConfig != nil && credConfig.Source == "vault" {
-		logging.Info("Loading Azure credentials from Vault...")
+	if m != nil && m.Provider.Emulated() {
+		logging.Infof("Using a static token for the emulator at %s", m.Provider.EndpointURL)
+		return emulatorCredential{}, nil
+	}
 
-		// Get credentials from Vault using manifest helper
+	if credConfig != nil && credConfig.Source == "oidc" && credentials != nil {
+		// CI jobs exchange their OIDC token through a federated credential of the app registration
+		logging.Info("Using the CI OIDC token as a federated credential")
+		cred, err = azidentity.NewClientAssertionCredential(credentials.TenantID, credentials.ClientID, func(ctx context.Context) (string, error) {
+			return credConfig.OIDCToken(ctx, "api://AzureADTokenExchange")
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create federated credential: %w", err)
+		}
+	} else if credConfig.FromSecretStore() {
+		// Load credentials from a secret store
+		logging.Infof("Loading Azure credentials from %s...", credConfig.Source)
+
+		// Get credentials from the secret store using manifest helper
 		vaultCreds, err := m.GetCloudCredentials(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load Azure credentials from Vault: %w", err)
+			return nil, fmt.Errorf("failed to load Azure credentials from %s: %w", credConfig.Source, err)
 		}
 
 		if vaultCreds != nil {
-			logging.Info("✅ Successfully loaded Azure credentials from Vault")
+			logging.Infof("✅ Successfully loaded Azure credentials from %s", credConfig.Source)
 			cred, err = azidentity.NewClientSecretCredential(
 				vaultCreds.Azure.TenantID,
 				vaultCreds.Azure.ClientID,
@@ -90,7 +159,7 @@ func New(ctx context.Context, subscriptionID, location, resourceGroup string, cr
 				nil,
 			)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create service principal credential from Vault: %w", err)
+				return nil, fmt.Errorf("failed to create service principal credential from %s: %w", credConfig.Source, err)
 			}
 		}
 	} else if credentials != nil && credentials.ClientID != "" && credentials.ClientSecret != "" && credentials.TenantID != "" {
@@ -112,32 +181,7 @@ func New(ctx context.Context, subscriptionID, location, resourceGroup string, cr
 			return nil, fmt.Errorf("failed to create default credential: %w", err)
 		}
 	}
-
-	// Create Azure clients
-	containerClient, err := armcontainerinstance.NewContainerGroupsClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create container groups client: %w", err)
-	}
-
-	registryClient, err := armcontainerregistry.NewRegistriesClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create registry client: %w", err)
-	}
-
-	resourceGroupClient, err := armresources.NewResourceGroupsClient(subscriptionID, cred, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource groups client: %w", err)
-	}
-
-	return &Provider{
-		subscriptionID:      subscriptionID,
-		location:            location,
-		resourceGroup:       resourceGroup,
-		credential:          cred,
-		containerClient:     containerClient,
-		registryClient:      registryClient,
-		resourceGroupClient: resourceGroupClient,
-	}, nil
+	return cred, nil
 }
 
 // Name returns the provider name.
@@ -145,6 +189,13 @@ func (p *Provider) Name() string {
 	return "azure"
 }
 
+// Close does nothing: the Resource Manager and Blob Storage clients send
+// requests through the Azure SDK's shared transport, which outlives the
+// provider.
+func (p *Provider) Close() error {
+	return nil
+}
+
 // Deploy deploys an application to Azure Container Instances.
 // This method:
 // 1. Creates resource group if it doesn't exist
@@ -152,7 +203,18 @@ func (p *Provider) Name() string {
 // 3. Pushes pre-built Docker image to ACR
 // 4. Deploys to Azure Container Instances
 // 5. Fetches Vault secrets if configured
+//
+// Sidecars are deployed as containers of the group next to the application's.
 func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	m = m.WithSidecars()
+
+	if err := validateImagePlatforms(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
 	if m.IsMultiContainer() {
 		logging.Info("Starting Azure Container Instances multi-container deployment...")
 		return p.deployMultiContainer(ctx, m)
@@ -174,27 +236,30 @@ func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.Dep
 
 	// Step 3: Push image to ACR with timestamped tag for rollback support
 	logging.Info("=== Distributing image to ACR ===")
-	deployTag := fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405"))
+	deployTag := m.VersionLabel(fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405")))
 	acrRegistry, err := registry.NewACRRegistry(p.credential, p.subscriptionID, p.resourceGroup, registryName, p.location, deployTag)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ACR registry handler: %w", err)
 	}
 
 	// Use Distributor to push image to registry
-	distributor := registry.NewDistributor(m.Image)
-	distributor.AddRegistry(acrRegistry)
+	distributor := registry.NewDistributor(m.Image, m.Platforms...)
+	dest := registry.Local(acrRegistry, m.Provider.RegistryURL, m.RepositoryName(), deployTag)
+	distributor.AddRegistry(dest)
 
 	imageURIs, err := distributor.Distribute(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to distribute image to ACR: %w", err)
 	}
 
-	imageURI := imageURIs[acrRegistry.GetRegistryURL()]
+	imageURI := imageURIs[dest.GetRegistryURL()]
 	logging.Infof("Successfully pushed image to ACR: %s", imageURI)
 
-	// Step 4: Deploy to Azure Container Instances
+	// Step 4: Deploy to Azure Container Instances, pulling the pushed image by
+	// digest so a tag moving later never changes the container group's image
+	image := registry.PinDigest(imageURI, distributor.Digest())
 	containerGroupName := m.Environment.Name
-	fqdn, err := p.deployContainerGroup(ctx, m, containerGroupName, imageURI, registryName, registryPassword)
+	fqdn, err := p.deployContainerGroup(ctx, m, containerGroupName, image, registryName, registryPassword)
 	if err != nil {
 		return nil, fmt.Errorf("failed to deploy container group: %w", err)
 	}
@@ -213,6 +278,7 @@ func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.Dep
 		URL:             url,
 		Status:          "Running",
 		Message:         "Deployment successful",
+		Images:          map[string]string{m.Application.Name: image},
 	}, nil
 }
 
@@ -232,32 +298,35 @@ func (p *Provider) deployMultiContainer(ctx context.Context, m *manifest.Manifes
 
 	// Step 3: Push ALL container images to ACR
 	logging.Infof("Distributing %d container images to ACR...", len(m.Containers))
-	containerImageURIs := make(map[string]string) // container name -> ACR URI
+	pinnedImages := make(map[string]string) // container name -> URI pinned by digest
 
 	for _, container := range m.Containers {
 		logging.Infof("Pushing container image: %s (%s)", container.Name, container.Image)
 
-		acrRegistry, err := registry.NewACRRegistry(p.credential, p.subscriptionID, p.resourceGroup, registryName, p.location, container.Name)
+		tag := m.ImageTag(container.Name, container.Name)
+		acrRegistry, err := registry.NewACRRegistry(p.credential, p.subscriptionID, p.resourceGroup, registryName, p.location, tag)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create ACR registry for container %s: %w", container.Name, err)
 		}
 
-		distributor := registry.NewDistributor(container.Image)
-		distributor.AddRegistry(acrRegistry)
+		distributor := registry.NewDistributor(container.Image, m.Platforms...)
+		dest := registry.Local(acrRegistry, m.Provider.RegistryURL, m.RepositoryName(), tag)
+		distributor.AddRegistry(dest)
 
 		imageURIs, err := distributor.Distribute(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to distribute image for container %s: %w", container.Name, err)
 		}
 
-		imageURI := imageURIs[acrRegistry.GetRegistryURL()]
-		containerImageURIs[container.Name] = imageURI
+		imageURI := imageURIs[dest.GetRegistryURL()]
+		pinnedImages[container.Name] = registry.PinDigest(imageURI, distributor.Digest())
 		logging.Infof("Image pushed to ACR: %s -> %s", container.Name, imageURI)
 	}
 
-	// Step 4: Deploy multi-container group to Azure Container Instances
+	// Step 4: Deploy multi-container group to Azure Container Instances, pulling
+	// the pushed images by digest
 	containerGroupName := m.Environment.Name
-	fqdn, err := p.deployMultiContainerGroup(ctx, m, containerGroupName, containerImageURIs, registryName, registryPassword)
+	fqdn, err := p.deployMultiContainerGroup(ctx, m, containerGroupName, pinnedImages, registryName, registryPassword)
 	if err != nil {
 		return nil, fmt.Errorf("failed to deploy multi-container group: %w", err)
 	}
@@ -276,6 +345,7 @@ func (p *Provider) deployMultiContainer(ctx context.Context, m *manifest.Manifes
 		URL:             url,
 		Status:          "Running",
 		Message:         fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers)),
+		Images:          pinnedImages,
 	}, nil
 }
 
@@ -291,7 +361,7 @@ func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
 		return fmt.Errorf("failed to begin delete container group: %w", err)
 	}
 
-	_, err = poller.PollUntilDone(ctx, nil)
+	_, err = pollOperation(ctx, p, operationContainerGroupDelete, poller)
 	if err != nil {
 		return fmt.Errorf("failed to delete container group: %w", err)
 	}
@@ -390,7 +460,7 @@ func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.D
 		return nil, fmt.Errorf("failed to begin rollback: %w", err)
 	}
 
-	_, err = poller.PollUntilDone(ctx, nil)
+	_, err = pollOperation(ctx, p, operationContainerGroupUpdate, poller)
 	if err != nil {
 		return nil, fmt.Errorf("rollback failed: %w", err)
 	}
@@ -431,6 +501,13 @@ func (p *Provider) ensureResourceGroup(ctx context.Context) error {
 // generateRegistryName generates a valid ACR name from the application name.
 // ACR names must be alphanumeric only, 5-50 characters.
 func (p *Provider) generateRegistryName(appName string) string {
+	return RegistryName(appName)
+}
+
+// RegistryName returns the name of the ACR registry images of the named
+// application are pushed to: the name's lowercase letters and digits, padded
+// to at least 5 and truncated to 50 characters as ACR requires.
+func RegistryName(appName string) string {
 	// Remove non-alphanumeric characters and convert to lowercase
 	name := strings.ToLower(appName)
 	name = strings.Map(func(r rune) rune {
@@ -520,10 +597,7 @@ func (p *Provider) deployContainerGroup(ctx context.Context, m *manifest.Manifes
 	// Build environment variables
 	envVars := make([]*armcontainerinstance.EnvironmentVariable, 0, len(m.EnvironmentVariables))
 	for key, value := range m.EnvironmentVariables {
-		envVars = append(envVars, &armcontainerinstance.EnvironmentVariable{
-			Name:  to.Ptr(key),
-			Value: to.Ptr(value),
-		})
+		envVars = append(envVars, aciEnvVar(m, key, value))
 	}
 
 	// Get registry login server
@@ -545,14 +619,7 @@ func (p *Provider) deployContainerGroup(ctx context.Context, m *manifest.Manifes
 		}
 	}
 
-	// Create DNS label from environment name
-	dnsLabel := strings.ToLower(m.Environment.Name)
-	dnsLabel = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			return r
-		}
-		return '-'
-	}, dnsLabel)
+	dnsLabel := m.DNSLabel()
 
 	containerProps := &armcontainerinstance.ContainerProperties{
 		Image: to.Ptr(image),
@@ -573,6 +640,7 @@ func (p *Provider) deployContainerGroup(ctx context.Context, m *manifest.Manifes
 			},
 		},
 		EnvironmentVariables: envVars,
+		Command:              containerCommand(m.GetPrimaryContainer()),
 	}
 
 	// Apply health check configuration as liveness probe
@@ -633,7 +701,7 @@ func (p *Provider) deployContainerGroup(ctx context.Context, m *manifest.Manifes
 		return "", fmt.Errorf("failed to begin create container group: %w", err)
 	}
 
-	result, err := poller.PollUntilDone(ctx, nil)
+	result, err := pollOperation(ctx, p, operationContainerGroupUpdate, poller)
 	if err != nil {
 		return "", fmt.Errorf("failed to create container group: %w", err)
 	}
@@ -668,11 +736,16 @@ func (p *Provider) deployMultiContainerGroup(ctx context.Context, m *manifest.Ma
 		}
 	}
 
+	requests, err := containerRequests(m.Containers, cpu, memoryGB)
+	if err != nil {
+		return "", err
+	}
+
 	// Build containers array
 	containers := make([]*armcontainerinstance.Container, 0, len(m.Containers))
 	allPorts := make([]*armcontainerinstance.Port, 0)
 
-	for _, containerDef := range m.Containers {
+	for i, containerDef := range m.Containers {
 		imageURI := containerImageURIs[containerDef.Name]
 
 		// Build environment variables for this container
@@ -680,10 +753,7 @@ func (p *Provider) deployMultiContainerGroup(ctx context.Context, m *manifest.Ma
 		for key, value := range containerDef.Environment {
 			// Expand environment variable references (e.g., ${DD_API_KEY})
 			expandedValue := os.ExpandEnv(value)
-			envVars = append(envVars, &armcontainerinstance.EnvironmentVariable{
-				Name:  to.Ptr(key),
-				Value: to.Ptr(expandedValue),
-			})
+			envVars = append(envVars, aciEnvVar(m, key, expandedValue))
 		}
 
 		// Build container ports
@@ -706,27 +776,18 @@ func (p *Provider) deployMultiContainerGroup(ctx context.Context, m *manifest.Ma
 			Properties: &armcontainerinstance.ContainerProperties{
 				Image: to.Ptr(imageURI),
 				Resources: &armcontainerinstance.ResourceRequirements{
-					Requests: &armcontainerinstance.ResourceRequests{
-						CPU:        to.Ptr(cpu / float64(len(m.Containers))), // Divide resources among containers
-						MemoryInGB: to.Ptr(memoryGB / float64(len(m.Containers))),
-					},
+					Requests: requests[i],
 				},
 				Ports:                containerPorts,
 				EnvironmentVariables: envVars,
+				Command:              containerCommand(containerDef),
 			},
 		}
 
 		containers = append(containers, container)
 	}
 
-	// Create DNS label from environment name
-	dnsLabel := strings.ToLower(m.Environment.Name)
-	dnsLabel = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			return r
-		}
-		return '-'
-	}, dnsLabel)
+	dnsLabel := m.DNSLabel()
 
 	containerGroup := armcontainerinstance.ContainerGroup{
 		Location: to.Ptr(p.location),
@@ -757,7 +818,7 @@ func (p *Provider) deployMultiContainerGroup(ctx context.Context, m *manifest.Ma
 		return "", fmt.Errorf("failed to begin create or update container group: %w", err)
 	}
 
-	result, err := poller.PollUntilDone(ctx, nil)
+	result, err := pollOperation(ctx, p, operationContainerGroupUpdate, poller)
 	if err != nil {
 		return "", fmt.Errorf("failed to create or update container group: %w", err)
 	}
@@ -819,74 +880,120 @@ func (p *Provider) waitForContainerGroup(ctx context.Context, name string) error
 	}
 }
 
-// findPreviousImage finds the previous image tag in ACR by listing tags
-// via the Docker V2 Distribution API and selecting the most recent deploy
-// tag before the current one.
+// findPreviousImage finds the image deployed before the current one by listing
+// the repository's tags through the ACR data-plane API and selecting the deploy
+// tag pushed before the current tag.
 func (p *Provider) findPreviousImage(ctx context.Context, registryName, repositoryName, currentImage string) (string, error) {
-	// Get ACR credentials for Docker V2 API access
+	// Get ACR credentials for data-plane API access
 	loginServer, password, err := p.getRegistryCredentials(ctx, registryName)
 	if err != nil {
 		return "", fmt.Errorf("failed to get registry credentials: %w", err)
 	}
 
-	// List tags using Docker V2 Distribution API
-	tagsURL := fmt.Sprintf("https://%s/v2/%s/tags/list", loginServer, registryName)
-	req, err := http.NewRequestWithContext(ctx, "GET", tagsURL, nil)
+	// Images are pushed to a repository named after the registry (see registry.ACRRegistry)
+	tags, err := listACRTags(ctx, http.DefaultClient, "https://"+loginServer, registryName, registryName, password)
 	if err != nil {
-		return "", fmt.Errorf("failed to create tags request: %w", err)
+		return "", err
 	}
-	req.SetBasicAuth(registryName, password)
+	return findPreviousImageFromTags(tags, currentImage)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to list ACR tags: %w", err)
-	}
-	defer resp.Body.Close()
+// acrTag is a repository tag as listed by the ACR data-plane API.
+type acrTag struct {
+	Name           string    `json:"name"`
+	Digest         string    `json:"digest"`
+	CreatedTime    time.Time `json:"createdTime"`
+	LastUpdateTime time.Time `json:"lastUpdateTime"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to list ACR tags: HTTP %d", resp.StatusCode)
-	}
+// listACRTags lists every tag of an ACR repository, following pagination links.
+// baseURL is the registry's https URL.
+func listACRTags(ctx context.Context, client *http.Client, baseURL, repository, username, password string) ([]acrTag, error) {
+	var tags []acrTag
+	next := fmt.Sprintf("/acr/v1/%s/_tags?n=100", repository)
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tags request: %w", err)
+		}
+		req.SetBasicAuth(username, password)
 
-	var tagsResponse struct {
-		Tags []string `json:"tags"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&tagsResponse); err != nil {
-		return "", fmt.Errorf("failed to parse tags response: %w", err)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ACR tags: %w", err)
+		}
+
+		var page struct {
+			Tags []acrTag `json:"tags"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list ACR tags: HTTP %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags response: %w", err)
+		}
+
+		tags = append(tags, page.Tags...)
+		next = nextLink(resp.Header.Get("Link"))
 	}
+	return tags, nil
+}
 
-	return findPreviousImageFromTags(tagsResponse.Tags, currentImage)
+// nextLink returns the path of a Link header's rel="next" target
+// (e.g., `</acr/v1/repo/_tags?last=deploy-1&n=100>; rel="next"`), or "" if there is none.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
 }
 
-// findPreviousImageFromTags selects the most recent deploy-* tag before the
-// current one from a list of ACR tags.
-func findPreviousImageFromTags(tags []string, currentImage string) (string, error) {
-	// Extract current tag from image URI (format: <registry>/<repo>:<tag>)
-	parts := strings.Split(currentImage, ":")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("invalid image format: %s", currentImage)
+// findPreviousImageFromTags selects the deploy-* tag pushed before the current
+// one, pinned by its digest. The current image is pinned by digest, matching
+// the last tag pushed with it, or tagged. When the current tag is not in the
+// list, the most recently pushed deploy tag is selected.
+func findPreviousImageFromTags(tags []acrTag, currentImage string) (string, error) {
+	// Extract current tag or digest from image URI (format: <registry>/<repo>@<digest>
+	// or <registry>/<repo>:<tag>)
+	imageBase, digest, pinned := strings.Cut(currentImage, "@")
+	currentTag := ""
+	if !pinned {
+		parts := strings.Split(currentImage, ":")
+		if len(parts) != 2 {
+			return "", fmt.Errorf("invalid image format: %s", currentImage)
+		}
+		imageBase, currentTag = parts[0], parts[1]
 	}
-	currentTag := parts[1]
-	imageBase := parts[0]
 
-	// Filter for deploy-* tags excluding the current one, then sort
-	var deployTags []string
+	var deployTags []acrTag
 	for _, tag := range tags {
-		if strings.HasPrefix(tag, "deploy-") && tag != currentTag {
+		if strings.HasPrefix(tag.Name, "deploy-") {
 			deployTags = append(deployTags, tag)
 		}
 	}
 
-	if len(deployTags) == 0 {
+	// Oldest push first
+	sort.SliceStable(deployTags, func(i, j int) bool {
+		return deployTags[i].LastUpdateTime.Before(deployTags[j].LastUpdateTime)
+	})
+
+	current := len(deployTags)
+	for i, tag := range deployTags {
+		if (pinned && tag.Digest == digest) || (!pinned && tag.Name == currentTag) {
+			current = i
+		}
+	}
+	if current == 0 {
 		return "", fmt.Errorf("no previous deployment found to roll back to")
 	}
-
-	// Sort lexicographically — deploy-YYYYMMDDTHHMMSS format sorts chronologically
-	sort.Strings(deployTags)
-
-	// Most recent previous tag is the last one
-	previousTag := deployTags[len(deployTags)-1]
-
-	return fmt.Sprintf("%s:%s", imageBase, previousTag), nil
+	previous := deployTags[current-1]
+	return registry.PinDigest(fmt.Sprintf("%s:%s", imageBase, previous.Name), previous.Digest), nil
 }
 
 // createTarGz creates a tar.gz archive of a directory.
@@ -946,3 +1053,110 @@ func createTarGz(sourceDir, targetFile string) error {
 		return err
 	})
 }
+
+// validateImagePlatforms fails early when an image cannot run in the Linux
+// container group that Deploy creates.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, registry.LinuxAMD64, "Azure Container Instances (Linux)", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aciEnvVar returns the environment variable name of a container of m. Secrets
+// delivered natively are secure values, which ACI never shows or returns.
+func aciEnvVar(m *manifest.Manifest, name, value string) *armcontainerinstance.EnvironmentVariable {
+	if m.NativeSecrets() && m.IsSecret(name) {
+		return &armcontainerinstance.EnvironmentVariable{Name: to.Ptr(name), SecureValue: to.Ptr(value)}
+	}
+	return &armcontainerinstance.EnvironmentVariable{Name: to.Ptr(name), Value: to.Ptr(value)}
+}
+
+// containerRequests returns the CPU and memory requested by each of the
+// containers of a group given cpu and memoryGB for the whole group: a
+// container's own resources, or an even share of what the others leave.
+func containerRequests(containers []manifest.Container, cpu, memoryGB float64) ([]*armcontainerinstance.ResourceRequests, error) {
+	cpuLeft, memoryLeft := cpu, memoryGB
+	cpuShares, memoryShares := 0, 0
+	for _, c := range containers {
+		if c.Resources != nil && c.Resources.CPU > 0 {
+			cpuLeft -= c.Resources.CPU
+		} else {
+			cpuShares++
+		}
+		if c.Resources != nil && c.Resources.MemoryGB > 0 {
+			memoryLeft -= c.Resources.MemoryGB
+		} else {
+			memoryShares++
+		}
+	}
+	if cpuShares > 0 && cpuLeft <= 0 {
+		return nil, fmt.Errorf("the containers' resources use all %g CPU cores of the container group, leaving none for %d other containers; raise azure.cpu", cpu, cpuShares)
+	}
+	if memoryShares > 0 && memoryLeft <= 0 {
+		return nil, fmt.Errorf("the containers' resources use all %g GB of memory of the container group, leaving none for %d other containers; raise azure.memory_gb", memoryGB, memoryShares)
+	}
+
+	requests := make([]*armcontainerinstance.ResourceRequests, len(containers))
+	for i, c := range containers {
+		request := &armcontainerinstance.ResourceRequests{}
+		if c.Resources != nil && c.Resources.CPU > 0 {
+			request.CPU = to.Ptr(c.Resources.CPU)
+		} else {
+			request.CPU = to.Ptr(cpuLeft / float64(cpuShares))
+		}
+		if c.Resources != nil && c.Resources.MemoryGB > 0 {
+			request.MemoryInGB = to.Ptr(c.Resources.MemoryGB)
+		} else {
+			request.MemoryInGB = to.Ptr(memoryLeft / float64(memoryShares))
+		}
+		requests[i] = request
+	}
+	return requests, nil
+}
+
+// containerCommand converts startup overrides to an ACI container command.
+// ACI has a single command that replaces the image ENTRYPOINT, so args are appended to it;
+// args alone and working directories cannot be expressed and are ignored with a warning.
+func containerCommand(c manifest.Container) []*string {
+	if c.Workdir != "" {
+		logging.Warnf("Container %s: workdir is not supported by Azure Container Instances and will be ignored", c.Name)
+	}
+	if len(c.Command) == 0 {
+		if len(c.Args) > 0 {
+			logging.Warnf("Container %s: args without command are not supported by Azure Container Instances and will be ignored", c.Name)
+		}
+		return nil
+	}
+
+	command := make([]*string, 0, len(c.Command)+len(c.Args))
+	for _, arg := range append(append([]string{}, c.Command...), c.Args...) {
+		command = append(command, to.Ptr(arg))
+	}
+	return command
+}
+
+// validateSecurity warns about security options Azure Container Instances cannot enforce, and fails
+// if an image runs as root when run_as_non_root is set.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	sec := m.Security
+	if sec == nil {
+		return nil
+	}
+
+	if sec.ReadOnlyRootFilesystem || sec.User != "" || len(sec.DropCapabilities) > 0 {
+		logging.Warn("security.read_only_root_filesystem, user and drop_capabilities are not supported by Azure Container Instances and will be ignored")
+	}
+
+	if !sec.RunAsNonRoot {
+		return nil
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidateNonRoot(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,9 +1,18 @@
 package azure
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
 )
 
 func TestProviderName(t *testing.T) {
@@ -13,6 +22,18 @@ func TestProviderName(t *testing.T) {
 	}
 }
 
+func TestNewCredentialOIDC(t *testing.T) {
+	sp := &manifest.AzureCredentialsConfig{TenantID: "00000000-0000-0000-0000-000000000001", ClientID: "00000000-0000-0000-0000-000000000002"}
+	creds := &manifest.CredentialsConfig{Source: "oidc", Azure: sp}
+	cred, err := NewCredential(context.Background(), sp, creds, &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "azure", Credentials: creds}})
+	if err != nil {
+		t.Fatalf("NewCredential failed: %v", err)
+	}
+	if _, ok := cred.(*azidentity.ClientAssertionCredential); !ok {
+		t.Errorf("Expected a federated client assertion credential, got %T", cred)
+	}
+}
+
 func TestGenerateRegistryName(t *testing.T) {
 	p := &Provider{}
 
@@ -194,6 +215,16 @@ func TestGenerateRegistryNameEdgeCases(t *testing.T) {
 	}
 }
 
+// pushedTags returns ACR tags pushed a minute apart in the given order.
+func pushedTags(names ...string) []acrTag {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tags := make([]acrTag, len(names))
+	for i, name := range names {
+		tags[i] = acrTag{Name: name, LastUpdateTime: base.Add(time.Duration(i) * time.Minute)}
+	}
+	return tags
+}
+
 func TestFindPreviousImageFromTags(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -205,7 +236,7 @@ func TestFindPreviousImageFromTags(t *testing.T) {
 		{
 			name:         "finds previous deploy tag",
 			currentImage: "myregistry.azurecr.io/myregistry:deploy-20260301T140000",
-			tags:         []string{"deploy-20260301T130000", "deploy-20260301T140000", "deploy-20260228T120000"},
+			tags:         []string{"deploy-20260228T120000", "deploy-20260301T130000", "deploy-20260301T140000"},
 			wantTag:      "deploy-20260301T130000",
 		},
 		{
@@ -241,7 +272,7 @@ func TestFindPreviousImageFromTags(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := findPreviousImageFromTags(tt.tags, tt.currentImage)
+			result, err := findPreviousImageFromTags(pushedTags(tt.tags...), tt.currentImage)
 
 			if tt.wantErr != "" {
 				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
@@ -313,7 +344,7 @@ func TestFindPreviousImageFromTagsEdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := findPreviousImageFromTags(tt.tags, tt.currentImage)
+			result, err := findPreviousImageFromTags(pushedTags(tt.tags...), tt.currentImage)
 
 			if tt.wantErr != "" {
 				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
@@ -332,3 +363,138 @@ func TestFindPreviousImageFromTagsEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestContainerCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		container manifest.Container
+		expected  []string
+	}{
+		{"no overrides", manifest.Container{Name: "web"}, nil},
+		{"command only", manifest.Container{Name: "web", Command: []string{"/bin/server"}}, []string{"/bin/server"}},
+		{"command and args", manifest.Container{Name: "web", Command: []string{"/bin/server"}, Args: []string{"--port", "80"}}, []string{"/bin/server", "--port", "80"}},
+		{"args only", manifest.Container{Name: "web", Args: []string{"--port", "80"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := containerCommand(tt.container)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d command parts, got %d", len(tt.expected), len(got))
+			}
+			for i, arg := range got {
+				if *arg != tt.expected[i] {
+					t.Errorf("Expected command[%d] = %q, got %q", i, tt.expected[i], *arg)
+				}
+			}
+		})
+	}
+}
+
+func TestContainerRequests(t *testing.T) {
+	containers := []manifest.Container{
+		{Name: "web"},
+		{Name: "proxy", Resources: &manifest.ContainerResources{CPU: 0.5, MemoryGB: 0.5}},
+		{Name: "logs", Resources: &manifest.ContainerResources{MemoryGB: 0.25}},
+	}
+	requests, err := containerRequests(containers, 2.0, 1.5)
+	if err != nil {
+		t.Fatalf("containerRequests() error = %v", err)
+	}
+	want := []struct{ cpu, memoryGB float64 }{{0.75, 0.75}, {0.5, 0.5}, {0.75, 0.25}}
+	for i, w := range want {
+		if *requests[i].CPU != w.cpu || *requests[i].MemoryInGB != w.memoryGB {
+			t.Errorf("%s: requests %g CPU and %g GB, want %g and %g", containers[i].Name, *requests[i].CPU, *requests[i].MemoryInGB, w.cpu, w.memoryGB)
+		}
+	}
+
+	if _, err := containerRequests(containers, 0.5, 1.5); err == nil || !strings.Contains(err.Error(), "azure.cpu") {
+		t.Errorf("Expected the group's CPU to be exhausted, got %v", err)
+	}
+}
+
+func TestFindPreviousImageByPushTime(t *testing.T) {
+	// Tag names don't determine the order: deploy-b was pushed last
+	tags := pushedTags("deploy-c", "deploy-a", "latest", "deploy-b")
+
+	result, err := findPreviousImageFromTags(tags, "reg.azurecr.io/repo:deploy-b")
+	if err != nil || result != "reg.azurecr.io/repo:deploy-a" {
+		t.Errorf("previous of deploy-b = %q, %v; want deploy-a", result, err)
+	}
+	result, err = findPreviousImageFromTags(tags, "reg.azurecr.io/repo:deploy-a")
+	if err != nil || result != "reg.azurecr.io/repo:deploy-c" {
+		t.Errorf("previous of deploy-a = %q, %v; want deploy-c", result, err)
+	}
+	if _, err := findPreviousImageFromTags(tags, "reg.azurecr.io/repo:deploy-c"); err == nil {
+		t.Error("expected no previous deployment before the first push")
+	}
+
+	// A current tag that was deleted falls back to the latest push
+	result, err = findPreviousImageFromTags(tags, "reg.azurecr.io/repo:deploy-gone")
+	if err != nil || result != "reg.azurecr.io/repo:deploy-b" {
+		t.Errorf("previous of a missing tag = %q, %v; want deploy-b", result, err)
+	}
+}
+
+func TestFindPreviousImagePinned(t *testing.T) {
+	// deploy-c redeployed the image of deploy-a
+	tags := pushedTags("deploy-a", "deploy-b", "deploy-c")
+	for i, digest := range []string{"sha256:aaa", "sha256:bbb", "sha256:aaa"} {
+		tags[i].Digest = digest
+	}
+
+	result, err := findPreviousImageFromTags(tags, "reg.azurecr.io/repo@sha256:aaa")
+	if err != nil || result != "reg.azurecr.io/repo@sha256:bbb" {
+		t.Errorf("previous of sha256:aaa = %q, %v; want deploy-b pinned by digest", result, err)
+	}
+	if _, err := findPreviousImageFromTags(tags[:1], "reg.azurecr.io/repo@sha256:aaa"); err == nil {
+		t.Error("expected no previous deployment before the first push")
+	}
+}
+
+func TestListACRTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "myregistry" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/acr/v1/myregistry/_tags" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		page := pushedTags("deploy-1", "deploy-2")
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</acr/v1/myregistry/_tags?last=deploy-2&n=100>; rel="next"`)
+		} else {
+			page = pushedTags("deploy-3")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"tags": page})
+	}))
+	defer server.Close()
+
+	tags, err := listACRTags(context.Background(), server.Client(), server.URL, "myregistry", "myregistry", "secret")
+	if err != nil {
+		t.Fatalf("listACRTags failed: %v", err)
+	}
+	if len(tags) != 3 || tags[2].Name != "deploy-3" || tags[0].LastUpdateTime.IsZero() {
+		t.Errorf("unexpected tags: %+v", tags)
+	}
+
+	if _, err := listACRTags(context.Background(), server.Client(), server.URL, "myregistry", "myregistry", "wrong"); err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Errorf("expected an authorization error, got: %v", err)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		`</acr/v1/repo/_tags?last=deploy-2&n=100>; rel="next"`: "/acr/v1/repo/_tags?last=deploy-2&n=100",
+		`</first>; rel="prev", </second>; rel="next"`:          "/second",
+		`</first>; rel="prev"`:                                 "",
+	}
+	for header, want := range tests {
+		if got := nextLink(header); got != want {
+			t.Errorf("nextLink(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
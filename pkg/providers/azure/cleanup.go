@@ -0,0 +1,120 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Prune deletes the tags of the application's ACR repository the policy
+// selects, ranked by when they were last pushed. Tags the container groups of
+// the application run are kept. Manifests left untagged are removed by the
+// registry's own retention policy, when enabled.
+func (p *Provider) Prune(ctx context.Context, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool) (*types.Cleanup, error) {
+	cleanup := &types.Cleanup{Provider: "azure", DryRun: dryRun}
+	registryName := p.generateRegistryName(m.Application.Name)
+	reg, err := p.registryClient.Get(ctx, p.resourceGroup, registryName, nil)
+	if found, err := exists(reg, err); err != nil {
+		return nil, fmt.Errorf("failed to get container registry: %w", err)
+	} else if !found {
+		return cleanup, nil
+	}
+	loginServer, password, err := p.getRegistryCredentials(ctx, registryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry credentials: %w", err)
+	}
+
+	// Images are pushed to a repository named after the registry (see registry.ACRRegistry)
+	baseURL := "https://" + loginServer
+	tags, err := listACRTags(ctx, http.DefaultClient, baseURL, registryName, registryName, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployed []string
+	pager := p.containerClient.NewListByResourceGroupPager(p.resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list container groups: %w", err)
+		}
+		for _, group := range page.Value {
+			if deref(group.Tags["Application"]) != m.Application.Name || group.Properties == nil {
+				continue
+			}
+			for _, c := range group.Properties.Containers {
+				if c.Properties != nil {
+					deployed = append(deployed, deref(c.Properties.Image))
+				}
+			}
+		}
+	}
+
+	selected := selectTags(tags, loginServer+"/"+registryName, deployed, policy, time.Now(), cleanup)
+	if dryRun {
+		return cleanup, nil
+	}
+	for _, tag := range selected {
+		logging.Info("Deleting ACR tag", "repository", registryName, "tag", tag)
+		if err := deleteACRTag(ctx, http.DefaultClient, baseURL, registryName, tag, registryName, password); err != nil {
+			return cleanup, err
+		}
+	}
+	return cleanup, nil
+}
+
+// selectTags returns the tags the policy deletes at now, and records them in
+// cleanup. Tags of repository that a deployed image references, by tag or by
+// digest, are kept.
+func selectTags(tags []acrTag, repository string, deployed []string, policy types.RetentionPolicy, now time.Time, cleanup *types.Cleanup) []string {
+	inUse := make(map[string]bool)
+	for _, image := range deployed {
+		if digest, ok := strings.CutPrefix(image, repository+"@"); ok {
+			inUse[digest] = true
+		} else if tag, ok := strings.CutPrefix(image, repository+":"); ok {
+			inUse[tag] = true
+		}
+	}
+
+	pushed := make([]time.Time, len(tags))
+	for i, tag := range tags {
+		pushed[i] = tag.LastUpdateTime
+	}
+
+	var selected []string
+	for i, expired := range policy.Expired(pushed, now) {
+		if !expired || inUse[tags[i].Name] || inUse[tags[i].Digest] {
+			cleanup.Kept++
+			continue
+		}
+		selected = append(selected, tags[i].Name)
+		cleanup.Prune("ACR tag", tags[i].Name, pushed[i])
+	}
+	return selected
+}
+
+// deleteACRTag deletes a tag of an ACR repository. baseURL is the registry's
+// https URL.
+func deleteACRTag(ctx context.Context, client *http.Client, baseURL, repository, tag, username, password string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/acr/v1/%s/_tags/%s", baseURL, repository, tag), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete tag request: %w", err)
+	}
+	req.SetBasicAuth(username, password)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete ACR tag %s: %w", tag, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete ACR tag %s: HTTP %d", tag, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestSelectTags(t *testing.T) {
+	tags := pushedTags("deploy-1", "deploy-2", "deploy-3", "deploy-4")
+	now := tags[3].LastUpdateTime.Add(time.Hour)
+	deployed := []string{"myregistry.azurecr.io/myregistry:deploy-1", "other.azurecr.io/other:deploy-2"}
+
+	cleanup := &types.Cleanup{Provider: "azure"}
+	selected := selectTags(tags, "myregistry.azurecr.io/myregistry", deployed, types.RetentionPolicy{KeepLast: 1}, now, cleanup)
+	if strings.Join(selected, " ") != "deploy-2 deploy-3" {
+		t.Errorf("selected = %v, want the expired tags not deployed from the repository", selected)
+	}
+	if len(cleanup.Deleted) != 2 || cleanup.Deleted[0] != (types.PrunedResource{Type: "ACR tag", Name: "deploy-2", Created: tags[1].LastUpdateTime}) {
+		t.Errorf("unexpected deleted resources: %+v", cleanup.Deleted)
+	}
+	if cleanup.Kept != 2 {
+		t.Errorf("Kept = %d, want 2", cleanup.Kept)
+	}
+}
+
+func TestSelectTagsPinned(t *testing.T) {
+	tags := pushedTags("deploy-1", "deploy-2", "deploy-3")
+	tags[0].Digest = "sha256:aaa"
+	tags[1].Digest = "sha256:bbb"
+	now := tags[2].LastUpdateTime.Add(time.Hour)
+	deployed := []string{"myregistry.azurecr.io/myregistry@sha256:aaa"}
+
+	cleanup := &types.Cleanup{Provider: "azure"}
+	selected := selectTags(tags, "myregistry.azurecr.io/myregistry", deployed, types.RetentionPolicy{KeepLast: 1}, now, cleanup)
+	if strings.Join(selected, " ") != "deploy-2" {
+		t.Errorf("selected = %v, want the expired tags of images not deployed", selected)
+	}
+}
+
+func TestDeleteACRTag(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "myregistry" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if err := deleteACRTag(context.Background(), server.Client(), server.URL, "myregistry", "deploy-1", "myregistry", "secret"); err != nil {
+		t.Fatalf("deleteACRTag failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/acr/v1/myregistry/_tags/deploy-1" {
+		t.Errorf("deleted = %v", deleted)
+	}
+
+	if err := deleteACRTag(context.Background(), server.Client(), server.URL, "myregistry", "deploy-1", "myregistry", "wrong"); err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Errorf("expected an authorization error, got: %v", err)
+	}
+}
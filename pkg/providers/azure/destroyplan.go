@@ -0,0 +1,46 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// PlanDestroy returns what Destroy would do: delete the environment's
+// container group. The resource group and the container registry, which the
+// application's other environments share, are kept.
+func (p *Provider) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	state, err := p.readInventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildDestroyPlan(m, p.resourceGroup, p.generateRegistryName(m.Application.Name), state), nil
+}
+
+// buildDestroyPlan returns what a destroy of m's environment does given the
+// existing resources in state.
+func buildDestroyPlan(m *manifest.Manifest, resourceGroup, registryName string, state inventoryState) *types.Plan {
+	plan := &types.Plan{Provider: "azure"}
+	others := 0
+	for _, group := range state.containerGroups {
+		if group.name == m.Environment.Name {
+			plan.Add(types.PlanDelete, "Container group", group.name, "with its containers and public IP address")
+		} else {
+			others++
+		}
+	}
+
+	shared := "kept"
+	if others > 0 {
+		shared = fmt.Sprintf("kept; shared with %d other environments", others)
+	}
+	if state.registry != "" {
+		plan.Add(types.PlanNoChange, "Container registry", registryName, shared)
+	}
+	if state.resourceGroup != "" {
+		plan.Add(types.PlanNoChange, "Resource group", resourceGroup, shared)
+	}
+	return plan
+}
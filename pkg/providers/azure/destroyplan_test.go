@@ -0,0 +1,42 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestBuildDestroyPlan(t *testing.T) {
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+	}
+	state := inventoryState{
+		resourceGroup: "/subscriptions/sub/resourceGroups/my-rg",
+		registry:      "/subscriptions/sub/resourceGroups/my-rg/providers/Microsoft.ContainerRegistry/registries/myappacr",
+		containerGroups: []containerGroupRef{
+			{name: "my-app-prod", id: "/subscriptions/sub/resourceGroups/my-rg/providers/Microsoft.ContainerInstance/containerGroups/my-app-prod"},
+			{name: "my-app-staging", id: "/subscriptions/sub/resourceGroups/my-rg/providers/Microsoft.ContainerInstance/containerGroups/my-app-staging"},
+		},
+	}
+	plan := buildDestroyPlan(m, "my-rg", "myappacr", state)
+
+	want := []types.ResourceChange{
+		{Action: types.PlanDelete, Type: "Container group", Name: "my-app-prod", Reason: "with its containers and public IP address"},
+		{Action: types.PlanNoChange, Type: "Container registry", Name: "myappacr", Reason: "kept; shared with 1 other environments"},
+		{Action: types.PlanNoChange, Type: "Resource group", Name: "my-rg", Reason: "kept; shared with 1 other environments"},
+	}
+	if plan.Provider != "azure" || len(plan.Changes) != len(want) {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	for i, c := range plan.Changes {
+		if c != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+
+	if plan := buildDestroyPlan(m, "my-rg", "myappacr", inventoryState{}); len(plan.Changes) != 0 {
+		t.Errorf("expected nothing to destroy, got %+v", plan.Changes)
+	}
+}
@@ -0,0 +1,60 @@
+package azure
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/readonly"
+)
+
+// emulatorCredential authenticates requests to a Resource Manager emulator,
+// which accepts any bearer token.
+type emulatorCredential struct{}
+
+// GetToken returns a static token.
+func (emulatorCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "emulator", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// ClientOptions returns the options of Resource Manager clients: nil for
+// Azure, or the emulator at provider.endpoint_url, reached without resource
+// provider registration and, for http:// endpoints, with tokens sent in
+// clear text. In read-only mode, requests that would change anything are
+// refused.
+func ClientOptions(config *manifest.ProviderConfig) *arm.ClientOptions {
+	var options *arm.ClientOptions
+	if config != nil && config.Emulated() {
+		options = emulatorClientOptions(config)
+	}
+	if readonly.Enabled() {
+		if options == nil {
+			options = &arm.ClientOptions{}
+		}
+		options.PerCallPolicies = append(options.PerCallPolicies, readonly.AzurePolicy{})
+	}
+	return options
+}
+
+// emulatorClientOptions returns the options of Resource Manager clients
+// calling the emulator at provider.endpoint_url.
+func emulatorClientOptions(config *manifest.ProviderConfig) *arm.ClientOptions {
+	endpoint := strings.TrimRight(config.EndpointURL, "/")
+	return &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Cloud: cloud.Configuration{
+				Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+					cloud.ResourceManager: {Endpoint: endpoint, Audience: endpoint},
+				},
+			},
+			InsecureAllowCredentialWithHTTP: strings.HasPrefix(endpoint, "http://"),
+		},
+		DisableRPRegistration: true,
+	}
+}
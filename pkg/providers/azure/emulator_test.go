@@ -0,0 +1,45 @@
+package azure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestClientOptions(t *testing.T) {
+	if opts := ClientOptions(&manifest.ProviderConfig{Name: "azure"}); opts != nil {
+		t.Errorf("Expected no options for Azure, got %+v", opts)
+	}
+
+	opts := ClientOptions(&manifest.ProviderConfig{Name: "azure", EndpointURL: "http://localhost:8080/"})
+	if opts == nil {
+		t.Fatal("Expected options for the emulator")
+	}
+	rm := opts.Cloud.Services[cloud.ResourceManager]
+	if rm.Endpoint != "http://localhost:8080" || rm.Audience != "http://localhost:8080" {
+		t.Errorf("Resource Manager = %+v, want the emulator", rm)
+	}
+	if !opts.InsecureAllowCredentialWithHTTP || !opts.DisableRPRegistration {
+		t.Errorf("Expected plain HTTP and no RP registration, got %+v", opts)
+	}
+
+	if opts := ClientOptions(&manifest.ProviderConfig{Name: "azure", EndpointURL: "https://emulator.local"}); opts.InsecureAllowCredentialWithHTTP {
+		t.Error("Expected tokens not to be sent in clear text to an https emulator")
+	}
+}
+
+func TestNewCredentialEmulator(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "azure", EndpointURL: "http://localhost:8080"}}
+	cred, err := NewCredential(context.Background(), nil, nil, m)
+	if err != nil {
+		t.Fatalf("NewCredential failed: %v", err)
+	}
+	token, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{"http://localhost:8080/.default"}})
+	if err != nil || token.Token == "" {
+		t.Errorf("GetToken = %+v, %v; want a static token", token, err)
+	}
+}
@@ -0,0 +1,121 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/secrets"
+)
+
+// UpdateEnvironmentVariables sets vars on the primary container of the running container
+// group. ACI applies environment changes by recreating the containers from the current
+// group definition, so the image is not pushed again.
+func (p *Provider) UpdateEnvironmentVariables(ctx context.Context, m *manifest.Manifest, vars map[string]string) ([]string, error) {
+	resp, err := p.containerClient.Get(ctx, p.resourceGroup, m.Environment.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container group: %w", err)
+	}
+	group := resp.ContainerGroup
+
+	container := primaryACIContainer(&group, m)
+	if container == nil {
+		return nil, fmt.Errorf("no containers found in container group")
+	}
+
+	// Secure values are never returned by the API, so they always count as changed
+	changed := secrets.Changed(aciEnvValues(container.Properties.EnvironmentVariables), vars)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	container.Properties.EnvironmentVariables = setACIEnv(container.Properties.EnvironmentVariables, m, vars, changed)
+
+	// Registry passwords are not returned by the API either; restore the ACR password
+	registryName := p.generateRegistryName(m.Application.Name)
+	loginServer, password, err := p.getRegistryCredentials(ctx, registryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry credentials: %w", err)
+	}
+	for _, cred := range group.Properties.ImageRegistryCredentials {
+		if cred.Server != nil && *cred.Server == loginServer {
+			cred.Password = to.Ptr(password)
+		}
+	}
+
+	logging.Infof("Updating %d environment variables on container group %s...", len(changed), m.Environment.Name)
+	poller, err := p.containerClient.BeginCreateOrUpdate(ctx, p.resourceGroup, m.Environment.Name, group, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update container group: %w", err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return nil, fmt.Errorf("container group update failed: %w", err)
+	}
+	if err := p.waitForContainerGroup(ctx, m.Environment.Name); err != nil {
+		return nil, fmt.Errorf("container group update failed: %w", err)
+	}
+	return changed, nil
+}
+
+// primaryACIContainer returns the container that receives the manifest's environment
+// variables: the first container, or the one named after the manifest's first container
+// in multi-container deployments.
+func primaryACIContainer(group *armcontainerinstance.ContainerGroup, m *manifest.Manifest) *armcontainerinstance.Container {
+	if group.Properties == nil || len(group.Properties.Containers) == 0 {
+		return nil
+	}
+	containers := group.Properties.Containers
+	if m.IsMultiContainer() {
+		for _, c := range containers {
+			if c.Name != nil && *c.Name == m.Containers[0].Name {
+				return ensureContainerProperties(c)
+			}
+		}
+	}
+	return ensureContainerProperties(containers[0])
+}
+
+// ensureContainerProperties makes sure c.Properties is non-nil.
+func ensureContainerProperties(c *armcontainerinstance.Container) *armcontainerinstance.Container {
+	if c.Properties == nil {
+		c.Properties = &armcontainerinstance.ContainerProperties{}
+	}
+	return c
+}
+
+// aciEnvValues returns the plain (non-secure) environment variables of a container.
+func aciEnvValues(env []*armcontainerinstance.EnvironmentVariable) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, e := range env {
+		if e.Name != nil && e.Value != nil {
+			values[*e.Name] = *e.Value
+		}
+	}
+	return values
+}
+
+// setACIEnv sets the named variables of a container of m from vars, replacing
+// existing entries in place.
+func setACIEnv(env []*armcontainerinstance.EnvironmentVariable, m *manifest.Manifest, vars map[string]string, names []string) []*armcontainerinstance.EnvironmentVariable {
+	for _, name := range names {
+		found := false
+		for _, e := range env {
+			if e.Name != nil && *e.Name == name {
+				if e.SecureValue != nil || e.Value == nil {
+					e.SecureValue = to.Ptr(vars[name])
+				} else {
+					e.Value = to.Ptr(vars[name])
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			env = append(env, aciEnvVar(m, name, vars[name]))
+		}
+	}
+	return env
+}
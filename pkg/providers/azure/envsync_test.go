@@ -0,0 +1,80 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestACIEnvValues(t *testing.T) {
+	env := []*armcontainerinstance.EnvironmentVariable{
+		{Name: to.Ptr("LOG_LEVEL"), Value: to.Ptr("info")},
+		{Name: to.Ptr("API_KEY")}, // secure values are not returned by the API
+	}
+
+	values := aciEnvValues(env)
+
+	if len(values) != 1 || values["LOG_LEVEL"] != "info" {
+		t.Errorf("Expected only LOG_LEVEL=info, got %v", values)
+	}
+}
+
+func TestSetACIEnv(t *testing.T) {
+	env := []*armcontainerinstance.EnvironmentVariable{
+		{Name: to.Ptr("LOG_LEVEL"), Value: to.Ptr("info")},
+		{Name: to.Ptr("API_KEY")},
+	}
+	vars := map[string]string{"LOG_LEVEL": "debug", "API_KEY": "new", "DB_PASSWORD": "secret"}
+
+	env = setACIEnv(env, &manifest.Manifest{}, vars, []string{"API_KEY", "DB_PASSWORD", "LOG_LEVEL"})
+
+	if len(env) != 3 {
+		t.Fatalf("Expected 3 variables, got %d", len(env))
+	}
+	if *env[0].Value != "debug" {
+		t.Errorf("Expected LOG_LEVEL=debug, got %s", *env[0].Value)
+	}
+	if env[1].SecureValue == nil || *env[1].SecureValue != "new" {
+		t.Errorf("Expected API_KEY to stay a secure value")
+	}
+	if *env[2].Name != "DB_PASSWORD" || *env[2].Value != "secret" {
+		t.Errorf("Expected DB_PASSWORD to be appended, got %+v", env[2])
+	}
+}
+
+func TestSetACIEnvNativeSecrets(t *testing.T) {
+	m := &manifest.Manifest{
+		Secrets:        []manifest.SecretRef{{Name: "DB_PASSWORD", SecretID: "db"}},
+		SecretDelivery: manifest.SecretDeliveryNative,
+	}
+	vars := map[string]string{"DB_PASSWORD": "secret"}
+
+	env := setACIEnv(nil, m, vars, []string{"DB_PASSWORD"})
+
+	if len(env) != 1 || env[0].Value != nil || env[0].SecureValue == nil || *env[0].SecureValue != "secret" {
+		t.Errorf("Expected DB_PASSWORD to be added as a secure value, got %+v", env)
+	}
+}
+
+func TestPrimaryACIContainer(t *testing.T) {
+	group := &armcontainerinstance.ContainerGroup{Properties: &armcontainerinstance.ContainerGroupProperties{
+		Containers: []*armcontainerinstance.Container{{Name: to.Ptr("sidecar")}, {Name: to.Ptr("web")}},
+	}}
+
+	multi := &manifest.Manifest{Containers: []manifest.Container{{Name: "web"}}}
+	if c := primaryACIContainer(group, multi); *c.Name != "web" || c.Properties == nil {
+		t.Errorf("Expected web container with properties, got %+v", c)
+	}
+
+	single := &manifest.Manifest{Image: "web:1"}
+	if c := primaryACIContainer(group, single); *c.Name != "sidecar" {
+		t.Errorf("Expected first container, got %s", *c.Name)
+	}
+
+	if c := primaryACIContainer(&armcontainerinstance.ContainerGroup{}, single); c != nil {
+		t.Errorf("Expected nil for empty group, got %+v", c)
+	}
+}
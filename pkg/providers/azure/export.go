@@ -0,0 +1,378 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerregistry/armcontainerregistry"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// exportFormatBicep is the export format supported by the Azure provider.
+const exportFormatBicep = "bicep"
+
+// API versions used for the exported resources.
+const (
+	bicepRegistryAPIVersion       = "2023-07-01"
+	bicepContainerGroupAPIVersion = "2023-05-01"
+)
+
+// Export renders the resources cloud-deploy created in the resource group (container
+// registry, container group and its managed identities) as a Bicep template, so they
+// can be adopted into ARM pipelines. The template is built from the live resources.
+func (p *Provider) Export(ctx context.Context, m *manifest.Manifest, format string) ([]byte, error) {
+	if format != exportFormatBicep {
+		return nil, fmt.Errorf("unsupported export format for Azure: %q (supported: %s)", format, exportFormatBicep)
+	}
+
+	groupResp, err := p.containerClient.Get(ctx, p.resourceGroup, m.Environment.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container group %s (has it been deployed?): %w", m.Environment.Name, err)
+	}
+
+	var reg *armcontainerregistry.Registry
+	registryName := p.generateRegistryName(m.Application.Name)
+	regResp, err := p.registryClient.Get(ctx, p.resourceGroup, registryName, nil)
+	if err != nil {
+		logging.Warnf("Could not read container registry %s, omitting it from the export: %v", registryName, err)
+	} else {
+		reg = &regResp.Registry
+	}
+
+	return []byte(renderBicep(p.resourceGroup, p.location, reg, &groupResp.ContainerGroup)), nil
+}
+
+// bicepWriter accumulates indented Bicep source.
+type bicepWriter struct {
+	b      strings.Builder
+	indent int
+}
+
+func (w *bicepWriter) line(format string, args ...interface{}) {
+	if format == "" {
+		w.b.WriteString("\n")
+		return
+	}
+	w.b.WriteString(strings.Repeat("  ", w.indent))
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteString("\n")
+}
+
+// open writes a line ending in an opening brace or bracket and indents.
+func (w *bicepWriter) open(format string, args ...interface{}) {
+	w.line(format, args...)
+	w.indent++
+}
+
+// close dedents and writes a closing brace or bracket.
+func (w *bicepWriter) close(s string) {
+	w.indent--
+	w.line("%s", s)
+}
+
+// bicepString quotes s as a Bicep string literal.
+func bicepString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	s = strings.ReplaceAll(s, `${`, `\${`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return "'" + s + "'"
+}
+
+// bicepNumber renders a float; Bicep has no float literals, so fractions use json().
+func bicepNumber(f float64) string {
+	if f == float64(int64(f)) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return fmt.Sprintf("json('%s')", strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// bicepIdentifier converts a name into a valid Bicep identifier.
+func bicepIdentifier(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			if upper && r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = b.Len() > 0
+		}
+	}
+	id := b.String()
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "p" + id
+	}
+	return id
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// renderBicep renders a registry (optional) and container group as a Bicep template.
+// Secrets are never written: secure environment variables and passwords for registries
+// other than the exported one become @secure() parameters, and the exported registry's
+// admin password is read with listCredentials() at deployment time.
+func renderBicep(resourceGroup, location string, reg *armcontainerregistry.Registry, group *armcontainerinstance.ContainerGroup) string {
+	w := &bicepWriter{}
+	var params []string
+
+	w.line("// Generated by cloud-deploy: resources in resource group %s.", resourceGroup)
+	w.line("// Deploy with: az deployment group create --resource-group %s --template-file <file>.bicep", resourceGroup)
+	w.line("// Compile to an ARM template with: az bicep build --file <file>.bicep")
+	w.line("")
+
+	// Resources are written into a separate buffer so parameters discovered while
+	// rendering can be declared first
+	body := &bicepWriter{}
+
+	registryLoginServer := ""
+	if reg != nil {
+		if reg.Properties != nil {
+			registryLoginServer = deref(reg.Properties.LoginServer)
+		}
+		body.open("resource registry 'Microsoft.ContainerRegistry/registries@%s' = {", bicepRegistryAPIVersion)
+		body.line("name: %s", bicepString(deref(reg.Name)))
+		body.line("location: location")
+		if reg.SKU != nil && reg.SKU.Name != nil {
+			body.open("sku: {")
+			body.line("name: %s", bicepString(string(*reg.SKU.Name)))
+			body.close("}")
+		}
+		if reg.Properties != nil && reg.Properties.AdminUserEnabled != nil {
+			body.open("properties: {")
+			body.line("adminUserEnabled: %t", *reg.Properties.AdminUserEnabled)
+			body.close("}")
+		}
+		writeBicepTags(body, reg.Tags)
+		body.close("}")
+		body.line("")
+	}
+
+	if group != nil {
+		body.open("resource containerGroup 'Microsoft.ContainerInstance/containerGroups@%s' = {", bicepContainerGroupAPIVersion)
+		body.line("name: %s", bicepString(deref(group.Name)))
+		body.line("location: location")
+		writeBicepTags(body, group.Tags)
+
+		if id := group.Identity; id != nil && id.Type != nil && *id.Type != armcontainerinstance.ResourceIdentityTypeNone {
+			body.open("identity: {")
+			body.line("type: %s", bicepString(string(*id.Type)))
+			if len(id.UserAssignedIdentities) > 0 {
+				ids := make([]string, 0, len(id.UserAssignedIdentities))
+				for k := range id.UserAssignedIdentities {
+					ids = append(ids, k)
+				}
+				sort.Strings(ids)
+				body.open("userAssignedIdentities: {")
+				for _, k := range ids {
+					body.line("%s: {}", bicepString(k))
+				}
+				body.close("}")
+			}
+			body.close("}")
+		}
+
+		props := group.Properties
+		if props == nil {
+			props = &armcontainerinstance.ContainerGroupProperties{}
+		}
+		body.open("properties: {")
+		if props.OSType != nil {
+			body.line("osType: %s", bicepString(string(*props.OSType)))
+		}
+		if props.RestartPolicy != nil {
+			body.line("restartPolicy: %s", bicepString(string(*props.RestartPolicy)))
+		}
+
+		body.open("containers: [")
+		for _, c := range props.Containers {
+			if c == nil || c.Properties == nil {
+				continue
+			}
+			params = append(params, writeBicepContainer(body, c)...)
+		}
+		body.close("]")
+
+		if ip := props.IPAddress; ip != nil {
+			body.open("ipAddress: {")
+			if ip.Type != nil {
+				body.line("type: %s", bicepString(string(*ip.Type)))
+			}
+			if ip.DNSNameLabel != nil {
+				body.line("dnsNameLabel: %s", bicepString(*ip.DNSNameLabel))
+			}
+			body.open("ports: [")
+			for _, port := range ip.Ports {
+				if port == nil || port.Port == nil {
+					continue
+				}
+				protocol := "TCP"
+				if port.Protocol != nil {
+					protocol = string(*port.Protocol)
+				}
+				body.line("{ port: %d, protocol: %s }", *port.Port, bicepString(protocol))
+			}
+			body.close("]")
+			body.close("}")
+		}
+
+		if len(props.ImageRegistryCredentials) > 0 {
+			body.open("imageRegistryCredentials: [")
+			for i, cred := range props.ImageRegistryCredentials {
+				if cred == nil {
+					continue
+				}
+				body.open("{")
+				if reg != nil && strings.EqualFold(deref(cred.Server), registryLoginServer) {
+					body.line("server: registry.properties.loginServer")
+					body.line("username: registry.name")
+					body.line("password: registry.listCredentials().passwords[0].value")
+				} else {
+					param := fmt.Sprintf("registryPassword%d", i+1)
+					params = append(params, param)
+					body.line("server: %s", bicepString(deref(cred.Server)))
+					body.line("username: %s", bicepString(deref(cred.Username)))
+					body.line("password: %s", param)
+				}
+				body.close("}")
+			}
+			body.close("]")
+		}
+
+		body.close("}")
+		body.close("}")
+	}
+
+	w.line("param location string = %s", bicepString(location))
+	for _, param := range params {
+		w.line("")
+		w.line("@secure()")
+		w.line("param %s string", param)
+	}
+	w.line("")
+	w.b.WriteString(body.b.String())
+
+	return w.b.String()
+}
+
+// writeBicepContainer writes a single container and returns any secure parameters it needs.
+func writeBicepContainer(w *bicepWriter, c *armcontainerinstance.Container) []string {
+	var params []string
+	props := c.Properties
+	name := deref(c.Name)
+
+	w.open("{")
+	w.line("name: %s", bicepString(name))
+	w.open("properties: {")
+	w.line("image: %s", bicepString(deref(props.Image)))
+
+	if len(props.Command) > 0 {
+		parts := make([]string, 0, len(props.Command))
+		for _, arg := range props.Command {
+			parts = append(parts, bicepString(deref(arg)))
+		}
+		w.line("command: [ %s ]", strings.Join(parts, ", "))
+	}
+
+	if props.Resources != nil && props.Resources.Requests != nil {
+		req := props.Resources.Requests
+		w.open("resources: {")
+		w.open("requests: {")
+		if req.CPU != nil {
+			w.line("cpu: %s", bicepNumber(*req.CPU))
+		}
+		if req.MemoryInGB != nil {
+			w.line("memoryInGB: %s", bicepNumber(*req.MemoryInGB))
+		}
+		w.close("}")
+		w.close("}")
+	}
+
+	if len(props.Ports) > 0 {
+		w.open("ports: [")
+		for _, port := range props.Ports {
+			if port == nil || port.Port == nil {
+				continue
+			}
+			protocol := "TCP"
+			if port.Protocol != nil {
+				protocol = string(*port.Protocol)
+			}
+			w.line("{ port: %d, protocol: %s }", *port.Port, bicepString(protocol))
+		}
+		w.close("]")
+	}
+
+	if len(props.EnvironmentVariables) > 0 {
+		w.open("environmentVariables: [")
+		for _, env := range props.EnvironmentVariables {
+			if env == nil {
+				continue
+			}
+			if env.SecureValue != nil || env.Value == nil {
+				param := bicepIdentifier(name + "_" + deref(env.Name))
+				params = append(params, param)
+				w.line("{ name: %s, secureValue: %s }", bicepString(deref(env.Name)), param)
+				continue
+			}
+			w.line("{ name: %s, value: %s }", bicepString(deref(env.Name)), bicepString(*env.Value))
+		}
+		w.close("]")
+	}
+
+	if probe := props.LivenessProbe; probe != nil && probe.HTTPGet != nil {
+		w.open("livenessProbe: {")
+		w.open("httpGet: {")
+		w.line("path: %s", bicepString(deref(probe.HTTPGet.Path)))
+		if probe.HTTPGet.Port != nil {
+			w.line("port: %d", *probe.HTTPGet.Port)
+		}
+		w.close("}")
+		if probe.PeriodSeconds != nil {
+			w.line("periodSeconds: %d", *probe.PeriodSeconds)
+		}
+		if probe.FailureThreshold != nil {
+			w.line("failureThreshold: %d", *probe.FailureThreshold)
+		}
+		if probe.InitialDelaySeconds != nil {
+			w.line("initialDelaySeconds: %d", *probe.InitialDelaySeconds)
+		}
+		w.close("}")
+	}
+
+	w.close("}")
+	w.close("}")
+	return params
+}
+
+// writeBicepTags writes a tags block in key order.
+func writeBicepTags(w *bicepWriter, tags map[string]*string) {
+	if len(tags) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w.open("tags: {")
+	for _, k := range keys {
+		w.line("%s: %s", bicepString(k), bicepString(deref(tags[k])))
+	}
+	w.close("}")
+}
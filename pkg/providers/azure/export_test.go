@@ -0,0 +1,187 @@
+package azure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerregistry/armcontainerregistry"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func testContainerGroup() *armcontainerinstance.ContainerGroup {
+	return &armcontainerinstance.ContainerGroup{
+		Name: to.Ptr("my-env"),
+		Tags: map[string]*string{
+			"ManagedBy":   to.Ptr("cloud-deploy"),
+			"Application": to.Ptr("my-app"),
+		},
+		Identity: &armcontainerinstance.ContainerGroupIdentity{
+			Type: to.Ptr(armcontainerinstance.ResourceIdentityTypeSystemAssigned),
+		},
+		Properties: &armcontainerinstance.ContainerGroupProperties{
+			OSType:        to.Ptr(armcontainerinstance.OperatingSystemTypesLinux),
+			RestartPolicy: to.Ptr(armcontainerinstance.ContainerGroupRestartPolicyAlways),
+			Containers: []*armcontainerinstance.Container{{
+				Name: to.Ptr("my-app"),
+				Properties: &armcontainerinstance.ContainerProperties{
+					Image: to.Ptr("myappregistry.azurecr.io/myappregistry:deploy-20240101T000000"),
+					Resources: &armcontainerinstance.ResourceRequirements{
+						Requests: &armcontainerinstance.ResourceRequests{
+							CPU:        to.Ptr(1.0),
+							MemoryInGB: to.Ptr(1.5),
+						},
+					},
+					Ports: []*armcontainerinstance.ContainerPort{
+						{Port: to.Ptr[int32](80), Protocol: to.Ptr(armcontainerinstance.ContainerNetworkProtocolTCP)},
+					},
+					EnvironmentVariables: []*armcontainerinstance.EnvironmentVariable{
+						{Name: to.Ptr("LOG_LEVEL"), Value: to.Ptr("info")},
+						{Name: to.Ptr("API_KEY"), SecureValue: to.Ptr("super-secret")},
+					},
+					LivenessProbe: &armcontainerinstance.ContainerProbe{
+						HTTPGet:       &armcontainerinstance.ContainerHTTPGet{Path: to.Ptr("/health"), Port: to.Ptr[int32](80)},
+						PeriodSeconds: to.Ptr[int32](10),
+					},
+				},
+			}},
+			IPAddress: &armcontainerinstance.IPAddress{
+				Type:         to.Ptr(armcontainerinstance.ContainerGroupIPAddressTypePublic),
+				DNSNameLabel: to.Ptr("my-env"),
+				Ports: []*armcontainerinstance.Port{
+					{Port: to.Ptr[int32](80), Protocol: to.Ptr(armcontainerinstance.ContainerGroupNetworkProtocolTCP)},
+				},
+			},
+			ImageRegistryCredentials: []*armcontainerinstance.ImageRegistryCredential{{
+				Server:   to.Ptr("myappregistry.azurecr.io"),
+				Username: to.Ptr("myappregistry"),
+				Password: to.Ptr("registry-password"),
+			}},
+		},
+	}
+}
+
+func testRegistry() *armcontainerregistry.Registry {
+	return &armcontainerregistry.Registry{
+		Name: to.Ptr("myappregistry"),
+		SKU:  &armcontainerregistry.SKU{Name: to.Ptr(armcontainerregistry.SKUNameBasic)},
+		Properties: &armcontainerregistry.RegistryProperties{
+			AdminUserEnabled: to.Ptr(true),
+			LoginServer:      to.Ptr("myappregistry.azurecr.io"),
+		},
+	}
+}
+
+func TestRenderBicep(t *testing.T) {
+	out := renderBicep("my-rg", "eastus", testRegistry(), testContainerGroup())
+
+	expected := []string{
+		"param location string = 'eastus'",
+		"resource registry 'Microsoft.ContainerRegistry/registries@2023-07-01' = {",
+		"name: 'myappregistry'",
+		"name: 'Basic'",
+		"adminUserEnabled: true",
+		"resource containerGroup 'Microsoft.ContainerInstance/containerGroups@2023-05-01' = {",
+		"type: 'SystemAssigned'",
+		"image: 'myappregistry.azurecr.io/myappregistry:deploy-20240101T000000'",
+		"cpu: 1",
+		"memoryInGB: json('1.5')",
+		"{ name: 'LOG_LEVEL', value: 'info' }",
+		"{ name: 'API_KEY', secureValue: myAppAPIKEY }",
+		"@secure()\nparam myAppAPIKEY string",
+		"path: '/health'",
+		"dnsNameLabel: 'my-env'",
+		"server: registry.properties.loginServer",
+		"password: registry.listCredentials().passwords[0].value",
+		"'ManagedBy': 'cloud-deploy'",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected Bicep to contain %q\nGot:\n%s", s, out)
+		}
+	}
+
+	for _, secret := range []string{"super-secret", "registry-password"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Bicep output must not contain secret %q", secret)
+		}
+	}
+}
+
+func TestRenderBicepWithoutRegistry(t *testing.T) {
+	out := renderBicep("my-rg", "eastus", nil, testContainerGroup())
+
+	if strings.Contains(out, "Microsoft.ContainerRegistry") {
+		t.Error("Expected no registry resource")
+	}
+	if !strings.Contains(out, "@secure()\nparam registryPassword1 string") {
+		t.Errorf("Expected registry password parameter\nGot:\n%s", out)
+	}
+	if !strings.Contains(out, "server: 'myappregistry.azurecr.io'") {
+		t.Error("Expected literal registry server")
+	}
+}
+
+func TestRenderBicepBalancedBraces(t *testing.T) {
+	out := renderBicep("my-rg", "eastus", testRegistry(), testContainerGroup())
+
+	if strings.Count(out, "{") != strings.Count(out, "}") {
+		t.Errorf("Unbalanced braces in output:\n%s", out)
+	}
+	if strings.Count(out, "[") != strings.Count(out, "]") {
+		t.Errorf("Unbalanced brackets in output:\n%s", out)
+	}
+}
+
+func TestBicepString(t *testing.T) {
+	tests := map[string]string{
+		"plain":       "'plain'",
+		"it's":        `'it\'s'`,
+		`back\slash`:  `'back\\slash'`,
+		"${template}": `'\${template}'`,
+	}
+	for in, expected := range tests {
+		if got := bicepString(in); got != expected {
+			t.Errorf("bicepString(%q) = %s, want %s", in, got, expected)
+		}
+	}
+}
+
+func TestBicepNumber(t *testing.T) {
+	tests := map[float64]string{
+		1:    "1",
+		2:    "2",
+		1.5:  "json('1.5')",
+		0.25: "json('0.25')",
+	}
+	for in, expected := range tests {
+		if got := bicepNumber(in); got != expected {
+			t.Errorf("bicepNumber(%v) = %s, want %s", in, got, expected)
+		}
+	}
+}
+
+func TestBicepIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"my-app_API_KEY": "myAppAPIKEY",
+		"web":            "web",
+		"1app":           "p1app",
+		"--":             "p",
+	}
+	for in, expected := range tests {
+		if got := bicepIdentifier(in); got != expected {
+			t.Errorf("bicepIdentifier(%q) = %s, want %s", in, got, expected)
+		}
+	}
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	p := &Provider{resourceGroup: "my-rg", location: "eastus"}
+	_, err := p.Export(context.Background(), &manifest.Manifest{}, "terraform")
+	if err == nil || !strings.Contains(err.Error(), "unsupported export format") {
+		t.Errorf("Expected unsupported format error, got: %v", err)
+	}
+}
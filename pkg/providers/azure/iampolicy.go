@@ -0,0 +1,56 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// roleDefinition is an Azure custom role definition, in the format accepted by
+// `az role definition create --role-definition`.
+type roleDefinition struct {
+	Name             string   `json:"Name"`
+	IsCustom         bool     `json:"IsCustom"`
+	Description      string   `json:"Description"`
+	Actions          []string `json:"Actions"`
+	NotActions       []string `json:"NotActions"`
+	AssignableScopes []string `json:"AssignableScopes"`
+}
+
+// IAMPolicy returns a custom role definition with the actions the deployer needs
+// for the features used in m, assignable on the manifest's resource group.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	if m.Provider.SubscriptionID == "" || m.Provider.ResourceGroup == "" {
+		return nil, fmt.Errorf("provider.subscription_id and provider.resource_group are required to scope the role")
+	}
+
+	description := fmt.Sprintf("Deploys %s to Azure Container Instances with cloud-deploy.", m.Application.Name)
+	if c := m.Provider.Credentials; c != nil && c.Source == "secrets-manager" {
+		description += " credentials-rotate also needs the Microsoft Graph Application.ReadWrite.OwnedBy application permission."
+	}
+
+	role := roleDefinition{
+		Name:        fmt.Sprintf("cloud-deploy deployer (%s)", m.Application.Name),
+		IsCustom:    true,
+		Description: description,
+		Actions: []string{
+			"Microsoft.Resources/subscriptions/resourceGroups/read",
+			"Microsoft.Resources/subscriptions/resourceGroups/write",
+			"Microsoft.ContainerRegistry/registries/read",
+			"Microsoft.ContainerRegistry/registries/write",
+			"Microsoft.ContainerRegistry/registries/listCredentials/action",
+			"Microsoft.ContainerInstance/containerGroups/read",
+			"Microsoft.ContainerInstance/containerGroups/write",
+			"Microsoft.ContainerInstance/containerGroups/delete",
+			"Microsoft.ContainerInstance/containerGroups/stop/action",
+			"Microsoft.ContainerInstance/containerGroups/containers/logs/read",
+		},
+		NotActions: []string{},
+		AssignableScopes: []string{
+			fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", m.Provider.SubscriptionID, m.Provider.ResourceGroup),
+		},
+	}
+
+	return json.MarshalIndent(role, "", "  ")
+}
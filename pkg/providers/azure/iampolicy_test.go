@@ -0,0 +1,52 @@
+package azure
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "azure", SubscriptionID: "sub-123", ResourceGroup: "my-rg"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+	}
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+
+	var role roleDefinition
+	if err := json.Unmarshal(data, &role); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	if !role.IsCustom || role.Name != "cloud-deploy deployer (my-app)" {
+		t.Errorf("Unexpected role: %+v", role)
+	}
+	if len(role.AssignableScopes) != 1 || role.AssignableScopes[0] != "/subscriptions/sub-123/resourceGroups/my-rg" {
+		t.Errorf("Expected role scoped to the resource group, got %v", role.AssignableScopes)
+	}
+	if !strings.Contains(string(data), "Microsoft.ContainerInstance/containerGroups/write") {
+		t.Error("Expected container group write action")
+	}
+	if !strings.Contains(string(data), "Microsoft.ContainerInstance/containerGroups/containers/logs/read") {
+		t.Error("Expected container logs read action")
+	}
+	if strings.Contains(role.Description, "Graph") {
+		t.Error("Unexpected Graph note without secrets-manager credentials")
+	}
+
+	m.Provider.Credentials = &manifest.CredentialsConfig{Source: "secrets-manager", SecretID: "cloud-deploy/azure"}
+	data, _ = IAMPolicy(m)
+	if !strings.Contains(string(data), "Application.ReadWrite.OwnedBy") {
+		t.Error("Expected Graph permission note for credential rotation")
+	}
+}
+
+func TestIAMPolicyRequiresScope(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected error without subscription and resource group")
+	}
+}
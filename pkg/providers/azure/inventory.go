@@ -0,0 +1,86 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// containerGroupRef is a container group found in the resource group.
+type containerGroupRef struct {
+	name string
+	id   string
+}
+
+// inventoryState records the application's existing resources by their ARM
+// resource IDs.
+type inventoryState struct {
+	resourceGroup   string
+	registry        string
+	containerGroups []containerGroupRef
+}
+
+// Inventory lists the application's resources: the resource group, the
+// container registry, and every container group in the resource group tagged
+// with the application, which includes the other environments deployed there.
+func (p *Provider) Inventory(ctx context.Context, m *manifest.Manifest) (*types.Inventory, error) {
+	state, err := p.readInventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildInventory(p.resourceGroup, p.generateRegistryName(m.Application.Name), state), nil
+}
+
+// readInventory reads which of the application's resources exist.
+func (p *Provider) readInventory(ctx context.Context, m *manifest.Manifest) (inventoryState, error) {
+	var state inventoryState
+
+	rg, err := p.resourceGroupClient.Get(ctx, p.resourceGroup, nil)
+	if found, err := exists(rg, err); err != nil {
+		return state, fmt.Errorf("failed to get resource group: %w", err)
+	} else if !found {
+		// Nothing can exist in a resource group that does not
+		return state, nil
+	}
+	state.resourceGroup = deref(rg.ID)
+
+	registryName := p.generateRegistryName(m.Application.Name)
+	registry, err := p.registryClient.Get(ctx, p.resourceGroup, registryName, nil)
+	if found, err := exists(registry, err); err != nil {
+		return state, fmt.Errorf("failed to get container registry: %w", err)
+	} else if found {
+		state.registry = deref(registry.ID)
+	}
+
+	pager := p.containerClient.NewListByResourceGroupPager(p.resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return state, fmt.Errorf("failed to list container groups: %w", err)
+		}
+		for _, group := range page.Value {
+			if deref(group.Tags["Application"]) == m.Application.Name {
+				state.containerGroups = append(state.containerGroups, containerGroupRef{name: deref(group.Name), id: deref(group.ID)})
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// buildInventory returns the inventory of the existing resources in state.
+func buildInventory(resourceGroup, registryName string, state inventoryState) *types.Inventory {
+	inventory := &types.Inventory{Provider: "azure"}
+	if state.resourceGroup != "" {
+		inventory.Add("Resource group", resourceGroup, state.resourceGroup, types.InventoryByName)
+	}
+	if state.registry != "" {
+		inventory.Add("Container registry", registryName, state.registry, types.InventoryByName)
+	}
+	for _, group := range state.containerGroups {
+		inventory.Add("Container group", group.name, group.id, types.InventoryByTag)
+	}
+	return inventory
+}
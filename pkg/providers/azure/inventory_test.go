@@ -0,0 +1,41 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestBuildInventoryEmpty(t *testing.T) {
+	inventory := buildInventory("my-rg", "myappacr", inventoryState{})
+	if inventory.Provider != "azure" || len(inventory.Resources) != 0 {
+		t.Errorf("expected no resources, got %+v", inventory)
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	state := inventoryState{
+		resourceGroup: "/subscriptions/sub/resourceGroups/my-rg",
+		registry:      "/subscriptions/sub/resourceGroups/my-rg/providers/Microsoft.ContainerRegistry/registries/myappacr",
+		containerGroups: []containerGroupRef{
+			{name: "my-app-prod", id: "/subscriptions/sub/resourceGroups/my-rg/providers/Microsoft.ContainerInstance/containerGroups/my-app-prod"},
+			{name: "my-app-staging", id: "/subscriptions/sub/resourceGroups/my-rg/providers/Microsoft.ContainerInstance/containerGroups/my-app-staging"},
+		},
+	}
+	inventory := buildInventory("my-rg", "myappacr", state)
+
+	want := []types.InventoryResource{
+		{Type: "Resource group", Name: "my-rg", ID: state.resourceGroup, Source: types.InventoryByName},
+		{Type: "Container registry", Name: "myappacr", ID: state.registry, Source: types.InventoryByName},
+		{Type: "Container group", Name: "my-app-prod", ID: state.containerGroups[0].id, Source: types.InventoryByTag},
+		{Type: "Container group", Name: "my-app-staging", ID: state.containerGroups[1].id, Source: types.InventoryByTag},
+	}
+	if len(inventory.Resources) != len(want) {
+		t.Fatalf("got %d resources, want %d: %+v", len(inventory.Resources), len(want), inventory.Resources)
+	}
+	for i, r := range inventory.Resources {
+		if r != want[i] {
+			t.Errorf("resource %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Logs prints the output of each container in the container group.
+// Azure Container Instances only keeps the logs of the running containers,
+// so output from before the last restart is not available.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	groupName := m.Environment.Name
+	resp, err := p.containerClient.Get(ctx, p.resourceGroup, groupName, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get container group: %w", err)
+	}
+
+	var containers []string
+	if resp.Properties != nil {
+		for _, c := range resp.Properties.Containers {
+			if c.Name != nil {
+				containers = append(containers, *c.Name)
+			}
+		}
+	}
+	if len(containers) == 0 {
+		return fmt.Errorf("container group %s has no containers", groupName)
+	}
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var entries []types.LogEntry
+		for _, container := range containers {
+			logs, err := p.containersClient.ListLogs(ctx, p.resourceGroup, groupName, container,
+				&armcontainerinstance.ContainersClientListLogsOptions{Timestamps: to.Ptr(true)})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get logs for container %s: %w", container, err)
+			}
+			if logs.Content == nil {
+				continue
+			}
+			for _, e := range parseContainerLogs(container, *logs.Content) {
+				if !e.Timestamp.Before(since) {
+					entries = append(entries, e)
+				}
+			}
+		}
+		return entries, nil
+	})
+}
+
+// parseContainerLogs splits container log output requested with timestamps into
+// entries. Each line starts with an RFC 3339 timestamp; lines without one are
+// continuations of the previous entry.
+func parseContainerLogs(container, content string) []types.LogEntry {
+	var entries []types.LogEntry
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		stamp, message, _ := strings.Cut(line, " ")
+		ts, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			if len(entries) > 0 {
+				last := &entries[len(entries)-1]
+				last.Message += "\n" + line
+				last.ID += "\n" + line
+			}
+			continue
+		}
+		entries = append(entries, types.LogEntry{
+			// The API has no entry IDs; the timestamped line identifies the entry
+			ID:        container + " " + line,
+			Timestamp: ts,
+			Source:    container,
+			Message:   message,
+		})
+	}
+	return entries
+}
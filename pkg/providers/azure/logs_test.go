@@ -0,0 +1,36 @@
+package azure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseContainerLogs(t *testing.T) {
+	content := "2024-05-01T12:00:00.123456789Z starting\n" +
+		"2024-05-01T12:00:01Z panic: boom\n" +
+		"goroutine 1 [running]:\n"
+
+	entries := parseContainerLogs("web", content)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC); !entries[0].Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", entries[0].Timestamp, want)
+	}
+	if entries[0].Source != "web" || entries[0].Message != "starting" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Message != "panic: boom\ngoroutine 1 [running]:" {
+		t.Errorf("Expected continuation line to be joined, got %q", entries[1].Message)
+	}
+	if entries[0].ID == entries[1].ID {
+		t.Error("Expected entries to have distinct IDs")
+	}
+}
+
+func TestParseContainerLogsEmpty(t *testing.T) {
+	if entries := parseContainerLogs("web", ""); len(entries) != 0 {
+		t.Errorf("Expected no entries, got %+v", entries)
+	}
+}
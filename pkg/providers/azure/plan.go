@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// planState records which of a deployment's resources already exist.
+type planState struct {
+	resourceGroup  bool
+	registry       bool
+	containerGroup bool
+}
+
+// Plan returns the changes Deploy would make for the manifest without changing
+// any Azure resources.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	m = m.WithSidecars()
+	var state planState
+
+	rg, err := p.resourceGroupClient.CheckExistence(ctx, p.resourceGroup, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check resource group: %w", err)
+	}
+	state.resourceGroup = rg.Success
+
+	// Nothing can exist in a resource group that does not
+	if state.resourceGroup {
+		registryName := p.generateRegistryName(m.Application.Name)
+		if state.registry, err = exists(p.registryClient.Get(ctx, p.resourceGroup, registryName, nil)); err != nil {
+			return nil, fmt.Errorf("failed to get container registry: %w", err)
+		}
+		if state.containerGroup, err = exists(p.containerClient.Get(ctx, p.resourceGroup, m.Environment.Name, nil)); err != nil {
+			return nil, fmt.Errorf("failed to get container group: %w", err)
+		}
+	}
+
+	return buildPlan(m, p.resourceGroup, p.generateRegistryName(m.Application.Name), state), nil
+}
+
+// exists reports whether an ARM Get call found its resource.
+func exists[T any](_ T, err error) (bool, error) {
+	var respErr *azcore.ResponseError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// buildPlan returns the changes a deployment of m makes given the existing resources.
+func buildPlan(m *manifest.Manifest, resourceGroup, registryName string, state planState) *types.Plan {
+	plan := &types.Plan{Provider: "azure"}
+
+	if state.resourceGroup {
+		plan.Add(types.PlanNoChange, "Resource group", resourceGroup, "")
+	} else {
+		plan.Add(types.PlanCreate, "Resource group", resourceGroup, "resource group does not exist")
+	}
+	if state.registry {
+		plan.Add(types.PlanNoChange, "Container registry", registryName, "")
+	} else {
+		plan.Add(types.PlanCreate, "Container registry", registryName, "registry does not exist")
+	}
+
+	// Single-container images get a new timestamped tag on every deploy
+	repository := fmt.Sprintf("%s.azurecr.io/%s", registryName, registryName)
+	if m.IsMultiContainer() {
+		action := types.PlanUpdate
+		if !state.registry {
+			action = types.PlanCreate
+		}
+		for _, c := range m.Containers {
+			plan.Add(action, "Container image", repository+":"+c.Name, "push "+c.Image)
+		}
+	} else {
+		plan.Add(types.PlanCreate, "Container image", repository+":deploy-<timestamp>", "push "+m.Image)
+	}
+
+	if state.containerGroup {
+		plan.Add(types.PlanUpdate, "Container group", m.Environment.Name, "redeploy with the new image")
+	} else {
+		plan.Add(types.PlanCreate, "Container group", m.Environment.Name, "container group does not exist")
+	}
+	return plan
+}
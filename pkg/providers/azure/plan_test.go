@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func planManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Image:       "my-app:1.2.0",
+	}
+}
+
+func changeActions(plan *types.Plan) map[string]types.PlanAction {
+	actions := make(map[string]types.PlanAction)
+	for _, c := range plan.Changes {
+		actions[c.Type+" "+c.Name] = c.Action
+	}
+	return actions
+}
+
+func TestBuildPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		state planState
+		want  map[string]types.PlanAction
+	}{
+		{
+			name:  "first deploy",
+			state: planState{},
+			want: map[string]types.PlanAction{
+				"Resource group my-rg":                                      types.PlanCreate,
+				"Container registry myapp":                                  types.PlanCreate,
+				"Container image myapp.azurecr.io/myapp:deploy-<timestamp>": types.PlanCreate,
+				"Container group my-app-prod":                               types.PlanCreate,
+			},
+		},
+		{
+			name:  "redeploy",
+			state: planState{resourceGroup: true, registry: true, containerGroup: true},
+			want: map[string]types.PlanAction{
+				"Resource group my-rg":                                      types.PlanNoChange,
+				"Container registry myapp":                                  types.PlanNoChange,
+				"Container image myapp.azurecr.io/myapp:deploy-<timestamp>": types.PlanCreate,
+				"Container group my-app-prod":                               types.PlanUpdate,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := buildPlan(planManifest(), "my-rg", "myapp", tt.state)
+			actions := changeActions(plan)
+			for key, want := range tt.want {
+				if actions[key] != want {
+					t.Errorf("%s: got %q, want %q", key, actions[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildPlanMultiContainer(t *testing.T) {
+	m := planManifest()
+	m.Image = ""
+	m.Containers = []manifest.Container{{Name: "web", Image: "web:1"}}
+
+	plan := buildPlan(m, "my-rg", "myapp", planState{resourceGroup: true, registry: true})
+	if got := changeActions(plan)["Container image myapp.azurecr.io/myapp:web"]; got != types.PlanUpdate {
+		t.Errorf("image action = %q, want update", got)
+	}
+}
+
+func TestExists(t *testing.T) {
+	if ok, err := exists(struct{}{}, nil); !ok || err != nil {
+		t.Errorf("exists(nil) = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := exists(struct{}{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}); ok || err != nil {
+		t.Errorf("exists(404) = %v, %v; want false, nil", ok, err)
+	}
+	if _, err := exists(struct{}{}, &azcore.ResponseError{StatusCode: http.StatusForbidden}); err == nil {
+		t.Error("exists(403) should return the error")
+	}
+	if _, err := exists(struct{}{}, errors.New("network down")); err == nil {
+		t.Error("exists should return other errors")
+	}
+}
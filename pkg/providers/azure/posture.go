@@ -0,0 +1,93 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// resourceProviders are the resource providers a subscription must have
+// registered to deploy to Container Instances.
+var resourceProviders = []string{
+	"Microsoft.ContainerInstance",
+	"Microsoft.ContainerRegistry",
+}
+
+// Posture checks that the provider's credentials can authenticate, the
+// registration of the resource providers deployments need, and the
+// subscription's Container Instances quotas in the location. Client secret
+// expiry is only visible in Microsoft Entra ID, so it is not checked.
+func (p *Provider) Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error) {
+	posture := &types.Posture{Provider: "azure"}
+
+	// Nothing else can be checked without a token
+	if _, err := p.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}}); err != nil {
+		posture.Add(types.PostureCredentials, "Azure credentials", types.PostureFailing, err.Error())
+		return posture, nil
+	}
+	posture.Add(types.PostureCredentials, "Azure credentials", types.PostureOK, "authenticated")
+
+	providersClient, err := armresources.NewProvidersClient(p.subscriptionID, p.credential, p.clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource providers client: %w", err)
+	}
+	for _, namespace := range resourceProviders {
+		resp, err := providersClient.Get(ctx, namespace, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get resource provider %s: %w", namespace, err)
+		}
+		addRegistration(posture, namespace, resp.RegistrationState)
+	}
+
+	locationClient, err := armcontainerinstance.NewLocationClient(p.subscriptionID, p.credential, p.clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create location client: %w", err)
+	}
+	var usages []*armcontainerinstance.Usage
+	pager := locationClient.NewListUsagePager(p.location, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Container Instances usage in %s: %w", p.location, err)
+		}
+		usages = append(usages, page.Value...)
+	}
+	addUsages(posture, p.location, usages)
+	return posture, nil
+}
+
+// addRegistration adds the check of a resource provider's registration.
+func addRegistration(posture *types.Posture, namespace string, state *string) {
+	switch {
+	case state != nil && *state == "Registered":
+		posture.Add(types.PostureAPI, namespace, types.PostureOK, "registered")
+	case state != nil:
+		posture.Add(types.PostureAPI, namespace, types.PostureFailing, *state)
+	default:
+		posture.Add(types.PostureAPI, namespace, types.PostureFailing, "not registered")
+	}
+}
+
+// addUsages adds a quota check for each Container Instances usage. Usages
+// with a limit of zero are resources the subscription cannot use at all in
+// the location (such as GPU cores) and are skipped.
+func addUsages(posture *types.Posture, location string, usages []*armcontainerinstance.Usage) {
+	for _, u := range usages {
+		if u.Name == nil || u.CurrentValue == nil || u.Limit == nil || *u.Limit <= 0 {
+			continue
+		}
+		name := ""
+		if u.Name.LocalizedValue != nil {
+			name = *u.Name.LocalizedValue
+		} else if u.Name.Value != nil {
+			name = *u.Name.Value
+		}
+		posture.AddQuota(fmt.Sprintf("%s (%s)", name, location), int64(*u.CurrentValue), int64(*u.Limit))
+	}
+}
@@ -0,0 +1,77 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// failingCredential is a credential that cannot get tokens.
+type failingCredential struct{}
+
+func (failingCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{}, errors.New("client secret has expired")
+}
+
+func TestPostureCredentialsFailing(t *testing.T) {
+	p := &Provider{subscriptionID: "sub", location: "eastus", credential: failingCredential{}}
+	posture, err := p.Posture(context.Background(), &manifest.Manifest{})
+	if err != nil {
+		t.Fatalf("Posture failed: %v", err)
+	}
+	if len(posture.Checks) != 1 || posture.Checks[0].Status != types.PostureFailing || posture.Checks[0].Detail != "client secret has expired" {
+		t.Errorf("Expected only a failing credentials check, got %+v", posture.Checks)
+	}
+}
+
+func TestAddRegistration(t *testing.T) {
+	posture := &types.Posture{}
+	addRegistration(posture, "Microsoft.ContainerInstance", to.Ptr("Registered"))
+	addRegistration(posture, "Microsoft.ContainerRegistry", to.Ptr("NotRegistered"))
+	addRegistration(posture, "Microsoft.Storage", nil)
+
+	want := []types.PostureCheck{
+		{Category: types.PostureAPI, Name: "Microsoft.ContainerInstance", Status: types.PostureOK, Detail: "registered"},
+		{Category: types.PostureAPI, Name: "Microsoft.ContainerRegistry", Status: types.PostureFailing, Detail: "NotRegistered"},
+		{Category: types.PostureAPI, Name: "Microsoft.Storage", Status: types.PostureFailing, Detail: "not registered"},
+	}
+	for i, c := range posture.Checks {
+		if c != want[i] {
+			t.Errorf("Check %d: got %+v, want %+v", i, c, want[i])
+		}
+	}
+}
+
+func TestAddUsages(t *testing.T) {
+	usage := func(name string, current, limit int32) *armcontainerinstance.Usage {
+		return &armcontainerinstance.Usage{
+			Name:         &armcontainerinstance.UsageName{Value: to.Ptr(name)},
+			CurrentValue: to.Ptr(current),
+			Limit:        to.Ptr(limit),
+		}
+	}
+	posture := &types.Posture{}
+	addUsages(posture, "eastus", []*armcontainerinstance.Usage{
+		usage("ContainerGroups", 95, 100),
+		usage("StandardCores", 4, 100),
+		usage("StandardK80Cores", 0, 0),
+	})
+
+	if len(posture.Checks) != 2 {
+		t.Fatalf("Expected usages without a limit to be skipped, got %+v", posture.Checks)
+	}
+	if c := posture.Checks[0]; c.Name != "ContainerGroups (eastus)" || c.Status != types.PostureWarning || c.Detail != "95 of 100 used" {
+		t.Errorf("Unexpected container groups check: %+v", c)
+	}
+	if c := posture.Checks[1]; c.Status != types.PostureOK {
+		t.Errorf("Unexpected cores check: %+v", c)
+	}
+}
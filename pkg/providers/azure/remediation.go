@@ -0,0 +1,63 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// RemediationEvents returns the restarts of the containers in the container
+// group. Azure Container Instances only keeps each container's restart count
+// and the state it last exited with, so one event is reported per restarted
+// container, timed at its last exit; exits before since are skipped.
+// Restarts without a recorded exit time are always reported.
+func (p *Provider) RemediationEvents(ctx context.Context, m *manifest.Manifest, since time.Time) ([]types.RemediationEvent, error) {
+	resp, err := p.containerClient.Get(ctx, p.resourceGroup, m.Environment.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container group: %w", err)
+	}
+	if resp.Properties == nil {
+		return nil, nil
+	}
+
+	var events []types.RemediationEvent
+	for _, c := range resp.Properties.Containers {
+		if event, ok := restartEvent(c); ok && (event.Timestamp.IsZero() || !event.Timestamp.Before(since)) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// restartEvent converts the instance view of a container that restarted.
+// Containers that never restarted are skipped.
+func restartEvent(c *armcontainerinstance.Container) (types.RemediationEvent, bool) {
+	if c == nil || c.Properties == nil || c.Properties.InstanceView == nil {
+		return types.RemediationEvent{}, false
+	}
+	view := c.Properties.InstanceView
+	if view.RestartCount == nil || *view.RestartCount == 0 {
+		return types.RemediationEvent{}, false
+	}
+
+	event := types.RemediationEvent{
+		Kind:    types.RemediationContainerRestarted,
+		Source:  deref(c.Name),
+		Message: fmt.Sprintf("restarted %d time(s)", *view.RestartCount),
+	}
+	if last := view.PreviousState; last != nil {
+		if last.FinishTime != nil {
+			event.Timestamp = *last.FinishTime
+		}
+		event.ExitCode = last.ExitCode
+		if detail := deref(last.DetailStatus); detail != "" {
+			event.Message += "; last exit: " + detail
+		}
+	}
+	return event, true
+}
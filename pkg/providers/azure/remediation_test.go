@@ -0,0 +1,50 @@
+package azure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestRestartEvent(t *testing.T) {
+	finished := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	container := func(view *armcontainerinstance.ContainerPropertiesInstanceView) *armcontainerinstance.Container {
+		return &armcontainerinstance.Container{
+			Name:       to.Ptr("web"),
+			Properties: &armcontainerinstance.ContainerProperties{InstanceView: view},
+		}
+	}
+
+	event, ok := restartEvent(container(&armcontainerinstance.ContainerPropertiesInstanceView{
+		RestartCount: to.Ptr(int32(3)),
+		PreviousState: &armcontainerinstance.ContainerState{
+			State:        to.Ptr("Terminated"),
+			DetailStatus: to.Ptr("Error"),
+			ExitCode:     to.Ptr(int32(137)),
+			FinishTime:   to.Ptr(finished),
+		},
+	}))
+	if !ok {
+		t.Fatal("Expected a restarted container to be reported")
+	}
+	if event.Kind != types.RemediationContainerRestarted || event.Source != "web" || !event.Timestamp.Equal(finished) {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Message != "restarted 3 time(s); last exit: Error" {
+		t.Errorf("Unexpected message: %q", event.Message)
+	}
+	if event.ExitCode == nil || *event.ExitCode != 137 {
+		t.Errorf("Expected exit code 137, got %v", event.ExitCode)
+	}
+
+	if _, ok := restartEvent(container(&armcontainerinstance.ContainerPropertiesInstanceView{RestartCount: to.Ptr(int32(0))})); ok {
+		t.Error("Expected a container that never restarted to be skipped")
+	}
+	if _, ok := restartEvent(container(nil)); ok {
+		t.Error("Expected a container without an instance view to be skipped")
+	}
+}
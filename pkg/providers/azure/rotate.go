@@ -0,0 +1,181 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// graphEndpoint is the Microsoft Graph API used to manage application client secrets.
+var graphEndpoint = "https://graph.microsoft.com/v1.0"
+
+// newClientSecretCredential creates a credential for a service principal client secret.
+var newClientSecretCredential = func(tenantID, clientID, secret string) (azcore.TokenCredential, error) {
+	return azidentity.NewClientSecretCredential(tenantID, clientID, secret, nil)
+}
+
+// passwordCredential is a client secret of a Microsoft Entra application.
+type passwordCredential struct {
+	KeyID       string `json:"keyId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Hint        string `json:"hint,omitempty"`
+	SecretText  string `json:"secretText,omitempty"`
+}
+
+// CreateCredentials adds a new client secret to the application of the current
+// service principal. The service principal needs permission to manage its own
+// application (Application.ReadWrite.OwnedBy).
+func (p *Provider) CreateCredentials(ctx context.Context, m *manifest.Manifest, current *credentials.ProviderCredentials) (*credentials.ProviderCredentials, error) {
+	cred, err := newClientSecretCredential(current.Azure.TenantID, current.Azure.ClientID, current.Azure.ClientSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service principal credential: %w", err)
+	}
+
+	body := map[string]interface{}{
+		"passwordCredential": passwordCredential{
+			DisplayName: "cloud-deploy " + time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		},
+	}
+	var created passwordCredential
+	if err := graphRequest(ctx, cred, http.MethodPost, applicationPath(current.Azure.ClientID)+"/addPassword", body, &created); err != nil {
+		return nil, fmt.Errorf("failed to add client secret: %w", err)
+	}
+	logging.Infof("Created client secret %s for application %s", created.KeyID, current.Azure.ClientID)
+
+	rotated := *current
+	rotated.Azure.ClientSecret = created.SecretText
+	return &rotated, nil
+}
+
+// VerifyCredentials checks that the client secret in creds can obtain a token
+// for Azure Resource Manager.
+func (p *Provider) VerifyCredentials(ctx context.Context, m *manifest.Manifest, creds *credentials.ProviderCredentials) error {
+	cred, err := newClientSecretCredential(creds.Azure.TenantID, creds.Azure.ClientID, creds.Azure.ClientSecret)
+	if err != nil {
+		return fmt.Errorf("failed to create service principal credential: %w", err)
+	}
+	return credentials.WaitUntilValid(ctx, func(ctx context.Context) error {
+		_, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+		return err
+	})
+}
+
+// RevokeCredentials removes the revoked client secret from the application.
+// Graph only exposes the first characters of each secret (its hint), so the
+// secret is identified by hint and must be unambiguous.
+func (p *Provider) RevokeCredentials(ctx context.Context, m *manifest.Manifest, active, revoked *credentials.ProviderCredentials) error {
+	cred, err := newClientSecretCredential(active.Azure.TenantID, active.Azure.ClientID, active.Azure.ClientSecret)
+	if err != nil {
+		return fmt.Errorf("failed to create service principal credential: %w", err)
+	}
+
+	var app struct {
+		PasswordCredentials []passwordCredential `json:"passwordCredentials"`
+	}
+	path := applicationPath(revoked.Azure.ClientID)
+	if err := graphRequest(ctx, cred, http.MethodGet, path+"?$select=passwordCredentials", nil, &app); err != nil {
+		return fmt.Errorf("failed to list client secrets: %w", err)
+	}
+
+	keyID, err := findPasswordCredential(app.PasswordCredentials, revoked.Azure.ClientSecret, active.Azure.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]string{"keyId": keyID}
+	if err := graphRequest(ctx, cred, http.MethodPost, path+"/removePassword", body, nil); err != nil {
+		return fmt.Errorf("failed to remove client secret %s: %w", keyID, err)
+	}
+	logging.Infof("Removed client secret %s from application %s", keyID, revoked.Azure.ClientID)
+	return nil
+}
+
+// findPasswordCredential returns the key ID of the application secret whose hint
+// matches secret. It fails when the hint also matches the secret being kept.
+func findPasswordCredential(creds []passwordCredential, secret, keep string) (string, error) {
+	if len(secret) < 3 {
+		return "", fmt.Errorf("client secret is too short to identify")
+	}
+	hint := secret[:3]
+	if strings.HasPrefix(keep, hint) {
+		return "", fmt.Errorf("old and new client secrets share the hint %q; remove the old secret manually", hint)
+	}
+
+	var matches []string
+	for _, c := range creds {
+		if c.Hint == hint {
+			matches = append(matches, c.KeyID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no client secret with hint %q found on the application", hint)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("multiple client secrets have the hint %q (%s); remove the old secret manually", hint, strings.Join(matches, ", "))
+	}
+}
+
+// applicationPath returns the Graph path of the application with the given client ID.
+func applicationPath(clientID string) string {
+	return fmt.Sprintf("/applications(appId='%s')", clientID)
+}
+
+// graphRequest sends a Microsoft Graph request authenticated with cred, encoding
+// in as the JSON body and decoding the response into out. in and out may be nil.
+func graphRequest(ctx context.Context, cred azcore.TokenCredential, method, path string, in, out interface{}) error {
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://graph.microsoft.com/.default"}})
+	if err != nil {
+		return fmt.Errorf("failed to get Microsoft Graph token: %w", err)
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, graphEndpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
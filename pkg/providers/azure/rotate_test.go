@@ -0,0 +1,118 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// staticCredential issues the client secret as the access token.
+type staticCredential struct {
+	secret string
+}
+
+func (c staticCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: c.secret}, nil
+}
+
+func testGraphServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	originalEndpoint, originalCredential := graphEndpoint, newClientSecretCredential
+	graphEndpoint = server.URL
+	newClientSecretCredential = func(tenantID, clientID, secret string) (azcore.TokenCredential, error) {
+		return staticCredential{secret: secret}, nil
+	}
+	t.Cleanup(func() {
+		graphEndpoint, newClientSecretCredential = originalEndpoint, originalCredential
+		server.Close()
+	})
+}
+
+func azureCredentials(secret string) *credentials.ProviderCredentials {
+	creds := &credentials.ProviderCredentials{}
+	creds.Azure.TenantID = "tenant"
+	creds.Azure.ClientID = "client-id"
+	creds.Azure.ClientSecret = secret
+	creds.Azure.SubscriptionID = "sub"
+	return creds
+}
+
+func TestCreateCredentials(t *testing.T) {
+	testGraphServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/applications(appId='client-id')/addPassword" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer old-secret" {
+			t.Errorf("Expected request authenticated with current secret, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"keyId":"new-key","hint":"new","secretText":"new-secret"}`))
+	})
+
+	rotated, err := (&Provider{}).CreateCredentials(context.Background(), &manifest.Manifest{}, azureCredentials("old-secret"))
+	if err != nil {
+		t.Fatalf("CreateCredentials failed: %v", err)
+	}
+	if rotated.Azure.ClientSecret != "new-secret" || rotated.Azure.SubscriptionID != "sub" {
+		t.Errorf("Unexpected rotated credentials: %+v", rotated.Azure)
+	}
+}
+
+func TestRevokeCredentials(t *testing.T) {
+	var removed string
+	testGraphServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"passwordCredentials":[{"keyId":"old-key","hint":"old"},{"keyId":"new-key","hint":"new"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/removePassword"):
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			removed = body["keyId"]
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	err := (&Provider{}).RevokeCredentials(context.Background(), &manifest.Manifest{}, azureCredentials("new-secret"), azureCredentials("old-secret"))
+	if err != nil {
+		t.Fatalf("RevokeCredentials failed: %v", err)
+	}
+	if removed != "old-key" {
+		t.Errorf("Expected old-key to be removed, got %q", removed)
+	}
+}
+
+func TestFindPasswordCredential(t *testing.T) {
+	creds := []passwordCredential{
+		{KeyID: "a", Hint: "abc"},
+		{KeyID: "b", Hint: "xyz"},
+		{KeyID: "c", Hint: "xyz"},
+	}
+
+	if keyID, err := findPasswordCredential(creds, "abc123", "def456"); err != nil || keyID != "a" {
+		t.Errorf("Expected a, got %q (%v)", keyID, err)
+	}
+
+	tests := map[string][2]string{
+		"no client secret": {"qqq123", "def456"},
+		"multiple":         {"xyz123", "def456"},
+		"share the hint":   {"abc123", "abc999"},
+		"too short":        {"ab", "def456"},
+	}
+	for want, args := range tests {
+		_, err := findPasswordCredential(creds, args[0], args[1])
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Scale always fails: a container group runs one fixed set of containers and
+// Azure Container Instances has no autoscaling, so there are no instance counts
+// to change. Running more copies means deploying more environments behind a
+// load balancer.
+func (p *Provider) Scale(ctx context.Context, m *manifest.Manifest) error {
+	return fmt.Errorf("Azure Container Instances does not autoscale: container group %s runs a fixed set of containers (deploy more environments behind a load balancer to run more copies)", m.Environment.Name)
+}
@@ -0,0 +1,20 @@
+package azure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestScaleUnsupported(t *testing.T) {
+	m := &manifest.Manifest{
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Instance:    manifest.InstanceConfig{MinInstances: 2, MaxInstances: 10},
+	}
+	err := (&Provider{}).Scale(context.Background(), m)
+	if err == nil || !strings.Contains(err.Error(), "does not autoscale: container group my-app-prod") {
+		t.Errorf("Expected a clear error, got: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Container Instances limits of a container group in most locations.
+const (
+	maxContainerGroupCPU      = 4
+	maxContainerGroupMemoryGB = 16
+)
+
+// Validate checks that Container Instances is offered in the location and
+// that the container group's CPU and memory are within its limits.
+func (p *Provider) Validate(ctx context.Context, m *manifest.Manifest) ([]types.ValidationFinding, error) {
+	var findings []types.ValidationFinding
+	add := func(field, format string, args ...any) {
+		findings = append(findings, types.ValidationFinding{Provider: "azure", Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	locations, err := p.containerGroupLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !locations[normalizeLocation(p.location)] {
+		add("provider.region", "Container Instances is not offered in %q", p.location)
+	}
+
+	if m.Azure != nil {
+		if m.Azure.CPU < 0 || m.Azure.CPU > maxContainerGroupCPU {
+			add("azure.cpu", "%g must be more than 0 and at most %d cores", m.Azure.CPU, maxContainerGroupCPU)
+		}
+		if m.Azure.MemoryGB < 0 || m.Azure.MemoryGB > maxContainerGroupMemoryGB {
+			add("azure.memory_gb", "%g must be more than 0 and at most %d GB", m.Azure.MemoryGB, maxContainerGroupMemoryGB)
+		}
+	}
+	return findings, nil
+}
+
+// containerGroupLocations returns the locations Container Instances offers
+// container groups in, normalized by normalizeLocation.
+func (p *Provider) containerGroupLocations(ctx context.Context) (map[string]bool, error) {
+	client, err := armresources.NewProvidersClient(p.subscriptionID, p.credential, p.clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource providers client: %w", err)
+	}
+	resp, err := client.Get(ctx, "Microsoft.ContainerInstance", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource provider Microsoft.ContainerInstance: %w", err)
+	}
+	locations := map[string]bool{}
+	for _, rt := range resp.ResourceTypes {
+		if rt.ResourceType == nil || !strings.EqualFold(*rt.ResourceType, "containerGroups") {
+			continue
+		}
+		for _, location := range rt.Locations {
+			if location != nil {
+				locations[normalizeLocation(*location)] = true
+			}
+		}
+	}
+	return locations, nil
+}
+
+// normalizeLocation returns the name of a location from its display name, so
+// "East US" and "eastus" compare equal.
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
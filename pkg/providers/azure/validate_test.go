@@ -0,0 +1,58 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/sub/providers/Microsoft.ContainerInstance" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"namespace":"Microsoft.ContainerInstance","resourceTypes":[
+{"resourceType":"containerGroups","locations":["East US","West Europe"]},
+{"resourceType":"containerGroupProfiles","locations":["Brazil South"]}]}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{
+		subscriptionID: "sub",
+		location:       "eastus",
+		credential:     emulatorCredential{},
+		clientOptions:  ClientOptions(&manifest.ProviderConfig{Name: "azure", EndpointURL: server.URL}),
+	}
+	m := &manifest.Manifest{Azure: &manifest.AzureConfig{CPU: 2, MemoryGB: 8}}
+	findings, err := p.Validate(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v", findings)
+	}
+
+	p.location = "brazilsouth"
+	m.Azure = &manifest.AzureConfig{CPU: 8, MemoryGB: 32}
+	findings, err = p.Validate(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	want := []string{
+		`provider.region: Container Instances is not offered in "brazilsouth"`,
+		"azure.cpu: 8 must be more than 0 and at most 4 cores",
+		"azure.memory_gb: 32 must be more than 0 and at most 16 GB",
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("Expected %d findings, got %+v", len(want), findings)
+	}
+	for i, f := range findings {
+		if got := f.Field + ": " + f.Message; got != want[i] {
+			t.Errorf("Finding %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,823 @@
+// Package azurecontainerapps provides an Azure Container Apps provider that
+// runs the manifest's containers as a container app: HTTPS ingress in front
+// of replicas that scale by scale rules, down to zero when idle. Every
+// deployment creates a revision of the app, so Rollback can return to the
+// template of an earlier revision. Images are pushed to the application's
+// ACR registry, the same registry the azure provider uses.
+package azurecontainerapps
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	"github.com/jvreagan/cloud-deploy/pkg/azureapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// API versions of the resource providers the provider calls.
+const (
+	appsAPIVersion          = "2024-03-01"
+	resourceGroupAPIVersion = "2021-04-01"
+	registryAPIVersion      = "2023-07-01"
+)
+
+// registryPasswordSecret is the app secret holding the ACR admin password
+// Container Apps pulls images with.
+const registryPasswordSecret = "registry-password"
+
+// pollInterval is how often a revision is checked while waiting for it.
+var pollInterval = 5 * time.Second
+
+// revisionTimeout is how long to wait for a new revision to be provisioned.
+var revisionTimeout = 15 * time.Minute
+
+// Provider implements the provider.Provider interface for Azure Container Apps.
+type Provider struct {
+	subscriptionID string
+	location       string
+	resourceGroup  string
+	credential     azcore.TokenCredential
+	client         *azureapi.Client
+	httpClient     *http.Client
+}
+
+// New creates a new Azure Container Apps provider instance. Credentials are
+// loaded as they are for the azure provider.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	if config.SubscriptionID == "" {
+		return nil, fmt.Errorf("subscription ID is required")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("location is required")
+	}
+	if config.ResourceGroup == "" {
+		return nil, fmt.Errorf("resource group is required")
+	}
+
+	var azureCreds *manifest.AzureCredentialsConfig
+	if config.Credentials != nil {
+		azureCreds = config.Credentials.Azure
+	}
+	cred, err := azure.NewCredential(ctx, azureCreds, config.Credentials, m)
+	if err != nil {
+		return nil, err
+	}
+	p := newProvider(cred, config.SubscriptionID, config.Region, config.ResourceGroup)
+	if config.Emulated() {
+		p.client.SetEndpoint(config.EndpointURL)
+	}
+	return p, nil
+}
+
+// newProvider creates the Resource Manager client for cred.
+func newProvider(cred azcore.TokenCredential, subscriptionID, location, resourceGroup string) *Provider {
+	return &Provider{
+		subscriptionID: subscriptionID,
+		location:       location,
+		resourceGroup:  resourceGroup,
+		credential:     cred,
+		client:         azureapi.New(cred, subscriptionID),
+		httpClient:     http.DefaultClient,
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "azure-container-apps"
+}
+
+// Close closes the idle connections of the provider's HTTP clients.
+func (p *Provider) Close() error {
+	p.client.CloseIdleConnections()
+	p.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// containerApp is a container app, as returned by Resource Manager.
+type containerApp struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		ProvisioningState    string `json:"provisioningState"`
+		RunningStatus        string `json:"runningStatus"`
+		ManagedEnvironmentID string `json:"managedEnvironmentId"`
+		LatestRevisionName   string `json:"latestRevisionName"`
+		EventStreamEndpoint  string `json:"eventStreamEndpoint"`
+		Configuration        struct {
+			Ingress *struct {
+				FQDN string `json:"fqdn"`
+			} `json:"ingress"`
+		} `json:"configuration"`
+		Template appTemplate `json:"template"`
+	} `json:"properties"`
+	SystemData struct {
+		LastModifiedAt string `json:"lastModifiedAt"`
+	} `json:"systemData"`
+}
+
+// url returns the app's HTTPS URL, or "" when it has no ingress.
+func (a *containerApp) url() string {
+	if a.Properties.Configuration.Ingress == nil || a.Properties.Configuration.Ingress.FQDN == "" {
+		return ""
+	}
+	return "https://" + a.Properties.Configuration.Ingress.FQDN
+}
+
+// appTemplate is the revision-scoped part of a container app: changing it
+// creates a new revision.
+type appTemplate struct {
+	RevisionSuffix string         `json:"revisionSuffix,omitempty"`
+	Containers     []appContainer `json:"containers"`
+	Scale          appScale       `json:"scale"`
+}
+
+// images returns the images of the template's containers, by container name.
+func (t appTemplate) images() map[string]string {
+	images := make(map[string]string, len(t.Containers))
+	for _, c := range t.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+// appContainer is a container of a container app revision.
+type appContainer struct {
+	Name      string       `json:"name"`
+	Image     string       `json:"image"`
+	Command   []string     `json:"command,omitempty"`
+	Args      []string     `json:"args,omitempty"`
+	Env       []envVar     `json:"env,omitempty"`
+	Resources appResources `json:"resources"`
+	Probes    []probe      `json:"probes,omitempty"`
+}
+
+// envVar is an environment variable of a container, set to a value or to an
+// app secret.
+type envVar struct {
+	Name      string `json:"name"`
+	Value     string `json:"value,omitempty"`
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+// appResources is the CPU and memory of a container.
+type appResources struct {
+	CPU    float64 `json:"cpu"`
+	Memory string  `json:"memory"`
+}
+
+// probe is a health probe of a container.
+type probe struct {
+	Type    string `json:"type"`
+	HTTPGet struct {
+		Path string `json:"path"`
+		Port int    `json:"port"`
+	} `json:"httpGet"`
+}
+
+// appScale is the replica range and scale rules of a revision.
+type appScale struct {
+	MinReplicas int         `json:"minReplicas"`
+	MaxReplicas int         `json:"maxReplicas"`
+	Rules       []scaleRule `json:"rules,omitempty"`
+}
+
+// scaleRule is an HTTP or custom KEDA scale rule.
+type scaleRule struct {
+	Name   string      `json:"name"`
+	HTTP   *ruleConfig `json:"http,omitempty"`
+	Custom *ruleConfig `json:"custom,omitempty"`
+}
+
+// ruleConfig configures a scale rule. Type is only set for custom rules.
+type ruleConfig struct {
+	Type     string            `json:"type,omitempty"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// revision is a revision of a container app.
+type revision struct {
+	Name       string `json:"name"`
+	Properties struct {
+		CreatedTime       string      `json:"createdTime"`
+		Active            bool        `json:"active"`
+		ProvisioningState string      `json:"provisioningState"`
+		ProvisioningError string      `json:"provisioningError"`
+		HealthState       string      `json:"healthState"`
+		Template          appTemplate `json:"template"`
+	} `json:"properties"`
+}
+
+// Deploy pushes the manifest's images to ACR and runs them as a container
+// app, creating the resource group, the registry, and the Container Apps
+// environment the first time.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := validateImagePlatforms(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Starting Azure Container Apps deployment...")
+
+	// Step 1: Ensure the resource group exists
+	if err := p.ensureResourceGroup(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ensure resource group: %w", err)
+	}
+
+	// Step 2: Push the images to ACR
+	server, images, err := p.pushImages(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Ensure the Container Apps environment exists
+	environmentID, err := p.ensureEnvironment(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: Create or update the app, which creates a revision
+	app, err := p.deployApp(ctx, m, environmentID, server, images)
+	if err != nil {
+		return nil, err
+	}
+
+	message := "Deployment successful"
+	if m.IsMultiContainer() {
+		message = fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers))
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             app.url(),
+		Status:          "Running",
+		Message:         message,
+		Images:          images,
+	}, nil
+}
+
+// deployApp creates or replaces the app with the manifest's configuration
+// and images, starts it if it was stopped, and waits for the new revision.
+func (p *Provider) deployApp(ctx context.Context, m *manifest.Manifest, environmentID, server string, images map[string]string) (*containerApp, error) {
+	name := m.Environment.Name
+	existing, err := p.getApp(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	username, password, err := p.registryCredentials(ctx, azure.RegistryName(m.Application.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []map[string]string{{"name": registryPasswordSecret, "value": password}}
+	secretNames := make(map[string]string, len(m.Secrets))
+	env := m.GetPrimaryContainer().Environment
+	for _, s := range m.Secrets {
+		secretNames[s.Name] = secretName(s.Name)
+		secrets = append(secrets, map[string]string{"name": secretNames[s.Name], "value": env[s.Name]})
+	}
+
+	body := map[string]interface{}{
+		"location": p.location,
+		"tags":     appTags(m),
+		"properties": map[string]interface{}{
+			"managedEnvironmentId": environmentID,
+			"configuration": map[string]interface{}{
+				"activeRevisionsMode": "Single",
+				"ingress":             ingress(m),
+				"registries": []map[string]string{{
+					"server":            server,
+					"username":          username,
+					"passwordSecretRef": registryPasswordSecret,
+				}},
+				"secrets": secrets,
+			},
+			"template": buildTemplate(m, images, secretNames),
+		},
+	}
+
+	if existing == nil {
+		logging.Infof("Creating container app: %s", name)
+	} else {
+		logging.Infof("Updating container app: %s", name)
+	}
+	var app containerApp
+	if err := p.client.Run(ctx, http.MethodPut, p.appPath(name), appsAPIVersion, body, &app); err != nil {
+		return nil, fmt.Errorf("failed to deploy container app %s: %w", name, err)
+	}
+
+	if app.Properties.RunningStatus == "Stopped" {
+		logging.Infof("Starting stopped container app: %s", name)
+		if err := p.client.Run(ctx, http.MethodPost, p.appPath(name)+"/start", appsAPIVersion, nil, nil); err != nil {
+			return nil, fmt.Errorf("failed to start container app %s: %w", name, err)
+		}
+	}
+
+	logging.Infof("Waiting for revision %s to be ready...", app.Properties.LatestRevisionName)
+	if err := p.waitForRevision(ctx, name, app.Properties.LatestRevisionName); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// Destroy deletes the app. The Container Apps environment is deleted too
+// when cloud-deploy named it for the deployment environment and no other app
+// runs in it; a configured container_apps.environment and the ACR registry,
+// which other environments share, are kept.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	logging.Infof("Deleting container app: %s", name)
+	err := p.client.Run(ctx, http.MethodDelete, p.appPath(name), appsAPIVersion, nil, nil)
+	if err != nil && !azureapi.IsNotFound(err) {
+		return fmt.Errorf("failed to delete container app %s: %w", name, err)
+	}
+
+	if m.ContainerApps == nil || m.ContainerApps.Environment == "" {
+		if err := p.deleteEnvironment(ctx, m.ContainerApps.ManagedEnvironment(name)); err != nil {
+			return err
+		}
+	}
+	logging.Info("Container Apps resources deleted successfully")
+	return nil
+}
+
+// Stop stops the app, which deactivates its revisions and stops serving
+// requests. The app and its revisions are preserved; running Deploy again
+// starts it.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	app, err := p.requireApp(ctx, m)
+	if err != nil {
+		return err
+	}
+	if app.Properties.RunningStatus == "Stopped" {
+		logging.Infof("Container app %s is already stopped", app.Name)
+		return nil
+	}
+	logging.Infof("Stopping container app: %s", app.Name)
+	if err := p.client.Run(ctx, http.MethodPost, p.appPath(app.Name)+"/stop", appsAPIVersion, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container app %s: %w", app.Name, err)
+	}
+	logging.Info("Container app stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the app.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	app, err := p.requireApp(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	status, health := appStatus(app.Properties.ProvisioningState, app.Properties.RunningStatus)
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             app.url(),
+		LastUpdated:     app.SystemData.LastModifiedAt,
+	}, nil
+}
+
+// appStatus returns the status and health of an app in its provisioning
+// state and running status.
+func appStatus(provisioningState, runningStatus string) (string, string) {
+	switch {
+	case provisioningState == "Failed":
+		return "Failed", "Red"
+	case provisioningState == "InProgress" || provisioningState == "Deleting":
+		return "Updating", "Yellow"
+	case runningStatus == "Stopped":
+		return "Stopped", "Grey"
+	case runningStatus == "Running":
+		return "Running", "Green"
+	default:
+		return runningStatus, "Unknown"
+	}
+}
+
+// Rollback redeploys the template of the revision before the current one,
+// its images, scale, and environment variables, as a new revision. Rolling
+// back twice returns to the revision rolled back from.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting Azure Container Apps rollback...")
+	app, err := p.requireApp(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := p.listRevisions(ctx, app.Name)
+	if err != nil {
+		return nil, err
+	}
+	previous := previousRevision(revisions, app.Properties.LatestRevisionName)
+	if previous == nil {
+		return nil, fmt.Errorf("no previous revision found for container app %s; it has been deployed only once", app.Name)
+	}
+
+	logging.Infof("Rolling back %s to the template of revision %s", app.Name, previous.Name)
+	template := previous.Properties.Template
+	template.RevisionSuffix = ""
+	body := map[string]interface{}{"properties": map[string]interface{}{"template": template}}
+	var updated containerApp
+	if err := p.client.Run(ctx, http.MethodPatch, p.appPath(app.Name), appsAPIVersion, body, &updated); err != nil {
+		return nil, fmt.Errorf("failed to roll back container app %s: %w", app.Name, err)
+	}
+	if err := p.waitForRevision(ctx, app.Name, updated.Properties.LatestRevisionName); err != nil {
+		return nil, err
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             updated.url(),
+		Status:          "Running",
+		Message:         "Rolled back to revision " + previous.Name,
+		Images:          template.images(),
+	}, nil
+}
+
+// previousRevision returns the newest provisioned revision other than
+// current, or nil when there is none.
+func previousRevision(revisions []revision, current string) *revision {
+	var previous *revision
+	for i, r := range revisions {
+		if r.Name == current || r.Properties.ProvisioningState != "Provisioned" {
+			continue
+		}
+		if previous == nil || r.Properties.CreatedTime > previous.Properties.CreatedTime {
+			previous = &revisions[i]
+		}
+	}
+	return previous
+}
+
+// appPath returns the Resource Manager path of the named app.
+func (p *Provider) appPath(name string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.App/containerApps/" + name
+}
+
+// environmentPath returns the Resource Manager path of the named Container
+// Apps environment.
+func (p *Provider) environmentPath(name string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.App/managedEnvironments/" + name
+}
+
+// registryPath returns the Resource Manager path of the named ACR registry.
+func (p *Provider) registryPath(name string) string {
+	return p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.ContainerRegistry/registries/" + name
+}
+
+// getApp returns the named app, or nil when it does not exist.
+func (p *Provider) getApp(ctx context.Context, name string) (*containerApp, error) {
+	var app containerApp
+	err := p.client.Do(ctx, http.MethodGet, p.appPath(name), appsAPIVersion, nil, &app)
+	if azureapi.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container app %s: %w", name, err)
+	}
+	return &app, nil
+}
+
+// requireApp returns the environment's app, failing when it does not exist.
+func (p *Provider) requireApp(ctx context.Context, m *manifest.Manifest) (*containerApp, error) {
+	app, err := p.getApp(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, fmt.Errorf("no container app found for environment %s", m.Environment.Name)
+	}
+	return app, nil
+}
+
+// listRevisions returns the revisions of the named app.
+func (p *Provider) listRevisions(ctx context.Context, name string) ([]revision, error) {
+	var revisions []revision
+	path := p.appPath(name) + "/revisions"
+	for path != "" {
+		var out struct {
+			Value    []revision `json:"value"`
+			NextLink string     `json:"nextLink"`
+		}
+		if err := p.client.Do(ctx, http.MethodGet, path, appsAPIVersion, nil, &out); err != nil {
+			return nil, fmt.Errorf("failed to list revisions of container app %s: %w", name, err)
+		}
+		revisions = append(revisions, out.Value...)
+		path = out.NextLink
+	}
+	return revisions, nil
+}
+
+// waitForRevision polls a revision of the app until it is provisioned.
+func (p *Provider) waitForRevision(ctx context.Context, app, name string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(revisionTimeout)
+
+	for {
+		var rev revision
+		if err := p.client.Do(ctx, http.MethodGet, p.appPath(app)+"/revisions/"+name, appsAPIVersion, nil, &rev); err != nil {
+			return fmt.Errorf("failed to get revision %s: %w", name, err)
+		}
+		switch rev.Properties.ProvisioningState {
+		case "Provisioned":
+			return nil
+		case "Failed":
+			return fmt.Errorf("revision %s failed to provision: %s", name, rev.Properties.ProvisioningError)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for revision %s to be provisioned", name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureResourceGroup creates the resource group, or updates it if it exists.
+func (p *Provider) ensureResourceGroup(ctx context.Context) error {
+	logging.Infof("Ensuring resource group exists: %s", p.resourceGroup)
+	body := map[string]interface{}{
+		"location": p.location,
+		"tags":     map[string]string{"ManagedBy": "cloud-deploy"},
+	}
+	return p.client.Do(ctx, http.MethodPut, p.client.ResourceGroupPath(p.resourceGroup), resourceGroupAPIVersion, body, nil)
+}
+
+// ensureEnvironment returns the ID of the app's Container Apps environment,
+// creating the environment if it doesn't exist.
+func (p *Provider) ensureEnvironment(ctx context.Context, m *manifest.Manifest) (string, error) {
+	name := m.ContainerApps.ManagedEnvironment(m.Environment.Name)
+	var env struct {
+		ID string `json:"id"`
+	}
+	err := p.client.Do(ctx, http.MethodGet, p.environmentPath(name), appsAPIVersion, nil, &env)
+	if err == nil {
+		return env.ID, nil
+	}
+	if !azureapi.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get Container Apps environment %s: %w", name, err)
+	}
+
+	logging.Infof("Creating Container Apps environment: %s", name)
+	body := map[string]interface{}{
+		"location":   p.location,
+		"tags":       map[string]string{"ManagedBy": "cloud-deploy"},
+		"properties": map[string]interface{}{},
+	}
+	if err := p.client.Run(ctx, http.MethodPut, p.environmentPath(name), appsAPIVersion, body, &env); err != nil {
+		return "", fmt.Errorf("failed to create Container Apps environment %s: %w", name, err)
+	}
+	return env.ID, nil
+}
+
+// deleteEnvironment deletes the named Container Apps environment unless an
+// app still runs in it.
+func (p *Provider) deleteEnvironment(ctx context.Context, name string) error {
+	var env struct {
+		ID string `json:"id"`
+	}
+	err := p.client.Do(ctx, http.MethodGet, p.environmentPath(name), appsAPIVersion, nil, &env)
+	if azureapi.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get Container Apps environment %s: %w", name, err)
+	}
+
+	var apps struct {
+		Value []containerApp `json:"value"`
+	}
+	path := p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.App/containerApps"
+	if err := p.client.Do(ctx, http.MethodGet, path, appsAPIVersion, nil, &apps); err != nil {
+		return fmt.Errorf("failed to list container apps: %w", err)
+	}
+	for _, app := range apps.Value {
+		if strings.EqualFold(app.Properties.ManagedEnvironmentID, env.ID) {
+			logging.Infof("Keeping Container Apps environment %s, which container app %s runs in", name, app.Name)
+			return nil
+		}
+	}
+
+	logging.Infof("Deleting Container Apps environment: %s", name)
+	if err := p.client.Run(ctx, http.MethodDelete, p.environmentPath(name), appsAPIVersion, nil, nil); err != nil && !azureapi.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Container Apps environment %s: %w", name, err)
+	}
+	return nil
+}
+
+// pushImages pushes the manifest's images to the application's ACR registry,
+// returning the registry's login server and the pushed images pinned by
+// digest, by container name. Every deployment pushes a new timestamped tag,
+// prefixed with the container name for multi-container deployments.
+func (p *Provider) pushImages(ctx context.Context, m *manifest.Manifest) (string, map[string]string, error) {
+	logging.Info("=== Distributing images to ACR ===")
+	registryName := azure.RegistryName(m.Application.Name)
+	deployTag := m.VersionLabel(fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405")))
+
+	server := ""
+	images := make(map[string]string)
+	for _, c := range containers(m) {
+		tag := deployTag
+		if m.IsMultiContainer() {
+			tag = c.Name + "-" + deployTag
+		}
+		acrRegistry, err := registry.NewACRRegistry(p.credential, p.subscriptionID, p.resourceGroup, registryName, p.location, tag)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to create ACR registry handler: %w", err)
+		}
+		distributor := registry.NewDistributor(c.Image, m.Platforms...)
+		dest := registry.Local(acrRegistry, m.Provider.RegistryURL, m.RepositoryName(), tag)
+		distributor.AddRegistry(dest)
+		imageURIs, err := distributor.Distribute(ctx)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to distribute image for container %s: %w", c.Name, err)
+		}
+		server = dest.GetRegistryURL()
+		images[c.Name] = registry.PinDigest(imageURIs[server], distributor.Digest())
+		logging.Infof("Image pushed to ACR: %s -> %s", c.Name, images[c.Name])
+	}
+	return server, images, nil
+}
+
+// registryCredentials returns the admin user name and password of the named
+// ACR registry.
+func (p *Provider) registryCredentials(ctx context.Context, name string) (string, string, error) {
+	var out struct {
+		Username  string `json:"username"`
+		Passwords []struct {
+			Value string `json:"value"`
+		} `json:"passwords"`
+	}
+	if err := p.client.Do(ctx, http.MethodPost, p.registryPath(name)+"/listCredentials", registryAPIVersion, nil, &out); err != nil {
+		return "", "", fmt.Errorf("failed to get registry credentials: %w", err)
+	}
+	if len(out.Passwords) == 0 {
+		return "", "", fmt.Errorf("no passwords found for registry %s", name)
+	}
+	return out.Username, out.Passwords[0].Value, nil
+}
+
+// containers returns the containers the app runs: the manifest's containers,
+// or its single image.
+func containers(m *manifest.Manifest) []manifest.Container {
+	if m.IsMultiContainer() {
+		return m.Containers
+	}
+	return []manifest.Container{m.GetPrimaryContainer()}
+}
+
+// buildTemplate returns the revision template running images, by container
+// name. Environment variables named in secretNames reference the app secret
+// holding their value instead of setting it.
+func buildTemplate(m *manifest.Manifest, images map[string]string, secretNames map[string]string) appTemplate {
+	cpu, memory := m.ContainerApps.Resources()
+	min, max := m.ContainerApps.Replicas()
+
+	template := appTemplate{Scale: appScale{MinReplicas: min, MaxReplicas: max}}
+	for i, c := range containers(m) {
+		if c.Workdir != "" {
+			logging.Warnf("Container %s: workdir is not supported by Azure Container Apps and will be ignored", c.Name)
+		}
+		container := appContainer{
+			Name:      c.Name,
+			Image:     images[c.Name],
+			Command:   c.Command,
+			Args:      c.Args,
+			Resources: appResources{CPU: cpu, Memory: memory},
+		}
+		names := make([]string, 0, len(c.Environment))
+		for name := range c.Environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if secret, ok := secretNames[name]; ok && i == 0 {
+				container.Env = append(container.Env, envVar{Name: name, SecretRef: secret})
+			} else {
+				container.Env = append(container.Env, envVar{Name: name, Value: c.Environment[name]})
+			}
+		}
+		if i == 0 && m.HealthCheck.Path != "" {
+			readiness := probe{Type: "Readiness"}
+			readiness.HTTPGet.Path = m.HealthCheck.Path
+			readiness.HTTPGet.Port = containerPort(c)
+			container.Probes = []probe{readiness}
+		}
+		template.Containers = append(template.Containers, container)
+	}
+
+	if m.ContainerApps != nil {
+		for _, r := range m.ContainerApps.ScaleRules {
+			rule := scaleRule{Name: r.Name}
+			if r.ConcurrentRequests > 0 {
+				rule.HTTP = &ruleConfig{Metadata: map[string]string{"concurrentRequests": strconv.Itoa(r.ConcurrentRequests)}}
+			} else {
+				rule.Custom = &ruleConfig{Type: r.Custom, Metadata: r.Metadata}
+			}
+			template.Scale.Rules = append(template.Scale.Rules, rule)
+		}
+	}
+	return template
+}
+
+// ingress returns the app's HTTPS ingress to the primary container's port.
+// Plain HTTP requests are redirected to HTTPS.
+func ingress(m *manifest.Manifest) map[string]interface{} {
+	return map[string]interface{}{
+		"external":      m.ContainerApps.IsExternal(),
+		"targetPort":    containerPort(m.GetPrimaryContainer()),
+		"transport":     "auto",
+		"allowInsecure": false,
+		"traffic":       []map[string]interface{}{{"latestRevision": true, "weight": 100}},
+	}
+}
+
+// containerPort returns the port the application listens on, defaulting to 80
+// as the azure provider does.
+func containerPort(c manifest.Container) int {
+	if len(c.Ports) > 0 && c.Ports[0].ContainerPort > 0 {
+		return c.Ports[0].ContainerPort
+	}
+	return 80
+}
+
+// secretName returns the name of the app secret holding an environment
+// variable. Secret names may only hold lowercase letters, digits, and '-'.
+func secretName(envName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, envName)
+	return strings.Trim(name, "-")
+}
+
+// appTags returns the app's tags: the manifest's tags, and the application
+// name and ManagedBy tags the azure provider also sets.
+func appTags(m *manifest.Manifest) map[string]string {
+	tags := map[string]string{
+		"Application": m.Application.Name,
+		"ManagedBy":   "cloud-deploy",
+	}
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// validateImagePlatforms fails early when an image cannot run on Container
+// Apps, which runs linux/amd64 containers.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, registry.LinuxAMD64, "Azure Container Apps", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSecurity warns about security options Container Apps cannot
+// enforce, and fails if an image runs as root when run_as_non_root is set.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	sec := m.Security
+	if sec == nil {
+		return nil
+	}
+	if sec.ReadOnlyRootFilesystem || sec.User != "" || len(sec.DropCapabilities) > 0 {
+		logging.Warn("security.read_only_root_filesystem, user and drop_capabilities are not supported by Azure Container Apps and will be ignored")
+	}
+	if !sec.RunAsNonRoot {
+		return nil
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidateNonRoot(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,506 @@
+package azurecontainerapps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const (
+	rgPath      = "/subscriptions/sub-123/resourceGroups/my-rg"
+	appPath     = rgPath + "/providers/Microsoft.App/containerApps/my-app-prod"
+	envPath     = rgPath + "/providers/Microsoft.App/managedEnvironments/my-app-prod-env"
+	environment = rgPath + "/providers/Microsoft.App/managedEnvironments/my-app-prod-env"
+)
+
+// fakeCredential returns a fixed token.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeARM serves the subset of the Resource Manager APIs the provider uses.
+// Changing the app's template creates a revision, which is provisioned at
+// once.
+type fakeARM struct {
+	mu          sync.Mutex
+	calls       []string
+	bodies      map[string]map[string]interface{} // "METHOD path" -> last JSON body
+	app         *containerApp
+	revisions   []revision
+	environment bool
+	otherApps   []containerApp // other apps in the resource group
+	logs        string         // log stream output of every container
+	url         string
+}
+
+func newFakeARM() *fakeARM {
+	return &fakeARM{bodies: make(map[string]map[string]interface{})}
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := r.Method + " " + r.URL.Path
+	f.calls = append(f.calls, call)
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.bodies[call] = body
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"ResourceNotFound","message":"not found"}}`))
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/listCredentials"):
+		w.Write([]byte(`{"username":"myappregistry","passwords":[{"value":"registry-secret"}]}`))
+	case r.URL.Path == rgPath+"/providers/Microsoft.App/containerApps":
+		apps := append([]containerApp{}, f.otherApps...)
+		if f.app != nil {
+			apps = append(apps, *f.app)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": apps})
+	case r.URL.Path == envPath:
+		switch r.Method {
+		case http.MethodGet:
+			if !f.environment {
+				notFound()
+				return
+			}
+		case http.MethodPut:
+			f.environment = true
+		case http.MethodDelete:
+			f.environment = false
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": environment})
+	case r.URL.Path == appPath:
+		f.serveApp(w, r, body, notFound)
+	case r.URL.Path == appPath+"/start":
+		f.app.Properties.RunningStatus = "Running"
+	case r.URL.Path == appPath+"/stop":
+		f.app.Properties.RunningStatus = "Stopped"
+	case r.URL.Path == appPath+"/getAuthtoken":
+		w.Write([]byte(`{"properties":{"token":"log-token"}}`))
+	case strings.HasSuffix(r.URL.Path, "/replicas"):
+		w.Write([]byte(`{"value":[{"name":"my-app-prod--001-abc","properties":{"containers":[{"name":"my-app"}]}}]}`))
+	case strings.HasSuffix(r.URL.Path, "/logstream"):
+		if r.Header.Get("Authorization") != "Bearer log-token" || r.URL.Query().Get("follow") != "false" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(f.logs))
+	case r.URL.Path == appPath+"/revisions":
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": f.revisions})
+	case strings.HasPrefix(r.URL.Path, appPath+"/revisions/"):
+		name := strings.TrimPrefix(r.URL.Path, appPath+"/revisions/")
+		for _, rev := range f.revisions {
+			if rev.Name == name {
+				json.NewEncoder(w).Encode(rev)
+				return
+			}
+		}
+		notFound()
+	default:
+		w.Write([]byte(`{}`))
+	}
+}
+
+// serveApp handles requests for the app itself.
+func (f *fakeARM) serveApp(w http.ResponseWriter, r *http.Request, body map[string]interface{}, notFound func()) {
+	switch r.Method {
+	case http.MethodGet:
+		if f.app == nil {
+			notFound()
+			return
+		}
+	case http.MethodDelete:
+		if f.app == nil {
+			notFound()
+			return
+		}
+		f.app = nil
+		return
+	case http.MethodPut, http.MethodPatch:
+		if f.app == nil {
+			f.app = &containerApp{ID: appPath, Name: "my-app-prod"}
+			f.app.Properties.RunningStatus = "Running"
+		}
+		props := body["properties"].(map[string]interface{})
+		data, _ := json.Marshal(props["template"])
+		var template appTemplate
+		json.Unmarshal(data, &template)
+		if env, ok := props["managedEnvironmentId"].(string); ok {
+			f.app.Properties.ManagedEnvironmentID = env
+		}
+		f.addRevision(template)
+	}
+	json.NewEncoder(w).Encode(f.app)
+}
+
+// addRevision makes template the app's latest, provisioned revision.
+func (f *fakeARM) addRevision(template appTemplate) {
+	rev := revision{Name: fmt.Sprintf("my-app-prod--%03d", len(f.revisions)+1)}
+	rev.Properties.CreatedTime = fmt.Sprintf("2026-03-01T10:%02d:00Z", len(f.revisions))
+	rev.Properties.ProvisioningState = "Provisioned"
+	rev.Properties.Template = template
+	f.revisions = append(f.revisions, rev)
+
+	f.app.Properties.ProvisioningState = "Succeeded"
+	f.app.Properties.LatestRevisionName = rev.Name
+	f.app.Properties.Template = template
+	f.app.Properties.Configuration.Ingress = &struct {
+		FQDN string `json:"fqdn"`
+	}{FQDN: "my-app-prod.kindhill-1234.eastus.azurecontainerapps.io"}
+	f.app.Properties.EventStreamEndpoint = f.url + appPath + "/eventstream"
+}
+
+// testProvider returns a provider that calls f.
+func testProvider(t *testing.T, f *fakeARM) *Provider {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	f.url = server.URL
+
+	p := newProvider(fakeCredential{}, "sub-123", "eastus", "my-rg")
+	p.client.SetEndpoint(server.URL)
+	p.client.SetPolling(time.Millisecond, time.Second)
+	p.httpClient = server.Client()
+
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+	return p
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:                "my-app:1.0",
+		Provider:             manifest.ProviderConfig{Name: "azure-container-apps", Region: "eastus", SubscriptionID: "sub-123", ResourceGroup: "my-rg"},
+		Application:          manifest.ApplicationConfig{Name: "my-app"},
+		Environment:          manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Ports:                []manifest.PortMapping{{ContainerPort: 8080}},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info", "DATABASE_URL": "postgres://secret"},
+		Secrets:              []manifest.SecretRef{{Name: "DATABASE_URL", SecretID: "prod/db"}},
+		HealthCheck:          manifest.HealthCheckConfig{Path: "/health"},
+		Tags:                 map[string]string{"team": "web"},
+		ContainerApps: &manifest.ContainerAppsConfig{MinReplicas: 1, MaxReplicas: 5, ScaleRules: []manifest.ContainerAppsScaleRule{
+			{Name: "http", ConcurrentRequests: 50},
+			{Name: "queue", Custom: "azure-servicebus", Metadata: map[string]string{"queueName": "jobs"}},
+		}},
+	}
+}
+
+func TestNewRequiresSettings(t *testing.T) {
+	for _, config := range []manifest.ProviderConfig{
+		{Region: "eastus", ResourceGroup: "my-rg"},
+		{SubscriptionID: "sub-123", ResourceGroup: "my-rg"},
+		{SubscriptionID: "sub-123", Region: "eastus"},
+	} {
+		if _, err := New(context.Background(), &config, &manifest.Manifest{}); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestDeployApp(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+
+	images := map[string]string{"my-app": "myappregistry.azurecr.io/myappregistry@sha256:a"}
+	app, err := p.deployApp(context.Background(), m, environment, "myappregistry.azurecr.io", images)
+	if err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if app.url() != "https://my-app-prod.kindhill-1234.eastus.azurecontainerapps.io" {
+		t.Errorf("unexpected URL: %s", app.url())
+	}
+
+	body := f.bodies["PUT "+appPath]
+	props := body["properties"].(map[string]interface{})
+	config := props["configuration"].(map[string]interface{})
+	ingress := config["ingress"].(map[string]interface{})
+	if ingress["external"] != true || ingress["targetPort"] != float64(8080) || ingress["allowInsecure"] != false {
+		t.Errorf("unexpected ingress: %v", ingress)
+	}
+	secrets, _ := json.Marshal(config["secrets"])
+	if string(secrets) != `[{"name":"registry-password","value":"registry-secret"},{"name":"database-url","value":"postgres://secret"}]` {
+		t.Errorf("unexpected secrets: %s", secrets)
+	}
+	registries, _ := json.Marshal(config["registries"])
+	if string(registries) != `[{"passwordSecretRef":"registry-password","server":"myappregistry.azurecr.io","username":"myappregistry"}]` {
+		t.Errorf("unexpected registries: %s", registries)
+	}
+	if tags := body["tags"].(map[string]interface{}); tags["Application"] != "my-app" || tags["team"] != "web" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	// Deploying a stopped app starts it
+	f.app.Properties.RunningStatus = "Stopped"
+	if _, err := p.deployApp(context.Background(), m, environment, "myappregistry.azurecr.io", images); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+	if f.app.Properties.RunningStatus != "Running" || len(f.revisions) != 2 {
+		t.Errorf("Expected a started app with 2 revisions, got %s with %d", f.app.Properties.RunningStatus, len(f.revisions))
+	}
+}
+
+func TestDeployAppFailedRevision(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	if _, err := p.deployApp(context.Background(), m, environment, "myappregistry.azurecr.io", map[string]string{"my-app": "x"}); err != nil {
+		t.Fatalf("deployApp failed: %v", err)
+	}
+
+	// The next revision fails to provision
+	f.mu.Lock()
+	f.app.Properties.LatestRevisionName = "my-app-prod--bad"
+	bad := revision{Name: "my-app-prod--bad"}
+	bad.Properties.ProvisioningState = "Failed"
+	bad.Properties.ProvisioningError = "ImagePullBackOff"
+	f.revisions = append(f.revisions, bad)
+	f.mu.Unlock()
+
+	err := p.waitForRevision(context.Background(), "my-app-prod", "my-app-prod--bad")
+	if err == nil || !strings.Contains(err.Error(), "ImagePullBackOff") {
+		t.Errorf("Expected the revision's provisioning error, got: %v", err)
+	}
+}
+
+func TestBuildTemplate(t *testing.T) {
+	m := testManifest()
+	template := buildTemplate(m, map[string]string{"my-app": "registry/my-app@sha256:a"}, map[string]string{"DATABASE_URL": "database-url"})
+
+	if len(template.Containers) != 1 {
+		t.Fatalf("Expected 1 container, got %d", len(template.Containers))
+	}
+	c := template.Containers[0]
+	if c.Image != "registry/my-app@sha256:a" || c.Resources != (appResources{CPU: 0.5, Memory: "1Gi"}) {
+		t.Errorf("unexpected container: %+v", c)
+	}
+	wantEnv := []envVar{{Name: "DATABASE_URL", SecretRef: "database-url"}, {Name: "LOG_LEVEL", Value: "info"}}
+	if !reflect.DeepEqual(c.Env, wantEnv) {
+		t.Errorf("Env = %+v, want %+v", c.Env, wantEnv)
+	}
+	if len(c.Probes) != 1 || c.Probes[0].HTTPGet.Path != "/health" || c.Probes[0].HTTPGet.Port != 8080 {
+		t.Errorf("unexpected probes: %+v", c.Probes)
+	}
+
+	scale, _ := json.Marshal(template.Scale)
+	want := `{"minReplicas":1,"maxReplicas":5,"rules":[{"name":"http","http":{"metadata":{"concurrentRequests":"50"}}},{"name":"queue","custom":{"type":"azure-servicebus","metadata":{"queueName":"jobs"}}}]}`
+	if string(scale) != want {
+		t.Errorf("scale = %s, want %s", scale, want)
+	}
+
+	// Multi-container deployments run every container; only the primary one is probed
+	m.Image = ""
+	m.Containers = []manifest.Container{
+		{Name: "web", Image: "web:1", Ports: []manifest.PortMapping{{ContainerPort: 3000}}, Args: []string{"--serve"}},
+		{Name: "worker", Image: "worker:1", Environment: map[string]string{"QUEUE": "jobs"}},
+	}
+	template = buildTemplate(m, map[string]string{"web": "registry/web@sha256:b", "worker": "registry/worker@sha256:c"}, nil)
+	if len(template.Containers) != 2 || template.Containers[1].Image != "registry/worker@sha256:c" || len(template.Containers[1].Probes) != 0 {
+		t.Errorf("unexpected containers: %+v", template.Containers)
+	}
+	if template.Containers[0].Args[0] != "--serve" || template.Containers[0].Probes[0].HTTPGet.Port != 3000 {
+		t.Errorf("unexpected primary container: %+v", template.Containers[0])
+	}
+}
+
+func TestSecretName(t *testing.T) {
+	tests := map[string]string{"DATABASE_URL": "database-url", "api.key": "api-key", "_TOKEN_": "token"}
+	for in, want := range tests {
+		if got := secretName(in); got != want {
+			t.Errorf("secretName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+
+	if _, err := p.Status(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no container app found") {
+		t.Errorf("Expected a missing app error, got: %v", err)
+	}
+
+	f.app = &containerApp{Name: "my-app-prod"}
+	f.app.Properties.RunningStatus = "Running"
+	f.addRevision(appTemplate{})
+	f.app.SystemData.LastModifiedAt = "2026-03-01T10:00:00Z"
+	status, err := p.Status(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.LastUpdated != "2026-03-01T10:00:00Z" || !strings.HasPrefix(status.URL, "https://") {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestAppStatus(t *testing.T) {
+	tests := []struct {
+		provisioning, running, status, health string
+	}{
+		{"Succeeded", "Running", "Running", "Green"},
+		{"Succeeded", "Stopped", "Stopped", "Grey"},
+		{"InProgress", "Running", "Updating", "Yellow"},
+		{"Failed", "Running", "Failed", "Red"},
+		{"Succeeded", "Processing", "Processing", "Unknown"},
+	}
+	for _, tt := range tests {
+		if status, health := appStatus(tt.provisioning, tt.running); status != tt.status || health != tt.health {
+			t.Errorf("appStatus(%s, %s) = %s, %s, want %s, %s", tt.provisioning, tt.running, status, health, tt.status, tt.health)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	f.app = &containerApp{Name: "my-app-prod"}
+	f.app.Properties.RunningStatus = "Running"
+
+	if err := p.Stop(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if f.app.Properties.RunningStatus != "Stopped" {
+		t.Errorf("Expected the app to be stopped, got %s", f.app.Properties.RunningStatus)
+	}
+
+	// Stopping again does nothing
+	calls := len(f.calls)
+	if err := p.Stop(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if len(f.calls) != calls+1 {
+		t.Errorf("Expected only the app to be read, got %v", f.calls[calls:])
+	}
+}
+
+func TestRollback(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+
+	f.app = &containerApp{Name: "my-app-prod"}
+	f.addRevision(appTemplate{Containers: []appContainer{{Name: "my-app", Image: "registry/my-app@sha256:a"}}})
+	if _, err := p.Rollback(context.Background(), m); err == nil || !strings.Contains(err.Error(), "deployed only once") {
+		t.Errorf("Expected an error without a previous revision, got: %v", err)
+	}
+
+	f.addRevision(appTemplate{Containers: []appContainer{{Name: "my-app", Image: "registry/my-app@sha256:b"}}})
+	result, err := p.Rollback(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.Images["my-app"] != "registry/my-app@sha256:a" || result.Message != "Rolled back to revision my-app-prod--001" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if f.app.Properties.Template.Containers[0].Image != "registry/my-app@sha256:a" || len(f.revisions) != 3 {
+		t.Errorf("Expected a third revision running image a, got %+v", f.app.Properties.Template)
+	}
+
+	// Rolling back again returns to the revision rolled back from
+	result, err = p.Rollback(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.Images["my-app"] != "registry/my-app@sha256:b" {
+		t.Errorf("Expected a second rollback to image b, got %+v", result.Images)
+	}
+}
+
+func TestPreviousRevision(t *testing.T) {
+	revs := make([]revision, 3)
+	for i, name := range []string{"r1", "r3", "r2"} {
+		revs[i].Name = name
+		revs[i].Properties.CreatedTime = "2026-03-01T10:0" + name[1:] + ":00Z"
+		revs[i].Properties.ProvisioningState = "Provisioned"
+	}
+	revs[2].Properties.ProvisioningState = "Failed"
+
+	if rev := previousRevision(revs, "r3"); rev == nil || rev.Name != "r1" {
+		t.Errorf("Expected r1, skipping the failed r2, got %+v", rev)
+	}
+	if rev := previousRevision(revs[:1], "r1"); rev != nil {
+		t.Errorf("Expected no previous revision, got %+v", rev)
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	f.environment = true
+	f.app = &containerApp{Name: "my-app-prod"}
+
+	// Another app still runs in the environment
+	other := containerApp{Name: "other"}
+	other.Properties.ManagedEnvironmentID = environment
+	f.otherApps = []containerApp{other}
+	if err := p.Destroy(context.Background(), m); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if f.app != nil || !f.environment {
+		t.Errorf("Expected the app to be deleted and the environment kept")
+	}
+
+	f.otherApps = nil
+	if err := p.Destroy(context.Background(), m); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if f.environment {
+		t.Errorf("Expected the unused environment to be deleted")
+	}
+
+	// A configured environment is never deleted
+	f.environment = true
+	m.ContainerApps.Environment = "my-app-prod-env"
+	if err := p.Destroy(context.Background(), m); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if !f.environment {
+		t.Errorf("Expected the configured environment to be kept")
+	}
+}
+
+func TestEnsureEnvironment(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+
+	id, err := p.ensureEnvironment(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("ensureEnvironment failed: %v", err)
+	}
+	if id != environment || !f.environment {
+		t.Errorf("Expected the environment to be created, got %q", id)
+	}
+	if body := f.bodies["PUT "+envPath]; body["location"] != "eastus" {
+		t.Errorf("unexpected environment body: %v", body)
+	}
+}
+
+func TestName(t *testing.T) {
+	p := newProvider(fakeCredential{}, "sub-123", "eastus", "my-rg")
+	if p.Name() != "azure-container-apps" {
+		t.Errorf("unexpected name: %s", p.Name())
+	}
+}
@@ -0,0 +1,106 @@
+package azurecontainerapps
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/azureapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// destroyState records which of a deployment's resources exist, and the
+// other apps running in its environment.
+type destroyState struct {
+	resourceGroup bool
+	registry      bool
+	app           bool
+	environment   bool
+
+	// otherApps are the names of the other apps in the environment
+	otherApps []string
+}
+
+// PlanDestroy returns what Destroy would do: delete the app, and the
+// Container Apps environment cloud-deploy created for it unless other apps
+// run in it. A configured environment, the container registry, and the
+// resource group are kept.
+func (p *Provider) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	var state destroyState
+	var err error
+
+	if state.resourceGroup, err = p.exists(ctx, p.client.ResourceGroupPath(p.resourceGroup), resourceGroupAPIVersion); err != nil {
+		return nil, fmt.Errorf("failed to get resource group: %w", err)
+	}
+	if !state.resourceGroup {
+		return buildDestroyPlan(m, p.resourceGroup, state), nil
+	}
+
+	if state.registry, err = p.exists(ctx, p.registryPath(azure.RegistryName(m.Application.Name)), registryAPIVersion); err != nil {
+		return nil, fmt.Errorf("failed to get container registry: %w", err)
+	}
+	app, err := p.getApp(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	state.app = app != nil
+
+	var env struct {
+		ID string `json:"id"`
+	}
+	name := m.ContainerApps.ManagedEnvironment(m.Environment.Name)
+	err = p.client.Do(ctx, http.MethodGet, p.environmentPath(name), appsAPIVersion, nil, &env)
+	switch {
+	case azureapi.IsNotFound(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get Container Apps environment %s: %w", name, err)
+	default:
+		state.environment = true
+		var apps struct {
+			Value []containerApp `json:"value"`
+		}
+		path := p.client.ResourceGroupPath(p.resourceGroup) + "/providers/Microsoft.App/containerApps"
+		if err := p.client.Do(ctx, http.MethodGet, path, appsAPIVersion, nil, &apps); err != nil {
+			return nil, fmt.Errorf("failed to list container apps: %w", err)
+		}
+		for _, a := range apps.Value {
+			if a.Name != m.Environment.Name && strings.EqualFold(a.Properties.ManagedEnvironmentID, env.ID) {
+				state.otherApps = append(state.otherApps, a.Name)
+			}
+		}
+	}
+
+	return buildDestroyPlan(m, p.resourceGroup, state), nil
+}
+
+// buildDestroyPlan returns what a destroy of m's environment does given the
+// existing resources in state.
+func buildDestroyPlan(m *manifest.Manifest, resourceGroup string, state destroyState) *types.Plan {
+	plan := &types.Plan{Provider: "azure-container-apps"}
+	if state.app {
+		plan.Add(types.PlanDelete, "Container app", m.Environment.Name, "with all of its revisions")
+	}
+
+	if state.environment {
+		name := m.ContainerApps.ManagedEnvironment(m.Environment.Name)
+		switch {
+		case m.ContainerApps != nil && m.ContainerApps.Environment != "":
+			plan.Add(types.PlanNoChange, "Container Apps environment", name, "kept; configured container_apps.environment")
+		case len(state.otherApps) > 0:
+			plan.Add(types.PlanNoChange, "Container Apps environment", name, "kept; container apps "+strings.Join(state.otherApps, ", ")+" run in it")
+		default:
+			plan.Add(types.PlanDelete, "Container Apps environment", name, "no other app runs in it")
+		}
+	}
+
+	if state.registry {
+		plan.Add(types.PlanNoChange, "Container registry", azure.RegistryName(m.Application.Name), "kept with the images of every environment")
+	}
+	if state.resourceGroup {
+		plan.Add(types.PlanNoChange, "Resource group", resourceGroup, "kept")
+	}
+	return plan
+}
@@ -0,0 +1,60 @@
+package azurecontainerapps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlanDestroy(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	f.environment = true
+	f.app = &containerApp{Name: "my-app-prod"}
+
+	other := containerApp{Name: "other"}
+	other.Properties.ManagedEnvironmentID = environment
+	f.otherApps = []containerApp{other}
+
+	plan, err := p.PlanDestroy(context.Background(), m)
+	if err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	want := []types.ResourceChange{
+		{Action: types.PlanDelete, Type: "Container app", Name: "my-app-prod", Reason: "with all of its revisions"},
+		{Action: types.PlanNoChange, Type: "Container Apps environment", Name: "my-app-prod-env", Reason: "kept; container apps other run in it"},
+		{Action: types.PlanNoChange, Type: "Container registry", Name: "myapp", Reason: "kept with the images of every environment"},
+		{Action: types.PlanNoChange, Type: "Resource group", Name: "my-rg", Reason: "kept"},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("unexpected plan: %+v", plan.Changes)
+	}
+	for i, c := range want {
+		if plan.Changes[i] != c {
+			t.Errorf("change %d = %+v, want %+v", i, plan.Changes[i], c)
+		}
+	}
+	for _, call := range f.calls {
+		if call[:3] != "GET" {
+			t.Errorf("Expected PlanDestroy to only read, got %s", call)
+		}
+	}
+
+	// The environment goes with the last app in it, unless it is configured
+	f.otherApps = nil
+	if plan, err = p.PlanDestroy(context.Background(), m); err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	if c := plan.Changes[1]; c.Action != types.PlanDelete {
+		t.Errorf("Expected the environment to be deleted, got %+v", c)
+	}
+	m.ContainerApps.Environment = "my-app-prod-env"
+	if plan, err = p.PlanDestroy(context.Background(), m); err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	if c := plan.Changes[1]; c.Action != types.PlanNoChange || c.Reason != "kept; configured container_apps.environment" {
+		t.Errorf("Expected the configured environment to be kept, got %+v", c)
+	}
+}
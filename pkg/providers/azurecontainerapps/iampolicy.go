@@ -0,0 +1,59 @@
+package azurecontainerapps
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// roleDefinition is an Azure custom role definition, in the format accepted by
+// `az role definition create --role-definition`.
+type roleDefinition struct {
+	Name             string   `json:"Name"`
+	IsCustom         bool     `json:"IsCustom"`
+	Description      string   `json:"Description"`
+	Actions          []string `json:"Actions"`
+	NotActions       []string `json:"NotActions"`
+	AssignableScopes []string `json:"AssignableScopes"`
+}
+
+// IAMPolicy returns a custom role definition with the actions the deployer
+// needs, assignable on the manifest's resource group. A Container Apps
+// environment created by deploy lives in the same resource group.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	if m.Provider.SubscriptionID == "" || m.Provider.ResourceGroup == "" {
+		return nil, fmt.Errorf("provider.subscription_id and provider.resource_group are required to scope the role")
+	}
+
+	role := roleDefinition{
+		Name:        fmt.Sprintf("cloud-deploy deployer (%s)", m.Application.Name),
+		IsCustom:    true,
+		Description: fmt.Sprintf("Deploys %s to Azure Container Apps with cloud-deploy.", m.Application.Name),
+		Actions: []string{
+			"Microsoft.Resources/subscriptions/resourceGroups/read",
+			"Microsoft.Resources/subscriptions/resourceGroups/write",
+			"Microsoft.ContainerRegistry/registries/read",
+			"Microsoft.ContainerRegistry/registries/write",
+			"Microsoft.ContainerRegistry/registries/listCredentials/action",
+			"Microsoft.App/managedEnvironments/read",
+			"Microsoft.App/managedEnvironments/write",
+			"Microsoft.App/managedEnvironments/delete",
+			"Microsoft.App/managedEnvironments/join/action",
+			"Microsoft.App/containerApps/read",
+			"Microsoft.App/containerApps/write",
+			"Microsoft.App/containerApps/delete",
+			"Microsoft.App/containerApps/start/action",
+			"Microsoft.App/containerApps/stop/action",
+			"Microsoft.App/containerApps/getAuthToken/action",
+			"Microsoft.App/containerApps/revisions/read",
+			"Microsoft.App/containerApps/revisions/replicas/read",
+		},
+		NotActions: []string{},
+		AssignableScopes: []string{
+			fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", m.Provider.SubscriptionID, m.Provider.ResourceGroup),
+		},
+	}
+
+	return json.MarshalIndent(role, "", "  ")
+}
@@ -0,0 +1,40 @@
+package azurecontainerapps
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	data, err := IAMPolicy(testManifest())
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var role roleDefinition
+	if err := json.Unmarshal(data, &role); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	if !role.IsCustom || role.Name != "cloud-deploy deployer (my-app)" {
+		t.Errorf("unexpected role: %+v", role)
+	}
+	if len(role.AssignableScopes) != 1 || role.AssignableScopes[0] != "/subscriptions/sub-123/resourceGroups/my-rg" {
+		t.Errorf("Expected the role to be scoped to the resource group, got %v", role.AssignableScopes)
+	}
+	actions := make(map[string]bool)
+	for _, a := range role.Actions {
+		actions[a] = true
+	}
+	for _, a := range []string{"Microsoft.App/containerApps/write", "Microsoft.App/managedEnvironments/join/action", "Microsoft.ContainerRegistry/registries/listCredentials/action"} {
+		if !actions[a] {
+			t.Errorf("Expected action %s", a)
+		}
+	}
+}
+
+func TestIAMPolicyRequiresScope(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{Provider: manifest.ProviderConfig{SubscriptionID: "sub-123"}}); err == nil {
+		t.Error("Expected an error without provider.resource_group")
+	}
+}
@@ -0,0 +1,144 @@
+package azurecontainerapps
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// tailLines is how many lines of each container's console log are read per
+// fetch; the log stream API returns at most 300.
+const tailLines = 300
+
+// replica is a replica of a revision, with the containers it runs.
+type replica struct {
+	Name       string `json:"name"`
+	Properties struct {
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+	} `json:"properties"`
+}
+
+// logLine is a line of the console log stream.
+type logLine struct {
+	TimeStamp string `json:"TimeStamp"`
+	Log       string `json:"Log"`
+}
+
+// Logs prints the console output of each container of the app's latest
+// revision, read from the Container Apps log stream. The log stream only
+// keeps the recent output of running replicas, so output from replicas that
+// were scaled in is not available.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	app, err := p.requireApp(ctx, m)
+	if err != nil {
+		return err
+	}
+	base, _, ok := strings.Cut(app.Properties.EventStreamEndpoint, "/subscriptions/")
+	if !ok {
+		return fmt.Errorf("container app %s has no log stream endpoint", app.Name)
+	}
+	revision := app.Properties.LatestRevisionName
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		// Replicas come and go as the app scales, so they are listed on every fetch
+		var replicas struct {
+			Value []replica `json:"value"`
+		}
+		if err := p.client.Do(ctx, http.MethodGet, p.appPath(app.Name)+"/revisions/"+revision+"/replicas", appsAPIVersion, nil, &replicas); err != nil {
+			return nil, fmt.Errorf("failed to list replicas of revision %s: %w", revision, err)
+		}
+
+		var token struct {
+			Properties struct {
+				Token string `json:"token"`
+			} `json:"properties"`
+		}
+		if err := p.client.Do(ctx, http.MethodPost, p.appPath(app.Name)+"/getAuthtoken", appsAPIVersion, nil, &token); err != nil {
+			return nil, fmt.Errorf("failed to get a log stream token: %w", err)
+		}
+
+		var entries []types.LogEntry
+		for _, r := range replicas.Value {
+			for _, c := range r.Properties.Containers {
+				streamURL := fmt.Sprintf("%s%s/providers/Microsoft.App/containerApps/%s/revisions/%s/replicas/%s/containers/%s/logstream?%s",
+					base, p.client.ResourceGroupPath(p.resourceGroup), app.Name, revision, r.Name, c.Name,
+					url.Values{"tailLines": {fmt.Sprint(tailLines)}, "follow": {"false"}}.Encode())
+				lines, err := p.readLogStream(ctx, streamURL, token.Properties.Token)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get logs for container %s of replica %s: %w", c.Name, r.Name, err)
+				}
+				for _, e := range parseLogStream(r.Name+"/"+c.Name, lines) {
+					if !e.Timestamp.Before(since) {
+						entries = append(entries, e)
+					}
+				}
+			}
+		}
+		return entries, nil
+	})
+}
+
+// readLogStream returns the body of a log stream request authenticated with
+// the app's log stream token.
+func (p *Provider) readLogStream(ctx context.Context, streamURL, token string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// parseLogStream converts log stream output, one JSON object per line, into
+// entries. Lines that are not log records, such as the stream's connection
+// notices, are skipped.
+func parseLogStream(source, content string) []types.LogEntry {
+	var entries []types.LogEntry
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line logLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339Nano, line.TimeStamp)
+		if err != nil {
+			continue
+		}
+		message := strings.TrimRight(line.Log, "\n")
+		entries = append(entries, types.LogEntry{
+			// The API has no entry IDs; the timestamped line identifies the entry
+			ID:        source + " " + line.TimeStamp + " " + message,
+			Timestamp: ts,
+			Source:    source,
+			Message:   message,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,56 @@
+package azurecontainerapps
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out}); err == nil {
+		t.Error("Expected Logs to fail before the first deployment")
+	}
+
+	f.app = &containerApp{Name: "my-app-prod"}
+	f.addRevision(appTemplate{})
+	now := time.Now().UTC()
+	f.logs = "{\"TimeStamp\":\"" + now.Add(-2*time.Hour).Format(time.RFC3339Nano) + "\",\"Log\":\"too old\"}\n" +
+		"{\"TimeStamp\":\"" + now.Add(-2*time.Second).Format(time.RFC3339Nano) + "\",\"Log\":\"listening on :8080\\n\"}\n" +
+		"{\"TimeStamp\":\"" + now.Add(-time.Second).Format(time.RFC3339Nano) + "\",\"Log\":\"handled request\"}\n"
+
+	if err := p.Logs(ctx, m, types.LogOptions{Since: time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[my-app-prod--001-abc/my-app] listening on :8080") || !strings.HasSuffix(lines[1], "handled request") {
+		t.Errorf("unexpected logs:\n%s", out.String())
+	}
+}
+
+func TestParseLogStream(t *testing.T) {
+	content := `{"TimeStamp":"2026-03-01T10:00:00.5Z","Log":"started\n"}
+Connecting to the container 'my-app'...
+{"TimeStamp":"not a time","Log":"skipped"}
+{"TimeStamp":"2026-03-01T10:00:01Z","Log":"ready"}
+`
+	entries := parseLogStream("replica/my-app", content)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Message != "started" || entries[0].Source != "replica/my-app" || !entries[0].Timestamp.Equal(time.Date(2026, 3, 1, 10, 0, 0, 5e8, time.UTC)) {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[0].ID == entries[1].ID {
+		t.Errorf("Expected distinct IDs, got %q", entries[0].ID)
+	}
+}
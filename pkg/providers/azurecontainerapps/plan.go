@@ -0,0 +1,106 @@
+package azurecontainerapps
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jvreagan/cloud-deploy/pkg/azureapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/azure"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// planState records which of a deployment's resources already exist.
+type planState struct {
+	resourceGroup bool
+	registry      bool
+	environment   bool
+
+	// app is nil when the app does not exist
+	app *containerApp
+}
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing any Azure resources.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	var state planState
+	var err error
+
+	if state.resourceGroup, err = p.exists(ctx, p.client.ResourceGroupPath(p.resourceGroup), resourceGroupAPIVersion); err != nil {
+		return nil, fmt.Errorf("failed to get resource group: %w", err)
+	}
+
+	// Nothing can exist in a resource group that does not
+	if state.resourceGroup {
+		if state.registry, err = p.exists(ctx, p.registryPath(azure.RegistryName(m.Application.Name)), registryAPIVersion); err != nil {
+			return nil, fmt.Errorf("failed to get container registry: %w", err)
+		}
+		if state.environment, err = p.exists(ctx, p.environmentPath(m.ContainerApps.ManagedEnvironment(m.Environment.Name)), appsAPIVersion); err != nil {
+			return nil, fmt.Errorf("failed to get Container Apps environment: %w", err)
+		}
+		if state.app, err = p.getApp(ctx, m.Environment.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return buildPlan(m, p.resourceGroup, state), nil
+}
+
+// exists reports whether a Resource Manager Get call finds its resource.
+func (p *Provider) exists(ctx context.Context, path, apiVersion string) (bool, error) {
+	err := p.client.Do(ctx, http.MethodGet, path, apiVersion, nil, nil)
+	switch {
+	case err == nil:
+		return true, nil
+	case azureapi.IsNotFound(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// buildPlan returns the changes a deployment of m makes given the existing resources.
+func buildPlan(m *manifest.Manifest, resourceGroup string, state planState) *types.Plan {
+	plan := &types.Plan{Provider: "azure-container-apps"}
+
+	if state.resourceGroup {
+		plan.Add(types.PlanNoChange, "Resource group", resourceGroup, "")
+	} else {
+		plan.Add(types.PlanCreate, "Resource group", resourceGroup, "resource group does not exist")
+	}
+
+	registryName := azure.RegistryName(m.Application.Name)
+	if state.registry {
+		plan.Add(types.PlanNoChange, "Container registry", registryName, "")
+	} else {
+		plan.Add(types.PlanCreate, "Container registry", registryName, "registry does not exist")
+	}
+	repository := fmt.Sprintf("%s.azurecr.io/%s", registryName, registryName)
+	for _, c := range containers(m) {
+		tag := "deploy-<timestamp>"
+		if m.IsMultiContainer() {
+			tag = c.Name + "-" + tag
+		}
+		plan.Add(types.PlanCreate, "Container image", repository+":"+tag, "push "+c.Image)
+	}
+
+	environment := m.ContainerApps.ManagedEnvironment(m.Environment.Name)
+	if state.environment {
+		plan.Add(types.PlanNoChange, "Container Apps environment", environment, "")
+	} else {
+		plan.Add(types.PlanCreate, "Container Apps environment", environment, "environment does not exist")
+	}
+
+	min, max := m.ContainerApps.Replicas()
+	replicas := fmt.Sprintf("%d-%d replicas", min, max)
+	switch {
+	case state.app == nil:
+		plan.Add(types.PlanCreate, "Container app", m.Environment.Name, "app does not exist; "+replicas)
+	case state.app.Properties.RunningStatus == "Stopped":
+		plan.Add(types.PlanUpdate, "Container app", m.Environment.Name, "start and create a revision with the new images; "+replicas)
+	default:
+		plan.Add(types.PlanUpdate, "Container app", m.Environment.Name, "create a revision with the new images; "+replicas)
+	}
+	return plan
+}
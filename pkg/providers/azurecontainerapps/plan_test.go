@@ -0,0 +1,73 @@
+package azurecontainerapps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	f := newFakeARM()
+	p := testProvider(t, f)
+	m := testManifest()
+
+	plan, err := p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	app := plan.Changes[len(plan.Changes)-1]
+	if app.Type != "Container app" || app.Action != types.PlanCreate || app.Reason != "app does not exist; 1-5 replicas" {
+		t.Errorf("Expected the app to be created, got %+v", app)
+	}
+	if env := plan.Changes[len(plan.Changes)-2]; env.Action != types.PlanCreate || env.Name != "my-app-prod-env" {
+		t.Errorf("Expected the environment to be created, got %+v", env)
+	}
+	for _, call := range f.calls {
+		if call[:3] != "GET" {
+			t.Errorf("Expected Plan to only read, got %s", call)
+		}
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	m := testManifest()
+
+	plan := buildPlan(m, "my-rg", planState{})
+	want := []types.ResourceChange{
+		{Action: types.PlanCreate, Type: "Resource group", Name: "my-rg", Reason: "resource group does not exist"},
+		{Action: types.PlanCreate, Type: "Container registry", Name: "myapp", Reason: "registry does not exist"},
+		{Action: types.PlanCreate, Type: "Container image", Name: "myapp.azurecr.io/myapp:deploy-<timestamp>", Reason: "push my-app:1.0"},
+		{Action: types.PlanCreate, Type: "Container Apps environment", Name: "my-app-prod-env", Reason: "environment does not exist"},
+		{Action: types.PlanCreate, Type: "Container app", Name: "my-app-prod", Reason: "app does not exist; 1-5 replicas"},
+	}
+	if plan.Provider != "azure-container-apps" || len(plan.Changes) != len(want) {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	for i, c := range want {
+		if plan.Changes[i] != c {
+			t.Errorf("change %d = %+v, want %+v", i, plan.Changes[i], c)
+		}
+	}
+
+	app := &containerApp{}
+	app.Properties.RunningStatus = "Stopped"
+	plan = buildPlan(m, "my-rg", planState{resourceGroup: true, registry: true, environment: true, app: app})
+	for _, c := range plan.Changes[:2] {
+		if c.Action != types.PlanNoChange {
+			t.Errorf("Expected no change, got %+v", c)
+		}
+	}
+	if c := plan.Changes[4]; c.Action != types.PlanUpdate || c.Reason != "start and create a revision with the new images; 1-5 replicas" {
+		t.Errorf("Expected the stopped app to be started, got %+v", c)
+	}
+
+	// Each container of a multi-container deployment gets its own tag
+	m.Image = ""
+	m.Containers = []manifest.Container{{Name: "web", Image: "web:1"}, {Name: "worker", Image: "worker:1"}}
+	plan = buildPlan(m, "my-rg", planState{})
+	if plan.Changes[2].Name != "myapp.azurecr.io/myapp:web-deploy-<timestamp>" || plan.Changes[3].Name != "myapp.azurecr.io/myapp:worker-deploy-<timestamp>" {
+		t.Errorf("unexpected images: %+v", plan.Changes[2:4])
+	}
+}
@@ -0,0 +1,104 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultEndpoint is the base URL of the DigitalOcean API.
+const DefaultEndpoint = "https://api.digitalocean.com"
+
+// client calls the DigitalOcean API v2, authenticating with an API token.
+type client struct {
+	token      string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// newClient creates a client that authenticates with token.
+func newClient(token string) *client {
+	return &client{
+		token:      token,
+		endpoint:   DefaultEndpoint,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// apiError is an error returned by the DigitalOcean API.
+type apiError struct {
+	StatusCode int
+	ID         string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("DigitalOcean API error (HTTP %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("DigitalOcean API error (HTTP %d): %s", e.StatusCode, e.Message)
+}
+
+// isNotFound reports whether err is an apiError for a missing resource.
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends a request for path (including any query string). in is marshalled
+// as the JSON body and the response is unmarshalled into out; either may be nil.
+func (c *client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to DigitalOcean failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &apiError{StatusCode: resp.StatusCode}
+		var errBody struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil {
+			apiErr.ID, apiErr.Message = errBody.ID, errBody.Message
+		} else {
+			apiErr.Message = strings.TrimSpace(string(respBody))
+		}
+		return apiErr
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"id":"not_found","message":"The resource you were accessing could not be found."}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream unavailable\n"))
+		}
+	}))
+	defer server.Close()
+	c := newClient("do-token")
+	c.endpoint = server.URL
+
+	err := c.do(context.Background(), http.MethodGet, "/v2/missing", nil, nil)
+	if !isNotFound(err) || err.Error() != "DigitalOcean API error (HTTP 404): The resource you were accessing could not be found." {
+		t.Errorf("Expected a not found error, got: %v", err)
+	}
+	err = c.do(context.Background(), http.MethodGet, "/v2/apps", nil, nil)
+	if isNotFound(err) || err.Error() != "DigitalOcean API error (HTTP 502): upstream unavailable" {
+		t.Errorf("Expected the body of a non-JSON error, got: %v", err)
+	}
+	if isNotFound(fmt.Errorf("wrapped: %w", &apiError{StatusCode: http.StatusNotFound})) != true {
+		t.Error("Expected a wrapped not found error to be detected")
+	}
+}
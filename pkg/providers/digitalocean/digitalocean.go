@@ -0,0 +1,692 @@
+// Package digitalocean provides a DigitalOcean App Platform provider. It
+// runs the manifest's image as a service of an App Platform app named after
+// the environment, sized by instance.type, with the manifest's environment
+// variables. Images are pushed to the account's DigitalOcean Container
+// Registry and deployed by digest, and rolling back redeploys the app's
+// previous deployment. The provider calls the DigitalOcean API v2 directly
+// with an API token, so cloud-deploy needs no DigitalOcean SDK module.
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// registryTier is the subscription tier of container registries the provider
+// creates. The free starter tier holds a single repository.
+const registryTier = "basic"
+
+// defaultPort is the port App Platform routes requests to when the manifest
+// declares none.
+const defaultPort = 8080
+
+// pollInterval is how often a deployment is checked while waiting for it.
+var pollInterval = 10 * time.Second
+
+// deploymentTimeout is how long to wait for App Platform to deploy an app.
+var deploymentTimeout = 20 * time.Minute
+
+// Provider implements the provider.Provider interface for DigitalOcean App Platform.
+type Provider struct {
+	region string
+	token  string
+	client *client
+}
+
+// New creates a new DigitalOcean App Platform provider instance. The API
+// token is loaded from a secret store when credentials.source is vault or
+// secrets-manager, from credentials.digitalocean.token, or else from
+// $DIGITALOCEAN_TOKEN, $DIGITALOCEAN_ACCESS_TOKEN, or doctl's configuration.
+// When provider.endpoint_url is set, the API is reached at that emulator.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	token, err := loadToken(ctx, config, m)
+	if err != nil {
+		return nil, err
+	}
+	logging.Info("Initializing DigitalOcean App Platform provider")
+	client := newClient(token)
+	if config.Emulated() {
+		client.endpoint = strings.TrimRight(config.EndpointURL, "/")
+	}
+	return &Provider{
+		region: manifest.DigitalOceanRegion(config.Region),
+		token:  token,
+		client: client,
+	}, nil
+}
+
+// loadToken returns the API token of the provider's credentials.
+func loadToken(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (string, error) {
+	creds := config.Credentials
+	if creds.FromSecretStore() {
+		logging.Infof("Loading DigitalOcean credentials from %s...", creds.Source)
+		stored, err := m.GetCloudCredentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to load DigitalOcean credentials from %s: %w", creds.Source, err)
+		}
+		if stored == nil || stored.DigitalOcean.Token == "" {
+			return "", fmt.Errorf("no DigitalOcean token found in %s", creds.Source)
+		}
+		return stored.DigitalOcean.Token, nil
+	}
+	if creds != nil && creds.DigitalOcean != nil && creds.DigitalOcean.Token != "" {
+		return creds.DigitalOcean.Token, nil
+	}
+	for _, name := range []string{"DIGITALOCEAN_TOKEN", "DIGITALOCEAN_ACCESS_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+	token, err := doctlToken()
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("no DigitalOcean API token found; set DIGITALOCEAN_TOKEN, credentials.digitalocean.token, or run 'doctl auth init'")
+	}
+	return token, nil
+}
+
+// doctlConfigPath is where doctl keeps its configuration.
+var doctlConfigPath = func() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "doctl", "config.yaml")
+}
+
+// doctlToken returns the token of doctl's current authentication context, or
+// "" when doctl is not configured.
+func doctlToken() (string, error) {
+	path := doctlConfigPath()
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read doctl configuration: %w", err)
+	}
+	var config struct {
+		AccessToken  string            `yaml:"access-token"`
+		Context      string            `yaml:"context"`
+		AuthContexts map[string]string `yaml:"auth-contexts"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse doctl configuration %s: %w", path, err)
+	}
+	if config.Context != "" && config.Context != "default" {
+		return config.AuthContexts[config.Context], nil
+	}
+	return config.AccessToken, nil
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "digitalocean"
+}
+
+// Close closes the idle connections of the App Platform client.
+func (p *Provider) Close() error {
+	p.client.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// app is an App Platform app. Its spec is kept as decoded JSON so updates
+// preserve the parts cloud-deploy does not manage, such as domains and alerts.
+type app struct {
+	ID                   string                 `json:"id"`
+	Spec                 map[string]interface{} `json:"spec"`
+	LiveURL              string                 `json:"live_url"`
+	UpdatedAt            string                 `json:"updated_at"`
+	ActiveDeployment     *deployment            `json:"active_deployment"`
+	InProgressDeployment *deployment            `json:"in_progress_deployment"`
+	PendingDeployment    *deployment            `json:"pending_deployment"`
+}
+
+// deployment is a deployment of an app.
+type deployment struct {
+	ID    string `json:"id"`
+	Phase string `json:"phase"`
+	Spec  struct {
+		Services []serviceSpec `json:"services"`
+	} `json:"spec"`
+	Progress struct {
+		Steps []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Reason struct {
+				Message string `json:"message"`
+			} `json:"reason"`
+		} `json:"steps"`
+	} `json:"progress"`
+}
+
+// failure returns why a failed deployment failed.
+func (d *deployment) failure() string {
+	for _, step := range d.Progress.Steps {
+		if step.Status == "ERROR" && step.Reason.Message != "" {
+			return step.Name + ": " + step.Reason.Message
+		}
+	}
+	return "phase " + d.Phase
+}
+
+// serviceSpec is a service component of an app spec.
+type serviceSpec struct {
+	Name             string       `json:"name"`
+	Image            imageSpec    `json:"image"`
+	HTTPPort         int          `json:"http_port"`
+	InstanceCount    int32        `json:"instance_count"`
+	InstanceSizeSlug string       `json:"instance_size_slug"`
+	RunCommand       string       `json:"run_command,omitempty"`
+	Envs             []envVar     `json:"envs,omitempty"`
+	HealthCheck      *healthCheck `json:"health_check,omitempty"`
+}
+
+// imageSpec is the container image a service runs.
+type imageSpec struct {
+	RegistryType string `json:"registry_type"`
+	Repository   string `json:"repository"`
+	Digest       string `json:"digest,omitempty"`
+	Tag          string `json:"tag,omitempty"`
+}
+
+// envVar is an environment variable of a service. Values of SECRET variables
+// are encrypted by App Platform and not returned by the API.
+type envVar struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Scope string `json:"scope"`
+	Type  string `json:"type"`
+}
+
+// healthCheck is the HTTP health check of a service.
+type healthCheck struct {
+	HTTPPath string `json:"http_path"`
+}
+
+// Deploy pushes the manifest's image to the container registry and runs it on
+// App Platform, creating the app the first time and the registry if the
+// account has none. It waits for the deployment to become active.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := registry.ValidatePlatform(ctx, m.Image, registry.LinuxAMD64, "DigitalOcean App Platform", ""); err != nil {
+		return nil, err
+	}
+	logging.Info("Starting DigitalOcean App Platform deployment...")
+
+	// Step 1: Ensure the container registry exists
+	registryName, err := p.ensureRegistry(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Push the image to the registry
+	logging.Info("=== Distributing image to DigitalOcean Container Registry ===")
+	image, err := pushImage(ctx, registryName, m.RepositoryName(), m.VersionLabel(fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405"))), p.token, m.Image, m.Provider.RegistryURL, m.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Create or update the app
+	name := m.Environment.Name
+	existing, err := p.findApp(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		App app `json:"app"`
+	}
+	if existing == nil {
+		logging.Infof("Creating App Platform app: %s", name)
+		body := map[string]interface{}{"spec": buildSpec(nil, m, p.region, image)}
+		if err := p.client.do(ctx, http.MethodPost, "/v2/apps", body, &out); err != nil {
+			return nil, fmt.Errorf("failed to create App Platform app %s: %w", name, err)
+		}
+	} else {
+		logging.Infof("Updating App Platform app: %s", name)
+		body := map[string]interface{}{"spec": buildSpec(existing.Spec, m, p.region, image)}
+		if err := p.client.do(ctx, http.MethodPut, "/v2/apps/"+existing.ID, body, &out); err != nil {
+			return nil, fmt.Errorf("failed to update App Platform app %s: %w", name, err)
+		}
+	}
+
+	// Step 4: Wait for the deployment the change started
+	a, err := p.waitForApp(ctx, &out.App)
+	if err != nil {
+		return nil, err
+	}
+
+	imageRef := fmt.Sprintf("%s/%s/%s@%s", registry.DOCRHost, registryName, image.Repository, image.Digest)
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             a.LiveURL,
+		Status:          "Running",
+		Message:         "Deployment successful",
+		Images:          map[string]string{m.Application.Name: imageRef},
+	}, nil
+}
+
+// waitForApp waits for the app's pending or in-progress deployment to become
+// active and returns the app as it is then. An app without one is returned
+// unchanged, as App Platform starts no deployment when the spec is unchanged.
+func (p *Provider) waitForApp(ctx context.Context, a *app) (*app, error) {
+	d := a.InProgressDeployment
+	if d == nil {
+		d = a.PendingDeployment
+	}
+	if d == nil {
+		return a, nil
+	}
+	if err := p.waitForDeployment(ctx, a.ID, d.ID); err != nil {
+		return nil, err
+	}
+	return p.getApp(ctx, a.ID)
+}
+
+// waitForDeployment polls a deployment until it is active, failing if it
+// errors, is canceled, or is superseded by another deployment first.
+func (p *Provider) waitForDeployment(ctx context.Context, appID, deploymentID string) error {
+	logging.Infof("Waiting for deployment %s...", deploymentID)
+	deadline := time.Now().Add(deploymentTimeout)
+	for {
+		var out struct {
+			Deployment deployment `json:"deployment"`
+		}
+		if err := p.client.do(ctx, http.MethodGet, "/v2/apps/"+appID+"/deployments/"+deploymentID, nil, &out); err != nil {
+			return fmt.Errorf("failed to get deployment %s: %w", deploymentID, err)
+		}
+		d := out.Deployment
+		switch d.Phase {
+		case "ACTIVE":
+			logging.Infof("Deployment %s is active", deploymentID)
+			return nil
+		case "ERROR", "CANCELED", "SUPERSEDED":
+			return fmt.Errorf("deployment %s failed (%s)", deploymentID, d.failure())
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for deployment %s (phase %s)", deploymentID, d.Phase)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// buildSpec returns the spec of the app running image. The service named
+// after the environment is replaced in existing, the spec of an existing app,
+// and the rest of it is kept; archiving is cleared so a stopped app starts.
+func buildSpec(existing map[string]interface{}, m *manifest.Manifest, region string, image imageSpec) map[string]interface{} {
+	name := m.Environment.Name
+	spec := make(map[string]interface{}, len(existing)+4)
+	for k, v := range existing {
+		spec[k] = v
+	}
+	spec["name"] = name
+	if _, ok := spec["region"]; !ok && region != "" {
+		spec["region"] = region
+	}
+	delete(spec, "maintenance")
+
+	service := toMap(buildService(m, image))
+	services, _ := spec["services"].([]interface{})
+	replaced := false
+	for i, s := range services {
+		if s, ok := s.(map[string]interface{}); ok && s["name"] == name {
+			services[i] = service
+			replaced = true
+		}
+	}
+	if !replaced {
+		services = append(services, service)
+	}
+	spec["services"] = services
+
+	if _, ok := spec["ingress"]; !ok {
+		spec["ingress"] = map[string]interface{}{
+			"rules": []interface{}{map[string]interface{}{
+				"match":     map[string]interface{}{"path": map[string]string{"prefix": "/"}},
+				"component": map[string]string{"name": name},
+			}},
+		}
+	}
+	return spec
+}
+
+// buildService returns the service component running image with the
+// manifest's port, size, command, environment variables, and health check.
+func buildService(m *manifest.Manifest, image imageSpec) serviceSpec {
+	c := m.GetPrimaryContainer()
+	size, count := manifest.DigitalOceanInstanceSize(m.Instance)
+	service := serviceSpec{
+		Name:             m.Environment.Name,
+		Image:            image,
+		HTTPPort:         defaultPort,
+		InstanceCount:    count,
+		InstanceSizeSlug: size,
+		RunCommand:       strings.Join(append(append([]string{}, c.Command...), c.Args...), " "),
+	}
+	if len(c.Ports) > 0 {
+		service.HTTPPort = c.Ports[0].ContainerPort
+	}
+	if m.HealthCheck.Path != "" {
+		service.HealthCheck = &healthCheck{HTTPPath: m.HealthCheck.Path}
+	}
+	if c.Workdir != "" {
+		logging.Warn("container.workdir is not supported by DigitalOcean App Platform and will be ignored")
+	}
+
+	secrets := make(map[string]bool, len(m.Secrets))
+	for _, s := range m.Secrets {
+		secrets[s.Name] = true
+	}
+	names := make([]string, 0, len(c.Environment))
+	for name := range c.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env := envVar{Key: name, Value: c.Environment[name], Scope: "RUN_TIME", Type: "GENERAL"}
+		if secrets[name] {
+			env.Type = "SECRET"
+		}
+		service.Envs = append(service.Envs, env)
+	}
+	return service
+}
+
+// toMap converts v to decoded JSON, so it can be placed in a spec kept as
+// decoded JSON.
+func toMap(v interface{}) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// Destroy deletes the app. The container registry is kept with the images of
+// every environment.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	a, err := p.findApp(ctx, m.Environment.Name)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		logging.Infof("App Platform app %s does not exist; nothing to destroy", m.Environment.Name)
+		return nil
+	}
+	logging.Infof("Deleting App Platform app: %s", m.Environment.Name)
+	if err := p.client.do(ctx, http.MethodDelete, "/v2/apps/"+a.ID, nil, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete App Platform app %s: %w", m.Environment.Name, err)
+	}
+	logging.Info("App Platform app deleted successfully")
+	return nil
+}
+
+// Stop archives the app, which stops its containers and billing while keeping
+// its spec and deployments; running Deploy again restores it.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	a, err := p.requireApp(ctx, m)
+	if err != nil {
+		return err
+	}
+	if archived(a) {
+		logging.Infof("App Platform app %s is already stopped", m.Environment.Name)
+		return nil
+	}
+	logging.Infof("Archiving App Platform app: %s", m.Environment.Name)
+	spec := make(map[string]interface{}, len(a.Spec)+1)
+	for k, v := range a.Spec {
+		spec[k] = v
+	}
+	spec["maintenance"] = map[string]bool{"archive": true}
+	var out struct {
+		App app `json:"app"`
+	}
+	if err := p.client.do(ctx, http.MethodPut, "/v2/apps/"+a.ID, map[string]interface{}{"spec": spec}, &out); err != nil {
+		return fmt.Errorf("failed to archive App Platform app %s: %w", m.Environment.Name, err)
+	}
+	if _, err := p.waitForApp(ctx, &out.App); err != nil {
+		return err
+	}
+	logging.Info("App Platform app stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// archived reports whether the app is archived.
+func archived(a *app) bool {
+	maintenance, _ := a.Spec["maintenance"].(map[string]interface{})
+	archive, _ := maintenance["archive"].(bool)
+	return archive
+}
+
+// Status returns the current status of the app.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	a, err := p.requireApp(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	status, health := appStatus(a)
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             a.LiveURL,
+		LastUpdated:     a.UpdatedAt,
+	}, nil
+}
+
+// appStatus returns the status and health of an app from its deployments.
+func appStatus(a *app) (string, string) {
+	switch {
+	case archived(a):
+		return "Stopped", "Grey"
+	case a.InProgressDeployment != nil || a.PendingDeployment != nil:
+		return "Updating", "Yellow"
+	case a.ActiveDeployment == nil:
+		return "Failed", "Red"
+	case a.ActiveDeployment.Phase == "ACTIVE":
+		return "Running", "Green"
+	default:
+		return a.ActiveDeployment.Phase, "Unknown"
+	}
+}
+
+// Rollback redeploys the deployment that was active before the current one,
+// without pinning the app to it, so the next Deploy proceeds as usual. The
+// current deployment then becomes the previous one, so rolling back twice
+// returns to where you started.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting DigitalOcean App Platform rollback...")
+	a, err := p.requireApp(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := p.previousDeployment(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("Rolling back %s to deployment %s", m.Environment.Name, previous.ID)
+	var out struct {
+		Deployment deployment `json:"deployment"`
+	}
+	body := map[string]interface{}{"deployment_id": previous.ID, "skip_pin": true}
+	if err := p.client.do(ctx, http.MethodPost, "/v2/apps/"+a.ID+"/rollback", body, &out); err != nil {
+		return nil, fmt.Errorf("failed to roll back App Platform app %s: %w", m.Environment.Name, err)
+	}
+	if err := p.waitForDeployment(ctx, a.ID, out.Deployment.ID); err != nil {
+		return nil, err
+	}
+
+	result := &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             a.LiveURL,
+		Status:          "Running",
+		Message:         "Rolled back to deployment " + previous.ID,
+	}
+	for _, s := range previous.Spec.Services {
+		if s.Name == m.Environment.Name && s.Image.Digest != "" {
+			result.Images = map[string]string{m.Application.Name: s.Image.Repository + "@" + s.Image.Digest}
+		}
+	}
+	return result, nil
+}
+
+// previousDeployment returns the most recent deployment that was active
+// before the app's active deployment.
+func (p *Provider) previousDeployment(ctx context.Context, a *app) (*deployment, error) {
+	if a.ActiveDeployment == nil {
+		return nil, fmt.Errorf("App Platform app %s has no active deployment to roll back from", a.ID)
+	}
+	var out struct {
+		Deployments []deployment `json:"deployments"`
+	}
+	if err := p.client.do(ctx, http.MethodGet, "/v2/apps/"+a.ID+"/deployments?per_page=50", nil, &out); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	// Deployments are listed newest first
+	passedActive := false
+	for i := range out.Deployments {
+		d := &out.Deployments[i]
+		if d.ID == a.ActiveDeployment.ID {
+			passedActive = true
+		} else if passedActive && d.Phase == "SUPERSEDED" {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("no previous deployment found for App Platform app %s; it has been deployed only once", a.ID)
+}
+
+// findApp returns the app with the given name, or nil when there is none.
+func (p *Provider) findApp(ctx context.Context, name string) (*app, error) {
+	const perPage = 100
+	for page := 1; ; page++ {
+		var out struct {
+			Apps []app `json:"apps"`
+		}
+		query := url.Values{"page": {strconv.Itoa(page)}, "per_page": {strconv.Itoa(perPage)}}
+		if err := p.client.do(ctx, http.MethodGet, "/v2/apps?"+query.Encode(), nil, &out); err != nil {
+			return nil, fmt.Errorf("failed to list App Platform apps: %w", err)
+		}
+		for i := range out.Apps {
+			if out.Apps[i].Spec["name"] == name {
+				return &out.Apps[i], nil
+			}
+		}
+		if len(out.Apps) < perPage {
+			return nil, nil
+		}
+	}
+}
+
+// getApp returns the app with the given ID.
+func (p *Provider) getApp(ctx context.Context, id string) (*app, error) {
+	var out struct {
+		App app `json:"app"`
+	}
+	if err := p.client.do(ctx, http.MethodGet, "/v2/apps/"+id, nil, &out); err != nil {
+		return nil, fmt.Errorf("failed to get App Platform app %s: %w", id, err)
+	}
+	return &out.App, nil
+}
+
+// requireApp returns the environment's app, failing when it does not exist.
+func (p *Provider) requireApp(ctx context.Context, m *manifest.Manifest) (*app, error) {
+	a, err := p.findApp(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, fmt.Errorf("no App Platform app found for environment %s", m.Environment.Name)
+	}
+	return a, nil
+}
+
+// getRegistry returns the name of the account's container registry, or ""
+// when the account has none.
+func (p *Provider) getRegistry(ctx context.Context) (string, error) {
+	var out struct {
+		Registry struct {
+			Name string `json:"name"`
+		} `json:"registry"`
+	}
+	err := p.client.do(ctx, http.MethodGet, "/v2/registry", nil, &out)
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get container registry: %w", err)
+	}
+	return out.Registry.Name, nil
+}
+
+// ensureRegistry returns the name of the container registry images are
+// pushed to. An account has at most one registry; when it has none, one is
+// created with the configured name or the application name.
+func (p *Provider) ensureRegistry(ctx context.Context, m *manifest.Manifest) (string, error) {
+	existing, err := p.getRegistry(ctx)
+	if err != nil {
+		return "", err
+	}
+	configured := m.DigitalOcean.RegistryName()
+	if existing != "" {
+		if configured != "" && configured != existing {
+			return "", fmt.Errorf("digitalocean.registry is %s, but the account's container registry is %s", configured, existing)
+		}
+		return existing, nil
+	}
+
+	name := configured
+	if name == "" {
+		name = m.Application.Name
+	}
+	logging.Infof("Creating container registry %s (%s tier)", name, registryTier)
+	body := map[string]string{"name": name, "subscription_tier_slug": registryTier}
+	if err := p.client.do(ctx, http.MethodPost, "/v2/registry", body, nil); err != nil {
+		return "", fmt.Errorf("failed to create container registry %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// pushImage pushes image as repository:tag to the named container registry,
+// or to the registry at registryURL when it is set, and returns the image App
+// Platform runs, pinned by digest.
+var pushImage = func(ctx context.Context, registryName, repository, tag, token, image, registryURL string, platforms []string) (imageSpec, error) {
+	docr, err := registry.NewDOCRRegistry(registryName, repository, tag, token)
+	if err != nil {
+		return imageSpec{}, fmt.Errorf("failed to create DOCR registry handler: %w", err)
+	}
+	distributor := registry.NewDistributor(image, platforms...)
+	dest := registry.Local(docr, registryURL, repository, tag)
+	distributor.AddRegistry(dest)
+	if _, err := distributor.Distribute(ctx); err != nil {
+		return imageSpec{}, fmt.Errorf("failed to distribute image: %w", err)
+	}
+	logging.Infof("Image pushed: %s", registry.PinDigest(dest.GetImageURI(), distributor.Digest()))
+	return imageSpec{RegistryType: "DOCR", Repository: repository, Digest: distributor.Digest()}, nil
+}
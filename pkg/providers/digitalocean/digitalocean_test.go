@@ -0,0 +1,451 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeAPI serves the subset of the DigitalOcean API the provider uses. New
+// deployments are deploying until they are read once, and then take the
+// phase in finalPhase.
+type fakeAPI struct {
+	mu          sync.Mutex
+	registry    string
+	created     map[string]string
+	apps        []map[string]interface{}
+	specs       []map[string]interface{}
+	deployments []map[string]interface{}
+	phases      map[string]string
+	finalPhase  string
+	rolledBack  map[string]interface{}
+	logFiles    map[string]string
+	requests    []string
+
+	server *httptest.Server
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, *Provider) {
+	t.Helper()
+	f := &fakeAPI{phases: make(map[string]string), finalPhase: "ACTIVE", logFiles: make(map[string]string)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+
+	oldInterval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldInterval })
+
+	c := newClient("do-token")
+	c.endpoint = f.server.URL
+	return f, &Provider{region: "nyc", token: "do-token", client: c}
+}
+
+// addApp adds an app with an active deployment.
+func (f *fakeAPI) addApp(name string, spec map[string]interface{}) map[string]interface{} {
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	spec["name"] = name
+	a := map[string]interface{}{
+		"id":                "app-" + name,
+		"spec":              spec,
+		"live_url":          "https://" + name + ".ondigitalocean.app",
+		"updated_at":        "2026-01-02T03:04:05Z",
+		"active_deployment": map[string]string{"id": "dep-active", "phase": "ACTIVE"},
+	}
+	f.apps = append(f.apps, a)
+	return a
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.HasPrefix(r.URL.Path, "/files/") {
+		w.Write([]byte(f.logFiles[r.URL.Path]))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer do-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"id":"unauthorized","message":"Unable to authenticate you"}`))
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")
+	switch {
+	case r.URL.Path == "/v2/registry" && r.Method == http.MethodGet:
+		if f.registry == "" {
+			notFound(w)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"registry": map[string]string{"name": f.registry}})
+	case r.URL.Path == "/v2/registry" && r.Method == http.MethodPost:
+		f.created = map[string]string{"name": body["name"].(string), "tier": body["subscription_tier_slug"].(string)}
+		f.registry = f.created["name"]
+		w.Write([]byte(`{"registry":{}}`))
+	case r.URL.Path == "/v2/apps" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"apps": f.apps})
+	case r.URL.Path == "/v2/apps" && r.Method == http.MethodPost:
+		spec := body["spec"].(map[string]interface{})
+		f.specs = append(f.specs, spec)
+		a := f.addApp(spec["name"].(string), spec)
+		delete(a, "active_deployment")
+		a["pending_deployment"] = f.newDeployment()
+		json.NewEncoder(w).Encode(map[string]interface{}{"app": a})
+	case len(parts) == 2 && parts[0] == "apps":
+		a := f.app(parts[1])
+		if a == nil {
+			notFound(w)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			spec := body["spec"].(map[string]interface{})
+			f.specs = append(f.specs, spec)
+			a["spec"] = spec
+			a["in_progress_deployment"] = f.newDeployment()
+		case http.MethodDelete:
+			f.apps = nil
+			w.Write([]byte(`{}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"app": a})
+		delete(a, "in_progress_deployment")
+	case len(parts) == 3 && parts[2] == "deployments":
+		json.NewEncoder(w).Encode(map[string]interface{}{"deployments": f.deployments})
+	case len(parts) == 3 && parts[2] == "rollback":
+		f.rolledBack = body
+		json.NewEncoder(w).Encode(map[string]interface{}{"deployment": f.newDeployment()})
+	case len(parts) == 4 && parts[2] == "deployments":
+		id := parts[3]
+		phase, ok := f.phases[id]
+		if !ok {
+			notFound(w)
+			return
+		}
+		f.phases[id] = f.finalPhase
+		d := map[string]interface{}{"id": id, "phase": phase}
+		if phase == "ERROR" {
+			d["progress"] = map[string]interface{}{"steps": []map[string]interface{}{
+				{"name": "deploy", "status": "ERROR", "reason": map[string]string{"message": "health check failed"}},
+			}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"deployment": d})
+	case len(parts) == 7 && parts[6] == "logs":
+		if r.URL.Query().Get("type") != "RUN" {
+			t := r.URL.Query().Get("type")
+			http.Error(w, `{"message":"bad type `+t+`"}`, http.StatusBadRequest)
+			return
+		}
+		var urls []string
+		for path := range f.logFiles {
+			urls = append(urls, f.server.URL+path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"historic_urls": urls})
+	default:
+		notFound(w)
+	}
+}
+
+func notFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"id":"not_found","message":"The resource you were accessing could not be found."}`))
+}
+
+// newDeployment starts a deployment, which is deploying until it is read.
+func (f *fakeAPI) newDeployment() map[string]string {
+	id := "dep-" + string(rune('a'+len(f.phases)))
+	f.phases[id] = "DEPLOYING"
+	return map[string]string{"id": id, "phase": "PENDING_DEPLOY"}
+}
+
+func (f *fakeAPI) app(id string) map[string]interface{} {
+	for _, a := range f.apps {
+		if a["id"] == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:       "localhost:1/my-app:v2",
+		Provider:    manifest.ProviderConfig{Name: "digitalocean", Region: "nyc3"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Instance:    manifest.InstanceConfig{Type: "t3.small", MinInstances: 2},
+		HealthCheck: manifest.HealthCheckConfig{Path: "/healthz"},
+		Ports:       []manifest.PortMapping{{ContainerPort: 3000}},
+		EnvironmentVariables: map[string]string{
+			"LOG_LEVEL":   "info",
+			"DB_PASSWORD": "s3cret",
+		},
+		Secrets: []manifest.SecretRef{{Name: "DB_PASSWORD", SecretID: "db"}},
+	}
+}
+
+// stubPush replaces the image push with one returning a fixed digest.
+func stubPush(t *testing.T) *[]string {
+	t.Helper()
+	old := pushImage
+	t.Cleanup(func() { pushImage = old })
+	var pushed []string
+	pushImage = func(ctx context.Context, registryName, repository, tag, token, image, registryURL string, platforms []string) (imageSpec, error) {
+		pushed = append(pushed, image+" -> "+registryName+"/"+repository)
+		return imageSpec{RegistryType: "DOCR", Repository: repository, Digest: "sha256:abc"}, nil
+	}
+	return &pushed
+}
+
+func TestProviderName(t *testing.T) {
+	if name := (&Provider{}).Name(); name != "digitalocean" {
+		t.Errorf("Name() = %q, want digitalocean", name)
+	}
+}
+
+func TestNewEmulator(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_TOKEN", "test")
+	m := testManifest()
+	m.Provider.EndpointURL = "http://localhost:4010/"
+	p, err := New(context.Background(), &m.Provider, m)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.client.endpoint != "http://localhost:4010" {
+		t.Errorf("endpoint = %q, want the emulator", p.client.endpoint)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_TOKEN", "")
+	t.Setenv("DIGITALOCEAN_ACCESS_TOKEN", "")
+	dir := t.TempDir()
+	oldPath := doctlConfigPath
+	doctlConfigPath = func() string { return filepath.Join(dir, "config.yaml") }
+	t.Cleanup(func() { doctlConfigPath = oldPath })
+	m := testManifest()
+	load := func(config manifest.ProviderConfig) (string, error) {
+		return loadToken(context.Background(), &config, m)
+	}
+
+	if _, err := load(m.Provider); err == nil || !strings.Contains(err.Error(), "doctl auth init") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("access-token: doctl-default\ncontext: work\nauth-contexts:\n  work: doctl-work\n"), 0o600)
+	if token, err := load(m.Provider); err != nil || token != "doctl-work" {
+		t.Errorf("Expected the token of doctl's context, got %q, %v", token, err)
+	}
+
+	t.Setenv("DIGITALOCEAN_ACCESS_TOKEN", "env-token")
+	if token, _ := load(m.Provider); token != "env-token" {
+		t.Errorf("Expected the environment's token, got %q", token)
+	}
+
+	config := m.Provider
+	config.Credentials = &manifest.CredentialsConfig{Source: "manifest", DigitalOcean: &manifest.DigitalOceanCredentialsConfig{Token: "manifest-token"}}
+	if token, _ := load(config); token != "manifest-token" {
+		t.Errorf("Expected the manifest's token, got %q", token)
+	}
+}
+
+func TestDeployCreatesApp(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	pushed := stubPush(t)
+	m := testManifest()
+	m.DigitalOcean = &manifest.DigitalOceanConfig{Registry: "acme"}
+
+	result, err := p.Deploy(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if fake.created["name"] != "acme" || fake.created["tier"] != "basic" {
+		t.Errorf("Expected the configured registry to be created, got %v", fake.created)
+	}
+	if strings.Join(*pushed, ", ") != "localhost:1/my-app:v2 -> acme/my-app" {
+		t.Errorf("Unexpected pushes: %v", *pushed)
+	}
+	if result.URL != "https://my-app-prod.ondigitalocean.app" || result.Images["my-app"] != "registry.digitalocean.com/acme/my-app@sha256:abc" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	spec := fake.specs[0]
+	if spec["name"] != "my-app-prod" || spec["region"] != "nyc" || spec["ingress"] == nil {
+		t.Errorf("Unexpected spec: %v", spec)
+	}
+	data, _ := json.Marshal(spec["services"])
+	var services []serviceSpec
+	json.Unmarshal(data, &services)
+	if len(services) != 1 {
+		t.Fatalf("Expected one service, got %v", services)
+	}
+	s := services[0]
+	if s.Image.Digest != "sha256:abc" || s.Image.RegistryType != "DOCR" || s.HTTPPort != 3000 ||
+		s.InstanceSizeSlug != "apps-s-1vcpu-2gb" || s.InstanceCount != 2 || s.HealthCheck.HTTPPath != "/healthz" {
+		t.Errorf("Unexpected service: %+v", s)
+	}
+	if len(s.Envs) != 2 || s.Envs[0].Key != "DB_PASSWORD" || s.Envs[0].Type != "SECRET" || s.Envs[1].Type != "GENERAL" {
+		t.Errorf("Expected DB_PASSWORD as a secret and LOG_LEVEL in plain text, got %+v", s.Envs)
+	}
+}
+
+func TestDeployUpdatesApp(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.registry = "acme"
+	stubPush(t)
+	fake.addApp("my-app-prod", map[string]interface{}{
+		"region":      "ams",
+		"domains":     []interface{}{map[string]interface{}{"domain": "www.example.com"}},
+		"maintenance": map[string]interface{}{"archive": true},
+		"services": []interface{}{
+			map[string]interface{}{"name": "my-app-prod", "instance_size_slug": "apps-s-1vcpu-0.5gb"},
+			map[string]interface{}{"name": "worker"},
+		},
+	})
+
+	if _, err := p.Deploy(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if fake.created != nil {
+		t.Error("Expected the account's registry to be used")
+	}
+	spec := fake.specs[0]
+	if spec["domains"] == nil || spec["region"] != "ams" {
+		t.Errorf("Expected the domains and region to be kept, got %v", spec)
+	}
+	if _, ok := spec["maintenance"]; ok {
+		t.Error("Expected the archived app to be restored")
+	}
+	services := spec["services"].([]interface{})
+	if len(services) != 2 || services[0].(map[string]interface{})["instance_size_slug"] != "apps-s-1vcpu-2gb" || services[1].(map[string]interface{})["name"] != "worker" {
+		t.Errorf("Expected the service to be replaced and the worker kept, got %v", services)
+	}
+}
+
+func TestDeployFailure(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.registry = "acme"
+	fake.finalPhase = "ERROR"
+	stubPush(t)
+
+	_, err := p.Deploy(context.Background(), testManifest())
+	if err == nil || !strings.Contains(err.Error(), "deploy: health check failed") {
+		t.Errorf("Expected the failed step, got: %v", err)
+	}
+}
+
+func TestEnsureRegistryMismatch(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.registry = "acme"
+	m := testManifest()
+	m.DigitalOcean = &manifest.DigitalOceanConfig{Registry: "other"}
+
+	if _, err := p.ensureRegistry(context.Background(), m); err == nil || !strings.Contains(err.Error(), "account's container registry is acme") {
+		t.Errorf("Expected a registry mismatch error, got: %v", err)
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	if err := p.Destroy(context.Background(), testManifest()); err != nil {
+		t.Errorf("Expected a missing app to have nothing to destroy, got: %v", err)
+	}
+
+	fake.addApp("my-app-prod", nil)
+	if err := p.Destroy(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if len(fake.apps) != 0 {
+		t.Error("Expected the app to be deleted")
+	}
+}
+
+func TestStop(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	if err := p.Stop(context.Background(), testManifest()); err == nil || !strings.Contains(err.Error(), "no App Platform app found") {
+		t.Errorf("Expected a missing app error, got: %v", err)
+	}
+
+	a := fake.addApp("my-app-prod", map[string]interface{}{"domains": []interface{}{}})
+	if err := p.Stop(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	spec := a["spec"].(map[string]interface{})
+	if !archived(&app{Spec: spec}) || spec["domains"] == nil {
+		t.Errorf("Expected the app to be archived with its spec kept, got %v", spec)
+	}
+
+	n := len(fake.specs)
+	if err := p.Stop(context.Background(), testManifest()); err != nil || len(fake.specs) != n {
+		t.Errorf("Expected an archived app to be left alone, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.addApp("my-app-prod", nil)
+
+	status, err := p.Status(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.URL != "https://my-app-prod.ondigitalocean.app" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	tests := []struct {
+		app    app
+		status string
+		health string
+	}{
+		{app{Spec: map[string]interface{}{"maintenance": map[string]interface{}{"archive": true}}}, "Stopped", "Grey"},
+		{app{ActiveDeployment: &deployment{Phase: "ACTIVE"}, InProgressDeployment: &deployment{}}, "Updating", "Yellow"},
+		{app{}, "Failed", "Red"},
+	}
+	for _, tt := range tests {
+		if status, health := appStatus(&tt.app); status != tt.status || health != tt.health {
+			t.Errorf("appStatus(%+v) = %s, %s, want %s, %s", tt.app, status, health, tt.status, tt.health)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.addApp("my-app-prod", nil)
+	fake.deployments = []map[string]interface{}{
+		{"id": "dep-pending", "phase": "PENDING_DEPLOY"},
+		{"id": "dep-active", "phase": "ACTIVE"},
+		{"id": "dep-failed", "phase": "ERROR"},
+		{"id": "dep-previous", "phase": "SUPERSEDED", "spec": map[string]interface{}{"services": []map[string]interface{}{
+			{"name": "my-app-prod", "image": map[string]string{"repository": "my-app", "digest": "sha256:old"}},
+		}}},
+	}
+
+	result, err := p.Rollback(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if fake.rolledBack["deployment_id"] != "dep-previous" || fake.rolledBack["skip_pin"] != true {
+		t.Errorf("Expected the previous deployment to be redeployed without pinning, got %v", fake.rolledBack)
+	}
+	if result.Images["my-app"] != "my-app@sha256:old" || result.Message != "Rolled back to deployment dep-previous" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	fake.deployments = fake.deployments[:3]
+	if _, err := p.Rollback(context.Background(), testManifest()); err == nil || !strings.Contains(err.Error(), "deployed only once") {
+		t.Errorf("Expected no previous deployment, got: %v", err)
+	}
+}
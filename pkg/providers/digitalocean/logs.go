@@ -0,0 +1,102 @@
+package digitalocean
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Logs prints the service's output from its active deployment. App Platform
+// returns the output as files at pre-signed URLs, which are read again for
+// new entries when following.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	a, err := p.requireApp(ctx, m)
+	if err != nil {
+		return err
+	}
+	if a.ActiveDeployment == nil {
+		return fmt.Errorf("App Platform app %s has no active deployment", m.Environment.Name)
+	}
+	component := m.Environment.Name
+	path := fmt.Sprintf("/v2/apps/%s/deployments/%s/components/%s/logs?%s", a.ID, a.ActiveDeployment.ID, url.PathEscape(component),
+		url.Values{"type": {"RUN"}, "follow": {"false"}}.Encode())
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var out struct {
+			HistoricURLs []string `json:"historic_urls"`
+		}
+		if err := p.client.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+			return nil, fmt.Errorf("failed to get logs of %s: %w", component, err)
+		}
+		var entries []types.LogEntry
+		for _, u := range out.HistoricURLs {
+			content, err := p.readLogs(ctx, u)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range parseLogs(component, content) {
+				if !e.Timestamp.Before(since) {
+					entries = append(entries, e)
+				}
+			}
+		}
+		return entries, nil
+	})
+}
+
+// readLogs returns the content of a log file at a pre-signed URL, which needs
+// no API token.
+func (p *Provider) readLogs(ctx context.Context, u string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to read logs: HTTP %d", resp.StatusCode)
+	}
+	return string(body), nil
+}
+
+// parseLogs converts App Platform log output, whose lines hold the component
+// name, an RFC 3339 timestamp, and the message, into entries. Lines without a
+// timestamp are skipped.
+func parseLogs(component, content string) []types.LogEntry {
+	var entries []types.LogEntry
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		rest := strings.TrimPrefix(line, component+" ")
+		timestamp, message, _ := strings.Cut(rest, " ")
+		ts, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, types.LogEntry{
+			// The files have no entry IDs; the timestamped line identifies the entry
+			ID:        line,
+			Timestamp: ts,
+			Source:    component,
+			Message:   strings.TrimSpace(message),
+		})
+	}
+	return entries
+}
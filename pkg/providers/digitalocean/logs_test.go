@@ -0,0 +1,52 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.addApp("my-app-prod", nil)
+	now := time.Now().UTC()
+	fake.logFiles["/files/run.log"] = "my-app-prod " + now.Add(-2*time.Hour).Format(time.RFC3339Nano) + " too old\n" +
+		"my-app-prod " + now.Add(-time.Second).Format(time.RFC3339Nano) + " listening on :8080\n"
+
+	var out bytes.Buffer
+	if err := p.Logs(context.Background(), testManifest(), types.LogOptions{Since: 10 * time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "listening on :8080") || strings.Contains(got, "too old") {
+		t.Errorf("Unexpected logs: %q", got)
+	}
+}
+
+func TestLogsNoApp(t *testing.T) {
+	_, p := newFakeAPI(t)
+	err := p.Logs(context.Background(), testManifest(), types.LogOptions{Output: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "no App Platform app found") {
+		t.Errorf("Expected a missing app error, got: %v", err)
+	}
+}
+
+func TestParseLogs(t *testing.T) {
+	content := "my-app-prod 2026-01-02T03:04:05.123456789Z listening on :8080\n" +
+		"not a log line\n" +
+		"2026-01-02T03:04:06Z  handled request\n"
+
+	entries := parseLogs("my-app-prod", content)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Message != "listening on :8080" || entries[0].Source != "my-app-prod" || entries[0].Timestamp.Nanosecond() != 123456789 {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Message != "handled request" || entries[0].ID == entries[1].ID {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+}
@@ -0,0 +1,49 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing anything in the account. Every deployment pushes the image and
+// updates the app to run it.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "digitalocean"}
+
+	registryName, err := p.getRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if registryName == "" {
+		registryName = m.DigitalOcean.RegistryName()
+		if registryName == "" {
+			registryName = m.Application.Name
+		}
+		plan.Add(types.PlanCreate, "Container registry", registryName, "the account has no registry ("+registryTier+" tier)")
+	} else {
+		plan.Add(types.PlanNoChange, "Container registry", registryName, "")
+	}
+	plan.Add(types.PlanCreate, "Container image", fmt.Sprintf("%s/%s/%s", registry.DOCRHost, registryName, m.Application.Name), "push "+m.Image)
+
+	name := m.Environment.Name
+	a, err := p.findApp(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	size, count := manifest.DigitalOceanInstanceSize(m.Instance)
+	reason := fmt.Sprintf("%d x %s, running %s", count, size, m.Image)
+	switch {
+	case a == nil:
+		plan.Add(types.PlanCreate, "App Platform app", name, reason)
+	case archived(a):
+		plan.Add(types.PlanUpdate, "App Platform app", name, "restore and run "+m.Image)
+	default:
+		plan.Add(types.PlanUpdate, "App Platform app", name, reason)
+	}
+	return plan, nil
+}
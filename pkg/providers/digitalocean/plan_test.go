@@ -0,0 +1,59 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func changeActions(plan *types.Plan) map[string]types.PlanAction {
+	actions := make(map[string]types.PlanAction)
+	for _, c := range plan.Changes {
+		actions[c.Type+" "+c.Name] = c.Action
+	}
+	return actions
+}
+
+func TestPlanNewApp(t *testing.T) {
+	_, p := newFakeAPI(t)
+
+	plan, err := p.Plan(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	actions := changeActions(plan)
+	for key, want := range map[string]types.PlanAction{
+		"Container registry my-app":                               types.PlanCreate,
+		"Container image registry.digitalocean.com/my-app/my-app": types.PlanCreate,
+		"App Platform app my-app-prod":                            types.PlanCreate,
+	} {
+		if actions[key] != want {
+			t.Errorf("%s: got %q, want %q", key, actions[key], want)
+		}
+	}
+	if plan.Provider != "digitalocean" {
+		t.Errorf("Provider = %q, want digitalocean", plan.Provider)
+	}
+}
+
+func TestPlanExistingApp(t *testing.T) {
+	fake, p := newFakeAPI(t)
+	fake.registry = "acme"
+	fake.addApp("my-app-prod", map[string]interface{}{"maintenance": map[string]interface{}{"archive": true}})
+
+	plan, err := p.Plan(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if n := plan.Count(types.PlanCreate); n != 1 {
+		t.Errorf("got %d creates, want only the image: %+v", n, plan.Changes)
+	}
+	actions := changeActions(plan)
+	if actions["Container registry acme"] != types.PlanNoChange || actions["App Platform app my-app-prod"] != types.PlanUpdate {
+		t.Errorf("Unexpected plan: %+v", plan.Changes)
+	}
+	if reason := plan.Changes[2].Reason; reason != "restore and run localhost:1/my-app:v2" {
+		t.Errorf("Reason = %q, want the archived app restored", reason)
+	}
+}
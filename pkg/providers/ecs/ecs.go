@@ -0,0 +1,941 @@
+// Package ecs provides an AWS ECS provider that runs the manifest's containers
+// as a Fargate service behind an Application Load Balancer. Images are pushed
+// to ECR, every deployment registers a new revision of the environment's task
+// definition, and container output is sent to CloudWatch Logs.
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	awsprovider "github.com/jvreagan/cloud-deploy/pkg/providers/aws"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// API versions and JSON target prefixes of the services the provider calls.
+const (
+	ecsTargetPrefix  = "AmazonEC2ContainerServiceV20141113."
+	logsTargetPrefix = "Logs_20140328."
+	ec2APIVersion    = "2016-11-15"
+	elbAPIVersion    = "2015-12-01"
+	iamAPIVersion    = "2010-05-08"
+)
+
+// iamEndpoint is the global IAM endpoint; IAM requests are signed for us-east-1.
+const iamEndpoint = "https://iam.amazonaws.com"
+
+// The role tasks pull images and write logs with when ecs.execution_role_arn
+// is not set, named as the ECS console names it.
+const (
+	executionRoleName   = "ecsTaskExecutionRole"
+	executionRolePolicy = "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"
+)
+
+// pollInterval is how often a rollout or deletion is checked while waiting for it.
+var pollInterval = 5 * time.Second
+
+// rolloutTimeout is how long Deploy and Rollback wait for the service's tasks
+// to become healthy, and Destroy for them to stop.
+var rolloutTimeout = 15 * time.Minute
+
+// cleanupTimeout is how long Destroy retries deleting the target group and
+// security groups while the load balancer and tasks release them.
+var cleanupTimeout = 5 * time.Minute
+
+// Provider implements the provider.Provider interface for AWS ECS on Fargate.
+type Provider struct {
+	region     string
+	config     aws.Config
+	ecsClient  *awsapi.Client
+	ec2Client  *awsapi.Client
+	elbClient  *awsapi.Client
+	logsClient *awsapi.Client
+	iamClient  *awsapi.Client
+}
+
+// New creates a new AWS ECS provider instance. Credentials are loaded as they
+// are for the aws provider.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	cfg, err := awsprovider.LoadConfig(ctx, config.Region, config.Credentials, m)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(cfg), nil
+}
+
+// newProvider creates the service clients for cfg.
+func newProvider(cfg aws.Config) *Provider {
+	iamConfig := cfg.Copy()
+	iamConfig.Region = "us-east-1"
+	iamClient := awsapi.New(iamConfig, "iam")
+	if cfg.BaseEndpoint == nil {
+		// Emulators serve IAM at the same endpoint as every other service
+		iamClient.SetEndpoint(iamEndpoint)
+	}
+
+	return &Provider{
+		region:     cfg.Region,
+		config:     cfg,
+		ecsClient:  awsapi.New(cfg, "ecs"),
+		ec2Client:  awsapi.New(cfg, "ec2"),
+		elbClient:  awsapi.New(cfg, "elasticloadbalancing"),
+		logsClient: awsapi.New(cfg, "logs"),
+		iamClient:  iamClient,
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "aws-ecs"
+}
+
+// Close closes the idle connections of the provider's clients.
+func (p *Provider) Close() error {
+	for _, c := range []*awsapi.Client{p.ecsClient, p.ec2Client, p.elbClient, p.logsClient, p.iamClient} {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// service is an ECS service, as returned by DescribeServices.
+type service struct {
+	ServiceName    string              `json:"serviceName"`
+	Status         string              `json:"status"`
+	DesiredCount   int                 `json:"desiredCount"`
+	RunningCount   int                 `json:"runningCount"`
+	TaskDefinition string              `json:"taskDefinition"`
+	Deployments    []serviceDeployment `json:"deployments"`
+	CreatedAt      float64             `json:"createdAt"`
+}
+
+// serviceDeployment is a deployment of an ECS service. The PRIMARY deployment
+// runs the service's current task definition.
+type serviceDeployment struct {
+	Status             string  `json:"status"`
+	TaskDefinition     string  `json:"taskDefinition"`
+	DesiredCount       int     `json:"desiredCount"`
+	RunningCount       int     `json:"runningCount"`
+	RolloutState       string  `json:"rolloutState"`
+	RolloutStateReason string  `json:"rolloutStateReason"`
+	UpdatedAt          float64 `json:"updatedAt"`
+}
+
+// primary returns the service's PRIMARY deployment, or nil.
+func (s *service) primary() *serviceDeployment {
+	for i := range s.Deployments {
+		if s.Deployments[i].Status == "PRIMARY" {
+			return &s.Deployments[i]
+		}
+	}
+	return nil
+}
+
+// Deploy pushes the manifest's images to ECR and runs them as a Fargate
+// service behind an Application Load Balancer, creating the cluster, log
+// group, execution role, security groups, and load balancer the first time.
+//
+// Each deployment registers a new revision of the task definition, with the
+// images pinned by digest so earlier revisions keep running the images they
+// were deployed with; Rollback returns to them.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := validateImagePlatforms(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Starting AWS ECS deployment")
+
+	// Step 1: Push images to ECR
+	images, err := p.pushImages(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return p.deployImages(ctx, m, images)
+}
+
+// deployImages runs images, the ECR image of each container by name, as the
+// environment's service.
+func (p *Provider) deployImages(ctx context.Context, m *manifest.Manifest, images map[string]string) (*types.DeploymentResult, error) {
+	cluster := clusterName(m)
+	name := m.Environment.Name
+
+	// Step 2: Create the log group and execution role the tasks need
+	if err := p.ensureLogGroup(ctx, m); err != nil {
+		return nil, err
+	}
+	executionRole, err := p.ensureExecutionRole(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Create the security groups and load balancer
+	network, err := p.ensureNetwork(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	lb, targetGroup, err := p.ensureLoadBalancer(ctx, m, network)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: Create the cluster and register the task definition
+	logging.Infof("Ensuring cluster: %s", cluster)
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"CreateCluster", map[string]interface{}{"clusterName": cluster, "tags": ecsTags(m)}, nil); err != nil {
+		return nil, fmt.Errorf("failed to create cluster: %w", err)
+	}
+	taskDefinition, err := p.registerTaskDefinition(ctx, m, images, executionRole)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Create or update the service
+	svc, err := p.describeService(ctx, cluster, name)
+	if err != nil {
+		return nil, err
+	}
+	input := map[string]interface{}{
+		"cluster":              cluster,
+		"taskDefinition":       taskDefinition,
+		"desiredCount":         desiredCount(m.ECS),
+		"networkConfiguration": networkConfiguration(m.ECS, network),
+	}
+	if svc == nil {
+		logging.Infof("Creating service: %s", name)
+		primary := m.GetPrimaryContainer()
+		input["serviceName"] = name
+		input["launchType"] = "FARGATE"
+		input["loadBalancers"] = []map[string]interface{}{{
+			"targetGroupArn": targetGroup,
+			"containerName":  primary.Name,
+			"containerPort":  containerPort(primary),
+		}}
+		input["healthCheckGracePeriodSeconds"] = 60
+		input["deploymentConfiguration"] = map[string]interface{}{
+			"deploymentCircuitBreaker": map[string]bool{"enable": true, "rollback": true},
+		}
+		input["propagateTags"] = "SERVICE"
+		if tags := ecsTags(m); len(tags) > 0 {
+			input["tags"] = tags
+		}
+		if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"CreateService", input, nil); err != nil {
+			return nil, fmt.Errorf("failed to create service: %w", err)
+		}
+	} else {
+		logging.Infof("Updating service: %s", name)
+		input["service"] = name
+		if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"UpdateService", input, nil); err != nil {
+			return nil, fmt.Errorf("failed to update service: %w", err)
+		}
+	}
+
+	// Step 6: Wait for the new tasks to become healthy
+	logging.Info("Waiting for rollout to finish...")
+	if err := p.waitForRollout(ctx, cluster, name); err != nil {
+		return nil, err
+	}
+
+	message := "Deployment successful"
+	if m.IsMultiContainer() {
+		message = fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers))
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             "http://" + lb.DNSName,
+		Status:          "Running",
+		Message:         message,
+		Images:          images,
+	}, nil
+}
+
+// Destroy deletes the service, its load balancer, target group, security
+// groups, log group, and task definitions. The cluster is deleted when no
+// other services run in it.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	cluster := clusterName(m)
+	name := m.Environment.Name
+
+	svc, err := p.describeService(ctx, cluster, name)
+	if err != nil {
+		return err
+	}
+	if svc != nil {
+		logging.Infof("Deleting service: %s", name)
+		if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"DeleteService", map[string]interface{}{"cluster": cluster, "service": name, "force": true}, nil); err != nil {
+			return fmt.Errorf("failed to delete service: %w", err)
+		}
+		// The security groups cannot be deleted while tasks still use them
+		logging.Info("Waiting for the service's tasks to stop...")
+		if err := p.waitForServiceDeleted(ctx, cluster, name); err != nil {
+			return err
+		}
+	}
+
+	if err := p.deleteLoadBalancer(ctx, m); err != nil {
+		return err
+	}
+	p.deleteSecurityGroups(ctx, m)
+
+	if err := p.deregisterTaskDefinitions(ctx, name); err != nil {
+		return err
+	}
+	if err := p.deleteLogGroup(ctx, m); err != nil {
+		return err
+	}
+	p.deleteClusterIfEmpty(ctx, cluster)
+
+	logging.Info("ECS resources deleted successfully")
+	return nil
+}
+
+// Stop scales the service to zero tasks. The service, load balancer, and task
+// definition revisions are preserved; running Deploy again scales it back up.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	cluster := clusterName(m)
+	name := m.Environment.Name
+
+	svc, err := p.describeService(ctx, cluster, name)
+	if err != nil {
+		return err
+	}
+	if svc == nil {
+		return fmt.Errorf("no ECS service found for environment %s in cluster %s", name, cluster)
+	}
+
+	logging.Infof("Scaling service %s to 0 tasks", name)
+	input := map[string]interface{}{"cluster": cluster, "service": name, "desiredCount": 0}
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"UpdateService", input, nil); err != nil {
+		return fmt.Errorf("failed to scale service: %w", err)
+	}
+	logging.Info("Service stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the service.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	cluster := clusterName(m)
+	svc, err := p.describeService(ctx, cluster, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("no ECS service found for environment %s in cluster %s", m.Environment.Name, cluster)
+	}
+
+	lb, err := p.findLoadBalancer(ctx, loadBalancerName(m))
+	if err != nil {
+		return nil, err
+	}
+	url := ""
+	if lb != nil {
+		url = "http://" + lb.DNSName
+	}
+
+	status, health := serviceStatus(svc)
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             url,
+		LastUpdated:     lastUpdated(svc),
+	}, nil
+}
+
+// serviceStatus returns the status and health of a service.
+func serviceStatus(svc *service) (string, string) {
+	primary := svc.primary()
+	switch {
+	case svc.DesiredCount == 0:
+		return "Stopped", "Grey"
+	case primary != nil && primary.RolloutState == "FAILED":
+		return "Failed", "Red"
+	case !rolloutComplete(svc):
+		if svc.RunningCount == 0 {
+			return "Updating", "Red"
+		}
+		return "Updating", "Yellow"
+	default:
+		return "Running", "Green"
+	}
+}
+
+// lastUpdated returns when the service's current deployment last changed.
+func lastUpdated(svc *service) string {
+	last := svc.CreatedAt
+	if primary := svc.primary(); primary != nil && primary.UpdatedAt > last {
+		last = primary.UpdatedAt
+	}
+	return time.Unix(int64(last), 0).UTC().Format(time.RFC3339)
+}
+
+// Rollback returns the service to the task definition revision before the
+// one it runs, whose images are pinned by digest, and waits for the rollout.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting AWS ECS rollback...")
+	cluster := clusterName(m)
+	name := m.Environment.Name
+
+	svc, err := p.describeService(ctx, cluster, name)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("no ECS service found for environment %s in cluster %s", name, cluster)
+	}
+	revisions, err := p.taskDefinitions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := previousTaskDefinition(revisions, svc.TaskDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("Rolling back %s to task definition %s", name, taskDefinitionName(previous))
+	input := map[string]interface{}{"cluster": cluster, "service": name, "taskDefinition": previous}
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"UpdateService", input, nil); err != nil {
+		return nil, fmt.Errorf("failed to roll back service: %w", err)
+	}
+
+	logging.Info("Waiting for rollout to finish...")
+	if err := p.waitForRollout(ctx, cluster, name); err != nil {
+		return nil, err
+	}
+
+	url := ""
+	lb, err := p.findLoadBalancer(ctx, loadBalancerName(m))
+	if err != nil {
+		return nil, err
+	}
+	if lb != nil {
+		url = "http://" + lb.DNSName
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             url,
+		Status:          "Running",
+		Message:         fmt.Sprintf("Rolled back to task definition %s", taskDefinitionName(previous)),
+	}, nil
+}
+
+// describeService returns the named service, or nil when it does not exist
+// or has been deleted.
+func (p *Provider) describeService(ctx context.Context, cluster, name string) (*service, error) {
+	var out struct {
+		Services []service `json:"services"`
+	}
+	input := map[string]interface{}{"cluster": cluster, "services": []string{name}}
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"DescribeServices", input, &out); err != nil {
+		if awsapi.IsErrorCode(err, "ClusterNotFoundException") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to describe service %s: %w", name, err)
+	}
+	for i := range out.Services {
+		if out.Services[i].Status != "INACTIVE" {
+			return &out.Services[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// rolloutComplete reports whether the service's current deployment finished
+// and runs all of its tasks.
+func rolloutComplete(svc *service) bool {
+	primary := svc.primary()
+	if primary == nil || len(svc.Deployments) > 1 {
+		return false
+	}
+	if primary.RolloutState != "" && primary.RolloutState != "COMPLETED" {
+		return false
+	}
+	return primary.RunningCount == primary.DesiredCount && svc.RunningCount == svc.DesiredCount
+}
+
+// waitForRollout polls the service until its current deployment finishes,
+// or fails when ECS's deployment circuit breaker stops it.
+func (p *Provider) waitForRollout(ctx context.Context, cluster, name string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(rolloutTimeout)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for service %s to roll out", name)
+		case <-ticker.C:
+			svc, err := p.describeService(ctx, cluster, name)
+			if err != nil {
+				return err
+			}
+			if svc == nil {
+				return fmt.Errorf("service %s no longer exists", name)
+			}
+			if primary := svc.primary(); primary != nil && primary.RolloutState == "FAILED" {
+				return fmt.Errorf("service %s failed to roll out: %s", name, primary.RolloutStateReason)
+			}
+			logging.Infof("Rollout status: %d of %d tasks running", svc.RunningCount, svc.DesiredCount)
+			if rolloutComplete(svc) {
+				return nil
+			}
+		}
+	}
+}
+
+// waitForServiceDeleted polls a deleted service until its tasks have stopped.
+func (p *Provider) waitForServiceDeleted(ctx context.Context, cluster, name string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(rolloutTimeout)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for the tasks of service %s to stop", name)
+		case <-ticker.C:
+			svc, err := p.describeService(ctx, cluster, name)
+			if err != nil {
+				return err
+			}
+			if svc == nil {
+				return nil
+			}
+		}
+	}
+}
+
+// pushImages pushes the image of each container to the application's ECR
+// repository, returning the pushed images pinned by digest by container name.
+func (p *Provider) pushImages(ctx context.Context, m *manifest.Manifest) (map[string]string, error) {
+	images := make(map[string]string)
+	for _, c := range deployContainers(m) {
+		// Containers of a multi-container deployment are tagged with their names, as on Elastic Beanstalk
+		tag := m.ImageTag("latest", "")
+		if m.IsMultiContainer() {
+			tag = m.ImageTag(c.Name, c.Name)
+		}
+		logging.Info("Pushing container image to ECR", "container", c.Name, "image", c.Image)
+
+		ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.RepositoryName(), tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ECR registry for container %s: %w", c.Name, err)
+		}
+		distributor := registry.NewDistributor(c.Image, m.Platforms...)
+		dest := registry.Local(ecrRegistry, m.Provider.RegistryURL, m.RepositoryName(), tag)
+		distributor.AddRegistry(dest)
+
+		imageURIs, err := distributor.Distribute(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to distribute image for container %s: %w", c.Name, err)
+		}
+		images[c.Name] = registry.PinDigest(imageURIs[dest.GetRegistryURL()], distributor.Digest())
+		logging.Info("Image pushed to ECR", "container", c.Name, "image_uri", images[c.Name])
+	}
+	return images, nil
+}
+
+// ensureExecutionRole returns the ARN of the role tasks are started with,
+// creating ecsTaskExecutionRole when no role is configured and it does not
+// exist yet.
+func (p *Provider) ensureExecutionRole(ctx context.Context, m *manifest.Manifest) (string, error) {
+	if m.ECS != nil && m.ECS.ExecutionRoleARN != "" {
+		return m.ECS.ExecutionRoleARN, nil
+	}
+
+	var role struct {
+		ARN string `xml:"Role>Arn"`
+	}
+	err := p.iamClient.Query(ctx, "GetRole", iamAPIVersion, url.Values{"RoleName": {executionRoleName}}, &role)
+	if err == nil {
+		return role.ARN, nil
+	}
+	if !awsapi.IsErrorCode(err, "NoSuchEntity") {
+		return "", fmt.Errorf("failed to get role %s: %w", executionRoleName, err)
+	}
+
+	logging.Infof("Creating task execution role: %s", executionRoleName)
+	trust := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+	params := url.Values{"RoleName": {executionRoleName}, "AssumeRolePolicyDocument": {trust}}
+	if err := p.iamClient.Query(ctx, "CreateRole", iamAPIVersion, params, &role); err != nil {
+		return "", fmt.Errorf("failed to create role %s: %w", executionRoleName, err)
+	}
+	params = url.Values{"RoleName": {executionRoleName}, "PolicyArn": {executionRolePolicy}}
+	if err := p.iamClient.Query(ctx, "AttachRolePolicy", iamAPIVersion, params, nil); err != nil {
+		return "", fmt.Errorf("failed to attach policy to role %s: %w", executionRoleName, err)
+	}
+	return role.ARN, nil
+}
+
+// registerTaskDefinition registers a new revision of the environment's task
+// definition and returns its ARN.
+func (p *Provider) registerTaskDefinition(ctx context.Context, m *manifest.Manifest, images map[string]string, executionRole string) (string, error) {
+	var out struct {
+		TaskDefinition struct {
+			ARN      string `json:"taskDefinitionArn"`
+			Revision int    `json:"revision"`
+		} `json:"taskDefinition"`
+	}
+	input := taskDefinition(m, images, executionRole, p.region)
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"RegisterTaskDefinition", input, &out); err != nil {
+		return "", fmt.Errorf("failed to register task definition: %w", err)
+	}
+	logging.Infof("Registered task definition %s:%d", m.Environment.Name, out.TaskDefinition.Revision)
+	return out.TaskDefinition.ARN, nil
+}
+
+// taskDefinitions returns the ARNs of the active revisions of a task
+// definition family, newest first.
+func (p *Provider) taskDefinitions(ctx context.Context, family string) ([]string, error) {
+	var arns []string
+	input := map[string]interface{}{"familyPrefix": family, "status": "ACTIVE", "sort": "DESC"}
+	for {
+		var out struct {
+			TaskDefinitionARNs []string `json:"taskDefinitionArns"`
+			NextToken          string   `json:"nextToken"`
+		}
+		if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"ListTaskDefinitions", input, &out); err != nil {
+			return nil, fmt.Errorf("failed to list task definitions: %w", err)
+		}
+		// familyPrefix also matches longer family names
+		for _, arn := range out.TaskDefinitionARNs {
+			if f, _ := taskDefinitionRevision(arn); f == family {
+				arns = append(arns, arn)
+			}
+		}
+		if out.NextToken == "" {
+			return arns, nil
+		}
+		input["nextToken"] = out.NextToken
+	}
+}
+
+// deregisterTaskDefinitions deregisters every active revision of a task
+// definition family.
+func (p *Provider) deregisterTaskDefinitions(ctx context.Context, family string) error {
+	arns, err := p.taskDefinitions(ctx, family)
+	if err != nil {
+		return err
+	}
+	for _, arn := range arns {
+		if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"DeregisterTaskDefinition", map[string]string{"taskDefinition": arn}, nil); err != nil {
+			return fmt.Errorf("failed to deregister task definition %s: %w", taskDefinitionName(arn), err)
+		}
+	}
+	if len(arns) > 0 {
+		logging.Infof("Deregistered %d task definition revisions of %s", len(arns), family)
+	}
+	return nil
+}
+
+// deleteClusterIfEmpty deletes the cluster when no services are left in it.
+// Clusters are shared by environments, so failures are only logged.
+func (p *Provider) deleteClusterIfEmpty(ctx context.Context, cluster string) {
+	var out struct {
+		Clusters []struct {
+			Status              string `json:"status"`
+			ActiveServicesCount int    `json:"activeServicesCount"`
+		} `json:"clusters"`
+	}
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"DescribeClusters", map[string]interface{}{"clusters": []string{cluster}}, &out); err != nil {
+		logging.Warnf("Could not describe cluster %s: %v", cluster, err)
+		return
+	}
+	if len(out.Clusters) == 0 || out.Clusters[0].Status != "ACTIVE" {
+		return
+	}
+	if n := out.Clusters[0].ActiveServicesCount; n > 0 {
+		logging.Infof("Keeping cluster %s, which runs %d other services", cluster, n)
+		return
+	}
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"DeleteCluster", map[string]string{"cluster": cluster}, nil); err != nil {
+		logging.Warnf("Could not delete cluster %s: %v", cluster, err)
+		return
+	}
+	logging.Infof("Deleted cluster: %s", cluster)
+}
+
+// taskDefinition returns the RegisterTaskDefinition request of a Fargate task
+// running the manifest's containers with images, logging to the environment's
+// log group.
+func taskDefinition(m *manifest.Manifest, images map[string]string, executionRole, region string) map[string]interface{} {
+	cpu, memory := m.ECS.TaskSize()
+	def := map[string]interface{}{
+		"family":                  m.Environment.Name,
+		"networkMode":             "awsvpc",
+		"requiresCompatibilities": []string{"FARGATE"},
+		"cpu":                     strconv.Itoa(cpu),
+		"memory":                  strconv.Itoa(memory),
+		"executionRoleArn":        executionRole,
+		"runtimePlatform": map[string]string{
+			"cpuArchitecture":       architecture(m.ECS),
+			"operatingSystemFamily": "LINUX",
+		},
+	}
+	if m.ECS != nil && m.ECS.TaskRoleARN != "" {
+		def["taskRoleArn"] = m.ECS.TaskRoleARN
+	}
+	if tags := ecsTags(m); len(tags) > 0 {
+		def["tags"] = tags
+	}
+
+	var containers []map[string]interface{}
+	for i, c := range deployContainers(m) {
+		containers = append(containers, containerDefinition(m, c, images[c.Name], i == 0, region))
+	}
+	def["containerDefinitions"] = containers
+	return def
+}
+
+// containerDefinition returns the ECS container definition of a container.
+// The primary container exposes the port the load balancer forwards to.
+func containerDefinition(m *manifest.Manifest, c manifest.Container, image string, primary bool, region string) map[string]interface{} {
+	if image == "" {
+		image = c.Image
+	}
+	def := map[string]interface{}{
+		"name":      c.Name,
+		"image":     image,
+		"essential": true,
+		"logConfiguration": map[string]interface{}{
+			"logDriver": "awslogs",
+			"options": map[string]string{
+				"awslogs-group":         logGroupName(m),
+				"awslogs-region":        region,
+				"awslogs-stream-prefix": "ecs",
+			},
+		},
+	}
+
+	env := make(map[string]string)
+	for k, v := range m.EnvironmentVariables {
+		env[k] = v
+	}
+	for k, v := range c.Environment {
+		env[k] = v
+	}
+	if len(env) > 0 {
+		names := make([]string, 0, len(env))
+		for k := range env {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		vars := make([]map[string]string, 0, len(names))
+		for _, k := range names {
+			vars = append(vars, map[string]string{"name": k, "value": env[k]})
+		}
+		def["environment"] = vars
+	}
+
+	var ports []map[string]interface{}
+	for _, port := range c.Ports {
+		if port.ContainerPort > 0 {
+			ports = append(ports, map[string]interface{}{"containerPort": port.ContainerPort, "protocol": "tcp"})
+		}
+	}
+	if primary && len(ports) == 0 {
+		ports = append(ports, map[string]interface{}{"containerPort": containerPort(c), "protocol": "tcp"})
+	}
+	if len(ports) > 0 {
+		def["portMappings"] = ports
+	}
+
+	// ECS names the image's ENTRYPOINT entryPoint and its CMD command
+	if len(c.Command) > 0 {
+		def["entryPoint"] = c.Command
+	}
+	if len(c.Args) > 0 {
+		def["command"] = c.Args
+	}
+	if c.Workdir != "" {
+		def["workingDirectory"] = c.Workdir
+	}
+
+	if sec := m.Security; sec != nil {
+		if sec.User != "" {
+			def["user"] = sec.User
+		}
+		if sec.ReadOnlyRootFilesystem {
+			def["readonlyRootFilesystem"] = true
+		}
+		if len(sec.DropCapabilities) > 0 {
+			def["linuxParameters"] = map[string]interface{}{
+				"capabilities": map[string]interface{}{"drop": sec.DropCapabilities},
+			}
+		}
+	}
+	return def
+}
+
+// networkConfiguration returns the awsvpc network configuration of the
+// service's tasks.
+func networkConfiguration(e *manifest.ECSConfig, n *network) map[string]interface{} {
+	assignPublicIP := "ENABLED"
+	if e != nil && e.AssignPublicIP != nil && !*e.AssignPublicIP {
+		assignPublicIP = "DISABLED"
+	}
+	return map[string]interface{}{
+		"awsvpcConfiguration": map[string]interface{}{
+			"subnets":        n.subnets,
+			"securityGroups": n.taskSecurityGroups,
+			"assignPublicIp": assignPublicIP,
+		},
+	}
+}
+
+// previousTaskDefinition returns the revision of arns, the active revisions
+// of a task definition, with the highest revision number below current's.
+func previousTaskDefinition(arns []string, current string) (string, error) {
+	_, currentRevision := taskDefinitionRevision(current)
+	previous, previousRevision := "", 0
+	for _, arn := range arns {
+		if _, revision := taskDefinitionRevision(arn); revision < currentRevision && revision > previousRevision {
+			previous, previousRevision = arn, revision
+		}
+	}
+	if previous == "" {
+		return "", fmt.Errorf("no previous task definition revision to roll back to (running %s)", taskDefinitionName(current))
+	}
+	return previous, nil
+}
+
+// taskDefinitionRevision splits a task definition ARN
+// (arn:aws:ecs:<region>:<account>:task-definition/<family>:<revision>) into
+// its family and revision.
+func taskDefinitionRevision(arn string) (string, int) {
+	name := taskDefinitionName(arn)
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return name, 0
+	}
+	revision, _ := strconv.Atoi(name[i+1:])
+	return name[:i], revision
+}
+
+// taskDefinitionName returns the family:revision part of a task definition ARN.
+func taskDefinitionName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// clusterName returns the cluster the service runs in.
+func clusterName(m *manifest.Manifest) string {
+	if m.ECS != nil && m.ECS.Cluster != "" {
+		return m.ECS.Cluster
+	}
+	return m.Application.Name
+}
+
+// desiredCount returns the number of tasks to run.
+func desiredCount(e *manifest.ECSConfig) int {
+	if e != nil && e.DesiredCount != nil {
+		return *e.DesiredCount
+	}
+	return 1
+}
+
+// architecture returns the CPU architecture of the tasks.
+func architecture(e *manifest.ECSConfig) string {
+	if e != nil && e.Architecture != "" {
+		return e.Architecture
+	}
+	return manifest.ECSArchitectureX86
+}
+
+// ecsTags converts the manifest's tags to ECS tags, sorted by key.
+func ecsTags(m *manifest.Manifest) []map[string]string {
+	keys := make([]string, 0, len(m.Tags))
+	for k := range m.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, map[string]string{"key": k, "value": m.Tags[k]})
+	}
+	return tags
+}
+
+// deployContainers returns the containers to run: the manifest's containers,
+// or the single container described by image.
+func deployContainers(m *manifest.Manifest) []manifest.Container {
+	if m.IsMultiContainer() {
+		return m.Containers
+	}
+	return []manifest.Container{m.GetPrimaryContainer()}
+}
+
+// containerPort returns the first port a container exposes, defaulting to 80.
+func containerPort(c manifest.Container) int {
+	if len(c.Ports) > 0 && c.Ports[0].ContainerPort > 0 {
+		return c.Ports[0].ContainerPort
+	}
+	return 80
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+// loadBalancerName returns the name of the environment's load balancer and
+// target group: letters, digits, and hyphens, at most 32 characters.
+func loadBalancerName(m *manifest.Manifest) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(m.Environment.Name, "-"), "-")
+	if len(name) > 32 {
+		name = strings.TrimRight(name[:32], "-")
+	}
+	if name == "" {
+		return "cloud-deploy"
+	}
+	return name
+}
+
+// validateImagePlatforms fails early when an image cannot run on the tasks'
+// CPU architecture.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	target := registry.LinuxAMD64
+	hint := "or set ecs.architecture to ARM64 for arm64 images"
+	if architecture(m.ECS) == manifest.ECSArchitectureARM {
+		target = registry.Platform{OS: "linux", Architecture: "arm64"}
+		hint = "or set ecs.architecture to X86_64 for amd64 images"
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, target, "Fargate "+architecture(m.ECS), hint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSecurity fails if an image runs as root when run_as_non_root is set.
+// The other security options are set on the container definitions; a
+// configured user overrides the image's.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	if m.Security == nil || !m.Security.RunAsNonRoot || m.Security.User != "" {
+		return nil
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidateNonRoot(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,612 @@
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeAWS serves the subset of the ECS, EC2, Elastic Load Balancing, IAM, and
+// CloudWatch Logs APIs the provider uses. Services roll out as soon as they
+// are described.
+type fakeAWS struct {
+	mu              sync.Mutex
+	calls           []string
+	requests        map[string]interface{} // action -> last JSON body or form
+	service         map[string]interface{}
+	taskDefinitions []string
+	securityGroups  map[string]string // name -> ID
+	loadBalancer    bool
+	targetGroup     bool
+	listener        bool
+	logGroup        bool
+	role            bool
+	rolloutFailed   bool
+	events          []logEvent
+}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{
+		requests:       make(map[string]interface{}),
+		securityGroups: make(map[string]string),
+	}
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		action := target[strings.Index(target, ".")+1:]
+		var in map[string]interface{}
+		json.Unmarshal(body, &in)
+		f.calls = append(f.calls, action)
+		f.requests[action] = in
+		out, code := f.handleJSON(action, in)
+		if code != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": code})
+			return
+		}
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+
+	form, _ := url.ParseQuery(string(body))
+	action := form.Get("Action")
+	f.calls = append(f.calls, action)
+	f.requests[action] = form
+	out, code := f.handleQuery(action, form)
+	if code != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "<ErrorResponse><Error><Code>%s</Code></Error></ErrorResponse>", code)
+		return
+	}
+	// EC2 responses have no result element
+	if form.Get("Version") == ec2APIVersion {
+		fmt.Fprintf(w, "<%sResponse>%s</%sResponse>", action, out, action)
+		return
+	}
+	fmt.Fprintf(w, "<%sResponse><%sResult>%s</%sResult></%sResponse>", action, action, out, action, action)
+}
+
+func (f *fakeAWS) handleJSON(action string, in map[string]interface{}) (interface{}, string) {
+	switch action {
+	case "CreateCluster", "DeleteCluster", "DeregisterTaskDefinition", "PutRetentionPolicy":
+		return map[string]interface{}{}, ""
+	case "DescribeClusters":
+		active := 0
+		if f.service != nil {
+			active = 1
+		}
+		return map[string]interface{}{"clusters": []map[string]interface{}{{"status": "ACTIVE", "activeServicesCount": active}}}, ""
+	case "RegisterTaskDefinition":
+		revision := len(f.taskDefinitions) + 1
+		arn := fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:task-definition/%s:%d", in["family"], revision)
+		f.taskDefinitions = append(f.taskDefinitions, arn)
+		return map[string]interface{}{"taskDefinition": map[string]interface{}{"taskDefinitionArn": arn, "revision": revision}}, ""
+	case "ListTaskDefinitions":
+		arns := make([]string, 0, len(f.taskDefinitions))
+		for i := len(f.taskDefinitions) - 1; i >= 0; i-- {
+			arns = append(arns, f.taskDefinitions[i])
+		}
+		return map[string]interface{}{"taskDefinitionArns": arns}, ""
+	case "DescribeServices":
+		if f.service == nil {
+			return map[string]interface{}{"services": []interface{}{}, "failures": []map[string]string{{"reason": "MISSING"}}}, ""
+		}
+		svc := f.service
+		desired := svc["desiredCount"]
+		state := "COMPLETED"
+		if f.rolloutFailed {
+			state = "FAILED"
+		}
+		svc["runningCount"] = desired
+		svc["deployments"] = []map[string]interface{}{{
+			"status": "PRIMARY", "taskDefinition": svc["taskDefinition"], "desiredCount": desired, "runningCount": desired,
+			"rolloutState": state, "rolloutStateReason": "tasks failed to start", "updatedAt": 1700000000.5,
+		}}
+		return map[string]interface{}{"services": []interface{}{svc}}, ""
+	case "CreateService":
+		f.service = map[string]interface{}{
+			"serviceName": in["serviceName"], "status": "ACTIVE",
+			"taskDefinition": in["taskDefinition"], "desiredCount": in["desiredCount"], "createdAt": 1690000000.0,
+		}
+		return map[string]interface{}{"service": f.service}, ""
+	case "UpdateService":
+		if f.service == nil {
+			return nil, "ServiceNotFoundException"
+		}
+		for _, key := range []string{"taskDefinition", "desiredCount"} {
+			if v, ok := in[key]; ok {
+				f.service[key] = v
+			}
+		}
+		return map[string]interface{}{"service": f.service}, ""
+	case "DeleteService":
+		f.service = nil
+		return map[string]interface{}{}, ""
+	case "CreateLogGroup":
+		if f.logGroup {
+			return nil, "ResourceAlreadyExistsException"
+		}
+		f.logGroup = true
+		return map[string]interface{}{}, ""
+	case "DeleteLogGroup":
+		if !f.logGroup {
+			return nil, "ResourceNotFoundException"
+		}
+		f.logGroup = false
+		return map[string]interface{}{}, ""
+	case "FilterLogEvents":
+		if !f.logGroup {
+			return nil, "ResourceNotFoundException"
+		}
+		return map[string]interface{}{"events": f.events}, ""
+	}
+	return nil, "UnknownOperationException"
+}
+
+func (f *fakeAWS) handleQuery(action string, form url.Values) (string, string) {
+	switch action {
+	case "DescribeVpcs":
+		return "<vpcSet><item><vpcId>vpc-1</vpcId></item></vpcSet>", ""
+	case "DescribeSubnets":
+		return "<subnetSet><item><subnetId>subnet-a</subnetId><vpcId>vpc-1</vpcId></item><item><subnetId>subnet-b</subnetId><vpcId>vpc-1</vpcId></item></subnetSet>", ""
+	case "DescribeSecurityGroups":
+		if id, ok := f.securityGroups[form.Get("Filter.1.Value.1")]; ok {
+			return "<securityGroupInfo><item><groupId>" + id + "</groupId></item></securityGroupInfo>", ""
+		}
+		return "<securityGroupInfo/>", ""
+	case "CreateSecurityGroup":
+		id := "sg-" + form.Get("GroupName")
+		f.securityGroups[form.Get("GroupName")] = id
+		return "<groupId>" + id + "</groupId>", ""
+	case "AuthorizeSecurityGroupIngress":
+		return "", ""
+	case "DeleteSecurityGroup":
+		for name, id := range f.securityGroups {
+			if id == form.Get("GroupId") {
+				delete(f.securityGroups, name)
+			}
+		}
+		return "", ""
+	case "DescribeLoadBalancers":
+		if !f.loadBalancer {
+			return "", "LoadBalancerNotFound"
+		}
+		return "<LoadBalancers><member><LoadBalancerArn>arn:lb</LoadBalancerArn><DNSName>my-env-1.us-east-1.elb.amazonaws.com</DNSName></member></LoadBalancers>", ""
+	case "CreateLoadBalancer":
+		f.loadBalancer = true
+		return "<LoadBalancers><member><LoadBalancerArn>arn:lb</LoadBalancerArn><DNSName>my-env-1.us-east-1.elb.amazonaws.com</DNSName></member></LoadBalancers>", ""
+	case "DeleteLoadBalancer":
+		f.loadBalancer, f.listener = false, false
+		return "", ""
+	case "DescribeTargetGroups":
+		if !f.targetGroup {
+			return "", "TargetGroupNotFound"
+		}
+		return "<TargetGroups><member><TargetGroupArn>arn:tg</TargetGroupArn></member></TargetGroups>", ""
+	case "CreateTargetGroup":
+		f.targetGroup = true
+		return "<TargetGroups><member><TargetGroupArn>arn:tg</TargetGroupArn></member></TargetGroups>", ""
+	case "ModifyTargetGroup":
+		return "", ""
+	case "DeleteTargetGroup":
+		f.targetGroup = false
+		return "", ""
+	case "DescribeListeners":
+		if !f.listener {
+			return "<Listeners/>", ""
+		}
+		return "<Listeners><member><Port>80</Port></member></Listeners>", ""
+	case "CreateListener":
+		f.listener = true
+		return "", ""
+	case "GetRole":
+		if !f.role {
+			return "", "NoSuchEntity"
+		}
+		return "<Role><Arn>arn:aws:iam::123456789012:role/ecsTaskExecutionRole</Arn></Role>", ""
+	case "CreateRole":
+		f.role = true
+		return "<Role><Arn>arn:aws:iam::123456789012:role/ecsTaskExecutionRole</Arn></Role>", ""
+	case "AttachRolePolicy":
+		return "", ""
+	}
+	return "", "InvalidAction"
+}
+
+// called reports whether action was called.
+func (f *fakeAWS) called(action string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == action {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestProvider returns a provider sending every request to fake.
+func newTestProvider(t *testing.T, fake *fakeAWS) *Provider {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	oldPoll := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldPoll })
+
+	p := newProvider(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	})
+	for _, c := range []interface{ SetEndpoint(string) }{p.ecsClient, p.ec2Client, p.elbClient, p.logsClient, p.iamClient} {
+		c.SetEndpoint(server.URL)
+	}
+	return p
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:                "my-app:latest",
+		Provider:             manifest.ProviderConfig{Name: "aws-ecs", Region: "us-east-1"},
+		Application:          manifest.ApplicationConfig{Name: "my-app"},
+		Environment:          manifest.EnvironmentConfig{Name: "my-env"},
+		Ports:                []manifest.PortMapping{{ContainerPort: 8080}},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info"},
+		HealthCheck:          manifest.HealthCheckConfig{Path: "/health"},
+		ECS:                  &manifest.ECSConfig{CPU: 512, LogRetentionDays: 14},
+	}
+}
+
+const testImage = "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app@sha256:abc"
+
+func TestDeployImages(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	result, err := p.deployImages(context.Background(), m, map[string]string{"my-app": testImage})
+	if err != nil {
+		t.Fatalf("deployImages() failed: %v", err)
+	}
+	if result.URL != "http://my-env-1.us-east-1.elb.amazonaws.com" || result.Images["my-app"] != testImage {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	for _, action := range []string{"CreateLogGroup", "PutRetentionPolicy", "CreateRole", "AttachRolePolicy", "CreateLoadBalancer", "CreateTargetGroup", "CreateListener", "CreateCluster", "CreateService"} {
+		if !fake.called(action) {
+			t.Errorf("expected %s to be called; calls: %v", action, fake.calls)
+		}
+	}
+
+	create := fake.requests["CreateService"].(map[string]interface{})
+	if create["taskDefinition"] != "arn:aws:ecs:us-east-1:123456789012:task-definition/my-env:1" || create["launchType"] != "FARGATE" {
+		t.Errorf("unexpected CreateService request: %v", create)
+	}
+	lbs := create["loadBalancers"].([]interface{})[0].(map[string]interface{})
+	if lbs["targetGroupArn"] != "arn:tg" || lbs["containerName"] != "my-app" || lbs["containerPort"] != 8080.0 {
+		t.Errorf("unexpected load balancer of service: %v", lbs)
+	}
+	awsvpc := create["networkConfiguration"].(map[string]interface{})["awsvpcConfiguration"].(map[string]interface{})
+	if fmt.Sprint(awsvpc["subnets"]) != "[subnet-a subnet-b]" || fmt.Sprint(awsvpc["securityGroups"]) != "[sg-my-env-tasks]" || awsvpc["assignPublicIp"] != "ENABLED" {
+		t.Errorf("unexpected network configuration: %v", awsvpc)
+	}
+
+	tg := fake.requests["CreateTargetGroup"].(url.Values)
+	if tg.Get("Port") != "8080" || tg.Get("TargetType") != "ip" || tg.Get("HealthCheckPath") != "/health" || tg.Get("VpcId") != "vpc-1" {
+		t.Errorf("unexpected CreateTargetGroup request: %v", tg)
+	}
+
+	// A second deployment registers a new revision and updates the service
+	fake.calls = nil
+	if _, err := p.deployImages(context.Background(), m, map[string]string{"my-app": testImage}); err != nil {
+		t.Fatalf("second deployImages() failed: %v", err)
+	}
+	for _, action := range []string{"CreateRole", "CreateLoadBalancer", "CreateTargetGroup", "CreateListener", "CreateService"} {
+		if fake.called(action) {
+			t.Errorf("expected %s not to be called again", action)
+		}
+	}
+	update := fake.requests["UpdateService"].(map[string]interface{})
+	if update["taskDefinition"] != "arn:aws:ecs:us-east-1:123456789012:task-definition/my-env:2" {
+		t.Errorf("unexpected UpdateService request: %v", update)
+	}
+}
+
+func TestDeployImagesRolloutFailed(t *testing.T) {
+	fake := newFakeAWS()
+	fake.rolloutFailed = true
+	p := newTestProvider(t, fake)
+
+	_, err := p.deployImages(context.Background(), testManifest(), map[string]string{"my-app": testImage})
+	if err == nil || !strings.Contains(err.Error(), "failed to roll out: tasks failed to start") {
+		t.Errorf("expected rollout failure, got %v", err)
+	}
+}
+
+func TestDeployImagesConfiguredRoleAndGroups(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	public := false
+	m.ECS.ExecutionRoleARN = "arn:aws:iam::123456789012:role/custom"
+	m.ECS.SecurityGroups = []string{"sg-custom"}
+	m.ECS.Subnets = []string{"subnet-a", "subnet-b"}
+	m.ECS.AssignPublicIP = &public
+
+	if _, err := p.deployImages(context.Background(), m, map[string]string{"my-app": testImage}); err != nil {
+		t.Fatalf("deployImages() failed: %v", err)
+	}
+	if fake.called("GetRole") || fake.called("DescribeVpcs") {
+		t.Errorf("expected the configured role and subnets to be used; calls: %v", fake.calls)
+	}
+	if _, ok := fake.securityGroups["my-env-tasks"]; ok {
+		t.Error("expected no task security group to be created")
+	}
+	awsvpc := fake.requests["CreateService"].(map[string]interface{})["networkConfiguration"].(map[string]interface{})["awsvpcConfiguration"].(map[string]interface{})
+	if fmt.Sprint(awsvpc["securityGroups"]) != "[sg-custom]" || awsvpc["assignPublicIp"] != "DISABLED" {
+		t.Errorf("unexpected network configuration: %v", awsvpc)
+	}
+	register := fake.requests["RegisterTaskDefinition"].(map[string]interface{})
+	if register["executionRoleArn"] != "arn:aws:iam::123456789012:role/custom" {
+		t.Errorf("unexpected execution role: %v", register["executionRoleArn"])
+	}
+}
+
+func TestStopStatusAndDestroy(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.Status(ctx, m); err == nil || !strings.Contains(err.Error(), "no ECS service found") {
+		t.Errorf("expected missing service error, got %v", err)
+	}
+	if _, err := p.deployImages(ctx, m, map[string]string{"my-app": testImage}); err != nil {
+		t.Fatalf("deployImages() failed: %v", err)
+	}
+
+	status, err := p.Status(ctx, m)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.URL != "http://my-env-1.us-east-1.elb.amazonaws.com" || status.LastUpdated != "2023-11-14T22:13:20Z" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	if err := p.Stop(ctx, m); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if status, _ := p.Status(ctx, m); status.Status != "Stopped" {
+		t.Errorf("expected Stopped after Stop, got %+v", status)
+	}
+
+	if err := p.Destroy(ctx, m); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if fake.service != nil || fake.loadBalancer || fake.targetGroup || fake.logGroup || len(fake.securityGroups) > 0 {
+		t.Errorf("expected all resources to be deleted: %+v", fake)
+	}
+	for _, action := range []string{"DeregisterTaskDefinition", "DeleteCluster"} {
+		if !fake.called(action) {
+			t.Errorf("expected %s to be called", action)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.deployImages(ctx, m, map[string]string{"my-app": testImage}); err != nil {
+		t.Fatalf("deployImages() failed: %v", err)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil || !strings.Contains(err.Error(), "no previous task definition revision") {
+		t.Errorf("expected no previous revision error, got %v", err)
+	}
+
+	if _, err := p.deployImages(ctx, m, map[string]string{"my-app": testImage}); err != nil {
+		t.Fatalf("deployImages() failed: %v", err)
+	}
+	result, err := p.Rollback(ctx, m)
+	if err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if result.Message != "Rolled back to task definition my-env:1" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if fake.service["taskDefinition"] != "arn:aws:ecs:us-east-1:123456789012:task-definition/my-env:1" {
+		t.Errorf("expected the service to run revision 1, got %v", fake.service["taskDefinition"])
+	}
+}
+
+func TestPreviousTaskDefinition(t *testing.T) {
+	arn := func(name string) string { return "arn:aws:ecs:us-east-1:123456789012:task-definition/" + name }
+	arns := []string{arn("web:7"), arn("web:5"), arn("web:2")}
+
+	tests := []struct {
+		current string
+		want    string
+	}{
+		{arn("web:7"), arn("web:5")},
+		{arn("web:5"), arn("web:2")},
+		{arn("web:6"), arn("web:5")},
+		{arn("web:2"), ""},
+	}
+	for _, tt := range tests {
+		got, err := previousTaskDefinition(arns, tt.current)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("previousTaskDefinition(%s) = %s, want error", tt.current, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("previousTaskDefinition(%s) = %s, %v, want %s", tt.current, got, err, tt.want)
+		}
+	}
+
+	if family, revision := taskDefinitionRevision(arn("my-env:12")); family != "my-env" || revision != 12 {
+		t.Errorf("taskDefinitionRevision() = %s, %d", family, revision)
+	}
+}
+
+func TestTaskDefinition(t *testing.T) {
+	m := testManifest()
+	m.ECS.Architecture = manifest.ECSArchitectureARM
+	m.ECS.TaskRoleARN = "arn:aws:iam::123456789012:role/app"
+	m.Container = &manifest.ContainerStartup{Command: []string{"/app"}, Args: []string{"serve"}}
+	m.Security = &manifest.SecurityConfig{User: "1000", ReadOnlyRootFilesystem: true, DropCapabilities: []string{"ALL"}}
+	m.Tags = map[string]string{"team": "web"}
+
+	def := taskDefinition(m, map[string]string{"my-app": testImage}, "arn:role/exec", "us-east-1")
+	data, _ := json.Marshal(def)
+	var got struct {
+		Family               string            `json:"family"`
+		CPU                  string            `json:"cpu"`
+		Memory               string            `json:"memory"`
+		TaskRoleARN          string            `json:"taskRoleArn"`
+		RuntimePlatform      map[string]string `json:"runtimePlatform"`
+		Tags                 []map[string]string
+		ContainerDefinitions []struct {
+			Name            string                   `json:"name"`
+			Image           string                   `json:"image"`
+			EntryPoint      []string                 `json:"entryPoint"`
+			Command         []string                 `json:"command"`
+			User            string                   `json:"user"`
+			ReadOnly        bool                     `json:"readonlyRootFilesystem"`
+			Environment     []map[string]string      `json:"environment"`
+			PortMappings    []map[string]interface{} `json:"portMappings"`
+			LinuxParameters struct {
+				Capabilities struct {
+					Drop []string `json:"drop"`
+				} `json:"capabilities"`
+			} `json:"linuxParameters"`
+			LogConfiguration struct {
+				LogDriver string            `json:"logDriver"`
+				Options   map[string]string `json:"options"`
+			} `json:"logConfiguration"`
+		} `json:"containerDefinitions"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Family != "my-env" || got.CPU != "512" || got.Memory != "1024" || got.TaskRoleARN != m.ECS.TaskRoleARN {
+		t.Errorf("unexpected task definition: %s", data)
+	}
+	if got.RuntimePlatform["cpuArchitecture"] != "ARM64" || len(got.Tags) != 1 || got.Tags[0]["key"] != "team" {
+		t.Errorf("unexpected platform or tags: %s", data)
+	}
+	if len(got.ContainerDefinitions) != 1 {
+		t.Fatalf("expected one container, got %s", data)
+	}
+	c := got.ContainerDefinitions[0]
+	if c.Name != "my-app" || c.Image != testImage || c.EntryPoint[0] != "/app" || c.Command[0] != "serve" {
+		t.Errorf("unexpected container: %+v", c)
+	}
+	if c.User != "1000" || !c.ReadOnly || c.LinuxParameters.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("unexpected security settings: %+v", c)
+	}
+	if len(c.Environment) != 1 || c.Environment[0]["name"] != "LOG_LEVEL" || c.PortMappings[0]["containerPort"] != 8080.0 {
+		t.Errorf("unexpected environment or ports: %+v", c)
+	}
+	if c.LogConfiguration.LogDriver != "awslogs" || c.LogConfiguration.Options["awslogs-group"] != "/ecs/my-app/my-env" || c.LogConfiguration.Options["awslogs-region"] != "us-east-1" {
+		t.Errorf("unexpected log configuration: %+v", c.LogConfiguration)
+	}
+}
+
+func TestTaskDefinitionMultiContainer(t *testing.T) {
+	m := testManifest()
+	m.Image = ""
+	m.Ports = nil
+	m.Containers = []manifest.Container{
+		{Name: "web", Image: "web:1", Environment: map[string]string{"ROLE": "web"}},
+		{Name: "worker", Image: "worker:1"},
+	}
+
+	def := taskDefinition(m, map[string]string{"web": "ecr/web@sha256:1", "worker": "ecr/worker@sha256:2"}, "arn:role/exec", "us-east-1")
+	containers := def["containerDefinitions"].([]map[string]interface{})
+	if len(containers) != 2 || containers[0]["image"] != "ecr/web@sha256:1" || containers[1]["image"] != "ecr/worker@sha256:2" {
+		t.Fatalf("unexpected containers: %v", containers)
+	}
+	if _, ok := containers[0]["portMappings"]; !ok {
+		t.Error("expected the primary container to expose the default port")
+	}
+	if _, ok := containers[1]["portMappings"]; ok {
+		t.Error("expected the worker to expose no ports")
+	}
+	if env := containers[0]["environment"].([]map[string]string); len(env) != 2 {
+		t.Errorf("expected manifest and container variables, got %v", env)
+	}
+}
+
+func TestServiceStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		svc            service
+		status, health string
+	}{
+		{"stopped", service{DesiredCount: 0}, "Stopped", "Grey"},
+		{"running", service{DesiredCount: 2, RunningCount: 2, Deployments: []serviceDeployment{{Status: "PRIMARY", DesiredCount: 2, RunningCount: 2, RolloutState: "COMPLETED"}}}, "Running", "Green"},
+		{"updating", service{DesiredCount: 2, RunningCount: 2, Deployments: []serviceDeployment{{Status: "PRIMARY", DesiredCount: 2, RolloutState: "IN_PROGRESS"}, {Status: "ACTIVE", RunningCount: 2}}}, "Updating", "Yellow"},
+		{"starting", service{DesiredCount: 1, Deployments: []serviceDeployment{{Status: "PRIMARY", DesiredCount: 1, RolloutState: "IN_PROGRESS"}}}, "Updating", "Red"},
+		{"failed", service{DesiredCount: 1, Deployments: []serviceDeployment{{Status: "PRIMARY", DesiredCount: 1, RolloutState: "FAILED"}}}, "Failed", "Red"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, health := serviceStatus(&tt.svc); status != tt.status || health != tt.health {
+				t.Errorf("serviceStatus() = %s, %s, want %s, %s", status, health, tt.status, tt.health)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerName(t *testing.T) {
+	tests := map[string]string{
+		"my-env":      "my-env",
+		"my_env.prod": "my-env-prod",
+		"a-very-long-environment-name-for-testing": "a-very-long-environment-name-for",
+		"-env-": "env",
+		"__":    "cloud-deploy",
+	}
+	for env, want := range tests {
+		m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: env}}
+		if got := loadBalancerName(m); got != want {
+			t.Errorf("loadBalancerName(%q) = %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestClusterAndDefaults(t *testing.T) {
+	m := testManifest()
+	if clusterName(m) != "my-app" || desiredCount(m.ECS) != 1 || architecture(nil) != "X86_64" {
+		t.Errorf("unexpected defaults: %s, %d, %s", clusterName(m), desiredCount(m.ECS), architecture(nil))
+	}
+	count := 3
+	m.ECS.Cluster = "shared"
+	m.ECS.DesiredCount = &count
+	if clusterName(m) != "shared" || desiredCount(m.ECS) != 3 {
+		t.Errorf("unexpected configured values: %s, %d", clusterName(m), desiredCount(m.ECS))
+	}
+}
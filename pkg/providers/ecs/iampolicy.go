@@ -0,0 +1,193 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	awsprovider "github.com/jvreagan/cloud-deploy/pkg/providers/aws"
+)
+
+// iamStatement is a statement of an IAM policy document.
+type iamStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// IAMPolicy returns the IAM policy the deployer needs for the features used in m,
+// scoped to the manifest's cluster, environment, and region where AWS allows it.
+// EC2 security group and ECS task definition actions cannot be scoped by name,
+// so they are granted on all resources.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	region := m.Provider.Region
+	if region == "" {
+		region = "*"
+	}
+	app := m.Application.Name
+	if app == "" {
+		return nil, fmt.Errorf("application.name is required to scope the policy")
+	}
+	cluster := clusterName(m)
+	env := m.Environment.Name
+	lbName := loadBalancerName(m)
+
+	ecs := func(resource string) string {
+		return fmt.Sprintf("arn:aws:ecs:%s:*:%s", region, resource)
+	}
+	elb := func(resource string) string {
+		return fmt.Sprintf("arn:aws:elasticloadbalancing:%s:*:%s", region, resource)
+	}
+
+	executionRole := "arn:aws:iam::*:role/" + executionRoleName
+	passRoles := []string{executionRole}
+	if m.ECS != nil && m.ECS.ExecutionRoleARN != "" {
+		passRoles = []string{m.ECS.ExecutionRoleARN}
+	}
+	if m.ECS != nil && m.ECS.TaskRoleARN != "" {
+		passRoles = append(passRoles, m.ECS.TaskRoleARN)
+	}
+
+	statements := []iamStatement{
+		{
+			Sid:    "ECSTaskDefinitions",
+			Effect: "Allow",
+			Action: []string{
+				"ecs:RegisterTaskDefinition",
+				"ecs:DeregisterTaskDefinition",
+				"ecs:ListTaskDefinitions",
+				"ecs:TagResource",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "ECSService",
+			Effect: "Allow",
+			Action: []string{
+				"ecs:CreateCluster",
+				"ecs:DescribeClusters",
+				"ecs:DeleteCluster",
+				"ecs:CreateService",
+				"ecs:DescribeServices",
+				"ecs:UpdateService",
+				"ecs:DeleteService",
+			},
+			Resource: []string{
+				ecs("cluster/" + cluster),
+				ecs("service/" + cluster + "/" + env),
+				ecs("task-definition/" + env + ":*"),
+			},
+		},
+		{
+			Sid:    "Networking",
+			Effect: "Allow",
+			Action: []string{
+				"ec2:DescribeVpcs",
+				"ec2:DescribeSubnets",
+				"ec2:DescribeSecurityGroups",
+				"ec2:CreateSecurityGroup",
+				"ec2:AuthorizeSecurityGroupIngress",
+				"ec2:DeleteSecurityGroup",
+				"elasticloadbalancing:DescribeLoadBalancers",
+				"elasticloadbalancing:DescribeTargetGroups",
+				"elasticloadbalancing:DescribeListeners",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "LoadBalancer",
+			Effect: "Allow",
+			Action: []string{
+				"elasticloadbalancing:CreateLoadBalancer",
+				"elasticloadbalancing:DeleteLoadBalancer",
+				"elasticloadbalancing:CreateTargetGroup",
+				"elasticloadbalancing:ModifyTargetGroup",
+				"elasticloadbalancing:DeleteTargetGroup",
+				"elasticloadbalancing:CreateListener",
+				"elasticloadbalancing:AddTags",
+			},
+			Resource: []string{
+				elb("loadbalancer/app/" + lbName + "/*"),
+				elb("targetgroup/" + lbName + "/*"),
+				elb("listener/app/" + lbName + "/*"),
+			},
+		},
+		{
+			Sid:    "ServiceLinkedRoles",
+			Effect: "Allow",
+			Action: []string{"iam:CreateServiceLinkedRole"},
+			Resource: []string{
+				"arn:aws:iam::*:role/aws-service-role/ecs.amazonaws.com/*",
+				"arn:aws:iam::*:role/aws-service-role/elasticloadbalancing.amazonaws.com/*",
+			},
+		},
+		{
+			Sid:    "Logs",
+			Effect: "Allow",
+			Action: []string{
+				"logs:CreateLogGroup",
+				"logs:PutRetentionPolicy",
+				"logs:TagResource",
+				"logs:FilterLogEvents",
+				"logs:DeleteLogGroup",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:logs:%s:*:log-group:%s*", region, logGroupName(m))},
+		},
+		{
+			Sid:    "ImagePush",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:CreateRepository",
+				"ecr:DescribeRepositories",
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:BatchGetImage",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:InitiateLayerUpload",
+				"ecr:UploadLayerPart",
+				"ecr:CompleteLayerUpload",
+				"ecr:PutImage",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:ecr:%s:*:repository/%s", region, m.RepositoryName())},
+		},
+		{
+			Sid:    "RegistryLogin",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:GetAuthorizationToken",
+				"sts:GetCallerIdentity",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:      "PassTaskRoles",
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: passRoles,
+		},
+	}
+
+	if m.ECS == nil || m.ECS.ExecutionRoleARN == "" {
+		statements = append(statements, iamStatement{
+			Sid:    "TaskExecutionRole",
+			Effect: "Allow",
+			Action: []string{
+				"iam:GetRole",
+				"iam:CreateRole",
+				"iam:AttachRolePolicy",
+			},
+			Resource: []string{executionRole},
+		})
+	}
+
+	if resources := awsprovider.SecretResources(m, region); len(resources) > 0 {
+		statements = append(statements, iamStatement{
+			Sid:      "ReadSecrets",
+			Effect:   "Allow",
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Resource: resources,
+		})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{"Version": "2012-10-17", "Statement": statements}, "", "  ")
+}
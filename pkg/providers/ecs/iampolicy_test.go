@@ -0,0 +1,88 @@
+package ecs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func policyStatements(t *testing.T, m *manifest.Manifest) map[string]iamStatement {
+	t.Helper()
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc struct {
+		Version   string
+		Statement []iamStatement
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	if doc.Version != "2012-10-17" {
+		t.Errorf("Unexpected policy version %q", doc.Version)
+	}
+	statements := make(map[string]iamStatement)
+	for _, s := range doc.Statement {
+		statements[s.Sid] = s
+	}
+	return statements
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIAMPolicy(t *testing.T) {
+	m := testManifest()
+	statements := policyStatements(t, m)
+
+	for _, sid := range []string{"ECSTaskDefinitions", "ECSService", "Networking", "LoadBalancer", "Logs", "ImagePush", "RegistryLogin", "PassTaskRoles", "TaskExecutionRole"} {
+		if _, ok := statements[sid]; !ok {
+			t.Errorf("Expected statement %s", sid)
+		}
+	}
+	if _, ok := statements["ReadSecrets"]; ok {
+		t.Error("Unexpected ReadSecrets statement without secrets")
+	}
+
+	if !containsString(statements["ECSService"].Resource, "arn:aws:ecs:us-east-1:*:service/my-app/my-env") {
+		t.Errorf("Expected the service scoped to the cluster, got %v", statements["ECSService"].Resource)
+	}
+	if !containsString(statements["LoadBalancer"].Resource, "arn:aws:elasticloadbalancing:us-east-1:*:loadbalancer/app/my-env/*") {
+		t.Errorf("Expected the load balancer scoped to the environment, got %v", statements["LoadBalancer"].Resource)
+	}
+	if !containsString(statements["Logs"].Resource, "arn:aws:logs:us-east-1:*:log-group:/ecs/my-app/my-env*") {
+		t.Errorf("Expected logs scoped to the log group, got %v", statements["Logs"].Resource)
+	}
+	if !containsString(statements["PassTaskRoles"].Resource, "arn:aws:iam::*:role/ecsTaskExecutionRole") {
+		t.Errorf("Expected the default execution role to be passable, got %v", statements["PassTaskRoles"].Resource)
+	}
+}
+
+func TestIAMPolicyConfiguredRoles(t *testing.T) {
+	m := testManifest()
+	m.ECS.ExecutionRoleARN = "arn:aws:iam::123456789012:role/exec"
+	m.ECS.TaskRoleARN = "arn:aws:iam::123456789012:role/app"
+	statements := policyStatements(t, m)
+
+	if _, ok := statements["TaskExecutionRole"]; ok {
+		t.Error("Unexpected TaskExecutionRole statement with a configured execution role")
+	}
+	roles := statements["PassTaskRoles"].Resource
+	if len(roles) != 2 || roles[0] != m.ECS.ExecutionRoleARN || roles[1] != m.ECS.TaskRoleARN {
+		t.Errorf("Expected the configured roles to be passable, got %v", roles)
+	}
+}
+
+func TestIAMPolicyRequiresApplication(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected an error without application.name")
+	}
+}
@@ -0,0 +1,103 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// logEvent is an event returned by CloudWatch Logs FilterLogEvents.
+type logEvent struct {
+	EventID       string `json:"eventId"`
+	LogStreamName string `json:"logStreamName"`
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+}
+
+// Logs prints the output of the service's containers from the environment's
+// CloudWatch log group. Streams are named ecs/<container>/<task ID>.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	group := logGroupName(m)
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var entries []types.LogEntry
+		input := map[string]interface{}{
+			"logGroupName": group,
+			"startTime":    since.UnixMilli(),
+		}
+		for {
+			var out struct {
+				Events    []logEvent `json:"events"`
+				NextToken string     `json:"nextToken"`
+			}
+			if err := p.logsClient.JSON(ctx, logsTargetPrefix+"FilterLogEvents", input, &out); err != nil {
+				if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+					return nil, fmt.Errorf("log group %s does not exist; deploy the environment first", group)
+				}
+				return nil, fmt.Errorf("failed to read log group %s: %w", group, err)
+			}
+			for _, e := range out.Events {
+				entries = append(entries, types.LogEntry{
+					ID:        e.EventID,
+					Timestamp: time.UnixMilli(e.Timestamp),
+					Source:    e.LogStreamName,
+					Message:   strings.TrimRight(e.Message, "\n"),
+				})
+			}
+			if out.NextToken == "" {
+				return entries, nil
+			}
+			input["nextToken"] = out.NextToken
+		}
+	})
+}
+
+// logGroupName returns the CloudWatch log group the containers log to.
+func logGroupName(m *manifest.Manifest) string {
+	return fmt.Sprintf("/ecs/%s/%s", m.Application.Name, m.Environment.Name)
+}
+
+// ensureLogGroup creates the environment's log group if it does not exist
+// and applies ecs.log_retention_days to it.
+func (p *Provider) ensureLogGroup(ctx context.Context, m *manifest.Manifest) error {
+	group := logGroupName(m)
+	input := map[string]interface{}{"logGroupName": group}
+	if tags := m.Tags; len(tags) > 0 {
+		input["tags"] = tags
+	}
+	err := p.logsClient.JSON(ctx, logsTargetPrefix+"CreateLogGroup", input, nil)
+	switch {
+	case err == nil:
+		logging.Infof("Created log group: %s", group)
+	case !awsapi.IsErrorCode(err, "ResourceAlreadyExistsException"):
+		return fmt.Errorf("failed to create log group %s: %w", group, err)
+	}
+
+	if m.ECS != nil && m.ECS.LogRetentionDays > 0 {
+		input := map[string]interface{}{"logGroupName": group, "retentionInDays": m.ECS.LogRetentionDays}
+		if err := p.logsClient.JSON(ctx, logsTargetPrefix+"PutRetentionPolicy", input, nil); err != nil {
+			return fmt.Errorf("failed to set retention of log group %s: %w", group, err)
+		}
+	}
+	return nil
+}
+
+// deleteLogGroup deletes the environment's log group if it exists.
+func (p *Provider) deleteLogGroup(ctx context.Context, m *manifest.Manifest) error {
+	group := logGroupName(m)
+	err := p.logsClient.JSON(ctx, logsTargetPrefix+"DeleteLogGroup", map[string]string{"logGroupName": group}, nil)
+	if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete log group %s: %w", group, err)
+	}
+	logging.Infof("Deleted log group: %s", group)
+	return nil
+}
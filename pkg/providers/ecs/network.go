@@ -0,0 +1,392 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// network is the VPC, subnets, and security groups of a deployment.
+type network struct {
+	vpcID              string
+	subnets            []string
+	lbSecurityGroup    string
+	taskSecurityGroups []string
+}
+
+// loadBalancer is an Application Load Balancer, as returned by DescribeLoadBalancers.
+type loadBalancer struct {
+	ARN     string `xml:"LoadBalancerArn"`
+	DNSName string `xml:"DNSName"`
+}
+
+// ensureNetwork finds the subnets to run in and creates the security groups
+// of the load balancer, open to the internet on port 80, and of the tasks,
+// open to the load balancer on the container port. Configured task security
+// groups are used as given.
+func (p *Provider) ensureNetwork(ctx context.Context, m *manifest.Manifest) (*network, error) {
+	subnets, vpcID, err := p.subnets(ctx, m.ECS)
+	if err != nil {
+		return nil, err
+	}
+	n := &network{vpcID: vpcID, subnets: subnets}
+
+	n.lbSecurityGroup, err = p.ensureSecurityGroup(ctx, vpcID, securityGroupName(m, "lb"), "Load balancer of "+m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeIngress(ctx, n.lbSecurityGroup, 80, "0.0.0.0/0"); err != nil {
+		return nil, err
+	}
+
+	if m.ECS != nil && len(m.ECS.SecurityGroups) > 0 {
+		n.taskSecurityGroups = m.ECS.SecurityGroups
+		return n, nil
+	}
+	taskGroup, err := p.ensureSecurityGroup(ctx, vpcID, securityGroupName(m, "tasks"), "Tasks of "+m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeIngress(ctx, taskGroup, containerPort(m.GetPrimaryContainer()), n.lbSecurityGroup); err != nil {
+		return nil, err
+	}
+	n.taskSecurityGroups = []string{taskGroup}
+	return n, nil
+}
+
+// subnets returns the configured subnets, or the default subnets of the
+// account's default VPC, and the VPC they are in.
+func (p *Provider) subnets(ctx context.Context, e *manifest.ECSConfig) ([]string, string, error) {
+	var params url.Values
+	if e != nil && len(e.Subnets) > 0 {
+		params = url.Values{}
+		for i, id := range e.Subnets {
+			params.Set(fmt.Sprintf("SubnetId.%d", i+1), id)
+		}
+	} else {
+		var vpcs struct {
+			IDs []string `xml:"vpcSet>item>vpcId"`
+		}
+		if err := p.ec2Client.EC2(ctx, "DescribeVpcs", ec2APIVersion, ec2Filters("is-default", "true"), &vpcs); err != nil {
+			return nil, "", fmt.Errorf("failed to find the default VPC: %w", err)
+		}
+		if len(vpcs.IDs) == 0 {
+			return nil, "", fmt.Errorf("the account has no default VPC in %s; set ecs.subnets", p.region)
+		}
+		params = ec2Filters("vpc-id", vpcs.IDs[0], "default-for-az", "true")
+	}
+
+	var out struct {
+		Subnets []struct {
+			ID    string `xml:"subnetId"`
+			VPCID string `xml:"vpcId"`
+		} `xml:"subnetSet>item"`
+	}
+	if err := p.ec2Client.EC2(ctx, "DescribeSubnets", ec2APIVersion, params, &out); err != nil {
+		return nil, "", fmt.Errorf("failed to describe subnets: %w", err)
+	}
+	if len(out.Subnets) == 0 {
+		return nil, "", fmt.Errorf("no default subnets found in %s; set ecs.subnets", p.region)
+	}
+
+	ids := make([]string, 0, len(out.Subnets))
+	vpcID := out.Subnets[0].VPCID
+	for _, s := range out.Subnets {
+		if s.VPCID != vpcID {
+			return nil, "", fmt.Errorf("ecs.subnets must be in one VPC, but %s is in %s and %s in %s", ids[0], vpcID, s.ID, s.VPCID)
+		}
+		ids = append(ids, s.ID)
+	}
+	return ids, vpcID, nil
+}
+
+// findSecurityGroup returns the ID of the named security group in a VPC, or "".
+func (p *Provider) findSecurityGroup(ctx context.Context, vpcID, name string) (string, error) {
+	var out struct {
+		IDs []string `xml:"securityGroupInfo>item>groupId"`
+	}
+	filters := ec2Filters("group-name", name)
+	if vpcID != "" {
+		filters = ec2Filters("group-name", name, "vpc-id", vpcID)
+	}
+	if err := p.ec2Client.EC2(ctx, "DescribeSecurityGroups", ec2APIVersion, filters, &out); err != nil {
+		return "", fmt.Errorf("failed to describe security group %s: %w", name, err)
+	}
+	if len(out.IDs) == 0 {
+		return "", nil
+	}
+	return out.IDs[0], nil
+}
+
+// ensureSecurityGroup returns the ID of the named security group, creating it
+// if it does not exist.
+func (p *Provider) ensureSecurityGroup(ctx context.Context, vpcID, name, description string) (string, error) {
+	id, err := p.findSecurityGroup(ctx, vpcID, name)
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	logging.Infof("Creating security group: %s", name)
+	var out struct {
+		ID string `xml:"groupId"`
+	}
+	params := url.Values{"GroupName": {name}, "GroupDescription": {description}, "VpcId": {vpcID}}
+	if err := p.ec2Client.EC2(ctx, "CreateSecurityGroup", ec2APIVersion, params, &out); err != nil {
+		return "", fmt.Errorf("failed to create security group %s: %w", name, err)
+	}
+	return out.ID, nil
+}
+
+// authorizeIngress allows TCP traffic on port into a security group from
+// source, a CIDR block or another security group.
+func (p *Provider) authorizeIngress(ctx context.Context, groupID string, port int, source string) error {
+	params := url.Values{
+		"GroupId":                    {groupID},
+		"IpPermissions.1.IpProtocol": {"tcp"},
+		"IpPermissions.1.FromPort":   {strconv.Itoa(port)},
+		"IpPermissions.1.ToPort":     {strconv.Itoa(port)},
+	}
+	if strings.HasPrefix(source, "sg-") {
+		params.Set("IpPermissions.1.Groups.1.GroupId", source)
+	} else {
+		params.Set("IpPermissions.1.IpRanges.1.CidrIp", source)
+	}
+	err := p.ec2Client.EC2(ctx, "AuthorizeSecurityGroupIngress", ec2APIVersion, params, nil)
+	if err != nil && !awsapi.IsErrorCode(err, "InvalidPermission.Duplicate") {
+		return fmt.Errorf("failed to allow port %d into security group %s: %w", port, groupID, err)
+	}
+	return nil
+}
+
+// ensureLoadBalancer creates the environment's internet-facing load balancer,
+// its target group, and a listener on port 80 forwarding to it, reusing those
+// that exist. It returns the load balancer and the target group's ARN.
+func (p *Provider) ensureLoadBalancer(ctx context.Context, m *manifest.Manifest, n *network) (*loadBalancer, string, error) {
+	name := loadBalancerName(m)
+	lb, err := p.findLoadBalancer(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if lb == nil {
+		logging.Infof("Creating load balancer: %s", name)
+		params := url.Values{
+			"Name":                    {name},
+			"Type":                    {"application"},
+			"Scheme":                  {"internet-facing"},
+			"SecurityGroups.member.1": {n.lbSecurityGroup},
+		}
+		for i, subnet := range n.subnets {
+			params.Set(fmt.Sprintf("Subnets.member.%d", i+1), subnet)
+		}
+		addELBTags(params, m)
+		var out struct {
+			LoadBalancers []loadBalancer `xml:"LoadBalancers>member"`
+		}
+		if err := p.elbClient.Query(ctx, "CreateLoadBalancer", elbAPIVersion, params, &out); err != nil {
+			return nil, "", fmt.Errorf("failed to create load balancer: %w", err)
+		}
+		if len(out.LoadBalancers) == 0 {
+			return nil, "", fmt.Errorf("failed to create load balancer: no load balancer returned")
+		}
+		lb = &out.LoadBalancers[0]
+	}
+
+	healthCheckPath := m.HealthCheck.Path
+	if healthCheckPath == "" {
+		healthCheckPath = "/"
+	}
+	targetGroup, err := p.findTargetGroup(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if targetGroup == "" {
+		logging.Infof("Creating target group: %s", name)
+		params := url.Values{
+			"Name":             {name},
+			"Protocol":         {"HTTP"},
+			"Port":             {strconv.Itoa(containerPort(m.GetPrimaryContainer()))},
+			"VpcId":            {n.vpcID},
+			"TargetType":       {"ip"},
+			"HealthCheckPath":  {healthCheckPath},
+			"Matcher.HttpCode": {"200-399"},
+		}
+		addELBTags(params, m)
+		var out struct {
+			ARNs []string `xml:"TargetGroups>member>TargetGroupArn"`
+		}
+		if err := p.elbClient.Query(ctx, "CreateTargetGroup", elbAPIVersion, params, &out); err != nil {
+			return nil, "", fmt.Errorf("failed to create target group: %w", err)
+		}
+		if len(out.ARNs) == 0 {
+			return nil, "", fmt.Errorf("failed to create target group: no target group returned")
+		}
+		targetGroup = out.ARNs[0]
+	} else {
+		params := url.Values{"TargetGroupArn": {targetGroup}, "HealthCheckPath": {healthCheckPath}}
+		if err := p.elbClient.Query(ctx, "ModifyTargetGroup", elbAPIVersion, params, nil); err != nil {
+			return nil, "", fmt.Errorf("failed to update target group health check: %w", err)
+		}
+	}
+
+	var listeners struct {
+		Ports []int `xml:"Listeners>member>Port"`
+	}
+	if err := p.elbClient.Query(ctx, "DescribeListeners", elbAPIVersion, url.Values{"LoadBalancerArn": {lb.ARN}}, &listeners); err != nil {
+		return nil, "", fmt.Errorf("failed to describe listeners: %w", err)
+	}
+	for _, port := range listeners.Ports {
+		if port == 80 {
+			return lb, targetGroup, nil
+		}
+	}
+	logging.Info("Creating HTTP listener on port 80")
+	params := url.Values{
+		"LoadBalancerArn":                        {lb.ARN},
+		"Protocol":                               {"HTTP"},
+		"Port":                                   {"80"},
+		"DefaultActions.member.1.Type":           {"forward"},
+		"DefaultActions.member.1.TargetGroupArn": {targetGroup},
+	}
+	if err := p.elbClient.Query(ctx, "CreateListener", elbAPIVersion, params, nil); err != nil {
+		return nil, "", fmt.Errorf("failed to create listener: %w", err)
+	}
+	return lb, targetGroup, nil
+}
+
+// findLoadBalancer returns the named load balancer, or nil if it does not exist.
+func (p *Provider) findLoadBalancer(ctx context.Context, name string) (*loadBalancer, error) {
+	var out struct {
+		LoadBalancers []loadBalancer `xml:"LoadBalancers>member"`
+	}
+	err := p.elbClient.Query(ctx, "DescribeLoadBalancers", elbAPIVersion, url.Values{"Names.member.1": {name}}, &out)
+	if awsapi.IsErrorCode(err, "LoadBalancerNotFound") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe load balancer %s: %w", name, err)
+	}
+	if len(out.LoadBalancers) == 0 {
+		return nil, nil
+	}
+	return &out.LoadBalancers[0], nil
+}
+
+// findTargetGroup returns the ARN of the named target group, or "".
+func (p *Provider) findTargetGroup(ctx context.Context, name string) (string, error) {
+	var out struct {
+		ARNs []string `xml:"TargetGroups>member>TargetGroupArn"`
+	}
+	err := p.elbClient.Query(ctx, "DescribeTargetGroups", elbAPIVersion, url.Values{"Names.member.1": {name}}, &out)
+	if awsapi.IsErrorCode(err, "TargetGroupNotFound") {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to describe target group %s: %w", name, err)
+	}
+	if len(out.ARNs) == 0 {
+		return "", nil
+	}
+	return out.ARNs[0], nil
+}
+
+// deleteLoadBalancer deletes the environment's load balancer, with its
+// listeners, and then its target group once the load balancer releases it.
+func (p *Provider) deleteLoadBalancer(ctx context.Context, m *manifest.Manifest) error {
+	name := loadBalancerName(m)
+	lb, err := p.findLoadBalancer(ctx, name)
+	if err != nil {
+		return err
+	}
+	if lb != nil {
+		if err := p.elbClient.Query(ctx, "DeleteLoadBalancer", elbAPIVersion, url.Values{"LoadBalancerArn": {lb.ARN}}, nil); err != nil {
+			return fmt.Errorf("failed to delete load balancer: %w", err)
+		}
+		logging.Infof("Deleted load balancer: %s", name)
+	}
+
+	targetGroup, err := p.findTargetGroup(ctx, name)
+	if err != nil || targetGroup == "" {
+		return err
+	}
+	err = p.retry(ctx, "ResourceInUse", func() error {
+		return p.elbClient.Query(ctx, "DeleteTargetGroup", elbAPIVersion, url.Values{"TargetGroupArn": {targetGroup}}, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete target group: %w", err)
+	}
+	logging.Infof("Deleted target group: %s", name)
+	return nil
+}
+
+// deleteSecurityGroups deletes the security groups Deploy created, once the
+// network interfaces of the tasks and load balancer release them. Failures
+// are only logged; the groups cost nothing and can be deleted later.
+func (p *Provider) deleteSecurityGroups(ctx context.Context, m *manifest.Manifest) {
+	for _, suffix := range []string{"tasks", "lb"} {
+		name := securityGroupName(m, suffix)
+		id, err := p.findSecurityGroup(ctx, "", name)
+		if err != nil {
+			logging.Warnf("Could not find security group %s: %v", name, err)
+			continue
+		}
+		if id == "" {
+			continue
+		}
+		err = p.retry(ctx, "DependencyViolation", func() error {
+			return p.ec2Client.EC2(ctx, "DeleteSecurityGroup", ec2APIVersion, url.Values{"GroupId": {id}}, nil)
+		})
+		if err != nil {
+			logging.Warnf("Could not delete security group %s (%s): %v", name, id, err)
+			continue
+		}
+		logging.Infof("Deleted security group: %s", name)
+	}
+}
+
+// retry calls fn until it does not fail with the error code, which AWS returns
+// while resources being deleted still hold on to what fn deletes.
+func (p *Provider) retry(ctx context.Context, code string, fn func() error) error {
+	deadline := time.Now().Add(cleanupTimeout)
+	for {
+		err := fn()
+		if !awsapi.IsErrorCode(err, code) || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// securityGroupName returns the name of a security group Deploy creates.
+func securityGroupName(m *manifest.Manifest, suffix string) string {
+	return m.Environment.Name + "-" + suffix
+}
+
+// addELBTags adds the manifest's tags to an Elastic Load Balancing request.
+func addELBTags(params url.Values, m *manifest.Manifest) {
+	for i, tag := range ecsTags(m) {
+		params.Set(fmt.Sprintf("Tags.member.%d.Key", i+1), tag["key"])
+		params.Set(fmt.Sprintf("Tags.member.%d.Value", i+1), tag["value"])
+	}
+}
+
+// ec2Filters returns the EC2 filter parameters for name and value pairs.
+func ec2Filters(pairs ...string) url.Values {
+	params := url.Values{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		n := i/2 + 1
+		params.Set(fmt.Sprintf("Filter.%d.Name", n), pairs[i])
+		params.Set(fmt.Sprintf("Filter.%d.Value.1", n), pairs[i+1])
+	}
+	return params
+}
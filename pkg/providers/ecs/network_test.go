@@ -0,0 +1,79 @@
+package ecs
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestEnsureNetwork(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	n, err := p.ensureNetwork(context.Background(), m)
+	if err != nil {
+		t.Fatalf("ensureNetwork() failed: %v", err)
+	}
+	if n.vpcID != "vpc-1" || n.lbSecurityGroup != "sg-my-env-lb" || len(n.taskSecurityGroups) != 1 || n.taskSecurityGroups[0] != "sg-my-env-tasks" {
+		t.Errorf("unexpected network: %+v", n)
+	}
+	ingress := fake.requests["AuthorizeSecurityGroupIngress"]
+	if ingress == nil {
+		t.Fatal("expected ingress to be authorized")
+	}
+
+	// Existing groups are reused
+	fake.calls = nil
+	if _, err := p.ensureNetwork(context.Background(), m); err != nil {
+		t.Fatalf("second ensureNetwork() failed: %v", err)
+	}
+	if fake.called("CreateSecurityGroup") {
+		t.Error("expected existing security groups to be reused")
+	}
+}
+
+func TestSubnetsConfigured(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+
+	ids, vpc, err := p.subnets(context.Background(), &manifest.ECSConfig{Subnets: []string{"subnet-a", "subnet-b"}})
+	if err != nil {
+		t.Fatalf("subnets() failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "subnet-a,subnet-b" || vpc != "vpc-1" {
+		t.Errorf("subnets() = %v, %s", ids, vpc)
+	}
+	if fake.called("DescribeVpcs") {
+		t.Error("expected the default VPC not to be looked up")
+	}
+}
+
+func TestDeleteLoadBalancerMissing(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+
+	if err := p.deleteLoadBalancer(context.Background(), testManifest()); err != nil {
+		t.Errorf("deleteLoadBalancer() without a load balancer failed: %v", err)
+	}
+}
+
+func TestEC2Filters(t *testing.T) {
+	params := ec2Filters("vpc-id", "vpc-1", "default-for-az", "true")
+	want := map[string]string{
+		"Filter.1.Name":    "vpc-id",
+		"Filter.1.Value.1": "vpc-1",
+		"Filter.2.Name":    "default-for-az",
+		"Filter.2.Value.1": "true",
+	}
+	if len(params) != len(want) {
+		t.Fatalf("unexpected filters: %v", params)
+	}
+	for k, v := range want {
+		if params.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, params.Get(k), v)
+		}
+	}
+}
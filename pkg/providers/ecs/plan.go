@@ -0,0 +1,74 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing anything in the account. Every deployment registers a new task
+// definition revision, which the service rolls out.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "aws-ecs"}
+	cluster := clusterName(m)
+	name := m.Environment.Name
+
+	var clusters struct {
+		Clusters []struct {
+			Status string `json:"status"`
+		} `json:"clusters"`
+	}
+	if err := p.ecsClient.JSON(ctx, ecsTargetPrefix+"DescribeClusters", map[string]interface{}{"clusters": []string{cluster}}, &clusters); err != nil {
+		return nil, fmt.Errorf("failed to describe cluster %s: %w", cluster, err)
+	}
+	if len(clusters.Clusters) == 0 || clusters.Clusters[0].Status != "ACTIVE" {
+		plan.Add(types.PlanCreate, "Cluster", cluster, "cluster does not exist")
+	} else {
+		plan.Add(types.PlanNoChange, "Cluster", cluster, "")
+	}
+
+	lbName := loadBalancerName(m)
+	lb, err := p.findLoadBalancer(ctx, lbName)
+	if err != nil {
+		return nil, err
+	}
+	if lb == nil {
+		plan.Add(types.PlanCreate, "LoadBalancer", lbName, "internet-facing, HTTP on port 80")
+	} else {
+		plan.Add(types.PlanNoChange, "LoadBalancer", lbName, lb.DNSName)
+	}
+	targetGroup, err := p.findTargetGroup(ctx, lbName)
+	if err != nil {
+		return nil, err
+	}
+	healthCheckPath := m.HealthCheck.Path
+	if healthCheckPath == "" {
+		healthCheckPath = "/"
+	}
+	if targetGroup == "" {
+		plan.Add(types.PlanCreate, "TargetGroup", lbName, "health check "+healthCheckPath)
+	} else {
+		plan.Add(types.PlanUpdate, "TargetGroup", lbName, "health check "+healthCheckPath)
+	}
+
+	var images []string
+	for _, c := range deployContainers(m) {
+		images = append(images, c.Image)
+	}
+	plan.Add(types.PlanCreate, "TaskDefinition", name, "new revision running "+strings.Join(images, ", "))
+
+	svc, err := p.describeService(ctx, cluster, name)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		plan.Add(types.PlanCreate, "Service", name, fmt.Sprintf("%d Fargate tasks", desiredCount(m.ECS)))
+	} else {
+		plan.Add(types.PlanUpdate, "Service", name, fmt.Sprintf("roll out the new revision (running %s)", taskDefinitionName(svc.TaskDefinition)))
+	}
+	return plan, nil
+}
@@ -0,0 +1,53 @@
+package ecs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	plan, err := p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want := map[string]types.PlanAction{
+		"LoadBalancer":   types.PlanCreate,
+		"TargetGroup":    types.PlanCreate,
+		"TaskDefinition": types.PlanCreate,
+		"Service":        types.PlanCreate,
+	}
+	for _, c := range plan.Changes {
+		if action, ok := want[c.Type]; ok && c.Action != action {
+			t.Errorf("%s: got %s, want %s", c.Type, c.Action, action)
+		}
+	}
+
+	if _, err := p.deployImages(context.Background(), m, map[string]string{"my-app": testImage}); err != nil {
+		t.Fatalf("deployImages() failed: %v", err)
+	}
+	plan, err = p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want = map[string]types.PlanAction{
+		"Cluster":        types.PlanNoChange,
+		"LoadBalancer":   types.PlanNoChange,
+		"TargetGroup":    types.PlanUpdate,
+		"TaskDefinition": types.PlanCreate,
+		"Service":        types.PlanUpdate,
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), plan.Changes)
+	}
+	for _, c := range plan.Changes {
+		if c.Action != want[c.Type] {
+			t.Errorf("%s: got %s, want %s", c.Type, c.Action, want[c.Type])
+		}
+	}
+}
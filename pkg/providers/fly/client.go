@@ -0,0 +1,153 @@
+package fly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMachinesEndpoint is the base URL of the Fly Machines API.
+const DefaultMachinesEndpoint = "https://api.machines.dev"
+
+// DefaultAPIEndpoint is the base URL of the Fly.io platform API, which serves
+// the GraphQL API and app logs.
+const DefaultAPIEndpoint = "https://api.fly.io"
+
+// client calls the Fly Machines and platform APIs, authenticating with an API
+// token.
+type client struct {
+	token            string
+	machinesEndpoint string
+	apiEndpoint      string
+	httpClient       *http.Client
+}
+
+// newClient creates a client that authenticates with token.
+func newClient(token string) *client {
+	return &client{
+		token:            token,
+		machinesEndpoint: DefaultMachinesEndpoint,
+		apiEndpoint:      DefaultAPIEndpoint,
+		httpClient:       http.DefaultClient,
+	}
+}
+
+// apiError is an error returned by a Fly.io API.
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Fly.io API error (HTTP %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("Fly.io API error (HTTP %d): %s", e.StatusCode, e.Message)
+}
+
+// isNotFound reports whether err is an apiError for a missing resource.
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// authorization returns the Authorization header of token. Tokens from 'fly
+// tokens create' carry their own FlyV1 scheme; others are bearer tokens.
+func authorization(token string) string {
+	if strings.HasPrefix(token, "FlyV1 ") {
+		return token
+	}
+	return "Bearer " + token
+}
+
+// machines sends a request to the Machines API for path (including any query
+// string).
+func (c *client) machines(ctx context.Context, method, path string, in, out interface{}) error {
+	return c.do(ctx, method, c.machinesEndpoint+path, in, out)
+}
+
+// graphql runs a GraphQL query or mutation and unmarshals its data into out,
+// which may be nil.
+func (c *client) graphql(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	body := map[string]interface{}{"query": query, "variables": variables}
+	if err := c.do(ctx, http.MethodPost, c.apiEndpoint+"/graphql", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("Fly.io GraphQL error: %s", strings.Join(messages, "; "))
+	}
+	if out == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("failed to decode GraphQL response: %w", err)
+	}
+	return nil
+}
+
+// do sends a request to u. in is marshalled as the JSON body and the response
+// is unmarshalled into out; either may be nil.
+func (c *client) do(ctx context.Context, method, u string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s %s request: %w", method, u, err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization(c.token))
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to Fly.io failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &apiError{StatusCode: resp.StatusCode}
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil && errBody.Error != "" {
+			apiErr.Message = errBody.Error
+		} else {
+			apiErr.Message = strings.TrimSpace(string(respBody))
+		}
+		return apiErr
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, u, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package fly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/apps/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"app not found"}`))
+		case "/graphql":
+			w.Write([]byte(`{"data":null,"errors":[{"message":"Could not find App"},{"message":"try again"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("upstream unavailable\n"))
+		}
+	}))
+	defer server.Close()
+	c := newClient("fly-token")
+	c.machinesEndpoint, c.apiEndpoint = server.URL, server.URL
+
+	err := c.machines(context.Background(), http.MethodGet, "/v1/apps/missing", nil, nil)
+	if !isNotFound(err) || err.Error() != "Fly.io API error (HTTP 404): app not found" {
+		t.Errorf("Expected a not found error, got: %v", err)
+	}
+	err = c.machines(context.Background(), http.MethodGet, "/v1/apps/my-app/machines", nil, nil)
+	if isNotFound(err) || err.Error() != "Fly.io API error (HTTP 502): upstream unavailable" {
+		t.Errorf("Expected the body of a non-JSON error, got: %v", err)
+	}
+	err = c.graphql(context.Background(), "query { viewer { id } }", nil, nil)
+	if err == nil || err.Error() != "Fly.io GraphQL error: Could not find App; try again" {
+		t.Errorf("Expected the GraphQL errors, got: %v", err)
+	}
+	if !isNotFound(fmt.Errorf("wrapped: %w", &apiError{StatusCode: http.StatusNotFound})) {
+		t.Error("Expected a wrapped not found error to be detected")
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	if got := authorization("fo1_abc"); got != "Bearer fo1_abc" {
+		t.Errorf("Expected a bearer token, got %q", got)
+	}
+	if got := authorization("FlyV1 fm2_abc"); got != "FlyV1 fm2_abc" {
+		t.Errorf("Expected a FlyV1 token to be sent as is, got %q", got)
+	}
+}
@@ -0,0 +1,736 @@
+// Package fly provides a Fly.io provider. It runs the manifest's image on
+// Fly Machines of an app named after the environment, with
+// instance.min_instances Machines in each of the manifest's regions behind
+// Fly's proxy, which serves the app on <environment>.fly.dev. Images are
+// pushed to the app's repository in the Fly.io registry and deployed by
+// digest, the manifest's secrets become app secrets, and each Machine keeps
+// the image it ran before so rolling back restores it. The provider calls
+// the Machines and GraphQL APIs directly with an API token, so cloud-deploy
+// needs no Fly.io SDK module.
+package fly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// defaultPort is the port Fly's proxy routes requests to when the manifest
+// declares none.
+const defaultPort = 8080
+
+// Metadata keys of the Machines the provider runs.
+const (
+	// managedKey marks the Machines cloud-deploy created; others are left alone
+	managedKey = "cloud_deploy"
+
+	// previousImageKey holds the image a Machine ran before its last deploy
+	previousImageKey = "cloud_deploy_previous_image"
+)
+
+// pollInterval is how often a Machine is checked while waiting for it.
+var pollInterval = 2 * time.Second
+
+// machineTimeout is how long to wait for a Machine to start and pass its
+// health checks.
+var machineTimeout = 5 * time.Minute
+
+// Provider implements the provider.Provider interface for Fly.io.
+type Provider struct {
+	org     string
+	regions []string
+	token   string
+	client  *client
+}
+
+// New creates a new Fly.io provider instance. The API token is loaded from a
+// secret store when credentials.source is vault or secrets-manager, from
+// credentials.fly.token, or else from $FLY_API_TOKEN, $FLY_ACCESS_TOKEN, or
+// flyctl's configuration. When provider.endpoint_url is set, both APIs are
+// reached at that emulator.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	token, err := loadToken(ctx, config, m)
+	if err != nil {
+		return nil, err
+	}
+	logging.Info("Initializing Fly.io provider")
+	client := newClient(token)
+	if config.Emulated() {
+		client.machinesEndpoint = strings.TrimRight(config.EndpointURL, "/")
+		client.apiEndpoint = client.machinesEndpoint
+	}
+	return &Provider{
+		org:     m.Fly.OrgSlug(),
+		regions: manifest.FlyRegions(m.Fly, config.Region),
+		token:   token,
+		client:  client,
+	}, nil
+}
+
+// loadToken returns the API token of the provider's credentials.
+func loadToken(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (string, error) {
+	creds := config.Credentials
+	if creds.FromSecretStore() {
+		logging.Infof("Loading Fly.io credentials from %s...", creds.Source)
+		stored, err := m.GetCloudCredentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to load Fly.io credentials from %s: %w", creds.Source, err)
+		}
+		if stored == nil || stored.Fly.Token == "" {
+			return "", fmt.Errorf("no Fly.io token found in %s", creds.Source)
+		}
+		return stored.Fly.Token, nil
+	}
+	if creds != nil && creds.Fly != nil && creds.Fly.Token != "" {
+		return creds.Fly.Token, nil
+	}
+	for _, name := range []string{"FLY_API_TOKEN", "FLY_ACCESS_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+	token, err := flyctlToken()
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("no Fly.io API token found; set FLY_API_TOKEN, credentials.fly.token, or run 'fly auth login'")
+	}
+	return token, nil
+}
+
+// flyctlConfigPath is where flyctl keeps its configuration.
+var flyctlConfigPath = func() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".fly", "config.yml")
+}
+
+// flyctlToken returns the token flyctl is logged in with, or "" when flyctl
+// is not configured.
+func flyctlToken() (string, error) {
+	path := flyctlConfigPath()
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read flyctl configuration: %w", err)
+	}
+	var config struct {
+		AccessToken string `yaml:"access_token"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse flyctl configuration %s: %w", path, err)
+	}
+	return config.AccessToken, nil
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "fly"
+}
+
+// Close closes the idle connections of the Machines and GraphQL API client.
+func (p *Provider) Close() error {
+	p.client.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// machine is a Fly Machine.
+type machine struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name,omitempty"`
+	State      string        `json:"state"`
+	Region     string        `json:"region"`
+	InstanceID string        `json:"instance_id"`
+	UpdatedAt  string        `json:"updated_at"`
+	Config     machineConfig `json:"config"`
+	Checks     []checkStatus `json:"checks,omitempty"`
+}
+
+// checkStatus is the last result of a Machine's health check.
+type checkStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Output string `json:"output"`
+}
+
+// healthy reports whether the Machine is started and passes its health
+// checks.
+func (m *machine) healthy() bool {
+	if m.State != "started" {
+		return false
+	}
+	for _, c := range m.Checks {
+		if c.Status != "passing" {
+			return false
+		}
+	}
+	return true
+}
+
+// machineConfig is the configuration of a Machine. The provider owns the
+// configuration of the Machines it creates and replaces it on every deploy.
+type machineConfig struct {
+	Image    string            `json:"image"`
+	Env      map[string]string `json:"env,omitempty"`
+	Guest    manifest.FlyGuest `json:"guest"`
+	Init     *initConfig       `json:"init,omitempty"`
+	Services []service         `json:"services,omitempty"`
+	Restart  *restartPolicy    `json:"restart,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// initConfig overrides the image's entrypoint and command.
+type initConfig struct {
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+}
+
+// service exposes a port of a Machine through Fly's proxy.
+type service struct {
+	Protocol     string        `json:"protocol"`
+	InternalPort int           `json:"internal_port"`
+	Ports        []servicePort `json:"ports"`
+	Checks       []check       `json:"checks,omitempty"`
+	Autostart    *bool         `json:"autostart,omitempty"`
+	Autostop     string        `json:"autostop,omitempty"`
+}
+
+// servicePort is a public port of a service.
+type servicePort struct {
+	Port       int      `json:"port"`
+	Handlers   []string `json:"handlers"`
+	ForceHTTPS bool     `json:"force_https,omitempty"`
+}
+
+// check is an HTTP health check of a service.
+type check struct {
+	Type        string `json:"type"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Interval    string `json:"interval"`
+	Timeout     string `json:"timeout"`
+	GracePeriod string `json:"grace_period"`
+}
+
+// restartPolicy is when Fly restarts a Machine's process after it exits.
+type restartPolicy struct {
+	Policy string `json:"policy"`
+}
+
+// Deploy creates the app the first time, with shared public IP addresses,
+// sets the manifest's secrets as app secrets, pushes the image to the app's
+// registry repository, and runs it on the app's Machines. Existing Machines
+// are updated one at a time, each waiting to start and pass its health
+// checks before the next, then missing Machines are created and those no
+// longer wanted are destroyed.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := registry.ValidatePlatform(ctx, m.Image, registry.LinuxAMD64, "Fly.io", ""); err != nil {
+		return nil, err
+	}
+	logging.Info("Starting Fly.io deployment...")
+	name := m.Environment.Name
+
+	// Step 1: Ensure the app exists
+	if err := p.ensureApp(ctx, name); err != nil {
+		return nil, err
+	}
+
+	// Step 2: Set the secrets, which Machines read when they are updated
+	if err := p.setSecrets(ctx, name, m); err != nil {
+		return nil, err
+	}
+
+	// Step 3: Push the image to the registry
+	logging.Info("=== Distributing image to the Fly.io registry ===")
+	image, err := pushImage(ctx, name, m.VersionLabel(fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405"))), p.token, m.Image, m.Provider.RegistryURL, m.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: Run the image on the Machines
+	machines, err := p.listMachines(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	_, count, _ := manifest.FlyMachineGuest(m.Instance)
+	pl := placeMachines(machines, p.regions, count)
+	config := buildConfig(m, image)
+	for _, mc := range pl.update {
+		updated := config
+		updated.Metadata = withPreviousImage(config.Metadata, mc.Config, image)
+		if err := p.updateMachine(ctx, name, mc, updated); err != nil {
+			return nil, err
+		}
+	}
+	for _, region := range pl.create {
+		if err := p.createMachine(ctx, name, region, config); err != nil {
+			return nil, err
+		}
+	}
+	for _, mc := range pl.remove {
+		logging.Infof("Destroying Machine %s in %s", mc.ID, mc.Region)
+		if err := p.client.machines(ctx, http.MethodDelete, machinePath(name, mc.ID)+"?force=true", nil, nil); err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("failed to destroy Machine %s: %w", mc.ID, err)
+		}
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             appURL(name),
+		Status:          "Running",
+		Message:         "Deployment successful",
+		Images:          map[string]string{m.Application.Name: image},
+	}, nil
+}
+
+// appURL returns the URL Fly's proxy serves an app on.
+func appURL(app string) string {
+	return "https://" + app + ".fly.dev"
+}
+
+// machinePath returns the Machines API path of a Machine of an app.
+func machinePath(app, id string) string {
+	return "/v1/apps/" + url.PathEscape(app) + "/machines/" + url.PathEscape(id)
+}
+
+// placement is how a deploy changes an app's Machines: the Machines to run
+// the new image on, the regions to create Machines in, and the Machines to
+// destroy.
+type placement struct {
+	update []machine
+	create []string
+	remove []machine
+}
+
+// placeMachines returns how to change the existing Machines to run count
+// Machines in each region. Machines in regions no longer listed, or beyond
+// the count of their region, are removed.
+func placeMachines(existing []machine, regions []string, count int) placement {
+	var pl placement
+	kept := make(map[string]int, len(regions))
+	for _, mc := range existing {
+		if slices.Contains(regions, mc.Region) && kept[mc.Region] < count {
+			kept[mc.Region]++
+			pl.update = append(pl.update, mc)
+		} else {
+			pl.remove = append(pl.remove, mc)
+		}
+	}
+	for _, region := range regions {
+		for i := kept[region]; i < count; i++ {
+			pl.create = append(pl.create, region)
+		}
+	}
+	return pl
+}
+
+// buildConfig returns the configuration of Machines running image with the
+// manifest's size, command, environment variables, and port, served over
+// HTTP and HTTPS with the manifest's health check. Secrets are left out of
+// the environment, as Machines read them from the app's secrets.
+func buildConfig(m *manifest.Manifest, image string) machineConfig {
+	c := m.GetPrimaryContainer()
+	guest, _, _ := manifest.FlyMachineGuest(m.Instance)
+	port := defaultPort
+	if len(c.Ports) > 0 {
+		port = c.Ports[0].ContainerPort
+	}
+	autostart := false
+	svc := service{
+		Protocol:     "tcp",
+		InternalPort: port,
+		Ports: []servicePort{
+			{Port: 80, Handlers: []string{"http"}, ForceHTTPS: true},
+			{Port: 443, Handlers: []string{"tls", "http"}},
+		},
+		// Stopped Machines stay stopped until the next deploy
+		Autostart: &autostart,
+		Autostop:  "off",
+	}
+	if m.HealthCheck.Path != "" {
+		svc.Checks = []check{{
+			Type:        "http",
+			Method:      "GET",
+			Path:        m.HealthCheck.Path,
+			Interval:    "15s",
+			Timeout:     "5s",
+			GracePeriod: "10s",
+		}}
+	}
+	if c.Workdir != "" {
+		logging.Warn("container.workdir is not supported by Fly Machines and will be ignored")
+	}
+
+	config := machineConfig{
+		Image:    image,
+		Guest:    guest,
+		Services: []service{svc},
+		Restart:  &restartPolicy{Policy: "always"},
+		Metadata: map[string]string{managedKey: "true"},
+	}
+	if len(c.Command) > 0 || len(c.Args) > 0 {
+		config.Init = &initConfig{Entrypoint: c.Command, Cmd: c.Args}
+	}
+	secrets := make(map[string]bool, len(m.Secrets))
+	for _, s := range m.Secrets {
+		secrets[s.Name] = true
+	}
+	for name, value := range c.Environment {
+		if secrets[name] {
+			continue
+		}
+		if config.Env == nil {
+			config.Env = make(map[string]string, len(c.Environment))
+		}
+		config.Env[name] = value
+	}
+	return config
+}
+
+// withPreviousImage returns metadata recording the image to roll back to for
+// a Machine with the current configuration that is changed to run image: the
+// image it runs, or the one it ran before when it already runs image.
+func withPreviousImage(metadata map[string]string, current machineConfig, image string) map[string]string {
+	out := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	previous := current.Image
+	if previous == image {
+		previous = current.Metadata[previousImageKey]
+	}
+	if previous != "" {
+		out[previousImageKey] = previous
+	} else {
+		delete(out, previousImageKey)
+	}
+	return out
+}
+
+// ensureApp creates the app in the organization if it does not exist and
+// allocates it shared public IP addresses, so Fly's proxy serves it on
+// <app>.fly.dev.
+func (p *Provider) ensureApp(ctx context.Context, name string) error {
+	exists, err := p.appExists(ctx, name)
+	if err != nil || exists {
+		return err
+	}
+	logging.Infof("Creating Fly.io app %s in organization %s", name, p.org)
+	body := map[string]string{"app_name": name, "org_slug": p.org}
+	if err := p.client.machines(ctx, http.MethodPost, "/v1/apps", body, nil); err != nil {
+		return fmt.Errorf("failed to create Fly.io app %s: %w", name, err)
+	}
+	for _, ipType := range []string{"shared_v4", "v6"} {
+		const mutation = `mutation($input: AllocateIPAddressInput!) { allocateIpAddress(input: $input) { ipAddress { address } } }`
+		input := map[string]interface{}{"input": map[string]string{"appId": name, "type": ipType}}
+		if err := p.client.graphql(ctx, mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to allocate a %s IP address to %s: %w", ipType, name, err)
+		}
+	}
+	return nil
+}
+
+// appExists reports whether the app exists.
+func (p *Provider) appExists(ctx context.Context, name string) (bool, error) {
+	err := p.client.machines(ctx, http.MethodGet, "/v1/apps/"+url.PathEscape(name), nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get Fly.io app %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// setSecrets sets the manifest's secrets, whose values are in the
+// environment, as app secrets. Other app secrets are kept.
+func (p *Provider) setSecrets(ctx context.Context, app string, m *manifest.Manifest) error {
+	if len(m.Secrets) == 0 {
+		return nil
+	}
+	env := m.GetPrimaryContainer().Environment
+	names := make([]string, 0, len(m.Secrets))
+	for _, s := range m.Secrets {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	secrets := make([]map[string]string, len(names))
+	for i, name := range names {
+		secrets[i] = map[string]string{"key": name, "value": env[name]}
+	}
+
+	logging.Infof("Setting %d app secrets", len(secrets))
+	const mutation = `mutation($input: SetSecretsInput!) { setSecrets(input: $input) { app { name } } }`
+	input := map[string]interface{}{"input": map[string]interface{}{"appId": app, "secrets": secrets, "replaceAll": false}}
+	if err := p.client.graphql(ctx, mutation, input, nil); err != nil {
+		return fmt.Errorf("failed to set secrets of %s: %w", app, err)
+	}
+	return nil
+}
+
+// listMachines returns the app's Machines created by cloud-deploy, oldest
+// first.
+func (p *Provider) listMachines(ctx context.Context, app string) ([]machine, error) {
+	var all []machine
+	if err := p.client.machines(ctx, http.MethodGet, "/v1/apps/"+url.PathEscape(app)+"/machines", nil, &all); err != nil {
+		return nil, fmt.Errorf("failed to list Machines of %s: %w", app, err)
+	}
+	var machines []machine
+	for _, mc := range all {
+		if mc.Config.Metadata[managedKey] == "true" && mc.State != "destroyed" && mc.State != "destroying" {
+			machines = append(machines, mc)
+		}
+	}
+	sort.SliceStable(machines, func(i, j int) bool { return machines[i].ID < machines[j].ID })
+	return machines, nil
+}
+
+// createMachine creates a Machine in region and waits for it to become
+// healthy.
+func (p *Provider) createMachine(ctx context.Context, app, region string, config machineConfig) error {
+	logging.Infof("Creating Machine in %s", region)
+	var created machine
+	body := map[string]interface{}{"region": region, "config": config}
+	if err := p.client.machines(ctx, http.MethodPost, "/v1/apps/"+url.PathEscape(app)+"/machines", body, &created); err != nil {
+		return fmt.Errorf("failed to create Machine in %s: %w", region, err)
+	}
+	return p.waitForMachine(ctx, app, created)
+}
+
+// updateMachine replaces the configuration of a Machine, which restarts it
+// (or starts it, when stopped), and waits for it to become healthy.
+func (p *Provider) updateMachine(ctx context.Context, app string, mc machine, config machineConfig) error {
+	logging.Infof("Updating Machine %s in %s", mc.ID, mc.Region)
+	var updated machine
+	if err := p.client.machines(ctx, http.MethodPost, machinePath(app, mc.ID), map[string]interface{}{"config": config}, &updated); err != nil {
+		return fmt.Errorf("failed to update Machine %s: %w", mc.ID, err)
+	}
+	return p.waitForMachine(ctx, app, updated)
+}
+
+// waitForMachine polls a Machine until its new instance is started and
+// passes its health checks, failing if it fails or is destroyed first.
+func (p *Provider) waitForMachine(ctx context.Context, app string, mc machine) error {
+	deadline := time.Now().Add(machineTimeout)
+	for {
+		var current machine
+		if err := p.client.machines(ctx, http.MethodGet, machinePath(app, mc.ID), nil, &current); err != nil {
+			return fmt.Errorf("failed to get Machine %s: %w", mc.ID, err)
+		}
+		switch {
+		case current.InstanceID == mc.InstanceID && current.healthy():
+			logging.Infof("Machine %s is healthy", mc.ID)
+			return nil
+		case current.State == "failed" || current.State == "destroyed":
+			return fmt.Errorf("Machine %s %s while starting", mc.ID, current.State)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for Machine %s to become healthy (state %s%s)", mc.ID, current.State, failingChecks(current.Checks))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// failingChecks describes the checks that do not pass, for errors.
+func failingChecks(checks []checkStatus) string {
+	out := ""
+	for _, c := range checks {
+		if c.Status != "passing" {
+			out += fmt.Sprintf(", check %s %s", c.Name, c.Status)
+		}
+	}
+	return out
+}
+
+// Destroy deletes the app with its Machines, IP addresses, secrets, and
+// registry repository.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	exists, err := p.appExists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		logging.Infof("Fly.io app %s does not exist; nothing to destroy", name)
+		return nil
+	}
+	logging.Infof("Deleting Fly.io app: %s", name)
+	if err := p.client.machines(ctx, http.MethodDelete, "/v1/apps/"+url.PathEscape(name), nil, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete Fly.io app %s: %w", name, err)
+	}
+	logging.Info("Fly.io app deleted successfully")
+	return nil
+}
+
+// Stop stops the app's Machines, which stops their billing for CPU and
+// memory while keeping them; running Deploy again starts them.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	machines, err := p.requireMachines(ctx, m)
+	if err != nil {
+		return err
+	}
+	for _, mc := range machines {
+		if mc.State != "started" && mc.State != "starting" {
+			continue
+		}
+		logging.Infof("Stopping Machine %s in %s", mc.ID, mc.Region)
+		if err := p.client.machines(ctx, http.MethodPost, machinePath(m.Environment.Name, mc.ID)+"/stop", nil, nil); err != nil {
+			return fmt.Errorf("failed to stop Machine %s: %w", mc.ID, err)
+		}
+	}
+	logging.Info("Fly.io Machines stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the app's Machines.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	machines, err := p.requireMachines(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	status, health := machinesStatus(machines)
+	lastUpdated := ""
+	for _, mc := range machines {
+		if mc.UpdatedAt > lastUpdated {
+			lastUpdated = mc.UpdatedAt
+		}
+	}
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             appURL(m.Environment.Name),
+		LastUpdated:     lastUpdated,
+	}, nil
+}
+
+// machinesStatus returns the status and health of an app from its Machines.
+func machinesStatus(machines []machine) (string, string) {
+	started, healthy, stopped := 0, 0, 0
+	for _, mc := range machines {
+		switch mc.State {
+		case "started":
+			started++
+			if mc.healthy() {
+				healthy++
+			}
+		case "stopped", "suspended":
+			stopped++
+		}
+	}
+	switch {
+	case stopped == len(machines):
+		return "Stopped", "Grey"
+	case started+stopped < len(machines):
+		return "Updating", "Yellow"
+	case healthy == len(machines):
+		return "Running", "Green"
+	case healthy == 0:
+		return "Unhealthy", "Red"
+	default:
+		return "Degraded", "Yellow"
+	}
+}
+
+// Rollback runs the image each Machine ran before its last deploy again, one
+// Machine at a time. The image it rolls back from becomes the one to roll
+// back to, so rolling back twice returns to where you started.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting Fly.io rollback...")
+	name := m.Environment.Name
+	machines, err := p.requireMachines(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	previous := ""
+	for _, mc := range machines {
+		if image := mc.Config.Metadata[previousImageKey]; image != "" {
+			previous = image
+			break
+		}
+	}
+	if previous == "" {
+		return nil, fmt.Errorf("no previous image recorded for Fly.io app %s; it has been deployed only once", name)
+	}
+
+	logging.Infof("Rolling back %s to %s", name, previous)
+	for _, mc := range machines {
+		config := mc.Config
+		config.Image = previous
+		config.Metadata = withPreviousImage(mc.Config.Metadata, mc.Config, previous)
+		if err := p.updateMachine(ctx, name, mc, config); err != nil {
+			return nil, err
+		}
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             appURL(name),
+		Status:          "Running",
+		Message:         "Rolled back to " + previous,
+		Images:          map[string]string{m.Application.Name: previous},
+	}, nil
+}
+
+// requireMachines returns the Machines of the environment's app, failing
+// when the app does not exist or runs none.
+func (p *Provider) requireMachines(ctx context.Context, m *manifest.Manifest) ([]machine, error) {
+	machines, err := p.listMachines(ctx, m.Environment.Name)
+	if isNotFound(err) {
+		return nil, fmt.Errorf("no Fly.io app found for environment %s", m.Environment.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(machines) == 0 {
+		return nil, fmt.Errorf("Fly.io app %s runs no Machines deployed by cloud-deploy", m.Environment.Name)
+	}
+	return machines, nil
+}
+
+// pushImage pushes image with tag to the app's repository in the Fly.io
+// registry, or in the registry at registryURL when it is set, and returns the
+// reference Machines run, pinned by digest.
+var pushImage = func(ctx context.Context, app, tag, token, image, registryURL string, platforms []string) (string, error) {
+	fly, err := registry.NewFlyRegistry(app, tag, token)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Fly.io registry handler: %w", err)
+	}
+	distributor := registry.NewDistributor(image, platforms...)
+	dest := registry.Local(fly, registryURL, app, tag)
+	distributor.AddRegistry(dest)
+	if _, err := distributor.Distribute(ctx); err != nil {
+		return "", fmt.Errorf("failed to distribute image: %w", err)
+	}
+	pinned := registry.PinDigest(dest.GetImageURI(), distributor.Digest())
+	logging.Infof("Image pushed: %s", pinned)
+	return pinned, nil
+}
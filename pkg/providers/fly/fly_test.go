@@ -0,0 +1,462 @@
+package fly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeAPI serves the subset of the Machines and GraphQL APIs the provider
+// uses for one organization. Machines are started and healthy as soon as
+// they are created or updated, unless failState is set.
+type fakeAPI struct {
+	mu        sync.Mutex
+	apps      map[string]bool
+	machines  map[string]*machine
+	nextID    int
+	failState string
+	graphql   []string
+	secrets   []interface{}
+	requests  []string
+
+	server *httptest.Server
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, *Provider) {
+	t.Helper()
+	f := &fakeAPI{apps: make(map[string]bool), machines: make(map[string]*machine)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+
+	oldInterval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldInterval })
+
+	c := newClient("fly-token")
+	c.machinesEndpoint, c.apiEndpoint = f.server.URL, f.server.URL
+	return f, &Provider{org: "personal", regions: []string{"iad", "lhr"}, token: "fly-token", client: c}
+}
+
+// addMachine adds a started Machine deployed by cloud-deploy.
+func (f *fakeAPI) addMachine(region, image, previous string) *machine {
+	f.nextID++
+	mc := &machine{
+		ID:         fmt.Sprintf("m%d", f.nextID),
+		State:      "started",
+		Region:     region,
+		InstanceID: "i0",
+		UpdatedAt:  fmt.Sprintf("2026-01-0%dT00:00:00Z", f.nextID),
+		Config:     machineConfig{Image: image, Metadata: map[string]string{managedKey: "true"}},
+	}
+	if previous != "" {
+		mc.Config.Metadata[previousImageKey] = previous
+	}
+	f.machines[mc.ID] = mc
+	return mc
+}
+
+// start gives a Machine a new instance that is started or in failState.
+func (f *fakeAPI) start(mc *machine) {
+	mc.InstanceID = fmt.Sprintf("%s-i%d", mc.ID, len(f.requests))
+	mc.State = "started"
+	if f.failState != "" {
+		mc.State = f.failState
+	}
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer fly-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"unauthorized"}`))
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	reply := func(v interface{}) { json.NewEncoder(w).Encode(v) }
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}
+
+	if r.URL.Path == "/graphql" {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		input := body.Variables["input"].(map[string]interface{})
+		switch {
+		case strings.Contains(body.Query, "allocateIpAddress"):
+			f.graphql = append(f.graphql, "allocate "+input["type"].(string))
+		case strings.Contains(body.Query, "setSecrets"):
+			f.graphql = append(f.graphql, "setSecrets")
+			f.secrets = input["secrets"].([]interface{})
+		}
+		reply(map[string]interface{}{"data": map[string]interface{}{}})
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/v1/apps/my-app-prod/logs") {
+		if r.URL.Query().Get("next_token") == "" {
+			w.Write([]byte(`{"data":[{"id":"l1","attributes":{"timestamp":"2026-01-02T03:04:05Z","message":"listening on :8080\n","region":"iad","instance":"m1"}},` +
+				`{"id":"l0","attributes":{"timestamp":"2020-01-01T00:00:00Z","message":"old","region":"iad","instance":"m1"}}],"meta":{"next_token":"t1"}}`))
+		} else {
+			w.Write([]byte(`{"data":[],"meta":{"next_token":"t1"}}`))
+		}
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/apps"), "/")
+	switch {
+	case r.URL.Path == "/v1/apps" && r.Method == http.MethodPost:
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		f.apps[body["app_name"]] = true
+		w.WriteHeader(http.StatusCreated)
+	case len(parts) == 2:
+		if !f.apps[parts[1]] {
+			notFound()
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.apps, parts[1])
+			f.machines = make(map[string]*machine)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		reply(map[string]string{"name": parts[1]})
+	case len(parts) == 3 && parts[2] == "machines":
+		if !f.apps[parts[1]] {
+			notFound()
+			return
+		}
+		if r.Method == http.MethodPost {
+			var body struct {
+				Region string        `json:"region"`
+				Config machineConfig `json:"config"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			mc := f.addMachine(body.Region, "", "")
+			mc.Config = body.Config
+			// New Machines are created, and start with the same instance
+			f.start(mc)
+			state := mc.State
+			mc.State = "created"
+			reply(mc)
+			mc.State = state
+			return
+		}
+		list := []*machine{}
+		for _, mc := range f.machines {
+			list = append(list, mc)
+		}
+		// An unmanaged Machine, such as one started by 'fly console'
+		list = append(list, &machine{ID: "console", State: "started", Region: "iad"})
+		reply(list)
+	case len(parts) >= 4 && parts[2] == "machines":
+		mc, ok := f.machines[parts[3]]
+		if !ok {
+			notFound()
+			return
+		}
+		switch {
+		case len(parts) == 5 && parts[4] == "stop":
+			mc.State = "stopped"
+			reply(map[string]bool{"ok": true})
+		case r.Method == http.MethodDelete:
+			delete(f.machines, mc.ID)
+			reply(map[string]bool{"ok": true})
+		case r.Method == http.MethodPost:
+			var body struct {
+				Config machineConfig `json:"config"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			mc.Config = body.Config
+			f.start(mc)
+			reply(mc)
+		default:
+			reply(mc)
+		}
+	default:
+		notFound()
+	}
+}
+
+func stubPush(t *testing.T) *[]string {
+	t.Helper()
+	var pushed []string
+	old := pushImage
+	pushImage = func(ctx context.Context, app, tag, token, image, registryURL string, platforms []string) (string, error) {
+		pushed = append(pushed, image)
+		return "registry.fly.io/" + app + ":deploy-1@sha256:new", nil
+	}
+	t.Cleanup(func() { pushImage = old })
+	return &pushed
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:       "my-app:1.0.0",
+		Provider:    manifest.ProviderConfig{Name: "fly"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Instance:    manifest.InstanceConfig{Type: "shared-cpu-2x"},
+		HealthCheck: manifest.HealthCheckConfig{Path: "/health"},
+		Ports:       []manifest.PortMapping{{ContainerPort: 3000}},
+		EnvironmentVariables: map[string]string{
+			"LOG_LEVEL":    "info",
+			"DATABASE_URL": "postgres://secret",
+		},
+		Secrets: []manifest.SecretRef{{Name: "DATABASE_URL", SecretID: "db"}},
+	}
+}
+
+func TestDeployCreatesApp(t *testing.T) {
+	f, p := newFakeAPI(t)
+	pushed := stubPush(t)
+
+	result, err := p.Deploy(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if result.URL != "https://my-app-prod.fly.dev" || result.Images["my-app"] != "registry.fly.io/my-app-prod:deploy-1@sha256:new" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(*pushed) != 1 || !f.apps["my-app-prod"] {
+		t.Errorf("Expected the app to be created and the image pushed, got %v, %v", f.apps, *pushed)
+	}
+	if strings.Join(f.graphql, ", ") != "allocate shared_v4, allocate v6, setSecrets" {
+		t.Errorf("Unexpected GraphQL calls: %v", f.graphql)
+	}
+	if len(f.secrets) != 1 || f.secrets[0].(map[string]interface{})["value"] != "postgres://secret" {
+		t.Errorf("Expected DATABASE_URL to be set as a secret, got %v", f.secrets)
+	}
+
+	regions := map[string]int{}
+	for _, mc := range f.machines {
+		regions[mc.Region]++
+		c := mc.Config
+		if c.Env["LOG_LEVEL"] != "info" || c.Env["DATABASE_URL"] != "" {
+			t.Errorf("Expected the secret to be left out of the environment, got %v", c.Env)
+		}
+		if c.Guest != (manifest.FlyGuest{CPUKind: "shared", CPUs: 2, MemoryMB: 512}) || c.Services[0].InternalPort != 3000 || c.Services[0].Checks[0].Path != "/health" {
+			t.Errorf("Unexpected Machine config: %+v", c)
+		}
+	}
+	if regions["iad"] != 1 || regions["lhr"] != 1 {
+		t.Errorf("Expected a Machine in each region, got %v", regions)
+	}
+}
+
+func TestDeployUpdatesMachines(t *testing.T) {
+	f, p := newFakeAPI(t)
+	stubPush(t)
+	f.apps["my-app-prod"] = true
+	f.addMachine("iad", "registry.fly.io/my-app-prod@sha256:old", "")
+	f.addMachine("syd", "registry.fly.io/my-app-prod@sha256:old", "")
+
+	if _, err := p.Deploy(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if len(f.graphql) != 1 {
+		t.Errorf("Expected only the secrets to be set for an existing app, got %v", f.graphql)
+	}
+	m1 := f.machines["m1"]
+	if m1 == nil || m1.Config.Image != "registry.fly.io/my-app-prod:deploy-1@sha256:new" || m1.Config.Metadata[previousImageKey] != "registry.fly.io/my-app-prod@sha256:old" {
+		t.Errorf("Expected m1 to run the new image and remember the old one, got %+v", m1)
+	}
+	if _, ok := f.machines["m2"]; ok {
+		t.Error("Expected the Machine in syd, which is not a region anymore, to be destroyed")
+	}
+	if len(f.machines) != 2 {
+		t.Errorf("Expected a Machine to be created in lhr, got %d Machines", len(f.machines))
+	}
+}
+
+func TestDeployUnhealthyMachine(t *testing.T) {
+	f, p := newFakeAPI(t)
+	stubPush(t)
+	f.apps["my-app-prod"] = true
+	f.addMachine("iad", "old", "")
+	f.failState = "failed"
+
+	_, err := p.Deploy(context.Background(), testManifest())
+	if err == nil || !strings.Contains(err.Error(), "Machine m1 failed while starting") {
+		t.Errorf("Expected the failed Machine to stop the deploy, got: %v", err)
+	}
+	for _, r := range f.requests {
+		if r == "POST /v1/apps/my-app-prod/machines" {
+			t.Error("Expected no Machine to be created after a failed update")
+		}
+	}
+}
+
+func TestPlaceMachines(t *testing.T) {
+	existing := []machine{{ID: "a", Region: "iad"}, {ID: "b", Region: "iad"}, {ID: "c", Region: "iad"}, {ID: "d", Region: "ams"}}
+	pl := placeMachines(existing, []string{"iad", "lhr"}, 2)
+	ids := func(ms []machine) string {
+		var out []string
+		for _, m := range ms {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	if ids(pl.update) != "a,b" || ids(pl.remove) != "c,d" || strings.Join(pl.create, ",") != "lhr,lhr" {
+		t.Errorf("Unexpected placement: update %s, remove %s, create %v", ids(pl.update), ids(pl.remove), pl.create)
+	}
+}
+
+func TestWithPreviousImage(t *testing.T) {
+	current := machineConfig{Image: "v2", Metadata: map[string]string{previousImageKey: "v1"}}
+	if got := withPreviousImage(nil, current, "v3")[previousImageKey]; got != "v2" {
+		t.Errorf("Expected the running image to become the previous one, got %q", got)
+	}
+	if got := withPreviousImage(nil, current, "v2")[previousImageKey]; got != "v1" {
+		t.Errorf("Expected a redeploy of the same image to keep the previous one, got %q", got)
+	}
+	if _, ok := withPreviousImage(map[string]string{previousImageKey: "x"}, machineConfig{}, "v1")[previousImageKey]; ok {
+		t.Error("Expected no previous image for a new Machine")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	f, p := newFakeAPI(t)
+	f.apps["my-app-prod"] = true
+	f.addMachine("iad", "v2", "v1")
+	f.addMachine("lhr", "v2", "v1")
+
+	result, err := p.Rollback(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.Images["my-app"] != "v1" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	for _, mc := range f.machines {
+		if mc.Config.Image != "v1" || mc.Config.Metadata[previousImageKey] != "v2" {
+			t.Errorf("Expected %s to run v1 and roll back to v2, got %+v", mc.ID, mc.Config)
+		}
+	}
+
+	f.machines = make(map[string]*machine)
+	f.addMachine("iad", "v1", "")
+	if _, err := p.Rollback(context.Background(), testManifest()); err == nil || !strings.Contains(err.Error(), "deployed only once") {
+		t.Errorf("Expected an error without a previous image, got: %v", err)
+	}
+}
+
+func TestStopAndStatus(t *testing.T) {
+	f, p := newFakeAPI(t)
+	f.apps["my-app-prod"] = true
+	f.addMachine("iad", "v1", "")
+	f.addMachine("lhr", "v1", "")
+	f.machines["m2"].Checks = []checkStatus{{Name: "servicecheck-00-http-3000", Status: "critical"}}
+
+	status, err := p.Status(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Degraded" || status.Health != "Yellow" || status.LastUpdated != "2026-01-02T00:00:00Z" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if err := p.Stop(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	status, _ = p.Status(context.Background(), testManifest())
+	if status.Status != "Stopped" || status.Health != "Grey" {
+		t.Errorf("Expected the Machines to be stopped, got %+v", status)
+	}
+
+	m := testManifest()
+	m.Environment.Name = "missing"
+	if _, err := p.Status(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no Fly.io app found") {
+		t.Errorf("Expected a missing app error, got: %v", err)
+	}
+}
+
+func TestMachinesStatus(t *testing.T) {
+	tests := []struct {
+		states []string
+		status string
+	}{
+		{[]string{"started", "started"}, "Running"},
+		{[]string{"started", "replacing"}, "Updating"},
+		{[]string{"stopped", "suspended"}, "Stopped"},
+	}
+	for _, tt := range tests {
+		var machines []machine
+		for _, s := range tt.states {
+			machines = append(machines, machine{State: s})
+		}
+		if status, _ := machinesStatus(machines); status != tt.status {
+			t.Errorf("machinesStatus(%v) = %s, want %s", tt.states, status, tt.status)
+		}
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	f, p := newFakeAPI(t)
+	f.apps["my-app-prod"] = true
+	if err := p.Destroy(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if f.apps["my-app-prod"] {
+		t.Error("Expected the app to be deleted")
+	}
+	// Destroying a missing app is not an error
+	if err := p.Destroy(context.Background(), testManifest()); err != nil {
+		t.Errorf("Expected no error for a missing app, got: %v", err)
+	}
+}
+
+func TestNewEmulator(t *testing.T) {
+	t.Setenv("FLY_API_TOKEN", "test")
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "fly", Region: "iad", EndpointURL: "http://localhost:4280"}}
+	p, err := New(context.Background(), &m.Provider, m)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.client.machinesEndpoint != "http://localhost:4280" || p.client.apiEndpoint != "http://localhost:4280" {
+		t.Errorf("endpoints = %q, %q; want the emulator", p.client.machinesEndpoint, p.client.apiEndpoint)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	dir := t.TempDir()
+	old := flyctlConfigPath
+	flyctlConfigPath = func() string { return filepath.Join(dir, "config.yml") }
+	t.Cleanup(func() { flyctlConfigPath = old })
+	t.Setenv("FLY_API_TOKEN", "")
+	t.Setenv("FLY_ACCESS_TOKEN", "")
+	ctx := context.Background()
+
+	if _, err := loadToken(ctx, &manifest.ProviderConfig{}, &manifest.Manifest{}); err == nil || !strings.Contains(err.Error(), "FLY_API_TOKEN") {
+		t.Errorf("Expected a missing token error, got: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "config.yml"), []byte("access_token: flyctl-token\n"), 0o600)
+	if token, err := loadToken(ctx, &manifest.ProviderConfig{}, &manifest.Manifest{}); err != nil || token != "flyctl-token" {
+		t.Errorf("Expected flyctl's token, got %q, %v", token, err)
+	}
+
+	t.Setenv("FLY_API_TOKEN", "env-token")
+	if token, _ := loadToken(ctx, &manifest.ProviderConfig{}, &manifest.Manifest{}); token != "env-token" {
+		t.Errorf("Expected $FLY_API_TOKEN, got %q", token)
+	}
+
+	config := &manifest.ProviderConfig{Credentials: &manifest.CredentialsConfig{Source: "manifest", Fly: &manifest.FlyCredentialsConfig{Token: "manifest-token"}}}
+	if token, _ := loadToken(ctx, config, &manifest.Manifest{}); token != "manifest-token" {
+		t.Errorf("Expected the manifest's token, got %q", token)
+	}
+}
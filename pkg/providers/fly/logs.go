@@ -0,0 +1,84 @@
+package fly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// maxLogPages limits how many pages of logs one read fetches.
+const maxLogPages = 20
+
+// logPage is a page of an app's logs.
+type logPage struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Timestamp string `json:"timestamp"`
+			Message   string `json:"message"`
+			Level     string `json:"level"`
+			Instance  string `json:"instance"`
+			Region    string `json:"region"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		NextToken string `json:"next_token"`
+	} `json:"meta"`
+}
+
+// Logs prints the output of the app's Machines, which Fly.io keeps for a
+// short time, labeled with each Machine's region and instance.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	app := m.Environment.Name
+	exists, err := p.appExists(ctx, app)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no Fly.io app found for environment %s", app)
+	}
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var entries []types.LogEntry
+		token := ""
+		for page := 0; page < maxLogPages; page++ {
+			var out logPage
+			u := fmt.Sprintf("%s/api/v1/apps/%s/logs?%s", p.client.apiEndpoint, url.PathEscape(app), url.Values{"next_token": {token}}.Encode())
+			if err := p.client.do(ctx, http.MethodGet, u, nil, &out); err != nil {
+				return nil, fmt.Errorf("failed to get logs of %s: %w", app, err)
+			}
+			entries = append(entries, parseLogs(out, since)...)
+			if len(out.Data) == 0 || out.Meta.NextToken == "" || out.Meta.NextToken == token {
+				break
+			}
+			token = out.Meta.NextToken
+		}
+		return entries, nil
+	})
+}
+
+// parseLogs converts a page of logs into entries written at or after since.
+// Entries without a valid timestamp are skipped.
+func parseLogs(page logPage, since time.Time) []types.LogEntry {
+	var entries []types.LogEntry
+	for _, d := range page.Data {
+		ts, err := time.Parse(time.RFC3339Nano, d.Attributes.Timestamp)
+		if err != nil || ts.Before(since) {
+			continue
+		}
+		entries = append(entries, types.LogEntry{
+			ID:        d.ID,
+			Timestamp: ts,
+			Source:    d.Attributes.Region + "/" + d.Attributes.Instance,
+			Message:   strings.TrimRight(d.Attributes.Message, "\n"),
+		})
+	}
+	return entries
+}
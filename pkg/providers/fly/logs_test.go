@@ -0,0 +1,34 @@
+package fly
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	f, p := newFakeAPI(t)
+	f.apps["my-app-prod"] = true
+
+	var out bytes.Buffer
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := p.Logs(context.Background(), testManifest(), types.LogOptions{Since: time.Since(since), Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "listening on :8080") || strings.Contains(out.String(), "old") {
+		t.Errorf("Expected only the recent entry, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "iad/m1") {
+		t.Errorf("Expected the entry to name its region and instance, got:\n%s", out.String())
+	}
+
+	m := testManifest()
+	m.Environment.Name = "missing"
+	if err := p.Logs(context.Background(), m, types.LogOptions{Output: &out}); err == nil || !strings.Contains(err.Error(), "no Fly.io app found") {
+		t.Errorf("Expected a missing app error, got: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package fly
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing anything in the account. Every deployment pushes the image and
+// runs it on each Machine.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "fly"}
+	name := m.Environment.Name
+	exists, err := p.appExists(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var machines []machine
+	if exists {
+		plan.Add(types.PlanNoChange, "Fly.io app", name, "")
+		if machines, err = p.listMachines(ctx, name); err != nil {
+			return nil, err
+		}
+	} else {
+		plan.Add(types.PlanCreate, "Fly.io app", name, "in organization "+p.org+", with shared IPv4 and IPv6 addresses")
+	}
+	if len(m.Secrets) > 0 {
+		plan.Add(types.PlanUpdate, "App secrets", name, fmt.Sprintf("set %d secrets", len(m.Secrets)))
+	}
+	plan.Add(types.PlanCreate, "Container image", registry.FlyHost+"/"+name, "push "+m.Image)
+
+	guest, count, _ := manifest.FlyMachineGuest(m.Instance)
+	size := fmt.Sprintf("%s, %d CPU, %d MB", guest.CPUKind, guest.CPUs, guest.MemoryMB)
+	pl := placeMachines(machines, p.regions, count)
+	for _, mc := range pl.update {
+		plan.Add(types.PlanUpdate, "Machine", mc.ID+" ("+mc.Region+")", "run "+m.Image)
+	}
+	for _, region := range pl.create {
+		plan.Add(types.PlanCreate, "Machine", region, size+", running "+m.Image)
+	}
+	for _, mc := range pl.remove {
+		plan.Add(types.PlanDelete, "Machine", mc.ID+" ("+mc.Region+")", "not in fly.regions or beyond instance.min_instances")
+	}
+	return plan, nil
+}
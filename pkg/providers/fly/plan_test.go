@@ -0,0 +1,40 @@
+package fly
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	f, p := newFakeAPI(t)
+
+	plan, err := p.Plan(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Changes) != 5 || plan.Changes[0].Action != types.PlanCreate || plan.Changes[0].Type != "Fly.io app" {
+		t.Errorf("Expected the app, secrets, image, and two Machines, got %+v", plan.Changes)
+	}
+
+	f.apps["my-app-prod"] = true
+	f.addMachine("iad", "v1", "")
+	f.addMachine("ams", "v1", "")
+	plan, err = p.Plan(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	actions := map[types.PlanAction]int{}
+	for _, c := range plan.Changes {
+		if c.Type == "Machine" {
+			actions[c.Action]++
+		}
+	}
+	if actions[types.PlanUpdate] != 1 || actions[types.PlanCreate] != 1 || actions[types.PlanDelete] != 1 {
+		t.Errorf("Expected a Machine to be updated, created, and deleted, got %+v", plan.Changes)
+	}
+	if len(f.machines) != 2 {
+		t.Error("Expected Plan to change nothing")
+	}
+}
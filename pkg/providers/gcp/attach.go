@@ -0,0 +1,90 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"github.com/googleapis/gax-go/v2"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Kinds of journaled Cloud Run operations, which are identified by their
+// operation names.
+const (
+	operationServiceCreate = "service-create"
+	operationServiceUpdate = "service-update"
+	operationServiceDelete = "service-delete"
+)
+
+// runOperation is a Cloud Run long-running operation on a service.
+type runOperation interface {
+	Name() string
+	Wait(ctx context.Context, opts ...gax.CallOption) (*runpb.Service, error)
+}
+
+// SetOperationJournal makes the provider journal the Cloud Run operations it
+// waits for.
+func (p *Provider) SetOperationJournal(journal types.OperationJournal) {
+	p.journal = journal
+}
+
+// Attach resumes a Cloud Run operation started by another invocation by its
+// name, waits for it to finish, and ends it in the journal.
+func (p *Provider) Attach(ctx context.Context, m *manifest.Manifest, op types.Operation) (*types.DeploymentResult, error) {
+	var resumed runOperation
+	switch op.Kind {
+	case operationServiceCreate:
+		resumed = p.runClient.CreateServiceOperation(op.ID)
+	case operationServiceUpdate:
+		resumed = p.runClient.UpdateServiceOperation(op.ID)
+	case operationServiceDelete:
+		resumed = p.runClient.DeleteServiceOperation(op.ID)
+	default:
+		return nil, fmt.Errorf("cannot attach to %s operations", op.Kind)
+	}
+
+	logging.Infof("Attaching to Cloud Run operation %s", op.ID)
+	if err := p.waitForOperation(ctx, op.Kind, resumed); err != nil {
+		return nil, fmt.Errorf("operation %s failed: %w", op.ID, err)
+	}
+	result := &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          "Deleted",
+		Message:         "Attached to the service deletion",
+	}
+	if op.Kind == operationServiceDelete {
+		return result, nil
+	}
+
+	url, err := p.waitForService(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, fmt.Errorf("service deployment failed for %s: %w", m.Environment.Name, err)
+	}
+	result.URL = url
+	result.Status = "Ready"
+	result.Message = "Attached to the service deployment"
+	return result, nil
+}
+
+// waitForOperation journals op as the operation in progress and waits for it
+// to finish. The operation stays in the journal if ctx ends first, since
+// Cloud Run carries on without us.
+func (p *Provider) waitForOperation(ctx context.Context, kind string, op runOperation) error {
+	if p.journal != nil {
+		if err := p.journal.Begin(ctx, types.Operation{Provider: "gcp", Kind: kind, ID: op.Name()}); err != nil {
+			logging.Warnf("Failed to journal Cloud Run operation %s: %v", op.Name(), err)
+		}
+	}
+	_, err := op.Wait(ctx)
+	if p.journal != nil && ctx.Err() == nil {
+		if err := p.journal.End(ctx); err != nil {
+			logging.Warnf("Failed to end Cloud Run operation %s in the journal: %v", op.Name(), err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"github.com/googleapis/gax-go/v2"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// fakeJournal records the operations begun and ended.
+type fakeJournal struct {
+	begun []types.Operation
+	ended int
+}
+
+func (j *fakeJournal) Begin(ctx context.Context, op types.Operation) error {
+	j.begun = append(j.begun, op)
+	return nil
+}
+
+func (j *fakeJournal) End(ctx context.Context) error {
+	j.ended++
+	return nil
+}
+
+// fakeRunOperation is a Cloud Run operation that finishes with err.
+type fakeRunOperation struct {
+	err error
+}
+
+func (fakeRunOperation) Name() string {
+	return "projects/my-project/locations/us-central1/operations/op-1"
+}
+
+func (o fakeRunOperation) Wait(ctx context.Context, opts ...gax.CallOption) (*runpb.Service, error) {
+	return &runpb.Service{}, o.err
+}
+
+func TestWaitForOperation(t *testing.T) {
+	journal := &fakeJournal{}
+	p := &Provider{}
+	p.SetOperationJournal(journal)
+
+	if err := p.waitForOperation(context.Background(), operationServiceUpdate, fakeRunOperation{}); err != nil {
+		t.Fatalf("waitForOperation failed: %v", err)
+	}
+	if len(journal.begun) != 1 || journal.begun[0].Kind != operationServiceUpdate || journal.begun[0].ID != "projects/my-project/locations/us-central1/operations/op-1" || journal.ended != 1 {
+		t.Errorf("Expected the operation to be begun and ended, got %+v, %d", journal.begun, journal.ended)
+	}
+
+	if err := p.waitForOperation(context.Background(), operationServiceUpdate, fakeRunOperation{err: errors.New("revision failed")}); err == nil {
+		t.Error("Expected the operation's error")
+	}
+	if journal.ended != 2 {
+		t.Error("Expected a failed operation to be ended")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.waitForOperation(ctx, operationServiceUpdate, fakeRunOperation{err: context.Canceled})
+	if journal.ended != 2 {
+		t.Error("Expected an operation still running when the context ended to stay in the journal")
+	}
+
+	// Without a journal, operations are only waited for
+	if err := (&Provider{}).waitForOperation(context.Background(), operationServiceCreate, fakeRunOperation{}); err != nil {
+		t.Errorf("waitForOperation without a journal failed: %v", err)
+	}
+}
+
+func TestAttachUnknownKind(t *testing.T) {
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-app-prod"}}
+	if _, err := (&Provider{}).Attach(context.Background(), m, types.Operation{Kind: "environment-update"}); err == nil || !strings.Contains(err.Error(), "cannot attach to environment-update operations") {
+		t.Errorf("Expected an error for another provider's operation, got: %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// canaryTag tags the traffic target of a canary revision, which gives it its
+// own URL for health checks.
+const canaryTag = "canary"
+
+// canaryFailureThreshold is how many health checks of a canary revision must
+// fail in a row to roll it back.
+const canaryFailureThreshold = 3
+
+// canaryCheckPeriod is how often a canary revision's health is checked.
+const canaryCheckPeriod = 10 * time.Second
+
+// updateService updates the existing Cloud Run service to service's revision
+// template. With deployment.strategy canary, traffic shifts to the new
+// revision in steps; otherwise the new revision takes all traffic at once.
+func (p *Provider) updateService(ctx context.Context, m *manifest.Manifest, service, existing *runpb.Service) error {
+	if m.Deployment.IsCanary() {
+		if existing.LatestReadyRevision != "" {
+			return p.deployCanary(ctx, m, service, existing)
+		}
+		logging.Info("No revision serves traffic yet, deploying without a canary")
+	}
+
+	op, err := p.runClient.UpdateService(ctx, &runpb.UpdateServiceRequest{Service: service})
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	if err := p.waitForOperation(ctx, operationServiceUpdate, op); err != nil {
+		return fmt.Errorf("failed to wait for service update: %w", err)
+	}
+	return nil
+}
+
+// deployCanary creates the new revision of service with the first canary
+// step's share of traffic, and promotes it through the following steps while
+// it stays healthy for deployment.canary.interval_seconds at each. A revision
+// that fails sends all traffic back to the revisions that served it before.
+func (p *Provider) deployCanary(ctx context.Context, m *manifest.Manifest, service, existing *runpb.Service) error {
+	steps := m.Deployment.CanarySteps()
+	stable := stableTraffic(existing.Traffic, revisionID(existing.LatestReadyRevision))
+
+	service.Traffic = canaryTraffic(stable, "", steps[0])
+	logging.Infof("Creating canary revision with %d%% of traffic", steps[0])
+	op, err := p.runClient.UpdateService(ctx, &runpb.UpdateServiceRequest{Service: service})
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	if err := p.waitForOperation(ctx, operationServiceUpdate, op); err != nil {
+		return fmt.Errorf("failed to wait for service update: %w", err)
+	}
+
+	current, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: service.Name})
+	if err != nil {
+		return fmt.Errorf("failed to get service: %w", err)
+	}
+	canary := revisionID(current.LatestCreatedRevision)
+
+	for i, percent := range steps {
+		if i > 0 {
+			logging.Infof("Promoting canary revision %s to %d%% of traffic", canary, percent)
+			if current, err = p.setTraffic(ctx, service.Name, canaryTraffic(stable, canary, percent)); err != nil {
+				return p.abandonCanary(ctx, service.Name, canary, stable, err)
+			}
+		}
+		if percent == 100 {
+			break
+		}
+		logging.Infof("Watching canary revision %s at %d%% of traffic for %s", canary, percent, m.Deployment.CanaryInterval())
+		if err := p.watchCanary(ctx, m, current.LatestCreatedRevision, taggedURL(current, canaryTag)); err != nil {
+			return p.abandonCanary(ctx, service.Name, canary, stable, fmt.Errorf("canary failed at %d%% of traffic: %w", percent, err))
+		}
+	}
+	logging.Infof("Canary revision %s promoted to all traffic", canary)
+	return nil
+}
+
+// setTraffic sends the service's traffic to the given targets and returns the
+// updated service.
+func (p *Provider) setTraffic(ctx context.Context, serviceName string, traffic []*runpb.TrafficTarget) (*runpb.Service, error) {
+	service, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	service.Traffic = traffic
+	op, err := p.runClient.UpdateService(ctx, &runpb.UpdateServiceRequest{Service: service})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update traffic: %w", err)
+	}
+	if err := p.waitForOperation(ctx, operationServiceUpdate, op); err != nil {
+		return nil, fmt.Errorf("failed to wait for traffic update: %w", err)
+	}
+	return p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+}
+
+// abandonCanary sends all traffic back to the stable revisions and returns
+// the canary's failure.
+func (p *Provider) abandonCanary(ctx context.Context, serviceName, canary string, stable []*runpb.TrafficTarget, cause error) error {
+	logging.Warnf("Canary revision %s failed, restoring traffic to the previous revisions: %v", canary, cause)
+	if _, err := p.setTraffic(ctx, serviceName, stable); err != nil {
+		return fmt.Errorf("canary revision %s failed (%v), and restoring traffic failed: %w", canary, cause, err)
+	}
+	return fmt.Errorf("canary revision %s failed, traffic restored to the previous revisions: %w", canary, cause)
+}
+
+// watchCanary checks the canary revision's health every canaryCheckPeriod
+// for deployment.canary.interval_seconds. The revision fails if Cloud Run
+// reports it failed, or, when the service is public and health_check.path is
+// set, if canaryFailureThreshold checks of the path on its tagged URL fail in
+// a row.
+func (p *Provider) watchCanary(ctx context.Context, m *manifest.Manifest, revisionName, url string) error {
+	ticker := time.NewTicker(canaryCheckPeriod)
+	defer ticker.Stop()
+
+	deadline := time.After(m.Deployment.CanaryInterval())
+	probe := p.publicAccess && m.HealthCheck.Path != "" && url != ""
+	failures := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return nil
+		case <-ticker.C:
+		}
+
+		revision, err := p.revisionsClient.GetRevision(ctx, &runpb.GetRevisionRequest{Name: revisionName})
+		if err != nil {
+			return fmt.Errorf("failed to get revision: %w", err)
+		}
+		if err := revisionError(revision); err != nil {
+			return err
+		}
+		if !probe {
+			continue
+		}
+		if err := probeCanary(ctx, strings.TrimSuffix(url, "/")+m.HealthCheck.Path); err != nil {
+			failures++
+			logging.Warnf("Canary health check failed (%d of %d): %v", failures, canaryFailureThreshold, err)
+			if failures >= canaryFailureThreshold {
+				return fmt.Errorf("health check failed %d times in a row: %w", failures, err)
+			}
+			continue
+		}
+		failures = 0
+	}
+}
+
+// revisionError returns an error when Cloud Run reports the revision is not
+// ready or its containers are unhealthy.
+func revisionError(revision *runpb.Revision) error {
+	for _, c := range revision.Conditions {
+		if (c.Type == "Ready" || c.Type == "ContainerHealthy") && c.State == runpb.Condition_CONDITION_FAILED {
+			message := c.Message
+			if message == "" {
+				message = "unknown error"
+			}
+			return fmt.Errorf("revision %s condition %s failed: %s", revisionID(revision.Name), c.Type, message)
+		}
+	}
+	return nil
+}
+
+// probeCanary requests url and returns an error unless it responds with a
+// 2xx or 3xx status.
+func probeCanary(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Timeout:       10 * time.Second,
+		Transport:     http.DefaultClient.Transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// stableTraffic returns the service's traffic targets with latest-revision
+// targets pinned to latestReady, the revision they route to before the
+// canary is created, and without tags, which belong to the canary.
+func stableTraffic(traffic []*runpb.TrafficTarget, latestReady string) []*runpb.TrafficTarget {
+	var stable []*runpb.TrafficTarget
+	for _, t := range traffic {
+		if t.Percent == 0 {
+			continue
+		}
+		target := proto.Clone(t).(*runpb.TrafficTarget)
+		target.Tag = ""
+		if target.Type == runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST || target.Revision == "" {
+			target.Type = runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION
+			target.Revision = latestReady
+		}
+		stable = append(stable, target)
+	}
+	if len(stable) == 0 {
+		stable = []*runpb.TrafficTarget{{
+			Type:     runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION,
+			Revision: latestReady,
+			Percent:  100,
+		}}
+	}
+	return stable
+}
+
+// canaryTraffic returns traffic targets giving the canary revision percent
+// of traffic and the stable targets the rest, in proportion to their shares.
+// An empty canary names the latest revision, before Cloud Run has named it.
+func canaryTraffic(stable []*runpb.TrafficTarget, canary string, percent int32) []*runpb.TrafficTarget {
+	target := &runpb.TrafficTarget{
+		Type:    runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST,
+		Percent: percent,
+		Tag:     canaryTag,
+	}
+	if canary != "" {
+		target.Type = runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION
+		target.Revision = canary
+	}
+	traffic := []*runpb.TrafficTarget{target}
+	if percent >= 100 {
+		return traffic
+	}
+
+	var total int32
+	for _, t := range stable {
+		total += t.Percent
+	}
+	remaining := 100 - percent
+	largest := -1
+	for _, t := range stable {
+		share := t.Percent * (100 - percent) / total
+		if share == 0 {
+			continue
+		}
+		scaled := proto.Clone(t).(*runpb.TrafficTarget)
+		scaled.Percent = share
+		remaining -= share
+		traffic = append(traffic, scaled)
+		if largest < 0 || share > traffic[largest].Percent {
+			largest = len(traffic) - 1
+		}
+	}
+	// Rounding leaves a remainder, which goes to the largest stable target
+	if largest < 0 {
+		first := proto.Clone(stable[0]).(*runpb.TrafficTarget)
+		first.Percent = 0
+		traffic = append(traffic, first)
+		largest = len(traffic) - 1
+	}
+	traffic[largest].Percent += remaining
+	return traffic
+}
+
+// taggedURL returns the URL of the service's traffic target with the given
+// tag, or "" if it has none.
+func taggedURL(service *runpb.Service, tag string) string {
+	for _, status := range service.TrafficStatuses {
+		if status.Tag == tag {
+			return status.Uri
+		}
+	}
+	return ""
+}
+
+// revisionID returns the ID of a revision from its full resource name.
+func revisionID(name string) string {
+	return path.Base(name)
+}
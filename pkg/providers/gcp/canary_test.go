@@ -0,0 +1,135 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+)
+
+// trafficText formats traffic targets as "revision:percent[tag]" for
+// comparison, with "LATEST" for latest-revision targets.
+func trafficText(traffic []*runpb.TrafficTarget) string {
+	parts := make([]string, len(traffic))
+	for i, t := range traffic {
+		revision := t.Revision
+		if t.Type == runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST {
+			revision = "LATEST"
+		}
+		parts[i] = fmt.Sprintf("%s:%d", revision, t.Percent)
+		if t.Tag != "" {
+			parts[i] += "[" + t.Tag + "]"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func revisionTarget(revision string, percent int32) *runpb.TrafficTarget {
+	return &runpb.TrafficTarget{
+		Type:     runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION,
+		Revision: revision,
+		Percent:  percent,
+	}
+}
+
+func TestStableTraffic(t *testing.T) {
+	latest := &runpb.TrafficTarget{Type: runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST, Percent: 100}
+	if got := trafficText(stableTraffic([]*runpb.TrafficTarget{latest}, "app-00002")); got != "app-00002:100" {
+		t.Errorf("Expected the latest revision to be pinned, got %s", got)
+	}
+
+	tagged := revisionTarget("app-00001", 0)
+	tagged.Tag = "canary"
+	split := []*runpb.TrafficTarget{revisionTarget("app-00002", 70), revisionTarget("app-00003", 30), tagged}
+	if got := trafficText(stableTraffic(split, "app-00003")); got != "app-00002:70 app-00003:30" {
+		t.Errorf("Expected the split without idle tagged targets, got %s", got)
+	}
+	if split[2].Tag != "canary" {
+		t.Error("Expected the service's traffic to be left unchanged")
+	}
+
+	if got := trafficText(stableTraffic(nil, "app-00002")); got != "app-00002:100" {
+		t.Errorf("Expected the latest ready revision to take all traffic without targets, got %s", got)
+	}
+}
+
+func TestCanaryTraffic(t *testing.T) {
+	tests := []struct {
+		name    string
+		stable  []*runpb.TrafficTarget
+		canary  string
+		percent int32
+		want    string
+	}{
+		{"first step", []*runpb.TrafficTarget{revisionTarget("app-1", 100)}, "", 10, "LATEST:10[canary] app-1:90"},
+		{"named canary", []*runpb.TrafficTarget{revisionTarget("app-1", 100)}, "app-2", 50, "app-2:50[canary] app-1:50"},
+		{"promoted", []*runpb.TrafficTarget{revisionTarget("app-1", 100)}, "app-2", 100, "app-2:100[canary]"},
+		{"proportional split", []*runpb.TrafficTarget{revisionTarget("app-1", 75), revisionTarget("app-2", 25)}, "app-3", 20, "app-3:20[canary] app-1:60 app-2:20"},
+		{"rounding remainder", []*runpb.TrafficTarget{revisionTarget("app-1", 50), revisionTarget("app-2", 50)}, "app-3", 25, "app-3:25[canary] app-1:38 app-2:37"},
+		{"shares rounding to zero", []*runpb.TrafficTarget{revisionTarget("app-1", 50), revisionTarget("app-2", 50)}, "app-3", 99, "app-3:99[canary] app-1:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trafficText(canaryTraffic(tt.stable, tt.canary, tt.percent)); got != tt.want {
+				t.Errorf("canaryTraffic() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevisionError(t *testing.T) {
+	healthy := &runpb.Revision{Name: "projects/p/locations/l/services/app/revisions/app-2", Conditions: []*runpb.Condition{
+		{Type: "Ready", State: runpb.Condition_CONDITION_SUCCEEDED},
+		{Type: "Active", State: runpb.Condition_CONDITION_FAILED},
+	}}
+	if err := revisionError(healthy); err != nil {
+		t.Errorf("Expected a ready revision to be healthy, got: %v", err)
+	}
+	failed := &runpb.Revision{Name: healthy.Name, Conditions: []*runpb.Condition{
+		{Type: "ContainerHealthy", State: runpb.Condition_CONDITION_FAILED, Message: "container exited with code 1"},
+	}}
+	if err := revisionError(failed); err == nil || err.Error() != "revision app-2 condition ContainerHealthy failed: container exited with code 1" {
+		t.Errorf("Expected the failed condition, got: %v", err)
+	}
+}
+
+func TestProbeCanary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+		case "/moved":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		default:
+			http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	if err := probeCanary(ctx, server.URL+"/health"); err != nil {
+		t.Errorf("Expected a healthy response, got: %v", err)
+	}
+	if err := probeCanary(ctx, server.URL+"/moved"); err != nil {
+		t.Errorf("Expected a redirect to count as healthy, got: %v", err)
+	}
+	if err := probeCanary(ctx, server.URL+"/broken"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected a 503 error, got: %v", err)
+	}
+}
+
+func TestTaggedURL(t *testing.T) {
+	service := &runpb.Service{TrafficStatuses: []*runpb.TrafficTargetStatus{
+		{Revision: "app-1", Percent: 90},
+		{Revision: "app-2", Percent: 10, Tag: "canary", Uri: "https://canary---app-abc-uc.a.run.app"},
+	}}
+	if got := taggedURL(service, canaryTag); got != "https://canary---app-abc-uc.a.run.app" {
+		t.Errorf("taggedURL() = %q", got)
+	}
+	if got := taggedURL(service, "blue"); got != "" {
+		t.Errorf("taggedURL() = %q, want none", got)
+	}
+}
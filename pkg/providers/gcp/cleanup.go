@@ -0,0 +1,223 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Prune deletes the versions of the application's Artifact Registry package
+// the policy selects, with their tags. Versions the Cloud Run services in the
+// region run, by tag or by digest, are kept, with the platform images of the
+// multi-arch image indexes kept.
+func (p *Provider) Prune(ctx context.Context, m *manifest.Manifest, policy types.RetentionPolicy, dryRun bool) (*types.Cleanup, error) {
+	packageName := fmt.Sprintf("projects/%s/locations/%s/repositories/%s/packages/%s", p.projectID, p.region, m.RepositoryName(), m.RepositoryName())
+	var versions []*artifactregistry.Version
+	err := p.registryClient.Projects.Locations.Repositories.Packages.Versions.List(packageName).View("FULL").Pages(ctx, func(page *artifactregistry.ListVersionsResponse) error {
+		versions = append(versions, page.Versions...)
+		return nil
+	})
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of %s: %w", m.RepositoryName(), err)
+	}
+
+	imagePath := fmt.Sprintf("%s-docker.pkg.dev/%s/%s/%s", p.region, p.projectID, m.RepositoryName(), m.RepositoryName())
+	deployed, err := p.deployedImages(ctx, imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := p.indexChildren(ctx, m, imagePath, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanup := &types.Cleanup{Provider: "gcp", DryRun: dryRun}
+	selected := selectVersions(versions, children, deployed, policy, time.Now(), cleanup)
+	if dryRun {
+		return cleanup, nil
+	}
+	for _, name := range selected {
+		logging.Info("Deleting Artifact Registry version", "version", name)
+		if _, err := p.registryClient.Projects.Locations.Repositories.Packages.Versions.Delete(name).Force(true).Context(ctx).Do(); err != nil {
+			return cleanup, fmt.Errorf("failed to delete version %s: %w", path.Base(name), err)
+		}
+	}
+	return cleanup, nil
+}
+
+// deployedImages returns the references to images of imagePath of the
+// containers of every Cloud Run service in the region, since the other
+// environments of the application share the package.
+func (p *Provider) deployedImages(ctx context.Context, imagePath string) ([]string, error) {
+	var images []string
+	it := p.runClient.ListServices(ctx, &runpb.ListServicesRequest{Parent: fmt.Sprintf("projects/%s/locations/%s", p.projectID, p.region)})
+	for {
+		service, err := it.Next()
+		if err == iterator.Done {
+			return images, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Cloud Run services: %w", err)
+		}
+		for _, c := range service.GetTemplate().GetContainers() {
+			if strings.HasPrefix(c.Image, imagePath+":") || strings.HasPrefix(c.Image, imagePath+"@") {
+				images = append(images, c.Image)
+			}
+		}
+	}
+}
+
+// indexChildren returns the digests of the images of each version of
+// imagePath that is a multi-arch image index, by the index's digest. The
+// indexes are read from the registry.
+func (p *Provider) indexChildren(ctx context.Context, m *manifest.Manifest, imagePath string, versions []*artifactregistry.Version) (map[string][]string, error) {
+	children := make(map[string][]string)
+	var auth authn.Authenticator
+	for _, v := range versions {
+		var metadata struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(v.Metadata, &metadata); err != nil || !ggcrtypes.MediaType(metadata.MediaType).IsIndex() {
+			continue
+		}
+		if auth == nil {
+			gcrRegistry, err := registry.NewGCRRegistry(p.projectID, p.region, m.RepositoryName(), "", "", p.clientOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Artifact Registry handler: %w", err)
+			}
+			if auth, err = gcrRegistry.GetAuthenticator(ctx); err != nil {
+				return nil, fmt.Errorf("failed to authenticate to Artifact Registry: %w", err)
+			}
+		}
+
+		digest := path.Base(v.Name)
+		ref, err := name.ParseReference(imagePath + "@" + digest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image reference %s@%s: %w", imagePath, digest, err)
+		}
+		desc, err := remote.Get(ref, remote.WithAuth(auth), remote.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index %s: %w", digest, err)
+		}
+		index, err := v1.ParseIndexManifest(bytes.NewReader(desc.Manifest))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image index %s: %w", digest, err)
+		}
+		for _, child := range index.Manifests {
+			children[digest] = append(children[digest], child.Digest.String())
+		}
+	}
+	return children, nil
+}
+
+// selectVersions returns the full names of the versions the policy deletes at
+// now, and records them in cleanup. Versions are named by their digest, and
+// versions whose digest or tags a deployed image references are kept. The
+// images of multi-arch image indexes, by the index's digest in children, are
+// not ranked: they are kept with any index referencing them that is kept, and
+// otherwise deleted after their indexes.
+func selectVersions(versions []*artifactregistry.Version, children map[string][]string, deployed []string, policy types.RetentionPolicy, now time.Time, cleanup *types.Cleanup) []string {
+	inUse := make(map[string]bool)
+	for _, image := range deployed {
+		if _, digest, ok := strings.Cut(image, "@"); ok {
+			inUse[digest] = true
+		} else if _, tag, ok := strings.Cut(path.Base(image), ":"); ok {
+			inUse[tag] = true
+		}
+	}
+
+	indexed := make(map[string]bool)
+	for _, digests := range children {
+		for _, digest := range digests {
+			indexed[digest] = true
+		}
+	}
+	var ranked, unranked []*artifactregistry.Version
+	for _, v := range versions {
+		if indexed[path.Base(v.Name)] {
+			unranked = append(unranked, v)
+		} else {
+			ranked = append(ranked, v)
+		}
+	}
+
+	created := make(map[string]time.Time, len(versions))
+	pushed := make([]time.Time, len(ranked))
+	for _, v := range versions {
+		created[v.Name], _ = time.Parse(time.RFC3339Nano, v.CreateTime)
+	}
+	for i, v := range ranked {
+		pushed[i] = created[v.Name]
+	}
+	kept := make(map[string]bool)
+	for i, expired := range policy.Expired(pushed, now) {
+		digest := path.Base(ranked[i].Name)
+		if !expired || inUse[digest] || containsAny(versionTags(ranked[i]), inUse) {
+			kept[digest] = true
+			for _, child := range children[digest] {
+				kept[child] = true
+			}
+		}
+	}
+
+	var selected []string
+	for _, v := range append(ranked, unranked...) {
+		digest := path.Base(v.Name)
+		if kept[digest] || inUse[digest] {
+			cleanup.Kept++
+			continue
+		}
+		name := strings.Join(versionTags(v), ",")
+		if name == "" {
+			name = digest
+		}
+		selected = append(selected, v.Name)
+		cleanup.Prune("Artifact Registry version", name, created[v.Name])
+	}
+	return selected
+}
+
+// versionTags returns the names of the tags of v.
+func versionTags(v *artifactregistry.Version) []string {
+	var tags []string
+	for _, tag := range v.RelatedTags {
+		tags = append(tags, path.Base(tag.Name))
+	}
+	return tags
+}
+
+// containsAny reports whether any of tags is in set.
+func containsAny(tags []string, set map[string]bool) bool {
+	for _, tag := range tags {
+		if set[tag] {
+			return true
+		}
+	}
+	return false
+}
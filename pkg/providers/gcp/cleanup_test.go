@@ -0,0 +1,101 @@
+package gcp
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestSelectVersions(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	pkg := "projects/my-project/locations/us-central1/repositories/my-app/packages/my-app"
+	version := func(digest string, days int, tags ...string) *artifactregistry.Version {
+		v := &artifactregistry.Version{Name: pkg + "/versions/" + digest, CreateTime: now.AddDate(0, 0, -days).Format(time.RFC3339Nano)}
+		for _, tag := range tags {
+			v.RelatedTags = append(v.RelatedTags, &artifactregistry.Tag{Name: pkg + "/tags/" + tag})
+		}
+		return v
+	}
+	versions := []*artifactregistry.Version{
+		version("sha256:a", 40, "deploy-1"),
+		version("sha256:b", 30),
+		version("sha256:c", 20, "deploy-3"),
+		version("sha256:d", 10, "deploy-4", "stable"),
+		version("sha256:e", 1, "latest"),
+	}
+	deployed := []string{
+		"us-central1-docker.pkg.dev/my-project/my-app/my-app:deploy-3",
+		"us-central1-docker.pkg.dev/my-project/my-app/my-app@sha256:b",
+	}
+
+	cleanup := &types.Cleanup{Provider: "gcp"}
+	selected := selectVersions(versions, nil, deployed, types.RetentionPolicy{KeepLast: 1}, now, cleanup)
+
+	if len(selected) != 2 || selected[0] != pkg+"/versions/sha256:a" || selected[1] != pkg+"/versions/sha256:d" {
+		t.Errorf("selected = %v, want the expired versions not deployed by tag or digest", selected)
+	}
+	want := []types.PrunedResource{
+		{Type: "Artifact Registry version", Name: "deploy-1", Created: now.AddDate(0, 0, -40)},
+		{Type: "Artifact Registry version", Name: "deploy-4,stable", Created: now.AddDate(0, 0, -10)},
+	}
+	if len(cleanup.Deleted) != len(want) {
+		t.Fatalf("got %d deleted, want %d: %+v", len(cleanup.Deleted), len(want), cleanup.Deleted)
+	}
+	for i, r := range cleanup.Deleted {
+		if !r.Created.Equal(want[i].Created) || r.Type != want[i].Type || r.Name != want[i].Name {
+			t.Errorf("deleted %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+	if cleanup.Kept != 3 {
+		t.Errorf("Kept = %d, want 3", cleanup.Kept)
+	}
+}
+
+func TestSelectVersionsImageIndex(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	pkg := "projects/my-project/locations/us-central1/repositories/my-app/packages/my-app"
+	version := func(digest string, days int, tags ...string) *artifactregistry.Version {
+		v := &artifactregistry.Version{Name: pkg + "/versions/" + digest, CreateTime: now.AddDate(0, 0, -days).Format(time.RFC3339Nano)}
+		for _, tag := range tags {
+			v.RelatedTags = append(v.RelatedTags, &artifactregistry.Tag{Name: pkg + "/tags/" + tag})
+		}
+		return v
+	}
+	// The indexes v1, v2, and v3 were pushed with their untagged images; v1 is deployed
+	versions := []*artifactregistry.Version{
+		version("sha256:v1-amd64", 30),
+		version("sha256:v1-arm64", 30),
+		version("sha256:v1", 30, "v1"),
+		version("sha256:v2-amd64", 20),
+		version("sha256:v2-arm64", 20),
+		version("sha256:v2", 20, "v2"),
+		version("sha256:v3-amd64", 10),
+		version("sha256:v3-arm64", 10),
+		version("sha256:v3", 10, "v3"),
+	}
+	children := map[string][]string{
+		"sha256:v1": {"sha256:v1-amd64", "sha256:v1-arm64"},
+		"sha256:v2": {"sha256:v2-amd64", "sha256:v2-arm64"},
+		"sha256:v3": {"sha256:v3-amd64", "sha256:v3-arm64"},
+	}
+	deployed := []string{"us-central1-docker.pkg.dev/my-project/my-app/my-app@sha256:v1"}
+
+	cleanup := &types.Cleanup{Provider: "gcp"}
+	selected := selectVersions(versions, children, deployed, types.RetentionPolicy{KeepLast: 1}, now, cleanup)
+
+	var got []string
+	for _, name := range selected {
+		got = append(got, path.Base(name))
+	}
+	if strings.Join(got, " ") != "sha256:v2 sha256:v2-amd64 sha256:v2-arm64" {
+		t.Errorf("selected = %v, want the expired index v2 with its images, after it", got)
+	}
+	if cleanup.Kept != 6 {
+		t.Errorf("Kept = %d, want the deployed index v1 and the newest index v3 with their images", cleanup.Kept)
+	}
+}
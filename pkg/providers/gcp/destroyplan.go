@@ -0,0 +1,39 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// PlanDestroy returns what Destroy would do: delete the environment's Cloud
+// Run service. The project and the Artifact Registry repository, which holds
+// the images of every environment, are kept.
+func (p *Provider) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	state, err := p.readInventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildDestroyPlan(m, state), nil
+}
+
+// buildDestroyPlan returns what a destroy of m's environment does given the
+// existing resources in state.
+func buildDestroyPlan(m *manifest.Manifest, state inventoryState) *types.Plan {
+	plan := &types.Plan{Provider: "gcp"}
+	if state.service != "" {
+		reason := "with all of its revisions"
+		if state.serviceURL != "" {
+			reason += "; releases " + state.serviceURL
+		}
+		plan.Add(types.PlanDelete, "Cloud Run service", m.Environment.Name, reason)
+	}
+	if state.repository != "" {
+		plan.Add(types.PlanNoChange, "Artifact Registry repository", m.RepositoryName(), "kept with the images of every environment")
+	}
+	if state.project != "" {
+		plan.Add(types.PlanNoChange, "Project", m.Provider.ProjectID, "kept")
+	}
+	return plan
+}
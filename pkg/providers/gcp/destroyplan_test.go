@@ -0,0 +1,35 @@
+package gcp
+
+import (
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestBuildDestroyPlan(t *testing.T) {
+	state := inventoryState{
+		project:    "projects/123456789",
+		repository: "projects/my-project/locations/us-central1/repositories/my-app",
+		service:    "projects/my-project/locations/us-central1/services/my-app-prod",
+		serviceURL: "https://my-app-prod-abc-uc.a.run.app",
+	}
+	plan := buildDestroyPlan(inventoryManifest(), state)
+
+	want := []types.ResourceChange{
+		{Action: types.PlanDelete, Type: "Cloud Run service", Name: "my-app-prod", Reason: "with all of its revisions; releases https://my-app-prod-abc-uc.a.run.app"},
+		{Action: types.PlanNoChange, Type: "Artifact Registry repository", Name: "my-app", Reason: "kept with the images of every environment"},
+		{Action: types.PlanNoChange, Type: "Project", Name: "my-project", Reason: "kept"},
+	}
+	if plan.Provider != "gcp" || len(plan.Changes) != len(want) {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	for i, c := range plan.Changes {
+		if c != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+
+	if plan := buildDestroyPlan(inventoryManifest(), inventoryState{}); len(plan.Changes) != 0 {
+		t.Errorf("expected nothing to destroy, got %+v", plan.Changes)
+	}
+}
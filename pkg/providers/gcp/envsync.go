@@ -0,0 +1,105 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/secrets"
+)
+
+// UpdateEnvironmentVariables sets vars on the primary container of the running Cloud Run
+// service. Only changed variables are written; the update creates a new revision from the
+// current template, so the image is not redeployed.
+func (p *Provider) UpdateEnvironmentVariables(ctx context.Context, m *manifest.Manifest, vars map[string]string) ([]string, error) {
+	serviceName := fmt.Sprintf("projects/%s/locations/%s/services/%s", p.projectID, p.region, m.Environment.Name)
+	service, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	container := primaryRunContainer(service, m)
+	if container == nil {
+		return nil, fmt.Errorf("service %s has no containers", m.Environment.Name)
+	}
+
+	var changed []string
+	if m.NativeSecrets() {
+		// Secrets delivered natively get new versions in Secret Manager
+		versions, err := p.storeSecrets(ctx, m, vars)
+		if err != nil {
+			return nil, err
+		}
+		container.Env, changed = setRunSecretEnv(container.Env, versions)
+	} else {
+		changed = secrets.Changed(runEnvValues(container.Env), vars)
+		container.Env = setRunEnv(container.Env, vars, changed)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	// Let Cloud Run name the new revision
+	service.Template.Revision = ""
+
+	logging.Infof("Updating %d environment variables on service %s...", len(changed), m.Environment.Name)
+	op, err := p.runClient.UpdateService(ctx, &runpb.UpdateServiceRequest{Service: service})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update service: %w", err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed to wait for service update: %w", err)
+	}
+	return changed, nil
+}
+
+// primaryRunContainer returns the service container that receives the manifest's
+// environment variables: the first container, or the one named after the manifest's
+// first container in multi-container deployments.
+func primaryRunContainer(service *runpb.Service, m *manifest.Manifest) *runpb.Container {
+	if service.Template == nil || len(service.Template.Containers) == 0 {
+		return nil
+	}
+	if m.IsMultiContainer() {
+		for _, c := range service.Template.Containers {
+			if c.Name == m.Containers[0].Name {
+				return c
+			}
+		}
+	}
+	return service.Template.Containers[0]
+}
+
+// runEnvValues returns the literal environment variables of a container.
+// Variables sourced from Secret Manager are skipped.
+func runEnvValues(env []*runpb.EnvVar) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, e := range env {
+		if v, ok := e.Values.(*runpb.EnvVar_Value); ok {
+			values[e.Name] = v.Value
+		}
+	}
+	return values
+}
+
+// setRunEnv sets the named variables from vars, replacing existing entries in place.
+func setRunEnv(env []*runpb.EnvVar, vars map[string]string, names []string) []*runpb.EnvVar {
+	for _, name := range names {
+		value := &runpb.EnvVar_Value{Value: vars[name]}
+		found := false
+		for _, e := range env {
+			if e.Name == name {
+				e.Values = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			env = append(env, &runpb.EnvVar{Name: name, Values: value})
+		}
+	}
+	return env
+}
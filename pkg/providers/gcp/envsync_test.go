@@ -0,0 +1,64 @@
+package gcp
+
+import (
+	"testing"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestRunEnvValues(t *testing.T) {
+	env := []*runpb.EnvVar{
+		{Name: "LOG_LEVEL", Values: &runpb.EnvVar_Value{Value: "info"}},
+		{Name: "API_KEY", Values: &runpb.EnvVar_ValueSource{ValueSource: &runpb.EnvVarSource{}}},
+	}
+
+	values := runEnvValues(env)
+
+	if len(values) != 1 || values["LOG_LEVEL"] != "info" {
+		t.Errorf("Expected only LOG_LEVEL=info, got %v", values)
+	}
+}
+
+func TestSetRunEnv(t *testing.T) {
+	env := []*runpb.EnvVar{
+		{Name: "LOG_LEVEL", Values: &runpb.EnvVar_Value{Value: "info"}},
+		{Name: "API_KEY", Values: &runpb.EnvVar_Value{Value: "old"}},
+	}
+	vars := map[string]string{"API_KEY": "new", "DB_PASSWORD": "secret"}
+
+	env = setRunEnv(env, vars, []string{"API_KEY", "DB_PASSWORD"})
+
+	values := runEnvValues(env)
+	expected := map[string]string{"LOG_LEVEL": "info", "API_KEY": "new", "DB_PASSWORD": "secret"}
+	if len(env) != 3 {
+		t.Fatalf("Expected 3 variables, got %d", len(env))
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, values[name])
+		}
+	}
+}
+
+func TestPrimaryRunContainer(t *testing.T) {
+	service := &runpb.Service{Template: &runpb.RevisionTemplate{Containers: []*runpb.Container{
+		{Name: "datadog-agent"},
+		{Name: "web"},
+	}}}
+
+	single := &manifest.Manifest{Image: "web:1"}
+	if c := primaryRunContainer(service, single); c.Name != "datadog-agent" {
+		t.Errorf("Expected first container for single-container manifest, got %s", c.Name)
+	}
+
+	multi := &manifest.Manifest{Containers: []manifest.Container{{Name: "web"}, {Name: "datadog-agent"}}}
+	if c := primaryRunContainer(service, multi); c.Name != "web" {
+		t.Errorf("Expected web container, got %s", c.Name)
+	}
+
+	if c := primaryRunContainer(&runpb.Service{}, single); c != nil {
+		t.Errorf("Expected nil for service without template, got %v", c)
+	}
+}
@@ -0,0 +1,296 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Export formats supported by the GCP provider.
+const (
+	exportFormatConfigConnector = "config-connector"
+	exportFormatTerraform       = "terraform"
+)
+
+// exportContainer is a container of the exported Cloud Run service.
+type exportContainer struct {
+	Name       string
+	Image      string
+	Command    []string
+	Args       []string
+	WorkingDir string
+}
+
+// exportSpec describes the resources cloud-deploy creates for a manifest.
+type exportSpec struct {
+	ProjectID      string
+	Region         string
+	OrganizationID string
+	BillingAccount string
+	APIs           []string
+	Repository     string
+	Service        string
+	Containers     []exportContainer
+	PublicAccess   bool
+}
+
+// Export renders the resources cloud-deploy created for the manifest (project, enabled APIs,
+// Artifact Registry repository, Cloud Run service and public IAM binding) as Config Connector
+// YAML or Terraform import blocks so they can be adopted into infrastructure-as-code.
+func (p *Provider) Export(ctx context.Context, m *manifest.Manifest, format string) ([]byte, error) {
+	// Prefer the images the live service runs; fall back to the registry URIs cloud-deploy pushes
+	var containers []exportContainer
+	serviceName := fmt.Sprintf("projects/%s/locations/%s/services/%s", p.projectID, p.region, m.Environment.Name)
+	service, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	if err == nil && service.Template != nil {
+		for _, c := range service.Template.Containers {
+			containers = append(containers, exportContainer{
+				Name:       c.Name,
+				Image:      c.Image,
+				Command:    c.Command,
+				Args:       c.Args,
+				WorkingDir: c.WorkingDir,
+			})
+		}
+	} else if err != nil {
+		logging.Warnf("Could not read Cloud Run service %s, exporting images from manifest: %v", m.Environment.Name, err)
+	}
+
+	spec := p.exportSpec(m, containers)
+
+	switch format {
+	case exportFormatConfigConnector:
+		return renderConfigConnector(spec)
+	case exportFormatTerraform:
+		return renderTerraformImports(spec), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format for GCP: %q (supported: %s, %s)",
+			format, exportFormatConfigConnector, exportFormatTerraform)
+	}
+}
+
+// exportSpec builds the export description for a manifest. When containers is empty,
+// the Artifact Registry image URIs used by Deploy are assumed.
+func (p *Provider) exportSpec(m *manifest.Manifest, containers []exportContainer) *exportSpec {
+	registryURL := fmt.Sprintf("%s-docker.pkg.dev/%s/%s", p.region, p.projectID, m.RepositoryName())
+
+	if len(containers) == 0 {
+		if m.IsMultiContainer() {
+			for _, c := range m.Containers {
+				containers = append(containers, exportContainer{
+					Name:       c.Name,
+					Image:      fmt.Sprintf("%s/%s:%s", registryURL, m.Application.Name, c.Name),
+					Command:    c.Command,
+					Args:       c.Args,
+					WorkingDir: c.Workdir,
+				})
+			}
+		} else {
+			startup := m.GetPrimaryContainer()
+			containers = append(containers, exportContainer{
+				Image:      fmt.Sprintf("%s/%s:latest", registryURL, m.Application.Name),
+				Command:    startup.Command,
+				Args:       startup.Args,
+				WorkingDir: startup.Workdir,
+			})
+		}
+	}
+
+	return &exportSpec{
+		ProjectID:      p.projectID,
+		Region:         p.region,
+		OrganizationID: p.organizationID,
+		BillingAccount: p.billingAccount,
+		APIs:           requiredAPIs,
+		Repository:     m.RepositoryName(),
+		Service:        m.Environment.Name,
+		Containers:     containers,
+		PublicAccess:   p.publicAccess,
+	}
+}
+
+// kccResource is a Config Connector resource manifest.
+type kccResource struct {
+	APIVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Metadata   kccMetadata            `yaml:"metadata"`
+	Spec       map[string]interface{} `yaml:"spec"`
+}
+
+type kccMetadata struct {
+	Name        string            `yaml:"name"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// renderConfigConnector renders the export as multi-document Config Connector YAML.
+// Every resource carries the "abandon" deletion policy so deleting the Kubernetes
+// object never deletes the underlying cloud resource during adoption.
+func renderConfigConnector(spec *exportSpec) ([]byte, error) {
+	abandon := func(extra map[string]string) map[string]string {
+		annotations := map[string]string{"cnrm.cloud.google.com/deletion-policy": "abandon"}
+		for k, v := range extra {
+			annotations[k] = v
+		}
+		return annotations
+	}
+	projectRef := map[string]interface{}{"external": spec.ProjectID}
+
+	projectSpec := map[string]interface{}{
+		"name":       spec.ProjectID,
+		"resourceID": spec.ProjectID,
+	}
+	if spec.BillingAccount != "" {
+		projectSpec["billingAccountRef"] = map[string]interface{}{"external": spec.BillingAccount}
+	}
+	if spec.OrganizationID != "" {
+		projectSpec["organizationRef"] = map[string]interface{}{"external": spec.OrganizationID}
+	}
+
+	resources := []kccResource{{
+		APIVersion: "resourcemanager.cnrm.cloud.google.com/v1beta1",
+		Kind:       "Project",
+		Metadata:   kccMetadata{Name: spec.ProjectID, Annotations: abandon(nil)},
+		Spec:       projectSpec,
+	}}
+
+	for _, api := range spec.APIs {
+		resources = append(resources, kccResource{
+			APIVersion: "serviceusage.cnrm.cloud.google.com/v1beta1",
+			Kind:       "Service",
+			Metadata: kccMetadata{
+				Name:        strings.TrimSuffix(api, ".googleapis.com"),
+				Annotations: abandon(map[string]string{"cnrm.cloud.google.com/disable-on-destroy": "false"}),
+			},
+			Spec: map[string]interface{}{
+				"resourceID": api,
+				"projectRef": projectRef,
+			},
+		})
+	}
+
+	resources = append(resources, kccResource{
+		APIVersion: "artifactregistry.cnrm.cloud.google.com/v1beta1",
+		Kind:       "ArtifactRegistryRepository",
+		Metadata:   kccMetadata{Name: spec.Repository, Annotations: abandon(nil)},
+		Spec: map[string]interface{}{
+			"resourceID": spec.Repository,
+			"format":     "DOCKER",
+			"location":   spec.Region,
+			"projectRef": projectRef,
+		},
+	})
+
+	containers := make([]map[string]interface{}, 0, len(spec.Containers))
+	for _, c := range spec.Containers {
+		container := map[string]interface{}{"image": c.Image}
+		if c.Name != "" {
+			container["name"] = c.Name
+		}
+		if len(c.Command) > 0 {
+			container["command"] = c.Command
+		}
+		if len(c.Args) > 0 {
+			container["args"] = c.Args
+		}
+		if c.WorkingDir != "" {
+			container["workingDir"] = c.WorkingDir
+		}
+		containers = append(containers, container)
+	}
+	resources = append(resources, kccResource{
+		APIVersion: "run.cnrm.cloud.google.com/v1beta1",
+		Kind:       "RunService",
+		Metadata:   kccMetadata{Name: spec.Service, Annotations: abandon(nil)},
+		Spec: map[string]interface{}{
+			"resourceID": spec.Service,
+			"location":   spec.Region,
+			"projectRef": projectRef,
+			"template":   map[string]interface{}{"containers": containers},
+		},
+	})
+
+	if spec.PublicAccess {
+		resources = append(resources, kccResource{
+			APIVersion: "iam.cnrm.cloud.google.com/v1beta1",
+			Kind:       "IAMPolicyMember",
+			Metadata:   kccMetadata{Name: spec.Service + "-public-invoker", Annotations: abandon(nil)},
+			Spec: map[string]interface{}{
+				"member": "allUsers",
+				"role":   "roles/run.invoker",
+				"resourceRef": map[string]interface{}{
+					"kind": "RunService",
+					"name": spec.Service,
+				},
+			},
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by cloud-deploy: Config Connector resources for adopting existing GCP resources\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, r := range resources {
+		if err := encoder.Encode(r); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", r.Kind, err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode Config Connector YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderTerraformImports renders the export as Terraform import blocks. Resource
+// configuration can then be generated with `terraform plan -generate-config-out=...`.
+func renderTerraformImports(spec *exportSpec) []byte {
+	var b strings.Builder
+	b.WriteString("# Generated by cloud-deploy: Terraform import blocks for existing GCP resources.\n")
+	b.WriteString("# Run `terraform plan -generate-config-out=generated.tf` to generate resource configuration.\n")
+
+	writeImport := func(to, id string) {
+		fmt.Fprintf(&b, "\nimport {\n  to = %s\n  id = %q\n}\n", to, id)
+	}
+
+	writeImport("google_project.project", fmt.Sprintf("projects/%s", spec.ProjectID))
+	for _, api := range spec.APIs {
+		writeImport("google_project_service."+terraformName(strings.TrimSuffix(api, ".googleapis.com")),
+			fmt.Sprintf("%s/%s", spec.ProjectID, api))
+	}
+	writeImport("google_artifact_registry_repository."+terraformName(spec.Repository),
+		fmt.Sprintf("projects/%s/locations/%s/repositories/%s", spec.ProjectID, spec.Region, spec.Repository))
+
+	serviceID := fmt.Sprintf("projects/%s/locations/%s/services/%s", spec.ProjectID, spec.Region, spec.Service)
+	writeImport("google_cloud_run_v2_service."+terraformName(spec.Service), serviceID)
+	if spec.PublicAccess {
+		writeImport("google_cloud_run_v2_service_iam_member."+terraformName(spec.Service)+"_public_invoker",
+			serviceID+" roles/run.invoker allUsers")
+	}
+
+	return []byte(b.String())
+}
+
+// terraformName converts a resource name into a valid Terraform identifier.
+func terraformName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
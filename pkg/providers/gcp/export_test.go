@@ -0,0 +1,187 @@
+package gcp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func testExportProvider(publicAccess bool) *Provider {
+	return &Provider{
+		projectID:      "test-project",
+		region:         "us-central1",
+		publicAccess:   publicAccess,
+		billingAccount: "AAAAAA-BBBBBB-CCCCCC",
+		organizationID: "123456789",
+	}
+}
+
+func TestExportSpecDefaultsToRegistryImages(t *testing.T) {
+	m := &manifest.Manifest{
+		Image:       "my-app:latest",
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-service"},
+	}
+
+	spec := testExportProvider(true).exportSpec(m, nil)
+
+	if spec.Service != "my-service" {
+		t.Errorf("Expected service 'my-service', got '%s'", spec.Service)
+	}
+	if spec.Repository != "my-app" {
+		t.Errorf("Expected repository 'my-app', got '%s'", spec.Repository)
+	}
+	if len(spec.Containers) != 1 {
+		t.Fatalf("Expected 1 container, got %d", len(spec.Containers))
+	}
+	expected := "us-central1-docker.pkg.dev/test-project/my-app/my-app:latest"
+	if spec.Containers[0].Image != expected {
+		t.Errorf("Expected image '%s', got '%s'", expected, spec.Containers[0].Image)
+	}
+}
+
+func TestExportSpecMultiContainer(t *testing.T) {
+	m := &manifest.Manifest{
+		Containers: []manifest.Container{
+			{Name: "web", Image: "web:1"},
+			{Name: "worker", Image: "worker:1"},
+		},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-service"},
+	}
+
+	spec := testExportProvider(true).exportSpec(m, nil)
+
+	if len(spec.Containers) != 2 {
+		t.Fatalf("Expected 2 containers, got %d", len(spec.Containers))
+	}
+	if spec.Containers[1].Name != "worker" || !strings.HasSuffix(spec.Containers[1].Image, "/my-app:worker") {
+		t.Errorf("Unexpected container: %+v", spec.Containers[1])
+	}
+}
+
+func TestRenderConfigConnectorStartupOverrides(t *testing.T) {
+	m := &manifest.Manifest{
+		Image:       "my-app:latest",
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-service"},
+		Container: &manifest.ContainerStartup{
+			Command: []string{"/bin/server"},
+			Args:    []string{"--port=8080"},
+			Workdir: "/srv",
+		},
+	}
+	spec := testExportProvider(false).exportSpec(m, nil)
+
+	out, err := renderConfigConnector(spec)
+	if err != nil {
+		t.Fatalf("renderConfigConnector failed: %v", err)
+	}
+	for _, s := range []string{"- /bin/server", "- --port=8080", "workingDir: /srv"} {
+		if !strings.Contains(string(out), s) {
+			t.Errorf("Expected output to contain %q\nGot:\n%s", s, out)
+		}
+	}
+}
+
+func TestRenderConfigConnector(t *testing.T) {
+	m := &manifest.Manifest{
+		Image:       "my-app:latest",
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-service"},
+	}
+	spec := testExportProvider(true).exportSpec(m, []exportContainer{{Image: "gcr.io/x/y:1"}})
+
+	out, err := renderConfigConnector(spec)
+	if err != nil {
+		t.Fatalf("renderConfigConnector failed: %v", err)
+	}
+
+	kinds := make(map[string]int)
+	decoder := yaml.NewDecoder(bytes.NewReader(out))
+	for {
+		var doc kccResource
+		if err := decoder.Decode(&doc); err != nil {
+			break
+		}
+		kinds[doc.Kind]++
+		if doc.Metadata.Annotations["cnrm.cloud.google.com/deletion-policy"] != "abandon" {
+			t.Errorf("%s %s: expected abandon deletion policy", doc.Kind, doc.Metadata.Name)
+		}
+		if doc.Kind == "Project" {
+			billing, _ := doc.Spec["billingAccountRef"].(map[string]interface{})
+			if billing["external"] != "AAAAAA-BBBBBB-CCCCCC" {
+				t.Errorf("Expected billing account ref, got %v", doc.Spec["billingAccountRef"])
+			}
+		}
+	}
+
+	expected := map[string]int{
+		"Project":                    1,
+		"Service":                    len(requiredAPIs),
+		"ArtifactRegistryRepository": 1,
+		"RunService":                 1,
+		"IAMPolicyMember":            1,
+	}
+	for kind, count := range expected {
+		if kinds[kind] != count {
+			t.Errorf("Expected %d %s resources, got %d", count, kind, kinds[kind])
+		}
+	}
+	if !strings.Contains(string(out), "gcr.io/x/y:1") {
+		t.Error("Expected live container image in output")
+	}
+}
+
+func TestRenderConfigConnectorPrivateService(t *testing.T) {
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-service"},
+	}
+	out, err := renderConfigConnector(testExportProvider(false).exportSpec(m, nil))
+	if err != nil {
+		t.Fatalf("renderConfigConnector failed: %v", err)
+	}
+	if strings.Contains(string(out), "IAMPolicyMember") {
+		t.Error("Expected no public invoker binding for private service")
+	}
+}
+
+func TestRenderTerraformImports(t *testing.T) {
+	m := &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-service"},
+	}
+	out := string(renderTerraformImports(testExportProvider(true).exportSpec(m, nil)))
+
+	expected := []string{
+		"to = google_project.project\n  id = \"projects/test-project\"",
+		"to = google_project_service.run\n  id = \"test-project/run.googleapis.com\"",
+		"to = google_artifact_registry_repository.my_app\n  id = \"projects/test-project/locations/us-central1/repositories/my-app\"",
+		"to = google_cloud_run_v2_service.my_service\n  id = \"projects/test-project/locations/us-central1/services/my-service\"",
+		"to = google_cloud_run_v2_service_iam_member.my_service_public_invoker",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected output to contain %q\nGot:\n%s", s, out)
+		}
+	}
+}
+
+func TestTerraformName(t *testing.T) {
+	tests := map[string]string{
+		"my-app":  "my_app",
+		"app.v2":  "app_v2",
+		"1st-app": "_1st_app",
+		"ok_name": "ok_name",
+	}
+	for in, expected := range tests {
+		if got := terraformName(in); got != expected {
+			t.Errorf("terraformName(%q) = %q, want %q", in, got, expected)
+		}
+	}
+}
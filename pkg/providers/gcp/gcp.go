@@ -4,8 +4,15 @@ package gcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
@@ -15,14 +22,25 @@ import (
 	"cloud.google.com/go/logging/logadmin"
 	run "cloud.google.com/go/run/apiv2"
 	"cloud.google.com/go/run/apiv2/runpb"
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/storage"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google/externalaccount"
+	"golang.org/x/sync/errgroup"
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
 	"google.golang.org/api/cloudbilling/v1"
 	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/option"
 	"google.golang.org/api/serviceusage/v1"
+	htransport "google.golang.org/api/transport/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/durationpb"
 
+	"github.com/jvreagan/cloud-deploy/pkg/cache"
 	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/readonly"
 	"github.com/jvreagan/cloud-deploy/pkg/registry"
 	"github.com/jvreagan/cloud-deploy/pkg/types"
 )
@@ -36,22 +54,32 @@ type Provider struct {
 	projectsClient  *cloudresourcemanager.Service
 	billingClient   *cloudbilling.APIService
 	usageClient     *serviceusage.Service
+	registryClient  *artifactregistry.Service
 	loggingClient   *logadmin.Client
+	secretsClient   secretManagerAPI
 	projectID       string
 	region          string
 	publicAccess    bool
 	billingAccount  string
 	organizationID  string
+	journal         types.OperationJournal
+
+	// clientOpts authenticate image pushes to Artifact Registry
+	clientOpts []option.ClientOption
+
+	// mu guards closers, the Close functions of the clients holding connections
+	mu      sync.Mutex
+	closers []func() error
 }
 
 // New creates a new GCP provider instance with the specified configuration and manifest.
 // Credentials can be loaded from:
-// 1. Vault (if credentials.source == "vault")
+// 1. A secret store (if credentials.source is "vault", "secrets-manager", "parameter-store", or "gcp-secret-manager")
 // 2. Environment variables (if credentials.source == "environment")
 // 3. Manifest (if credentials contain service_account_key)
 // 4. Default application credentials (fallback)
 //
-// The provider will automatically:
+// Creating the provider does not change anything in the project. On deploy it will:
 // - Create the project if it doesn't exist
 // - Link the billing account
 // - Enable required APIs
@@ -70,128 +98,186 @@ func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manif
 
 	logging.Infof("Initializing GCP provider for project: %s", projectID)
 
-	// Check if credentials should be loaded from Vault
-	var credOption option.ClientOption
-	var err error
-
-	if config.Credentials != nil && config.Credentials.Source == "vault" {
-		logging.Info("Loading GCP credentials from Vault...")
-
-		// Get credentials from Vault using manifest helper
-		vaultCreds, err := m.GetCloudCredentials(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load GCP credentials from Vault: %w", err)
-		}
-
-		if vaultCreds != nil && vaultCreds.GCP.ServiceAccountKey != "" {
-			logging.Info("✅ Successfully loaded GCP credentials from Vault")
-			credOption = option.WithCredentialsJSON([]byte(vaultCreds.GCP.ServiceAccountKey))
-		}
-	} else {
-		// Load service account credentials from manifest (existing behavior)
-		credOption, err = loadCredentials(config.Credentials)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load credentials: %w", err)
-		}
-	}
-
-	// Build client options - only add credOption if not nil
-	var clientOpts []option.ClientOption
-	if credOption != nil {
-		clientOpts = append(clientOpts, credOption)
-	}
-
-	// Initialize Cloud Resource Manager client (for project management)
-	projectsClient, err := cloudresourcemanager.NewService(ctx, clientOpts...)
+	clientOpts, err := ClientOptions(ctx, config, m)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Cloud Resource Manager client: %w", err)
+		return nil, err
 	}
-
-	// Initialize Cloud Billing client
-	billingClient, err := cloudbilling.NewService(ctx, clientOpts...)
+	grpcOpts, err := GRPCClientOptions(config, clientOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Cloud Billing client: %w", err)
+		return nil, err
 	}
-
-	// Initialize Service Usage client (for enabling APIs)
-	usageClient, err := serviceusage.NewService(ctx, clientOpts...)
+	restOpts, err := RESTClientOptions(ctx, clientOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Service Usage client: %w", err)
+		return nil, err
 	}
 
-	// Initialize Cloud Build client
-	buildClient, err := cloudbuild.NewClient(ctx, clientOpts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Cloud Build client: %w", err)
+	provider := &Provider{
+		projectID:      projectID,
+		region:         config.Region,
+		publicAccess:   publicAccess,
+		billingAccount: config.BillingAccountID,
+		organizationID: config.OrganizationID,
+		clientOpts:     clientOpts,
+	}
+
+	// Create the clients concurrently. Each sets a different field, and the
+	// ones holding connections are tracked so Close releases them, including
+	// when another fails. The clients keep ctx for refreshing tokens, so it
+	// must not be a context canceled once they are created.
+	var g errgroup.Group
+	g.Go(func() error {
+		// Cloud Resource Manager, for project management
+		client, err := cloudresourcemanager.NewService(ctx, restOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Cloud Resource Manager client: %w", err)
+		}
+		provider.projectsClient = client
+		return nil
+	})
+	g.Go(func() error {
+		client, err := cloudbilling.NewService(ctx, restOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Cloud Billing client: %w", err)
+		}
+		provider.billingClient = client
+		return nil
+	})
+	g.Go(func() error {
+		// Service Usage, for enabling APIs
+		client, err := serviceusage.NewService(ctx, restOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Service Usage client: %w", err)
+		}
+		provider.usageClient = client
+		return nil
+	})
+	g.Go(func() error {
+		// Artifact Registry, for planning image pushes
+		client, err := artifactregistry.NewService(ctx, restOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Artifact Registry client: %w", err)
+		}
+		provider.registryClient = client
+		return nil
+	})
+	g.Go(func() error {
+		client, err := cloudbuild.NewClient(ctx, grpcOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Cloud Build client: %w", err)
+		}
+		provider.buildClient = client
+		provider.track(client.Close)
+		return nil
+	})
+	g.Go(func() error {
+		client, err := run.NewServicesClient(ctx, grpcOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Cloud Run client: %w", err)
+		}
+		provider.runClient = client
+		provider.track(client.Close)
+		return nil
+	})
+	g.Go(func() error {
+		client, err := run.NewRevisionsClient(ctx, grpcOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Cloud Run Revisions client: %w", err)
+		}
+		provider.revisionsClient = client
+		provider.track(client.Close)
+		return nil
+	})
+	g.Go(func() error {
+		client, err := storage.NewClient(ctx, clientOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Storage client: %w", err)
+		}
+		provider.storageClient = client
+		provider.track(client.Close)
+		return nil
+	})
+	g.Go(func() error {
+		// Secret Manager, for secrets delivered natively
+		client, err := secretmanager.NewClient(ctx, grpcOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Secret Manager client: %w", err)
+		}
+		provider.secretsClient = client
+		provider.track(client.Close)
+		return nil
+	})
+	g.Go(func() error {
+		client, err := logadmin.NewClient(ctx, projectID, grpcOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create Logging client: %w", err)
+		}
+		provider.loggingClient = client
+		provider.track(client.Close)
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		provider.Close()
+		return nil, err
 	}
 
-	// Initialize Cloud Run client
-	runClient, err := run.NewServicesClient(ctx, clientOpts...)
-	if err != nil {
-		buildClient.Close()
-		return nil, fmt.Errorf("failed to create Cloud Run client: %w", err)
-	}
+	logging.Info("GCP provider initialized successfully")
+	return provider, nil
+}
 
-	// Initialize Cloud Run Revisions client
-	revisionsClient, err := run.NewRevisionsClient(ctx, clientOpts...)
-	if err != nil {
-		buildClient.Close()
-		runClient.Close()
-		return nil, fmt.Errorf("failed to create Cloud Run Revisions client: %w", err)
-	}
+// track adds close to the functions Close calls.
+func (p *Provider) track(close func() error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closers = append(p.closers, close)
+}
 
-	// Initialize Cloud Storage client
-	storageClient, err := storage.NewClient(ctx, clientOpts...)
-	if err != nil {
-		buildClient.Close()
-		runClient.Close()
-		revisionsClient.Close()
-		return nil, fmt.Errorf("failed to create Storage client: %w", err)
+// Close releases the connections of the provider's clients. The provider must
+// not be used afterwards; closing it again does nothing.
+func (p *Provider) Close() error {
+	p.mu.Lock()
+	closers := p.closers
+	p.closers = nil
+	p.mu.Unlock()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i](); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	// Initialize Cloud Logging client (will be configured after project is ready)
-	loggingClient, err := logadmin.NewClient(ctx, projectID, clientOpts...)
-	if err != nil {
-		buildClient.Close()
-		runClient.Close()
-		revisionsClient.Close()
-		storageClient.Close()
-		return nil, fmt.Errorf("failed to create Logging client: %w", err)
+// ensureProjectReady creates the project if needed, links its billing account,
+// and enables the APIs deployments use. A ready project is remembered in the
+// local cache, so deploys within cache.DefaultTTL skip the checks unless run
+// with -refresh.
+func (p *Provider) ensureProjectReady(ctx context.Context) error {
+	key := p.readyCacheKey()
+	if cache.Fresh(key, cache.DefaultTTL) {
+		logging.Infof("Project %s was ready within %s; skipping its checks (-refresh checks again)", p.projectID, cache.DefaultTTL)
+		return nil
 	}
-
-	provider := &Provider{
-		buildClient:     buildClient,
-		runClient:       runClient,
-		revisionsClient: revisionsClient,
-		storageClient:   storageClient,
-		projectsClient:  projectsClient,
-		billingClient:   billingClient,
-		usageClient:     usageClient,
-		loggingClient:   loggingClient,
-		projectID:       projectID,
-		region:          config.Region,
-		publicAccess:    publicAccess,
-		billingAccount:  config.BillingAccountID,
-		organizationID:  config.OrganizationID,
-	}
-
-	// Ensure project exists and is properly configured
-	if err := provider.ensureProject(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ensure project: %w", err)
+	if err := p.ensureProject(ctx); err != nil {
+		return fmt.Errorf("failed to ensure project: %w", err)
 	}
-
-	// Link billing account
-	if err := provider.ensureBillingLinked(ctx); err != nil {
-		return nil, fmt.Errorf("failed to link billing account: %w", err)
+	if err := p.ensureBillingLinked(ctx); err != nil {
+		return fmt.Errorf("failed to link billing account: %w", err)
 	}
-
-	// Enable required APIs
-	if err := provider.ensureAPIsEnabled(ctx); err != nil {
-		return nil, fmt.Errorf("failed to enable required APIs: %w", err)
+	if err := p.ensureAPIsEnabled(ctx); err != nil {
+		return fmt.Errorf("failed to enable required APIs: %w", err)
 	}
+	if err := cache.Mark(key); err != nil {
+		logging.Debug("Failed to cache project readiness", "error", err)
+	}
+	return nil
+}
 
-	logging.Info("GCP provider initialized successfully")
-	return provider, nil
+// readyCacheKey returns the key of the project's readiness in the local cache.
+// It changes with the billing account and the required APIs, which are then
+// checked again.
+func (p *Provider) readyCacheKey() string {
+	return fmt.Sprintf("gcp-project-ready:%s:%s:%s", p.projectID, p.billingAccount, strings.Join(requiredAPIs, ","))
 }
 
 // Name returns the provider name.
@@ -201,6 +287,16 @@ func (p *Provider) Name() string {
 
 // Deploy deploys an application to Google Cloud Run.
 func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := validateImagePlatforms(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := p.ensureProjectReady(ctx); err != nil {
+		return nil, err
+	}
+
 	if m.IsMultiContainer() {
 		logging.Info("Starting Google Cloud Run multi-container deployment...")
 		return p.deployMultiContainer(ctx, m)
@@ -211,30 +307,23 @@ func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.Dep
 	// Step 1: Push image to GCR (Artifact Registry)
 	logging.Info("=== Distributing image to GCR ===")
 
-	// Get credentials JSON for GCR authentication
-	var credsJSON string
-	if m.Provider.Credentials != nil {
-		if m.Provider.Credentials.ServiceAccountKeyJSON != "" {
-			credsJSON = m.Provider.Credentials.ServiceAccountKeyJSON
-		}
-	}
-
-	repositoryName := m.Application.Name
-	gcrRegistry, err := registry.NewGCRRegistry(p.projectID, p.region, repositoryName, "latest", credsJSON)
+	repositoryName := m.RepositoryName()
+	gcrRegistry, err := registry.NewGCRRegistry(p.projectID, p.region, repositoryName, m.ImageTag("latest", ""), "", p.clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GCR registry handler: %w", err)
 	}
 
 	// Use Distributor to push image to registry
-	distributor := registry.NewDistributor(m.Image)
-	distributor.AddRegistry(gcrRegistry)
+	distributor := registry.NewDistributor(m.Image, m.Platforms...)
+	dest := registry.Local(gcrRegistry, m.Provider.RegistryURL, repositoryName, m.ImageTag("latest", ""))
+	distributor.AddRegistry(dest)
 
 	imageURIs, err := distributor.Distribute(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to distribute image to GCR: %w", err)
 	}
 
-	imageURI := imageURIs[gcrRegistry.GetRegistryURL()]
+	imageURI := imageURIs[dest.GetRegistryURL()]
 	logging.Infof("Successfully pushed image to GCR: %s", imageURI)
 
 	// Step 2: Deploy to Cloud Run
@@ -264,42 +353,39 @@ func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.Dep
 		URL:             url,
 		Status:          "Ready",
 		Message:         "Deployment successful",
+		Images:          map[string]string{m.Application.Name: registry.PinDigest(imageURI, distributor.Digest())},
 	}, nil
 }
 
 // deployMultiContainer deploys a multi-container application using Cloud Run sidecars.
 func (p *Provider) deployMultiContainer(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
-	// Get credentials JSON for GCR authentication
-	var credsJSON string
-	if m.Provider.Credentials != nil {
-		if m.Provider.Credentials.ServiceAccountKeyJSON != "" {
-			credsJSON = m.Provider.Credentials.ServiceAccountKeyJSON
-		}
-	}
-
 	// Step 1: Push ALL container images to GCR
 	logging.Infof("Distributing %d container images to GCR...", len(m.Containers))
 	containerImageURIs := make(map[string]string) // container name -> GCR URI
+	pinnedImages := make(map[string]string)       // container name -> URI pinned by digest
 
 	for _, container := range m.Containers {
 		logging.Infof("Pushing container image: %s (%s)", container.Name, container.Image)
 
-		repositoryName := m.Application.Name
-		gcrRegistry, err := registry.NewGCRRegistry(p.projectID, p.region, repositoryName, container.Name, credsJSON)
+		repositoryName := m.RepositoryName()
+		tag := m.ImageTag(container.Name, container.Name)
+		gcrRegistry, err := registry.NewGCRRegistry(p.projectID, p.region, repositoryName, tag, "", p.clientOpts...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create GCR registry for container %s: %w", container.Name, err)
 		}
 
-		distributor := registry.NewDistributor(container.Image)
-		distributor.AddRegistry(gcrRegistry)
+		distributor := registry.NewDistributor(container.Image, m.Platforms...)
+		dest := registry.Local(gcrRegistry, m.Provider.RegistryURL, repositoryName, tag)
+		distributor.AddRegistry(dest)
 
 		imageURIs, err := distributor.Distribute(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to distribute image for container %s: %w", container.Name, err)
 		}
 
-		imageURI := imageURIs[gcrRegistry.GetRegistryURL()]
+		imageURI := imageURIs[dest.GetRegistryURL()]
 		containerImageURIs[container.Name] = imageURI
+		pinnedImages[container.Name] = registry.PinDigest(imageURI, distributor.Digest())
 		logging.Infof("Image pushed to GCR: %s -> %s", container.Name, imageURI)
 	}
 
@@ -329,6 +415,7 @@ func (p *Provider) deployMultiContainer(ctx context.Context, m *manifest.Manifes
 		URL:             url,
 		Status:          "Ready",
 		Message:         fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers)),
+		Images:          pinnedImages,
 	}, nil
 }
 
@@ -349,7 +436,7 @@ func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
 	}
 
 	// Wait for deletion to complete
-	if _, err := op.Wait(ctx); err != nil {
+	if err := p.waitForOperation(ctx, operationServiceDelete, op); err != nil {
 		return fmt.Errorf("failed to wait for service deletion: %w", err)
 	}
 
@@ -378,13 +465,13 @@ func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
 	}
 
 	// Wait for deletion to complete
-	if _, err := op.Wait(ctx); err != nil {
+	if err := p.waitForOperation(ctx, operationServiceDelete, op); err != nil {
 		return fmt.Errorf("failed to wait for service deletion: %w", err)
 	}
 
 	logging.Info("Service stopped successfully")
 	logging.Info("Container images are preserved in Artifact Registry")
-	logging.Info("Run 'cloud-deploy -command deploy' to restart")
+	logging.Info("Run 'cloud-deploy deploy' to restart")
 	return nil
 }
 
@@ -443,21 +530,21 @@ func (p *Provider) deployService(ctx context.Context, m *manifest.Manifest, serv
 	existingService, err := p.runClient.GetService(ctx, getReq)
 	serviceExists := err == nil
 
-	// Build environment variables
-	envVars := make([]*runpb.EnvVar, 0, len(m.EnvironmentVariables))
-	for key, value := range m.EnvironmentVariables {
-		envVars = append(envVars, &runpb.EnvVar{
-			Name: key,
-			Values: &runpb.EnvVar_Value{
-				Value: value,
-			},
-		})
+	// Build environment variables, referencing secrets delivered natively
+	versions, err := p.storeSecrets(ctx, m, m.EnvironmentVariables)
+	if err != nil {
+		return err
 	}
+	envVars := runEnv(m.EnvironmentVariables, versions)
 
 	// Build container resources from manifest configuration
+	startup := m.GetPrimaryContainer()
 	container := &runpb.Container{
-		Image: imageTag,
-		Env:   envVars,
+		Image:      imageTag,
+		Env:        envVars,
+		Command:    startup.Command,
+		Args:       startup.Args,
+		WorkingDir: startup.Workdir,
 	}
 
 	// Apply Cloud Run configuration if specified
@@ -540,19 +627,12 @@ func (p *Provider) deployService(ctx context.Context, m *manifest.Manifest, serv
 		service.Template = existingService.Template
 		service.Template.Containers[0].Image = imageTag
 		service.Template.Containers[0].Env = envVars
+		service.Template.Containers[0].Command = startup.Command
+		service.Template.Containers[0].Args = startup.Args
+		service.Template.Containers[0].WorkingDir = startup.Workdir
 
-		req := &runpb.UpdateServiceRequest{
-			Service: service,
-		}
-
-		op, err := p.runClient.UpdateService(ctx, req)
-		if err != nil {
-			return fmt.Errorf("failed to update service: %w", err)
-		}
-
-		_, err = op.Wait(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to wait for service update: %w", err)
+		if err := p.updateService(ctx, m, service, existingService); err != nil {
+			return err
 		}
 	} else {
 		logging.Infof("Creating new service: %s", serviceName)
@@ -568,7 +648,7 @@ func (p *Provider) deployService(ctx context.Context, m *manifest.Manifest, serv
 			return fmt.Errorf("failed to create service: %w", err)
 		}
 
-		_, err = op.Wait(ctx)
+		err = p.waitForOperation(ctx, operationServiceCreate, op)
 		if err != nil {
 			return fmt.Errorf("failed to wait for service creation: %w", err)
 		}
@@ -592,9 +672,15 @@ func (p *Provider) deployMultiContainerService(ctx context.Context, m *manifest.
 	getReq := &runpb.GetServiceRequest{
 		Name: serviceFullName,
 	}
-	_, err := p.runClient.GetService(ctx, getReq)
+	existingService, err := p.runClient.GetService(ctx, getReq)
 	serviceExists := err == nil
 
+	// Secrets delivered natively are referenced by the primary container, which receives them
+	versions, err := p.storeSecrets(ctx, m, m.Containers[0].Environment)
+	if err != nil {
+		return err
+	}
+
 	// Build containers array from manifest
 	containers := make([]*runpb.Container, 0, len(m.Containers))
 
@@ -602,21 +688,21 @@ func (p *Provider) deployMultiContainerService(ctx context.Context, m *manifest.
 		imageURI := containerImageURIs[containerDef.Name]
 
 		// Build environment variables for this container
-		envVars := make([]*runpb.EnvVar, 0, len(containerDef.Environment))
-		for key, value := range containerDef.Environment {
-			envVars = append(envVars, &runpb.EnvVar{
-				Name: key,
-				Values: &runpb.EnvVar_Value{
-					Value: value,
-				},
-			})
+		var envVars []*runpb.EnvVar
+		if containerDef.Name == m.Containers[0].Name {
+			envVars = runEnv(containerDef.Environment, versions)
+		} else {
+			envVars = runEnv(containerDef.Environment, nil)
 		}
 
 		// Create container
 		container := &runpb.Container{
-			Name:  containerDef.Name,
-			Image: imageURI,
-			Env:   envVars,
+			Name:       containerDef.Name,
+			Image:      imageURI,
+			Env:        envVars,
+			Command:    containerDef.Command,
+			Args:       containerDef.Args,
+			WorkingDir: containerDef.Workdir,
 		}
 
 		// Set ports ONLY for the first container (ingress container)
@@ -705,18 +791,8 @@ func (p *Provider) deployMultiContainerService(ctx context.Context, m *manifest.
 		service.Name = serviceFullName
 		service.Template = revisionTemplate
 
-		req := &runpb.UpdateServiceRequest{
-			Service: service,
-		}
-
-		op, err := p.runClient.UpdateService(ctx, req)
-		if err != nil {
-			return fmt.Errorf("failed to update service: %w", err)
-		}
-
-		_, err = op.Wait(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to wait for service update: %w", err)
+		if err := p.updateService(ctx, m, service, existingService); err != nil {
+			return err
 		}
 	} else {
 		logging.Infof("Creating new multi-container service: %s", serviceName)
@@ -732,7 +808,7 @@ func (p *Provider) deployMultiContainerService(ctx context.Context, m *manifest.
 			return fmt.Errorf("failed to create service: %w", err)
 		}
 
-		_, err = op.Wait(ctx)
+		err = p.waitForOperation(ctx, operationServiceCreate, op)
 		if err != nil {
 			return fmt.Errorf("failed to wait for service creation: %w", err)
 		}
@@ -826,6 +902,134 @@ func (p *Provider) getServiceURL(ctx context.Context, serviceName string) (strin
 	return service.Uri, nil
 }
 
+// ClientOptions returns the options that authenticate Google Cloud API
+// clients with the provider's credentials: the CI job's OIDC token, from a
+// secret store, from the manifest, or Application Default Credentials when
+// there are none. With impersonate_service_account set, those credentials
+// impersonate the service account.
+func ClientOptions(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) ([]option.ClientOption, error) {
+	// Emulators take unauthenticated requests
+	if config.Emulated() {
+		logging.Infof("Sending GCP requests to emulator: %s", config.EndpointURL)
+		return []option.ClientOption{
+			option.WithEndpoint(strings.TrimRight(config.EndpointURL, "/") + "/"),
+			option.WithoutAuthentication(),
+		}, nil
+	}
+
+	var credOption option.ClientOption
+	if c := config.Credentials; c != nil && c.Source == "oidc" {
+		// CI jobs exchange their OIDC token with the workload identity provider
+		logging.Infof("Exchanging the CI OIDC token with GCP workload identity provider %s", c.WorkloadIdentityProvider)
+		ts, err := oidcTokenSource(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workload identity credentials: %w", err)
+		}
+		credOption = option.WithTokenSource(ts)
+	} else if config.Credentials.FromSecretStore() {
+		logging.Infof("Loading GCP credentials from %s...", config.Credentials.Source)
+
+		// Get credentials from the secret store using manifest helper
+		vaultCreds, err := m.GetCloudCredentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load GCP credentials from %s: %w", config.Credentials.Source, err)
+		}
+
+		if vaultCreds != nil && vaultCreds.GCP.ServiceAccountKey != "" {
+			logging.Infof("✅ Successfully loaded GCP credentials from %s", config.Credentials.Source)
+			credOption = option.WithCredentialsJSON([]byte(vaultCreds.GCP.ServiceAccountKey))
+		}
+	} else {
+		// Load service account credentials from manifest (existing behavior)
+		var err error
+		credOption, err = loadCredentials(config.Credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials: %w", err)
+		}
+	}
+
+	// Only add credOption if not nil
+	var clientOpts []option.ClientOption
+	if credOption != nil {
+		clientOpts = append(clientOpts, credOption)
+	}
+
+	if c := config.Credentials; c != nil && c.ImpersonateServiceAccount != "" {
+		logging.Infof("Impersonating GCP service account %s", c.ImpersonateServiceAccount)
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: c.ImpersonateServiceAccount,
+			Delegates:       c.ImpersonationDelegates,
+			Scopes:          []string{"https://www.googleapis.com/auth/cloud-platform"},
+		}, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to impersonate service account %s: %w", c.ImpersonateServiceAccount, err)
+		}
+		clientOpts = []option.ClientOption{option.WithTokenSource(ts)}
+	}
+	return clientOpts, nil
+}
+
+// oidcTokenSource returns a token source that exchanges the CI job's OIDC
+// token with the workload identity provider of c for a federated token.
+func oidcTokenSource(ctx context.Context, c *manifest.CredentialsConfig) (oauth2.TokenSource, error) {
+	audience := "//iam.googleapis.com/" + c.WorkloadIdentityProvider
+	return externalaccount.NewTokenSource(ctx, externalaccount.Config{
+		Audience:             audience,
+		SubjectTokenType:     "urn:ietf:params:oauth:token-type:jwt",
+		TokenURL:             "https://sts.googleapis.com/v1/token",
+		Scopes:               []string{"https://www.googleapis.com/auth/cloud-platform"},
+		SubjectTokenSupplier: ciIDToken{creds: c, audience: "https:" + audience},
+	})
+}
+
+// ciIDToken supplies the CI job's OIDC token, requested for the workload
+// identity provider's default audience unless oidc_audience is set.
+type ciIDToken struct {
+	creds    *manifest.CredentialsConfig
+	audience string
+}
+
+// SubjectToken implements externalaccount.SubjectTokenSupplier.
+func (t ciIDToken) SubjectToken(ctx context.Context, _ externalaccount.SupplierOptions) (string, error) {
+	return t.creds.OIDCToken(ctx, t.audience)
+}
+
+// GRPCClientOptions returns the options of gRPC clients such as Cloud Run's:
+// clientOpts, or for an emulator, its host and port without authentication,
+// over plain text when endpoint_url is an http:// URL. Methods that would
+// change anything are refused in read-only mode.
+func GRPCClientOptions(config *manifest.ProviderConfig, clientOpts []option.ClientOption) ([]option.ClientOption, error) {
+	readOnly := option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(readonly.UnaryClientInterceptor))
+	if !config.Emulated() {
+		return append(slices.Clone(clientOpts), readOnly), nil
+	}
+	u, err := url.Parse(config.EndpointURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint_url %q: %w", config.EndpointURL, err)
+	}
+	opts := []option.ClientOption{option.WithEndpoint(u.Host), option.WithoutAuthentication(), readOnly}
+	if u.Scheme == "http" {
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	}
+	return opts, nil
+}
+
+// RESTClientOptions returns the options of REST clients such as Resource
+// Manager's: clientOpts, or in read-only mode an HTTP client authenticated
+// with them that refuses requests that would change anything.
+func RESTClientOptions(ctx context.Context, clientOpts []option.ClientOption) ([]option.ClientOption, error) {
+	if !readonly.Enabled() {
+		return clientOpts, nil
+	}
+	client, _, err := htransport.NewClient(ctx, append(slices.Clone(clientOpts), option.WithScopes("https://www.googleapis.com/auth/cloud-platform"))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP HTTP client: %w", err)
+	}
+	client.Transport = readonly.Transport(client.Transport)
+	// The endpoint of an emulator still applies; credentials are the client's
+	return append(slices.Clone(clientOpts), option.WithHTTPClient(client)), nil
+}
+
 // loadCredentials loads GCP service account credentials from the manifest.
 func loadCredentials(creds *manifest.CredentialsConfig) (option.ClientOption, error) {
 	if creds == nil {
@@ -851,7 +1055,31 @@ func loadCredentials(creds *manifest.CredentialsConfig) (option.ClientOption, er
 		return option.WithCredentialsJSON([]byte(creds.ServiceAccountKeyJSON)), nil
 	}
 
-	return nil, fmt.Errorf("either service_account_key_path, service_account_key_json, or source: environment is required")
+	// Option 4: Exchange an external token with Workload Identity Federation
+	if creds.WorkloadIdentityConfigPath != "" {
+		logging.Infof("Loading Workload Identity Federation credentials from: %s", creds.WorkloadIdentityConfigPath)
+		data, err := os.ReadFile(creds.WorkloadIdentityConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workload identity configuration: %w", err)
+		}
+		var config struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse workload identity configuration %s: %w", creds.WorkloadIdentityConfigPath, err)
+		}
+		if config.Type != "external_account" {
+			return nil, fmt.Errorf("workload identity configuration %s must have type external_account, got %q", creds.WorkloadIdentityConfigPath, config.Type)
+		}
+		return option.WithCredentialsJSON(data), nil
+	}
+
+	// Option 5: Impersonate a service account with Application Default Credentials
+	if creds.ImpersonateServiceAccount != "" {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("either service_account_key_path, service_account_key_json, workload_identity_config_path, impersonate_service_account, or source: environment is required")
 }
 
 // ensureProject creates the GCP project if it doesn't exist.
@@ -926,43 +1154,63 @@ func (p *Provider) ensureBillingLinked(ctx context.Context) error {
 	return nil
 }
 
-// ensureAPIsEnabled enables required APIs for Cloud Run deployment.
-func (p *Provider) ensureAPIsEnabled(ctx context.Context) error {
-	requiredAPIs := []string{
-		"cloudbuild.googleapis.com",
-		"run.googleapis.com",
-		"storage.googleapis.com",
-		"containerregistry.googleapis.com",
-		"serviceusage.googleapis.com",
-	}
+// requiredAPIs are the Google Cloud APIs enabled in the project for Cloud Run deployment.
+var requiredAPIs = []string{
+	"cloudbuild.googleapis.com",
+	"run.googleapis.com",
+	"storage.googleapis.com",
+	"containerregistry.googleapis.com",
+	"serviceusage.googleapis.com",
+	"secretmanager.googleapis.com",
+}
 
+// ensureAPIsEnabled enables the required APIs that are not enabled yet. The
+// APIs are checked with one request and enabled with another, whose operation
+// enables them all at once.
+func (p *Provider) ensureAPIsEnabled(ctx context.Context) error {
 	logging.Info("Enabling required GCP APIs...")
 
-	for _, api := range requiredAPIs {
-		serviceName := fmt.Sprintf("projects/%s/services/%s", p.projectID, api)
-
-		// Check if API is already enabled
-		service, err := p.usageClient.Services.Get(serviceName).Context(ctx).Do()
-		if err == nil && service.State == "ENABLED" {
-			logging.Infof("  ✓ %s (already enabled)", api)
-			continue
-		}
-
-		// Enable the API
-		logging.Infof("  → Enabling %s...", api)
-		enableReq := &serviceusage.EnableServiceRequest{}
-		op, err := p.usageClient.Services.Enable(serviceName, enableReq).Context(ctx).Do()
-		if err != nil {
-			return fmt.Errorf("failed to enable API %s: %w", api, err)
+	parent := "projects/" + p.projectID
+	names := make([]string, len(requiredAPIs))
+	for i, api := range requiredAPIs {
+		names[i] = parent + "/services/" + api
+	}
+	disabled := requiredAPIs
+	// When the APIs cannot be checked, all are enabled; enabling an enabled API does nothing
+	if resp, err := p.usageClient.Services.BatchGet(parent).Names(names...).Context(ctx).Do(); err == nil {
+		enabled := map[string]bool{}
+		for _, service := range resp.Services {
+			// Services are named by project number, so match them by API
+			enabled[path.Base(service.Name)] = service.State == "ENABLED"
 		}
-
-		// Wait for API enablement to complete with polling
-		if !op.Done {
-			if err := p.waitForAPIEnablement(ctx, op.Name, api); err != nil {
-				return fmt.Errorf("failed to wait for API %s enablement: %w", api, err)
+		disabled = nil
+		for _, api := range requiredAPIs {
+			if enabled[api] {
+				logging.Infof("  ✓ %s (already enabled)", api)
+			} else {
+				disabled = append(disabled, api)
 			}
 		}
+	}
+	if len(disabled) == 0 {
+		logging.Info("All required APIs enabled")
+		return nil
+	}
 
+	apis := strings.Join(disabled, ", ")
+	logging.Infof("  → Enabling %s...", apis)
+	op, err := p.usageClient.Services.BatchEnable(parent, &serviceusage.BatchEnableServicesRequest{ServiceIds: disabled}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to enable APIs %s: %w", apis, err)
+	}
+	if !op.Done {
+		if err := p.waitForAPIEnablement(ctx, op.Name, apis); err != nil {
+			return fmt.Errorf("failed to wait for APIs %s to be enabled: %w", apis, err)
+		}
+	} else if op.Error != nil {
+		return fmt.Errorf("failed to enable APIs %s: %s", apis, op.Error.Message)
+	}
+	for _, api := range disabled {
 		logging.Infof("  ✓ %s (enabled)", api)
 	}
 
@@ -1000,17 +1248,23 @@ func (p *Provider) waitForProjectCreation(ctx context.Context, operationName str
 	}
 }
 
-// waitForAPIEnablement polls the API enablement operation until it completes.
-func (p *Provider) waitForAPIEnablement(ctx context.Context, operationName, apiName string) error {
-	ticker := time.NewTicker(5 * time.Second)
+// apiPollInterval is how often the operation enabling APIs is polled. It is a
+// variable so tests can shorten it.
+var apiPollInterval = 5 * time.Second
+
+// waitForAPIEnablement polls the operation enabling apis until it completes.
+func (p *Provider) waitForAPIEnablement(ctx context.Context, operationName, apis string) error {
+	ticker := time.NewTicker(apiPollInterval)
 	defer ticker.Stop()
 
 	timeout := time.After(5 * time.Minute)
 
 	for {
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-timeout:
-			return fmt.Errorf("timeout waiting for API %s enablement (5 minutes elapsed)", apiName)
+			return fmt.Errorf("timeout waiting for APIs %s to be enabled (5 minutes elapsed)", apis)
 		case <-ticker.C:
 			op, err := p.usageClient.Operations.Get(operationName).Context(ctx).Do()
 			if err != nil {
@@ -1021,11 +1275,10 @@ func (p *Provider) waitForAPIEnablement(ctx context.Context, operationName, apiN
 				if op.Error != nil {
 					return fmt.Errorf("API enablement failed: %s", op.Error.Message)
 				}
-				logging.Infof("    API %s enabled successfully", apiName)
 				return nil
 			}
 
-			logging.Infof("    Waiting for %s to be enabled...", apiName)
+			logging.Infof("    Waiting for %s to be enabled...", apis)
 		}
 	}
 }
@@ -1153,8 +1406,9 @@ func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.D
 			break
 		}
 
-		// Check if this revision belongs to our service
-		if strings.Contains(revision.Name, serviceName) {
+		// Check if this revision belongs to our service; maintenance revisions
+		// never ran the application
+		if strings.Contains(revision.Name, serviceName) && revision.Labels[maintenanceLabel] == "" {
 			serviceRevisions = append(serviceRevisions, revision)
 		}
 	}
@@ -1227,7 +1481,7 @@ func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.D
 
 	// Wait for rollback to complete
 	logging.Info("Waiting for rollback to complete...")
-	_, err = op.Wait(ctx)
+	err = p.waitForOperation(ctx, operationServiceUpdate, op)
 	if err != nil {
 		return nil, fmt.Errorf("rollback failed: %w", err)
 	}
@@ -1246,3 +1500,37 @@ func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.D
 		Message:         fmt.Sprintf("Rolled back to revision %s", prevRevisionName),
 	}, nil
 }
+
+// validateImagePlatforms fails early when an image cannot run on Cloud Run,
+// which only runs linux/amd64 containers.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, registry.LinuxAMD64, "Cloud Run", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSecurity warns about security options Cloud Run cannot enforce, and fails
+// if an image runs as root when run_as_non_root is set.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	sec := m.Security
+	if sec == nil {
+		return nil
+	}
+
+	if sec.ReadOnlyRootFilesystem || sec.User != "" || len(sec.DropCapabilities) > 0 {
+		logging.Warn("security.read_only_root_filesystem, user and drop_capabilities are not supported by Cloud Run and will be ignored")
+	}
+
+	if !sec.RunAsNonRoot {
+		return nil
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidateNonRoot(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
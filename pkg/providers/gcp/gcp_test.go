@@ -1,9 +1,23 @@
 package gcp
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"golang.org/x/oauth2/google/externalaccount"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/cache"
 	"github.com/jvreagan/cloud-deploy/pkg/manifest"
 )
 
@@ -49,7 +63,7 @@ func TestLoadCredentials(t *testing.T) {
 			name:        "with empty credentials",
 			creds:       &manifest.CredentialsConfig{},
 			expectError: true,
-			errorMsg:    "either service_account_key_path, service_account_key_json, or source: environment is required",
+			errorMsg:    "either service_account_key_path, service_account_key_json, workload_identity_config_path, impersonate_service_account, or source: environment is required",
 		},
 		{
 			name: "with both path and JSON (path takes precedence)",
@@ -231,6 +245,160 @@ func TestLoadCredentialsPathPrecedenceOverJSON(t *testing.T) {
 	}
 }
 
+func TestClientOptions(t *testing.T) {
+	ctx := context.Background()
+	config := &manifest.ProviderConfig{Name: "gcp", Credentials: &manifest.CredentialsConfig{Source: "environment"}}
+	opts, err := ClientOptions(ctx, config, &manifest.Manifest{Provider: *config})
+	if err != nil || len(opts) != 0 {
+		t.Errorf("Expected no options for Application Default Credentials, got %d (%v)", len(opts), err)
+	}
+
+	config.Credentials = &manifest.CredentialsConfig{ServiceAccountKeyPath: "/path/to/key.json"}
+	if opts, err = ClientOptions(ctx, config, &manifest.Manifest{Provider: *config}); err != nil || len(opts) != 1 {
+		t.Errorf("Expected one credentials option, got %d (%v)", len(opts), err)
+	}
+
+	config.Credentials = &manifest.CredentialsConfig{}
+	if _, err := ClientOptions(ctx, config, &manifest.Manifest{Provider: *config}); err == nil {
+		t.Error("Expected an error without credentials")
+	}
+}
+
+func TestImpersonationClientOptions(t *testing.T) {
+	ctx := context.Background()
+	config := &manifest.ProviderConfig{Name: "gcp", Credentials: &manifest.CredentialsConfig{
+		ServiceAccountKeyJSON:     `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token"}`,
+		ImpersonateServiceAccount: "deployer@my-project.iam.gserviceaccount.com",
+		ImpersonationDelegates:    []string{"ci@my-project.iam.gserviceaccount.com"},
+	}}
+	opts, err := ClientOptions(ctx, config, &manifest.Manifest{Provider: *config})
+	if err != nil || len(opts) != 1 {
+		t.Errorf("Expected one impersonated token source option, got %d (%v)", len(opts), err)
+	}
+}
+
+func TestOIDCClientOptions(t *testing.T) {
+	t.Setenv("CI_JOB_JWT", "ci-jwt")
+	creds := &manifest.CredentialsConfig{
+		Source:                   "oidc",
+		OIDCTokenEnv:             "CI_JOB_JWT",
+		WorkloadIdentityProvider: "projects/123/locations/global/workloadIdentityPools/ci/providers/gitlab",
+	}
+	config := &manifest.ProviderConfig{Name: "gcp", Credentials: creds}
+	opts, err := ClientOptions(context.Background(), config, &manifest.Manifest{Provider: *config})
+	if err != nil || len(opts) != 1 {
+		t.Errorf("Expected one workload identity token source option, got %d (%v)", len(opts), err)
+	}
+
+	token, err := ciIDToken{creds: creds}.SubjectToken(context.Background(), externalaccount.SupplierOptions{})
+	if err != nil || token != "ci-jwt" {
+		t.Errorf("Expected the CI job's token, got %q (%v)", token, err)
+	}
+}
+
+func TestLoadWorkloadIdentityCredentials(t *testing.T) {
+	dir := t.TempDir()
+	external := filepath.Join(dir, "external.json")
+	os.WriteFile(external, []byte(`{"type":"external_account","audience":"//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/ci/providers/github","subject_token_type":"urn:ietf:params:oauth:token-type:jwt","token_url":"https://sts.googleapis.com/v1/token","credential_source":{"file":"/tmp/token"}}`), 0600)
+	key := filepath.Join(dir, "key.json")
+	os.WriteFile(key, []byte(`{"type":"service_account"}`), 0600)
+
+	if opt, err := loadCredentials(&manifest.CredentialsConfig{WorkloadIdentityConfigPath: external}); err != nil || opt == nil {
+		t.Errorf("Expected a credentials option, got %v, %v", opt, err)
+	}
+	if _, err := loadCredentials(&manifest.CredentialsConfig{WorkloadIdentityConfigPath: key}); err == nil || !strings.Contains(err.Error(), "must have type external_account") {
+		t.Errorf("Expected a type error for a service account key, got %v", err)
+	}
+	if _, err := loadCredentials(&manifest.CredentialsConfig{WorkloadIdentityConfigPath: filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("Expected an error for a missing configuration")
+	}
+	if opt, err := loadCredentials(&manifest.CredentialsConfig{ImpersonateServiceAccount: "deployer@my-project.iam.gserviceaccount.com"}); err != nil || opt != nil {
+		t.Errorf("Expected Application Default Credentials to be impersonated, got %v, %v", opt, err)
+	}
+}
+
+func TestEmulatorClientOptions(t *testing.T) {
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// gRPC clients connecting to the emulator open with an HTTP/2 preface
+		if r.Method == "PRI" {
+			return
+		}
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.Write([]byte(`{"projectId":"my-project","lifecycleState":"ACTIVE"}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	config := &manifest.ProviderConfig{Name: "gcp", ProjectID: "my-project", EndpointURL: server.URL}
+	m := &manifest.Manifest{Provider: *config}
+	p, err := New(ctx, config, m)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := p.projectsClient.Projects.Get("my-project").Context(ctx).Do(); err != nil {
+		t.Fatalf("Get project failed: %v", err)
+	}
+	if gotPath != "/v1/projects/my-project" || gotAuth != "" {
+		t.Errorf("Expected an unauthenticated request to the emulator, got %s with Authorization %q", gotPath, gotAuth)
+	}
+
+	opts, err := GRPCClientOptions(config, nil)
+	if err != nil || len(opts) != 4 {
+		t.Errorf("Expected the emulator's host, no authentication, the read-only interceptor, and plain text, got %d options (%v)", len(opts), err)
+	}
+	config.EndpointURL = "https://emulator.example.com"
+	if opts, err = GRPCClientOptions(config, nil); err != nil || len(opts) != 3 {
+		t.Errorf("Expected TLS for an https:// emulator, got %d options (%v)", len(opts), err)
+	}
+	config.EndpointURL = ""
+	if opts, err = GRPCClientOptions(config, nil); err != nil || len(opts) != 1 {
+		t.Errorf("Expected the client options and the read-only interceptor without an emulator, got %d (%v)", len(opts), err)
+	}
+}
+
+func TestNewTracksClients(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	config := &manifest.ProviderConfig{Name: "gcp", ProjectID: "my-project", EndpointURL: server.URL}
+	p, err := New(context.Background(), config, &manifest.Manifest{Provider: *config})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.buildClient == nil || p.runClient == nil || p.revisionsClient == nil || p.storageClient == nil || p.loggingClient == nil ||
+		p.secretsClient == nil || p.projectsClient == nil || p.billingClient == nil || p.usageClient == nil || p.registryClient == nil {
+		t.Fatalf("Expected every client to be created, got %+v", p)
+	}
+	if len(p.closers) != 6 {
+		t.Errorf("Expected the 6 clients holding connections to be tracked, got %d", len(p.closers))
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if len(p.closers) != 0 {
+		t.Error("Expected Close to forget the closed clients")
+	}
+}
+
+func TestClose(t *testing.T) {
+	var closed []string
+	p := &Provider{}
+	p.track(func() error { closed = append(closed, "build"); return nil })
+	p.track(func() error { closed = append(closed, "run"); return errors.New("connection reset") })
+	p.track(func() error { closed = append(closed, "logging"); return nil })
+
+	if err := p.Close(); err == nil || err.Error() != "connection reset" {
+		t.Errorf("Expected the failed close's error, got: %v", err)
+	}
+	if strings.Join(closed, ",") != "logging,run,build" {
+		t.Errorf("Expected every client to be closed in reverse order, got %v", closed)
+	}
+	if err := p.Close(); err != nil || len(closed) != 3 {
+		t.Errorf("Expected closing again to do nothing, got %v and %v", err, closed)
+	}
+}
+
 func TestProviderFields(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -286,3 +454,69 @@ func TestProviderFields(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureAPIsEnabled(t *testing.T) {
+	defer func(d time.Duration) { apiPollInterval = d }(apiPollInterval)
+	apiPollInterval = time.Millisecond
+
+	var requests []string
+	var enabling []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/projects/my-project/services:batchGet":
+			var services []map[string]string
+			for _, name := range r.URL.Query()["names"] {
+				api := name[strings.LastIndex(name, "/")+1:]
+				state := "ENABLED"
+				if api == "run.googleapis.com" || api == "cloudbuild.googleapis.com" {
+					state = "DISABLED"
+				}
+				services = append(services, map[string]string{"name": "projects/123456/services/" + api, "state": state})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"services": services})
+		case "POST /v1/projects/my-project/services:batchEnable":
+			var req serviceusage.BatchEnableServicesRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			enabling = req.ServiceIds
+			fmt.Fprint(w, `{"name":"operations/enable-1","done":false}`)
+		case "GET /v1/operations/enable-1":
+			fmt.Fprint(w, `{"name":"operations/enable-1","done":true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	usageClient, err := serviceusage.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Provider{projectID: "my-project", usageClient: usageClient}
+	if err := p.ensureAPIsEnabled(context.Background()); err != nil {
+		t.Fatalf("ensureAPIsEnabled failed: %v", err)
+	}
+	if strings.Join(enabling, ",") != "cloudbuild.googleapis.com,run.googleapis.com" {
+		t.Errorf("Expected the disabled APIs to be enabled in one batch, got %v", enabling)
+	}
+	if len(requests) != 3 {
+		t.Errorf("Expected one check, one batch enable, and one poll, got %v", requests)
+	}
+}
+
+func TestEnsureProjectReadyCached(t *testing.T) {
+	t.Setenv(cache.EnvPath, filepath.Join(t.TempDir(), "cache.json"))
+	p := &Provider{projectID: "my-project", billingAccount: "123456-123456-123456"}
+	if err := cache.Mark(p.readyCacheKey()); err != nil {
+		t.Fatal(err)
+	}
+	// Without clients, any check would panic
+	if err := p.ensureProjectReady(context.Background()); err != nil {
+		t.Errorf("Expected a cached ready project to skip its checks, got: %v", err)
+	}
+
+	other := &Provider{projectID: "my-project", billingAccount: "654321-654321-654321"}
+	if other.readyCacheKey() == p.readyCacheKey() {
+		t.Error("Expected another billing account to be checked again")
+	}
+}
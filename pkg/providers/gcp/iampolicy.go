@@ -0,0 +1,83 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// roleBinding is a predefined role the deployer needs on a resource.
+type roleBinding struct {
+	Role     string `json:"role"`
+	Resource string `json:"resource"`
+	Reason   string `json:"reason"`
+}
+
+// IAMPolicy returns the roles the deployer's principal needs for the features used
+// in m, each with the resource it must be granted on.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	projectID := m.Provider.ProjectID
+	if projectID == "" {
+		return nil, fmt.Errorf("provider.project_id is required to scope the roles")
+	}
+	project := "projects/" + projectID
+
+	bindings := []roleBinding{
+		{Role: "roles/run.admin", Resource: project, Reason: "Create, update, roll back, and delete Cloud Run services"},
+		{Role: "roles/iam.serviceAccountUser", Resource: project, Reason: "Deploy Cloud Run services that run as the project's service account"},
+		{Role: "roles/artifactregistry.admin", Resource: project, Reason: "Create the Artifact Registry repository and push images"},
+		{Role: "roles/serviceusage.serviceUsageAdmin", Resource: project, Reason: "Enable the APIs Cloud Run deployments need"},
+		{Role: "roles/logging.viewer", Resource: project, Reason: "Read the service's logs for the logs and status -deep commands"},
+		{Role: "roles/browser", Resource: project, Reason: "Check that the project exists"},
+	}
+
+	if m.Provider.BillingAccountID != "" {
+		bindings = append(bindings,
+			roleBinding{Role: "roles/billing.user", Resource: "billingAccounts/" + m.Provider.BillingAccountID, Reason: "Link the billing account to the project"},
+			roleBinding{Role: "roles/billing.projectManager", Resource: project, Reason: "Link the billing account to the project"},
+		)
+	}
+
+	// The project is created when it does not exist, which is granted on its parent
+	parent := "organizations/<ORGANIZATION_ID>"
+	if m.Provider.OrganizationID != "" {
+		parent = "organizations/" + m.Provider.OrganizationID
+	}
+	bindings = append(bindings, roleBinding{
+		Role:     "roles/resourcemanager.projectCreator",
+		Resource: parent,
+		Reason:   "Create the project if it does not exist (not needed for existing projects)",
+	})
+
+	if m.NativeSecrets() {
+		bindings = append(bindings, roleBinding{
+			Role:     "roles/secretmanager.admin",
+			Resource: project,
+			Reason:   "Store secrets in Secret Manager and let the service's service account read them (secret_delivery: native)",
+		})
+	}
+
+	for _, ref := range m.Secrets {
+		if ref.SecretSource() != manifest.SecretSourceGCPSecretManager {
+			continue
+		}
+		bindings = append(bindings, roleBinding{
+			Role:     "roles/secretmanager.secretAccessor",
+			Resource: ref.GCPSecretName(projectID),
+			Reason:   fmt.Sprintf("Read the secret of %s", ref.Name),
+		})
+	}
+
+	if c := m.Provider.Credentials; c != nil && c.Source == "secrets-manager" {
+		bindings = append(bindings, roleBinding{
+			Role:     "roles/iam.serviceAccountKeyAdmin",
+			Resource: project,
+			Reason:   "Create and disable the deployer's own service account keys during credentials-rotate",
+		})
+	}
+
+	return json.MarshalIndent(struct {
+		Bindings []roleBinding `json:"bindings"`
+	}{bindings}, "", "  ")
+}
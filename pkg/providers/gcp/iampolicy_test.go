@@ -0,0 +1,69 @@
+package gcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func policyBindings(t *testing.T, m *manifest.Manifest) map[string]string {
+	t.Helper()
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc struct {
+		Bindings []roleBinding `json:"bindings"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	bindings := make(map[string]string)
+	for _, b := range doc.Bindings {
+		bindings[b.Role] = b.Resource
+	}
+	return bindings
+}
+
+func TestIAMPolicy(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{
+		Name:             "gcp",
+		ProjectID:        "my-project",
+		BillingAccountID: "0X0X0X-0X0X0X-0X0X0X",
+		OrganizationID:   "123456789",
+	}}
+	bindings := policyBindings(t, m)
+
+	expected := map[string]string{
+		"roles/run.admin":                      "projects/my-project",
+		"roles/artifactregistry.admin":         "projects/my-project",
+		"roles/logging.viewer":                 "projects/my-project",
+		"roles/billing.user":                   "billingAccounts/0X0X0X-0X0X0X-0X0X0X",
+		"roles/resourcemanager.projectCreator": "organizations/123456789",
+	}
+	for role, resource := range expected {
+		if bindings[role] != resource {
+			t.Errorf("Expected %s on %s, got %q", role, resource, bindings[role])
+		}
+	}
+	if _, ok := bindings["roles/iam.serviceAccountKeyAdmin"]; ok {
+		t.Error("Unexpected key admin role without secrets-manager credentials")
+	}
+
+	m.Provider.Credentials = &manifest.CredentialsConfig{Source: "secrets-manager", SecretID: "cloud-deploy/gcp"}
+	if _, ok := policyBindings(t, m)["roles/iam.serviceAccountKeyAdmin"]; !ok {
+		t.Error("Expected key admin role for credential rotation")
+	}
+
+	m.Secrets = []manifest.SecretRef{{Name: "API_KEY", Source: manifest.SecretSourceGCPSecretManager, SecretID: "api-key"}}
+	if resource := policyBindings(t, m)["roles/secretmanager.secretAccessor"]; resource != "projects/my-project/secrets/api-key" {
+		t.Errorf("Expected secret accessor on the secret, got %q", resource)
+	}
+}
+
+func TestIAMPolicyRequiresProject(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected error without a project ID")
+	}
+}
@@ -0,0 +1,97 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// inventoryState records the application's existing resources by their full
+// resource names.
+type inventoryState struct {
+	project    string
+	repository string
+	service    string
+	serviceURL string
+}
+
+// Inventory lists the application's resources: the project, the Artifact
+// Registry repository, and the environment's Cloud Run service.
+func (p *Provider) Inventory(ctx context.Context, m *manifest.Manifest) (*types.Inventory, error) {
+	state, err := p.readInventory(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildInventory(m, state), nil
+}
+
+// readInventory reads which of the application's resources exist.
+func (p *Provider) readInventory(ctx context.Context, m *manifest.Manifest) (inventoryState, error) {
+	var state inventoryState
+
+	project, err := p.projectsClient.Projects.Get(p.projectID).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	switch {
+	case err == nil:
+		state.project = fmt.Sprintf("projects/%d", project.ProjectNumber)
+	case errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusForbidden):
+		// Nothing exists in a project that does not exist, and the project
+		// of another account cannot be read
+		return state, nil
+	default:
+		return state, fmt.Errorf("failed to get project %s: %w", p.projectID, err)
+	}
+
+	projectName := fmt.Sprintf("projects/%s", p.projectID)
+	repoName := fmt.Sprintf("%s/locations/%s/repositories/%s", projectName, p.region, m.RepositoryName())
+	_, err = p.registryClient.Projects.Locations.Repositories.Get(repoName).Context(ctx).Do()
+	switch {
+	case err == nil:
+		state.repository = repoName
+	case errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusForbidden):
+		// The repository does not exist, or the Artifact Registry API is disabled
+	default:
+		return state, fmt.Errorf("failed to get Artifact Registry repository %s: %w", m.RepositoryName(), err)
+	}
+
+	serviceName := fmt.Sprintf("%s/locations/%s/services/%s", projectName, p.region, m.Environment.Name)
+	service, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	switch {
+	case err == nil:
+		state.service = serviceName
+		state.serviceURL = service.Uri
+	case status.Code(err) == codes.NotFound || status.Code(err) == codes.PermissionDenied:
+		// The service does not exist, or the Cloud Run API is disabled
+	default:
+		return state, fmt.Errorf("failed to get Cloud Run service %s: %w", m.Environment.Name, err)
+	}
+
+	return state, nil
+}
+
+// buildInventory returns the inventory of the existing resources in state.
+func buildInventory(m *manifest.Manifest, state inventoryState) *types.Inventory {
+	inventory := &types.Inventory{Provider: "gcp"}
+	if state.project != "" {
+		inventory.Add("Project", m.Provider.ProjectID, state.project, types.InventoryByName)
+	}
+	if state.repository != "" {
+		inventory.Add("Artifact Registry repository", m.RepositoryName(), state.repository, types.InventoryByName)
+	}
+	if state.service != "" {
+		inventory.Add("Cloud Run service", m.Environment.Name, state.service, types.InventoryByName)
+		if state.serviceURL != "" {
+			inventory.Add("Cloud Run URL", m.Environment.Name, state.serviceURL, types.InventoryByName)
+		}
+	}
+	return inventory
+}
@@ -0,0 +1,48 @@
+package gcp
+
+import (
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func inventoryManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "gcp", ProjectID: "my-project", Region: "us-central1"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+	}
+}
+
+func TestBuildInventoryEmpty(t *testing.T) {
+	inventory := buildInventory(inventoryManifest(), inventoryState{})
+	if inventory.Provider != "gcp" || len(inventory.Resources) != 0 {
+		t.Errorf("expected no resources, got %+v", inventory)
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	state := inventoryState{
+		project:    "projects/123456789",
+		repository: "projects/my-project/locations/us-central1/repositories/my-app",
+		service:    "projects/my-project/locations/us-central1/services/my-app-prod",
+		serviceURL: "https://my-app-prod-abc-uc.a.run.app",
+	}
+	inventory := buildInventory(inventoryManifest(), state)
+
+	want := []types.InventoryResource{
+		{Type: "Project", Name: "my-project", ID: state.project, Source: types.InventoryByName},
+		{Type: "Artifact Registry repository", Name: "my-app", ID: state.repository, Source: types.InventoryByName},
+		{Type: "Cloud Run service", Name: "my-app-prod", ID: state.service, Source: types.InventoryByName},
+		{Type: "Cloud Run URL", Name: "my-app-prod", ID: state.serviceURL, Source: types.InventoryByName},
+	}
+	if len(inventory.Resources) != len(want) {
+		t.Fatalf("got %d resources, want %d: %+v", len(inventory.Resources), len(want), inventory.Resources)
+	}
+	for i, r := range inventory.Resources {
+		if r != want[i] {
+			t.Errorf("resource %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/logadmin"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Logs prints the Cloud Run service's container output from Cloud Logging.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	serviceName := m.Environment.Name
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		var entries []types.LogEntry
+		it := p.loggingClient.Entries(ctx, logadmin.Filter(serviceLogFilter(p.projectID, serviceName, since)))
+		for {
+			e, err := it.Next()
+			if err == iterator.Done {
+				return entries, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read logs for service %s: %w", serviceName, err)
+			}
+			entries = append(entries, logEntry(e))
+		}
+	})
+}
+
+// serviceLogFilter returns the Cloud Logging filter selecting the stdout and
+// stderr of a Cloud Run service written at or after since.
+func serviceLogFilter(projectID, serviceName string, since time.Time) string {
+	return fmt.Sprintf(`resource.type="cloud_run_revision" AND resource.labels.service_name=%q AND `+
+		`(logName="projects/%s/logs/run.googleapis.com%%2Fstdout" OR logName="projects/%s/logs/run.googleapis.com%%2Fstderr") AND `+
+		`timestamp>=%q`,
+		serviceName, projectID, projectID, since.UTC().Format(time.RFC3339Nano))
+}
+
+// logEntry converts a Cloud Logging entry, labelled with the revision that wrote it.
+func logEntry(e *logging.Entry) types.LogEntry {
+	var source string
+	if e.Resource != nil {
+		source = e.Resource.Labels["revision_name"]
+	}
+	return types.LogEntry{
+		ID:        e.InsertID,
+		Timestamp: e.Timestamp,
+		Source:    source,
+		Message:   payloadString(e.Payload),
+	}
+}
+
+// payloadString formats a log payload. Structured payloads are printed as JSON.
+func payloadString(payload interface{}) string {
+	switch v := payload.(type) {
+	case string:
+		return strings.TrimRight(v, "\n")
+	case proto.Message:
+		if data, err := protojson.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprint(payload)
+	}
+	return string(data)
+}
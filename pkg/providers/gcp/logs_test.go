@@ -0,0 +1,68 @@
+package gcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestServiceLogFilter(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	filter := serviceLogFilter("my-project", "my-service", since)
+
+	for _, want := range []string{
+		`resource.type="cloud_run_revision"`,
+		`resource.labels.service_name="my-service"`,
+		`logName="projects/my-project/logs/run.googleapis.com%2Fstdout"`,
+		`logName="projects/my-project/logs/run.googleapis.com%2Fstderr"`,
+		`timestamp>="2024-05-01T12:00:00Z"`,
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("Filter %q does not contain %q", filter, want)
+		}
+	}
+}
+
+func TestLogEntry(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	e := logEntry(&logging.Entry{
+		InsertID:  "abc",
+		Timestamp: ts,
+		Payload:   "listening on :8080\n",
+		Resource: &mrpb.MonitoredResource{
+			Labels: map[string]string{"revision_name": "my-service-00002-xyz"},
+		},
+	})
+
+	if e.ID != "abc" || !e.Timestamp.Equal(ts) || e.Source != "my-service-00002-xyz" || e.Message != "listening on :8080" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+}
+
+func TestPayloadString(t *testing.T) {
+	structured, err := structpb.NewStruct(map[string]interface{}{"message": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    string
+	}{
+		{"text", "hello\n", "hello"},
+		{"structured", structured, `{"message":"hello"}`},
+		{"other", map[string]int{"count": 1}, `{"count":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.ReplaceAll(payloadString(tt.payload), " ", ""); got != tt.want {
+				t.Errorf("payloadString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// maintenanceAnnotation is the service annotation holding the revision
+// template and traffic the service had before maintenance mode, so they can be
+// restored.
+const maintenanceAnnotation = "cloud-deploy/maintenance"
+
+// maintenanceLabel marks maintenance revisions, which rollback skips.
+const maintenanceLabel = "cloud-deploy-maintenance"
+
+// maintenanceState is the content of the maintenance annotation, with the
+// template and traffic targets in their protobuf JSON form.
+type maintenanceState struct {
+	Template json.RawMessage   `json:"template"`
+	Traffic  []json.RawMessage `json:"traffic"`
+}
+
+// SetMaintenance sends the Cloud Run service's traffic to a revision of
+// maintenance.image when on is true, and restores the revision template and
+// traffic it had before when on is false. The revisions serving the
+// application are kept, ready to take traffic again; a deploy also ends
+// maintenance mode.
+func (p *Provider) SetMaintenance(ctx context.Context, m *manifest.Manifest, on bool) error {
+	serviceName := fmt.Sprintf("projects/%s/locations/%s/services/%s", p.projectID, p.region, m.Environment.Name)
+	service, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	if err != nil {
+		return fmt.Errorf("failed to get service: %w", err)
+	}
+
+	_, active := service.Annotations[maintenanceAnnotation]
+	switch {
+	case on && active:
+		logging.Infof("Service %s is already in maintenance mode", m.Environment.Name)
+		return nil
+	case !on && !active:
+		logging.Infof("Service %s is not in maintenance mode", m.Environment.Name)
+		return nil
+	case on:
+		image := m.Maintenance.CloudRunImage()
+		if image == "" {
+			return fmt.Errorf("maintenance.image is required to serve a maintenance page on Cloud Run")
+		}
+		message, status := m.Maintenance.Response()
+		if err := maintenanceService(service, image, message, status); err != nil {
+			return err
+		}
+		logging.Infof("Sending the traffic of service %s to a maintenance revision of %s...", m.Environment.Name, image)
+	default:
+		if err := restoreService(service); err != nil {
+			return err
+		}
+		logging.Infof("Restoring the traffic of service %s...", m.Environment.Name)
+	}
+
+	op, err := p.runClient.UpdateService(ctx, &runpb.UpdateServiceRequest{Service: service})
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for service update: %w", err)
+	}
+	return nil
+}
+
+// maintenanceService changes service to run image in a new revision labeled
+// as a maintenance revision and send it all traffic, saving the template and
+// traffic it replaces in the maintenance annotation. The revision keeps the
+// template's scaling, service account, and network settings, and gets the
+// maintenance response as MAINTENANCE_MESSAGE and MAINTENANCE_STATUS_CODE.
+func maintenanceService(service *runpb.Service, image, message string, status int) error {
+	if service.Template == nil {
+		return fmt.Errorf("service %s has no revision template", service.Name)
+	}
+	state := maintenanceState{}
+	var err error
+	if state.Template, err = protojson.Marshal(service.Template); err != nil {
+		return fmt.Errorf("failed to save the revision template: %w", err)
+	}
+	for _, t := range service.Traffic {
+		data, err := protojson.Marshal(t)
+		if err != nil {
+			return fmt.Errorf("failed to save the traffic: %w", err)
+		}
+		state.Traffic = append(state.Traffic, data)
+	}
+	annotation, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save the service's state: %w", err)
+	}
+
+	template := proto.Clone(service.Template).(*runpb.RevisionTemplate)
+	template.Revision = ""
+	template.Volumes = nil
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	template.Labels[maintenanceLabel] = "true"
+	template.Containers = []*runpb.Container{{
+		Name:  "maintenance",
+		Image: image,
+		Env: []*runpb.EnvVar{
+			{Name: "MAINTENANCE_MESSAGE", Values: &runpb.EnvVar_Value{Value: message}},
+			{Name: "MAINTENANCE_STATUS_CODE", Values: &runpb.EnvVar_Value{Value: strconv.Itoa(status)}},
+		},
+	}}
+
+	service.Template = template
+	service.Traffic = []*runpb.TrafficTarget{{
+		Type:    runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST,
+		Percent: 100,
+	}}
+	if service.Annotations == nil {
+		service.Annotations = make(map[string]string)
+	}
+	service.Annotations[maintenanceAnnotation] = string(annotation)
+	return nil
+}
+
+// restoreService changes service back to the template and traffic saved in
+// its maintenance annotation and removes the annotation. Cloud Run names the
+// revision of the restored template.
+func restoreService(service *runpb.Service) error {
+	var state maintenanceState
+	if err := json.Unmarshal([]byte(service.Annotations[maintenanceAnnotation]), &state); err != nil {
+		return fmt.Errorf("failed to read annotation %s: %w", maintenanceAnnotation, err)
+	}
+	template := &runpb.RevisionTemplate{}
+	if err := protojson.Unmarshal(state.Template, template); err != nil {
+		return fmt.Errorf("failed to read the saved revision template: %w", err)
+	}
+	template.Revision = ""
+	traffic := make([]*runpb.TrafficTarget, 0, len(state.Traffic))
+	for _, data := range state.Traffic {
+		t := &runpb.TrafficTarget{}
+		if err := protojson.Unmarshal(data, t); err != nil {
+			return fmt.Errorf("failed to read the saved traffic: %w", err)
+		}
+		traffic = append(traffic, t)
+	}
+
+	service.Template = template
+	service.Traffic = traffic
+	delete(service.Annotations, maintenanceAnnotation)
+	return nil
+}
@@ -0,0 +1,70 @@
+package gcp
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+)
+
+func TestMaintenanceService(t *testing.T) {
+	service := &runpb.Service{
+		Name: "projects/p/locations/us-central1/services/web",
+		Template: &runpb.RevisionTemplate{
+			Revision:       "web-v2",
+			ServiceAccount: "app@p.iam.gserviceaccount.com",
+			Scaling:        &runpb.RevisionScaling{MinInstanceCount: 1, MaxInstanceCount: 5},
+			Volumes:        []*runpb.Volume{{Name: "config"}},
+			Containers:     []*runpb.Container{{Image: "us-docker.pkg.dev/p/app/web@sha256:abc"}},
+		},
+		Traffic: []*runpb.TrafficTarget{
+			{Type: runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION, Revision: "web-v1", Percent: 90},
+			{Type: runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST, Percent: 10},
+		},
+	}
+
+	if err := maintenanceService(service, "maintenance:latest", "Back soon", 503); err != nil {
+		t.Fatalf("maintenanceService failed: %v", err)
+	}
+	template := service.Template
+	if len(template.Containers) != 1 || template.Containers[0].Image != "maintenance:latest" || template.Revision != "" || template.Volumes != nil {
+		t.Errorf("Expected a maintenance revision without volumes, got %+v", template)
+	}
+	if template.Labels[maintenanceLabel] != "true" || template.ServiceAccount != "app@p.iam.gserviceaccount.com" || template.Scaling.MaxInstanceCount != 5 {
+		t.Errorf("Expected the label and the service's settings, got %+v", template)
+	}
+	env := template.Containers[0].Env
+	if len(env) != 2 || env[0].GetValue() != "Back soon" || env[1].GetValue() != "503" {
+		t.Errorf("Unexpected environment: %v", env)
+	}
+	if len(service.Traffic) != 1 || service.Traffic[0].Percent != 100 || service.Traffic[0].Type != runpb.TrafficTargetAllocationType_TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST {
+		t.Errorf("Expected all traffic on the maintenance revision, got %v", service.Traffic)
+	}
+
+	if err := restoreService(service); err != nil {
+		t.Fatalf("restoreService failed: %v", err)
+	}
+	if _, ok := service.Annotations[maintenanceAnnotation]; ok {
+		t.Error("Expected the annotation to be removed")
+	}
+	template = service.Template
+	if template.Containers[0].Image != "us-docker.pkg.dev/p/app/web@sha256:abc" || len(template.Volumes) != 1 || template.Revision != "" || template.Labels[maintenanceLabel] != "" {
+		t.Errorf("Expected the application's template, got %+v", template)
+	}
+	if len(service.Traffic) != 2 || service.Traffic[0].Revision != "web-v1" || service.Traffic[0].Percent != 90 || service.Traffic[1].Percent != 10 {
+		t.Errorf("Expected the saved traffic, got %v", service.Traffic)
+	}
+}
+
+func TestRestoreServiceBadAnnotation(t *testing.T) {
+	service := &runpb.Service{Annotations: map[string]string{maintenanceAnnotation: "{"}}
+	if err := restoreService(service); err == nil || !strings.Contains(err.Error(), "failed to read annotation") {
+		t.Errorf("Expected an unreadable annotation error, got: %v", err)
+	}
+}
+
+func TestMaintenanceServiceWithoutTemplate(t *testing.T) {
+	if err := maintenanceService(&runpb.Service{Name: "web"}, "maintenance:latest", "", 503); err == nil || !strings.Contains(err.Error(), "no revision template") {
+		t.Errorf("Expected an error without a template, got: %v", err)
+	}
+}
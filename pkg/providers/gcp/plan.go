@@ -0,0 +1,154 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// planState records which of a deployment's resources already exist.
+type planState struct {
+	project      bool
+	billing      bool
+	disabledAPIs []string
+	repository   bool
+	service      bool
+}
+
+// Plan returns the changes Deploy would make for the manifest without changing
+// the project.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	state, err := p.readPlanState(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildPlan(m, p.projectID, p.region, p.billingAccount, p.publicAccess, state), nil
+}
+
+// readPlanState looks up the deployment's existing resources.
+func (p *Provider) readPlanState(ctx context.Context, m *manifest.Manifest) (planState, error) {
+	var state planState
+
+	// Like ensureProject, any failure to read the project means it will be created
+	if _, err := p.projectsClient.Projects.Get(p.projectID).Context(ctx).Do(); err != nil {
+		state.disabledAPIs = requiredAPIs
+		return state, nil
+	}
+	state.project = true
+
+	projectName := fmt.Sprintf("projects/%s", p.projectID)
+	billingInfo, err := p.billingClient.Projects.GetBillingInfo(projectName).Context(ctx).Do()
+	if err != nil {
+		return state, fmt.Errorf("failed to get billing info: %w", err)
+	}
+	state.billing = billingInfo.BillingEnabled
+
+	runEnabled := true
+	for _, api := range requiredAPIs {
+		service, err := p.usageClient.Services.Get(fmt.Sprintf("%s/services/%s", projectName, api)).Context(ctx).Do()
+		if err == nil && service.State == "ENABLED" {
+			continue
+		}
+		state.disabledAPIs = append(state.disabledAPIs, api)
+		if api == "run.googleapis.com" {
+			runEnabled = false
+		}
+	}
+
+	repoName := fmt.Sprintf("%s/locations/%s/repositories/%s", projectName, p.region, m.RepositoryName())
+	_, err = p.registryClient.Projects.Locations.Repositories.Get(repoName).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	switch {
+	case err == nil:
+		state.repository = true
+	case errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound:
+	default:
+		return state, fmt.Errorf("failed to get Artifact Registry repository %s: %w", m.RepositoryName(), err)
+	}
+
+	// Services cannot exist while the Cloud Run API is disabled
+	if !runEnabled {
+		return state, nil
+	}
+	serviceName := fmt.Sprintf("%s/locations/%s/services/%s", projectName, p.region, m.Environment.Name)
+	_, err = p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	switch {
+	case err == nil:
+		state.service = true
+	case status.Code(err) == codes.NotFound:
+	default:
+		return state, fmt.Errorf("failed to get Cloud Run service %s: %w", m.Environment.Name, err)
+	}
+	return state, nil
+}
+
+// buildPlan returns the changes a deployment of m makes given the existing resources.
+func buildPlan(m *manifest.Manifest, projectID, region, billingAccount string, publicAccess bool, state planState) *types.Plan {
+	plan := &types.Plan{Provider: "gcp"}
+	app := m.Application.Name
+
+	if state.project {
+		plan.Add(types.PlanNoChange, "Project", projectID, "")
+	} else {
+		plan.Add(types.PlanCreate, "Project", projectID, "project does not exist")
+	}
+	if !state.billing {
+		plan.Add(types.PlanUpdate, "Project billing", projectID, "link billing account "+billingAccount)
+	}
+	for _, api := range state.disabledAPIs {
+		plan.Add(types.PlanUpdate, "Service API", api, "enable API")
+	}
+
+	if state.repository {
+		plan.Add(types.PlanNoChange, "Artifact Registry repository", m.RepositoryName(), "")
+	} else {
+		plan.Add(types.PlanCreate, "Artifact Registry repository", m.RepositoryName(), "repository does not exist")
+	}
+	imageAction := types.PlanUpdate
+	if !state.repository {
+		imageAction = types.PlanCreate
+	}
+	registryURL := fmt.Sprintf("%s-docker.pkg.dev/%s/%s", region, projectID, app)
+	if m.IsMultiContainer() {
+		for _, c := range m.Containers {
+			plan.Add(imageAction, "Container image", fmt.Sprintf("%s/%s:%s", registryURL, app, c.Name), "push "+c.Image)
+		}
+	} else {
+		plan.Add(imageAction, "Container image", fmt.Sprintf("%s/%s:latest", registryURL, app), "push "+m.Image)
+	}
+
+	if state.service && m.Deployment.IsCanary() {
+		plan.Add(types.PlanUpdate, "Cloud Run service", m.Environment.Name, fmt.Sprintf("deploy a new revision as a canary, shifting traffic to it in steps of %s", canaryStepsText(m.Deployment.CanarySteps())))
+	} else if state.service {
+		plan.Add(types.PlanUpdate, "Cloud Run service", m.Environment.Name, "deploy a new revision")
+	} else {
+		plan.Add(types.PlanCreate, "Cloud Run service", m.Environment.Name, "service does not exist")
+	}
+	if publicAccess {
+		if state.service {
+			plan.Add(types.PlanNoChange, "Cloud Run IAM policy", m.Environment.Name, "")
+		} else {
+			plan.Add(types.PlanCreate, "Cloud Run IAM policy", m.Environment.Name, "allow unauthenticated invocations")
+		}
+	}
+	return plan
+}
+
+// canaryStepsText formats canary traffic percentages, e.g. "10%, 50%, 100%".
+func canaryStepsText(steps []int32) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = fmt.Sprintf("%d%%", step)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,97 @@
+package gcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func planManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		Image:       "my-app:1.2.0",
+	}
+}
+
+func changeActions(plan *types.Plan) map[string]types.PlanAction {
+	actions := make(map[string]types.PlanAction)
+	for _, c := range plan.Changes {
+		actions[c.Type+" "+c.Name] = c.Action
+	}
+	return actions
+}
+
+func TestBuildPlanNewProject(t *testing.T) {
+	plan := buildPlan(planManifest(), "my-project", "us-central1", "0123-4567", true, planState{disabledAPIs: requiredAPIs})
+
+	actions := changeActions(plan)
+	for key, want := range map[string]types.PlanAction{
+		"Project my-project":                  types.PlanCreate,
+		"Project billing my-project":          types.PlanUpdate,
+		"Service API run.googleapis.com":      types.PlanUpdate,
+		"Artifact Registry repository my-app": types.PlanCreate,
+		"Cloud Run service my-app-prod":       types.PlanCreate,
+		"Cloud Run IAM policy my-app-prod":    types.PlanCreate,
+		"Container image us-central1-docker.pkg.dev/my-project/my-app/my-app:latest": types.PlanCreate,
+	} {
+		if actions[key] != want {
+			t.Errorf("%s: got %q, want %q", key, actions[key], want)
+		}
+	}
+	if plan.Provider != "gcp" {
+		t.Errorf("Provider = %q, want gcp", plan.Provider)
+	}
+}
+
+func TestBuildPlanExistingService(t *testing.T) {
+	state := planState{project: true, billing: true, repository: true, service: true}
+	plan := buildPlan(planManifest(), "my-project", "us-central1", "", false, state)
+
+	if n := plan.Count(types.PlanCreate); n != 0 {
+		t.Errorf("got %d creates for an existing service, want 0: %+v", n, plan.Changes)
+	}
+	actions := changeActions(plan)
+	if actions["Cloud Run service my-app-prod"] != types.PlanUpdate {
+		t.Errorf("service action = %q, want update", actions["Cloud Run service my-app-prod"])
+	}
+	if _, ok := actions["Project billing my-project"]; ok {
+		t.Error("billing is already linked but plan links it")
+	}
+	if _, ok := actions["Cloud Run IAM policy my-app-prod"]; ok {
+		t.Error("private service should not plan an IAM policy")
+	}
+}
+
+func TestBuildPlanMultiContainer(t *testing.T) {
+	m := planManifest()
+	m.Image = ""
+	m.Containers = []manifest.Container{
+		{Name: "web", Image: "web:1"},
+		{Name: "worker", Image: "worker:1"},
+	}
+	plan := buildPlan(m, "my-project", "us-central1", "", true, planState{project: true, billing: true, repository: true})
+
+	actions := changeActions(plan)
+	for _, name := range []string{"web", "worker"} {
+		key := "Container image us-central1-docker.pkg.dev/my-project/my-app/my-app:" + name
+		if actions[key] != types.PlanUpdate {
+			t.Errorf("%s: got %q, want update", key, actions[key])
+		}
+	}
+}
+
+func TestBuildPlanCanary(t *testing.T) {
+	m := planManifest()
+	m.Deployment.Strategy = manifest.StrategyCanary
+	state := planState{project: true, billing: true, repository: true, service: true}
+	plan := buildPlan(m, "my-project", "us-central1", "", false, state)
+
+	for _, c := range plan.Changes {
+		if c.Type == "Cloud Run service" && !strings.Contains(c.Reason, "in steps of 10%, 50%, 100%") {
+			t.Errorf("Expected the canary steps in the service change, got %q", c.Reason)
+		}
+	}
+}
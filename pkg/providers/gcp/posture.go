@@ -0,0 +1,122 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	iam "google.golang.org/api/iam/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Posture checks the service account key the provider authenticates with,
+// the project's billing, and the APIs deployments need. A missing project,
+// unlinked billing, and disabled APIs are warnings since Deploy fixes them.
+func (p *Provider) Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error) {
+	posture := &types.Posture{Provider: "gcp"}
+
+	key, err := serviceAccountKeyJSON(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		posture.Add(types.PostureCredentials, "Application Default Credentials", types.PostureOK, "expiry is managed by the credential source")
+	} else if err := p.addKeyExpiry(ctx, posture, key); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.projectsClient.Projects.Get(p.projectID).Context(ctx).Do(); err != nil {
+		posture.Add(types.PostureBilling, "Project "+p.projectID, types.PostureWarning, "project cannot be read; deploy creates it: "+err.Error())
+		return posture, nil
+	}
+
+	projectName := fmt.Sprintf("projects/%s", p.projectID)
+	billingInfo, err := p.billingClient.Projects.GetBillingInfo(projectName).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing info: %w", err)
+	}
+	if billingInfo.BillingEnabled {
+		posture.Add(types.PostureBilling, "Project billing", types.PostureOK, billingInfo.BillingAccountName)
+	} else {
+		posture.Add(types.PostureBilling, "Project billing", types.PostureWarning, "billing is not enabled; deploy links billing account "+p.billingAccount)
+	}
+
+	for _, api := range requiredAPIs {
+		service, err := p.usageClient.Services.Get(fmt.Sprintf("%s/services/%s", projectName, api)).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get state of API %s: %w", api, err)
+		}
+		if service.State == "ENABLED" {
+			posture.Add(types.PostureAPI, api, types.PostureOK, "enabled")
+		} else {
+			posture.Add(types.PostureAPI, api, types.PostureWarning, "disabled; deploy enables it")
+		}
+	}
+	return posture, nil
+}
+
+// serviceAccountKeyJSON returns the service account key the manifest
+// authenticates with, or "" for Application Default Credentials.
+func serviceAccountKeyJSON(ctx context.Context, m *manifest.Manifest) (string, error) {
+	creds := m.Provider.Credentials
+	switch {
+	case creds == nil || creds.Source == "environment":
+		return "", nil
+	case creds.FromSecretStore():
+		stored, err := m.GetCloudCredentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to load GCP credentials from %s: %w", creds.Source, err)
+		}
+		if stored == nil {
+			return "", nil
+		}
+		return stored.GCP.ServiceAccountKey, nil
+	case creds.ServiceAccountKeyPath != "":
+		data, err := os.ReadFile(creds.ServiceAccountKeyPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account key: %w", err)
+		}
+		return string(data), nil
+	default:
+		return creds.ServiceAccountKeyJSON, nil
+	}
+}
+
+// addKeyExpiry adds a check of the service account key's state and expiry.
+func (p *Provider) addKeyExpiry(ctx context.Context, posture *types.Posture, key string) error {
+	k, err := parseServiceAccountKey(key)
+	if err != nil {
+		return err
+	}
+	service, err := newIAMService(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create IAM client: %w", err)
+	}
+	name := fmt.Sprintf("projects/-/serviceAccounts/%s/keys/%s", k.ClientEmail, k.PrivateKeyID)
+	info, err := service.Projects.ServiceAccounts.Keys.Get(name).Context(ctx).Do()
+	if err != nil {
+		posture.Add(types.PostureCredentials, "Service account key "+k.PrivateKeyID, types.PostureFailing, err.Error())
+		return nil
+	}
+	addKeyCheck(posture, k.PrivateKeyID, info, time.Now())
+	return nil
+}
+
+// addKeyCheck adds the check of a service account key. Keys without an
+// expiry are valid until the year 9999.
+func addKeyCheck(posture *types.Posture, keyID string, key *iam.ServiceAccountKey, now time.Time) {
+	name := "Service account key " + keyID
+	if key.Disabled {
+		posture.Add(types.PostureCredentials, name, types.PostureFailing, "key is disabled")
+		return
+	}
+	expires, err := time.Parse(time.RFC3339, key.ValidBeforeTime)
+	if err != nil || expires.Year() >= 9999 {
+		posture.Add(types.PostureCredentials, name, types.PostureOK, "does not expire")
+		return
+	}
+	posture.AddExpiry(name, expires, now)
+}
@@ -0,0 +1,67 @@
+package gcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	iam "google.golang.org/api/iam/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestServiceAccountKeyJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, []byte(`{"from":"file"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		creds *manifest.CredentialsConfig
+		want  string
+	}{
+		{"no credentials", nil, ""},
+		{"environment", &manifest.CredentialsConfig{Source: "environment"}, ""},
+		{"key file", &manifest.CredentialsConfig{ServiceAccountKeyPath: path}, `{"from":"file"}`},
+		{"key JSON", &manifest.CredentialsConfig{ServiceAccountKeyJSON: `{"from":"manifest"}`}, `{"from":"manifest"}`},
+	}
+	for _, tt := range tests {
+		m := &manifest.Manifest{Provider: manifest.ProviderConfig{Credentials: tt.creds}}
+		got, err := serviceAccountKeyJSON(context.Background(), m)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q (%v), want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Credentials: &manifest.CredentialsConfig{ServiceAccountKeyPath: "/does/not/exist"}}}
+	if _, err := serviceAccountKeyJSON(context.Background(), m); err == nil {
+		t.Error("Expected an error for a missing key file")
+	}
+}
+
+func TestAddKeyCheck(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		key    *iam.ServiceAccountKey
+		status types.PostureStatus
+		detail string
+	}{
+		{"no expiry", &iam.ServiceAccountKey{ValidBeforeTime: "9999-12-31T23:59:59Z"}, types.PostureOK, "does not expire"},
+		{"expiring", &iam.ServiceAccountKey{ValidBeforeTime: "2025-06-03T00:00:00Z"}, types.PostureWarning, "expires 2025-06-03T00:00:00Z"},
+		{"expired", &iam.ServiceAccountKey{ValidBeforeTime: "2025-05-01T00:00:00Z"}, types.PostureFailing, "expired 2025-05-01T00:00:00Z"},
+		{"disabled", &iam.ServiceAccountKey{Disabled: true, ValidBeforeTime: "9999-12-31T23:59:59Z"}, types.PostureFailing, "key is disabled"},
+	}
+	for _, tt := range tests {
+		posture := &types.Posture{}
+		addKeyCheck(posture, "abc123", tt.key, now)
+		c := posture.Checks[0]
+		if c.Name != "Service account key abc123" || c.Status != tt.status || c.Detail != tt.detail {
+			t.Errorf("%s: unexpected check %+v", tt.name, c)
+		}
+	}
+}
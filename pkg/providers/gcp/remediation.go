@@ -0,0 +1,77 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/logadmin"
+	"google.golang.org/api/iterator"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+var (
+	// containerFailurePattern matches the Cloud Run system log messages of a
+	// container instance that stopped and was restarted.
+	containerFailurePattern = regexp.MustCompile(`(?i)container called exit|memory limit of .* exceeded|probe failed|container terminated`)
+
+	// exitCodePattern extracts the exit code from "Container called exit(1)."
+	exitCodePattern = regexp.MustCompile(`exit\((-?\d+)\)`)
+)
+
+// RemediationEvents returns the container exits in the Cloud Run service's
+// revisions since the given time, which Cloud Run recovers from by starting
+// new instances. They are read from the service's system log.
+func (p *Provider) RemediationEvents(ctx context.Context, m *manifest.Manifest, since time.Time) ([]types.RemediationEvent, error) {
+	serviceName := m.Environment.Name
+	var events []types.RemediationEvent
+	it := p.loggingClient.Entries(ctx, logadmin.Filter(systemLogFilter(p.projectID, serviceName, since)))
+	for {
+		e, err := it.Next()
+		if err == iterator.Done {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read system logs for service %s: %w", serviceName, err)
+		}
+		if event, ok := remediationEvent(e); ok {
+			events = append(events, event)
+		}
+	}
+}
+
+// systemLogFilter returns the Cloud Logging filter selecting the messages
+// Cloud Run wrote about a service's instances at or after since.
+func systemLogFilter(projectID, serviceName string, since time.Time) string {
+	return fmt.Sprintf(`resource.type="cloud_run_revision" AND resource.labels.service_name=%q AND `+
+		`logName="projects/%s/logs/run.googleapis.com%%2Fvarlog%%2Fsystem" AND severity>=WARNING AND `+
+		`timestamp>=%q`,
+		serviceName, projectID, since.UTC().Format(time.RFC3339Nano))
+}
+
+// remediationEvent converts a system log entry about a container that
+// stopped. Other entries are skipped.
+func remediationEvent(e *logging.Entry) (types.RemediationEvent, bool) {
+	entry := logEntry(e)
+	if !containerFailurePattern.MatchString(entry.Message) {
+		return types.RemediationEvent{}, false
+	}
+	event := types.RemediationEvent{
+		Timestamp: entry.Timestamp,
+		Kind:      types.RemediationContainerRestarted,
+		Source:    entry.Source,
+		Message:   entry.Message,
+	}
+	if match := exitCodePattern.FindStringSubmatch(entry.Message); match != nil {
+		if code, err := strconv.ParseInt(match[1], 10, 32); err == nil {
+			exitCode := int32(code)
+			event.ExitCode = &exitCode
+		}
+	}
+	return event, true
+}
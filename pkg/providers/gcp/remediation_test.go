@@ -0,0 +1,58 @@
+package gcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestSystemLogFilter(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	filter := systemLogFilter("my-project", "my-service", since)
+
+	for _, want := range []string{
+		`resource.labels.service_name="my-service"`,
+		`logName="projects/my-project/logs/run.googleapis.com%2Fvarlog%2Fsystem"`,
+		`timestamp>="2024-05-01T12:00:00Z"`,
+	} {
+		if !strings.Contains(filter, want) {
+			t.Errorf("Filter %q does not contain %q", filter, want)
+		}
+	}
+}
+
+func TestRemediationEvent(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(message string) *logging.Entry {
+		return &logging.Entry{
+			Timestamp: ts,
+			Payload:   message,
+			Resource:  &mrpb.MonitoredResource{Labels: map[string]string{"revision_name": "my-service-00002-xyz"}},
+		}
+	}
+
+	event, ok := remediationEvent(entry("Container called exit(1)."))
+	if !ok {
+		t.Fatal("Expected a container exit to be reported")
+	}
+	if event.Kind != types.RemediationContainerRestarted || event.Source != "my-service-00002-xyz" || !event.Timestamp.Equal(ts) {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.ExitCode == nil || *event.ExitCode != 1 {
+		t.Errorf("Expected exit code 1, got %v", event.ExitCode)
+	}
+
+	event, ok = remediationEvent(entry("Memory limit of 512 MiB exceeded with 530 MiB used."))
+	if !ok || event.ExitCode != nil {
+		t.Errorf("Expected a memory limit event without exit code, got %+v (ok=%v)", event, ok)
+	}
+
+	if _, ok := remediationEvent(entry("Starting new instance. Reason: AUTOSCALING")); ok {
+		t.Error("Expected autoscaling to be skipped")
+	}
+}
@@ -0,0 +1,105 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2/google"
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// newIAMService creates an IAM client that authenticates with a service account key.
+var newIAMService = func(ctx context.Context, key string) (*iam.Service, error) {
+	return iam.NewService(ctx, option.WithCredentialsJSON([]byte(key)))
+}
+
+// serviceAccountKey holds the fields of a service account JSON key file used for rotation.
+type serviceAccountKey struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+}
+
+// parseServiceAccountKey extracts the service account and key ID from a JSON key file.
+func parseServiceAccountKey(key string) (*serviceAccountKey, error) {
+	var k serviceAccountKey
+	if err := json.Unmarshal([]byte(key), &k); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	if k.ClientEmail == "" || k.PrivateKeyID == "" {
+		return nil, fmt.Errorf("service account key is missing client_email or private_key_id")
+	}
+	return &k, nil
+}
+
+// CreateCredentials creates a new JSON key for the service account that owns the
+// current key.
+func (p *Provider) CreateCredentials(ctx context.Context, m *manifest.Manifest, current *credentials.ProviderCredentials) (*credentials.ProviderCredentials, error) {
+	key, err := parseServiceAccountKey(current.GCP.ServiceAccountKey)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := newIAMService(ctx, current.GCP.ServiceAccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IAM client: %w", err)
+	}
+
+	created, err := service.Projects.ServiceAccounts.Keys.Create(
+		"projects/-/serviceAccounts/"+key.ClientEmail,
+		&iam.CreateServiceAccountKeyRequest{},
+	).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key for service account %s: %w", key.ClientEmail, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(created.PrivateKeyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode service account key: %w", err)
+	}
+	logging.Infof("Created key %s for service account %s", created.Name, key.ClientEmail)
+
+	rotated := *current
+	rotated.GCP.ServiceAccountKey = string(data)
+	return &rotated, nil
+}
+
+// VerifyCredentials checks that the service account key in creds can obtain an access token.
+func (p *Provider) VerifyCredentials(ctx context.Context, m *manifest.Manifest, creds *credentials.ProviderCredentials) error {
+	gcpCreds, err := google.CredentialsFromJSON(ctx, []byte(creds.GCP.ServiceAccountKey), iam.CloudPlatformScope)
+	if err != nil {
+		return fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	return credentials.WaitUntilValid(ctx, func(ctx context.Context) error {
+		_, err := gcpCreds.TokenSource.Token()
+		return err
+	})
+}
+
+// RevokeCredentials disables the revoked service account key. The key is
+// disabled rather than deleted, so it can be re-enabled if something still
+// depends on it.
+func (p *Provider) RevokeCredentials(ctx context.Context, m *manifest.Manifest, active, revoked *credentials.ProviderCredentials) error {
+	key, err := parseServiceAccountKey(revoked.GCP.ServiceAccountKey)
+	if err != nil {
+		return err
+	}
+
+	service, err := newIAMService(ctx, active.GCP.ServiceAccountKey)
+	if err != nil {
+		return fmt.Errorf("failed to create IAM client: %w", err)
+	}
+
+	name := fmt.Sprintf("projects/-/serviceAccounts/%s/keys/%s", key.ClientEmail, key.PrivateKeyID)
+	if _, err := service.Projects.ServiceAccounts.Keys.Disable(name, &iam.DisableServiceAccountKeyRequest{}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to disable service account key %s: %w", key.PrivateKeyID, err)
+	}
+	logging.Infof("Disabled key %s for service account %s", key.PrivateKeyID, key.ClientEmail)
+	return nil
+}
@@ -0,0 +1,90 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
+
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const testServiceAccountKey = `{"type":"service_account","client_email":"deployer@my-project.iam.gserviceaccount.com","private_key_id":"oldkey"}`
+
+func testIAMServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	original := newIAMService
+	newIAMService = func(ctx context.Context, key string) (*iam.Service, error) {
+		return iam.NewService(ctx, option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	}
+	t.Cleanup(func() {
+		newIAMService = original
+		server.Close()
+	})
+}
+
+func TestParseServiceAccountKey(t *testing.T) {
+	key, err := parseServiceAccountKey(testServiceAccountKey)
+	if err != nil {
+		t.Fatalf("parseServiceAccountKey failed: %v", err)
+	}
+	if key.ClientEmail != "deployer@my-project.iam.gserviceaccount.com" || key.PrivateKeyID != "oldkey" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+
+	for _, invalid := range []string{"not-json", `{"client_email":"a@b"}`} {
+		if _, err := parseServiceAccountKey(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestCreateCredentials(t *testing.T) {
+	newKey := `{"type":"service_account","client_email":"deployer@my-project.iam.gserviceaccount.com","private_key_id":"newkey"}`
+	testIAMServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/projects/-/serviceAccounts/deployer@my-project.iam.gserviceaccount.com/keys") {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"name":"projects/my-project/serviceAccounts/deployer/keys/newkey","privateKeyData":"` + base64.StdEncoding.EncodeToString([]byte(newKey)) + `"}`))
+	})
+
+	current := &credentials.ProviderCredentials{}
+	current.GCP.ProjectID = "my-project"
+	current.GCP.ServiceAccountKey = testServiceAccountKey
+
+	rotated, err := (&Provider{}).CreateCredentials(context.Background(), &manifest.Manifest{}, current)
+	if err != nil {
+		t.Fatalf("CreateCredentials failed: %v", err)
+	}
+	if rotated.GCP.ServiceAccountKey != newKey {
+		t.Errorf("Unexpected key: %s", rotated.GCP.ServiceAccountKey)
+	}
+	if rotated.GCP.ProjectID != "my-project" {
+		t.Error("Expected project ID to be preserved")
+	}
+}
+
+func TestRevokeCredentials(t *testing.T) {
+	var path string
+	testIAMServer(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{}`))
+	})
+
+	revoked := &credentials.ProviderCredentials{}
+	revoked.GCP.ServiceAccountKey = testServiceAccountKey
+
+	if err := (&Provider{}).RevokeCredentials(context.Background(), &manifest.Manifest{}, revoked, revoked); err != nil {
+		t.Fatalf("RevokeCredentials failed: %v", err)
+	}
+	if !strings.HasSuffix(path, "/serviceAccounts/deployer@my-project.iam.gserviceaccount.com/keys/oldkey:disable") {
+		t.Errorf("Unexpected request path: %s", path)
+	}
+}
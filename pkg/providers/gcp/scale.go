@@ -0,0 +1,54 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Scale sets the minimum and maximum instance counts of the running Cloud Run service
+// from cloud_run.min_instances and cloud_run.max_instances. The update creates a new
+// revision from the current template, so the image is not redeployed.
+func (p *Provider) Scale(ctx context.Context, m *manifest.Manifest) error {
+	if m.CloudRun == nil || (m.CloudRun.MinInstances == 0 && m.CloudRun.MaxInstances == 0) {
+		return fmt.Errorf("no instance counts to scale to: set -min and -max, or cloud_run.min_instances and cloud_run.max_instances")
+	}
+
+	serviceName := fmt.Sprintf("projects/%s/locations/%s/services/%s", p.projectID, p.region, m.Environment.Name)
+	service, err := p.runClient.GetService(ctx, &runpb.GetServiceRequest{Name: serviceName})
+	if err != nil {
+		return fmt.Errorf("failed to get service: %w", err)
+	}
+	if service.Template == nil {
+		return fmt.Errorf("service %s has no revision template", m.Environment.Name)
+	}
+	service.Template.Scaling = runScaling(service.Template.Scaling, m.CloudRun)
+
+	// Let Cloud Run name the new revision
+	service.Template.Revision = ""
+
+	logging.Infof("Scaling service %s to %d-%d instances...", m.Environment.Name, service.Template.Scaling.MinInstanceCount, service.Template.Scaling.MaxInstanceCount)
+	op, err := p.runClient.UpdateService(ctx, &runpb.UpdateServiceRequest{Service: service})
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	if _, err := op.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for service update: %w", err)
+	}
+	return nil
+}
+
+// runScaling returns the service's scaling with the instance counts of cfg. The
+// minimum is always set, as deploys set it (0 scales the service to zero when it
+// is idle); the current maximum is kept when cfg sets none.
+func runScaling(current *runpb.RevisionScaling, cfg *manifest.CloudRunConfig) *runpb.RevisionScaling {
+	scaling := &runpb.RevisionScaling{MinInstanceCount: cfg.MinInstances, MaxInstanceCount: cfg.MaxInstances}
+	if scaling.MaxInstanceCount == 0 && current != nil {
+		scaling.MaxInstanceCount = current.MaxInstanceCount
+	}
+	return scaling
+}
@@ -0,0 +1,38 @@
+package gcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/run/apiv2/runpb"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestRunScaling(t *testing.T) {
+	current := &runpb.RevisionScaling{MinInstanceCount: 1, MaxInstanceCount: 20}
+
+	scaling := runScaling(current, &manifest.CloudRunConfig{MinInstances: 2, MaxInstances: 10})
+	if scaling.MinInstanceCount != 2 || scaling.MaxInstanceCount != 10 {
+		t.Errorf("Expected 2-10 instances, got %d-%d", scaling.MinInstanceCount, scaling.MaxInstanceCount)
+	}
+
+	// Without a maximum, the service keeps its current one
+	scaling = runScaling(current, &manifest.CloudRunConfig{MinInstances: 0})
+	if scaling.MinInstanceCount != 0 || scaling.MaxInstanceCount != 20 {
+		t.Errorf("Expected 0-20 instances, got %d-%d", scaling.MinInstanceCount, scaling.MaxInstanceCount)
+	}
+
+	scaling = runScaling(nil, &manifest.CloudRunConfig{MaxInstances: 5})
+	if scaling.MinInstanceCount != 0 || scaling.MaxInstanceCount != 5 {
+		t.Errorf("Expected 0-5 instances, got %d-%d", scaling.MinInstanceCount, scaling.MaxInstanceCount)
+	}
+}
+
+func TestScaleWithoutInstanceCounts(t *testing.T) {
+	m := &manifest.Manifest{Environment: manifest.EnvironmentConfig{Name: "my-service"}}
+	if err := (&Provider{}).Scale(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no instance counts") {
+		t.Errorf("Expected an error without instance counts, got: %v", err)
+	}
+}
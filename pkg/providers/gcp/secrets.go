@@ -0,0 +1,204 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/run/apiv2/runpb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// secretAccessorRole lets the service's runtime service account read a secret.
+const secretAccessorRole = "roles/secretmanager.secretAccessor"
+
+// secretManagerAPI is the subset of the Secret Manager client used to store
+// secrets delivered natively.
+type secretManagerAPI interface {
+	GetSecret(ctx context.Context, req *secretmanagerpb.GetSecretRequest, opts ...gax.CallOption) (*secretmanagerpb.Secret, error)
+	CreateSecret(ctx context.Context, req *secretmanagerpb.CreateSecretRequest, opts ...gax.CallOption) (*secretmanagerpb.Secret, error)
+	AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error)
+	AddSecretVersion(ctx context.Context, req *secretmanagerpb.AddSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.SecretVersion, error)
+	GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error)
+	SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error)
+}
+
+// secretVersion is a version of a secret in Secret Manager, as Cloud Run
+// references it.
+type secretVersion struct {
+	secret  string
+	version string
+}
+
+// storeSecrets stores the secrets of a manifest with secret_delivery native,
+// whose values are in values, in Secret Manager, one secret per variable named
+// after the service (e.g., my-env_DB_PASSWORD), and returns their versions
+// keyed by variable name. A version is added only when the value changed, and
+// the service's runtime service account is allowed to read the secrets. Other
+// manifests store none.
+func (p *Provider) storeSecrets(ctx context.Context, m *manifest.Manifest, values map[string]string) (map[string]secretVersion, error) {
+	if !m.NativeSecrets() {
+		return nil, nil
+	}
+	member, err := p.runtimeServiceAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]secretVersion, len(m.Secrets))
+	for _, ref := range m.Secrets {
+		id := secretID(m.Environment.Name, ref.Name)
+		name := fmt.Sprintf("projects/%s/secrets/%s", p.projectID, id)
+		if err := p.ensureSecret(ctx, name, id); err != nil {
+			return nil, fmt.Errorf("failed to store secret %s: %w", ref.Name, err)
+		}
+		version, err := p.ensureSecretVersion(ctx, name, values[ref.Name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to store secret %s: %w", ref.Name, err)
+		}
+		if err := p.grantSecretAccess(ctx, name, member); err != nil {
+			return nil, fmt.Errorf("failed to store secret %s: %w", ref.Name, err)
+		}
+		versions[ref.Name] = secretVersion{secret: id, version: version}
+	}
+	logging.Infof("Stored %d secrets in Secret Manager", len(versions))
+	return versions, nil
+}
+
+// secretID returns the ID of the Secret Manager secret holding the variable
+// name of the service env.
+func secretID(env, name string) string {
+	return env + "_" + name
+}
+
+// ensureSecret creates the secret name unless it exists.
+func (p *Provider) ensureSecret(ctx context.Context, name, id string) error {
+	_, err := p.secretsClient.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: name})
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+	logging.Infof("Creating secret: %s", id)
+	_, err = p.secretsClient.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+		Parent:   "projects/" + p.projectID,
+		SecretId: id,
+		Secret: &secretmanagerpb.Secret{
+			Replication: &secretmanagerpb.Replication{
+				Replication: &secretmanagerpb.Replication_Automatic_{Automatic: &secretmanagerpb.Replication_Automatic{}},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create secret: %w", err)
+	}
+	return nil
+}
+
+// ensureSecretVersion returns the number of the latest version of the secret
+// name if it holds value, or of a new version holding it.
+func (p *Provider) ensureSecretVersion(ctx context.Context, name, value string) (string, error) {
+	latest, err := p.secretsClient.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name + "/versions/latest"})
+	switch {
+	case err == nil && string(latest.GetPayload().GetData()) == value:
+		return path.Base(latest.Name), nil
+	case err != nil && status.Code(err) != codes.NotFound && status.Code(err) != codes.FailedPrecondition:
+		return "", fmt.Errorf("failed to read the latest version: %w", err)
+	}
+
+	version, err := p.secretsClient.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  name,
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(value)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to add a version: %w", err)
+	}
+	return path.Base(version.Name), nil
+}
+
+// grantSecretAccess allows member to read the secret name, unless it can.
+func (p *Provider) grantSecretAccess(ctx context.Context, name, member string) error {
+	policy, err := p.secretsClient.GetIamPolicy(ctx, &iampb.GetIamPolicyRequest{Resource: name})
+	if err != nil {
+		return fmt.Errorf("failed to get IAM policy: %w", err)
+	}
+	i := slices.IndexFunc(policy.Bindings, func(b *iampb.Binding) bool { return b.Role == secretAccessorRole })
+	switch {
+	case i < 0:
+		policy.Bindings = append(policy.Bindings, &iampb.Binding{Role: secretAccessorRole, Members: []string{member}})
+	case slices.Contains(policy.Bindings[i].Members, member):
+		return nil
+	default:
+		policy.Bindings[i].Members = append(policy.Bindings[i].Members, member)
+	}
+	if _, err := p.secretsClient.SetIamPolicy(ctx, &iampb.SetIamPolicyRequest{Resource: name, Policy: policy}); err != nil {
+		return fmt.Errorf("failed to set IAM policy: %w", err)
+	}
+	return nil
+}
+
+// runtimeServiceAccount returns the IAM member of the service account Cloud
+// Run services run as: the project's Compute Engine default service account.
+func (p *Provider) runtimeServiceAccount(ctx context.Context) (string, error) {
+	project, err := p.projectsClient.Projects.Get(p.projectID).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to get project %s: %w", p.projectID, err)
+	}
+	return fmt.Sprintf("serviceAccount:%d-compute@developer.gserviceaccount.com", project.ProjectNumber), nil
+}
+
+// runEnv returns the environment variables env of a Cloud Run container.
+// Variables stored in Secret Manager reference their secret versions.
+func runEnv(env map[string]string, versions map[string]secretVersion) []*runpb.EnvVar {
+	envVars := make([]*runpb.EnvVar, 0, len(env))
+	for key, value := range env {
+		if v, ok := versions[key]; ok {
+			envVars = append(envVars, &runpb.EnvVar{Name: key, Values: secretEnvSource(v)})
+			continue
+		}
+		envVars = append(envVars, &runpb.EnvVar{
+			Name: key,
+			Values: &runpb.EnvVar_Value{
+				Value: value,
+			},
+		})
+	}
+	return envVars
+}
+
+// secretEnvSource returns the source of a variable read from the secret version v.
+func secretEnvSource(v secretVersion) *runpb.EnvVar_ValueSource {
+	return &runpb.EnvVar_ValueSource{ValueSource: &runpb.EnvVarSource{
+		SecretKeyRef: &runpb.SecretKeySelector{Secret: v.secret, Version: v.version},
+	}}
+}
+
+// setRunSecretEnv points the variables of env stored in Secret Manager at
+// their versions, and returns env and the names of the variables it changed.
+func setRunSecretEnv(env []*runpb.EnvVar, versions map[string]secretVersion) ([]*runpb.EnvVar, []string) {
+	var changed []string
+	for name, v := range versions {
+		i := slices.IndexFunc(env, func(e *runpb.EnvVar) bool { return e.Name == name })
+		if i < 0 {
+			env = append(env, &runpb.EnvVar{Name: name, Values: secretEnvSource(v)})
+			changed = append(changed, name)
+			continue
+		}
+		ref := env[i].GetValueSource().GetSecretKeyRef()
+		if ref.GetSecret() != v.secret || ref.GetVersion() != v.version {
+			env[i].Values = secretEnvSource(v)
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return env, changed
+}
@@ -0,0 +1,121 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/run/apiv2/runpb"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSecretManager holds the versions and IAM policies of secrets in memory.
+type fakeSecretManager struct {
+	secrets  map[string][]string
+	policies map[string]*iampb.Policy
+	setCalls int
+}
+
+func (f *fakeSecretManager) GetSecret(ctx context.Context, req *secretmanagerpb.GetSecretRequest, opts ...gax.CallOption) (*secretmanagerpb.Secret, error) {
+	if _, ok := f.secrets[req.Name]; !ok {
+		return nil, status.Error(codes.NotFound, "secret not found")
+	}
+	return &secretmanagerpb.Secret{Name: req.Name}, nil
+}
+
+func (f *fakeSecretManager) CreateSecret(ctx context.Context, req *secretmanagerpb.CreateSecretRequest, opts ...gax.CallOption) (*secretmanagerpb.Secret, error) {
+	name := req.Parent + "/secrets/" + req.SecretId
+	f.secrets[name] = nil
+	return &secretmanagerpb.Secret{Name: name}, nil
+}
+
+func (f *fakeSecretManager) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	secret := req.Name[:len(req.Name)-len("/versions/latest")]
+	versions := f.secrets[secret]
+	if len(versions) == 0 {
+		return nil, status.Error(codes.NotFound, "no versions")
+	}
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    fmt.Sprintf("%s/versions/%d", secret, len(versions)),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(versions[len(versions)-1])},
+	}, nil
+}
+
+func (f *fakeSecretManager) AddSecretVersion(ctx context.Context, req *secretmanagerpb.AddSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.SecretVersion, error) {
+	f.secrets[req.Parent] = append(f.secrets[req.Parent], string(req.Payload.Data))
+	return &secretmanagerpb.SecretVersion{Name: fmt.Sprintf("%s/versions/%d", req.Parent, len(f.secrets[req.Parent]))}, nil
+}
+
+func (f *fakeSecretManager) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	if policy, ok := f.policies[req.Resource]; ok {
+		return policy, nil
+	}
+	return &iampb.Policy{}, nil
+}
+
+func (f *fakeSecretManager) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest, opts ...gax.CallOption) (*iampb.Policy, error) {
+	f.setCalls++
+	f.policies[req.Resource] = req.Policy
+	return req.Policy, nil
+}
+
+func TestStoreSecretVersions(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSecretManager{secrets: make(map[string][]string), policies: make(map[string]*iampb.Policy)}
+	p := &Provider{projectID: "my-project", secretsClient: fake}
+	name := "projects/my-project/secrets/my-env_DB_PASSWORD"
+	member := "serviceAccount:123-compute@developer.gserviceaccount.com"
+
+	if err := p.ensureSecret(ctx, name, "my-env_DB_PASSWORD"); err != nil {
+		t.Fatalf("ensureSecret() error = %v", err)
+	}
+	for i, tt := range []struct{ value, want string }{{"one", "1"}, {"one", "1"}, {"two", "2"}} {
+		version, err := p.ensureSecretVersion(ctx, name, tt.value)
+		if err != nil || version != tt.want {
+			t.Errorf("ensureSecretVersion(%d) = %s, %v, want version %s", i, version, err, tt.want)
+		}
+	}
+
+	for range 2 {
+		if err := p.grantSecretAccess(ctx, name, member); err != nil {
+			t.Fatalf("grantSecretAccess() error = %v", err)
+		}
+	}
+	bindings := fake.policies[name].Bindings
+	if fake.setCalls != 1 || len(bindings) != 1 || bindings[0].Role != secretAccessorRole || bindings[0].Members[0] != member {
+		t.Errorf("Expected one accessor binding set once, got %v after %d calls", bindings, fake.setCalls)
+	}
+}
+
+func TestRunEnvSecrets(t *testing.T) {
+	versions := map[string]secretVersion{"DB_PASSWORD": {secret: "my-env_DB_PASSWORD", version: "3"}}
+	env := runEnv(map[string]string{"LOG_LEVEL": "info", "DB_PASSWORD": "secret"}, versions)
+
+	values := runEnvValues(env)
+	if len(values) != 1 || values["LOG_LEVEL"] != "info" {
+		t.Errorf("Expected only LOG_LEVEL as a value, got %v", values)
+	}
+
+	env, changed := setRunSecretEnv(env, versions)
+	if len(changed) != 0 {
+		t.Errorf("Expected no changes, got %v", changed)
+	}
+	versions["DB_PASSWORD"] = secretVersion{secret: "my-env_DB_PASSWORD", version: "4"}
+	versions["API_KEY"] = secretVersion{secret: "my-env_API_KEY", version: "1"}
+	env, changed = setRunSecretEnv(env, versions)
+	if len(env) != 3 || len(changed) != 2 || changed[0] != "API_KEY" || changed[1] != "DB_PASSWORD" {
+		t.Fatalf("Expected API_KEY and DB_PASSWORD to change, got %v", changed)
+	}
+	for _, e := range env {
+		if e.Name == "DB_PASSWORD" {
+			ref := e.Values.(*runpb.EnvVar_ValueSource).ValueSource.SecretKeyRef
+			if ref.Secret != "my-env_DB_PASSWORD" || ref.Version != "4" {
+				t.Errorf("Expected DB_PASSWORD to reference version 4, got %v", ref)
+			}
+		}
+	}
+}
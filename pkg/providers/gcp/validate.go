@@ -0,0 +1,136 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// regionName matches GCP region names, e.g. us-central1 or
+// northamerica-northeast2.
+var regionName = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+
+// Cloud Run memory limits, in bytes.
+const (
+	mebibyte          = 1 << 20
+	gibibyte          = 1 << 30
+	cloudRunMinMemory = 128 * mebibyte
+	cloudRunMaxMemory = 32 * gibibyte
+)
+
+// cloudRunMinCPU is the least CPU Cloud Run allows with memory above each
+// limit, from the largest limit down.
+var cloudRunMinCPU = []struct {
+	memory int64
+	cpu    float64
+}{
+	{24 * gibibyte, 8},
+	{16 * gibibyte, 6},
+	{8 * gibibyte, 4},
+	{4 * gibibyte, 2},
+	{1 * gibibyte, 1},
+	{512 * mebibyte, 0.5},
+}
+
+// Validate checks the region name and that the Cloud Run CPU and memory are a
+// combination Cloud Run accepts. Both are checked from Cloud Run's documented
+// limits, without calling the API.
+func (p *Provider) Validate(ctx context.Context, m *manifest.Manifest) ([]types.ValidationFinding, error) {
+	var findings []types.ValidationFinding
+	if !regionName.MatchString(p.region) {
+		findings = append(findings, types.ValidationFinding{Provider: "gcp", Field: "provider.region", Message: fmt.Sprintf("%q is not a GCP region name (e.g., us-central1)", p.region)})
+	}
+	return append(findings, cloudRunResourceFindings(m.CloudRun)...), nil
+}
+
+// cloudRunResourceFindings checks the CPU and memory of c, with Cloud Run's
+// defaults for those not set.
+func cloudRunResourceFindings(c *manifest.CloudRunConfig) []types.ValidationFinding {
+	cpuValue, memoryValue := "1", "512Mi"
+	if c != nil && c.CPU != "" {
+		cpuValue = c.CPU
+	}
+	if c != nil && c.Memory != "" {
+		memoryValue = c.Memory
+	}
+
+	var findings []types.ValidationFinding
+	add := func(field, format string, args ...any) {
+		findings = append(findings, types.ValidationFinding{Provider: "gcp", Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	cpu, err := parseCPU(cpuValue)
+	cpuValid := err == nil && cpu >= 0.08 && (cpu <= 1 || cpu == 2 || cpu == 4 || cpu == 6 || cpu == 8)
+	switch {
+	case err != nil:
+		add("cloud_run.cpu", "%v", err)
+	case !cpuValid:
+		add("cloud_run.cpu", "%s must be between 0.08 and 1, or 2, 4, 6, or 8", cpuValue)
+	}
+	memory, err := parseMemory(memoryValue)
+	if err != nil {
+		add("cloud_run.memory", "%v", err)
+		return findings
+	}
+	if memory < cloudRunMinMemory || memory > cloudRunMaxMemory {
+		add("cloud_run.memory", "%s must be between 128Mi and 32Gi", memoryValue)
+		return findings
+	}
+	if !cpuValid {
+		return findings
+	}
+	for _, limit := range cloudRunMinCPU {
+		if memory > limit.memory {
+			if cpu < limit.cpu {
+				add("cloud_run.memory", "%s of memory needs at least %s CPU, got %s", memoryValue, strconv.FormatFloat(limit.cpu, 'f', -1, 64), cpuValue)
+			}
+			break
+		}
+	}
+	return findings
+}
+
+// parseCPU parses a Kubernetes CPU quantity, e.g. "2", "0.5", or "500m".
+func parseCPU(value string) (float64, error) {
+	scale := 1.0
+	number := value
+	if strings.HasSuffix(value, "m") {
+		scale, number = 0.001, strings.TrimSuffix(value, "m")
+	}
+	cpu, err := strconv.ParseFloat(number, 64)
+	if err != nil || cpu <= 0 {
+		return 0, fmt.Errorf("%q is not a CPU quantity (e.g., \"1\", \"0.5\", or \"500m\")", value)
+	}
+	return cpu * scale, nil
+}
+
+// memorySuffixes are the units of Kubernetes memory quantities, binary first
+// so "Mi" is not taken for "M".
+var memorySuffixes = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+}
+
+// parseMemory parses a Kubernetes memory quantity in bytes, e.g. "512Mi",
+// "2Gi", or "1G".
+func parseMemory(value string) (int64, error) {
+	number, scale := value, 1.0
+	for _, s := range memorySuffixes {
+		if strings.HasSuffix(value, s.suffix) {
+			number, scale = strings.TrimSuffix(value, s.suffix), s.bytes
+			break
+		}
+	}
+	bytes, err := strconv.ParseFloat(number, 64)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("%q is not a memory quantity (e.g., \"512Mi\" or \"2Gi\")", value)
+	}
+	return int64(bytes * scale), nil
+}
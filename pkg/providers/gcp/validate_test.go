@@ -0,0 +1,56 @@
+package gcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestCloudRunResourceFindings(t *testing.T) {
+	tests := []struct {
+		name        string
+		cpu, memory string
+		want        []string
+	}{
+		{name: "defaults"},
+		{name: "fractional", cpu: "500m", memory: "512Mi"},
+		{name: "large", cpu: "8", memory: "32Gi"},
+		{name: "decimal memory", cpu: "2", memory: "4G"},
+		{name: "cpu not offered", cpu: "3", want: []string{"cloud_run.cpu: 3 must be between 0.08 and 1, or 2, 4, 6, or 8"}},
+		{name: "bad quantities", cpu: "one", memory: "lots", want: []string{`cloud_run.cpu: "one" is not a CPU quantity`, `cloud_run.memory: "lots" is not a memory quantity`}},
+		{name: "too little memory", memory: "64Mi", want: []string{"cloud_run.memory: 64Mi must be between 128Mi and 32Gi"}},
+		{name: "too little cpu", cpu: "2", memory: "16Gi", want: []string{"cloud_run.memory: 16Gi of memory needs at least 4 CPU, got 2"}},
+		{name: "fractional cpu", cpu: "0.25", memory: "1Gi", want: []string{"needs at least 0.5 CPU, got 0.25"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := cloudRunResourceFindings(&manifest.CloudRunConfig{CPU: tt.cpu, Memory: tt.memory})
+			if len(findings) != len(tt.want) {
+				t.Fatalf("Expected %d findings, got %+v", len(tt.want), findings)
+			}
+			for i, f := range findings {
+				if got := f.Field + ": " + f.Message; !strings.Contains(got, tt.want[i]) {
+					t.Errorf("Finding %d = %q, want it to contain %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := &manifest.Manifest{CloudRun: &manifest.CloudRunConfig{CPU: "1", Memory: "8Gi"}}
+	findings, err := (&Provider{region: "us-central-1"}).Validate(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if len(findings) != 2 || findings[0].Field != "provider.region" || findings[1].Field != "cloud_run.memory" {
+		t.Errorf("Expected region and memory findings, got %+v", findings)
+	}
+
+	findings, _ = (&Provider{region: "northamerica-northeast2"}).Validate(context.Background(), &manifest.Manifest{})
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v", findings)
+	}
+}
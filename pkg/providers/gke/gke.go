@@ -0,0 +1,549 @@
+// Package gke provides a Google Kubernetes Engine provider for workloads that
+// outgrow Cloud Run. It runs the manifest's images on a GKE cluster the way
+// the kubernetes provider does on any cluster: as a Deployment, a Service,
+// and an optional Ingress named after the environment. An Autopilot cluster
+// is created when the cluster doesn't exist, and images are pushed to
+// Artifact Registry as for Cloud Run and deployed by digest.
+package gke
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
+	container "google.golang.org/api/container/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+	"google.golang.org/api/transport"
+
+	"github.com/jvreagan/cloud-deploy/pkg/kubeapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/gcp"
+	"github.com/jvreagan/cloud-deploy/pkg/providers/kubernetes"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// cloudPlatformScope is the OAuth scope of the tokens the cluster's API
+// server is called with, as gke-gcloud-auth-plugin requests.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Labels marking what cloud-deploy created: the resource label of clusters it
+// created, and the label of the Deployments it applies.
+const (
+	clusterLabel    = "managed-by"
+	deploymentLabel = "app.kubernetes.io/managed-by"
+	managedBy       = "cloud-deploy"
+)
+
+// requiredAPIs are the Google Cloud APIs enabled in the project for GKE deployments.
+var requiredAPIs = []string{
+	"container.googleapis.com",
+	"artifactregistry.googleapis.com",
+}
+
+// pollInterval is how often a cluster or cluster operation is checked while waiting for it.
+var pollInterval = 10 * time.Second
+
+// clusterTimeout is how long to wait for GKE to create, repair, or delete a cluster.
+var clusterTimeout = 30 * time.Minute
+
+// Provider implements the provider.Provider interface for GKE.
+type Provider struct {
+	projectID       string
+	region          string
+	clusterName     string
+	clientOpts      []option.ClientOption
+	containerClient *container.Service
+	usageClient     *serviceusage.Service
+	registryClient  *artifactregistry.Service
+	tokenSource     oauth2.TokenSource
+}
+
+// New creates a new GKE provider instance. Credentials are loaded as they
+// are for the gcp provider; the project must already exist.
+//
+// Creating the provider does not change anything in the project. On deploy it
+// enables the GKE and Artifact Registry APIs and creates the cluster if needed.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	if config.ProjectID == "" {
+		return nil, fmt.Errorf("provider.project_id is required in manifest for GKE deployments")
+	}
+	logging.Infof("Initializing GKE provider for project: %s", config.ProjectID)
+
+	clientOpts, err := gcp.ClientOptions(ctx, config, m)
+	if err != nil {
+		return nil, err
+	}
+	restOpts, err := gcp.RESTClientOptions(ctx, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	containerClient, err := container.NewService(ctx, restOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GKE client: %w", err)
+	}
+	usageClient, err := serviceusage.NewService(ctx, restOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Service Usage client: %w", err)
+	}
+	registryClient, err := artifactregistry.NewService(ctx, restOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Artifact Registry client: %w", err)
+	}
+	tokenSource, err := clusterTokenSource(ctx, config, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		projectID:       config.ProjectID,
+		region:          config.Region,
+		clusterName:     m.GKE.ClusterName(m.Application.Name),
+		clientOpts:      clientOpts,
+		containerClient: containerClient,
+		usageClient:     usageClient,
+		registryClient:  registryClient,
+		tokenSource:     tokenSource,
+	}, nil
+}
+
+// clusterTokenSource returns the source of the tokens the cluster is called
+// with: the GCP credentials, or a fixed token for an emulator, whose clusters
+// accept any.
+func clusterTokenSource(ctx context.Context, config *manifest.ProviderConfig, clientOpts []option.ClientOption) (oauth2.TokenSource, error) {
+	if config.Emulated() {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "emulator"}), nil
+	}
+	creds, err := transport.Creds(ctx, append(clientOpts, option.WithScopes(cloudPlatformScope))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GCP credentials: %w", err)
+	}
+	return creds.TokenSource, nil
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "gcp-gke"
+}
+
+// Close does nothing: the REST clients' connections are pooled by the
+// transport they are created with, and time out when idle.
+func (p *Provider) Close() error {
+	return nil
+}
+
+// Deploy deploys an application to the GKE cluster.
+// This method:
+// 1. Enables the GKE and Artifact Registry APIs
+// 2. Creates an Autopilot cluster if the cluster doesn't exist
+// 3. Pushes the images to Artifact Registry
+// 4. Applies the Deployment, Service, and Ingress running the pushed images
+//
+// The images are deployed by digest, so every push of a new image rolls out
+// new pods even when its tag is unchanged.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, registry.LinuxAMD64, "GKE", ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.ensureAPIsEnabled(ctx); err != nil {
+		return nil, fmt.Errorf("failed to enable required APIs: %w", err)
+	}
+	cluster, err := p.ensureCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Info("=== Distributing images to Artifact Registry ===")
+	pushed, err := p.pushImages(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	workload, err := p.workload(cluster, m)
+	if err != nil {
+		return nil, err
+	}
+	return workload.Deploy(ctx, pushed)
+}
+
+// Destroy deletes the Ingress, Service, and Deployment of the environment.
+// The cluster is deleted with them when cloud-deploy created it and no other
+// deployment of cloud-deploy runs in it; the Artifact Registry repository is
+// kept with the images of every environment.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	cluster, err := p.getCluster(ctx)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		logging.Infof("GKE cluster %s does not exist; nothing to destroy", p.clusterName)
+		return nil
+	}
+	workload, err := p.workload(cluster, m)
+	if err != nil {
+		return err
+	}
+	if err := workload.Destroy(ctx, m); err != nil {
+		return err
+	}
+
+	if cluster.ResourceLabels[clusterLabel] != managedBy {
+		logging.Infof("Cluster %s kept: it was not created by cloud-deploy", p.clusterName)
+		return nil
+	}
+	others, err := p.otherDeployments(ctx, cluster, m)
+	if err != nil {
+		return err
+	}
+	if len(others) > 0 {
+		logging.Infof("Cluster %s kept: deployments %s run in it", p.clusterName, strings.Join(others, ", "))
+		return nil
+	}
+
+	logging.Infof("Deleting GKE cluster: %s (this takes several minutes)", p.clusterName)
+	op, err := p.containerClient.Projects.Locations.Clusters.Delete(p.clusterPath()).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to delete GKE cluster %s: %w", p.clusterName, err)
+	}
+	if err := p.waitForOperation(ctx, op); err != nil {
+		return fmt.Errorf("failed to delete GKE cluster %s: %w", p.clusterName, err)
+	}
+	logging.Info("GKE cluster deleted successfully")
+	return nil
+}
+
+// Stop scales the Deployment to zero pods; the cluster keeps running.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	workload, err := p.requireWorkload(ctx, m)
+	if err != nil {
+		return err
+	}
+	return workload.Stop(ctx, m)
+}
+
+// Status returns the current status of the deployment.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	workload, err := p.requireWorkload(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return workload.Status(ctx, m)
+}
+
+// Rollback returns the Deployment to its previous revision.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	workload, err := p.requireWorkload(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return workload.Rollback(ctx, m)
+}
+
+// Logs prints the output of each container in the deployment's pods.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	workload, err := p.requireWorkload(ctx, m)
+	if err != nil {
+		return err
+	}
+	return workload.Logs(ctx, m, opts)
+}
+
+func (p *Provider) locationPath() string {
+	return fmt.Sprintf("projects/%s/locations/%s", p.projectID, p.region)
+}
+
+func (p *Provider) clusterPath() string {
+	return p.locationPath() + "/clusters/" + p.clusterName
+}
+
+// isNotFound reports whether err is a Google API error for a missing resource.
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// getCluster returns the cluster, or nil when it doesn't exist.
+func (p *Provider) getCluster(ctx context.Context) (*container.Cluster, error) {
+	cluster, err := p.containerClient.Projects.Locations.Clusters.Get(p.clusterPath()).Context(ctx).Do()
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GKE cluster %s: %w", p.clusterName, err)
+	}
+	return cluster, nil
+}
+
+// ensureCluster returns the cluster once it is running, creating an Autopilot
+// cluster when it doesn't exist. An existing Standard cluster is deployed to
+// as it is.
+func (p *Provider) ensureCluster(ctx context.Context) (*container.Cluster, error) {
+	cluster, err := p.getCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cluster == nil:
+		logging.Infof("Creating GKE Autopilot cluster: %s (this takes several minutes)", p.clusterName)
+		request := &container.CreateClusterRequest{Cluster: &container.Cluster{
+			Name:           p.clusterName,
+			Autopilot:      &container.Autopilot{Enabled: true},
+			ResourceLabels: map[string]string{clusterLabel: managedBy},
+		}}
+		op, err := p.containerClient.Projects.Locations.Clusters.Create(p.locationPath(), request).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GKE cluster %s: %w", p.clusterName, err)
+		}
+		if err := p.waitForOperation(ctx, op); err != nil {
+			return nil, fmt.Errorf("failed to create GKE cluster %s: %w", p.clusterName, err)
+		}
+	case cluster.Autopilot != nil && cluster.Autopilot.Enabled:
+		logging.Infof("Using GKE Autopilot cluster: %s", p.clusterName)
+	default:
+		logging.Infof("Using GKE Standard cluster: %s", p.clusterName)
+	}
+	return p.waitForCluster(ctx)
+}
+
+// waitForCluster polls the cluster until its API server can be used: after
+// it is created, or while GKE provisions it for another deployment.
+func (p *Provider) waitForCluster(ctx context.Context) (*container.Cluster, error) {
+	timeout := time.After(clusterTimeout)
+	for {
+		cluster, err := p.getCluster(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if cluster == nil {
+			return nil, fmt.Errorf("GKE cluster %s no longer exists", p.clusterName)
+		}
+
+		// Reconciling and degraded clusters still serve the Kubernetes API
+		switch cluster.Status {
+		case "RUNNING", "RECONCILING", "DEGRADED":
+			return cluster, nil
+		case "STOPPING", "ERROR":
+			return nil, fmt.Errorf("GKE cluster %s is %s: %s", p.clusterName, cluster.Status, cluster.StatusMessage)
+		}
+		logging.Infof("Cluster status: %s", cluster.Status)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, fmt.Errorf("timeout waiting for GKE cluster %s to be running", p.clusterName)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// waitForOperation polls a cluster operation until it is done, returning its error.
+func (p *Provider) waitForOperation(ctx context.Context, op *container.Operation) error {
+	name := p.locationPath() + "/operations/" + op.Name
+	timeout := time.After(clusterTimeout)
+	for op.Status != "DONE" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for operation %s", op.Name)
+		case <-time.After(pollInterval):
+		}
+
+		var err error
+		if op, err = p.containerClient.Projects.Locations.Operations.Get(name).Context(ctx).Do(); err != nil {
+			return fmt.Errorf("failed to get operation %s: %w", name, err)
+		}
+	}
+	if op.Error != nil && op.Error.Message != "" {
+		return errors.New(op.Error.Message)
+	}
+	return nil
+}
+
+// kubeConfig returns the endpoint and credentials of the cluster's API
+// server: its CA certificate, and OAuth access tokens of the provider's
+// credentials, which GKE accepts as gke-gcloud-auth-plugin sends them.
+func (p *Provider) kubeConfig(cluster *container.Cluster, m *manifest.Manifest) (*kubeapi.Config, error) {
+	if cluster.Endpoint == "" || cluster.MasterAuth == nil {
+		return nil, fmt.Errorf("GKE cluster %s has no endpoint yet", p.clusterName)
+	}
+	ca, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate of GKE cluster %s: %w", p.clusterName, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("CA certificate of GKE cluster %s is not PEM-encoded", p.clusterName)
+	}
+
+	return &kubeapi.Config{
+		Server:    "https://" + cluster.Endpoint,
+		Namespace: namespace(m),
+		TLS:       &tls.Config{RootCAs: pool},
+		TokenSource: func(ctx context.Context) (string, error) {
+			token, err := p.tokenSource.Token()
+			if err != nil {
+				return "", fmt.Errorf("failed to get GCP access token: %w", err)
+			}
+			return token.AccessToken, nil
+		},
+	}, nil
+}
+
+// namespace returns the namespace deployed to.
+func namespace(m *manifest.Manifest) string {
+	if m.Kubernetes != nil && m.Kubernetes.Namespace != "" {
+		return m.Kubernetes.Namespace
+	}
+	return "default"
+}
+
+// workload returns the kubernetes provider that manages the deployment's
+// objects in the cluster.
+func (p *Provider) workload(cluster *container.Cluster, m *manifest.Manifest) (*kubernetes.Provider, error) {
+	cfg, err := p.kubeConfig(cluster, m)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewWithConfig(cfg, m), nil
+}
+
+// requireWorkload returns the kubernetes provider for the existing cluster.
+func (p *Provider) requireWorkload(ctx context.Context, m *manifest.Manifest) (*kubernetes.Provider, error) {
+	cluster, err := p.getCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		return nil, fmt.Errorf("no GKE cluster %s found in %s", p.clusterName, p.region)
+	}
+	return p.workload(cluster, m)
+}
+
+// otherDeployments returns the namespace and name of each Deployment
+// cloud-deploy applied to the cluster for an environment other than m's.
+func (p *Provider) otherDeployments(ctx context.Context, cluster *container.Cluster, m *manifest.Manifest) ([]string, error) {
+	cfg, err := p.kubeConfig(cluster, m)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	query := url.Values{"labelSelector": {deploymentLabel + "=" + managedBy}}
+	if err := kubeapi.New(cfg).Do(ctx, http.MethodGet, "/apis/apps/v1/deployments?"+query.Encode(), nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	var others []string
+	for _, d := range list.Items {
+		if d.Metadata.Namespace == cfg.Namespace && d.Metadata.Name == m.Environment.Name {
+			continue
+		}
+		others = append(others, d.Metadata.Namespace+"/"+d.Metadata.Name)
+	}
+	slices.Sort(others)
+	return others, nil
+}
+
+// ensureAPIsEnabled enables the APIs GKE deployments need.
+func (p *Provider) ensureAPIsEnabled(ctx context.Context) error {
+	for _, api := range requiredAPIs {
+		name := fmt.Sprintf("projects/%s/services/%s", p.projectID, api)
+		service, err := p.usageClient.Services.Get(name).Context(ctx).Do()
+		if err == nil && service.State == "ENABLED" {
+			continue
+		}
+
+		logging.Infof("Enabling %s...", api)
+		op, err := p.usageClient.Services.Enable(name, &serviceusage.EnableServiceRequest{}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to enable API %s: %w", api, err)
+		}
+		timeout := time.After(clusterTimeout)
+		for !op.Done {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timeout:
+				return fmt.Errorf("timeout waiting for API %s to be enabled", api)
+			case <-time.After(pollInterval):
+			}
+			name := op.Name
+			if op, err = p.usageClient.Operations.Get(name).Context(ctx).Do(); err != nil {
+				return fmt.Errorf("failed to get operation %s: %w", name, err)
+			}
+		}
+		if op.Error != nil {
+			return fmt.Errorf("failed to enable API %s: %s", api, op.Error.Message)
+		}
+	}
+	return nil
+}
+
+// pushImage pushes image to the Artifact Registry repository under tag, or to
+// the registry at registryURL when it is set, and returns the pushed image
+// pinned by digest. It is a variable so tests can replace it.
+var pushImage = func(ctx context.Context, projectID, region, repository, tag string, clientOpts []option.ClientOption, image, registryURL string, platforms []string) (string, error) {
+	gcrRegistry, err := registry.NewGCRRegistry(projectID, region, repository, tag, "", clientOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GCR registry handler: %w", err)
+	}
+	distributor := registry.NewDistributor(image, platforms...)
+	dest := registry.Local(gcrRegistry, registryURL, repository, tag)
+	distributor.AddRegistry(dest)
+	imageURIs, err := distributor.Distribute(ctx)
+	if err != nil {
+		return "", err
+	}
+	return registry.PinDigest(imageURIs[dest.GetRegistryURL()], distributor.Digest()), nil
+}
+
+// pushImages pushes the manifest's images to the application's Artifact
+// Registry repository, tagged as the gcp provider tags them, and returns a
+// copy of m running the pushed images.
+func (p *Provider) pushImages(ctx context.Context, m *manifest.Manifest) (*manifest.Manifest, error) {
+	pushed := *m
+	if !m.IsMultiContainer() {
+		image, err := pushImage(ctx, p.projectID, p.region, m.RepositoryName(), m.ImageTag("latest", ""), p.clientOpts, m.Image, m.Provider.RegistryURL, m.Platforms)
+		if err != nil {
+			return nil, fmt.Errorf("failed to distribute image to Artifact Registry: %w", err)
+		}
+		logging.Infof("Successfully pushed image: %s", image)
+		pushed.Image = image
+		return &pushed, nil
+	}
+
+	pushed.Containers = slices.Clone(m.Containers)
+	for i, c := range pushed.Containers {
+		image, err := pushImage(ctx, p.projectID, p.region, m.RepositoryName(), m.ImageTag(c.Name, c.Name), p.clientOpts, c.Image, m.Provider.RegistryURL, m.Platforms)
+		if err != nil {
+			return nil, fmt.Errorf("failed to distribute image for container %s: %w", c.Name, err)
+		}
+		logging.Infof("Image pushed: %s -> %s", c.Name, image)
+		pushed.Containers[i].Image = image
+	}
+	return &pushed, nil
+}
@@ -0,0 +1,374 @@
+package gke
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	artifactregistry "google.golang.org/api/artifactregistry/v1"
+	container "google.golang.org/api/container/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const (
+	locationPath = "/v1/projects/my-project/locations/us-central1"
+	clusterPath  = locationPath + "/clusters/my-cluster"
+)
+
+// fakeGCP serves the subset of the GKE, Service Usage, and Artifact Registry
+// APIs the provider uses, and the Kubernetes API of the cluster. A created
+// cluster is provisioning until it is read once.
+type fakeGCP struct {
+	mu          sync.Mutex
+	cluster     map[string]interface{}
+	created     *container.Cluster
+	disabled    map[string]bool
+	repository  bool
+	deployments []string // namespace/name of each Deployment cloud-deploy applied
+	objects     map[string]bool
+	requests    []string
+
+	kube *httptest.Server
+}
+
+func newFakeGCP(t *testing.T) (*fakeGCP, *Provider) {
+	t.Helper()
+	f := &fakeGCP{disabled: make(map[string]bool), objects: make(map[string]bool)}
+	f.kube = httptest.NewTLSServer(http.HandlerFunc(f.serveKube))
+	t.Cleanup(f.kube.Close)
+	server := httptest.NewServer(http.HandlerFunc(f.serveGCP))
+	t.Cleanup(server.Close)
+
+	oldInterval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldInterval })
+
+	ctx := context.Background()
+	opts := []option.ClientOption{option.WithEndpoint(server.URL + "/"), option.WithoutAuthentication()}
+	containerClient, err := container.NewService(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usageClient, err := serviceusage.NewService(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registryClient, err := artifactregistry.NewService(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, &Provider{
+		projectID:       "my-project",
+		region:          "us-central1",
+		clusterName:     "my-cluster",
+		containerClient: containerClient,
+		usageClient:     usageClient,
+		registryClient:  registryClient,
+		tokenSource:     oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "gcp-token"}),
+	}
+}
+
+// runningCluster returns a running cluster whose API server is the fake's.
+func (f *fakeGCP) runningCluster(autopilot bool, labels map[string]string) map[string]interface{} {
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.kube.Certificate().Raw})
+	return map[string]interface{}{
+		"name":           "my-cluster",
+		"status":         "RUNNING",
+		"endpoint":       strings.TrimPrefix(f.kube.URL, "https://"),
+		"masterAuth":     map[string]string{"clusterCaCertificate": base64.StdEncoding.EncodeToString(ca)},
+		"autopilot":      map[string]bool{"enabled": autopilot},
+		"resourceLabels": labels,
+	}
+}
+
+func (f *fakeGCP) serveGCP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, ":enable"):
+		api := strings.TrimSuffix(path[strings.LastIndex(path, "/")+1:], ":enable")
+		delete(f.disabled, api)
+		w.Write([]byte(`{"name":"operations/enable","done":true}`))
+	case strings.Contains(path, "/services/"):
+		state := "ENABLED"
+		if f.disabled[path[strings.LastIndex(path, "/")+1:]] {
+			state = "DISABLED"
+		}
+		json.NewEncoder(w).Encode(map[string]string{"state": state})
+	case strings.Contains(path, "/repositories/"):
+		if !f.repository {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	case path == locationPath+"/clusters" && r.Method == http.MethodPost:
+		var request container.CreateClusterRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.created = request.Cluster
+		f.cluster = f.runningCluster(true, request.Cluster.ResourceLabels)
+		f.cluster["status"] = "PROVISIONING"
+		w.Write([]byte(`{"name":"op-create","status":"RUNNING"}`))
+	case path == locationPath+"/operations/op-create":
+		w.Write([]byte(`{"name":"op-create","status":"DONE"}`))
+	case path == clusterPath && r.Method == http.MethodDelete:
+		f.cluster = nil
+		w.Write([]byte(`{"name":"op-delete","status":"DONE"}`))
+	case path == clusterPath && f.cluster != nil:
+		json.NewEncoder(w).Encode(f.cluster)
+		f.cluster["status"] = "RUNNING"
+	default:
+		http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+	}
+}
+
+func (f *fakeGCP) serveKube(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer gcp-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	path := r.URL.Path
+	switch {
+	case path == "/apis/apps/v1/deployments":
+		var items []map[string]interface{}
+		for _, d := range f.deployments {
+			namespace, name, _ := strings.Cut(d, "/")
+			items = append(items, map[string]interface{}{"metadata": map[string]string{"namespace": namespace, "name": name}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case r.Method == http.MethodDelete && f.objects[path]:
+		delete(f.objects, path)
+		w.Write([]byte(`{"kind":"Status","status":"Success"}`))
+	case r.Method == http.MethodGet && f.objects[path] && strings.Contains(path, "/deployments/"):
+		w.Write([]byte(`{"metadata":{"name":"web","generation":1,"creationTimestamp":"2026-01-02T03:04:05Z"},"spec":{"replicas":2},"status":{"observedGeneration":1,"replicas":2,"updatedReplicas":2,"availableReplicas":2}}`))
+	case r.Method == http.MethodGet && f.objects[path]:
+		w.Write([]byte(`{"spec":{"type":"ClusterIP"}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","reason":"NotFound","message":"not found"}`))
+	}
+}
+
+func (f *fakeGCP) requested(request string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	deploymentPath = "/apis/apps/v1/namespaces/apps/deployments/web"
+	servicePath    = "/api/v1/namespaces/apps/services/web"
+	ingressPath    = "/apis/networking.k8s.io/v1/namespaces/apps/ingresses/web"
+)
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:       "registry.example.com/my-app:v2",
+		Provider:    manifest.ProviderConfig{Name: "gcp-gke", Region: "us-central1", ProjectID: "my-project"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "web"},
+		GKE:         &manifest.GKEConfig{Cluster: "my-cluster"},
+		Kubernetes:  &manifest.KubernetesConfig{Namespace: "apps"},
+	}
+}
+
+func TestProviderName(t *testing.T) {
+	if name := (&Provider{}).Name(); name != "gcp-gke" {
+		t.Errorf("Name() = %q, want gcp-gke", name)
+	}
+}
+
+func TestClusterTokenSourceEmulator(t *testing.T) {
+	config := &manifest.ProviderConfig{Name: "gcp-gke", EndpointURL: "http://localhost:8085"}
+	source, err := clusterTokenSource(context.Background(), config, nil)
+	if err != nil {
+		t.Fatalf("clusterTokenSource failed: %v", err)
+	}
+	if token, err := source.Token(); err != nil || token.AccessToken != "emulator" {
+		t.Errorf("Token() = %v, %v; want the emulator token", token, err)
+	}
+}
+
+func TestEnsureClusterCreatesAutopilot(t *testing.T) {
+	fake, p := newFakeGCP(t)
+
+	cluster, err := p.ensureCluster(context.Background())
+	if err != nil {
+		t.Fatalf("ensureCluster failed: %v", err)
+	}
+	if cluster.Status != "RUNNING" {
+		t.Errorf("Expected to wait for the cluster to run, got %s", cluster.Status)
+	}
+	if fake.created == nil || fake.created.Name != "my-cluster" || fake.created.Autopilot == nil || !fake.created.Autopilot.Enabled {
+		t.Fatalf("Expected an Autopilot cluster to be created, got %+v", fake.created)
+	}
+	if fake.created.ResourceLabels[clusterLabel] != managedBy {
+		t.Errorf("Expected the cluster to be labeled, got %v", fake.created.ResourceLabels)
+	}
+	if !fake.requested("GET " + locationPath + "/operations/op-create") {
+		t.Error("Expected the create operation to be waited for")
+	}
+}
+
+func TestEnsureClusterExisting(t *testing.T) {
+	fake, p := newFakeGCP(t)
+	fake.cluster = fake.runningCluster(false, nil)
+
+	if _, err := p.ensureCluster(context.Background()); err != nil {
+		t.Fatalf("ensureCluster failed: %v", err)
+	}
+	if fake.created != nil {
+		t.Error("Expected the existing Standard cluster to be used")
+	}
+
+	fake.cluster["status"] = "ERROR"
+	fake.cluster["statusMessage"] = "quota exceeded"
+	if _, err := p.waitForCluster(context.Background()); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected the cluster's error, got: %v", err)
+	}
+}
+
+func TestEnsureAPIsEnabled(t *testing.T) {
+	fake, p := newFakeGCP(t)
+	fake.disabled["container.googleapis.com"] = true
+
+	if err := p.ensureAPIsEnabled(context.Background()); err != nil {
+		t.Fatalf("ensureAPIsEnabled failed: %v", err)
+	}
+	if !fake.requested("POST /v1/projects/my-project/services/container.googleapis.com:enable") {
+		t.Error("Expected the disabled API to be enabled")
+	}
+	if fake.requested("POST /v1/projects/my-project/services/artifactregistry.googleapis.com:enable") {
+		t.Error("Expected the enabled API to be left alone")
+	}
+}
+
+func TestPushImages(t *testing.T) {
+	defer func(f func(context.Context, string, string, string, string, []option.ClientOption, string, string, []string) (string, error)) {
+		pushImage = f
+	}(pushImage)
+	var pushed []string
+	pushImage = func(ctx context.Context, projectID, region, repository, tag string, clientOpts []option.ClientOption, image, registryURL string, platforms []string) (string, error) {
+		pushed = append(pushed, image+" -> "+repository+":"+tag)
+		return region + "-docker.pkg.dev/" + projectID + "/" + repository + "/" + repository + "@sha256:" + tag, nil
+	}
+	p := &Provider{projectID: "my-project", region: "us-central1"}
+
+	m := testManifest()
+	got, err := p.pushImages(context.Background(), m)
+	if err != nil {
+		t.Fatalf("pushImages failed: %v", err)
+	}
+	if got.Image != "us-central1-docker.pkg.dev/my-project/my-app/my-app@sha256:latest" || m.Image != "registry.example.com/my-app:v2" {
+		t.Errorf("Expected a copy running the pushed image, got %s (original %s)", got.Image, m.Image)
+	}
+
+	m.Image = ""
+	m.Containers = []manifest.Container{{Name: "web", Image: "web:1"}, {Name: "worker", Image: "worker:1"}}
+	if got, err = p.pushImages(context.Background(), m); err != nil {
+		t.Fatalf("pushImages failed: %v", err)
+	}
+	if got.Containers[1].Image != "us-central1-docker.pkg.dev/my-project/my-app/my-app@sha256:worker" || m.Containers[1].Image != "worker:1" {
+		t.Errorf("Expected a copy running the pushed images, got %+v (original %+v)", got.Containers, m.Containers)
+	}
+	if strings.Join(pushed, ", ") != "registry.example.com/my-app:v2 -> my-app:latest, web:1 -> my-app:web, worker:1 -> my-app:worker" {
+		t.Errorf("Unexpected pushes: %v", pushed)
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	managed := map[string]string{clusterLabel: managedBy}
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		deployments   []string
+		deleteCluster bool
+	}{
+		{"last deployment", managed, []string{"apps/web"}, true},
+		{"other deployments", managed, []string{"apps/web", "apps/api"}, false},
+		{"cluster not created by cloud-deploy", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, p := newFakeGCP(t)
+			fake.cluster = fake.runningCluster(true, tt.labels)
+			fake.deployments = tt.deployments
+			for _, path := range []string{deploymentPath, servicePath, ingressPath} {
+				fake.objects[path] = true
+			}
+
+			if err := p.Destroy(context.Background(), testManifest()); err != nil {
+				t.Fatalf("Destroy failed: %v", err)
+			}
+			if len(fake.objects) != 0 {
+				t.Errorf("Expected the deployment's objects to be deleted, left %v", fake.objects)
+			}
+			if deleted := fake.requested("DELETE " + clusterPath); deleted != tt.deleteCluster {
+				t.Errorf("Cluster deleted = %v, want %v", deleted, tt.deleteCluster)
+			}
+		})
+	}
+
+	_, p := newFakeGCP(t)
+	if err := p.Destroy(context.Background(), testManifest()); err != nil {
+		t.Errorf("Expected a missing cluster to have nothing to destroy, got: %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	fake, p := newFakeGCP(t)
+	m := testManifest()
+
+	if _, err := p.Status(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no GKE cluster my-cluster found") {
+		t.Errorf("Expected a missing cluster error, got: %v", err)
+	}
+
+	fake.cluster = fake.runningCluster(true, nil)
+	fake.objects[deploymentPath] = true
+	fake.objects[servicePath] = true
+	status, err := p.Status(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Running" || status.URL != "http://web.apps.svc.cluster.local" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestKubeConfig(t *testing.T) {
+	p := &Provider{clusterName: "my-cluster"}
+	m := testManifest()
+
+	if _, err := p.kubeConfig(&container.Cluster{}, m); err == nil || !strings.Contains(err.Error(), "no endpoint") {
+		t.Errorf("Expected a missing endpoint error, got: %v", err)
+	}
+	cluster := &container.Cluster{Endpoint: "203.0.113.10", MasterAuth: &container.MasterAuth{ClusterCaCertificate: base64.StdEncoding.EncodeToString([]byte("not a certificate"))}}
+	if _, err := p.kubeConfig(cluster, m); err == nil || !strings.Contains(err.Error(), "not PEM-encoded") {
+		t.Errorf("Expected an invalid CA error, got: %v", err)
+	}
+	if namespace(&manifest.Manifest{}) != "default" {
+		t.Errorf("Expected the default namespace, got %s", namespace(&manifest.Manifest{}))
+	}
+}
@@ -0,0 +1,37 @@
+package gke
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// roleBinding is a predefined role the deployer needs on a resource.
+type roleBinding struct {
+	Role     string `json:"role"`
+	Resource string `json:"resource"`
+	Reason   string `json:"reason"`
+}
+
+// IAMPolicy returns the roles the deployer's principal needs for the features used
+// in m, each with the resource it must be granted on. GKE authorizes requests
+// to the cluster's API server with these roles too, so no RBAC Role is needed.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	projectID := m.Provider.ProjectID
+	if projectID == "" {
+		return nil, fmt.Errorf("provider.project_id is required to scope the roles")
+	}
+	project := "projects/" + projectID
+
+	bindings := []roleBinding{
+		{Role: "roles/container.admin", Resource: project, Reason: "Create and delete the cluster, and apply, roll back, and delete the deployment's objects in it"},
+		{Role: "roles/iam.serviceAccountUser", Resource: project, Reason: "Create Autopilot clusters whose nodes run as the project's default service account"},
+		{Role: "roles/artifactregistry.admin", Resource: project, Reason: "Create the Artifact Registry repository and push images"},
+		{Role: "roles/serviceusage.serviceUsageAdmin", Resource: project, Reason: "Enable the APIs GKE deployments need"},
+	}
+
+	return json.MarshalIndent(struct {
+		Bindings []roleBinding `json:"bindings"`
+	}{bindings}, "", "  ")
+}
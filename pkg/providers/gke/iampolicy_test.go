@@ -0,0 +1,35 @@
+package gke
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	data, err := IAMPolicy(&manifest.Manifest{Provider: manifest.ProviderConfig{Name: "gcp-gke", ProjectID: "my-project"}})
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc struct {
+		Bindings []roleBinding `json:"bindings"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	bindings := make(map[string]string)
+	for _, b := range doc.Bindings {
+		bindings[b.Role] = b.Resource
+	}
+
+	for _, role := range []string{"roles/container.admin", "roles/iam.serviceAccountUser", "roles/artifactregistry.admin", "roles/serviceusage.serviceUsageAdmin"} {
+		if bindings[role] != "projects/my-project" {
+			t.Errorf("Expected %s on projects/my-project, got %q", role, bindings[role])
+		}
+	}
+
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected an error without a project ID")
+	}
+}
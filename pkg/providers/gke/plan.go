@@ -0,0 +1,117 @@
+package gke
+
+import (
+	"context"
+	"fmt"
+
+	container "google.golang.org/api/container/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// planState records which of a deployment's resources already exist.
+type planState struct {
+	disabledAPIs []string
+	cluster      *container.Cluster
+	repository   bool
+
+	// Changes to the deployment's objects when the cluster exists
+	workload *types.Plan
+}
+
+// Plan returns the changes Deploy would make for the manifest without changing
+// the project or the cluster.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	state, err := p.readPlanState(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return buildPlan(m, p.projectID, p.region, p.clusterName, state), nil
+}
+
+// readPlanState looks up the deployment's existing resources.
+func (p *Provider) readPlanState(ctx context.Context, m *manifest.Manifest) (planState, error) {
+	var state planState
+	for _, api := range requiredAPIs {
+		service, err := p.usageClient.Services.Get(fmt.Sprintf("projects/%s/services/%s", p.projectID, api)).Context(ctx).Do()
+		if err == nil && service.State == "ENABLED" {
+			continue
+		}
+		state.disabledAPIs = append(state.disabledAPIs, api)
+	}
+	// Clusters and repositories cannot exist while their APIs are disabled
+	if len(state.disabledAPIs) > 0 {
+		return state, nil
+	}
+
+	_, err := p.registryClient.Projects.Locations.Repositories.Get(p.locationPath() + "/repositories/" + m.RepositoryName()).Context(ctx).Do()
+	switch {
+	case err == nil:
+		state.repository = true
+	case isNotFound(err):
+	default:
+		return state, fmt.Errorf("failed to get Artifact Registry repository %s: %w", m.RepositoryName(), err)
+	}
+
+	if state.cluster, err = p.getCluster(ctx); err != nil || state.cluster == nil {
+		return state, err
+	}
+	workload, err := p.workload(state.cluster, m)
+	if err != nil {
+		return state, err
+	}
+	if state.workload, err = workload.Plan(ctx, m); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// buildPlan returns the changes a deployment of m makes given the existing resources.
+func buildPlan(m *manifest.Manifest, projectID, region, clusterName string, state planState) *types.Plan {
+	plan := &types.Plan{Provider: "gcp-gke"}
+	for _, api := range state.disabledAPIs {
+		plan.Add(types.PlanUpdate, "Service API", api, "enable API")
+	}
+
+	switch cluster := state.cluster; {
+	case cluster == nil:
+		plan.Add(types.PlanCreate, "GKE Autopilot cluster", clusterName, "cluster does not exist")
+	case cluster.Autopilot != nil && cluster.Autopilot.Enabled:
+		plan.Add(types.PlanNoChange, "GKE Autopilot cluster", clusterName, "")
+	default:
+		plan.Add(types.PlanNoChange, "GKE Standard cluster", clusterName, "")
+	}
+
+	app := m.Application.Name
+	if state.repository {
+		plan.Add(types.PlanNoChange, "Artifact Registry repository", m.RepositoryName(), "")
+	} else {
+		plan.Add(types.PlanCreate, "Artifact Registry repository", m.RepositoryName(), "repository does not exist")
+	}
+	imageAction := types.PlanUpdate
+	if !state.repository {
+		imageAction = types.PlanCreate
+	}
+	registryURL := fmt.Sprintf("%s-docker.pkg.dev/%s/%s", region, projectID, app)
+	if m.IsMultiContainer() {
+		for _, c := range m.Containers {
+			plan.Add(imageAction, "Container image", fmt.Sprintf("%s/%s:%s", registryURL, app, c.Name), "push "+c.Image)
+		}
+	} else {
+		plan.Add(imageAction, "Container image", fmt.Sprintf("%s/%s:latest", registryURL, app), "push "+m.Image)
+	}
+
+	if state.workload != nil {
+		plan.Changes = append(plan.Changes, state.workload.Changes...)
+		return plan
+	}
+	// A new cluster has none of the deployment's objects
+	name := m.Environment.Name
+	plan.Add(types.PlanCreate, "Deployment", name, "cluster does not exist")
+	plan.Add(types.PlanCreate, "Service", name, "cluster does not exist")
+	if m.Kubernetes != nil && m.Kubernetes.Ingress != nil {
+		plan.Add(types.PlanCreate, "Ingress", name, m.Kubernetes.Ingress.Host)
+	}
+	return plan
+}
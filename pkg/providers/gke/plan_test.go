@@ -0,0 +1,94 @@
+package gke
+
+import (
+	"context"
+	"testing"
+
+	container "google.golang.org/api/container/v1"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func changeActions(plan *types.Plan) map[string]types.PlanAction {
+	actions := make(map[string]types.PlanAction)
+	for _, c := range plan.Changes {
+		actions[c.Type+" "+c.Name] = c.Action
+	}
+	return actions
+}
+
+func TestBuildPlanNewCluster(t *testing.T) {
+	m := testManifest()
+	m.Kubernetes.Ingress = &manifest.KubernetesIngress{Host: "app.example.com"}
+	plan := buildPlan(m, "my-project", "us-central1", "my-cluster", planState{disabledAPIs: requiredAPIs})
+
+	actions := changeActions(plan)
+	for key, want := range map[string]types.PlanAction{
+		"Service API container.googleapis.com": types.PlanUpdate,
+		"GKE Autopilot cluster my-cluster":     types.PlanCreate,
+		"Artifact Registry repository my-app":  types.PlanCreate,
+		"Deployment web":                       types.PlanCreate,
+		"Service web":                          types.PlanCreate,
+		"Ingress web":                          types.PlanCreate,
+		"Container image us-central1-docker.pkg.dev/my-project/my-app/my-app:latest": types.PlanCreate,
+	} {
+		if actions[key] != want {
+			t.Errorf("%s: got %q, want %q", key, actions[key], want)
+		}
+	}
+	if plan.Provider != "gcp-gke" {
+		t.Errorf("Provider = %q, want gcp-gke", plan.Provider)
+	}
+}
+
+func TestBuildPlanExistingCluster(t *testing.T) {
+	workload := &types.Plan{}
+	workload.Add(types.PlanUpdate, "Deployment", "web", "image changes")
+	state := planState{
+		cluster:    &container.Cluster{Autopilot: &container.Autopilot{}},
+		repository: true,
+		workload:   workload,
+	}
+	plan := buildPlan(testManifest(), "my-project", "us-central1", "my-cluster", state)
+
+	if n := plan.Count(types.PlanCreate); n != 0 {
+		t.Errorf("got %d creates for an existing cluster, want 0: %+v", n, plan.Changes)
+	}
+	actions := changeActions(plan)
+	if actions["GKE Standard cluster my-cluster"] != types.PlanNoChange {
+		t.Errorf("cluster action = %q, want no-op", actions["GKE Standard cluster my-cluster"])
+	}
+	if actions["Deployment web"] != types.PlanUpdate {
+		t.Errorf("Deployment action = %q, want the workload's update", actions["Deployment web"])
+	}
+	if _, ok := actions["Service web"]; ok {
+		t.Error("Expected the workload's changes to replace the defaults")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	fake, p := newFakeGCP(t)
+	fake.repository = true
+	fake.cluster = fake.runningCluster(true, nil)
+
+	plan, err := p.Plan(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	actions := changeActions(plan)
+	if actions["GKE Autopilot cluster my-cluster"] != types.PlanNoChange || actions["Deployment web"] != types.PlanCreate {
+		t.Errorf("Unexpected plan: %+v", plan.Changes)
+	}
+	if !fake.requested("GET " + deploymentPath) {
+		t.Error("Expected the cluster's objects to be read")
+	}
+
+	fake.disabled["container.googleapis.com"] = true
+	if plan, err = p.Plan(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if actions := changeActions(plan); actions["GKE Autopilot cluster my-cluster"] != types.PlanCreate {
+		t.Errorf("Expected a cluster create while the API is disabled, got %+v", plan.Changes)
+	}
+}
@@ -0,0 +1,44 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// policyRule is a rule of an RBAC Role.
+type policyRule struct {
+	APIGroups []string `json:"apiGroups"`
+	Resources []string `json:"resources"`
+	Verbs     []string `json:"verbs"`
+}
+
+// IAMPolicy returns an RBAC Role granting the deployer what it needs in the
+// manifest's namespace, to be bound to the deployer's user or service account
+// with a RoleBinding. The namespace defaults to "default" since the kubeconfig
+// context is not read.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	namespace := "default"
+	if m.Kubernetes != nil && m.Kubernetes.Namespace != "" {
+		namespace = m.Kubernetes.Namespace
+	}
+
+	rules := []policyRule{
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "create", "patch", "delete"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"replicasets"}, Verbs: []string{"list"}},
+		{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"get", "create", "patch", "delete"}},
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+		{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+		// The posture command reads the namespace's quotas
+		{APIGroups: []string{""}, Resources: []string{"resourcequotas"}, Verbs: []string{"list"}},
+		// Deploy deletes an ingress left over when none is configured
+		{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses"}, Verbs: []string{"get", "create", "patch", "delete"}},
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       "Role",
+		"metadata":   map[string]string{"name": "cloud-deploy", "namespace": namespace},
+		"rules":      rules,
+	}, "", "  ")
+}
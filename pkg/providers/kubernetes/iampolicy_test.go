@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	m := testManifest()
+	out, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy() failed: %v", err)
+	}
+
+	var role struct {
+		Kind     string            `json:"kind"`
+		Metadata map[string]string `json:"metadata"`
+		Rules    []policyRule      `json:"rules"`
+	}
+	if err := json.Unmarshal(out, &role); err != nil {
+		t.Fatalf("invalid role: %v", err)
+	}
+	if role.Kind != "Role" || role.Metadata["namespace"] != "apps" {
+		t.Errorf("unexpected role: %s", out)
+	}
+
+	resources := make(map[string]bool)
+	for _, rule := range role.Rules {
+		for _, r := range rule.Resources {
+			resources[r] = true
+		}
+	}
+	for _, r := range []string{"deployments", "replicasets", "services", "ingresses", "pods/log"} {
+		if !resources[r] {
+			t.Errorf("expected a rule for %s", r)
+		}
+	}
+
+	out, _ = IAMPolicy(&manifest.Manifest{})
+	json.Unmarshal(out, &role)
+	if role.Metadata["namespace"] != "default" {
+		t.Errorf("expected the default namespace, got %s", role.Metadata["namespace"])
+	}
+}
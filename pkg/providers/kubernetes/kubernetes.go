@@ -0,0 +1,941 @@
+// Package kubernetes provides deployment functionality for any Kubernetes
+// cluster reachable through a kubeconfig. It implements the Provider interface
+// by applying a Deployment, a Service, and an optional Ingress named after the
+// environment, running the manifest's images as given: they must already be
+// in a registry the cluster can pull from.
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/kubeapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// pollInterval is how often a rollout or load balancer is checked while waiting for it.
+var pollInterval = 5 * time.Second
+
+// rolloutTimeout is how long Deploy and Rollback wait for the pods to become available.
+var rolloutTimeout = 10 * time.Minute
+
+// loadBalancerTimeout is how long Deploy waits for a LoadBalancer service's address.
+var loadBalancerTimeout = 5 * time.Minute
+
+// Labels set on every object, and annotations read from Deployments and ReplicaSets.
+const (
+	labelName       = "app.kubernetes.io/name"
+	labelPartOf     = "app.kubernetes.io/part-of"
+	labelManagedBy  = "app.kubernetes.io/managed-by"
+	revisionKey     = "deployment.kubernetes.io/revision"
+	podTemplateHash = "pod-template-hash"
+	changeCauseKey  = "kubernetes.io/change-cause"
+	servicePortName = "http"
+	defaultReplicas = int32(1)
+
+	// RuntimeClass SpinKube installs for Spin applications
+	defaultWasmRuntimeClass = "wasmtime-spin-v2"
+)
+
+// Provider implements the provider.Provider interface for Kubernetes.
+type Provider struct {
+	namespace string
+	client    *kubeapi.Client
+}
+
+// New creates a new Kubernetes provider instance.
+//
+// The cluster and credentials come from the kubeconfig context in the
+// kubernetes section (default: the current context of $KUBECONFIG or
+// ~/.kube/config). Inside a cluster without a kubeconfig, the pod's service
+// account is used.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	if config.Credentials.FromSecretStore() {
+		return nil, fmt.Errorf("loading Kubernetes credentials from %s is not supported", config.Credentials.Source)
+	}
+
+	cfg, err := loadConfig(m.Kubernetes)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithConfig(cfg, m), nil
+}
+
+// NewWithConfig creates a Kubernetes provider instance for the cluster of
+// cfg, for providers that look up a cluster's endpoint and credentials
+// themselves instead of reading a kubeconfig.
+func NewWithConfig(cfg *kubeapi.Config, m *manifest.Manifest) *Provider {
+	namespace := cfg.Namespace
+	if m.Kubernetes != nil && m.Kubernetes.Namespace != "" {
+		namespace = m.Kubernetes.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &Provider{namespace: namespace, client: kubeapi.New(cfg)}
+}
+
+// loadConfig reads the kubeconfig context to deploy with, falling back to the
+// pod's service account when no kubeconfig file exists.
+func loadConfig(k *manifest.KubernetesConfig) (*kubeapi.Config, error) {
+	path, context := kubeapi.DefaultConfigFile(), ""
+	explicit := false
+	if k != nil {
+		if k.Kubeconfig != "" {
+			path, explicit = k.Kubeconfig, true
+		}
+		context = k.Context
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !explicit {
+		if cfg, err := kubeapi.InClusterConfig(); err == nil {
+			logging.Info("Using the in-cluster service account")
+			return cfg, nil
+		}
+	}
+	logging.Infof("Using kubeconfig: %s", path)
+	return kubeapi.LoadConfigFile(path, context)
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "kubernetes"
+}
+
+// Close closes the idle connections to the API server.
+func (p *Provider) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// objectMeta is the metadata of a Kubernetes object.
+type objectMeta struct {
+	Name              string            `json:"name"`
+	UID               string            `json:"uid,omitempty"`
+	Generation        int64             `json:"generation,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+	OwnerReferences   []struct {
+		UID string `json:"uid"`
+	} `json:"ownerReferences,omitempty"`
+}
+
+// podTemplate is the pod template of a Deployment or ReplicaSet, kept as
+// decoded JSON so it can be written back unchanged on rollback.
+type podTemplate map[string]interface{}
+
+// deployment is an apps/v1 Deployment.
+type deployment struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int32 `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+		Template podTemplate `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration"`
+		Replicas           int32 `json:"replicas"`
+		UpdatedReplicas    int32 `json:"updatedReplicas"`
+		ReadyReplicas      int32 `json:"readyReplicas"`
+		AvailableReplicas  int32 `json:"availableReplicas"`
+		Conditions         []struct {
+			Type           string    `json:"type"`
+			Status         string    `json:"status"`
+			Reason         string    `json:"reason"`
+			Message        string    `json:"message"`
+			LastUpdateTime time.Time `json:"lastUpdateTime"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// replicas returns the number of pods the Deployment asks for.
+func (d *deployment) replicas() int32 {
+	if d.Spec.Replicas == nil {
+		return defaultReplicas
+	}
+	return *d.Spec.Replicas
+}
+
+// replicaSet is an apps/v1 ReplicaSet, one per revision of a Deployment.
+type replicaSet struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Template podTemplate `json:"template"`
+	} `json:"spec"`
+}
+
+// service is a v1 Service.
+type service struct {
+	Spec struct {
+		Type string `json:"type"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []struct {
+				IP       string `json:"ip"`
+				Hostname string `json:"hostname"`
+			} `json:"ingress"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+// Deploy deploys an application to the Kubernetes cluster.
+// This method:
+// 1. Applies the Deployment running the manifest's images
+// 2. Applies the Service in front of its pods, and the Ingress when configured
+// 3. Waits for the rollout to finish, as kubectl rollout status does
+//
+// Objects are applied with server-side apply, so Deploy creates them the first
+// time and updates them in place afterwards; each update is a new revision of
+// the Deployment that Rollback can return to.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Infof("Starting Kubernetes deployment to namespace %s...", p.namespace)
+
+	name := m.Environment.Name
+	if m.Wasm() {
+		if err := validateWasmImages(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	logging.Infof("Applying deployment: %s", name)
+	if err := p.client.Apply(ctx, p.deploymentPath(name), p.deploymentObject(m), nil); err != nil {
+		return nil, fmt.Errorf("failed to apply deployment: %w", err)
+	}
+
+	logging.Infof("Applying service: %s", name)
+	if err := p.client.Apply(ctx, p.servicePath(name), p.serviceObject(m), nil); err != nil {
+		return nil, fmt.Errorf("failed to apply service: %w", err)
+	}
+
+	// Without an ingress configured, one left over from an earlier deploy is deleted
+	if ingress := p.ingressObject(m); ingress != nil {
+		logging.Infof("Applying ingress: %s", name)
+		if err := p.client.Apply(ctx, p.ingressPath(name), ingress, nil); err != nil {
+			return nil, fmt.Errorf("failed to apply ingress: %w", err)
+		}
+	} else if err := p.delete(ctx, "ingress", p.ingressPath(name)); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Waiting for rollout to finish...")
+	if err := p.waitForRollout(ctx, name); err != nil {
+		return nil, err
+	}
+
+	url, err := p.serviceURL(ctx, m, true)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]string)
+	for _, c := range deployContainers(m) {
+		images[c.Name] = c.Image
+	}
+	message := "Deployment successful"
+	if m.IsMultiContainer() {
+		message = fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers))
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             url,
+		Status:          "Running",
+		Message:         message,
+		Images:          images,
+	}, nil
+}
+
+// Destroy deletes the Ingress, Service, and Deployment of the environment. The
+// Deployment's ReplicaSets and pods are deleted with it.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	if err := p.delete(ctx, "ingress", p.ingressPath(name)); err != nil {
+		return err
+	}
+	if err := p.delete(ctx, "service", p.servicePath(name)); err != nil {
+		return err
+	}
+	if err := p.delete(ctx, "deployment", p.deploymentPath(name)); err != nil {
+		return err
+	}
+	logging.Info("Kubernetes resources deleted successfully")
+	return nil
+}
+
+// Stop scales the Deployment to zero pods. The Deployment, Service, and
+// revision history are preserved; running Deploy again scales it back up.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	logging.Infof("Scaling deployment %s to 0 replicas", name)
+	patch := map[string]interface{}{"spec": map[string]interface{}{"replicas": 0}}
+	if err := p.client.Patch(ctx, p.deploymentPath(name), kubeapi.MergePatch, patch, nil); err != nil {
+		if kubeapi.IsNotFound(err) {
+			return fmt.Errorf("no deployment found for environment %s in namespace %s", name, p.namespace)
+		}
+		return fmt.Errorf("failed to scale deployment: %w", err)
+	}
+	logging.Info("Deployment stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the deployment.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	d, err := p.getDeployment(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := p.serviceURL(ctx, m, false)
+	if err != nil {
+		return nil, err
+	}
+
+	status, health := "Running", "Green"
+	switch desired := d.replicas(); {
+	case desired == 0:
+		status, health = "Stopped", "Grey"
+	case !rolloutComplete(d):
+		status, health = "Updating", "Yellow"
+		if d.Status.AvailableReplicas == 0 {
+			health = "Red"
+		}
+	}
+
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          status,
+		Health:          health,
+		URL:             url,
+		LastUpdated:     lastUpdated(d),
+	}, nil
+}
+
+// Rollback returns the Deployment to its previous revision, as kubectl
+// rollout undo does: the pod template of the ReplicaSet with the highest
+// revision below the current one is written back to the Deployment, which
+// makes it the newest revision.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting Kubernetes rollback...")
+	name := m.Environment.Name
+
+	d, err := p.getDeployment(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := p.replicaSets(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := previousRevision(sets, d.Metadata.Annotations[revisionKey])
+	if err != nil {
+		return nil, err
+	}
+
+	template := previous.Spec.Template
+	if meta, ok := template["metadata"].(map[string]interface{}); ok {
+		if labels, ok := meta["labels"].(map[string]interface{}); ok {
+			delete(labels, podTemplateHash)
+		}
+	}
+	logging.Infof("Rolling back %s to revision %s", name, previous.Metadata.Annotations[revisionKey])
+
+	patch := []map[string]interface{}{{"op": "replace", "path": "/spec/template", "value": template}}
+	if err := p.client.Patch(ctx, p.deploymentPath(name), kubeapi.JSONPatch, patch, nil); err != nil {
+		return nil, fmt.Errorf("failed to roll back deployment: %w", err)
+	}
+
+	logging.Info("Waiting for rollout to finish...")
+	if err := p.waitForRollout(ctx, name); err != nil {
+		return nil, err
+	}
+
+	url, err := p.serviceURL(ctx, m, false)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             url,
+		Status:          "Running",
+		Message:         fmt.Sprintf("Rolled back to image %s", strings.Join(templateImages(template), ", ")),
+	}, nil
+}
+
+// lastUpdated returns when the Deployment's conditions last changed.
+func lastUpdated(d *deployment) string {
+	var last time.Time
+	for _, c := range d.Status.Conditions {
+		if c.LastUpdateTime.After(last) {
+			last = c.LastUpdateTime
+		}
+	}
+	if last.IsZero() {
+		last = d.Metadata.CreationTimestamp
+	}
+	return last.Format(time.RFC3339)
+}
+
+// getDeployment returns the Deployment named name.
+func (p *Provider) getDeployment(ctx context.Context, name string) (*deployment, error) {
+	var d deployment
+	if err := p.client.Do(ctx, http.MethodGet, p.deploymentPath(name), nil, &d); err != nil {
+		if kubeapi.IsNotFound(err) {
+			return nil, fmt.Errorf("no deployment found for environment %s in namespace %s", name, p.namespace)
+		}
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	return &d, nil
+}
+
+// replicaSets returns the ReplicaSets owned by the Deployment.
+func (p *Provider) replicaSets(ctx context.Context, d *deployment) ([]replicaSet, error) {
+	var selector []string
+	for k, v := range d.Spec.Selector.MatchLabels {
+		selector = append(selector, k+"="+v)
+	}
+	sort.Strings(selector)
+	query := url.Values{"labelSelector": {strings.Join(selector, ",")}}
+
+	var list struct {
+		Items []replicaSet `json:"items"`
+	}
+	path := "/apis/apps/v1/namespaces/" + p.namespace + "/replicasets?" + query.Encode()
+	if err := p.client.Do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	var owned []replicaSet
+	for _, rs := range list.Items {
+		for _, owner := range rs.Metadata.OwnerReferences {
+			if owner.UID == d.Metadata.UID {
+				owned = append(owned, rs)
+				break
+			}
+		}
+	}
+	return owned, nil
+}
+
+// previousRevision returns the ReplicaSet with the highest revision below current.
+func previousRevision(sets []replicaSet, current string) (*replicaSet, error) {
+	currentRevision, err := strconv.ParseInt(current, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("deployment has no revision to roll back from")
+	}
+
+	var previous *replicaSet
+	var previousRevision int64
+	for i := range sets {
+		revision, err := strconv.ParseInt(sets[i].Metadata.Annotations[revisionKey], 10, 64)
+		if err != nil || revision >= currentRevision {
+			continue
+		}
+		if revision > previousRevision {
+			previous, previousRevision = &sets[i], revision
+		}
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("no previous revision found to roll back to")
+	}
+	return previous, nil
+}
+
+// templateImages returns the images of the containers of a pod template.
+func templateImages(template podTemplate) []string {
+	var images []string
+	spec, _ := template["spec"].(map[string]interface{})
+	containers, _ := spec["containers"].([]interface{})
+	for _, c := range containers {
+		if container, ok := c.(map[string]interface{}); ok {
+			if image, ok := container["image"].(string); ok {
+				images = append(images, image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// rolloutComplete reports whether every pod of the Deployment runs its latest
+// pod template and is available, as kubectl rollout status checks.
+func rolloutComplete(d *deployment) bool {
+	desired := d.replicas()
+	return d.Status.ObservedGeneration >= d.Metadata.Generation &&
+		d.Status.UpdatedReplicas == desired &&
+		d.Status.Replicas == desired &&
+		d.Status.AvailableReplicas == desired
+}
+
+// waitForRollout polls the Deployment until its rollout finishes, or fails
+// when the Deployment's progress deadline is exceeded.
+func (p *Provider) waitForRollout(ctx context.Context, name string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(rolloutTimeout)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for deployment %s to roll out", name)
+		case <-ticker.C:
+			d, err := p.getDeployment(ctx, name)
+			if err != nil {
+				return err
+			}
+			if d.Status.ObservedGeneration < d.Metadata.Generation {
+				continue // Conditions still describe the previous rollout
+			}
+			for _, c := range d.Status.Conditions {
+				if c.Type == "Progressing" && c.Reason == "ProgressDeadlineExceeded" {
+					return fmt.Errorf("deployment %s failed to roll out: %s", name, c.Message)
+				}
+			}
+			logging.Infof("Rollout status: %d of %d updated replicas available", d.Status.AvailableReplicas, d.replicas())
+			if rolloutComplete(d) {
+				return nil
+			}
+		}
+	}
+}
+
+// serviceURL returns the URL of the deployment: the ingress host, the
+// service's load balancer, or its address inside the cluster. When wait is
+// set, a load balancer still being provisioned is waited for.
+func (p *Provider) serviceURL(ctx context.Context, m *manifest.Manifest, wait bool) (string, error) {
+	if k := m.Kubernetes; k != nil && k.Ingress != nil {
+		scheme := "http"
+		if k.Ingress.TLSSecret != "" {
+			scheme = "https"
+		}
+		return scheme + "://" + k.Ingress.Host + strings.TrimSuffix(k.Ingress.Path, "/"), nil
+	}
+
+	name := m.Environment.Name
+	timeout := time.After(loadBalancerTimeout)
+	for {
+		var svc service
+		if err := p.client.Do(ctx, http.MethodGet, p.servicePath(name), nil, &svc); err != nil {
+			return "", fmt.Errorf("failed to get service: %w", err)
+		}
+		if svc.Spec.Type != manifest.KubernetesServiceLoadBalancer {
+			return fmt.Sprintf("http://%s.%s.svc.cluster.local", name, p.namespace), nil
+		}
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			host := ingress.Hostname
+			if host == "" {
+				host = ingress.IP
+			}
+			if host != "" {
+				return "http://" + host, nil
+			}
+		}
+		if !wait {
+			return "", nil
+		}
+
+		logging.Info("Waiting for the load balancer address...")
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeout:
+			logging.Warnf("Load balancer of service %s has no address yet; check 'status' later", name)
+			return "", nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// delete deletes the object at path, ignoring objects that do not exist.
+func (p *Provider) delete(ctx context.Context, kind, path string) error {
+	options := map[string]interface{}{"apiVersion": "v1", "kind": "DeleteOptions", "propagationPolicy": "Background"}
+	err := p.client.Do(ctx, http.MethodDelete, path, options, nil)
+	if kubeapi.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", kind, err)
+	}
+	logging.Infof("Deleted %s: %s", kind, path[strings.LastIndex(path, "/")+1:])
+	return nil
+}
+
+func (p *Provider) deploymentPath(name string) string {
+	return "/apis/apps/v1/namespaces/" + p.namespace + "/deployments/" + name
+}
+
+func (p *Provider) servicePath(name string) string {
+	return "/api/v1/namespaces/" + p.namespace + "/services/" + name
+}
+
+func (p *Provider) ingressPath(name string) string {
+	return "/apis/networking.k8s.io/v1/namespaces/" + p.namespace + "/ingresses/" + name
+}
+
+// deployContainers returns the containers to run: the manifest's containers,
+// or the single container described by image.
+func deployContainers(m *manifest.Manifest) []manifest.Container {
+	if m.IsMultiContainer() {
+		return m.Containers
+	}
+	return []manifest.Container{m.GetPrimaryContainer()}
+}
+
+// containerPort returns the first port a container exposes, defaulting to 80.
+func containerPort(c manifest.Container) int {
+	if len(c.Ports) > 0 && c.Ports[0].ContainerPort > 0 {
+		return c.Ports[0].ContainerPort
+	}
+	return 80
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// dnsLabel turns a name into a DNS label, which Kubernetes requires of
+// container names and accepts as a label value.
+func dnsLabel(name string) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == "" {
+		return "app"
+	}
+	return name
+}
+
+// labels returns the labels of every object of the deployment. The selector
+// labels select its pods and never change.
+func labels(m *manifest.Manifest) map[string]string {
+	l := selectorLabels(m)
+	l[labelManagedBy] = "cloud-deploy"
+	l[labelPartOf] = dnsLabel(m.Application.Name)
+	return l
+}
+
+func selectorLabels(m *manifest.Manifest) map[string]string {
+	return map[string]string{labelName: m.Environment.Name}
+}
+
+// metadata returns the metadata of an object of the deployment, with the
+// manifest's tags and extra as annotations.
+func (p *Provider) metadata(m *manifest.Manifest, extra map[string]string) map[string]interface{} {
+	meta := map[string]interface{}{
+		"name":      m.Environment.Name,
+		"namespace": p.namespace,
+		"labels":    labels(m),
+	}
+	annotations := make(map[string]string)
+	for k, v := range m.Tags {
+		annotations[k] = v
+	}
+	for k, v := range extra {
+		annotations[k] = v
+	}
+	if len(annotations) > 0 {
+		meta["annotations"] = annotations
+	}
+	return meta
+}
+
+// runtimeClassName returns the RuntimeClass of the pods, or "" for the
+// cluster's default runtime.
+func runtimeClassName(m *manifest.Manifest) string {
+	if m.Kubernetes != nil && m.Kubernetes.RuntimeClassName != "" {
+		return m.Kubernetes.RuntimeClassName
+	}
+	if m.Wasm() {
+		return defaultWasmRuntimeClass
+	}
+	return ""
+}
+
+// validateWasmImages fails early when an image of a WebAssembly deployment is
+// a container image, which the Wasm runtime class cannot run.
+func validateWasmImages(ctx context.Context, m *manifest.Manifest) error {
+	target := "RuntimeClass " + runtimeClassName(m)
+	for _, c := range deployContainers(m) {
+		if err := registry.ValidatePlatform(ctx, c.Image, registry.Wasm, target, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deploymentObject builds the Deployment running the manifest's containers in
+// each pod.
+func (p *Provider) deploymentObject(m *manifest.Manifest) map[string]interface{} {
+	replicas := defaultReplicas
+	var pullSecret string
+	if k := m.Kubernetes; k != nil {
+		if k.Replicas != nil {
+			replicas = *k.Replicas
+		}
+		pullSecret = k.ImagePullSecret
+	}
+
+	primary := m.GetPrimaryContainer()
+	var containers []map[string]interface{}
+	var images []string
+	for _, c := range deployContainers(m) {
+		containers = append(containers, containerSpec(m, c, c.Name == primary.Name))
+		images = append(images, c.Image)
+	}
+
+	podSpec := map[string]interface{}{"containers": containers}
+	if pullSecret != "" {
+		podSpec["imagePullSecrets"] = []map[string]string{{"name": pullSecret}}
+	}
+	if runtimeClass := runtimeClassName(m); runtimeClass != "" {
+		podSpec["runtimeClassName"] = runtimeClass
+	}
+
+	// Recorded in the revision history, as kubectl rollout history shows it
+	changeCause := map[string]string{changeCauseKey: "cloud-deploy: " + strings.Join(images, ", ")}
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   p.metadata(m, changeCause),
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"selector": map[string]interface{}{"matchLabels": selectorLabels(m)},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels(m)},
+				"spec":     podSpec,
+			},
+		},
+	}
+}
+
+// containerSpec builds the pod spec entry of a container. The primary
+// container gets the manifest's health check as its probes.
+func containerSpec(m *manifest.Manifest, c manifest.Container, primary bool) map[string]interface{} {
+	spec := map[string]interface{}{
+		"name":  dnsLabel(c.Name),
+		"image": c.Image,
+	}
+
+	env := make(map[string]string)
+	for k, v := range m.EnvironmentVariables {
+		env[k] = v
+	}
+	for k, v := range c.Environment {
+		env[k] = v
+	}
+	if len(env) > 0 {
+		names := make([]string, 0, len(env))
+		for k := range env {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		vars := make([]map[string]string, 0, len(names))
+		for _, k := range names {
+			vars = append(vars, map[string]string{"name": k, "value": env[k]})
+		}
+		spec["env"] = vars
+	}
+
+	var ports []map[string]interface{}
+	for _, port := range c.Ports {
+		if port.ContainerPort > 0 {
+			ports = append(ports, map[string]interface{}{"containerPort": port.ContainerPort, "protocol": "TCP"})
+		}
+	}
+	if primary && len(ports) == 0 {
+		ports = append(ports, map[string]interface{}{"containerPort": containerPort(c), "protocol": "TCP"})
+	}
+	if len(ports) > 0 {
+		spec["ports"] = ports
+	}
+
+	if len(c.Command) > 0 {
+		spec["command"] = c.Command
+	}
+	if len(c.Args) > 0 {
+		spec["args"] = c.Args
+	}
+	if c.Workdir != "" {
+		spec["workingDir"] = c.Workdir
+	}
+	if resources := resourceRequirements(m.Kubernetes); resources != nil {
+		spec["resources"] = resources
+	}
+	if sc := securityContext(m.Security); sc != nil {
+		spec["securityContext"] = sc
+	}
+
+	// Apply health check configuration to the primary container
+	if primary && m.HealthCheck.Path != "" {
+		probe := func(delay, failures int) map[string]interface{} {
+			return map[string]interface{}{
+				"httpGet":             map[string]interface{}{"path": m.HealthCheck.Path, "port": containerPort(c)},
+				"initialDelaySeconds": delay,
+				"periodSeconds":       10,
+				"failureThreshold":    failures,
+			}
+		}
+		spec["readinessProbe"] = probe(5, 3)
+		spec["livenessProbe"] = probe(15, 3)
+		logging.Infof("Configured health check with path: %s", m.HealthCheck.Path)
+	}
+	return spec
+}
+
+// resourceRequirements converts the manifest's resources to a container's
+// resource requests and limits.
+func resourceRequirements(k *manifest.KubernetesConfig) map[string]interface{} {
+	if k == nil || k.Resources == nil {
+		return nil
+	}
+	quantities := func(cpu, memory string) map[string]string {
+		q := make(map[string]string)
+		if cpu != "" {
+			q["cpu"] = cpu
+		}
+		if memory != "" {
+			q["memory"] = memory
+		}
+		return q
+	}
+
+	resources := make(map[string]interface{})
+	r := k.Resources
+	if requests := quantities(r.CPURequest, r.MemoryRequest); len(requests) > 0 {
+		resources["requests"] = requests
+	}
+	if limits := quantities(r.CPULimit, r.MemoryLimit); len(limits) > 0 {
+		resources["limits"] = limits
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	return resources
+}
+
+// securityContext converts security hardening options to a container security context.
+func securityContext(sec *manifest.SecurityConfig) map[string]interface{} {
+	if sec == nil {
+		return nil
+	}
+	sc := make(map[string]interface{})
+	if sec.ReadOnlyRootFilesystem {
+		sc["readOnlyRootFilesystem"] = true
+	}
+	if sec.RunAsNonRoot {
+		sc["runAsNonRoot"] = true
+	}
+	if sec.User != "" {
+		user, group, _ := strings.Cut(sec.User, ":")
+		if uid, err := strconv.Atoi(user); err == nil {
+			sc["runAsUser"] = uid
+		} else {
+			logging.Warnf("security.user %q is not numeric; Kubernetes only accepts user IDs and it will be ignored", sec.User)
+		}
+		if gid, err := strconv.Atoi(group); err == nil {
+			sc["runAsGroup"] = gid
+		}
+	}
+	if len(sec.DropCapabilities) > 0 {
+		sc["capabilities"] = map[string]interface{}{"drop": sec.DropCapabilities}
+	}
+	if len(sc) == 0 {
+		return nil
+	}
+	return sc
+}
+
+// serviceType returns the type of the Service: as configured, or LoadBalancer
+// unless an ingress routes to it.
+func serviceType(m *manifest.Manifest) string {
+	k := m.Kubernetes
+	switch {
+	case k != nil && k.ServiceType != "":
+		return k.ServiceType
+	case k != nil && k.Ingress != nil:
+		return manifest.KubernetesServiceClusterIP
+	default:
+		return manifest.KubernetesServiceLoadBalancer
+	}
+}
+
+// serviceObject builds the Service exposing the primary container on port 80.
+func (p *Provider) serviceObject(m *manifest.Manifest) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   p.metadata(m, nil),
+		"spec": map[string]interface{}{
+			"type":     serviceType(m),
+			"selector": selectorLabels(m),
+			"ports": []map[string]interface{}{{
+				"name":       servicePortName,
+				"port":       80,
+				"targetPort": containerPort(m.GetPrimaryContainer()),
+				"protocol":   "TCP",
+			}},
+		},
+	}
+}
+
+// ingressObject builds the Ingress routing the configured host to the
+// Service, or returns nil when no ingress is configured.
+func (p *Provider) ingressObject(m *manifest.Manifest) map[string]interface{} {
+	if m.Kubernetes == nil || m.Kubernetes.Ingress == nil {
+		return nil
+	}
+	cfg := m.Kubernetes.Ingress
+	path := cfg.Path
+	if path == "" {
+		path = "/"
+	}
+
+	spec := map[string]interface{}{
+		"rules": []map[string]interface{}{{
+			"host": cfg.Host,
+			"http": map[string]interface{}{
+				"paths": []map[string]interface{}{{
+					"path":     path,
+					"pathType": "Prefix",
+					"backend": map[string]interface{}{
+						"service": map[string]interface{}{
+							"name": m.Environment.Name,
+							"port": map[string]interface{}{"name": servicePortName},
+						},
+					},
+				}},
+			},
+		}},
+	}
+	if cfg.ClassName != "" {
+		spec["ingressClassName"] = cfg.ClassName
+	}
+	if cfg.TLSSecret != "" {
+		spec["tls"] = []map[string]interface{}{{"hosts": []string{cfg.Host}, "secretName": cfg.TLSSecret}}
+	}
+	return map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   p.metadata(m, cfg.Annotations),
+		"spec":       spec,
+	}
+}
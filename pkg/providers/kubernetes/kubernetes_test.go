@@ -0,0 +1,584 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	ggcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/kubeapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeCluster serves the subset of the Kubernetes API the provider uses,
+// keeping applied objects by path. Deployments roll out as soon as they are
+// read.
+type fakeCluster struct {
+	mu          sync.Mutex
+	objects     map[string]map[string]interface{}
+	replicaSets []replicaSet
+	quotas      []resourceQuota
+	patches     map[string]interface{} // path -> last JSON or merge patch
+	requests    []string
+	lbHostname  string
+}
+
+func newFakeCluster() *fakeCluster {
+	return &fakeCluster{
+		objects:    make(map[string]map[string]interface{}),
+		patches:    make(map[string]interface{}),
+		lbHostname: "lb.example.com",
+	}
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	path := r.URL.Path
+	obj, exists := f.objects[path]
+	switch {
+	case r.Method == http.MethodPatch && r.Header.Get("Content-Type") == kubeapi.ApplyPatch:
+		var applied map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&applied)
+		generation := 1.0
+		if exists {
+			generation = obj["metadata"].(map[string]interface{})["generation"].(float64) + 1
+		}
+		meta := applied["metadata"].(map[string]interface{})
+		meta["generation"] = generation
+		meta["uid"] = "uid-" + meta["name"].(string)
+		f.objects[path] = applied
+		json.NewEncoder(w).Encode(applied)
+	case r.Method == http.MethodPatch && exists:
+		var patch interface{}
+		json.NewDecoder(r.Body).Decode(&patch)
+		f.patches[path] = patch
+		meta := obj["metadata"].(map[string]interface{})
+		meta["generation"] = meta["generation"].(float64) + 1
+		json.NewEncoder(w).Encode(obj)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/replicasets"):
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": f.replicaSets})
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/resourcequotas"):
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": f.quotas})
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/pods"):
+		w.Write([]byte(`{"items":[{"metadata":{"name":"web-abc"},"spec":{"containers":[{"name":"my-app"}]}}]}`))
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/log"):
+		if r.URL.Query().Get("timestamps") != "true" || r.URL.Query().Get("sinceTime") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ts := time.Now().UTC().Format(time.RFC3339Nano)
+		w.Write([]byte(ts + " listening on :8080\n" + ts + " ready\n"))
+	case r.Method == http.MethodGet && exists:
+		f.rollOut(obj)
+		json.NewEncoder(w).Encode(obj)
+	case r.Method == http.MethodDelete && exists:
+		delete(f.objects, path)
+		w.Write([]byte(`{"kind":"Status","status":"Success"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","status":"Failure","reason":"NotFound","code":404,"message":"not found"}`))
+	}
+}
+
+// rollOut completes the rollout of a Deployment, or provisions the load
+// balancer of a Service.
+func (f *fakeCluster) rollOut(obj map[string]interface{}) {
+	spec, _ := obj["spec"].(map[string]interface{})
+	switch obj["kind"] {
+	case "Deployment":
+		replicas := spec["replicas"]
+		meta := obj["metadata"].(map[string]interface{})
+		obj["status"] = map[string]interface{}{
+			"observedGeneration": meta["generation"],
+			"replicas":           replicas,
+			"updatedReplicas":    replicas,
+			"availableReplicas":  replicas,
+		}
+	case "Service":
+		if spec["type"] == manifest.KubernetesServiceLoadBalancer && f.lbHostname != "" {
+			obj["status"] = map[string]interface{}{
+				"loadBalancer": map[string]interface{}{"ingress": []map[string]string{{"hostname": f.lbHostname}}},
+			}
+		}
+	}
+}
+
+func (f *fakeCluster) requested(request string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeCluster) object(path string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[path]
+}
+
+const (
+	deploymentPath = "/apis/apps/v1/namespaces/apps/deployments/web"
+	servicePath    = "/api/v1/namespaces/apps/services/web"
+	ingressPath    = "/apis/networking.k8s.io/v1/namespaces/apps/ingresses/web"
+)
+
+func newTestProvider(t *testing.T, fake *fakeCluster) *Provider {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	oldInterval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldInterval })
+
+	return &Provider{namespace: "apps", client: kubeapi.New(&kubeapi.Config{Server: server.URL})}
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:                "registry.example.com/my-app:v2",
+		Provider:             manifest.ProviderConfig{Name: "kubernetes"},
+		Application:          manifest.ApplicationConfig{Name: "My App"},
+		Environment:          manifest.EnvironmentConfig{Name: "web"},
+		Ports:                []manifest.PortMapping{{ContainerPort: 8080}},
+		HealthCheck:          manifest.HealthCheckConfig{Path: "/healthz"},
+		EnvironmentVariables: map[string]string{"B": "2", "A": "1"},
+		Kubernetes: &manifest.KubernetesConfig{
+			Namespace: "apps",
+			Resources: &manifest.KubernetesResources{CPURequest: "250m", MemoryLimit: "512Mi"},
+		},
+	}
+}
+
+// lookup follows keys and array indexes through decoded JSON.
+func lookup(v interface{}, keys ...interface{}) interface{} {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			m, _ := v.(map[string]interface{})
+			v = m[k]
+		case int:
+			a, _ := v.([]interface{})
+			if k >= len(a) {
+				return nil
+			}
+			v = a[k]
+		}
+	}
+	return v
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	config := `current-context: dev
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev, namespace: team-a}
+clusters:
+- name: dev
+  cluster: {server: "https://dev.example.com"}
+users:
+- name: dev
+  user: {token: abc}
+`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := testManifest()
+	m.Kubernetes = &manifest.KubernetesConfig{Kubeconfig: path}
+	p, err := New(context.Background(), &m.Provider, m)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if p.namespace != "team-a" || p.client.Endpoint() != "https://dev.example.com" {
+		t.Errorf("expected the context's namespace and server, got %s at %s", p.namespace, p.client.Endpoint())
+	}
+
+	m.Kubernetes.Namespace = "apps"
+	if p, _ = New(context.Background(), &m.Provider, m); p.namespace != "apps" {
+		t.Errorf("expected the manifest's namespace, got %s", p.namespace)
+	}
+
+	m.Kubernetes.Kubeconfig = filepath.Join(t.TempDir(), "missing")
+	if _, err := New(context.Background(), &m.Provider, m); err == nil {
+		t.Error("expected an error for a missing kubeconfig")
+	}
+
+	m.Provider.Credentials = &manifest.CredentialsConfig{Source: "secrets-manager", SecretID: "x"}
+	if _, err := New(context.Background(), &m.Provider, m); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected secret store credentials to be rejected, got %v", err)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+
+	result, err := p.Deploy(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	if result.URL != "http://lb.example.com" || result.Images["My App"] != "registry.example.com/my-app:v2" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	d := fake.object(deploymentPath)
+	if d == nil {
+		t.Fatal("deployment was not applied")
+	}
+	if lookup(d, "spec", "replicas") != 1.0 {
+		t.Errorf("expected 1 replica by default, got %v", lookup(d, "spec", "replicas"))
+	}
+	if lookup(d, "spec", "selector", "matchLabels", labelName) != "web" || lookup(d, "metadata", "labels", labelPartOf) != "my-app" {
+		t.Errorf("unexpected labels: %v", lookup(d, "metadata", "labels"))
+	}
+
+	c := lookup(d, "spec", "template", "spec", "containers", 0)
+	if lookup(c, "name") != "my-app" || lookup(c, "image") != "registry.example.com/my-app:v2" {
+		t.Errorf("unexpected container: %v", c)
+	}
+	if lookup(c, "env", 0, "name") != "A" || lookup(c, "env", 1, "value") != "2" {
+		t.Errorf("expected sorted environment variables, got %v", lookup(c, "env"))
+	}
+	if lookup(c, "resources", "requests", "cpu") != "250m" || lookup(c, "resources", "limits", "memory") != "512Mi" {
+		t.Errorf("unexpected resources: %v", lookup(c, "resources"))
+	}
+	for _, probe := range []string{"readinessProbe", "livenessProbe"} {
+		if lookup(c, probe, "httpGet", "path") != "/healthz" || lookup(c, probe, "httpGet", "port") != 8080.0 {
+			t.Errorf("unexpected %s: %v", probe, lookup(c, probe))
+		}
+	}
+
+	svc := fake.object(servicePath)
+	if lookup(svc, "spec", "type") != "LoadBalancer" || lookup(svc, "spec", "ports", 0, "targetPort") != 8080.0 {
+		t.Errorf("unexpected service: %v", svc)
+	}
+	if !fake.requested("DELETE " + ingressPath) {
+		t.Error("expected a leftover ingress to be deleted when none is configured")
+	}
+}
+
+func TestDeployPendingLoadBalancer(t *testing.T) {
+	fake := newFakeCluster()
+	fake.lbHostname = ""
+	p := newTestProvider(t, fake)
+	old := loadBalancerTimeout
+	loadBalancerTimeout = 10 * time.Millisecond
+	defer func() { loadBalancerTimeout = old }()
+
+	result, err := p.Deploy(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	if result.URL != "" {
+		t.Errorf("expected no URL before the load balancer has an address, got %s", result.URL)
+	}
+}
+
+func TestDeployIngress(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+
+	m := testManifest()
+	replicas := int32(3)
+	m.Kubernetes.Replicas = &replicas
+	m.Kubernetes.Ingress = &manifest.KubernetesIngress{
+		Host:        "app.example.com",
+		ClassName:   "nginx",
+		TLSSecret:   "app-tls",
+		Annotations: map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+	}
+	result, err := p.Deploy(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	if result.URL != "https://app.example.com" {
+		t.Errorf("unexpected URL: %s", result.URL)
+	}
+
+	ing := fake.object(ingressPath)
+	if lookup(ing, "spec", "ingressClassName") != "nginx" || lookup(ing, "spec", "tls", 0, "secretName") != "app-tls" {
+		t.Errorf("unexpected ingress: %v", ing)
+	}
+	if lookup(ing, "spec", "rules", 0, "http", "paths", 0, "backend", "service", "name") != "web" {
+		t.Errorf("unexpected ingress backend: %v", lookup(ing, "spec", "rules"))
+	}
+	if lookup(ing, "metadata", "annotations", "cert-manager.io/cluster-issuer") != "letsencrypt" {
+		t.Errorf("unexpected annotations: %v", lookup(ing, "metadata", "annotations"))
+	}
+	if lookup(fake.object(servicePath), "spec", "type") != "ClusterIP" {
+		t.Error("expected a ClusterIP service behind an ingress")
+	}
+	if lookup(fake.object(deploymentPath), "spec", "replicas") != 3.0 {
+		t.Error("expected 3 replicas")
+	}
+}
+
+func TestDeployProgressDeadlineExceeded(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == deploymentPath {
+			w.Write([]byte(`{"metadata":{"name":"web","generation":2},"spec":{"replicas":1},"status":{"observedGeneration":2,
+				"conditions":[{"type":"Progressing","status":"False","reason":"ProgressDeadlineExceeded","message":"ReplicaSet \"web-1\" has timed out progressing."}]}}`))
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	p.client.SetEndpoint(server.URL)
+
+	_, err := p.Deploy(context.Background(), testManifest())
+	if err == nil || !strings.Contains(err.Error(), "timed out progressing") {
+		t.Errorf("expected a progress deadline error, got %v", err)
+	}
+}
+
+func TestDeployMultiContainer(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+
+	m := testManifest()
+	m.Image = ""
+	m.Kubernetes.ServiceType = manifest.KubernetesServiceNodePort
+	m.Containers = []manifest.Container{
+		{Name: "web", Image: "web:1", Ports: []manifest.PortMapping{{ContainerPort: 3000}}},
+		{Name: "Worker_1", Image: "worker:1", Environment: map[string]string{"QUEUE": "jobs"}},
+	}
+	result, err := p.Deploy(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	if result.URL != "http://web.apps.svc.cluster.local" {
+		t.Errorf("unexpected URL: %s", result.URL)
+	}
+
+	d := fake.object(deploymentPath)
+	containers := lookup(d, "spec", "template", "spec", "containers").([]interface{})
+	if len(containers) != 2 || lookup(containers[1], "name") != "worker-1" {
+		t.Fatalf("unexpected containers: %v", containers)
+	}
+	if lookup(containers[1], "readinessProbe") != nil || lookup(containers[0], "readinessProbe", "httpGet", "port") != 3000.0 {
+		t.Error("expected probes on the primary container only")
+	}
+	if lookup(containers[1], "ports") != nil {
+		t.Error("expected no ports on a container without ports")
+	}
+}
+
+func TestDeployWasm(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// A Spin application and a container image in a registry
+	artifact := mutate.ConfigMediaType(mutate.MediaType(empty.Image, ggcrtypes.OCIManifestSchema1), "application/vnd.wasm.config.v0+json")
+	artifact, err := mutate.AppendLayers(artifact, static.NewLayer([]byte("\x00asm"), "application/vnd.fermyon.spin.application.v1+config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wasmRef, _ := name.ParseReference(host + "/hello-spin:v1")
+	if err := remote.Write(wasmRef, artifact); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+	img, _ := random.Image(64, 1)
+	containerRef, _ := name.ParseReference(host + "/my-app:v1")
+	if err := remote.Write(containerRef, img); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	m.ArtifactType = manifest.ArtifactWasm
+
+	m.Image = containerRef.String()
+	if _, err := p.Deploy(context.Background(), m); err == nil || !strings.Contains(err.Error(), "is a container image") {
+		t.Errorf("expected a container image to be rejected, got %v", err)
+	}
+	if fake.object(deploymentPath) != nil {
+		t.Error("expected nothing to be applied for a rejected image")
+	}
+
+	m.Image = wasmRef.String()
+	if _, err := p.Deploy(context.Background(), m); err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	if rc := lookup(fake.object(deploymentPath), "spec", "template", "spec", "runtimeClassName"); rc != "wasmtime-spin-v2" {
+		t.Errorf("expected the SpinKube runtime class, got %v", rc)
+	}
+}
+
+func TestRuntimeClassName(t *testing.T) {
+	m := testManifest()
+	if rc := runtimeClassName(m); rc != "" {
+		t.Errorf("expected the default runtime for containers, got %q", rc)
+	}
+	m.ArtifactType = manifest.ArtifactWasm
+	m.Kubernetes.RuntimeClassName = "wasmtime"
+	if rc := runtimeClassName(m); rc != "wasmtime" {
+		t.Errorf("expected the configured runtime class, got %q", rc)
+	}
+}
+
+func TestStopAndDestroy(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	if _, err := p.Deploy(context.Background(), m); err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+
+	if err := p.Stop(context.Background(), m); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if lookup(fake.patches[deploymentPath], "spec", "replicas") != 0.0 {
+		t.Errorf("expected a patch to 0 replicas, got %v", fake.patches[deploymentPath])
+	}
+
+	if err := p.Destroy(context.Background(), m); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if fake.object(deploymentPath) != nil || fake.object(servicePath) != nil {
+		t.Error("expected the deployment and service to be deleted")
+	}
+	// Destroying again finds nothing to delete
+	if err := p.Destroy(context.Background(), m); err != nil {
+		t.Errorf("Destroy() of a destroyed deployment failed: %v", err)
+	}
+	if err := p.Stop(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no deployment found") {
+		t.Errorf("expected a missing deployment error, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	if _, err := p.Status(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no deployment found") {
+		t.Errorf("expected a missing deployment error, got %v", err)
+	}
+
+	if _, err := p.Deploy(context.Background(), m); err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	status, err := p.Status(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.URL != "http://lb.example.com" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	d := &deployment{}
+	d.Metadata.Generation = 2
+	d.Status.ObservedGeneration = 2
+	d.Status.Replicas, d.Status.UpdatedReplicas, d.Status.AvailableReplicas = 1, 1, 1
+	if !rolloutComplete(d) {
+		t.Error("expected the rollout to be complete")
+	}
+
+	d.Status.ObservedGeneration = 1
+	if rolloutComplete(d) {
+		t.Error("expected an unobserved generation to be in progress")
+	}
+
+	d.Status.ObservedGeneration = 2
+	d.Status.Replicas = 2 // old pods still terminating
+	if rolloutComplete(d) {
+		t.Error("expected old replicas to keep the rollout in progress")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	if _, err := p.Deploy(context.Background(), m); err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	fake.object(deploymentPath)["metadata"].(map[string]interface{})["annotations"] = map[string]string{revisionKey: "3"}
+
+	rs := func(revision, image, owner string) replicaSet {
+		var r replicaSet
+		r.Metadata.Name = "web-" + revision
+		r.Metadata.Annotations = map[string]string{revisionKey: revision}
+		r.Metadata.OwnerReferences = []struct {
+			UID string `json:"uid"`
+		}{{UID: owner}}
+		r.Spec.Template = podTemplate{
+			"metadata": map[string]interface{}{"labels": map[string]interface{}{labelName: "web", podTemplateHash: "abc"}},
+			"spec":     map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "my-app", "image": image}}},
+		}
+		return r
+	}
+	fake.replicaSets = []replicaSet{
+		rs("1", "my-app:v0", "uid-web"),
+		rs("2", "my-app:v1", "uid-web"),
+		rs("3", "my-app:v2", "uid-web"),
+		rs("9", "other:v9", "uid-other"),
+	}
+
+	result, err := p.Rollback(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if !strings.Contains(result.Message, "my-app:v1") {
+		t.Errorf("expected a rollback to revision 2, got %q", result.Message)
+	}
+
+	patch := fake.patches[deploymentPath]
+	if lookup(patch, 0, "op") != "replace" || lookup(patch, 0, "path") != "/spec/template" {
+		t.Fatalf("unexpected patch: %v", patch)
+	}
+	if lookup(patch, 0, "value", "metadata", "labels", podTemplateHash) != nil {
+		t.Error("expected the pod-template-hash label to be removed")
+	}
+
+	fake.replicaSets = fake.replicaSets[2:]
+	if _, err := p.Rollback(context.Background(), m); err == nil || !strings.Contains(err.Error(), "no previous revision") {
+		t.Errorf("expected no previous revision, got %v", err)
+	}
+}
+
+func TestDNSLabel(t *testing.T) {
+	tests := map[string]string{
+		"my-app":                       "my-app",
+		"My App":                       "my-app",
+		"worker_1":                     "worker-1",
+		"--x--":                        "x",
+		"!!!":                          "app",
+		strings.Repeat("a", 70):        strings.Repeat("a", 63),
+		strings.Repeat("a", 62) + "_b": strings.Repeat("a", 62),
+	}
+	for in, want := range tests {
+		if got := dnsLabel(in); got != want {
+			t.Errorf("dnsLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logstream"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// pod is a v1 Pod, with the names of its containers.
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+	} `json:"spec"`
+}
+
+// Logs prints the output of each container in the deployment's pods. The pods
+// are listed again on every poll, so following continues across rollouts.
+// Only the logs of existing pods can be read; the output of deleted pods is gone.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	if _, err := p.getDeployment(ctx, m.Environment.Name); err != nil {
+		return err
+	}
+
+	return logstream.Stream(ctx, opts, func(ctx context.Context, since time.Time) ([]types.LogEntry, error) {
+		pods, err := p.pods(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []types.LogEntry
+		for _, pod := range pods {
+			for _, c := range pod.Spec.Containers {
+				query := url.Values{
+					"container":  {c.Name},
+					"timestamps": {"true"},
+					"sinceTime":  {since.UTC().Format(time.RFC3339)},
+				}
+				path := "/api/v1/namespaces/" + p.namespace + "/pods/" + pod.Metadata.Name + "/log?" + query.Encode()
+				logs, err := p.client.DoRaw(ctx, path)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get logs for container %s of pod %s: %w", c.Name, pod.Metadata.Name, err)
+				}
+
+				source := pod.Metadata.Name
+				if len(pod.Spec.Containers) > 1 {
+					source += "/" + c.Name
+				}
+				for _, e := range parseContainerLogs(source, string(logs)) {
+					if !e.Timestamp.Before(since) {
+						entries = append(entries, e)
+					}
+				}
+			}
+		}
+		return entries, nil
+	})
+}
+
+// pods returns the pods of the deployment.
+func (p *Provider) pods(ctx context.Context, m *manifest.Manifest) ([]pod, error) {
+	query := url.Values{"labelSelector": {labelName + "=" + m.Environment.Name}}
+	var list struct {
+		Items []pod `json:"items"`
+	}
+	if err := p.client.Do(ctx, http.MethodGet, "/api/v1/namespaces/"+p.namespace+"/pods?"+query.Encode(), nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return list.Items, nil
+}
+
+// parseContainerLogs splits container log output requested with timestamps into
+// entries. Each line starts with an RFC 3339 timestamp; lines without one are
+// continuations of the previous entry.
+func parseContainerLogs(source, content string) []types.LogEntry {
+	var entries []types.LogEntry
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		stamp, message, _ := strings.Cut(line, " ")
+		ts, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			if len(entries) > 0 {
+				last := &entries[len(entries)-1]
+				last.Message += "\n" + line
+				last.ID += "\n" + line
+			}
+			continue
+		}
+		entries = append(entries, types.LogEntry{
+			// The API has no entry IDs; the timestamped line identifies the entry
+			ID:        source + " " + line,
+			Timestamp: ts,
+			Source:    source,
+			Message:   message,
+		})
+	}
+	return entries
+}
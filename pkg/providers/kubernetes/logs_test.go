@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	var out bytes.Buffer
+	if err := p.Logs(context.Background(), m, types.LogOptions{Since: time.Minute, Output: &out}); err == nil {
+		t.Error("expected an error without a deployment")
+	}
+
+	if _, err := p.Deploy(context.Background(), m); err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	if err := p.Logs(context.Background(), m, types.LogOptions{Since: time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs() failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[web-abc] listening on :8080") || !strings.HasSuffix(lines[1], "[web-abc] ready") {
+		t.Errorf("unexpected logs:\n%s", out.String())
+	}
+}
+
+func TestParseContainerLogs(t *testing.T) {
+	content := "2026-01-02T03:04:05.123456789Z panic: boom\n\tgoroutine 1\n2026-01-02T03:04:06Z restarted\n"
+	entries := parseContainerLogs("web-abc/app", content)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Message != "panic: boom\n\tgoroutine 1" || entries[0].Source != "web-abc/app" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Timestamp.Second() != 6 || entries[1].Message != "restarted" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+}
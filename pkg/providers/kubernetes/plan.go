@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/kubeapi"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without changing
+// anything in the cluster. Existing objects are updated in place; a Deployment
+// whose images change rolls out new pods.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "kubernetes"}
+	name := m.Environment.Name
+
+	var d deployment
+	found, err := p.get(ctx, p.deploymentPath(name), &d)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, c := range deployContainers(m) {
+		images = append(images, c.Image)
+	}
+	slices.Sort(images)
+	switch current := templateImages(d.Spec.Template); {
+	case !found:
+		plan.Add(types.PlanCreate, "Deployment", name, "deployment does not exist")
+	case !slices.Equal(current, images):
+		plan.Add(types.PlanUpdate, "Deployment", name, fmt.Sprintf("roll out %s (running %s)", strings.Join(images, ", "), strings.Join(current, ", ")))
+	default:
+		plan.Add(types.PlanUpdate, "Deployment", name, "settings are re-applied; pods only restart if the pod template changed")
+	}
+
+	var svc service
+	found, err = p.get(ctx, p.servicePath(name), &svc)
+	if err != nil {
+		return nil, err
+	}
+	switch want := serviceType(m); {
+	case !found:
+		plan.Add(types.PlanCreate, "Service", name, want)
+	case svc.Spec.Type != want:
+		plan.Add(types.PlanUpdate, "Service", name, fmt.Sprintf("type %s -> %s", svc.Spec.Type, want))
+	default:
+		plan.Add(types.PlanNoChange, "Service", name, "")
+	}
+
+	found, err = p.get(ctx, p.ingressPath(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	switch configured := m.Kubernetes != nil && m.Kubernetes.Ingress != nil; {
+	case configured && !found:
+		plan.Add(types.PlanCreate, "Ingress", name, m.Kubernetes.Ingress.Host)
+	case configured:
+		plan.Add(types.PlanUpdate, "Ingress", name, m.Kubernetes.Ingress.Host)
+	case found:
+		plan.Add(types.PlanDelete, "Ingress", name, "kubernetes.ingress is not configured")
+	}
+	return plan, nil
+}
+
+// get reads the object at path into out, reporting whether it exists.
+func (p *Provider) get(ctx context.Context, path string, out interface{}) (bool, error) {
+	err := p.client.Do(ctx, http.MethodGet, path, nil, out)
+	if kubeapi.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	plan, err := p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[0].Action != types.PlanCreate || plan.Changes[1].Action != types.PlanCreate {
+		t.Errorf("expected the deployment and service to be created, got %+v", plan.Changes)
+	}
+
+	if _, err := p.Deploy(context.Background(), m); err != nil {
+		t.Fatalf("Deploy() failed: %v", err)
+	}
+	m.Image = "registry.example.com/my-app:v3"
+	m.Kubernetes.Ingress = &manifest.KubernetesIngress{Host: "app.example.com"}
+	plan, err = p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want := []struct {
+		action types.PlanAction
+		kind   string
+		reason string
+	}{
+		{types.PlanUpdate, "Deployment", "roll out registry.example.com/my-app:v3 (running registry.example.com/my-app:v2)"},
+		{types.PlanUpdate, "Service", "type LoadBalancer -> ClusterIP"},
+		{types.PlanCreate, "Ingress", "app.example.com"},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), plan.Changes)
+	}
+	for i, w := range want {
+		c := plan.Changes[i]
+		if c.Action != w.action || c.Type != w.kind || !strings.Contains(c.Reason, w.reason) {
+			t.Errorf("change %d: expected %s %s (%s), got %+v", i, w.action, w.kind, w.reason, c)
+		}
+	}
+}
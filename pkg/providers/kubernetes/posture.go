@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// resourceQuota is a v1 ResourceQuota, with its limits and usage.
+type resourceQuota struct {
+	Metadata objectMeta `json:"metadata"`
+	Status   struct {
+		Hard map[string]string `json:"hard"`
+		Used map[string]string `json:"used"`
+	} `json:"status"`
+}
+
+// Posture checks the expiry of the client certificate the provider
+// authenticates with and the usage of the namespace's ResourceQuotas.
+// Tokens from credential plugins are refreshed when they expire, so they are
+// not checked.
+func (p *Provider) Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error) {
+	posture := &types.Posture{Provider: "kubernetes"}
+	if expires, ok := p.client.CertificateExpiry(); ok {
+		posture.AddExpiry("Client certificate", expires, time.Now())
+	}
+
+	var list struct {
+		Items []resourceQuota `json:"items"`
+	}
+	if err := p.client.Do(ctx, http.MethodGet, "/api/v1/namespaces/"+p.namespace+"/resourcequotas", nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list resource quotas: %w", err)
+	}
+	if len(list.Items) == 0 {
+		posture.Add(types.PostureQuota, "ResourceQuotas ("+p.namespace+")", types.PostureOK, "none; the namespace is not limited")
+	}
+	for _, q := range list.Items {
+		addResourceQuota(posture, p.namespace, q)
+	}
+	return posture, nil
+}
+
+// addResourceQuota adds a quota check for each resource the quota limits.
+// Resources whose quantities cannot be parsed are skipped.
+func addResourceQuota(posture *types.Posture, namespace string, q resourceQuota) {
+	resources := make([]string, 0, len(q.Status.Hard))
+	for resource := range q.Status.Hard {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	for _, resource := range resources {
+		hard := q.Status.Hard[resource]
+		used := q.Status.Used[resource]
+		if used == "" {
+			used = "0"
+		}
+		h, err := parseQuantity(hard)
+		if err != nil {
+			continue
+		}
+		u, err := parseQuantity(used)
+		if err != nil {
+			continue
+		}
+		name := fmt.Sprintf("%s/%s (%s)", q.Metadata.Name, resource, namespace)
+		status := types.PostureFailing
+		if h > 0 {
+			status = types.QuotaStatus(u, h)
+		}
+		posture.Add(types.PostureQuota, name, status, fmt.Sprintf("%s of %s used", used, hard))
+	}
+}
+
+// quantitySuffixes are the multipliers of the suffixes of resource quantities.
+var quantitySuffixes = map[string]float64{
+	"n": 1e-9, "u": 1e-6, "m": 1e-3,
+	"k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+}
+
+// parseQuantity parses a resource quantity such as "500m", "2Gi", or "1e3".
+func parseQuantity(s string) (float64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '+' && r != '-'
+	})
+	if i > 0 {
+		if mult, ok := quantitySuffixes[s[i:]]; ok {
+			n, err := strconv.ParseFloat(s[:i], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid quantity %q", s)
+			}
+			return n * mult, nil
+		}
+	}
+	// Plain numbers and decimal exponents ("1e3")
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
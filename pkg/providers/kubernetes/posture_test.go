@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPosture(t *testing.T) {
+	fake := newFakeCluster()
+	p := newTestProvider(t, fake)
+
+	posture, err := p.Posture(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Posture() failed: %v", err)
+	}
+	if len(posture.Checks) != 1 || posture.Checks[0].Status != types.PostureOK || posture.Checks[0].Name != "ResourceQuotas (apps)" {
+		t.Errorf("expected a single check for a namespace without quotas, got %+v", posture.Checks)
+	}
+
+	var q resourceQuota
+	q.Metadata.Name = "compute"
+	q.Status.Hard = map[string]string{"requests.cpu": "4", "requests.memory": "8Gi", "pods": "10"}
+	q.Status.Used = map[string]string{"requests.cpu": "3500m", "requests.memory": "2Gi"}
+	fake.quotas = []resourceQuota{q}
+
+	posture, err = p.Posture(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Posture() failed: %v", err)
+	}
+	want := []types.PostureCheck{
+		{Category: types.PostureQuota, Name: "compute/pods (apps)", Status: types.PostureOK, Detail: "0 of 10 used"},
+		{Category: types.PostureQuota, Name: "compute/requests.cpu (apps)", Status: types.PostureWarning, Detail: "3500m of 4 used"},
+		{Category: types.PostureQuota, Name: "compute/requests.memory (apps)", Status: types.PostureOK, Detail: "2Gi of 8Gi used"},
+	}
+	if len(posture.Checks) != len(want) {
+		t.Fatalf("expected %d checks, got %+v", len(want), posture.Checks)
+	}
+	for i, w := range want {
+		if posture.Checks[i] != w {
+			t.Errorf("check %d: expected %+v, got %+v", i, w, posture.Checks[i])
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]float64{
+		"2":     2,
+		"500m":  0.5,
+		"1.5":   1.5,
+		"2Gi":   2 << 30,
+		"512Mi": 512 << 20,
+		"1k":    1000,
+		"1e3":   1000,
+		"3E":    3e18,
+	}
+	for in, want := range tests {
+		got, err := parseQuantity(in)
+		if err != nil || got != want {
+			t.Errorf("parseQuantity(%q) = %v (%v), want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "1Xi"} {
+		if _, err := parseQuantity(in); err == nil {
+			t.Errorf("parseQuantity(%q): expected an error", in)
+		}
+	}
+}
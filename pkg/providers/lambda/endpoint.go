@@ -0,0 +1,247 @@
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// urlConfig is the function URL of the live alias.
+type urlConfig struct {
+	FunctionUrl string
+	AuthType    string
+}
+
+// httpAPI is an API Gateway HTTP API. API Gateway uses camel-case JSON.
+type httpAPI struct {
+	APIID       string `json:"apiId"`
+	Name        string `json:"name"`
+	APIEndpoint string `json:"apiEndpoint"`
+}
+
+// ensureEndpoint exposes the alias over HTTP as lambda.endpoint configures
+// and removes the endpoint of the other kind, returning the endpoint's URL.
+func (p *Provider) ensureEndpoint(ctx context.Context, m *manifest.Manifest, aliasARN string) (string, error) {
+	name := m.Environment.Name
+	switch m.Lambda.HTTPEndpoint() {
+	case manifest.LambdaEndpointAPIGateway:
+		if err := p.deleteFunctionURL(ctx, name); err != nil {
+			return "", err
+		}
+		return p.ensureAPI(ctx, m, aliasARN)
+	case manifest.LambdaEndpointNone:
+		if err := p.deleteFunctionURL(ctx, name); err != nil {
+			return "", err
+		}
+		return "", p.deleteAPI(ctx, name)
+	default:
+		if err := p.deleteAPI(ctx, name); err != nil {
+			return "", err
+		}
+		return p.ensureFunctionURL(ctx, m)
+	}
+}
+
+// endpointURL returns the URL of the function's HTTP endpoint, or "" when it
+// has none.
+func (p *Provider) endpointURL(ctx context.Context, m *manifest.Manifest) (string, error) {
+	switch m.Lambda.HTTPEndpoint() {
+	case manifest.LambdaEndpointAPIGateway:
+		api, err := p.findAPI(ctx, m.Environment.Name)
+		if err != nil || api == nil {
+			return "", err
+		}
+		return api.APIEndpoint, nil
+	case manifest.LambdaEndpointNone:
+		return "", nil
+	default:
+		c, err := p.getFunctionURL(ctx, m.Environment.Name)
+		if err != nil || c == nil {
+			return "", err
+		}
+		return c.FunctionUrl, nil
+	}
+}
+
+// authType returns the auth type of the function URL.
+func authType(c *manifest.LambdaConfig) string {
+	if c != nil && c.IAMAuth {
+		return "AWS_IAM"
+	}
+	return "NONE"
+}
+
+// getFunctionURL returns the function URL of the live alias, or nil when it
+// has none.
+func (p *Provider) getFunctionURL(ctx context.Context, name string) (*urlConfig, error) {
+	var c urlConfig
+	if err := p.lambdaClient.REST(ctx, http.MethodGet, functionURLPath(name), nil, &c); err != nil {
+		if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get function URL of %s: %w", name, err)
+	}
+	return &c, nil
+}
+
+// ensureFunctionURL creates or updates the function URL of the live alias.
+// Public URLs also need a resource policy that lets anyone invoke the
+// function through the URL.
+func (p *Provider) ensureFunctionURL(ctx context.Context, m *manifest.Manifest) (string, error) {
+	name := m.Environment.Name
+	auth := authType(m.Lambda)
+
+	c, err := p.getFunctionURL(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	input := map[string]string{"AuthType": auth}
+	switch {
+	case c == nil:
+		logging.Infof("Creating function URL for %s", name)
+		c = &urlConfig{}
+		if err := p.lambdaClient.REST(ctx, http.MethodPost, functionURLPath(name), input, c); err != nil {
+			return "", fmt.Errorf("failed to create function URL: %w", err)
+		}
+	case c.AuthType != auth:
+		logging.Infof("Changing the auth type of the function URL to %s", auth)
+		if err := p.lambdaClient.REST(ctx, http.MethodPut, functionURLPath(name), input, c); err != nil {
+			return "", fmt.Errorf("failed to update function URL: %w", err)
+		}
+	}
+
+	if auth == "NONE" {
+		// Invoking through a URL requires both permissions
+		permissions := []map[string]interface{}{
+			{
+				"StatementId":         "FunctionURLAllowPublicAccess",
+				"Action":              "lambda:InvokeFunctionUrl",
+				"Principal":           "*",
+				"FunctionUrlAuthType": "NONE",
+			},
+			{
+				"StatementId":           "FunctionURLAllowInvokeAction",
+				"Action":                "lambda:InvokeFunction",
+				"Principal":             "*",
+				"InvokedViaFunctionUrl": true,
+			},
+		}
+		for _, permission := range permissions {
+			if err := p.addPermission(ctx, name, permission); err != nil {
+				return "", err
+			}
+		}
+	}
+	return c.FunctionUrl, nil
+}
+
+// deleteFunctionURL deletes the function URL of the live alias if it exists.
+func (p *Provider) deleteFunctionURL(ctx context.Context, name string) error {
+	err := p.lambdaClient.REST(ctx, http.MethodDelete, functionURLPath(name), nil, nil)
+	if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete function URL of %s: %w", name, err)
+	}
+	logging.Infof("Deleted function URL of %s", name)
+	return nil
+}
+
+// functionURLPath returns the path of the live alias's function URL.
+func functionURLPath(name string) string {
+	return urlConfigPath + name + "/url?Qualifier=" + aliasName
+}
+
+// addPermission adds a statement to the live alias's resource policy.
+// Statements are added once; later deployments find them in place.
+func (p *Provider) addPermission(ctx context.Context, name string, statement map[string]interface{}) error {
+	path := functionsPath + name + "/policy?Qualifier=" + aliasName
+	err := p.lambdaClient.REST(ctx, http.MethodPost, path, statement, nil)
+	if err != nil && !awsapi.IsErrorCode(err, "ResourceConflictException") {
+		return fmt.Errorf("failed to add permission %s to function %s: %w", statement["StatementId"], name, err)
+	}
+	return nil
+}
+
+// findAPI returns the HTTP API named after the environment, or nil when it
+// does not exist.
+func (p *Provider) findAPI(ctx context.Context, name string) (*httpAPI, error) {
+	token := ""
+	for {
+		path := apisPath
+		if token != "" {
+			path += "?nextToken=" + url.QueryEscape(token)
+		}
+		var out struct {
+			Items     []httpAPI `json:"items"`
+			NextToken string    `json:"nextToken"`
+		}
+		if err := p.apiClient.REST(ctx, http.MethodGet, path, nil, &out); err != nil {
+			return nil, fmt.Errorf("failed to list HTTP APIs: %w", err)
+		}
+		for i := range out.Items {
+			if out.Items[i].Name == name {
+				return &out.Items[i], nil
+			}
+		}
+		if out.NextToken == "" {
+			return nil, nil
+		}
+		token = out.NextToken
+	}
+}
+
+// ensureAPI creates an HTTP API that proxies every request to the alias,
+// using API Gateway's quick create, and lets API Gateway invoke the alias.
+func (p *Provider) ensureAPI(ctx context.Context, m *manifest.Manifest, aliasARN string) (string, error) {
+	name := m.Environment.Name
+	api, err := p.findAPI(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if api == nil {
+		logging.Infof("Creating HTTP API: %s", name)
+		input := map[string]interface{}{
+			"name":         name,
+			"protocolType": "HTTP",
+			"target":       aliasARN,
+		}
+		if len(m.Tags) > 0 {
+			input["tags"] = m.Tags
+		}
+		api = &httpAPI{}
+		if err := p.apiClient.REST(ctx, http.MethodPost, apisPath, input, api); err != nil {
+			return "", fmt.Errorf("failed to create HTTP API: %w", err)
+		}
+	}
+
+	permission := map[string]interface{}{
+		"StatementId": "APIGatewayInvoke",
+		"Action":      "lambda:InvokeFunction",
+		"Principal":   "apigateway.amazonaws.com",
+		"SourceArn":   fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/*", p.region, accountID(aliasARN), api.APIID),
+	}
+	if err := p.addPermission(ctx, name, permission); err != nil {
+		return "", err
+	}
+	return api.APIEndpoint, nil
+}
+
+// deleteAPI deletes the HTTP API named after the environment if it exists.
+func (p *Provider) deleteAPI(ctx context.Context, name string) error {
+	api, err := p.findAPI(ctx, name)
+	if err != nil || api == nil {
+		return err
+	}
+	if err := p.apiClient.REST(ctx, http.MethodDelete, apisPath+"/"+api.APIID, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete HTTP API %s: %w", name, err)
+	}
+	logging.Infof("Deleted HTTP API: %s", name)
+	return nil
+}
@@ -0,0 +1,67 @@
+package lambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func TestEnsureFunctionURLPermissionsOnce(t *testing.T) {
+	fake := newFakeAWS()
+	fake.function = map[string]interface{}{}
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	for i := 0; i < 2; i++ {
+		url, err := p.ensureFunctionURL(context.Background(), m)
+		if err != nil {
+			t.Fatalf("ensureFunctionURL() failed on call %d: %v", i+1, err)
+		}
+		if url != "https://abc.lambda-url.us-east-1.on.aws/" {
+			t.Errorf("unexpected URL %q", url)
+		}
+	}
+	if len(fake.permissions) != 2 {
+		t.Errorf("expected two permissions, got %v", fake.permissions)
+	}
+
+	// IAM-authenticated URLs are invoked by callers' own permissions
+	fake.permissions = nil
+	m.Lambda.IAMAuth = true
+	if _, err := p.ensureFunctionURL(context.Background(), m); err != nil {
+		t.Fatalf("ensureFunctionURL() failed: %v", err)
+	}
+	if fake.urlAuth != "AWS_IAM" || len(fake.permissions) != 0 {
+		t.Errorf("unexpected auth %q and permissions %v", fake.urlAuth, fake.permissions)
+	}
+}
+
+func TestEndpointURL(t *testing.T) {
+	fake := newFakeAWS()
+	fake.function = map[string]interface{}{}
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if url, err := p.endpointURL(ctx, m); err != nil || url != "" {
+		t.Errorf("endpointURL() without an endpoint = %q, %v", url, err)
+	}
+
+	m.Lambda.Endpoint = manifest.LambdaEndpointAPIGateway
+	fake.api = true
+	if url, err := p.endpointURL(ctx, m); err != nil || url != "https://abc123.execute-api.us-east-1.amazonaws.com" {
+		t.Errorf("endpointURL() = %q, %v", url, err)
+	}
+	if err := p.deleteAPI(ctx, "my-env"); err != nil || fake.api {
+		t.Errorf("deleteAPI() failed: %v", err)
+	}
+	if err := p.deleteAPI(ctx, "my-env"); err != nil {
+		t.Errorf("deleteAPI() of a deleted API failed: %v", err)
+	}
+
+	m.Lambda.Endpoint = manifest.LambdaEndpointNone
+	if url, err := p.endpointURL(ctx, m); err != nil || url != "" {
+		t.Errorf("endpointURL() with no endpoint = %q, %v", url, err)
+	}
+}
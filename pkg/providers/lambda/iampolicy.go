@@ -0,0 +1,161 @@
+package lambda
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	awsprovider "github.com/jvreagan/cloud-deploy/pkg/providers/aws"
+)
+
+// iamStatement is a statement of an IAM policy document.
+type iamStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// IAMPolicy returns the IAM policy the deployer needs for the features used in m,
+// scoped to the manifest's function, environment, and region. API Gateway
+// names APIs by generated ID, so HTTP API actions are granted on all APIs in
+// the region.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	region := m.Provider.Region
+	if region == "" {
+		region = "*"
+	}
+	app := m.Application.Name
+	if app == "" {
+		return nil, fmt.Errorf("application.name is required to scope the policy")
+	}
+	function := fmt.Sprintf("arn:aws:lambda:%s:*:function:%s", region, m.Environment.Name)
+
+	role := "arn:aws:iam::*:role/" + roleName
+	if m.Lambda != nil && m.Lambda.RoleARN != "" {
+		role = m.Lambda.RoleARN
+	}
+
+	statements := []iamStatement{
+		{
+			Sid:    "Function",
+			Effect: "Allow",
+			Action: []string{
+				"lambda:GetFunction",
+				"lambda:GetFunctionConfiguration",
+				"lambda:CreateFunction",
+				"lambda:UpdateFunctionCode",
+				"lambda:UpdateFunctionConfiguration",
+				"lambda:DeleteFunction",
+				"lambda:PutFunctionConcurrency",
+				"lambda:DeleteFunctionConcurrency",
+				"lambda:PublishVersion",
+				"lambda:ListVersionsByFunction",
+				"lambda:GetAlias",
+				"lambda:CreateAlias",
+				"lambda:UpdateAlias",
+				"lambda:AddPermission",
+				"lambda:TagResource",
+			},
+			Resource: []string{function, function + ":*"},
+		},
+		{
+			Sid:    "ImagePush",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:CreateRepository",
+				"ecr:DescribeRepositories",
+				"ecr:BatchCheckLayerAvailability",
+				"ecr:BatchGetImage",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:InitiateLayerUpload",
+				"ecr:UploadLayerPart",
+				"ecr:CompleteLayerUpload",
+				"ecr:PutImage",
+				// Lambda adds a statement to the repository policy to pull the image
+				"ecr:GetRepositoryPolicy",
+				"ecr:SetRepositoryPolicy",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:ecr:%s:*:repository/%s", region, m.RepositoryName())},
+		},
+		{
+			Sid:    "RegistryLogin",
+			Effect: "Allow",
+			Action: []string{
+				"ecr:GetAuthorizationToken",
+				"sts:GetCallerIdentity",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "Logs",
+			Effect: "Allow",
+			Action: []string{
+				"logs:CreateLogGroup",
+				"logs:PutRetentionPolicy",
+				"logs:TagResource",
+				"logs:FilterLogEvents",
+				"logs:DeleteLogGroup",
+			},
+			Resource: []string{fmt.Sprintf("arn:aws:logs:%s:*:log-group:%s*", region, logGroupName(m))},
+		},
+		{
+			Sid:      "PassFunctionRole",
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: []string{role},
+		},
+	}
+
+	if m.Lambda == nil || m.Lambda.RoleARN == "" {
+		statements = append(statements, iamStatement{
+			Sid:    "FunctionRole",
+			Effect: "Allow",
+			Action: []string{
+				"iam:GetRole",
+				"iam:CreateRole",
+				"iam:AttachRolePolicy",
+			},
+			Resource: []string{role},
+		})
+	}
+
+	// Deploy removes the kind of endpoint the manifest does not configure, so both are granted
+	statements = append(statements,
+		iamStatement{
+			Sid:    "FunctionURL",
+			Effect: "Allow",
+			Action: []string{
+				"lambda:GetFunctionUrlConfig",
+				"lambda:CreateFunctionUrlConfig",
+				"lambda:UpdateFunctionUrlConfig",
+				"lambda:DeleteFunctionUrlConfig",
+			},
+			Resource: []string{function + ":" + aliasName},
+		},
+		iamStatement{
+			Sid:    "HTTPAPI",
+			Effect: "Allow",
+			Action: []string{
+				"apigateway:GET",
+				"apigateway:POST",
+				"apigateway:DELETE",
+			},
+			Resource: []string{
+				fmt.Sprintf("arn:aws:apigateway:%s::/apis", region),
+				fmt.Sprintf("arn:aws:apigateway:%s::/apis/*", region),
+			},
+		},
+	)
+
+	if resources := awsprovider.SecretResources(m, region); len(resources) > 0 {
+		statements = append(statements, iamStatement{
+			Sid:      "ReadSecrets",
+			Effect:   "Allow",
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Resource: resources,
+		})
+	}
+
+	return json.MarshalIndent(map[string]interface{}{"Version": "2012-10-17", "Statement": statements}, "", "  ")
+}
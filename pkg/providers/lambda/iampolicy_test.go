@@ -0,0 +1,89 @@
+package lambda
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func policyStatements(t *testing.T, m *manifest.Manifest) map[string]iamStatement {
+	t.Helper()
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc struct {
+		Version   string
+		Statement []iamStatement
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	if doc.Version != "2012-10-17" {
+		t.Errorf("Unexpected policy version %q", doc.Version)
+	}
+	statements := make(map[string]iamStatement)
+	for _, s := range doc.Statement {
+		statements[s.Sid] = s
+	}
+	return statements
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIAMPolicy(t *testing.T) {
+	m := testManifest()
+	statements := policyStatements(t, m)
+
+	for _, sid := range []string{"Function", "FunctionURL", "HTTPAPI", "Logs", "ImagePush", "RegistryLogin", "PassFunctionRole", "FunctionRole"} {
+		if _, ok := statements[sid]; !ok {
+			t.Errorf("Expected statement %s", sid)
+		}
+	}
+	if _, ok := statements["ReadSecrets"]; ok {
+		t.Error("Unexpected ReadSecrets statement without secrets")
+	}
+
+	if !containsString(statements["Function"].Resource, "arn:aws:lambda:us-east-1:*:function:my-env:*") {
+		t.Errorf("Expected the function and its versions, got %v", statements["Function"].Resource)
+	}
+	if !containsString(statements["FunctionURL"].Resource, "arn:aws:lambda:us-east-1:*:function:my-env:live") {
+		t.Errorf("Expected the function URL scoped to the alias, got %v", statements["FunctionURL"].Resource)
+	}
+	if !containsString(statements["Logs"].Resource, "arn:aws:logs:us-east-1:*:log-group:/aws/lambda/my-env*") {
+		t.Errorf("Expected logs scoped to the log group, got %v", statements["Logs"].Resource)
+	}
+	if !containsString(statements["ImagePush"].Action, "ecr:SetRepositoryPolicy") {
+		t.Errorf("Expected Lambda to be allowed to pull from the repository, got %v", statements["ImagePush"].Action)
+	}
+	if !containsString(statements["PassFunctionRole"].Resource, "arn:aws:iam::*:role/cloud-deploy-lambda") {
+		t.Errorf("Expected the default role to be passable, got %v", statements["PassFunctionRole"].Resource)
+	}
+}
+
+func TestIAMPolicyConfiguredRole(t *testing.T) {
+	m := testManifest()
+	m.Lambda.RoleARN = "arn:aws:iam::123456789012:role/app"
+	statements := policyStatements(t, m)
+
+	if _, ok := statements["FunctionRole"]; ok {
+		t.Error("Unexpected FunctionRole statement with a configured role")
+	}
+	if roles := statements["PassFunctionRole"].Resource; len(roles) != 1 || roles[0] != m.Lambda.RoleARN {
+		t.Errorf("Expected the configured role to be passable, got %v", roles)
+	}
+}
+
+func TestIAMPolicyRequiresApplication(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{}); err == nil {
+		t.Error("Expected an error without application.name")
+	}
+}
@@ -0,0 +1,704 @@
+// Package lambda provides an AWS Lambda provider that runs the manifest's
+// image as a container image function. Images are pushed to ECR, every
+// deployment publishes a new version of the function, and the "live" alias,
+// which the function URL or API Gateway HTTP API invokes, points at the
+// version serving requests.
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/jvreagan/cloud-deploy/pkg/awsapi"
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	awsprovider "github.com/jvreagan/cloud-deploy/pkg/providers/aws"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// API paths, versions, and JSON target prefixes of the services the provider calls.
+const (
+	functionsPath    = "/2015-03-31/functions/"
+	urlConfigPath    = "/2021-10-31/functions/"
+	concurrencyPath  = "/2017-10-31/functions/"
+	apisPath         = "/v2/apis"
+	logsTargetPrefix = "Logs_20140328."
+	iamAPIVersion    = "2010-05-08"
+)
+
+// iamEndpoint is the global IAM endpoint; IAM requests are signed for us-east-1.
+const iamEndpoint = "https://iam.amazonaws.com"
+
+// aliasName is the alias the HTTP endpoint invokes. Deploy and Rollback
+// point it at the version that serves requests.
+const aliasName = "live"
+
+// The role functions run as when lambda.role_arn is not set, allowed to
+// write logs and nothing else.
+const (
+	roleName   = "cloud-deploy-lambda"
+	rolePolicy = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+)
+
+// pollInterval is how often a function update is checked while waiting for it.
+var pollInterval = 2 * time.Second
+
+// updateTimeout is how long Deploy waits for the function to be created or
+// updated.
+var updateTimeout = 10 * time.Minute
+
+// roleTimeout is how long Deploy retries creating the function while a new
+// role propagates through IAM.
+var roleTimeout = 2 * time.Minute
+
+// Provider implements the provider.Provider interface for AWS Lambda.
+type Provider struct {
+	region       string
+	config       aws.Config
+	lambdaClient *awsapi.Client
+	apiClient    *awsapi.Client
+	logsClient   *awsapi.Client
+	iamClient    *awsapi.Client
+}
+
+// New creates a new AWS Lambda provider instance. Credentials are loaded as
+// they are for the aws provider.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	cfg, err := awsprovider.LoadConfig(ctx, config.Region, config.Credentials, m)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(cfg), nil
+}
+
+// newProvider creates the service clients for cfg.
+func newProvider(cfg aws.Config) *Provider {
+	iamConfig := cfg.Copy()
+	iamConfig.Region = "us-east-1"
+	iamClient := awsapi.New(iamConfig, "iam")
+	if cfg.BaseEndpoint == nil {
+		// Emulators serve IAM at the same endpoint as every other service
+		iamClient.SetEndpoint(iamEndpoint)
+	}
+
+	return &Provider{
+		region:       cfg.Region,
+		config:       cfg,
+		lambdaClient: awsapi.New(cfg, "lambda"),
+		apiClient:    awsapi.New(cfg, "apigateway"),
+		logsClient:   awsapi.New(cfg, "logs"),
+		iamClient:    iamClient,
+	}
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "aws-lambda"
+}
+
+// Close closes the idle connections of the provider's clients.
+func (p *Provider) Close() error {
+	for _, c := range []*awsapi.Client{p.lambdaClient, p.apiClient, p.logsClient, p.iamClient} {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// functionConfiguration is the configuration of a function or of one of its
+// published versions.
+type functionConfiguration struct {
+	FunctionName           string
+	FunctionArn            string
+	Version                string
+	Description            string
+	State                  string
+	StateReason            string
+	LastUpdateStatus       string
+	LastUpdateStatusReason string
+	LastModified           string
+}
+
+// function is a function as returned by GetFunction.
+type function struct {
+	Configuration functionConfiguration
+	Code          struct {
+		ImageUri string
+	}
+	Concurrency *struct {
+		ReservedConcurrentExecutions *int
+	}
+}
+
+// reservedConcurrency returns the function's reserved concurrency, or nil
+// when it is unreserved.
+func (f *function) reservedConcurrency() *int {
+	if f.Concurrency == nil {
+		return nil
+	}
+	return f.Concurrency.ReservedConcurrentExecutions
+}
+
+// alias is a function alias.
+type alias struct {
+	AliasArn        string
+	Name            string
+	FunctionVersion string
+}
+
+// Deploy pushes the manifest's image to ECR and runs it as a Lambda function,
+// creating the function, its log group, and its HTTP endpoint the first time.
+//
+// Each deployment publishes a new version of the function, with the image
+// pinned by digest, and points the live alias at it; Rollback points the
+// alias back at the previous version.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := validateImagePlatform(ctx, m); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Starting AWS Lambda deployment")
+
+	// Step 1: Push the image to ECR
+	image, err := p.pushImage(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return p.deployImage(ctx, m, image)
+}
+
+// deployImage runs image, an ECR image, as the environment's function.
+func (p *Provider) deployImage(ctx context.Context, m *manifest.Manifest, image string) (*types.DeploymentResult, error) {
+	name := m.Environment.Name
+
+	// Step 2: Create the log group and the role the function runs as
+	if err := p.ensureLogGroup(ctx, m); err != nil {
+		return nil, err
+	}
+	role, err := p.ensureRole(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Create or update the function
+	fn, err := p.getFunction(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	config := functionConfig(m, role)
+	if fn == nil {
+		logging.Infof("Creating function: %s", name)
+		if err := p.createFunction(ctx, m, image, config); err != nil {
+			return nil, err
+		}
+	} else {
+		logging.Infof("Updating function: %s", name)
+		code := map[string]interface{}{"ImageUri": image, "Architectures": []string{architecture(m.Lambda)}}
+		if err := p.lambdaClient.REST(ctx, http.MethodPut, functionsPath+name+"/code", code, nil); err != nil {
+			return nil, fmt.Errorf("failed to update function code: %w", err)
+		}
+		if err := p.waitForUpdate(ctx, name); err != nil {
+			return nil, err
+		}
+		if err := p.lambdaClient.REST(ctx, http.MethodPut, functionsPath+name+"/configuration", config, nil); err != nil {
+			return nil, fmt.Errorf("failed to update function configuration: %w", err)
+		}
+	}
+	logging.Info("Waiting for the function to be ready...")
+	if err := p.waitForUpdate(ctx, name); err != nil {
+		return nil, err
+	}
+
+	// Step 4: Reserve concurrency, which also starts a stopped function again
+	if err := p.applyConcurrency(ctx, m, fn); err != nil {
+		return nil, err
+	}
+
+	// Step 5: Publish a version and point the alias at it
+	version, err := p.publishVersion(ctx, name, image)
+	if err != nil {
+		return nil, err
+	}
+	a, err := p.setAlias(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 6: Expose the alias over HTTP
+	url, err := p.ensureEndpoint(ctx, m, a.AliasArn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             url,
+		Status:          "Running",
+		Message:         fmt.Sprintf("Deployment successful (version %s)", version),
+		Images:          map[string]string{m.GetPrimaryContainer().Name: image},
+	}, nil
+}
+
+// Destroy deletes the function with its versions, alias, and function URL,
+// its API Gateway HTTP API, and its log group. The shared cloud-deploy-lambda
+// role is kept.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	if err := p.deleteAPI(ctx, name); err != nil {
+		return err
+	}
+
+	err := p.lambdaClient.REST(ctx, http.MethodDelete, functionsPath+name, nil, nil)
+	switch {
+	case err == nil:
+		logging.Infof("Deleted function: %s", name)
+	case !awsapi.IsErrorCode(err, "ResourceNotFoundException"):
+		return fmt.Errorf("failed to delete function: %w", err)
+	}
+
+	if err := p.deleteLogGroup(ctx, m); err != nil {
+		return err
+	}
+	logging.Info("Lambda resources deleted successfully")
+	return nil
+}
+
+// Stop reserves zero concurrency for the function, which rejects every
+// invocation. The function, its versions, and its endpoint are preserved;
+// running Deploy again lifts the limit.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	name := m.Environment.Name
+	logging.Infof("Setting reserved concurrency of function %s to 0", name)
+	input := map[string]int{"ReservedConcurrentExecutions": 0}
+	if err := p.lambdaClient.REST(ctx, http.MethodPut, concurrencyPath+name+"/concurrency", input, nil); err != nil {
+		if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+			return fmt.Errorf("no Lambda function found for environment %s", name)
+		}
+		return fmt.Errorf("failed to stop function: %w", err)
+	}
+	logging.Info("Function stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the function.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	name := m.Environment.Name
+	fn, err := p.getFunction(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("no Lambda function found for environment %s", name)
+	}
+
+	url, err := p.endpointURL(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	status, health := functionStatus(fn)
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		Status:          status,
+		Health:          health,
+		URL:             url,
+		LastUpdated:     lastModified(fn.Configuration.LastModified),
+	}, nil
+}
+
+// functionStatus returns the status and health of a function.
+func functionStatus(fn *function) (string, string) {
+	c := fn.Configuration
+	switch {
+	case c.State == "Failed" || c.LastUpdateStatus == "Failed":
+		return "Failed", "Red"
+	case c.State == "Inactive":
+		// Functions that have not been invoked for weeks are reactivated by the next invocation
+		return "Inactive", "Grey"
+	case c.State == "Pending" || c.LastUpdateStatus == "InProgress":
+		return "Updating", "Yellow"
+	case fn.reservedConcurrency() != nil && *fn.reservedConcurrency() == 0:
+		return "Stopped", "Grey"
+	default:
+		return "Running", "Green"
+	}
+}
+
+// lastModified converts Lambda's LastModified timestamp (e.g.,
+// "2024-01-02T03:04:05.000+0000") to RFC 3339.
+func lastModified(s string) string {
+	t, err := time.Parse("2006-01-02T15:04:05.000-0700", s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Rollback points the live alias at the version published before the one it
+// points at. Versions keep the image they were published with, so no image
+// is pushed.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting AWS Lambda rollback...")
+	name := m.Environment.Name
+
+	current, err := p.getAlias(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no Lambda function with a %s alias found for environment %s", aliasName, name)
+	}
+	versions, err := p.versions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := previousVersion(versions, current.FunctionVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("Rolling back %s from version %s to version %s", name, current.FunctionVersion, previous)
+	if _, err := p.setAlias(ctx, name, previous); err != nil {
+		return nil, err
+	}
+
+	url, err := p.endpointURL(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: name,
+		URL:             url,
+		Status:          "Running",
+		Message:         fmt.Sprintf("Rolled back to version %s", previous),
+	}, nil
+}
+
+// getFunction returns the named function, or nil when it does not exist.
+func (p *Provider) getFunction(ctx context.Context, name string) (*function, error) {
+	var fn function
+	if err := p.lambdaClient.REST(ctx, http.MethodGet, functionsPath+name, nil, &fn); err != nil {
+		if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get function %s: %w", name, err)
+	}
+	return &fn, nil
+}
+
+// createFunction creates the function running image. A role created moments
+// ago may not be assumable by Lambda yet, so creation is retried until IAM
+// has propagated it.
+func (p *Provider) createFunction(ctx context.Context, m *manifest.Manifest, image string, config map[string]interface{}) error {
+	input := map[string]interface{}{
+		"FunctionName":  m.Environment.Name,
+		"PackageType":   "Image",
+		"Code":          map[string]string{"ImageUri": image},
+		"Architectures": []string{architecture(m.Lambda)},
+	}
+	for k, v := range config {
+		input[k] = v
+	}
+	if len(m.Tags) > 0 {
+		input["Tags"] = m.Tags
+	}
+
+	deadline := time.Now().Add(roleTimeout)
+	for {
+		err := p.lambdaClient.REST(ctx, http.MethodPost, strings.TrimSuffix(functionsPath, "/"), input, nil)
+		if err == nil {
+			return nil
+		}
+		if !awsapi.IsErrorCode(err, "InvalidParameterValueException") || !strings.Contains(err.Error(), "cannot be assumed") || time.Now().After(deadline) {
+			return fmt.Errorf("failed to create function: %w", err)
+		}
+		logging.Info("Waiting for the function's role to propagate...")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// waitForUpdate polls the function until it is active and its last update
+// finished, which Lambda requires before the next update.
+func (p *Provider) waitForUpdate(ctx context.Context, name string) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(updateTimeout)
+
+	for {
+		var c functionConfiguration
+		if err := p.lambdaClient.REST(ctx, http.MethodGet, functionsPath+name+"/configuration", nil, &c); err != nil {
+			return fmt.Errorf("failed to get function %s: %w", name, err)
+		}
+		switch {
+		case c.State == "Failed":
+			return fmt.Errorf("function %s failed: %s", name, c.StateReason)
+		case c.LastUpdateStatus == "Failed":
+			return fmt.Errorf("update of function %s failed: %s", name, c.LastUpdateStatusReason)
+		case c.State != "Pending" && c.LastUpdateStatus != "InProgress":
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for function %s to be updated", name)
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyConcurrency reserves lambda.reserved_concurrency for the function, or
+// removes a reservation it no longer has, including the one Stop sets.
+func (p *Provider) applyConcurrency(ctx context.Context, m *manifest.Manifest, existing *function) error {
+	name := m.Environment.Name
+	if m.Lambda != nil && m.Lambda.ReservedConcurrency != nil {
+		input := map[string]int{"ReservedConcurrentExecutions": *m.Lambda.ReservedConcurrency}
+		if err := p.lambdaClient.REST(ctx, http.MethodPut, concurrencyPath+name+"/concurrency", input, nil); err != nil {
+			return fmt.Errorf("failed to reserve concurrency: %w", err)
+		}
+		return nil
+	}
+	if existing != nil && existing.reservedConcurrency() != nil {
+		logging.Info("Removing the function's reserved concurrency")
+		if err := p.lambdaClient.REST(ctx, http.MethodDelete, concurrencyPath+name+"/concurrency", nil, nil); err != nil {
+			return fmt.Errorf("failed to remove reserved concurrency: %w", err)
+		}
+	}
+	return nil
+}
+
+// publishVersion publishes the function's current code and configuration as
+// a new version and returns its number.
+func (p *Provider) publishVersion(ctx context.Context, name, image string) (string, error) {
+	var out functionConfiguration
+	input := map[string]string{"Description": "cloud-deploy: " + image}
+	if err := p.lambdaClient.REST(ctx, http.MethodPost, functionsPath+name+"/versions", input, &out); err != nil {
+		return "", fmt.Errorf("failed to publish version: %w", err)
+	}
+	logging.Infof("Published version %s of function %s", out.Version, name)
+	return out.Version, nil
+}
+
+// getAlias returns the function's live alias, or nil when it does not exist.
+func (p *Provider) getAlias(ctx context.Context, name string) (*alias, error) {
+	var a alias
+	if err := p.lambdaClient.REST(ctx, http.MethodGet, functionsPath+name+"/aliases/"+aliasName, nil, &a); err != nil {
+		if awsapi.IsErrorCode(err, "ResourceNotFoundException") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get alias %s of function %s: %w", aliasName, name, err)
+	}
+	return &a, nil
+}
+
+// setAlias points the live alias at version, creating the alias if needed.
+func (p *Provider) setAlias(ctx context.Context, name, version string) (*alias, error) {
+	existing, err := p.getAlias(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var a alias
+	if existing == nil {
+		input := map[string]string{"Name": aliasName, "FunctionVersion": version}
+		if err := p.lambdaClient.REST(ctx, http.MethodPost, functionsPath+name+"/aliases", input, &a); err != nil {
+			return nil, fmt.Errorf("failed to create alias %s: %w", aliasName, err)
+		}
+	} else {
+		input := map[string]string{"FunctionVersion": version}
+		if err := p.lambdaClient.REST(ctx, http.MethodPut, functionsPath+name+"/aliases/"+aliasName, input, &a); err != nil {
+			return nil, fmt.Errorf("failed to update alias %s: %w", aliasName, err)
+		}
+	}
+	logging.Infof("Alias %s now points at version %s", aliasName, version)
+	return &a, nil
+}
+
+// versions returns the published versions of a function.
+func (p *Provider) versions(ctx context.Context, name string) ([]string, error) {
+	var versions []string
+	marker := ""
+	for {
+		path := functionsPath + name + "/versions"
+		if marker != "" {
+			path += "?Marker=" + url.QueryEscape(marker)
+		}
+		var out struct {
+			Versions   []functionConfiguration
+			NextMarker string
+		}
+		if err := p.lambdaClient.REST(ctx, http.MethodGet, path, nil, &out); err != nil {
+			return nil, fmt.Errorf("failed to list versions of function %s: %w", name, err)
+		}
+		for _, v := range out.Versions {
+			versions = append(versions, v.Version)
+		}
+		if out.NextMarker == "" {
+			return versions, nil
+		}
+		marker = out.NextMarker
+	}
+}
+
+// previousVersion returns the highest published version of versions below
+// current. $LATEST, the unpublished version, is ignored.
+func previousVersion(versions []string, current string) (string, error) {
+	currentNumber, err := strconv.Atoi(current)
+	if err != nil {
+		return "", fmt.Errorf("alias %s points at version %s, which cannot be rolled back", aliasName, current)
+	}
+	previous := 0
+	for _, v := range versions {
+		n, err := strconv.Atoi(v)
+		if err == nil && n < currentNumber && n > previous {
+			previous = n
+		}
+	}
+	if previous == 0 {
+		return "", fmt.Errorf("no version published before version %s to roll back to", current)
+	}
+	return strconv.Itoa(previous), nil
+}
+
+// pushImage pushes the manifest's image to the application's ECR repository,
+// returning the pushed image pinned by digest. Lambda only runs images from
+// ECR repositories in the function's region.
+func (p *Provider) pushImage(ctx context.Context, m *manifest.Manifest) (string, error) {
+	logging.Info("Pushing image to ECR", "image", m.Image)
+	ecrRegistry, err := registry.NewECRRegistry(p.config, p.region, m.RepositoryName(), m.ImageTag("latest", ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to create ECR registry: %w", err)
+	}
+	distributor := registry.NewDistributor(m.Image, m.Platforms...)
+	dest := registry.Local(ecrRegistry, m.Provider.RegistryURL, m.RepositoryName(), m.ImageTag("latest", ""))
+	distributor.AddRegistry(dest)
+
+	imageURIs, err := distributor.Distribute(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to distribute image: %w", err)
+	}
+	image := registry.PinDigest(imageURIs[dest.GetRegistryURL()], distributor.Digest())
+	logging.Info("Image pushed to ECR", "image_uri", image)
+	return image, nil
+}
+
+// ensureRole returns the ARN of the role the function runs as, creating
+// cloud-deploy-lambda when no role is configured and it does not exist yet.
+func (p *Provider) ensureRole(ctx context.Context, m *manifest.Manifest) (string, error) {
+	if m.Lambda != nil && m.Lambda.RoleARN != "" {
+		return m.Lambda.RoleARN, nil
+	}
+
+	var role struct {
+		ARN string `xml:"Role>Arn"`
+	}
+	err := p.iamClient.Query(ctx, "GetRole", iamAPIVersion, url.Values{"RoleName": {roleName}}, &role)
+	if err == nil {
+		return role.ARN, nil
+	}
+	if !awsapi.IsErrorCode(err, "NoSuchEntity") {
+		return "", fmt.Errorf("failed to get role %s: %w", roleName, err)
+	}
+
+	logging.Infof("Creating function role: %s", roleName)
+	trust := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+	params := url.Values{"RoleName": {roleName}, "AssumeRolePolicyDocument": {trust}}
+	if err := p.iamClient.Query(ctx, "CreateRole", iamAPIVersion, params, &role); err != nil {
+		return "", fmt.Errorf("failed to create role %s: %w", roleName, err)
+	}
+	params = url.Values{"RoleName": {roleName}, "PolicyArn": {rolePolicy}}
+	if err := p.iamClient.Query(ctx, "AttachRolePolicy", iamAPIVersion, params, nil); err != nil {
+		return "", fmt.Errorf("failed to attach policy to role %s: %w", roleName, err)
+	}
+	return role.ARN, nil
+}
+
+// functionConfig returns the configuration of the function that
+// CreateFunction and UpdateFunctionConfiguration accept: its role, size,
+// environment variables, and startup overrides.
+func functionConfig(m *manifest.Manifest, role string) map[string]interface{} {
+	memory, timeout := m.Lambda.FunctionSize()
+	config := map[string]interface{}{
+		"Role":        role,
+		"MemorySize":  memory,
+		"Timeout":     timeout,
+		"Description": fmt.Sprintf("%s (%s), deployed by cloud-deploy", m.Application.Name, m.Environment.Name),
+		"Environment": map[string]interface{}{"Variables": environment(m)},
+		"LoggingConfig": map[string]string{
+			"LogFormat": "Text",
+			"LogGroup":  logGroupName(m),
+		},
+	}
+
+	// Lambda names the image's ENTRYPOINT EntryPoint and its CMD Command; an
+	// empty image config resets overrides removed from the manifest
+	c := m.GetPrimaryContainer()
+	imageConfig := map[string]interface{}{}
+	if len(c.Command) > 0 {
+		imageConfig["EntryPoint"] = c.Command
+	}
+	if len(c.Args) > 0 {
+		imageConfig["Command"] = c.Args
+	}
+	if c.Workdir != "" {
+		imageConfig["WorkingDirectory"] = c.Workdir
+	}
+	config["ImageConfig"] = imageConfig
+	return config
+}
+
+// environment returns the function's environment variables. Unlike the
+// other providers, the map is never nil, so variables removed from the
+// manifest are removed from the function.
+func environment(m *manifest.Manifest) map[string]string {
+	env := make(map[string]string, len(m.EnvironmentVariables))
+	for k, v := range m.EnvironmentVariables {
+		env[k] = v
+	}
+	return env
+}
+
+// architecture returns the function's instruction set.
+func architecture(c *manifest.LambdaConfig) string {
+	if c != nil && c.Architecture != "" {
+		return c.Architecture
+	}
+	return manifest.LambdaArchitectureX86
+}
+
+// accountID returns the account ID in an ARN.
+func accountID(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[4]
+}
+
+// validateImagePlatform fails early when the image cannot run on the
+// function's instruction set.
+func validateImagePlatform(ctx context.Context, m *manifest.Manifest) error {
+	target := registry.LinuxAMD64
+	hint := "or set lambda.architecture to arm64 for arm64 images"
+	if architecture(m.Lambda) == manifest.LambdaArchitectureARM {
+		target = registry.Platform{OS: "linux", Architecture: "arm64"}
+		hint = "or set lambda.architecture to x86_64 for amd64 images"
+	}
+	return registry.ValidatePlatform(ctx, m.Image, target, "Lambda "+architecture(m.Lambda), hint)
+}
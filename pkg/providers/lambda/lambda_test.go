@@ -0,0 +1,643 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+const functionARN = "arn:aws:lambda:us-east-1:123456789012:function:my-env"
+
+// fakeAWS serves the subset of the Lambda, API Gateway, IAM, and CloudWatch
+// Logs APIs the provider uses. Function updates finish as soon as the
+// function is read again.
+type fakeAWS struct {
+	mu          sync.Mutex
+	calls       []string
+	requests    map[string]interface{} // operation -> last JSON body or form
+	function    map[string]interface{}
+	updating    bool
+	updateFail  bool
+	versions    []string
+	alias       string
+	concurrency *int
+	urlAuth     string
+	api         bool
+	permissions []string
+	role        bool
+	roleDelays  int // CreateFunction calls to reject while the role propagates
+	logGroup    bool
+	events      []logEvent
+}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{requests: make(map[string]interface{})}
+}
+
+// restError is a REST-JSON error response.
+type restError struct {
+	status  int
+	code    string
+	message string
+}
+
+func notFound(what string) *restError {
+	return &restError{http.StatusNotFound, "ResourceNotFoundException", what + " not found"}
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		action := target[strings.Index(target, ".")+1:]
+		var in map[string]interface{}
+		json.Unmarshal(body, &in)
+		f.calls = append(f.calls, action)
+		f.requests[action] = in
+		out, code := f.handleLogs(action)
+		if code != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": code})
+			return
+		}
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+
+	if r.Method == http.MethodPost && r.Header.Get("Content-Type") == "application/x-www-form-urlencoded; charset=utf-8" {
+		form, _ := url.ParseQuery(string(body))
+		action := form.Get("Action")
+		f.calls = append(f.calls, action)
+		f.requests[action] = form
+		out, code := f.handleIAM(action)
+		if code != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<ErrorResponse><Error><Code>%s</Code></Error></ErrorResponse>", code)
+			return
+		}
+		fmt.Fprintf(w, "<%sResponse><%sResult>%s</%sResult></%sResponse>", action, action, out, action, action)
+		return
+	}
+
+	var in map[string]interface{}
+	json.Unmarshal(body, &in)
+	op, out, rerr := f.handleREST(r.Method, r.URL.Path, r.URL.Query(), in)
+	f.calls = append(f.calls, op)
+	f.requests[op] = in
+	if rerr != nil {
+		w.Header().Set("X-Amzn-ErrorType", rerr.code)
+		w.WriteHeader(rerr.status)
+		json.NewEncoder(w).Encode(map[string]string{"Type": "User", "message": rerr.message})
+		return
+	}
+	if out == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// configuration returns the function's configuration, finishing any update.
+func (f *fakeAWS) configuration() map[string]interface{} {
+	c := map[string]interface{}{
+		"FunctionName": "my-env", "FunctionArn": functionARN, "Version": "$LATEST",
+		"State": "Active", "LastUpdateStatus": "Successful", "LastModified": "2024-01-02T03:04:05.000+0000",
+	}
+	if f.updating {
+		c["LastUpdateStatus"] = "InProgress"
+		f.updating = false
+	} else if f.updateFail {
+		c["LastUpdateStatus"], c["LastUpdateStatusReason"] = "Failed", "image is not a Lambda image"
+	}
+	return c
+}
+
+func (f *fakeAWS) handleREST(method, path string, query url.Values, in map[string]interface{}) (string, interface{}, *restError) {
+	if strings.HasPrefix(path, apisPath) {
+		switch {
+		case method == http.MethodGet:
+			items := []map[string]string{}
+			if f.api {
+				items = append(items, map[string]string{"apiId": "api1", "name": "other"}, map[string]string{"apiId": "abc123", "name": "my-env", "apiEndpoint": "https://abc123.execute-api.us-east-1.amazonaws.com"})
+			}
+			return "GetApis", map[string]interface{}{"items": items}, nil
+		case method == http.MethodPost:
+			f.api = true
+			return "CreateApi", map[string]string{"apiId": "abc123", "name": "my-env", "apiEndpoint": "https://abc123.execute-api.us-east-1.amazonaws.com"}, nil
+		case method == http.MethodDelete && path == apisPath+"/abc123":
+			f.api = false
+			return "DeleteApi", nil, nil
+		}
+		return "UnknownApiOperation", nil, notFound(path)
+	}
+
+	if path == strings.TrimSuffix(functionsPath, "/") && method == http.MethodPost {
+		if f.roleDelays > 0 {
+			f.roleDelays--
+			return "CreateFunction", nil, &restError{http.StatusBadRequest, "InvalidParameterValueException", "The role defined for the function cannot be assumed by Lambda."}
+		}
+		f.function = in
+		c := f.configuration()
+		f.updating = true
+		return "CreateFunction", c, nil
+	}
+
+	var prefix string
+	for _, p := range []string{functionsPath, urlConfigPath, concurrencyPath} {
+		if strings.HasPrefix(path, p+"my-env") {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return "UnknownFunction", nil, notFound(path)
+	}
+	if f.function == nil {
+		return method + " " + strings.TrimPrefix(path, prefix), nil, notFound("Function")
+	}
+	sub := strings.TrimPrefix(path, prefix+"my-env")
+
+	switch {
+	case prefix == concurrencyPath && sub == "/concurrency":
+		if method == http.MethodDelete {
+			f.concurrency = nil
+			return "DeleteFunctionConcurrency", nil, nil
+		}
+		n := int(in["ReservedConcurrentExecutions"].(float64))
+		f.concurrency = &n
+		return "PutFunctionConcurrency", in, nil
+
+	case prefix == urlConfigPath && sub == "/url":
+		if query.Get("Qualifier") != aliasName {
+			return "FunctionUrl", nil, &restError{http.StatusBadRequest, "InvalidParameterValueException", "unexpected qualifier"}
+		}
+		switch method {
+		case http.MethodGet:
+			if f.urlAuth == "" {
+				return "GetFunctionUrlConfig", nil, notFound("Function URL")
+			}
+		case http.MethodPost, http.MethodPut:
+			f.urlAuth = in["AuthType"].(string)
+		case http.MethodDelete:
+			if f.urlAuth == "" {
+				return "DeleteFunctionUrlConfig", nil, notFound("Function URL")
+			}
+			f.urlAuth = ""
+			return "DeleteFunctionUrlConfig", nil, nil
+		}
+		op := map[string]string{http.MethodGet: "GetFunctionUrlConfig", http.MethodPost: "CreateFunctionUrlConfig", http.MethodPut: "UpdateFunctionUrlConfig"}[method]
+		return op, map[string]string{"FunctionUrl": "https://abc.lambda-url.us-east-1.on.aws/", "AuthType": f.urlAuth}, nil
+	}
+
+	if prefix != functionsPath {
+		return "UnknownFunctionOperation", nil, notFound(path)
+	}
+	switch {
+	case sub == "" && method == http.MethodGet:
+		out := map[string]interface{}{"Configuration": f.configuration(), "Code": map[string]string{"ImageUri": testImage}}
+		if f.concurrency != nil {
+			out["Concurrency"] = map[string]int{"ReservedConcurrentExecutions": *f.concurrency}
+		}
+		return "GetFunction", out, nil
+	case sub == "" && method == http.MethodDelete:
+		f.function, f.versions, f.alias, f.urlAuth, f.permissions = nil, nil, "", "", nil
+		return "DeleteFunction", nil, nil
+	case sub == "/configuration" && method == http.MethodGet:
+		return "GetFunctionConfiguration", f.configuration(), nil
+	case sub == "/configuration" && method == http.MethodPut:
+		f.function["Config"] = in
+		c := f.configuration()
+		f.updating = true
+		return "UpdateFunctionConfiguration", c, nil
+	case sub == "/code" && method == http.MethodPut:
+		c := f.configuration()
+		f.updating = true
+		return "UpdateFunctionCode", c, nil
+	case sub == "/versions" && method == http.MethodPost:
+		f.versions = append(f.versions, strconv.Itoa(len(f.versions)+1))
+		c := f.configuration()
+		c["Version"] = f.versions[len(f.versions)-1]
+		return "PublishVersion", c, nil
+	case sub == "/versions" && method == http.MethodGet:
+		versions := []map[string]string{{"Version": "$LATEST"}}
+		for _, v := range f.versions {
+			versions = append(versions, map[string]string{"Version": v})
+		}
+		return "ListVersionsByFunction", map[string]interface{}{"Versions": versions}, nil
+	case sub == "/aliases/"+aliasName && method == http.MethodGet:
+		if f.alias == "" {
+			return "GetAlias", nil, notFound("Alias")
+		}
+		return "GetAlias", f.aliasResponse(), nil
+	case sub == "/aliases" && method == http.MethodPost:
+		f.alias = in["FunctionVersion"].(string)
+		return "CreateAlias", f.aliasResponse(), nil
+	case sub == "/aliases/"+aliasName && method == http.MethodPut:
+		f.alias = in["FunctionVersion"].(string)
+		return "UpdateAlias", f.aliasResponse(), nil
+	case sub == "/policy" && method == http.MethodPost:
+		sid := in["StatementId"].(string)
+		for _, p := range f.permissions {
+			if p == sid {
+				return "AddPermission", nil, &restError{http.StatusConflict, "ResourceConflictException", "statement exists"}
+			}
+		}
+		f.permissions = append(f.permissions, sid)
+		return "AddPermission", map[string]string{"Statement": "{}"}, nil
+	}
+	return "UnknownFunctionOperation", nil, notFound(path)
+}
+
+func (f *fakeAWS) aliasResponse() map[string]string {
+	return map[string]string{"AliasArn": functionARN + ":" + aliasName, "Name": aliasName, "FunctionVersion": f.alias}
+}
+
+func (f *fakeAWS) handleLogs(action string) (interface{}, string) {
+	switch action {
+	case "CreateLogGroup":
+		if f.logGroup {
+			return nil, "ResourceAlreadyExistsException"
+		}
+		f.logGroup = true
+		return map[string]interface{}{}, ""
+	case "PutRetentionPolicy":
+		return map[string]interface{}{}, ""
+	case "DeleteLogGroup":
+		if !f.logGroup {
+			return nil, "ResourceNotFoundException"
+		}
+		f.logGroup = false
+		return map[string]interface{}{}, ""
+	case "FilterLogEvents":
+		if !f.logGroup {
+			return nil, "ResourceNotFoundException"
+		}
+		return map[string]interface{}{"events": f.events}, ""
+	}
+	return nil, "UnknownOperationException"
+}
+
+func (f *fakeAWS) handleIAM(action string) (string, string) {
+	switch action {
+	case "GetRole":
+		if !f.role {
+			return "", "NoSuchEntity"
+		}
+		return "<Role><Arn>arn:aws:iam::123456789012:role/cloud-deploy-lambda</Arn></Role>", ""
+	case "CreateRole":
+		f.role = true
+		return "<Role><Arn>arn:aws:iam::123456789012:role/cloud-deploy-lambda</Arn></Role>", ""
+	case "AttachRolePolicy":
+		return "", ""
+	}
+	return "", "InvalidAction"
+}
+
+// called reports whether action was called.
+func (f *fakeAWS) called(action string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == action {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestProvider returns a provider sending every request to fake.
+func newTestProvider(t *testing.T, fake *fakeAWS) *Provider {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	oldPoll := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldPoll })
+
+	p := newProvider(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+	})
+	for _, c := range []interface{ SetEndpoint(string) }{p.lambdaClient, p.apiClient, p.logsClient, p.iamClient} {
+		c.SetEndpoint(server.URL)
+	}
+	return p
+}
+
+func testManifest() *manifest.Manifest {
+	reserved := 5
+	return &manifest.Manifest{
+		Image:                "my-app:latest",
+		Provider:             manifest.ProviderConfig{Name: "aws-lambda", Region: "us-east-1"},
+		Application:          manifest.ApplicationConfig{Name: "my-app"},
+		Environment:          manifest.EnvironmentConfig{Name: "my-env"},
+		EnvironmentVariables: map[string]string{"LOG_LEVEL": "info"},
+		Lambda:               &manifest.LambdaConfig{MemorySize: 1024, ReservedConcurrency: &reserved, LogRetentionDays: 14},
+	}
+}
+
+const testImage = "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app@sha256:abc"
+
+func TestDeployImage(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	result, err := p.deployImage(context.Background(), m, testImage)
+	if err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if result.URL != "https://abc.lambda-url.us-east-1.on.aws/" || result.Images["my-app"] != testImage || result.Message != "Deployment successful (version 1)" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	for _, action := range []string{"CreateLogGroup", "PutRetentionPolicy", "CreateRole", "AttachRolePolicy", "CreateFunction", "PutFunctionConcurrency", "PublishVersion", "CreateAlias", "CreateFunctionUrlConfig", "AddPermission"} {
+		if !fake.called(action) {
+			t.Errorf("expected %s to be called; calls: %v", action, fake.calls)
+		}
+	}
+	create := fake.requests["CreateFunction"].(map[string]interface{})
+	if create["PackageType"] != "Image" || create["MemorySize"] != 1024.0 || create["Timeout"] != 30.0 || create["Role"] != "arn:aws:iam::123456789012:role/cloud-deploy-lambda" {
+		t.Errorf("unexpected CreateFunction request: %v", create)
+	}
+	if code := create["Code"].(map[string]interface{}); code["ImageUri"] != testImage {
+		t.Errorf("unexpected code: %v", code)
+	}
+	if env := create["Environment"].(map[string]interface{})["Variables"].(map[string]interface{}); env["LOG_LEVEL"] != "info" {
+		t.Errorf("unexpected environment: %v", env)
+	}
+	if fmt.Sprint(create["Architectures"]) != "[x86_64]" {
+		t.Errorf("unexpected architectures: %v", create["Architectures"])
+	}
+	if fake.alias != "1" || fake.urlAuth != "NONE" || *fake.concurrency != 5 {
+		t.Errorf("unexpected state: alias %q, URL auth %q", fake.alias, fake.urlAuth)
+	}
+	if fmt.Sprint(fake.permissions) != "[FunctionURLAllowPublicAccess FunctionURLAllowInvokeAction]" {
+		t.Errorf("unexpected permissions: %v", fake.permissions)
+	}
+
+	// A second deployment updates the function and publishes a new version
+	fake.calls = nil
+	m.Lambda.ReservedConcurrency = nil
+	result, err = p.deployImage(context.Background(), m, testImage)
+	if err != nil {
+		t.Fatalf("second deployImage() failed: %v", err)
+	}
+	for _, action := range []string{"CreateRole", "CreateFunction", "CreateAlias", "CreateFunctionUrlConfig"} {
+		if fake.called(action) {
+			t.Errorf("expected %s not to be called again", action)
+		}
+	}
+	for _, action := range []string{"UpdateFunctionCode", "UpdateFunctionConfiguration", "DeleteFunctionConcurrency", "UpdateAlias"} {
+		if !fake.called(action) {
+			t.Errorf("expected %s to be called; calls: %v", action, fake.calls)
+		}
+	}
+	if fake.alias != "2" || result.Message != "Deployment successful (version 2)" {
+		t.Errorf("expected the alias to point at version 2, got %q", fake.alias)
+	}
+}
+
+func TestDeployImageWaitsForRole(t *testing.T) {
+	fake := newFakeAWS()
+	fake.roleDelays = 2
+	p := newTestProvider(t, fake)
+
+	if _, err := p.deployImage(context.Background(), testManifest(), testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if fake.function == nil {
+		t.Error("expected the function to be created once the role propagated")
+	}
+}
+
+func TestDeployImageUpdateFailed(t *testing.T) {
+	fake := newFakeAWS()
+	fake.updateFail = true
+	p := newTestProvider(t, fake)
+
+	_, err := p.deployImage(context.Background(), testManifest(), testImage)
+	if err == nil || !strings.Contains(err.Error(), "image is not a Lambda image") {
+		t.Errorf("expected update failure, got %v", err)
+	}
+}
+
+func TestDeployImageConfiguredRole(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	m.Lambda.RoleARN = "arn:aws:iam::123456789012:role/app"
+	m.Lambda.Architecture = manifest.LambdaArchitectureARM
+	m.Container = &manifest.ContainerStartup{Command: []string{"/bin/app"}, Args: []string{"serve"}, Workdir: "/srv"}
+
+	if _, err := p.deployImage(context.Background(), m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if fake.called("GetRole") || fake.called("CreateRole") {
+		t.Error("expected the configured role to be used")
+	}
+	create := fake.requests["CreateFunction"].(map[string]interface{})
+	if create["Role"] != m.Lambda.RoleARN || fmt.Sprint(create["Architectures"]) != "[arm64]" {
+		t.Errorf("unexpected CreateFunction request: %v", create)
+	}
+	imageConfig := create["ImageConfig"].(map[string]interface{})
+	if fmt.Sprint(imageConfig["EntryPoint"]) != "[/bin/app]" || fmt.Sprint(imageConfig["Command"]) != "[serve]" || imageConfig["WorkingDirectory"] != "/srv" {
+		t.Errorf("unexpected image config: %v", imageConfig)
+	}
+}
+
+func TestStopStatusAndDestroy(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.Status(ctx, m); err == nil {
+		t.Error("expected an error before the function exists")
+	}
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+
+	status, err := p.Status(ctx, m)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if status.Status != "Running" || status.Health != "Green" || status.URL != "https://abc.lambda-url.us-east-1.on.aws/" || status.LastUpdated != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	if err := p.Stop(ctx, m); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if status, _ := p.Status(ctx, m); status.Status != "Stopped" {
+		t.Errorf("expected Stopped, got %s", status.Status)
+	}
+
+	if err := p.Destroy(ctx, m); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if fake.function != nil || fake.logGroup {
+		t.Error("expected the function and log group to be deleted")
+	}
+	if err := p.Destroy(ctx, m); err != nil {
+		t.Errorf("Destroy() of a destroyed environment failed: %v", err)
+	}
+}
+
+func TestAPIGatewayEndpoint(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	m.Lambda.Endpoint = manifest.LambdaEndpointAPIGateway
+	ctx := context.Background()
+
+	result, err := p.deployImage(ctx, m, testImage)
+	if err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if result.URL != "https://abc123.execute-api.us-east-1.amazonaws.com" {
+		t.Errorf("unexpected URL %q", result.URL)
+	}
+	create := fake.requests["CreateApi"].(map[string]interface{})
+	if create["target"] != functionARN+":live" || create["protocolType"] != "HTTP" {
+		t.Errorf("unexpected CreateApi request: %v", create)
+	}
+	permission := fake.requests["AddPermission"].(map[string]interface{})
+	if permission["SourceArn"] != "arn:aws:execute-api:us-east-1:123456789012:abc123/*" || permission["Principal"] != "apigateway.amazonaws.com" {
+		t.Errorf("unexpected permission: %v", permission)
+	}
+	if fake.urlAuth != "" {
+		t.Error("expected no function URL")
+	}
+
+	// Switching to a function URL removes the API
+	m.Lambda.Endpoint = manifest.LambdaEndpointFunctionURL
+	m.Lambda.IAMAuth = true
+	if _, err := p.deployImage(ctx, m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if fake.api || fake.urlAuth != "AWS_IAM" {
+		t.Errorf("expected only an IAM-authenticated function URL, got API %v and URL auth %q", fake.api, fake.urlAuth)
+	}
+
+	// And none removes both
+	m.Lambda.Endpoint = manifest.LambdaEndpointNone
+	result, err = p.deployImage(ctx, m, testImage)
+	if err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	if fake.api || fake.urlAuth != "" || result.URL != "" {
+		t.Errorf("expected no endpoint, got %+v", result)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	ctx := context.Background()
+
+	if _, err := p.Rollback(ctx, m); err == nil {
+		t.Error("expected an error before the function exists")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.deployImage(ctx, m, testImage); err != nil {
+			t.Fatalf("deployImage() failed: %v", err)
+		}
+	}
+
+	result, err := p.Rollback(ctx, m)
+	if err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if fake.alias != "1" || result.Message != "Rolled back to version 1" {
+		t.Errorf("expected the alias to point at version 1, got %q (%s)", fake.alias, result.Message)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil {
+		t.Error("expected an error without an earlier version")
+	}
+}
+
+func TestPreviousVersion(t *testing.T) {
+	tests := []struct {
+		versions []string
+		current  string
+		want     string
+		wantErr  bool
+	}{
+		{versions: []string{"$LATEST", "1", "2", "3"}, current: "3", want: "2"},
+		{versions: []string{"$LATEST", "1", "2", "10"}, current: "10", want: "2"},
+		{versions: []string{"$LATEST", "1", "2", "3"}, current: "2", want: "1"},
+		{versions: []string{"$LATEST", "1"}, current: "1", wantErr: true},
+		{versions: []string{"$LATEST", "1"}, current: "$LATEST", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := previousVersion(tt.versions, tt.current)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("previousVersion(%v, %s) = %q, %v; want %q", tt.versions, tt.current, got, err, tt.want)
+		}
+	}
+}
+
+func TestFunctionStatus(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name       string
+		fn         function
+		wantStatus string
+	}{
+		{name: "active", fn: function{Configuration: functionConfiguration{State: "Active", LastUpdateStatus: "Successful"}}, wantStatus: "Running"},
+		{name: "updating", fn: function{Configuration: functionConfiguration{State: "Active", LastUpdateStatus: "InProgress"}}, wantStatus: "Updating"},
+		{name: "failed", fn: function{Configuration: functionConfiguration{State: "Failed"}}, wantStatus: "Failed"},
+		{name: "inactive", fn: function{Configuration: functionConfiguration{State: "Inactive"}}, wantStatus: "Inactive"},
+		{name: "stopped", fn: function{
+			Configuration: functionConfiguration{State: "Active"},
+			Concurrency:   &struct{ ReservedConcurrentExecutions *int }{&zero},
+		}, wantStatus: "Stopped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := functionStatus(&tt.fn); status != tt.wantStatus {
+				t.Errorf("functionStatus() = %s, want %s", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFunctionConfigDefaults(t *testing.T) {
+	m := testManifest()
+	m.Lambda = nil
+	m.EnvironmentVariables = nil
+	config := functionConfig(m, "arn:role")
+
+	if config["MemorySize"] != manifest.DefaultLambdaMemorySize || config["Timeout"] != manifest.DefaultLambdaTimeout {
+		t.Errorf("unexpected size: %v MB, %v s", config["MemorySize"], config["Timeout"])
+	}
+	// An empty map, rather than nil, clears variables removed from the manifest
+	if env := config["Environment"].(map[string]interface{})["Variables"].(map[string]string); env == nil || len(env) != 0 {
+		t.Errorf("unexpected environment %v", env)
+	}
+	if architecture(m.Lambda) != manifest.LambdaArchitectureX86 || authType(m.Lambda) != "NONE" {
+		t.Error("unexpected defaults")
+	}
+	if id := accountID(functionARN); id != "123456789012" {
+		t.Errorf("accountID() = %q", id)
+	}
+}
@@ -0,0 +1,61 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	var out bytes.Buffer
+	err := p.Logs(context.Background(), m, types.LogOptions{Since: time.Minute, Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "deploy the environment first") {
+		t.Errorf("expected missing log group error, got %v", err)
+	}
+
+	now := time.Now().UnixMilli()
+	fake.logGroup = true
+	fake.events = []logEvent{
+		{EventID: "1", LogStreamName: "2024/01/02/[1]abc", Timestamp: now - 2000, Message: "INIT_START Runtime Version: provided\n"},
+		{EventID: "2", LogStreamName: "2024/01/02/[1]abc", Timestamp: now - 1000, Message: "handled request"},
+	}
+	if err := p.Logs(context.Background(), m, types.LogOptions{Since: time.Minute, Output: &out}); err != nil {
+		t.Fatalf("Logs() failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "[2024/01/02/[1]abc] INIT_START Runtime Version: provided") || !strings.HasSuffix(lines[1], "[2024/01/02/[1]abc] handled request") {
+		t.Errorf("unexpected logs:\n%s", out.String())
+	}
+	if group := fake.requests["FilterLogEvents"].(map[string]interface{})["logGroupName"]; group != "/aws/lambda/my-env" {
+		t.Errorf("unexpected log group %v", group)
+	}
+}
+
+func TestEnsureLogGroup(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	for i := 0; i < 2; i++ {
+		if err := p.ensureLogGroup(context.Background(), m); err != nil {
+			t.Fatalf("ensureLogGroup() failed on call %d: %v", i+1, err)
+		}
+	}
+	if days := fake.requests["PutRetentionPolicy"].(map[string]interface{})["retentionInDays"]; days != 14.0 {
+		t.Errorf("unexpected retention %v", days)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.deleteLogGroup(context.Background(), m); err != nil {
+			t.Fatalf("deleteLogGroup() failed on call %d: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package lambda
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without
+// changing anything in the account. Every deployment publishes a new version
+// of the function and points the live alias at it.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "aws-lambda"}
+	name := m.Environment.Name
+	memory, timeout := m.Lambda.FunctionSize()
+	size := fmt.Sprintf("%d MB, %ds timeout, %s", memory, timeout, architecture(m.Lambda))
+
+	fn, err := p.getFunction(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if fn == nil {
+		plan.Add(types.PlanCreate, "Function", name, size)
+	} else {
+		plan.Add(types.PlanUpdate, "Function", name, size)
+	}
+	plan.Add(types.PlanCreate, "Version", name, "new version running "+m.Image)
+
+	a, err := p.getAlias(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		plan.Add(types.PlanCreate, "Alias", aliasName, "")
+	} else {
+		plan.Add(types.PlanUpdate, "Alias", aliasName, "point at the new version (serving version "+a.FunctionVersion+")")
+	}
+
+	switch m.Lambda.HTTPEndpoint() {
+	case manifest.LambdaEndpointAPIGateway:
+		api, err := p.findAPI(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if api == nil {
+			plan.Add(types.PlanCreate, "HTTPAPI", name, "proxy to the "+aliasName+" alias")
+		} else {
+			plan.Add(types.PlanNoChange, "HTTPAPI", name, api.APIEndpoint)
+		}
+	case manifest.LambdaEndpointFunctionURL:
+		c, err := p.getFunctionURL(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		auth := authType(m.Lambda)
+		switch {
+		case c == nil:
+			plan.Add(types.PlanCreate, "FunctionURL", name, "auth type "+auth)
+		case c.AuthType != auth:
+			plan.Add(types.PlanUpdate, "FunctionURL", name, fmt.Sprintf("auth type %s -> %s", c.AuthType, auth))
+		default:
+			plan.Add(types.PlanNoChange, "FunctionURL", name, c.FunctionUrl)
+		}
+	}
+	return plan, nil
+}
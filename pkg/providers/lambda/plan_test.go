@@ -0,0 +1,53 @@
+package lambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	fake := newFakeAWS()
+	p := newTestProvider(t, fake)
+	m := testManifest()
+
+	plan, err := p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want := map[string]types.PlanAction{
+		"Function":    types.PlanCreate,
+		"Version":     types.PlanCreate,
+		"Alias":       types.PlanCreate,
+		"FunctionURL": types.PlanCreate,
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), plan.Changes)
+	}
+	for _, c := range plan.Changes {
+		if c.Action != want[c.Type] {
+			t.Errorf("%s: got %s, want %s", c.Type, c.Action, want[c.Type])
+		}
+	}
+
+	if _, err := p.deployImage(context.Background(), m, testImage); err != nil {
+		t.Fatalf("deployImage() failed: %v", err)
+	}
+	m.Lambda.IAMAuth = true
+	plan, err = p.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want = map[string]types.PlanAction{
+		"Function":    types.PlanUpdate,
+		"Version":     types.PlanCreate,
+		"Alias":       types.PlanUpdate,
+		"FunctionURL": types.PlanUpdate,
+	}
+	for _, c := range plan.Changes {
+		if c.Action != want[c.Type] {
+			t.Errorf("%s: got %s, want %s", c.Type, c.Action, want[c.Type])
+		}
+	}
+}
@@ -0,0 +1,496 @@
+// Package mock provides a provider that simulates deployments without a cloud
+// account. Operations take a configurable time and can be made to fail, so
+// pipelines, hooks, and notifications can be tested end to end.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// defaultFailureMessage is the error message of injected failures.
+const defaultFailureMessage = "injected failure"
+
+// version is one deployment of an environment's images.
+type version struct {
+	Images     []string  `json:"images"`
+	DeployedAt time.Time `json:"deployed_at"`
+}
+
+// deployment is the simulated state of an environment.
+type deployment struct {
+	Application string `json:"application"`
+	Environment string `json:"environment"`
+	Status      string `json:"status"`
+
+	// Versions deployed, oldest first; the last one is running
+	Versions []version `json:"versions"`
+
+	// Instance counts set by the scale command
+	MinInstances int32 `json:"min_instances,omitempty"`
+	MaxInstances int32 `json:"max_instances,omitempty"`
+
+	// Set by the maintenance command while traffic is paused
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// Events are returned by the logs command
+	Events []types.LogEntry `json:"events"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Deployments of every mock provider in the process, keyed by application and
+// environment, for manifests without a state file.
+var (
+	storeMu sync.Mutex
+	store   = make(map[string]*deployment)
+)
+
+// Provider simulates deployments in memory or in a state file.
+type Provider struct {
+	config manifest.MockConfig
+	random func() float64
+	now    func() time.Time
+}
+
+// New creates a mock provider configured by the manifest's mock section.
+func New(m *manifest.Manifest) *Provider {
+	p := &Provider{random: rand.Float64, now: time.Now}
+	if m.Mock != nil {
+		p.config = *m.Mock
+	}
+	logging.Info("Using mock provider: no cloud resources will be created")
+	return p
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "mock"
+}
+
+// Close does nothing; the mock provider holds no connections.
+func (p *Provider) Close() error {
+	return nil
+}
+
+// Deploy records a new version of the manifest's images and marks the
+// environment ready.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := p.simulate(ctx, "deploy"); err != nil {
+		return nil, err
+	}
+
+	var n int
+	err := p.withState(func(deployments map[string]*deployment) error {
+		key := deploymentKey(m)
+		d := deployments[key]
+		if d == nil {
+			d = &deployment{Application: m.Application.Name, Environment: m.Environment.Name}
+			deployments[key] = d
+		}
+		now := p.now().UTC()
+		d.Versions = append(d.Versions, version{Images: m.Images(), DeployedAt: now})
+		d.Status = "Ready"
+		d.UpdatedAt = now
+		n = len(d.Versions)
+		d.log(now, "deploy", fmt.Sprintf("Deployed version %d: %s", n, strings.Join(m.Images(), ", ")))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             environmentURL(m),
+		Status:          "Ready",
+		Message:         fmt.Sprintf("Simulated deployment successful (version %d)", n),
+		Images:          deployedImages(m),
+	}, nil
+}
+
+// Destroy removes the simulated deployment. Destroying an environment that
+// was never deployed succeeds.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	if err := p.simulate(ctx, "destroy"); err != nil {
+		return err
+	}
+	return p.withState(func(deployments map[string]*deployment) error {
+		delete(deployments, deploymentKey(m))
+		return nil
+	})
+}
+
+// Stop marks the environment stopped, keeping its versions.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	if err := p.simulate(ctx, "stop"); err != nil {
+		return err
+	}
+	return p.withState(func(deployments map[string]*deployment) error {
+		d, err := find(deployments, m)
+		if err != nil {
+			return err
+		}
+		now := p.now().UTC()
+		d.Status = "Stopped"
+		d.UpdatedAt = now
+		d.log(now, "stop", "Environment stopped")
+		return nil
+	})
+}
+
+// Status returns the simulated state of the environment.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	if err := p.simulate(ctx, "status"); err != nil {
+		return nil, err
+	}
+
+	var status *types.DeploymentStatus
+	err := p.withState(func(deployments map[string]*deployment) error {
+		d, err := find(deployments, m)
+		if err != nil {
+			return err
+		}
+		health := "Green"
+		if d.Status != "Ready" {
+			health = "Grey"
+		}
+		current := d.Status
+		if d.Maintenance && d.Status == "Ready" {
+			current, health = "Maintenance", "Yellow"
+		}
+		status = &types.DeploymentStatus{
+			ApplicationName: d.Application,
+			EnvironmentName: d.Environment,
+			Status:          current,
+			Health:          health,
+			URL:             environmentURL(m),
+			LastUpdated:     d.UpdatedAt.Format(time.RFC3339),
+		}
+		return nil
+	})
+	return status, err
+}
+
+// Rollback returns the environment to the version deployed before the current one.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := p.simulate(ctx, "rollback"); err != nil {
+		return nil, err
+	}
+
+	var previous version
+	err := p.withState(func(deployments map[string]*deployment) error {
+		d, err := find(deployments, m)
+		if err != nil {
+			return err
+		}
+		if len(d.Versions) < 2 {
+			return fmt.Errorf("no previous version to roll back to")
+		}
+		d.Versions = d.Versions[:len(d.Versions)-1]
+		previous = d.Versions[len(d.Versions)-1]
+
+		now := p.now().UTC()
+		d.Status = "Ready"
+		d.UpdatedAt = now
+		d.log(now, "rollback", fmt.Sprintf("Rolled back to version %d: %s", len(d.Versions), strings.Join(previous.Images, ", ")))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             environmentURL(m),
+		Status:          "Ready",
+		Message:         fmt.Sprintf("Rolled back to %s", strings.Join(previous.Images, ", ")),
+	}, nil
+}
+
+// Logs prints the events of the simulated deployment. There is no running
+// application, so following is not supported.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	if opts.Follow {
+		return fmt.Errorf("following logs is not supported by the mock provider")
+	}
+	if err := p.simulate(ctx, "logs"); err != nil {
+		return err
+	}
+
+	var events []types.LogEntry
+	err := p.withState(func(deployments map[string]*deployment) error {
+		d, err := find(deployments, m)
+		if err != nil {
+			return err
+		}
+		events = slices.Clone(d.Events)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	since := p.now().Add(-opts.Since)
+	for _, e := range events {
+		if opts.Since > 0 && e.Timestamp.Before(since) {
+			continue
+		}
+		if _, err := fmt.Fprintln(opts.Output, e.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scale records the manifest's instance.min_instances and
+// instance.max_instances as the environment's instance counts.
+func (p *Provider) Scale(ctx context.Context, m *manifest.Manifest) error {
+	if err := p.simulate(ctx, "scale"); err != nil {
+		return err
+	}
+	return p.withState(func(deployments map[string]*deployment) error {
+		d, err := find(deployments, m)
+		if err != nil {
+			return err
+		}
+		now := p.now().UTC()
+		d.MinInstances = m.Instance.MinInstances
+		d.MaxInstances = m.Instance.MaxInstances
+		d.UpdatedAt = now
+		d.log(now, "scale", fmt.Sprintf("Scaled to %d-%d instances", d.MinInstances, d.MaxInstances))
+		return nil
+	})
+}
+
+// SetMaintenance records whether the environment's traffic is paused behind
+// the manifest's maintenance response. Deploys leave the mode as it is.
+func (p *Provider) SetMaintenance(ctx context.Context, m *manifest.Manifest, on bool) error {
+	if err := p.simulate(ctx, "maintenance"); err != nil {
+		return err
+	}
+	return p.withState(func(deployments map[string]*deployment) error {
+		d, err := find(deployments, m)
+		if err != nil {
+			return err
+		}
+		if d.Maintenance == on {
+			return nil
+		}
+		now := p.now().UTC()
+		d.Maintenance = on
+		d.UpdatedAt = now
+		if on {
+			_, code := m.Maintenance.Response()
+			d.log(now, "maintenance", fmt.Sprintf("Maintenance mode on: serving HTTP %d", code))
+		} else {
+			d.log(now, "maintenance", "Maintenance mode off: traffic restored")
+		}
+		return nil
+	})
+}
+
+// Plan returns the changes Deploy would make to the simulated environment.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	if err := p.simulate(ctx, "plan"); err != nil {
+		return nil, err
+	}
+
+	plan := &types.Plan{Provider: "mock"}
+	err := p.withState(func(deployments map[string]*deployment) error {
+		if deployments[deploymentKey(m)] != nil {
+			plan.Add(types.PlanUpdate, "Simulated environment", m.Environment.Name, "deploy "+strings.Join(m.Images(), ", "))
+		} else {
+			plan.Add(types.PlanCreate, "Simulated environment", m.Environment.Name, "environment does not exist")
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// Posture reports the simulated environments of every application as an
+// unlimited quota. The mock provider needs no credentials.
+func (p *Provider) Posture(ctx context.Context, m *manifest.Manifest) (*types.Posture, error) {
+	if err := p.simulate(ctx, "posture"); err != nil {
+		return nil, err
+	}
+
+	posture := &types.Posture{Provider: "mock"}
+	posture.Add(types.PostureCredentials, "Mock credentials", types.PostureOK, "none needed")
+	err := p.withState(func(deployments map[string]*deployment) error {
+		posture.AddQuota("Simulated environments", int64(len(deployments)), 0)
+		return nil
+	})
+	return posture, err
+}
+
+// Inventory lists the simulated environments of the manifest's application.
+func (p *Provider) Inventory(ctx context.Context, m *manifest.Manifest) (*types.Inventory, error) {
+	if err := p.simulate(ctx, "inventory"); err != nil {
+		return nil, err
+	}
+
+	inventory := &types.Inventory{Provider: "mock"}
+	err := p.withState(func(deployments map[string]*deployment) error {
+		keys := make([]string, 0, len(deployments))
+		for key, d := range deployments {
+			if d.Application == m.Application.Name {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			inventory.Add("Simulated environment", deployments[key].Environment, "mock://"+key, types.InventoryByName)
+		}
+		return nil
+	})
+	return inventory, err
+}
+
+// PlanDestroy reports that Destroy would delete the simulated environment,
+// with its versions and events.
+func (p *Provider) PlanDestroy(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	if err := p.simulate(ctx, "plan"); err != nil {
+		return nil, err
+	}
+
+	plan := &types.Plan{Provider: "mock"}
+	err := p.withState(func(deployments map[string]*deployment) error {
+		if d := deployments[deploymentKey(m)]; d != nil {
+			plan.Add(types.PlanDelete, "Simulated environment", d.Environment, fmt.Sprintf("with %d versions", len(d.Versions)))
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// simulate waits for the operation's latency and then fails if the operation
+// is configured to fail.
+func (p *Provider) simulate(ctx context.Context, op string) error {
+	if d := p.latency(op); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if slices.Contains(p.config.FailOn, op) || (p.config.FailureRate > 0 && p.random() < p.config.FailureRate) {
+		message := p.config.FailureMessage
+		if message == "" {
+			message = defaultFailureMessage
+		}
+		return fmt.Errorf("mock %s failed: %s", op, message)
+	}
+	return nil
+}
+
+// latency returns how long op takes.
+func (p *Provider) latency(op string) time.Duration {
+	ms, ok := p.config.OperationLatencyMS[op]
+	if !ok {
+		ms = p.config.LatencyMS
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// withState calls fn with the deployments, read from the state file when one
+// is configured and saved back afterwards.
+func (p *Provider) withState(fn func(deployments map[string]*deployment) error) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if p.config.StateFile == "" {
+		return fn(store)
+	}
+
+	deployments, err := loadState(p.config.StateFile)
+	if err != nil {
+		return err
+	}
+	if err := fn(deployments); err != nil {
+		return err
+	}
+	return saveState(p.config.StateFile, deployments)
+}
+
+// loadState reads deployments from a state file. A missing file has none.
+func loadState(path string) (map[string]*deployment, error) {
+	deployments := make(map[string]*deployment)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return deployments, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &deployments); err != nil {
+		return nil, fmt.Errorf("failed to parse mock state file %s: %w", path, err)
+	}
+	return deployments, nil
+}
+
+// saveState writes deployments to a state file.
+func saveState(path string, deployments map[string]*deployment) error {
+	data, err := json.MarshalIndent(deployments, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write mock state file: %w", err)
+	}
+	return nil
+}
+
+// find returns the deployment of m's environment.
+func find(deployments map[string]*deployment, m *manifest.Manifest) (*deployment, error) {
+	d := deployments[deploymentKey(m)]
+	if d == nil {
+		return nil, fmt.Errorf("no deployment found for environment %s", m.Environment.Name)
+	}
+	return d, nil
+}
+
+// log records an event of the deployment.
+func (d *deployment) log(at time.Time, source, message string) {
+	d.Events = append(d.Events, types.LogEntry{Timestamp: at, Source: source, Message: message})
+}
+
+// deploymentKey identifies m's environment in the state.
+func deploymentKey(m *manifest.Manifest) string {
+	return m.Application.Name + "/" + m.Environment.Name
+}
+
+// deployedImages returns m's images by container name. Nothing is pushed, so
+// they are not pinned by digest.
+func deployedImages(m *manifest.Manifest) map[string]string {
+	if !m.IsMultiContainer() {
+		return map[string]string{m.Application.Name: m.Image}
+	}
+	images := make(map[string]string)
+	for _, c := range m.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+// environmentURL returns the URL reported for m's environment. The .localhost
+// domain resolves to the local machine, so nothing outside it is reached.
+func environmentURL(m *manifest.Manifest) string {
+	return fmt.Sprintf("http://%s.mock.localhost", strings.ToLower(m.Environment.Name))
+}
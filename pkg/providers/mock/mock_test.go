@@ -0,0 +1,356 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// testManifest returns a manifest whose environment is unique to the test, so
+// tests sharing the in-memory store don't see each other's deployments.
+func testManifest(t *testing.T, mock *manifest.MockConfig) *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:       "my-app:1.0.0",
+		Provider:    manifest.ProviderConfig{Name: "mock"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: t.Name()},
+		Mock:        mock,
+	}
+}
+
+func TestProviderName(t *testing.T) {
+	if name := New(testManifest(t, nil)).Name(); name != "mock" {
+		t.Errorf("Name() = %q, want mock", name)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	p := New(m)
+
+	if _, err := p.Status(ctx, m); err == nil || !strings.Contains(err.Error(), "no deployment found") {
+		t.Errorf("Expected status of a new environment to fail, got: %v", err)
+	}
+
+	result, err := p.Deploy(ctx, m)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if result.Status != "Ready" || result.URL != "http://testlifecycle.mock.localhost" || result.Images["my-app"] != "my-app:1.0.0" {
+		t.Errorf("Unexpected deploy result: %+v", result)
+	}
+
+	m.Image = "my-app:2.0.0"
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("second Deploy failed: %v", err)
+	}
+
+	result, err = p.Rollback(ctx, m)
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if result.Message != "Rolled back to my-app:1.0.0" {
+		t.Errorf("Rollback message = %q", result.Message)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil || !strings.Contains(err.Error(), "no previous version") {
+		t.Errorf("Expected rollback past the first version to fail, got: %v", err)
+	}
+
+	if err := p.Stop(ctx, m); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	status, err := p.Status(ctx, m)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Stopped" || status.Health != "Grey" {
+		t.Errorf("Unexpected status after stop: %+v", status)
+	}
+
+	var out bytes.Buffer
+	if err := p.Logs(ctx, m, types.LogOptions{Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	for _, want := range []string{"[deploy] Deployed version 1: my-app:1.0.0", "[deploy] Deployed version 2: my-app:2.0.0", "[rollback] Rolled back to version 1", "[stop] Environment stopped"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Logs missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := p.Destroy(ctx, m); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if _, err := p.Status(ctx, m); err == nil {
+		t.Error("Expected status after destroy to fail")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	p := New(m)
+
+	plan, err := p.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.Count(types.PlanCreate) != 1 {
+		t.Errorf("Expected a create before the first deploy: %+v", plan.Changes)
+	}
+
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	plan, err = p.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if plan.Count(types.PlanUpdate) != 1 {
+		t.Errorf("Expected an update after deploying: %+v", plan.Changes)
+	}
+}
+
+func TestPosture(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	p := New(m)
+
+	// Other tests share the in-memory deployments
+	before, err := p.Posture(ctx, m)
+	if err != nil {
+		t.Fatalf("Posture failed: %v", err)
+	}
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	after, err := p.Posture(ctx, m)
+	if err != nil {
+		t.Fatalf("Posture failed: %v", err)
+	}
+
+	if after.Count(types.PostureOK) != 2 || len(after.Checks) != 2 {
+		t.Fatalf("Expected two passing checks: %+v", after.Checks)
+	}
+	var used, wasUsed int
+	fmt.Sscanf(before.Checks[1].Detail, "%d used", &wasUsed)
+	fmt.Sscanf(after.Checks[1].Detail, "%d used", &used)
+	if used != wasUsed+1 {
+		t.Errorf("Expected the deployment to be counted: %q -> %q", before.Checks[1].Detail, after.Checks[1].Detail)
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, &manifest.MockConfig{FailOn: []string{"rollback"}, FailureMessage: "simulated outage"})
+	p := New(m)
+
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy should not fail: %v", err)
+	}
+	if _, err := p.Rollback(ctx, m); err == nil || err.Error() != "mock rollback failed: simulated outage" {
+		t.Errorf("Expected injected rollback failure, got: %v", err)
+	}
+
+	m.Mock = &manifest.MockConfig{FailureRate: 0.5}
+	p = New(m)
+	p.random = func() float64 { return 0.4 }
+	if _, err := p.Deploy(ctx, m); err == nil || err.Error() != "mock deploy failed: injected failure" {
+		t.Errorf("Expected random failure below the rate, got: %v", err)
+	}
+	p.random = func() float64 { return 0.6 }
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Errorf("Expected no failure above the rate, got: %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	p := New(testManifest(t, &manifest.MockConfig{LatencyMS: 10, OperationLatencyMS: map[string]int{"deploy": 5000, "status": 0}}))
+
+	if d := p.latency("deploy"); d != 5*time.Second {
+		t.Errorf("deploy latency = %v, want 5s", d)
+	}
+	if d := p.latency("status"); d != 0 {
+		t.Errorf("status latency = %v, want 0", d)
+	}
+	if d := p.latency("stop"); d != 10*time.Millisecond {
+		t.Errorf("stop latency = %v, want 10ms", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.simulate(ctx, "deploy"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a cancelled deploy to stop waiting, got: %v", err)
+	}
+}
+
+func TestStateFile(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, &manifest.MockConfig{StateFile: filepath.Join(t.TempDir(), "mock-state.json")})
+
+	if _, err := New(m).Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+
+	// A new provider, as in a later run, sees the deployment
+	status, err := New(m).Status(ctx, m)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "Ready" {
+		t.Errorf("Status = %q, want Ready", status.Status)
+	}
+
+	// The in-memory store is not used with a state file
+	m.Mock = nil
+	if _, err := New(m).Status(ctx, m); err == nil {
+		t.Error("Expected the deployment to exist only in the state file")
+	}
+}
+
+func TestLogsFollowUnsupported(t *testing.T) {
+	m := testManifest(t, nil)
+	err := New(m).Logs(context.Background(), m, types.LogOptions{Follow: true})
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("Expected follow to be unsupported, got: %v", err)
+	}
+}
+
+func TestScale(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	p := New(m)
+
+	m.Instance = manifest.InstanceConfig{MinInstances: 2, MaxInstances: 10}
+	if err := p.Scale(ctx, m); err == nil || !strings.Contains(err.Error(), "no deployment found") {
+		t.Errorf("Expected scaling a new environment to fail, got: %v", err)
+	}
+
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if err := p.Scale(ctx, m); err != nil {
+		t.Fatalf("Scale failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := p.Logs(ctx, m, types.LogOptions{Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "[scale] Scaled to 2-10 instances") {
+		t.Errorf("Logs missing the scale event:\n%s", out.String())
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	p := New(m)
+
+	if err := p.SetMaintenance(ctx, m, true); err == nil || !strings.Contains(err.Error(), "no deployment found") {
+		t.Errorf("Expected maintenance of a new environment to fail, got: %v", err)
+	}
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+
+	m.Maintenance = &manifest.MaintenanceConfig{StatusCode: 502}
+	for i := 0; i < 2; i++ {
+		if err := p.SetMaintenance(ctx, m, true); err != nil {
+			t.Fatalf("SetMaintenance failed: %v", err)
+		}
+	}
+	status, err := p.Status(ctx, m)
+	if err != nil || status.Status != "Maintenance" || status.Health != "Yellow" {
+		t.Errorf("Expected the environment in maintenance, got %+v, %v", status, err)
+	}
+
+	if err := p.SetMaintenance(ctx, m, false); err != nil {
+		t.Fatalf("SetMaintenance failed: %v", err)
+	}
+	if status, _ := p.Status(ctx, m); status.Status != "Ready" {
+		t.Errorf("Expected the environment ready again, got %s", status.Status)
+	}
+
+	var out bytes.Buffer
+	if err := p.Logs(ctx, m, types.LogOptions{Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	if n := strings.Count(out.String(), "Maintenance mode on: serving HTTP 502"); n != 1 {
+		t.Errorf("Expected one maintenance event, got %d:\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), "Maintenance mode off") {
+		t.Errorf("Logs missing the end of maintenance:\n%s", out.String())
+	}
+}
+
+func TestInventory(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	m.Application.Name = "inventory-app"
+	p := New(m)
+
+	inventory, err := p.Inventory(ctx, m)
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if len(inventory.Resources) != 0 {
+		t.Errorf("Expected no resources before deploying: %+v", inventory.Resources)
+	}
+
+	if _, err := p.Deploy(ctx, m); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	other := testManifest(t, nil)
+	other.Environment.Name = "other-app-env"
+	if _, err := p.Deploy(ctx, other); err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+
+	inventory, err = p.Inventory(ctx, m)
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	want := types.InventoryResource{Type: "Simulated environment", Name: t.Name(), ID: "mock://inventory-app/" + t.Name(), Source: types.InventoryByName}
+	if len(inventory.Resources) != 1 || inventory.Resources[0] != want {
+		t.Errorf("Expected only the application's environment: %+v", inventory.Resources)
+	}
+}
+
+func TestPlanDestroy(t *testing.T) {
+	ctx := context.Background()
+	m := testManifest(t, nil)
+	p := New(m)
+
+	plan, err := p.PlanDestroy(ctx, m)
+	if err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	if len(plan.Changes) != 0 {
+		t.Errorf("Expected nothing to destroy before deploying: %+v", plan.Changes)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Deploy(ctx, m); err != nil {
+			t.Fatalf("Deploy failed: %v", err)
+		}
+	}
+	if plan, err = p.PlanDestroy(ctx, m); err != nil {
+		t.Fatalf("PlanDestroy failed: %v", err)
+	}
+	want := types.ResourceChange{Action: types.PlanDelete, Type: "Simulated environment", Name: t.Name(), Reason: "with 2 versions"}
+	if len(plan.Changes) != 1 || plan.Changes[0] != want {
+		t.Errorf("Expected the environment to be deleted: %+v", plan.Changes)
+	}
+	if _, err := p.Status(ctx, m); err != nil {
+		t.Errorf("Expected the environment to still exist: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// policyStatement is a statement of an OCI IAM policy.
+type policyStatement struct {
+	Statement string `json:"statement"`
+	Reason    string `json:"reason"`
+}
+
+// IAMPolicy returns the policy statements the deployer's group needs for the
+// features used in m, scoped to the manifest's compartment.
+func IAMPolicy(m *manifest.Manifest) ([]byte, error) {
+	compartmentID := m.Provider.CompartmentID
+	if compartmentID == "" {
+		return nil, fmt.Errorf("provider.compartment_id is required to scope the policy")
+	}
+	compartment := "compartment id " + compartmentID
+	allow := func(verb, resources, where string) string {
+		return fmt.Sprintf("Allow group <GROUP_NAME> to %s %s in %s", verb, resources, where)
+	}
+
+	statements := []policyStatement{
+		{Statement: allow("manage", "compute-container-family", compartment), Reason: "Create, stop, and delete container instances and read their logs"},
+		{Statement: allow("use", "virtual-network-family", compartment), Reason: "Attach container instances to the subnet (grant in the subnet's compartment if it differs)"},
+		{Statement: allow("manage", "repos", compartment), Reason: "Create OCIR repositories, push and list images, and delete repositories on destroy"},
+		{Statement: allow("read", "objectstorage-namespaces", "tenancy"), Reason: "Look up the namespace OCIR image paths start with"},
+	}
+
+	if c := m.Provider.Credentials; c == nil || c.OCI == nil || c.OCI.RegistryUsername == "" {
+		statements = append(statements, policyStatement{
+			Statement: allow("inspect", "users", "tenancy"),
+			Reason:    "Look up the API user's name for OCIR login (not needed with credentials.oci.registry_username)",
+		})
+	}
+
+	return json.MarshalIndent(struct {
+		Statements []policyStatement `json:"statements"`
+	}{statements}, "", "  ")
+}
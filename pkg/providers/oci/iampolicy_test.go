@@ -0,0 +1,65 @@
+package oci
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func policyStatements(t *testing.T, m *manifest.Manifest) []string {
+	t.Helper()
+	data, err := IAMPolicy(m)
+	if err != nil {
+		t.Fatalf("IAMPolicy failed: %v", err)
+	}
+	var doc struct {
+		Statements []policyStatement `json:"statements"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("IAMPolicy returned invalid JSON: %v", err)
+	}
+	var statements []string
+	for _, s := range doc.Statements {
+		statements = append(statements, s.Statement)
+	}
+	return statements
+}
+
+func TestIAMPolicy(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{Name: "oci", CompartmentID: "ocid1.compartment.oc1..aaa"}}
+	statements := policyStatements(t, m)
+
+	expected := []string{
+		"Allow group <GROUP_NAME> to manage compute-container-family in compartment id ocid1.compartment.oc1..aaa",
+		"Allow group <GROUP_NAME> to manage repos in compartment id ocid1.compartment.oc1..aaa",
+		"Allow group <GROUP_NAME> to read objectstorage-namespaces in tenancy",
+		"Allow group <GROUP_NAME> to inspect users in tenancy",
+	}
+	joined := strings.Join(statements, "\n")
+	for _, s := range expected {
+		if !strings.Contains(joined, s) {
+			t.Errorf("expected statement %q, got:\n%s", s, joined)
+		}
+	}
+}
+
+func TestIAMPolicyRegistryUsername(t *testing.T) {
+	m := &manifest.Manifest{Provider: manifest.ProviderConfig{
+		Name:          "oci",
+		CompartmentID: "ocid1.compartment.oc1..aaa",
+		Credentials:   &manifest.CredentialsConfig{OCI: &manifest.OCICredentialsConfig{RegistryUsername: "jane"}},
+	}}
+	for _, s := range policyStatements(t, m) {
+		if strings.Contains(s, "users") {
+			t.Errorf("unexpected statement with registry_username set: %s", s)
+		}
+	}
+}
+
+func TestIAMPolicyRequiresCompartment(t *testing.T) {
+	if _, err := IAMPolicy(&manifest.Manifest{Provider: manifest.ProviderConfig{Name: "oci"}}); err == nil {
+		t.Error("expected error without compartment_id")
+	}
+}
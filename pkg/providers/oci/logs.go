@@ -0,0 +1,46 @@
+package oci
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Logs prints the recent output of each container in the container instance.
+// OCI Container Instances return container output without timestamps, so
+// every line retrieved is printed and following is not supported.
+func (p *Provider) Logs(ctx context.Context, m *manifest.Manifest, opts types.LogOptions) error {
+	if opts.Follow {
+		return fmt.Errorf("following logs is not supported by OCI Container Instances")
+	}
+
+	instance, err := p.currentInstance(ctx, m.Environment.Name)
+	if err != nil {
+		return err
+	}
+	if err := p.containerInstances.Do(ctx, http.MethodGet, "/20210415/containerInstances/"+instance.ID, nil, instance); err != nil {
+		return fmt.Errorf("failed to get container instance: %w", err)
+	}
+	if len(instance.Containers) == 0 {
+		return fmt.Errorf("container instance %s has no containers", m.Environment.Name)
+	}
+
+	for _, c := range instance.Containers {
+		logs, err := p.containerInstances.DoRaw(ctx, http.MethodPost, "/20210415/containers/"+c.ContainerID+"/actions/retrieveLogs", nil)
+		if err != nil {
+			return fmt.Errorf("failed to get logs for container %s: %w", c.DisplayName, err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(logs))
+		for scanner.Scan() {
+			if _, err := fmt.Fprintf(opts.Output, "[%s] %s\n", c.DisplayName, scanner.Text()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
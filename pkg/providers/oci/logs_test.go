@@ -0,0 +1,32 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestLogs(t *testing.T) {
+	fake := &fakeOCI{instances: []containerInstance{{
+		ID: "ocid1.ci", DisplayName: "my-app-prod", LifecycleState: "ACTIVE",
+		Containers: []instanceRef{{ContainerID: "ocid1.container", DisplayName: "web"}},
+	}}}
+	p := newTestProvider(t, fake)
+
+	var out bytes.Buffer
+	if err := p.Logs(context.Background(), testManifest(), types.LogOptions{Output: &out}); err != nil {
+		t.Fatalf("Logs failed: %v", err)
+	}
+	if out.String() != "[web] listening on :8080\n[web] ready\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestLogsFollowUnsupported(t *testing.T) {
+	p := newTestProvider(t, &fakeOCI{})
+	if err := p.Logs(context.Background(), testManifest(), types.LogOptions{Follow: true}); err == nil {
+		t.Error("expected error when following")
+	}
+}
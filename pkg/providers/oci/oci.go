@@ -0,0 +1,838 @@
+// Package oci provides deployment functionality for Oracle Cloud Infrastructure
+// Container Instances. It implements the Provider interface for deploying
+// containerized applications to OCI, pushing images to OCI Registry (OCIR).
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/ociapi"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Service endpoints, formatted with the region.
+var (
+	containerInstancesEndpoint = "https://compute-containers.%s.oci.oraclecloud.com"
+	coreEndpoint               = "https://iaas.%s.oraclecloud.com"
+	identityEndpoint           = "https://identity.%s.oci.oraclecloud.com"
+	objectStorageEndpoint      = "https://objectstorage.%s.oraclecloud.com"
+	artifactsEndpoint          = "https://artifacts.%s.oci.oraclecloud.com"
+)
+
+// pollInterval is how often a container instance is checked while waiting for it.
+var pollInterval = 10 * time.Second
+
+// Default container instance resources.
+const (
+	defaultShape    = "CI.Standard.E4.Flex"
+	defaultOCPUs    = 1.0
+	defaultMemoryGB = 4.0
+)
+
+// Provider implements the provider.Provider interface for OCI.
+type Provider struct {
+	region        string
+	compartmentID string
+	tenancyID     string
+	userID        string
+
+	containerInstances *ociapi.Client
+	core               *ociapi.Client
+	identity           *ociapi.Client
+	objectStorage      *ociapi.Client
+	artifacts          *ociapi.Client
+
+	registryUsername string
+	authToken        string
+}
+
+// New creates a new OCI provider instance.
+//
+// Authentication methods:
+//  1. Manifest: Provide user_id, fingerprint and private_key_path under credentials.oci
+//     and tenancy_id (if credentials.source == "manifest")
+//  2. OCI CLI config file: ~/.oci/config, or credentials.oci.config_file and profile (default)
+//
+// Pushing images to OCIR also needs an auth token, from credentials.oci.auth_token
+// or the OCI_AUTH_TOKEN environment variable.
+//
+// When provider.endpoint_url is set, every service is reached at that
+// emulator; requests are still signed.
+func New(ctx context.Context, config *manifest.ProviderConfig, m *manifest.Manifest) (*Provider, error) {
+	if config.CompartmentID == "" {
+		return nil, fmt.Errorf("compartment ID is required")
+	}
+
+	var ociCreds *manifest.OCICredentialsConfig
+	if config.Credentials != nil && config.Credentials.OCI != nil {
+		ociCreds = config.Credentials.OCI
+	}
+
+	cfg, err := loadConfig(config, ociCreds)
+	if err != nil {
+		return nil, err
+	}
+	if config.Region != "" {
+		cfg.Region = config.Region
+	}
+	if config.TenancyID != "" {
+		cfg.TenancyID = config.TenancyID
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("region is required")
+	}
+	if cfg.TenancyID == "" || cfg.UserID == "" || cfg.Fingerprint == "" {
+		return nil, fmt.Errorf("tenancy, user and fingerprint are required to sign OCI requests")
+	}
+
+	p := &Provider{
+		region:             cfg.Region,
+		compartmentID:      config.CompartmentID,
+		tenancyID:          cfg.TenancyID,
+		userID:             cfg.UserID,
+		containerInstances: ociapi.New(cfg, fmt.Sprintf(containerInstancesEndpoint, cfg.Region)),
+		core:               ociapi.New(cfg, fmt.Sprintf(coreEndpoint, cfg.Region)),
+		identity:           ociapi.New(cfg, fmt.Sprintf(identityEndpoint, cfg.Region)),
+		objectStorage:      ociapi.New(cfg, fmt.Sprintf(objectStorageEndpoint, cfg.Region)),
+		artifacts:          ociapi.New(cfg, fmt.Sprintf(artifactsEndpoint, cfg.Region)),
+		authToken:          os.Getenv("OCI_AUTH_TOKEN"),
+	}
+	if config.Emulated() {
+		for _, client := range []*ociapi.Client{p.containerInstances, p.core, p.identity, p.objectStorage, p.artifacts} {
+			client.SetEndpoint(config.EndpointURL)
+		}
+	}
+	if ociCreds != nil {
+		p.registryUsername = ociCreds.RegistryUsername
+		if ociCreds.AuthToken != "" {
+			p.authToken = ociCreds.AuthToken
+		}
+	}
+	return p, nil
+}
+
+// loadConfig loads the API signing key from the manifest or the OCI CLI config file.
+func loadConfig(config *manifest.ProviderConfig, creds *manifest.OCICredentialsConfig) (*ociapi.Config, error) {
+	if config.Credentials.FromSecretStore() {
+		return nil, fmt.Errorf("loading OCI credentials from %s is not supported", config.Credentials.Source)
+	}
+
+	if config.Credentials != nil && config.Credentials.Source == "manifest" {
+		if creds == nil || creds.UserID == "" || creds.Fingerprint == "" || creds.PrivateKeyPath == "" {
+			return nil, fmt.Errorf("credentials.oci.user_id, fingerprint and private_key_path are required when source is manifest")
+		}
+		key, err := ociapi.LoadPrivateKey(creds.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		logging.Info("Using API signing key from manifest")
+		return &ociapi.Config{
+			UserID:      creds.UserID,
+			Fingerprint: creds.Fingerprint,
+			PrivateKey:  key,
+		}, nil
+	}
+
+	path, profile := ociapi.DefaultConfigFile(), ""
+	if creds != nil {
+		if creds.ConfigFile != "" {
+			path = creds.ConfigFile
+		}
+		profile = creds.Profile
+	}
+	logging.Infof("Using OCI CLI config file: %s", path)
+	return ociapi.LoadConfigFile(path, profile)
+}
+
+// Name returns the provider name.
+func (p *Provider) Name() string {
+	return "oci"
+}
+
+// Close closes the idle connections of the provider's clients.
+func (p *Provider) Close() error {
+	for _, c := range []*ociapi.Client{p.containerInstances, p.core, p.identity, p.objectStorage, p.artifacts} {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// containerInstance is a container instance of the Container Instances API.
+type containerInstance struct {
+	ID               string            `json:"id"`
+	DisplayName      string            `json:"displayName"`
+	LifecycleState   string            `json:"lifecycleState"`
+	LifecycleDetails string            `json:"lifecycleDetails,omitempty"`
+	Containers       []instanceRef     `json:"containers,omitempty"`
+	Vnics            []vnicRef         `json:"vnics,omitempty"`
+	FreeformTags     map[string]string `json:"freeformTags,omitempty"`
+	TimeCreated      time.Time         `json:"timeCreated"`
+	TimeUpdated      time.Time         `json:"timeUpdated"`
+}
+
+// instanceRef identifies a container of a container instance.
+type instanceRef struct {
+	ContainerID string `json:"containerId"`
+	DisplayName string `json:"displayName"`
+}
+
+// vnicRef identifies a VNIC attached to a container instance.
+type vnicRef struct {
+	VnicID string `json:"vnicId"`
+}
+
+// container is a container of the Container Instances API.
+type container struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	ImageURL    string `json:"imageUrl"`
+}
+
+// Deploy deploys an application to OCI Container Instances.
+// This method:
+// 1. Pushes the pre-built Docker images to OCIR with a timestamped tag
+// 2. Creates a new container instance running them
+// 3. Waits for it to become active
+// 4. Deletes the container instance it replaces
+//
+// Container instances cannot change their images, so every deployment
+// replaces the instance, and its public IP address changes.
+func (p *Provider) Deploy(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	if err := validateImagePlatforms(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := validateSecurity(ctx, m); err != nil {
+		return nil, err
+	}
+
+	logging.Info("Starting OCI Container Instances deployment...")
+
+	namespace, username, err := p.registryLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Push images to OCIR with timestamped tag for rollback support
+	logging.Info("=== Distributing images to OCIR ===")
+	deployTag := m.VersionLabel(fmt.Sprintf("deploy-%s", time.Now().UTC().Format("20060102T150405")))
+	images := make(map[string]string)
+	pinnedImages := make(map[string]string)
+	for _, c := range deployContainers(m) {
+		ocirRegistry, err := registry.NewOCIRRegistry(p.artifacts, p.compartmentID, p.region, namespace,
+			repositoryName(m, c), deployTag, username, p.authToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OCIR registry handler: %w", err)
+		}
+
+		distributor := registry.NewDistributor(c.Image, m.Platforms...)
+		dest := registry.Local(ocirRegistry, m.Provider.RegistryURL, repositoryName(m, c), deployTag)
+		distributor.AddRegistry(dest)
+		imageURIs, err := distributor.Distribute(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to distribute image for container %s: %w", c.Name, err)
+		}
+		images[c.Name] = imageURIs[dest.GetRegistryURL()]
+		pinnedImages[c.Name] = registry.PinDigest(images[c.Name], distributor.Digest())
+		logging.Infof("Image pushed to OCIR: %s -> %s", c.Name, images[c.Name])
+	}
+
+	instance, err := p.replaceInstance(ctx, m, images, namespace, username)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := p.instanceURL(ctx, m, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	message := "Deployment successful"
+	if m.IsMultiContainer() {
+		message = fmt.Sprintf("Multi-container deployment successful (%d containers)", len(m.Containers))
+	}
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             url,
+		Status:          "Running",
+		Message:         message,
+		Images:          pinnedImages,
+	}, nil
+}
+
+// Destroy removes the container instances of the environment and the
+// application's OCIR repositories, including all pushed images.
+func (p *Provider) Destroy(ctx context.Context, m *manifest.Manifest) error {
+	instances, err := p.findInstances(ctx, m.Environment.Name)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if err := p.deleteInstance(ctx, instance.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range deployContainers(m) {
+		name := repositoryName(m, c)
+		id, err := registry.FindOCIRRepository(ctx, p.artifacts, p.compartmentID, name)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			continue
+		}
+		logging.Infof("Deleting OCIR repository: %s", name)
+		if err := p.artifacts.Do(ctx, http.MethodDelete, "/20160918/container/repositories/"+id, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete OCIR repository %s: %w", name, err)
+		}
+	}
+
+	logging.Info("Container instance terminated successfully")
+	return nil
+}
+
+// Stop stops the running container instance without deleting it.
+// The container instance is preserved; running Deploy again replaces it.
+func (p *Provider) Stop(ctx context.Context, m *manifest.Manifest) error {
+	instance, err := p.currentInstance(ctx, m.Environment.Name)
+	if err != nil {
+		return err
+	}
+
+	logging.Infof("Stopping container instance: %s", instance.ID)
+	if err := p.containerInstances.Do(ctx, http.MethodPost, "/20210415/containerInstances/"+instance.ID+"/actions/stop", nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container instance: %w", err)
+	}
+
+	logging.Info("Container instance stopped successfully (restart with 'deploy' command)")
+	return nil
+}
+
+// Status returns the current status of the deployment.
+func (p *Provider) Status(ctx context.Context, m *manifest.Manifest) (*types.DeploymentStatus, error) {
+	instance, err := p.currentInstance(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.containerInstances.Do(ctx, http.MethodGet, "/20210415/containerInstances/"+instance.ID, nil, instance); err != nil {
+		return nil, fmt.Errorf("failed to get container instance: %w", err)
+	}
+
+	url, err := p.instanceURL(ctx, m, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastUpdated string
+	if !instance.TimeUpdated.IsZero() {
+		lastUpdated = instance.TimeUpdated.Format(time.RFC3339)
+	}
+
+	return &types.DeploymentStatus{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		Status:          instance.LifecycleState,
+		Health:          "N/A", // Container health checks restart containers but aren't reported per instance
+		URL:             url,
+		LastUpdated:     lastUpdated,
+	}, nil
+}
+
+// Rollback replaces the container instance with one running the previous
+// images in OCIR, found from their timestamped deploy tags.
+func (p *Provider) Rollback(ctx context.Context, m *manifest.Manifest) (*types.DeploymentResult, error) {
+	logging.Info("Starting OCI Container Instances rollback...")
+
+	instance, err := p.currentInstance(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.containerInstances.Do(ctx, http.MethodGet, "/20210415/containerInstances/"+instance.ID, nil, instance); err != nil {
+		return nil, fmt.Errorf("failed to get container instance: %w", err)
+	}
+	if len(instance.Containers) == 0 {
+		return nil, fmt.Errorf("no containers found in container instance")
+	}
+
+	images := make(map[string]string)
+	for _, ref := range instance.Containers {
+		var c container
+		if err := p.containerInstances.Do(ctx, http.MethodGet, "/20210415/containers/"+ref.ContainerID, nil, &c); err != nil {
+			return nil, fmt.Errorf("failed to get container %s: %w", ref.DisplayName, err)
+		}
+		logging.Infof("Current image of %s: %s", c.DisplayName, c.ImageURL)
+
+		previous, err := p.findPreviousImage(ctx, c.ImageURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find previous image for container %s: %w", c.DisplayName, err)
+		}
+		logging.Infof("Rolling back %s to previous image: %s", c.DisplayName, previous)
+		images[c.DisplayName] = previous
+	}
+
+	namespace, username, err := p.registryLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := p.replaceInstance(ctx, m, images, namespace, username)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := p.instanceURL(ctx, m, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make([]string, 0, len(images))
+	for _, image := range images {
+		previous = append(previous, image)
+	}
+	sort.Strings(previous)
+	return &types.DeploymentResult{
+		ApplicationName: m.Application.Name,
+		EnvironmentName: m.Environment.Name,
+		URL:             url,
+		Status:          "Running",
+		Message:         fmt.Sprintf("Rolled back to image %s", strings.Join(previous, ", ")),
+	}, nil
+}
+
+// registryLogin returns the tenancy's object storage namespace, which OCIR
+// image paths start with, and the registry username.
+func (p *Provider) registryLogin(ctx context.Context) (string, string, error) {
+	var namespace string
+	if err := p.objectStorage.Do(ctx, http.MethodGet, "/n/", nil, &namespace); err != nil {
+		return "", "", fmt.Errorf("failed to get object storage namespace: %w", err)
+	}
+
+	username := p.registryUsername
+	if username == "" {
+		var user struct {
+			Name string `json:"name"`
+		}
+		if err := p.identity.Do(ctx, http.MethodGet, "/20160918/users/"+p.userID, nil, &user); err != nil {
+			return "", "", fmt.Errorf("failed to get registry username (set credentials.oci.registry_username): %w", err)
+		}
+		username = user.Name
+	}
+	return namespace, username, nil
+}
+
+// replaceInstance creates a container instance running images, keyed by
+// container name, waits for it to become active, and then deletes the
+// instances it replaces.
+func (p *Provider) replaceInstance(ctx context.Context, m *manifest.Manifest, images map[string]string, namespace, username string) (*containerInstance, error) {
+	previous, err := p.findInstances(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	availabilityDomain, err := p.availabilityDomain(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infof("Creating container instance: %s", m.Environment.Name)
+	details := p.instanceDetails(m, availabilityDomain, images, namespace, username)
+	var instance containerInstance
+	if err := p.containerInstances.Do(ctx, http.MethodPost, "/20210415/containerInstances", details, &instance); err != nil {
+		return nil, fmt.Errorf("failed to create container instance: %w", err)
+	}
+
+	logging.Info("Waiting for container instance to be ready...")
+	if err := p.waitForInstance(ctx, &instance); err != nil {
+		return nil, err
+	}
+
+	for _, old := range previous {
+		if err := p.deleteInstance(ctx, old.ID); err != nil {
+			return nil, fmt.Errorf("new container instance is active but the old one could not be deleted: %w", err)
+		}
+	}
+	return &instance, nil
+}
+
+// findInstances returns the container instances named name that are not
+// deleted or failed, newest first.
+func (p *Provider) findInstances(ctx context.Context, name string) ([]containerInstance, error) {
+	query := url.Values{"compartmentId": {p.compartmentID}, "displayName": {name}}
+	var list struct {
+		Items []containerInstance `json:"items"`
+	}
+	if err := p.containerInstances.Do(ctx, http.MethodGet, "/20210415/containerInstances?"+query.Encode(), nil, &list); err != nil {
+		return nil, fmt.Errorf("failed to list container instances: %w", err)
+	}
+
+	var instances []containerInstance
+	for _, instance := range list.Items {
+		switch instance.LifecycleState {
+		case "DELETING", "DELETED", "FAILED":
+			continue
+		}
+		instances = append(instances, instance)
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].TimeCreated.After(instances[j].TimeCreated)
+	})
+	return instances, nil
+}
+
+// currentInstance returns the newest container instance named name.
+func (p *Provider) currentInstance(ctx context.Context, name string) (*containerInstance, error) {
+	instances, err := p.findInstances(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no container instance found for environment %s", name)
+	}
+	return &instances[0], nil
+}
+
+// deleteInstance requests deletion of a container instance.
+func (p *Provider) deleteInstance(ctx context.Context, id string) error {
+	logging.Infof("Deleting container instance: %s", id)
+	err := p.containerInstances.Do(ctx, http.MethodDelete, "/20210415/containerInstances/"+id, nil, nil)
+	if err != nil && !ociapi.IsNotFound(err) {
+		return fmt.Errorf("failed to delete container instance %s: %w", id, err)
+	}
+	return nil
+}
+
+// waitForInstance polls the container instance until it is active.
+func (p *Provider) waitForInstance(ctx context.Context, instance *containerInstance) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(10 * time.Minute)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for container instance to be ready")
+		case <-ticker.C:
+			if err := p.containerInstances.Do(ctx, http.MethodGet, "/20210415/containerInstances/"+instance.ID, nil, instance); err != nil {
+				return fmt.Errorf("failed to get container instance status: %w", err)
+			}
+			logging.Infof("Container instance status: %s", instance.LifecycleState)
+
+			switch instance.LifecycleState {
+			case "ACTIVE":
+				return nil
+			case "FAILED", "DELETED":
+				return fmt.Errorf("container instance provisioning failed: %s", instance.LifecycleDetails)
+			}
+		}
+	}
+}
+
+// availabilityDomain returns the configured availability domain or the
+// region's first one.
+func (p *Provider) availabilityDomain(ctx context.Context, m *manifest.Manifest) (string, error) {
+	if m.OCI != nil && m.OCI.AvailabilityDomain != "" {
+		return m.OCI.AvailabilityDomain, nil
+	}
+
+	var domains []struct {
+		Name string `json:"name"`
+	}
+	query := url.Values{"compartmentId": {p.tenancyID}}
+	if err := p.identity.Do(ctx, http.MethodGet, "/20160918/availabilityDomains?"+query.Encode(), nil, &domains); err != nil {
+		return "", fmt.Errorf("failed to list availability domains: %w", err)
+	}
+	if len(domains) == 0 {
+		return "", fmt.Errorf("no availability domains found in %s", p.region)
+	}
+	return domains[0].Name, nil
+}
+
+// instanceURL returns the URL of the primary container, using the instance's
+// public IP address (or private one, without a public IP).
+func (p *Provider) instanceURL(ctx context.Context, m *manifest.Manifest, instance *containerInstance) (string, error) {
+	if len(instance.Vnics) == 0 {
+		return "", nil
+	}
+
+	var vnic struct {
+		PublicIP  string `json:"publicIp"`
+		PrivateIP string `json:"privateIp"`
+	}
+	if err := p.core.Do(ctx, http.MethodGet, "/20160918/vnics/"+instance.Vnics[0].VnicID, nil, &vnic); err != nil {
+		return "", fmt.Errorf("failed to get container instance VNIC: %w", err)
+	}
+
+	host := vnic.PublicIP
+	if host == "" {
+		host = vnic.PrivateIP
+	}
+	if host == "" {
+		return "", nil
+	}
+	if port := containerPort(m.GetPrimaryContainer()); port != 80 {
+		host += ":" + strconv.Itoa(port)
+	}
+	return "http://" + host, nil
+}
+
+// findPreviousImage returns the image in the same OCIR repository as current
+// with the newest deploy tag older than current's.
+func (p *Provider) findPreviousImage(ctx context.Context, current string) (string, error) {
+	repository, tag, err := parseImageURL(current)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{"compartmentId": {p.compartmentID}, "repositoryName": {repository}}
+	var list struct {
+		Items []struct {
+			Version string `json:"version"`
+		} `json:"items"`
+	}
+	if err := p.artifacts.Do(ctx, http.MethodGet, "/20160918/container/images?"+query.Encode(), nil, &list); err != nil {
+		return "", fmt.Errorf("failed to list images in %s: %w", repository, err)
+	}
+
+	tags := make([]string, 0, len(list.Items))
+	for _, image := range list.Items {
+		tags = append(tags, image.Version)
+	}
+	previous, err := previousDeployTag(tags, tag)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(current, tag) + previous, nil
+}
+
+// previousDeployTag returns the newest deploy tag older than current.
+// deploy-YYYYMMDDTHHMMSS tags sort chronologically.
+func previousDeployTag(tags []string, current string) (string, error) {
+	var previous string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "deploy-") && tag < current && tag > previous {
+			previous = tag
+		}
+	}
+	if previous == "" {
+		return "", fmt.Errorf("no previous deployment found to roll back to")
+	}
+	return previous, nil
+}
+
+// parseImageURL splits an OCIR image URL (<region>.ocir.io/<namespace>/<repository>:<tag>)
+// into its repository and tag.
+func parseImageURL(image string) (string, string, error) {
+	parts := strings.SplitN(image, "/", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("invalid OCIR image: %s", image)
+	}
+	repository, tag, ok := strings.Cut(parts[2], ":")
+	if !ok || repository == "" || tag == "" {
+		return "", "", fmt.Errorf("invalid OCIR image: %s", image)
+	}
+	return repository, tag, nil
+}
+
+// deployContainers returns the containers to run: the manifest's containers,
+// or the single container described by image.
+func deployContainers(m *manifest.Manifest) []manifest.Container {
+	if m.IsMultiContainer() {
+		return m.Containers
+	}
+	return []manifest.Container{m.GetPrimaryContainer()}
+}
+
+// repositoryName returns the OCIR repository for a container's image. OCIR
+// repository names are lowercase; multi-container applications get one
+// repository per container under the application name.
+func repositoryName(m *manifest.Manifest, c manifest.Container) string {
+	name := m.RepositoryName()
+	if m.IsMultiContainer() {
+		name += "/" + c.Name
+	}
+	return strings.ToLower(name)
+}
+
+// containerPort returns the first port a container exposes, defaulting to 80.
+func containerPort(c manifest.Container) int {
+	if len(c.Ports) > 0 && c.Ports[0].ContainerPort > 0 {
+		return c.Ports[0].ContainerPort
+	}
+	return 80
+}
+
+// instanceDetails builds the request to create a container instance running
+// images, keyed by container name, pulled from OCIR with the given login.
+func (p *Provider) instanceDetails(m *manifest.Manifest, availabilityDomain string, images map[string]string, namespace, username string) map[string]interface{} {
+	shape, ocpus, memoryGB := defaultShape, defaultOCPUs, defaultMemoryGB
+	publicIP := true
+	if cfg := m.OCI; cfg != nil {
+		if cfg.Shape != "" {
+			shape = cfg.Shape
+		}
+		if cfg.OCPUs > 0 {
+			ocpus = cfg.OCPUs
+		}
+		if cfg.MemoryGB > 0 {
+			memoryGB = cfg.MemoryGB
+		}
+		if cfg.PublicIP != nil {
+			publicIP = *cfg.PublicIP
+		}
+	}
+
+	primary := m.GetPrimaryContainer()
+	var containers []map[string]interface{}
+	for _, c := range deployContainers(m) {
+		env := make(map[string]string)
+		for k, v := range m.EnvironmentVariables {
+			env[k] = v
+		}
+		for k, v := range c.Environment {
+			env[k] = v
+		}
+
+		details := map[string]interface{}{
+			"displayName": c.Name,
+			"imageUrl":    images[c.Name],
+		}
+		if len(env) > 0 {
+			details["environmentVariables"] = env
+		}
+		if len(c.Command) > 0 {
+			details["command"] = c.Command
+		}
+		if len(c.Args) > 0 {
+			details["arguments"] = c.Args
+		}
+		if c.Workdir != "" {
+			details["workingDirectory"] = c.Workdir
+		}
+		if sc := securityContext(m.Security); sc != nil {
+			details["securityContext"] = sc
+		}
+
+		// Apply health check configuration to the primary container
+		if c.Name == primary.Name && m.HealthCheck.Path != "" {
+			details["healthChecks"] = []map[string]interface{}{{
+				"healthCheckType":       "HTTP",
+				"path":                  m.HealthCheck.Path,
+				"port":                  containerPort(c),
+				"intervalInSeconds":     10,
+				"failureThreshold":      3,
+				"initialDelayInSeconds": 5,
+			}}
+			logging.Infof("Configured health check with path: %s", m.HealthCheck.Path)
+		}
+		containers = append(containers, details)
+	}
+
+	tags := map[string]string{
+		"ManagedBy":   "cloud-deploy",
+		"Application": m.Application.Name,
+	}
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+
+	var subnetID string
+	if m.OCI != nil {
+		subnetID = m.OCI.SubnetID
+	}
+
+	return map[string]interface{}{
+		"compartmentId":      p.compartmentID,
+		"availabilityDomain": availabilityDomain,
+		"displayName":        m.Environment.Name,
+		"shape":              shape,
+		"shapeConfig": map[string]interface{}{
+			"ocpus":       ocpus,
+			"memoryInGBs": memoryGB,
+		},
+		"containers": containers,
+		"vnics": []map[string]interface{}{{
+			"subnetId":           subnetID,
+			"isPublicIpAssigned": publicIP,
+		}},
+		"imagePullSecrets": []map[string]interface{}{{
+			"secretType":       "BASIC",
+			"registryEndpoint": p.region + ".ocir.io",
+			"username":         base64.StdEncoding.EncodeToString([]byte(namespace + "/" + username)),
+			"password":         base64.StdEncoding.EncodeToString([]byte(p.authToken)),
+		}},
+		"containerRestartPolicy": "ALWAYS",
+		"freeformTags":           tags,
+	}
+}
+
+// securityContext converts security hardening options to a container security context.
+func securityContext(sec *manifest.SecurityConfig) map[string]interface{} {
+	if sec == nil {
+		return nil
+	}
+	sc := map[string]interface{}{"securityContextType": "LINUX"}
+	if sec.ReadOnlyRootFilesystem {
+		sc["isRootFileSystemReadonly"] = true
+	}
+	if sec.RunAsNonRoot {
+		sc["isNonRootUserCheckEnabled"] = true
+	}
+	if sec.User != "" {
+		user, group, _ := strings.Cut(sec.User, ":")
+		if uid, err := strconv.Atoi(user); err == nil {
+			sc["runAsUser"] = uid
+		} else {
+			logging.Warnf("security.user %q is not numeric; OCI Container Instances only accept user IDs and it will be ignored", sec.User)
+		}
+		if gid, err := strconv.Atoi(group); err == nil {
+			sc["runAsGroup"] = gid
+		}
+	}
+	if len(sec.DropCapabilities) > 0 {
+		sc["capabilities"] = map[string]interface{}{"dropCapabilities": sec.DropCapabilities}
+	}
+	return sc
+}
+
+// validateImagePlatforms fails early when an image cannot run on the
+// x86 shapes that Deploy defaults to.
+func validateImagePlatforms(ctx context.Context, m *manifest.Manifest) error {
+	if m.OCI != nil && strings.Contains(m.OCI.Shape, ".A1.") {
+		return nil // Ampere (Arm) shapes
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidatePlatform(ctx, image, registry.LinuxAMD64, "OCI Container Instances", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSecurity fails if an image runs as root when run_as_non_root is set.
+// OCI also enforces this at startup, but checking first avoids a failed instance.
+func validateSecurity(ctx context.Context, m *manifest.Manifest) error {
+	if m.Security == nil || !m.Security.RunAsNonRoot {
+		return nil
+	}
+	for _, image := range m.Images() {
+		if err := registry.ValidateNonRoot(ctx, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
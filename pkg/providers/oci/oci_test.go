@@ -0,0 +1,408 @@
+package oci
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/ociapi"
+)
+
+// fakeOCI serves the subset of the OCI APIs the provider uses.
+type fakeOCI struct {
+	mu        sync.Mutex
+	instances []containerInstance
+	images    map[string]string // container ID -> image URL
+	created   map[string]interface{}
+	requests  []string
+}
+
+func (f *fakeOCI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && path == "/n/":
+		w.Write([]byte(`"ns"`))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/20160918/users/"):
+		w.Write([]byte(`{"name":"jane@example.com"}`))
+	case r.Method == http.MethodGet && path == "/20160918/availabilityDomains":
+		w.Write([]byte(`[{"name":"Uocm:PHX-AD-1"},{"name":"Uocm:PHX-AD-2"}]`))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/20160918/vnics/"):
+		w.Write([]byte(`{"publicIp":"203.0.113.10","privateIp":"10.0.0.5"}`))
+	case r.Method == http.MethodGet && path == "/20160918/container/images":
+		w.Write([]byte(`{"items":[{"version":"deploy-20240101T000000"},{"version":"deploy-20240201T000000"},{"version":"deploy-20240301T000000"},{"version":"latest"}]}`))
+	case r.Method == http.MethodGet && path == "/20210415/containerInstances":
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": f.instances})
+	case r.Method == http.MethodPost && path == "/20210415/containerInstances":
+		json.NewDecoder(r.Body).Decode(&f.created)
+		instance := containerInstance{
+			ID:             "ocid1.new",
+			DisplayName:    f.created["displayName"].(string),
+			LifecycleState: "CREATING",
+			Containers:     []instanceRef{{ContainerID: "ocid1.container.new", DisplayName: "my-app"}},
+			Vnics:          []vnicRef{{VnicID: "ocid1.vnic"}},
+			TimeCreated:    time.Now(),
+		}
+		f.instances = append(f.instances, instance)
+		json.NewEncoder(w).Encode(instance)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/20210415/containerInstances/"):
+		id := strings.TrimPrefix(path, "/20210415/containerInstances/")
+		for i := range f.instances {
+			if f.instances[i].ID == id {
+				f.instances[i].LifecycleState = strings.Replace(f.instances[i].LifecycleState, "CREATING", "ACTIVE", 1)
+				json.NewEncoder(w).Encode(f.instances[i])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/20210415/containerInstances/"):
+		id := strings.TrimPrefix(path, "/20210415/containerInstances/")
+		for i := range f.instances {
+			if f.instances[i].ID == id {
+				f.instances[i].LifecycleState = "DELETING"
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/actions/stop"):
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/actions/retrieveLogs"):
+		w.Write([]byte("listening on :8080\nready\n"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/20210415/containers/"):
+		id := strings.TrimPrefix(path, "/20210415/containers/")
+		json.NewEncoder(w).Encode(container{ID: id, DisplayName: "my-app", ImageURL: f.images[id]})
+	case r.Method == http.MethodGet && path == "/20160918/container/repositories":
+		w.Write([]byte(`{"items":[{"id":"ocid1.repo","displayName":"my-app"}]}`))
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/20160918/container/repositories/"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"NotFound","message":"unexpected request"}`))
+	}
+}
+
+func (f *fakeOCI) requested(request string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestProvider(t *testing.T, fake *fakeOCI) *Provider {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	oldInterval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = oldInterval })
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ociapi.New(&ociapi.Config{TenancyID: "t", UserID: "u", Fingerprint: "f", PrivateKey: key}, server.URL)
+	return &Provider{
+		region:             "us-phoenix-1",
+		compartmentID:      "ocid1.compartment",
+		tenancyID:          "ocid1.tenancy",
+		userID:             "ocid1.user",
+		containerInstances: client,
+		core:               client,
+		identity:           client,
+		objectStorage:      client,
+		artifacts:          client,
+		authToken:          "token",
+	}
+}
+
+func testManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Image:       "my-app:latest",
+		Provider:    manifest.ProviderConfig{Name: "oci", Region: "us-phoenix-1", CompartmentID: "ocid1.compartment"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		OCI:         &manifest.OCIConfig{SubnetID: "ocid1.subnet"},
+	}
+}
+
+func TestProviderName(t *testing.T) {
+	p := &Provider{}
+	if p.Name() != "oci" {
+		t.Errorf("Expected provider name 'oci', got '%s'", p.Name())
+	}
+}
+
+func writeKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewFromManifestCredentials(t *testing.T) {
+	t.Setenv("OCI_AUTH_TOKEN", "env-token")
+	config := &manifest.ProviderConfig{
+		Name:          "oci",
+		Region:        "us-ashburn-1",
+		CompartmentID: "ocid1.compartment",
+		TenancyID:     "ocid1.tenancy",
+		Credentials: &manifest.CredentialsConfig{
+			Source: "manifest",
+			OCI: &manifest.OCICredentialsConfig{
+				UserID:         "ocid1.user",
+				Fingerprint:    "aa:bb",
+				PrivateKeyPath: writeKey(t),
+			},
+		},
+	}
+	p, err := New(context.Background(), config, testManifest())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.containerInstances.Endpoint() != "https://compute-containers.us-ashburn-1.oci.oraclecloud.com" {
+		t.Errorf("unexpected endpoint: %s", p.containerInstances.Endpoint())
+	}
+	if p.authToken != "env-token" {
+		t.Errorf("expected auth token from OCI_AUTH_TOKEN, got %q", p.authToken)
+	}
+
+	config.TenancyID = ""
+	if _, err := New(context.Background(), config, testManifest()); err == nil {
+		t.Error("expected error without a tenancy")
+	}
+}
+
+func TestNewFromConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	config := "[DEFAULT]\nuser=ocid1.user\nfingerprint=aa:bb\nkey_file=" + writeKey(t) + "\ntenancy=ocid1.tenancy\nregion=us-phoenix-1\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(context.Background(), &manifest.ProviderConfig{
+		Name:          "oci",
+		CompartmentID: "ocid1.compartment",
+		Credentials:   &manifest.CredentialsConfig{OCI: &manifest.OCICredentialsConfig{ConfigFile: path, AuthToken: "token"}},
+	}, testManifest())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.region != "us-phoenix-1" || p.tenancyID != "ocid1.tenancy" || p.authToken != "token" {
+		t.Errorf("unexpected provider: %+v", p)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(context.Background(), &manifest.ProviderConfig{Name: "oci"}, testManifest()); err == nil {
+		t.Error("expected error without compartment")
+	}
+	_, err := New(context.Background(), &manifest.ProviderConfig{
+		Name:          "oci",
+		CompartmentID: "c",
+		Credentials:   &manifest.CredentialsConfig{Source: "vault"},
+	}, testManifest())
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected unsupported source error, got %v", err)
+	}
+}
+
+func TestReplaceInstance(t *testing.T) {
+	fake := &fakeOCI{instances: []containerInstance{
+		{ID: "ocid1.old", DisplayName: "my-app-prod", LifecycleState: "ACTIVE", TimeCreated: time.Now().Add(-time.Hour)},
+		{ID: "ocid1.gone", DisplayName: "my-app-prod", LifecycleState: "DELETED"},
+	}}
+	p := newTestProvider(t, fake)
+	m := testManifest()
+	m.HealthCheck.Path = "/health"
+	m.Ports = []manifest.PortMapping{{ContainerPort: 8080}}
+
+	instance, err := p.replaceInstance(context.Background(), m, map[string]string{"my-app": "us-phoenix-1.ocir.io/ns/my-app:deploy-1"}, "ns", "jane")
+	if err != nil {
+		t.Fatalf("replaceInstance failed: %v", err)
+	}
+	if instance.LifecycleState != "ACTIVE" {
+		t.Errorf("expected active instance, got %s", instance.LifecycleState)
+	}
+	if !fake.requested("DELETE /20210415/containerInstances/ocid1.old") {
+		t.Error("expected the old instance to be deleted")
+	}
+	if fake.requested("DELETE /20210415/containerInstances/ocid1.gone") {
+		t.Error("expected deleted instances to be ignored")
+	}
+
+	if fake.created["availabilityDomain"] != "Uocm:PHX-AD-1" || fake.created["shape"] != defaultShape {
+		t.Errorf("unexpected instance details: %v", fake.created)
+	}
+	containers := fake.created["containers"].([]interface{})
+	c := containers[0].(map[string]interface{})
+	if c["imageUrl"] != "us-phoenix-1.ocir.io/ns/my-app:deploy-1" {
+		t.Errorf("unexpected container: %v", c)
+	}
+	check := c["healthChecks"].([]interface{})[0].(map[string]interface{})
+	if check["path"] != "/health" || check["port"] != float64(8080) {
+		t.Errorf("unexpected health check: %v", check)
+	}
+
+	url, err := p.instanceURL(context.Background(), m, instance)
+	if err != nil || url != "http://203.0.113.10:8080" {
+		t.Errorf("instanceURL() = %q, %v", url, err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake := &fakeOCI{instances: []containerInstance{{
+		ID: "ocid1.ci", DisplayName: "my-app-prod", LifecycleState: "ACTIVE",
+		Vnics: []vnicRef{{VnicID: "ocid1.vnic"}}, TimeUpdated: updated,
+	}}}
+	p := newTestProvider(t, fake)
+
+	status, err := p.Status(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Status != "ACTIVE" || status.URL != "http://203.0.113.10" || status.LastUpdated != "2024-03-01T12:00:00Z" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestStatusNotDeployed(t *testing.T) {
+	p := newTestProvider(t, &fakeOCI{})
+	if _, err := p.Status(context.Background(), testManifest()); err == nil || !strings.Contains(err.Error(), "no container instance") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	fake := &fakeOCI{instances: []containerInstance{{ID: "ocid1.ci", DisplayName: "my-app-prod", LifecycleState: "ACTIVE"}}}
+	p := newTestProvider(t, fake)
+	if err := p.Stop(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if !fake.requested("POST /20210415/containerInstances/ocid1.ci/actions/stop") {
+		t.Error("expected stop action")
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	fake := &fakeOCI{instances: []containerInstance{{ID: "ocid1.ci", DisplayName: "my-app-prod", LifecycleState: "INACTIVE"}}}
+	p := newTestProvider(t, fake)
+	if err := p.Destroy(context.Background(), testManifest()); err != nil {
+		t.Fatalf("Destroy failed: %v", err)
+	}
+	if !fake.requested("DELETE /20210415/containerInstances/ocid1.ci") {
+		t.Error("expected container instance to be deleted")
+	}
+	if !fake.requested("DELETE /20160918/container/repositories/ocid1.repo") {
+		t.Error("expected repository to be deleted")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fake := &fakeOCI{
+		instances: []containerInstance{{
+			ID: "ocid1.ci", DisplayName: "my-app-prod", LifecycleState: "ACTIVE",
+			Containers: []instanceRef{{ContainerID: "ocid1.container", DisplayName: "my-app"}},
+		}},
+		images: map[string]string{"ocid1.container": "us-phoenix-1.ocir.io/ns/my-app:deploy-20240201T000000"},
+	}
+	p := newTestProvider(t, fake)
+
+	result, err := p.Rollback(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if !strings.Contains(result.Message, "us-phoenix-1.ocir.io/ns/my-app:deploy-20240101T000000") {
+		t.Errorf("unexpected rollback result: %+v", result)
+	}
+	c := fake.created["containers"].([]interface{})[0].(map[string]interface{})
+	if c["imageUrl"] != "us-phoenix-1.ocir.io/ns/my-app:deploy-20240101T000000" {
+		t.Errorf("unexpected rollback image: %v", c["imageUrl"])
+	}
+}
+
+func TestPreviousDeployTag(t *testing.T) {
+	tags := []string{"deploy-20240101T000000", "deploy-20240301T000000", "deploy-20240201T000000", "latest"}
+
+	got, err := previousDeployTag(tags, "deploy-20240301T000000")
+	if err != nil || got != "deploy-20240201T000000" {
+		t.Errorf("previousDeployTag() = %q, %v", got, err)
+	}
+
+	// After a rollback, the newer tags are skipped
+	got, err = previousDeployTag(tags, "deploy-20240201T000000")
+	if err != nil || got != "deploy-20240101T000000" {
+		t.Errorf("previousDeployTag() = %q, %v", got, err)
+	}
+
+	if _, err := previousDeployTag(tags, "deploy-20240101T000000"); err == nil {
+		t.Error("expected error without an older deployment")
+	}
+}
+
+func TestParseImageURL(t *testing.T) {
+	repo, tag, err := parseImageURL("us-phoenix-1.ocir.io/ns/my-app/web:deploy-1")
+	if err != nil || repo != "my-app/web" || tag != "deploy-1" {
+		t.Errorf("parseImageURL() = %q, %q, %v", repo, tag, err)
+	}
+	if _, _, err := parseImageURL("my-app:latest"); err == nil {
+		t.Error("expected error for non-OCIR image")
+	}
+}
+
+func TestRepositoryName(t *testing.T) {
+	m := testManifest()
+	m.Application.Name = "My-App"
+	if got := repositoryName(m, m.GetPrimaryContainer()); got != "my-app" {
+		t.Errorf("repositoryName() = %q", got)
+	}
+
+	m.Image = ""
+	m.Containers = []manifest.Container{{Name: "web", Image: "web:1"}}
+	if got := repositoryName(m, m.Containers[0]); got != "my-app/web" {
+		t.Errorf("repositoryName() = %q", got)
+	}
+}
+
+func TestSecurityContext(t *testing.T) {
+	if securityContext(nil) != nil {
+		t.Error("expected no security context without security settings")
+	}
+	sc := securityContext(&manifest.SecurityConfig{
+		ReadOnlyRootFilesystem: true,
+		RunAsNonRoot:           true,
+		User:                   "1000:2000",
+		DropCapabilities:       []string{"ALL"},
+	})
+	if sc["isRootFileSystemReadonly"] != true || sc["isNonRootUserCheckEnabled"] != true ||
+		sc["runAsUser"] != 1000 || sc["runAsGroup"] != 2000 {
+		t.Errorf("unexpected security context: %v", sc)
+	}
+}
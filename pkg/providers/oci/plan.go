@@ -0,0 +1,41 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/registry"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Plan returns the changes Deploy would make for the manifest without changing
+// any OCI resources. Container instances cannot be updated in place, so every
+// deploy creates a new instance and deletes the ones it replaces.
+func (p *Provider) Plan(ctx context.Context, m *manifest.Manifest) (*types.Plan, error) {
+	plan := &types.Plan{Provider: "oci"}
+
+	for _, c := range deployContainers(m) {
+		repo := repositoryName(m, c)
+		id, err := registry.FindOCIRRepository(ctx, p.artifacts, p.compartmentID, repo)
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			plan.Add(types.PlanNoChange, "OCIR repository", repo, "")
+		} else {
+			plan.Add(types.PlanCreate, "OCIR repository", repo, "repository does not exist")
+		}
+		plan.Add(types.PlanCreate, "Container image", repo+":deploy-<timestamp>", "push "+c.Image)
+	}
+
+	previous, err := p.findInstances(ctx, m.Environment.Name)
+	if err != nil {
+		return nil, err
+	}
+	plan.Add(types.PlanCreate, "Container instance", m.Environment.Name, "container instances are replaced on every deploy")
+	for _, old := range previous {
+		plan.Add(types.PlanDelete, "Container instance", old.ID, fmt.Sprintf("replaced by the new %s instance", m.Environment.Name))
+	}
+	return plan, nil
+}
@@ -0,0 +1,43 @@
+package oci
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func TestPlan(t *testing.T) {
+	fake := &fakeOCI{instances: []containerInstance{{ID: "ocid1.old", DisplayName: "my-app-prod", LifecycleState: "ACTIVE"}}}
+	p := newTestProvider(t, fake)
+
+	plan, err := p.Plan(context.Background(), testManifest())
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	want := []types.ResourceChange{
+		{Action: types.PlanNoChange, Type: "OCIR repository", Name: "my-app"},
+		{Action: types.PlanCreate, Type: "Container image", Name: "my-app:deploy-<timestamp>"},
+		{Action: types.PlanCreate, Type: "Container instance", Name: "my-app-prod"},
+		{Action: types.PlanDelete, Type: "Container instance", Name: "ocid1.old"},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(plan.Changes), len(want), plan.Changes)
+	}
+	for i, w := range want {
+		got := plan.Changes[i]
+		if got.Action != w.Action || got.Type != w.Type || got.Name != w.Name {
+			t.Errorf("change %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	// Planning must never change anything
+	for _, r := range fake.requests {
+		if !strings.HasPrefix(r, http.MethodGet+" ") {
+			t.Errorf("Plan made a %s request", r)
+		}
+	}
+}
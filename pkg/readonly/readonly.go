@@ -0,0 +1,213 @@
+// Package readonly refuses the API requests that would change anything, so
+// auditors and on-call viewers can run status, logs, and history with
+// production credentials. Read-only mode is turned on once, with -read-only
+// or read_only in the user configuration, and is enforced by the clients
+// cloud-deploy constructs: HTTP transports, AWS SDK middleware, gRPC
+// interceptors, and Azure pipeline policies all consult Enabled on every
+// request, so no code path can write while it is on.
+//
+// Reads are recognized by the operation's name (DescribeEnvironments,
+// ListServices, getIamPolicy, ...) or, for plain HTTP APIs, by the method:
+// GET, HEAD, and OPTIONS. Credential exchanges with token endpoints are
+// allowed so clients can authenticate.
+package readonly
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"google.golang.org/grpc"
+)
+
+var enabled atomic.Bool
+
+// Enable turns read-only mode on for the rest of the process.
+func Enable() {
+	enabled.Store(true)
+}
+
+// Enabled reports whether read-only mode is on.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// ErrReadOnly is the error of every refused request.
+var ErrReadOnly = errors.New("read-only mode")
+
+// Error is a request refused in read-only mode.
+type Error struct {
+	// Operation that was refused, e.g. "UpdateEnvironment" or "PUT example.com/v2/apps/1"
+	Operation string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("read-only mode: refusing %s", e.Operation)
+}
+
+// Is makes errors.Is(err, ErrReadOnly) true for refused requests.
+func (e *Error) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// readPrefixes start the names of operations that only read.
+var readPrefixes = []string{"describe", "list", "get", "batchget", "check", "filter", "lookup", "search", "head"}
+
+// authOperations exchange credentials for tokens without changing anything.
+var authOperations = map[string]bool{
+	"AssumeRole":                true,
+	"AssumeRoleWithWebIdentity": true,
+	"AssumeRoleWithSAML":        true,
+	"CreateToken":               true,
+}
+
+// IsRead reports whether the named operation only reads: its name is a read
+// verb such as Describe, List, or Get, followed by the rest of the name in
+// camel case (getIamPolicy, ListServices).
+func IsRead(operation string) bool {
+	lower := strings.ToLower(operation)
+	for _, prefix := range readPrefixes {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		rest := operation[len(prefix):]
+		if rest == "" || !unicode.IsLower(rune(rest[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOperation returns an *Error in read-only mode when the named operation
+// does not only read.
+func CheckOperation(operation string) error {
+	if !Enabled() || IsRead(operation) || authOperations[operation] {
+		return nil
+	}
+	return &Error{Operation: operation}
+}
+
+// Check returns an *Error in read-only mode when req would change anything.
+// The operation of AWS Query (Action) and JSON (X-Amz-Target) requests, of
+// Google custom methods (":getIamPolicy"), and of Azure actions
+// ("/listKeys") decides; other requests must be GET, HEAD, or OPTIONS, or go
+// to a token endpoint.
+func Check(req *http.Request) error {
+	if !Enabled() {
+		return nil
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+	if isTokenEndpoint(req.URL) {
+		return nil
+	}
+	operation, err := requestOperation(req)
+	if err != nil {
+		return err
+	}
+	if operation != "" {
+		return CheckOperation(operation)
+	}
+	return &Error{Operation: fmt.Sprintf("%s %s%s", req.Method, req.URL.Host, req.URL.Path)}
+}
+
+// isTokenEndpoint reports whether u exchanges credentials for tokens, as
+// OAuth 2.0 token endpoints and container registry token exchanges do.
+func isTokenEndpoint(u *url.URL) bool {
+	return strings.HasSuffix(u.Path, "/token") || strings.HasSuffix(u.Path, "/oauth2/exchange")
+}
+
+// requestOperation returns the name of the operation req calls, if it has
+// one. Form bodies are read and restored.
+func requestOperation(req *http.Request) (string, error) {
+	if target := req.Header.Get("X-Amz-Target"); target != "" {
+		return target[strings.LastIndex(target, ".")+1:], nil
+	}
+	if i := strings.LastIndex(path.Base(req.URL.Path), ":"); i >= 0 {
+		return path.Base(req.URL.Path)[i+1:], nil
+	}
+	if segment := path.Base(req.URL.Path); IsRead(segment) {
+		return segment, nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "application/x-www-form-urlencoded" || req.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", nil
+	}
+	return form.Get("Action"), nil
+}
+
+// roundTripper refuses requests Check rejects.
+type roundTripper struct {
+	next http.RoundTripper
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := Check(req); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Transport returns next, or http.DefaultTransport when next is nil, refusing
+// the requests Check rejects while read-only mode is on.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripper{next: next}
+}
+
+// AWSAPIOption is an AWS SDK API option (aws.Config.APIOptions) that refuses
+// operations CheckOperation rejects before they are sent.
+func AWSAPIOption(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ReadOnly", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		if err := CheckOperation(awsmiddleware.GetOperationName(ctx)); err != nil {
+			return middleware.InitializeOutput{}, middleware.Metadata{}, err
+		}
+		return next.HandleInitialize(ctx, in)
+	}), middleware.Before)
+}
+
+// UnaryClientInterceptor refuses the gRPC methods CheckOperation rejects,
+// e.g. /google.cloud.run.v2.Services/UpdateService.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := CheckOperation(path.Base(method)); err != nil {
+		return err
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// AzurePolicy is an Azure SDK pipeline policy refusing the requests Check
+// rejects.
+type AzurePolicy struct{}
+
+// Do checks the request and passes it on.
+func (AzurePolicy) Do(req *policy.Request) (*http.Response, error) {
+	if err := Check(req.Raw()); err != nil {
+		return nil, err
+	}
+	return req.Next()
+}
@@ -0,0 +1,168 @@
+package readonly
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// enableForTest turns read-only mode on until the test ends.
+func enableForTest(t *testing.T) {
+	t.Helper()
+	Enable()
+	t.Cleanup(func() { enabled.Store(false) })
+}
+
+func TestIsRead(t *testing.T) {
+	reads := []string{"DescribeEnvironments", "ListServices", "getIamPolicy", "GetObject", "BatchGetImage", "CheckDNSAvailability", "FilterLogEvents", "LookupEvents", "HeadObject", "listKeys", "get"}
+	for _, op := range reads {
+		if !IsRead(op) {
+			t.Errorf("IsRead(%q) = false, want true", op)
+		}
+	}
+	writes := []string{"UpdateEnvironment", "CreateService", "setIamPolicy", "DeleteObject", "Listener", "Getaway", "PutImage", "checkout", ""}
+	for _, op := range writes {
+		if IsRead(op) {
+			t.Errorf("IsRead(%q) = true, want false", op)
+		}
+	}
+}
+
+func TestCheckOperation(t *testing.T) {
+	if err := CheckOperation("TerminateEnvironment"); err != nil {
+		t.Errorf("Expected no check while read-only mode is off, got: %v", err)
+	}
+	enableForTest(t)
+
+	for _, op := range []string{"DescribeEnvironments", "AssumeRole", "CreateToken"} {
+		if err := CheckOperation(op); err != nil {
+			t.Errorf("CheckOperation(%q) = %v, want nil", op, err)
+		}
+	}
+	err := CheckOperation("TerminateEnvironment")
+	if !errors.Is(err, ErrReadOnly) || err.Error() != "read-only mode: refusing TerminateEnvironment" {
+		t.Errorf("CheckOperation(TerminateEnvironment) = %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	enableForTest(t)
+
+	newRequest := func(method, url, body string) *http.Request {
+		return httptest.NewRequest(method, url, strings.NewReader(body))
+	}
+	tests := []struct {
+		name    string
+		req     func() *http.Request
+		wantErr string
+	}{
+		{name: "POST", req: func() *http.Request { return newRequest(http.MethodPost, "https://api.example.com/v2/apps", "") }, wantErr: "refusing POST api.example.com/v2/apps"},
+		{name: "DELETE", req: func() *http.Request { return newRequest(http.MethodDelete, "https://api.example.com/v2/apps/1", "") }, wantErr: "refusing DELETE api.example.com/v2/apps/1"},
+		{name: "HEAD", req: func() *http.Request { return newRequest(http.MethodHead, "https://api.example.com/v2/apps", "") }},
+		{name: "token", req: func() *http.Request {
+			return newRequest(http.MethodPost, "https://oauth2.googleapis.com/token", "grant_type=x")
+		}},
+		{name: "registry token", req: func() *http.Request {
+			return newRequest(http.MethodPost, "https://myregistry.azurecr.io/oauth2/exchange", "")
+		}},
+		{name: "Google custom read", req: func() *http.Request {
+			return newRequest(http.MethodPost, "https://run.googleapis.com/v2/projects/p/services/s:getIamPolicy", "{}")
+		}},
+		{name: "Google custom write", req: func() *http.Request {
+			return newRequest(http.MethodPost, "https://run.googleapis.com/v2/projects/p/services/s:setIamPolicy", "{}")
+		}, wantErr: "refusing setIamPolicy"},
+		{name: "Azure action", req: func() *http.Request {
+			return newRequest(http.MethodPost, "https://management.azure.com/subscriptions/s/providers/Microsoft.Storage/storageAccounts/a/listKeys", "")
+		}},
+		{name: "X-Amz-Target", req: func() *http.Request {
+			req := newRequest(http.MethodPost, "https://logs.us-east-1.amazonaws.com/", "{}")
+			req.Header.Set("X-Amz-Target", "Logs_20140328.PutRetentionPolicy")
+			return req
+		}, wantErr: "refusing PutRetentionPolicy"},
+		{name: "Query Action", req: func() *http.Request {
+			req := newRequest(http.MethodPost, "https://elasticbeanstalk.us-east-1.amazonaws.com/", "Action=DescribeEnvironments&Version=2010-12-01")
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+			return req
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.req())
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected the request to be allowed, got: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckRestoresFormBody(t *testing.T) {
+	enableForTest(t)
+
+	body := "Action=TerminateEnvironment&EnvironmentName=prod"
+	req := httptest.NewRequest(http.MethodPost, "https://elasticbeanstalk.us-east-1.amazonaws.com/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := Check(req); err == nil || !strings.Contains(err.Error(), "TerminateEnvironment") {
+		t.Fatalf("Expected TerminateEnvironment to be refused, got: %v", err)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil || string(data) != body {
+		t.Errorf("Expected the body to be restored, got %q, %v", data, err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	// Off, everything passes
+	resp, err := client.Post(server.URL+"/apps", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Expected the request to pass while read-only mode is off, got: %v", err)
+	}
+	resp.Body.Close()
+
+	enableForTest(t)
+	resp, err = client.Get(server.URL + "/apps")
+	if err != nil {
+		t.Fatalf("Expected GET to pass, got: %v", err)
+	}
+	resp.Body.Close()
+	if _, err := client.Post(server.URL+"/apps", "application/json", strings.NewReader("{}")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected POST to be refused, got: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", requests)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	enableForTest(t)
+
+	var invoked []string
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		invoked = append(invoked, method)
+		return nil
+	}
+	if err := UnaryClientInterceptor(context.Background(), "/google.cloud.run.v2.Services/GetService", nil, nil, nil, invoker); err != nil {
+		t.Errorf("Expected GetService to pass, got: %v", err)
+	}
+	if err := UnaryClientInterceptor(context.Background(), "/google.cloud.run.v2.Services/UpdateService", nil, nil, nil, invoker); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected UpdateService to be refused, got: %v", err)
+	}
+	if len(invoked) != 1 {
+		t.Errorf("Expected only GetService to be invoked, got %v", invoked)
+	}
+}
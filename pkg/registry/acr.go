@@ -2,11 +2,17 @@ package registry
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerregistry/armcontainerregistry"
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -52,7 +58,9 @@ func (a *ACRRegistry) GetImageReference() string {
 	return a.imageURI
 }
 
-// GetAuthenticator returns the authenticator for ACR using admin credentials
+// GetAuthenticator returns the authenticator for ACR using admin credentials,
+// or a refresh token exchanged for the Azure AD token of the credential when
+// the admin user is disabled
 func (a *ACRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
 	// Create registries client
 	client, err := armcontainerregistry.NewRegistriesClient(a.subscriptionID, a.cred, nil)
@@ -107,12 +115,9 @@ func (a *ACRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator
 
 	// Get admin credentials
 	creds, err := client.ListCredentials(ctx, a.resourceGroup, a.registryName, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ACR credentials: %w", err)
-	}
-
-	if creds.Username == nil || len(creds.Passwords) == 0 {
-		return nil, fmt.Errorf("no admin credentials available for ACR")
+	if err != nil || creds.Username == nil || len(creds.Passwords) == 0 {
+		logging.Info("ACR admin credentials unavailable, exchanging the Azure AD token for an ACR token")
+		return exchangeACRToken(ctx, a.cred, "https://"+a.loginServer, a.loginServer)
 	}
 
 	username := *creds.Username
@@ -126,3 +131,49 @@ func (a *ACRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator
 		Password: password,
 	}, nil
 }
+
+// acrTokenUser is the user name ACR refresh tokens are presented with.
+const acrTokenUser = "00000000-0000-0000-0000-000000000000"
+
+// exchangeACRToken exchanges the Azure AD token of cred for a refresh token
+// of the registry service at endpoint, as az acr login does, so registries
+// with the admin user disabled are pushed to with role assignments such as
+// AcrPush.
+func exchangeACRToken(ctx context.Context, cred azcore.TokenCredential, endpoint, service string) (authn.Authenticator, error) {
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure AD token for ACR: %w", err)
+	}
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {service},
+		"access_token": {token.Token},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/oauth2/exchange", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange Azure AD token for ACR token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACR token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ACR token exchange failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil || out.RefreshToken == "" {
+		return nil, fmt.Errorf("ACR returned no refresh token")
+	}
+	return &authn.Basic{
+		Username: acrTokenUser,
+		Password: out.RefreshToken,
+	}, nil
+}
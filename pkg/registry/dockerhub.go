@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// DockerHubHost is the host of Docker Hub image references.
+const DockerHubHost = "docker.io"
+
+// DockerHubRegistry represents a repository of a Docker Hub user or organization
+type DockerHubRegistry struct {
+	namespace string
+	imageTag  string
+	username  string
+	token     string
+	imageURI  string
+}
+
+// NewDockerHubRegistry creates a new Docker Hub registry handler for a
+// repository of namespace, the user or organization, which defaults to
+// username. Docker Hub takes a personal or organization access token as the
+// password and creates missing repositories on push.
+func NewDockerHubRegistry(namespace, repository, imageTag, username, token string) (*DockerHubRegistry, error) {
+	if username == "" {
+		return nil, fmt.Errorf("a user name is required to push to Docker Hub")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("an access token is required to push to Docker Hub")
+	}
+	if namespace == "" {
+		namespace = username
+	}
+	return &DockerHubRegistry{
+		namespace: namespace,
+		imageTag:  imageTag,
+		username:  username,
+		token:     token,
+		imageURI:  fmt.Sprintf("%s/%s/%s:%s", DockerHubHost, namespace, repository, imageTag),
+	}, nil
+}
+
+// GetRegistryURL returns the Docker Hub namespace URL
+func (d *DockerHubRegistry) GetRegistryURL() string {
+	return DockerHubHost + "/" + d.namespace
+}
+
+// GetImageURI returns the full image URI in Docker Hub
+func (d *DockerHubRegistry) GetImageURI() string {
+	return d.imageURI
+}
+
+// GetImageReference returns the full image reference for Docker Hub
+func (d *DockerHubRegistry) GetImageReference() string {
+	return d.imageURI
+}
+
+// GetAuthenticator returns the authenticator for Docker Hub, which takes the
+// access token as the password of the user name
+func (d *DockerHubRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	return &authn.Basic{
+		Username: d.username,
+		Password: d.token,
+	}, nil
+}
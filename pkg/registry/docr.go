@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// DOCRHost is the host of the DigitalOcean Container Registry.
+const DOCRHost = "registry.digitalocean.com"
+
+// DOCRRegistry represents a repository of a DigitalOcean Container Registry
+type DOCRRegistry struct {
+	registryName string
+	repository   string
+	imageTag     string
+	token        string
+	imageURI     string
+}
+
+// NewDOCRRegistry creates a new DOCR registry handler for a repository of the
+// account's registry named registryName. The registry must exist; DOCR creates
+// missing repositories on push.
+func NewDOCRRegistry(registryName, repository, imageTag, token string) (*DOCRRegistry, error) {
+	if registryName == "" {
+		return nil, fmt.Errorf("registry name is required for DOCR")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("an API token is required to push to DOCR")
+	}
+	return &DOCRRegistry{
+		registryName: registryName,
+		repository:   repository,
+		imageTag:     imageTag,
+		token:        token,
+		imageURI:     fmt.Sprintf("%s/%s/%s:%s", DOCRHost, registryName, repository, imageTag),
+	}, nil
+}
+
+// GetRegistryURL returns the DOCR registry URL
+func (d *DOCRRegistry) GetRegistryURL() string {
+	return DOCRHost + "/" + d.registryName
+}
+
+// GetImageURI returns the full image URI in DOCR
+func (d *DOCRRegistry) GetImageURI() string {
+	return d.imageURI
+}
+
+// GetImageReference returns the full image reference for DOCR
+func (d *DOCRRegistry) GetImageReference() string {
+	return d.imageURI
+}
+
+// GetAuthenticator returns the authenticator for DOCR, which accepts the API
+// token as both user name and password
+func (d *DOCRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	return &authn.Basic{
+		Username: d.token,
+		Password: d.token,
+	}, nil
+}
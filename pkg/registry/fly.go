@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// FlyHost is the host of the Fly.io registry.
+const FlyHost = "registry.fly.io"
+
+// FlyRegistry represents the repository of a Fly.io app in the Fly.io registry
+type FlyRegistry struct {
+	app      string
+	imageTag string
+	token    string
+	imageURI string
+}
+
+// NewFlyRegistry creates a new Fly.io registry handler for the repository of
+// the named app, which must exist before images are pushed to it.
+func NewFlyRegistry(app, imageTag, token string) (*FlyRegistry, error) {
+	if app == "" {
+		return nil, fmt.Errorf("app name is required for the Fly.io registry")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("an API token is required to push to the Fly.io registry")
+	}
+	return &FlyRegistry{
+		app:      app,
+		imageTag: imageTag,
+		token:    token,
+		imageURI: fmt.Sprintf("%s/%s:%s", FlyHost, app, imageTag),
+	}, nil
+}
+
+// GetRegistryURL returns the Fly.io registry URL
+func (f *FlyRegistry) GetRegistryURL() string {
+	return FlyHost
+}
+
+// GetImageURI returns the full image URI in the Fly.io registry
+func (f *FlyRegistry) GetImageURI() string {
+	return f.imageURI
+}
+
+// GetImageReference returns the full image reference for the Fly.io registry
+func (f *FlyRegistry) GetImageReference() string {
+	return f.imageURI
+}
+
+// GetAuthenticator returns the authenticator for the Fly.io registry, which
+// takes the API token as the password of any user name
+func (f *FlyRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	return &authn.Basic{
+		Username: "x",
+		Password: f.token,
+	}, nil
+}
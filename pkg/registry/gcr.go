@@ -3,14 +3,16 @@ package registry
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
 
 	"github.com/google/go-containerregistry/pkg/authn"
-	"golang.org/x/oauth2/google"
+	ggcrgoogle "github.com/google/go-containerregistry/pkg/v1/google"
 	artifactregistry "google.golang.org/api/artifactregistry/v1"
 	"google.golang.org/api/option"
+	"google.golang.org/api/transport"
 )
 
 // GCRRegistry represents a Google Container Registry (Artifact Registry)
@@ -22,19 +24,36 @@ type GCRRegistry struct {
 	registryURL     string
 	imageURI        string
 	credentialsJSON string
+	clientOpts      []option.ClientOption
 }
 
-// NewGCRRegistry creates a new GCR registry handler
-func NewGCRRegistry(projectID, region, repositoryName, imageTag, credentialsJSON string) (*GCRRegistry, error) {
+// NewGCRRegistry creates a new GCR registry handler. The repository is
+// created and pushed to with clientOpts, the provider's Google API client
+// options, when given, so every kind of credentials the provider supports
+// works; otherwise with credentialsJSON, or Application Default Credentials
+// if it is empty.
+func NewGCRRegistry(projectID, region, repositoryName, imageTag, credentialsJSON string, clientOpts ...option.ClientOption) (*GCRRegistry, error) {
 	return &GCRRegistry{
 		projectID:       projectID,
 		region:          region,
 		repositoryName:  repositoryName,
 		imageTag:        imageTag,
 		credentialsJSON: credentialsJSON,
+		clientOpts:      clientOpts,
 	}, nil
 }
 
+// options returns the client options of the registry's credentials.
+func (g *GCRRegistry) options() []option.ClientOption {
+	if len(g.clientOpts) > 0 {
+		return g.clientOpts
+	}
+	if g.credentialsJSON != "" {
+		return []option.ClientOption{option.WithCredentialsJSON([]byte(g.credentialsJSON))}
+	}
+	return nil
+}
+
 // GetRegistryURL returns the GCR registry URL
 func (g *GCRRegistry) GetRegistryURL() string {
 	return g.registryURL
@@ -60,14 +79,8 @@ func (g *GCRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator
 	g.imageURI = fmt.Sprintf("%s/%s:%s", g.registryURL, g.repositoryName, g.imageTag)
 
 	// Create Artifact Registry client
-	var client *artifactregistry.Service
-	var err error
-
-	if g.credentialsJSON != "" {
-		client, err = artifactregistry.NewService(ctx, option.WithCredentialsJSON([]byte(g.credentialsJSON)))
-	} else {
-		client, err = artifactregistry.NewService(ctx)
-	}
+	opts := g.options()
+	client, err := artifactregistry.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Artifact Registry client: %w", err)
 	}
@@ -106,28 +119,20 @@ func (g *GCRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator
 		logging.Infof("Artifact Registry repository %s already exists", g.repositoryName)
 	}
 
-	// Get OAuth2 token source from service account credentials
-	var creds *google.Credentials
-	if g.credentialsJSON != "" {
-		creds, err = google.CredentialsFromJSON(ctx, []byte(g.credentialsJSON), "https://www.googleapis.com/auth/cloud-platform")
-	} else {
-		creds, err = google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
-	}
+	// Get OAuth2 token source from the credentials
+	creds, err := transport.Creds(ctx, append(slices.Clone(opts), option.WithScopes("https://www.googleapis.com/auth/cloud-platform"))...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Google credentials: %w", err)
 	}
 
-	// Get an OAuth2 token
-	token, err := creds.TokenSource.Token()
-	if err != nil {
+	// Get an OAuth2 token now, so missing credentials fail before the push
+	if _, err := creds.TokenSource.Token(); err != nil {
 		return nil, fmt.Errorf("failed to get OAuth2 token: %w", err)
 	}
 
 	logging.Info("Successfully retrieved GCR OAuth2 credentials")
 
-	// Return authenticator with oauth2accesstoken as username and token as password
-	return &authn.Basic{
-		Username: "oauth2accesstoken",
-		Password: token.AccessToken,
-	}, nil
+	// Return an authenticator sending oauth2accesstoken as the username and
+	// the token as the password, refreshed when pushes outlast it
+	return ggcrgoogle.NewTokenSourceAuthenticator(creds.TokenSource), nil
 }
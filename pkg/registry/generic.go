@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// GenericRegistry represents a repository of any registry implementing the
+// OCI Distribution API, such as Harbor, Quay, or Artifactory
+type GenericRegistry struct {
+	url      string
+	username string
+	password string
+	insecure bool
+	imageURI string
+}
+
+// NewGenericRegistry creates a registry handler that pushes
+// repository:imageTag under url, the registry host and an optional path
+// prefix such as a Harbor project (e.g., "harbor.example.com/platform").
+// Without a user name, the credentials of docker login and Docker credential
+// helpers are used. Insecure registries are reached over plain HTTP.
+func NewGenericRegistry(url, repository, imageTag, username, password string, insecure bool) (*GenericRegistry, error) {
+	url = strings.TrimRight(url, "/")
+	if url == "" {
+		return nil, fmt.Errorf("registry URL is required")
+	}
+	if username != "" && password == "" {
+		return nil, fmt.Errorf("a password or token is required to push to %s as %s", url, username)
+	}
+	return &GenericRegistry{
+		url:      url,
+		username: username,
+		password: password,
+		insecure: insecure,
+		imageURI: fmt.Sprintf("%s/%s:%s", url, repository, imageTag),
+	}, nil
+}
+
+// GetRegistryURL returns the registry URL
+func (g *GenericRegistry) GetRegistryURL() string {
+	return g.url
+}
+
+// GetImageURI returns the full image URI in the registry
+func (g *GenericRegistry) GetImageURI() string {
+	return g.imageURI
+}
+
+// GetImageReference returns the full image reference for the registry
+func (g *GenericRegistry) GetImageReference() string {
+	return g.imageURI
+}
+
+// Insecure reports whether the registry is reached over plain HTTP
+func (g *GenericRegistry) Insecure() bool {
+	return g.insecure
+}
+
+// GetAuthenticator returns the authenticator for the registry: the user name
+// and password, or the Docker credentials of the registry host
+func (g *GenericRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	if g.username != "" {
+		return &authn.Basic{
+			Username: g.username,
+			Password: g.password,
+		}, nil
+	}
+	host, _, _ := strings.Cut(g.url, "/")
+	reg, err := name.NewRegistry(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry host %q: %w", host, err)
+	}
+	return authn.DefaultKeychain.Resolve(reg)
+}
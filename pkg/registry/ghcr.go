@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// GHCRHost is the host of the GitHub Container Registry.
+const GHCRHost = "ghcr.io"
+
+// GHCRRegistry represents a package of a GitHub user or organization in the
+// GitHub Container Registry
+type GHCRRegistry struct {
+	owner    string
+	imageTag string
+	username string
+	token    string
+	imageURI string
+}
+
+// NewGHCRRegistry creates a new GHCR registry handler for a package of owner,
+// the GitHub user or organization. The token is a personal access token with
+// the write:packages scope, or $GITHUB_TOKEN when empty, which GitHub Actions
+// workflows get with the packages: write permission. The user name defaults
+// to $GITHUB_ACTOR, then to owner. GHCR creates missing packages on push.
+func NewGHCRRegistry(owner, repository, imageTag, username, token string) (*GHCRRegistry, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("owner is required for GHCR")
+	}
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("a personal access token or GITHUB_TOKEN is required to push to GHCR")
+	}
+	if username == "" {
+		username = os.Getenv("GITHUB_ACTOR")
+	}
+	if username == "" {
+		username = owner
+	}
+	// Image names in GHCR are lowercase, while GitHub owners need not be
+	owner = strings.ToLower(owner)
+	return &GHCRRegistry{
+		owner:    owner,
+		imageTag: imageTag,
+		username: username,
+		token:    token,
+		imageURI: fmt.Sprintf("%s/%s/%s:%s", GHCRHost, owner, repository, imageTag),
+	}, nil
+}
+
+// GetRegistryURL returns the GHCR owner URL
+func (g *GHCRRegistry) GetRegistryURL() string {
+	return GHCRHost + "/" + g.owner
+}
+
+// GetImageURI returns the full image URI in GHCR
+func (g *GHCRRegistry) GetImageURI() string {
+	return g.imageURI
+}
+
+// GetImageReference returns the full image reference for GHCR
+func (g *GHCRRegistry) GetImageReference() string {
+	return g.imageURI
+}
+
+// GetAuthenticator returns the authenticator for GHCR, which takes the token
+// as the password of the user name
+func (g *GHCRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	return &authn.Basic{
+		Username: g.username,
+		Password: g.token,
+	}, nil
+}
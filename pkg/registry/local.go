@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// LocalRegistry represents a registry without authentication, such as a
+// registry:2 container or a cloud registry emulator, that stands in for a
+// provider's registry in local testing.
+type LocalRegistry struct {
+	host     string
+	imageURI string
+}
+
+// NewLocalRegistry creates a registry handler that pushes repository:imageTag
+// to the registry at host (e.g., "localhost:5000"). Registries on localhost
+// are reached over plain HTTP.
+func NewLocalRegistry(host, repository, imageTag string) (*LocalRegistry, error) {
+	host = strings.TrimRight(host, "/")
+	if host == "" {
+		return nil, fmt.Errorf("registry host is required")
+	}
+	return &LocalRegistry{
+		host:     host,
+		imageURI: fmt.Sprintf("%s/%s:%s", host, repository, imageTag),
+	}, nil
+}
+
+// Local returns r, or a LocalRegistry for repository:imageTag at host when
+// host is set, so providers push to the registry of provider.registry_url
+// instead of their own.
+func Local(r Registry, host, repository, imageTag string) Registry {
+	if host == "" {
+		return r
+	}
+	local, _ := NewLocalRegistry(host, repository, imageTag)
+	return local
+}
+
+// GetRegistryURL returns the registry host
+func (l *LocalRegistry) GetRegistryURL() string {
+	return l.host
+}
+
+// GetImageURI returns the full image URI in the registry
+func (l *LocalRegistry) GetImageURI() string {
+	return l.imageURI
+}
+
+// GetImageReference returns the full image reference for the registry
+func (l *LocalRegistry) GetImageReference() string {
+	return l.imageURI
+}
+
+// GetAuthenticator returns the anonymous authenticator
+func (l *LocalRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	return authn.Anonymous, nil
+}
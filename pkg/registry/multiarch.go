@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// PlatformImage returns the reference image is tagged as in the Docker daemon
+// when built for platform, since the daemon keeps one platform per tag: image
+// with the platform appended to its tag (e.g., "my-app:v1-linux-arm64").
+func PlatformImage(image, platform string) string {
+	suffix := strings.ReplaceAll(platform, "/", "-")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image + "-" + suffix
+	}
+	return image + ":latest-" + suffix
+}
+
+// loadIndex assembles a multi-arch image index of the source image built for
+// each of the distributor's platforms in the Docker daemon. Unless all of them
+// are there, the source's index is pulled from its registry instead, keeping
+// only those platforms.
+func (d *Distributor) loadIndex(ctx context.Context, ref name.Reference) (v1.ImageIndex, error) {
+	platforms, err := parsePlatforms(d.platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	adds := make([]mutate.IndexAddendum, 0, len(platforms))
+	for i, platform := range platforms {
+		platformRef, err := name.ParseReference(PlatformImage(d.sourceImage, d.platforms[i]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image reference for %s: %w", d.platforms[i], err)
+		}
+		img, err := daemon.Image(platformRef, daemon.WithContext(ctx))
+		if err != nil {
+			logging.Infof("Image for %s not found in Docker daemon, pulling %s from its registry...", d.platforms[i], d.sourceImage)
+			return d.pullIndex(ctx, ref, platforms)
+		}
+		adds = append(adds, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: platform}})
+	}
+	// Images from the Docker daemon have Docker manifests, which manifest lists index
+	return mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.DockerManifestList), adds...), nil
+}
+
+// pullIndex reads the image index ref refers to from its registry, keeping
+// the images of platforms.
+func (d *Distributor) pullIndex(ctx context.Context, ref name.Reference, platforms []*v1.Platform) (v1.ImageIndex, error) {
+	auth, err := d.pullAuth(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	index, err := remote.Index(ref, auth, remote.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image index manifest: %w", err)
+	}
+
+	adds := make([]mutate.IndexAddendum, 0, len(platforms))
+	for _, platform := range platforms {
+		found := false
+		for _, desc := range manifest.Manifests {
+			if desc.Platform == nil || !desc.Platform.Satisfies(*platform) {
+				continue
+			}
+			img, err := index.Image(desc.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s image: %w", platform, err)
+			}
+			adds = append(adds, mutate.IndexAddendum{Add: img, Descriptor: desc})
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("image %s has no %s image; build it for every platform (e.g., with `docker buildx build --platform`)", ref, platform)
+		}
+	}
+	mediaType, err := index.MediaType()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image index media type: %w", err)
+	}
+	return mutate.AppendManifests(mutate.IndexMediaType(empty.Index, mediaType), adds...), nil
+}
+
+// parsePlatforms parses platforms in os/arch[/variant] form.
+func parsePlatforms(platforms []string) ([]*v1.Platform, error) {
+	parsed := make([]*v1.Platform, 0, len(platforms))
+	for _, p := range platforms {
+		platform, err := v1.ParsePlatform(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform %q: %w", p, err)
+		}
+		parsed = append(parsed, platform)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestPlatformImage(t *testing.T) {
+	tests := []struct {
+		image, platform, want string
+	}{
+		{"my-app:v1", "linux/arm64", "my-app:v1-linux-arm64"},
+		{"localhost:5000/team/my-app", "linux/amd64", "localhost:5000/team/my-app:latest-linux-amd64"},
+		{"my-app:20260304-120000", "linux/arm/v7", "my-app:20260304-120000-linux-arm-v7"},
+	}
+	for _, tt := range tests {
+		if got := PlatformImage(tt.image, tt.platform); got != tt.want {
+			t.Errorf("PlatformImage(%q, %q) = %q, want %q", tt.image, tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestDistributeMultiArch(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// A multi-arch image, only in a registry, built for more platforms than deployed
+	var adds []mutate.IndexAddendum
+	images := make(map[string]v1.Hash)
+	for _, p := range []string{"linux/amd64", "linux/arm64", "linux/s390x"} {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		platform, _ := v1.ParsePlatform(p)
+		adds = append(adds, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: platform}})
+		images[p], _ = img.Digest()
+	}
+	source := host + "/app:v1"
+	sourceRef, _ := name.ParseReference(source)
+	if err := remote.WriteIndex(sourceRef, mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), adds...)); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+
+	target := host + "/mirror/app:v1"
+	d := NewDistributor(source, "linux/amd64", "linux/arm64")
+	d.AddRegistry(&mockRegistry{registryURL: host + "/mirror", imageReference: target, imageURI: target})
+	if _, err := d.Distribute(context.Background()); err != nil {
+		t.Fatalf("Distribute returned error: %v", err)
+	}
+
+	targetRef, _ := name.ParseReference(target)
+	index, err := remote.Index(targetRef)
+	if err != nil {
+		t.Fatalf("pushed image index not found: %v", err)
+	}
+	digest, _ := index.Digest()
+	if d.Digest() != digest.String() {
+		t.Errorf("Digest() = %q, want the index digest %s", d.Digest(), digest)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Manifests) != 2 {
+		t.Fatalf("pushed index has %d images, want 2", len(manifest.Manifests))
+	}
+	for _, desc := range manifest.Manifests {
+		if p := desc.Platform.String(); images[p] != desc.Digest || p == "linux/s390x" {
+			t.Errorf("pushed %s image %s, want only the amd64 and arm64 images", p, desc.Digest)
+		}
+	}
+
+	// Deploying a platform the image was not built for fails
+	d = NewDistributor(source, "linux/amd64", "linux/arm/v7")
+	d.AddRegistry(&mockRegistry{registryURL: host + "/mirror", imageReference: target, imageURI: target})
+	if _, err := d.Distribute(context.Background()); err == nil || !strings.Contains(err.Error(), "has no linux/arm/v7 image") {
+		t.Errorf("Expected a missing platform error, got: %v", err)
+	}
+}
+
+func TestDistributeSinglePlatform(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// A multi-arch image, only in a registry, deployed for one of its platforms
+	var adds []mutate.IndexAddendum
+	images := make(map[string]v1.Image)
+	for _, p := range []string{"linux/amd64", "linux/arm64"} {
+		platform, _ := v1.ParsePlatform(p)
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, _ := img.ConfigFile()
+		cfg.OS, cfg.Architecture = platform.OS, platform.Architecture
+		if img, err = mutate.ConfigFile(img, cfg); err != nil {
+			t.Fatal(err)
+		}
+		adds = append(adds, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: platform}})
+		images[p] = img
+	}
+	source := host + "/app:v1"
+	sourceRef, _ := name.ParseReference(source)
+	if err := remote.WriteIndex(sourceRef, mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), adds...)); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+
+	target := host + "/mirror/app:v1"
+	d := NewDistributor(source, "linux/arm64")
+	d.AddRegistry(&mockRegistry{registryURL: host + "/mirror", imageReference: target, imageURI: target})
+	if _, err := d.Distribute(context.Background()); err != nil {
+		t.Fatalf("Distribute returned error: %v", err)
+	}
+	want, _ := images["linux/arm64"].Digest()
+	targetRef, _ := name.ParseReference(target)
+	pushed, err := remote.Head(targetRef)
+	if err != nil {
+		t.Fatalf("pushed image not found: %v", err)
+	}
+	if pushed.Digest != want || d.Digest() != want.String() {
+		t.Errorf("pushed %s, want the linux/arm64 image %s", pushed.Digest, want)
+	}
+
+	// A single-platform image built for another platform fails
+	amd64 := host + "/app:amd64"
+	amd64Ref, _ := name.ParseReference(amd64)
+	if err := remote.Write(amd64Ref, images["linux/amd64"]); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+	d = NewDistributor(amd64, "linux/arm64")
+	d.AddRegistry(&mockRegistry{registryURL: host + "/mirror", imageReference: target, imageURI: target})
+	if _, err := d.Distribute(context.Background()); err == nil || !strings.Contains(err.Error(), "built for linux/amd64, not linux/arm64") {
+		t.Errorf("Expected a platform mismatch error, got: %v", err)
+	}
+}
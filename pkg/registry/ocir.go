@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+	"github.com/jvreagan/cloud-deploy/pkg/ociapi"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// OCIRRegistry represents an Oracle Cloud Infrastructure Registry repository
+type OCIRRegistry struct {
+	artifacts     *ociapi.Client
+	compartmentID string
+	region        string
+	namespace     string
+	repository    string
+	imageTag      string
+	username      string
+	authToken     string
+	registryURL   string
+	imageURI      string
+}
+
+// ocirRepository is a container repository of the OCI Artifacts API.
+type ocirRepository struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// NewOCIRRegistry creates a new OCIR registry handler. artifacts is a client for
+// the OCI Artifacts API, used to create the repository in compartmentID; username
+// is the registry user without the tenancy namespace.
+func NewOCIRRegistry(artifacts *ociapi.Client, compartmentID, region, namespace, repository, imageTag, username, authToken string) (*OCIRRegistry, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("object storage namespace is required for OCIR")
+	}
+	if authToken == "" {
+		return nil, fmt.Errorf("an auth token is required to push to OCIR")
+	}
+	return &OCIRRegistry{
+		artifacts:     artifacts,
+		compartmentID: compartmentID,
+		region:        region,
+		namespace:     namespace,
+		repository:    repository,
+		imageTag:      imageTag,
+		username:      username,
+		authToken:     authToken,
+		registryURL:   fmt.Sprintf("%s.ocir.io", region),
+		imageURI:      fmt.Sprintf("%s.ocir.io/%s/%s:%s", region, namespace, repository, imageTag),
+	}, nil
+}
+
+// GetRegistryURL returns the OCIR registry URL
+func (o *OCIRRegistry) GetRegistryURL() string {
+	return o.registryURL
+}
+
+// GetImageURI returns the full image URI in OCIR
+func (o *OCIRRegistry) GetImageURI() string {
+	return o.imageURI
+}
+
+// GetImageReference returns the full image reference for OCIR
+func (o *OCIRRegistry) GetImageReference() string {
+	return o.imageURI
+}
+
+// GetAuthenticator ensures the repository exists and returns the authenticator
+// for OCIR using an auth token
+func (o *OCIRRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	// OCIR creates missing repositories on push in the root compartment, so
+	// create it explicitly to keep it in the deployment's compartment
+	if _, err := EnsureOCIRRepository(ctx, o.artifacts, o.compartmentID, o.repository); err != nil {
+		return nil, err
+	}
+
+	return &authn.Basic{
+		Username: o.namespace + "/" + o.username,
+		Password: o.authToken,
+	}, nil
+}
+
+// FindOCIRRepository returns the OCID of the repository named name in
+// compartmentID, or "" if there is none.
+func FindOCIRRepository(ctx context.Context, artifacts *ociapi.Client, compartmentID, name string) (string, error) {
+	query := url.Values{"compartmentId": {compartmentID}, "displayName": {name}}
+	var list struct {
+		Items []ocirRepository `json:"items"`
+	}
+	if err := artifacts.Do(ctx, http.MethodGet, "/20160918/container/repositories?"+query.Encode(), nil, &list); err != nil {
+		return "", fmt.Errorf("failed to list OCIR repositories: %w", err)
+	}
+	for _, repo := range list.Items {
+		if repo.DisplayName == name {
+			return repo.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// EnsureOCIRRepository creates the private repository named name in
+// compartmentID if it doesn't exist, returning its OCID.
+func EnsureOCIRRepository(ctx context.Context, artifacts *ociapi.Client, compartmentID, name string) (string, error) {
+	logging.Infof("Ensuring OCIR repository exists: %s", name)
+
+	id, err := FindOCIRRepository(ctx, artifacts, compartmentID, name)
+	if err != nil {
+		return "", err
+	}
+	if id != "" {
+		logging.Infof("OCIR repository %s already exists", name)
+		return id, nil
+	}
+
+	logging.Infof("Creating OCIR repository: %s", name)
+	var created ocirRepository
+	err = artifacts.Do(ctx, http.MethodPost, "/20160918/container/repositories", map[string]interface{}{
+		"compartmentId": compartmentID,
+		"displayName":   name,
+		"isPublic":      false,
+	}, &created)
+	if err != nil {
+		return "", fmt.Errorf("failed to create OCIR repository %s: %w", name, err)
+	}
+	return created.ID, nil
+}
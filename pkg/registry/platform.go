@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Platform identifies the operating system and CPU architecture an image runs on
+type Platform struct {
+	OS           string
+	Architecture string
+}
+
+// String returns the platform in "os/arch" form (e.g. "linux/amd64")
+func (p Platform) String() string {
+	return p.OS + "/" + p.Architecture
+}
+
+// LinuxAMD64 is the platform required by targets that only run x86-64 Linux containers
+var LinuxAMD64 = Platform{OS: "linux", Architecture: "amd64"}
+
+// ImagePlatforms inspects an image and returns the platforms it supports.
+// The local Docker daemon is consulted first, since that is where Distribute pushes
+// from; otherwise the image is read from its registry. Multi-arch image indexes
+// return every platform they contain, and WebAssembly OCI artifacts return Wasm.
+func ImagePlatforms(ctx context.Context, image string) ([]Platform, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference: %w", err)
+	}
+
+	if img, err := daemon.Image(ref, daemon.WithContext(ctx)); err == nil {
+		if cfg, err := img.ConfigFile(); err == nil {
+			return []Platform{normalizePlatform(cfg.OS, cfg.Architecture)}, nil
+		}
+	}
+
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image from Docker daemon or registry: %w", err)
+	}
+
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index: %w", err)
+		}
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index manifest: %w", err)
+		}
+
+		var platforms []Platform
+		for _, m := range manifest.Manifests {
+			// Attestation manifests are listed with an "unknown/unknown" platform
+			if m.Platform == nil || m.Platform.OS == "unknown" {
+				continue
+			}
+			platforms = append(platforms, normalizePlatform(m.Platform.OS, m.Platform.Architecture))
+		}
+		return platforms, nil
+	}
+
+	if isWasmArtifact(desc.Manifest) {
+		return []Platform{Wasm}, nil
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image config: %w", err)
+	}
+	return []Platform{normalizePlatform(cfg.OS, cfg.Architecture)}, nil
+}
+
+// ValidatePlatform fails early when image cannot run on the target platform.
+// targetName describes the deployment target for the error message (e.g. "Cloud Run"),
+// and hint is an optional provider-specific remediation appended to it.
+// If the image cannot be inspected, a warning is logged and validation is skipped.
+func ValidatePlatform(ctx context.Context, image string, target Platform, targetName, hint string) error {
+	platforms, err := ImagePlatforms(ctx, image)
+	if err != nil {
+		logging.Warnf("Could not inspect platform of image %s, skipping platform validation: %v", image, err)
+		return nil
+	}
+	return checkPlatforms(image, platforms, target, targetName, hint)
+}
+
+// checkPlatforms returns an error with remediation advice when target is not among platforms.
+func checkPlatforms(image string, platforms []Platform, target Platform, targetName, hint string) error {
+	if len(platforms) == 0 {
+		return nil
+	}
+
+	found := make([]string, 0, len(platforms))
+	wasm := true
+	for _, p := range platforms {
+		if p == target || (p.IsWasm() && target.IsWasm()) {
+			return nil
+		}
+		found = append(found, p.String())
+		wasm = wasm && p.IsWasm()
+	}
+
+	// WebAssembly and container workloads need different runtimes, so
+	// rebuilding for another architecture does not help
+	if target.IsWasm() {
+		return fmt.Errorf("image %s is a container image for %s, but %s runs WebAssembly workloads; push a WebAssembly OCI artifact (e.g., with `spin registry push`) or remove artifact_type: wasm", image, strings.Join(found, ", "), targetName)
+	}
+	if wasm {
+		return fmt.Errorf("image %s is a WebAssembly artifact, but %s runs only %s containers; deploy it with artifact_type: wasm to a provider that runs WebAssembly workloads (kubernetes)", image, targetName, target)
+	}
+
+	msg := fmt.Sprintf("image %s is built for %s, but %s runs %s containers", image, strings.Join(found, ", "), targetName, target)
+
+	osMatches := false
+	for _, p := range platforms {
+		if p.OS == target.OS {
+			osMatches = true
+			break
+		}
+	}
+	if !osMatches {
+		msg += fmt.Sprintf("; use a %s base image and rebuild", target.OS)
+	} else {
+		msg += fmt.Sprintf("; rebuild with `docker buildx build --platform %s` (or publish a multi-arch image)", target)
+	}
+	if hint != "" {
+		msg += "; " + hint
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// normalizePlatform maps architecture aliases to the names used by OCI image configs.
+func normalizePlatform(os, arch string) Platform {
+	switch arch {
+	case "x86_64", "x86-64":
+		arch = "amd64"
+	case "aarch64":
+		arch = "arm64"
+	}
+	// Docker Desktop's Wasm images used "wasi" before WASI preview 1 was named
+	if os == "wasi" {
+		os = Wasm.OS
+	}
+	return Platform{OS: os, Architecture: arch}
+}
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlatformString(t *testing.T) {
+	if got := LinuxAMD64.String(); got != "linux/amd64" {
+		t.Errorf("Expected linux/amd64, got %s", got)
+	}
+}
+
+func TestCheckPlatformsMatch(t *testing.T) {
+	platforms := []Platform{{OS: "linux", Architecture: "arm64"}, {OS: "linux", Architecture: "amd64"}}
+	if err := checkPlatforms("app:latest", platforms, LinuxAMD64, "Cloud Run", ""); err != nil {
+		t.Errorf("Expected multi-arch image to match, got: %v", err)
+	}
+}
+
+func TestCheckPlatformsNoPlatforms(t *testing.T) {
+	if err := checkPlatforms("app:latest", nil, LinuxAMD64, "Cloud Run", ""); err != nil {
+		t.Errorf("Expected no error when platforms are unknown, got: %v", err)
+	}
+}
+
+func TestCheckPlatformsArchitectureMismatch(t *testing.T) {
+	platforms := []Platform{{OS: "linux", Architecture: "arm64"}}
+	err := checkPlatforms("app:latest", platforms, LinuxAMD64, "instance type t3.small", "or choose a Graviton instance type")
+	if err == nil {
+		t.Fatal("Expected error for arm64 image on amd64 target")
+	}
+
+	for _, s := range []string{"linux/arm64", "instance type t3.small", "--platform linux/amd64", "Graviton"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("Expected error to contain %q, got: %v", s, err)
+		}
+	}
+}
+
+func TestCheckPlatformsOSMismatch(t *testing.T) {
+	platforms := []Platform{{OS: "windows", Architecture: "amd64"}}
+	err := checkPlatforms("app:latest", platforms, LinuxAMD64, "Cloud Run", "")
+	if err == nil {
+		t.Fatal("Expected error for Windows image on Linux target")
+	}
+	if !strings.Contains(err.Error(), "use a linux base image") {
+		t.Errorf("Expected base image remediation, got: %v", err)
+	}
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := map[string]string{
+		"x86_64":  "amd64",
+		"aarch64": "arm64",
+		"amd64":   "amd64",
+		"arm":     "arm",
+	}
+	for in, expected := range tests {
+		if got := normalizePlatform("linux", in); got.Architecture != expected {
+			t.Errorf("normalizePlatform(%q) = %s, want %s", in, got.Architecture, expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// promoteKeychain provides the credentials of promoted images: those of
+// docker login and Docker credential helpers such as docker-credential-ecr-login,
+// gcloud, and az acr login.
+var promoteKeychain = authn.DefaultKeychain
+
+// PromotionTarget returns the reference an image is promoted to. to is either
+// a tag, which is applied to source's repository, or a full image reference
+// in any registry.
+func PromotionTarget(source, to string) (string, error) {
+	sourceRef, err := name.ParseReference(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference %s: %w", source, err)
+	}
+	if tag, err := name.NewTag(sourceRef.Context().Name()+":"+to, name.StrictValidation); err == nil && tag.TagStr() == to {
+		return tag.Name(), nil
+	}
+	targetRef, err := name.ParseReference(to)
+	if err != nil {
+		return "", fmt.Errorf("%q is neither a tag nor an image reference: %w", to, err)
+	}
+	if _, ok := targetRef.(name.Digest); ok {
+		return "", fmt.Errorf("cannot promote to %s: the target must be a tag, not a digest", to)
+	}
+	return targetRef.Name(), nil
+}
+
+// Promote copies the image at source to target by digest, without rebuilding
+// it, and returns the digest. Within a repository the image is only tagged;
+// otherwise its layers are copied to the target registry, which may belong to
+// another cloud. Multi-arch indexes are copied with every platform.
+func Promote(ctx context.Context, source, target string) (string, error) {
+	sourceRef, err := name.ParseReference(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference %s: %w", source, err)
+	}
+	targetRef, err := name.NewTag(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse target tag %s: %w", target, err)
+	}
+
+	desc, err := remote.Get(sourceRef, remote.WithAuthFromKeychain(promoteKeychain), remote.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image %s: %w", source, err)
+	}
+	digest := desc.Digest.String()
+	logging.Infof("Promoting %s (%s) to %s", source, digest, target)
+
+	opts := []remote.Option{remote.WithAuthFromKeychain(promoteKeychain), remote.WithContext(ctx)}
+	switch {
+	case sourceRef.Context() == targetRef.Context():
+		err = remote.Tag(targetRef, desc, opts...)
+	case desc.MediaType.IsIndex():
+		idx, idxErr := desc.ImageIndex()
+		if idxErr != nil {
+			return "", fmt.Errorf("failed to read image index %s: %w", source, idxErr)
+		}
+		err = remote.WriteIndex(targetRef, idx, opts...)
+	default:
+		img, imgErr := desc.Image()
+		if imgErr != nil {
+			return "", fmt.Errorf("failed to read image %s: %w", source, imgErr)
+		}
+		err = remote.Write(targetRef, img, opts...)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", target, err)
+	}
+
+	pushed, err := remote.Head(targetRef, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify %s: %w", target, err)
+	}
+	if pushed.Digest.String() != digest {
+		return "", fmt.Errorf("promoted image %s has digest %s, want %s", target, pushed.Digest, digest)
+	}
+	return digest, nil
+}
@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestPromotionTarget(t *testing.T) {
+	tests := []struct {
+		source, to, want, wantErr string
+	}{
+		{"123.dkr.ecr.us-east-1.amazonaws.com/app:staging", "prod", "123.dkr.ecr.us-east-1.amazonaws.com/app:prod", ""},
+		{"123.dkr.ecr.us-east-1.amazonaws.com/app@sha256:" + strings.Repeat("a", 64), "prod", "123.dkr.ecr.us-east-1.amazonaws.com/app:prod", ""},
+		{"myapp:staging", "v1.2.0", "index.docker.io/library/myapp:v1.2.0", ""},
+		{"123.dkr.ecr.us-east-1.amazonaws.com/app:staging", "myregistry.azurecr.io/app:prod", "myregistry.azurecr.io/app:prod", ""},
+		{"myapp:staging", "myregistry.azurecr.io/app@sha256:" + strings.Repeat("a", 64), "", "not a digest"},
+		{"Not A Reference", "prod", "", "failed to parse"},
+		{"myapp:staging", "Not A Tag", "", "neither a tag nor"},
+	}
+	for _, tt := range tests {
+		got, err := PromotionTarget(tt.source, tt.to)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("PromotionTarget(%q, %q) error = %v, want %q", tt.source, tt.to, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("PromotionTarget(%q, %q) = %q, %v; want %q", tt.source, tt.to, got, err, tt.want)
+		}
+	}
+}
+
+func TestPromote(t *testing.T) {
+	staging := httptest.NewServer(ggcrregistry.New())
+	defer staging.Close()
+	prod := httptest.NewServer(ggcrregistry.New())
+	defer prod.Close()
+	stagingHost := strings.TrimPrefix(staging.URL, "http://")
+	prodHost := strings.TrimPrefix(prod.URL, "http://")
+
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := stagingHost + "/app:staging"
+	sourceRef, _ := name.ParseReference(source)
+	if err := remote.Write(sourceRef, img); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+	want, _ := img.Digest()
+
+	idx, err := random.Index(512, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexSource := stagingHost + "/multi:staging"
+	indexRef, _ := name.ParseReference(indexSource)
+	if err := remote.WriteIndex(indexRef, idx); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+	wantIndex, _ := idx.Digest()
+
+	tests := []struct {
+		name, source, target, want string
+	}{
+		{"retag in the same repository", source, stagingHost + "/app:prod", want.String()},
+		{"copy to another registry", source, prodHost + "/app:prod", want.String()},
+		{"copy a multi-arch index", indexSource, prodHost + "/multi:prod", wantIndex.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := Promote(context.Background(), tt.source, tt.target)
+			if err != nil {
+				t.Fatalf("Promote failed: %v", err)
+			}
+			if digest != tt.want {
+				t.Errorf("digest = %s, want %s", digest, tt.want)
+			}
+			targetRef, _ := name.ParseReference(tt.target)
+			pushed, err := remote.Head(targetRef)
+			if err != nil {
+				t.Fatalf("promoted image not found: %v", err)
+			}
+			if pushed.Digest.String() != tt.want {
+				t.Errorf("promoted digest = %s, want %s", pushed.Digest, tt.want)
+			}
+		})
+	}
+
+	if _, err := Promote(context.Background(), stagingHost+"/app:missing", prodHost+"/app:prod"); err == nil || !strings.Contains(err.Error(), "failed to read image") {
+		t.Errorf("Expected an error for a missing source image, got: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// ValidateReference checks that image is a well-formed image reference: an
+// optional registry host, a lowercase repository, and a tag or digest (e.g.,
+// "ghcr.io/acme/my-app:v1.2.0").
+func ValidateReference(image string) error {
+	if _, err := name.ParseReference(image); err != nil {
+		return fmt.Errorf("%q is not a valid image reference: repositories are lowercase, tags at most 128 characters of letters, digits, '_', '.', and '-', and digests sha256:<64 hex digits>", image)
+	}
+	return nil
+}
+
+// ImageTag returns the tag of image, or "latest" if it has none.
+func ImageTag(image string) string {
+	if tag, err := name.NewTag(image); err == nil {
+		return tag.TagStr()
+	}
+	return "latest"
+}
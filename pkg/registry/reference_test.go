@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReference(t *testing.T) {
+	for _, image := range []string{
+		"my-app",
+		"my-app:latest",
+		"ghcr.io/acme/my-app:v1.2.0",
+		"localhost:5000/my-app",
+		"my-app@sha256:" + strings.Repeat("a", 64),
+	} {
+		if err := ValidateReference(image); err != nil {
+			t.Errorf("ValidateReference(%q) failed: %v", image, err)
+		}
+	}
+	for _, image := range []string{
+		"My-App:latest",
+		"my-app:v1:2",
+		"my-app@sha256:abc",
+		"my app",
+	} {
+		if err := ValidateReference(image); err == nil || !strings.Contains(err.Error(), "is not a valid image reference") {
+			t.Errorf("Expected ValidateReference(%q) to fail, got %v", image, err)
+		}
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, want := range map[string]string{
+		"my-app:v1.2.0":                  "v1.2.0",
+		"ghcr.io/acme/my-app:sha-abc123": "sha-abc123",
+		"my-app":                         "latest",
+		"my-app@sha256:" + strings.Repeat("a", 64): "latest",
+	} {
+		if got := ImageTag(image); got != want {
+			t.Errorf("ImageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
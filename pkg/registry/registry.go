@@ -1,17 +1,37 @@
 // Package registry provides functionality for distributing Docker images
-// to cloud provider container registries (ECR, ACR, GCR).
+// to cloud provider container registries (ECR, ACR, GCR, OCIR).
 package registry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/jvreagan/cloud-deploy/pkg/logging"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/daemon"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentPushes is how many registries Distribute pushes to at once.
+const maxConcurrentPushes = 4
+
+// Pushes failing for reasons that may not recur, such as throttling, a
+// registry error, or a dropped connection, are retried with exponential
+// backoff: after pushBackoff, then twice as long, and so on.
+var (
+	pushAttempts = 4
+	pushBackoff  = 2 * time.Second
 )
 
 // Registry represents a cloud provider container registry
@@ -29,16 +49,43 @@ type Registry interface {
 	GetImageURI() string
 }
 
+// PushResult is the outcome of pushing the image to one registry.
+type PushResult struct {
+	Registry string // URL of the registry
+	ImageURI string // URI of the pushed image
+	Digest   string // Digest of the pushed image
+	Attempts int    // Number of pushes attempted
+	Err      error  // Why the push failed, or nil
+}
+
 // Distributor handles distributing a Docker image to multiple cloud registries
 type Distributor struct {
 	sourceImage string
+	platforms   []string
 	registries  []Registry
+	digest      string
+	results     []PushResult
+
+	// mu guards auths, which registries pushed to concurrently share
+	mu    sync.Mutex
+	auths map[Registry]*registryAuth
+}
+
+// registryAuth is the authenticator of a target registry once resolved. Its
+// lock is held while the registry's credentials are resolved, so that pushes
+// to other registries do not wait on a token exchange.
+type registryAuth struct {
+	mu   sync.Mutex
+	auth authn.Authenticator
 }
 
-// NewDistributor creates a new image distributor
-func NewDistributor(sourceImage string) *Distributor {
+// NewDistributor creates a new image distributor. Given more than one
+// platform (e.g., "linux/amd64" and "linux/arm64"), it pushes a multi-arch
+// image index of the source image built for each.
+func NewDistributor(sourceImage string, platforms ...string) *Distributor {
 	return &Distributor{
 		sourceImage: sourceImage,
+		platforms:   platforms,
 		registries:  make([]Registry, 0),
 	}
 }
@@ -48,9 +95,26 @@ func (d *Distributor) AddRegistry(registry Registry) {
 	d.registries = append(d.registries, registry)
 }
 
-// Distribute reads the image from Docker daemon and pushes it to all registered registries
+// Digest returns the digest of the image pushed by Distribute, which is the
+// same in every registry.
+func (d *Distributor) Digest() string {
+	return d.digest
+}
+
+// Results returns the outcome of pushing to each registry, in the order the
+// registries were added, once Distribute has run.
+func (d *Distributor) Results() []PushResult {
+	return d.results
+}
+
+// Distribute reads the image from Docker daemon and pushes it to all registered registries.
+// Images that are not in the Docker daemon are pulled from their registry instead,
+// which lets earlier deployments be redeployed from the registry they were pushed to.
+//
+// Registries are pushed to concurrently, each retried on transient failures,
+// and a push failing does not stop the others. The URIs of the images pushed
+// are returned by registry URL, with the failures of the others joined.
 func (d *Distributor) Distribute(ctx context.Context) (map[string]string, error) {
-	imageURIs := make(map[string]string)
 
 	// Load image from Docker daemon once
 	logging.Infof("Loading image %s from Docker daemon...", d.sourceImage)
@@ -59,39 +123,230 @@ func (d *Distributor) Distribute(ctx context.Context) (map[string]string, error)
 		return nil, fmt.Errorf("failed to parse source image reference: %w", err)
 	}
 
-	img, err := daemon.Image(sourceRef)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load image from Docker daemon: %w", err)
+	var img remote.Taggable
+	var digest v1.Hash
+	if len(d.platforms) > 1 {
+		index, err := d.loadIndex(ctx, sourceRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load multi-arch image from Docker daemon or registry: %w", err)
+		}
+		img = index
+		digest, err = index.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute image index digest: %w", err)
+		}
+	} else {
+		platforms, err := parsePlatforms(d.platforms)
+		if err != nil {
+			return nil, err
+		}
+		var platform *v1.Platform
+		if len(platforms) == 1 {
+			platform = platforms[0]
+		}
+		image, err := daemon.Image(sourceRef)
+		if err != nil {
+			logging.Infof("Image not found in Docker daemon, pulling %s from its registry...", d.sourceImage)
+			image, err = d.pull(ctx, sourceRef, platform)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load image from Docker daemon or registry: %w", err)
+			}
+		}
+		if err := checkImagePlatform(image, platform); err != nil {
+			return nil, fmt.Errorf("image %s: %w", d.sourceImage, err)
+		}
+		img = image
+		digest, err = image.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute image digest: %w", err)
+		}
 	}
 	logging.Info("Image loaded successfully")
+	d.digest = digest.String()
 
-	// Distribute to each registry
-	for _, registry := range d.registries {
-		logging.Infof("=== Distributing to %s ===", registry.GetRegistryURL())
+	// Distribute to every registry
+	d.results = make([]PushResult, len(d.registries))
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentPushes)
+	for i, registry := range d.registries {
+		g.Go(func() error {
+			d.results[i] = d.push(ctx, registry, img)
+			return nil
+		})
+	}
+	g.Wait()
 
-		// Get authenticator
-		logging.Info("Preparing authentication...")
-		auth, err := registry.GetAuthenticator(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get authenticator for registry %s: %w", registry.GetRegistryURL(), err)
+	imageURIs := make(map[string]string)
+	var errs []error
+	if len(d.results) > 1 {
+		logging.Info("=== Distribution summary ===")
+	}
+	for _, r := range d.results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			if len(d.results) > 1 {
+				logging.Warnf("❌ %s: %v", r.Registry, r.Err)
+			}
+			continue
+		}
+		imageURIs[r.Registry] = r.ImageURI
+		if len(d.results) > 1 {
+			logging.Infof("✅ %s: %s (%s)", r.Registry, r.ImageURI, r.Digest)
 		}
+	}
+	return imageURIs, errors.Join(errs...)
+}
 
-		// Parse target reference
-		targetRef, err := name.ParseReference(registry.GetImageReference())
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse target image reference: %w", err)
+// push pushes img to registry, retrying transient failures.
+func (d *Distributor) push(ctx context.Context, registry Registry, img remote.Taggable) PushResult {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = d.pushOnce(ctx, registry, img); err == nil {
+			logging.Infof("Successfully pushed to %s", registry.GetRegistryURL())
+			return PushResult{Registry: registry.GetRegistryURL(), ImageURI: registry.GetImageURI(), Digest: d.digest, Attempts: attempt}
 		}
-		logging.Infof("Target: %s", targetRef.Name())
+		if attempt == pushAttempts || !transient(err) {
+			return PushResult{Registry: registry.GetRegistryURL(), Attempts: attempt, Err: err}
+		}
+		wait := pushBackoff << (attempt - 1)
+		logging.Warnf("Pushing to %s failed, retrying in %s: %v", registry.GetRegistryURL(), wait, err)
+		select {
+		case <-ctx.Done():
+			return PushResult{Registry: registry.GetRegistryURL(), Attempts: attempt, Err: err}
+		case <-time.After(wait):
+		}
+	}
+}
 
-		// Push image to registry using OCI Distribution API
-		logging.Infof("Pushing image to %s...", registry.GetRegistryURL())
-		if err := remote.Write(targetRef, img, remote.WithAuth(auth), remote.WithContext(ctx)); err != nil {
-			return nil, fmt.Errorf("failed to push image to registry %s: %w", registry.GetRegistryURL(), err)
+// pushOnce authenticates with registry and pushes img to it.
+func (d *Distributor) pushOnce(ctx context.Context, registry Registry, img remote.Taggable) error {
+	// Get authenticator
+	auth, err := d.authenticator(ctx, registry)
+	if err != nil {
+		return err
+	}
+
+	// Parse target reference
+	targetRef, err := reference(registry)
+	if err != nil {
+		return fmt.Errorf("failed to parse target image reference: %w", err)
+	}
+
+	// Push image to registry using OCI Distribution API
+	logging.Infof("Pushing image to %s...", targetRef.Name())
+	if err := remote.Push(targetRef, img, remote.WithAuth(auth), remote.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to push image to registry %s: %w", registry.GetRegistryURL(), err)
+	}
+	return nil
+}
+
+// transient reports whether a push failed for a reason that may not recur:
+// throttling, a registry error, or a network failure.
+func transient(err error) bool {
+	var registryErr *transport.Error
+	if errors.As(err, &registryErr) {
+		return registryErr.StatusCode == http.StatusTooManyRequests || registryErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// pull reads an image from its registry. The image of platform is read from a
+// multi-arch image index, when platform is not nil.
+func (d *Distributor) pull(ctx context.Context, ref name.Reference, platform *v1.Platform) (v1.Image, error) {
+	auth, err := d.pullAuth(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	opts := []remote.Option{auth, remote.WithContext(ctx)}
+	if platform != nil {
+		opts = append(opts, remote.WithPlatform(*platform))
+	}
+	return remote.Image(ref, opts...)
+}
+
+// checkImagePlatform returns an error when img is built for another platform
+// than platform. Any image passes when platform is nil, and images whose
+// config names no platform pass.
+func checkImagePlatform(img v1.Image, platform *v1.Platform) error {
+	if platform == nil {
+		return nil
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("failed to read image config: %w", err)
+	}
+	if got := cfg.Platform(); got != nil && !got.Satisfies(*platform) {
+		return fmt.Errorf("image is built for %s, not %s of platforms", got, platform)
+	}
+	return nil
+}
+
+// pullAuth returns the credentials images are pulled from ref's registry with.
+// Images in one of the target registries are pulled with that registry's
+// credentials; others use the local Docker credentials.
+func (d *Distributor) pullAuth(ctx context.Context, ref name.Reference) (remote.Option, error) {
+	for _, registry := range d.registries {
+		authenticator, err := d.authenticator(ctx, registry)
+		if err != nil {
+			return nil, err
+		}
+		// Some registries only know their image reference once authenticated
+		target, err := reference(registry)
+		if err == nil && target.Context().RegistryStr() == ref.Context().RegistryStr() {
+			return remote.WithAuth(authenticator), nil
 		}
-		logging.Infof("Successfully pushed to %s", registry.GetRegistryURL())
+	}
+	return remote.WithAuthFromKeychain(authn.DefaultKeychain), nil
+}
 
-		imageURIs[registry.GetRegistryURL()] = registry.GetImageURI()
+// authenticator returns the authenticator of a target registry, asking the
+// registry until it answers once. Registries are asked concurrently.
+func (d *Distributor) authenticator(ctx context.Context, registry Registry) (authn.Authenticator, error) {
+	d.mu.Lock()
+	if d.auths == nil {
+		d.auths = make(map[Registry]*registryAuth)
+	}
+	ra, ok := d.auths[registry]
+	if !ok {
+		ra = &registryAuth{}
+		d.auths[registry] = ra
 	}
+	d.mu.Unlock()
 
-	return imageURIs, nil
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	if ra.auth != nil {
+		return ra.auth, nil
+	}
+	auth, err := registry.GetAuthenticator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authenticator for registry %s: %w", registry.GetRegistryURL(), err)
+	}
+	ra.auth = auth
+	return auth, nil
+}
+
+// reference parses the image reference of a target registry, which is
+// reached over plain HTTP when it is insecure.
+func reference(registry Registry) (name.Reference, error) {
+	var opts []name.Option
+	if r, ok := registry.(interface{ Insecure() bool }); ok && r.Insecure() {
+		opts = append(opts, name.Insecure)
+	}
+	return name.ParseReference(registry.GetImageReference(), opts...)
+}
+
+// PinDigest returns image with its tag replaced by digest, so it refers to
+// exactly the image that was pushed (e.g., "registry/app@sha256:...").
+// image is returned unchanged when digest is empty.
+func PinDigest(image, digest string) string {
+	if digest == "" {
+		return image
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return image
+	}
+	return ref.Context().Name() + "@" + digest
 }
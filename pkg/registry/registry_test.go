@@ -2,11 +2,31 @@ package registry
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"google.golang.org/api/option"
+
+	"github.com/jvreagan/cloud-deploy/pkg/ociapi"
 )
 
 // awsConfigStub returns a minimal aws.Config for constructor tests (no real credentials).
@@ -117,6 +137,45 @@ func TestNewGCRRegistry(t *testing.T) {
 	}
 }
 
+// staticCredential is an Azure credential with a fixed token.
+type staticCredential struct{}
+
+func (staticCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "aad-token"}, nil
+}
+
+func TestExchangeACRToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/oauth2/exchange" || r.Form.Get("grant_type") != "access_token" || r.Form.Get("service") != "myapp.azurecr.io" || r.Form.Get("access_token") != "aad-token" {
+			t.Errorf("Unexpected exchange %s %v", r.URL.Path, r.Form)
+		}
+		w.Write([]byte(`{"refresh_token":"acr-refresh"}`))
+	}))
+	defer server.Close()
+
+	auth, err := exchangeACRToken(context.Background(), staticCredential{}, server.URL, "myapp.azurecr.io")
+	if err != nil {
+		t.Fatalf("exchangeACRToken returned error: %v", err)
+	}
+	if basic := auth.(*authn.Basic); basic.Username != acrTokenUser || basic.Password != "acr-refresh" {
+		t.Errorf("unexpected authenticator: %+v", basic)
+	}
+}
+
+func TestGCRRegistryOptions(t *testing.T) {
+	if r, _ := NewGCRRegistry("my-project", "us-central1", "myapp", "v1", ""); r.options() != nil {
+		t.Errorf("Expected Application Default Credentials without credentials, got %v", r.options())
+	}
+	if r, _ := NewGCRRegistry("my-project", "us-central1", "myapp", "v1", `{"type":"service_account"}`); len(r.options()) != 1 {
+		t.Errorf("Expected the credentials JSON option, got %v", r.options())
+	}
+	opts := []option.ClientOption{option.WithTokenSource(nil), option.WithQuotaProject("my-project")}
+	if r, _ := NewGCRRegistry("my-project", "us-central1", "myapp", "v1", `{"type":"service_account"}`, opts...); len(r.options()) != 2 {
+		t.Errorf("Expected the provider's client options to take precedence, got %v", r.options())
+	}
+}
+
 func TestNewACRRegistry(t *testing.T) {
 	r, err := NewACRRegistry(nil, "sub-123", "my-rg", "myregistry", "eastus", "v1.0.0")
 	if err != nil {
@@ -139,11 +198,294 @@ func TestNewACRRegistry(t *testing.T) {
 	}
 }
 
+// ociClientStub returns an OCI API client with a throwaway signing key for endpoint.
+func ociClientStub(t *testing.T, endpoint string) *ociapi.Client {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return ociapi.New(&ociapi.Config{Region: "us-ashburn-1", TenancyID: "t", UserID: "u", Fingerprint: "f", PrivateKey: key}, endpoint)
+}
+
+func TestNewOCIRRegistry(t *testing.T) {
+	r, err := NewOCIRRegistry(nil, "ocid1.compartment", "us-ashburn-1", "axaxnpcrorw5", "myapp", "v1.0.0", "jane@example.com", "token")
+	if err != nil {
+		t.Fatalf("NewOCIRRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "us-ashburn-1.ocir.io" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "us-ashburn-1.ocir.io/axaxnpcrorw5/myapp:v1.0.0"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+
+	if _, err := NewOCIRRegistry(nil, "c", "us-ashburn-1", "ns", "myapp", "v1", "user", ""); err == nil {
+		t.Error("expected error without an auth token")
+	}
+	if _, err := NewOCIRRegistry(nil, "c", "us-ashburn-1", "", "myapp", "v1", "user", "token"); err == nil {
+		t.Error("expected error without a namespace")
+	}
+}
+
+func TestOCIRRegistryGetAuthenticator(t *testing.T) {
+	var created map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("displayName") != "myapp" || r.URL.Query().Get("compartmentId") != "ocid1.compartment" {
+				t.Errorf("unexpected list query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"items":[]}`))
+		case http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"id":"ocid1.containerrepo","displayName":"myapp"}`))
+		}
+	}))
+	defer server.Close()
+
+	r, err := NewOCIRRegistry(ociClientStub(t, server.URL), "ocid1.compartment", "us-ashburn-1", "ns", "myapp", "v1", "jane@example.com", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := r.GetAuthenticator(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthenticator returned error: %v", err)
+	}
+	if created["displayName"] != "myapp" || created["isPublic"] != false {
+		t.Errorf("unexpected repository request: %v", created)
+	}
+	cfg, _ := auth.Authorization()
+	if cfg.Username != "ns/jane@example.com" || cfg.Password != "token" {
+		t.Errorf("unexpected credentials: %+v", cfg)
+	}
+}
+
+func TestEnsureOCIRRepositoryExisting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s request for an existing repository", r.Method)
+		}
+		w.Write([]byte(`{"items":[{"id":"ocid1.containerrepo","displayName":"myapp"}]}`))
+	}))
+	defer server.Close()
+
+	id, err := EnsureOCIRRepository(context.Background(), ociClientStub(t, server.URL), "c", "myapp")
+	if err != nil || id != "ocid1.containerrepo" {
+		t.Errorf("EnsureOCIRRepository() = %q, %v", id, err)
+	}
+}
+
+func TestNewDOCRRegistry(t *testing.T) {
+	r, err := NewDOCRRegistry("my-registry", "myapp", "v1.0.0", "do-token")
+	if err != nil {
+		t.Fatalf("NewDOCRRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "registry.digitalocean.com/my-registry" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "registry.digitalocean.com/my-registry/myapp:v1.0.0"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+	auth, err := r.GetAuthenticator(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthenticator returned error: %v", err)
+	}
+	if basic := auth.(*authn.Basic); basic.Username != "do-token" || basic.Password != "do-token" {
+		t.Errorf("unexpected authenticator: %+v", basic)
+	}
+
+	if _, err := NewDOCRRegistry("my-registry", "myapp", "v1", ""); err == nil {
+		t.Error("expected error without an API token")
+	}
+	if _, err := NewDOCRRegistry("", "myapp", "v1", "do-token"); err == nil {
+		t.Error("expected error without a registry name")
+	}
+}
+
+func TestNewFlyRegistry(t *testing.T) {
+	r, err := NewFlyRegistry("my-app-prod", "deploy-1", "fly-token")
+	if err != nil {
+		t.Fatalf("NewFlyRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "registry.fly.io" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "registry.fly.io/my-app-prod:deploy-1"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+	auth, err := r.GetAuthenticator(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthenticator returned error: %v", err)
+	}
+	if basic := auth.(*authn.Basic); basic.Username != "x" || basic.Password != "fly-token" {
+		t.Errorf("unexpected authenticator: %+v", basic)
+	}
+
+	if _, err := NewFlyRegistry("my-app-prod", "v1", ""); err == nil {
+		t.Error("expected error without an API token")
+	}
+	if _, err := NewFlyRegistry("", "v1", "fly-token"); err == nil {
+		t.Error("expected error without an app name")
+	}
+}
+
+func TestNewDockerHubRegistry(t *testing.T) {
+	r, err := NewDockerHubRegistry("my-org", "myapp", "v1.0.0", "ci-bot", "dckr_pat_token")
+	if err != nil {
+		t.Fatalf("NewDockerHubRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "docker.io/my-org" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "docker.io/my-org/myapp:v1.0.0"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+	if ref, err := name.ParseReference(r.GetImageReference()); err != nil || ref.Context().RegistryStr() != "index.docker.io" {
+		t.Errorf("Expected a Docker Hub reference, got %v (%v)", ref, err)
+	}
+	auth, err := r.GetAuthenticator(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthenticator returned error: %v", err)
+	}
+	if basic := auth.(*authn.Basic); basic.Username != "ci-bot" || basic.Password != "dckr_pat_token" {
+		t.Errorf("unexpected authenticator: %+v", basic)
+	}
+
+	if r, _ := NewDockerHubRegistry("", "myapp", "v1", "ci-bot", "token"); r.GetImageURI() != "docker.io/ci-bot/myapp:v1" {
+		t.Errorf("Expected the user's namespace by default, got %q", r.GetImageURI())
+	}
+	if _, err := NewDockerHubRegistry("my-org", "myapp", "v1", "ci-bot", ""); err == nil {
+		t.Error("expected error without an access token")
+	}
+	if _, err := NewDockerHubRegistry("my-org", "myapp", "v1", "", "token"); err == nil {
+		t.Error("expected error without a user name")
+	}
+}
+
+func TestNewGHCRRegistry(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GITHUB_ACTOR", "")
+	r, err := NewGHCRRegistry("My-Org", "myapp", "v1.0.0", "ci-bot", "ghp_token")
+	if err != nil {
+		t.Fatalf("NewGHCRRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "ghcr.io/my-org" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "ghcr.io/my-org/myapp:v1.0.0"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+	auth, err := r.GetAuthenticator(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthenticator returned error: %v", err)
+	}
+	if basic := auth.(*authn.Basic); basic.Username != "ci-bot" || basic.Password != "ghp_token" {
+		t.Errorf("unexpected authenticator: %+v", basic)
+	}
+
+	if _, err := NewGHCRRegistry("my-org", "myapp", "v1", "", ""); err == nil {
+		t.Error("expected error without a token")
+	}
+	if _, err := NewGHCRRegistry("", "myapp", "v1", "", "ghp_token"); err == nil {
+		t.Error("expected error without an owner")
+	}
+
+	// GitHub Actions workflows authenticate with GITHUB_TOKEN as the actor
+	t.Setenv("GITHUB_TOKEN", "ghs_token")
+	t.Setenv("GITHUB_ACTOR", "octocat")
+	r, err = NewGHCRRegistry("my-org", "myapp", "v1", "", "")
+	if err != nil {
+		t.Fatalf("NewGHCRRegistry returned error: %v", err)
+	}
+	auth, _ = r.GetAuthenticator(context.Background())
+	if basic := auth.(*authn.Basic); basic.Username != "octocat" || basic.Password != "ghs_token" {
+		t.Errorf("Expected GITHUB_TOKEN as the actor, got %+v", basic)
+	}
+}
+
+func TestNewGenericRegistry(t *testing.T) {
+	r, err := NewGenericRegistry("harbor.example.com/platform/", "myapp", "v1.0.0", "robot$ci", "harbor-token", false)
+	if err != nil {
+		t.Fatalf("NewGenericRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "harbor.example.com/platform" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "harbor.example.com/platform/myapp:v1.0.0"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+	auth, err := r.GetAuthenticator(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthenticator returned error: %v", err)
+	}
+	if basic := auth.(*authn.Basic); basic.Username != "robot$ci" || basic.Password != "harbor-token" {
+		t.Errorf("unexpected authenticator: %+v", basic)
+	}
+	if ref, _ := reference(r); ref.Context().Scheme() != "https" {
+		t.Errorf("Expected HTTPS for a secure registry, got %s", ref.Context().Scheme())
+	}
+
+	// Without a user name, the Docker credentials of the host are used
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	insecure, err := NewGenericRegistry("registry.internal:5000", "myapp", "v1", "", "", true)
+	if err != nil {
+		t.Fatalf("NewGenericRegistry returned error: %v", err)
+	}
+	if auth, err := insecure.GetAuthenticator(context.Background()); err != nil || auth != authn.Anonymous {
+		t.Errorf("GetAuthenticator() = %v, %v; want anonymous without Docker credentials", auth, err)
+	}
+	if ref, _ := reference(insecure); ref.Context().Scheme() != "http" {
+		t.Errorf("Expected plain HTTP for an insecure registry, got %s", ref.Context().Scheme())
+	}
+
+	if _, err := NewGenericRegistry("", "myapp", "v1", "", "", false); err == nil {
+		t.Error("expected error without a URL")
+	}
+	if _, err := NewGenericRegistry("quay.io/acme", "myapp", "v1", "acme+ci", "", false); err == nil {
+		t.Error("expected error with a user name and no password")
+	}
+}
+
+func TestNewLocalRegistry(t *testing.T) {
+	r, err := NewLocalRegistry("localhost:5000/", "my-app", "deploy-1")
+	if err != nil {
+		t.Fatalf("NewLocalRegistry returned error: %v", err)
+	}
+	if r.GetRegistryURL() != "localhost:5000" {
+		t.Errorf("GetRegistryURL() = %q", r.GetRegistryURL())
+	}
+	if want := "localhost:5000/my-app:deploy-1"; r.GetImageURI() != want || r.GetImageReference() != want {
+		t.Errorf("GetImageURI() = %q, want %q", r.GetImageURI(), want)
+	}
+	if auth, err := r.GetAuthenticator(context.Background()); err != nil || auth != authn.Anonymous {
+		t.Errorf("GetAuthenticator() = %v, %v; want anonymous", auth, err)
+	}
+	if _, err := NewLocalRegistry("", "my-app", "v1"); err == nil {
+		t.Error("expected error without a host")
+	}
+
+	fly, _ := NewFlyRegistry("my-app-prod", "v1", "fly-token")
+	if got := Local(fly, "", "my-app-prod", "v1"); got != Registry(fly) {
+		t.Errorf("Local without a host = %v, want the provider's registry", got)
+	}
+	if got := Local(fly, "localhost:5000", "my-app-prod", "v1"); got.GetImageURI() != "localhost:5000/my-app-prod:v1" {
+		t.Errorf("Local with a host = %q, want the local registry", got.GetImageURI())
+	}
+}
+
 func TestRegistryInterfaceCompliance(t *testing.T) {
 	// Verify all registry types satisfy the Registry interface at compile time
 	var _ Registry = (*ECRRegistry)(nil)
+	var _ Registry = (*LocalRegistry)(nil)
 	var _ Registry = (*GCRRegistry)(nil)
 	var _ Registry = (*ACRRegistry)(nil)
+	var _ Registry = (*OCIRRegistry)(nil)
+	var _ Registry = (*DOCRRegistry)(nil)
+	var _ Registry = (*FlyRegistry)(nil)
+	var _ Registry = (*DockerHubRegistry)(nil)
+	var _ Registry = (*GHCRRegistry)(nil)
+	var _ Registry = (*GenericRegistry)(nil)
 }
 
 func TestECRRegistryGetters(t *testing.T) {
@@ -418,3 +760,210 @@ func TestDistributeWithNoRegistries(t *testing.T) {
 		t.Errorf("Distribute with no registries returned %d results, want 0", len(result))
 	}
 }
+
+func TestDistributePullsFromRegistry(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// An image that is only in a registry, not in the Docker daemon
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := host + "/app:v1"
+	sourceRef, _ := name.ParseReference(source)
+	if err := remote.Write(sourceRef, img); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+	want, _ := img.Digest()
+
+	target := host + "/app:deploy-2"
+	d := NewDistributor(source)
+	d.AddRegistry(&mockRegistry{registryURL: host, imageReference: target, imageURI: target})
+	uris, err := d.Distribute(context.Background())
+	if err != nil {
+		t.Fatalf("Distribute returned error: %v", err)
+	}
+	if uris[host] != target {
+		t.Errorf("image URI = %q, want %q", uris[host], target)
+	}
+	if d.Digest() != want.String() {
+		t.Errorf("Digest() = %q, want %q", d.Digest(), want)
+	}
+
+	targetRef, _ := name.ParseReference(target)
+	pushed, err := remote.Head(targetRef)
+	if err != nil {
+		t.Fatalf("pushed image not found: %v", err)
+	}
+	if pushed.Digest != want {
+		t.Errorf("pushed digest = %s, want %s", pushed.Digest, want)
+	}
+}
+
+func TestDistributeRetriesAndReports(t *testing.T) {
+	defer func(b time.Duration) { pushBackoff = b }(pushBackoff)
+	pushBackoff = 0
+
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// A registry throttling the first two pushes, without the error body
+	// go-containerregistry would retry on by itself
+	var throttled atomic.Int32
+	backend := ggcrregistry.New()
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" && throttled.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+	flakyHost := strings.TrimPrefix(flaky.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := host + "/app:v1"
+	sourceRef, _ := name.ParseReference(source)
+	if err := remote.Write(sourceRef, img); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+	want, _ := img.Digest()
+
+	d := NewDistributor(source)
+	d.AddRegistry(&mockRegistry{registryURL: flakyHost, imageReference: flakyHost + "/app:v1", imageURI: flakyHost + "/app:v1"})
+	d.AddRegistry(&mockRegistry{registryURL: host + "/mirror", imageReference: host + "/mirror/app:v1", imageURI: host + "/mirror/app:v1"})
+	d.AddRegistry(&mockRegistry{registryURL: "denied.example.com", authError: fmt.Errorf("access denied")})
+	uris, err := d.Distribute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("Distribute error = %v, want the failure of denied.example.com", err)
+	}
+	if len(uris) != 2 || uris[flakyHost] != flakyHost+"/app:v1" || uris[host+"/mirror"] != host+"/mirror/app:v1" {
+		t.Errorf("image URIs = %v, want those of the two reachable registries", uris)
+	}
+
+	results := d.Results()
+	if len(results) != 3 {
+		t.Fatalf("Results() returned %d results, want 3", len(results))
+	}
+	if r := results[0]; r.Err != nil || r.Attempts != 3 || r.Digest != want.String() {
+		t.Errorf("throttled registry result = %+v, want success with digest %s after 3 attempts", r, want)
+	}
+	if r := results[1]; r.Err != nil || r.Attempts != 1 {
+		t.Errorf("mirror result = %+v, want success after 1 attempt", r)
+	}
+	if r := results[2]; r.Err == nil || r.Attempts != 1 || r.ImageURI != "" {
+		t.Errorf("denied registry result = %+v, want a failure without retries", r)
+	}
+}
+
+// blockingRegistry answers for its credentials once release is closed, and
+// counts how often it is asked.
+type blockingRegistry struct {
+	mockRegistry
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (b *blockingRegistry) GetAuthenticator(ctx context.Context) (authn.Authenticator, error) {
+	b.calls.Add(1)
+	<-b.release
+	return b.mockRegistry.GetAuthenticator(ctx)
+}
+
+func TestAuthenticatorResolvesRegistriesConcurrently(t *testing.T) {
+	slow := &blockingRegistry{mockRegistry: mockRegistry{registryURL: "slow.example.com"}, release: make(chan struct{})}
+	fast := &mockRegistry{registryURL: "fast.example.com"}
+	d := NewDistributor("myapp:latest")
+	d.AddRegistry(slow)
+	d.AddRegistry(fast)
+	ctx := context.Background()
+
+	slowDone := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := d.authenticator(ctx, slow)
+			slowDone <- err
+		}()
+	}
+
+	fastDone := make(chan error, 1)
+	go func() {
+		_, err := d.authenticator(ctx, fast)
+		fastDone <- err
+	}()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Fatalf("authenticator(fast) error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("authenticator(fast) waited on the credentials of another registry")
+	}
+
+	close(slow.release)
+	for range 2 {
+		if err := <-slowDone; err != nil {
+			t.Fatalf("authenticator(slow) error = %v", err)
+		}
+	}
+	if n := slow.calls.Load(); n != 1 {
+		t.Errorf("slow registry asked for credentials %d times, want 1", n)
+	}
+}
+
+func TestAuthenticatorRetriesFailedCredentials(t *testing.T) {
+	r := &mockRegistry{registryURL: "registry.example.com", authError: fmt.Errorf("token exchange failed")}
+	d := NewDistributor("myapp:latest")
+	d.AddRegistry(r)
+
+	if _, err := d.authenticator(context.Background(), r); err == nil {
+		t.Fatal("authenticator() error = nil, want the registry's error")
+	}
+	r.authError = nil
+	if _, err := d.authenticator(context.Background(), r); err != nil {
+		t.Errorf("authenticator() after a failure error = %v, want nil", err)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttled", &transport.Error{StatusCode: http.StatusTooManyRequests}, true},
+		{"unavailable", fmt.Errorf("push: %w", &transport.Error{StatusCode: http.StatusServiceUnavailable}), true},
+		{"unauthorized", &transport.Error{StatusCode: http.StatusUnauthorized, Errors: []transport.Diagnostic{{Code: transport.UnauthorizedErrorCode}}}, false},
+		{"connection reset", &net.OpError{Op: "read", Err: fmt.Errorf("connection reset by peer")}, true},
+		{"truncated", io.ErrUnexpectedEOF, true},
+		{"other", fmt.Errorf("access denied"), false},
+	}
+	for _, tt := range tests {
+		if got := transient(tt.err); got != tt.want {
+			t.Errorf("transient(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPinDigest(t *testing.T) {
+	tests := []struct {
+		image, digest, want string
+	}{
+		{"123.dkr.ecr.us-east-1.amazonaws.com/app:latest", "sha256:abc", "123.dkr.ecr.us-east-1.amazonaws.com/app@sha256:abc"},
+		{"myregistry.azurecr.io/app", "sha256:abc", "myregistry.azurecr.io/app@sha256:abc"},
+		{"myapp:v1", "sha256:abc", "index.docker.io/library/myapp@sha256:abc"},
+		{"myapp:v1", "", "myapp:v1"},
+		{"Not A Reference", "sha256:abc", "Not A Reference"},
+	}
+	for _, tt := range tests {
+		if got := PinDigest(tt.image, tt.digest); got != tt.want {
+			t.Errorf("PinDigest(%q, %q) = %q, want %q", tt.image, tt.digest, got, tt.want)
+		}
+	}
+}
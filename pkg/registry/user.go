@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// ImageUser returns the user an image's processes run as (the Dockerfile USER
+// instruction). An empty string means the image runs as root.
+// Like ImagePlatforms, the local Docker daemon is consulted before the registry;
+// for multi-arch images the first platform's configuration is used.
+func ImageUser(ctx context.Context, image string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference: %w", err)
+	}
+
+	cfg, err := imageConfig(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return cfg.Config.User, nil
+}
+
+// ValidateNonRoot fails when image runs as root. If the image cannot be inspected,
+// a warning is logged and validation is skipped.
+func ValidateNonRoot(ctx context.Context, image string) error {
+	user, err := ImageUser(ctx, image)
+	if err != nil {
+		logging.Warnf("Could not inspect user of image %s, skipping non-root validation: %v", image, err)
+		return nil
+	}
+	return checkNonRoot(image, user)
+}
+
+// checkNonRoot returns an error with remediation advice when user refers to root.
+func checkNonRoot(image, user string) error {
+	if user == "" {
+		return fmt.Errorf("image %s runs as root but security.run_as_non_root is set; add a USER instruction to the Dockerfile (e.g. USER 1000)", image)
+	}
+	if name, _, _ := strings.Cut(user, ":"); name == "root" || name == "0" {
+		return fmt.Errorf("image %s runs as %q but security.run_as_non_root is set; change the Dockerfile USER instruction to a non-root user", image, user)
+	}
+	return nil
+}
+
+// imageConfig reads the config file of an image from the local Docker daemon or its registry.
+func imageConfig(ctx context.Context, ref name.Reference) (*v1.ConfigFile, error) {
+	if img, err := daemon.Image(ref, daemon.WithContext(ctx)); err == nil {
+		if cfg, err := img.ConfigFile(); err == nil {
+			return cfg, nil
+		}
+	}
+
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image from Docker daemon or registry: %w", err)
+	}
+
+	var img v1.Image
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index: %w", err)
+		}
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image index manifest: %w", err)
+		}
+		if len(manifest.Manifests) == 0 {
+			return nil, fmt.Errorf("image index has no manifests")
+		}
+		img, err = idx.Image(manifest.Manifests[0].Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image from index: %w", err)
+		}
+	} else {
+		img, err = desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image config: %w", err)
+	}
+	return cfg, nil
+}
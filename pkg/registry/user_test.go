@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckNonRoot(t *testing.T) {
+	tests := []struct {
+		user    string
+		wantErr string
+	}{
+		{"", "add a USER instruction"},
+		{"root", "non-root user"},
+		{"0", "non-root user"},
+		{"0:0", "non-root user"},
+		{"root:staff", "non-root user"},
+		{"1000", ""},
+		{"app", ""},
+		{"1000:1000", ""},
+	}
+
+	for _, tt := range tests {
+		err := checkNonRoot("app:latest", tt.user)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkNonRoot(%q) unexpected error: %v", tt.user, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkNonRoot(%q) = %v, want error containing %q", tt.user, err, tt.wantErr)
+		}
+	}
+}
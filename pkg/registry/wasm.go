@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Wasm is the platform of WebAssembly workloads, whether they are packaged as
+// OCI artifacts (Spin applications, wasm-to-oci modules) or as images built
+// for wasip1/wasm with docker buildx.
+var Wasm = Platform{OS: "wasip1", Architecture: "wasm"}
+
+// IsWasm reports whether p is a WebAssembly platform.
+func (p Platform) IsWasm() bool {
+	return p.Architecture == "wasm"
+}
+
+// wasmMediaTypePrefixes are the media type prefixes of the configs, layers,
+// and artifact types of WebAssembly OCI artifacts.
+var wasmMediaTypePrefixes = []string{
+	"application/vnd.wasm.",         // CNCF Wasm OCI artifact layout
+	"application/vnd.module.wasm.",  // wasm-to-oci
+	"application/vnd.fermyon.spin.", // Spin applications
+}
+
+// artifactManifest holds the fields of an OCI manifest that identify what it packages.
+type artifactManifest struct {
+	ArtifactType string `json:"artifactType"`
+	Config       struct {
+		MediaType string `json:"mediaType"`
+	} `json:"config"`
+	Layers []struct {
+		MediaType string `json:"mediaType"`
+	} `json:"layers"`
+}
+
+// isWasmArtifact reports whether a raw OCI manifest packages a WebAssembly
+// artifact rather than a container image.
+func isWasmArtifact(raw []byte) bool {
+	var m artifactManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return false
+	}
+	mediaTypes := []string{m.ArtifactType, m.Config.MediaType}
+	for _, l := range m.Layers {
+		mediaTypes = append(mediaTypes, l.MediaType)
+	}
+	for _, mt := range mediaTypes {
+		for _, prefix := range wasmMediaTypePrefixes {
+			if strings.HasPrefix(mt, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// wasmArtifact returns a Wasm OCI artifact packaging a single module.
+func wasmArtifact(t *testing.T) v1.Image {
+	t.Helper()
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, "application/vnd.wasm.config.v0+json")
+	img, err := mutate.AppendLayers(img, static.NewLayer([]byte("\x00asm\x01\x00\x00\x00"), "application/vnd.wasm.content.layer.v1+wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestIsWasmArtifact(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     bool
+	}{
+		{"wasm config", `{"config":{"mediaType":"application/vnd.wasm.config.v0+json"},"layers":[]}`, true},
+		{"spin application", `{"config":{"mediaType":"application/vnd.oci.image.config.v1+json"},"layers":[{"mediaType":"application/vnd.fermyon.spin.application.v1+config"}]}`, true},
+		{"wasm-to-oci", `{"config":{"mediaType":"application/vnd.oci.image.config.v1+json"},"layers":[{"mediaType":"application/vnd.module.wasm.content.layer.v1+wasm"}]}`, true},
+		{"artifact type", `{"artifactType":"application/vnd.wasm.component.v1+wasm","config":{"mediaType":"application/vnd.oci.empty.v1+json"}}`, true},
+		{"container image", `{"config":{"mediaType":"application/vnd.oci.image.config.v1+json"},"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip"}]}`, false},
+		{"invalid", `not json`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWasmArtifact([]byte(tt.manifest)); got != tt.want {
+				t.Errorf("isWasmArtifact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImagePlatformsWasmArtifact(t *testing.T) {
+	server := httptest.NewServer(ggcrregistry.New())
+	defer server.Close()
+
+	image := strings.TrimPrefix(server.URL, "http://") + "/hello-spin:v1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, wasmArtifact(t)); err != nil {
+		t.Fatalf("failed to seed registry: %v", err)
+	}
+
+	platforms, err := ImagePlatforms(context.Background(), image)
+	if err != nil {
+		t.Fatalf("ImagePlatforms returned error: %v", err)
+	}
+	if len(platforms) != 1 || platforms[0] != Wasm {
+		t.Errorf("ImagePlatforms() = %v, want [%s]", platforms, Wasm)
+	}
+
+	err = ValidatePlatform(context.Background(), image, LinuxAMD64, "Cloud Run", "")
+	if err == nil || !strings.Contains(err.Error(), "is a WebAssembly artifact, but Cloud Run runs only linux/amd64 containers") {
+		t.Errorf("Expected unsupported WebAssembly error, got: %v", err)
+	}
+	if err := ValidatePlatform(context.Background(), image, Wasm, "RuntimeClass wasmtime-spin-v2", ""); err != nil {
+		t.Errorf("Expected the artifact to run on a Wasm target, got: %v", err)
+	}
+}
+
+func TestCheckPlatformsWasm(t *testing.T) {
+	// Docker's Wasm images are platform wasi/wasm or wasip1/wasm
+	if err := checkPlatforms("app:wasm", []Platform{normalizePlatform("wasi", "wasm")}, Wasm, "RuntimeClass spin", ""); err != nil {
+		t.Errorf("Expected wasi/wasm image to match, got: %v", err)
+	}
+
+	err := checkPlatforms("app:latest", []Platform{LinuxAMD64}, Wasm, "RuntimeClass spin", "")
+	if err == nil || !strings.Contains(err.Error(), "is a container image for linux/amd64") || !strings.Contains(err.Error(), "artifact_type: wasm") {
+		t.Errorf("Expected container image error, got: %v", err)
+	}
+
+	// An index with both Linux and Wasm variants is judged as a container image
+	err = checkPlatforms("app:latest", []Platform{{OS: "linux", Architecture: "arm64"}, Wasm}, LinuxAMD64, "Cloud Run", "")
+	if err == nil || strings.Contains(err.Error(), "WebAssembly") || !strings.Contains(err.Error(), "--platform linux/amd64") {
+		t.Errorf("Expected architecture mismatch error, got: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+
+	"github.com/jvreagan/cloud-deploy/pkg/credentials"
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// keyVaultAPI is the subset of the Key Vault secrets client used by the resolver.
+type keyVaultAPI interface {
+	GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+}
+
+// azureKeyVaultResolver reads secrets from Azure Key Vault with the default
+// Azure credential chain, with one client per vault.
+type azureKeyVaultResolver struct {
+	clients map[string]keyVaultAPI
+}
+
+// Fetch returns the value of a version of a Key Vault secret.
+func (r *azureKeyVaultResolver) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	secretURL := ref.KeyVaultSecretURL()
+	if secretURL == "" {
+		return "", fmt.Errorf("secret %s has no key_vault_uri", ref.SecretID)
+	}
+	vaultURL, name, version, err := credentials.SplitKeyVaultSecretURL(secretURL)
+	if err != nil {
+		return "", err
+	}
+	if ref.Version != "" {
+		version = ref.Version
+	}
+
+	client, ok := r.clients[vaultURL]
+	if !ok {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+		if client, err = azsecrets.NewClient(vaultURL, cred, nil); err != nil {
+			return "", fmt.Errorf("failed to create Key Vault client: %w", err)
+		}
+		r.clients[vaultURL] = client
+	}
+
+	result, err := client.GetSecret(ctx, name, version, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve secret %s: %w", secretURL, err)
+	}
+	if result.Value == nil {
+		return "", fmt.Errorf("secret %s has no value", secretURL)
+	}
+	return *result.Value, nil
+}
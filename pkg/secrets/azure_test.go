@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeKeyVault serves secrets from a map keyed by name and version.
+type fakeKeyVault map[string]string
+
+func (f fakeKeyVault) GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	value, ok := f[name+"/"+version]
+	if !ok {
+		return azsecrets.GetSecretResponse{}, errors.New("SecretNotFound")
+	}
+	return azsecrets.GetSecretResponse{Secret: azsecrets.Secret{Value: &value}}, nil
+}
+
+func TestAzureKeyVaultFetch(t *testing.T) {
+	r := &azureKeyVaultResolver{clients: map[string]keyVaultAPI{
+		"https://my-vault.vault.azure.net": fakeKeyVault{"db/": "latest", "db/abc123": "pinned"},
+	}}
+
+	tests := []struct {
+		ref  manifest.SecretRef
+		want string
+	}{
+		{manifest.SecretRef{SecretID: "db", KeyVaultURI: "https://my-vault.vault.azure.net/"}, "latest"},
+		{manifest.SecretRef{SecretID: "db", KeyVaultURI: "https://my-vault.vault.azure.net", Version: "abc123"}, "pinned"},
+		{manifest.SecretRef{SecretID: "https://my-vault.vault.azure.net/secrets/db/abc123"}, "pinned"},
+	}
+	for _, tt := range tests {
+		if got, err := r.Fetch(context.Background(), tt.ref); err != nil || got != tt.want {
+			t.Errorf("Fetch(%+v) = %q, %v; want %q", tt.ref, got, err, tt.want)
+		}
+	}
+	for _, ref := range []manifest.SecretRef{{SecretID: "db"}, {SecretID: "https://my-vault.vault.azure.net/keys/db"}} {
+		if _, err := r.Fetch(context.Background(), ref); err == nil {
+			t.Errorf("Expected an error for %+v", ref)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/googleapis/gax-go/v2"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// secretVersionAccessor is the subset of the Secret Manager client used by the resolver.
+type secretVersionAccessor interface {
+	AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error)
+	Close() error
+}
+
+// gcpSecretManagerResolver reads secrets from Google Secret Manager with
+// Application Default Credentials. The client is created on the first fetch.
+type gcpSecretManagerResolver struct {
+	client secretVersionAccessor
+}
+
+// Fetch returns the payload of a version of a Secret Manager secret.
+func (r *gcpSecretManagerResolver) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	name := ref.GCPSecretName("")
+	if name == "" {
+		return "", fmt.Errorf("secret %s has no project", ref.SecretID)
+	}
+	version := ref.Version
+	if version == "" {
+		version = "latest"
+	}
+
+	if r.client == nil {
+		client, err := secretmanager.NewClient(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to create Secret Manager client: %w", err)
+		}
+		r.client = client
+	}
+
+	result, err := r.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: name + "/versions/" + version,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret %s version %s: %w", name, version, err)
+	}
+	return string(result.GetPayload().GetData()), nil
+}
+
+// Close closes the Secret Manager client.
+func (r *gcpSecretManagerResolver) Close() error {
+	if r.client == nil {
+		return nil
+	}
+	return r.client.Close()
+}
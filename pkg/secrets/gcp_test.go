@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/googleapis/gax-go/v2"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeSecretVersions serves secret versions from a map keyed by version name.
+type fakeSecretVersions struct {
+	versions map[string]string
+	closed   bool
+}
+
+func (f *fakeSecretVersions) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	value, ok := f.versions[req.Name]
+	if !ok {
+		return nil, context.DeadlineExceeded
+	}
+	return &secretmanagerpb.AccessSecretVersionResponse{Name: req.Name, Payload: &secretmanagerpb.SecretPayload{Data: []byte(value)}}, nil
+}
+
+func (f *fakeSecretVersions) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestGCPSecretManagerFetch(t *testing.T) {
+	fake := &fakeSecretVersions{versions: map[string]string{
+		"projects/my-project/secrets/db/versions/latest": `{"password":"latest"}`,
+		"projects/other/secrets/api/versions/3":          "pinned",
+	}}
+	r := &gcpSecretManagerResolver{client: fake}
+
+	tests := []struct {
+		ref  manifest.SecretRef
+		want string
+	}{
+		{manifest.SecretRef{SecretID: "db", Project: "my-project"}, `{"password":"latest"}`},
+		{manifest.SecretRef{SecretID: "api", Project: "other", Version: "3"}, "pinned"},
+		{manifest.SecretRef{SecretID: "projects/other/secrets/api", Version: "3"}, "pinned"},
+	}
+	for _, tt := range tests {
+		if got, err := r.Fetch(context.Background(), tt.ref); err != nil || got != tt.want {
+			t.Errorf("Fetch(%+v) = %q, %v; want %q", tt.ref, got, err, tt.want)
+		}
+	}
+	if _, err := r.Fetch(context.Background(), manifest.SecretRef{SecretID: "db"}); err == nil {
+		t.Error("Expected an error for a secret without a project")
+	}
+
+	r.Close()
+	if !fake.closed {
+		t.Error("Expected the client to be closed")
+	}
+}
+
+func TestResolveGCPSecretProject(t *testing.T) {
+	r := &countingResolver{values: map[string]string{"db": "secret"}}
+	original := NewResolver
+	NewResolver = func(ctx context.Context, source string, m *manifest.Manifest) (Resolver, error) {
+		return resolverFunc(func(ctx context.Context, ref manifest.SecretRef) (string, error) {
+			if source != SourceGCPSecretManager || ref.Project != "my-project" {
+				t.Errorf("Unexpected %s secret %+v", source, ref)
+			}
+			return r.Fetch(ctx, ref)
+		}), nil
+	}
+	t.Cleanup(func() { NewResolver = original })
+
+	m := &manifest.Manifest{
+		Provider: manifest.ProviderConfig{Name: "gcp", ProjectID: "my-project"},
+		Secrets:  []manifest.SecretRef{{Name: "DB_PASSWORD", Source: SourceGCPSecretManager, SecretID: "db"}},
+	}
+	values, err := Resolve(context.Background(), m)
+	if err != nil || values["DB_PASSWORD"] != "secret" {
+		t.Errorf("Resolve() = %v, %v", values, err)
+	}
+}
+
+// resolverFunc adapts a function to a Resolver.
+type resolverFunc func(ctx context.Context, ref manifest.SecretRef) (string, error)
+
+func (f resolverFunc) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	return f(ctx, ref)
+}
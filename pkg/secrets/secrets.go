@@ -0,0 +1,267 @@
+// Package secrets resolves the secret references in a manifest's secrets section
+// into environment variable values, so secret values never have to be written
+// into the manifest itself.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+)
+
+// Secret sources.
+const (
+	// SourceSecretsManager is the AWS Secrets Manager secret source (the default).
+	SourceSecretsManager = manifest.SecretSourceSecretsManager
+
+	// SourceGCPSecretManager is the Google Secret Manager secret source.
+	SourceGCPSecretManager = manifest.SecretSourceGCPSecretManager
+
+	// SourceAzureKeyVault is the Azure Key Vault secret source.
+	SourceAzureKeyVault = manifest.SecretSourceAzureKeyVault
+
+	// SourceVault is the HashiCorp Vault secret source, configured by the
+	// manifest's vault section.
+	SourceVault = manifest.SecretSourceVault
+)
+
+// Resolver fetches the raw value of a secret from a secret store.
+type Resolver interface {
+	// Fetch returns the secret's value as stored (a string or a JSON document).
+	Fetch(ctx context.Context, ref manifest.SecretRef) (string, error)
+}
+
+// prefetcher is a Resolver that reads many secrets at once. Resolve passes it
+// every reference of its source before fetching them one by one.
+type prefetcher interface {
+	Prefetch(ctx context.Context, refs []manifest.SecretRef) error
+}
+
+// NewResolver creates a resolver for a secret source of the manifest m. Secrets
+// Manager secrets that do not set their own region are read in the provider's
+// region for AWS providers.
+var NewResolver = func(ctx context.Context, source string, m *manifest.Manifest) (Resolver, error) {
+	switch source {
+	case "", SourceSecretsManager:
+		region := ""
+		if m.Provider.Cloud() == "aws" {
+			region = m.Provider.Region
+		}
+		return &secretsManagerResolver{
+			defaultRegion: region,
+			clients:       make(map[string]secretsManagerAPI),
+		}, nil
+	case SourceGCPSecretManager:
+		return &gcpSecretManagerResolver{}, nil
+	case SourceAzureKeyVault:
+		return &azureKeyVaultResolver{clients: make(map[string]keyVaultAPI)}, nil
+	case SourceVault:
+		if m.Vault == nil {
+			return nil, fmt.Errorf("vault configuration is required for vault secrets")
+		}
+		client, err := vault.Shared(*m.Vault.ClientConfig())
+		if err != nil {
+			return nil, err
+		}
+		return &vaultResolver{client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret source: %s", source)
+	}
+}
+
+// Resolve returns the value of every secret in the manifest, keyed by environment
+// variable name. Each secret is fetched once even when several keys are read from it.
+func Resolve(ctx context.Context, m *manifest.Manifest) (map[string]string, error) {
+	values := make(map[string]string, len(m.Secrets))
+	if len(m.Secrets) == 0 {
+		return values, nil
+	}
+
+	project := ""
+	if m.Provider.Cloud() == "gcp" {
+		project = m.Provider.ProjectID
+	}
+
+	resolvers := make(map[string]Resolver)
+	defer func() {
+		for _, r := range resolvers {
+			if c, ok := r.(io.Closer); ok {
+				c.Close()
+			}
+		}
+	}()
+	refs := make([]manifest.SecretRef, len(m.Secrets))
+	bySource := make(map[string][]manifest.SecretRef)
+	for i, ref := range m.Secrets {
+		source := ref.SecretSource()
+		if source == SourceGCPSecretManager && ref.Project == "" {
+			ref.Project = project
+		}
+		refs[i] = ref
+
+		if _, ok := resolvers[source]; !ok {
+			resolver, err := NewResolver(ctx, source, m)
+			if err != nil {
+				return nil, err
+			}
+			resolvers[source] = resolver
+		}
+		bySource[source] = append(bySource[source], ref)
+	}
+	for source, resolver := range resolvers {
+		if p, ok := resolver.(prefetcher); ok {
+			if err := p.Prefetch(ctx, bySource[source]); err != nil {
+				return nil, fmt.Errorf("failed to resolve %s secrets: %w", source, err)
+			}
+		}
+	}
+
+	fetched := make(map[string]string)
+	for _, ref := range refs {
+		source := ref.SecretSource()
+		resolver := resolvers[source]
+
+		cacheKey := source + "|" + ref.Region + "|" + ref.Project + "|" + ref.KeyVaultURI + "|" + ref.SecretID + "|" + ref.Version + "|" + ref.VaultPath
+		raw, ok := fetched[cacheKey]
+		if !ok {
+			var err error
+			raw, err = resolver.Fetch(ctx, ref)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve secret %s: %w", ref.Name, err)
+			}
+			fetched[cacheKey] = raw
+		}
+
+		key := ref.Key
+		if ref.VaultKey != "" {
+			key = ref.VaultKey
+		}
+		value, err := extractKey(raw, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve secret %s: %w", ref.Name, err)
+		}
+		values[ref.Name] = value
+	}
+	return values, nil
+}
+
+// Apply resolves the manifest's secrets and merges them into its environment variables
+// (and the primary container's, for multi-container deployments). Secrets take
+// precedence over plain environment variables with the same name.
+func Apply(ctx context.Context, m *manifest.Manifest) error {
+	if len(m.Secrets) == 0 {
+		return nil
+	}
+
+	values, err := Resolve(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	if m.EnvironmentVariables == nil {
+		m.EnvironmentVariables = make(map[string]string, len(values))
+	}
+	for name, value := range values {
+		m.EnvironmentVariables[name] = value
+	}
+
+	if m.IsMultiContainer() {
+		if m.Containers[0].Environment == nil {
+			m.Containers[0].Environment = make(map[string]string, len(values))
+		}
+		for name, value := range values {
+			m.Containers[0].Environment[name] = value
+		}
+	}
+	return nil
+}
+
+// extractKey returns the field key of a JSON secret, or the whole secret when key is empty.
+func extractKey(raw, key string) (string, error) {
+	if key == "" {
+		return raw, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot read key %q", key)
+	}
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret", key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode key %q: %w", key, err)
+	}
+	return string(encoded), nil
+}
+
+// secretsManagerAPI is the subset of the Secrets Manager client used by the resolver.
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// secretsManagerResolver reads secrets from AWS Secrets Manager, with one client per region.
+type secretsManagerResolver struct {
+	defaultRegion string
+	clients       map[string]secretsManagerAPI
+}
+
+// Fetch returns the SecretString of a Secrets Manager secret.
+func (r *secretsManagerResolver) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	region := ref.Region
+	if region == "" {
+		region = r.defaultRegion
+	}
+
+	client, ok := r.clients[region]
+	if !ok {
+		var opts []func(*config.LoadOptions) error
+		if region != "" {
+			opts = append(opts, config.WithRegion(region))
+		}
+		cfg, err := config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return "", fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		client = secretsmanager.NewFromConfig(cfg)
+		r.clients[region] = client
+	}
+
+	result, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(ref.SecretID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve secret %s: %w", ref.SecretID, err)
+	}
+	if result.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", ref.SecretID)
+	}
+	return *result.SecretString, nil
+}
+
+// Changed returns the sorted names of the variables in desired whose values differ
+// from (or are missing in) current.
+func Changed(current, desired map[string]string) []string {
+	var changed []string
+	for name, value := range desired {
+		if existing, ok := current[name]; !ok || existing != value {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,178 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// countingResolver serves secrets from a map and counts fetches.
+type countingResolver struct {
+	values  map[string]string
+	fetches int
+}
+
+func (r *countingResolver) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	r.fetches++
+	v, ok := r.values[ref.SecretID]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func withResolver(t *testing.T, r Resolver) {
+	t.Helper()
+	original := NewResolver
+	NewResolver = func(ctx context.Context, source string, m *manifest.Manifest) (Resolver, error) {
+		if source != SourceSecretsManager {
+			return nil, errors.New("unexpected source " + source)
+		}
+		return r, nil
+	}
+	t.Cleanup(func() { NewResolver = original })
+}
+
+func TestResolve(t *testing.T) {
+	r := &countingResolver{values: map[string]string{
+		"myapp/db":  `{"host":"db.internal","port":5432}`,
+		"myapp/api": "plain-api-key",
+	}}
+	withResolver(t, r)
+
+	m := &manifest.Manifest{Secrets: []manifest.SecretRef{
+		{Name: "DB_HOST", SecretID: "myapp/db", Key: "host"},
+		{Name: "DB_PORT", SecretID: "myapp/db", Key: "port"},
+		{Name: "API_KEY", SecretID: "myapp/api"},
+	}}
+
+	values, err := Resolve(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	expected := map[string]string{"DB_HOST": "db.internal", "DB_PORT": "5432", "API_KEY": "plain-api-key"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+	if r.fetches != 2 {
+		t.Errorf("Expected each secret to be fetched once (2 fetches), got %d", r.fetches)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	withResolver(t, &countingResolver{values: map[string]string{"myapp/db": `{"host":"db"}`}})
+
+	tests := []struct {
+		ref     manifest.SecretRef
+		wantErr string
+	}{
+		{manifest.SecretRef{Name: "X", SecretID: "missing"}, "not found"},
+		{manifest.SecretRef{Name: "X", SecretID: "myapp/db", Key: "password"}, `key "password" not found`},
+		{manifest.SecretRef{Name: "X", SecretID: "myapp/db", Source: "unknown"}, "unexpected source"},
+	}
+	for _, tt := range tests {
+		_, err := Resolve(context.Background(), &manifest.Manifest{Secrets: []manifest.SecretRef{tt.ref}})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	withResolver(t, &countingResolver{values: map[string]string{"myapp/api": "secret"}})
+
+	m := &manifest.Manifest{
+		EnvironmentVariables: map[string]string{"API_KEY": "placeholder", "LOG_LEVEL": "info"},
+		Containers:           []manifest.Container{{Name: "web"}, {Name: "worker"}},
+		Secrets:              []manifest.SecretRef{{Name: "API_KEY", SecretID: "myapp/api"}},
+	}
+
+	if err := Apply(context.Background(), m); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if m.EnvironmentVariables["API_KEY"] != "secret" || m.EnvironmentVariables["LOG_LEVEL"] != "info" {
+		t.Errorf("Unexpected environment variables: %v", m.EnvironmentVariables)
+	}
+	if m.Containers[0].Environment["API_KEY"] != "secret" {
+		t.Errorf("Expected primary container to receive secret, got %v", m.Containers[0].Environment)
+	}
+	if m.Containers[1].Environment != nil {
+		t.Errorf("Expected other containers to be untouched, got %v", m.Containers[1].Environment)
+	}
+}
+
+func TestExtractKey(t *testing.T) {
+	raw := `{"user":"admin","port":5432,"tls":{"enabled":true}}`
+	tests := map[string]string{
+		"":     raw,
+		"user": "admin",
+		"port": "5432",
+		"tls":  `{"enabled":true}`,
+	}
+	for key, expected := range tests {
+		got, err := extractKey(raw, key)
+		if err != nil || got != expected {
+			t.Errorf("extractKey(%q) = %q, %v; want %q", key, got, err, expected)
+		}
+	}
+
+	if _, err := extractKey("not-json", "user"); err == nil {
+		t.Error("Expected error for non-JSON secret with key")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	current := map[string]string{"A": "1", "B": "2"}
+	desired := map[string]string{"A": "1", "B": "3", "C": "4"}
+
+	if got := Changed(current, desired); !reflect.DeepEqual(got, []string{"B", "C"}) {
+		t.Errorf("Expected [B C], got %v", got)
+	}
+	if got := Changed(current, map[string]string{"A": "1"}); len(got) != 0 {
+		t.Errorf("Expected no changes, got %v", got)
+	}
+}
+
+// fakeSecretsManager is a secretsManagerAPI serving fixed values.
+type fakeSecretsManager struct {
+	values map[string]*string
+}
+
+func (f *fakeSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	v, ok := f.values[aws.ToString(params.SecretId)]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: v}, nil
+}
+
+func TestSecretsManagerResolverFetch(t *testing.T) {
+	r := &secretsManagerResolver{
+		defaultRegion: "us-east-1",
+		clients: map[string]secretsManagerAPI{
+			"us-east-1": &fakeSecretsManager{values: map[string]*string{
+				"myapp/api": aws.String("key"),
+				"binary":    nil,
+			}},
+		},
+	}
+	ctx := context.Background()
+
+	if v, err := r.Fetch(ctx, manifest.SecretRef{SecretID: "myapp/api"}); err != nil || v != "key" {
+		t.Errorf("Expected 'key', got %q (%v)", v, err)
+	}
+	if _, err := r.Fetch(ctx, manifest.SecretRef{SecretID: "binary"}); err == nil || !strings.Contains(err.Error(), "no string value") {
+		t.Errorf("Expected no string value error, got: %v", err)
+	}
+	if _, err := r.Fetch(ctx, manifest.SecretRef{SecretID: "missing"}); err == nil {
+		t.Error("Expected error for missing secret")
+	}
+}
@@ -0,0 +1,51 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+)
+
+// vaultResolver reads secrets from HashiCorp Vault, with the client the
+// command shares, which the command closes as it exits.
+type vaultResolver struct {
+	client *vault.Client
+
+	// prefetched are the fields of the secrets read by Prefetch, by path
+	prefetched map[string]map[string]interface{}
+}
+
+// Prefetch reads the secrets at the distinct vault_paths of refs concurrently,
+// for Fetch to return.
+func (r *vaultResolver) Prefetch(ctx context.Context, refs []manifest.SecretRef) error {
+	paths := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		paths = append(paths, ref.VaultPath)
+	}
+	prefetched, err := r.client.ReadAll(ctx, paths)
+	if err != nil {
+		return err
+	}
+	r.prefetched = prefetched
+	return nil
+}
+
+// Fetch returns the fields of the secret at the reference's vault_path as a
+// JSON object, from which vault_key selects a field.
+func (r *vaultResolver) Fetch(ctx context.Context, ref manifest.SecretRef) (string, error) {
+	fields, ok := r.prefetched[ref.VaultPath]
+	if !ok {
+		var err error
+		if fields, err = r.client.Read(ctx, ref.VaultPath); err != nil {
+			return "", err
+		}
+	}
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode secret %s: %w", ref.VaultPath, err)
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/vault"
+)
+
+func TestResolveVault(t *testing.T) {
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/myapp/db" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		reads++
+		w.Write([]byte(`{"data":{"data":{"host":"db.internal","password":"hunter2"},"metadata":{"version":2}}}`))
+	}))
+	defer server.Close()
+
+	m := &manifest.Manifest{
+		Vault: &manifest.VaultConfig{Address: server.URL, Auth: manifest.VaultAuthConfig{Token: "root"}},
+		Secrets: []manifest.SecretRef{
+			{Name: "DB_HOST", VaultPath: "secret/data/myapp/db", VaultKey: "host"},
+			{Name: "DB_PASSWORD", VaultPath: "secret/data/myapp/db", VaultKey: "password"},
+		},
+	}
+	values, err := Resolve(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if values["DB_HOST"] != "db.internal" || values["DB_PASSWORD"] != "hunter2" {
+		t.Errorf("Unexpected values %v", values)
+	}
+	if reads != 1 {
+		t.Errorf("Expected the secret to be read once, got %d", reads)
+	}
+}
+
+func TestResolveVaultKeepsLeases(t *testing.T) {
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := strings.TrimPrefix(r.URL.Path, "/v1/"); path {
+		case "auth/approle/login":
+			w.Write([]byte(`{"auth":{"client_token":"s.login","lease_duration":3600,"renewable":false}}`))
+		case "database/creds/app":
+			w.Write([]byte(`{"lease_id":"database/creds/app/abc","lease_duration":3600,"data":{"username":"v-app","password":"pw"}}`))
+		case "sys/leases/revoke", "auth/token/revoke-self":
+			revoked = append(revoked, path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	m := &manifest.Manifest{
+		Vault: &manifest.VaultConfig{Address: server.URL, Auth: manifest.VaultAuthConfig{Method: "approle", RoleID: "role", SecretID: "secret"}},
+		Secrets: []manifest.SecretRef{
+			{Name: "DB_PASSWORD", VaultPath: "database/creds/app", VaultKey: "password"},
+		},
+	}
+	values, err := Resolve(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if values["DB_PASSWORD"] != "pw" {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	// The deployment runs with the dynamic credentials after the command exits
+	if err := vault.CloseShared(); err != nil {
+		t.Fatalf("CloseShared() error = %v", err)
+	}
+	if len(revoked) > 0 {
+		t.Errorf("Expected the injected secret's lease to survive, got revocations %v", revoked)
+	}
+}
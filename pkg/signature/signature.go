@@ -0,0 +1,178 @@
+// Package signature verifies detached signatures of manifests, so that
+// regulated environments can refuse to deploy a manifest that was not signed
+// by a trusted key or was changed after signing. Manifests are signed with
+// the standard tools:
+//
+//	minisign -Sm deploy-manifest.yaml                      # writes deploy-manifest.yaml.minisig
+//	cosign sign-blob --key cosign.key deploy-manifest.yaml \
+//	    --output-signature deploy-manifest.yaml.sig
+//
+// and verified against the matching public key (minisign.pub or cosign.pub).
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signature file suffixes, appended to the manifest location by default.
+const (
+	MinisignSuffix = ".minisig"
+	CosignSuffix   = ".sig"
+)
+
+// ErrInvalid is returned when a signature does not match the data and key.
+var ErrInvalid = errors.New("signature does not match the manifest (it was modified after signing, or signed with another key)")
+
+// Key is a public key that manifests are verified against.
+type Key struct {
+	// minisign keys
+	minisignID [8]byte
+	minisign   ed25519.PublicKey
+
+	// PEM (cosign) keys: *ecdsa.PublicKey, ed25519.PublicKey, or *rsa.PublicKey
+	pem crypto.PublicKey
+}
+
+// ParseKey parses a minisign public key file or a PEM-encoded public key such
+// as cosign.pub.
+func ParseKey(data []byte) (*Key, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unsupported PEM block %q (expected PUBLIC KEY)", block.Type)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		switch pub.(type) {
+		case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+			return &Key{pem: pub}, nil
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", pub)
+		}
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(minisignLine(data, 0))
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, errors.New("invalid public key: expected a minisign public key or a PEM PUBLIC KEY")
+	}
+	k := &Key{minisign: ed25519.PublicKey(raw[10:])}
+	copy(k.minisignID[:], raw[2:10])
+	return k, nil
+}
+
+// Suffix returns the conventional signature file suffix for the key.
+func (k *Key) Suffix() string {
+	if k.minisign != nil {
+		return MinisignSuffix
+	}
+	return CosignSuffix
+}
+
+// Verify checks that sig, the contents of a signature file, is a valid
+// signature of data by the key.
+func (k *Key) Verify(data, sig []byte) error {
+	if k.minisign != nil {
+		return k.verifyMinisign(data, sig)
+	}
+	return k.verifyPEM(data, sig)
+}
+
+// verifyMinisign checks a minisign signature file: the signature of the data
+// (of its BLAKE2b-512 hash for the default prehashed "ED" algorithm) and the
+// global signature covering the trusted comment.
+func (k *Key) verifyMinisign(data, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(minisignLine(sig, 0))
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature file")
+	}
+	if !bytes.Equal(raw[2:10], k.minisignID[:]) {
+		return fmt.Errorf("manifest was signed with minisign key %X, not the trusted key %X", reverse(raw[2:10]), reverse(k.minisignID[:]))
+	}
+
+	message := data
+	switch string(raw[:2]) {
+	case "ED":
+		hash := blake2b.Sum512(data)
+		message = hash[:]
+	case "Ed":
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", raw[:2])
+	}
+	if !ed25519.Verify(k.minisign, message, raw[10:]) {
+		return ErrInvalid
+	}
+
+	comment, ok := strings.CutPrefix(minisignLine(sig, 1), "trusted comment: ")
+	if !ok {
+		return errors.New("invalid minisign signature file: missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(minisignLine(sig, 2))
+	if err != nil || !ed25519.Verify(k.minisign, slices.Concat(raw[10:], []byte(comment)), global) {
+		return errors.New("invalid minisign signature file: trusted comment was modified")
+	}
+	return nil
+}
+
+// verifyPEM checks a base64 signature made by cosign sign-blob, or by another
+// tool signing the SHA-256 digest of the data.
+func (k *Key) verifyPEM(data, sig []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return errors.New("invalid signature file: expected a base64 signature")
+	}
+	digest := sha256.Sum256(data)
+
+	var ok bool
+	switch pub := k.pem.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, digest[:], decoded)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, data, decoded)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], decoded) == nil
+	}
+	if !ok {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// minisignLine returns the nth line of a minisign file that is not an
+// untrusted comment, which minisign ignores.
+func minisignLine(data []byte, n int) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		if n == 0 {
+			return line
+		}
+		n--
+	}
+	return ""
+}
+
+// reverse returns a key ID in the byte order minisign prints it.
+func reverse(id []byte) []byte {
+	out := make([]byte, len(id))
+	for i, b := range id {
+		out[len(id)-1-i] = b
+	}
+	return out
+}
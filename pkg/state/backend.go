@@ -0,0 +1,185 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"google.golang.org/api/option"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// DefaultPrefix is the key prefix of history objects in s3 and gcs buckets.
+const DefaultPrefix = "cloud-deploy/state"
+
+// Backend stores history documents by key.
+type Backend interface {
+	// Load returns the document stored at key, or nil if there is none.
+	Load(ctx context.Context, key string) ([]byte, error)
+
+	// Save stores data at key, replacing any existing document.
+	Save(ctx context.Context, key string, data []byte) error
+}
+
+// NewBackend creates the backend configured by cfg. defaultRegion is the S3
+// bucket's region when cfg does not set one.
+var NewBackend = func(ctx context.Context, cfg manifest.StateConfig, defaultRegion string) (Backend, error) {
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	switch cfg.Backend {
+	case "", "local":
+		dir := cfg.Path
+		if dir == "" {
+			configDir, err := os.UserConfigDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to find state directory: %w", err)
+			}
+			dir = filepath.Join(configDir, "cloud-deploy", "state")
+		}
+		return &localBackend{dir: dir}, nil
+
+	case "s3":
+		region := cfg.Region
+		if region == "" {
+			region = defaultRegion
+		}
+		var opts []func(*config.LoadOptions) error
+		if region != "" {
+			opts = append(opts, config.WithRegion(region))
+		}
+		awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			if cfg.EndpointURL != "" {
+				o.BaseEndpoint = aws.String(cfg.EndpointURL)
+				o.UsePathStyle = true
+			}
+		})
+		return &s3Backend{client: client, bucket: cfg.Bucket, prefix: prefix}, nil
+
+	case "gcs":
+		var opts []option.ClientOption
+		if cfg.EndpointURL != "" {
+			opts = append(opts, option.WithEndpoint(strings.TrimRight(cfg.EndpointURL, "/")+"/storage/v1/"), option.WithoutAuthentication())
+		}
+		client, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cloud Storage client: %w", err)
+		}
+		return &gcsBackend{bucket: client.Bucket(cfg.Bucket), prefix: prefix}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown state backend: %s", cfg.Backend)
+	}
+}
+
+// localBackend stores documents as files under a directory.
+type localBackend struct {
+	dir string
+}
+
+// Load reads the file of key.
+func (b *localBackend) Load(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(b.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save writes the file of key, creating its directory.
+func (b *localBackend) Save(_ context.Context, key string, data []byte) error {
+	file := filepath.Join(b.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+// s3API is the subset of the S3 client used by the s3 backend.
+type s3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// s3Backend stores documents as objects in an S3 bucket.
+type s3Backend struct {
+	client s3API
+	bucket string
+	prefix string
+}
+
+// Load reads the object of key.
+func (b *s3Backend) Load(ctx context.Context, key string) ([]byte, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+	})
+	var noSuchKey *s3types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+// Save writes the object of key.
+func (b *s3Backend) Save(ctx context.Context, key string, data []byte) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(path.Join(b.prefix, key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// gcsBackend stores documents as objects in a Cloud Storage bucket.
+type gcsBackend struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+// Load reads the object of key.
+func (b *gcsBackend) Load(ctx context.Context, key string) ([]byte, error) {
+	r, err := b.bucket.Object(path.Join(b.prefix, key)).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Save writes the object of key.
+func (b *gcsBackend) Save(ctx context.Context, key string, data []byte) error {
+	w := b.bucket.Object(path.Join(b.prefix, key)).NewWriter(ctx)
+	w.ContentType = "application/json"
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,124 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// fakeS3 keeps objects in memory.
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := f.objects[*in.Bucket+"/"+*in.Key]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*in.Bucket+"/"+*in.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestLocalBackend(t *testing.T) {
+	ctx := context.Background()
+	b := &localBackend{dir: filepath.Join(t.TempDir(), "state")}
+
+	if data, err := b.Load(ctx, "aws/app/env.json"); data != nil || err != nil {
+		t.Errorf("Load of a missing key = %q, %v; want nil, nil", data, err)
+	}
+	if err := b.Save(ctx, "aws/app/env.json", []byte("[]")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if data, err := b.Load(ctx, "aws/app/env.json"); string(data) != "[]" || err != nil {
+		t.Errorf("Load = %q, %v; want []", data, err)
+	}
+}
+
+func TestS3Backend(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeS3{objects: make(map[string][]byte)}
+	b := &s3Backend{client: client, bucket: "my-bucket", prefix: DefaultPrefix}
+
+	if data, err := b.Load(ctx, "aws/app/env.json"); data != nil || err != nil {
+		t.Errorf("Load of a missing key = %q, %v; want nil, nil", data, err)
+	}
+	if err := b.Save(ctx, "aws/app/env.json", []byte("[]")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, ok := client.objects["my-bucket/cloud-deploy/state/aws/app/env.json"]; !ok {
+		t.Errorf("object not stored under the prefix: %v", client.objects)
+	}
+	if data, err := b.Load(ctx, "aws/app/env.json"); string(data) != "[]" || err != nil {
+		t.Errorf("Load = %q, %v; want []", data, err)
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	ctx := context.Background()
+
+	b, err := NewBackend(ctx, manifest.StateConfig{Path: "/tmp/state"}, "")
+	if err != nil {
+		t.Fatalf("NewBackend failed: %v", err)
+	}
+	if local, ok := b.(*localBackend); !ok || local.dir != "/tmp/state" {
+		t.Errorf("default backend = %#v, want local in /tmp/state", b)
+	}
+
+	b, err = NewBackend(ctx, manifest.StateConfig{Backend: "s3", Bucket: "my-bucket", Prefix: "deploys"}, "us-west-2")
+	if err != nil {
+		t.Fatalf("NewBackend failed: %v", err)
+	}
+	if s3b, ok := b.(*s3Backend); !ok || s3b.bucket != "my-bucket" || s3b.prefix != "deploys" {
+		t.Errorf("s3 backend = %#v", b)
+	}
+
+	b, err = NewBackend(ctx, manifest.StateConfig{Backend: "s3", Bucket: "my-bucket", EndpointURL: "http://localhost:4566"}, "us-east-1")
+	if err != nil {
+		t.Fatalf("NewBackend failed: %v", err)
+	}
+	if opts := b.(*s3Backend).client.(*s3.Client).Options(); opts.BaseEndpoint == nil || *opts.BaseEndpoint != "http://localhost:4566" || !opts.UsePathStyle {
+		t.Errorf("Expected path-style requests to the emulator, got %v, %v", opts.BaseEndpoint, opts.UsePathStyle)
+	}
+
+	m := &manifest.Manifest{
+		Provider: manifest.ProviderConfig{Name: "aws-ecs", Region: "us-east-1", EndpointURL: "http://localhost:4566"},
+		State:    &manifest.StateConfig{Backend: "s3", Bucket: "my-bucket"},
+	}
+	old := NewBackend
+	defer func() { NewBackend = old }()
+	var got manifest.StateConfig
+	NewBackend = func(ctx context.Context, cfg manifest.StateConfig, defaultRegion string) (Backend, error) {
+		got = cfg
+		return &localBackend{}, nil
+	}
+	manifestBackend(ctx, m)
+	if got.EndpointURL != "http://localhost:4566" {
+		t.Errorf("Expected the bucket of an emulated cloud to use its emulator, got %q", got.EndpointURL)
+	}
+	m.State.Backend = "gcs"
+	manifestBackend(ctx, m)
+	if got.EndpointURL != "" {
+		t.Errorf("Expected a bucket of another cloud not to use the emulator, got %q", got.EndpointURL)
+	}
+	NewBackend = old
+
+	if _, err := NewBackend(ctx, manifest.StateConfig{Backend: "dynamodb"}, ""); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Skew is how far the running cloud-deploy is from the version that last
+// deployed an environment.
+type Skew int
+
+const (
+	// SkewNone: the same version, a newer patch release, or a version that
+	// cannot be compared (such as a development build).
+	SkewNone Skew = iota
+
+	// SkewMinor: a different minor release, or an older release. Resources
+	// are compatible, but the environment may be changed in ways the other
+	// version would not.
+	SkewMinor
+
+	// SkewMajor: a different major release, or a different minor release
+	// before 1.0, which may change the resource model incompatibly.
+	SkewMajor
+)
+
+// VersionSkew compares the version of cloud-deploy that last deployed an
+// environment with the running version.
+func VersionSkew(recorded, running string) Skew {
+	r, ok := parseVersion(recorded)
+	if !ok {
+		return SkewNone
+	}
+	c, ok := parseVersion(running)
+	if !ok {
+		return SkewNone
+	}
+
+	switch {
+	case r[0] != c[0], r[0] == 0 && r[1] != c[1]:
+		return SkewMajor
+	case r[1] != c[1], c[2] < r[2]:
+		return SkewMinor
+	default:
+		return SkewNone
+	}
+}
+
+// parseVersion parses a semantic version such as v1.2.3 or 1.2.3-rc.1,
+// ignoring any pre-release or build suffix.
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// LastToolVersion returns the version of cloud-deploy that recorded the
+// newest version of the environment, or "" if none is recorded.
+func (s *Store) LastToolVersion(ctx context.Context) (string, error) {
+	records, err := s.History(ctx)
+	if err != nil {
+		return "", err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if v := records[i].ToolVersion; v != "" {
+			return v, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVersionSkew(t *testing.T) {
+	tests := []struct {
+		recorded, running string
+		want              Skew
+	}{
+		{"1.2.3", "1.2.3", SkewNone},
+		{"v1.2.3", "1.2.4", SkewNone},
+		{"1.2.4", "1.2.3", SkewMinor},
+		{"1.2.3", "1.3.0", SkewMinor},
+		{"1.3.0", "1.2.9", SkewMinor},
+		{"1.9.0", "2.0.0", SkewMajor},
+		{"2.0.0", "1.9.0", SkewMajor},
+		{"0.3.1", "0.4.0", SkewMajor},
+		{"0.4.0-rc.1", "v0.4.2+dirty", SkewNone},
+		{"0.4.0", "dev", SkewNone},
+		{"", "1.0.0", SkewNone},
+	}
+	for _, tt := range tests {
+		if got := VersionSkew(tt.recorded, tt.running); got != tt.want {
+			t.Errorf("VersionSkew(%q, %q) = %d, want %d", tt.recorded, tt.running, got, tt.want)
+		}
+	}
+}
+
+func TestLastToolVersion(t *testing.T) {
+	ctx := context.Background()
+	s, _ := testStore(t)
+
+	if v, err := s.LastToolVersion(ctx); err != nil || v != "" {
+		t.Fatalf("LastToolVersion of a new environment = %q, %v; want empty", v, err)
+	}
+
+	s.Record(ctx, Record{Command: CommandDeploy, ToolVersion: "0.3.0"})
+	s.Record(ctx, Record{Command: CommandDeploy, ToolVersion: "0.4.1"})
+	// Versions recorded before tool versions were tracked are skipped
+	s.Record(ctx, Record{Command: CommandRollback})
+
+	if v, err := s.LastToolVersion(ctx); err != nil || v != "0.4.1" {
+		t.Errorf("LastToolVersion = %q, %v; want 0.4.1", v, err)
+	}
+}
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// Operations journals the long-running provider operation an environment has
+// in progress, so that another invocation, possibly by another operator, can
+// attach to it. It implements types.OperationJournal.
+type Operations struct {
+	backend     Backend
+	key         string
+	command     string
+	toolVersion string
+	operator    string
+	now         func() time.Time
+}
+
+// OpenOperations returns the operation journal of m's environment, in the
+// backend configured by the manifest's state section. Operations begun
+// through it are recorded as started by command with cloud-deploy toolVersion.
+func OpenOperations(ctx context.Context, m *manifest.Manifest, command, toolVersion string) (*Operations, error) {
+	backend, err := manifestBackend(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Operations{
+		backend:     backend,
+		key:         path.Join("operations", m.Provider.Name, m.Application.Name, m.Environment.Name+".json"),
+		command:     command,
+		toolVersion: toolVersion,
+		operator:    operator(),
+		now:         time.Now,
+	}, nil
+}
+
+// operator returns the user and host cloud-deploy runs as.
+func operator() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
+
+// Current returns the operation in progress, or nil when there is none.
+func (o *Operations) Current(ctx context.Context) (*types.Operation, error) {
+	data, err := o.backend.Load(ctx, o.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation journal: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var op *types.Operation
+	if err := json.Unmarshal(data, &op); err != nil {
+		return nil, fmt.Errorf("failed to parse operation journal %s: %w", o.key, err)
+	}
+	return op, nil
+}
+
+// Begin records op as in progress, with the journal's command, tool version,
+// and operator.
+func (o *Operations) Begin(ctx context.Context, op types.Operation) error {
+	op.Command = o.command
+	op.ToolVersion = o.toolVersion
+	op.Operator = o.operator
+	op.Started = o.now().UTC()
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := o.backend.Save(ctx, o.key, data); err != nil {
+		return fmt.Errorf("failed to write operation journal: %w", err)
+	}
+	return nil
+}
+
+// End records that no operation is in progress. Backends cannot delete, so
+// the journal is left holding null.
+func (o *Operations) End(ctx context.Context) error {
+	if err := o.backend.Save(ctx, o.key, []byte("null")); err != nil {
+		return fmt.Errorf("failed to write operation journal: %w", err)
+	}
+	return nil
+}
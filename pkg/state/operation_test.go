@@ -0,0 +1,72 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func testOperations(t *testing.T) (*Operations, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "gcp", Region: "us-central1"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-staging"},
+		State:       &manifest.StateConfig{Path: dir},
+	}
+	o, err := OpenOperations(context.Background(), m, "deploy", "1.4.0")
+	if err != nil {
+		t.Fatalf("OpenOperations failed: %v", err)
+	}
+	o.operator = "alice@build-1"
+	o.now = func() time.Time { return time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) }
+	return o, dir
+}
+
+func TestOperations(t *testing.T) {
+	ctx := context.Background()
+	o, dir := testOperations(t)
+
+	if op, err := o.Current(ctx); op != nil || err != nil {
+		t.Fatalf("Current of a new journal = %v, %v; want none", op, err)
+	}
+
+	if err := o.Begin(ctx, types.Operation{Provider: "gcp", Kind: "service-update", ID: "projects/p/locations/l/operations/123"}); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	op, err := o.Current(ctx)
+	if err != nil || op == nil {
+		t.Fatalf("Current = %v, %v; want the operation", op, err)
+	}
+	if op.ID != "projects/p/locations/l/operations/123" || op.Command != "deploy" || op.ToolVersion != "1.4.0" || op.Operator != "alice@build-1" || op.Started.IsZero() {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "operations", "gcp", "my-app", "my-app-staging.json")); err != nil {
+		t.Errorf("Expected the journal to be stored per environment: %v", err)
+	}
+
+	if err := o.End(ctx); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+	if op, err := o.Current(ctx); op != nil || err != nil {
+		t.Errorf("Current after End = %v, %v; want none", op, err)
+	}
+}
+
+func TestOperationsCorrupt(t *testing.T) {
+	o, dir := testOperations(t)
+	path := filepath.Join(dir, "operations", "gcp", "my-app")
+	os.MkdirAll(path, 0o755)
+	os.WriteFile(filepath.Join(path, "my-app-staging.json"), []byte("{"), 0o644)
+
+	if _, err := o.Current(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to parse operation journal") {
+		t.Errorf("Expected a parse error, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Promotion is an image copied from one tag to another by the image promote
+// command.
+type Promotion struct {
+	// Image reference promoted from
+	Source string `json:"source"`
+
+	// Tag promoted to
+	Target string `json:"target"`
+
+	// Digest of the promoted image, which deploys of Target are pinned to
+	Digest string `json:"digest"`
+
+	// Version of cloud-deploy that promoted the image
+	ToolVersion string `json:"tool_version,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Pinned returns Target pinned to the promoted digest.
+func (p Promotion) Pinned() string {
+	ref, err := name.ParseReference(p.Target)
+	if err != nil {
+		return p.Target
+	}
+	return ref.Context().Name() + "@" + p.Digest
+}
+
+// Promotions reads and writes the image promotions of an application, which
+// are shared by all of its environments.
+type Promotions struct {
+	backend Backend
+	key     string
+	now     func() time.Time
+}
+
+// OpenPromotions returns the promotion log of m's application, in the backend
+// configured by the manifest's state section.
+func OpenPromotions(ctx context.Context, m *manifest.Manifest) (*Promotions, error) {
+	backend, err := manifestBackend(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Promotions{
+		backend: backend,
+		key:     path.Join("promotions", m.Application.Name+".json"),
+		now:     time.Now,
+	}, nil
+}
+
+// List returns the recorded promotions, oldest first.
+func (p *Promotions) List(ctx context.Context) ([]Promotion, error) {
+	data, err := p.backend.Load(ctx, p.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image promotions: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var promotions []Promotion
+	if err := json.Unmarshal(data, &promotions); err != nil {
+		return nil, fmt.Errorf("failed to parse image promotions %s: %w", p.key, err)
+	}
+	return promotions, nil
+}
+
+// Record adds promo to the log and returns it with its timestamp set.
+func (p *Promotions) Record(ctx context.Context, promo Promotion) (Promotion, error) {
+	promotions, err := p.List(ctx)
+	if err != nil {
+		return Promotion{}, err
+	}
+
+	promo.Timestamp = p.now().UTC()
+	promotions = append(promotions, promo)
+	if len(promotions) > maxRecords {
+		promotions = promotions[len(promotions)-maxRecords:]
+	}
+
+	data, err := json.MarshalIndent(promotions, "", "  ")
+	if err != nil {
+		return Promotion{}, err
+	}
+	if err := p.backend.Save(ctx, p.key, data); err != nil {
+		return Promotion{}, fmt.Errorf("failed to write image promotions: %w", err)
+	}
+	return promo, nil
+}
+
+// Latest returns the newest promotion to image, which may be written in any
+// equivalent form (e.g., "my-app:prod" for "index.docker.io/library/my-app:prod").
+// ok is false when image was never promoted to.
+func (p *Promotions) Latest(ctx context.Context, image string) (promo Promotion, ok bool, err error) {
+	promotions, err := p.List(ctx)
+	if err != nil {
+		return Promotion{}, false, err
+	}
+	target := normalizeReference(image)
+	for i := len(promotions) - 1; i >= 0; i-- {
+		if normalizeReference(promotions[i].Target) == target {
+			return promotions[i], true, nil
+		}
+	}
+	return Promotion{}, false, nil
+}
+
+// normalizeReference returns the fully qualified form of an image reference,
+// or the reference itself when it cannot be parsed.
+func normalizeReference(image string) string {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return image
+	}
+	return ref.Name()
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func testPromotions(t *testing.T) (*Promotions, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "gcp", Region: "us-central1"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-staging"},
+		State:       &manifest.StateConfig{Path: dir},
+	}
+	p, err := OpenPromotions(context.Background(), m)
+	if err != nil {
+		t.Fatalf("OpenPromotions failed: %v", err)
+	}
+	p.now = func() time.Time { return time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) }
+	return p, dir
+}
+
+func TestPromotions(t *testing.T) {
+	ctx := context.Background()
+	p, dir := testPromotions(t)
+
+	if _, ok, err := p.Latest(ctx, "my-app:prod"); ok || err != nil {
+		t.Fatalf("Latest of a new log = %v, %v; want not found", ok, err)
+	}
+
+	for _, digest := range []string{"sha256:aaa", "sha256:bbb"} {
+		promo, err := p.Record(ctx, Promotion{Source: "my-app:staging", Target: "my-app:prod", Digest: digest})
+		if err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		if promo.Timestamp.IsZero() {
+			t.Error("Expected the timestamp to be set")
+		}
+	}
+	if _, err := p.Record(ctx, Promotion{Source: "my-app:staging", Target: "my-app:canary", Digest: "sha256:ccc"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	promo, ok, err := p.Latest(ctx, "index.docker.io/library/my-app:prod")
+	if err != nil || !ok {
+		t.Fatalf("Latest = %v, %v; want found", ok, err)
+	}
+	if promo.Digest != "sha256:bbb" {
+		t.Errorf("Expected the newest promotion, got digest %s", promo.Digest)
+	}
+	if got := promo.Pinned(); got != "index.docker.io/library/my-app@sha256:bbb" {
+		t.Errorf("Pinned() = %q", got)
+	}
+	if _, ok, _ := p.Latest(ctx, "my-app:staging"); ok {
+		t.Error("Expected promotion sources not to be found")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "promotions", "my-app.json")); err != nil {
+		t.Errorf("Expected the promotions to be stored per application: %v", err)
+	}
+}
+
+func TestPromotionsCorrupt(t *testing.T) {
+	p, dir := testPromotions(t)
+	os.MkdirAll(filepath.Join(dir, "promotions"), 0o755)
+	os.WriteFile(filepath.Join(dir, "promotions", "my-app.json"), []byte("{"), 0o644)
+
+	if _, _, err := p.Latest(context.Background(), "my-app:prod"); err == nil || !strings.Contains(err.Error(), "failed to parse image promotions") {
+		t.Errorf("Expected a parse error, got: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+// Package state records the history of an environment's deployments: the
+// images deployed (pinned by digest), the URL, and when and how each version
+// was deployed. The history backs the history command and lets rollback -to
+// redeploy any earlier version, on every provider, and roll-forward return to
+// the newest good version after a rollback.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+// maxRecords is how many versions of an environment are kept; older ones are dropped.
+const maxRecords = 100
+
+// Commands that create a version.
+const (
+	CommandDeploy      = "deploy"
+	CommandRollback    = "rollback"
+	CommandRollForward = "roll-forward"
+)
+
+// Record is one deployed version of an environment.
+type Record struct {
+	// Version label, numbered from v1 per environment
+	Version string `json:"version"`
+
+	// Command that deployed the version: "deploy", "rollback", or "roll-forward"
+	Command string `json:"command"`
+
+	Provider    string `json:"provider"`
+	Application string `json:"application"`
+	Environment string `json:"environment"`
+
+	// Images deployed, by container name
+	Images map[string]string `json:"images,omitempty"`
+
+	URL     string `json:"url,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// Version restored by rollback -to or roll-forward
+	RestoredVersion string `json:"restored_version,omitempty"`
+
+	// Version of cloud-deploy that deployed the version
+	ToolVersion string `json:"tool_version,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewRecord returns the record of a deployment result.
+func NewRecord(command, provider string, result *types.DeploymentResult) Record {
+	return Record{
+		Command:     command,
+		Provider:    provider,
+		Application: result.ApplicationName,
+		Environment: result.EnvironmentName,
+		Images:      result.Images,
+		URL:         result.URL,
+		Status:      result.Status,
+		Message:     result.Message,
+	}
+}
+
+// Store reads and writes the history of one environment.
+type Store struct {
+	backend Backend
+	key     string
+	now     func() time.Time
+}
+
+// Open returns the history store of m's environment, in the backend configured
+// by the manifest's state section.
+func Open(ctx context.Context, m *manifest.Manifest) (*Store, error) {
+	backend, err := manifestBackend(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		backend: backend,
+		key:     path.Join(m.Provider.Name, m.Application.Name, m.Environment.Name+".json"),
+		now:     time.Now,
+	}, nil
+}
+
+// manifestBackend creates the backend configured by the manifest's state section.
+func manifestBackend(ctx context.Context, m *manifest.Manifest) (Backend, error) {
+	var cfg manifest.StateConfig
+	if m.State != nil {
+		cfg = *m.State
+	}
+
+	region := ""
+	if m.Provider.Cloud() == "aws" {
+		region = m.Provider.Region
+	}
+	// Buckets of an emulated cloud are stored by its emulator
+	if cfg.EndpointURL == "" && m.Provider.Emulated() &&
+		((cfg.Backend == "s3" && m.Provider.Cloud() == "aws") || (cfg.Backend == "gcs" && m.Provider.Cloud() == "gcp")) {
+		cfg.EndpointURL = m.Provider.EndpointURL
+	}
+	return NewBackend(ctx, cfg, region)
+}
+
+// History returns the recorded versions, oldest first.
+func (s *Store) History(ctx context.Context) ([]Record, error) {
+	data, err := s.backend.Load(ctx, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment history: %w", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment history %s: %w", s.key, err)
+	}
+	return records, nil
+}
+
+// Record adds rec to the history as the next version and returns it with its
+// version label and timestamp set.
+func (s *Store) Record(ctx context.Context, rec Record) (Record, error) {
+	records, err := s.History(ctx)
+	if err != nil {
+		return Record{}, err
+	}
+
+	next := 1
+	if len(records) > 0 {
+		next = versionNumber(records[len(records)-1].Version) + 1
+	}
+	rec.Version = fmt.Sprintf("v%d", next)
+	rec.Timestamp = s.now().UTC()
+
+	records = append(records, rec)
+	if len(records) > maxRecords {
+		records = records[len(records)-maxRecords:]
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return Record{}, err
+	}
+	if err := s.backend.Save(ctx, s.key, data); err != nil {
+		return Record{}, fmt.Errorf("failed to write deployment history: %w", err)
+	}
+	return rec, nil
+}
+
+// Find returns the recorded version ("v3", or just "3").
+func (s *Store) Find(ctx context.Context, version string) (Record, error) {
+	records, err := s.History(ctx)
+	if err != nil {
+		return Record{}, err
+	}
+
+	n := versionNumber(version)
+	for _, rec := range records {
+		if n > 0 && versionNumber(rec.Version) == n {
+			return rec, nil
+		}
+	}
+	return Record{}, fmt.Errorf("version %s not found in deployment history (see the history command)", version)
+}
+
+// RollForwardTarget returns the version roll-forward redeploys after a
+// rollback: the newest deployed version newer than the one the rollback
+// restored, skipping the versions that were rolled back.
+func (s *Store) RollForwardTarget(ctx context.Context) (Record, error) {
+	records, err := s.History(ctx)
+	if err != nil {
+		return Record{}, err
+	}
+	return rollForwardTarget(records)
+}
+
+// rollForwardTarget returns the roll-forward target of a history, oldest first.
+// A version is known to be bad when a rollback replaced it, either directly or
+// after an earlier roll-forward restored it.
+func rollForwardTarget(records []Record) (Record, error) {
+	if len(records) == 0 || records[len(records)-1].Command != CommandRollback {
+		return Record{}, fmt.Errorf("the latest recorded version is not a rollback, so there is nothing to roll forward from (see the history command)")
+	}
+	rollback := records[len(records)-1]
+	restored := versionNumber(rollback.RestoredVersion)
+	if restored == 0 {
+		// The provider's own rollback restores the version before the one it replaced
+		return Record{}, fmt.Errorf("%s used the provider's rollback, so no version newer than the restored one is recorded; roll forward with rollback -to instead", rollback.Version)
+	}
+
+	bad := make(map[int]bool)
+	for i := 1; i < len(records); i++ {
+		if records[i].Command != CommandRollback {
+			continue
+		}
+		replaced := records[i-1]
+		bad[versionNumber(replaced.Version)] = true
+		if replaced.RestoredVersion != "" {
+			bad[versionNumber(replaced.RestoredVersion)] = true
+		}
+	}
+
+	for i := len(records) - 2; i >= 0; i-- {
+		rec := records[i]
+		n := versionNumber(rec.Version)
+		if n <= restored {
+			break
+		}
+		if rec.Command == CommandDeploy && !bad[n] && len(rec.Images) > 0 {
+			return rec, nil
+		}
+	}
+	return Record{}, fmt.Errorf("no version deployed after %s is known to be good", rollback.RestoredVersion)
+}
+
+// versionNumber returns the number of a version label, or 0 if it is not one.
+func versionNumber(version string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
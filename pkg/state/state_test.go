@@ -0,0 +1,211 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+	"github.com/jvreagan/cloud-deploy/pkg/types"
+)
+
+func testStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := &manifest.Manifest{
+		Provider:    manifest.ProviderConfig{Name: "aws", Region: "us-east-1"},
+		Application: manifest.ApplicationConfig{Name: "my-app"},
+		Environment: manifest.EnvironmentConfig{Name: "my-app-prod"},
+		State:       &manifest.StateConfig{Path: dir},
+	}
+	s, err := Open(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s.now = func() time.Time { return time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) }
+	return s, dir
+}
+
+func TestRecordAndHistory(t *testing.T) {
+	ctx := context.Background()
+	s, dir := testStore(t)
+
+	history, err := s.History(ctx)
+	if err != nil || len(history) != 0 {
+		t.Fatalf("History of a new environment = %v, %v; want empty", history, err)
+	}
+
+	result := &types.DeploymentResult{
+		ApplicationName: "my-app",
+		EnvironmentName: "my-app-prod",
+		URL:             "http://my-app.example.com",
+		Status:          "Ready",
+		Images:          map[string]string{"my-app": "123.dkr.ecr.us-east-1.amazonaws.com/my-app@sha256:aaa"},
+	}
+	rec, err := s.Record(ctx, NewRecord(CommandDeploy, "aws", result))
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if rec.Version != "v1" || !rec.Timestamp.Equal(s.now()) {
+		t.Errorf("first record = %+v, want v1 at %v", rec, s.now())
+	}
+	if rec, _ = s.Record(ctx, NewRecord(CommandRollback, "aws", result)); rec.Version != "v2" {
+		t.Errorf("second version = %q, want v2", rec.Version)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "aws", "my-app", "my-app-prod.json")); err != nil {
+		t.Errorf("history file not written: %v", err)
+	}
+
+	history, err = s.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Command != CommandDeploy || history[1].Command != CommandRollback {
+		t.Errorf("unexpected history: %+v", history)
+	}
+	if history[0].URL != result.URL || history[0].Images["my-app"] != result.Images["my-app"] {
+		t.Errorf("record did not keep the result: %+v", history[0])
+	}
+}
+
+func TestRecordKeepsRecentVersions(t *testing.T) {
+	ctx := context.Background()
+	s, _ := testStore(t)
+
+	for i := 0; i < maxRecords+5; i++ {
+		if _, err := s.Record(ctx, Record{Command: CommandDeploy}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	history, _ := s.History(ctx)
+	if len(history) != maxRecords {
+		t.Fatalf("kept %d records, want %d", len(history), maxRecords)
+	}
+	if first, last := history[0].Version, history[len(history)-1].Version; first != "v6" || last != fmt.Sprintf("v%d", maxRecords+5) {
+		t.Errorf("kept versions %s to %s", first, last)
+	}
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	s, _ := testStore(t)
+	for _, image := range []string{"my-app:1", "my-app:2"} {
+		if _, err := s.Record(ctx, Record{Command: CommandDeploy, Images: map[string]string{"my-app": image}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, version := range []string{"v1", "1"} {
+		rec, err := s.Find(ctx, version)
+		if err != nil || rec.Images["my-app"] != "my-app:1" {
+			t.Errorf("Find(%q) = %+v, %v", version, rec, err)
+		}
+	}
+	for _, version := range []string{"v3", "latest", "v0"} {
+		if _, err := s.Find(ctx, version); err == nil || !strings.Contains(err.Error(), "not found in deployment history") {
+			t.Errorf("Find(%q) error = %v", version, err)
+		}
+	}
+}
+
+func TestRollForwardTarget(t *testing.T) {
+	images := func(tag string) map[string]string { return map[string]string{"my-app": "my-app@sha256:" + tag} }
+	deploy := func(version, tag string) Record {
+		return Record{Version: version, Command: CommandDeploy, Images: images(tag)}
+	}
+	rollback := func(version, to string) Record {
+		return Record{Version: version, Command: CommandRollback, RestoredVersion: to}
+	}
+
+	tests := []struct {
+		name    string
+		records []Record
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "rolled back too far",
+			records: []Record{deploy("v1", "a"), deploy("v2", "b"), deploy("v3", "c"), rollback("v4", "v1")},
+			want:    "v2",
+		},
+		{
+			name:    "newest good version",
+			records: []Record{deploy("v1", "a"), deploy("v2", "b"), deploy("v3", "c"), deploy("v4", "d"), rollback("v5", "1")},
+			want:    "v3",
+		},
+		{
+			name: "restored version rolled back again",
+			records: []Record{deploy("v1", "a"), deploy("v2", "b"), deploy("v3", "c"), rollback("v4", "v1"),
+				{Version: "v5", Command: CommandRollForward, RestoredVersion: "v2", Images: images("b")}, rollback("v6", "v1")},
+			wantErr: "no version deployed after v1 is known to be good",
+		},
+		{
+			name:    "only the failed version is newer",
+			records: []Record{deploy("v1", "a"), deploy("v2", "b"), rollback("v3", "v1")},
+			wantErr: "no version deployed after v1 is known to be good",
+		},
+		{
+			name:    "provider rollback",
+			records: []Record{deploy("v1", "a"), deploy("v2", "b"), rollback("v3", "")},
+			wantErr: "v3 used the provider's rollback",
+		},
+		{
+			name:    "latest version is a deploy",
+			records: []Record{deploy("v1", "a"), deploy("v2", "b")},
+			wantErr: "not a rollback",
+		},
+		{
+			name:    "empty history",
+			wantErr: "not a rollback",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rollForwardTarget(tt.records)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("rollForwardTarget() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.Version != tt.want {
+				t.Errorf("rollForwardTarget() = %s, %v; want %s", got.Version, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreRollForwardTarget(t *testing.T) {
+	ctx := context.Background()
+	s, _ := testStore(t)
+	for _, rec := range []Record{
+		{Command: CommandDeploy, Images: map[string]string{"my-app": "my-app:1"}},
+		{Command: CommandDeploy, Images: map[string]string{"my-app": "my-app:2"}},
+		{Command: CommandDeploy, Images: map[string]string{"my-app": "my-app:3"}},
+		{Command: CommandRollback, RestoredVersion: "v1", Images: map[string]string{"my-app": "my-app:1"}},
+	} {
+		if _, err := s.Record(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec, err := s.RollForwardTarget(ctx)
+	if err != nil || rec.Images["my-app"] != "my-app:2" {
+		t.Errorf("RollForwardTarget() = %+v, %v; want v2", rec, err)
+	}
+}
+
+func TestHistoryCorrupt(t *testing.T) {
+	s, dir := testStore(t)
+	file := filepath.Join(dir, "aws", "my-app", "my-app-prod.json")
+	os.MkdirAll(filepath.Dir(file), 0700)
+	os.WriteFile(file, []byte("not json"), 0600)
+
+	if _, err := s.History(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to parse deployment history") {
+		t.Errorf("expected a parse error, got: %v", err)
+	}
+}
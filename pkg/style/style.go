@@ -0,0 +1,125 @@
+// Package style formats terminal output. Colors are only used when writing to
+// a terminal and can be turned off with -no-color or NO_COLOR; high-contrast
+// mode replaces colors with bold, underline, and reverse video; ASCII mode
+// replaces symbols such as ✓ with words, for CI logs and screen readers.
+package style
+
+import (
+	"os"
+	"strings"
+)
+
+// Options selects how output is styled.
+type Options struct {
+	// NoColor disables colors and other escape sequences
+	NoColor bool
+
+	// HighContrast uses bold, underline, and reverse video instead of colors,
+	// so meaning never depends on telling colors apart
+	HighContrast bool
+
+	// ASCII replaces symbols with plain-text equivalents
+	ASCII bool
+}
+
+// ANSI escape sequences.
+const (
+	reset     = "\x1b[0m"
+	bold      = "\x1b[1m"
+	underline = "\x1b[4m"
+	reverse   = "\x1b[7m"
+	red       = "\x1b[31m"
+	green     = "\x1b[32m"
+	yellow    = "\x1b[33m"
+)
+
+var (
+	current Options
+	color   bool
+)
+
+// symbols maps the symbols used in messages to their ASCII replacements.
+// Decorative symbols are dropped.
+var symbols = strings.NewReplacer(
+	"✅ ", "[OK] ",
+	"✅", "[OK]",
+	"✓ ", "[OK] ",
+	"✓", "[OK]",
+	"❌ ", "[FAILED] ",
+	"❌", "[FAILED]",
+	"✗ ", "[FAILED] ",
+	"✗", "[FAILED]",
+	"⚠️ ", "[WARNING] ",
+	"⚠️", "[WARNING]",
+	"⚠ ", "[WARNING] ",
+	"⚠", "[WARNING]",
+	"📦 ", "",
+	"📦", "",
+	"—", "-",
+)
+
+// Configure sets the output style. Colors are enabled only when out is a
+// terminal, o.NoColor is unset, NO_COLOR is empty, and TERM is not "dumb".
+func Configure(o Options, out *os.File) {
+	current = o
+	color = !o.NoColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(out)
+}
+
+// Color reports whether escape sequences are written.
+func Color() bool {
+	return color
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Text returns s with symbols replaced by ASCII text in ASCII mode.
+func Text(s string) string {
+	if !current.ASCII {
+		return s
+	}
+	return symbols.Replace(s)
+}
+
+// Success styles s as a successful outcome.
+func Success(s string) string {
+	if current.HighContrast {
+		return wrap(bold, s)
+	}
+	return wrap(green, s)
+}
+
+// Warning styles s as a partial failure that needs attention.
+func Warning(s string) string {
+	if current.HighContrast {
+		return wrap(bold+underline, s)
+	}
+	return wrap(yellow, s)
+}
+
+// Failure styles s as a failed outcome.
+func Failure(s string) string {
+	if current.HighContrast {
+		return wrap(bold+reverse, s)
+	}
+	return wrap(red, s)
+}
+
+// Header styles s as a table header.
+func Header(s string) string {
+	return wrap(bold, s)
+}
+
+// wrap surrounds s with the escape sequence when colors are enabled.
+func wrap(code, s string) string {
+	if !color || s == "" {
+		return s
+	}
+	return code + s + reset
+}
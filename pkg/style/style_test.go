@@ -0,0 +1,81 @@
+package style
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setOptions sets the style for a test and restores the default afterwards.
+func setOptions(t *testing.T, o Options, useColor bool) {
+	t.Helper()
+	current, color = o, useColor
+	t.Cleanup(func() { current, color = Options{}, false })
+}
+
+func TestText(t *testing.T) {
+	setOptions(t, Options{}, false)
+	if got := Text("✓ Deployment successful!"); got != "✓ Deployment successful!" {
+		t.Errorf("Expected symbols to be kept, got %q", got)
+	}
+
+	setOptions(t, Options{ASCII: true}, false)
+	tests := map[string]string{
+		"✓ Deployment successful!":          "[OK] Deployment successful!",
+		"✅ Successfully loaded credentials": "[OK] Successfully loaded credentials",
+		"✗ CNAME prefix app is taken":       "[FAILED] CNAME prefix app is taken",
+		"📦 Pushing image":                   "Pushing image",
+		"old credentials — still active":    "old credentials - still active",
+		"✓ デプロイが完了しました":                     "[OK] デプロイが完了しました",
+	}
+	for in, want := range tests {
+		if got := Text(in); got != want {
+			t.Errorf("Text(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStyles(t *testing.T) {
+	setOptions(t, Options{}, false)
+	if got := Failure("failed"); got != "failed" {
+		t.Errorf("Expected no escapes without color, got %q", got)
+	}
+
+	setOptions(t, Options{}, true)
+	if got := Success("ok"); got != green+"ok"+reset {
+		t.Errorf("Success() = %q", got)
+	}
+	if got := Failure("failed"); got != red+"failed"+reset {
+		t.Errorf("Failure() = %q", got)
+	}
+
+	setOptions(t, Options{HighContrast: true}, true)
+	if got := Failure("failed"); got != bold+reverse+"failed"+reset {
+		t.Errorf("Expected reverse video in high-contrast mode, got %q", got)
+	}
+	if got := Warning("partial"); got != bold+underline+"partial"+reset {
+		t.Errorf("Expected underline in high-contrast mode, got %q", got)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { current, color = Options{}, false })
+
+	// A regular file is not a terminal
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Setenv("NO_COLOR", "")
+	Configure(Options{}, f)
+	if Color() {
+		t.Error("Expected no color when output is not a terminal")
+	}
+
+	Configure(Options{ASCII: true}, nil)
+	if Color() || Text("✓") != "[OK]" {
+		t.Error("Expected ASCII mode without color")
+	}
+}
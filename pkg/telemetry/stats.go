@@ -0,0 +1,102 @@
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/style"
+)
+
+// Summary aggregates the events of one command on one provider.
+type Summary struct {
+	Command     string
+	Provider    string
+	Runs        int
+	Failures    int
+	AvgDuration time.Duration
+	MaxDuration time.Duration
+}
+
+// SuccessRate returns the fraction of runs that succeeded.
+func (s Summary) SuccessRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Runs-s.Failures) / float64(s.Runs)
+}
+
+// Summarize groups events by command and provider, sorted by command then provider.
+func Summarize(events []Event) []Summary {
+	type key struct{ command, provider string }
+	totals := make(map[key]*Summary)
+	durations := make(map[key]time.Duration)
+
+	for _, e := range events {
+		k := key{e.Command, e.Provider}
+		s, ok := totals[k]
+		if !ok {
+			s = &Summary{Command: e.Command, Provider: e.Provider}
+			totals[k] = s
+		}
+		d := time.Duration(e.DurationMS) * time.Millisecond
+		s.Runs++
+		if !e.Success {
+			s.Failures++
+		}
+		if d > s.MaxDuration {
+			s.MaxDuration = d
+		}
+		durations[k] += d
+	}
+
+	summaries := make([]Summary, 0, len(totals))
+	for k, s := range totals {
+		s.AvgDuration = durations[k] / time.Duration(s.Runs)
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Command != summaries[j].Command {
+			return summaries[i].Command < summaries[j].Command
+		}
+		return summaries[i].Provider < summaries[j].Provider
+	})
+	return summaries
+}
+
+// WriteSummary writes summaries as an aligned table. Rows with failures are
+// highlighted when the output style allows it.
+func WriteSummary(w io.Writer, summaries []Summary) error {
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tPROVIDER\tRUNS\tFAILURES\tSUCCESS\tAVG DURATION\tMAX DURATION")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.0f%%\t%s\t%s\n",
+			s.Command, s.Provider, s.Runs, s.Failures, s.SuccessRate()*100,
+			s.AvgDuration.Round(time.Second), s.MaxDuration.Round(time.Second))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Rows are styled after alignment so escape sequences don't affect column widths
+	lines := strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			line = style.Header(line)
+		case summaries[i-1].Failures == summaries[i-1].Runs:
+			line = style.Failure(line)
+		case summaries[i-1].Failures > 0:
+			line = style.Warning(line)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
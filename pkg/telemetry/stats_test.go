@@ -0,0 +1,52 @@
+package telemetry
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	events := []Event{
+		{Command: "deploy", Provider: "aws", DurationMS: 60000, Success: true},
+		{Command: "deploy", Provider: "aws", DurationMS: 120000, Success: false},
+		{Command: "deploy", Provider: "aws", DurationMS: 180000, Success: true},
+		{Command: "status", Provider: "gcp", DurationMS: 1000, Success: true},
+		{Command: "deploy", Provider: "azure", DurationMS: 30000, Success: true},
+	}
+
+	summaries := Summarize(events)
+	if len(summaries) != 3 {
+		t.Fatalf("Expected 3 summaries, got %d", len(summaries))
+	}
+
+	aws := summaries[0]
+	if aws.Command != "deploy" || aws.Provider != "aws" {
+		t.Fatalf("Expected deploy/aws first, got %s/%s", aws.Command, aws.Provider)
+	}
+	if aws.Runs != 3 || aws.Failures != 1 || aws.AvgDuration != 2*time.Minute || aws.MaxDuration != 3*time.Minute {
+		t.Errorf("Unexpected summary: %+v", aws)
+	}
+	if rate := aws.SuccessRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("Expected success rate 2/3, got %f", rate)
+	}
+	if summaries[1].Provider != "azure" || summaries[2].Command != "status" {
+		t.Errorf("Unexpected order: %+v", summaries)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	var buf bytes.Buffer
+	WriteSummary(&buf, []Summary{{Command: "deploy", Provider: "aws", Runs: 4, Failures: 1, AvgDuration: 90 * time.Second, MaxDuration: 2 * time.Minute}})
+
+	out := buf.String()
+	for _, s := range []string{"COMMAND", "deploy", "aws", "75%", "1m30s", "2m0s"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected output to contain %q\nGot:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("Expected no escape sequences when not writing to a terminal, got %q", out)
+	}
+}
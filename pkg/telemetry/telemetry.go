@@ -0,0 +1,200 @@
+// Package telemetry records anonymous usage events (command, provider, duration,
+// success) so maintainers and platform teams can see aggregate failure modes.
+//
+// Telemetry is opt-in. CLOUD_DEPLOY_TELEMETRY controls it:
+//   - unset (default): events are only appended to a local log on this machine
+//   - "on": events are also sent to CLOUD_DEPLOY_TELEMETRY_ENDPOINT
+//   - "off": nothing is recorded or sent
+//
+// Events never contain manifest contents, names, URLs, or error messages: only
+// the fixed fields of Event, with command and provider limited to known values.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+)
+
+// Environment variables that configure telemetry.
+const (
+	EnvMode     = "CLOUD_DEPLOY_TELEMETRY"
+	EnvEndpoint = "CLOUD_DEPLOY_TELEMETRY_ENDPOINT"
+	EnvLogPath  = "CLOUD_DEPLOY_TELEMETRY_LOG"
+)
+
+// sendTimeout bounds how long a command can be delayed by an unreachable endpoint.
+const sendTimeout = 2 * time.Second
+
+// Known commands and providers; anything else is reported as "other" so
+// arbitrary user input never leaves the machine.
+var (
+	knownCommands = map[string]bool{
+		"deploy": true, "stop": true, "destroy": true, "status": true, "rollback": true, "roll-forward": true,
+		"plan": true, "history": true, "scale": true, "logs": true, "export": true, "check-cname": true, "secrets-sync": true, "credentials-rotate": true,
+		"iam-policy": true, "posture": true, "inventory": true, "maintenance": true, "image": true, "attach": true, "validate": true, "cleanup": true,
+	}
+	knownProviders = map[string]bool{"aws": true, "aws-ecs": true, "aws-lambda": true, "aws-apprunner": true, "gcp": true, "gcp-gke": true, "azure": true, "azure-container-apps": true, "azure-app-service": true, "oci": true, "kubernetes": true, "digitalocean": true, "fly": true, "mock": true, "multi": true}
+)
+
+// Event is a single anonymous usage record.
+type Event struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Command    string    `json:"command"`
+	Provider   string    `json:"provider"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	Version    string    `json:"version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+}
+
+// NewEvent creates a redacted event for a command run.
+func NewEvent(command, provider, version string, duration time.Duration, success bool) Event {
+	if !knownCommands[command] {
+		command = "other"
+	}
+	if !knownProviders[provider] {
+		provider = "other"
+	}
+	return Event{
+		Timestamp:  time.Now().UTC().Truncate(time.Second),
+		Command:    command,
+		Provider:   provider,
+		DurationMS: duration.Milliseconds(),
+		Success:    success,
+		Version:    version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+	}
+}
+
+// Enabled reports whether events are recorded at all.
+func Enabled() bool {
+	return os.Getenv(EnvMode) != "off"
+}
+
+// sendingEnabled reports whether events are sent to the configured endpoint.
+func sendingEnabled() bool {
+	return os.Getenv(EnvMode) == "on" && os.Getenv(EnvEndpoint) != ""
+}
+
+// LogPath returns the path of the local event log.
+func LogPath() (string, error) {
+	if path := os.Getenv(EnvLogPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cloud-deploy", "telemetry.jsonl"), nil
+}
+
+// Record appends e to the local log and, when opted in, sends it to the endpoint.
+// Telemetry must never affect a command, so failures are only logged at debug level.
+func Record(ctx context.Context, e Event) {
+	if !Enabled() {
+		return
+	}
+
+	if err := appendLocal(e); err != nil {
+		logging.Debug("Failed to record telemetry locally", "error", err)
+	}
+
+	if sendingEnabled() {
+		if err := send(ctx, os.Getenv(EnvEndpoint), e); err != nil {
+			logging.Debug("Failed to send telemetry", "error", err)
+		}
+	}
+}
+
+// appendLocal appends e to the local event log.
+func appendLocal(e Event) error {
+	path, err := LogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// send posts e as JSON to endpoint.
+func send(ctx context.Context, endpoint string, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// LoadLocal reads the events in the local log. A missing log has no events.
+func LoadLocal() ([]Event, error) {
+	path, err := LogPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		// Skip lines damaged by interrupted writes
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
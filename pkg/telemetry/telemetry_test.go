@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewEventRedactsUnknownValues(t *testing.T) {
+	e := NewEvent("deploy my-secret-app", "https://internal.example.com", "1.0.0", 1500*time.Millisecond, true)
+	if e.Command != "other" || e.Provider != "other" {
+		t.Errorf("Expected unknown command and provider to be redacted, got %q/%q", e.Command, e.Provider)
+	}
+
+	e = NewEvent("deploy", "aws", "1.0.0", 1500*time.Millisecond, false)
+	if e.Command != "deploy" || e.Provider != "aws" || e.DurationMS != 1500 || e.Success {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+}
+
+func TestNewEventKeepsKnownCommands(t *testing.T) {
+	for _, command := range []string{"iam-policy", "posture", "inventory", "maintenance", "image", "attach", "validate", "cleanup"} {
+		if e := NewEvent(command, "aws", "1.0.0", time.Second, true); e.Command != command {
+			t.Errorf("Expected %s to be reported, got %q", command, e.Command)
+		}
+	}
+}
+
+func TestRecordLocalOnlyByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	t.Setenv(EnvLogPath, path)
+	t.Setenv(EnvMode, "")
+
+	sent := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+	}))
+	defer server.Close()
+	t.Setenv(EnvEndpoint, server.URL)
+
+	Record(context.Background(), NewEvent("deploy", "aws", "1.0.0", time.Second, true))
+	Record(context.Background(), NewEvent("status", "gcp", "1.0.0", time.Second, false))
+
+	events, err := LoadLocal()
+	if err != nil {
+		t.Fatalf("LoadLocal failed: %v", err)
+	}
+	if len(events) != 2 || events[1].Command != "status" {
+		t.Errorf("Expected 2 local events, got %+v", events)
+	}
+	if sent {
+		t.Error("Expected nothing to be sent without opting in")
+	}
+}
+
+func TestRecordSendsWhenOptedIn(t *testing.T) {
+	t.Setenv(EnvLogPath, filepath.Join(t.TempDir(), "telemetry.jsonl"))
+	t.Setenv(EnvMode, "on")
+
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+	t.Setenv(EnvEndpoint, server.URL)
+
+	Record(context.Background(), NewEvent("rollback", "azure", "1.0.0", time.Second, true))
+	if received.Command != "rollback" || received.Provider != "azure" {
+		t.Errorf("Expected event to be sent, got %+v", received)
+	}
+}
+
+func TestRecordOff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	t.Setenv(EnvLogPath, path)
+	t.Setenv(EnvMode, "off")
+
+	Record(context.Background(), NewEvent("deploy", "aws", "1.0.0", time.Second, true))
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected no local log when telemetry is off")
+	}
+}
+
+func TestLoadLocalSkipsDamagedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	t.Setenv(EnvLogPath, path)
+	os.WriteFile(path, []byte(`{"command":"deploy","provider":"aws"}`+"\n"+`{"comm`+"\n"), 0600)
+
+	events, err := LoadLocal()
+	if err != nil || len(events) != 1 {
+		t.Errorf("Expected 1 event, got %d (%v)", len(events), err)
+	}
+
+	t.Setenv(EnvLogPath, filepath.Join(t.TempDir(), "missing.jsonl"))
+	if events, err := LoadLocal(); err != nil || len(events) != 0 {
+		t.Errorf("Expected no events for a missing log, got %v (%v)", events, err)
+	}
+}
@@ -0,0 +1,77 @@
+package transcript
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/style"
+)
+
+// Replay writes the events of t to w, pausing between them as long as the
+// command did, divided by speed. A speed of 0 or less writes them without pausing.
+func Replay(ctx context.Context, w io.Writer, t *Transcript, speed float64) error {
+	events := slices.Clone(t.Events)
+	// Requests are recorded when they finish but shown when they started
+	slices.SortStableFunc(events, func(a, b Event) int { return cmp.Compare(a.OffsetMS, b.OffsetMS) })
+
+	start := time.Now()
+	for _, e := range events {
+		if speed > 0 {
+			due := start.Add(time.Duration(float64(e.OffsetMS)/speed) * time.Millisecond)
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(w, FormatEvent(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatEvent renders an event as one line: its time since the command
+// started, then the logged message or the request summary.
+func FormatEvent(e Event) string {
+	offset := fmt.Sprintf("[%7.1fs]", float64(e.OffsetMS)/1000)
+
+	if r := e.Request; r != nil {
+		outcome := fmt.Sprint(r.Status)
+		if r.Error != "" {
+			outcome = r.Error
+		}
+		line := fmt.Sprintf("%s       → %s %s%s %s (%dms)", offset, r.Method, r.Host, r.Path, outcome, r.DurationMS)
+		if r.Error != "" || r.Status >= 400 {
+			return style.Warning(line)
+		}
+		return line
+	}
+
+	line := fmt.Sprintf("%s %-5s %s", offset, e.Level, e.Message)
+	if len(e.Attrs) > 0 {
+		keys := slices.Sorted(maps.Keys(e.Attrs))
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + "=" + e.Attrs[k]
+		}
+		line += "  " + strings.Join(pairs, " ")
+	}
+	switch e.Level {
+	case "WARN":
+		return style.Warning(line)
+	case "ERROR":
+		return style.Failure(line)
+	}
+	return line
+}
@@ -0,0 +1,55 @@
+package transcript
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	tr := &Transcript{Events: []Event{
+		{OffsetMS: 2000, Level: "INFO", Message: "Waiting for environment"},
+		{OffsetMS: 0, Level: "INFO", Message: "Pushing image", Attrs: map[string]string{"image": "my-app:1", "container": "web"}},
+		{OffsetMS: 1200, Request: &Request{Method: "POST", Host: "elasticbeanstalk.us-east-1.amazonaws.com", Path: "/", Status: 200, DurationMS: 85}},
+	}}
+
+	var out strings.Builder
+	if err := Replay(context.Background(), &out, tr, 0); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	want := []string{
+		"[    0.0s] INFO  Pushing image  container=web image=my-app:1",
+		"[    1.2s]       → POST elasticbeanstalk.us-east-1.amazonaws.com/ 200 (85ms)",
+		"[    2.0s] INFO  Waiting for environment",
+	}
+	if got := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Replay output:\n%s\nwant:\n%s", out.String(), strings.Join(want, "\n"))
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	tr := &Transcript{Events: []Event{{OffsetMS: 1000, Level: "INFO", Message: "done"}}}
+
+	start := time.Now()
+	if err := Replay(context.Background(), &strings.Builder{}, tr, 20); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Replay at 20x took %v, want about 50ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Replay(ctx, &strings.Builder{}, tr, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a cancelled replay to stop, got: %v", err)
+	}
+}
+
+func TestFormatEventErrors(t *testing.T) {
+	line := FormatEvent(Event{OffsetMS: 61500, Request: &Request{Method: "GET", Host: "api.example.com", Path: "/x", Error: "connection refused", DurationMS: 3}})
+	if line != "[   61.5s]       → GET api.example.com/x connection refused (3ms)" {
+		t.Errorf("unexpected line: %q", line)
+	}
+}
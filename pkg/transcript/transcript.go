@@ -0,0 +1,203 @@
+// Package transcript records a command's progress (every logged step, a summary
+// of each API request, and their timing) to a file that can be replayed later,
+// for demos, teaching, and analyzing failed deployments.
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+)
+
+// Transcript is the recording of one command.
+type Transcript struct {
+	Command     string    `json:"command"`
+	Provider    string    `json:"provider,omitempty"`
+	Application string    `json:"application,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	CLIVersion  string    `json:"cli_version,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	DurationMS  int64     `json:"duration_ms"`
+	Success     bool      `json:"success"`
+	Events      []Event   `json:"events"`
+}
+
+// Event is a logged step or an API request, at its time since the command started.
+type Event struct {
+	OffsetMS int64 `json:"offset_ms"`
+
+	// Logged steps
+	Level   string            `json:"level,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+
+	// API requests
+	Request *Request `json:"request,omitempty"`
+}
+
+// Request summarizes an API request. Query strings, headers, and bodies are
+// never recorded, since they can carry credentials and signatures.
+type Request struct {
+	Method     string `json:"method"`
+	Host       string `json:"host"`
+	Path       string `json:"path"`
+	Status     int    `json:"status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Recorder collects the events of a running command.
+type Recorder struct {
+	mu         sync.Mutex
+	transcript Transcript
+	now        func() time.Time
+}
+
+// NewRecorder starts recording a command.
+func NewRecorder(command, provider, application, environment, cliVersion string) *Recorder {
+	r := &Recorder{now: time.Now}
+	r.transcript = Transcript{
+		Command:     command,
+		Provider:    provider,
+		Application: application,
+		Environment: environment,
+		CLIVersion:  cliVersion,
+		StartedAt:   r.now().UTC(),
+	}
+	return r
+}
+
+// add records an event that happened at t.
+func (r *Recorder) add(t time.Time, e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.OffsetMS = t.Sub(r.transcript.StartedAt).Milliseconds()
+	r.transcript.Events = append(r.transcript.Events, e)
+}
+
+// Transcript returns a copy of the recording so far.
+func (r *Recorder) Transcript() Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.transcript
+	t.Events = append([]Event(nil), r.transcript.Events...)
+	return t
+}
+
+// Save writes the transcript, marking the command as finished now.
+func (r *Recorder) Save(path string, success bool) error {
+	r.mu.Lock()
+	r.transcript.DurationMS = r.now().Sub(r.transcript.StartedAt).Milliseconds()
+	r.transcript.Success = success
+	r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.Transcript(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Load reads a transcript file.
+func Load(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Handler returns a log handler that records every message it handles and
+// passes it on to next.
+func (r *Recorder) Handler(next slog.Handler) slog.Handler {
+	return &handler{recorder: r, next: next}
+}
+
+// handler records log messages as events.
+type handler struct {
+	recorder *Recorder
+	next     slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	e := Event{Level: rec.Level.String(), Message: logging.SanitizeString(rec.Message)}
+	if rec.NumAttrs() > 0 {
+		attrs := make(map[string]interface{}, rec.NumAttrs())
+		rec.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		e.Attrs = make(map[string]string, len(attrs))
+		for k, v := range logging.SanitizeMap(attrs) {
+			e.Attrs[k] = fmt.Sprint(v)
+		}
+	}
+	at := rec.Time
+	if at.IsZero() {
+		at = h.recorder.now()
+	}
+	h.recorder.add(at, e)
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{recorder: h.recorder, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{recorder: h.recorder, next: h.next.WithGroup(name)}
+}
+
+// Transport returns an HTTP transport that records a summary of every request
+// it sends through next.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{recorder: r, next: next}
+}
+
+// transport records HTTP requests as events.
+type transport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.recorder.now()
+	resp, err := t.next.RoundTrip(req)
+
+	summary := &Request{
+		Method:     req.Method,
+		Host:       req.URL.Host,
+		Path:       req.URL.Path,
+		DurationMS: t.recorder.now().Sub(start).Milliseconds(),
+	}
+	if err != nil {
+		// url.Error repeats the URL, query string included
+		cause := err
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			cause = urlErr.Err
+		}
+		summary.Error = logging.SanitizeString(cause.Error())
+	} else {
+		summary.Status = resp.StatusCode
+	}
+	t.recorder.add(start, Event{Request: summary})
+	return resp, err
+}
@@ -0,0 +1,96 @@
+package transcript
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	r := NewRecorder("deploy", "aws", "my-app", "my-app-prod", "1.0.0")
+
+	var out strings.Builder
+	logger := slog.New(r.Handler(slog.NewTextHandler(&out, nil)))
+	logger.Info("Pushing image", "container", "web")
+	logger.Warn("Using password=hunter2 from the manifest", "token", "abc123")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: r.Transport(http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/v2/repositories?signature=secret")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if !strings.Contains(out.String(), "Pushing image") {
+		t.Errorf("messages were not passed on to the next handler: %q", out.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	if err := r.Save(path, true); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	tr, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if tr.Command != "deploy" || tr.Provider != "aws" || tr.Environment != "my-app-prod" || !tr.Success {
+		t.Errorf("unexpected transcript metadata: %+v", tr)
+	}
+	if len(tr.Events) != 3 {
+		t.Fatalf("recorded %d events, want 3: %+v", len(tr.Events), tr.Events)
+	}
+	if e := tr.Events[0]; e.Level != "INFO" || e.Message != "Pushing image" || e.Attrs["container"] != "web" {
+		t.Errorf("unexpected log event: %+v", e)
+	}
+	if e := tr.Events[1]; strings.Contains(e.Message, "hunter2") || e.Attrs["token"] != "[REDACTED]" {
+		t.Errorf("log event was not sanitized: %+v", e)
+	}
+	req := tr.Events[2].Request
+	if req == nil || req.Method != "GET" || req.Path != "/v2/repositories" || req.Status != http.StatusNotFound {
+		t.Errorf("unexpected request event: %+v", req)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	r := NewRecorder("deploy", "oci", "my-app", "my-app-prod", "1.0.0")
+	client := &http.Client{Transport: r.Transport(http.DefaultTransport)}
+
+	// Nothing listens on port 1
+	_, err := client.Get("http://127.0.0.1:1/path?token=secret")
+	if err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if !strings.Contains(err.Error(), "token=secret") {
+		t.Errorf("the caller's error should be unchanged: %v", err)
+	}
+
+	req := r.Transcript().Events[0].Request
+	if req.Error == "" || strings.Contains(req.Error, "secret") {
+		t.Errorf("unexpected request error: %q", req.Error)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "failed to read transcript") {
+		t.Errorf("expected a read error, got: %v", err)
+	}
+}
+
+func TestRecorderOffsets(t *testing.T) {
+	r := NewRecorder("deploy", "aws", "my-app", "my-app-prod", "1.0.0")
+	start := r.transcript.StartedAt
+	r.add(start.Add(1500*time.Millisecond), Event{Message: "later"})
+
+	if got := r.Transcript().Events[0].OffsetMS; got != 1500 {
+		t.Errorf("OffsetMS = %d, want 1500", got)
+	}
+}
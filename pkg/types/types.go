@@ -1,43 +1,484 @@
 // Package types provides shared types used across cloud-deploy packages.
 package types
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
 // DeploymentResult contains information about a successful deployment.
 // This is returned by the Deploy method after a deployment completes.
 type DeploymentResult struct {
 	// Name of the deployed application
-	ApplicationName string
+	ApplicationName string `json:"application_name"`
 
 	// Name of the deployed environment
-	EnvironmentName string
+	EnvironmentName string `json:"environment_name"`
 
 	// Public URL where the application can be accessed
-	URL string
+	URL string `json:"url"`
 
 	// Current status (e.g., "Launching", "Ready", "Updating")
-	Status string
+	Status string `json:"status"`
 
 	// Human-readable message with deployment details
-	Message string
+	Message string `json:"message,omitempty"`
+
+	// Images deployed, by container name (the application name for
+	// single-container deployments), pinned by digest where the provider
+	// pushed them to a registry
+	Images map[string]string `json:"images,omitempty"`
 }
 
 // DeploymentStatus contains the current status of a deployment.
 // This is returned by the Status method.
 type DeploymentStatus struct {
 	// Name of the application
-	ApplicationName string
+	ApplicationName string `json:"application_name"`
 
 	// Name of the environment
-	EnvironmentName string
+	EnvironmentName string `json:"environment_name"`
 
 	// Current status (e.g., "Ready", "Updating", "Terminating")
-	Status string
+	Status string `json:"status"`
 
 	// Health status (e.g., "Green", "Yellow", "Red", "Grey")
-	Health string
+	Health string `json:"health"`
 
 	// Public URL where the application can be accessed
-	URL string
+	URL string `json:"url"`
 
 	// Timestamp of last update (format varies by provider)
-	LastUpdated string
+	LastUpdated string `json:"last_updated,omitempty"`
+}
+
+// LogOptions controls which application logs the Logs method returns.
+type LogOptions struct {
+	// How far back to read logs from (e.g., 10m for the last ten minutes)
+	Since time.Duration
+
+	// Keep streaming new log entries until the context is cancelled
+	Follow bool
+
+	// Where log entries are written, one per line
+	Output io.Writer
+}
+
+// LogEntry is a single line of application output.
+type LogEntry struct {
+	// Provider-specific unique ID, used to avoid printing an entry twice when following
+	ID string
+
+	// Time the entry was written
+	Timestamp time.Time
+
+	// Where the entry came from (e.g., log stream, revision, or container name)
+	Source string
+
+	// The log line
+	Message string
+}
+
+// String formats the entry as "<timestamp> [<source>] <message>".
+func (e LogEntry) String() string {
+	ts := e.Timestamp.UTC().Format(time.RFC3339)
+	if e.Source == "" {
+		return ts + " " + e.Message
+	}
+	return ts + " [" + e.Source + "] " + e.Message
+}
+
+// Kinds of remediation events.
+const (
+	// The platform replaced an instance, such as after failed health checks
+	RemediationInstanceReplaced = "instance-replaced"
+
+	// A container exited and the platform restarted it
+	RemediationContainerRestarted = "container-restarted"
+)
+
+// RemediationEvent is an action the cloud provider took on its own to keep a
+// deployment running. A deployment can report Ready while its instances are
+// being replaced or its containers keep restarting.
+type RemediationEvent struct {
+	// Time of the event
+	Timestamp time.Time `json:"timestamp"`
+
+	// What happened (RemediationInstanceReplaced or RemediationContainerRestarted)
+	Kind string `json:"kind"`
+
+	// The instance, revision, or container affected
+	Source string `json:"source"`
+
+	// Details reported by the provider
+	Message string `json:"message"`
+
+	// Exit code of the container that was restarted, when known
+	ExitCode *int32 `json:"exit_code,omitempty"`
+}
+
+// String formats the event as "<timestamp> [<source>] <kind>: <message>",
+// followed by the exit code when known.
+func (e RemediationEvent) String() string {
+	s := e.Timestamp.UTC().Format(time.RFC3339) + " [" + e.Source + "] " + e.Kind
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	if e.ExitCode != nil {
+		s += fmt.Sprintf(" (exit code %d)", *e.ExitCode)
+	}
+	return s
+}
+
+// PlanAction is what a deployment would do to a resource.
+type PlanAction string
+
+// Plan actions.
+const (
+	PlanCreate   PlanAction = "create"
+	PlanUpdate   PlanAction = "update"
+	PlanDelete   PlanAction = "delete"
+	PlanNoChange PlanAction = "no-change"
+)
+
+// ResourceChange is a change a deployment would make to a cloud resource.
+type ResourceChange struct {
+	// What would happen to the resource
+	Action PlanAction `json:"action"`
+
+	// Kind of resource (e.g., "S3 bucket", "Cloud Run service")
+	Type string `json:"type"`
+
+	// Name or identifier of the resource
+	Name string `json:"name"`
+
+	// Why the change is needed (e.g., "push my-app:latest") - optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// Dependent is a resource outside the environment being destroyed that
+// depends on a resource the destroy would delete, and would break with it.
+type Dependent struct {
+	// Resource that would be deleted (e.g., "CloudFormation stack cloud-deploy-my-app")
+	Resource string `json:"resource"`
+
+	// Kind of the dependent resource (e.g., "Elastic Beanstalk environment")
+	Type string `json:"type"`
+
+	// Name of the dependent resource
+	Name string `json:"name"`
+}
+
+// Plan lists the changes a deployment or destroy would make, in the order it
+// would make them. This is returned by the Plan and PlanDestroy methods
+// without changing anything.
+type Plan struct {
+	// Provider the plan is for
+	Provider string `json:"provider"`
+
+	// Changes to each resource the deployment or destroy touches
+	Changes []ResourceChange `json:"changes"`
+
+	// Resources outside the environment, such as other environments and DNS
+	// records, that depend on a resource a destroy would delete - destroy
+	// plans only
+	Dependents []Dependent `json:"dependents,omitempty"`
+}
+
+// Add appends a change to the plan.
+func (p *Plan) Add(action PlanAction, resourceType, name, reason string) {
+	p.Changes = append(p.Changes, ResourceChange{Action: action, Type: resourceType, Name: name, Reason: reason})
+}
+
+// AddDependent records that a resource depends on one the plan deletes.
+func (p *Plan) AddDependent(resource, resourceType, name string) {
+	p.Dependents = append(p.Dependents, Dependent{Resource: resource, Type: resourceType, Name: name})
+}
+
+// Count returns the number of changes with the given action.
+func (p *Plan) Count(action PlanAction) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// PostureStatus is the outcome of a posture check.
+type PostureStatus string
+
+// Posture check outcomes.
+const (
+	PostureOK      PostureStatus = "ok"
+	PostureWarning PostureStatus = "warning"
+	PostureFailing PostureStatus = "failing"
+)
+
+// Categories of posture checks.
+const (
+	PostureQuota       = "quota"
+	PostureCredentials = "credentials"
+	PostureBilling     = "billing"
+	PostureAPI         = "api"
+)
+
+// Thresholds at which posture checks warn.
+const (
+	// Share of a quota in use
+	QuotaWarningRatio = 0.8
+
+	// Time left before credentials expire
+	ExpiryWarningWindow = 7 * 24 * time.Hour
+)
+
+// PostureCheck is one account-level check, such as a quota or the expiry of
+// the credentials cloud-deploy authenticates with.
+type PostureCheck struct {
+	// What was checked (PostureQuota, PostureCredentials, PostureBilling, or PostureAPI)
+	Category string `json:"category"`
+
+	// Name of the quota, credential, or API
+	Name string `json:"name"`
+
+	// Outcome of the check
+	Status PostureStatus `json:"status"`
+
+	// Usage, expiry, or state found (e.g., "18 of 20 used") - optional
+	Detail string `json:"detail,omitempty"`
+}
+
+// Posture summarizes the health of the account a provider deploys to, so
+// problems such as exhausted quotas or expiring credentials can be spotted
+// before a deployment fails on them.
+type Posture struct {
+	// Provider the posture is for
+	Provider string `json:"provider"`
+
+	// Checks, in the order they were made
+	Checks []PostureCheck `json:"checks"`
+}
+
+// Add appends a check to the posture.
+func (p *Posture) Add(category, name string, status PostureStatus, detail string) {
+	p.Checks = append(p.Checks, PostureCheck{Category: category, Name: name, Status: status, Detail: detail})
+}
+
+// AddQuota appends a quota check with the QuotaStatus of its usage. A limit of
+// zero or less means unlimited.
+func (p *Posture) AddQuota(name string, used, limit int64) {
+	if limit <= 0 {
+		p.Add(PostureQuota, name, PostureOK, fmt.Sprintf("%d used (no limit)", used))
+		return
+	}
+	p.Add(PostureQuota, name, QuotaStatus(float64(used), float64(limit)), fmt.Sprintf("%d of %d used", used, limit))
+}
+
+// QuotaStatus returns the status of a quota with the given usage and limit:
+// a warning once QuotaWarningRatio of the limit is used and failing once all
+// of it is.
+func QuotaStatus(used, limit float64) PostureStatus {
+	switch {
+	case used >= limit:
+		return PostureFailing
+	case used >= QuotaWarningRatio*limit:
+		return PostureWarning
+	}
+	return PostureOK
+}
+
+// AddExpiry appends a credentials check, warning once the credential expires
+// within ExpiryWarningWindow of now and failing once it has expired.
+func (p *Posture) AddExpiry(name string, expires, now time.Time) {
+	left := expires.Sub(now)
+	status := PostureOK
+	detail := "expires " + expires.UTC().Format(time.RFC3339)
+	switch {
+	case left <= 0:
+		status = PostureFailing
+		detail = "expired " + expires.UTC().Format(time.RFC3339)
+	case left < ExpiryWarningWindow:
+		status = PostureWarning
+	}
+	p.Add(PostureCredentials, name, status, detail)
+}
+
+// Count returns the number of checks with the given status.
+func (p *Posture) Count(status PostureStatus) int {
+	n := 0
+	for _, c := range p.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidationFinding is a problem with a manifest that its own validation
+// cannot catch because it depends on what the provider offers, such as an
+// instance type that is not available in the region.
+type ValidationFinding struct {
+	// Provider the finding is for; empty for findings independent of the provider
+	Provider string `json:"provider,omitempty"`
+
+	// Manifest field at fault (e.g., "instance.type")
+	Field string `json:"field"`
+
+	// What is wrong with the field's value
+	Message string `json:"message"`
+}
+
+// How the resources of an inventory were found.
+const (
+	// InventoryByName resources are named after the application or environment
+	InventoryByName = "name"
+	// InventoryByTag resources carry the application's tag or label
+	InventoryByTag = "tag"
+	// InventoryByState resources are recorded in the deployment history
+	InventoryByState = "state"
+)
+
+// InventoryResource is a cloud resource that belongs to an application.
+type InventoryResource struct {
+	// Kind of resource (e.g., "Elastic Beanstalk environment", "DNS record")
+	Type string `json:"type"`
+
+	// Name of the resource
+	Name string `json:"name"`
+
+	// ID the provider's CLI and console identify the resource by (e.g., an
+	// ARN or a full resource name)
+	ID string `json:"id"`
+
+	// How the resource was found (InventoryByName, InventoryByTag, or InventoryByState)
+	Source string `json:"source"`
+}
+
+// Inventory lists the cloud resources associated with an application, with
+// IDs suitable for auditing or manual cleanup.
+type Inventory struct {
+	// Provider the resources are on
+	Provider string `json:"provider"`
+
+	// Resources, in the order they were found
+	Resources []InventoryResource `json:"resources"`
+}
+
+// Add appends a resource to the inventory. A resource that is already listed
+// is not added again.
+func (i *Inventory) Add(resourceType, name, id, source string) {
+	for _, r := range i.Resources {
+		if r.Type == resourceType && r.ID == id {
+			return
+		}
+	}
+	i.Resources = append(i.Resources, InventoryResource{Type: resourceType, Name: name, ID: id, Source: source})
+}
+
+// RetentionPolicy selects the images and application versions a cleanup
+// deletes: all but the KeepLast newest, and of those only the ones older than
+// OlderThan when it is set.
+type RetentionPolicy struct {
+	// Number of the newest items that are always kept
+	KeepLast int `json:"keep_last"`
+
+	// Minimum age of the items deleted, 0 for any age
+	OlderThan time.Duration `json:"older_than,omitempty"`
+}
+
+// Expired reports for each item, by the time it was created, whether the
+// policy deletes it at now.
+func (p RetentionPolicy) Expired(created []time.Time, now time.Time) []bool {
+	order := make([]int, len(created))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return created[order[a]].After(created[order[b]]) })
+
+	expired := make([]bool, len(created))
+	for rank, i := range order {
+		expired[i] = rank >= p.KeepLast && (p.OlderThan == 0 || now.Sub(created[i]) > p.OlderThan)
+	}
+	return expired
+}
+
+// PrunedResource is an image, application version, or source bundle a
+// cleanup deleted.
+type PrunedResource struct {
+	// Kind of resource (e.g., "ECR image", "Elastic Beanstalk application version")
+	Type string `json:"type"`
+
+	// Name of the resource (e.g., an image's tags or digest, a version label)
+	Name string `json:"name"`
+
+	// When the resource was created or pushed
+	Created time.Time `json:"created"`
+}
+
+// Cleanup is the result of pruning an application's old images and
+// application versions.
+type Cleanup struct {
+	// Provider the resources were pruned on
+	Provider string `json:"provider"`
+
+	// Whether the resources were only listed, not deleted
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Resources deleted, or that would be with DryRun
+	Deleted []PrunedResource `json:"deleted"`
+
+	// Number of resources kept by the policy or because they are deployed
+	Kept int `json:"kept"`
+}
+
+// Prune records a deleted resource.
+func (c *Cleanup) Prune(resourceType, name string, created time.Time) {
+	c.Deleted = append(c.Deleted, PrunedResource{Type: resourceType, Name: name, Created: created})
+}
+
+// Operation is a long-running provider operation in progress, such as an
+// Elastic Beanstalk environment update, a Cloud Run operation, or an Azure
+// poller. It is journaled so that if cloud-deploy dies while it runs, another
+// invocation can attach to it instead of starting a conflicting one.
+type Operation struct {
+	// Provider that started the operation
+	Provider string `json:"provider"`
+
+	// Kind of operation, which tells the provider how to resume it (e.g.,
+	// "environment-update")
+	Kind string `json:"kind"`
+
+	// ID the provider resumes the operation with: an environment name, an
+	// operation name, or a poller's resume token
+	ID string `json:"id"`
+
+	// Command that started the operation
+	Command string `json:"command,omitempty"`
+
+	// User and host that started the operation (e.g., "alice@build-1")
+	Operator string `json:"operator,omitempty"`
+
+	// Version of cloud-deploy that started the operation
+	ToolVersion string `json:"tool_version,omitempty"`
+
+	Started time.Time `json:"started"`
+}
+
+// String returns a one-line description of the operation.
+func (o Operation) String() string {
+	return fmt.Sprintf("%s %s (%s) started by %s at %s", o.Command, o.Kind, o.ID, o.Operator, o.Started.Local().Format(time.DateTime))
+}
+
+// OperationJournal persists the long-running operation a provider has in
+// progress.
+type OperationJournal interface {
+	// Begin records op as in progress.
+	Begin(ctx context.Context, op Operation) error
+
+	// End records that the operation in progress finished.
+	End(ctx context.Context) error
 }
@@ -2,6 +2,7 @@ package types
 
 import (
 	"testing"
+	"time"
 )
 
 func TestDeploymentResult(t *testing.T) {
@@ -141,3 +142,163 @@ func TestDeploymentStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestLogEntryString(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	e := LogEntry{Timestamp: ts, Source: "web", Message: "listening on :8080"}
+	if got := e.String(); got != "2024-01-02T03:04:05Z [web] listening on :8080" {
+		t.Errorf("Unexpected format: %s", got)
+	}
+
+	e.Source = ""
+	if got := e.String(); got != "2024-01-02T03:04:05Z listening on :8080" {
+		t.Errorf("Unexpected format without source: %s", got)
+	}
+}
+
+func TestRemediationEventString(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	code := int32(137)
+
+	e := RemediationEvent{Timestamp: ts, Source: "web", Kind: RemediationContainerRestarted, Message: "OOMKilled", ExitCode: &code}
+	if got := e.String(); got != "2024-01-02T03:04:05Z [web] container-restarted: OOMKilled (exit code 137)" {
+		t.Errorf("Unexpected format: %s", got)
+	}
+
+	e = RemediationEvent{Timestamp: ts, Source: "i-0abc", Kind: RemediationInstanceReplaced}
+	if got := e.String(); got != "2024-01-02T03:04:05Z [i-0abc] instance-replaced" {
+		t.Errorf("Unexpected format without message: %s", got)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	p := &Plan{Provider: "aws"}
+	p.Add(PlanCreate, "S3 bucket", "my-bucket", "")
+	p.Add(PlanUpdate, "Elastic Beanstalk environment", "my-env", "deploy new version")
+	p.Add(PlanCreate, "ECR repository", "my-app", "")
+	p.Add(PlanNoChange, "Elastic Beanstalk application", "my-app", "")
+
+	if len(p.Changes) != 4 || p.Changes[1].Reason != "deploy new version" {
+		t.Fatalf("Unexpected changes: %+v", p.Changes)
+	}
+	if p.Count(PlanCreate) != 2 || p.Count(PlanUpdate) != 1 || p.Count(PlanDelete) != 0 || p.Count(PlanNoChange) != 1 {
+		t.Errorf("Unexpected counts: %+v", p.Changes)
+	}
+}
+
+func TestPlanAddDependent(t *testing.T) {
+	p := &Plan{Provider: "aws"}
+	p.Add(PlanDelete, "CloudFormation stack", "cloud-deploy-my-app", "")
+	p.AddDependent("CloudFormation stack cloud-deploy-my-app", "Elastic Beanstalk environment", "my-app-staging")
+
+	want := Dependent{Resource: "CloudFormation stack cloud-deploy-my-app", Type: "Elastic Beanstalk environment", Name: "my-app-staging"}
+	if len(p.Dependents) != 1 || p.Dependents[0] != want {
+		t.Errorf("Unexpected dependents: %+v", p.Dependents)
+	}
+}
+
+func TestPostureQuota(t *testing.T) {
+	tests := []struct {
+		used, limit int64
+		status      PostureStatus
+		detail      string
+	}{
+		{3, 10, PostureOK, "3 of 10 used"},
+		{8, 10, PostureWarning, "8 of 10 used"},
+		{10, 10, PostureFailing, "10 of 10 used"},
+		{12, 10, PostureFailing, "12 of 10 used"},
+		{5, 0, PostureOK, "5 used (no limit)"},
+	}
+	for _, tt := range tests {
+		p := &Posture{}
+		p.AddQuota("Environments", tt.used, tt.limit)
+		c := p.Checks[0]
+		if c.Category != PostureQuota || c.Status != tt.status || c.Detail != tt.detail {
+			t.Errorf("AddQuota(%d, %d) = %+v, want %s %q", tt.used, tt.limit, c, tt.status, tt.detail)
+		}
+	}
+}
+
+func TestPostureExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expires time.Time
+		status  PostureStatus
+		detail  string
+	}{
+		{now.Add(30 * 24 * time.Hour), PostureOK, "expires 2025-07-01T12:00:00Z"},
+		{now.Add(2 * 24 * time.Hour), PostureWarning, "expires 2025-06-03T12:00:00Z"},
+		{now.Add(-time.Hour), PostureFailing, "expired 2025-06-01T11:00:00Z"},
+	}
+	for _, tt := range tests {
+		p := &Posture{}
+		p.AddExpiry("Client certificate", tt.expires, now)
+		c := p.Checks[0]
+		if c.Category != PostureCredentials || c.Status != tt.status || c.Detail != tt.detail {
+			t.Errorf("AddExpiry(%s) = %+v, want %s %q", tt.expires, c, tt.status, tt.detail)
+		}
+	}
+}
+
+func TestPostureCount(t *testing.T) {
+	p := &Posture{Provider: "gcp"}
+	p.Add(PostureBilling, "Billing", PostureOK, "")
+	p.Add(PostureAPI, "run.googleapis.com", PostureFailing, "disabled")
+	p.Add(PostureAPI, "storage.googleapis.com", PostureOK, "")
+	if p.Count(PostureOK) != 2 || p.Count(PostureFailing) != 1 || p.Count(PostureWarning) != 0 {
+		t.Errorf("Unexpected counts: %+v", p.Checks)
+	}
+}
+
+func TestInventoryAdd(t *testing.T) {
+	inventory := &Inventory{Provider: "aws"}
+	inventory.Add("S3 bucket", "my-bucket", "arn:aws:s3:::my-bucket", InventoryByName)
+	inventory.Add("Container image", "my-app:v1", "my-app@sha256:abc", InventoryByState)
+	inventory.Add("S3 bucket", "my-bucket", "arn:aws:s3:::my-bucket", InventoryByTag)
+
+	if len(inventory.Resources) != 2 {
+		t.Fatalf("expected the duplicate bucket to be skipped, got %+v", inventory.Resources)
+	}
+	if r := inventory.Resources[0]; r.Source != InventoryByName || r.Name != "my-bucket" {
+		t.Errorf("unexpected first resource: %+v", r)
+	}
+}
+
+func TestOperationString(t *testing.T) {
+	op := Operation{Command: "deploy", Kind: "service-update", ID: "operations/op-1", Operator: "alice@build-1", Started: time.Date(2026, 3, 4, 12, 0, 0, 0, time.Local)}
+	if got, want := op.String(), "deploy service-update (operations/op-1) started by alice@build-1 at 2026-03-04 12:00:00"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	created := []time.Time{
+		now.Add(-48 * time.Hour),
+		now.Add(-time.Hour),
+		now.Add(-72 * time.Hour),
+		now.Add(-24 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []bool
+	}{
+		{"keep last", RetentionPolicy{KeepLast: 2}, []bool{true, false, true, false}},
+		{"keep all", RetentionPolicy{KeepLast: 4}, []bool{false, false, false, false}},
+		{"older than", RetentionPolicy{KeepLast: 1, OlderThan: 60 * time.Hour}, []bool{false, false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Expired(created, now)
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expired() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
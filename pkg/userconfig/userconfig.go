@@ -0,0 +1,127 @@
+// Package userconfig reads the cloud-deploy configuration of the user or
+// machine running it, for settings that belong to the operator rather than to
+// any one manifest. The file is config.yaml in the cloud-deploy directory of
+// the user's configuration directory (e.g., ~/.config/cloud-deploy/config.yaml),
+// or the file named by CLOUD_DEPLOY_CONFIG:
+//
+//	concurrency:
+//	  max_parallel: 6     # deployments running at once
+//	  providers:          # deployments running at once per provider
+//	    aws: 2
+//	  priorities:         # environment classes with higher priorities start first
+//	    prod: 100
+//	    preview: -100
+//	read_only: true       # refuse every command and API request that changes anything
+package userconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPath names the configuration file to read instead of the default.
+const EnvPath = "CLOUD_DEPLOY_CONFIG"
+
+// DefaultPriorities rank environment classes when the configuration sets no
+// priorities: production first, previews last, and everything else between.
+var DefaultPriorities = map[string]int{
+	"prod":       100,
+	"production": 100,
+	"staging":    50,
+	"dev":        -50,
+	"preview":    -100,
+}
+
+// Config is the user configuration.
+type Config struct {
+	// Limits and ordering of deployments run together - optional
+	Concurrency ConcurrencyConfig `yaml:"concurrency,omitempty"`
+
+	// Run in read-only mode, as -read-only does, for viewers that must never
+	// change anything - default: false
+	ReadOnly bool `yaml:"read_only,omitempty"`
+}
+
+// ConcurrencyConfig limits how many deployments of a batch run at once, so
+// large batches stay within provider API quotas, and orders them so important
+// environments are not stuck behind the others.
+type ConcurrencyConfig struct {
+	// Most deployments running at once across all providers - default: unlimited
+	MaxParallel int `yaml:"max_parallel,omitempty"`
+
+	// Most deployments running at once per provider (e.g., aws: 2) - optional
+	Providers map[string]int `yaml:"providers,omitempty"`
+
+	// Priority of each environment class (environment.class); higher priorities
+	// start first and unlisted classes have priority 0 - default: DefaultPriorities
+	Priorities map[string]int `yaml:"priorities,omitempty"`
+}
+
+// Priority returns the priority of an environment class.
+func (c *ConcurrencyConfig) Priority(class string) int {
+	priorities := c.Priorities
+	if priorities == nil {
+		priorities = DefaultPriorities
+	}
+	return priorities[class]
+}
+
+// Path returns the location of the configuration file.
+func Path() (string, error) {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cloud-deploy", "config.yaml"), nil
+}
+
+// Load reads the configuration file. A missing file is an empty configuration,
+// unless it was named by CLOUD_DEPLOY_CONFIG.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, nil
+	}
+	cfg, err := LoadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && os.Getenv(EnvPath) == "" {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
+
+// LoadFile reads the configuration from path.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user configuration: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse user configuration %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid user configuration %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validate checks that concurrency limits are not negative.
+func (c *Config) validate() error {
+	if c.Concurrency.MaxParallel < 0 {
+		return fmt.Errorf("concurrency.max_parallel must not be negative")
+	}
+	for name, limit := range c.Concurrency.Providers {
+		if limit < 0 {
+			return fmt.Errorf("concurrency.providers.%s must not be negative", name)
+		}
+	}
+	return nil
+}
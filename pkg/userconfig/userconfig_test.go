@@ -0,0 +1,69 @@
+package userconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+concurrency:
+  max_parallel: 4
+  providers:
+    aws: 2
+  priorities:
+    prod: 10
+    preview: -5
+read_only: true
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPath, path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	c := cfg.Concurrency
+	if c.MaxParallel != 4 || c.Providers["aws"] != 2 {
+		t.Errorf("Unexpected concurrency limits: %+v", c)
+	}
+	if c.Priority("prod") != 10 || c.Priority("preview") != -5 || c.Priority("staging") != 0 {
+		t.Errorf("Expected the configured priorities to replace the defaults: %+v", c.Priorities)
+	}
+	if !cfg.ReadOnly {
+		t.Error("Expected read_only to be set")
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	t.Setenv(EnvPath, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected a missing default file to be an empty configuration, got: %v", err)
+	}
+	if cfg.Concurrency.Priority("prod") != DefaultPriorities["prod"] {
+		t.Error("Expected the default priorities")
+	}
+
+	t.Setenv(EnvPath, filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(); err == nil {
+		t.Error("Expected a missing CLOUD_DEPLOY_CONFIG file to fail")
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("concurrency:\n  providers:\n    gcp: -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "concurrency.providers.gcp must not be negative") {
+		t.Errorf("Expected a negative limit to fail, got: %v", err)
+	}
+}
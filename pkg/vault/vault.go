@@ -0,0 +1,599 @@
+// Package vault reads secrets from HashiCorp Vault's KV secrets engine over
+// its HTTP API, for the manifest's Vault secrets and provider credentials.
+//
+// The client logs in on first use with the configured auth method: a token,
+// AppRole, AWS IAM, which signs an sts:GetCallerIdentity request with the
+// AWS default credential chain so runners on EC2, ECS, or CI with AWS
+// credentials need no Vault secret of their own, or Kubernetes, which sends
+// the pod's service account token.
+//
+// Secrets are read by their API path; the version of a KV engine is looked up
+// from its mount, so KV version 2 paths may leave out the data/ segment.
+//
+// Tokens from a login are renewed in the background until the command's
+// context ends, so they do not expire during long deployments. A command
+// shares one client per configuration (see Shared), which it closes as it
+// exits. Close revokes the login token, unless secrets with leases, such as
+// dynamic database credentials handed to the deployment, were read with it.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/jvreagan/cloud-deploy/pkg/logging"
+)
+
+// Auth methods.
+const (
+	AuthToken      = "token"
+	AuthAppRole    = "approle"
+	AuthAWSIAM     = "aws-iam"
+	AuthKubernetes = "kubernetes"
+)
+
+// maxConcurrentReads is how many secrets ReadAll reads at once.
+const maxConcurrentReads = 8
+
+// DefaultKVMount is the mount of the KV secrets engine of a Vault dev server.
+const DefaultKVMount = "secret"
+
+// DefaultServiceAccountTokenPath is where Kubernetes projects the pod's
+// service account token.
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// stsBody is the body of the sts:GetCallerIdentity request signed for AWS IAM
+// logins.
+const stsBody = "Action=GetCallerIdentity&Version=2011-06-15"
+
+// renewAfter returns when to renew a token with ttl left: after two thirds
+// of it, leaving time to retry before it expires.
+var renewAfter = func(ttl time.Duration) <-chan time.Time {
+	return time.After(ttl * 2 / 3)
+}
+
+// Config is where a Vault server is and how to log in to it.
+type Config struct {
+	// Address of the server, e.g. https://vault.example.com:8200 (default: $VAULT_ADDR)
+	Address string
+
+	// TLSSkipVerify disables verification of the server's certificate
+	TLSSkipVerify bool
+
+	// Namespace of Vault Enterprise every request is made in (default:
+	// $VAULT_NAMESPACE)
+	Namespace string
+
+	// KVMount is the mount of the KV secrets engine holding provider
+	// credentials (default: DefaultKVMount)
+	KVMount string
+
+	// Auth is how to log in
+	Auth AuthConfig
+}
+
+// AuthConfig is how to log in to Vault.
+type AuthConfig struct {
+	// Method is token, approle, aws-iam, or kubernetes (default: token)
+	Method string
+
+	// MountPath of the auth method (default: approle, aws, or kubernetes)
+	MountPath string
+
+	// Token for token auth (default: $VAULT_TOKEN)
+	Token string
+
+	// RoleID and SecretID for AppRole auth
+	RoleID   string
+	SecretID string
+
+	// Role to log in as with AWS IAM or Kubernetes auth
+	Role string
+
+	// Region of the STS endpoint signed for AWS IAM auth (default: the global
+	// endpoint, sts.amazonaws.com); it must match the auth method's sts_endpoint
+	Region string
+
+	// ServerID is sent as X-Vault-AWS-IAM-Server-ID with AWS IAM auth, if the
+	// auth method requires it
+	ServerID string
+
+	// ServiceAccountTokenPath is the file holding the service account token
+	// for Kubernetes auth (default: DefaultServiceAccountTokenPath)
+	ServiceAccountTokenPath string
+}
+
+// Client reads secrets from a Vault server.
+type Client struct {
+	address    string
+	namespace  string
+	auth       AuthConfig
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	token string
+
+	// kvVersions are the versions of the KV engines read from, by mount path
+	kvVersions map[string]string
+
+	// loggedIn is true when token came from a login, not the configuration
+	loggedIn bool
+
+	// leased is true once a secret with a lease was read, which revoking the
+	// token would revoke
+	leased bool
+
+	// stop ends the renewal of the token
+	stop     chan struct{}
+	renewing sync.WaitGroup
+}
+
+// New creates a client for cfg. It does not contact the server.
+func New(cfg Config) (*Client, error) {
+	address := cfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("vault address is required (set vault.address or VAULT_ADDR)")
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if cfg.TLSSkipVerify {
+		httpClient.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	return &Client{
+		address:    strings.TrimRight(address, "/"),
+		namespace:  strings.Trim(namespace, "/"),
+		auth:       cfg.Auth,
+		httpClient: httpClient,
+		kvVersions: make(map[string]string),
+	}, nil
+}
+
+// Authenticate logs in with the configured auth method, unless the client
+// already has a token.
+func (c *Client) Authenticate(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" {
+		return nil
+	}
+
+	switch c.auth.Method {
+	case "", AuthToken:
+		token := c.auth.Token
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+		if token == "" {
+			return fmt.Errorf("vault token is required (set vault.auth.token or VAULT_TOKEN)")
+		}
+		c.token = token
+		return nil
+	case AuthAppRole:
+		return c.login(ctx, c.loginPath("approle"), map[string]string{
+			"role_id":   c.auth.RoleID,
+			"secret_id": c.auth.SecretID,
+		})
+	case AuthAWSIAM:
+		data, err := awsIAMLoginData(ctx, c.auth)
+		if err != nil {
+			return err
+		}
+		return c.login(ctx, c.loginPath("aws"), data)
+	case AuthKubernetes:
+		data, err := kubernetesLoginData(c.auth)
+		if err != nil {
+			return err
+		}
+		return c.login(ctx, c.loginPath("kubernetes"), data)
+	default:
+		return fmt.Errorf("unsupported vault auth method: %s", c.auth.Method)
+	}
+}
+
+// loginPath returns the login path of the auth method, mounted at the
+// configured mount path or else at defaultMount.
+func (c *Client) loginPath(defaultMount string) string {
+	mount := strings.Trim(c.auth.MountPath, "/")
+	if mount == "" {
+		mount = defaultMount
+	}
+	return "auth/" + mount + "/login"
+}
+
+// authResponse is the response of logins and token renewals.
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// ttl returns how long the token is valid for, or 0 if it cannot be renewed.
+func (r authResponse) ttl() time.Duration {
+	if !r.Auth.Renewable {
+		return 0
+	}
+	return time.Duration(r.Auth.LeaseDuration) * time.Second
+}
+
+// login logs in at path with data and keeps the client token it returns,
+// renewing it until ctx ends or the client is closed. The caller holds c.mu.
+func (c *Client) login(ctx context.Context, path string, data interface{}) error {
+	var out authResponse
+	if err := c.do(ctx, http.MethodPost, path, "", data, &out); err != nil {
+		return fmt.Errorf("failed to authenticate to vault: %w", err)
+	}
+	if out.Auth.ClientToken == "" {
+		return fmt.Errorf("failed to authenticate to vault: no client token returned")
+	}
+	c.token = out.Auth.ClientToken
+	c.loggedIn = true
+
+	if ttl := out.ttl(); ttl > 0 {
+		c.stop = make(chan struct{})
+		c.renewing.Add(1)
+		go c.renew(ctx, c.stop, ttl)
+	}
+	return nil
+}
+
+// renew renews the client's token before each ttl runs out, until ctx ends,
+// stop is closed, or the token cannot be renewed any further.
+func (c *Client) renew(ctx context.Context, stop <-chan struct{}, ttl time.Duration) {
+	defer c.renewing.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-renewAfter(ttl):
+		}
+
+		c.mu.Lock()
+		token := c.token
+		c.mu.Unlock()
+
+		var out authResponse
+		if err := c.do(ctx, http.MethodPost, "auth/token/renew-self", token, map[string]string{}, &out); err != nil {
+			if ctx.Err() == nil {
+				logging.Warnf("Failed to renew the vault token: %v", err)
+			}
+			return
+		}
+		if ttl = out.ttl(); ttl <= 0 {
+			return
+		}
+	}
+}
+
+// Close stops renewing the client's token and revokes the token the client
+// logged in with. Tokens from the configuration or VAULT_TOKEN are left valid,
+// as are tokens secrets with leases were read with: Vault revokes a token's
+// leases with it, and the deployment runs with those secrets. Such tokens
+// expire at the end of their TTL.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	c.mu.Unlock()
+	c.renewing.Wait()
+
+	c.mu.Lock()
+	token, revoke := c.token, c.loggedIn && !c.leased
+	if c.loggedIn {
+		c.token, c.loggedIn = "", false
+	}
+	c.mu.Unlock()
+	if !revoke {
+		return nil
+	}
+
+	// The command's context may already be canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := c.do(ctx, http.MethodPost, "auth/token/revoke-self", token, map[string]string{}, nil); err != nil {
+		return fmt.Errorf("failed to revoke the vault token: %w", err)
+	}
+	return nil
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[Config]*Client)
+)
+
+// Shared returns the client of cfg shared by the command, creating it on
+// first use, so secrets and provider credentials log in once. CloseShared
+// closes it.
+func Shared(cfg Config) (*Client, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if c, ok := shared[cfg]; ok {
+		return c, nil
+	}
+	c, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	shared[cfg] = c
+	return c, nil
+}
+
+// CloseShared closes the clients Shared returned, as the command exits.
+func CloseShared() error {
+	sharedMu.Lock()
+	clients := shared
+	shared = make(map[Config]*Client)
+	sharedMu.Unlock()
+
+	var errs []error
+	for _, c := range clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// awsIAMLoginData returns the login data of the AWS IAM auth method: an
+// sts:GetCallerIdentity request signed with the AWS default credential chain,
+// which Vault sends to STS to learn the caller's identity.
+func awsIAMLoginData(ctx context.Context, auth AuthConfig) (map[string]string, error) {
+	if auth.Role == "" {
+		return nil, fmt.Errorf("vault.auth.role is required for aws-iam authentication")
+	}
+
+	region, endpoint := "us-east-1", "https://sts.amazonaws.com/"
+	if auth.Region != "" {
+		region, endpoint = auth.Region, fmt.Sprintf("https://sts.%s.amazonaws.com/", auth.Region)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS credentials for vault aws-iam authentication: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(stsBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if auth.ServerID != "" {
+		req.Header.Set("X-Vault-AWS-IAM-Server-ID", auth.ServerID)
+	}
+	hash := sha256.Sum256([]byte(stsBody))
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "sts", region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign the sts:GetCallerIdentity request: %w", err)
+	}
+
+	headers, err := json.Marshal(req.Header)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"role":                    auth.Role,
+		"iam_http_request_method": http.MethodPost,
+		"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(endpoint)),
+		"iam_request_body":        base64.StdEncoding.EncodeToString([]byte(stsBody)),
+		"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+	}, nil
+}
+
+// kubernetesLoginData returns the login data of the Kubernetes auth method:
+// the role and the pod's service account token, which Vault checks with the
+// cluster's TokenReview API.
+func kubernetesLoginData(auth AuthConfig) (map[string]string, error) {
+	if auth.Role == "" {
+		return nil, fmt.Errorf("vault.auth.role is required for kubernetes authentication")
+	}
+	path := auth.ServiceAccountTokenPath
+	if path == "" {
+		path = DefaultServiceAccountTokenPath
+	}
+	jwt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account token for vault kubernetes authentication: %w", err)
+	}
+	return map[string]string{
+		"role": auth.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}, nil
+}
+
+// Read returns the fields of the secret at path, e.g. secret/myapp/db,
+// logging in first if needed. For a KV version 2 engine, data/ is added after
+// the mount unless path has it, e.g. secret/data/myapp/db.
+func (c *Client) Read(ctx context.Context, path string) (map[string]interface{}, error) {
+	if err := c.Authenticate(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	path = strings.Trim(path, "/")
+	mount, version := c.kvVersion(ctx, token, path)
+	if version == "2" {
+		if rest := strings.TrimPrefix(path, mount); !strings.HasPrefix(rest, "data/") {
+			path = mount + "data/" + rest
+		}
+	}
+
+	var out struct {
+		LeaseID string                 `json:"lease_id"`
+		Data    map[string]interface{} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, token, nil, &out); err != nil {
+		return nil, fmt.Errorf("failed to read %s from vault: %w", path, err)
+	}
+	if out.LeaseID != "" {
+		c.mu.Lock()
+		c.leased = true
+		c.mu.Unlock()
+	}
+	if out.Data == nil {
+		return nil, fmt.Errorf("failed to read %s from vault: secret not found", path)
+	}
+	// KV version 2 nests the fields under data, next to the version's metadata
+	if fields, ok := out.Data["data"].(map[string]interface{}); ok {
+		if _, ok := out.Data["metadata"]; ok || version == "2" {
+			return fields, nil
+		}
+	}
+	return out.Data, nil
+}
+
+// ReadAll reads the secret at each distinct path, at most maxConcurrentReads
+// at a time, and returns their fields by path. It fails with the first
+// secret that cannot be read.
+func (c *Client) ReadAll(ctx context.Context, paths []string) (map[string]map[string]interface{}, error) {
+	// Log in once, before the reads
+	if err := c.Authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	secrets := make(map[string]map[string]interface{}, len(paths))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentReads)
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		g.Go(func() error {
+			fields, err := c.Read(gctx, path)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			secrets[path] = fields
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// kvVersion returns the mount path, with a trailing slash, and the KV version
+// of the secrets engine path is in. It returns "" for both if the mount cannot
+// be looked up, e.g. on servers before Vault 1.1, and the version is "" for
+// other engines.
+func (c *Client) kvVersion(ctx context.Context, token, path string) (string, string) {
+	c.mu.Lock()
+	for mount, version := range c.kvVersions {
+		if strings.HasPrefix(path, mount) {
+			c.mu.Unlock()
+			return mount, version
+		}
+	}
+	c.mu.Unlock()
+
+	var out struct {
+		Data struct {
+			Path    string            `json:"path"`
+			Type    string            `json:"type"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "sys/internal/ui/mounts/"+path, token, nil, &out); err != nil || out.Data.Path == "" {
+		return "", ""
+	}
+	mount, version := strings.TrimPrefix(out.Data.Path, c.namespace+"/"), ""
+	if out.Data.Type == "kv" {
+		version = out.Data.Options["version"]
+		if version == "" {
+			version = "1"
+		}
+	}
+	c.mu.Lock()
+	c.kvVersions[mount] = version
+	c.mu.Unlock()
+	return mount, version
+}
+
+// do sends a request to the API path /v1/<path> and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+strings.TrimLeft(path, "/"), body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("secret not found")
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && len(apiErr.Errors) > 0 {
+			return fmt.Errorf("%s (HTTP %d)", strings.Join(apiErr.Errors, "; "), resp.StatusCode)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
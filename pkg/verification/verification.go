@@ -0,0 +1,143 @@
+// Package verification runs the manifest's post-deploy checks: HTTP requests
+// to the new deployment that must get the expected responses, so a deployment
+// that starts but does not serve correctly is caught and rolled back.
+package verification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+// Result is the outcome of a verification check.
+type Result struct {
+	// Name of the check
+	Name string `json:"name"`
+
+	// URL that was requested
+	URL string `json:"url"`
+
+	// Passed reports whether the check got the expected response
+	Passed bool `json:"passed"`
+
+	// HTTP status of the last response, or why the last attempt failed
+	Detail string `json:"detail,omitempty"`
+
+	// Attempts made
+	Attempts int `json:"attempts"`
+}
+
+// String formats the result as a line of deploy output.
+func (r Result) String() string {
+	s := fmt.Sprintf("%s (%s): ", r.Name, r.URL)
+	if r.Passed {
+		return s + r.Detail
+	}
+	return s + fmt.Sprintf("failed after %d attempts: %s", r.Attempts, r.Detail)
+}
+
+// sleep waits between attempts. It is a variable so tests can replace it.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Run runs every check concurrently against the deployment at baseURL and
+// returns the results in the order of checks. A baseURL without a scheme is
+// requested with https.
+func Run(ctx context.Context, baseURL string, checks []manifest.VerificationCheck) []Result {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c manifest.VerificationCheck) {
+			defer wg.Done()
+			results[i] = run(ctx, baseURL+c.Path, c)
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// Failed returns the number of results that did not pass.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+// run tries a single check until it passes or runs out of attempts.
+func run(ctx context.Context, rawURL string, c manifest.VerificationCheck) Result {
+	result := Result{Name: c.CheckName(), URL: rawURL}
+	for result.Attempts < c.Attempts() {
+		if result.Attempts > 0 {
+			if err := sleep(ctx, c.Interval()); err != nil {
+				result.Detail = err.Error()
+				return result
+			}
+		}
+		result.Attempts++
+		detail, err := attempt(ctx, rawURL, c)
+		if err == nil {
+			result.Passed = true
+			result.Detail = detail
+			return result
+		}
+		result.Detail = err.Error()
+	}
+	return result
+}
+
+// attempt requests rawURL once and returns its status, or an error when the
+// request fails or the status is not the expected one. Redirects are not
+// followed, so a check can expect one.
+func attempt(ctx context.Context, rawURL string, c manifest.VerificationCheck) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	client := &http.Client{
+		Transport:     http.DefaultClient.Transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return "", err
+	}
+	resp.Body.Close()
+
+	switch {
+	case c.ExpectedStatus != 0 && resp.StatusCode != c.ExpectedStatus:
+		return "", fmt.Errorf("HTTP %s, expected %d", resp.Status, c.ExpectedStatus)
+	case c.ExpectedStatus == 0 && resp.StatusCode >= 400:
+		return "", fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return "HTTP " + resp.Status, nil
+}
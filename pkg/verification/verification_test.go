@@ -0,0 +1,102 @@
+package verification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jvreagan/cloud-deploy/pkg/manifest"
+)
+
+func intPtr(i int) *int { return &i }
+
+// noSleep replaces the wait between attempts until the test ends.
+func noSleep(t *testing.T) {
+	t.Helper()
+	original := sleep
+	sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	t.Cleanup(func() { sleep = original })
+}
+
+func TestRun(t *testing.T) {
+	noSleep(t)
+	var readyCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+		case "/ready":
+			// Ready on the third attempt
+			if readyCalls.Add(1) < 3 {
+				http.Error(w, "starting", http.StatusServiceUnavailable)
+			}
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	results := Run(context.Background(), server.URL+"/", []manifest.VerificationCheck{
+		{Name: "health", Path: "/health"},
+		{Path: "/ready"},
+		{Name: "redirect", Path: "/old", ExpectedStatus: http.StatusMovedPermanently},
+		{Name: "missing", Path: "/missing", Retries: intPtr(1)},
+		{Name: "wrong status", Path: "/health", ExpectedStatus: http.StatusNoContent, Retries: intPtr(0)},
+	})
+
+	if n := Failed(results); n != 2 {
+		t.Fatalf("Failed() = %d, want 2: %+v", n, results)
+	}
+	if r := results[0]; !r.Passed || r.Attempts != 1 || r.Detail != "HTTP 200 OK" || r.URL != server.URL+"/health" {
+		t.Errorf("health = %+v", r)
+	}
+	if r := results[1]; !r.Passed || r.Attempts != 3 || r.Name != "/ready" {
+		t.Errorf("Expected /ready to pass on the third attempt, got %+v", r)
+	}
+	if r := results[2]; !r.Passed {
+		t.Errorf("Expected the redirect to be checked without following it, got %+v", r)
+	}
+	if r := results[3]; r.Passed || r.Attempts != 2 || !strings.Contains(r.String(), "failed after 2 attempts: HTTP 404 Not Found") {
+		t.Errorf("missing = %+v (%s)", r, r)
+	}
+	if r := results[4]; r.Passed || r.Attempts != 1 || r.Detail != "HTTP 200 OK, expected 204" {
+		t.Errorf("wrong status = %+v", r)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	noSleep(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	results := Run(context.Background(), server.URL, []manifest.VerificationCheck{{Path: "/slow", TimeoutSeconds: 1, Retries: intPtr(0)}})
+	if results[0].Passed || !strings.Contains(results[0].Detail, "deadline exceeded") {
+		t.Errorf("Expected a timeout, got %+v", results[0])
+	}
+}
+
+func TestRunDefaultScheme(t *testing.T) {
+	results := Run(context.Background(), "my-app.example.invalid", []manifest.VerificationCheck{{Path: "/health", Retries: intPtr(0)}})
+	if results[0].URL != "https://my-app.example.invalid/health" {
+		t.Errorf("URL = %q, want https", results[0].URL)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := Run(ctx, "http://127.0.0.1:1", []manifest.VerificationCheck{{Path: "/health", IntervalSeconds: intPtr(60)}})
+	if results[0].Passed || results[0].Attempts != 1 {
+		t.Errorf("Expected a canceled check to stop after one attempt, got %+v", results[0])
+	}
+}